@@ -17,18 +17,49 @@
 package vcert
 
 import (
+	"context"
+	"crypto/tls"
 	"crypto/x509"
 	"fmt"
 	"log"
 
 	"github.com/Venafi/vcert/v5/pkg/endpoint"
+	"github.com/Venafi/vcert/v5/pkg/util"
+	"github.com/Venafi/vcert/v5/pkg/venafi/acme"
 	"github.com/Venafi/vcert/v5/pkg/venafi/cloud"
+	"github.com/Venafi/vcert/v5/pkg/venafi/est"
 	"github.com/Venafi/vcert/v5/pkg/venafi/fake"
 	"github.com/Venafi/vcert/v5/pkg/venafi/firefly"
+	"github.com/Venafi/vcert/v5/pkg/venafi/scep"
 	"github.com/Venafi/vcert/v5/pkg/venafi/tpp"
 	"github.com/Venafi/vcert/v5/pkg/verror"
 )
 
+// retryPolicySetter is implemented by the connectors whose HTTP requests are retried
+type retryPolicySetter interface {
+	SetRetryPolicy(policy util.RetryPolicy)
+}
+
+// proxySetter is implemented by the connectors whose HTTP requests can be sent through a proxy
+type proxySetter interface {
+	SetProxy(proxy util.ProxyConfig)
+}
+
+// transportConfigSetter is implemented by the connectors whose HTTP transport can be tuned
+type transportConfigSetter interface {
+	SetTransportConfig(config util.TransportConfig)
+}
+
+// tlsConfigSetter is implemented by the connectors whose TLS settings can be set
+type tlsConfigSetter interface {
+	SetTLSConfig(config *tls.Config)
+}
+
+// zoneCacheSetter is implemented by the connectors that can cache zone configurations
+type zoneCacheSetter interface {
+	SetZoneConfigurationCache(cache *endpoint.ZoneConfigurationCache)
+}
+
 type newClientArgs struct {
 	authenticate bool
 }
@@ -40,11 +71,17 @@ type newClientArgs struct {
 // The returned connector will be authenticated by default, but it's possible to pass a bool argument to indicate if it's
 // desired to get the connector authenticated already or not.
 func (cfg *Config) NewClient(args ...interface{}) (connector endpoint.Connector, err error) {
-	return cfg.newClient(args)
+	return cfg.newClient(context.Background(), args)
+}
+
+// NewClientContext is like NewClient, with ctx used to authenticate the connector. The connector does not keep ctx:
+// each of its methods takes the context of the call
+func (cfg *Config) NewClientContext(ctx context.Context, args ...interface{}) (connector endpoint.Connector, err error) {
+	return cfg.newClient(ctx, args)
 }
 
 // this function is to manage the variadic arguments
-func (cfg *Config) newClient(args []interface{}) (connector endpoint.Connector, err error) {
+func (cfg *Config) newClient(ctx context.Context, args []interface{}) (connector endpoint.Connector, err error) {
 
 	var clientArgs *newClientArgs
 	clientArgs, err = getNewClientArguments(args)
@@ -52,6 +89,15 @@ func (cfg *Config) newClient(args []interface{}) (connector endpoint.Connector,
 		return nil, err
 	}
 
+	err = cfg.Proxy.IsValid()
+	if err != nil {
+		return nil, fmt.Errorf("%w: %s", verror.UserDataError, err)
+	}
+	err = cfg.Transport.IsValid()
+	if err != nil {
+		return nil, fmt.Errorf("%w: %s", verror.UserDataError, err)
+	}
+
 	var connectionTrustBundle *x509.CertPool
 
 	if cfg.ConnectionTrust != "" {
@@ -69,6 +115,12 @@ func (cfg *Config) newClient(args []interface{}) (connector endpoint.Connector,
 		connector, err = tpp.NewConnector(cfg.BaseUrl, cfg.Zone, cfg.LogVerbose, connectionTrustBundle)
 	case endpoint.ConnectorTypeFirefly:
 		connector, err = firefly.NewConnector(cfg.BaseUrl, cfg.Zone, cfg.LogVerbose, connectionTrustBundle)
+	case endpoint.ConnectorTypeACME:
+		connector, err = acme.NewConnector(cfg.BaseUrl, cfg.Zone, cfg.LogVerbose, connectionTrustBundle, cfg.ACMEChallenge)
+	case endpoint.ConnectorTypeEST:
+		connector, err = est.NewConnector(cfg.BaseUrl, cfg.Zone, cfg.LogVerbose, connectionTrustBundle)
+	case endpoint.ConnectorTypeSCEP:
+		connector, err = scep.NewConnector(cfg.BaseUrl, cfg.Zone, cfg.LogVerbose, connectionTrustBundle)
 	case endpoint.ConnectorTypeFake:
 		connector = fake.NewConnector(cfg.LogVerbose, connectionTrustBundle)
 	default:
@@ -80,9 +132,24 @@ func (cfg *Config) newClient(args []interface{}) (connector endpoint.Connector,
 
 	connector.SetZone(cfg.Zone)
 	connector.SetHTTPClient(cfg.Client)
+	if r, ok := connector.(retryPolicySetter); ok {
+		r.SetRetryPolicy(cfg.RetryPolicy)
+	}
+	if p, ok := connector.(proxySetter); ok {
+		p.SetProxy(cfg.Proxy)
+	}
+	if t, ok := connector.(transportConfigSetter); ok {
+		t.SetTransportConfig(cfg.Transport)
+	}
+	if t, ok := connector.(tlsConfigSetter); ok && cfg.TLSConfig != nil {
+		t.SetTLSConfig(cfg.TLSConfig)
+	}
+	if z, ok := connector.(zoneCacheSetter); ok && cfg.ZoneCache != nil {
+		z.SetZoneConfigurationCache(cfg.ZoneCache)
+	}
 
 	if clientArgs.authenticate {
-		err = connector.Authenticate(cfg.Credentials)
+		err = connector.Authenticate(ctx, cfg.Credentials)
 	}
 
 	return
@@ -121,5 +188,10 @@ func getNewClientArguments(args []interface{}) (*newClientArgs, error) {
 // The returned connector will be authenticated by default, but it's possible to pass a bool argument to indicate if it's
 // desired to get the connector authenticated already or not.
 func NewClient(cfg *Config, args ...interface{}) (endpoint.Connector, error) {
-	return cfg.newClient(args)
+	return cfg.newClient(context.Background(), args)
+}
+
+// NewClientContext is like NewClient, with ctx used to authenticate the connector
+func NewClientContext(ctx context.Context, cfg *Config, args ...interface{}) (endpoint.Connector, error) {
+	return cfg.newClient(ctx, args)
 }
@@ -17,6 +17,7 @@
 package vcert
 
 import (
+	"context"
 	"crypto/tls"
 	"crypto/x509/pkix"
 	"encoding/json"
@@ -44,6 +45,7 @@ func print(a interface{}) {
 }
 
 func TestNewClient(t *testing.T) {
+	ctx := context.Background()
 	var haltIf = func(err error) {
 		if err != nil {
 			t.Fatal(err)
@@ -64,21 +66,22 @@ func TestNewClient(t *testing.T) {
 		DNSNames: []string{"www.client.venafi.example.com", "ww1.client.venafi.example.com"},
 	}
 
-	err = c.GenerateRequest(nil, req)
+	err = c.GenerateRequest(ctx, nil, req)
 	haltIf(err)
 	print(req)
 
-	id, err := c.RequestCertificate(req)
+	id, err := c.RequestCertificate(ctx, req)
 	haltIf(err)
 	print(id)
 
 	req.Timeout = 180 * time.Second
-	certs, err := c.RetrieveCertificate(req)
+	certs, err := c.RetrieveCertificate(ctx, req)
 	haltIf(err)
 	print(certs)
 }
 
 func TestNewClientWithFileConfig(t *testing.T) {
+	ctx := context.Background()
 	var haltIf = func(err error) {
 		if err != nil {
 			t.Fatal(err)
@@ -115,16 +118,16 @@ func TestNewClientWithFileConfig(t *testing.T) {
 		DNSNames: []string{"www.client.venafi.example.com", "ww1.client.venafi.example.com"},
 	}
 
-	err = c.GenerateRequest(nil, req)
+	err = c.GenerateRequest(ctx, nil, req)
 	haltIf(err)
 	print(req)
 
-	id, err := c.RequestCertificate(req)
+	id, err := c.RequestCertificate(ctx, req)
 	haltIf(err)
 	print(id)
 
 	req.Timeout = 180 * time.Second
-	certs, err := c.RetrieveCertificate(req)
+	certs, err := c.RetrieveCertificate(ctx, req)
 	haltIf(err)
 	print(certs)
 }
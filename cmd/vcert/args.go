@@ -17,6 +17,9 @@
 package main
 
 import (
+	"encoding/asn1"
+	"time"
+
 	"github.com/Venafi/vcert/v5/pkg/certificate"
 	"github.com/Venafi/vcert/v5/pkg/venafi"
 )
@@ -36,6 +39,7 @@ const (
 	commandSshPickupName    = "sshpickup"
 	commandSshEnrollName    = "sshenroll"
 	commandSshGetConfigName = "sshgetconfig"
+	commandSshRevokeName    = "sshrevoke"
 )
 
 var (
@@ -44,6 +48,7 @@ var (
 
 type commandFlags struct {
 	apiKey               string
+	appDNs               []string
 	appInfo              string
 	audience             string
 	caDN                 string
@@ -54,6 +59,8 @@ type commandFlags struct {
 	clientSecret         string
 	clientP12            string
 	clientP12PW          string
+	clientCertFile       string
+	clientKeyFile        string
 	commonName           string
 	config               string
 	country              string
@@ -65,6 +72,7 @@ type commandFlags struct {
 	emailSans            rfc822NameSlice
 	file                 string
 	format               string
+	output               string
 	friendlyName         string
 	insecure             bool
 	instance             string
@@ -79,7 +87,22 @@ type commandFlags struct {
 	keyType              *certificate.KeyType
 	keyTypeString        string
 	locality             string
+	logFile              string
+	auditFile            string
+	auditSyslog          string
+	batch                string
+	batchConcurrency     int
+	batchDir             string
+	batchResult          string
+	allExpiring          bool
+	expiresWithin        string
+	recursive            bool
+	logFormat            string
+	logLevel             string
 	noPickup             bool
+	verifySCT            bool
+	requireSCT           bool
+	ctLogList            string
 	noPrompt             bool
 	noRetire             bool
 	org                  string
@@ -88,42 +111,71 @@ type commandFlags struct {
 	pickupIDFile         string
 	profile              string
 	replaceInstance      bool
+	retireBlocklist      bool
 	revocationReason     string
 	scope                string
 	sshCred              bool
 	pmCred               bool
+	renewToken           bool
+	renewBefore          time.Duration
+	renewInterval        time.Duration
 	state                string
 	testMode             bool
 	testModeDelay        int
 	thumbprint           string
+	serialNumber         string
 	timeout              int
 	tlsAddress           string
 	email                string
 	password             string
 	token                string
 	userName             string
+	proxyURL             string
+	proxyUser            string
+	proxyPassword        string
+	noProxy              string
 	trustBundle          string
 	upnSans              rfc822NameSlice
+	ridSans              []asn1.ObjectIdentifier
 	uriSans              uriSlice
 	url                  string
 	deviceURL            string
+	tokenURL             string
+	externalJWT          string
+	serviceAccountID     string
+	serviceAccountKey    string
 	verbose              bool
 	zone                 string
 	omitSans             bool
+	reuseKey             bool
 	csrFormat            string
 	credFormat           string
 	validDays            string
 	validPeriod          string
+	notAfter             string
 	platformString       string
 	platform             venafi.Platform
+	acmeAccountKey       string
+	acmeChallenge        string
+	acmeDNSCommand       string
+	acmeEmail            string
+	acmeHTTPAddress      string
+	acmeWebroot          string
+	eabKeyID             string
+	eabHMACKey           string
 	policyName           string
 	policySpecLocation   string
 	policyConfigStarter  bool
 	verifyPolicyConfig   bool
+	policyTranslate      bool
+	policyTranslateTo    string
+	createApp            bool
+	appOwners            []string
+	appDescription       string
 	sshCertKeyId         string
 	sshCertObjectName    string
 	sshCertDestAddrs     stringSlice
-	sshCertValidHours    int
+	validHours           int
 	sshCertTemplate      string
 	sshCertFolder        string
 	sshCertPubKeyData    string
@@ -137,6 +189,7 @@ type commandFlags struct {
 	sshCertExtension     stringSlice
 	sshCertPrincipal     stringSlice
 	sshCertWindows       bool
+	sshCertRenew         bool
 	sshFileCertEnroll    string
 	sshFileGetConfig     string
 }
@@ -0,0 +1,145 @@
+/*
+ * Copyright 2023 Venafi, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *  http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package main
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/Venafi/vcert/v5/pkg/certificate"
+	"github.com/Venafi/vcert/v5/pkg/playbook/app/audit"
+)
+
+// openCommandAuditLog opens the audit log set with the audit flags, or returns nil when they are not set. It is opened
+// before the operation audited is run, so the operation does not run when it cannot be recorded
+func openCommandAuditLog() (*audit.Log, error) {
+	opts := audit.Options{File: flags.auditFile, Syslog: flags.auditSyslog}
+	if opts.IsEmpty() {
+		return nil, nil
+	}
+	l, err := audit.Open(opts)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open audit log: %w", err)
+	}
+	return l, nil
+}
+
+// playbookAuditOptions returns the audit settings of the playbook, overridden by the audit flags
+func playbookAuditOptions(config *audit.Options) audit.Options {
+	opts := audit.Options{}
+	if config != nil {
+		opts = *config
+	}
+	if flags.auditFile != "" {
+		opts.File = flags.auditFile
+	}
+	if flags.auditSyslog != "" {
+		opts.Syslog = flags.auditSyslog
+	}
+	return opts
+}
+
+// recordAudit writes event to l, failed with err when it is not nil
+func recordAudit(l *audit.Log, event audit.Event, err error) {
+	if l == nil {
+		return
+	}
+	if err != nil {
+		event.Error = err.Error()
+	}
+	l.Record(event)
+}
+
+// requestAuditParameters returns the parameters of req recorded in the audit log. Secrets, like the key password,
+// are left out
+func requestAuditParameters(req *certificate.Request) map[string]string {
+	params := map[string]string{
+		"csrOrigin": req.CsrOrigin.String(),
+		"keyType":   req.KeyType.String(),
+	}
+	if req.KeyLength > 0 {
+		params["keySize"] = strconv.Itoa(req.KeyLength)
+	}
+	if req.KeyCurve != certificate.EllipticCurveNotSet {
+		params["keyCurve"] = req.KeyCurve.String()
+	}
+	if len(req.DNSNames) > 0 {
+		params["sanDNS"] = strings.Join(req.DNSNames, ",")
+	}
+	if len(req.EmailAddresses) > 0 {
+		params["sanEmail"] = strings.Join(req.EmailAddresses, ",")
+	}
+	if len(req.IPAddresses) > 0 {
+		ips := make([]string, 0, len(req.IPAddresses))
+		for _, ip := range req.IPAddresses {
+			ips = append(ips, ip.String())
+		}
+		params["sanIP"] = strings.Join(ips, ",")
+	}
+	if len(req.URIs) > 0 {
+		uris := make([]string, 0, len(req.URIs))
+		for _, uri := range req.URIs {
+			uris = append(uris, uri.String())
+		}
+		params["sanURI"] = strings.Join(uris, ",")
+	}
+	if len(req.UPNs) > 0 {
+		params["sanUPN"] = strings.Join(req.UPNs, ",")
+	}
+	if len(req.RegisteredIDs) > 0 {
+		rids := make([]string, 0, len(req.RegisteredIDs))
+		for _, rid := range req.RegisteredIDs {
+			rids = append(rids, rid.String())
+		}
+		params["sanRID"] = strings.Join(rids, ",")
+	}
+	if req.NotAfter != nil {
+		params["notAfter"] = req.NotAfter.Format(time.RFC3339)
+	} else if req.ValidityDuration != nil {
+		params["validity"] = req.ValidityDuration.String()
+	}
+	return params
+}
+
+// enrollAuditEvent returns the audit event of the enrollment of req, picked up by pickupID
+func enrollAuditEvent(platform string, req *certificate.Request, pickupID string) audit.Event {
+	return audit.Event{
+		Operation:  audit.OperationEnroll,
+		Platform:   platform,
+		Zone:       flags.zone,
+		CommonName: req.Subject.CommonName,
+		Parameters: requestAuditParameters(req),
+		PickupID:   pickupID,
+	}
+}
+
+// revokeAuditParameters returns the parameters of req recorded in the audit log
+func revokeAuditParameters(req *certificate.RevocationRequest) map[string]string {
+	params := map[string]string{}
+	if req.CertificateDN != "" {
+		params["dn"] = req.CertificateDN
+	}
+	if req.Reason != "" {
+		params["reason"] = req.Reason
+	}
+	if req.Disable {
+		params["disable"] = "true"
+	}
+	return params
+}
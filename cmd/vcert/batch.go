@@ -0,0 +1,549 @@
+/*
+ * Copyright 2023 Venafi, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *  http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package main
+
+import (
+	"bytes"
+	"context"
+	"crypto/x509"
+	"encoding/asn1"
+	"encoding/csv"
+	"encoding/json"
+	"encoding/pem"
+	"fmt"
+	"io"
+	"net"
+	"net/url"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/Venafi/vcert/v5"
+	"github.com/Venafi/vcert/v5/pkg/certificate"
+	"github.com/Venafi/vcert/v5/pkg/endpoint"
+	"github.com/Venafi/vcert/v5/pkg/playbook/app/audit"
+	"github.com/Venafi/vcert/v5/pkg/util"
+)
+
+const batchProgressWidth = 30
+
+// batchEntry is a row of the manifest of enroll --batch. CSV manifests name the same columns in their header, and
+// separate the values of the SAN columns with semicolons
+type batchEntry struct {
+	CommonName string   `json:"cn"`
+	Zone       string   `json:"zone,omitempty"`
+	DNSSans    []string `json:"san-dns,omitempty"`
+	IPSans     []string `json:"san-ip,omitempty"`
+	EmailSans  []string `json:"san-email,omitempty"`
+	URISans    []string `json:"san-uri,omitempty"`
+	UPNSans    []string `json:"san-upn,omitempty"`
+	RIDSans    []string `json:"san-rid,omitempty"`
+	File       string   `json:"file,omitempty"`
+
+	ips  []net.IP
+	uris []*url.URL
+	rids []asn1.ObjectIdentifier
+}
+
+// batchResult is the outcome of a row of the manifest
+type batchResult struct {
+	Row          int        `json:"row"`
+	CommonName   string     `json:"cn"`
+	Zone         string     `json:"zone,omitempty"`
+	File         string     `json:"file,omitempty"`
+	PickupID     string     `json:"pickupId,omitempty"`
+	SerialNumber string     `json:"serialNumber,omitempty"`
+	NotAfter     *time.Time `json:"notAfter,omitempty"`
+	Error        string     `json:"error,omitempty"`
+}
+
+// batchSummary holds the results of enroll --batch, in the order of the rows of the manifest
+type batchSummary struct {
+	Total     int           `json:"total"`
+	Succeeded int           `json:"succeeded"`
+	Failed    int           `json:"failed"`
+	Duration  string        `json:"duration"`
+	Results   []batchResult `json:"results"`
+}
+
+var batchColumns = map[string]func(e *batchEntry, value string){
+	"cn":        func(e *batchEntry, value string) { e.CommonName = value },
+	"zone":      func(e *batchEntry, value string) { e.Zone = value },
+	"san-dns":   func(e *batchEntry, value string) { e.DNSSans = splitBatchValues(value) },
+	"san-ip":    func(e *batchEntry, value string) { e.IPSans = splitBatchValues(value) },
+	"san-email": func(e *batchEntry, value string) { e.EmailSans = splitBatchValues(value) },
+	"san-uri":   func(e *batchEntry, value string) { e.URISans = splitBatchValues(value) },
+	"san-upn":   func(e *batchEntry, value string) { e.UPNSans = splitBatchValues(value) },
+	"san-rid":   func(e *batchEntry, value string) { e.RIDSans = splitBatchValues(value) },
+	"file":      func(e *batchEntry, value string) { e.File = value },
+}
+
+var batchFileNameUnsafe = regexp.MustCompile(`[^A-Za-z0-9._-]`)
+
+// readBatchManifest reads the rows of the manifest in fileName, a JSON array of rows when its name ends with .json or
+// its content starts with [, a CSV file with a header otherwise
+func readBatchManifest(fileName string) ([]batchEntry, error) {
+	data, err := os.ReadFile(fileName)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read the batch manifest: %w", err)
+	}
+
+	var entries []batchEntry
+	if strings.EqualFold(filepath.Ext(fileName), ".json") || bytes.HasPrefix(bytes.TrimSpace(data), []byte("[")) {
+		decoder := json.NewDecoder(bytes.NewReader(data))
+		decoder.DisallowUnknownFields()
+		err = decoder.Decode(&entries)
+	} else {
+		entries, err = parseBatchCSV(data)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse the batch manifest %s: %w", fileName, err)
+	}
+	if len(entries) == 0 {
+		return nil, fmt.Errorf("the batch manifest %s has no rows", fileName)
+	}
+
+	for i := range entries {
+		err = entries[i].parse()
+		if err != nil {
+			return nil, fmt.Errorf("row %d of the batch manifest: %w", i+1, err)
+		}
+	}
+	return entries, nil
+}
+
+func parseBatchCSV(data []byte) ([]batchEntry, error) {
+	reader := csv.NewReader(bytes.NewReader(data))
+	reader.Comment = '#'
+	reader.TrimLeadingSpace = true
+
+	header, err := reader.Read()
+	if err == io.EOF {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	hasCN := false
+	for i, column := range header {
+		column = strings.ToLower(strings.TrimSpace(strings.TrimPrefix(column, "\ufeff")))
+		if _, ok := batchColumns[column]; !ok {
+			return nil, fmt.Errorf("unknown column %q, columns are cn, zone, san-dns, san-ip, san-email, san-uri, san-upn, san-rid and file", header[i])
+		}
+		hasCN = hasCN || column == "cn"
+		header[i] = column
+	}
+	if !hasCN {
+		return nil, fmt.Errorf("the header has no cn column")
+	}
+
+	var entries []batchEntry
+	for {
+		record, err := reader.Read()
+		if err == io.EOF {
+			return entries, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+		entry := batchEntry{}
+		for i, value := range record {
+			batchColumns[header[i]](&entry, strings.TrimSpace(value))
+		}
+		entries = append(entries, entry)
+	}
+}
+
+func splitBatchValues(value string) []string {
+	var values []string
+	for _, v := range strings.Split(value, ";") {
+		v = strings.TrimSpace(v)
+		if v != "" {
+			values = append(values, v)
+		}
+	}
+	return values
+}
+
+// parse checks the row, and parses its IP, URI and RID SANs
+func (e *batchEntry) parse() error {
+	if e.CommonName == "" {
+		return fmt.Errorf("a common name is required")
+	}
+	for _, v := range e.IPSans {
+		ip := net.ParseIP(v)
+		if ip == nil {
+			return fmt.Errorf("invalid IP address %q", v)
+		}
+		e.ips = append(e.ips, ip)
+	}
+	for _, v := range e.URISans {
+		uri, err := url.Parse(v)
+		if err != nil || uri.Scheme == "" {
+			return fmt.Errorf("invalid URI %q", v)
+		}
+		e.uris = append(e.uris, uri)
+	}
+	for _, v := range e.RIDSans {
+		rid, err := certificate.ParseOID(v)
+		if err != nil {
+			return err
+		}
+		e.rids = append(e.rids, rid)
+	}
+	return nil
+}
+
+// assignBatchFiles sets the file every row is written to: its file, relative to dir unless absolute, or a file of
+// dir named after its common name. Two rows cannot be written to the same file
+func assignBatchFiles(entries []batchEntry, dir string, format string) error {
+	ext := ".pem"
+	switch format {
+	case "json":
+		ext = ".json"
+	case Pkcs12:
+		ext = ".p12"
+	case JKSFormat:
+		ext = ".jks"
+	}
+
+	rows := map[string]int{}
+	for i := range entries {
+		file := entries[i].File
+		if file == "" {
+			file = batchFileNameUnsafe.ReplaceAllString(entries[i].CommonName, "_") + ext
+		}
+		if !filepath.IsAbs(file) {
+			file = filepath.Join(dir, file)
+		}
+		file = filepath.Clean(file)
+		if row, ok := rows[file]; ok {
+			return fmt.Errorf("rows %d and %d of the batch manifest are both written to %s", row, i+1, file)
+		}
+		rows[file] = i + 1
+		entries[i].File = file
+	}
+	return nil
+}
+
+// batchEnroller requests the certificates of a manifest. The rows of a zone share its connector, and all connectors
+// share their HTTP connections through the transport of util.NewTransport
+type batchEnroller struct {
+	cfg      vcert.Config
+	auditLog *audit.Log
+
+	mu         sync.Mutex
+	connectors map[string]*batchConnector
+}
+
+type batchConnector struct {
+	once       sync.Once
+	connector  endpoint.Connector
+	zoneConfig *endpoint.ZoneConfiguration
+	err        error
+}
+
+func newBatchEnroller(cfg vcert.Config, auditLog *audit.Log) *batchEnroller {
+	return &batchEnroller{cfg: cfg, auditLog: auditLog, connectors: map[string]*batchConnector{}}
+}
+
+// connector returns the connector of zone, connecting and reading the zone configuration on first use
+func (b *batchEnroller) connector(ctx context.Context, zone string) (endpoint.Connector, *endpoint.ZoneConfiguration, error) {
+	b.mu.Lock()
+	c, ok := b.connectors[zone]
+	if !ok {
+		c = &batchConnector{}
+		b.connectors[zone] = c
+	}
+	b.mu.Unlock()
+
+	c.once.Do(func() {
+		cfg := b.cfg
+		cfg.Zone = zone
+		c.connector, c.err = vcert.NewClient(&cfg)
+		if c.err != nil {
+			c.err = fmt.Errorf("unable to connect to %s: %w", cfg.ConnectorType, c.err)
+			return
+		}
+		c.zoneConfig, c.err = c.connector.ReadZoneConfiguration(ctx)
+		if c.err != nil {
+			c.err = fmt.Errorf("failed to read zone configuration for %s: %w", zone, c.err)
+		}
+	})
+	return c.connector, c.zoneConfig, c.err
+}
+
+// run requests the certificates of entries, concurrency at a time
+func (b *batchEnroller) run(ctx context.Context, entries []batchEntry, concurrency int, progress *batchProgress) *batchSummary {
+	start := time.Now()
+	summary := &batchSummary{Total: len(entries), Results: make([]batchResult, len(entries))}
+	if concurrency > len(entries) {
+		concurrency = len(entries)
+	}
+
+	rows := make(chan int)
+	wg := sync.WaitGroup{}
+	for w := 0; w < concurrency; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range rows {
+				summary.Results[i] = b.enroll(ctx, i+1, &entries[i])
+				progress.update(summary.Results[i].Error != "")
+			}
+		}()
+	}
+	for i := range entries {
+		rows <- i
+	}
+	close(rows)
+	wg.Wait()
+	progress.finish()
+
+	for _, result := range summary.Results {
+		if result.Error != "" {
+			summary.Failed++
+		} else {
+			summary.Succeeded++
+		}
+	}
+	summary.Duration = time.Since(start).Round(time.Millisecond).String()
+	return summary
+}
+
+func (b *batchEnroller) enroll(ctx context.Context, row int, entry *batchEntry) batchResult {
+	zone := entry.Zone
+	if zone == "" {
+		zone = b.cfg.Zone
+	}
+	result := batchResult{Row: row, CommonName: entry.CommonName, Zone: zone}
+
+	pcc, err := b.request(ctx, zone, entry, &result)
+	if err == nil {
+		err = writeBatchCertificate(entry.File, pcc)
+	}
+	if err != nil {
+		result.Error = err.Error()
+		return result
+	}
+	result.File = entry.File
+
+	block, _ := pem.Decode([]byte(pcc.Certificate))
+	if block != nil {
+		cert, err := x509.ParseCertificate(block.Bytes)
+		if err == nil {
+			result.SerialNumber = fmt.Sprintf("%x", cert.SerialNumber)
+			result.NotAfter = &cert.NotAfter
+		}
+	}
+	return result
+}
+
+func (b *batchEnroller) request(ctx context.Context, zone string, entry *batchEntry, result *batchResult) (*certificate.PEMCollection, error) {
+	if zone == "" && (b.cfg.ConnectorType == endpoint.ConnectorTypeTPP || b.cfg.ConnectorType == endpoint.ConnectorTypeCloud ||
+		b.cfg.ConnectorType == endpoint.ConnectorTypeFirefly) {
+		return nil, fmt.Errorf("a zone is required, set it in the zone column of the row or with --zone")
+	}
+	connector, zoneConfig, err := b.connector(ctx, zone)
+	if err != nil {
+		return nil, err
+	}
+
+	req := fillCertificateRequest(&certificate.Request{}, &flags)
+	req.Subject.CommonName = entry.CommonName
+	req.DNSNames = entry.DNSSans
+	req.IPAddresses = entry.ips
+	req.EmailAddresses = entry.EmailSans
+	req.URIs = entry.uris
+	req.UPNs = entry.UPNSans
+	req.RegisteredIDs = entry.rids
+	err = connector.GenerateRequest(ctx, zoneConfig, req)
+	if err != nil {
+		return nil, err
+	}
+
+	var pcc *certificate.PEMCollection
+	if connector.SupportSynchronousRequestCertificate() {
+		if flags.timeout > 0 {
+			req.Timeout = time.Duration(flags.timeout) * time.Second
+		}
+		pcc, err = connector.SynchronousRequestCertificate(ctx, req)
+		b.record(zone, req, "", err)
+		if err != nil {
+			return nil, err
+		}
+	} else {
+		result.PickupID, err = connector.RequestCertificate(ctx, req)
+		b.record(zone, req, result.PickupID, err)
+		if err != nil {
+			return nil, err
+		}
+		req.ChainOption = certificate.ChainOptionFromString(flags.chainOption)
+		req.KeyPassword = flags.keyPassword
+		// Unlike retrieveCertificate, the connector waits for the issuance without logging, which would garble the progress bar
+		pcc, err = retrieveCertificateNew(ctx, connector, req, time.Duration(flags.timeout)*time.Second)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	err = pcc.AddPrivateKey(req.PrivateKey, []byte(flags.keyPassword), flags.format)
+	if err != nil {
+		return nil, err
+	}
+	if flags.format == Pkcs12 || flags.format == JKSFormat {
+		pcc.PrivateKey, err = util.DecryptPkcs8PrivateKey(pcc.PrivateKey, flags.keyPassword)
+		if err != nil {
+			return nil, err
+		}
+	}
+	return pcc, nil
+}
+
+func (b *batchEnroller) record(zone string, req *certificate.Request, pickupID string, err error) {
+	event := enrollAuditEvent(b.cfg.ConnectorType.String(), req, pickupID)
+	event.Zone = zone
+	recordAudit(b.auditLog, event, err)
+}
+
+func writeBatchCertificate(file string, pcc *certificate.PEMCollection) error {
+	err := os.MkdirAll(filepath.Dir(file), 0700)
+	if err != nil {
+		return err
+	}
+	result := &Result{
+		Pcc: pcc,
+		Config: &Config{
+			Command:     commandEnrollName,
+			Format:      flags.format,
+			JKSAlias:    flags.jksAlias,
+			JKSPassword: flags.jksPassword,
+			ChainOption: certificate.ChainOptionFromString(flags.chainOption),
+			AllFile:     file,
+			KeyPassword: flags.keyPassword,
+		},
+	}
+	err = result.Flush()
+	if err != nil {
+		return fmt.Errorf("failed to write %s: %w", file, err)
+	}
+	return nil
+}
+
+// batchProgress draws a progress bar of the enrollments on a terminal, and nothing otherwise
+type batchProgress struct {
+	mu     sync.Mutex
+	w      io.Writer
+	total  int
+	done   int
+	failed int
+}
+
+func newBatchProgress(total int, f *os.File) *batchProgress {
+	p := &batchProgress{total: total}
+	if isTerminal(f) {
+		p.w = f
+		p.draw()
+	}
+	return p
+}
+
+func (p *batchProgress) update(failed bool) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.done++
+	if failed {
+		p.failed++
+	}
+	p.draw()
+}
+
+func (p *batchProgress) finish() {
+	if p.w != nil {
+		fmt.Fprintln(p.w)
+	}
+}
+
+func (p *batchProgress) draw() {
+	if p.w == nil {
+		return
+	}
+	filled := batchProgressWidth
+	if p.total > 0 {
+		filled = p.done * batchProgressWidth / p.total
+	}
+	line := fmt.Sprintf("\r[%s%s] %d/%d", strings.Repeat("=", filled), strings.Repeat(" ", batchProgressWidth-filled), p.done, p.total)
+	if p.failed > 0 {
+		line += fmt.Sprintf(", %d failed", p.failed)
+	}
+	fmt.Fprint(p.w, line)
+}
+
+// doCommandEnrollBatch requests the certificates of the --batch manifest, and writes the results to --batch-result
+// or the standard output. It fails when any row failed, after the other rows are done
+func doCommandEnrollBatch(ctx context.Context, cfg vcert.Config, auditLog *audit.Log) error {
+	entries, err := readBatchManifest(flags.batch)
+	if err != nil {
+		return err
+	}
+	dir := flags.batchDir
+	if dir == "" {
+		dir = "."
+	}
+	err = assignBatchFiles(entries, dir, flags.format)
+	if err != nil {
+		return err
+	}
+
+	logf("Requesting %d certificates from %s, %d at a time", len(entries), cfg.ConnectorType, flags.batchConcurrency)
+	summary := newBatchEnroller(cfg, auditLog).run(ctx, entries, flags.batchConcurrency, newBatchProgress(len(entries), os.Stderr))
+	logf("Enrolled %d of %d certificates in %s, %d failed", summary.Succeeded, summary.Total, summary.Duration, summary.Failed)
+
+	var failure error
+	if summary.Failed > 0 {
+		failure = fmt.Errorf("%d of %d certificates failed to enroll, see the batch results for the errors", summary.Failed, summary.Total)
+	}
+	if isStructuredOutput() && flags.batchResult == "" {
+		return writeSummaryResult(commandEnrollName, summary, failure)
+	}
+	err = writeBatchSummary(summary, flags.batchResult)
+	if err != nil {
+		return err
+	}
+	return failure
+}
+
+// writeBatchSummary writes the JSON summary of --batch or --all-expiring to fileName, or the standard output when it is empty
+func writeBatchSummary(summary interface{}, fileName string) error {
+	data, err := json.MarshalIndent(summary, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to construct the batch results: %w", err)
+	}
+	data = append(data, '\n')
+	if fileName == "" {
+		_, err = os.Stdout.Write(data)
+		return err
+	}
+	err = os.WriteFile(fileName, data, 0600)
+	if err != nil {
+		return fmt.Errorf("failed to write the batch results: %w", err)
+	}
+	return nil
+}
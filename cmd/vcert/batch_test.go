@@ -0,0 +1,222 @@
+/*
+ * Copyright 2023 Venafi, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *  http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package main
+
+import (
+	"context"
+	"crypto/x509"
+	"encoding/pem"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/Venafi/vcert/v5"
+	"github.com/Venafi/vcert/v5/pkg/endpoint"
+)
+
+func writeManifest(t *testing.T, name string, content string) string {
+	fileName := filepath.Join(t.TempDir(), name)
+	err := os.WriteFile(fileName, []byte(content), 0600)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return fileName
+}
+
+func TestReadBatchManifest(t *testing.T) {
+	csvManifest := writeManifest(t, "manifest.csv", "\ufeffCN,Zone,san-dns,san-ip,file\n"+
+		"# migrated from the load balancers\n"+
+		"www.example.com,Certificates\\Web,www.example.com; example.com,10.0.0.1,\n"+
+		"\"api.example.com\",,,,api/cert.pem\n")
+	jsonManifest := writeManifest(t, "manifest", `[
+		{"cn": "www.example.com", "zone": "Certificates\\Web", "san-dns": ["www.example.com", "example.com"], "san-ip": ["10.0.0.1"]},
+		{"cn": "api.example.com", "file": "api/cert.pem"}
+	]`)
+
+	for _, fileName := range []string{csvManifest, jsonManifest} {
+		entries, err := readBatchManifest(fileName)
+		if err != nil {
+			t.Fatalf("%s: %s", fileName, err)
+		}
+		if len(entries) != 2 {
+			t.Fatalf("%s: expected 2 rows, got %d", fileName, len(entries))
+		}
+		www, api := entries[0], entries[1]
+		if www.CommonName != "www.example.com" || www.Zone != "Certificates\\Web" || strings.Join(www.DNSSans, ",") != "www.example.com,example.com" {
+			t.Fatalf("%s: unexpected first row %+v", fileName, www)
+		}
+		if len(www.ips) != 1 || www.ips[0].String() != "10.0.0.1" {
+			t.Fatalf("%s: expected IP SAN 10.0.0.1, got %v", fileName, www.ips)
+		}
+		if api.CommonName != "api.example.com" || api.Zone != "" || len(api.DNSSans) != 0 || api.File != "api/cert.pem" {
+			t.Fatalf("%s: unexpected second row %+v", fileName, api)
+		}
+	}
+}
+
+func TestReadBatchManifestErrors(t *testing.T) {
+	cases := map[string]struct {
+		name     string
+		manifest string
+		err      string
+	}{
+		"unknown column":   {"m.csv", "cn,owner\nwww.example.com,ops\n", `unknown column "owner"`},
+		"no cn column":     {"m.csv", "zone,san-dns\nWeb,www.example.com\n", "no cn column"},
+		"empty":            {"m.csv", "cn\n", "has no rows"},
+		"missing cn":       {"m.csv", "cn,zone\nwww.example.com,Web\n,Web\n", "row 2 of the batch manifest: a common name is required"},
+		"wrong field":      {"m.csv", "cn,zone\nwww.example.com\n", "wrong number of fields"},
+		"invalid IP":       {"m.json", `[{"cn": "www.example.com", "san-ip": ["10.0.0.300"]}]`, `invalid IP address "10.0.0.300"`},
+		"invalid URI":      {"m.json", `[{"cn": "www.example.com", "san-uri": ["www.example.com"]}]`, `invalid URI "www.example.com"`},
+		"invalid RID":      {"m.csv", "cn,san-rid\nwww.example.com,1.2.3;example\n", `invalid OID "example"`},
+		"unknown JSON key": {"m.json", `[{"cn": "www.example.com", "owner": "ops"}]`, `unknown field "owner"`},
+	}
+	for name, c := range cases {
+		t.Run(name, func(t *testing.T) {
+			_, err := readBatchManifest(writeManifest(t, c.name, c.manifest))
+			if err == nil || !strings.Contains(err.Error(), c.err) {
+				t.Fatalf("expected error containing %q, got %v", c.err, err)
+			}
+		})
+	}
+}
+
+func TestAssignBatchFiles(t *testing.T) {
+	entries := []batchEntry{{CommonName: "*.example.com"}, {CommonName: "api.example.com", File: "/etc/ssl/api.p12"}}
+	err := assignBatchFiles(entries, "certs", Pkcs12)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if entries[0].File != filepath.Join("certs", "_.example.com.p12") || entries[1].File != "/etc/ssl/api.p12" {
+		t.Fatalf("unexpected files %s and %s", entries[0].File, entries[1].File)
+	}
+
+	entries = []batchEntry{{CommonName: "www.example.com"}, {CommonName: "api.example.com", File: "./www.example.com.pem"}}
+	err = assignBatchFiles(entries, ".", "pem")
+	if err == nil || !strings.Contains(err.Error(), "rows 1 and 2") {
+		t.Fatalf("expected an error for rows written to the same file, got %v", err)
+	}
+}
+
+func TestValidateBatchFlags(t *testing.T) {
+	cases := map[string]struct {
+		flags commandFlags
+		err   string
+	}{
+		"valid":          {commandFlags{batchConcurrency: 8, format: "pem"}, ""},
+		"common name":    {commandFlags{batchConcurrency: 8, commonName: "www.example.com"}, "--cn cannot be used with --batch"},
+		"SANs":           {commandFlags{batchConcurrency: 8, dnsSans: stringSlice{"www.example.com"}}, "--san-* options"},
+		"service CSR":    {commandFlags{batchConcurrency: 8, csrOption: "service"}, "only supports --csr local"},
+		"file":           {commandFlags{batchConcurrency: 8, file: "all.pem"}, "--batch-dir"},
+		"no pickup":      {commandFlags{batchConcurrency: 8, noPickup: true}, "--no-pickup"},
+		"DER":            {commandFlags{batchConcurrency: 8, format: DERFormat}, "--format der"},
+		"SCT":            {commandFlags{batchConcurrency: 8, requireSCT: true}, "--require-sct"},
+		"no concurrency": {commandFlags{}, "--batch-concurrency must be at least 1"},
+	}
+	for name, c := range cases {
+		t.Run(name, func(t *testing.T) {
+			flags = c.flags
+			err := validateBatchFlags()
+			if c.err == "" {
+				if err != nil {
+					t.Fatal(err)
+				}
+			} else if err == nil || !strings.Contains(err.Error(), c.err) {
+				t.Fatalf("expected error containing %q, got %v", c.err, err)
+			}
+		})
+	}
+}
+
+func TestEnrollBatch(t *testing.T) {
+	flags = commandFlags{format: "pem", chainOption: "root-last", batchConcurrency: 2}
+	dir := t.TempDir()
+	entries := []batchEntry{
+		{CommonName: "www.example.com", DNSSans: []string{"www.example.com", "example.com"}},
+		{CommonName: "api.example.com", Zone: "APIs"},
+		// the fake connector refuses venafi.com certificates
+		{CommonName: "www.venafi.com"},
+	}
+	err := assignBatchFiles(entries, dir, flags.format)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	progressFile, err := os.Create(filepath.Join(dir, "progress"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer progressFile.Close()
+
+	cfg := vcert.Config{ConnectorType: endpoint.ConnectorTypeFake, Zone: "Default"}
+	summary := newBatchEnroller(cfg, nil).run(context.Background(), entries, flags.batchConcurrency, newBatchProgress(len(entries), progressFile))
+	if summary.Total != 3 || summary.Succeeded != 2 || summary.Failed != 1 {
+		t.Fatalf("expected 2 of 3 certificates enrolled, got %+v", summary)
+	}
+
+	for i, result := range summary.Results {
+		if result.Row != i+1 || result.CommonName != entries[i].CommonName {
+			t.Fatalf("expected the results in the order of the rows, got %+v at %d", result, i)
+		}
+	}
+	if summary.Results[0].Zone != "Default" || summary.Results[1].Zone != "APIs" {
+		t.Fatalf("expected the zone of the row or the default zone, got %s and %s", summary.Results[0].Zone, summary.Results[1].Zone)
+	}
+	failed := summary.Results[2]
+	if failed.Error == "" || failed.File != "" {
+		t.Fatalf("expected the venafi.com certificate to fail, got %+v", failed)
+	}
+	if _, err = os.Stat(entries[2].File); !os.IsNotExist(err) {
+		t.Fatalf("expected no file for the failed row, got %v", err)
+	}
+
+	www := summary.Results[0]
+	if www.SerialNumber == "" || www.NotAfter == nil {
+		t.Fatalf("expected the serial number and expiry of the certificate, got %+v", www)
+	}
+	content, err := os.ReadFile(www.File)
+	if err != nil {
+		t.Fatal(err)
+	}
+	block, rest := pem.Decode(content)
+	if block == nil || block.Type != "CERTIFICATE" {
+		t.Fatalf("expected %s to start with the certificate", www.File)
+	}
+	cert, err := x509.ParseCertificate(block.Bytes)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if cert.Subject.CommonName != "www.example.com" || strings.Join(cert.DNSNames, ",") != "www.example.com,example.com" {
+		t.Fatalf("unexpected certificate for %s with SANs %v", cert.Subject.CommonName, cert.DNSNames)
+	}
+	if !strings.Contains(string(rest), "PRIVATE KEY") {
+		t.Fatalf("expected %s to hold the private key", www.File)
+	}
+
+	resultFile := filepath.Join(dir, "results.json")
+	err = writeBatchSummary(summary, resultFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	content, err = os.ReadFile(resultFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(string(content), `"failed": 1`) || !strings.Contains(string(content), `"cn": "www.venafi.com"`) {
+		t.Fatalf("unexpected batch results %s", content)
+	}
+}
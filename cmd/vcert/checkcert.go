@@ -0,0 +1,454 @@
+/*
+ * Copyright 2023 Venafi, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *  http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package main
+
+import (
+	"bytes"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/rsa"
+	"crypto/sha1"
+	"crypto/x509"
+	"encoding/hex"
+	"encoding/json"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/urfave/cli/v2"
+
+	"github.com/Venafi/vcert/v5"
+	"github.com/Venafi/vcert/v5/pkg/certificate"
+	"github.com/Venafi/vcert/v5/pkg/endpoint"
+	"github.com/Venafi/vcert/v5/pkg/playbook/app/domain"
+	"github.com/Venafi/vcert/v5/pkg/playbook/app/installer"
+	"github.com/Venafi/vcert/v5/pkg/util"
+)
+
+const (
+	commandCheckCertName = "checkcert"
+
+	checkCertFormatText = "text"
+	checkCertFormatJSON = "json"
+)
+
+var commandCheckCert = &cli.Command{
+	Before: runBeforeCommand,
+	Name:   commandCheckCertName,
+	Flags:  checkCertFlags,
+	Action: doCommandCheckCert,
+	Usage: "To inspect a local certificate file (PEM, DER, PKCS#12 or JKS) and report its subject, SANs, " +
+		"expiration, key, chain validity and, when a zone is specified, its compliance with the zone policy",
+	UsageText: ` vcert checkcert --file /path-to/cert.pem
+		 vcert checkcert --file /path-to/cert.p12 --key-password <PKCS#12 password> --format json
+		 vcert checkcert --file /path-to/keystore.jks --jks-alias <alias> --jks-password <store password> --key-password <key password>
+		 vcert checkcert --file /path-to/cert.pem --renew-before 30d
+		 vcert checkcert --file /path-to/cert.pem -k <VaaS API key> -z "<app name>\<CIT alias>"
+		 vcert checkcert --file /path-to/cert.pem -u https://tpp.example.com -t <TPP access token> -z "<policy folder DN>"`,
+}
+
+type checkCertOptions struct {
+	file        string
+	format      string
+	renewBefore string
+}
+
+var (
+	checkCertOpts = checkCertOptions{}
+
+	flagCheckCertFile = &cli.StringFlag{
+		Name:        "file",
+		Usage:       "REQUIRED. The certificate file to inspect, in PEM, DER, PKCS#12 or JKS format. Example: --file /path-to/cert.pem",
+		Destination: &checkCertOpts.file,
+		TakesFile:   true,
+	}
+
+	flagCheckCertFormat = &cli.StringFlag{
+		Name:        "format",
+		Usage:       "Use to specify the output format of the report. Options include: text | json",
+		Destination: &checkCertOpts.format,
+		Value:       checkCertFormatText,
+	}
+
+	flagCheckCertRenewBefore = &cli.StringFlag{
+		Name: "renew-before",
+		Usage: "Use to specify the renewal window used to report the renewal date, as a number of days, a percentage " +
+			"of the certificate lifetime or a duration. Example: --renew-before 30d",
+		Destination: &checkCertOpts.renewBefore,
+		Value:       domain.DefaultRenewBefore,
+	}
+
+	flagCheckCertZone = &cli.StringFlag{
+		Name:        "zone",
+		Destination: &flags.zone,
+		Usage:       "Use to check the certificate against the policy of the zone. Requires the credentials to connect to the Venafi platform.",
+		Aliases:     []string{"z"},
+	}
+
+	checkCertFlags = sortedFlags(flagsApppend(
+		flagCheckCertFile,
+		flagCheckCertFormat,
+		flagCheckCertRenewBefore,
+		flagCheckCertZone,
+		flagKey,
+		flagToken,
+		flagUrl,
+		flagConfig,
+		flagProfile,
+		flagTestMode,
+		flagTrustBundle,
+		flagKeyPassword,
+		flagJKSAlias,
+		flagJKSPassword,
+		commonFlags,
+	))
+)
+
+// checkCertReport is what checkcert finds out about a certificate file
+type checkCertReport struct {
+	File           string     `json:"file,omitempty"`
+	Format         string     `json:"format"`
+	Subject        string     `json:"subject"`
+	Issuer         string     `json:"issuer"`
+	Serial         string     `json:"serial"`
+	Thumbprint     string     `json:"thumbprint"`
+	DNSNames       []string   `json:"dnsNames,omitempty"`
+	IPAddresses    []string   `json:"ipAddresses,omitempty"`
+	EmailAddresses []string   `json:"emailAddresses,omitempty"`
+	URIs           []string   `json:"uris,omitempty"`
+	NotBefore      time.Time  `json:"notBefore"`
+	NotAfter       time.Time  `json:"notAfter"`
+	DaysRemaining  int        `json:"daysRemaining"`
+	Expired        bool       `json:"expired"`
+	RenewAt        *time.Time `json:"renewAt,omitempty"`
+	NeedsRenewal   bool       `json:"needsRenewal"`
+	KeyType        string     `json:"keyType"`
+	HasPrivateKey  bool       `json:"hasPrivateKey"`
+	KeyMatches     bool       `json:"keyMatches"`
+	ChainLength    int        `json:"chainLength"`
+	ChainValid     bool       `json:"chainValid"`
+	ChainError     string     `json:"chainError,omitempty"`
+	Zone           string     `json:"zone,omitempty"`
+	PolicyValid    *bool      `json:"policyValid,omitempty"`
+	PolicyError    string     `json:"policyError,omitempty"`
+}
+
+func doCommandCheckCert(c *cli.Context) error {
+	if checkCertOpts.file == "" {
+		return fmt.Errorf("missing required flag --file")
+	}
+	format := strings.ToLower(checkCertOpts.format)
+	if format != checkCertFormatText && format != checkCertFormatJSON {
+		return fmt.Errorf("unsupported format %q. Should be %s or %s", checkCertOpts.format, checkCertFormatText, checkCertFormatJSON)
+	}
+	window, err := domain.ParseRenewBefore(checkCertOpts.renewBefore)
+	if err != nil {
+		return fmt.Errorf("invalid --renew-before %q: %w", checkCertOpts.renewBefore, err)
+	}
+
+	keyPassword, err := readPasswordsFromInputFlag(flags.keyPassword, 0)
+	if err != nil {
+		return err
+	}
+	storePassword, err := readPasswordsFromInputFlag(flags.jksPassword, 0)
+	if err != nil {
+		return err
+	}
+	if storePassword == "" {
+		storePassword = keyPassword
+	}
+
+	data, err := os.ReadFile(checkCertOpts.file)
+	if err != nil {
+		return fmt.Errorf("failed to read certificate file: %w", err)
+	}
+	lc, err := installer.LoadCertificate(data, flags.jksAlias, storePassword, keyPassword)
+	if err != nil {
+		return fmt.Errorf("failed to load certificate from %s: %w", checkCertOpts.file, err)
+	}
+
+	var roots *x509.CertPool
+	if flags.trustBundle != "" {
+		bundle, err := os.ReadFile(flags.trustBundle)
+		if err != nil {
+			return fmt.Errorf("failed to read trust bundle: %w", err)
+		}
+		roots = x509.NewCertPool()
+		roots.AppendCertsFromPEM(bundle)
+	}
+
+	report := buildCheckCertReport(lc, roots, window, time.Now())
+	report.File = checkCertOpts.file
+
+	if flags.zone != "" {
+		zoneConfig, err := readZoneConfiguration(c)
+		if err != nil {
+			return err
+		}
+		checkCertificatePolicy(zoneConfig, flags.zone, lc.Certificate, &report)
+	}
+
+	if format == checkCertFormatJSON {
+		err = writeCheckCertJSON(os.Stdout, report)
+	} else {
+		err = writeCheckCertText(os.Stdout, report)
+	}
+	if err != nil {
+		return err
+	}
+	return report.problems()
+}
+
+// readZoneConfiguration reads the configuration of the zone, including its policy, from the Venafi platform
+func readZoneConfiguration(c *cli.Context) (*endpoint.ZoneConfiguration, error) {
+	err := setTLSConfig()
+	if err != nil {
+		return nil, err
+	}
+	cfg, err := buildConfig(c, &flags)
+	if err != nil {
+		return nil, fmt.Errorf("failed to build vcert config: %s", err)
+	}
+	connector, err := vcert.NewClient(&cfg)
+	if err != nil {
+		return nil, err
+	}
+	zoneConfig, err := connector.ReadZoneConfiguration(c.Context)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read the configuration of zone %s: %w", flags.zone, err)
+	}
+	return zoneConfig, nil
+}
+
+// checkCertificatePolicy validates cert against the policy of zone, and records the outcome in report
+func checkCertificatePolicy(zoneConfig *endpoint.ZoneConfiguration, zone string, cert *x509.Certificate, report *checkCertReport) {
+	report.Zone = zone
+	valid := true
+	err := zoneConfig.ValidateCertificateRequest(requestFromCertificate(cert))
+	if err != nil {
+		valid = false
+		report.PolicyError = err.Error()
+	}
+	report.PolicyValid = &valid
+}
+
+// requestFromCertificate returns the request that would have produced cert, so it can be validated against a policy
+func requestFromCertificate(cert *x509.Certificate) *certificate.Request {
+	req := &certificate.Request{
+		Subject:        cert.Subject,
+		DNSNames:       cert.DNSNames,
+		EmailAddresses: cert.EmailAddresses,
+		IPAddresses:    cert.IPAddresses,
+		URIs:           cert.URIs,
+	}
+	switch pub := cert.PublicKey.(type) {
+	case *rsa.PublicKey:
+		req.KeyType = certificate.KeyTypeRSA
+		req.KeyLength = pub.N.BitLen()
+	case *ecdsa.PublicKey:
+		req.KeyType = certificate.KeyTypeECDSA
+		_ = req.KeyCurve.Set(pub.Curve.Params().Name)
+	case ed25519.PublicKey:
+		req.KeyType = certificate.KeyTypeED25519
+		req.KeyCurve = certificate.EllipticCurveED25519
+	}
+	return req
+}
+
+// parsePEMPrivateKey parses the private key of block, and asks for --key-password when it is encrypted
+func parsePEMPrivateKey(block *pem.Block, keyPassword string) (crypto.PrivateKey, error) {
+	// nolint:staticcheck
+	if keyPassword == "" && (x509.IsEncryptedPEMBlock(block) || block.Type == "ENCRYPTED PRIVATE KEY") {
+		return nil, fmt.Errorf("the private key is encrypted. Use --key-password to specify its password")
+	}
+	return util.ParsePrivateKeyBlock(block, keyPassword)
+}
+
+// buildCheckCertReport inspects the certificate at the time now. The chain is verified against roots, or the
+// system roots when nil, in addition to the self-signed certificates found in the chain itself
+func buildCheckCertReport(lc *installer.LoadedCertificate, roots *x509.CertPool, window domain.RenewWindow, now time.Time) checkCertReport {
+	cert := lc.Certificate
+	thumbprint := sha1.Sum(cert.Raw) // #nosec G401 -- the SHA-1 thumbprint identifies certificates in the Venafi platform
+	report := checkCertReport{
+		Format:         lc.Format,
+		Subject:        cert.Subject.String(),
+		Issuer:         cert.Issuer.String(),
+		Serial:         cert.SerialNumber.String(),
+		Thumbprint:     strings.ToUpper(hex.EncodeToString(thumbprint[:])),
+		DNSNames:       cert.DNSNames,
+		EmailAddresses: cert.EmailAddresses,
+		NotBefore:      cert.NotBefore,
+		NotAfter:       cert.NotAfter,
+		DaysRemaining:  int(cert.NotAfter.Sub(now).Hours() / 24),
+		Expired:        now.After(cert.NotAfter),
+		KeyType:        describePublicKey(cert.PublicKey),
+		HasPrivateKey:  lc.PrivateKey != nil,
+		ChainLength:    len(lc.Chain),
+	}
+	for _, ip := range cert.IPAddresses {
+		report.IPAddresses = append(report.IPAddresses, ip.String())
+	}
+	for _, uri := range cert.URIs {
+		report.URIs = append(report.URIs, uri.String())
+	}
+
+	if renewAt := window.RenewalDate(cert.NotBefore, cert.NotAfter); !renewAt.IsZero() {
+		report.RenewAt = &renewAt
+		report.NeedsRenewal = !now.Before(renewAt)
+	}
+
+	report.KeyMatches = installer.PrivateKeyMatches(cert, lc.PrivateKey)
+
+	err := verifyChain(lc, roots, now)
+	report.ChainValid = err == nil
+	if err != nil {
+		report.ChainError = err.Error()
+	}
+	return report
+}
+
+func verifyChain(lc *installer.LoadedCertificate, roots *x509.CertPool, now time.Time) error {
+	if roots == nil {
+		var err error
+		roots, err = x509.SystemCertPool()
+		if err != nil {
+			roots = x509.NewCertPool()
+		}
+	} else {
+		roots = roots.Clone()
+	}
+
+	intermediates := x509.NewCertPool()
+	for _, c := range lc.Chain {
+		if bytes.Equal(c.RawIssuer, c.RawSubject) && c.CheckSignatureFrom(c) == nil {
+			roots.AddCert(c)
+		} else {
+			intermediates.AddCert(c)
+		}
+	}
+
+	_, err := lc.Certificate.Verify(x509.VerifyOptions{
+		Roots:         roots,
+		Intermediates: intermediates,
+		CurrentTime:   now,
+		KeyUsages:     []x509.ExtKeyUsage{x509.ExtKeyUsageAny},
+	})
+	return err
+}
+
+func describePublicKey(pub crypto.PublicKey) string {
+	switch k := pub.(type) {
+	case *rsa.PublicKey:
+		return fmt.Sprintf("RSA %d", k.N.BitLen())
+	case *ecdsa.PublicKey:
+		return fmt.Sprintf("ECDSA %s", k.Curve.Params().Name)
+	case ed25519.PublicKey:
+		return "ED25519"
+	default:
+		return "unknown"
+	}
+}
+
+// problems returns an error describing why the certificate is not fit for use, if it is not
+func (r checkCertReport) problems() error {
+	var errs []error
+	if r.Expired {
+		errs = append(errs, fmt.Errorf("certificate expired on %s", r.NotAfter.Format(time.RFC3339)))
+	}
+	if r.HasPrivateKey && !r.KeyMatches {
+		errs = append(errs, fmt.Errorf("private key does not match the certificate"))
+	}
+	if !r.ChainValid {
+		errs = append(errs, fmt.Errorf("certificate chain is not valid: %s", r.ChainError))
+	}
+	if r.PolicyValid != nil && !*r.PolicyValid {
+		errs = append(errs, fmt.Errorf("certificate does not comply with the policy of zone %s: %s", r.Zone, r.PolicyError))
+	}
+	return errors.Join(errs...)
+}
+
+func writeCheckCertJSON(out io.Writer, report checkCertReport) error {
+	enc := json.NewEncoder(out)
+	enc.SetIndent("", "  ")
+	return enc.Encode(report)
+}
+
+func writeCheckCertText(out io.Writer, r checkCertReport) error {
+	w := tabwriter.NewWriter(out, 0, 0, 2, ' ', 0)
+	writeCheckCertFields(w, r)
+	return w.Flush()
+}
+
+// writeCheckCertFields writes the report as tab separated lines, to be aligned by a tabwriter
+func writeCheckCertFields(w io.Writer, r checkCertReport) {
+	orNone := func(values []string) string {
+		if len(values) == 0 {
+			return "-"
+		}
+		return strings.Join(values, ", ")
+	}
+
+	if r.File != "" {
+		fmt.Fprintf(w, "File:\t%s (%s)\n", r.File, r.Format)
+	}
+	fmt.Fprintf(w, "Subject:\t%s\n", r.Subject)
+	fmt.Fprintf(w, "Issuer:\t%s\n", r.Issuer)
+	fmt.Fprintf(w, "Serial:\t%s\n", r.Serial)
+	fmt.Fprintf(w, "Thumbprint:\t%s\n", r.Thumbprint)
+	fmt.Fprintf(w, "DNS SANs:\t%s\n", orNone(r.DNSNames))
+	fmt.Fprintf(w, "IP SANs:\t%s\n", orNone(r.IPAddresses))
+	fmt.Fprintf(w, "Email SANs:\t%s\n", orNone(r.EmailAddresses))
+	fmt.Fprintf(w, "URI SANs:\t%s\n", orNone(r.URIs))
+	fmt.Fprintf(w, "Not before:\t%s\n", r.NotBefore.Format(time.RFC3339))
+	fmt.Fprintf(w, "Not after:\t%s (%d days remaining)\n", r.NotAfter.Format(time.RFC3339), r.DaysRemaining)
+	if r.RenewAt != nil {
+		fmt.Fprintf(w, "Renew at:\t%s (renewal needed: %s)\n", r.RenewAt.Format(time.RFC3339), yesNo(r.NeedsRenewal))
+	} else {
+		fmt.Fprintf(w, "Renew at:\tdisabled\n")
+	}
+	fmt.Fprintf(w, "Key type:\t%s\n", r.KeyType)
+	if r.HasPrivateKey {
+		fmt.Fprintf(w, "Private key:\tfound (matches certificate: %s)\n", yesNo(r.KeyMatches))
+	} else {
+		fmt.Fprintf(w, "Private key:\tnot found\n")
+	}
+	if r.ChainValid {
+		fmt.Fprintf(w, "Chain:\tvalid (%d certificates)\n", r.ChainLength)
+	} else {
+		fmt.Fprintf(w, "Chain:\tnot valid (%d certificates): %s\n", r.ChainLength, r.ChainError)
+	}
+	if r.PolicyValid != nil {
+		if *r.PolicyValid {
+			fmt.Fprintf(w, "Policy:\tcompliant with zone %s\n", r.Zone)
+		} else {
+			fmt.Fprintf(w, "Policy:\tnot compliant with zone %s: %s\n", r.Zone, r.PolicyError)
+		}
+	}
+}
+
+func yesNo(b bool) string {
+	if b {
+		return "yes"
+	}
+	return "no"
+}
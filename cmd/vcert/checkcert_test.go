@@ -0,0 +1,195 @@
+/*
+ * Copyright 2023 Venafi, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *  http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package main
+
+import (
+	"bytes"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"math/big"
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/Venafi/vcert/v5/pkg/certificate"
+	"github.com/Venafi/vcert/v5/pkg/playbook/app/domain"
+	"github.com/Venafi/vcert/v5/pkg/playbook/app/installer"
+	"github.com/Venafi/vcert/v5/test/testcert"
+)
+
+func newTestChain(t *testing.T, notBefore time.Time, notAfter time.Time) (root testcert.Certificate, leaf testcert.Certificate) {
+	root = testcert.Issue(&x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{CommonName: "Test Root CA"},
+		NotBefore:             notBefore,
+		NotAfter:              notAfter.AddDate(1, 0, 0),
+		IsCA:                  true,
+		BasicConstraintsValid: true,
+		KeyUsage:              x509.KeyUsageCertSign,
+	}, nil, nil)
+	leaf = testcert.Issue(&x509.Certificate{
+		SerialNumber: big.NewInt(2),
+		Subject:      pkix.Name{CommonName: "checkcert.example.com", Organization: []string{"Venafi"}},
+		DNSNames:     []string{"checkcert.example.com", "www.checkcert.example.com"},
+		NotBefore:    notBefore,
+		NotAfter:     notAfter,
+	}, nil, &root)
+	return root, leaf
+}
+
+func pemCertificate(cert *x509.Certificate) []byte {
+	return pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: cert.Raw})
+}
+
+func pemKey(t *testing.T, key crypto.Signer) []byte {
+	t.Helper()
+	der, err := x509.MarshalECPrivateKey(key.(*ecdsa.PrivateKey))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: der})
+}
+
+func TestCheckCertPEMBundle(t *testing.T) {
+	now := time.Now()
+	root, leaf := newTestChain(t, now.Add(-time.Hour), now.AddDate(0, 0, 90))
+
+	// The root comes first, so the leaf has to be found among the certificates
+	var data []byte
+	data = append(data, pemCertificate(root.Cert)...)
+	data = append(data, pemCertificate(leaf.Cert)...)
+	data = append(data, pemKey(t, leaf.Key)...)
+
+	lc, err := installer.LoadCertificate(data, "", "", "")
+	if err != nil {
+		t.Fatalf("could not load PEM bundle: %s", err)
+	}
+	if lc.Format != "PEM" || lc.Certificate.Subject.CommonName != "checkcert.example.com" || len(lc.Chain) != 1 {
+		t.Fatalf("unexpected certificate %s in %s format, with %d chain certificates", lc.Certificate.Subject, lc.Format, len(lc.Chain))
+	}
+
+	window, _ := domain.ParseRenewBefore("30d")
+	report := buildCheckCertReport(lc, x509.NewCertPool(), window, now)
+	if !report.ChainValid {
+		t.Fatalf("expected a valid chain, got %s", report.ChainError)
+	}
+	if !report.HasPrivateKey || !report.KeyMatches {
+		t.Fatal("expected the private key to match the certificate")
+	}
+	if report.KeyType != "ECDSA P-256" {
+		t.Fatalf("unexpected key type %s", report.KeyType)
+	}
+	if report.DaysRemaining != 89 || report.Expired || report.NeedsRenewal {
+		t.Fatalf("unexpected expiry: %d days remaining, expired %t, needs renewal %t", report.DaysRemaining, report.Expired, report.NeedsRenewal)
+	}
+	if report.RenewAt == nil || !report.RenewAt.Equal(leaf.Cert.NotAfter.AddDate(0, 0, -30)) {
+		t.Fatalf("unexpected renewal date %v", report.RenewAt)
+	}
+	if err = report.problems(); err != nil {
+		t.Fatalf("unexpected problems: %s", err)
+	}
+
+	out := bytes.Buffer{}
+	if err = writeCheckCertText(&out, report); err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(out.String(), "checkcert.example.com, www.checkcert.example.com") {
+		t.Fatalf("SANs missing from report:\n%s", out.String())
+	}
+}
+
+func TestCheckCertProblems(t *testing.T) {
+	now := time.Now()
+	_, expired := newTestChain(t, now.AddDate(0, 0, -90), now.AddDate(0, 0, -1))
+	_, other := newTestChain(t, now.Add(-time.Hour), now.AddDate(0, 0, 90))
+
+	// Neither the root nor the key of the certificate are in the file
+	data := append(pemCertificate(expired.Cert), pemKey(t, other.Key)...)
+	lc, err := installer.LoadCertificate(data, "", "", "")
+	if err != nil {
+		t.Fatalf("could not load PEM: %s", err)
+	}
+
+	window, _ := domain.ParseRenewBefore(domain.DefaultRenewBefore)
+	report := buildCheckCertReport(lc, x509.NewCertPool(), window, now)
+	if !report.Expired || !report.NeedsRenewal {
+		t.Fatal("expected the certificate to be expired and in need of renewal")
+	}
+	if report.KeyMatches {
+		t.Fatal("expected the private key not to match the certificate")
+	}
+	if report.ChainValid {
+		t.Fatal("expected the chain not to be valid")
+	}
+
+	err = report.problems()
+	if err == nil {
+		t.Fatal("expected problems to be reported")
+	}
+	for _, msg := range []string{"expired", "private key does not match", "chain is not valid"} {
+		if !strings.Contains(err.Error(), msg) {
+			t.Errorf("expected %q in %q", msg, err)
+		}
+	}
+}
+
+func TestCheckCertDERAndPKCS12(t *testing.T) {
+	now := time.Now()
+	_, leaf := newTestChain(t, now.Add(-time.Hour), now.AddDate(0, 0, 90))
+
+	lc, err := installer.LoadCertificate(leaf.Cert.Raw, "", "", "")
+	if err != nil {
+		t.Fatalf("could not load DER certificate: %s", err)
+	}
+	if lc.Format != "DER" || lc.PrivateKey != nil {
+		t.Fatalf("unexpected %s certificate", lc.Format)
+	}
+
+	p12, err := os.ReadFile("../../test-files/playbook/cert.p12")
+	if err != nil {
+		t.Fatal(err)
+	}
+	lc, err = installer.LoadCertificate(p12, "", "newPassword!", "")
+	if err != nil {
+		t.Fatalf("could not load PKCS#12: %s", err)
+	}
+	if lc.Format != "PKCS#12" || lc.PrivateKey == nil {
+		t.Fatalf("expected a PKCS#12 certificate with its private key, got %s", lc.Format)
+	}
+
+	_, err = installer.LoadCertificate(p12, "", "wrongPassword", "")
+	if err == nil {
+		t.Fatal("expected an error with a wrong PKCS#12 password")
+	}
+}
+
+func TestCheckCertRequestFromCertificate(t *testing.T) {
+	now := time.Now()
+	_, leaf := newTestChain(t, now.Add(-time.Hour), now.AddDate(0, 0, 90))
+
+	req := requestFromCertificate(leaf.Cert)
+	if req.Subject.CommonName != "checkcert.example.com" || len(req.DNSNames) != 2 {
+		t.Fatalf("unexpected subject %s and SANs %v", req.Subject, req.DNSNames)
+	}
+	if req.KeyType != certificate.KeyTypeECDSA || req.KeyCurve != certificate.EllipticCurveP256 {
+		t.Fatalf("unexpected key %s %s", req.KeyType.String(), req.KeyCurve.String())
+	}
+}
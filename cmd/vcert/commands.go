@@ -17,6 +17,8 @@
 package main
 
 import (
+	"context"
+	"crypto"
 	"crypto/tls"
 	"crypto/x509"
 	"encoding/json"
@@ -33,12 +35,15 @@ import (
 
 	"github.com/Venafi/vcert/v5/pkg/venafi"
 	"github.com/urfave/cli/v2"
+	"go.uber.org/zap"
 	"golang.org/x/crypto/pkcs12"
 	"gopkg.in/yaml.v2"
 
 	"github.com/Venafi/vcert/v5"
 	"github.com/Venafi/vcert/v5/pkg/certificate"
 	"github.com/Venafi/vcert/v5/pkg/endpoint"
+	"github.com/Venafi/vcert/v5/pkg/playbook/app/audit"
+	"github.com/Venafi/vcert/v5/pkg/playbook/app/installer"
 	"github.com/Venafi/vcert/v5/pkg/policy"
 	"github.com/Venafi/vcert/v5/pkg/util"
 	"github.com/Venafi/vcert/v5/pkg/venafi/cloud"
@@ -54,7 +59,7 @@ var (
 	commandEnroll = &cli.Command{
 		Before: runBeforeCommand,
 		Flags:  enrollFlags,
-		Action: doCommandEnroll1,
+		Action: withCommandOutput(doCommandEnroll1),
 		Name:   commandEnrollName,
 		Usage:  "To enroll a certificate",
 		UsageText: ` vcert enroll <Required Venafi as a Service -OR- Trust Protection Platform Config> <Options>
@@ -63,13 +68,15 @@ var (
 		 vcert enroll -u https://tpp.example.com -t <TPP access token> -z "<policy folder DN>" --cn <common name>
 		 vcert enroll -u https://tpp.example.com -t <TPP access token> -z "<policy folder DN>" --cn <common name> --key-size 4096 --san-dns <alt name> --san-dns <alt name2>
 		 vcert enroll -u https://tpp.example.com -t <TPP access token> -z "<policy folder DN>" --cn <common name> --key-type ecdsa --key-curve p384 --san-dns <alt name> -san-dns <alt name2>
-		 vcert enroll -u https://tpp.example.com -t <TPP access token> -z "<policy folder DN>" --p12-file <PKCS#12 client cert> --p12-password <PKCS#12 password> --cn <common name>`,
+		 vcert enroll -u https://tpp.example.com -t <TPP access token> -z "<policy folder DN>" --p12-file <PKCS#12 client cert> --p12-password <PKCS#12 password> --cn <common name>
+		 vcert enroll --platform est -u https://est.example.com --username <EST user> --password <EST user password> --cn <common name>
+		 vcert enroll --platform scep -u http://scep.example.com/cgi-bin/pkiclient.exe --password <challenge password> --cn <common name>`,
 	}
 	commandGetCred = &cli.Command{
 		Before: runBeforeCommand,
 		Name:   commandGetCredName,
 		Flags:  getCredFlags,
-		Action: doCommandCredMgmt1,
+		Action: withCommandOutput(doCommandCredMgmt1),
 		Usage:  "To obtain a new credential (token) for authentication",
 		UsageText: ` vcert getcred -u https://tpp.example.com --username <TPP user> --password <TPP user password>
 		vcert getcred --email <email address for VaaS headless registration> [--password <password>] [--format (text|json)]
@@ -108,17 +115,18 @@ var (
 		Before: runBeforeCommand,
 		Name:   commandPickupName,
 		Flags:  pickupFlags,
-		Action: doCommandPickup1,
+		Action: withCommandOutput(doCommandPickup1),
 		Usage:  "To download a certificate",
 		UsageText: ` vcert pickup <Required Venafi as a Service -OR- Trust Protection Platform Config> <Options>
 		 vcert pickup -k <VaaS API key> [--pickup-id <ID value> | --pickup-id-file <file containing ID value>]
-		 vcert pickup -u https://tpp.example.com -t <TPP access token> --pickup-id <ID value>`,
+		 vcert pickup -u https://tpp.example.com -t <TPP access token> --pickup-id <ID value>
+		 vcert pickup --platform firefly -u https://firefly.example.com -t <Firefly access token> --pickup-id <request ID value>`,
 	}
 	commandRevoke = &cli.Command{
 		Before: runBeforeCommand,
 		Name:   commandRevokeName,
 		Flags:  revokeFlags,
-		Action: doCommandRevoke1,
+		Action: withCommandOutput(doCommandRevoke1),
 		Usage:  "To revoke a certificate",
 		UsageText: ` vcert revoke <Required Trust Protection Platform Config> <Options>
 		 vcert revoke -u https://tpp.example.com -t <TPP access token> --thumbprint <cert SHA1 thumbprint>
@@ -128,11 +136,13 @@ var (
 		Before: runBeforeCommand,
 		Name:   commandRenewName,
 		Flags:  renewFlags,
-		Action: doCommandRenew1,
+		Action: withCommandOutput(doCommandRenew1),
 		Usage:  "To renew a certificate",
 		UsageText: ` vcert renew <Required Venafi as a Service -OR- Trust Protection Platform Config> <Options>
         vcert renew -u https://tpp.example.com -t <TPP access token> --id <ID value>
-		vcert renew -k <VaaS API key> --thumbprint <cert SHA1 fingerprint>`,
+		vcert renew -k <VaaS API key> --thumbprint <cert SHA1 fingerprint>
+		vcert renew -u https://tpp.example.com -t <TPP access token> -z <policy folder DN> --all-expiring --expires-within 14d
+		vcert renew --platform est -u https://est.example.com --p12-file <PKCS#12 with the certificate to renew> --p12-password <PKCS#12 password>`,
 	}
 	commandRetire = &cli.Command{
 		Before: runBeforeCommand,
@@ -153,17 +163,20 @@ var (
 		Usage:  "To apply a certificate policy specification to a zone",
 		UsageText: ` vcert setpolicy <Required Venafi as a Service -OR- Trust Protection Platform Config> <Options>
         vcert setpolicy -u https://tpp.example.com -t <TPP access token> -z "<policy folder DN>" --file /path-to/policy.spec
-		vcert setpolicy -k <VaaS API key> -z "<app name>\<CIT alias>" --file /path-to/policy.spec`,
+		vcert setpolicy -k <VaaS API key> -z "<app name>\<CIT alias>" --file /path-to/policy.spec
+		vcert setpolicy -k <VaaS API key> -z "<app name>\<CIT alias>" --create-app --app-owner jdoe@example.com --app-description "<description>"`,
 	}
 	commandGetPolicy = &cli.Command{
 		Before: runBeforeCommand,
 		Name:   commandGetePolicyName,
 		Flags:  getPolicyFlags,
-		Action: doCommandGetPolicy,
+		Action: withCommandOutput(doCommandGetPolicy),
 		Usage:  "To retrieve the certificate policy of a zone",
 		UsageText: ` vcert getpolicy <Required Venafi as a Service -OR- Trust Protection Platform Config> <Options>
         vcert getpolicy -u https://tpp.example.com -t <TPP access token> -z "<policy folder DN>"
-		vcert getpolicy -k <VaaS API key> -z "<app name>\<CIT alias>"`,
+		vcert getpolicy -k <VaaS API key> -z "<app name>\<CIT alias>"
+		vcert getpolicy --platform firefly -u https://firefly.example.com -t <Firefly access token> -z "<policy name>"
+		vcert getpolicy --platform firefly -u https://firefly.example.com -t <Firefly access token>`,
 	}
 
 	commandSshPickup = &cli.Command{
@@ -175,12 +188,21 @@ var (
 		UsageText: `vcert sshpickup -u https://tpp.example.com -t <TPP access token> --pickup-id <ssh cert DN>`,
 	}
 	commandSshEnroll = &cli.Command{
+		Before: runBeforeCommand,
+		Name:   commandSshEnrollName,
+		Flags:  sshEnrollFlags,
+		Action: doCommandEnrollSshCert,
+		Usage:  "To enroll a SSH Certificate",
+		UsageText: `vcert sshenroll -u https://tpp.example.com -t <TPP access token> --template <val> --id <val> --principal bob --principal alice --valid-hours 1
+		vcert sshenroll -u https://tpp.example.com -t <TPP access token> --renew --pickup-id <ssh cert DN>`,
+	}
+	commandSshRevoke = &cli.Command{
 		Before:    runBeforeCommand,
-		Name:      commandSshEnrollName,
-		Flags:     sshEnrollFlags,
-		Action:    doCommandEnrollSshCert,
-		Usage:     "To enroll a SSH Certificate",
-		UsageText: `vcert sshenroll -u https://tpp.example.com -t <TPP access token> --template <val> --id <val> --principal bob --principal alice --valid-hours 1`,
+		Name:      commandSshRevokeName,
+		Flags:     sshRevokeFlags,
+		Action:    doCommandSshRevoke,
+		Usage:     "To revoke a SSH Certificate",
+		UsageText: `vcert sshrevoke -u https://tpp.example.com -t <TPP access token> --pickup-id <ssh cert DN>`,
 	}
 	commandSshGetConfig = &cli.Command{
 		Before:    runBeforeCommand,
@@ -199,6 +221,8 @@ func runBeforeCommand(c *cli.Context) error {
 	flags.emailSans = c.StringSlice("san-email")
 	flags.upnSans = c.StringSlice("san-upn")
 	flags.customFields = c.StringSlice("field")
+	flags.appOwners = c.StringSlice("app-owner")
+	flags.appDNs = c.StringSlice("app-dn")
 	flags.sshCertExtension = c.StringSlice("extension")
 	flags.sshCertPrincipal = c.StringSlice("principal")
 	flags.sshCertSourceAddrs = c.StringSlice("source-address")
@@ -229,6 +253,13 @@ func runBeforeCommand(c *cli.Context) error {
 		uri, _ := url.Parse(stringURI)
 		flags.uriSans = append(flags.uriSans, uri)
 	}
+	for _, stringRID := range c.StringSlice("san-rid") {
+		rid, err := certificate.ParseOID(stringRID)
+		if err != nil {
+			return fmt.Errorf("invalid --san-rid: %w", err)
+		}
+		flags.ridSans = append(flags.ridSans, rid)
+	}
 
 	if flags.platformString != "" {
 		flags.platform = venafi.GetPlatformType(flags.platformString)
@@ -245,6 +276,25 @@ func runBeforeCommand(c *cli.Context) error {
 		}
 	}
 
+	return configureLogging()
+}
+
+// configureLogging applies the log-format, log-level and log-file flags.
+// When the logs are formatted as JSON or written to a file, the messages of the CLI go through the zap logger too
+func configureLogging() error {
+	opts := util.LogOptions{Format: flags.logFormat, Level: flags.logLevel, File: flags.logFile}
+	if opts == (util.LogOptions{}) {
+		return nil
+	}
+
+	err := util.ConfigureLoggerWithOptions(opts)
+	if err != nil {
+		return err
+	}
+	if opts.RedirectsStdLog() {
+		logger = zap.NewStdLog(zap.L())
+		logf = logger.Printf
+	}
 	return nil
 }
 
@@ -295,17 +345,42 @@ func setTLSConfig() error {
 		tlsConfig.BuildNameToCertificate()
 	}
 
+	if flags.clientCertFile != "" {
+		cert, err := util.LoadClientCertificate(flags.clientCertFile, flags.clientKeyFile)
+		if err != nil {
+			return err
+		}
+		tlsConfig.Certificates = []tls.Certificate{*cert}
+	}
+
 	//Setting TLS configuration
 	http.DefaultTransport.(*http.Transport).TLSClientConfig = &tlsConfig
 
 	return nil
 }
 
+// getClientCertificate returns the TLS client certificate loaded from -client-pkcs12 or --client-cert-file
+func getClientCertificate() (*certificate.PEMCollection, error) {
+	if len(tlsConfig.Certificates) == 0 || len(tlsConfig.Certificates[0].Certificate) == 0 {
+		return nil, fmt.Errorf("no client certificate was provided")
+	}
+	cert, err := x509.ParseCertificate(tlsConfig.Certificates[0].Certificate[0])
+	if err != nil {
+		return nil, err
+	}
+	return certificate.NewPEMCollection(cert, nil, nil)
+}
+
 func doCommandEnroll1(c *cli.Context) error {
 	err := validateEnrollFlags(c.Command.Name)
 	if err != nil {
 		return err
 	}
+	auditLog, err := openCommandAuditLog()
+	if err != nil {
+		return err
+	}
+	defer auditLog.Close()
 	err = setTLSConfig()
 	if err != nil {
 		return err
@@ -315,6 +390,9 @@ func doCommandEnroll1(c *cli.Context) error {
 	if err != nil {
 		return fmt.Errorf("Failed to build vcert config: %s", err)
 	}
+	if flags.batch != "" {
+		return doCommandEnrollBatch(c.Context, cfg, auditLog)
+	}
 
 	connector, err := vcert.NewClient(&cfg)
 	if err != nil {
@@ -325,14 +403,14 @@ func doCommandEnroll1(c *cli.Context) error {
 	var req = &certificate.Request{}
 	var pcc = &certificate.PEMCollection{}
 
-	zoneConfig, err := connector.ReadZoneConfiguration()
+	zoneConfig, err := connector.ReadZoneConfiguration(c.Context)
 
 	if err != nil {
 		return err
 	}
 	logf("Successfully read zone configuration for %s", flags.zone)
 	req = fillCertificateRequest(req, &flags)
-	err = connector.GenerateRequest(zoneConfig, req)
+	err = connector.GenerateRequest(c.Context, zoneConfig, req)
 	if err != nil {
 		return err
 	}
@@ -348,13 +426,25 @@ func doCommandEnroll1(c *cli.Context) error {
 	passwordAutogenerated := false
 
 	if connector.SupportSynchronousRequestCertificate() {
-		pcc, err = connector.SynchronousRequestCertificate(req)
+		if flags.timeout > 0 {
+			req.Timeout = time.Duration(flags.timeout) * time.Second
+		}
+		pcc, err = connector.SynchronousRequestCertificate(c.Context, req)
+		recordAudit(auditLog, enrollAuditEvent(cfg.ConnectorType.String(), req, ""), err)
 		if err != nil {
 			return err
 		}
 		logf("Successfully requested certificate for %s", requestedFor)
+
+		if req.CsrOrigin == certificate.LocalGeneratedCSR {
+			err = pcc.AddPrivateKey(req.PrivateKey, []byte(flags.keyPassword), flags.format)
+			if err != nil {
+				return err
+			}
+		}
 	} else {
-		flags.pickupID, err = connector.RequestCertificate(req)
+		flags.pickupID, err = connector.RequestCertificate(c.Context, req)
+		recordAudit(auditLog, enrollAuditEvent(cfg.ConnectorType.String(), req, flags.pickupID), err)
 		if err != nil {
 			return err
 		}
@@ -392,7 +482,7 @@ func doCommandEnroll1(c *cli.Context) error {
 			}
 
 			req.Timeout = time.Duration(180) * time.Second
-			pcc, err = retrieveCertificate(connector, req, time.Duration(flags.timeout)*time.Second)
+			pcc, err = retrieveCertificate(c.Context, connector, req, time.Duration(flags.timeout)*time.Second)
 			if err != nil {
 				return err
 			}
@@ -430,9 +520,15 @@ func doCommandEnroll1(c *cli.Context) error {
 	if passwordAutogenerated {
 		flags.keyPassword = ""
 	}
+	scts, err := checkSCTs(pcc)
+	if err != nil {
+		return err
+	}
+
 	result := &Result{
 		Pcc:      pcc,
 		PickupId: flags.pickupID,
+		SCTs:     scts,
 		Config: &Config{
 			Command:      c.Command.Name,
 			Format:       flags.format,
@@ -484,7 +580,7 @@ func doCommandEnrollSshCert(c *cli.Context) error {
 		}
 	}
 
-	err = connector.Ping()
+	err = connector.Ping(c.Context)
 
 	if err != nil {
 		logf("Unable to connect to %s: %s", cfg.ConnectorType, err)
@@ -537,7 +633,14 @@ func doCommandEnrollSshCert(c *cli.Context) error {
 	}
 
 	req.Timeout = time.Duration(flags.timeout) * time.Second
-	data, err := connector.RequestSSHCertificate(req)
+	var data *certificate.SshCertificateObject
+	if flags.sshCertRenew {
+		req.PickupID = flags.sshCertPickupId
+		req.Guid = flags.sshCertGuid
+		data, err = connector.RenewSSHCertificate(c.Context, req)
+	} else {
+		data, err = connector.RequestSSHCertificate(c.Context, req)
+	}
 
 	if err != nil {
 		return err
@@ -557,7 +660,7 @@ func doCommandEnrollSshCert(c *cli.Context) error {
 		}
 
 		retReq.Timeout = time.Duration(10) * time.Second
-		data, err = connector.RetrieveSSHCertificate(&retReq)
+		data, err = connector.RetrieveSSHCertificate(c.Context, &retReq)
 		if err != nil {
 			return fmt.Errorf("Failed to retrieve SSH certificate '%s'. Error: %s", flags.pickupID, err)
 		}
@@ -579,11 +682,13 @@ func doCommandEnrollSshCert(c *cli.Context) error {
 		privateKeyS = AddLineEnding(privateKeyS)
 	}
 
-	privateKeyFileName := flags.sshFileCertEnroll
-	if privateKeyFileName == "" {
-		privateKeyFileName = data.CertificateDetails.KeyID
+	privateKeyS, err = sshPrivateKeyData(data, privateKeyS)
+	if err != nil {
+		return err
 	}
 
+	privateKeyFileName := sshPrivateKeyFileName(data)
+
 	// Check if the files already exist and prompt the user to overwrite
 	if !flags.noPrompt {
 		err = validateExistingFile(privateKeyFileName)
@@ -614,8 +719,8 @@ func fillSshCertificateRequest(req *certificate.SshCertRequest, cf *commandFlags
 		req.ObjectName = cf.sshCertObjectName
 	}
 
-	if cf.sshCertValidHours > 0 {
-		req.ValidityPeriod = strconv.Itoa(cf.sshCertValidHours) + "h"
+	if cf.validHours > 0 {
+		req.ValidityPeriod = strconv.Itoa(cf.validHours) + "h"
 	}
 
 	if cf.sshCertFolder != "" {
@@ -660,13 +765,17 @@ func doCommandCredMgmt1(c *cli.Context) error {
 		return err
 	}
 
+	if c.Command.Name == commandGetCredName && flags.renewToken {
+		return doRenewTokens(c.Context)
+	}
+
 	cfg, err := buildConfig(c, &flags)
 	if err != nil {
 		return fmt.Errorf("Failed to build vcert config: %s", err)
 	}
 
 	var clientP12 bool
-	if flags.clientP12 != "" {
+	if flags.clientP12 != "" || flags.clientCertFile != "" {
 		clientP12 = true
 	}
 
@@ -707,11 +816,11 @@ func doCommandCredMgmt1(c *cli.Context) error {
 	switch c.Command.Name {
 	case commandGetCredName:
 		if vaasConnector != nil {
-			return getVaaSCredentials(vaasConnector, &cfg)
+			return getVaaSCredentials(c.Context, vaasConnector, &cfg)
 		} else if tppConnector != nil {
-			return getTppCredentials(tppConnector, &cfg, clientP12)
+			return getTppCredentials(c.Context, tppConnector, &cfg, clientP12)
 		} else {
-			return getFireflyCredentials(fireflyConnector, &cfg)
+			return getFireflyCredentials(c.Context, fireflyConnector, &cfg)
 		}
 	case commandCheckCredName:
 		//TODO: quick workaround to supress logs when output is in JSON.
@@ -720,7 +829,7 @@ func doCommandCredMgmt1(c *cli.Context) error {
 		}
 
 		if cfg.Credentials.AccessToken != "" {
-			resp, err := tppConnector.VerifyAccessToken(&endpoint.Authentication{
+			resp, err := tppConnector.VerifyAccessToken(c.Context, &endpoint.Authentication{
 				AccessToken: cfg.Credentials.AccessToken,
 			})
 			if err != nil {
@@ -744,7 +853,7 @@ func doCommandCredMgmt1(c *cli.Context) error {
 		}
 	case commandVoidCredName:
 		if cfg.Credentials.AccessToken != "" {
-			err := tppConnector.RevokeAccessToken(&endpoint.Authentication{
+			err := tppConnector.RevokeAccessToken(c.Context, &endpoint.Authentication{
 				AccessToken: cfg.Credentials.AccessToken,
 			})
 			if err != nil {
@@ -761,14 +870,14 @@ func doCommandCredMgmt1(c *cli.Context) error {
 	return nil
 }
 
-func getTppCredentials(tppConnector *tpp.Connector, cfg *vcert.Config, clientP12 bool) error {
+func getTppCredentials(ctx context.Context, tppConnector *tpp.Connector, cfg *vcert.Config, clientP12 bool) error {
 	//TODO: quick workaround to suppress logs when output is in JSON.
 	if flags.credFormat != "json" {
 		logf("Getting credentials...")
 	}
 
 	if cfg.Credentials.RefreshToken != "" {
-		resp, err := tppConnector.RefreshAccessToken(&endpoint.Authentication{
+		resp, err := tppConnector.RefreshAccessToken(ctx, &endpoint.Authentication{
 			RefreshToken: cfg.Credentials.RefreshToken,
 			ClientId:     flags.clientId,
 			Scope:        flags.scope,
@@ -780,6 +889,9 @@ func getTppCredentials(tppConnector *tpp.Connector, cfg *vcert.Config, clientP12
 			if err := outputJSON(resp); err != nil {
 				return err
 			}
+		} else if isStructuredOutput() {
+			return writeCommandResult(commandGetCredName, tppCredentialResult(resp.Access_token, resp.Expires,
+				resp.Refresh_token, resp.Refresh_until))
 		} else {
 			tm := time.Unix(int64(resp.Expires), 0).UTC().Format(time.RFC3339)
 			fmt.Println("access_token: ", resp.Access_token)
@@ -801,7 +913,7 @@ func getTppCredentials(tppConnector *tpp.Connector, cfg *vcert.Config, clientP12
 			auth.Scope = "certificate:manage,revoke;configuration:manage"
 		}
 
-		resp, err := tppConnector.GetRefreshToken(auth)
+		resp, err := tppConnector.GetRefreshToken(ctx, auth)
 		if err != nil {
 			return err
 		}
@@ -809,6 +921,9 @@ func getTppCredentials(tppConnector *tpp.Connector, cfg *vcert.Config, clientP12
 			if err := outputJSON(resp); err != nil {
 				return err
 			}
+		} else if isStructuredOutput() {
+			return writeCommandResult(commandGetCredName, tppCredentialResult(resp.Access_token, resp.Expires,
+				resp.Refresh_token, resp.Refresh_until))
 		} else {
 			tm := time.Unix(int64(resp.Expires), 0).UTC().Format(time.RFC3339)
 			fmt.Println("access_token: ", resp.Access_token)
@@ -819,7 +934,7 @@ func getTppCredentials(tppConnector *tpp.Connector, cfg *vcert.Config, clientP12
 			}
 		}
 	} else if clientP12 {
-		resp, err := tppConnector.GetRefreshToken(&endpoint.Authentication{
+		resp, err := tppConnector.GetRefreshToken(ctx, &endpoint.Authentication{
 			ClientPKCS12: clientP12,
 			Scope:        flags.scope,
 			ClientId:     flags.clientId})
@@ -830,6 +945,9 @@ func getTppCredentials(tppConnector *tpp.Connector, cfg *vcert.Config, clientP12
 			if err := outputJSON(resp); err != nil {
 				return err
 			}
+		} else if isStructuredOutput() {
+			return writeCommandResult(commandGetCredName, tppCredentialResult(resp.Access_token, resp.Expires,
+				resp.Refresh_token, resp.Refresh_until))
 		} else {
 			tm := time.Unix(int64(resp.Expires), 0).UTC().Format(time.RFC3339)
 			fmt.Println("access_token: ", resp.Access_token)
@@ -846,7 +964,7 @@ func getTppCredentials(tppConnector *tpp.Connector, cfg *vcert.Config, clientP12
 	return nil
 }
 
-func getVaaSCredentials(vaasConnector *cloud.Connector, cfg *vcert.Config) error {
+func getVaaSCredentials(ctx context.Context, vaasConnector *cloud.Connector, cfg *vcert.Config) error {
 	//TODO: quick workaround to suppress logs when output is in JSON.
 	if flags.credFormat != "json" {
 		logf("Getting credentials...")
@@ -854,7 +972,7 @@ func getVaaSCredentials(vaasConnector *cloud.Connector, cfg *vcert.Config) error
 
 	if cfg.Credentials.User != "" {
 
-		statusCode, userDetails, err := vaasConnector.CreateAPIUserAccount(cfg.Credentials.User, cfg.Credentials.Password)
+		statusCode, userDetails, err := vaasConnector.CreateAPIUserAccount(ctx, cfg.Credentials.User, cfg.Credentials.Password)
 
 		if err != nil {
 			return fmt.Errorf("failed to create a User Account/rotate API Key in VaaS: %s", err)
@@ -866,6 +984,11 @@ func getVaaSCredentials(vaasConnector *cloud.Connector, cfg *vcert.Config) error
 			if err := outputJSON(apiKey); err != nil {
 				return err
 			}
+		} else if isStructuredOutput() {
+			return writeCommandResult(commandGetCredName, credentialResult{
+				APIKey:        apiKey.Key,
+				APIKeyExpires: apiKey.ValidityEndDateString,
+			})
 		} else {
 			var headerMessage string
 			if statusCode == http.StatusCreated {
@@ -888,13 +1011,13 @@ func getVaaSCredentials(vaasConnector *cloud.Connector, cfg *vcert.Config) error
 	return nil
 }
 
-func getFireflyCredentials(fireflyConnector *firefly.Connector, cfg *vcert.Config) error {
+func getFireflyCredentials(ctx context.Context, fireflyConnector *firefly.Connector, cfg *vcert.Config) error {
 	//TODO: quick workaround to suppress logs when output is in JSON.
 	if flags.credFormat != "json" {
 		logf("Getting credentials...")
 	}
 
-	token, err := fireflyConnector.Authorize(cfg.Credentials)
+	token, err := fireflyConnector.Authorize(ctx, cfg.Credentials)
 
 	if err != nil {
 		return err
@@ -903,6 +1026,16 @@ func getFireflyCredentials(fireflyConnector *firefly.Connector, cfg *vcert.Confi
 		if err := outputJSON(token); err != nil {
 			return err
 		}
+	} else if isStructuredOutput() {
+		result := credentialResult{
+			AccessToken:  token.AccessToken,
+			RefreshToken: token.RefreshToken,
+			TokenType:    token.TokenType,
+		}
+		if !token.Expiry.IsZero() {
+			result.AccessTokenExpires = token.Expiry.UTC().Format(time.RFC3339)
+		}
+		return writeCommandResult(commandGetCredName, result)
 	} else {
 		fmt.Println("access_token: ", token.AccessToken)
 		fmt.Println("refresh_token: ", token.RefreshToken)
@@ -968,8 +1101,10 @@ func doCommandPickup1(c *cli.Context) error {
 		flags.pickupID = strings.TrimSpace(string(bytes))
 	}
 	var req = &certificate.Request{
-		PickupID:    flags.pickupID,
-		ChainOption: certificate.ChainOptionFromString(flags.chainOption),
+		PickupID:     flags.pickupID,
+		Thumbprint:   flags.thumbprint,
+		SerialNumber: flags.serialNumber,
+		ChainOption:  certificate.ChainOptionFromString(flags.chainOption),
 	}
 	if flags.keyPassword != "" {
 		// key password is provided, which means will be requesting private key
@@ -977,7 +1112,7 @@ func doCommandPickup1(c *cli.Context) error {
 		req.FetchPrivateKey = true
 	}
 	var pcc *certificate.PEMCollection
-	pcc, err = retrieveCertificate(connector, req, time.Duration(flags.timeout)*time.Second)
+	pcc, err = retrieveCertificate(c.Context, connector, req, time.Duration(flags.timeout)*time.Second)
 	if err != nil {
 		errStr := err.Error()
 		sliceString := strings.Split(errStr, ":")
@@ -986,7 +1121,7 @@ func doCommandPickup1(c *cli.Context) error {
 		if strings.TrimSpace(errToValidate) == "Failed to lookup private key vault id" && wasPasswordEmpty {
 			req.KeyPassword = ""
 			req.FetchPrivateKey = false
-			pcc, err = retrieveCertificate(connector, req, time.Duration(flags.timeout)*time.Second)
+			pcc, err = retrieveCertificate(c.Context, connector, req, time.Duration(flags.timeout)*time.Second)
 
 			if err != nil {
 				return fmt.Errorf("Failed to retrieve certificate: %s", err)
@@ -996,6 +1131,8 @@ func doCommandPickup1(c *cli.Context) error {
 			return fmt.Errorf("Failed to retrieve certificate: %s", err)
 		}
 	}
+	// the connector fills the Pickup ID of a certificate found by thumbprint or serial number
+	flags.pickupID = req.PickupID
 	logf("Successfully retrieved request for %s", flags.pickupID)
 
 	if pcc.PrivateKey != "" && (flags.format == Pkcs12 || flags.format == JKSFormat || flags.format == util.LegacyPem) || (flags.noPrompt && wasPasswordEmpty && pcc.PrivateKey != "") {
@@ -1020,9 +1157,15 @@ func doCommandPickup1(c *cli.Context) error {
 		flags.keyPassword = ""
 	}
 
+	scts, err := checkSCTs(pcc)
+	if err != nil {
+		return err
+	}
+
 	result := &Result{
 		Pcc:      pcc,
 		PickupId: flags.pickupID,
+		SCTs:     scts,
 		Config: &Config{
 			Command:      c.Command.Name,
 			Format:       flags.format,
@@ -1050,6 +1193,11 @@ func doCommandRevoke1(c *cli.Context) error {
 	if err != nil {
 		return err
 	}
+	auditLog, err := openCommandAuditLog()
+	if err != nil {
+		return err
+	}
+	defer auditLog.Close()
 	err = setTLSConfig()
 	if err != nil {
 		return err
@@ -1092,12 +1240,27 @@ func doCommandRevoke1(c *cli.Context) error {
 	revReq.Reason = flags.revocationReason
 	revReq.Comments = "revocation request from command line utility"
 
-	err = connector.RevokeCertificate(revReq)
+	err = connector.RevokeCertificate(c.Context, revReq)
+	recordAudit(auditLog, audit.Event{
+		Operation:  audit.OperationRevoke,
+		Platform:   cfg.ConnectorType.String(),
+		Zone:       flags.zone,
+		Thumbprint: flags.thumbprint,
+		Parameters: revokeAuditParameters(revReq),
+	}, err)
 	if err != nil {
 		return fmt.Errorf("Failed to revoke certificate: %s", err)
 	}
 	logf("Successfully created revocation request for %s", requestedFor)
 
+	if isStructuredOutput() {
+		return writeCommandResult(c.Command.Name, revokeResult{
+			CertificateDN: revReq.CertificateDN,
+			Thumbprint:    revReq.Thumbprint,
+			Reason:        revReq.Reason,
+			Retired:       revReq.Disable,
+		})
+	}
 	return nil
 }
 
@@ -1123,7 +1286,11 @@ func doCommandRetire(c *cli.Context) error {
 		logf("Successfully connected to %s", cfg.ConnectorType)
 	}
 
-	var retReq = &certificate.RetireRequest{}
+	if flags.retireBlocklist && cfg.ConnectorType != endpoint.ConnectorTypeCloud {
+		return fmt.Errorf("--blocklist is only supported by Venafi as a Service")
+	}
+
+	var retReq = &certificate.RetireRequest{AddToBlocklist: flags.retireBlocklist}
 	switch true {
 	case flags.distinguishedName != "":
 		retReq.CertificateDN = flags.distinguishedName
@@ -1143,7 +1310,7 @@ func doCommandRetire(c *cli.Context) error {
 		return ""
 	}()
 
-	err = connector.RetireCertificate(retReq)
+	err = connector.RetireCertificate(c.Context, retReq)
 	if err != nil {
 		return fmt.Errorf("Failed to retire certificate: %s", err)
 	}
@@ -1168,47 +1335,53 @@ func doCommandCreatePolicy(c *cli.Context) error {
 	policyName := flags.policyName
 	policySpecLocation := flags.policySpecLocation
 
-	logf("Loading policy specification from %s", policySpecLocation)
-
-	file, bytes, err := policy.GetFileAndBytes(policySpecLocation)
-
-	if err != nil {
-		return err
-	}
-
-	if flags.verbose {
-		logf("Policy specification file was successfully opened")
-	}
+	// An application can be provisioned with the issuing templates that exist already, without any policy specification
+	var ps *policy.PolicySpecification
+	if policySpecLocation != "" {
+		logf("Loading policy specification from %s", policySpecLocation)
 
-	fileExt := policy.GetFileType(policySpecLocation)
-	fileExt = strings.ToLower(fileExt)
+		file, bytes, err := policy.GetFileAndBytes(policySpecLocation)
 
-	if flags.verifyPolicyConfig {
-		err = policy.VerifyPolicySpec(bytes, fileExt)
 		if err != nil {
-			err = fmt.Errorf("policy specification file is not valid: %s", err)
 			return err
-		} else {
-			logf("policy specification %s is valid", policySpecLocation)
-			return nil
 		}
-	}
+		defer file.Close()
 
-	//based on the extension call the appropriate method to feed the policySpecification
-	//structure.
-	var policySpecification policy.PolicySpecification
-	if fileExt == policy.JsonExtension {
-		err = json.Unmarshal(bytes, &policySpecification)
-		if err != nil {
-			return err
+		if flags.verbose {
+			logf("Policy specification file was successfully opened")
 		}
-	} else if fileExt == policy.YamlExtension {
-		err = yaml.Unmarshal(bytes, &policySpecification)
-		if err != nil {
-			return err
+
+		fileExt := policy.GetFileType(policySpecLocation)
+		fileExt = strings.ToLower(fileExt)
+
+		if flags.verifyPolicyConfig {
+			err = policy.VerifyPolicySpec(bytes, fileExt)
+			if err != nil {
+				err = fmt.Errorf("policy specification file is not valid: %s", err)
+				return err
+			} else {
+				logf("policy specification %s is valid", policySpecLocation)
+				return nil
+			}
 		}
-	} else {
-		return fmt.Errorf("the specified file is not supported")
+
+		//based on the extension call the appropriate method to feed the policySpecification
+		//structure.
+		var policySpecification policy.PolicySpecification
+		if fileExt == policy.JsonExtension {
+			err = json.Unmarshal(bytes, &policySpecification)
+			if err != nil {
+				return err
+			}
+		} else if fileExt == policy.YamlExtension {
+			err = yaml.Unmarshal(bytes, &policySpecification)
+			if err != nil {
+				return err
+			}
+		} else {
+			return fmt.Errorf("the specified file is not supported")
+		}
+		ps = &policySpecification
 	}
 
 	cfg, err := buildConfig(c, &flags)
@@ -1222,11 +1395,84 @@ func doCommandCreatePolicy(c *cli.Context) error {
 		return err
 	}
 
-	_, err = connector.SetPolicy(policyName, &policySpecification)
+	if ps != nil {
+		if flags.policyTranslate {
+			switch cfg.ConnectorType {
+			case endpoint.ConnectorTypeCloud:
+				ps, err = translatePolicySpecification(ps, venafi.TLSPCloud)
+			case endpoint.ConnectorTypeTPP:
+				ps, err = translatePolicySpecification(ps, venafi.TPP)
+			default:
+				err = fmt.Errorf("policy specifications can only be translated for TPP and VaaS")
+			}
+			if err != nil {
+				return err
+			}
+		}
+
+		_, err = connector.SetPolicy(c.Context, policyName, ps)
+		if err != nil {
+			return err
+		}
+	}
+
+	if flags.createApp {
+		return provisionApplication(connector, policyName)
+	}
+	return nil
+}
+
+// applicationProvisioner is implemented by the connectors able to create the applications certificates are requested for
+type applicationProvisioner interface {
+	SetApplication(req cloud.ApplicationRequest) (*policy.Application, error)
+}
 
-	defer file.Close()
+// provisionApplication creates or updates the application of zone, with the owners and description set by the flags,
+// and assigns the issuing template of zone to it
+func provisionApplication(connector endpoint.Connector, zone string) error {
+	provisioner, ok := connector.(applicationProvisioner)
+	if !ok {
+		return fmt.Errorf("--create-app is only supported by Venafi as a Service, not by %s", connector.GetType())
+	}
+	req := cloud.ApplicationRequest{
+		Name:        policy.GetApplicationName(zone),
+		Description: flags.appDescription,
+		Owners:      flags.appOwners,
+	}
+	if citName := policy.GetCitName(zone); citName != "" {
+		req.IssuingTemplates = []string{citName}
+	}
+	_, err := provisioner.SetApplication(req)
+	if err != nil {
+		return fmt.Errorf("failed to provision application %s: %w", req.Name, err)
+	}
+	logf("Successfully provisioned application %s", req.Name)
+	return nil
+}
 
-	return err
+// policyLister is implemented by the connectors able to list the names of the policies they issue certificates from
+type policyLister interface {
+	ListPolicies() ([]string, error)
+}
+
+// listPolicies prints the names of the policies of the connector, one per line
+func listPolicies(command string, connector endpoint.Connector) error {
+	lister, ok := connector.(policyLister)
+	if !ok {
+		return fmt.Errorf("listing policies is not supported by %s", connector.GetType())
+	}
+	names, err := lister.ListPolicies()
+	if err != nil {
+		return err
+	}
+	if isStructuredOutput() {
+		return writeCommandResult(command, policyResult{Policies: names})
+	}
+	log.Println("Policies are:")
+	for _, name := range names {
+		fmt.Println(name)
+	}
+	return nil
 }
 
 func doCommandGetPolicy(c *cli.Context) error {
@@ -1263,7 +1509,11 @@ func doCommandGetPolicy(c *cli.Context) error {
 
 		}
 
-		ps, err = connector.GetPolicy(policyName)
+		if policyName == "" {
+			return listPolicies(c.Command.Name, connector)
+		}
+
+		ps, err = connector.GetPolicy(c.Context, policyName)
 
 		if err != nil {
 			return err
@@ -1275,6 +1525,13 @@ func doCommandGetPolicy(c *cli.Context) error {
 
 	}
 
+	if flags.policyTranslateTo != "" {
+		ps, err = translatePolicySpecification(ps, venafi.GetPlatformType(flags.policyTranslateTo))
+		if err != nil {
+			return err
+		}
+	}
+
 	var b []byte
 
 	if policySpecLocation != "" {
@@ -1300,6 +1557,13 @@ func doCommandGetPolicy(c *cli.Context) error {
 			return err
 		}
 		log.Printf("policy was written in: %s", policySpecLocation)
+		if isStructuredOutput() {
+			return writeCommandResult(c.Command.Name, policyResult{Zone: policyName, File: policySpecLocation})
+		}
+
+	} else if isStructuredOutput() {
+
+		return writeCommandResult(c.Command.Name, policyResult{Zone: policyName, Policy: ps})
 
 	} else {
 
@@ -1315,11 +1579,40 @@ func doCommandGetPolicy(c *cli.Context) error {
 	return nil
 }
 
+// translatePolicySpecification adapts ps to the given platform, logging every change made to it
+func translatePolicySpecification(ps *policy.PolicySpecification, platform venafi.Platform) (*policy.PolicySpecification, error) {
+	var translated *policy.PolicySpecification
+	var changes []string
+	var err error
+	if platform == venafi.TPP {
+		translated, changes, err = policy.TranslateForTpp(ps)
+	} else {
+		translated, changes, err = policy.TranslateForCloud(ps)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to translate policy specification for %s: %w", platform, err)
+	}
+
+	logf("Translated policy specification for %s", platform)
+	for _, change := range changes {
+		logf("\t%s", change)
+	}
+	return translated, nil
+}
+
 func doCommandRenew1(c *cli.Context) error {
 	err := validateRenewFlags1(c.Command.Name)
 	if err != nil {
 		return err
 	}
+	auditLog, err := openCommandAuditLog()
+	if err != nil {
+		return err
+	}
+	defer auditLog.Close()
+	if flags.allExpiring {
+		return doCommandRenewAllExpiring(c, auditLog)
+	}
 
 	err = setTLSConfig()
 	if err != nil {
@@ -1347,7 +1640,13 @@ func doCommandRenew1(c *cli.Context) error {
 	}
 
 	// here we fetch old cert anyway
-	oldPcc, err := connector.RetrieveCertificate(searchReq)
+	var oldPcc *certificate.PEMCollection
+	if cfg.ConnectorType == endpoint.ConnectorTypeEST {
+		// EST servers do not store certificates, the certificate to renew is the TLS client certificate
+		oldPcc, err = getClientCertificate()
+	} else {
+		oldPcc, err = connector.RetrieveCertificate(c.Context, searchReq)
+	}
 	if err != nil {
 		return fmt.Errorf("Failed to fetch old certificate by id %s: %s", flags.distinguishedName, err)
 	}
@@ -1372,6 +1671,14 @@ func doCommandRenew1(c *cli.Context) error {
 		req = certificate.NewRequest(oldCert)
 		// override values with those from command line flags
 		req = fillCertificateRequest(req, &flags)
+		if flags.reuseKey {
+			key, err := readReusedPrivateKey()
+			if err != nil {
+				return err
+			}
+			req.SetPrivateKey(key)
+			logf("Reusing the private key of the certificate")
+		}
 
 	case "service" == flags.csrOption:
 		// logger.Panic("service side renewal is not implemented")
@@ -1384,7 +1691,7 @@ func doCommandRenew1(c *cli.Context) error {
 	// here we ignore zone for Renew action, however, API still needs it
 	zoneConfig := &endpoint.ZoneConfiguration{}
 
-	err = connector.GenerateRequest(zoneConfig, req)
+	err = connector.GenerateRequest(c.Context, zoneConfig, req)
 	if err != nil {
 		return err
 	}
@@ -1403,7 +1710,14 @@ func doCommandRenew1(c *cli.Context) error {
 
 	renewReq := generateRenewalRequest(&flags, req)
 
-	flags.pickupID, err = connector.RenewCertificate(renewReq)
+	flags.pickupID, err = connector.RenewCertificate(c.Context, renewReq)
+	renewEvent := enrollAuditEvent(cfg.ConnectorType.String(), req, flags.pickupID)
+	renewEvent.Operation = audit.OperationRenew
+	renewEvent.Thumbprint = flags.thumbprint
+	if flags.distinguishedName != "" {
+		renewEvent.Parameters["dn"] = flags.distinguishedName
+	}
+	recordAudit(auditLog, renewEvent, err)
 
 	if err != nil {
 		return err
@@ -1428,7 +1742,7 @@ func doCommandRenew1(c *cli.Context) error {
 		}
 
 		req.Timeout = time.Duration(180) * time.Second
-		pcc, err = retrieveCertificate(connector, req, time.Duration(flags.timeout)*time.Second)
+		pcc, err = retrieveCertificate(c.Context, connector, req, time.Duration(flags.timeout)*time.Second)
 		if err != nil {
 			return err
 		}
@@ -1487,15 +1801,21 @@ func doCommandRenew1(c *cli.Context) error {
 		if err == nil {
 			old, _ := json.Marshal(oldCert.PublicKey)
 			newCrt, _ := json.Marshal(newCert.PublicKey)
-			if len(old) > 0 && string(old) == string(newCrt) {
+			if len(old) > 0 && string(old) == string(newCrt) && !flags.reuseKey {
 				logf("WARNING: private key reused")
 			}
 		}
 	}
 
+	scts, err := checkSCTs(pcc)
+	if err != nil {
+		return err
+	}
+
 	result := &Result{
 		Pcc:      pcc,
 		PickupId: flags.pickupID,
+		SCTs:     scts,
 		Config: &Config{
 			Command:      c.Command.Name,
 			Format:       flags.format,
@@ -1518,6 +1838,47 @@ func doCommandRenew1(c *cli.Context) error {
 	return nil
 }
 
+// readReusedPrivateKey returns the private key of the certificate to renew, read from --key-file, or from --file when
+// the private key is stored along with the certificate
+func readReusedPrivateKey() (crypto.Signer, error) {
+	location := flags.keyFile
+	if location == "" {
+		location = flags.file
+	}
+	data, err := os.ReadFile(location)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read private key to reuse: %w", err)
+	}
+
+	var key crypto.PrivateKey
+	if flags.keyFile != "" {
+		for block, rest := pem.Decode(data); block != nil && key == nil; block, rest = pem.Decode(rest) {
+			if strings.HasSuffix(block.Type, "PRIVATE KEY") {
+				key, err = parsePEMPrivateKey(block, flags.keyPassword)
+				if err != nil {
+					return nil, err
+				}
+			}
+		}
+	} else {
+		storePassword := flags.jksPassword
+		if storePassword == "" {
+			storePassword = flags.keyPassword
+		}
+		lc, err := installer.LoadCertificate(data, flags.jksAlias, storePassword, flags.keyPassword)
+		if err != nil {
+			return nil, fmt.Errorf("failed to load private key to reuse from %s: %w", location, err)
+		}
+		key = lc.PrivateKey
+	}
+
+	signer, ok := key.(crypto.Signer)
+	if !ok {
+		return nil, fmt.Errorf("no private key to reuse found in %s", location)
+	}
+	return signer, nil
+}
+
 func doCommandSshGetConfig(c *cli.Context) error {
 
 	err := validateGetSshConfigFlags(c.Command.Name)
@@ -1549,7 +1910,7 @@ func doCommandSshGetConfig(c *cli.Context) error {
 		logf("Successfully built connector for %s", cfg.ConnectorType)
 	}
 
-	err = connector.Ping()
+	err = connector.Ping(c.Context)
 
 	if err != nil {
 		logf("Unable to connect to %s: %s", cfg.ConnectorType, err)
@@ -1565,7 +1926,7 @@ func doCommandSshGetConfig(c *cli.Context) error {
 		req.Guid = flags.sshCertGuid
 	}
 
-	conf, err := connector.RetrieveSshConfig(req)
+	conf, err := connector.RetrieveSshConfig(c.Context, req)
 	if err != nil {
 		return err
 	}
@@ -1697,7 +2058,7 @@ func doCommandSshPickup(c *cli.Context) error {
 	req = buildSshCertRequest(req, &flags)
 
 	req.Timeout = time.Duration(10) * time.Second
-	data, err := connector.RetrieveSSHCertificate(&req)
+	data, err := connector.RetrieveSSHCertificate(c.Context, &req)
 
 	if err != nil {
 		return fmt.Errorf("failed to retrieve certificate: %s", err)
@@ -1710,12 +2071,14 @@ func doCommandSshPickup(c *cli.Context) error {
 		privateKeyS = AddLineEnding(privateKeyS)
 	}
 
-	// If --file is not set, use Key ID as filename
-	privateKeyFileName := flags.sshFileCertEnroll
-	if privateKeyFileName == "" {
-		privateKeyFileName = data.CertificateDetails.KeyID
+	privateKeyS, err = sshPrivateKeyData(data, privateKeyS)
+	if err != nil {
+		return err
 	}
 
+	// If --file is not set, use Key ID as filename
+	privateKeyFileName := sshPrivateKeyFileName(data)
+
 	// Check if the files already exist and prompt the user to overwrite
 	if !flags.noPrompt {
 		err = validateExistingFile(privateKeyFileName)
@@ -1732,6 +2095,48 @@ func doCommandSshPickup(c *cli.Context) error {
 	return nil
 }
 
+func doCommandSshRevoke(c *cli.Context) error {
+
+	err := validateSshRevokeFlags(c.Command.Name)
+	if err != nil {
+		return err
+	}
+
+	err = setTLSConfig()
+	if err != nil {
+		return err
+	}
+
+	cfg, err := buildConfig(c, &flags)
+	if err != nil {
+		return fmt.Errorf("Failed to build vcert config: %s", err)
+	}
+
+	connector, err := vcert.NewClient(&cfg)
+	if err != nil {
+		return fmt.Errorf("Unable to connect to %s: %s", cfg.ConnectorType, err)
+	}
+	logf("Successfully connected to %s", cfg.ConnectorType)
+
+	req := &certificate.SshCertRequest{
+		PickupID: flags.sshCertPickupId,
+		Guid:     flags.sshCertGuid,
+	}
+
+	err = connector.RevokeSSHCertificate(c.Context, req)
+	if err != nil {
+		return fmt.Errorf("failed to revoke SSH certificate: %s", err)
+	}
+
+	revokedID := req.PickupID
+	if revokedID == "" {
+		revokedID = req.Guid
+	}
+	logf("Successfully revoked SSH certificate %s", revokedID)
+
+	return nil
+}
+
 func buildSshCertRequest(r certificate.SshCertRequest, cf *commandFlags) certificate.SshCertRequest {
 
 	if cf.sshCertKeyPassphrase != "" {
@@ -21,13 +21,16 @@ import (
 	"fmt"
 	"math/big"
 	"os"
+	"strings"
 	"time"
 
 	"github.com/urfave/cli/v2"
 
 	"github.com/Venafi/vcert/v5"
 	"github.com/Venafi/vcert/v5/pkg/endpoint"
+	"github.com/Venafi/vcert/v5/pkg/util"
 	"github.com/Venafi/vcert/v5/pkg/venafi"
+	"github.com/Venafi/vcert/v5/pkg/venafi/acme"
 )
 
 func buildConfig(c *cli.Context, flags *commandFlags) (cfg vcert.Config, err error) {
@@ -39,6 +42,10 @@ func buildConfig(c *cli.Context, flags *commandFlags) (cfg vcert.Config, err err
 		if err != nil {
 			return cfg, err
 		}
+		// The tokens of the file are refreshed for the application vcert getcred requests them for by default
+		if cfg.Credentials != nil && cfg.Credentials.RefreshToken != "" && cfg.Credentials.ClientId == "" {
+			cfg.Credentials.ClientId = "vcert-cli"
+		}
 	} else {
 		// Get values from Env Vars and set it to flags before building the config object
 		// Only do these when values are not loaded from config file
@@ -65,6 +72,41 @@ func buildConfig(c *cli.Context, flags *commandFlags) (cfg vcert.Config, err err
 					time.Sleep(1 * time.Second)
 				}
 			}
+		} else if flags.platform == venafi.ACME {
+			connectorType = endpoint.ConnectorTypeACME
+			baseURL = flags.url
+			if baseURL == "" {
+				baseURL = getPropertyFromEnvironment(vCertURL)
+			}
+			auth.ACMEAccount = &endpoint.ACMEAccount{
+				Email:      flags.acmeEmail,
+				KeyFile:    flags.acmeAccountKey,
+				EABKeyID:   flags.eabKeyID,
+				EABHMACKey: flags.eabHMACKey,
+			}
+			cfg.ACMEChallenge = &acme.ChallengeConfig{
+				Type:        flags.acmeChallenge,
+				HTTPAddress: flags.acmeHTTPAddress,
+				Webroot:     flags.acmeWebroot,
+				DNSCommand:  flags.acmeDNSCommand,
+			}
+		} else if flags.platform == venafi.EST {
+			connectorType = endpoint.ConnectorTypeEST
+			baseURL = flags.url
+			if baseURL == "" {
+				baseURL = getPropertyFromEnvironment(vCertURL)
+			}
+			// The TLS client certificate, if any, is set from --p12-file on the default transport
+			auth.User = flags.userName
+			auth.Password = flags.password
+		} else if flags.platform == venafi.SCEP {
+			connectorType = endpoint.ConnectorTypeSCEP
+			baseURL = flags.url
+			if baseURL == "" {
+				baseURL = getPropertyFromEnvironment(vCertURL)
+			}
+			// The password is the challenge password of the CSR
+			auth.Password = flags.password
 		} else if flags.platform == venafi.Firefly || (flags.userName != "" || tokenS != "" || flags.clientP12 != "" || c.Command.Name == "sshgetconfig") {
 
 			if flags.platform == venafi.Firefly {
@@ -121,6 +163,18 @@ func buildConfig(c *cli.Context, flags *commandFlags) (cfg vcert.Config, err err
 			connectorType = endpoint.ConnectorTypeCloud
 			baseURL = flags.url
 			auth.APIKey = apiKey
+			if apiKey == "" {
+				auth.TokenURL = flags.tokenURL
+				auth.ExternalJWT = flags.externalJWT
+				if flags.serviceAccountKey != "" {
+					data, err := os.ReadFile(flags.serviceAccountKey)
+					if err != nil {
+						return cfg, fmt.Errorf("failed to read service account private key: %w", err)
+					}
+					auth.ClientId = flags.serviceAccountID
+					auth.PrivateKey = string(data)
+				}
+			}
 			if flags.email != "" {
 				auth.User = flags.email
 				auth.Password = flags.password
@@ -157,6 +211,30 @@ func buildConfig(c *cli.Context, flags *commandFlags) (cfg vcert.Config, err err
 		}
 	}
 
+	// proxy may be overridden by CLI flags
+	if flags.proxyURL == "" && (flags.proxyUser != "" || flags.proxyPassword != "" || flags.noProxy != "") {
+		return cfg, fmt.Errorf("--proxy is required to use --proxy-user, --proxy-password or --no-proxy")
+	}
+	if flags.proxyURL != "" {
+		if cfg.Proxy.URL != "" {
+			logf("Overriding proxy based on command line flag.")
+		}
+		cfg.Proxy = util.ProxyConfig{
+			URL:      flags.proxyURL,
+			User:     flags.proxyUser,
+			Password: flags.proxyPassword,
+		}
+		for _, host := range strings.Split(flags.noProxy, ",") {
+			if strings.TrimSpace(host) != "" {
+				cfg.Proxy.NoProxy = append(cfg.Proxy.NoProxy, strings.TrimSpace(host))
+			}
+		}
+	}
+	err = cfg.Proxy.IsValid()
+	if err != nil {
+		return cfg, err
+	}
+
 	// zone may be overridden by CLI flag
 	if flags.zone != "" {
 		if cfg.Zone != "" {
@@ -171,7 +249,10 @@ func buildConfig(c *cli.Context, flags *commandFlags) (cfg vcert.Config, err err
 	}
 
 	if c.Command.Name == commandEnrollName || c.Command.Name == commandPickupName {
-		if cfg.Zone == "" && cfg.ConnectorType != endpoint.ConnectorTypeFake && !(flags.pickupID != "" || flags.pickupIDFile != "") {
+		// ACME servers have no zones, and the EST CA label and the SCEP CA identifier are optional
+		if cfg.Zone == "" && cfg.ConnectorType != endpoint.ConnectorTypeFake && cfg.ConnectorType != endpoint.ConnectorTypeACME &&
+			cfg.ConnectorType != endpoint.ConnectorTypeEST && cfg.ConnectorType != endpoint.ConnectorTypeSCEP &&
+			!(flags.pickupID != "" || flags.pickupIDFile != "") {
 			return cfg, fmt.Errorf("Zone cannot be empty. Use -z option")
 		}
 	}
@@ -0,0 +1,289 @@
+/*
+ * Copyright 2023 Venafi, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *  http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package main
+
+import (
+	"crypto/x509"
+	"encoding/pem"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/urfave/cli/v2"
+
+	"github.com/Venafi/vcert/v5/pkg/certificate"
+	"github.com/Venafi/vcert/v5/pkg/playbook/app/domain"
+	"github.com/Venafi/vcert/v5/pkg/playbook/app/installer"
+)
+
+const (
+	commandConvertName = "convert"
+
+	convertFormatPEM = "pem"
+)
+
+var commandConvert = &cli.Command{
+	Before: runBeforeCommand,
+	Name:   commandConvertName,
+	Flags:  convertFlags,
+	Action: doCommandConvert,
+	Usage: "To convert a local certificate, along with its private key and chain, between the PEM, PKCS#12, JKS " +
+		"and DER formats without connecting to the Venafi platform",
+	UsageText: ` vcert convert --file /path-to/cert.pem --format pkcs12 --out /path-to/cert.p12 --out-password <PKCS#12 password>
+		 vcert convert --file /path-to/cert.pem --key-file /path-to/key.pem --chain-file /path-to/chain.pem --format jks --jks-alias <alias> --out /path-to/keystore.jks --out-password <store password>
+		 vcert convert --file /path-to/keystore.jks --jks-alias <alias> --jks-password <store password> --key-password <key password> --format pem --out /path-to/cert.pem
+		 vcert convert --file /path-to/cert.p12 --key-password <PKCS#12 password> --format der --out /path-to/cert.der`,
+}
+
+type convertOptions struct {
+	file          string
+	keyFile       string
+	chainFile     string
+	format        string
+	out           string
+	outPassword   string
+	p12Encryption string
+}
+
+var (
+	convertOpts = convertOptions{}
+
+	flagConvertFile = &cli.StringFlag{
+		Name:        "file",
+		Usage:       "REQUIRED. The certificate file to convert, in PEM, DER, PKCS#12 or JKS format. Example: --file /path-to/cert.pem",
+		Destination: &convertOpts.file,
+		TakesFile:   true,
+	}
+
+	flagConvertKeyFile = &cli.StringFlag{
+		Name:        "key-file",
+		Usage:       "Use to specify the PEM file of the private key, when it is not stored in --file. Example: --key-file /path-to/key.pem",
+		Destination: &convertOpts.keyFile,
+		TakesFile:   true,
+	}
+
+	flagConvertChainFile = &cli.StringFlag{
+		Name:        "chain-file",
+		Usage:       "Use to specify the PEM file of the chain certificates, when they are not stored in --file. Example: --chain-file /path-to/chain.pem",
+		Destination: &convertOpts.chainFile,
+		TakesFile:   true,
+	}
+
+	flagConvertFormat = &cli.StringFlag{
+		Name: "format",
+		Usage: "Use to specify the format of the converted file. Options include: pem | pkcs12 | jks | der. " +
+			"PKCS#12 and JKS formats require a private key, and JKS format requires --jks-alias and --out-password. " +
+			"DER format holds the certificate alone.",
+		Destination: &convertOpts.format,
+		Value:       convertFormatPEM,
+	}
+
+	flagConvertOut = &cli.StringFlag{
+		Name:        "out",
+		Usage:       "REQUIRED. Use to specify the file where the converted certificate is written. Example: --out /path-to/cert.p12",
+		Destination: &convertOpts.out,
+		TakesFile:   true,
+	}
+
+	flagConvertOutPassword = &cli.StringFlag{
+		Name: "out-password",
+		Usage: "Use to specify the password protecting the private key in the converted file, and the store for PKCS#12 and JKS " +
+			"formats. Defaults to --key-password. The private key of PEM files is encrypted in PKCS#8 format when set. Example: --out-password file:/path-to/passwd.txt",
+		Destination: &convertOpts.outPassword,
+	}
+
+	flagConvertP12Encryption = &cli.StringFlag{
+		Name:        "p12-encryption",
+		Usage:       "Use to specify the encryption of PKCS#12 files. Options include: legacy | modern",
+		Destination: &convertOpts.p12Encryption,
+		Value:       domain.P12EncryptionLegacy,
+	}
+
+	convertFlags = sortedFlags(flagsApppend(
+		flagConvertFile,
+		flagConvertKeyFile,
+		flagConvertChainFile,
+		flagConvertFormat,
+		flagConvertOut,
+		flagConvertOutPassword,
+		flagConvertP12Encryption,
+		flagKeyPassword,
+		flagJKSAlias,
+		flagJKSPassword,
+		flagChainOption,
+		commonFlags,
+	))
+)
+
+func doCommandConvert(c *cli.Context) error {
+	if convertOpts.file == "" {
+		return fmt.Errorf("missing required flag --file")
+	}
+	if convertOpts.out == "" {
+		return fmt.Errorf("missing required flag --out")
+	}
+	format := strings.ToLower(convertOpts.format)
+	switch format {
+	case convertFormatPEM, Pkcs12, JKSFormat, DERFormat:
+	default:
+		return fmt.Errorf("unsupported format %q. Should be one of pem, pkcs12, jks or der", convertOpts.format)
+	}
+	encryption := strings.ToLower(convertOpts.p12Encryption)
+	if encryption != domain.P12EncryptionLegacy && encryption != domain.P12EncryptionModern {
+		return fmt.Errorf("unsupported --p12-encryption %q. Should be %s or %s", convertOpts.p12Encryption,
+			domain.P12EncryptionLegacy, domain.P12EncryptionModern)
+	}
+
+	keyPassword, err := readPasswordsFromInputFlag(flags.keyPassword, 0)
+	if err != nil {
+		return err
+	}
+	storePassword, err := readPasswordsFromInputFlag(flags.jksPassword, 0)
+	if err != nil {
+		return err
+	}
+	if storePassword == "" {
+		storePassword = keyPassword
+	}
+	outPassword, err := readPasswordsFromInputFlag(convertOpts.outPassword, 0)
+	if err != nil {
+		return err
+	}
+	if outPassword == "" {
+		outPassword = keyPassword
+	}
+	if format == JKSFormat {
+		if flags.jksAlias == "" {
+			return fmt.Errorf("--jks-alias is required for JKS format")
+		}
+		if outPassword == "" {
+			return fmt.Errorf("a password is required for JKS format. Use --out-password to specify it")
+		}
+	}
+
+	data, err := os.ReadFile(convertOpts.file)
+	if err != nil {
+		return fmt.Errorf("failed to read certificate file: %w", err)
+	}
+	lc, err := installer.LoadCertificate(data, flags.jksAlias, storePassword, keyPassword)
+	if err != nil {
+		return fmt.Errorf("failed to load certificate from %s: %w", convertOpts.file, err)
+	}
+	err = addConvertInputs(lc, convertOpts.keyFile, convertOpts.chainFile, keyPassword)
+	if err != nil {
+		return err
+	}
+
+	content, err := convertCertificate(lc, format, certificate.ChainOptionFromString(flags.chainOption), outPassword, flags.jksAlias, encryption)
+	if err != nil {
+		return err
+	}
+	err = os.WriteFile(convertOpts.out, content, 0600)
+	if err != nil {
+		return fmt.Errorf("failed to write converted certificate: %w", err)
+	}
+	logf("Successfully converted %s certificate %s to %s format in %s", lc.Format, convertOpts.file, format, convertOpts.out)
+	return nil
+}
+
+// addConvertInputs reads the private key and the chain certificates stored in their own PEM files into lc
+func addConvertInputs(lc *installer.LoadedCertificate, keyFile string, chainFile string, keyPassword string) error {
+	if keyFile != "" {
+		data, err := os.ReadFile(keyFile)
+		if err != nil {
+			return fmt.Errorf("failed to read private key file: %w", err)
+		}
+		lc.PrivateKey = nil
+		for block, rest := pem.Decode(data); block != nil && lc.PrivateKey == nil; block, rest = pem.Decode(rest) {
+			if strings.HasSuffix(block.Type, "PRIVATE KEY") {
+				lc.PrivateKey, err = parsePEMPrivateKey(block, keyPassword)
+				if err != nil {
+					return err
+				}
+			}
+		}
+		if lc.PrivateKey == nil {
+			return fmt.Errorf("no private key found in %s", keyFile)
+		}
+	}
+
+	if chainFile != "" {
+		data, err := os.ReadFile(chainFile)
+		if err != nil {
+			return fmt.Errorf("failed to read chain file: %w", err)
+		}
+		for block, rest := pem.Decode(data); block != nil; block, rest = pem.Decode(rest) {
+			if block.Type != "CERTIFICATE" {
+				continue
+			}
+			cert, err := x509.ParseCertificate(block.Bytes)
+			if err != nil {
+				return fmt.Errorf("could not parse chain certificate: %w", err)
+			}
+			lc.Chain = append(lc.Chain, cert)
+		}
+	}
+	return nil
+}
+
+// convertCertificate returns lc encoded in format, packaged the same way the playbook installers do. The chain is left
+// out when chainOption is ignore, and only PEM files honour the root-first order, as other formats define their own
+func convertCertificate(lc *installer.LoadedCertificate, format string, chainOption certificate.ChainOption, password string, jksAlias string, encryption string) ([]byte, error) {
+	pcc := certificate.PEMCollection{
+		Certificate: string(pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: lc.Certificate.Raw})),
+	}
+	if chainOption != certificate.ChainOptionIgnore {
+		for _, cert := range lc.Chain {
+			pcc.Chain = append(pcc.Chain, string(pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: cert.Raw})))
+		}
+		order := domain.ChainOrderRootLast
+		if format == convertFormatPEM && chainOption == certificate.ChainOptionRootFirst {
+			order = domain.ChainOrderRootFirst
+		}
+		pcc.Chain = installer.OrderChain(pcc.Certificate, pcc.Chain, order, false)
+	}
+	if lc.PrivateKey != nil {
+		der, err := x509.MarshalPKCS8PrivateKey(lc.PrivateKey)
+		if err != nil {
+			return nil, fmt.Errorf("could not marshal private key: %w", err)
+		}
+		pcc.PrivateKey = string(pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: der}))
+	}
+
+	if lc.PrivateKey == nil && (format == Pkcs12 || format == JKSFormat) {
+		return nil, fmt.Errorf("a private key is required for %s format. Use --key-file to specify it", strings.ToUpper(format))
+	}
+
+	output := &Output{Certificate: pcc.Certificate, Chain: pcc.Chain}
+	switch format {
+	case Pkcs12:
+		return installer.PackageAsPKCS12(pcc, password, encryption)
+	case JKSFormat:
+		return installer.PackageAsJKS(pcc, password, jksAlias, "")
+	case DERFormat:
+		return output.AsDER()
+	default:
+		if pcc.PrivateKey != "" {
+			var err error
+			output.PrivateKey, err = installer.MarshalPKCS8PrivateKey(pcc.PrivateKey, password)
+			if err != nil {
+				return nil, err
+			}
+		}
+		return output.Format(&Config{Format: convertFormatPEM, ChainOption: chainOption})
+	}
+}
@@ -0,0 +1,89 @@
+/*
+ * Copyright 2023 Venafi, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *  http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package main
+
+import (
+	"bytes"
+	"crypto/ecdsa"
+	"crypto/x509"
+	"encoding/pem"
+	"testing"
+	"time"
+
+	"github.com/Venafi/vcert/v5/pkg/certificate"
+	"github.com/Venafi/vcert/v5/pkg/playbook/app/domain"
+	"github.com/Venafi/vcert/v5/pkg/playbook/app/installer"
+)
+
+func TestConvertRoundTrip(t *testing.T) {
+	now := time.Now()
+	root, leaf := newTestChain(t, now.Add(-time.Hour), now.AddDate(0, 0, 90))
+	lc := &installer.LoadedCertificate{Format: "PEM", Certificate: leaf.Cert, Chain: []*x509.Certificate{root.Cert}, PrivateKey: leaf.Key}
+
+	for _, format := range []string{Pkcs12, JKSFormat, convertFormatPEM} {
+		data, err := convertCertificate(lc, format, certificate.ChainOptionRootLast, "secret!", "vcert", domain.P12EncryptionModern)
+		if err != nil {
+			t.Fatalf("could not convert to %s: %s", format, err)
+		}
+		converted, err := installer.LoadCertificate(data, "vcert", "secret!", "secret!")
+		if err != nil {
+			t.Fatalf("could not load %s conversion: %s", format, err)
+		}
+		if !converted.Certificate.Equal(leaf.Cert) || len(converted.Chain) != 1 || !converted.Chain[0].Equal(root.Cert) {
+			t.Fatalf("unexpected certificate or chain in %s conversion", format)
+		}
+		if !leaf.Key.(*ecdsa.PrivateKey).Equal(converted.PrivateKey) {
+			t.Fatalf("unexpected private key in %s conversion", format)
+		}
+	}
+
+	data, err := convertCertificate(lc, DERFormat, certificate.ChainOptionRootLast, "", "", domain.P12EncryptionLegacy)
+	if err != nil {
+		t.Fatalf("could not convert to DER: %s", err)
+	}
+	if !bytes.Equal(data, leaf.Cert.Raw) {
+		t.Fatal("expected the DER conversion to hold the certificate alone")
+	}
+}
+
+func TestConvertPEMChainOption(t *testing.T) {
+	now := time.Now()
+	root, leaf := newTestChain(t, now.Add(-time.Hour), now.AddDate(0, 0, 90))
+	lc := &installer.LoadedCertificate{Format: "DER", Certificate: leaf.Cert, Chain: []*x509.Certificate{root.Cert}}
+
+	data, err := convertCertificate(lc, convertFormatPEM, certificate.ChainOptionRootFirst, "", "", "")
+	if err != nil {
+		t.Fatalf("could not convert to PEM: %s", err)
+	}
+	first, rest := pem.Decode(data)
+	if first == nil || !bytes.Equal(first.Bytes, root.Cert.Raw) || !bytes.Contains(rest, pemCertificate(leaf.Cert)) {
+		t.Fatalf("expected the root certificate first, got:\n%s", data)
+	}
+
+	data, err = convertCertificate(lc, convertFormatPEM, certificate.ChainOptionIgnore, "", "", "")
+	if err != nil {
+		t.Fatalf("could not convert to PEM: %s", err)
+	}
+	if !bytes.Equal(data, pemCertificate(leaf.Cert)) {
+		t.Fatalf("expected the certificate alone, got:\n%s", data)
+	}
+
+	_, err = convertCertificate(lc, Pkcs12, certificate.ChainOptionRootLast, "secret!", "", "")
+	if err == nil {
+		t.Fatal("expected an error converting a certificate without private key to PKCS#12")
+	}
+}
@@ -14,6 +14,8 @@ const (
 	vcertClientID     = "VCERT_CLIENT_ID"
 	vcertClientSecret = "VCERT_CLIENT_SECRET" // #nosec G101
 	vcertDeviceURL    = "VCERT_DEVICE_URL"
+	vcertTokenURL     = "VCERT_TOKEN_URL"
+	vcertExternalJWT  = "VCERT_EXTERNAL_JWT"
 )
 
 type envVar struct {
@@ -80,6 +82,16 @@ var (
 			Destination: &flags.deviceURL,
 			FlagName:    "--device-url",
 		},
+		{
+			EnvVarName:  vcertTokenURL,
+			Destination: &flags.tokenURL,
+			FlagName:    "--token-url",
+		},
+		{
+			EnvVarName:  vcertExternalJWT,
+			Destination: &flags.externalJWT,
+			FlagName:    "--external-jwt",
+		},
 	}
 )
 
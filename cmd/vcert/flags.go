@@ -28,7 +28,10 @@ var (
 		Name: "platform",
 		Usage: "Use to specify the platform VCert will use to execute the given command. Only accepted values are:\n" +
 			"\t\tFor getcred command: --platform oidc\n" +
-			"\t\tFor enroll command: --platform firefly",
+			"\t\tFor enroll command: --platform firefly, --platform acme, --platform est, --platform scep\n" +
+			"\t\tFor pickup command: --platform firefly\n" +
+			"\t\tFor getpolicy command: --platform firefly\n" +
+			"\t\tFor renew command: --platform est",
 		Destination: &flags.platformString,
 	}
 
@@ -36,6 +39,9 @@ var (
 		Name: "url",
 		Usage: "REQUIRED/TPP/Firefly/OIDC. The URL of the service. \n\t\tTPP example: -u https://tpp.example.com" +
 			"\n\t\tFirefly example: -u https://firefly.example.com" +
+			"\n\t\tACME example: -u https://acme-v02.api.letsencrypt.org/directory (default for ACME)" +
+			"\n\t\tEST example: -u https://est.example.com (the /.well-known/est path is added when missing)" +
+			"\n\t\tSCEP example: -u http://scep.example.com/cgi-bin/pkiclient.exe" +
 			"\n\t\tOIDC example: -u https://my.okta.domain//oauth2/v1/token",
 		Destination: &flags.url,
 		Aliases:     []string{"u"},
@@ -62,9 +68,58 @@ var (
 		Destination: &flags.deviceURL,
 	}
 
+	flagACMEAccountKey = &cli.StringFlag{
+		Name:        "acme-account-key",
+		Usage:       "ACME. Path to the PEM private key of the ACME account. A new key is created there if the file does not exist. If omitted, a new account is registered on every request.",
+		Destination: &flags.acmeAccountKey,
+	}
+
+	flagACMEChallenge = &cli.StringFlag{
+		Name:        "acme-challenge",
+		Usage:       "ACME. The challenge type used to prove control of the requested names: http-01 (default) or dns-01.",
+		Destination: &flags.acmeChallenge,
+	}
+
+	flagACMEDNSCommand = &cli.StringFlag{
+		Name: "acme-dns-command",
+		Usage: "REQUIRED/ACME with dns-01 challenge. Command that creates and deletes the challenge TXT records. It is called with the arguments " +
+			"\"present\" or \"cleanup\", followed by the record FQDN and value. Example: --acme-dns-command /usr/local/bin/dns-hook.sh",
+		Destination: &flags.acmeDNSCommand,
+	}
+
+	flagACMEEmail = &cli.StringFlag{
+		Name:        "acme-email",
+		Usage:       "ACME. Contact email address registered with the ACME account.",
+		Destination: &flags.acmeEmail,
+	}
+
+	flagACMEHTTPAddress = &cli.StringFlag{
+		Name:        "acme-http-address",
+		Usage:       "ACME. Address the built-in server listens on to answer http-01 challenges. Defaults to :80",
+		Destination: &flags.acmeHTTPAddress,
+	}
+
+	flagACMEWebroot = &cli.StringFlag{
+		Name:        "acme-webroot",
+		Usage:       "ACME. Document root of an existing web server. http-01 challenge files are written to <webroot>/.well-known/acme-challenge instead of starting the built-in server.",
+		Destination: &flags.acmeWebroot,
+	}
+
+	flagEABKeyID = &cli.StringFlag{
+		Name:        "eab-kid",
+		Usage:       "ACME. Key identifier for External Account Binding, when required by the ACME server.",
+		Destination: &flags.eabKeyID,
+	}
+
+	flagEABHMACKey = &cli.StringFlag{
+		Name:        "eab-hmac",
+		Usage:       "ACME. Base64url encoded HMAC key for External Account Binding. Required with --eab-kid.",
+		Destination: &flags.eabHMACKey,
+	}
+
 	flagUser = &cli.StringFlag{
 		Name: "username",
-		Usage: "Use to specify the username of a Trust Protection Platform or the username of OAuth 2.0 password flow grant." +
+		Usage: "Use to specify the username of a Trust Protection Platform, the username of OAuth 2.0 password flow grant or the username for HTTP basic authentication with an EST server." +
 			"Required if -p12-file or -t is not present and may not be combined with either.",
 		Destination: &flags.userName,
 	}
@@ -84,7 +139,7 @@ var (
 
 	flagPassword = &cli.StringFlag{
 		Name:        "password",
-		Usage:       "Use to specify the Trust Protection Platform user's password or the optional password for the headless registration in VaaS or the password for OAuth 2.0 password flow grant.",
+		Usage:       "Use to specify the Trust Protection Platform user's password or the optional password for the headless registration in VaaS or the password for OAuth 2.0 password flow grant or the password for HTTP basic authentication with an EST server or the challenge password of a SCEP server.",
 		Destination: &flags.password,
 	}
 
@@ -108,13 +163,37 @@ var (
 		Usage:       "Use to specify a PEM file name to be used as trust anchors when communicating with the remote server.",
 		Destination: &flags.trustBundle,
 	}
+	flagProxy = &cli.StringFlag{
+		Name: "proxy",
+		Usage: "Use to specify the URL of the proxy the requests to the remote server are sent through, instead of the\n\t\t" +
+			"one of the HTTP_PROXY and HTTPS_PROXY environment variables. The http, https and socks5 schemes are supported.\n\t\t" +
+			"Example: --proxy socks5://jumphost.example.com:1080",
+		Destination: &flags.proxyURL,
+	}
+	flagProxyUser = &cli.StringFlag{
+		Name:        "proxy-user",
+		Usage:       "Use to specify the username to authenticate to the proxy.",
+		Destination: &flags.proxyUser,
+	}
+	flagProxyPassword = &cli.StringFlag{
+		Name:        "proxy-password",
+		Usage:       "Use to specify the password to authenticate to the proxy.",
+		Destination: &flags.proxyPassword,
+	}
+	flagNoProxy = &cli.StringFlag{
+		Name: "no-proxy",
+		Usage: "Use to specify a comma separated list of hosts reached without the proxy, with the syntax of the NO_PROXY\n\t\t" +
+			"environment variable. Example: --no-proxy localhost,.internal.example.com",
+		Destination: &flags.noProxy,
+	}
 	flagZone = &cli.StringFlag{
 		Name:        "zone",
 		Destination: &flags.zone,
 		Usage: "REQUIRED. The zone that defines the enrollment configuration. In Trust Protection Platform this is " +
 			"equivalent to the policy folder path where the certificate object will be placed. " + UtilityShortName +
 			" prepends \\VED\\Policy\\, so you only need to specify child folders under the root Policy folder. " +
-			"Example: -z Corp\\Engineering",
+			"Example: -z Corp\\Engineering. For EST, the optional label of the CA, when the server hosts more than one CA. " +
+			"For SCEP, the optional CA identifier.",
 		Aliases: []string{"z"},
 	}
 
@@ -126,15 +205,17 @@ var (
 	}
 
 	flagKeyCurve = &cli.StringFlag{
-		Name:        "key-curve",
-		Usage:       "Use to specify the ECDSA key curve. Options include: p256 | p384 | p521",
+		Name: "key-curve",
+		Usage: "Use to specify the ECDSA key curve. Options include: p256 (or secp256r1) | p384 (or secp384r1) | p521 (or secp521r1) | " +
+			"brainpoolP256r1 | brainpoolP384r1 | brainpoolP512r1 | ed25519 (same as --key-type ed25519)",
 		Destination: &flags.keyCurveString,
 		DefaultText: "p256",
 	}
 
 	flagKeyType = &cli.StringFlag{
-		Name:        "key-type",
-		Usage:       "Use to specify a key type. Options include: rsa | ecdsa",
+		Name: "key-type",
+		Usage: "Use to specify a key type. Options include: rsa | ecdsa | ed25519. The experimental ml-dsa-44 | ml-dsa-65 | ml-dsa-87 " +
+			"post-quantum key types require the VCERT_EXPERIMENTAL_PQ=true environment variable and a local generated CSR",
 		Destination: &flags.keyTypeString,
 		DefaultText: "rsa",
 	}
@@ -221,15 +302,23 @@ var (
 	flagUPNSans = &cli.StringSliceFlag{
 		Name: "san-upn",
 		Usage: "Use to specify a User Principal Name (UPN) Subject Alternative Name. " +
-			"This option can be repeated to specify more than one value like this: --san-upn me@abc.xyz --san-upn you@abc.xyz etc.",
-		Hidden: true,
+			"This option can be repeated to specify more than one value like this: --san-upn me@abc.xyz --san-upn you@abc.xyz etc. " +
+			"Required by Windows smart card logon certificates.",
+	}
+
+	flagRIDSans = &cli.StringSliceFlag{
+		Name: "san-rid",
+		Usage: "Use to specify a Registered ID (RID) Subject Alternative Name, an OID in dotted notation. Requires --csr local. " +
+			"This option can be repeated to specify more than one value like this: --san-rid 1.2.3.4 --san-rid 1.2.3.5 etc.",
 	}
 
 	flagFormat = &cli.StringFlag{
 		Name: "format",
-		Usage: "Use to specify the output format. Options include: pem | json | pkcs12 | jks." +
+		Usage: "Use to specify the output format. Options include: pem | json | pkcs12 | jks | der | pkcs7." +
 			" If PKCS#12 or JKS formats are specified, the --file parameter is required." +
-			" For JKS format, the --jks-alias parameter is required and a password must be provided (see --key-password and --jks-password).",
+			" For JKS format, the --jks-alias parameter is required and a password must be provided (see --key-password and --jks-password)." +
+			" If DER or PKCS#7 formats are specified, the --cert-file parameter is required: DER writes the certificate alone in binary form," +
+			" PKCS#7 writes a binary .p7b bundle of the certificate and its chain. The private key, if any, is written in PEM format to --key-file.",
 		Destination: &flags.format,
 		Value:       "pem",
 	}
@@ -297,6 +386,101 @@ var (
 		Value:       false,
 	}
 
+	flagLogFormat = &cli.StringFlag{
+		Name:        "log-format",
+		Usage:       "Use to specify the format of the log messages. Options include: console | json. Default is console",
+		Destination: &flags.logFormat,
+	}
+
+	flagLogLevel = &cli.StringFlag{
+		Name:        "log-level",
+		Usage:       "Use to specify the minimum level of the log messages. Options include: debug | info | warn | error. Default is info",
+		Destination: &flags.logLevel,
+	}
+
+	flagLogFile = &cli.StringFlag{
+		Name:        "log-file",
+		Usage:       "Use to write the log messages to a file instead of stderr. The file is rotated when it reaches 100 megabytes",
+		Destination: &flags.logFile,
+		TakesFile:   true,
+	}
+
+	flagAuditFile = &cli.StringFlag{
+		Name: "audit-file",
+		Usage: "Use to append a JSON line to the file for every certificate enrolled, renewed, revoked or installed and every " +
+			"after-install action run, with who ran it, when, where, its parameters and its result. The file is never truncated by vcert",
+		Destination: &flags.auditFile,
+		TakesFile:   true,
+	}
+
+	flagAuditSyslog = &cli.StringFlag{
+		Name: "audit-syslog",
+		Usage: "Use to send the audit events of --audit-file to syslog instead, or as well. Options include: local | udp://host:port | " +
+			"tcp://host:port. Not supported on Windows",
+		Destination: &flags.auditSyslog,
+	}
+
+	flagBatch = &cli.StringFlag{
+		Name: "batch",
+		Usage: "Use to request a certificate for every row of a CSV or JSON manifest, instead of a single certificate for --cn. " +
+			"Rows hold cn, zone, san-dns, san-ip, san-email, san-uri, san-upn, san-rid and file; the other enroll options apply to all rows. " +
+			"Example: --batch /path-to/manifest.csv",
+		Destination: &flags.batch,
+		TakesFile:   true,
+	}
+
+	flagBatchConcurrency = &cli.IntFlag{
+		Name:        "batch-concurrency",
+		Value:       8,
+		Usage:       "Use to specify how many certificates of --batch or --all-expiring are requested at the same time.",
+		Destination: &flags.batchConcurrency,
+	}
+
+	flagRenewAllExpiring = &cli.BoolFlag{
+		Name: "all-expiring",
+		Usage: "Use to renew every certificate of the zone that expires within --expires-within, instead of a single certificate " +
+			"identified by -id or -thumbprint. The renewals are requested without a CSR, so Trust Protection Platform generates " +
+			"the keys and provisions the certificates to their applications. Only supported by Trust Protection Platform.",
+		Destination: &flags.allExpiring,
+	}
+
+	flagRenewZone = &cli.StringFlag{
+		Name:        "zone",
+		Destination: &flags.zone,
+		Usage: "Use with --all-expiring to specify the policy folder whose certificates are renewed. " + UtilityShortName +
+			" prepends \\VED\\Policy\\, so you only need to specify child folders under the root Policy folder. Example: -z Corp\\Engineering",
+		Aliases: []string{"z"},
+	}
+
+	flagRenewExpiresWithin = &cli.StringFlag{
+		Name:        "expires-within",
+		Usage:       "Use to specify how soon the certificates renewed by --all-expiring expire, as a number of days or a duration. Example: --expires-within 14d",
+		Value:       "30d",
+		Destination: &flags.expiresWithin,
+	}
+
+	flagRenewRecursive = &cli.BoolFlag{
+		Name:        "recursive",
+		Usage:       "Use to include the certificates of the sub-folders of the zone in --all-expiring",
+		Destination: &flags.recursive,
+	}
+
+	flagBatchDir = &cli.StringFlag{
+		Name: "batch-dir",
+		Usage: "Use to specify the directory where the certificates of --batch are written, each to the file of its row, or to a " +
+			"file named after its common name when the row has none. Defaults to the current directory.",
+		Destination: &flags.batchDir,
+		TakesFile:   true,
+	}
+
+	flagBatchResult = &cli.StringFlag{
+		Name: "batch-result",
+		Usage: "Use to write the JSON results of --batch or --all-expiring, with the outcome of every certificate, to a file instead of the standard output. " +
+			"Example: --batch-result /path-to/results.json",
+		Destination: &flags.batchResult,
+		TakesFile:   true,
+	}
+
 	flagNoPrompt = &cli.BoolFlag{
 		Name: "no-prompt",
 		Usage: "Use to exclude credential and password prompts. If you enable the prompt and you enter incorrect information, " +
@@ -310,6 +494,29 @@ var (
 		Destination: &flags.noPickup,
 	}
 
+	flagVerifySCT = &cli.BoolFlag{
+		Name: "verify-sct",
+		Usage: "Use to verify the Signed Certificate Timestamps (SCTs) embedded in the retrieved certificate with the " +
+			"Certificate Transparency logs, and to include them in the JSON output.",
+		Destination: &flags.verifySCT,
+	}
+
+	flagRequireSCT = &cli.BoolFlag{
+		Name: "require-sct",
+		Usage: "Use to fail when the retrieved certificate is publicly trusted but has no valid SCT, i.e. it has not " +
+			"been logged in Certificate Transparency. Implies --verify-sct.",
+		Destination: &flags.requireSCT,
+	}
+
+	flagCTLogList = &cli.StringFlag{
+		Name: "ct-log-list",
+		Usage: "Use to specify the URL or file of the Certificate Transparency log list, in v3 JSON format, used to " +
+			"verify SCTs. Defaults to " + defaultCTLogList,
+		Destination: &flags.ctLogList,
+	}
+
+	sctFlags = []cli.Flag{flagVerifySCT, flagRequireSCT, flagCTLogList}
+
 	flagTestMode = &cli.BoolFlag{
 		Name: "test-mode",
 		Usage: "Use to test enrollment without a connection to a real endpoint." +
@@ -380,7 +587,7 @@ var (
 	flagConfig = &cli.StringFlag{
 		Name: "config",
 		Usage: "Use to specify INI configuration file containing connection details instead\n" +
-			"\t\tFor TPP: url, access_token, tpp_zone\n" +
+			"\t\tFor TPP: url, access_token, refresh_token, tpp_client_id, tpp_zone\n" +
 			"\t\tFor VaaS: cloud_apikey, cloud_zone\n" +
 			"\t\tTPP & VaaS: trust_bundle, test_mode",
 		Destination: &flags.config,
@@ -406,6 +613,20 @@ var (
 		Destination: &flags.clientP12PW,
 	}
 
+	flagClientCertFile = &cli.StringFlag{
+		Name:        "client-cert-file",
+		Usage:       "Use to specify a PEM client certificate for mutual TLS, as an alternative to --p12-file. Use in combination with --client-key-file option.",
+		Destination: &flags.clientCertFile,
+		TakesFile:   true,
+	}
+
+	flagClientKeyFile = &cli.StringFlag{
+		Name:        "client-key-file",
+		Usage:       "Use to specify the PEM private key of the client certificate set with --client-cert-file.",
+		Destination: &flags.clientKeyFile,
+		TakesFile:   true,
+	}
+
 	flagClientP12Deprecated = &cli.StringFlag{
 		Name:        "client-pkcs12",
 		Usage:       "Use p12-file",
@@ -437,6 +658,21 @@ var (
 		Destination: &flags.thumbprint,
 	}
 
+	flagPickupThumbprint = &cli.StringFlag{
+		Name: "thumbprint",
+		Usage: "Use to pick up the certificate with this SHA1 thumbprint, instead of by Pickup ID, i.e. to fetch again the chain or " +
+			"private key of a certificate issued earlier. Value may be specified as a string or read from the certificate file " +
+			"using the file: prefix. Not supported by Firefly, ACME, EST and SCEP.",
+		Destination: &flags.thumbprint,
+	}
+
+	flagPickupSerial = &cli.StringFlag{
+		Name: "serial",
+		Usage: "Use to pick up the certificate with this hexadecimal serial number, instead of by Pickup ID. Value may be specified " +
+			"as a string or read from the certificate file using the file: prefix. Not supported by Firefly, ACME, EST and SCEP.",
+		Destination: &flags.serialNumber,
+	}
+
 	flagInstance = &cli.StringSliceFlag{
 		Name:        "instance",
 		Usage:       "Use to provide the name/address of the compute instance and an identifier for the workload using the certificate. Example: --instance node:workload",
@@ -448,6 +684,13 @@ var (
 		Usage: "Use to specify the hostname, FQDN or IP address and TCP port where the certificate can be validated after issuance and installation. Example: --tls-address 10.20.30.40:443",
 	}
 
+	flagAppDN = &cli.StringSliceFlag{
+		Name: "app-dn",
+		Usage: "Use to associate the certificate with an existing Trust Protection Platform Application object, which TPP " +
+			"provisions the certificate to once it is issued. The DN may be relative to \\VED\\Policy. Repeat the flag for " +
+			"several applications. Example: --app-dn \"Servers\\web01\\Apache\"",
+	}
+
 	flagAppInfo = &cli.StringSliceFlag{
 		Name:        "app-info",
 		Usage:       "Use to identify the application requesting the certificate with details like vendor name, application name, and application version.",
@@ -474,6 +717,12 @@ var (
 		Destination: &flags.noRetire,
 	}
 
+	flagRetireBlocklist = &cli.BoolFlag{
+		Name:        "blocklist",
+		Usage:       "Add the retired certificate to the blocklist, so it is not discovered or imported again. Works only with Venafi as a Service",
+		Destination: &flags.retireBlocklist,
+	}
+
 	flagScope = &cli.StringFlag{
 		Name:        "scope",
 		Usage:       "Use to request specific scopes and restrictions.",
@@ -493,6 +742,27 @@ var (
 		Destination: &flags.pmCred,
 	}
 
+	flagRenewToken = &cli.BoolFlag{
+		Name: "renew-token",
+		Usage: "Use to renew the TPP or Firefly access token of the --config file when it is about to expire, and write the\n" +
+			"\t\tnew tokens back to it. The file is locked meanwhile, so concurrent vcert runs don't overwrite each other's tokens",
+		Destination: &flags.renewToken,
+	}
+
+	flagRenewBefore = &cli.DurationFlag{
+		Name:        "renew-before",
+		Usage:       "Use with --renew-token to specify how long before its expiration the access token is renewed, e.g. 30m",
+		Destination: &flags.renewBefore,
+		Value:       defaultTokenRenewBefore,
+	}
+
+	flagRenewInterval = &cli.DurationFlag{
+		Name: "renew-interval",
+		Usage: "Use with --renew-token to keep vcert running and check the tokens every interval, e.g. 5m, until SIGTERM or\n" +
+			"\t\tSIGINT is received. The tokens are checked once when not set",
+		Destination: &flags.renewInterval,
+	}
+
 	flagClientId = &cli.StringFlag{
 		Name:        "client-id",
 		Usage:       "Use to specify the application that will be using the token.",
@@ -506,6 +776,33 @@ var (
 		Destination: &flags.clientSecret,
 	}
 
+	flagTokenURL = &cli.StringFlag{
+		Name:        "token-url",
+		Usage:       "Use to specify the Venafi Control Plane URL where the service account JWT is exchanged for an access token.",
+		Destination: &flags.tokenURL,
+	}
+
+	flagExternalJWT = &cli.StringFlag{
+		Name: "external-jwt",
+		Usage: "Use to specify a JWT issued by an identity provider trusted by a Venafi Control Plane service account.\n" +
+			"\t Requires --token-url. Cannot be combined with --service-account-key.",
+		Destination: &flags.externalJWT,
+	}
+
+	flagServiceAccountID = &cli.StringFlag{
+		Name:        "service-account-id",
+		Usage:       "Use to specify the client ID of the Venafi Control Plane service account owning the --service-account-key.",
+		Destination: &flags.serviceAccountID,
+	}
+
+	flagServiceAccountKey = &cli.StringFlag{
+		Name: "service-account-key",
+		Usage: "Use to specify the path to the PEM private key of a Venafi Control Plane service account, used to sign\n" +
+			"\t the JWT exchanged for an access token. Requires --token-url and --service-account-id.",
+		Destination: &flags.serviceAccountKey,
+		TakesFile:   true,
+	}
+
 	flagAudience = &cli.StringFlag{
 		Name: "audience",
 		Usage: "Use to specify the audience param to get an access token for OAuth 2.0 identity providers\n" +
@@ -524,6 +821,13 @@ var (
 		Destination: &flags.omitSans,
 	}
 
+	flagReusePrivateKey = &cli.BoolFlag{
+		Name: "reuse-private-key",
+		Usage: "Use to renew the certificate with its existing private key, instead of generating a new one. The private key is read from the --key-file, " +
+			"or from the --file when it is stored along with the certificate, decrypted with the --key-password. Requires -csr local.",
+		Destination: &flags.reuseKey,
+	}
+
 	flagCSRFormat = &cli.StringFlag{
 		Name: "format",
 		Usage: "Generates the Certificate Signing Request in the specified format. Options include: pem | json\n" +
@@ -533,6 +837,15 @@ var (
 		Value:       "pem",
 	}
 
+	flagOutput = &cli.StringFlag{
+		Name: "output",
+		Usage: "Use to specify the format of the results written to the standard output. Options include: text | json | yaml.\n" +
+			"\tWith json or yaml, a single document is written, with the fields command, success and either result or error," +
+			" so scripts do not need to parse the text output. Logs are still written to the standard error. Default is text",
+		Destination: &flags.output,
+		Value:       TextOutput,
+	}
+
 	flagCredFormat = &cli.StringFlag{
 		Name:        "format",
 		Usage:       "Use to output credentials in an alternate format. Example: --format json",
@@ -556,11 +869,19 @@ var (
 		Destination: &flags.validPeriod,
 	}
 
+	flagNotAfter = &cli.StringFlag{
+		Name: "not-after",
+		Usage: "Specify the date the certificate needs to expire, as an RFC 3339 date and time or as a YYYY-MM-DD date, at\n" +
+			"\tmidnight UTC, if allowed by the CA and the policy. Example: --not-after 2025-12-31T18:00:00Z",
+		Destination: &flags.notAfter,
+	}
+
 	flagPolicyName = &cli.StringFlag{
 		Name: "zone",
 		Usage: "REQUIRED. Use to specify target zone for applying or retrieving certificate policy. " +
 			"In Trust Protection Platform this is the path (DN) of a policy folder and in Venafi as a Service " +
 			"this is the name of an Application and Issuing Template separated by a backslash. " +
+			"In Firefly this is the name of a policy, and the names of all the policies are listed by getpolicy when omitted. " +
 			"Example: -z Engineering\\Internal Certs",
 		Destination: &flags.policyName,
 		Aliases:     []string{"z"},
@@ -584,6 +905,39 @@ var (
 		Destination: &flags.verifyPolicyConfig,
 	}
 
+	flagPolicyTranslate = &cli.BoolFlag{
+		Name: "translate",
+		Usage: "Use to adapt the policy specification to the platform it is applied to, i.e. a policy specification retrieved from TPP\n\t" +
+			"can be applied to VaaS. Attributes are converted when the platform has an equivalent, and removed otherwise",
+		Destination: &flags.policyTranslate,
+	}
+
+	flagCreateApp = &cli.BoolFlag{
+		Name: "create-app",
+		Usage: "Use to create the VaaS application of the zone, or update it when it exists, and assign the issuing template of the zone to it.\n\t" +
+			"Without --file the issuing template must exist already, and no policy specification is applied",
+		Destination: &flags.createApp,
+	}
+
+	flagAppOwner = &cli.StringSliceFlag{
+		Name: "app-owner",
+		Usage: "Use with --create-app to specify the name of a user or team owning the application. Repeat it for several owners.\n\t" +
+			"Defaults to the current user for a new application. Example: --app-owner jdoe@example.com --app-owner \"Platform Team\"",
+	}
+
+	flagAppDescription = &cli.StringFlag{
+		Name:        "app-description",
+		Usage:       "Use with --create-app to specify the description of the application",
+		Destination: &flags.appDescription,
+	}
+
+	flagPolicyTranslateTo = &cli.StringFlag{
+		Name: "translate-to",
+		Usage: "Use to adapt the retrieved policy specification to another platform, so it can be applied to it with the setpolicy action.\n\t" +
+			"Options: tpp, vaas",
+		Destination: &flags.policyTranslateTo,
+	}
+
 	//SSH Certificate flags
 
 	flagKeyId = &cli.StringFlag{
@@ -602,9 +956,10 @@ var (
 			"This is applicable for client certificates and used for reporting/auditing only",
 	}
 	flagValidityHours = &cli.IntFlag{
-		Name:        "valid-hours",
-		Usage:       "How much time the requester wants to have the certificate valid, the format is hours. ",
-		Destination: &flags.sshCertValidHours,
+		Name: "valid-hours",
+		Usage: "How much time the requester wants to have the certificate valid, the format is hours.\n" +
+			"\tUse it to request short-lived certificates, where the CA and the policy allow them. Example: --valid-hours 12",
+		Destination: &flags.validHours,
 	}
 
 	flagSshCertCa = &cli.StringFlag{
@@ -661,12 +1016,28 @@ var (
 		Destination: &flags.sshCertGuid,
 	}
 
+	flagSshCertRenew = &cli.BoolFlag{
+		Name: "renew",
+		Usage: "Use to renew the SSH certificate specified by --pickup-id or --guid instead of requesting a new one. " +
+			"The --public-key option sets how the key pair of the renewed certificate is obtained",
+		Destination: &flags.sshCertRenew,
+	}
+
 	flagSshCertExtension = &cli.StringSliceFlag{
 		Name: "extension",
 		Usage: "The requested certificate extensions. For normal extensions use --extension <value> and " +
 			"for key value extensions use --extension <k:v>",
 	}
 
+	flagSshFormat = &cli.StringFlag{
+		Name: "format",
+		Usage: "Use to specify the format of the private key. Options include: pem | legacy-pem | openssh." +
+			" With openssh, the private key is written in the OpenSSH format, as ssh-keygen does, and the files are named" +
+			" after the key type, i.e. id_ed25519, id_ed25519.pub and id_ed25519-cert.pub, when --file is not set.",
+		Destination: &flags.format,
+		Value:       "pem",
+	}
+
 	flagSshCertWindows = &cli.BoolFlag{
 		Name:        "windows",
 		Usage:       "Use it to add end of lines in MS Windows format \\r\\n",
@@ -694,9 +1065,12 @@ var (
 		TakesFile:   true,
 	}
 
-	commonFlags              = []cli.Flag{flagInsecure, flagVerbose, flagNoPrompt}
+	logFlags                 = []cli.Flag{flagLogFormat, flagLogLevel, flagLogFile}
+	auditFlags               = []cli.Flag{flagAuditFile, flagAuditSyslog}
+	batchFlags               = []cli.Flag{flagBatch, flagBatchConcurrency, flagBatchDir, flagBatchResult}
+	commonFlags              = flagsApppend(flagInsecure, flagVerbose, flagNoPrompt, logFlags)
 	keyFlags                 = []cli.Flag{flagKeyType, flagKeySize, flagKeyCurve, flagKeyFile, flagKeyPassword}
-	sansFlags                = []cli.Flag{flagDNSSans, flagEmailSans, flagIPSans, flagURISans, flagUPNSans}
+	sansFlags                = []cli.Flag{flagDNSSans, flagEmailSans, flagIPSans, flagURISans, flagUPNSans, flagRIDSans}
 	subjectFlags             = flagsApppend(flagCommonName, flagCountry, flagState, flagLocality, flagOrg, flagOrgUnits)
 	sortableCredentialsFlags = []cli.Flag{
 		flagTestMode,
@@ -708,9 +1082,19 @@ var (
 		flagTPPPasswordDeprecated,
 		flagClientP12,
 		flagClientP12PW,
+		flagClientCertFile,
+		flagClientKeyFile,
 		flagClientP12Deprecated,
 		flagClientP12PWDeprecated,
 		flagTrustBundle,
+		flagProxy,
+		flagProxyUser,
+		flagProxyPassword,
+		flagNoProxy,
+		flagTokenURL,
+		flagExternalJWT,
+		flagServiceAccountID,
+		flagServiceAccountKey,
 	}
 
 	credentialsFlags = []cli.Flag{
@@ -720,6 +1104,17 @@ var (
 		delimiter(" "),
 	}
 
+	acmeFlags = flagsApppend(
+		flagACMEAccountKey,
+		flagACMEChallenge,
+		flagACMEDNSCommand,
+		flagACMEEmail,
+		flagACMEHTTPAddress,
+		flagACMEWebroot,
+		flagEABKeyID,
+		flagEABHMACKey,
+	)
+
 	genCsrFlags = sortedFlags(flagsApppend(
 		subjectFlags,
 		sansFlags,
@@ -728,6 +1123,7 @@ var (
 		flagNoPrompt,
 		flagVerbose,
 		flagCSRFormat,
+		logFlags,
 	))
 
 	enrollFlags = flagsApppend(
@@ -752,16 +1148,26 @@ var (
 			flagFriendlyName,
 			keyFlags,
 			flagNoPickup,
+			flagOutput,
 			flagPickupIDFile,
 			flagTimeout,
 			flagCustomField,
 			flagTlsAddress,
+			flagAppDN,
 			flagAppInfo,
 			flagInstance,
 			flagReplace,
 			flagOmitSans,
 			flagValidDays,
+			flagValidityHours,
 			flagValidPeriod,
+			flagNotAfter,
+			flagUser,
+			flagPassword,
+			acmeFlags,
+			sctFlags,
+			auditFlags,
+			batchFlags,
 		)),
 	)
 
@@ -778,9 +1184,14 @@ var (
 			flagJKSPassword,
 			flagKeyFile,
 			flagKeyPassword,
+			flagOutput,
 			flagPickupID,
 			flagPickupIDFile,
+			flagPickupSerial,
+			flagPickupThumbprint,
+			flagPlatform,
 			flagTimeout,
+			sctFlags,
 			commonFlags,
 		)),
 	)
@@ -789,15 +1200,18 @@ var (
 		credentialsFlags,
 		flagDistinguishedName,
 		sortedFlags(flagsApppend(
+			flagOutput,
 			flagRevocationNoRetire,
 			flagRevocationReason,
 			flagThumbprint,
 			commonFlags,
 			sortableCredentialsFlags,
+			auditFlags,
 		)),
 	)
 
 	renewFlags = flagsApppend(
+		flagPlatform,
 		flagDistinguishedName,
 		flagThumbprint,
 		credentialsFlags,
@@ -813,12 +1227,24 @@ var (
 			flagChainOption,
 			flagCSROption,
 			keyFlags,
+			flagOutput,
 			flagNoPickup,
 			flagTimeout,
 			commonFlags,
 			sortableCredentialsFlags,
 			flagPickupIDFile,
 			flagOmitSans,
+			flagReusePrivateKey,
+			flagUser,
+			flagPassword,
+			sctFlags,
+			auditFlags,
+			flagRenewZone,
+			flagRenewAllExpiring,
+			flagRenewExpiresWithin,
+			flagRenewRecursive,
+			flagBatchConcurrency,
+			flagBatchResult,
 		)),
 	)
 
@@ -827,19 +1253,24 @@ var (
 		flagThumbprint,
 		flagDistinguishedName,
 		sortedFlags(flagsApppend(
+			flagRetireBlocklist,
 			commonFlags,
 			sortableCredentialsFlags,
 		)),
 	)
 
-	commonCredFlags = []cli.Flag{flagConfig, flagProfile, flagUrl, flagToken, flagTrustBundle}
+	commonCredFlags = []cli.Flag{flagConfig, flagProfile, flagUrl, flagToken, flagTrustBundle, flagProxy, flagProxyUser,
+		flagProxyPassword, flagNoProxy}
 
 	getCredFlags = sortedFlags(flagsApppend(
 		flagPlatform,
 		commonCredFlags,
 		flagClientP12,
 		flagClientP12PW,
+		flagClientCertFile,
+		flagClientKeyFile,
 		flagCredFormat,
+		flagOutput,
 		flagEmail,
 		flagPassword,
 		flagUser,
@@ -850,6 +1281,9 @@ var (
 		flagClientSecret,
 		flagAudience,
 		flagDeviceURL,
+		flagRenewToken,
+		flagRenewBefore,
+		flagRenewInterval,
 		commonFlags,
 	))
 
@@ -872,19 +1306,34 @@ var (
 		flagPolicyName,
 		flagPolicyConfigFile,
 		flagPolicyVerifyConfigFile,
+		flagPolicyTranslate,
+		flagCreateApp,
+		flagAppOwner,
+		flagAppDescription,
 		flagTrustBundle,
+		flagProxy,
+		flagProxyUser,
+		flagProxyPassword,
+		flagNoProxy,
 		flagInsecure,
 	))
 
 	getPolicyFlags = sortedFlags(flagsApppend(
+		flagPlatform,
 		flagKey,
 		flagUrl,
 		flagToken,
 		flagVerbose,
+		flagOutput,
 		flagPolicyName,
 		flagPolicyConfigFile,
 		flagPolicyStarterConfigFile,
+		flagPolicyTranslateTo,
 		flagTrustBundle,
+		flagProxy,
+		flagProxyUser,
+		flagProxyPassword,
+		flagNoProxy,
 		flagInsecure,
 	))
 
@@ -892,17 +1341,26 @@ var (
 		flagUrl,
 		flagToken,
 		flagTrustBundle,
+		flagProxy,
+		flagProxyUser,
+		flagProxyPassword,
+		flagNoProxy,
 		flagSshCertPickupId,
 		flagSshCertGuid,
 		flagSshPassPhrase,
 		commonFlags,
 		flagSshCertWindows,
+		flagSshFormat,
 	))
 
 	sshEnrollFlags = sortedFlags(flagsApppend(
 		flagUrl,
 		flagToken,
 		flagTrustBundle,
+		flagProxy,
+		flagProxyUser,
+		flagProxyPassword,
+		flagNoProxy,
 		flagKeyId,
 		flagObjectName,
 		flagDestinationAddress,
@@ -918,13 +1376,33 @@ var (
 		flagSshPassPhrase,
 		flagSshCertWindows,
 		flagSshFileCertEnroll,
-		flagFormat,
+		flagSshCertPickupId,
+		flagSshCertGuid,
+		flagSshCertRenew,
+		flagSshFormat,
+		commonFlags,
+	))
+
+	sshRevokeFlags = sortedFlags(flagsApppend(
+		flagUrl,
+		flagToken,
+		flagTrustBundle,
+		flagProxy,
+		flagProxyUser,
+		flagProxyPassword,
+		flagNoProxy,
+		flagSshCertPickupId,
+		flagSshCertGuid,
 		commonFlags,
 	))
 
 	sshGetConfigFlags = sortedFlags(flagsApppend(
 		flagUrl,
 		flagTrustBundle,
+		flagProxy,
+		flagProxyUser,
+		flagProxyPassword,
+		flagNoProxy,
 		flagToken,
 		flagSshCertCa,
 		flagSshCertGuid,
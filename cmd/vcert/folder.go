@@ -0,0 +1,261 @@
+/*
+ * Copyright 2023 Venafi, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *  http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package main
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/urfave/cli/v2"
+
+	"github.com/Venafi/vcert/v5"
+	"github.com/Venafi/vcert/v5/pkg/endpoint"
+)
+
+const (
+	commandCreateFolderName     = "createfolder"
+	commandSetFolderAttrName    = "setfolderattr"
+	commandMoveCertificateName  = "movecert"
+	flagFolderAttributeName     = "attribute"
+	folderAttributeSeparator    = "="
+	folderCommandsPlatformError = "%s is only supported by Trust Protection Platform, not by %s"
+)
+
+var (
+	commandCreateFolder = &cli.Command{
+		Before: runBeforeCommand,
+		Name:   commandCreateFolderName,
+		Flags:  createFolderFlags,
+		Action: doCommandCreateFolder,
+		Usage:  "To create a policy folder in Trust Protection Platform, along with its missing parent folders",
+		UsageText: ` vcert createfolder -u https://tpp.example.com -t <TPP access token> -z "<policy folder DN>"
+		 vcert createfolder -u https://tpp.example.com -t <TPP access token> -z "Certificates\Team\Web"`,
+	}
+
+	commandSetFolderAttr = &cli.Command{
+		Before: runBeforeCommand,
+		Name:   commandSetFolderAttrName,
+		Flags:  setFolderAttrFlags,
+		Action: doCommandSetFolderAttr,
+		Usage:  "To set the certificate attributes of a policy folder in Trust Protection Platform",
+		UsageText: ` vcert setfolderattr -u https://tpp.example.com -t <TPP access token> -z "<policy folder DN>" --attribute "<attribute>=<value>"
+		 vcert setfolderattr -u https://tpp.example.com -t <TPP access token> -z "Certificates\Team" --attribute "Organization=Venafi" --locked
+		 vcert setfolderattr -u https://tpp.example.com -t <TPP access token> -z "Certificates\Team" --attribute "Organizational Unit=Web" --attribute "Organizational Unit=Ops"`,
+	}
+
+	commandMoveCertificate = &cli.Command{
+		Before: runBeforeCommand,
+		Name:   commandMoveCertificateName,
+		Flags:  moveCertificateFlags,
+		Action: doCommandMoveCertificate,
+		Usage:  "To move or rename a certificate object in Trust Protection Platform, keeping its history and associations",
+		UsageText: ` vcert movecert -u https://tpp.example.com -t <TPP access token> --id "<certificate DN>" --to "<policy folder DN>"
+		 vcert movecert -u https://tpp.example.com -t <TPP access token> --id "Certificates\www.example.com" --to "Certificates\Web\www.example.com"`,
+	}
+)
+
+type folderOptions struct {
+	folder        string
+	certificateDN string
+	locked        bool
+	to            string
+}
+
+var (
+	folderOpts = folderOptions{}
+
+	flagFolder = &cli.StringFlag{
+		Name:        "zone",
+		Usage:       "REQUIRED. Use to specify the DN of the policy folder. The \\VED\\Policy prefix is optional. Example: -z Certificates\\Team",
+		Destination: &folderOpts.folder,
+		Aliases:     []string{"z"},
+	}
+
+	flagFolderAttribute = &cli.StringSliceFlag{
+		Name: flagFolderAttributeName,
+		Usage: "REQUIRED. Use to specify a certificate attribute of the policy folder in format 'name=value'. If many values " +
+			"for the same attribute are required, use syntax '--attribute name=value1 --attribute name=value2'. Example: --attribute \"Organization=Venafi\"",
+	}
+
+	flagFolderLocked = &cli.BoolFlag{
+		Name:        "locked",
+		Usage:       "Use to lock the attributes set, so certificate requests cannot override them. Unlocked attributes are defaults",
+		Destination: &folderOpts.locked,
+	}
+
+	flagMoveID = &cli.StringFlag{
+		Name:        "id",
+		Usage:       "REQUIRED. Use to specify the DN of the certificate to move or rename. Example: --id Certificates\\www.example.com",
+		Destination: &folderOpts.certificateDN,
+	}
+
+	flagMoveTo = &cli.StringFlag{
+		Name: "to",
+		Usage: "REQUIRED. Use to specify the new DN of the certificate, or the policy folder to move it into keeping its name. " +
+			"Example: --to Certificates\\Web",
+		Destination: &folderOpts.to,
+	}
+
+	folderConnectionFlags = flagsApppend(
+		flagUrl,
+		flagToken,
+		flagConfig,
+		flagProfile,
+		flagTrustBundle,
+		flagProxy,
+		flagProxyUser,
+		flagProxyPassword,
+		flagNoProxy,
+		commonFlags,
+	)
+
+	createFolderFlags = sortedFlags(flagsApppend(
+		flagFolder,
+		folderConnectionFlags,
+	))
+
+	setFolderAttrFlags = sortedFlags(flagsApppend(
+		flagFolder,
+		flagFolderAttribute,
+		flagFolderLocked,
+		folderConnectionFlags,
+	))
+
+	moveCertificateFlags = sortedFlags(flagsApppend(
+		flagMoveID,
+		flagMoveTo,
+		folderConnectionFlags,
+	))
+)
+
+// folderManager is implemented by the connectors able to manage policy folders and the certificate objects in them
+type folderManager interface {
+	CreatePolicyFolder(name string) (string, error)
+	SetPolicyFolderAttribute(folder string, attribute string, values []string, locked bool) error
+	MoveCertificate(certificateDN string, newDN string) (string, error)
+}
+
+func doCommandCreateFolder(c *cli.Context) error {
+	if folderOpts.folder == "" {
+		return fmt.Errorf("missing required flag --zone")
+	}
+	manager, err := newFolderManager(c)
+	if err != nil {
+		return err
+	}
+	dn, err := manager.CreatePolicyFolder(folderOpts.folder)
+	if err != nil {
+		return fmt.Errorf("failed to create policy folder: %w", err)
+	}
+	logf("Successfully created policy folder %s", dn)
+	return nil
+}
+
+func doCommandSetFolderAttr(c *cli.Context) error {
+	if folderOpts.folder == "" {
+		return fmt.Errorf("missing required flag --zone")
+	}
+	names, values, err := parseFolderAttributes(c.StringSlice(flagFolderAttributeName))
+	if err != nil {
+		return err
+	}
+	manager, err := newFolderManager(c)
+	if err != nil {
+		return err
+	}
+	for _, name := range names {
+		err = manager.SetPolicyFolderAttribute(folderOpts.folder, name, values[name], folderOpts.locked)
+		if err != nil {
+			return err
+		}
+		logf("Successfully set attribute %q of policy folder %s", name, folderOpts.folder)
+	}
+	return nil
+}
+
+func doCommandMoveCertificate(c *cli.Context) error {
+	if folderOpts.certificateDN == "" {
+		return fmt.Errorf("missing required flag --id")
+	}
+	if folderOpts.to == "" {
+		return fmt.Errorf("missing required flag --to")
+	}
+	manager, err := newFolderManager(c)
+	if err != nil {
+		return err
+	}
+	dn, err := manager.MoveCertificate(folderOpts.certificateDN, folderOpts.to)
+	if err != nil {
+		return fmt.Errorf("failed to move certificate: %w", err)
+	}
+	logf("Successfully moved certificate %s to %s", folderOpts.certificateDN, dn)
+	return nil
+}
+
+// newFolderManager connects to the Trust Protection Platform set by the flags of command c
+func newFolderManager(c *cli.Context) (folderManager, error) {
+	if flags.config == "" && flags.token == "" && getPropertyFromEnvironment(vCertToken) == "" {
+		return nil, fmt.Errorf("an access token is required for communicating with Trust Protection Platform")
+	}
+	err := validateConnectionFlags(c.Command.Name)
+	if err != nil {
+		return nil, err
+	}
+	err = setTLSConfig()
+	if err != nil {
+		return nil, err
+	}
+
+	cfg, err := buildConfig(c, &flags)
+	if err != nil {
+		return nil, fmt.Errorf("failed to build vcert config: %s", err)
+	}
+	if cfg.ConnectorType != endpoint.ConnectorTypeTPP {
+		return nil, fmt.Errorf(folderCommandsPlatformError, c.Command.Name, cfg.ConnectorType)
+	}
+	connector, err := vcert.NewClient(&cfg)
+	if err != nil {
+		return nil, err
+	}
+	manager, ok := connector.(folderManager)
+	if !ok {
+		return nil, fmt.Errorf(folderCommandsPlatformError, c.Command.Name, connector.GetType())
+	}
+	return manager, nil
+}
+
+// parseFolderAttributes returns the names of the attributes set with --attribute, in the order they are first set,
+// and the values of every attribute
+func parseFolderAttributes(attributes []string) ([]string, map[string][]string, error) {
+	if len(attributes) == 0 {
+		return nil, nil, fmt.Errorf("missing required flag --%s", flagFolderAttributeName)
+	}
+	names := make([]string, 0)
+	values := make(map[string][]string)
+	for _, attribute := range attributes {
+		name, value, found := strings.Cut(attribute, folderAttributeSeparator)
+		name = strings.TrimSpace(name)
+		if !found || name == "" {
+			return nil, nil, fmt.Errorf("invalid attribute %q. Expected format is 'name=value'", attribute)
+		}
+		if _, ok := values[name]; !ok {
+			names = append(names, name)
+		}
+		values[name] = append(values[name], value)
+	}
+	return names, values, nil
+}
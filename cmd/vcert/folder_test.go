@@ -0,0 +1,48 @@
+/*
+ * Copyright 2023 Venafi, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *  http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package main
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestParseFolderAttributes(t *testing.T) {
+	names, values, err := parseFolderAttributes([]string{"Organizational Unit=Web", "Organization=Venafi",
+		"Organizational Unit=Ops", "Description=a=b"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(names, []string{"Organizational Unit", "Organization", "Description"}) {
+		t.Fatalf("unexpected attribute names %v", names)
+	}
+	expected := map[string][]string{
+		"Organizational Unit": {"Web", "Ops"},
+		"Organization":        {"Venafi"},
+		"Description":         {"a=b"},
+	}
+	if !reflect.DeepEqual(values, expected) {
+		t.Fatalf("unexpected attribute values %v", values)
+	}
+
+	for _, invalid := range [][]string{nil, {"Organization"}, {"=Venafi"}} {
+		_, _, err = parseFolderAttributes(invalid)
+		if err == nil {
+			t.Fatalf("expected an error parsing attributes %v", invalid)
+		}
+	}
+}
@@ -17,10 +17,15 @@
 package main
 
 import (
+	"bytes"
+	"crypto/x509"
+	"encoding/asn1"
 	"encoding/json"
+	"encoding/pem"
 	"fmt"
 	"io/ioutil"
 	t "log"
+	"net/url"
 	"os"
 	"testing"
 
@@ -42,6 +47,41 @@ func TestGenerateCsrForCommandGenCsr(t *testing.T) {
 	}
 }
 
+func TestGenerateCsrWithURIAndOtherSANs(t *testing.T) {
+	cf := getCommandFlags()
+	spiffeID, _ := url.Parse("spiffe://vfidev.com/ns/default/sa/vcert")
+	cf.uriSans = uriSlice{spiffeID}
+	cf.upnSans = rfc822NameSlice{"vcert@vfidev.com"}
+	cf.ridSans = []asn1.ObjectIdentifier{{1, 2, 3, 4}}
+
+	_, csrPEM, err := generateCsrForCommandGenCsr(cf, nil)
+	if err != nil {
+		t.Fatalf("%s", err)
+	}
+	block, _ := pem.Decode(csrPEM)
+	csr, err := x509.ParseCertificateRequest(block.Bytes)
+	if err != nil {
+		t.Fatalf("%s", err)
+	}
+	if len(csr.URIs) != 1 || csr.URIs[0].String() != spiffeID.String() {
+		t.Fatalf("expected URI SAN %s, got %v", spiffeID, csr.URIs)
+	}
+	for _, ext := range csr.Extensions {
+		if ext.Id.String() != "2.5.29.17" {
+			continue
+		}
+		if !bytes.Contains(ext.Value, []byte("vcert@vfidev.com")) {
+			t.Fatal("UPN SAN missing from CSR")
+		}
+		// registeredID [8] 1.2.3.4
+		if !bytes.Contains(ext.Value, []byte{0x88, 0x03, 0x2a, 0x03, 0x04}) {
+			t.Fatal("registeredID SAN missing from CSR")
+		}
+		return
+	}
+	t.Fatal("SAN extension missing from CSR")
+}
+
 func TestWriteOutKeyAndCsr(t *testing.T) {
 	cf := getCommandFlags()
 	key, csr, err := generateCsrForCommandGenCsr(cf, []byte("pass"))
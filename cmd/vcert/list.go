@@ -0,0 +1,282 @@
+/*
+ * Copyright 2023 Venafi, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *  http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/urfave/cli/v2"
+
+	"github.com/Venafi/vcert/v5"
+	"github.com/Venafi/vcert/v5/pkg/playbook/app/domain"
+	"github.com/Venafi/vcert/v5/pkg/venafi/tpp"
+)
+
+const commandListName = "list"
+
+var commandList = &cli.Command{
+	Before: runBeforeCommand,
+	Name:   commandListName,
+	Flags:  listFlags,
+	Action: doCommandList,
+	Usage: "To search the certificates of Trust Protection Platform by common name, DNS name, thumbprint, policy folder " +
+		"or expiration date, one page at a time",
+	UsageText: ` vcert list -u https://tpp.example.com -t <TPP access token> -z "<policy folder DN>" --recursive
+		 vcert list -u https://tpp.example.com -t <TPP access token> --cn www.example.com
+		 vcert list -u https://tpp.example.com -t <TPP access token> -z "<policy folder DN>" --expires-within 30d --all --format json`,
+}
+
+type listOptions struct {
+	cn            string
+	san           string
+	thumbprint    string
+	recursive     bool
+	expiresWithin string
+	limit         int
+	offset        int
+	all           bool
+	format        string
+}
+
+var (
+	listOpts = listOptions{}
+
+	flagListZone = &cli.StringFlag{
+		Name:        "zone",
+		Destination: &flags.zone,
+		Usage: "Use to only list the certificates of a policy folder. " + UtilityShortName + " prepends \\VED\\Policy\\, " +
+			"so you only need to specify child folders under the root Policy folder. Example: -z Corp\\Engineering",
+		Aliases: []string{"z"},
+	}
+
+	flagListCN = &cli.StringFlag{
+		Name:        "cn",
+		Usage:       "Use to only list the certificates with this common name",
+		Destination: &listOpts.cn,
+	}
+
+	flagListSAN = &cli.StringFlag{
+		Name:        "san",
+		Usage:       "Use to only list the certificates with this DNS subject alternative name",
+		Destination: &listOpts.san,
+	}
+
+	flagListThumbprint = &cli.StringFlag{
+		Name:        "thumbprint",
+		Usage:       "Use to only list the certificate with this SHA-1 thumbprint",
+		Destination: &listOpts.thumbprint,
+	}
+
+	flagListRecursive = &cli.BoolFlag{
+		Name:        "recursive",
+		Usage:       "Use to include the certificates of the sub-folders of the zone",
+		Destination: &listOpts.recursive,
+	}
+
+	flagListExpiresWithin = &cli.StringFlag{
+		Name: "expires-within",
+		Usage: "Use to only list the certificates that are still valid and expire within this period, as a number of " +
+			"days or a duration. Example: --expires-within 30d",
+		Destination: &listOpts.expiresWithin,
+	}
+
+	flagListLimit = &cli.IntFlag{
+		Name:        "limit",
+		Usage:       "Use to specify the number of certificates per page",
+		Destination: &listOpts.limit,
+		Value:       tpp.DefaultSearchLimit,
+	}
+
+	flagListOffset = &cli.IntFlag{
+		Name:        "offset",
+		Usage:       "Use to specify the number of matching certificates to skip, to get the next pages",
+		Destination: &listOpts.offset,
+	}
+
+	flagListAll = &cli.BoolFlag{
+		Name:        "all",
+		Usage:       "Use to list every matching certificate instead of a single page",
+		Destination: &listOpts.all,
+	}
+
+	flagListFormat = &cli.StringFlag{
+		Name:        "format",
+		Usage:       "Use to specify the output format. Options include: text | json",
+		Destination: &listOpts.format,
+		Value:       checkCertFormatText,
+	}
+
+	listFlags = sortedFlags(flagsApppend(
+		flagListZone,
+		flagListCN,
+		flagListSAN,
+		flagListThumbprint,
+		flagListRecursive,
+		flagListExpiresWithin,
+		flagListLimit,
+		flagListOffset,
+		flagListAll,
+		flagListFormat,
+		flagToken,
+		flagUrl,
+		flagConfig,
+		flagProfile,
+		flagTestMode,
+		flagTrustBundle,
+		commonFlags,
+	))
+)
+
+// listReport is the page of certificates written by the list command
+type listReport struct {
+	Total        int                 `json:"total"`
+	Offset       int                 `json:"offset"`
+	Certificates []listedCertificate `json:"certificates"`
+}
+
+type listedCertificate struct {
+	DN         string    `json:"dn"`
+	CN         string    `json:"cn"`
+	DNSNames   []string  `json:"dnsNames,omitempty"`
+	Serial     string    `json:"serial"`
+	Thumbprint string    `json:"thumbprint"`
+	ValidFrom  time.Time `json:"validFrom"`
+	ValidTo    time.Time `json:"validTo"`
+}
+
+func doCommandList(c *cli.Context) error {
+	format := strings.ToLower(listOpts.format)
+	if format != checkCertFormatText && format != checkCertFormatJSON {
+		return fmt.Errorf("unsupported format %q. Should be %s or %s", listOpts.format, checkCertFormatText, checkCertFormatJSON)
+	}
+	if listOpts.limit <= 0 || listOpts.offset < 0 {
+		return fmt.Errorf("--limit should be greater than 0 and --offset should not be negative")
+	}
+	if listOpts.recursive && flags.zone == "" {
+		return fmt.Errorf("--recursive requires --zone")
+	}
+	filter, err := buildListFilter(listOpts, flags.zone, time.Now())
+	if err != nil {
+		return err
+	}
+
+	err = setTLSConfig()
+	if err != nil {
+		return err
+	}
+	cfg, err := buildConfig(c, &flags)
+	if err != nil {
+		return fmt.Errorf("failed to build vcert config: %s", err)
+	}
+	connector, err := vcert.NewClient(&cfg)
+	if err != nil {
+		return err
+	}
+	tppConnector, ok := connector.(*tpp.Connector)
+	if !ok {
+		return fmt.Errorf("the %s command is only supported by Trust Protection Platform", commandListName)
+	}
+
+	report := listReport{Offset: filter.Offset}
+	var found []tpp.CertificateSearchInfo
+	if listOpts.all {
+		found, err = tppConnector.FindAllCertificates(c.Context, filter)
+		report.Total = filter.Offset + len(found)
+	} else {
+		var page *tpp.CertificateSearchResponse
+		page, err = tppConnector.FindCertificates(c.Context, filter)
+		if page != nil {
+			found, report.Total = page.Certificates, page.Count
+		}
+	}
+	if err != nil {
+		return fmt.Errorf("failed to search certificates: %w", err)
+	}
+	report.Certificates = make([]listedCertificate, 0, len(found))
+	for _, info := range found {
+		report.Certificates = append(report.Certificates, listedCertificate{
+			DN:         info.DN,
+			CN:         info.X509.CN,
+			DNSNames:   info.X509.SANS.DNS,
+			Serial:     info.X509.Serial,
+			Thumbprint: info.X509.Thumbprint,
+			ValidFrom:  info.X509.ValidFrom,
+			ValidTo:    info.X509.ValidTo,
+		})
+	}
+
+	if format == checkCertFormatJSON {
+		return writeListJSON(os.Stdout, report)
+	}
+	return writeListText(os.Stdout, report)
+}
+
+// buildListFilter returns the search filter for opts, relative to the time now
+func buildListFilter(opts listOptions, zone string, now time.Time) (tpp.CertificateFilter, error) {
+	filter := tpp.CertificateFilter{
+		CN:         opts.cn,
+		SAN:        opts.san,
+		Thumbprint: opts.thumbprint,
+		ParentDN:   zone,
+		Recursive:  opts.recursive,
+		Limit:      opts.limit,
+		Offset:     opts.offset,
+	}
+	if opts.expiresWithin != "" {
+		window, err := domain.ParseRenewBefore(opts.expiresWithin)
+		if err != nil || window.Duration <= 0 {
+			return filter, fmt.Errorf("invalid --expires-within %q. Should be a number of days or a duration (i.e. '30d')", opts.expiresWithin)
+		}
+		filter.ExpiresAfter = now
+		filter.ExpiresBefore = now.Add(window.Duration)
+	}
+	return filter, nil
+}
+
+func writeListJSON(out io.Writer, report listReport) error {
+	enc := json.NewEncoder(out)
+	enc.SetIndent("", "  ")
+	return enc.Encode(report)
+}
+
+func writeListText(out io.Writer, report listReport) error {
+	w := tabwriter.NewWriter(out, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "DN\tCOMMON NAME\tSERIAL\tTHUMBPRINT\tVALID TO")
+	for _, cert := range report.Certificates {
+		fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\n", cert.DN, cert.CN, cert.Serial, cert.Thumbprint, cert.ValidTo.Format(time.RFC3339))
+	}
+	if err := w.Flush(); err != nil {
+		return err
+	}
+
+	if len(report.Certificates) == 0 {
+		_, err := fmt.Fprintf(out, "\nNo certificates found (%d in total)\n", report.Total)
+		return err
+	}
+	last := report.Offset + len(report.Certificates)
+	_, err := fmt.Fprintf(out, "\nShowing %d-%d of %d certificates\n", report.Offset+1, last, report.Total)
+	if err == nil && last < report.Total {
+		_, err = fmt.Fprintf(out, "Use --offset %d to get the next page\n", last)
+	}
+	return err
+}
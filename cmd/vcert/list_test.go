@@ -0,0 +1,78 @@
+/*
+ * Copyright 2023 Venafi, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *  http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package main
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestBuildListFilter(t *testing.T) {
+	now := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	opts := listOptions{cn: "www.example.com", recursive: true, expiresWithin: "30d", limit: 50, offset: 100}
+
+	filter, err := buildListFilter(opts, "Certificates\\Web", now)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if filter.CN != "www.example.com" || filter.ParentDN != "Certificates\\Web" || !filter.Recursive {
+		t.Fatalf("unexpected filter %+v", filter)
+	}
+	if filter.Limit != 50 || filter.Offset != 100 {
+		t.Fatalf("unexpected paging %d/%d", filter.Limit, filter.Offset)
+	}
+	if !filter.ExpiresAfter.Equal(now) || !filter.ExpiresBefore.Equal(now.AddDate(0, 0, 30)) {
+		t.Fatalf("unexpected expiry window %s - %s", filter.ExpiresAfter, filter.ExpiresBefore)
+	}
+
+	for _, invalid := range []string{"10%", "0", "soon"} {
+		opts.expiresWithin = invalid
+		if _, err = buildListFilter(opts, "", now); err == nil {
+			t.Errorf("expected an error for --expires-within %q", invalid)
+		}
+	}
+}
+
+func TestWriteListText(t *testing.T) {
+	report := listReport{
+		Total:  3,
+		Offset: 0,
+		Certificates: []listedCertificate{
+			{DN: "\\VED\\Policy\\Web\\www.example.com", CN: "www.example.com", Serial: "01", Thumbprint: "AB"},
+			{DN: "\\VED\\Policy\\Web\\api.example.com", CN: "api.example.com", Serial: "02", Thumbprint: "CD"},
+		},
+	}
+	out := bytes.Buffer{}
+	if err := writeListText(&out, report); err != nil {
+		t.Fatal(err)
+	}
+	for _, expected := range []string{"api.example.com", "Showing 1-2 of 3 certificates", "Use --offset 2"} {
+		if !strings.Contains(out.String(), expected) {
+			t.Errorf("expected %q in output:\n%s", expected, out.String())
+		}
+	}
+
+	out.Reset()
+	if err := writeListText(&out, listReport{}); err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(out.String(), "No certificates found") {
+		t.Errorf("unexpected output:\n%s", out.String())
+	}
+}
@@ -80,12 +80,23 @@ func main() {
 			commandRenew,
 			commandRevoke,
 			commandRetire,
+			commandCheckCert,
+			commandConvert,
+			commandScan,
+			commandList,
+			commandProvision,
 			commandCreatePolicy,
 			commandGetPolicy,
+			commandCreateFolder,
+			commandSetFolderAttr,
+			commandMoveCertificate,
 			commandSshPickup,
 			commandSshEnroll,
+			commandSshRevoke,
 			commandSshGetConfig,
 			commandRunPlaybook,
+			commandService,
+			commandEncryptSecret,
 		},
 		EnableBashCompletion: true, //todo: write BashComplete function for options
 		Authors:              authors,
@@ -113,6 +124,7 @@ ACTIONS:
    retire       To retire a certificate
    revoke       To revoke a certificate
    run          To retrieve and install certificates using a vcert playbook file
+   service      To run a vcert playbook file as a systemd unit or a Windows service
 
    getpolicy    To retrieve the certificate policy of a zone
    setpolicy    To apply a certificate policy specification to a zone
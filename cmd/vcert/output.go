@@ -0,0 +1,225 @@
+/*
+ * Copyright 2023 Venafi, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *  http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package main
+
+import (
+	"crypto/x509"
+	"encoding/json"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/urfave/cli/v2"
+	"gopkg.in/yaml.v2"
+
+	"github.com/Venafi/vcert/v5/pkg/certificate"
+	"github.com/Venafi/vcert/v5/pkg/policy"
+)
+
+// Values of --output
+const (
+	TextOutput = "text"
+	JSONOutput = "json"
+	YAMLOutput = "yaml"
+)
+
+// commandOutput is the document written to the standard output when --output is json or yaml. Result holds the
+// outcome of the command, and Error is set when it failed, along with the partial result of the commands handling
+// many certificates. Fields may be added to these documents, but
+// existing ones are never renamed or removed
+type commandOutput struct {
+	Command string        `json:"command"`
+	Success bool          `json:"success"`
+	Result  interface{}   `json:"result,omitempty"`
+	Error   *commandError `json:"error,omitempty"`
+}
+
+// commandError is the machine-readable form of the error a command failed with
+type commandError struct {
+	Message string `json:"message"`
+}
+
+// partialResultError is returned by the commands that fail after producing a result, i.e. the batches with failed
+// rows, so the result is still part of the error document
+type partialResultError struct {
+	error
+	result interface{}
+}
+
+// certificateResult is the result of enroll, pickup and renew. The certificate, chain, private key and CSR are only
+// included when they are not written to a file
+type certificateResult struct {
+	PickupID     string                                   `json:"pickupId,omitempty"`
+	SerialNumber string                                   `json:"serialNumber,omitempty"`
+	NotAfter     *time.Time                               `json:"notAfter,omitempty"`
+	Certificate  string                                   `json:"certificate,omitempty"`
+	Chain        []string                                 `json:"chain,omitempty"`
+	PrivateKey   string                                   `json:"privateKey,omitempty"`
+	CSR          string                                   `json:"csr,omitempty"`
+	SCTs         []certificate.SignedCertificateTimestamp `json:"scts,omitempty"`
+}
+
+// revokeResult is the result of revoke
+type revokeResult struct {
+	CertificateDN string `json:"certificateDn,omitempty"`
+	Thumbprint    string `json:"thumbprint,omitempty"`
+	Reason        string `json:"reason,omitempty"`
+	Retired       bool   `json:"retired"`
+}
+
+// credentialResult is the result of getcred. Only the fields returned by the platform are set
+type credentialResult struct {
+	AccessToken        string `json:"accessToken,omitempty"`
+	AccessTokenExpires string `json:"accessTokenExpires,omitempty"`
+	RefreshToken       string `json:"refreshToken,omitempty"`
+	RefreshUntil       string `json:"refreshUntil,omitempty"`
+	TokenType          string `json:"tokenType,omitempty"`
+	APIKey             string `json:"apiKey,omitempty"`
+	APIKeyExpires      string `json:"apiKeyExpires,omitempty"`
+}
+
+// policyResult is the result of getpolicy: the policy of a zone, the names of the policies when no zone is given,
+// or the file the policy was written to
+type policyResult struct {
+	Zone     string                      `json:"zone,omitempty"`
+	Policy   *policy.PolicySpecification `json:"policy,omitempty"`
+	Policies []string                    `json:"policies,omitempty"`
+	File     string                      `json:"file,omitempty"`
+}
+
+// isStructuredOutput returns true when --output asks for a JSON or YAML document instead of text
+func isStructuredOutput() bool {
+	return flags.output == JSONOutput || flags.output == YAMLOutput
+}
+
+func validateOutputFlag() error {
+	switch flags.output {
+	case "", TextOutput, JSONOutput, YAMLOutput:
+		return nil
+	default:
+		return fmt.Errorf("unexpected output %q. Options include: %s | %s | %s", flags.output, TextOutput, JSONOutput, YAMLOutput)
+	}
+}
+
+// withCommandOutput wraps the action of a command supporting --output, so a failure is also reported as a
+// machine-readable document on the standard output when --output is json or yaml
+func withCommandOutput(action cli.ActionFunc) cli.ActionFunc {
+	return func(c *cli.Context) error {
+		err := validateOutputFlag()
+		if err != nil {
+			return err
+		}
+		err = action(c)
+		if err != nil && isStructuredOutput() {
+			var result interface{}
+			var partial *partialResultError
+			if errors.As(err, &partial) {
+				result = partial.result
+			}
+			writeErr := writeCommandOutput(os.Stdout, flags.output, c.Command.Name, result, err)
+			if writeErr != nil {
+				logf("failed to write the error document: %s", writeErr)
+			}
+		}
+		return err
+	}
+}
+
+// writeCommandResult writes the result of a successful command to the standard output, in the format of --output
+func writeCommandResult(command string, result interface{}) error {
+	return writeCommandOutput(os.Stdout, flags.output, command, result, nil)
+}
+
+// writeCommandOutput writes the document of command to w, in the given format. YAML documents are converted from
+// the JSON ones, so both formats share the same field names
+func writeCommandOutput(w io.Writer, format string, command string, result interface{}, cmdErr error) error {
+	doc := commandOutput{Command: command, Success: cmdErr == nil, Result: result}
+	if cmdErr != nil {
+		doc.Error = &commandError{Message: cmdErr.Error()}
+	}
+
+	data, err := json.MarshalIndent(doc, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to construct the %s output: %w", format, err)
+	}
+	if format == YAMLOutput {
+		// MapSlice keeps the fields in the order of the JSON document
+		var fields yaml.MapSlice
+		err = yaml.Unmarshal(data, &fields)
+		if err == nil {
+			data, err = yaml.Marshal(fields)
+		}
+		if err != nil {
+			return fmt.Errorf("failed to construct the %s output: %w", format, err)
+		}
+	} else {
+		data = append(data, '\n')
+	}
+	_, err = w.Write(data)
+	return err
+}
+
+// writeSummaryResult writes the summary of --batch or --all-expiring as the result of command, or as the partial
+// result of the error document when failure is not nil
+func writeSummaryResult(command string, summary interface{}, failure error) error {
+	if failure != nil {
+		return &partialResultError{error: failure, result: summary}
+	}
+	return writeCommandResult(command, summary)
+}
+
+// tppCredentialResult returns the result of getcred for the tokens issued by TPP, whose expirations are Unix times
+func tppCredentialResult(accessToken string, expires int, refreshToken string, refreshUntil int) credentialResult {
+	result := credentialResult{
+		AccessToken:        accessToken,
+		AccessTokenExpires: time.Unix(int64(expires), 0).UTC().Format(time.RFC3339),
+		RefreshToken:       refreshToken,
+	}
+	if refreshToken != "" {
+		result.RefreshUntil = time.Unix(int64(refreshUntil), 0).UTC().Format(time.RFC3339)
+	}
+	return result
+}
+
+// newCertificateResult returns the result of enroll, pickup and renew for the outputs not written to a file.
+// The serial number and expiration come from certPEM, which is the issued certificate even when it is written to a file
+func newCertificateResult(certPEM string, stdOut *Output) certificateResult {
+	result := certificateResult{
+		PickupID:    stdOut.PickupId,
+		Certificate: strings.TrimSpace(stdOut.Certificate),
+		PrivateKey:  strings.TrimSpace(stdOut.PrivateKey),
+		CSR:         strings.TrimSpace(stdOut.CSR),
+		SCTs:        stdOut.SCTs,
+	}
+	for _, chainCert := range stdOut.Chain {
+		result.Chain = append(result.Chain, strings.TrimSpace(chainCert))
+	}
+
+	block, _ := pem.Decode([]byte(certPEM))
+	if block != nil {
+		cert, err := x509.ParseCertificate(block.Bytes)
+		if err == nil {
+			result.SerialNumber = fmt.Sprintf("%x", cert.SerialNumber)
+			result.NotAfter = &cert.NotAfter
+		}
+	}
+	return result
+}
@@ -0,0 +1,165 @@
+/*
+ * Copyright 2023 Venafi, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *  http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package main
+
+import (
+	"bytes"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/json"
+	"encoding/pem"
+	"fmt"
+	"math/big"
+	"strings"
+	"testing"
+	"time"
+
+	"gopkg.in/yaml.v2"
+)
+
+func TestWriteCommandOutput(t *testing.T) {
+	result := revokeResult{CertificateDN: "\\VED\\Policy\\Web\\www.example.com", Reason: "key-compromise", Retired: true}
+
+	var buf bytes.Buffer
+	err := writeCommandOutput(&buf, JSONOutput, commandRevokeName, result, nil)
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	var doc map[string]interface{}
+	err = json.Unmarshal(buf.Bytes(), &doc)
+	if err != nil {
+		t.Fatalf("output is not a JSON document: %s", err)
+	}
+	if doc["command"] != commandRevokeName || doc["success"] != true || doc["error"] != nil {
+		t.Fatalf("unexpected document %v", doc)
+	}
+	if doc["result"].(map[string]interface{})["certificateDn"] != result.CertificateDN {
+		t.Fatalf("unexpected result %v", doc["result"])
+	}
+
+	buf.Reset()
+	err = writeCommandOutput(&buf, YAMLOutput, commandRevokeName, result, nil)
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	// The YAML document shares the field names of the JSON one, in the same order
+	expected := "command: revoke\nsuccess: true\nresult:\n  certificateDn: \\VED\\Policy\\Web\\www.example.com\n" +
+		"  reason: key-compromise\n  retired: true\n"
+	if buf.String() != expected {
+		t.Fatalf("unexpected YAML document:\n%s", buf.String())
+	}
+}
+
+func TestWriteCommandOutputError(t *testing.T) {
+	cases := map[string]struct {
+		err            error
+		expectedResult bool
+	}{
+		"Error": {
+			err: fmt.Errorf("Failed to revoke certificate: not found"),
+		},
+		"PartialResult": {
+			err:            &partialResultError{error: fmt.Errorf("1 of 2 certificates failed to enroll"), result: batchSummary{Total: 2}},
+			expectedResult: true,
+		},
+	}
+	for name, c := range cases {
+		t.Run(name, func(t *testing.T) {
+			var result interface{}
+			if partial, ok := c.err.(*partialResultError); ok {
+				result = partial.result
+			}
+			var buf bytes.Buffer
+			err := writeCommandOutput(&buf, YAMLOutput, commandEnrollName, result, c.err)
+			if err != nil {
+				t.Fatalf("unexpected error: %s", err)
+			}
+			var doc map[string]interface{}
+			err = yaml.Unmarshal(buf.Bytes(), &doc)
+			if err != nil {
+				t.Fatalf("output is not a YAML document: %s", err)
+			}
+			if doc["success"] != false {
+				t.Fatalf("expected success to be false, got %v", doc["success"])
+			}
+			if doc["error"].(map[interface{}]interface{})["message"] != c.err.Error() {
+				t.Fatalf("unexpected error object %v", doc["error"])
+			}
+			if _, ok := doc["result"]; ok != c.expectedResult {
+				t.Fatalf("unexpected result %v", doc["result"])
+			}
+		})
+	}
+}
+
+func TestValidateOutputFlag(t *testing.T) {
+	cases := map[string]struct {
+		output      string
+		expectedErr bool
+	}{
+		"Default": {output: ""},
+		"Text":    {output: TextOutput},
+		"JSON":    {output: JSONOutput},
+		"YAML":    {output: YAMLOutput},
+		"Invalid": {output: "xml", expectedErr: true},
+	}
+	for name, c := range cases {
+		t.Run(name, func(t *testing.T) {
+			flags = commandFlags{output: c.output}
+			defer func() { flags = commandFlags{} }()
+
+			err := validateOutputFlag()
+			if (err != nil) != c.expectedErr {
+				t.Fatalf("unexpected error: %v", err)
+			}
+		})
+	}
+}
+
+func TestNewCertificateResult(t *testing.T) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	notAfter := time.Now().Add(24 * time.Hour).UTC().Truncate(time.Second)
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(0xabc123),
+		Subject:      pkix.Name{CommonName: "www.example.com"},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     notAfter,
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	certPEM := string(pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}))
+
+	// The certificate is written to a file, so only its serial number and expiration are part of the result
+	result := newCertificateResult(certPEM, &Output{PickupId: "\\VED\\Policy\\Web\\www.example.com", Chain: []string{certPEM}})
+	if result.Certificate != "" || result.PickupID == "" {
+		t.Fatalf("unexpected result %+v", result)
+	}
+	if result.SerialNumber != "abc123" || result.NotAfter == nil || !result.NotAfter.Equal(notAfter) {
+		t.Fatalf("unexpected serial number or expiration in %+v", result)
+	}
+	if len(result.Chain) != 1 || result.Chain[0] != strings.TrimSpace(certPEM) {
+		t.Fatalf("unexpected chain %v", result.Chain)
+	}
+}
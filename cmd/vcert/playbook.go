@@ -16,20 +16,32 @@
 package main
 
 import (
+	"context"
 	"crypto/tls"
 	"crypto/x509"
 	"encoding/pem"
+	"errors"
 	"fmt"
+	"io"
 	"net/http"
 	"os"
+	"os/signal"
+	"syscall"
+	"text/tabwriter"
+	"time"
 
 	"github.com/urfave/cli/v2"
 	"go.uber.org/zap"
 	"golang.org/x/crypto/pkcs12"
 
+	"github.com/Venafi/vcert/v5/pkg/playbook/app/audit"
 	"github.com/Venafi/vcert/v5/pkg/playbook/app/domain"
+	"github.com/Venafi/vcert/v5/pkg/playbook/app/metrics"
 	"github.com/Venafi/vcert/v5/pkg/playbook/app/parser"
+	"github.com/Venafi/vcert/v5/pkg/playbook/app/report"
 	"github.com/Venafi/vcert/v5/pkg/playbook/app/service"
+	"github.com/Venafi/vcert/v5/pkg/playbook/app/state"
+	"github.com/Venafi/vcert/v5/pkg/playbook/app/vcertutil"
 	"github.com/Venafi/vcert/v5/pkg/util"
 	"github.com/Venafi/vcert/v5/pkg/venafi"
 )
@@ -47,15 +59,32 @@ var commandRunPlaybook = &cli.Command{
 	UsageText: `vcert run
    vcert run -f /path/to/my/file.yml
    vcert run -f ./myFile.yaml --force-renew
-   vcert run -f ./myFile.yaml --debug`,
+   vcert run -f ./myFile.yaml --debug
+   vcert run -f ./myFile.yaml --dry-run
+   vcert run -f ./myFile.yaml --daemon --jitter 5m
+   vcert run -f ./myFile.yaml --daemon --metrics-listen :9090
+   vcert run -f ./myFile.yaml --state-file ./vcert-state.json
+   vcert run -f ./myFile.yaml --result-file ./report.json
+   vcert run -f ./myFile.yaml --require-complete-chain
+   vcert run -f ./myFile.yaml --status
+   vcert run -f ./myFile.yaml --validate-only`,
 	Action: doRunPlaybook,
 	Flags:  playbookFlags,
 }
 
 type runOptions struct {
-	debug    bool
-	filepath string
-	force    bool
+	daemon       bool
+	debug        bool
+	dryRun       bool
+	filepath     string
+	force        bool
+	jitter       time.Duration
+	metrics      string
+	requireChain bool
+	resultFile   string
+	stateFile    string
+	status       bool
+	validateOnly bool
 }
 
 var (
@@ -70,6 +99,14 @@ var (
 		Destination: &playbookOptions.debug,
 	}
 
+	PBFlagDryRun = &cli.BoolFlag{
+		Name:        "dry-run",
+		Usage:       "reports the certificates that would be requested, and the installations and actions that would run, without making any changes",
+		Required:    false,
+		Value:       false,
+		Destination: &playbookOptions.dryRun,
+	}
+
 	PBFlagFilepath = &cli.StringFlag{
 		Name:        "file",
 		Aliases:     []string{"f"},
@@ -88,21 +125,108 @@ var (
 		Destination: &playbookOptions.force,
 	}
 
+	PBFlagDaemon = &cli.BoolFlag{
+		Name:        "daemon",
+		Usage:       "keeps vcert running and executes each certificate task according to its schedule, until SIGTERM or SIGINT is received. SIGHUP reloads the playbook file",
+		Required:    false,
+		Value:       false,
+		Destination: &playbookOptions.daemon,
+	}
+
+	PBFlagJitter = &cli.DurationFlag{
+		Name:        "jitter",
+		Usage:       "maximum random delay added to every scheduled task run in daemon mode, e.g. 5m",
+		Required:    false,
+		Value:       service.DefaultJitter,
+		Destination: &playbookOptions.jitter,
+	}
+
+	PBFlagMetricsListen = &cli.StringFlag{
+		Name:        "metrics-listen",
+		Usage:       "address on which Prometheus metrics are served at /metrics in daemon mode, e.g. :9090",
+		Required:    false,
+		Destination: &playbookOptions.metrics,
+	}
+
+	PBFlagRequireCompleteChain = &cli.BoolFlag{
+		Name:        "require-complete-chain",
+		Usage:       "fails the tasks whose certificate chain does not reach a root certificate, even after downloading the missing issuers from the Authority Information Access extension, or does not validate, instead of installing the chain",
+		Required:    false,
+		Value:       false,
+		Destination: &playbookOptions.requireChain,
+	}
+
+	PBFlagResultFile = &cli.StringFlag{
+		Name:        "result-file",
+		Usage:       "the path to the JSON file reporting, for every task, the action taken (installed, renewed, revoked, skipped or failed), its reason, the certificate and the errors of the run",
+		Required:    false,
+		Destination: &playbookOptions.resultFile,
+	}
+
+	PBFlagStateFile = &cli.StringFlag{
+		Name:        "state-file",
+		Usage:       "the path to the file recording the certificates issued and the pending certificate requests. Overrides stateFile in the playbook config",
+		Required:    false,
+		Destination: &playbookOptions.stateFile,
+	}
+
+	PBFlagStatus = &cli.BoolFlag{
+		Name:        "status",
+		Usage:       "prints the certificates recorded in the state file for every task, without running the tasks or contacting the Venafi platform",
+		Required:    false,
+		Value:       false,
+		Destination: &playbookOptions.status,
+	}
+
+	PBFlagValidateOnly = &cli.BoolFlag{
+		Name:        "validate-only",
+		Usage:       "checks the playbook file for unknown fields, values of the wrong type and missing or invalid settings, without running the tasks or contacting the Venafi platform",
+		Required:    false,
+		Value:       false,
+		Destination: &playbookOptions.validateOnly,
+	}
+
 	playbookFlags = flagsApppend(
+		PBFlagDaemon,
 		PBFlagDebug,
+		PBFlagDryRun,
 		PBFlagFilepath,
 		PBFlagForce,
+		PBFlagJitter,
+		PBFlagMetricsListen,
+		PBFlagRequireCompleteChain,
+		PBFlagResultFile,
+		PBFlagStateFile,
+		PBFlagStatus,
+		PBFlagValidateOnly,
+		logFlags,
+		auditFlags,
 	)
 )
 
 func doRunPlaybook(_ *cli.Context) error {
-	err := util.ConfigureLogger(playbookOptions.debug)
+	err := util.ConfigureLoggerWithOptions(playbookLogOptions(nil))
 	if err != nil {
 		return err
 	}
 	zap.L().Info("running playbook file", zap.String("file", playbookOptions.filepath))
 	zap.L().Debug("debug is enabled")
 
+	if playbookOptions.daemon && playbookOptions.dryRun {
+		zap.L().Error("flags [daemon] and [dry-run] cannot be used together")
+		os.Exit(1)
+	}
+
+	if playbookOptions.metrics != "" && !playbookOptions.daemon {
+		zap.L().Error("flag [metrics-listen] can only be used with flag [daemon]")
+		os.Exit(1)
+	}
+
+	if playbookOptions.daemon && playbookOptions.resultFile != "" {
+		zap.L().Error("flags [daemon] and [result-file] cannot be used together")
+		os.Exit(1)
+	}
+
 	playbook, err := parser.ReadPlaybook(playbookOptions.filepath)
 	if err != nil {
 		zap.L().Error(fmt.Errorf("%w", err).Error())
@@ -115,69 +239,367 @@ func doRunPlaybook(_ *cli.Context) error {
 		os.Exit(1)
 	}
 
+	if playbookOptions.validateOnly {
+		zap.L().Info("playbook file is valid", zap.String("file", playbookOptions.filepath))
+		return nil
+	}
+
+	// Settings in the playbook are only known now. Flags take precedence over them
+	if playbook.Config.Log != nil {
+		err = util.ConfigureLoggerWithOptions(playbookLogOptions(playbook.Config.Log))
+		if err != nil {
+			return err
+		}
+	}
+
+	if playbookOptions.stateFile != "" {
+		playbook.Config.StateFile = playbookOptions.stateFile
+	}
+	if playbook.Config.StateFile != "" {
+		playbook.Config.State, err = state.Load(playbook.Config.StateFile)
+		if err != nil {
+			zap.L().Error("could not load state file", zap.Error(err))
+			os.Exit(1)
+		}
+	}
+
+	if playbookOptions.status {
+		if playbook.Config.State == nil {
+			zap.L().Error("flag [status] requires a state file, set with flag [state-file] or stateFile in the playbook config")
+			os.Exit(1)
+		}
+		printPlaybookStatus(os.Stdout, playbook)
+		return nil
+	}
+
 	//Set the forceRenew variable
 	playbook.Config.ForceRenew = playbookOptions.force
+	playbook.Config.DryRun = playbookOptions.dryRun
+	if playbookOptions.requireChain {
+		playbook.Config.RequireCompleteChain = true
+	}
 
 	if len(playbook.CertificateTasks) == 0 {
 		zap.L().Info("no tasks in the playbook. Nothing to do")
 		return nil
 	}
 
+	// A dry run makes no changes, so there is nothing to audit
+	auditOptions := playbookAuditOptions(playbook.Config.Audit)
+	if !auditOptions.IsEmpty() && !playbook.Config.DryRun {
+		playbook.Config.AuditLog, err = audit.Open(auditOptions)
+		if err != nil {
+			zap.L().Error("could not open audit log", zap.Error(err))
+			os.Exit(1)
+		}
+		defer playbook.Config.AuditLog.Close()
+	}
+
 	// emulate the setTLSConfig from vcert
-	err = setPlaybookTLSConfig(playbook)
+	err = setPlaybookTLSConfig(&playbook)
 	if err != nil {
 		zap.L().Error("tls config error", zap.Error(err))
 		os.Exit(1)
 	}
 
-	zap.L().Info("using Venafi Platform", zap.String("platform", playbook.Config.Connection.Platform.String()))
+	if len(playbook.Config.Connections) == 0 {
+		zap.L().Info("using Venafi Platform", zap.String("platform", playbook.Config.Connection.Platform.String()))
+	}
+	for _, connection := range playbook.Config.Connections {
+		zap.L().Info("using Venafi Platform", zap.String("connection", connection.Name),
+			zap.String("platform", connection.Platform.String()))
+	}
+
+	// Ctrl-C cancels the tasks in progress, which stop waiting for their certificates
+	ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGTERM, os.Interrupt)
+	defer stop()
 
-	if playbook.Config.Connection.Platform == venafi.TPP {
-		err = service.ValidateTPPCredentials(&playbook)
+	// Refreshing the TPP access token updates the playbook file, so it is skipped on dry runs
+	if playbook.Config.UsesPlatform(venafi.TPP) && !playbook.Config.DryRun {
+		err = service.ValidateTPPCredentials(ctx, &playbook)
 		if err != nil {
 			zap.L().Error("invalid tpp credentials", zap.Error(err))
 			os.Exit(1)
 		}
 	}
 
-	for _, certTask := range playbook.CertificateTasks {
-		zap.L().Info("running playbook task", zap.String("task", certTask.Name))
-		errors := service.Execute(playbook.Config, certTask)
-		if len(errors) > 0 {
-			for _, err2 := range errors {
+	if playbookOptions.daemon {
+		// Under the Windows service manager, stopping the service cancels ctx like SIGTERM does
+		ctx, serviceStopped := serviceContext(ctx)
+		defer serviceStopped()
+		return runPlaybookDaemon(ctx, playbook)
+	}
+
+	if playbookOptions.resultFile != "" {
+		playbook.Config.Report = report.New()
+	}
+	results := service.ExecuteTasks(ctx, playbook.Config, playbook.CertificateTasks)
+	if playbook.Config.Report != nil {
+		err = playbook.Config.Report.Write(playbookOptions.resultFile)
+		if err != nil {
+			zap.L().Error("could not write result file", zap.String("file", playbookOptions.resultFile), zap.Error(err))
+			os.Exit(1)
+		}
+	}
+	if len(results) > 0 {
+		for _, certTask := range playbook.CertificateTasks {
+			for _, err2 := range results[certTask.Name] {
 				zap.L().Error("error running task", zap.String("task", certTask.Name), zap.Error(err2))
 			}
-			os.Exit(1)
 		}
+		os.Exit(1)
 	}
 
+	if playbook.Config.DryRun {
+		zap.L().Info("playbook dry run finished. No changes were made")
+		return nil
+	}
 	zap.L().Info("playbook run finished")
 	return nil
 }
 
-func setPlaybookTLSConfig(playbook domain.Playbook) error {
+// printPlaybookStatus writes the certificate recorded in the state file for every task of the playbook, in playbook order
+func printPlaybookStatus(out io.Writer, playbook domain.Playbook) {
+	formatTime := func(t *time.Time) string {
+		if t == nil {
+			return "-"
+		}
+		return t.UTC().Format(time.RFC3339)
+	}
+	orDash := func(s string) string {
+		if s == "" {
+			return "-"
+		}
+		return s
+	}
+
+	w := tabwriter.NewWriter(out, 0, 0, 2, ' ', 0)
+	_, _ = fmt.Fprintln(w, "TASK\tSTATUS\tSERIAL\tTHUMBPRINT\tNOT AFTER\tRENEW AT\tISSUED AT\tPICKUP ID\tZONE")
+	for _, task := range playbook.CertificateTasks {
+		ts, found := playbook.Config.State.Task(task.Name)
+		if !found {
+			_, _ = fmt.Fprintf(w, "%s\tunknown\t-\t-\t-\t-\t-\t-\t-\n", task.Name)
+			continue
+		}
+		status := ts.Status
+		if ts.Adopted {
+			status += " (adopted)"
+		}
+		_, _ = fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\t%s\t%s\t%s\t%s\n", task.Name, status, orDash(ts.Serial), orDash(ts.Thumbprint),
+			formatTime(ts.NotAfter), formatTime(ts.RenewAt), formatTime(ts.IssuedAt), orDash(ts.PickupID), orDash(ts.Zone))
+	}
+	_ = w.Flush()
+}
+
+// playbookLogOptions returns the log settings of the playbook, overridden by the debug and log flags
+func playbookLogOptions(config *util.LogOptions) util.LogOptions {
+	opts := util.LogOptions{}
+	if config != nil {
+		opts = *config
+	}
+	if flags.logFormat != "" {
+		opts.Format = flags.logFormat
+	}
+	if flags.logLevel != "" {
+		opts.Level = flags.logLevel
+	}
+	if flags.logFile != "" {
+		opts.File = flags.logFile
+	}
+	if playbookOptions.debug {
+		opts.Level = "debug"
+	}
+	return opts
+}
+
+// runPlaybookDaemon runs the playbook daemon until ctx is done, which happens when the process is interrupted.
+// The playbook file is reloaded every time SIGHUP is received
+func runPlaybookDaemon(ctx context.Context, playbook domain.Playbook) error {
+	daemon, err := service.NewDaemon(playbook, playbookOptions.jitter)
+	if err != nil {
+		zap.L().Error("could not start playbook daemon", zap.Error(err))
+		os.Exit(1)
+	}
+
+	var metricsServer *http.Server
+	if playbookOptions.metrics != "" {
+		metricsServer = startMetricsServer(playbookOptions.metrics)
+	}
+
+	reload := make(chan os.Signal, 1)
+	signal.Notify(reload, syscall.SIGHUP)
+	defer signal.Stop(reload)
+
+	daemon.Start(ctx)
+	for ctx.Err() == nil {
+		select {
+		case <-ctx.Done():
+		case <-reload:
+			zap.L().Info("received reload signal", zap.String("file", playbookOptions.filepath))
+			playbook = reloadPlaybookDaemon(daemon, playbook)
+		}
+	}
+	zap.L().Info("received termination signal")
+	daemon.Stop()
+
+	if metricsServer != nil {
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		err = metricsServer.Shutdown(ctx)
+		if err != nil {
+			zap.L().Warn("could not stop metrics server", zap.Error(err))
+		}
+	}
+
+	return nil
+}
+
+// reloadPlaybookDaemon reads the playbook file again and applies its tasks and config to the daemon.
+// Returns the playbook in use by the daemon, which is the current one when the playbook file cannot be applied
+func reloadPlaybookDaemon(daemon *service.Daemon, current domain.Playbook) domain.Playbook {
+	playbook, err := parser.ReadPlaybook(playbookOptions.filepath)
+	if err != nil {
+		zap.L().Error("could not reload playbook file. Keeping current playbook", zap.Error(err))
+		return current
+	}
+
+	_, err = playbook.IsValid()
+	if err != nil {
+		zap.L().Error("invalid playbook file. Keeping current playbook", zap.String("file", playbookOptions.filepath), zap.Error(err))
+		return current
+	}
+
+	if playbookOptions.stateFile != "" {
+		playbook.Config.StateFile = playbookOptions.stateFile
+	}
+	// The state may be in use by running tasks, so it is only loaded again when the state file changes
+	// The audit log stays open for the lifetime of the daemon. Audit settings of the reloaded file apply on restart
+	playbook.Config.AuditLog = current.Config.AuditLog
+
+	if playbook.Config.StateFile == current.Config.StateFile {
+		playbook.Config.State = current.Config.State
+	} else if playbook.Config.StateFile != "" {
+		playbook.Config.State, err = state.Load(playbook.Config.StateFile)
+		if err != nil {
+			zap.L().Error("could not load state file. Keeping current playbook", zap.Error(err))
+			return current
+		}
+	}
+
+	// The TLS configuration of the default connection is only installed once the daemon accepted the playbook, so a
+	// rejected reload changes nothing
+	err = buildPlaybookTLSConfigs(&playbook)
+	if err != nil {
+		zap.L().Error("tls config error. Keeping current playbook", zap.Error(err))
+		return current
+	}
+
+	err = daemon.Reload(playbook)
+	if err != nil {
+		zap.L().Error("could not reload playbook daemon. Keeping current playbook", zap.Error(err))
+		return current
+	}
+	installPlaybookTLSConfig(playbook.Config.Connection.TLSConfig)
+	// Zone configurations are read again, so a reload also picks up the policy changes made in the platform
+	vcertutil.InvalidateZoneCache()
+	return playbook
+}
+
+// startMetricsServer serves the playbook metrics at /metrics on the given address, until the server is shut down
+func startMetricsServer(address string) *http.Server {
+	mux := http.NewServeMux()
+	mux.Handle("/metrics", metrics.Handler())
+	server := &http.Server{
+		Addr:              address,
+		Handler:           mux,
+		ReadHeaderTimeout: 10 * time.Second,
+	}
+
+	go func() {
+		zap.L().Info("serving metrics", zap.String("address", address))
+		err := server.ListenAndServe()
+		if err != nil && !errors.Is(err, http.ErrServerClosed) {
+			zap.L().Error("metrics server failed", zap.String("address", address), zap.Error(err))
+		}
+	}()
+	return server
+}
+
+// setPlaybookTLSConfig sets the TLS configuration of each connection of the playbook to the Venafi platforms, and
+// installs the one of the default connection as the TLS configuration of http.DefaultTransport
+func setPlaybookTLSConfig(playbook *domain.Playbook) error {
+	err := buildPlaybookTLSConfigs(playbook)
+	if err != nil {
+		return err
+	}
+	installPlaybookTLSConfig(playbook.Config.Connection.TLSConfig)
+	return nil
+}
+
+// buildPlaybookTLSConfigs sets a new TLS configuration, built from its settings, to each connection of the playbook.
+// Every setting comes from the playbook, so a setting removed from the playbook file is also removed from the
+// configuration when the playbook is reloaded
+func buildPlaybookTLSConfigs(playbook *domain.Playbook) error {
+	for i, connection := range playbook.Config.Connections {
+		config, err := newConnectionTLSConfig(connection, playbook.CertificateTasks)
+		if err != nil {
+			return fmt.Errorf("connection %q: %w", connection.Name, err)
+		}
+		playbook.Config.Connections[i].TLSConfig = config
+	}
+	// The default connection is the first of the list, when config.connection is one
+	if len(playbook.Config.Connections) > 0 {
+		playbook.Config.Connection.TLSConfig = playbook.Config.Connections[0].TLSConfig
+		return nil
+	}
+
+	config, err := newConnectionTLSConfig(playbook.Config.Connection, playbook.CertificateTasks)
+	if err != nil {
+		return err
+	}
+	playbook.Config.Connection.TLSConfig = config
+	return nil
+}
+
+// newConnectionTLSConfig returns a new TLS configuration built from the settings of connection. The PKCS#12 file of
+// the client certificate may be installed by one of tasks
+func newConnectionTLSConfig(connection domain.Connection, tasks domain.CertificateTasks) (*tls.Config, error) {
 	// NOTE: This should use the standard setTLSConfig from vCert once incorporated into vCert
 	//  added here mostly to deal with TPP servers that are enabled for certificate authentication
 	//  and to enable certificate authentication
+	tlsConfig := &tls.Config{} // #nosec G402 -- the minimum TLS version of crypto/tls is used
 
-	// Set RenegotiateFreelyAsClient in case of we're communicating with MTLS enabled TPP server
-	if playbook.Config.Connection.Platform == venafi.TPP {
+	// Set RenegotiateFreelyAsClient in case of we're communicating with MTLS enabled TPP server.
+	platform := connection.Platform
+	if platform == venafi.TPP {
 		tlsConfig.Renegotiation = tls.RenegotiateFreelyAsClient
 	}
 
-	if playbook.Config.Connection.Insecure {
-		tlsConfig.InsecureSkipVerify = true // #nosec G402
-	}
+	tlsConfig.InsecureSkipVerify = connection.Insecure // #nosec G402
 
 	// Try to set up certificate authentication if enabled
-	if playbook.Config.Connection.Platform == venafi.TPP && playbook.Config.Connection.Credentials.P12Task != "" {
-		zap.L().Info("attempting to enable certificate authentication to TPP")
+	credentials := connection.Credentials
+	if (platform == venafi.TPP || platform == venafi.EST) && (credentials.ClientCertFile != "" || credentials.ClientP12File != "") {
+		zap.L().Info("enabling certificate authentication", zap.String("platform", platform.String()))
+		var cert *tls.Certificate
+		var err error
+		if credentials.ClientCertFile != "" {
+			cert, err = util.LoadClientCertificate(credentials.ClientCertFile, credentials.ClientKeyFile)
+		} else {
+			cert, err = util.LoadClientPKCS12(credentials.ClientP12File, credentials.ClientP12Password)
+		}
+		if err != nil {
+			return nil, err
+		}
+		tlsConfig.Certificates = []tls.Certificate{*cert}
+	} else if (platform == venafi.TPP || platform == venafi.EST) && credentials.P12Task != "" {
+		zap.L().Info("attempting to enable certificate authentication", zap.String("platform", platform.String()))
 		var p12FileLocation string
 		var p12Password string
 
 		// Figure out which certificate task in the playbook the PKCS12 authentication should use
-		for _, task := range playbook.CertificateTasks {
-			if task.Name == playbook.Config.Connection.Credentials.P12Task {
+		for _, task := range tasks {
+			if task.Name == credentials.P12Task {
 				for _, inst := range task.Installations {
 					// Find the first installation that is of type P12
 					if inst.Type == domain.FormatPKCS12 {
@@ -204,7 +626,7 @@ func setPlaybookTLSConfig(playbook domain.Playbook) error {
 			// We have a PKCS12 file to use, set it up for cert authentication
 			blocks, err := pkcs12.ToPEM(p12, p12Password)
 			if err != nil {
-				return fmt.Errorf("failed converting PKCS#12 archive file to PEM blocks: %w", err)
+				return nil, fmt.Errorf("failed converting PKCS#12 archive file to PEM blocks: %w", err)
 			}
 
 			var pemData []byte
@@ -215,7 +637,7 @@ func setPlaybookTLSConfig(playbook domain.Playbook) error {
 			// Construct TLS certificate from PEM data
 			cert, err := tls.X509KeyPair(pemData, pemData)
 			if err != nil {
-				return fmt.Errorf("failed reading PEM data to build X.509 certificate: %w", err)
+				return nil, fmt.Errorf("failed reading PEM data to build X.509 certificate: %w", err)
 			}
 
 			caCertPool := x509.NewCertPool()
@@ -229,17 +651,23 @@ func setPlaybookTLSConfig(playbook domain.Playbook) error {
 
 	}
 
+	return tlsConfig, nil
+}
+
+// installPlaybookTLSConfig makes config the TLS configuration of http.DefaultTransport, used by the connectors built
+// without the TLS configuration of their connection. The transports shared by the connectors are built again, so the
+// connections of the previous TLS configurations are closed
+func installPlaybookTLSConfig(config *tls.Config) {
 	// Create own Transport to allow HTTP1.1 connections
 	transport := &http.Transport{
 		// Only one request is made with a client
 		DisableKeepAlives: true,
 		// This is to allow for http1.1 connections
 		ForceAttemptHTTP2: false,
-		TLSClientConfig:   &tlsConfig,
+		TLSClientConfig:   config,
 	}
 
 	//Setting Default HTTP Transport
 	http.DefaultTransport = transport
-
-	return nil
+	util.ResetTransports()
 }
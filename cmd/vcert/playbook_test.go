@@ -2,12 +2,18 @@ package main
 
 import (
 	"bufio"
+	"bytes"
 	"crypto/tls"
+	"crypto/x509"
 	"encoding/json"
 	"encoding/pem"
+	"fmt"
 	"net/http"
 	"os"
+	"path/filepath"
+	"strings"
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/suite"
 	"go.uber.org/zap"
@@ -15,7 +21,12 @@ import (
 	"golang.org/x/crypto/pkcs12"
 
 	"github.com/Venafi/vcert/v5/pkg/playbook/app/domain"
+	"github.com/Venafi/vcert/v5/pkg/playbook/app/parser"
+	"github.com/Venafi/vcert/v5/pkg/playbook/app/service"
+	"github.com/Venafi/vcert/v5/pkg/playbook/app/state"
+	"github.com/Venafi/vcert/v5/pkg/util"
 	"github.com/Venafi/vcert/v5/pkg/venafi"
+	"github.com/Venafi/vcert/v5/test/testcert"
 )
 
 type logLine struct {
@@ -62,7 +73,7 @@ func (s *PlaybookSuite) TestPlaybook_SetTLSConfig() {
 		},
 	}
 
-	err := setPlaybookTLSConfig(playbook)
+	err := setPlaybookTLSConfig(&playbook)
 	s.NoError(err)
 
 	tlsCfg := http.DefaultTransport.(*http.Transport).TLSClientConfig
@@ -122,7 +133,7 @@ func (s *PlaybookSuite) TestPlaybook_SetTLSConfig_noP12Certificate() {
 		},
 	}
 
-	err = setPlaybookTLSConfig(playbook)
+	err = setPlaybookTLSConfig(&playbook)
 	s.NoError(err)
 
 	tlsCfg := http.DefaultTransport.(*http.Transport).TLSClientConfig
@@ -172,7 +183,7 @@ func (s *PlaybookSuite) TestPlaybook_SetTLSConfig_noCertAuth() {
 		},
 	}
 
-	err := setPlaybookTLSConfig(playbook)
+	err := setPlaybookTLSConfig(&playbook)
 	s.NoError(err)
 
 	tlsCfg := http.DefaultTransport.(*http.Transport).TLSClientConfig
@@ -193,9 +204,169 @@ func (s *PlaybookSuite) TestPlaybook_SetTLSConfig_Insecure() {
 		},
 	}
 
-	err := setPlaybookTLSConfig(playbook)
+	err := setPlaybookTLSConfig(&playbook)
 	s.NoError(err)
 
 	tlsCfg := http.DefaultTransport.(*http.Transport).TLSClientConfig
 	s.True(tlsCfg.InsecureSkipVerify)
 }
+
+func (s *PlaybookSuite) TestPlaybook_SetTLSConfig_Connections() {
+	dir := s.T().TempDir()
+	client := testcert.Issue(testcert.Template("client", false), nil, nil)
+	key, err := x509.MarshalPKCS8PrivateKey(client.Key)
+	s.Require().NoError(err)
+	s.Require().NoError(os.WriteFile(filepath.Join(dir, "client.crt"), []byte(client.PEM()), 0600))
+	s.Require().NoError(os.WriteFile(filepath.Join(dir, "client.key"), pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: key}), 0600))
+
+	playbook := domain.Playbook{
+		Config: domain.Config{
+			Connections: []domain.Connection{
+				{
+					Name:     "cloud",
+					Platform: venafi.TLSPCloud,
+				},
+				{
+					Name:     "tpp",
+					Platform: venafi.TPP,
+					Insecure: true,
+					Credentials: domain.Authentication{
+						ClientCertFile: filepath.Join(dir, "client.crt"),
+						ClientKeyFile:  filepath.Join(dir, "client.key"),
+					},
+				},
+			},
+		},
+	}
+	playbook.Config.Connection = playbook.Config.Connections[0]
+
+	err = setPlaybookTLSConfig(&playbook)
+	s.Require().NoError(err)
+
+	// Each connection gets its own settings, whatever the default connection is
+	cloudTLSConfig := playbook.Config.Connections[0].TLSConfig
+	s.Require().NotNil(cloudTLSConfig)
+	s.False(cloudTLSConfig.InsecureSkipVerify)
+	s.NotEqual(tls.RenegotiateFreelyAsClient, cloudTLSConfig.Renegotiation)
+	s.Empty(cloudTLSConfig.Certificates)
+
+	tppTLSConfig := playbook.Config.Connections[1].TLSConfig
+	s.Require().NotNil(tppTLSConfig)
+	s.True(tppTLSConfig.InsecureSkipVerify)
+	s.Equal(tls.RenegotiateFreelyAsClient, tppTLSConfig.Renegotiation)
+	s.Require().Len(tppTLSConfig.Certificates, 1)
+	s.Equal(client.Cert.Raw, tppTLSConfig.Certificates[0].Certificate[0])
+
+	// The tasks of the default connection, and the requests outside of any connection, use the first one
+	s.Same(cloudTLSConfig, playbook.Config.Connection.TLSConfig)
+	s.Same(cloudTLSConfig, http.DefaultTransport.(*http.Transport).TLSClientConfig)
+	s.Same(tppTLSConfig, playbook.Config.ForTask(domain.CertificateTask{Connection: "tpp"}).Connection.TLSConfig)
+}
+
+func (s *PlaybookSuite) TestPlaybook_ReloadTLSConfig() {
+	dir := s.T().TempDir()
+	writeClientCert := func(name string) []byte {
+		cert := testcert.Issue(testcert.Template(name, false), nil, nil)
+		key, err := x509.MarshalPKCS8PrivateKey(cert.Key)
+		s.Require().NoError(err)
+		s.Require().NoError(os.WriteFile(filepath.Join(dir, name+".crt"), []byte(cert.PEM()), 0600))
+		s.Require().NoError(os.WriteFile(filepath.Join(dir, name+".key"), pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: key}), 0600))
+		return cert.Cert.Raw
+	}
+	firstCert := writeClientCert("first")
+	secondCert := writeClientCert("second")
+
+	playbookFile := filepath.Join(dir, "playbook.yaml")
+	writePlaybook := func(client string, insecure bool, schedule string) {
+		data := fmt.Sprintf(`config:
+  connection:
+    platform: tpp
+    url: https://tpp.venafi.example
+    insecure: %t
+    credentials:
+      accessToken: foo123
+      clientCertFile: %s
+      clientKeyFile: %s
+certificateTasks:
+  - name: web
+    schedule: "%s"
+    request:
+      zone: "Open Source\\vcert"
+      subject:
+        commonName: web.venafi.example
+    installations:
+      - format: PEM
+        file: %s
+        chainFile: %s
+        keyFile: %s
+`, insecure, filepath.Join(dir, client+".crt"), filepath.Join(dir, client+".key"), schedule,
+			filepath.Join(dir, "web.crt"), filepath.Join(dir, "chain.crt"), filepath.Join(dir, "web.key"))
+		s.Require().NoError(os.WriteFile(playbookFile, []byte(data), 0600))
+	}
+	defaultFilepath := playbookOptions.filepath
+	playbookOptions.filepath = playbookFile
+	defer func() {
+		playbookOptions.filepath = defaultFilepath
+	}()
+
+	writePlaybook("first", true, "@daily")
+	playbook, err := parser.ReadPlaybook(playbookFile)
+	s.Require().NoError(err)
+	_, err = playbook.IsValid()
+	s.Require().NoError(err)
+	s.Require().NoError(setPlaybookTLSConfig(&playbook))
+	daemon, err := service.NewDaemon(playbook, 0)
+	s.Require().NoError(err)
+	defer daemon.Stop()
+
+	// connectorTLSConfig returns the TLS configuration of the transport a connector gets
+	connectorTLSConfig := func() *tls.Config {
+		return util.NewTransport(util.TransportConfig{}, util.ProxyConfig{}, nil).TLSClientConfig
+	}
+	s.Equal(firstCert, connectorTLSConfig().Certificates[0].Certificate[0])
+	s.True(connectorTLSConfig().InsecureSkipVerify)
+
+	// A rejected reload changes nothing
+	writePlaybook("second", false, "not a schedule")
+	reloaded := reloadPlaybookDaemon(daemon, playbook)
+	s.Equal(playbook, reloaded)
+	s.Equal(firstCert, connectorTLSConfig().Certificates[0].Certificate[0])
+	s.True(connectorTLSConfig().InsecureSkipVerify)
+
+	// An accepted reload replaces the client certificate, and turns certificate verification on again
+	writePlaybook("second", false, "@hourly")
+	reloaded = reloadPlaybookDaemon(daemon, playbook)
+	s.Equal("@hourly", reloaded.CertificateTasks[0].Schedule)
+	s.Equal(secondCert, connectorTLSConfig().Certificates[0].Certificate[0])
+	s.False(connectorTLSConfig().InsecureSkipVerify)
+}
+
+func (s *PlaybookSuite) TestPlaybook_PrintStatus() {
+	st, err := state.Load(filepath.Join(s.T().TempDir(), "state.json"))
+	s.Require().NoError(err)
+
+	notAfter := time.Date(2024, 1, 30, 0, 0, 0, 0, time.UTC)
+	renewAt := time.Date(2024, 1, 20, 0, 0, 0, 0, time.UTC)
+	issuedAt := time.Date(2023, 11, 1, 0, 0, 0, 0, time.UTC)
+	s.Require().NoError(st.SetTask("issuedTask", state.TaskState{Status: state.StatusIssued, Serial: "1234",
+		Thumbprint: "abcd", NotAfter: &notAfter, RenewAt: &renewAt, IssuedAt: &issuedAt, PickupID: "pickup-1", Zone: "My\\App"}))
+	s.Require().NoError(st.SetTask("pendingTask", state.TaskState{Status: state.StatusPending, PickupID: "pickup-2"}))
+
+	playbook := domain.Playbook{
+		CertificateTasks: domain.CertificateTasks{
+			{Name: "issuedTask"},
+			{Name: "pendingTask"},
+			{Name: "newTask"},
+		},
+		Config: domain.Config{State: st},
+	}
+
+	out := bytes.Buffer{}
+	printPlaybookStatus(&out, playbook)
+
+	lines := strings.Split(strings.TrimSpace(out.String()), "\n")
+	s.Require().Len(lines, 4)
+	s.Equal([]string{"issuedTask", "issued", "1234", "abcd", "2024-01-30T00:00:00Z", "2024-01-20T00:00:00Z", "2023-11-01T00:00:00Z", "pickup-1", "My\\App"}, strings.Fields(lines[1]))
+	s.Equal([]string{"pendingTask", "pending", "-", "-", "-", "-", "-", "pickup-2", "-"}, strings.Fields(lines[2]))
+	s.Equal([]string{"newTask", "unknown", "-", "-", "-", "-", "-", "-", "-"}, strings.Fields(lines[3]))
+}
@@ -0,0 +1,358 @@
+/*
+ * Copyright 2023 Venafi, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *  http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package main
+
+import (
+	"crypto/x509"
+	"encoding/pem"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/urfave/cli/v2"
+
+	"github.com/Venafi/vcert/v5/pkg/certificate"
+	"github.com/Venafi/vcert/v5/pkg/playbook/app/domain"
+	"github.com/Venafi/vcert/v5/pkg/playbook/app/installer"
+)
+
+const (
+	commandProvisionName = "provision"
+
+	provisionTargetCitrix = "citrix"
+	provisionTargetF5     = "f5"
+)
+
+var commandProvision = &cli.Command{
+	Before: runBeforeCommand,
+	Name:   commandProvisionName,
+	Flags:  provisionFlags,
+	Action: doCommandProvision,
+	Usage: "To install a local certificate and its private key in a target system. Supported targets are f5, to upload " +
+		"the certificate to an F5 BIG-IP and assign it to a client SSL profile, and citrix, to install it as a certkey " +
+		"of a Citrix ADC and bind it to SSL virtual servers",
+	UsageText: ` vcert provision --target f5 --file /path-to/cert.pem --key-file /path-to/key.pem --f5-address bigip.example.com --f5-username admin --f5-password file:/path-to/password.txt --f5-cert-name www.example.com
+		 vcert provision --target f5 --file /path-to/cert.p12 --key-password <PKCS#12 password> --f5-address bigip.example.com:8443 --f5-username admin --f5-password <password> --f5-cert-name www.example.com --f5-profile www_clientssl
+		 vcert provision --target f5 --file /path-to/bundle.pem --f5-address bigip.example.com --f5-ca-cert /path-to/bigip-ca.pem --f5-username admin --f5-password <password> --f5-partition Apps --f5-cert-name www.example.com
+		 vcert provision --target citrix --file /path-to/cert.pem --key-file /path-to/key.pem --citrix-address adc.example.com --citrix-username nsroot --citrix-password file:/path-to/password.txt --citrix-cert-key www.example.com --citrix-vserver vs_www`,
+}
+
+type provisionOptions struct {
+	target         string
+	file           string
+	keyFile        string
+	citrixAddress  string
+	citrixCACert   string
+	citrixCertKey  string
+	citrixInsecure bool
+	citrixPassword string
+	citrixUsername string
+	citrixVServers []string
+	f5Address      string
+	f5CACert       string
+	f5CertName     string
+	f5Insecure     bool
+	f5Partition    string
+	f5Password     string
+	f5Profile      string
+	f5Username     string
+}
+
+var (
+	provisionOpts = provisionOptions{}
+
+	flagProvisionTarget = &cli.StringFlag{
+		Name:        "target",
+		Usage:       "REQUIRED. The system to install the certificate in. Options include: f5 | citrix",
+		Destination: &provisionOpts.target,
+	}
+
+	flagProvisionFile = &cli.StringFlag{
+		Name: "file",
+		Usage: "REQUIRED. The certificate file to install, in PEM, DER, PKCS#12 or JKS format, along with its chain " +
+			"and, unless --key-file is used, its private key. Example: --file /path-to/cert.pem",
+		Destination: &provisionOpts.file,
+		TakesFile:   true,
+	}
+
+	flagProvisionKeyFile = &cli.StringFlag{
+		Name:        "key-file",
+		Usage:       "Use to specify the PEM file of the private key, when it is not in the certificate file. Example: --key-file /path-to/key.pem",
+		Destination: &provisionOpts.keyFile,
+		TakesFile:   true,
+	}
+
+	flagProvisionCitrixAddress = &cli.StringFlag{
+		Name:        "citrix-address",
+		Usage:       "Use to specify the host, and optionally the port, of the Citrix ADC management interface. Example: --citrix-address adc.example.com",
+		Destination: &provisionOpts.citrixAddress,
+	}
+
+	flagProvisionCitrixCACert = &cli.StringFlag{
+		Name:        "citrix-ca-cert",
+		Usage:       "Use to specify a PEM file with the CA certificates used to verify the certificate of the Citrix ADC management interface",
+		Destination: &provisionOpts.citrixCACert,
+		TakesFile:   true,
+	}
+
+	flagProvisionCitrixCertKey = &cli.StringFlag{
+		Name: "citrix-cert-key",
+		Usage: "Use to specify the name of the certkey created in the Citrix ADC, or updated in place when it already exists. " +
+			"Example: --citrix-cert-key www.example.com",
+		Destination: &provisionOpts.citrixCertKey,
+	}
+
+	flagProvisionCitrixInsecure = &cli.BoolFlag{
+		Name:        "citrix-insecure",
+		Usage:       "Use to skip the verification of the certificate of the Citrix ADC management interface. Not recommended",
+		Destination: &provisionOpts.citrixInsecure,
+	}
+
+	flagProvisionCitrixPassword = &cli.StringFlag{
+		Name:        "citrix-password",
+		Usage:       "Use to specify the password of the Citrix ADC user. Example: --citrix-password file:/path-to/password.txt",
+		Destination: &provisionOpts.citrixPassword,
+	}
+
+	flagProvisionCitrixUsername = &cli.StringFlag{
+		Name:        "citrix-username",
+		Usage:       "Use to specify the Citrix ADC user, which needs permission to manage certificates and SSL virtual servers",
+		Destination: &provisionOpts.citrixUsername,
+	}
+
+	flagProvisionCitrixVServer = &cli.StringSliceFlag{
+		Name: "citrix-vserver",
+		Usage: "Use to specify an SSL virtual server to bind the certificate to, replacing its server certificate. " +
+			"This option can be repeated to specify more than one virtual server like this: --citrix-vserver vs_www --citrix-vserver vs_www_alt",
+	}
+
+	flagProvisionF5Address = &cli.StringFlag{
+		Name:        "f5-address",
+		Usage:       "Use to specify the host, and optionally the port, of the BIG-IP management interface. Example: --f5-address bigip.example.com:8443",
+		Destination: &provisionOpts.f5Address,
+	}
+
+	flagProvisionF5CACert = &cli.StringFlag{
+		Name:        "f5-ca-cert",
+		Usage:       "Use to specify a PEM file with the CA certificates used to verify the certificate of the BIG-IP management interface",
+		Destination: &provisionOpts.f5CACert,
+		TakesFile:   true,
+	}
+
+	flagProvisionF5CertName = &cli.StringFlag{
+		Name: "f5-cert-name",
+		Usage: "Use to specify the base name of the certificate, key and chain objects created in the BIG-IP. " +
+			"The thumbprint of the certificate is appended to it. Example: --f5-cert-name www.example.com",
+		Destination: &provisionOpts.f5CertName,
+	}
+
+	flagProvisionF5Insecure = &cli.BoolFlag{
+		Name:        "f5-insecure",
+		Usage:       "Use to skip the verification of the certificate of the BIG-IP management interface. Not recommended",
+		Destination: &provisionOpts.f5Insecure,
+	}
+
+	flagProvisionF5Partition = &cli.StringFlag{
+		Name:        "f5-partition",
+		Usage:       "Use to specify the BIG-IP partition the objects are created in",
+		Destination: &provisionOpts.f5Partition,
+		Value:       domain.DefaultF5Partition,
+	}
+
+	flagProvisionF5Password = &cli.StringFlag{
+		Name:        "f5-password",
+		Usage:       "Use to specify the password of the BIG-IP user. Example: --f5-password file:/path-to/password.txt",
+		Destination: &provisionOpts.f5Password,
+	}
+
+	flagProvisionF5Profile = &cli.StringFlag{
+		Name: "f5-profile",
+		Usage: "Use to specify the client SSL profile to assign the certificate to. The certificate previously " +
+			"installed by vcert, or the default certificate of the profile, is replaced. Example: --f5-profile www_clientssl",
+		Destination: &provisionOpts.f5Profile,
+	}
+
+	flagProvisionF5Username = &cli.StringFlag{
+		Name:        "f5-username",
+		Usage:       "Use to specify the BIG-IP user, which needs permission to manage certificates and client SSL profiles",
+		Destination: &provisionOpts.f5Username,
+	}
+
+	provisionFlags = sortedFlags(flagsApppend(
+		flagProvisionTarget,
+		flagProvisionFile,
+		flagProvisionKeyFile,
+		flagProvisionCitrixAddress,
+		flagProvisionCitrixCACert,
+		flagProvisionCitrixCertKey,
+		flagProvisionCitrixInsecure,
+		flagProvisionCitrixPassword,
+		flagProvisionCitrixUsername,
+		flagProvisionCitrixVServer,
+		flagProvisionF5Address,
+		flagProvisionF5CACert,
+		flagProvisionF5CertName,
+		flagProvisionF5Insecure,
+		flagProvisionF5Partition,
+		flagProvisionF5Password,
+		flagProvisionF5Profile,
+		flagProvisionF5Username,
+		flagKeyPassword,
+		flagJKSAlias,
+		flagJKSPassword,
+		commonFlags,
+	))
+)
+
+func doCommandProvision(c *cli.Context) error {
+	if provisionOpts.target == "" {
+		return fmt.Errorf("missing required flag --target")
+	}
+	target := strings.ToLower(provisionOpts.target)
+	if target != provisionTargetF5 && target != provisionTargetCitrix {
+		return fmt.Errorf("unsupported target %q. Should be either %s or %s", provisionOpts.target, provisionTargetF5, provisionTargetCitrix)
+	}
+	if provisionOpts.file == "" {
+		return fmt.Errorf("missing required flag --file")
+	}
+	provisionOpts.citrixVServers = c.StringSlice("citrix-vserver")
+
+	keyPassword, err := readPasswordsFromInputFlag(flags.keyPassword, 0)
+	if err != nil {
+		return err
+	}
+	storePassword, err := readPasswordsFromInputFlag(flags.jksPassword, 0)
+	if err != nil {
+		return err
+	}
+	if storePassword == "" {
+		storePassword = keyPassword
+	}
+	lc, err := loadProvisionCertificate(provisionOpts.file, provisionOpts.keyFile, flags.jksAlias, storePassword, keyPassword)
+	if err != nil {
+		return err
+	}
+	pcc, err := provisionPEMCollection(lc)
+	if err != nil {
+		return err
+	}
+
+	installation, address, err := provisionInstallation(target)
+	if err != nil {
+		return err
+	}
+	if _, err = installation.IsValid(); err != nil {
+		return fmt.Errorf("invalid %s settings: %s", target, strings.TrimSpace(err.Error()))
+	}
+
+	err = installer.GetInstaller(*installation).Install(c.Context, *pcc)
+	if err != nil {
+		return fmt.Errorf("failed to provision certificate to %s: %w", address, err)
+	}
+	logf("Successfully provisioned certificate %s to %s", lc.Certificate.Subject.CommonName, address)
+	return nil
+}
+
+// provisionInstallation returns the playbook installation built from the flags of target, along with the address
+// of the target system
+func provisionInstallation(target string) (*domain.Installation, string, error) {
+	if target == provisionTargetCitrix {
+		password, err := readPasswordsFromInputFlag(provisionOpts.citrixPassword, 0)
+		if err != nil {
+			return nil, "", err
+		}
+		return &domain.Installation{
+			Type:           domain.FormatCitrixADC,
+			CitrixAddress:  provisionOpts.citrixAddress,
+			CitrixCACert:   provisionOpts.citrixCACert,
+			CitrixCertKey:  provisionOpts.citrixCertKey,
+			CitrixInsecure: provisionOpts.citrixInsecure,
+			CitrixPassword: password,
+			CitrixUsername: provisionOpts.citrixUsername,
+			CitrixVServers: provisionOpts.citrixVServers,
+		}, provisionOpts.citrixAddress, nil
+	}
+
+	password, err := readPasswordsFromInputFlag(provisionOpts.f5Password, 0)
+	if err != nil {
+		return nil, "", err
+	}
+	return &domain.Installation{
+		Type:        domain.FormatF5,
+		F5Address:   provisionOpts.f5Address,
+		F5CACert:    provisionOpts.f5CACert,
+		F5CertName:  provisionOpts.f5CertName,
+		F5Insecure:  provisionOpts.f5Insecure,
+		F5Partition: provisionOpts.f5Partition,
+		F5Password:  password,
+		F5Profile:   provisionOpts.f5Profile,
+		F5Username:  provisionOpts.f5Username,
+	}, provisionOpts.f5Address, nil
+}
+
+// loadProvisionCertificate reads the certificate, its chain and its private key from file or, when keyFile is set,
+// the private key from keyFile
+func loadProvisionCertificate(file string, keyFile string, jksAlias string, storePassword string, keyPassword string) (*installer.LoadedCertificate, error) {
+	data, err := os.ReadFile(file)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read certificate file: %w", err)
+	}
+	lc, err := installer.LoadCertificate(data, jksAlias, storePassword, keyPassword)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load certificate from %s: %w", file, err)
+	}
+
+	if keyFile != "" {
+		keyData, err := os.ReadFile(keyFile)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read private key file: %w", err)
+		}
+		block, _ := pem.Decode(keyData)
+		if block == nil || !strings.HasSuffix(block.Type, "PRIVATE KEY") {
+			return nil, fmt.Errorf("no PEM private key found in %s", keyFile)
+		}
+		lc.PrivateKey, err = parsePEMPrivateKey(block, keyPassword)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	if lc.PrivateKey == nil {
+		return nil, fmt.Errorf("no private key found in %s. Use --key-file to specify the private key file", file)
+	}
+	if !installer.PrivateKeyMatches(lc.Certificate, lc.PrivateKey) {
+		return nil, fmt.Errorf("the private key does not match the certificate")
+	}
+	return lc, nil
+}
+
+// provisionPEMCollection returns the certificate, chain and unencrypted private key of lc in PEM format
+func provisionPEMCollection(lc *installer.LoadedCertificate) (*certificate.PEMCollection, error) {
+	keyDER, err := x509.MarshalPKCS8PrivateKey(lc.PrivateKey)
+	if err != nil {
+		return nil, fmt.Errorf("failed to prepare private key: %w", err)
+	}
+
+	pcc := &certificate.PEMCollection{
+		Certificate: string(pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: lc.Certificate.Raw})),
+		PrivateKey:  string(pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: keyDER})),
+	}
+	for _, cert := range lc.Chain {
+		pcc.Chain = append(pcc.Chain, string(pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: cert.Raw})))
+	}
+	return pcc, nil
+}
@@ -0,0 +1,74 @@
+/*
+ * Copyright 2023 Venafi, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *  http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/Venafi/vcert/v5/pkg/playbook/app/installer"
+)
+
+func TestProvisionLoadCertificate(t *testing.T) {
+	now := time.Now()
+	root, leaf := newTestChain(t, now.Add(-time.Hour), now.AddDate(0, 0, 90))
+	_, other := newTestChain(t, now.Add(-time.Hour), now.AddDate(0, 0, 90))
+
+	dir := t.TempDir()
+	certFile := filepath.Join(dir, "cert.pem")
+	keyFile := filepath.Join(dir, "key.pem")
+	otherKeyFile := filepath.Join(dir, "other.pem")
+	if err := os.WriteFile(certFile, append(pemCertificate(leaf.Cert), pemCertificate(root.Cert)...), 0600); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(keyFile, pemKey(t, leaf.Key), 0600); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(otherKeyFile, pemKey(t, other.Key), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	_, err := loadProvisionCertificate(certFile, "", "", "", "")
+	if err == nil {
+		t.Fatal("expected an error when the private key is missing")
+	}
+	_, err = loadProvisionCertificate(certFile, otherKeyFile, "", "", "")
+	if err == nil {
+		t.Fatal("expected an error when the private key does not match the certificate")
+	}
+
+	lc, err := loadProvisionCertificate(certFile, keyFile, "", "", "")
+	if err != nil {
+		t.Fatalf("could not load certificate and private key: %s", err)
+	}
+	pcc, err := provisionPEMCollection(lc)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(pemCertificate(leaf.Cert)) != pcc.Certificate {
+		t.Fatal("unexpected certificate")
+	}
+	if len(pcc.Chain) != 1 || string(pemCertificate(root.Cert)) != pcc.Chain[0] {
+		t.Fatalf("unexpected chain with %d certificates", len(pcc.Chain))
+	}
+	lc, err = installer.LoadCertificate([]byte(pcc.Certificate+pcc.PrivateKey), "", "", "")
+	if err != nil || !installer.PrivateKeyMatches(lc.Certificate, lc.PrivateKey) {
+		t.Fatalf("unexpected private key %s: %v", pcc.PrivateKey, err)
+	}
+}
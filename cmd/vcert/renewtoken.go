@@ -0,0 +1,88 @@
+/*
+ * Copyright 2023 Venafi, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *  http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package main
+
+import (
+	"context"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+
+	"github.com/Venafi/vcert/v5"
+)
+
+// defaultTokenRenewBefore is how long before its expiration getcred --renew-token renews the access token
+const defaultTokenRenewBefore = 15 * time.Minute
+
+// doRenewTokens renews the access token of the --config file when it is about to expire. With --renew-interval, the
+// tokens keep being checked every interval until SIGTERM or SIGINT is received, so it can run as a service, while a
+// single check suits cron
+func doRenewTokens(ctx context.Context) error {
+	if flags.renewInterval <= 0 {
+		expires, err := renewTokens(ctx)
+		if err != nil {
+			return err
+		}
+		if isStructuredOutput() {
+			return writeCommandResult(commandGetCredName, credentialResult{AccessTokenExpires: formatTokenExpiration(expires)})
+		}
+		return nil
+	}
+
+	ctx, stop := signal.NotifyContext(ctx, syscall.SIGTERM, os.Interrupt)
+	defer stop()
+
+	ticker := time.NewTicker(flags.renewInterval)
+	defer ticker.Stop()
+	for {
+		// A failure is retried on the next check, as the access token may still be valid until then
+		_, err := renewTokens(ctx)
+		if err != nil {
+			logf("failed to renew tokens: %s", err)
+		}
+
+		select {
+		case <-ctx.Done():
+			logf("stopping token renewal")
+			return nil
+		case <-ticker.C:
+		}
+	}
+}
+
+// renewTokens renews the tokens of the --config file when needed, and returns when the access token expires
+func renewTokens(ctx context.Context) (time.Time, error) {
+	expires, renewed, err := vcert.RenewTokensInFile(ctx, flags.config, flags.profile, flags.renewBefore)
+	if err != nil {
+		return expires, err
+	}
+	if renewed {
+		logf("Access token renewed in %s, expires %s", flags.config, formatTokenExpiration(expires))
+	} else {
+		logf("Access token in %s is still valid, expires %s", flags.config, formatTokenExpiration(expires))
+	}
+	return expires, nil
+}
+
+// formatTokenExpiration returns the expiration of a token in RFC 3339 format, or "unknown" when it is not known
+func formatTokenExpiration(expires time.Time) string {
+	if expires.IsZero() {
+		return "unknown"
+	}
+	return expires.UTC().Format(time.RFC3339)
+}
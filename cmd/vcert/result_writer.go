@@ -20,6 +20,7 @@ import (
 	"bytes"
 	"crypto/rand"
 	"crypto/x509"
+	"encoding/asn1"
 	"encoding/json"
 	"encoding/pem"
 	"fmt"
@@ -34,6 +35,11 @@ import (
 	"github.com/Venafi/vcert/v5/pkg/util"
 )
 
+var (
+	oidPKCS7Data       = asn1.ObjectIdentifier{1, 2, 840, 113549, 1, 7, 1}
+	oidPKCS7SignedData = asn1.ObjectIdentifier{1, 2, 840, 113549, 1, 7, 2}
+)
+
 type Config struct {
 	Command     string
 	Format      string
@@ -55,6 +61,7 @@ type Result struct {
 	Pcc      *certificate.PEMCollection
 	PickupId string
 	Config   *Config
+	SCTs     []certificate.SignedCertificateTimestamp
 }
 
 type Output struct {
@@ -63,6 +70,8 @@ type Output struct {
 	PrivateKey  string   `json:",omitempty"`
 	Chain       []string `json:",omitempty"`
 	PickupId    string   `json:",omitempty"`
+	// SCTs are only written in JSON format
+	SCTs []certificate.SignedCertificateTimestamp `json:",omitempty"`
 }
 
 func (o *Output) AsPKCS12(c *Config) ([]byte, error) {
@@ -94,32 +103,9 @@ func (o *Output) AsPKCS12(c *Config) ([]byte, error) {
 	if p == nil {
 		return nil, fmt.Errorf("missing private key PEM")
 	}
-	var privDER []byte
-	if util.X509IsEncryptedPEMBlock(p) {
-		privDER, err = util.X509DecryptPEMBlock(p, []byte(c.KeyPassword))
-		if err != nil {
-			return nil, fmt.Errorf("private key PEM decryption error: %s", err)
-		}
-	} else {
-		privDER = p.Bytes
-	}
-	var privKey interface{}
-	switch p.Type {
-	case "EC PRIVATE KEY":
-		privKey, err = x509.ParseECPrivateKey(privDER)
-		if err != nil {
-			privKey, err = x509.ParsePKCS8PrivateKey(privDER)
-		}
-	case "RSA PRIVATE KEY":
-		privKey, err = x509.ParsePKCS1PrivateKey(privDER)
-		if err != nil {
-			privKey, err = x509.ParsePKCS8PrivateKey(privDER)
-		}
-	default:
-		return nil, fmt.Errorf("unexpected private key PEM type: %s", p.Type)
-	}
+	privKey, err := util.ParsePrivateKeyBlock(p, c.KeyPassword)
 	if err != nil {
-		return nil, fmt.Errorf("private key error(3): %s", err)
+		return nil, err
 	}
 
 	bytes, err := pkcs12.Encode(rand.Reader, privKey, cert, chain_list, c.KeyPassword)
@@ -132,7 +118,7 @@ func (o *Output) AsPKCS12(c *Config) ([]byte, error) {
 
 func (o *Output) AsJKS(c *Config) ([]byte, error) {
 
-	var err interface{}
+	var err error
 
 	if len(o.Certificate) == 0 || len(o.PrivateKey) == 0 {
 		return nil, fmt.Errorf("at least certificate and private key are required")
@@ -167,39 +153,12 @@ func (o *Output) AsJKS(c *Config) ([]byte, error) {
 	if p == nil {
 		return nil, fmt.Errorf("missing private key PEM")
 	}
-	var privDER []byte
-
 	//decrypting the PK because due the restriction that always will be requested the key password
 	//to the user(--key-password or pass phrase value from prompt) for jks format then the PK always
 	//will be encrypted with the key password provided
-	if util.X509IsEncryptedPEMBlock(p) {
-		privDER, err = util.X509DecryptPEMBlock(p, []byte(c.KeyPassword))
-		if err != nil {
-			return nil, fmt.Errorf("private key PEM decryption error: %s", err)
-		}
-	} else {
-		privDER = p.Bytes
-	}
-
-	//Unmarshalling the PK
-	var privKey interface{}
-
-	switch p.Type {
-	case "EC PRIVATE KEY":
-		privKey, err = x509.ParseECPrivateKey(privDER)
-		if err != nil {
-			privKey, err = x509.ParsePKCS8PrivateKey(privDER)
-		}
-	case "RSA PRIVATE KEY":
-		privKey, err = x509.ParsePKCS1PrivateKey(privDER)
-		if err != nil {
-			privKey, err = x509.ParsePKCS8PrivateKey(privDER)
-		}
-	default:
-		return nil, fmt.Errorf("unexpected private key PEM type: %s", p.Type)
-	}
+	privKey, err := util.ParsePrivateKeyBlock(p, c.KeyPassword)
 	if err != nil {
-		return nil, fmt.Errorf("private key error(3): %s", err)
+		return nil, err
 	}
 
 	//Marshalling the PK to PKCS8, which is mandatory for JKS format
@@ -240,6 +199,72 @@ func (o *Output) AsJKS(c *Config) ([]byte, error) {
 	return buffer.Bytes(), nil
 }
 
+// AsDER returns the certificate in binary DER encoding. The chain and the private key are not included
+func (o *Output) AsDER() ([]byte, error) {
+	p, _ := pem.Decode([]byte(o.Certificate))
+	if p == nil || p.Type != "CERTIFICATE" {
+		return nil, fmt.Errorf("certificate parse error(1)")
+	}
+	return p.Bytes, nil
+}
+
+// AsPKCS7 returns a DER encoded PKCS#7 bundle (.p7b) of the certificate and its chain, as required by Windows
+// and some appliances to import a certificate chain. The chain is left out when the chain option is ignore
+func (o *Output) AsPKCS7(c *Config) ([]byte, error) {
+	der, err := o.AsDER()
+	if err != nil {
+		return nil, err
+	}
+	certs := [][]byte{der}
+
+	if c.ChainOption != certificate.ChainOptionIgnore {
+		for _, chainCert := range o.Chain {
+			p, _ := pem.Decode([]byte(chainCert))
+			if p == nil || p.Type != "CERTIFICATE" {
+				return nil, fmt.Errorf("chain certificate parse error")
+			}
+			certs = append(certs, p.Bytes)
+		}
+	}
+
+	return marshalPKCS7CertsOnly(certs)
+}
+
+// marshalPKCS7CertsOnly encodes the DER certificates as a PKCS#7 (RFC 2315) signed data without signers
+func marshalPKCS7CertsOnly(certs [][]byte) ([]byte, error) {
+	type contentInfo struct {
+		ContentType asn1.ObjectIdentifier
+		Content     asn1.RawValue `asn1:"optional"`
+	}
+	type signedData struct {
+		Version          int
+		DigestAlgorithms asn1.RawValue
+		ContentInfo      contentInfo
+		Certificates     asn1.RawValue
+		SignerInfos      asn1.RawValue
+	}
+
+	var raw []byte
+	for _, cert := range certs {
+		raw = append(raw, cert...)
+	}
+
+	sd, err := asn1.Marshal(signedData{
+		Version:          1,
+		DigestAlgorithms: asn1.RawValue{Class: asn1.ClassUniversal, Tag: asn1.TagSet, IsCompound: true},
+		ContentInfo:      contentInfo{ContentType: oidPKCS7Data},
+		Certificates:     asn1.RawValue{Class: asn1.ClassContextSpecific, Tag: 0, IsCompound: true, Bytes: raw},
+		SignerInfos:      asn1.RawValue{Class: asn1.ClassUniversal, Tag: asn1.TagSet, IsCompound: true},
+	})
+	if err != nil {
+		return nil, fmt.Errorf("PKCS#7 encode error: %s", err)
+	}
+
+	// The explicit [0] tag around the signed data is written by hand, as tags are not applied to raw values
+	content := asn1.RawValue{Class: asn1.ClassContextSpecific, Tag: 0, IsCompound: true, Bytes: sd}
+	return asn1.Marshal(contentInfo{ContentType: oidPKCS7SignedData, Content: content})
+}
+
 func (o *Output) Format(c *Config) ([]byte, error) {
 	switch strings.ToLower(c.Format) {
 	case "json":
@@ -311,6 +336,7 @@ func (r *Result) Flush() error {
 		allFileOutput.Certificate = r.Pcc.Certificate
 		allFileOutput.Chain = r.Pcc.Chain
 		allFileOutput.CSR = r.Pcc.CSR
+		allFileOutput.SCTs = r.SCTs
 
 		var bytes []byte
 		if r.Config.Format == "pkcs12" {
@@ -339,7 +365,14 @@ func (r *Result) Flush() error {
 			if r.Config.ChainFile == "" {
 				certFileOutput.Chain = r.Pcc.Chain
 			}
-			err = writeFile(certFileOutput, r, r.Config.CertFile)
+			switch r.Config.Format {
+			case DERFormat:
+				err = writeBinaryFile(certFileOutput.AsDER, r.Config.CertFile)
+			case PKCS7Format:
+				err = writeBinaryFile(func() ([]byte, error) { return certFileOutput.AsPKCS7(r.Config) }, r.Config.CertFile)
+			default:
+				err = writeFile(certFileOutput, r, r.Config.CertFile)
+			}
 			errors = append(errors, err)
 		} else {
 			stdOut.Certificate = r.Pcc.Certificate
@@ -372,6 +405,10 @@ func (r *Result) Flush() error {
 			stdOut.Chain = r.Pcc.Chain
 		}
 	}
+	if r.Config.AllFile == "" {
+		stdOut.SCTs = r.SCTs
+	}
+
 	// PickupId is special -- it wasn't supposed to be written to -file
 	if r.Config.Command == commandEnrollName || r.Config.Command == commandRenewName {
 		if r.Config.PickupIdFile != "" && r.PickupId != "" {
@@ -384,13 +421,6 @@ func (r *Result) Flush() error {
 		}
 	}
 
-	// and flush the rest to STDOUT
-	bytes, err := stdOut.Format(r.Config)
-	if err != nil {
-		return err // something worse than file permission problem
-	}
-	fmt.Fprint(os.Stdout, string(bytes))
-
 	var finalError error
 	for _, e := range errors {
 		if e != nil {
@@ -400,6 +430,23 @@ func (r *Result) Flush() error {
 			finalError = fmt.Errorf("%s%s; ", finalError, e)
 		}
 	}
+
+	// With --output json or yaml, the outputs not written to a file are part of the document of the command.
+	// A failure is reported in the error document instead
+	if isStructuredOutput() {
+		if finalError != nil {
+			return finalError
+		}
+		return writeCommandResult(r.Config.Command, newCertificateResult(r.Pcc.Certificate, stdOut))
+	}
+
+	// and flush the rest to STDOUT
+	bytes, err := stdOut.Format(r.Config)
+	if err != nil {
+		return err // something worse than file permission problem
+	}
+	fmt.Fprint(os.Stdout, string(bytes))
+
 	return finalError
 }
 
@@ -427,3 +474,12 @@ func outputJSON(resp interface{}) error {
 	}
 	return err
 }
+
+// writeBinaryFile writes the bytes returned by encode, for the formats that cannot be written with Output.Format
+func writeBinaryFile(encode func() ([]byte, error), filePath string) error {
+	bytes, err := encode()
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(filePath, bytes, 0600)
+}
@@ -17,6 +17,10 @@
 package main
 
 import (
+	"crypto/x509"
+	"encoding/asn1"
+	"os"
+	"path/filepath"
 	"testing"
 
 	"github.com/Venafi/vcert/v5/pkg/certificate"
@@ -135,6 +139,7 @@ func TestPKCS12withEncPK(t *testing.T) {
 			"",
 			"asdf",
 		},
+		nil,
 	}
 	err := result.Flush()
 
@@ -165,6 +170,7 @@ func TestPKCS12withPlainPK(t *testing.T) {
 			"",
 			"",
 		},
+		nil,
 	}
 	err := result.Flush()
 
@@ -195,6 +201,7 @@ func TestPKCS12withPlainEcPK(t *testing.T) {
 			"",
 			"",
 		},
+		nil,
 	}
 	err := result.Flush()
 
@@ -225,6 +232,7 @@ func TestJKSWithEncPKWithoutJKSPass(t *testing.T) {
 			"",
 			"password",
 		},
+		nil,
 	}
 	err := result.Flush()
 
@@ -255,6 +263,7 @@ func TestJKSWithEncPKAndJKSPass(t *testing.T) {
 			"",
 			"password",
 		},
+		nil,
 	}
 	err := result.Flush()
 
@@ -262,3 +271,76 @@ func TestJKSWithEncPKAndJKSPass(t *testing.T) {
 		t.Fatal("Failed to output the results: ", err)
 	}
 }
+
+func TestDERCertFile(t *testing.T) {
+	certFile := filepath.Join(t.TempDir(), "cert.cer")
+	result := &Result{
+		Pcc: &certificate.PEMCollection{
+			Certificate: cert,
+			Chain:       chain,
+		},
+		Config: &Config{Command: "pickup", Format: DERFormat, CertFile: certFile},
+	}
+	err := result.Flush()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	der, err := os.ReadFile(certFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = x509.ParseCertificate(der)
+	if err != nil {
+		t.Fatalf("certificate file is not DER encoded: %s", err)
+	}
+}
+
+func TestPKCS7CertFile(t *testing.T) {
+	certFile := filepath.Join(t.TempDir(), "cert.p7b")
+	result := &Result{
+		Pcc: &certificate.PEMCollection{
+			Certificate: cert,
+			Chain:       chain,
+		},
+		Config: &Config{Command: "pickup", Format: PKCS7Format, CertFile: certFile},
+	}
+	err := result.Flush()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	data, err := os.ReadFile(certFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var info struct {
+		ContentType asn1.ObjectIdentifier
+		Content     asn1.RawValue `asn1:"explicit,tag:0"`
+	}
+	_, err = asn1.Unmarshal(data, &info)
+	if err != nil {
+		t.Fatalf("bundle is not a PKCS#7 content info: %s", err)
+	}
+	if !info.ContentType.Equal(oidPKCS7SignedData) {
+		t.Fatalf("unexpected content type %s", info.ContentType)
+	}
+	var sd struct {
+		Version          int
+		DigestAlgorithms asn1.RawValue
+		ContentInfo      asn1.RawValue
+		Certificates     asn1.RawValue `asn1:"tag:0"`
+		SignerInfos      asn1.RawValue
+	}
+	_, err = asn1.Unmarshal(info.Content.Bytes, &sd)
+	if err != nil {
+		t.Fatalf("bundle does not contain signed data: %s", err)
+	}
+	certs, err := x509.ParseCertificates(sd.Certificates.Bytes)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(certs) != 1+len(chain) {
+		t.Fatalf("expected %d certificates in the bundle, got %d", 1+len(chain), len(certs))
+	}
+}
@@ -0,0 +1,363 @@
+/*
+ * Copyright 2023 Venafi, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *  http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package main
+
+import (
+	"bufio"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"os"
+	"strings"
+	"sync"
+	"text/tabwriter"
+	"time"
+
+	"github.com/urfave/cli/v2"
+
+	"github.com/Venafi/vcert/v5/pkg/endpoint"
+	"github.com/Venafi/vcert/v5/pkg/playbook/app/domain"
+	"github.com/Venafi/vcert/v5/pkg/playbook/app/installer"
+)
+
+const (
+	commandScanName = "scan"
+
+	defaultScanPort        = "443"
+	defaultScanTimeout     = 10 * time.Second
+	defaultScanConcurrency = 10
+)
+
+var commandScan = &cli.Command{
+	Before: runBeforeCommand,
+	Name:   commandScanName,
+	Flags:  scanFlags,
+	Action: doCommandScan,
+	Usage: "To connect to TLS endpoints and report the certificate chain they present, the negotiated protocol and " +
+		"cipher suite, the expiration and, when a zone is specified, the compliance of the certificate with the zone policy",
+	UsageText: ` vcert scan --host www.example.com
+		 vcert scan --host www.example.com:8443 --host 10.20.30.40:443 --server-name www.example.com
+		 vcert scan --hosts-file /path-to/endpoints.txt --format json
+		 vcert scan --host www.example.com -k <VaaS API key> -z "<app name>\<CIT alias>"
+		 vcert scan --host www.example.com -u https://tpp.example.com -t <TPP access token> -z "<policy folder DN>"`,
+}
+
+type scanOptions struct {
+	hostsFile   string
+	serverName  string
+	format      string
+	renewBefore string
+	timeout     int
+	concurrency int
+}
+
+var (
+	scanOpts = scanOptions{}
+
+	flagScanHost = &cli.StringSliceFlag{
+		Name: "host",
+		Usage: "The endpoint to scan, as host:port. The port defaults to 443. " +
+			"This option can be repeated to scan more than one endpoint like this: --host one.example.com --host two.example.com:8443",
+	}
+
+	flagScanHostsFile = &cli.StringFlag{
+		Name:        "hosts-file",
+		Usage:       "Use to read the endpoints to scan from a file, one host:port per line. Empty lines and lines starting with # are ignored",
+		Destination: &scanOpts.hostsFile,
+		TakesFile:   true,
+	}
+
+	flagScanServerName = &cli.StringFlag{
+		Name:        "server-name",
+		Usage:       "Use to specify the server name sent in the TLS handshake (SNI) and checked against the certificate. Defaults to the host of each endpoint",
+		Destination: &scanOpts.serverName,
+	}
+
+	flagScanFormat = &cli.StringFlag{
+		Name:        "format",
+		Usage:       "Use to specify the output format of the report. Options include: text | json",
+		Destination: &scanOpts.format,
+		Value:       checkCertFormatText,
+	}
+
+	flagScanRenewBefore = &cli.StringFlag{
+		Name: "renew-before",
+		Usage: "Use to specify the renewal window used to report the renewal date, as a number of days, a percentage " +
+			"of the certificate lifetime or a duration. Example: --renew-before 30d",
+		Destination: &scanOpts.renewBefore,
+		Value:       domain.DefaultRenewBefore,
+	}
+
+	flagScanTimeout = &cli.IntFlag{
+		Name:        "timeout",
+		Usage:       "Use to specify the time in seconds to wait for the TLS handshake of each endpoint",
+		Destination: &scanOpts.timeout,
+		Value:       int(defaultScanTimeout / time.Second),
+	}
+
+	flagScanConcurrency = &cli.IntFlag{
+		Name:        "concurrency",
+		Usage:       "Use to specify the number of endpoints scanned at the same time",
+		Destination: &scanOpts.concurrency,
+		Value:       defaultScanConcurrency,
+	}
+
+	scanFlags = sortedFlags(flagsApppend(
+		flagScanHost,
+		flagScanHostsFile,
+		flagScanServerName,
+		flagScanFormat,
+		flagScanRenewBefore,
+		flagScanTimeout,
+		flagScanConcurrency,
+		flagCheckCertZone,
+		flagKey,
+		flagToken,
+		flagUrl,
+		flagConfig,
+		flagProfile,
+		flagTestMode,
+		flagTrustBundle,
+		commonFlags,
+	))
+)
+
+// scanReport is what scan finds out about an endpoint. The certificate fields are only set when the handshake succeeds
+type scanReport struct {
+	Endpoint        string `json:"endpoint"`
+	ServerName      string `json:"serverName"`
+	Error           string `json:"error,omitempty"`
+	Protocol        string `json:"protocol,omitempty"`
+	CipherSuite     string `json:"cipherSuite,omitempty"`
+	OCSPStapled     bool   `json:"ocspStapled"`
+	HostnameMatches bool   `json:"hostnameMatches"`
+	*checkCertReport
+
+	cert *x509.Certificate
+}
+
+func doCommandScan(c *cli.Context) error {
+	format := strings.ToLower(scanOpts.format)
+	if format != checkCertFormatText && format != checkCertFormatJSON {
+		return fmt.Errorf("unsupported format %q. Should be %s or %s", scanOpts.format, checkCertFormatText, checkCertFormatJSON)
+	}
+	window, err := domain.ParseRenewBefore(scanOpts.renewBefore)
+	if err != nil {
+		return fmt.Errorf("invalid --renew-before %q: %w", scanOpts.renewBefore, err)
+	}
+	if scanOpts.timeout <= 0 || scanOpts.concurrency <= 0 {
+		return fmt.Errorf("--timeout and --concurrency should be greater than 0")
+	}
+
+	endpoints := c.StringSlice("host")
+	if scanOpts.hostsFile != "" {
+		data, err := os.ReadFile(scanOpts.hostsFile)
+		if err != nil {
+			return fmt.Errorf("failed to read hosts file: %w", err)
+		}
+		endpoints = append(endpoints, parseScanEndpoints(string(data))...)
+	}
+	if len(endpoints) == 0 {
+		return fmt.Errorf("missing required flag --host or --hosts-file")
+	}
+
+	var roots *x509.CertPool
+	if flags.trustBundle != "" {
+		bundle, err := os.ReadFile(flags.trustBundle)
+		if err != nil {
+			return fmt.Errorf("failed to read trust bundle: %w", err)
+		}
+		roots = x509.NewCertPool()
+		roots.AppendCertsFromPEM(bundle)
+	}
+
+	// The zone configuration is read once, and every certificate is checked against it
+	var zoneConfig *endpoint.ZoneConfiguration
+	if flags.zone != "" {
+		zoneConfig, err = readZoneConfiguration(c)
+		if err != nil {
+			return err
+		}
+	}
+
+	reports := make([]scanReport, len(endpoints))
+	sem := make(chan struct{}, scanOpts.concurrency)
+	wg := sync.WaitGroup{}
+	for i, address := range endpoints {
+		wg.Add(1)
+		go func(i int, address string) {
+			defer wg.Done()
+			sem <- struct{}{}
+			defer func() { <-sem }()
+
+			reports[i] = scanEndpoint(address, scanOpts.serverName, time.Duration(scanOpts.timeout)*time.Second, roots, window, time.Now())
+		}(i, address)
+	}
+	wg.Wait()
+
+	if zoneConfig != nil {
+		for _, r := range reports {
+			if r.checkCertReport != nil {
+				checkCertificatePolicy(zoneConfig, flags.zone, r.cert, r.checkCertReport)
+			}
+		}
+	}
+
+	if format == checkCertFormatJSON {
+		err = writeScanJSON(os.Stdout, reports)
+	} else {
+		err = writeScanText(os.Stdout, reports)
+	}
+	if err != nil {
+		return err
+	}
+
+	var errs []error
+	for _, r := range reports {
+		if err := r.problems(); err != nil {
+			errs = append(errs, fmt.Errorf("%s: %w", r.Endpoint, err))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// parseScanEndpoints returns the endpoints listed in data, one per line. Empty lines and comments are ignored
+func parseScanEndpoints(data string) []string {
+	var endpoints []string
+	scanner := bufio.NewScanner(strings.NewReader(data))
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		endpoints = append(endpoints, line)
+	}
+	return endpoints
+}
+
+// splitScanEndpoint returns the host and port of address, using the default port when address has none
+func splitScanEndpoint(address string) (host string, port string) {
+	host, port, err := net.SplitHostPort(address)
+	if err != nil {
+		// No port, which also covers IPv6 addresses without brackets
+		return strings.Trim(address, "[]"), defaultScanPort
+	}
+	return host, port
+}
+
+// scanEndpoint connects to address and inspects the certificate it presents at the time now. The chain is verified
+// against roots, or the system roots when nil, by buildCheckCertReport rather than by the handshake, so endpoints
+// with an invalid chain are reported as well
+func scanEndpoint(address string, serverName string, timeout time.Duration, roots *x509.CertPool, window domain.RenewWindow, now time.Time) scanReport {
+	host, port := splitScanEndpoint(address)
+	if serverName == "" {
+		serverName = host
+	}
+	report := scanReport{
+		Endpoint:   net.JoinHostPort(host, port),
+		ServerName: serverName,
+	}
+
+	dialer := &net.Dialer{Timeout: timeout}
+	// #nosec G402 -- the chain is verified afterwards, and old protocols are accepted so they can be reported
+	conn, err := tls.DialWithDialer(dialer, "tcp", report.Endpoint, &tls.Config{
+		ServerName:         serverName,
+		InsecureSkipVerify: true,
+		MinVersion:         tls.VersionTLS10,
+	})
+	if err != nil {
+		report.Error = err.Error()
+		return report
+	}
+	defer func() {
+		_ = conn.Close()
+	}()
+
+	state := conn.ConnectionState()
+	report.Protocol = tlsVersionName(state.Version)
+	report.CipherSuite = tls.CipherSuiteName(state.CipherSuite)
+	report.OCSPStapled = len(state.OCSPResponse) > 0
+	if len(state.PeerCertificates) == 0 {
+		report.Error = "no certificate presented"
+		return report
+	}
+
+	lc := &installer.LoadedCertificate{Format: "TLS", Certificate: state.PeerCertificates[0], Chain: state.PeerCertificates[1:]}
+	certReport := buildCheckCertReport(lc, roots, window, now)
+	report.checkCertReport = &certReport
+	report.cert = lc.Certificate
+	report.HostnameMatches = lc.Certificate.VerifyHostname(serverName) == nil
+	return report
+}
+
+func tlsVersionName(version uint16) string {
+	switch version {
+	case tls.VersionTLS10:
+		return "TLS 1.0"
+	case tls.VersionTLS11:
+		return "TLS 1.1"
+	case tls.VersionTLS12:
+		return "TLS 1.2"
+	case tls.VersionTLS13:
+		return "TLS 1.3"
+	default:
+		return fmt.Sprintf("0x%04X", version)
+	}
+}
+
+// problems returns an error describing why the endpoint is not fit for use, if it is not
+func (r scanReport) problems() error {
+	if r.Error != "" {
+		return fmt.Errorf("scan failed: %s", r.Error)
+	}
+	var errs []error
+	if !r.HostnameMatches {
+		errs = append(errs, fmt.Errorf("certificate is not valid for %s", r.ServerName))
+	}
+	return errors.Join(append(errs, r.checkCertReport.problems())...)
+}
+
+func writeScanJSON(out io.Writer, reports []scanReport) error {
+	enc := json.NewEncoder(out)
+	enc.SetIndent("", "  ")
+	return enc.Encode(reports)
+}
+
+func writeScanText(out io.Writer, reports []scanReport) error {
+	w := tabwriter.NewWriter(out, 0, 0, 2, ' ', 0)
+	for i, r := range reports {
+		if i > 0 {
+			fmt.Fprintln(w)
+		}
+		fmt.Fprintf(w, "Endpoint:\t%s\n", r.Endpoint)
+		fmt.Fprintf(w, "Server name:\t%s\n", r.ServerName)
+		if r.Error != "" {
+			fmt.Fprintf(w, "Error:\t%s\n", r.Error)
+			continue
+		}
+		fmt.Fprintf(w, "Protocol:\t%s\n", r.Protocol)
+		fmt.Fprintf(w, "Cipher suite:\t%s\n", r.CipherSuite)
+		fmt.Fprintf(w, "OCSP stapled:\t%s\n", yesNo(r.OCSPStapled))
+		fmt.Fprintf(w, "Hostname matches:\t%s\n", yesNo(r.HostnameMatches))
+		writeCheckCertFields(w, *r.checkCertReport)
+	}
+	return w.Flush()
+}
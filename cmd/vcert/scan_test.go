@@ -0,0 +1,117 @@
+/*
+ * Copyright 2023 Venafi, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *  http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package main
+
+import (
+	"bytes"
+	"crypto/x509"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/Venafi/vcert/v5/pkg/playbook/app/domain"
+)
+
+func TestScanEndpoint(t *testing.T) {
+	server := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	defer server.Close()
+	address := strings.TrimPrefix(server.URL, "https://")
+
+	window, _ := domain.ParseRenewBefore("30d")
+	roots := x509.NewCertPool()
+	roots.AddCert(server.Certificate())
+
+	// The certificate of httptest is valid for example.com
+	report := scanEndpoint(address, "example.com", time.Second*5, roots, window, time.Now())
+	if report.Error != "" {
+		t.Fatalf("unexpected scan error: %s", report.Error)
+	}
+	if report.Protocol != "TLS 1.3" || report.CipherSuite == "" {
+		t.Fatalf("unexpected protocol %s and cipher suite %s", report.Protocol, report.CipherSuite)
+	}
+	if !report.HostnameMatches || !report.ChainValid {
+		t.Fatalf("expected the hostname to match and the chain to be valid, got %s", report.ChainError)
+	}
+	if err := report.problems(); err != nil {
+		t.Fatalf("unexpected problems: %s", err)
+	}
+
+	out := bytes.Buffer{}
+	if err := writeScanText(&out, []scanReport{report}); err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(out.String(), "Cipher suite:") || !strings.Contains(out.String(), "example.com") {
+		t.Fatalf("unexpected report:\n%s", out.String())
+	}
+
+	// The server name defaults to the host, an IP address the certificate is also valid for
+	report = scanEndpoint(address, "", time.Second*5, x509.NewCertPool(), window, time.Now())
+	if !report.HostnameMatches || report.ChainValid {
+		t.Fatal("expected the hostname to match and the chain not to be valid")
+	}
+	err := report.problems()
+	if err == nil || !strings.Contains(err.Error(), "chain is not valid") {
+		t.Fatalf("expected the chain to be reported, got %v", err)
+	}
+}
+
+func TestScanEndpointError(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	address := strings.TrimPrefix(server.URL, "http://")
+	server.Close()
+
+	window, _ := domain.ParseRenewBefore(domain.DefaultRenewBefore)
+	report := scanEndpoint(address, "", time.Second, nil, window, time.Now())
+	if report.Error == "" || report.problems() == nil {
+		t.Fatal("expected the scan of a closed port to fail")
+	}
+
+	out := bytes.Buffer{}
+	if err := writeScanJSON(&out, []scanReport{report}); err != nil {
+		t.Fatal(err)
+	}
+	var decoded []map[string]interface{}
+	if err := json.Unmarshal(out.Bytes(), &decoded); err != nil {
+		t.Fatal(err)
+	}
+	if _, found := decoded[0]["subject"]; found || decoded[0]["error"] == "" {
+		t.Fatalf("unexpected JSON report %s", out.String())
+	}
+}
+
+func TestParseScanEndpoints(t *testing.T) {
+	endpoints := parseScanEndpoints("www.example.com\n\n  # comment\n10.20.30.40:8443\n[::1]:443\n")
+	if len(endpoints) != 3 {
+		t.Fatalf("unexpected endpoints %v", endpoints)
+	}
+
+	cases := map[string][2]string{
+		"www.example.com":      {"www.example.com", "443"},
+		"www.example.com:8443": {"www.example.com", "8443"},
+		"[::1]:8443":           {"::1", "8443"},
+		"::1":                  {"::1", "443"},
+	}
+	for address, expected := range cases {
+		host, port := splitScanEndpoint(address)
+		if host != expected[0] || port != expected[1] {
+			t.Errorf("%s: expected %s and %s, got %s and %s", address, expected[0], expected[1], host, port)
+		}
+	}
+}
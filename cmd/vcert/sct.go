@@ -0,0 +1,177 @@
+/*
+ * Copyright 2023 Venafi, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *  http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package main
+
+import (
+	"crypto/x509"
+	"encoding/pem"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/Venafi/vcert/v5/pkg/certificate"
+)
+
+const (
+	defaultCTLogList = "https://www.gstatic.com/ct/log_list/v3/log_list.json"
+	ctLogListTimeout = 30 * time.Second
+)
+
+// checkSCTs returns the SCTs embedded in the retrieved certificate, verified with the logs in --ct-log-list, when
+// --verify-sct or --require-sct is set. With --require-sct, publicly trusted certificates without a valid SCT are
+// rejected. Certificates issued by private CAs are not logged in CT, so they are only reported
+func checkSCTs(pcc *certificate.PEMCollection) ([]certificate.SignedCertificateTimestamp, error) {
+	if (!flags.verifySCT && !flags.requireSCT) || pcc == nil || pcc.Certificate == "" {
+		return nil, nil
+	}
+
+	cert, chain, err := parsePEMCollectionCerts(pcc)
+	if err != nil {
+		return nil, err
+	}
+	scts, err := certificate.ParseSCTs(cert)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read the SCTs of the certificate: %w", err)
+	}
+
+	verified := 0
+	if len(scts) > 0 {
+		verified, err = verifySCTs(cert, chain, scts)
+		if err != nil {
+			if flags.requireSCT {
+				return nil, err
+			}
+			logf("WARNING: could not verify SCTs: %s", err)
+		}
+	}
+	for _, sct := range scts {
+		status := "not verified"
+		if sct.Verified {
+			status = "verified by " + sct.LogDescription
+		}
+		logf("SCT from log %s issued at %s: %s", sct.LogID, sct.Timestamp.Format(time.RFC3339), status)
+	}
+	logf("Certificate has %d SCTs, %d verified", len(scts), verified)
+
+	if flags.requireSCT && verified == 0 {
+		_, err = cert.Verify(x509.VerifyOptions{Intermediates: certPool(chain), KeyUsages: []x509.ExtKeyUsage{x509.ExtKeyUsageAny}})
+		if err != nil {
+			logf("Certificate is not issued by a publicly trusted CA, skipping the SCT requirement")
+			return scts, nil
+		}
+		return nil, fmt.Errorf("publicly trusted certificate has no valid SCTs, so it may be rejected by browsers")
+	}
+	return scts, nil
+}
+
+// verifySCTs verifies the scts of cert with the logs in --ct-log-list and returns the number of valid SCTs
+func verifySCTs(cert *x509.Certificate, chain []*x509.Certificate, scts []certificate.SignedCertificateTimestamp) (int, error) {
+	var issuer *x509.Certificate
+	for _, c := range chain {
+		if cert.CheckSignatureFrom(c) == nil {
+			issuer = c
+			break
+		}
+	}
+	if issuer == nil {
+		return 0, fmt.Errorf("the issuer of the certificate is not in the chain")
+	}
+
+	logs, err := readCTLogList(flags.ctLogList)
+	if err != nil {
+		return 0, err
+	}
+
+	verified := 0
+	for i := range scts {
+		err = scts[i].Verify(cert, issuer, logs)
+		if err != nil {
+			logf("WARNING: %s", err)
+			continue
+		}
+		verified++
+	}
+	return verified, nil
+}
+
+// readCTLogList reads the CT log list from location, a URL or a file. Defaults to the list published by Google
+func readCTLogList(location string) ([]certificate.CTLog, error) {
+	if location == "" {
+		location = defaultCTLogList
+	}
+
+	var data []byte
+	var err error
+	if strings.HasPrefix(location, "https://") || strings.HasPrefix(location, "http://") {
+		client := &http.Client{
+			Timeout:   ctLogListTimeout,
+			Transport: &http.Transport{Proxy: http.ProxyFromEnvironment},
+		}
+		var res *http.Response
+		res, err = client.Get(location)
+		if err != nil {
+			return nil, fmt.Errorf("failed to retrieve CT log list: %w", err)
+		}
+		defer res.Body.Close()
+		if res.StatusCode != http.StatusOK {
+			return nil, fmt.Errorf("failed to retrieve CT log list: %s", res.Status)
+		}
+		data, err = io.ReadAll(res.Body)
+	} else {
+		data, err = os.ReadFile(location)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read CT log list: %w", err)
+	}
+	return certificate.ParseCTLogList(data)
+}
+
+func parsePEMCollectionCerts(pcc *certificate.PEMCollection) (*x509.Certificate, []*x509.Certificate, error) {
+	block, _ := pem.Decode([]byte(pcc.Certificate))
+	if block == nil || block.Type != "CERTIFICATE" {
+		return nil, nil, fmt.Errorf("could not decode the certificate")
+	}
+	cert, err := x509.ParseCertificate(block.Bytes)
+	if err != nil {
+		return nil, nil, fmt.Errorf("could not parse the certificate: %w", err)
+	}
+
+	chain := make([]*x509.Certificate, 0, len(pcc.Chain))
+	for _, c := range pcc.Chain {
+		block, _ = pem.Decode([]byte(c))
+		if block == nil {
+			continue
+		}
+		chainCert, err := x509.ParseCertificate(block.Bytes)
+		if err != nil {
+			return nil, nil, fmt.Errorf("could not parse chain certificate: %w", err)
+		}
+		chain = append(chain, chainCert)
+	}
+	return cert, chain, nil
+}
+
+func certPool(certs []*x509.Certificate) *x509.CertPool {
+	pool := x509.NewCertPool()
+	for _, c := range certs {
+		pool.AddCert(c)
+	}
+	return pool
+}
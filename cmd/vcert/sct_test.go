@@ -0,0 +1,93 @@
+/*
+ * Copyright 2023 Venafi, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *  http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package main
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/Venafi/vcert/v5/pkg/certificate"
+)
+
+func TestCheckSCTsPrivateCA(t *testing.T) {
+	now := time.Now()
+	root, leaf := newTestChain(t, now.Add(-time.Hour), now.AddDate(0, 0, 90))
+	pcc := &certificate.PEMCollection{
+		Certificate: string(pemCertificate(leaf.Cert)),
+		Chain:       []string{string(pemCertificate(root.Cert))},
+	}
+
+	defer func(verify bool, require bool) {
+		flags.verifySCT, flags.requireSCT = verify, require
+	}(flags.verifySCT, flags.requireSCT)
+
+	flags.verifySCT, flags.requireSCT = false, false
+	scts, err := checkSCTs(pcc)
+	if err != nil || scts != nil {
+		t.Fatalf("expected SCTs not to be checked, got %v: %v", scts, err)
+	}
+
+	// certificates of private CAs are not logged, so they are accepted without SCTs
+	flags.requireSCT = true
+	scts, err = checkSCTs(pcc)
+	if err != nil {
+		t.Fatalf("unexpected error checking SCTs: %s", err)
+	}
+	if len(scts) != 0 {
+		t.Fatalf("expected no SCTs, got %v", scts)
+	}
+}
+
+func TestReadCTLogList(t *testing.T) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	der, err := x509.MarshalPKIXPublicKey(&key.PublicKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	logID := sha256.Sum256(der)
+	path := filepath.Join(t.TempDir(), "log_list.json")
+	err = os.WriteFile(path, []byte(fmt.Sprintf(`{"operators": [{"name": "Test", "logs": [{"description": "Test Log", "log_id": "%s", "key": "%s"}]}]}`,
+		base64.StdEncoding.EncodeToString(logID[:]), base64.StdEncoding.EncodeToString(der))), 0600)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	logs, err := readCTLogList(path)
+	if err != nil {
+		t.Fatalf("could not read CT log list: %s", err)
+	}
+	if len(logs) != 1 || logs[0].Description != "Test Log" || string(logs[0].ID) != string(logID[:]) {
+		t.Fatalf("unexpected logs: %+v", logs)
+	}
+
+	_, err = readCTLogList(filepath.Join(t.TempDir(), "missing.json"))
+	if err == nil {
+		t.Fatal("expected an error reading a missing log list")
+	}
+}
@@ -0,0 +1,150 @@
+/*
+ * Copyright 2023 Venafi, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *  http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package main
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/howeyc/gopass"
+	"github.com/urfave/cli/v2"
+
+	"github.com/Venafi/vcert/v5/pkg/playbook/app/secret"
+)
+
+const (
+	commandEncryptSecretName = "encrypt-secret"
+)
+
+var commandEncryptSecret = &cli.Command{
+	Name:   commandEncryptSecretName,
+	Flags:  encryptSecretFlags,
+	Action: doCommandEncryptSecret,
+	Usage: "To encrypt a credential for a playbook, so the playbook can be checked into source control without " +
+		"plaintext secrets. The value printed is set in the playbook after the !encrypted tag and decrypted by vcert run",
+	UsageText: ` vcert encrypt-secret
+		 echo -n "$TPP_PASSWORD" | vcert encrypt-secret
+		 vcert encrypt-secret --key passphrase --passphrase file:/path-to/passphrase.txt`,
+}
+
+type encryptSecretOptions struct {
+	key        string
+	passphrase string
+}
+
+var (
+	encryptSecretOpts = encryptSecretOptions{}
+
+	flagEncryptSecretKey = &cli.StringFlag{
+		Name: "key",
+		Usage: "Use to specify the key encrypting the credential. Options include: machine | passphrase. The machine key " +
+			"is protected by DPAPI on Windows and kept in the keyring of the user by libsecret on Linux, so only the " +
+			"same user on the same machine can decrypt the value. Values encrypted with a passphrase are decrypted " +
+			"with the passphrase set in the " + secret.EnvPassphrase + " environment variable",
+		Destination: &encryptSecretOpts.key,
+		Value:       secret.KeyMachine,
+	}
+
+	flagEncryptSecretPassphrase = &cli.StringFlag{
+		Name: "passphrase",
+		Usage: "Use to specify the passphrase of --key passphrase. Defaults to the " + secret.EnvPassphrase +
+			" environment variable, and is prompted for otherwise. Example: --passphrase file:/path-to/passphrase.txt",
+		Destination: &encryptSecretOpts.passphrase,
+	}
+
+	encryptSecretFlags = sortedFlags(flagsApppend(
+		flagEncryptSecretKey,
+		flagEncryptSecretPassphrase,
+	))
+)
+
+func doCommandEncryptSecret(c *cli.Context) error {
+	return encryptSecret(os.Stdin, os.Stdout)
+}
+
+// encryptSecret reads the credential from in, prompting for it when in is a terminal, and writes it encrypted to out
+func encryptSecret(in *os.File, out io.Writer) error {
+	key := strings.ToLower(encryptSecretOpts.key)
+	passphrase := ""
+	if key == secret.KeyPassphrase {
+		var err error
+		passphrase, err = readSecretPassphrase(in)
+		if err != nil {
+			return err
+		}
+	}
+
+	plaintext, err := readSecretInput(in, "Enter the secret to encrypt:")
+	if err != nil {
+		return err
+	}
+	if plaintext == "" {
+		return fmt.Errorf("the secret to encrypt is empty")
+	}
+
+	value, err := secret.Encrypt(plaintext, key, passphrase)
+	if err != nil {
+		return err
+	}
+	_, err = fmt.Fprintf(out, "%s %s\n", secret.Tag, value)
+	return err
+}
+
+func readSecretPassphrase(in *os.File) (string, error) {
+	if encryptSecretOpts.passphrase != "" {
+		return readPasswordsFromInputFlag(encryptSecretOpts.passphrase, 0)
+	}
+	if passphrase := os.Getenv(secret.EnvPassphrase); passphrase != "" {
+		return passphrase, nil
+	}
+	if !isTerminal(in) {
+		return "", fmt.Errorf("--passphrase or the %s environment variable is required when the secret is read from the standard input", secret.EnvPassphrase)
+	}
+	return readSecretInput(in, "Enter the passphrase:")
+}
+
+// readSecretInput prompts twice for a value on a terminal, and reads all of in otherwise
+func readSecretInput(in *os.File, prompt string) (string, error) {
+	if !isTerminal(in) {
+		data, err := io.ReadAll(in)
+		if err != nil {
+			return "", err
+		}
+		return strings.TrimRight(string(data), "\r\n"), nil
+	}
+
+	// the prompts go to stderr, so stdout only holds the encrypted value
+	input, err := gopass.GetPasswdPrompt(prompt, true, in, os.Stderr)
+	if err != nil {
+		return "", err
+	}
+	verify, err := gopass.GetPasswdPrompt("Verifying - "+prompt, true, in, os.Stderr)
+	if err != nil {
+		return "", err
+	}
+	if !doValuesMatch(input, verify) {
+		return "", fmt.Errorf("values don't match")
+	}
+	return string(input), nil
+}
+
+func isTerminal(f *os.File) bool {
+	fi, err := f.Stat()
+	return err == nil && fi.Mode()&os.ModeCharDevice != 0
+}
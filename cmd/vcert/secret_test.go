@@ -0,0 +1,65 @@
+/*
+ * Copyright 2023 Venafi, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *  http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package main
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/Venafi/vcert/v5/pkg/playbook/app/secret"
+)
+
+func TestEncryptSecret(t *testing.T) {
+	inFile := filepath.Join(t.TempDir(), "secret")
+	err := os.WriteFile(inFile, []byte("s3cr3t!\n"), 0600)
+	if err != nil {
+		t.Fatal(err)
+	}
+	in, err := os.Open(inFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer in.Close()
+
+	t.Setenv(secret.EnvPassphrase, "")
+	encryptSecretOpts = encryptSecretOptions{key: secret.KeyPassphrase}
+	err = encryptSecret(in, &bytes.Buffer{})
+	if err == nil || !strings.Contains(err.Error(), "--passphrase") {
+		t.Fatalf("expected an error for the missing passphrase, got %v", err)
+	}
+
+	var out bytes.Buffer
+	encryptSecretOpts = encryptSecretOptions{key: secret.KeyPassphrase, passphrase: "pass:correct horse"}
+	err = encryptSecret(in, &out)
+	if err != nil {
+		t.Fatal(err)
+	}
+	value, found := strings.CutPrefix(strings.TrimSpace(out.String()), secret.Tag+" ")
+	if !found {
+		t.Fatalf("expected a value tagged %s, got %q", secret.Tag, out.String())
+	}
+	plaintext, err := secret.Decrypt(value, "correct horse")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if plaintext != "s3cr3t!" {
+		t.Fatalf("expected the secret without the line break, got %q", plaintext)
+	}
+}
@@ -0,0 +1,270 @@
+/*
+ * Copyright 2023 Venafi, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *  http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package main
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/urfave/cli/v2"
+
+	"github.com/Venafi/vcert/v5/pkg/playbook/app/service"
+)
+
+const (
+	commandServiceName = "service"
+
+	// defaultServiceName is the name of the service running the playbook daemon when --name is not set
+	defaultServiceName = "vcert"
+	// serviceDescription describes the service in the systemd unit and the Windows service manager
+	serviceDescription = "Venafi VCert playbook daemon"
+)
+
+var commandService = &cli.Command{
+	Name: commandServiceName,
+	Usage: "To run a playbook as a service: a systemd unit on Linux or a Windows service, running vcert run --daemon " +
+		"with the given playbook file, started at boot",
+	UsageText: ` vcert service install --file /etc/vcert/playbook.yaml
+		 vcert service install --file /etc/vcert/playbook.yaml --user vcert --log-file /var/log/vcert.log
+		 vcert service install --file /etc/vcert/playbook.yaml --print > /etc/systemd/system/vcert.service
+		 vcert service status
+		 vcert service stop
+		 vcert service start
+		 vcert service uninstall`,
+	Subcommands: []*cli.Command{
+		{
+			Name:   "install",
+			Usage:  "To register the service, enable it at boot and start it",
+			Flags:  serviceInstallFlags,
+			Action: doServiceInstall,
+		},
+		{
+			Name:   "uninstall",
+			Usage:  "To stop the service and remove it",
+			Flags:  serviceFlags,
+			Action: doServiceUninstall,
+		},
+		{
+			Name:   "start",
+			Usage:  "To start the service",
+			Flags:  serviceFlags,
+			Action: doServiceStart,
+		},
+		{
+			Name:   "stop",
+			Usage:  "To stop the service",
+			Flags:  serviceFlags,
+			Action: doServiceStop,
+		},
+		{
+			Name:   "status",
+			Usage:  "To print the status of the service",
+			Flags:  serviceFlags,
+			Action: doServiceStatus,
+		},
+	},
+}
+
+type serviceOptions struct {
+	filepath  string
+	jitter    time.Duration
+	logFile   string
+	metrics   string
+	name      string
+	print     bool
+	stateFile string
+	user      string
+}
+
+var (
+	serviceOpts = serviceOptions{}
+
+	flagServiceName = &cli.StringFlag{
+		Name:        "name",
+		Usage:       "the name of the service",
+		Value:       defaultServiceName,
+		Destination: &serviceOpts.name,
+	}
+
+	flagServiceFile = &cli.StringFlag{
+		Name:        "file",
+		Aliases:     []string{"f"},
+		Usage:       "the path to the playbook file run by the service",
+		Required:    true,
+		Destination: &serviceOpts.filepath,
+	}
+
+	flagServiceJitter = &cli.DurationFlag{
+		Name:        "jitter",
+		Usage:       "maximum random delay added to every scheduled task run, e.g. 5m",
+		Value:       service.DefaultJitter,
+		Destination: &serviceOpts.jitter,
+	}
+
+	flagServiceLogFile = &cli.StringFlag{
+		Name:        "log-file",
+		Usage:       "the path to the file the service logs to. Recommended on Windows, where services have no console",
+		Destination: &serviceOpts.logFile,
+	}
+
+	flagServiceMetricsListen = &cli.StringFlag{
+		Name:        "metrics-listen",
+		Usage:       "address on which the service serves Prometheus metrics at /metrics, e.g. :9090",
+		Destination: &serviceOpts.metrics,
+	}
+
+	flagServicePrint = &cli.BoolFlag{
+		Name:        "print",
+		Usage:       "prints the systemd unit instead of installing it. Not supported on Windows",
+		Destination: &serviceOpts.print,
+	}
+
+	flagServiceStateFile = &cli.StringFlag{
+		Name:        "state-file",
+		Usage:       "the path to the state file of the service. Overrides stateFile in the playbook config",
+		Destination: &serviceOpts.stateFile,
+	}
+
+	flagServiceUser = &cli.StringFlag{
+		Name:        "user",
+		Usage:       "the user running the service. Defaults to root on Linux and LocalSystem on Windows",
+		Destination: &serviceOpts.user,
+	}
+
+	serviceFlags = flagsApppend(
+		flagServiceName,
+	)
+
+	serviceInstallFlags = sortedFlags(flagsApppend(
+		flagServiceFile,
+		flagServiceJitter,
+		flagServiceLogFile,
+		flagServiceMetricsListen,
+		flagServiceName,
+		flagServicePrint,
+		flagServiceStateFile,
+		flagServiceUser,
+	))
+)
+
+func doServiceInstall(_ *cli.Context) error {
+	executable, err := serviceExecutable()
+	if err != nil {
+		return err
+	}
+	args, err := serviceArgs(serviceOpts)
+	if err != nil {
+		return err
+	}
+	return installService(serviceOpts, executable, args)
+}
+
+func doServiceUninstall(_ *cli.Context) error {
+	return uninstallService(serviceOpts.name)
+}
+
+func doServiceStart(_ *cli.Context) error {
+	return startService(serviceOpts.name)
+}
+
+func doServiceStop(_ *cli.Context) error {
+	return stopService(serviceOpts.name)
+}
+
+func doServiceStatus(_ *cli.Context) error {
+	return printServiceStatus(serviceOpts.name)
+}
+
+// serviceExecutable returns the absolute path of the running vcert binary, which is the binary the service runs
+func serviceExecutable() (string, error) {
+	executable, err := os.Executable()
+	if err != nil {
+		return "", fmt.Errorf("could not find the path of the vcert binary: %w", err)
+	}
+	return filepath.EvalSymlinks(executable)
+}
+
+// serviceArgs returns the arguments of vcert run for the service. Paths are made absolute, as services do not run in
+// the current directory
+func serviceArgs(opts serviceOptions) ([]string, error) {
+	playbookFile, err := filepath.Abs(opts.filepath)
+	if err != nil {
+		return nil, err
+	}
+	if _, err = os.Stat(playbookFile); err != nil {
+		return nil, fmt.Errorf("could not read the playbook file: %w", err)
+	}
+
+	args := []string{commandRunPlaybookName, "--daemon", "--file", playbookFile}
+	if opts.jitter != service.DefaultJitter {
+		args = append(args, "--jitter", opts.jitter.String())
+	}
+	if opts.metrics != "" {
+		args = append(args, "--metrics-listen", opts.metrics)
+	}
+	for _, file := range []struct{ flag, path string }{{"--state-file", opts.stateFile}, {"--log-file", opts.logFile}} {
+		if file.path == "" {
+			continue
+		}
+		path, err := filepath.Abs(file.path)
+		if err != nil {
+			return nil, err
+		}
+		args = append(args, file.flag, path)
+	}
+	return args, nil
+}
+
+// systemdUnit returns the systemd unit running the vcert binary executable with args. The unit reloads the playbook
+// with SIGHUP, and restarts the daemon when it fails
+func systemdUnit(opts serviceOptions, executable string, args []string) string {
+	command := make([]string, 0, len(args)+1)
+	for _, arg := range append([]string{executable}, args...) {
+		command = append(command, systemdQuote(arg))
+	}
+
+	var unit strings.Builder
+	unit.WriteString("[Unit]\n")
+	unit.WriteString("Description=" + serviceDescription + "\n")
+	unit.WriteString("Wants=network-online.target\n")
+	unit.WriteString("After=network-online.target\n")
+	unit.WriteString("\n[Service]\n")
+	unit.WriteString("Type=simple\n")
+	unit.WriteString("ExecStart=" + strings.Join(command, " ") + "\n")
+	unit.WriteString("ExecReload=/bin/kill -HUP $MAINPID\n")
+	unit.WriteString("Restart=on-failure\n")
+	unit.WriteString("RestartSec=30\n")
+	if opts.user != "" {
+		unit.WriteString("User=" + opts.user + "\n")
+	}
+	unit.WriteString("\n[Install]\n")
+	unit.WriteString("WantedBy=multi-user.target\n")
+	return unit.String()
+}
+
+// systemdQuote escapes the specifiers and variables systemd expands in command lines, and quotes arg when it has
+// spaces
+func systemdQuote(arg string) string {
+	arg = strings.NewReplacer(`\`, `\\`, `"`, `\"`, "%", "%%", "$", "$$").Replace(arg)
+	if strings.ContainsAny(arg, " \t'") {
+		return `"` + arg + `"`
+	}
+	return arg
+}
@@ -0,0 +1,115 @@
+//go:build !windows
+
+/*
+ * Copyright 2023 Venafi, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *  http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+
+	"go.uber.org/zap"
+)
+
+// systemdUnitDir is the directory of the units installed by the administrator
+const systemdUnitDir = "/etc/systemd/system"
+
+// systemdUnitFile returns the path of the unit of the service name
+func systemdUnitFile(name string) string {
+	return filepath.Join(systemdUnitDir, name+".service")
+}
+
+// installService writes the systemd unit of the service, or prints it when --print is set, and enables and starts it
+func installService(opts serviceOptions, executable string, args []string) error {
+	unit := systemdUnit(opts, executable, args)
+	if opts.print {
+		_, err := fmt.Print(unit)
+		return err
+	}
+
+	unitFile := systemdUnitFile(opts.name)
+	err := os.WriteFile(unitFile, []byte(unit), 0644)
+	if err != nil {
+		return fmt.Errorf("could not write the systemd unit: %w", err)
+	}
+	zap.L().Info("systemd unit written", zap.String("file", unitFile))
+
+	err = systemctl("daemon-reload")
+	if err != nil {
+		return err
+	}
+	err = systemctl("enable", "--now", opts.name)
+	if err != nil {
+		return err
+	}
+	zap.L().Info("service enabled and started", zap.String("service", opts.name))
+	return nil
+}
+
+// uninstallService stops and disables the service, and removes its systemd unit
+func uninstallService(name string) error {
+	err := systemctl("disable", "--now", name)
+	if err != nil {
+		return err
+	}
+	unitFile := systemdUnitFile(name)
+	err = os.Remove(unitFile)
+	if err != nil && !errors.Is(err, os.ErrNotExist) {
+		return fmt.Errorf("could not remove the systemd unit: %w", err)
+	}
+	err = systemctl("daemon-reload")
+	if err != nil {
+		return err
+	}
+	zap.L().Info("service removed", zap.String("service", name))
+	return nil
+}
+
+func startService(name string) error {
+	return systemctl("start", name)
+}
+
+func stopService(name string) error {
+	return systemctl("stop", name)
+}
+
+// printServiceStatus prints the status of the service as reported by systemctl. systemctl exits with a non-zero
+// status when the service is not running, which is reported as an error
+func printServiceStatus(name string) error {
+	return systemctl("status", "--no-pager", name)
+}
+
+// systemctl runs systemctl with args, writing its output to the console
+func systemctl(args ...string) error {
+	cmd := exec.Command("systemctl", args...)
+	cmd.Stdout = os.Stdout
+	cmd.Stderr = os.Stderr
+	err := cmd.Run()
+	if err != nil {
+		return fmt.Errorf("systemctl %s failed: %w", args[0], err)
+	}
+	return nil
+}
+
+// serviceContext returns ctx as is. systemd stops the daemon with SIGTERM, which already cancels ctx
+func serviceContext(ctx context.Context) (context.Context, func()) {
+	return ctx, func() {}
+}
@@ -0,0 +1,82 @@
+/*
+ * Copyright 2023 Venafi, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *  http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/Venafi/vcert/v5/pkg/playbook/app/service"
+)
+
+func TestServiceArgs(t *testing.T) {
+	dir := t.TempDir()
+	playbookFile := filepath.Join(dir, "playbook.yaml")
+	err := os.WriteFile(playbookFile, []byte("config: {}\n"), 0600)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	_, err = serviceArgs(serviceOptions{filepath: filepath.Join(dir, "missing.yaml"), jitter: service.DefaultJitter})
+	if err == nil {
+		t.Fatal("expected an error for a missing playbook file")
+	}
+
+	args, err := serviceArgs(serviceOptions{filepath: playbookFile, jitter: service.DefaultJitter})
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := "run --daemon --file " + playbookFile
+	if strings.Join(args, " ") != expected {
+		t.Fatalf("expected %q, got %q", expected, strings.Join(args, " "))
+	}
+
+	args, err = serviceArgs(serviceOptions{filepath: playbookFile, jitter: 10 * time.Minute, metrics: ":9090",
+		stateFile: filepath.Join(dir, "state.json")})
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected = "run --daemon --file " + playbookFile + " --jitter 10m0s --metrics-listen :9090 --state-file " +
+		filepath.Join(dir, "state.json")
+	if strings.Join(args, " ") != expected {
+		t.Fatalf("expected %q, got %q", expected, strings.Join(args, " "))
+	}
+}
+
+func TestSystemdUnit(t *testing.T) {
+	unit := systemdUnit(serviceOptions{user: "vcert"}, "/usr/local/bin/vcert",
+		[]string{"run", "--daemon", "--file", "/etc/vcert/my playbook.yaml", "--log-file", "/var/log/vcert-%i.log"})
+
+	for _, line := range []string{
+		`ExecStart=/usr/local/bin/vcert run --daemon --file "/etc/vcert/my playbook.yaml" --log-file /var/log/vcert-%%i.log`,
+		"ExecReload=/bin/kill -HUP $MAINPID",
+		"User=vcert",
+		"WantedBy=multi-user.target",
+	} {
+		if !strings.Contains(unit, line+"\n") {
+			t.Errorf("expected the unit to have line %q, got:\n%s", line, unit)
+		}
+	}
+
+	unit = systemdUnit(serviceOptions{}, "/usr/local/bin/vcert", []string{"run"})
+	if strings.Contains(unit, "User=") {
+		t.Errorf("expected no User= line without a user, got:\n%s", unit)
+	}
+}
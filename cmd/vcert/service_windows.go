@@ -0,0 +1,260 @@
+//go:build windows
+
+/*
+ * Copyright 2023 Venafi, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *  http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package main
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"go.uber.org/zap"
+	"golang.org/x/sys/windows/svc"
+	"golang.org/x/sys/windows/svc/mgr"
+)
+
+// serviceStopTimeout is how long vcert service stop and uninstall wait for the service to stop
+const serviceStopTimeout = 60 * time.Second
+
+// installService registers the Windows service, started automatically at boot, and starts it
+func installService(opts serviceOptions, executable string, args []string) error {
+	if opts.print {
+		return fmt.Errorf("--print is only supported for systemd units")
+	}
+
+	m, err := mgr.Connect()
+	if err != nil {
+		return fmt.Errorf("could not connect to the service manager: %w", err)
+	}
+	defer m.Disconnect()
+
+	s, err := m.CreateService(opts.name, executable, mgr.Config{
+		Description:      serviceDescription,
+		DisplayName:      serviceDescription,
+		ServiceStartName: opts.user,
+		StartType:        mgr.StartAutomatic,
+	}, args...)
+	if err != nil {
+		return fmt.Errorf("could not create service %s: %w", opts.name, err)
+	}
+	defer s.Close()
+
+	// The daemon is restarted when it fails, like with Restart=on-failure in the systemd unit
+	err = s.SetRecoveryActions([]mgr.RecoveryAction{{Type: mgr.ServiceRestart, Delay: 30 * time.Second}}, 24*60*60)
+	if err != nil {
+		zap.L().Warn("could not set the recovery actions of the service", zap.String("service", opts.name), zap.Error(err))
+	}
+	zap.L().Info("service created", zap.String("service", opts.name))
+
+	err = s.Start()
+	if err != nil {
+		return fmt.Errorf("could not start service %s: %w", opts.name, err)
+	}
+	zap.L().Info("service started", zap.String("service", opts.name))
+	return nil
+}
+
+// uninstallService stops the Windows service and removes it
+func uninstallService(name string) error {
+	m, s, err := openService(name)
+	if err != nil {
+		return err
+	}
+	defer m.Disconnect()
+	defer s.Close()
+
+	err = stopAndWait(s)
+	if err != nil {
+		return err
+	}
+	err = s.Delete()
+	if err != nil {
+		return fmt.Errorf("could not remove service %s: %w", name, err)
+	}
+	zap.L().Info("service removed", zap.String("service", name))
+	return nil
+}
+
+func startService(name string) error {
+	m, s, err := openService(name)
+	if err != nil {
+		return err
+	}
+	defer m.Disconnect()
+	defer s.Close()
+
+	err = s.Start()
+	if err != nil {
+		return fmt.Errorf("could not start service %s: %w", name, err)
+	}
+	return nil
+}
+
+func stopService(name string) error {
+	m, s, err := openService(name)
+	if err != nil {
+		return err
+	}
+	defer m.Disconnect()
+	defer s.Close()
+
+	return stopAndWait(s)
+}
+
+// printServiceStatus prints the state of the Windows service
+func printServiceStatus(name string) error {
+	m, s, err := openService(name)
+	if err != nil {
+		return err
+	}
+	defer m.Disconnect()
+	defer s.Close()
+
+	status, err := s.Query()
+	if err != nil {
+		return fmt.Errorf("could not query service %s: %w", name, err)
+	}
+	fmt.Printf("%s: %s\n", name, serviceStateString(status.State))
+	return nil
+}
+
+// openService connects to the service manager and opens the service name
+func openService(name string) (*mgr.Mgr, *mgr.Service, error) {
+	m, err := mgr.Connect()
+	if err != nil {
+		return nil, nil, fmt.Errorf("could not connect to the service manager: %w", err)
+	}
+	s, err := m.OpenService(name)
+	if err != nil {
+		_ = m.Disconnect()
+		return nil, nil, fmt.Errorf("could not open service %s: %w", name, err)
+	}
+	return m, s, nil
+}
+
+// stopAndWait asks the service to stop, and waits until it is stopped. Stopping a stopped service is not an error
+func stopAndWait(s *mgr.Service) error {
+	status, err := s.Query()
+	if err != nil {
+		return fmt.Errorf("could not query service %s: %w", s.Name, err)
+	}
+	if status.State == svc.Stopped {
+		return nil
+	}
+
+	status, err = s.Control(svc.Stop)
+	if err != nil {
+		return fmt.Errorf("could not stop service %s: %w", s.Name, err)
+	}
+	deadline := time.Now().Add(serviceStopTimeout)
+	for status.State != svc.Stopped {
+		if time.Now().After(deadline) {
+			return fmt.Errorf("service %s did not stop after %s", s.Name, serviceStopTimeout)
+		}
+		time.Sleep(500 * time.Millisecond)
+		status, err = s.Query()
+		if err != nil {
+			return fmt.Errorf("could not query service %s: %w", s.Name, err)
+		}
+	}
+	zap.L().Info("service stopped", zap.String("service", s.Name))
+	return nil
+}
+
+func serviceStateString(state svc.State) string {
+	switch state {
+	case svc.Stopped:
+		return "stopped"
+	case svc.StartPending:
+		return "starting"
+	case svc.StopPending:
+		return "stopping"
+	case svc.Running:
+		return "running"
+	case svc.ContinuePending:
+		return "resuming"
+	case svc.PausePending:
+		return "pausing"
+	case svc.Paused:
+		return "paused"
+	default:
+		return fmt.Sprintf("unknown (%d)", state)
+	}
+}
+
+// windowsService reports the state of the playbook daemon to the service manager, and stops the daemon when the
+// service is stopped or the system shuts down
+type windowsService struct {
+	cancel context.CancelFunc
+	done   chan struct{}
+}
+
+// Execute implements svc.Handler
+func (s *windowsService) Execute(_ []string, requests <-chan svc.ChangeRequest, status chan<- svc.Status) (bool, uint32) {
+	status <- svc.Status{State: svc.Running, Accepts: svc.AcceptStop | svc.AcceptShutdown}
+	for {
+		select {
+		case <-s.done:
+			// The daemon stopped by itself
+			status <- svc.Status{State: svc.StopPending}
+			return false, 0
+		case request := <-requests:
+			switch request.Cmd {
+			case svc.Interrogate:
+				status <- request.CurrentStatus
+			case svc.Stop, svc.Shutdown:
+				zap.L().Info("received service stop request")
+				status <- svc.Status{State: svc.StopPending}
+				s.cancel()
+				<-s.done
+				return false, 0
+			}
+		}
+	}
+}
+
+// serviceContext returns a context cancelled when the Windows service running the daemon is stopped, along with the
+// function reporting the service as stopped once the daemon has finished. ctx is returned as is when vcert does not
+// run as a Windows service
+func serviceContext(ctx context.Context) (context.Context, func()) {
+	isService, err := svc.IsWindowsService()
+	if err != nil {
+		zap.L().Warn("could not determine whether vcert runs as a Windows service", zap.Error(err))
+	}
+	if !isService {
+		return ctx, func() {}
+	}
+
+	ctx, cancel := context.WithCancel(ctx)
+	handler := &windowsService{cancel: cancel, done: make(chan struct{})}
+	stopped := make(chan struct{})
+	go func() {
+		defer close(stopped)
+		// The name is ignored for services running in their own process
+		err := svc.Run(defaultServiceName, handler)
+		if err != nil {
+			zap.L().Error("could not run as a Windows service", zap.Error(err))
+			cancel()
+		}
+	}()
+	return ctx, func() {
+		close(handler.done)
+		<-stopped
+		cancel()
+	}
+}
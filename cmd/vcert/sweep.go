@@ -0,0 +1,195 @@
+/*
+ * Copyright 2023 Venafi, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *  http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/urfave/cli/v2"
+
+	"github.com/Venafi/vcert/v5"
+	"github.com/Venafi/vcert/v5/pkg/certificate"
+	"github.com/Venafi/vcert/v5/pkg/playbook/app/audit"
+	"github.com/Venafi/vcert/v5/pkg/venafi/tpp"
+)
+
+// sweepResult is the outcome of the renewal of an expiring certificate
+type sweepResult struct {
+	DN         string    `json:"dn"`
+	CommonName string    `json:"cn"`
+	Serial     string    `json:"serial,omitempty"`
+	ValidTo    time.Time `json:"validTo"`
+	PickupID   string    `json:"pickupId,omitempty"`
+	Error      string    `json:"error,omitempty"`
+}
+
+// sweepSummary holds the results of renew --all-expiring, in the order the certificates expire
+type sweepSummary struct {
+	Zone          string        `json:"zone"`
+	ExpiresBefore time.Time     `json:"expiresBefore"`
+	Total         int           `json:"total"`
+	Succeeded     int           `json:"succeeded"`
+	Failed        int           `json:"failed"`
+	Duration      string        `json:"duration"`
+	Results       []sweepResult `json:"results"`
+}
+
+// certificateRenewer requests the renewal of a certificate, as endpoint.Connector does
+type certificateRenewer interface {
+	RenewCertificate(ctx context.Context, req *certificate.RenewalRequest) (string, error)
+}
+
+// sweeper renews the certificates found by renew --all-expiring
+type sweeper struct {
+	renewer  certificateRenewer
+	platform string
+	zone     string
+	auditLog *audit.Log
+}
+
+// run requests the renewal of certificates, concurrency at a time
+func (s *sweeper) run(ctx context.Context, certificates []tpp.CertificateSearchInfo, concurrency int, progress *batchProgress) *sweepSummary {
+	start := time.Now()
+	summary := &sweepSummary{Zone: s.zone, Total: len(certificates), Results: make([]sweepResult, len(certificates))}
+	if concurrency > len(certificates) {
+		concurrency = len(certificates)
+	}
+
+	indexes := make(chan int)
+	wg := sync.WaitGroup{}
+	for w := 0; w < concurrency; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range indexes {
+				summary.Results[i] = s.renew(ctx, certificates[i])
+				progress.update(summary.Results[i].Error != "")
+			}
+		}()
+	}
+	for i := range certificates {
+		indexes <- i
+	}
+	close(indexes)
+	wg.Wait()
+	progress.finish()
+
+	for _, result := range summary.Results {
+		if result.Error != "" {
+			summary.Failed++
+		} else {
+			summary.Succeeded++
+		}
+	}
+	summary.Duration = time.Since(start).Round(time.Millisecond).String()
+	return summary
+}
+
+// renew requests the renewal of info without a CSR, so the platform generates the new key pair, or reuses the CSR of
+// the certificate, according to its policy
+func (s *sweeper) renew(ctx context.Context, info tpp.CertificateSearchInfo) sweepResult {
+	result := sweepResult{DN: info.DN, CommonName: info.X509.CN, Serial: info.X509.Serial, ValidTo: info.X509.ValidTo}
+
+	pickupID, err := s.renewer.RenewCertificate(ctx, &certificate.RenewalRequest{CertificateDN: info.DN})
+	recordAudit(s.auditLog, audit.Event{
+		Operation:  audit.OperationRenew,
+		Platform:   s.platform,
+		Zone:       s.zone,
+		CommonName: info.X509.CN,
+		Parameters: map[string]string{"dn": info.DN, "allExpiring": "true"},
+		PickupID:   pickupID,
+		Serial:     info.X509.Serial,
+		Thumbprint: info.X509.Thumbprint,
+	}, err)
+	if err != nil {
+		result.Error = err.Error()
+		return result
+	}
+	result.PickupID = pickupID
+	return result
+}
+
+// doCommandRenewAllExpiring searches the zone for the certificates that expire within --expires-within, and requests
+// their renewal. The results are written to --batch-result or the standard output. It fails when any renewal failed,
+// after the other renewals are done
+func doCommandRenewAllExpiring(c *cli.Context, auditLog *audit.Log) error {
+	err := setTLSConfig()
+	if err != nil {
+		return err
+	}
+	cfg, err := buildConfig(c, &flags)
+	if err != nil {
+		return fmt.Errorf("Failed to build vcert config: %s", err)
+	}
+	if cfg.Zone == "" {
+		return fmt.Errorf("a zone is required to search for the expiring certificates to renew")
+	}
+
+	now := time.Now()
+	filter, err := buildListFilter(listOptions{expiresWithin: flags.expiresWithin, recursive: flags.recursive,
+		limit: tpp.DefaultSearchLimit}, cfg.Zone, now)
+	if err != nil {
+		return err
+	}
+
+	connector, err := vcert.NewClient(&cfg)
+	if err != nil {
+		return fmt.Errorf("unable to connect to %s: %w", cfg.ConnectorType, err)
+	}
+	tppConnector, ok := connector.(*tpp.Connector)
+	if !ok {
+		return fmt.Errorf("--all-expiring is only supported by Trust Protection Platform")
+	}
+
+	found, err := tppConnector.FindAllCertificates(c.Context, filter)
+	if err != nil {
+		return fmt.Errorf("failed to search the expiring certificates: %w", err)
+	}
+	logf("Found %d certificates in %s expiring before %s", len(found), cfg.Zone, filter.ExpiresBefore.Format(time.RFC3339))
+
+	s := &sweeper{renewer: connector, platform: cfg.ConnectorType.String(), zone: cfg.Zone, auditLog: auditLog}
+	summary := s.run(c.Context, sortByExpiration(found), flags.batchConcurrency, newBatchProgress(len(found), os.Stderr))
+	summary.ExpiresBefore = filter.ExpiresBefore
+	logf("Requested the renewal of %d of %d certificates in %s, %d failed", summary.Succeeded, summary.Total,
+		summary.Duration, summary.Failed)
+
+	var failure error
+	if summary.Failed > 0 {
+		failure = fmt.Errorf("%d of %d certificates failed to renew, see the results for the errors", summary.Failed, summary.Total)
+	}
+	if isStructuredOutput() && flags.batchResult == "" {
+		return writeSummaryResult(commandRenewName, summary, failure)
+	}
+	err = writeBatchSummary(summary, flags.batchResult)
+	if err != nil {
+		return err
+	}
+	return failure
+}
+
+// sortByExpiration sorts certificates so the ones that expire first are renewed first
+func sortByExpiration(certificates []tpp.CertificateSearchInfo) []tpp.CertificateSearchInfo {
+	sort.SliceStable(certificates, func(i, j int) bool {
+		return certificates[i].X509.ValidTo.Before(certificates[j].X509.ValidTo)
+	})
+	return certificates
+}
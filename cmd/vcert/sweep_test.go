@@ -0,0 +1,107 @@
+/*
+ * Copyright 2023 Venafi, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *  http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package main
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/Venafi/vcert/v5/pkg/certificate"
+	"github.com/Venafi/vcert/v5/pkg/venafi/tpp"
+)
+
+type fakeRenewer struct {
+	mu      sync.Mutex
+	renewed []string
+}
+
+func (r *fakeRenewer) RenewCertificate(_ context.Context, req *certificate.RenewalRequest) (string, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.renewed = append(r.renewed, req.CertificateDN)
+	if strings.HasSuffix(req.CertificateDN, "broken") {
+		return "", fmt.Errorf("certificate renewal error")
+	}
+	return req.CertificateDN, nil
+}
+
+func TestSweeperRun(t *testing.T) {
+	now := time.Now()
+	var found []tpp.CertificateSearchInfo
+	for i, name := range []string{"www", "broken", "api", "mail"} {
+		info := tpp.CertificateSearchInfo{DN: "\\VED\\Policy\\Web\\" + name}
+		info.X509.CN = name + ".example.com"
+		info.X509.ValidTo = now.Add(time.Duration(10-i) * 24 * time.Hour)
+		found = append(found, info)
+	}
+
+	renewer := &fakeRenewer{}
+	s := &sweeper{renewer: renewer, platform: "TPP", zone: "Web"}
+	summary := s.run(context.Background(), sortByExpiration(found), 2, &batchProgress{total: len(found)})
+
+	if summary.Total != 4 || summary.Succeeded != 3 || summary.Failed != 1 {
+		t.Fatalf("unexpected summary %+v", summary)
+	}
+	if len(renewer.renewed) != 4 {
+		t.Fatalf("expected 4 renewals, got %v", renewer.renewed)
+	}
+	// The certificates that expire first are renewed first
+	if summary.Results[0].CommonName != "mail.example.com" || summary.Results[3].CommonName != "www.example.com" {
+		t.Fatalf("results are not sorted by expiration: %+v", summary.Results)
+	}
+	for _, result := range summary.Results {
+		if result.CommonName == "broken.example.com" {
+			if result.Error == "" || result.PickupID != "" {
+				t.Fatalf("expected an error for the broken certificate, got %+v", result)
+			}
+		} else if result.Error != "" || result.PickupID != result.DN {
+			t.Fatalf("unexpected result %+v", result)
+		}
+	}
+}
+
+func TestValidateRenewAllExpiringFlags(t *testing.T) {
+	cases := map[string]struct {
+		flags commandFlags
+		err   string
+	}{
+		"valid":          {commandFlags{batchConcurrency: 8}, ""},
+		"service CSR":    {commandFlags{batchConcurrency: 8, csrOption: "service"}, ""},
+		"id":             {commandFlags{batchConcurrency: 8, distinguishedName: "\\VED\\Policy\\Web\\www"}, "-id and -thumbprint"},
+		"local CSR":      {commandFlags{batchConcurrency: 8, csrOption: "local"}, "only supports --csr service"},
+		"file":           {commandFlags{batchConcurrency: 8, file: "all.pem"}, "are not retrieved"},
+		"reuse key":      {commandFlags{batchConcurrency: 8, reuseKey: true}, "--reuse-private-key"},
+		"no concurrency": {commandFlags{}, "--batch-concurrency must be at least 1"},
+	}
+	for name, c := range cases {
+		t.Run(name, func(t *testing.T) {
+			flags = c.flags
+			err := validateRenewAllExpiringFlags()
+			if c.err == "" {
+				if err != nil {
+					t.Fatal(err)
+				}
+			} else if err == nil || !strings.Contains(err.Error(), c.err) {
+				t.Fatalf("expected error containing %q, got %v", c.err, err)
+			}
+		})
+	}
+}
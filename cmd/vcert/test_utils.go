@@ -34,6 +34,8 @@ func unsetEnvironmentVariables() {
 	os.Unsetenv(vcertClientID)
 	os.Unsetenv(vcertClientSecret)
 	os.Unsetenv(vcertDeviceURL)
+	os.Unsetenv(vcertTokenURL)
+	os.Unsetenv(vcertExternalJWT)
 }
 
 func getCliContext(command string) *cli.Context {
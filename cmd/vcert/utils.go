@@ -18,6 +18,7 @@
 package main
 
 import (
+	"context"
 	"crypto/sha1"
 	"crypto/x509"
 	"encoding/hex"
@@ -34,6 +35,7 @@ import (
 
 	"github.com/spf13/viper"
 	"github.com/urfave/cli/v2"
+	"golang.org/x/crypto/ssh"
 
 	"github.com/Venafi/vcert/v5"
 	"github.com/Venafi/vcert/v5/pkg/certificate"
@@ -44,6 +46,8 @@ import (
 const (
 	JKSFormat              = "jks"
 	Pkcs12                 = "pkcs12"
+	DERFormat              = "der"
+	PKCS7Format            = "pkcs7"
 	Sha256                 = "SHA256"
 	SshCertPubKeyServ      = "service"
 	SshCertPubKeyFilePreff = "file:"
@@ -104,6 +108,9 @@ func fillCertificateRequest(req *certificate.Request, cf *commandFlags) *certifi
 	if len(cf.upnSans) > 0 {
 		req.UPNs = cf.upnSans
 	}
+	if len(cf.ridSans) > 0 {
+		req.RegisteredIDs = cf.ridSans
+	}
 	req.OmitSANs = cf.omitSans
 	for _, f := range cf.customFields {
 		k, v, err := parseCustomField(f)
@@ -124,6 +131,7 @@ func fillCertificateRequest(req *certificate.Request, cf *commandFlags) *certifi
 		req.Location.TLSAddress = cf.tlsAddress
 		req.Location.Replace = cf.replaceInstance
 	}
+	req.ApplicationDNs = cf.appDNs
 
 	origin := OriginName
 	if len(cf.appInfo) > 0 {
@@ -197,6 +205,16 @@ func fillCertificateRequest(req *certificate.Request, cf *commandFlags) *certifi
 		}
 	}
 
+	if cf.validHours > 0 {
+		duration := time.Duration(cf.validHours) * time.Hour
+		req.ValidityDuration = &duration
+	}
+
+	if cf.notAfter != "" {
+		notAfter, _ := parseNotAfter(cf.notAfter)
+		req.NotAfter = &notAfter
+	}
+
 	if cf.validPeriod != "" {
 		req.ValidityPeriod = cf.validPeriod
 	}
@@ -204,6 +222,20 @@ func fillCertificateRequest(req *certificate.Request, cf *commandFlags) *certifi
 	return req
 }
 
+// parseNotAfter parses the value of --not-after, either an RFC 3339 date and time or a date, which is taken as
+// midnight UTC
+func parseNotAfter(value string) (time.Time, error) {
+	notAfter, err := time.Parse(time.RFC3339, value)
+	if err == nil {
+		return notAfter, nil
+	}
+	notAfter, err = time.Parse(time.DateOnly, value)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("--not-after must be an RFC 3339 date and time, like 2025-12-31T18:00:00Z, or a date, like 2025-12-31")
+	}
+	return notAfter, nil
+}
+
 func generateRenewalRequest(cf *commandFlags, certReq *certificate.Request) *certificate.RenewalRequest {
 	req := &certificate.RenewalRequest{}
 
@@ -256,6 +288,35 @@ func readThumbprintFromFile(fname string) (string, error) {
 	return "", fmt.Errorf("failed to parse file %s", fname)
 }
 
+// readSerialNumberFromFile returns the hexadecimal serial number in fname, or the one of the first certificate in it
+func readSerialNumberFromFile(fname string) (string, error) {
+	bytes, err := os.ReadFile(fname)
+	if err != nil {
+		return "", err
+	}
+
+	s := strings.ToUpper(strings.Replace(strings.TrimSpace(string(bytes)), ":", "", -1))
+	matched, _ := regexp.MatchString("^[A-F0-9]+$", s)
+	if matched {
+		return s, nil
+	}
+
+	for {
+		var block *pem.Block
+		block, bytes = pem.Decode(bytes)
+		if block == nil {
+			return "", fmt.Errorf("failed to parse file %s", fname)
+		}
+		if block.Type == "CERTIFICATE" {
+			cert, err := x509.ParseCertificate(block.Bytes)
+			if err != nil {
+				return "", fmt.Errorf("failed to read certificate from file: %s: %s", fname, err)
+			}
+			return fmt.Sprintf("%X", cert.SerialNumber), nil
+		}
+	}
+}
+
 func readCSRfromFile(fileName string) ([]byte, error) {
 	bytes, err := os.ReadFile(fileName)
 	if err != nil {
@@ -273,10 +334,10 @@ func readCSRfromFile(fileName string) ([]byte, error) {
 	}
 }
 
-func retrieveCertificate(connector endpoint.Connector, req *certificate.Request, timeout time.Duration) (certificates *certificate.PEMCollection, err error) {
+func retrieveCertificate(ctx context.Context, connector endpoint.Connector, req *certificate.Request, timeout time.Duration) (certificates *certificate.PEMCollection, err error) {
 	startTime := time.Now()
 	for {
-		certificates, err = connector.RetrieveCertificate(req)
+		certificates, err = connector.RetrieveCertificate(ctx, req)
 		if err != nil {
 			_, ok := err.(endpoint.ErrCertificatePending)
 			if ok && timeout > 0 {
@@ -300,9 +361,9 @@ func retrieveCertificate(connector endpoint.Connector, req *certificate.Request,
 
 // TODO: This one utilizes req.Timeout feature that is added to connector.RetrieveCertificate(), but
 // it cannot do logging in CLI context right now -- logger.Printf("Issuance of certificate is pending ...")
-func retrieveCertificateNew(connector endpoint.Connector, req *certificate.Request, timeout time.Duration) (certificates *certificate.PEMCollection, err error) {
+func retrieveCertificateNew(ctx context.Context, connector endpoint.Connector, req *certificate.Request, timeout time.Duration) (certificates *certificate.PEMCollection, err error) {
 	req.Timeout = timeout
-	certificates, err = connector.RetrieveCertificate(req)
+	certificates, err = connector.RetrieveCertificate(ctx, req)
 	if err != nil {
 		return nil, err
 	}
@@ -374,14 +435,14 @@ func writeToFile(content []byte, fileName string, perm os.FileMode) error {
 
 func writeSshFiles(privateKeyFileName string, privKey, pubKey, cert []byte) error {
 
-	fileName, err := normalizeSshCertFileName(privateKeyFileName)
+	fileName, err := sshFilePath(privateKeyFileName)
 
 	if err != nil {
 		return err
 	}
 
 	if !isPubKeyInFile() && len(privKey) > 0 {
-		err = writeToFile(privKey, fileName, 0600)
+		err = writeSshFile(privKey, fileName, 0600)
 		if err != nil {
 			return err
 		}
@@ -391,7 +452,7 @@ func writeSshFiles(privateKeyFileName string, privKey, pubKey, cert []byte) erro
 	//only write public key into a file if is not provided.
 	if !isPubKeyInFile() {
 		pubFileName := fileName + sshPubKeyFileExt
-		err = writeToFile(pubKey, pubFileName, 0644)
+		err = writeSshFile(pubKey, pubFileName, 0644)
 		if err != nil {
 			return err
 		}
@@ -400,7 +461,7 @@ func writeSshFiles(privateKeyFileName string, privKey, pubKey, cert []byte) erro
 	}
 
 	certFileName := fileName + sshCertFileExt
-	err = writeToFile(cert, certFileName, 0644)
+	err = writeSshFile(cert, certFileName, 0644)
 	if err != nil {
 		return err
 	}
@@ -410,6 +471,67 @@ func writeSshFiles(privateKeyFileName string, privKey, pubKey, cert []byte) erro
 
 }
 
+// writeSshFile writes content to fileName. With --format openssh, lines end with \n, as OpenSSH expects, and the
+// permissions are also set on files that already exist, since ssh refuses private keys readable by others
+func writeSshFile(content []byte, fileName string, perm os.FileMode) error {
+	if flags.format != util.OpenSSHFormat {
+		return writeToFile(content, fileName, perm)
+	}
+
+	s := strings.TrimRight(strings.ReplaceAll(string(content), "\r\n", "\n"), "\n") + "\n"
+	err := writeToFile([]byte(s), fileName, perm)
+	if err != nil {
+		return err
+	}
+	return os.Chmod(fileName, perm)
+}
+
+// sshFilePath returns the path of the private key file, to which the extensions of the public key and certificate
+// files are added. It is used as it is with --format openssh, and turned into a file name in the current directory
+// otherwise
+func sshFilePath(name string) (string, error) {
+	if flags.format == util.OpenSSHFormat {
+		return name, nil
+	}
+	return normalizeSshCertFileName(name)
+}
+
+// sshPrivateKeyFileName returns the name of the private key file of the SSH certificate: the --file value or, when
+// not set, the key ID of the certificate. With --format openssh, it defaults to the name ssh-keygen gives to keys of
+// the same type, i.e. id_ed25519, so that ssh picks them up
+func sshPrivateKeyFileName(data *certificate.SshCertificateObject) string {
+	if flags.sshFileCertEnroll != "" {
+		return flags.sshFileCertEnroll
+	}
+	if flags.format == util.OpenSSHFormat {
+		pubKey, _, _, _, err := ssh.ParseAuthorizedKey([]byte(data.PublicKeyData))
+		if err == nil {
+			switch keyType := pubKey.Type(); {
+			case keyType == ssh.KeyAlgoRSA:
+				return "id_rsa"
+			case keyType == ssh.KeyAlgoED25519:
+				return "id_ed25519"
+			case strings.HasPrefix(keyType, "ecdsa-"):
+				return "id_ecdsa"
+			}
+		}
+	}
+	return data.CertificateDetails.KeyID
+}
+
+// sshPrivateKeyData returns the private key of the SSH certificate as it is written to the private key file. With
+// --format openssh, keys generated by the service are converted to the OpenSSH format, keeping their passphrase
+func sshPrivateKeyData(data *certificate.SshCertificateObject, privateKey string) (string, error) {
+	if flags.format != util.OpenSSHFormat || privateKey == "" {
+		return privateKey, nil
+	}
+	b, err := util.ConvertToOpenSSHPrivateKey([]byte(privateKey), data.CertificateDetails.KeyID, flags.keyPassword)
+	if err != nil {
+		return "", fmt.Errorf("failed to write the private key in the %s format: %w", util.OpenSSHFormat, err)
+	}
+	return string(b), nil
+}
+
 func printExtensions(e map[string]interface{}) {
 	logf("\tExtensions: ")
 	if len(e) > 0 {
@@ -523,7 +645,7 @@ func getSshPubKeyFromFile() (content string, err error) {
 }
 
 func getExistingSshFiles(id string) ([]string, error) {
-	fileName, err := normalizeSshCertFileName(id)
+	fileName, err := sshFilePath(id)
 	if err != nil {
 		return nil, err
 	}
@@ -565,19 +687,26 @@ func AddLineEnding(s string) string {
 func IsCSRServiceVaaSGenerated(commandName string) bool {
 	cloudSerViceGenerated := false
 	if commandName == commandPickupName {
-		context := &cli.Context{
+		cliContext := &cli.Context{
 			Command: &cli.Command{
 				Name: commandPickupName,
 			},
 		}
-		cfg, err := buildConfig(context, &flags)
+		cfg, err := buildConfig(cliContext, &flags)
 		if err == nil {
 			connector, err := vcert.NewClient(&cfg)
 			if err == nil && endpoint.ConnectorTypeCloud == connector.GetType() {
 				var req = &certificate.Request{
 					PickupID: flags.pickupID,
 				}
-				cloudSerViceGenerated, _ = connector.IsCSRServiceGenerated(req)
+				// the file: values of --thumbprint and --serial are only read after this check
+				if !strings.HasPrefix(flags.thumbprint, "file:") {
+					req.Thumbprint = flags.thumbprint
+				}
+				if !strings.HasPrefix(flags.serialNumber, "file:") {
+					req.SerialNumber = flags.serialNumber
+				}
+				cloudSerViceGenerated, _ = connector.IsCSRServiceGenerated(context.Background(), req)
 			}
 		}
 	}
@@ -623,24 +752,25 @@ func getUserParameterProvidedForGetCred() (string, error) {
 	}
 
 	identityParameters := map[string]bool{
-		flagUser.Name:      flags.userName != "",
-		flagToken.Name:     tokenS != "",
-		flagClientP12.Name: flags.clientP12 != "",
-		flagEmail.Name:     flags.email != "",
+		flagUser.Name:           flags.userName != "",
+		flagToken.Name:          tokenS != "",
+		flagClientP12.Name:      flags.clientP12 != "",
+		flagClientCertFile.Name: flags.clientCertFile != "",
+		flagEmail.Name:          flags.email != "",
 	}
 
 	var uniqueIdentity string
 	for identityName, identityValue := range identityParameters {
 		if identityValue {
 			if uniqueIdentity != "" {
-				return "", fmt.Errorf("only one of either --username, --p12-file, -t or --email can be specified")
+				return "", fmt.Errorf("only one of either --username, --p12-file, --client-cert-file, -t or --email can be specified")
 			}
 			uniqueIdentity = identityName
 		}
 	}
 
 	if uniqueIdentity == "" {
-		return "", fmt.Errorf("either --username, --p12-file, -t or --email must be specified")
+		return "", fmt.Errorf("either --username, --p12-file, --client-cert-file, -t or --email must be specified")
 	}
 
 	return uniqueIdentity, nil
@@ -22,10 +22,12 @@ import (
 	"os"
 	"regexp"
 	"strings"
+	"time"
 
 	"github.com/Venafi/vcert/v5/pkg/certificate"
 	"github.com/Venafi/vcert/v5/pkg/util"
 	"github.com/Venafi/vcert/v5/pkg/venafi"
+	"github.com/Venafi/vcert/v5/pkg/venafi/acme"
 )
 
 // RevocationReasonOptions is an array of strings containing reasons for certificate revocation
@@ -66,6 +68,12 @@ func readData(commandName string) error {
 			return fmt.Errorf("Failed to read certificate fingerprint: %s", err)
 		}
 	}
+	if strings.HasPrefix(flags.serialNumber, "file:") {
+		flags.serialNumber, err = readSerialNumberFromFile(flags.serialNumber[5:])
+		if err != nil {
+			return fmt.Errorf("Failed to read certificate serial number: %s", err)
+		}
+	}
 
 	if err = readPasswordsFromInputFlags(commandName, &flags); err != nil {
 		return fmt.Errorf("Failed to read password from input: %s", err)
@@ -75,15 +83,31 @@ func readData(commandName string) error {
 
 func validateCommonFlags(commandName string) error {
 
-	if flags.format != "" && flags.format != "pem" && flags.format != "json" && flags.format != "pkcs12" && flags.format != JKSFormat && flags.format != util.LegacyPem {
+	if flags.format != "" && flags.format != "pem" && flags.format != "json" && flags.format != "pkcs12" && flags.format != JKSFormat && flags.format != util.LegacyPem &&
+		flags.format != DERFormat && flags.format != PKCS7Format {
 		return fmt.Errorf("Unexpected output format: %s", flags.format)
 	}
+	err := validateClientCertificateFlags()
+	if err != nil {
+		return err
+	}
 	if flags.file != "" && (flags.certFile != "" || flags.chainFile != "" || flags.keyFile != "") {
 		return fmt.Errorf("The '-file' option cannot be used used with any other -*-file flags. Either all data goes into one file or individual files must be specified using the appropriate flags")
 	}
 
 	var csrOptionRegex *regexp.Regexp
-	if flags.platform == venafi.Firefly {
+	if flags.platform == venafi.ACME || flags.platform == venafi.EST {
+		csrOptionRegex = regexp.MustCompile(`(^file:).*$|^local$|^$`)
+		if !csrOptionRegex.MatchString(flags.csrOption) {
+			return fmt.Errorf("unexpected --csr option provided: %s; specify one of the following options: %s, or %s", flags.csrOption, "'file:<filename>'", "'local'")
+		}
+	} else if flags.platform == venafi.SCEP {
+		// SCEP messages are signed with the key of the request, so the CSR must be generated locally
+		csrOptionRegex = regexp.MustCompile(`^local$|^$`)
+		if !csrOptionRegex.MatchString(flags.csrOption) {
+			return fmt.Errorf("unexpected --csr option provided: %s; only %s is supported by SCEP", flags.csrOption, "'local'")
+		}
+	} else if flags.platform == venafi.Firefly {
 		csrOptionRegex = regexp.MustCompile(`(^file:).*$|^service$|^$`)
 		if !csrOptionRegex.MatchString(flags.csrOption) {
 			return fmt.Errorf("unexpected --csr option provided: %s; specify one of the following options: %s, or %s", flags.csrOption, "'file:<filename>'", "'service'")
@@ -101,6 +125,10 @@ func validateCommonFlags(commandName string) error {
 		return fmt.Errorf("the '--keytype','--keycurve' and '--key-size' options cannot be used when '--csr file:' option is provided")
 	}
 
+	if (flags.keyTypeString == "ed25519" || strings.ToLower(flags.keyCurveString) == "ed25519") && flags.format == util.LegacyPem {
+		return fmt.Errorf("ed25519 keys cannot be written in the %s format", util.LegacyPem)
+	}
+
 	switch flags.keyTypeString {
 	case "rsa":
 		kt := certificate.KeyTypeRSA
@@ -108,21 +136,54 @@ func validateCommonFlags(commandName string) error {
 	case "ecdsa":
 		kt := certificate.KeyTypeECDSA
 		flags.keyType = &kt
+	case "ed25519":
+		if flags.keyCurveString != "" && strings.ToLower(flags.keyCurveString) != "ed25519" {
+			return fmt.Errorf("the --key-curve option cannot be used with the ed25519 key type")
+		}
+		kt := certificate.KeyTypeED25519
+		flags.keyType = &kt
+		flags.keyCurve = certificate.EllipticCurveED25519
+	case "ml-dsa-44", "ml-dsa-65", "ml-dsa-87":
+		if flags.keyCurveString != "" || flags.keySize > 0 {
+			return fmt.Errorf("the --key-curve and --key-size options cannot be used with the %s key type", flags.keyTypeString)
+		}
+		if flags.format == util.LegacyPem {
+			return fmt.Errorf("%s keys cannot be written in the %s format", flags.keyTypeString, util.LegacyPem)
+		}
+		var kt certificate.KeyType
+		if err := kt.Set(flags.keyTypeString, ""); err != nil {
+			return err
+		}
+		flags.keyType = &kt
 	case "":
 	default:
 		return fmt.Errorf("unknown key type: %s", flags.keyTypeString)
 	}
 
 	switch strings.ToLower(flags.keyCurveString) {
-	case "p256":
+	case "p256", "secp256r1", "prime256v1":
 		flags.keyCurve = certificate.EllipticCurveP256
-	case "p384":
+	case "p384", "secp384r1":
 		flags.keyCurve = certificate.EllipticCurveP384
-	case "p521":
+	case "p521", "secp521r1":
 		flags.keyCurve = certificate.EllipticCurveP521
+	case "brainpoolp256r1":
+		flags.keyCurve = certificate.EllipticCurveBrainpoolP256r1
+	case "brainpoolp384r1":
+		flags.keyCurve = certificate.EllipticCurveBrainpoolP384r1
+	case "brainpoolp512r1":
+		flags.keyCurve = certificate.EllipticCurveBrainpoolP512r1
+	case "ed25519":
+		// ed25519 is not an ECDSA curve, the key type follows it
+		if flags.keyTypeString == "rsa" {
+			return fmt.Errorf("the ed25519 key curve cannot be used with the rsa key type")
+		}
+		kt := certificate.KeyTypeED25519
+		flags.keyType = &kt
+		flags.keyCurve = certificate.EllipticCurveED25519
 	case "":
 	default:
-		return fmt.Errorf("unknown EC key curve: %s", flags.keyTypeString)
+		return fmt.Errorf("unknown EC key curve: %s", flags.keyCurveString)
 
 	}
 	return nil
@@ -135,6 +196,8 @@ func validateConnectionFlags(commandName string) error {
 			flags.password != "" ||
 			flags.token != "" ||
 			flags.url != "" ||
+			flags.externalJWT != "" ||
+			flags.serviceAccountKey != "" ||
 			flags.testMode {
 			return fmt.Errorf("connection details cannot be specified with flags when -config is used")
 		}
@@ -153,10 +216,38 @@ func validateConnectionFlags(commandName string) error {
 	if flags.testMode {
 		return nil
 	}
+	if flags.platform == venafi.ACME {
+		// ACME accounts are validated along with the enroll flags
+		return nil
+	}
+	if flags.platform == venafi.EST {
+		if flags.url == "" && getPropertyFromEnvironment(vCertURL) == "" {
+			return fmt.Errorf("missing -u (URL) parameter")
+		}
+		if flags.userName != "" && flags.password == "" {
+			return fmt.Errorf("a password is required along with the username for EST basic authentication")
+		}
+		if flags.clientP12 == "" && flags.clientP12PW != "" {
+			return fmt.Errorf("-client-pkcs12-pw can only be specified in combination with -client-pkcs12")
+		}
+		return nil
+	}
+	if flags.platform == venafi.SCEP {
+		if flags.url == "" && getPropertyFromEnvironment(vCertURL) == "" {
+			return fmt.Errorf("missing -u (URL) parameter")
+		}
+		return nil
+	}
+	if flags.platform == venafi.Firefly && tppToken == "" {
+		return fmt.Errorf("an access token is required for communicating with Firefly")
+	}
 	if flags.userName == "" && tppToken == "" {
 		// should be SaaS endpoint
 		if commandName != "sshgetconfig" && flags.apiKey == "" && getPropertyFromEnvironment(vCertApiKey) == "" {
-			return fmt.Errorf("An API key is required for communicating with Venafi as a Service")
+			if !isServiceAccountSet() {
+				return fmt.Errorf("An API key or a service account is required for communicating with Venafi as a Service")
+			}
+			return validateServiceAccountFlags()
 		}
 	} else {
 		// should be TPP service
@@ -175,10 +266,40 @@ func validateConnectionFlags(commandName string) error {
 	return nil
 }
 
+// validateClientCertificateFlags checks the PEM client certificate for mutual TLS is complete and
+// not combined with a PKCS#12 one
+func validateClientCertificateFlags() error {
+	if (flags.clientCertFile == "") != (flags.clientKeyFile == "") {
+		return fmt.Errorf("--client-cert-file and --client-key-file must be specified together")
+	}
+	if flags.clientCertFile != "" && flags.clientP12 != "" {
+		return fmt.Errorf("--client-cert-file cannot be used along with --p12-file")
+	}
+	return nil
+}
+
+// isServiceAccountSet reports whether Venafi Control Plane service account credentials were provided
+func isServiceAccountSet() bool {
+	return flags.externalJWT != "" || getPropertyFromEnvironment(vcertExternalJWT) != "" || flags.serviceAccountKey != ""
+}
+
+func validateServiceAccountFlags() error {
+	if flags.externalJWT != "" && flags.serviceAccountKey != "" {
+		return fmt.Errorf("--external-jwt and --service-account-key cannot be used together")
+	}
+	if flags.tokenURL == "" && getPropertyFromEnvironment(vcertTokenURL) == "" {
+		return fmt.Errorf("--token-url is required for service account authentication")
+	}
+	if flags.serviceAccountKey != "" && flags.serviceAccountID == "" {
+		return fmt.Errorf("--service-account-id is required along with --service-account-key")
+	}
+	return nil
+}
+
 func validatePKCS12Flags(commandName string) error {
 	if flags.format == "pkcs12" {
 		if commandName == commandEnrollName {
-			if flags.file == "" && flags.csrOption != "service" {
+			if flags.file == "" && flags.batch == "" && flags.csrOption != "service" {
 				return fmt.Errorf("PKCS#12 format requires certificate, private key, and chain to be written to a single file; specify using --file")
 			}
 		} else {
@@ -199,11 +320,29 @@ func validatePKCS12Flags(commandName string) error {
 	return nil
 }
 
+// validateCertificateOnlyFlags checks the der and pkcs7 formats, which only hold certificates.
+// The certificate is written to --cert-file, and the private key, if any, to --key-file in PEM format
+func validateCertificateOnlyFlags() error {
+	if flags.format != DERFormat && flags.format != PKCS7Format {
+		return nil
+	}
+	if flags.file != "" {
+		return fmt.Errorf(`The --file parameter may not be used when --format is "%s", as the private key cannot be included; use --cert-file and --key-file instead`, flags.format)
+	}
+	if flags.certFile == "" {
+		return fmt.Errorf(`The --cert-file parameter is required when --format is "%s"`, flags.format)
+	}
+	if flags.format == PKCS7Format && flags.chainFile != "" {
+		return fmt.Errorf(`The --chain-file parameter may not be used when --format is "pkcs7", as the chain is included in the --cert-file bundle`)
+	}
+	return nil
+}
+
 func validateJKSFlags(commandName string) error {
 	if flags.format == JKSFormat {
 
 		if commandName == commandEnrollName {
-			if flags.file == "" && flags.csrOption != "service" {
+			if flags.file == "" && flags.batch == "" && flags.csrOption != "service" {
 				return fmt.Errorf("JKS format requires certificate, private key, and chain to be written to a single file; specify using --file")
 			}
 		} else {
@@ -255,12 +394,21 @@ func validateEnrollFlags(commandName string) error {
 	if err != nil {
 		return err
 	}
+	err = validateValidityFlags()
+	if err != nil {
+		return err
+	}
 
 	err = readData(commandName)
 	if err != nil {
 		return err
 	}
-	if strings.Index(flags.csrOption, "file:") == 0 {
+	if flags.batch != "" {
+		err = validateBatchFlags()
+		if err != nil {
+			return err
+		}
+	} else if strings.Index(flags.csrOption, "file:") == 0 {
 		if flags.commonName != "" {
 			return fmt.Errorf("The '-cn' option cannot be used in -csr file: provided mode")
 		}
@@ -274,6 +422,10 @@ func validateEnrollFlags(commandName string) error {
 		return fmt.Errorf("The `-chain ignore` option cannot be used with -chain-file option")
 	}
 
+	if len(flags.ridSans) > 0 && flags.csrOption == "service" {
+		return fmt.Errorf("--san-rid requires a CSR generated by vcert, use --csr local")
+	}
+
 	apiKey := flags.apiKey
 	if apiKey == "" {
 		apiKey = getPropertyFromEnvironment(vCertApiKey)
@@ -290,22 +442,36 @@ func validateEnrollFlags(commandName string) error {
 		if zone == "" {
 			zone = getPropertyFromEnvironment(vCertZone)
 		}
+		// The rows of a batch manifest may name their zones, they are checked once the manifest is read
+		zoneRequired := zone == "" && flags.batch == ""
 
-		if flags.platform == venafi.Firefly {
+		if flags.platform == venafi.ACME {
+			challenge := acme.ChallengeConfig{Type: flags.acmeChallenge, DNSCommand: flags.acmeDNSCommand}
+			if err := challenge.IsValid(); err != nil {
+				return err
+			}
+			if flags.eabKeyID != "" && flags.eabHMACKey == "" {
+				return fmt.Errorf("--eab-hmac is required when --eab-kid is set")
+			}
+		} else if flags.platform == venafi.EST {
+			// EST credentials are validated with the connection flags, and the zone is an optional CA label
+		} else if flags.platform == venafi.SCEP {
+			// The challenge password is optional, and so is the CA identifier set by the zone
+		} else if flags.platform == venafi.Firefly {
 			if token == "" {
 				return fmt.Errorf("an access token is required for communicating with Firefly")
 			}
 
-			if zone == "" {
+			if zoneRequired {
 				return fmt.Errorf("a zone is required for requesting a certificate from Firefly")
 			}
 		} else {
 			if flags.userName == "" && token == "" {
 				// should be SaaS endpoint
-				if apiKey == "" {
-					return fmt.Errorf("An API key is required for enrollment with Venafi as a Service")
+				if apiKey == "" && !isServiceAccountSet() {
+					return fmt.Errorf("An API key or a service account is required for enrollment with Venafi as a Service")
 				}
-				if zone == "" {
+				if zoneRequired {
 					return fmt.Errorf("A zone is required for requesting a certificate from Venafi as a Service")
 				}
 			} else {
@@ -317,7 +483,7 @@ func validateEnrollFlags(commandName string) error {
 					return fmt.Errorf("An access token or password is required for communicating with Trust Protection Platform")
 				}
 
-				if zone == "" {
+				if zoneRequired {
 					return fmt.Errorf("A zone is required for requesting a certificate from Trust Protection Platform")
 				}
 
@@ -354,6 +520,11 @@ func validateEnrollFlags(commandName string) error {
 		return err
 	}
 
+	err = validateCertificateOnlyFlags()
+	if err != nil {
+		return err
+	}
+
 	if flags.userName != "" || flags.password != "" {
 		logf("Warning: User\\Password authentication is deprecated, please use access token instead.")
 	}
@@ -366,6 +537,64 @@ func validateEnrollFlags(commandName string) error {
 		return fmt.Errorf("--instance and --tls-address are not applicable to Venafi as a Service")
 	}
 
+	if len(flags.appDNs) > 0 && (apiKey != "" || (flags.platform != venafi.Undefined && flags.platform != venafi.TPP)) {
+		return fmt.Errorf("--app-dn is only applicable to Trust Protection Platform")
+	}
+
+	return nil
+}
+
+// validateBatchFlags checks the options of enroll --batch. The common names, SANs and files of the certificates are
+// read from the manifest, and every certificate is written to a single file
+func validateBatchFlags() error {
+	if flags.commonName != "" {
+		return fmt.Errorf("--cn cannot be used with --batch, the common names are read from the manifest")
+	}
+	if len(flags.dnsSans) > 0 || len(flags.ipSans) > 0 || len(flags.emailSans) > 0 || len(flags.uriSans) > 0 || len(flags.upnSans) > 0 ||
+		len(flags.ridSans) > 0 {
+		return fmt.Errorf("the --san-* options cannot be used with --batch, the SANs are read from the manifest")
+	}
+	if flags.csrOption != "" && flags.csrOption != "local" {
+		return fmt.Errorf("--batch only supports --csr local")
+	}
+	if flags.file != "" || flags.certFile != "" || flags.keyFile != "" || flags.chainFile != "" || flags.pickupIDFile != "" {
+		return fmt.Errorf("--file, --cert-file, --key-file, --chain-file and --pickup-id-file cannot be used with --batch, " +
+			"use --batch-dir and the file column of the manifest instead")
+	}
+	if flags.noPickup {
+		return fmt.Errorf("--no-pickup cannot be used with --batch")
+	}
+	if flags.format == DERFormat || flags.format == PKCS7Format {
+		return fmt.Errorf("--format %s cannot be used with --batch, as it does not hold the private key", flags.format)
+	}
+	if flags.verifySCT || flags.requireSCT {
+		return fmt.Errorf("--verify-sct and --require-sct cannot be used with --batch")
+	}
+	if flags.batchConcurrency < 1 {
+		return fmt.Errorf("--batch-concurrency must be at least 1")
+	}
+	return nil
+}
+
+// validateRenewAllExpiringFlags checks the options of renew --all-expiring. The certificates are found by searching
+// the zone, and renewed by the platform without being retrieved
+func validateRenewAllExpiringFlags() error {
+	if flags.distinguishedName != "" || flags.thumbprint != "" {
+		return fmt.Errorf("-id and -thumbprint cannot be used with --all-expiring, the certificates are found in the zone")
+	}
+	if flags.csrOption != "" && flags.csrOption != "service" {
+		return fmt.Errorf("--all-expiring only supports --csr service")
+	}
+	if flags.file != "" || flags.certFile != "" || flags.keyFile != "" || flags.chainFile != "" || flags.pickupIDFile != "" {
+		return fmt.Errorf("--file, --cert-file, --key-file, --chain-file and --pickup-id-file cannot be used with --all-expiring, " +
+			"the renewed certificates are not retrieved")
+	}
+	if flags.reuseKey || flags.omitSans {
+		return fmt.Errorf("--reuse-private-key and --omit-sans cannot be used with --all-expiring")
+	}
+	if flags.batchConcurrency < 1 {
+		return fmt.Errorf("--batch-concurrency must be at least 1")
+	}
 	return nil
 }
 
@@ -387,6 +616,34 @@ func validateValidDaysFlag(cn string) bool {
 	return true
 }
 
+// validateValidityFlags checks that the validity of the certificate is requested with one flag at most, and that it
+// ends in the future
+func validateValidityFlags() error {
+	set := 0
+	for _, isSet := range []bool{flags.validDays != "", flags.validHours != 0, flags.notAfter != ""} {
+		if isSet {
+			set++
+		}
+	}
+	if set > 1 {
+		return fmt.Errorf("only one of --valid-days, --valid-hours and --not-after can be set")
+	}
+
+	if flags.validHours < 0 {
+		return fmt.Errorf("--valid-hours must be a positive number of hours")
+	}
+	if flags.notAfter != "" {
+		notAfter, err := parseNotAfter(flags.notAfter)
+		if err != nil {
+			return err
+		}
+		if !notAfter.After(time.Now()) {
+			return fmt.Errorf("--not-after must be a date in the future")
+		}
+	}
+	return nil
+}
+
 func validateCredMgmtFlags1(commandName string) error {
 	var err error
 
@@ -395,6 +652,10 @@ func validateCredMgmtFlags1(commandName string) error {
 		tokenS = getPropertyFromEnvironment(vCertToken)
 	}
 
+	if commandName == commandGetCredName && flags.renewToken && flags.config == "" {
+		return fmt.Errorf("--renew-token requires --config, the file holding the tokens to renew")
+	}
+
 	if flags.config != "" {
 		if flags.apiKey != "" ||
 			flags.userName != "" ||
@@ -489,7 +750,7 @@ func validateGenerateFlags1(commandName string) error {
 	}
 	// ...or at least one Subject Alternative Name
 	if len(flags.dnsSans) > 0 || len(flags.ipSans) > 0 || len(flags.emailSans) > 0 ||
-		len(flags.uriSans) > 0 || len(flags.upnSans) > 0 {
+		len(flags.uriSans) > 0 || len(flags.upnSans) > 0 || len(flags.ridSans) > 0 {
 		return nil
 	}
 	// the enrolling CA may have more strict requirements when the CSR is submitted
@@ -511,7 +772,22 @@ func validateRenewFlags1(commandName string) error {
 		return err
 	}
 
-	if flags.distinguishedName == "" && flags.thumbprint == "" {
+	if flags.platform == venafi.EST {
+		// EST servers identify the certificate to renew by the TLS client certificate
+		if flags.clientP12 == "" && flags.clientCertFile == "" {
+			return fmt.Errorf("-client-pkcs12 or --client-cert-file with the certificate to renew is required to renew with EST")
+		}
+		if flags.distinguishedName != "" || flags.thumbprint != "" {
+			return fmt.Errorf("-id and -thumbprint cannot be used to renew with EST")
+		}
+		if flags.csrOption == "service" {
+			return fmt.Errorf("-csr service is not supported by EST")
+		}
+	} else if flags.platform == venafi.SCEP {
+		return fmt.Errorf("renewal is not supported by SCEP, enroll a new certificate instead")
+	} else if flags.allExpiring {
+		return validateRenewAllExpiringFlags()
+	} else if flags.distinguishedName == "" && flags.thumbprint == "" {
 		return fmt.Errorf("-id or -thumbprint required to identify the certificate to renew")
 	}
 	if flags.distinguishedName != "" && flags.thumbprint != "" {
@@ -554,6 +830,14 @@ func validateRenewFlags1(commandName string) error {
 				"-cn, -c, -o, -ou, -l, -st, -san-*, -key-type, -key-size")
 		}
 	}
+	if flags.reuseKey {
+		if flags.csrOption != "" && flags.csrOption != "local" {
+			return fmt.Errorf("-reuse-private-key can only be used with -csr local")
+		}
+		if flags.keyFile == "" && flags.file == "" {
+			return fmt.Errorf("-reuse-private-key requires -key-file or -file with the private key to reuse")
+		}
+	}
 	if flags.csrOption == "" || flags.csrOption == "local" {
 		if flags.commonName != "" ||
 			flags.country != "" ||
@@ -576,6 +860,11 @@ func validateRenewFlags1(commandName string) error {
 		return err
 	}
 
+	err = validateCertificateOnlyFlags()
+	if err != nil {
+		return err
+	}
+
 	return nil
 }
 
@@ -600,12 +889,17 @@ func validatePickupFlags1(commandName string) error {
 		return err
 	}
 
-	if flags.pickupID == "" && flags.pickupIDFile == "" {
-		return fmt.Errorf("A Pickup ID is required to pickup a certificate provided by -pickup-id OR -pickup-id-file options")
+	if flags.pickupID == "" && flags.pickupIDFile == "" && flags.thumbprint == "" && flags.serialNumber == "" {
+		return fmt.Errorf("A Pickup ID is required to pickup a certificate provided by -pickup-id OR -pickup-id-file options, " +
+			"or the certificate must be identified by --thumbprint OR --serial")
 	}
 	if flags.pickupID != "" && flags.pickupIDFile != "" {
 		return fmt.Errorf("Both -pickup-id and -pickup-id-file options cannot be specified at the same time")
 	}
+	if (flags.pickupID != "" || flags.pickupIDFile != "") && (flags.thumbprint != "" || flags.serialNumber != "") ||
+		flags.thumbprint != "" && flags.serialNumber != "" {
+		return fmt.Errorf("Only one of a Pickup ID, --thumbprint and --serial can be used to identify the certificate")
+	}
 
 	err = validatePKCS12Flags(commandName)
 	if err != nil {
@@ -617,6 +911,11 @@ func validatePickupFlags1(commandName string) error {
 		return err
 	}
 
+	err = validateCertificateOnlyFlags()
+	if err != nil {
+		return err
+	}
+
 	return nil
 }
 
@@ -697,10 +996,28 @@ func validateGetPolicyFlags(commandName string) error {
 			return fmt.Errorf("credentials are required")
 		}
 
-		if flags.policyName == "" {
+		if flags.platform == venafi.Firefly && flags.token == "" && getPropertyFromEnvironment(vCertToken) == "" {
+			return fmt.Errorf("an access token is required for communicating with Firefly")
+		}
+
+		// The policies of a Firefly instance are listed when no zone is given
+		if flags.policyName == "" && flags.platform != venafi.Firefly {
 			return fmt.Errorf("zone is required")
 		}
 	}
+
+	switch flags.platform {
+	case venafi.Undefined, venafi.TPP, venafi.TLSPCloud, venafi.Firefly:
+	default:
+		return fmt.Errorf("unsupported platform for %s: %s. Options: firefly", commandName, flags.platformString)
+	}
+
+	if flags.policyTranslateTo != "" {
+		platform := venafi.GetPlatformType(flags.policyTranslateTo)
+		if platform != venafi.TPP && platform != venafi.TLSPCloud {
+			return fmt.Errorf("unsupported value for translate-to: %s. Options: tpp, vaas", flags.policyTranslateTo)
+		}
+	}
 	return nil
 }
 
@@ -724,12 +1041,20 @@ func validateSetPolicyFlags(commandName string) error {
 			return fmt.Errorf("zone is required")
 		}
 
-		if flags.policySpecLocation == "" {
+		if flags.policySpecLocation == "" && !flags.createApp {
 			return fmt.Errorf("a policy specification file is required")
 		}
 
 	}
 
+	if flags.createApp && isVerifyPolicy {
+		return fmt.Errorf("--create-app cannot be used with --verify")
+	}
+
+	if !flags.createApp && (len(flags.appOwners) > 0 || flags.appDescription != "") {
+		return fmt.Errorf("--app-owner and --app-description can only be used with --create-app")
+	}
+
 	return nil
 }
 
@@ -739,8 +1064,17 @@ func validateSshEnrollFlags(commandName string) error {
 		return err
 	}
 
-	if flags.sshCertTemplate == "" {
-		return fmt.Errorf("certificate issuing template value is required (--template)")
+	if flags.sshCertRenew {
+		if flags.sshCertPickupId == "" && flags.sshCertGuid == "" {
+			return fmt.Errorf("please provide the pick up id or guid of the SSH certificate to renew")
+		}
+	} else {
+		if flags.sshCertPickupId != "" || flags.sshCertGuid != "" {
+			return fmt.Errorf("--pickup-id and --guid can only be used along with --renew")
+		}
+		if flags.sshCertTemplate == "" {
+			return fmt.Errorf("certificate issuing template value is required (--template)")
+		}
 	}
 
 	if flags.sshCertPubKey == "" {
@@ -753,6 +1087,11 @@ func validateSshEnrollFlags(commandName string) error {
 		}
 	}
 
+	err = validateSshFormatFlags()
+	if err != nil {
+		return err
+	}
+
 	err = readData(commandName)
 	if err != nil {
 		return err
@@ -761,6 +1100,20 @@ func validateSshEnrollFlags(commandName string) error {
 	return nil
 }
 
+// validateSshFormatFlags checks the format the SSH private key is written in
+func validateSshFormatFlags() error {
+	switch flags.format {
+	case "", "pem", util.LegacyPem:
+		return nil
+	case util.OpenSSHFormat:
+		if flags.sshCertWindows {
+			return fmt.Errorf("--windows cannot be used with --format %s, OpenSSH only reads files with \\n line endings", util.OpenSSHFormat)
+		}
+		return nil
+	}
+	return fmt.Errorf("unexpected SSH output format: %s, please provide: pem, legacy-pem or %s", flags.format, util.OpenSSHFormat)
+}
+
 func validateGetSshConfigFlags(commandName string) error {
 
 	err := validateConnectionFlags(commandName)
@@ -786,6 +1139,11 @@ func validateSshRetrieveFlags(commandName string) error {
 		return fmt.Errorf("please provide a pick up id or guid value")
 	}
 
+	err = validateSshFormatFlags()
+	if err != nil {
+		return err
+	}
+
 	err = readData(commandName)
 	if err != nil {
 		return err
@@ -793,6 +1151,20 @@ func validateSshRetrieveFlags(commandName string) error {
 	return nil
 }
 
+func validateSshRevokeFlags(commandName string) error {
+
+	err := validateConnectionFlags(commandName)
+	if err != nil {
+		return err
+	}
+
+	if flags.sshCertPickupId == "" && flags.sshCertGuid == "" {
+		return fmt.Errorf("please provide a pick up id or guid value")
+	}
+
+	return nil
+}
+
 func validateExistingFile(f string) error {
 	fileNames, err := getExistingSshFiles(f)
 
@@ -17,22 +17,35 @@
 package vcert
 
 import (
+	"crypto/tls"
 	"fmt"
 	"log"
 	"net/http"
 	"os"
 	"os/user"
 	"path/filepath"
+	"strings"
 
 	"gopkg.in/ini.v1"
 
 	"github.com/Venafi/vcert/v5/pkg/endpoint"
+	"github.com/Venafi/vcert/v5/pkg/util"
+	"github.com/Venafi/vcert/v5/pkg/venafi/acme"
 )
 
 const (
 	//common keys
-	platformUrlKey = "url"
-	trustBundleKey = "trust_bundle"
+	platformUrlKey   = "url"
+	trustBundleKey   = "trust_bundle"
+	proxyUrlKey      = "proxy_url"
+	proxyUserKey     = "proxy_user"
+	proxyPasswordKey = "proxy_password" // #nosec G101 // False positive
+	noProxyKey       = "no_proxy"
+
+	//TPP keys
+	tppAccessTokenKey  = "access_token"  // #nosec G101 // False positive
+	tppRefreshTokenKey = "refresh_token" // #nosec G101 // False positive
+	tppClientIdKey     = "tpp_client_id"
 
 	//Firefly keys
 	fireflyUrlKey          = "firefly_url"
@@ -63,6 +76,25 @@ type Config struct {
 	LogVerbose      bool
 	// http.Client to use durring construction
 	Client *http.Client
+	// RetryPolicy defines how the TPP and Venafi as a Service connectors retry rate limited requests and transient
+	// errors. The zero value uses the defaults of util.RetryPolicy. Ignored when Client is set
+	RetryPolicy util.RetryPolicy
+	// Proxy defines the proxy the connectors send their requests through. The zero value uses the proxy of the
+	// HTTP_PROXY, HTTPS_PROXY and NO_PROXY environment variables. Ignored when Client is set
+	Proxy util.ProxyConfig
+	// Transport tunes the HTTP transport of the connectors: connection pooling, HTTP/2 and TLS session resumption.
+	// Connectors built with the same settings share their transport. The zero value uses the defaults of
+	// util.TransportConfig. Ignored when Client is set
+	Transport util.TransportConfig
+	// TLSConfig holds the TLS settings of the connectors, like the client certificate or InsecureSkipVerify, instead
+	// of the ones of http.DefaultTransport. ConnectionTrust replaces its root CAs. It must not be changed once used.
+	// Ignored when Client is set
+	TLSConfig *tls.Config
+	// ACMEChallenge describes how the challenges of an ACME server are fulfilled. Only used by the ACME connector
+	ACMEChallenge *acme.ChallengeConfig
+	// ZoneCache caches the zone configurations read by the TPP and Venafi as a Service connectors. Sharing it between
+	// the connectors of a process avoids reading the policy of a zone on every request. Optional
+	ZoneCache *endpoint.ZoneConfigurationCache
 }
 
 // LoadConfigFromFile is deprecated. In the future will be rewritten.
@@ -106,14 +138,41 @@ func LoadConfigFromFile(path, section string) (cfg Config, err error) {
 	var connectorType endpoint.ConnectorType
 	var baseUrl string
 	var auth = &endpoint.Authentication{}
-	if m.has("tpp_user") || m.has("access_token") {
+	if m.has("tpp_user") || m.has(tppAccessTokenKey) || m.has(tppRefreshTokenKey) {
 		connectorType = endpoint.ConnectorTypeTPP
 		if m["tpp_url"] != "" {
 			baseUrl = m["tpp_url"]
 		} else if m[platformUrlKey] != "" {
 			baseUrl = m[platformUrlKey]
 		}
-		auth.AccessToken = m["access_token"]
+		auth.AccessToken = m[tppAccessTokenKey]
+		auth.RefreshToken = m[tppRefreshTokenKey]
+		auth.ClientId = m[tppClientIdKey]
+		if auth.RefreshToken != "" {
+			// The file is locked while the connector refreshes the tokens, and read again once locked, so the tokens
+			// already refreshed by another vcert process are used rather than refreshed twice
+			auth.LockTokens = func() (string, string, func() error, error) {
+				unlock, err := util.LockFile(fname)
+				if err != nil {
+					return "", "", nil, fmt.Errorf("failed to lock tokens: %s", err)
+				}
+				lockedFile, err := ini.Load(fname)
+				if err != nil {
+					_ = unlock()
+					return "", "", nil, fmt.Errorf("failed to lock tokens: %s", err)
+				}
+				s := lockedFile.Section(section)
+				return s.Key(tppAccessTokenKey).String(), s.Key(tppRefreshTokenKey).String(), unlock, nil
+			}
+			// TPP rotates the refresh token on every refresh, so the new pair replaces the old one in the file.
+			// It is called with the lock taken by LockTokens held
+			auth.OnTokenRefresh = func(accessToken string, refreshToken string) error {
+				return saveTokensToFile(fname, section, map[string]string{
+					tppAccessTokenKey:  accessToken,
+					tppRefreshTokenKey: refreshToken,
+				})
+			}
+		}
 		auth.User = m["tpp_user"]
 		auth.Password = m["tpp_password"]
 		if m.has("tpp_zone") {
@@ -174,6 +233,21 @@ func LoadConfigFromFile(path, section string) (cfg Config, err error) {
 		cfg.ConnectionTrust = string(data)
 	}
 
+	cfg.Proxy = util.ProxyConfig{
+		URL:      m[proxyUrlKey],
+		User:     m[proxyUserKey],
+		Password: m[proxyPasswordKey],
+	}
+	if m[noProxyKey] != "" {
+		for _, host := range strings.Split(m[noProxyKey], ",") {
+			cfg.Proxy.NoProxy = append(cfg.Proxy.NoProxy, strings.TrimSpace(host))
+		}
+	}
+	err = cfg.Proxy.IsValid()
+	if err != nil {
+		return cfg, fmt.Errorf("failed to load config: %s", err)
+	}
+
 	cfg.ConnectorType = connectorType
 	cfg.Credentials = auth
 	cfg.BaseUrl = baseUrl
@@ -181,6 +255,52 @@ func LoadConfigFromFile(path, section string) (cfg Config, err error) {
 	return
 }
 
+// saveTokensToFile writes the tokens, by key, to the section of the configuration file at path. The file is written to
+// a temporary file first and renamed, so the refresh token is never lost to a half written file.
+// Callers hold the lock of the file, taken with util.LockFile
+func saveTokensToFile(path string, section string, tokens map[string]string) error {
+	iniFile, err := ini.Load(path)
+	if err != nil {
+		return fmt.Errorf("failed to save tokens: %s", err)
+	}
+	s := iniFile.Section(section)
+	for key, value := range tokens {
+		s.Key(key).SetValue(value)
+	}
+
+	info, err := os.Stat(path)
+	if err != nil {
+		return fmt.Errorf("failed to save tokens: %s", err)
+	}
+	tmp, err := os.CreateTemp(filepath.Dir(path), filepath.Base(path)+".*.tmp")
+	if err != nil {
+		return fmt.Errorf("failed to save tokens: %s", err)
+	}
+	defer func() {
+		_ = os.Remove(tmp.Name())
+	}()
+
+	_, err = iniFile.WriteTo(tmp)
+	if err == nil {
+		err = tmp.Chmod(info.Mode().Perm())
+	}
+	if err == nil {
+		err = tmp.Sync()
+	}
+	if closeErr := tmp.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		return fmt.Errorf("failed to save tokens: %s", err)
+	}
+
+	err = os.Rename(tmp.Name(), path)
+	if err != nil {
+		return fmt.Errorf("failed to save tokens: %s", err)
+	}
+	return nil
+}
+
 func expand(path string) (string, error) {
 	if len(path) == 0 || path[0] != '~' {
 		return path, nil
@@ -212,20 +332,30 @@ func (d set) has(key string) bool {
 
 func validateSection(s *ini.Section) error {
 	var TPPValidKeys set = map[string]bool{
-		platformUrlKey: true,
-		"access_token": true,
-		"tpp_url":      true,
-		"tpp_user":     true,
-		"tpp_password": true,
-		"tpp_zone":     true,
-		trustBundleKey: true,
+		platformUrlKey:     true,
+		tppAccessTokenKey:  true,
+		tppRefreshTokenKey: true,
+		tppClientIdKey:     true,
+		"tpp_url":          true,
+		"tpp_user":         true,
+		"tpp_password":     true,
+		"tpp_zone":         true,
+		trustBundleKey:     true,
+		proxyUrlKey:        true,
+		proxyUserKey:       true,
+		proxyPasswordKey:   true,
+		noProxyKey:         true,
 	}
 	var CloudValidKeys set = map[string]bool{
-		platformUrlKey: true,
-		trustBundleKey: true,
-		"cloud_url":    true,
-		"cloud_apikey": true,
-		"cloud_zone":   true,
+		platformUrlKey:   true,
+		trustBundleKey:   true,
+		"cloud_url":      true,
+		proxyUrlKey:      true,
+		proxyUserKey:     true,
+		proxyPasswordKey: true,
+		noProxyKey:       true,
+		"cloud_apikey":   true,
+		"cloud_zone":     true,
 	}
 	var FireflyValidKeys set = map[string]bool{
 		platformUrlKey:         true,
@@ -241,28 +371,33 @@ func validateSection(s *ini.Section) error {
 		fireflyScopeKey:        true,
 		trustBundleKey:         true,
 		fireflyZoneKey:         true,
+		proxyUrlKey:            true,
+		proxyUserKey:           true,
+		proxyPasswordKey:       true,
+		noProxyKey:             true,
 	}
 
 	log.Printf("Validating configuration section %s", s.Name())
 	var m dict = s.KeysHash()
 
-	if m.has("access_token") && m.has("cloud_apikey") && m.has(fireflyAccessTokenKey) {
+	if m.has(tppAccessTokenKey) && m.has("cloud_apikey") && m.has(fireflyAccessTokenKey) {
 		return fmt.Errorf("configuration issue in section %s: only one between TPP token, cloud api key or OAuth token can be set", s.Name())
 	}
-	if m.has("tpp_user") || m.has("access_token") || m.has("tpp_password") {
+	if m.has("tpp_user") || m.has(tppAccessTokenKey) || m.has(tppRefreshTokenKey) || m.has("tpp_password") {
 		// looks like TPP config section
 		for k := range m {
 			if !TPPValidKeys.has(k) {
 				return fmt.Errorf("illegal key '%s' in TPP section %s", k, s.Name())
 			}
 		}
-		if m.has("tpp_user") && m.has("access_token") {
+		hasToken := m.has(tppAccessTokenKey) || m.has(tppRefreshTokenKey)
+		if m.has("tpp_user") && hasToken {
 			return fmt.Errorf("configuration issue in section %s: could not have both TPP user and access token", s.Name())
 		}
-		if !m.has("tpp_user") && !m.has("access_token") {
+		if !m.has("tpp_user") && !hasToken {
 			return fmt.Errorf("configuration issue in section %s: missing TPP user", s.Name())
 		}
-		if !m.has("tpp_password") && !m.has("access_token") {
+		if !m.has("tpp_password") && !hasToken {
 			return fmt.Errorf("configuration issue in section %s: missing TPP password", s.Name())
 		}
 	} else if m.has("cloud_apikey") {
@@ -19,6 +19,8 @@ package vcert
 import (
 	"io/ioutil"
 	"os"
+	"path/filepath"
+	"runtime"
 	"testing"
 )
 
@@ -39,6 +41,13 @@ url = https://ha-tpp1.example.com:5008/vedsdk
 access_token = ns1dofUPmsdxTLQS2hM1gQ==
 tpp_zone = devops\vcert`
 
+const validTPPConfigRefreshToken = `# the tokens are rotated in place
+url = https://ha-tpp1.example.com:5008/vedsdk
+access_token = ns1dofUPmsdxTLQS2hM1gQ==
+refresh_token = 7fdfsXgbd7slT1bxeqXBbQ==
+tpp_client_id = vcert-sdk
+tpp_zone = devops\vcert`
+
 const emptyConfig = ``
 
 const invalidTPPConfig = `# cloud zone cannot be used in TPP section
@@ -73,6 +82,19 @@ cloud_apikey = xxxxxxxx-b256-4c43-a4d4-15372ce2d548
 tpp_user = admin
 cloud_zone = Default`
 
+const validTPPConfigProxy = `
+url = https://ha-tpp1.example.com:5008/vedsdk
+access_token = ns1dofUPmsdxTLQS2hM1gQ==
+tpp_zone = devops\vcert
+proxy_url = socks5://jumphost.example.com:1080
+proxy_user = proxyuser
+proxy_password = xxx
+no_proxy = localhost, .internal.example.com`
+
+const invalidCloudConfigProxy = `# proxy scheme is not supported
+cloud_apikey = xxxxxxxx-b256-4c43-a4d4-15372ce2d548
+proxy_url = ftp://proxy.example.com`
+
 func TestLoadFromFile(t *testing.T) {
 	var cases = []struct {
 		valid   bool
@@ -81,15 +103,18 @@ func TestLoadFromFile(t *testing.T) {
 		{true, validTestModeConfig},
 		{true, validTPPConfig},
 		{true, validTPPConfigDeprecated},
+		{true, validTPPConfigRefreshToken},
 		{true, validCloudConfig},
 		{true, validCloudConfig},
 		{true, validCloudConfig2},
+		{true, validTPPConfigProxy},
 		{false, emptyConfig},
 		{false, invalidTestModeConfig},
 		{false, invalidTPPConfig},
 		{false, invalidTPPConfig2},
 		{false, invalidTPPConfig3},
 		{false, invalidCloudConfig},
+		{false, invalidCloudConfigProxy},
 	}
 	for _, test_case := range cases {
 		tmpfile, err := ioutil.TempFile("", "")
@@ -116,3 +141,82 @@ func TestLoadFromFile(t *testing.T) {
 		}
 	}
 }
+
+func TestLoadTokensFromFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "vcert.ini")
+	err := os.WriteFile(path, []byte("[tpp]\n"+validTPPConfigRefreshToken), 0600)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	cfg, err := LoadConfigFromFile(path, "tpp")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if cfg.Credentials.RefreshToken != "7fdfsXgbd7slT1bxeqXBbQ==" || cfg.Credentials.ClientId != "vcert-sdk" {
+		t.Fatalf("unexpected credentials %+v", cfg.Credentials)
+	}
+	if cfg.Credentials.OnTokenRefresh == nil || cfg.Credentials.LockTokens == nil {
+		t.Fatal("refreshed tokens are not persisted")
+	}
+
+	// The tokens of the file are read again under the lock, and saved while holding it
+	accessToken, refreshToken, unlock, err := cfg.Credentials.LockTokens()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if accessToken != cfg.Credentials.AccessToken || refreshToken != cfg.Credentials.RefreshToken {
+		t.Fatalf("unexpected locked tokens %q, %q", accessToken, refreshToken)
+	}
+
+	// The rotated tokens replace the old ones, and the rest of the file is kept
+	err = cfg.Credentials.OnTokenRefresh("access-2", "refresh-2")
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = unlock()
+	if err != nil {
+		t.Fatal(err)
+	}
+	cfg, err = LoadConfigFromFile(path, "tpp")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if cfg.Credentials.AccessToken != "access-2" || cfg.Credentials.RefreshToken != "refresh-2" {
+		t.Fatalf("tokens not persisted, got %+v", cfg.Credentials)
+	}
+	if cfg.Zone != "devops\\vcert" {
+		t.Fatalf("unexpected zone %q", cfg.Zone)
+	}
+	info, err := os.Stat(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if runtime.GOOS != "windows" && info.Mode().Perm() != 0600 {
+		t.Fatalf("file mode changed to %v", info.Mode().Perm())
+	}
+}
+
+func TestLoadProxyFromFile(t *testing.T) {
+	tmpfile, err := ioutil.TempFile("", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.Remove(tmpfile.Name())
+
+	err = ioutil.WriteFile(tmpfile.Name(), []byte(validTPPConfigProxy), 0644)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	cfg, err := LoadConfigFromFile(tmpfile.Name(), "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if cfg.Proxy.URL != "socks5://jumphost.example.com:1080" || cfg.Proxy.User != "proxyuser" || cfg.Proxy.Password != "xxx" {
+		t.Fatalf("unexpected proxy config: %+v", cfg.Proxy)
+	}
+	if len(cfg.Proxy.NoProxy) != 2 || cfg.Proxy.NoProxy[0] != "localhost" || cfg.Proxy.NoProxy[1] != ".internal.example.com" {
+		t.Fatalf("unexpected no_proxy: %v", cfg.Proxy.NoProxy)
+	}
+}
@@ -1,6 +1,7 @@
 package main
 
 import (
+	"context"
 	"crypto/x509/pkix"
 	"encoding/json"
 	"fmt"
@@ -35,7 +36,8 @@ func main() {
 		fireflyConfig.ConnectionTrust = string(buf)
 	}
 
-	connector, err := vcert.NewClient(&fireflyConfig)
+	ctx := context.Background()
+	connector, err := vcert.NewClientContext(ctx, &fireflyConfig)
 	if err != nil {
 		log.Fatalf("error creating client: %s", err.Error())
 	}
@@ -55,7 +57,7 @@ func main() {
 		KeyCurve:  certificate.EllipticCurveP256,
 	}
 
-	pcc, err := connector.SynchronousRequestCertificate(request)
+	pcc, err := connector.SynchronousRequestCertificate(ctx, request)
 	if err != nil {
 		log.Fatalf("error requesting certificate: %s", err.Error())
 	}
@@ -17,6 +17,7 @@
 package main
 
 import (
+	"context"
 	"crypto/sha1"
 	"crypto/x509"
 	"crypto/x509/pkix"
@@ -49,7 +50,8 @@ func main() {
 	config := tppConfig
 	//config := cloudConfig
 	//config := mockConfig
-	c, err := vcert.NewClient(config)
+	ctx := context.Background()
+	c, err := vcert.NewClientContext(ctx, config)
 	if err != nil {
 		t.Fatalf("could not connect to endpoint: %s", err)
 	}
@@ -107,7 +109,7 @@ func main() {
 	//
 	// 1.2. Generate private key and certificate request (CSR) based on request's options
 	//
-	err = c.GenerateRequest(nil, enrollReq)
+	err = c.GenerateRequest(ctx, nil, enrollReq)
 	if err != nil {
 		t.Fatalf("could not generate certificate request: %s", err)
 	}
@@ -115,7 +117,7 @@ func main() {
 	//
 	// 1.3. Submit certificate request, get request ID as a response
 	//
-	requestID, err := c.RequestCertificate(enrollReq)
+	requestID, err := c.RequestCertificate(ctx, enrollReq)
 	if err != nil {
 		t.Fatalf("could not submit certificate request: %s", err)
 	}
@@ -128,7 +130,7 @@ func main() {
 		PickupID: requestID,
 		Timeout:  180 * time.Second,
 	}
-	pcc, err := c.RetrieveCertificate(pickupReq)
+	pcc, err := c.RetrieveCertificate(ctx, pickupReq)
 	if err != nil {
 		t.Fatalf("could not retrieve certificate using requestId %s: %s", requestID, err)
 	}
@@ -155,7 +157,7 @@ func main() {
 	//
 	// 2.2. Submit renewal request
 	//
-	newRequestID, err := c.RenewCertificate(renewReq)
+	newRequestID, err := c.RenewCertificate(ctx, renewReq)
 	if err != nil {
 		t.Fatalf("could not submit certificate renewal request: %s", err)
 	}
@@ -168,7 +170,7 @@ func main() {
 		PickupID: newRequestID,
 		Timeout:  180 * time.Second,
 	}
-	pcc2, err := c.RetrieveCertificate(renewRetrieveReq)
+	pcc2, err := c.RetrieveCertificate(ctx, renewRetrieveReq)
 	if err != nil {
 		t.Fatalf("could not retrieve certificate using requestId %s: %s", requestID, err)
 	}
@@ -191,7 +193,7 @@ func main() {
 	// 3.2. Submit revocation request (not supported in Venafi Cloud)
 	//
 	if config.ConnectorType != endpoint.ConnectorTypeCloud {
-		err = c.RevokeCertificate(revokeReq)
+		err = c.RevokeCertificate(ctx, revokeReq)
 		if err != nil {
 			t.Fatalf("could not submit certificate revocation request: %s", err)
 		}
@@ -222,7 +224,7 @@ func main() {
 			Reconcile:       false,
 		}
 	}
-	importResp, err := c.ImportCertificate(importReq)
+	importResp, err := c.ImportCertificate(ctx, importReq)
 	if err != nil {
 		t.Fatalf("could not import certificate: %s", err)
 	}
@@ -252,7 +254,7 @@ func main() {
 		}
 	}
 
-	pcc3, err := c.RetrieveCertificate(importedRetriveReq)
+	pcc3, err := c.RetrieveCertificate(ctx, importedRetriveReq)
 	if err != nil {
 		t.Fatalf("could not retrieve certificate using requestId %s: %s", requestID, err)
 	}
@@ -280,7 +282,7 @@ func main() {
 			t.Fatalf("could not create TPP connector: %s", err)
 		}
 
-		resp, err := tppConnector.GetRefreshToken(&endpoint.Authentication{
+		resp, err := tppConnector.GetRefreshToken(ctx, &endpoint.Authentication{
 			User:     os.Getenv("TPP_USER"),
 			Password: os.Getenv("TPP_PASSWORD"),
 			Scope:    "certificate:manage,revoke;", ClientId: "websdk"})
@@ -290,7 +292,7 @@ func main() {
 		fmt.Printf("Refresh token is %s", resp.Refresh_token)
 
 		auth := &endpoint.Authentication{RefreshToken: resp.Refresh_token, ClientId: "websdk"}
-		err = tppConnector.Authenticate(auth)
+		err = tppConnector.Authenticate(ctx, auth)
 		if err != nil {
 			t.Fatalf("err is not nil, err: %s", err)
 		}
@@ -302,7 +304,7 @@ func main() {
 	//
 
 	_l := 10
-	certList, err := c.ListCertificates(endpoint.Filter{Limit: &_l})
+	certList, err := c.ListCertificates(ctx, endpoint.Filter{Limit: &_l})
 	if err != nil {
 		t.Fatal(err)
 	}
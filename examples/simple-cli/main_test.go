@@ -17,6 +17,7 @@
 package main
 
 import (
+	"context"
 	"crypto/tls"
 	"crypto/x509"
 	"crypto/x509/pkix"
@@ -38,6 +39,7 @@ func init() {
 }
 
 func TestRequestCertificate(t *testing.T) {
+	ctx := context.Background()
 	//
 	// 0. Get client instance based on connection config
 	//
@@ -71,7 +73,7 @@ func TestRequestCertificate(t *testing.T) {
 	//
 	// 2. Generate private key and certificate request (CSR) based on request's options
 	//
-	err = c.GenerateRequest(nil, req)
+	err = c.GenerateRequest(ctx, nil, req)
 	if err != nil {
 		t.Fatalf("could not generate certificate request: %s", err)
 	}
@@ -79,7 +81,7 @@ func TestRequestCertificate(t *testing.T) {
 	//
 	// 3. Submit certificate request, get request ID as a response
 	//
-	requestID, err := c.RequestCertificate(req)
+	requestID, err := c.RequestCertificate(ctx, req)
 	if err != nil {
 		t.Fatalf("could not submit certificate request: %s", err)
 	}
@@ -89,7 +91,7 @@ func TestRequestCertificate(t *testing.T) {
 	//
 	req.PickupID = requestID
 	req.Timeout = 180 * time.Second
-	pcc, err := c.RetrieveCertificate(req)
+	pcc, err := c.RetrieveCertificate(ctx, req)
 	if err != nil {
 		t.Fatalf("could not retrieve certificate using requestId %s: %s", requestID, err)
 	}
@@ -107,6 +109,7 @@ func TestRequestCertificate(t *testing.T) {
 }
 
 func TestRevokeCertificate(t *testing.T) {
+	ctx := context.Background()
 	//
 	// 0. Get client instance based on connection config
 	//
@@ -128,7 +131,7 @@ func TestRevokeCertificate(t *testing.T) {
 	//
 	// 2. Submit revocation request
 	//
-	err = c.RevokeCertificate(req)
+	err = c.RevokeCertificate(ctx, req)
 	if err != nil {
 		t.Fatalf("could not submit certificate revocation request: %s", err)
 	}
@@ -139,6 +142,7 @@ func TestRevokeCertificate(t *testing.T) {
 }
 
 func TestRenewCertificate(t *testing.T) {
+	ctx := context.Background()
 	//
 	// 0. Get client instance based on connection config
 	//
@@ -161,7 +165,7 @@ func TestRenewCertificate(t *testing.T) {
 	//
 	// 2. Submit renewal request
 	//
-	requestID, err := c.RenewCertificate(renewReq)
+	requestID, err := c.RenewCertificate(ctx, renewReq)
 	if err != nil {
 		t.Fatalf("could not submit certificate renewal request: %s", err)
 	}
@@ -173,7 +177,7 @@ func TestRenewCertificate(t *testing.T) {
 		PickupID: requestID,
 		Timeout:  180 * time.Second,
 	}
-	pcc, err := c.RetrieveCertificate(req)
+	pcc, err := c.RetrieveCertificate(ctx, req)
 	if err != nil {
 		t.Fatalf("could not retrieve certificate using requestId %s: %s", requestID, err)
 	}
@@ -200,6 +204,7 @@ func TestRenewCertificate(t *testing.T) {
 }
 
 func TestImportCertificate(t *testing.T) {
+	ctx := context.Background()
 	//
 	// 0. Get client instance based on connection config
 	//
@@ -228,19 +233,19 @@ func TestImportCertificate(t *testing.T) {
 		KeyPassword: "newPassw0rd!",
 	}
 
-	err = c.GenerateRequest(nil, req)
+	err = c.GenerateRequest(ctx, nil, req)
 	if err != nil {
 		t.Fatalf("could not generate certificate request: %s", err)
 	}
 
-	requestID, err := c.RequestCertificate(req)
+	requestID, err := c.RequestCertificate(ctx, req)
 	if err != nil {
 		t.Fatalf("could not submit certificate request: %s", err)
 	}
 
 	req.PickupID = requestID
 	req.Timeout = 180 * time.Second
-	pcc, err := c.RetrieveCertificate(req)
+	pcc, err := c.RetrieveCertificate(ctx, req)
 	if err != nil {
 		t.Fatalf("could not retrieve certificate using requestId %s: %s", requestID, err)
 	}
@@ -262,7 +267,7 @@ func TestImportCertificate(t *testing.T) {
 		Password:        "newPassw0rd!",
 		Reconcile:       false,
 	}
-	importResp, err := c.ImportCertificate(importReq)
+	importResp, err := c.ImportCertificate(ctx, importReq)
 	if err != nil {
 		t.Fatalf("could not import certificate: %s", err)
 	}
@@ -278,7 +283,7 @@ func TestImportCertificate(t *testing.T) {
 		KeyPassword:     "newPassw0rd!",
 		FetchPrivateKey: true,
 	}
-	pcc2, err := c.RetrieveCertificate(req)
+	pcc2, err := c.RetrieveCertificate(ctx, req)
 	if err != nil {
 		t.Fatalf("could not retrieve certificate using requestId %s: %s", requestID, err)
 	}
@@ -1,6 +1,7 @@
 package vcert
 
 import (
+	"context"
 	"crypto/tls"
 	"crypto/x509/pkix"
 	"fmt"
@@ -30,7 +31,8 @@ import (
 // handshake has completed.
 func (cfg *Config) NewListener(domains ...string) net.Listener {
 	l := listener{}
-	conn, err := cfg.NewClient()
+	ctx := context.Background()
+	conn, err := cfg.NewClientContext(ctx)
 	if err != nil {
 		l.e = err
 		return &l
@@ -49,7 +51,7 @@ func (cfg *Config) NewListener(domains ...string) net.Listener {
 			d = parsedHost
 		}
 		log.Println("Retrieving certificate for domain", d)
-		cert, err := getSimpleCertificate(conn, d)
+		cert, err := getSimpleCertificate(ctx, conn, d)
 		if err != nil {
 			l.e = err
 			return &l
@@ -71,23 +73,23 @@ func (cfg *Config) NewListener(domains ...string) net.Listener {
 	return &l
 }
 
-func getSimpleCertificate(conn endpoint.Connector, cn string) (tls.Certificate, error) {
+func getSimpleCertificate(ctx context.Context, conn endpoint.Connector, cn string) (tls.Certificate, error) {
 	req := certificate.Request{Subject: pkix.Name{CommonName: cn}, DNSNames: []string{cn}, CsrOrigin: certificate.LocalGeneratedCSR}
-	zc, err := conn.ReadZoneConfiguration()
+	zc, err := conn.ReadZoneConfiguration(ctx)
 	if err != nil {
 		return tls.Certificate{}, err
 	}
-	err = conn.GenerateRequest(zc, &req)
+	err = conn.GenerateRequest(ctx, zc, &req)
 	if err != nil {
 		return tls.Certificate{}, err
 	}
-	requestID, err := conn.RequestCertificate(&req)
+	requestID, err := conn.RequestCertificate(ctx, &req)
 	if err != nil {
 		return tls.Certificate{}, err
 	}
 	req.PickupID = requestID
 	req.Timeout = time.Minute
-	certCollection, err := conn.RetrieveCertificate(&req)
+	certCollection, err := conn.RetrieveCertificate(ctx, &req)
 	if err != nil {
 		return tls.Certificate{}, err
 	}
@@ -0,0 +1,400 @@
+/*
+ * Copyright 2023 Venafi, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *  http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package certificate
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/sha512"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/asn1"
+	"math/big"
+	"sync"
+)
+
+// The Go standard library does not implement the brainpool curves of RFC 5639, and crypto/x509 cannot
+// marshal their keys nor sign requests with them. This file provides the curves, which crypto/ecdsa
+// accepts as custom curves, and the encodings needed to write their keys and certificate requests
+
+var (
+	oidPublicKeyECDSA     = asn1.ObjectIdentifier{1, 2, 840, 10045, 2, 1}
+	oidBrainpoolP256r1    = asn1.ObjectIdentifier{1, 3, 36, 3, 3, 2, 8, 1, 1, 7}
+	oidBrainpoolP384r1    = asn1.ObjectIdentifier{1, 3, 36, 3, 3, 2, 8, 1, 1, 11}
+	oidBrainpoolP512r1    = asn1.ObjectIdentifier{1, 3, 36, 3, 3, 2, 8, 1, 1, 13}
+	oidSignatureECDSASHA2 = map[crypto.Hash]asn1.ObjectIdentifier{
+		crypto.SHA256: {1, 2, 840, 10045, 4, 3, 2},
+		crypto.SHA384: {1, 2, 840, 10045, 4, 3, 3},
+		crypto.SHA512: {1, 2, 840, 10045, 4, 3, 4},
+	}
+
+	brainpoolOnce                                     sync.Once
+	brainpoolP256r1, brainpoolP384r1, brainpoolP512r1 *weierstrassCurve
+)
+
+// weierstrassCurve is a short Weierstrass curve y² = x³ + ax + b over a prime field. Unlike
+// elliptic.CurveParams, it does not assume a = -3, which does not hold for the brainpool r1 curves.
+// Points are handled in affine coordinates with math/big, like the deprecated generic implementation
+// of elliptic.CurveParams, so operations are not constant time
+type weierstrassCurve struct {
+	params *elliptic.CurveParams
+	a      *big.Int
+	oid    asn1.ObjectIdentifier
+	hash   crypto.Hash
+}
+
+func initBrainpool() {
+	brainpoolP256r1 = newWeierstrassCurve("brainpoolP256r1", 256, oidBrainpoolP256r1, crypto.SHA256,
+		"A9FB57DBA1EEA9BC3E660A909D838D726E3BF623D52620282013481D1F6E5377",
+		"7D5A0975FC2C3057EEF67530417AFFE7FB8055C126DC5C6CE94A4B44F330B5D9",
+		"26DC5C6CE94A4B44F330B5D9BBD77CBF958416295CF7E1CE6BCCDC18FF8C07B6",
+		"8BD2AEB9CB7E57CB2C4B482FFC81B7AFB9DE27E1E3BD23C23A4453BD9ACE3262",
+		"547EF835C3DAC4FD97F8461A14611DC9C27745132DED8E545C1D54C72F046997",
+		"A9FB57DBA1EEA9BC3E660A909D838D718C397AA3B561A6F7901E0E82974856A7")
+	brainpoolP384r1 = newWeierstrassCurve("brainpoolP384r1", 384, oidBrainpoolP384r1, crypto.SHA384,
+		"8CB91E82A3386D280F5D6F7E50E641DF152F7109ED5456B412B1DA197FB71123ACD3A729901D1A71874700133107EC53",
+		"7BC382C63D8C150C3C72080ACE05AFA0C2BEA28E4FB22787139165EFBA91F90F8AA5814A503AD4EB04A8C7DD22CE2826",
+		"04A8C7DD22CE28268B39B55416F0447C2FB77DE107DCD2A62E880EA53EEB62D57CB4390295DBC9943AB78696FA504C11",
+		"1D1C64F068CF45FFA2A63A81B7C13F6B8847A3E77EF14FE3DB7FCAFE0CBD10E8E826E03436D646AAEF87B2E247D4AF1E",
+		"8ABE1D7520F9C2A45CB1EB8E95CFD55262B70B29FEEC5864E19C054FF99129280E4646217791811142820341263C5315",
+		"8CB91E82A3386D280F5D6F7E50E641DF152F7109ED5456B31F166E6CAC0425A7CF3AB6AF6B7FC3103B883202E9046565")
+	brainpoolP512r1 = newWeierstrassCurve("brainpoolP512r1", 512, oidBrainpoolP512r1, crypto.SHA512,
+		"AADD9DB8DBE9C48B3FD4E6AE33C9FC07CB308DB3B3C9D20ED6639CCA703308717D4D9B009BC66842AECDA12AE6A380E62881FF2F2D82C68528AA6056583A48F3",
+		"7830A3318B603B89E2327145AC234CC594CBDD8D3DF91610A83441CAEA9863BC2DED5D5AA8253AA10A2EF1C98B9AC8B57F1117A72BF2C7B9E7C1AC4D77FC94CA",
+		"3DF91610A83441CAEA9863BC2DED5D5AA8253AA10A2EF1C98B9AC8B57F1117A72BF2C7B9E7C1AC4D77FC94CADC083E67984050B75EBAE5DD2809BD638016F723",
+		"81AEE4BDD82ED9645A21322E9C4C6A9385ED9F70B5D916C1B43B62EEF4D0098EFF3B1F78E2D0D48D50D1687B93B97D5F7C6D5047406A5E688B352209BCB9F822",
+		"7DDE385D566332ECC0EABFA9CF7822FDF209F70024A57B1AA000C55B881F8111B2DCDE494A5F485E5BCA4BD88A2763AED1CA2B2FA8F0540678CD1E0F3AD80892",
+		"AADD9DB8DBE9C48B3FD4E6AE33C9FC07CB308DB3B3C9D20ED6639CCA70330870553E5C414CA92619418661197FAC10471DB1D381085DDADDB58796829CA90069")
+}
+
+func newWeierstrassCurve(name string, bitSize int, oid asn1.ObjectIdentifier, hash crypto.Hash, p, a, b, gx, gy, n string) *weierstrassCurve {
+	hexInt := func(s string) *big.Int {
+		v, _ := new(big.Int).SetString(s, 16)
+		return v
+	}
+	return &weierstrassCurve{
+		params: &elliptic.CurveParams{P: hexInt(p), N: hexInt(n), B: hexInt(b), Gx: hexInt(gx), Gy: hexInt(gy), BitSize: bitSize, Name: name},
+		a:      hexInt(a),
+		oid:    oid,
+		hash:   hash,
+	}
+}
+
+// brainpoolCurve returns the elliptic.Curve of a brainpool EllipticCurve, or nil for any other curve
+func brainpoolCurve(curve EllipticCurve) *weierstrassCurve {
+	brainpoolOnce.Do(initBrainpool)
+	switch curve {
+	case EllipticCurveBrainpoolP256r1:
+		return brainpoolP256r1
+	case EllipticCurveBrainpoolP384r1:
+		return brainpoolP384r1
+	case EllipticCurveBrainpoolP512r1:
+		return brainpoolP512r1
+	default:
+		return nil
+	}
+}
+
+// Params returns the parameters of the curve. B is only meaningful along with a, so they must not be used
+// with the arithmetic methods of elliptic.CurveParams
+func (c *weierstrassCurve) Params() *elliptic.CurveParams {
+	return c.params
+}
+
+// IsOnCurve reports whether the point (x, y) is on the curve
+func (c *weierstrassCurve) IsOnCurve(x, y *big.Int) bool {
+	p := c.params.P
+	if x.Sign() < 0 || x.Cmp(p) >= 0 || y.Sign() < 0 || y.Cmp(p) >= 0 {
+		return false
+	}
+	y2 := new(big.Int).Mul(y, y)
+	y2.Mod(y2, p)
+	return c.polynomial(x).Cmp(y2) == 0
+}
+
+// polynomial returns x³ + ax + b
+func (c *weierstrassCurve) polynomial(x *big.Int) *big.Int {
+	x3 := new(big.Int).Mul(x, x)
+	x3.Mul(x3, x)
+	ax := new(big.Int).Mul(c.a, x)
+	x3.Add(x3, ax)
+	x3.Add(x3, c.params.B)
+	return x3.Mod(x3, c.params.P)
+}
+
+// Add returns the sum of (x1, y1) and (x2, y2). The point at infinity is (0, 0)
+func (c *weierstrassCurve) Add(x1, y1, x2, y2 *big.Int) (*big.Int, *big.Int) {
+	if x1.Sign() == 0 && y1.Sign() == 0 {
+		return new(big.Int).Set(x2), new(big.Int).Set(y2)
+	}
+	if x2.Sign() == 0 && y2.Sign() == 0 {
+		return new(big.Int).Set(x1), new(big.Int).Set(y1)
+	}
+	p := c.params.P
+	if x1.Cmp(x2) == 0 {
+		if y1.Cmp(y2) == 0 {
+			return c.Double(x1, y1)
+		}
+		return new(big.Int), new(big.Int)
+	}
+
+	// λ = (y2 - y1) / (x2 - x1)
+	num := new(big.Int).Sub(y2, y1)
+	den := new(big.Int).Sub(x2, x1)
+	den.Mod(den, p)
+	lambda := num.Mul(num, den.ModInverse(den, p))
+	lambda.Mod(lambda, p)
+	return c.fromLambda(lambda, x1, y1, x2)
+}
+
+// Double returns 2 * (x, y)
+func (c *weierstrassCurve) Double(x, y *big.Int) (*big.Int, *big.Int) {
+	if y.Sign() == 0 {
+		return new(big.Int), new(big.Int)
+	}
+	p := c.params.P
+
+	// λ = (3x² + a) / 2y
+	num := new(big.Int).Mul(x, x)
+	num.Mul(num, big.NewInt(3))
+	num.Add(num, c.a)
+	den := new(big.Int).Lsh(y, 1)
+	den.Mod(den, p)
+	lambda := num.Mul(num, den.ModInverse(den, p))
+	lambda.Mod(lambda, p)
+	return c.fromLambda(lambda, x, y, x)
+}
+
+// fromLambda completes an addition of (x1, y1) and a point with abscissa x2, given the slope between them
+func (c *weierstrassCurve) fromLambda(lambda, x1, y1, x2 *big.Int) (*big.Int, *big.Int) {
+	p := c.params.P
+	x3 := new(big.Int).Mul(lambda, lambda)
+	x3.Sub(x3, x1)
+	x3.Sub(x3, x2)
+	x3.Mod(x3, p)
+
+	y3 := new(big.Int).Sub(x1, x3)
+	y3.Mul(y3, lambda)
+	y3.Sub(y3, y1)
+	y3.Mod(y3, p)
+	return x3, y3
+}
+
+// ScalarMult returns k * (x, y), where k is a big-endian integer
+func (c *weierstrassCurve) ScalarMult(x, y *big.Int, k []byte) (*big.Int, *big.Int) {
+	rx, ry := new(big.Int), new(big.Int)
+	for _, b := range k {
+		for bit := 7; bit >= 0; bit-- {
+			rx, ry = c.Double(rx, ry)
+			if b>>uint(bit)&1 == 1 {
+				rx, ry = c.Add(rx, ry, x, y)
+			}
+		}
+	}
+	return rx, ry
+}
+
+// ScalarBaseMult returns k * G, where G is the base point of the curve and k a big-endian integer
+func (c *weierstrassCurve) ScalarBaseMult(k []byte) (*big.Int, *big.Int) {
+	return c.ScalarMult(c.params.Gx, c.params.Gy, k)
+}
+
+// isBrainpoolKey returns the ECDSA key and its curve when key is a brainpool key
+func isBrainpoolKey(key interface{}) (*ecdsa.PrivateKey, *weierstrassCurve, bool) {
+	k, ok := key.(*ecdsa.PrivateKey)
+	if !ok {
+		return nil, nil, false
+	}
+	curve, ok := k.Curve.(*weierstrassCurve)
+	return k, curve, ok
+}
+
+// marshalBrainpoolPoint encodes a public key as an uncompressed point, as in SEC 1, section 2.3.3
+func marshalBrainpoolPoint(curve *weierstrassCurve, x, y *big.Int) []byte {
+	size := (curve.params.BitSize + 7) / 8
+	point := make([]byte, 1+2*size)
+	point[0] = 4
+	x.FillBytes(point[1 : 1+size])
+	y.FillBytes(point[1+size:])
+	return point
+}
+
+type brainpoolECPrivateKey struct {
+	Version       int
+	PrivateKey    []byte
+	NamedCurveOID asn1.ObjectIdentifier `asn1:"optional,explicit,tag:0"`
+	PublicKey     asn1.BitString        `asn1:"optional,explicit,tag:1"`
+}
+
+type brainpoolPKCS8 struct {
+	Version    int
+	Algo       pkix.AlgorithmIdentifier
+	PrivateKey []byte
+}
+
+type brainpoolPublicKeyInfo struct {
+	Algorithm pkix.AlgorithmIdentifier
+	PublicKey asn1.BitString
+}
+
+// marshalBrainpoolECPrivateKey encodes a brainpool ECDSA key in the SEC 1 format of the EC PRIVATE KEY PEM blocks.
+// The curve is omitted when the key is wrapped in PKCS #8, which already identifies it
+func marshalBrainpoolECPrivateKey(key *ecdsa.PrivateKey, curve *weierstrassCurve, withOID bool) ([]byte, error) {
+	privateKey := make([]byte, (curve.params.N.BitLen()+7)/8)
+	point := marshalBrainpoolPoint(curve, key.X, key.Y)
+	ecKey := brainpoolECPrivateKey{
+		Version:    1,
+		PrivateKey: key.D.FillBytes(privateKey),
+		PublicKey:  asn1.BitString{Bytes: point, BitLength: 8 * len(point)},
+	}
+	if withOID {
+		ecKey.NamedCurveOID = curve.oid
+	}
+	return asn1.Marshal(ecKey)
+}
+
+// marshalBrainpoolPKCS8PrivateKey encodes a brainpool ECDSA key in the unencrypted PKCS #8 format
+func marshalBrainpoolPKCS8PrivateKey(key *ecdsa.PrivateKey, curve *weierstrassCurve) ([]byte, error) {
+	ecKey, err := marshalBrainpoolECPrivateKey(key, curve, false)
+	if err != nil {
+		return nil, err
+	}
+	algo, err := brainpoolAlgorithm(curve)
+	if err != nil {
+		return nil, err
+	}
+	return asn1.Marshal(brainpoolPKCS8{Algo: algo, PrivateKey: ecKey})
+}
+
+// marshalBrainpoolPublicKey encodes the public key of a brainpool ECDSA key as a SubjectPublicKeyInfo
+func marshalBrainpoolPublicKey(key *ecdsa.PublicKey, curve *weierstrassCurve) ([]byte, error) {
+	algo, err := brainpoolAlgorithm(curve)
+	if err != nil {
+		return nil, err
+	}
+	point := marshalBrainpoolPoint(curve, key.X, key.Y)
+	return asn1.Marshal(brainpoolPublicKeyInfo{
+		Algorithm: algo,
+		PublicKey: asn1.BitString{Bytes: point, BitLength: 8 * len(point)},
+	})
+}
+
+func brainpoolAlgorithm(curve *weierstrassCurve) (pkix.AlgorithmIdentifier, error) {
+	params, err := asn1.Marshal(curve.oid)
+	if err != nil {
+		return pkix.AlgorithmIdentifier{}, err
+	}
+	return pkix.AlgorithmIdentifier{Algorithm: oidPublicKeyECDSA, Parameters: asn1.RawValue{FullBytes: params}}, nil
+}
+
+type rawCertificateRequest struct {
+	TBS                asn1.RawValue
+	SignatureAlgorithm pkix.AlgorithmIdentifier
+	Signature          asn1.BitString
+}
+
+type rawCertificateRequestInfo struct {
+	Version       int
+	Subject       asn1.RawValue
+	PublicKey     asn1.RawValue
+	RawAttributes []asn1.RawValue `asn1:"tag:0"`
+}
+
+// createBrainpoolCertificateRequest creates a certificate request signed with a brainpool key.
+// crypto/x509 encodes the request with a throwaway P-256 key, whose public key is then replaced
+// before the request is signed again with key
+func createBrainpoolCertificateRequest(template *x509.CertificateRequest, key *ecdsa.PrivateKey, curve *weierstrassCurve) ([]byte, error) {
+	placeholder, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		return nil, err
+	}
+	der, err := x509.CreateCertificateRequest(rand.Reader, template, placeholder)
+	if err != nil {
+		return nil, err
+	}
+
+	var csr rawCertificateRequest
+	if _, err = asn1.Unmarshal(der, &csr); err != nil {
+		return nil, err
+	}
+	var info rawCertificateRequestInfo
+	if _, err = asn1.Unmarshal(csr.TBS.FullBytes, &info); err != nil {
+		return nil, err
+	}
+
+	publicKey, err := marshalBrainpoolPublicKey(&key.PublicKey, curve)
+	if err != nil {
+		return nil, err
+	}
+	info.PublicKey = asn1.RawValue{FullBytes: publicKey}
+	tbs, err := asn1.Marshal(info)
+	if err != nil {
+		return nil, err
+	}
+
+	var digest []byte
+	switch curve.hash {
+	case crypto.SHA256:
+		sum := sha256.Sum256(tbs)
+		digest = sum[:]
+	case crypto.SHA384:
+		sum := sha512.Sum384(tbs)
+		digest = sum[:]
+	default:
+		sum := sha512.Sum512(tbs)
+		digest = sum[:]
+	}
+	signature, err := ecdsa.SignASN1(rand.Reader, key, digest)
+	if err != nil {
+		return nil, err
+	}
+
+	return asn1.Marshal(rawCertificateRequest{
+		TBS:                asn1.RawValue{FullBytes: tbs},
+		SignatureAlgorithm: pkix.AlgorithmIdentifier{Algorithm: oidSignatureECDSASHA2[curve.hash]},
+		Signature:          asn1.BitString{Bytes: signature, BitLength: 8 * len(signature)},
+	})
+}
+
+type rawCertificate struct {
+	TBS struct {
+		Version      int `asn1:"optional,explicit,default:0,tag:0"`
+		SerialNumber *big.Int
+		Signature    pkix.AlgorithmIdentifier
+		Issuer       asn1.RawValue
+		Validity     asn1.RawValue
+		Subject      asn1.RawValue
+		PublicKey    asn1.RawValue
+	}
+}
+
+// brainpoolKeyMatchesCertificate reports whether the certificate in DER format holds the public key of a
+// brainpool private key. crypto/x509 cannot parse such certificates, so the public key is compared as encoded
+func brainpoolKeyMatchesCertificate(certDER []byte, key *ecdsa.PrivateKey, curve *weierstrassCurve) (bool, error) {
+	var cert rawCertificate
+	if _, err := asn1.Unmarshal(certDER, &cert); err != nil {
+		return false, err
+	}
+	publicKey, err := marshalBrainpoolPublicKey(&key.PublicKey, curve)
+	if err != nil {
+		return false, err
+	}
+	return string(cert.TBS.PublicKey.FullBytes) == string(publicKey), nil
+}
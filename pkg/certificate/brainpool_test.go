@@ -0,0 +1,135 @@
+/*
+ * Copyright 2023 Venafi, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *  http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package certificate
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/sha256"
+	"crypto/sha512"
+	"encoding/asn1"
+	"encoding/pem"
+	"testing"
+)
+
+var brainpoolCurves = []EllipticCurve{EllipticCurveBrainpoolP256r1, EllipticCurveBrainpoolP384r1, EllipticCurveBrainpoolP512r1}
+
+func TestBrainpoolCurveParams(t *testing.T) {
+	for _, ec := range brainpoolCurves {
+		curve := brainpoolCurve(ec)
+		if curve == nil {
+			t.Fatalf("no curve for %s", ec.String())
+		}
+		params := curve.Params()
+		if !curve.IsOnCurve(params.Gx, params.Gy) {
+			t.Fatalf("generator of %s is not on the curve", params.Name)
+		}
+		x, y := curve.ScalarBaseMult(params.N.Bytes())
+		if x.Sign() != 0 || y.Sign() != 0 {
+			t.Fatalf("N·G of %s is not the point at infinity", params.Name)
+		}
+	}
+}
+
+func TestGenerateCertificateRequestWithBrainpoolKey(t *testing.T) {
+	for _, ec := range brainpoolCurves {
+		req := getCertificateRequestForTest()
+		var err error
+		req.PrivateKey, err = GenerateECDSAPrivateKey(ec)
+		if err != nil {
+			t.Fatalf("Error generating %s Private Key\nError: %s", ec.String(), err)
+		}
+
+		err = req.GenerateCSR()
+		if err != nil {
+			t.Fatalf("Error generating Certificate Request\nError: %s", err)
+		}
+
+		pemBlock, _ := pem.Decode(req.GetCSR())
+		if pemBlock == nil || pemBlock.Type != "CERTIFICATE REQUEST" {
+			t.Fatalf("Failed to decode CSR as PEM")
+		}
+
+		var csr rawCertificateRequest
+		if _, err = asn1.Unmarshal(pemBlock.Bytes, &csr); err != nil {
+			t.Fatalf("Error parsing generated Certificate Request\nError: %s", err)
+		}
+		key := req.PrivateKey.(*ecdsa.PrivateKey)
+		curve := brainpoolCurve(ec)
+		publicKey, err := marshalBrainpoolPublicKey(&key.PublicKey, curve)
+		if err != nil {
+			t.Fatal(err)
+		}
+		var info rawCertificateRequestInfo
+		if _, err = asn1.Unmarshal(csr.TBS.FullBytes, &info); err != nil {
+			t.Fatal(err)
+		}
+		if string(info.PublicKey.FullBytes) != string(publicKey) {
+			t.Fatalf("%s public key missing from Certificate Request", ec.String())
+		}
+
+		var digest []byte
+		switch curve.hash {
+		case crypto.SHA256:
+			sum := sha256.Sum256(csr.TBS.FullBytes)
+			digest = sum[:]
+		case crypto.SHA384:
+			sum := sha512.Sum384(csr.TBS.FullBytes)
+			digest = sum[:]
+		default:
+			sum := sha512.Sum512(csr.TBS.FullBytes)
+			digest = sum[:]
+		}
+		if !csr.SignatureAlgorithm.Algorithm.Equal(oidSignatureECDSASHA2[curve.hash]) {
+			t.Fatalf("unexpected signature algorithm %s", csr.SignatureAlgorithm.Algorithm)
+		}
+		if !ecdsa.VerifyASN1(&key.PublicKey, digest, csr.Signature.Bytes) {
+			t.Fatalf("invalid signature of %s Certificate Request", ec.String())
+		}
+	}
+}
+
+func TestGetBrainpoolPrivateKeyPEMBock(t *testing.T) {
+	key, err := GenerateECDSAPrivateKey(EllipticCurveBrainpoolP384r1)
+	if err != nil {
+		t.Fatalf("Error generating Private Key\nError: %s", err)
+	}
+
+	block, err := GetPrivateKeyPEMBock(key)
+	if err != nil {
+		t.Fatalf("Error getting PEM block\nError: %s", err)
+	}
+	if block.Type != "PRIVATE KEY" {
+		t.Fatalf("unexpected PEM type %s", block.Type)
+	}
+
+	block, err = GetPrivateKeyPEMBock(key, "legacy-pem")
+	if err != nil {
+		t.Fatalf("Error getting PEM block\nError: %s", err)
+	}
+	if block.Type != "EC PRIVATE KEY" {
+		t.Fatalf("unexpected PEM type %s", block.Type)
+	}
+
+	block, err = GetEncryptedPrivateKeyPEMBock(key, []byte("password"))
+	if err != nil {
+		t.Fatalf("Error getting encrypted PEM block\nError: %s", err)
+	}
+	if block.Type != "EC PRIVATE KEY" || block.Headers["DEK-Info"] == "" {
+		t.Fatalf("unexpected encrypted PEM block %s", block.Type)
+	}
+}
@@ -104,6 +104,20 @@ type TppSshCertRetrieveRequest struct {
 	IncludeCertificateDetails bool
 }
 
+type TppSshCertRenewRequest struct {
+	Guid                      string `json:"Guid,omitempty"`
+	DN                        string `json:"DN,omitempty"`
+	PublicKeyData             string `json:"PublicKeyData,omitempty"`
+	IncludePrivateKeyData     bool   `json:"IncludePrivateKeyData,omitempty"`
+	PrivateKeyPassphrase      string `json:"PrivateKeyPassphrase,omitempty"`
+	IncludeCertificateDetails bool   `json:"IncludeCertificateDetails,omitempty"`
+}
+
+type TppSshCertRevokeRequest struct {
+	Guid string `json:"Guid,omitempty"`
+	DN   string `json:"DN,omitempty"`
+}
+
 type TppSshCertOperationResponse struct {
 	ProcessingDetails  ProcessingDetails
 	Guid               string
@@ -162,6 +176,8 @@ type RetireRequest struct {
 	CertificateDN string
 	Thumbprint    string
 	Description   string
+	// AddToBlocklist prevents the retired certificate from being discovered or imported again. Only used by VaaS
+	AddToBlocklist bool
 }
 
 type RenewalRequest struct {
@@ -328,6 +344,9 @@ func GetPrivateKeyPEMBock(key crypto.Signer, format ...string) (*pem.Block, erro
 			return &pem.Block{Type: "PRIVATE KEY", Bytes: dataBytes}, err
 		}
 	case *ecdsa.PrivateKey:
+		if _, curve, ok := isBrainpoolKey(k); ok {
+			return getBrainpoolPrivateKeyPEMBlock(k, curve, currentFormat)
+		}
 		if currentFormat == "legacy-pem" {
 			b, err := x509.MarshalECPrivateKey(k)
 			if err != nil {
@@ -352,8 +371,33 @@ func GetPrivateKeyPEMBock(key crypto.Signer, format ...string) (*pem.Block, erro
 			return &pem.Block{Type: "PRIVATE KEY", Bytes: dataBytes}, err
 		}
 	default:
-		return nil, fmt.Errorf("%w: unable to format Key", verror.VcertError)
+		if !isMLDSAPrivateKey(key) {
+			return nil, fmt.Errorf("%w: unable to format Key", verror.VcertError)
+		}
+		if currentFormat == "legacy-pem" {
+			return nil, fmt.Errorf("%w: unable to format Key. Legacy format for ML-DSA is not supported", verror.VcertError)
+		}
+		dataBytes, err := pkcs8.MarshalPrivateKey(key, nil, nil)
+		if err != nil {
+			return nil, err
+		}
+		return &pem.Block{Type: "PRIVATE KEY", Bytes: dataBytes}, nil
+	}
+}
+
+func getBrainpoolPrivateKeyPEMBlock(key *ecdsa.PrivateKey, curve *weierstrassCurve, format string) (*pem.Block, error) {
+	if format == "legacy-pem" {
+		b, err := marshalBrainpoolECPrivateKey(key, curve, true)
+		if err != nil {
+			return nil, err
+		}
+		return &pem.Block{Type: "EC PRIVATE KEY", Bytes: b}, nil
 	}
+	b, err := marshalBrainpoolPKCS8PrivateKey(key, curve)
+	if err != nil {
+		return nil, err
+	}
+	return &pem.Block{Type: "PRIVATE KEY", Bytes: b}, nil
 }
 
 // GetEncryptedPrivateKeyPEMBock gets the private key as an encrypted PEM data block
@@ -374,6 +418,15 @@ func GetEncryptedPrivateKeyPEMBock(key crypto.Signer, password []byte, format ..
 			return &pem.Block{Type: "ENCRYPTED PRIVATE KEY", Bytes: dataBytes}, err
 		}
 	case *ecdsa.PrivateKey:
+		if _, curve, ok := isBrainpoolKey(k); ok {
+			// PKCS #8 encryption relies on crypto/x509 to encode the key, so brainpool keys are always
+			// encrypted in the legacy format
+			b, err := marshalBrainpoolECPrivateKey(k, curve, true)
+			if err != nil {
+				return nil, err
+			}
+			return util.X509EncryptPEMBlock(rand.Reader, "EC PRIVATE KEY", b, password, util.PEMCipherAES256)
+		}
 		if currentFormat == "legacy-pem" {
 			b, err := x509.MarshalECPrivateKey(k)
 			if err != nil {
@@ -398,7 +451,17 @@ func GetEncryptedPrivateKeyPEMBock(key crypto.Signer, password []byte, format ..
 			return &pem.Block{Type: "ENCRYPTED PRIVATE KEY", Bytes: dataBytes}, err
 		}
 	default:
-		return nil, fmt.Errorf("%w: unable to format Key", verror.VcertError)
+		if !isMLDSAPrivateKey(key) {
+			return nil, fmt.Errorf("%w: unable to format Key", verror.VcertError)
+		}
+		if currentFormat == "legacy-pem" {
+			return nil, fmt.Errorf("%w: unable to format Key. Legacy format for ML-DSA is not supported", verror.VcertError)
+		}
+		dataBytes, err := pkcs8.MarshalPrivateKey(key, password, nil)
+		if err != nil {
+			return nil, err
+		}
+		return &pem.Block{Type: "ENCRYPTED PRIVATE KEY", Bytes: dataBytes}, nil
 	}
 }
 
@@ -430,6 +493,8 @@ func GenerateECDSAPrivateKey(curve EllipticCurve) (crypto.Signer, error) {
 		c = elliptic.P256()
 	case EllipticCurveED25519:
 		return nil, fmt.Errorf("%w: unable to generate ECDSA key. ED25519 curve is not supported, use GenerateED25519PrivateKey instead", verror.VcertError)
+	case EllipticCurveBrainpoolP256r1, EllipticCurveBrainpoolP384r1, EllipticCurveBrainpoolP512r1:
+		c = brainpoolCurve(curve)
 	}
 
 	priv, err = ecdsa.GenerateKey(c, rand.Reader)
@@ -469,6 +534,7 @@ func NewRequest(cert *x509.Certificate) *Request {
 	req.IPAddresses = cert.IPAddresses
 	req.URIs = cert.URIs
 	req.UPNs, _ = getUserPrincipalNameSANs(cert)
+	req.RegisteredIDs, _ = getRegisteredIDSANs(cert)
 
 	req.SignatureAlgorithm = cert.SignatureAlgorithm
 	switch pub := cert.PublicKey.(type) {
@@ -482,7 +548,10 @@ func NewRequest(cert *x509.Certificate) *Request {
 		req.KeyType = KeyTypeED25519
 		_ = req.KeyCurve.Set("ed25519")
 	default:
-		// vcert only works with RSA, ECDSA & Ed25519 keys
+		// vcert only works with RSA, ECDSA & Ed25519 keys, along with the experimental ML-DSA ones
+		if kt, ok := mldsaKeyType(pub); ok {
+			req.KeyType = kt
+		}
 	}
 	return req
 }
@@ -23,6 +23,8 @@ import (
 	"encoding/pem"
 	"fmt"
 
+	"software.sslmate.com/src/go-pkcs12"
+
 	"github.com/Venafi/vcert/v5/pkg/verror"
 )
 
@@ -34,7 +36,8 @@ type PEMCollection struct {
 	CSR         string   `json:",omitempty"`
 }
 
-// NewPEMCollection creates a PEMCollection based on the data being passed in
+// NewPEMCollection creates a PEMCollection based on the data being passed in. Private keys that are not exportable,
+// like the ones held by an HSM, are left out of the collection
 func NewPEMCollection(certificate *x509.Certificate, privateKey crypto.Signer, privateKeyPassword []byte, format ...string) (*PEMCollection, error) {
 	collection := PEMCollection{}
 	currentFormat := ""
@@ -44,7 +47,7 @@ func NewPEMCollection(certificate *x509.Certificate, privateKey crypto.Signer, p
 	if certificate != nil {
 		collection.Certificate = string(pem.EncodeToMemory(GetCertificatePEMBlock(certificate.Raw)))
 	}
-	if privateKey != nil {
+	if privateKey != nil && IsKeyExportable(privateKey) {
 		var p *pem.Block
 		var err error
 		if len(privateKeyPassword) > 0 {
@@ -126,8 +129,82 @@ func PEMCollectionFromBytes(certBytes []byte, chainOrder ChainOption) (*PEMColle
 	return collection, nil
 }
 
-// AddPrivateKey adds a Private Key to the PEMCollection. Note that the collection can only contain one private key
+// PEMCollectionFromPKCS12 creates a PEMCollection from a PKCS#12 keystore protected by password, as downloaded for
+// the private keys generated by the platform. The private key is encrypted again with password, so it is not held
+// unencrypted in the collection. The chain is ordered from the issuer of the certificate when the keystore does not
+// follow that order
+func PEMCollectionFromPKCS12(data []byte, password string, chainOrder ChainOption) (*PEMCollection, error) {
+	privateKey, cert, caCerts, err := pkcs12.DecodeChain(data, password)
+	if err != nil {
+		return nil, fmt.Errorf("failed to decode the PKCS#12 keystore: %w", err)
+	}
+	signer, ok := privateKey.(crypto.Signer)
+	if !ok {
+		return nil, fmt.Errorf("unsupported private key of type %T in the PKCS#12 keystore", privateKey)
+	}
+
+	var privateKeyPassword []byte
+	if password != "" {
+		privateKeyPassword = []byte(password)
+	}
+	collection, err := NewPEMCollection(cert, signer, privateKeyPassword)
+	if err != nil {
+		return nil, err
+	}
+	if chainOrder == ChainOptionIgnore {
+		return collection, nil
+	}
+
+	chain := sortChain(cert, caCerts)
+	if chainOrder == ChainOptionRootFirst {
+		for i, j := 0, len(chain)-1; i < j; i, j = i+1, j-1 {
+			chain[i], chain[j] = chain[j], chain[i]
+		}
+	}
+	for _, caCert := range chain {
+		err = collection.AddChainElement(caCert)
+		if err != nil {
+			return nil, err
+		}
+	}
+	return collection, nil
+}
+
+// sortChain orders the certificates of the chain of leaf from its issuer up to the root. The certificates that are
+// not in the path of leaf are kept at the end, in their original order
+func sortChain(leaf *x509.Certificate, chain []*x509.Certificate) []*x509.Certificate {
+	sorted := make([]*x509.Certificate, 0, len(chain))
+	used := make([]bool, len(chain))
+	current := leaf
+	for len(sorted) < len(chain) {
+		next := -1
+		for i, candidate := range chain {
+			if !used[i] && current.CheckSignatureFrom(candidate) == nil {
+				next = i
+				break
+			}
+		}
+		if next < 0 {
+			break
+		}
+		used[next] = true
+		sorted = append(sorted, chain[next])
+		current = chain[next]
+	}
+	for i, caCert := range chain {
+		if !used[i] {
+			sorted = append(sorted, caCert)
+		}
+	}
+	return sorted
+}
+
+// AddPrivateKey adds a Private Key to the PEMCollection. Note that the collection can only contain one private key.
+// Private keys that are not exportable, like the ones held by an HSM, are not added
 func (col *PEMCollection) AddPrivateKey(privateKey crypto.Signer, privateKeyPassword []byte, format ...string) error {
+	if privateKey != nil && !IsKeyExportable(privateKey) {
+		return nil
+	}
 
 	currentFormat := ""
 	if len(format) > 0 && format[0] != "" {
@@ -21,9 +21,11 @@ import (
 	"crypto/rand"
 	"crypto/rsa"
 	"crypto/x509"
+	"encoding/asn1"
 	"encoding/pem"
 	"math/big"
 	"net"
+	"net/url"
 	"os"
 	"reflect"
 	"strings"
@@ -165,6 +167,61 @@ func TestGenerateCertificateRequestWithRSAKey(t *testing.T) {
 	}
 }
 
+func TestGenerateCertificateRequestWithURIAndOtherSANs(t *testing.T) {
+	key, err := GenerateECDSAPrivateKey(EllipticCurveP256)
+	if err != nil {
+		t.Fatalf("Error generating ECDSA Private Key\nError: %s", err)
+	}
+	spiffeID, _ := url.Parse("spiffe://venafi.example/ns/default/sa/web")
+	rid := asn1.ObjectIdentifier{1, 3, 6, 1, 4, 1, 311, 25, 1}
+	req := Request{
+		PrivateKey:    key,
+		URIs:          []*url.URL{spiffeID},
+		UPNs:          []string{"user@venafi.example"},
+		RegisteredIDs: []asn1.ObjectIdentifier{rid},
+	}
+	err = req.GenerateCSR()
+	if err != nil {
+		t.Fatalf("Error generating Certificate Request\nError: %s", err)
+	}
+
+	pemBlock, _ := pem.Decode(req.GetCSR())
+	csr, err := x509.ParseCertificateRequest(pemBlock.Bytes)
+	if err != nil {
+		t.Fatalf("Error parsing generated Certificate Request\nError: %s", err)
+	}
+	if len(csr.URIs) != 1 || csr.URIs[0].String() != spiffeID.String() {
+		t.Fatalf("expected URI SAN %s, got %v", spiffeID, csr.URIs)
+	}
+	for _, ext := range csr.Extensions {
+		// the subject is empty, so the SAN extension must be critical
+		if ext.Id.Equal(oidExtensionSubjectAltName) && !ext.Critical {
+			t.Fatal("expected a critical SAN extension")
+		}
+	}
+
+	// The SANs of the certificate issued for the CSR are kept when renewing it
+	template := &x509.Certificate{SerialNumber: big.NewInt(1), ExtraExtensions: csr.Extensions}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, csr.PublicKey, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cert, err := x509.ParseCertificate(der)
+	if err != nil {
+		t.Fatal(err)
+	}
+	renewal := NewRequest(cert)
+	if len(renewal.URIs) != 1 || renewal.URIs[0].String() != spiffeID.String() {
+		t.Fatalf("expected URI SAN %s, got %v", spiffeID, renewal.URIs)
+	}
+	if !reflect.DeepEqual(renewal.UPNs, req.UPNs) {
+		t.Fatalf("expected UPN SANs %v, got %v", req.UPNs, renewal.UPNs)
+	}
+	if len(renewal.RegisteredIDs) != 1 || !renewal.RegisteredIDs[0].Equal(rid) {
+		t.Fatalf("expected registeredID SAN %s, got %v", rid, renewal.RegisteredIDs)
+	}
+}
+
 func TestGenerateCertificateRequestWithECDSAKey(t *testing.T) {
 	req := getCertificateRequestForTest()
 	var err error
@@ -280,7 +337,7 @@ func TestKeyTypeString(t *testing.T) {
 	if s != "RSA" {
 		t.Fatalf("Unexpected string value was returned.  Expected: RSA Actual: %s", s)
 	}
-	keyType = 5
+	keyType = 100
 	s = keyType.String()
 	if s != "" {
 		t.Fatalf("Unexpected string value was returned.  Expected: \"\" Actual: %s", s)
@@ -625,6 +682,60 @@ func Test_NewRequest(t *testing.T) {
 	}
 }
 
+func TestRequest_SetPrivateKey(t *testing.T) {
+	rsaPk, err := GenerateRSAPrivateKey(2048)
+	if err != nil {
+		t.Fatalf("Error generating RSA Private Key\nError: %s", err)
+	}
+	ecdsaPk, err := GenerateECDSAPrivateKey(EllipticCurveP384)
+	if err != nil {
+		t.Fatalf("Error generating ECDSA Private Key\nError: %s", err)
+	}
+
+	cases := []struct {
+		name       string
+		key        crypto.Signer
+		expRequest Request
+	}{
+		{"rsa key", rsaPk, Request{KeyType: KeyTypeRSA, KeyLength: 2048, PrivateKey: rsaPk}},
+		{"ecdsa key", ecdsaPk, Request{KeyType: KeyTypeECDSA, KeyCurve: EllipticCurveP384, PrivateKey: ecdsaPk}},
+	}
+	for _, c := range cases {
+		c := c
+		t.Run(c.name, func(t *testing.T) {
+			// the key type of the request is replaced by the one of the key
+			req := Request{KeyType: KeyTypeED25519, KeyCurve: EllipticCurveED25519}
+			req.SetPrivateKey(c.key)
+			if !reflect.DeepEqual(req, c.expRequest) {
+				t.Fatalf("expected request to be %v, got %v", c.expRequest, req)
+			}
+			err := req.GeneratePrivateKey()
+			if err != nil || req.PrivateKey != c.key {
+				t.Fatalf("expected the private key set to be kept, got error: %v", err)
+			}
+		})
+	}
+}
+
+func TestRequest_GetValidityDuration(t *testing.T) {
+	week := 7 * 24 * time.Hour
+	notAfter := time.Now().Add(48 * time.Hour)
+
+	if v := (&Request{}).GetValidityDuration(); v != nil {
+		t.Fatalf("expected no validity, got %s", v)
+	}
+	if v := (&Request{ValidityHours: 12}).GetValidityDuration(); v == nil || *v != 12*time.Hour {
+		t.Fatalf("expected validity of 12h, got %v", v)
+	}
+	if v := (&Request{ValidityHours: 12, ValidityDuration: &week}).GetValidityDuration(); v == nil || *v != week {
+		t.Fatalf("expected ValidityDuration to take precedence over ValidityHours, got %v", v)
+	}
+	v := (&Request{ValidityDuration: &week, NotAfter: &notAfter}).GetValidityDuration()
+	if v == nil || *v > 48*time.Hour || *v < 47*time.Hour {
+		t.Fatalf("expected NotAfter to take precedence over ValidityDuration, got %v", v)
+	}
+}
+
 func TestRequest_SetCSR_and_GetCSR(t *testing.T) {
 	checkCN := "setcsr.example.com"
 	certificateRequest := x509.CertificateRequest{}
@@ -0,0 +1,68 @@
+/*
+ * Copyright 2023 Venafi, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *  http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package certificate
+
+import (
+	"bytes"
+	"crypto/x509"
+	"encoding/pem"
+)
+
+// BuildChain returns the PEM encoded issued certificates, followed by the issuers of the last one up to the root,
+// looked up in candidates. Enrollment protocols like EST and SCEP usually return only the issued certificate, along
+// with the CA certificates of the server
+func BuildChain(issued []*x509.Certificate, candidates []*x509.Certificate) []byte {
+	certs := append([]*x509.Certificate{}, issued...)
+	for current := certs[len(certs)-1]; !IsSelfSigned(current); {
+		issuer := FindIssuer(current, candidates)
+		if issuer == nil || containsCertificate(certs, issuer) {
+			break
+		}
+		certs = append(certs, issuer)
+		current = issuer
+	}
+
+	var chain []byte
+	for _, cert := range certs {
+		chain = append(chain, pem.EncodeToMemory(GetCertificatePEMBlock(cert.Raw))...)
+	}
+	return chain
+}
+
+// FindIssuer returns the certificate of candidates that signed cert, or nil when none did
+func FindIssuer(cert *x509.Certificate, candidates []*x509.Certificate) *x509.Certificate {
+	for _, candidate := range candidates {
+		if bytes.Equal(cert.RawIssuer, candidate.RawSubject) && cert.CheckSignatureFrom(candidate) == nil {
+			return candidate
+		}
+	}
+	return nil
+}
+
+// IsSelfSigned returns true when cert is signed by its own key, like the certificate of a root CA
+func IsSelfSigned(cert *x509.Certificate) bool {
+	return bytes.Equal(cert.RawIssuer, cert.RawSubject) && cert.CheckSignatureFrom(cert) == nil
+}
+
+func containsCertificate(certs []*x509.Certificate, cert *x509.Certificate) bool {
+	for _, c := range certs {
+		if c.Equal(cert) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,85 @@
+/*
+ * Copyright 2023 Venafi, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *  http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package certificate
+
+import (
+	"crypto/x509"
+	"testing"
+
+	"github.com/Venafi/vcert/v5/test/testcert"
+)
+
+func TestBuildChain(t *testing.T) {
+	root := testcert.Issue(testcert.Template("Test Root", true), nil, nil)
+	intermediate := testcert.Issue(testcert.Template("Test Intermediate", true), nil, &root)
+	leaf := testcert.Issue(testcert.Template("leaf.example.com", false), nil, &intermediate)
+	otherRoot := testcert.Issue(testcert.Template("Other Root", true), nil, nil)
+
+	cases := []struct {
+		name       string
+		issued     []testcert.Certificate
+		candidates []testcert.Certificate
+		expected   []testcert.Certificate
+	}{
+		{name: "CompleteChain", issued: []testcert.Certificate{leaf}, candidates: []testcert.Certificate{otherRoot, root, intermediate},
+			expected: []testcert.Certificate{leaf, intermediate, root}},
+		{name: "IssuerMissing", issued: []testcert.Certificate{leaf}, candidates: []testcert.Certificate{root},
+			expected: []testcert.Certificate{leaf}},
+		{name: "IssuedWithIntermediate", issued: []testcert.Certificate{leaf, intermediate}, candidates: []testcert.Certificate{root, intermediate},
+			expected: []testcert.Certificate{leaf, intermediate, root}},
+		{name: "SelfSigned", issued: []testcert.Certificate{root}, candidates: []testcert.Certificate{root},
+			expected: []testcert.Certificate{root}},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			issued := make([]*x509.Certificate, 0)
+			for _, cert := range c.issued {
+				issued = append(issued, cert.Cert)
+			}
+			candidates := make([]*x509.Certificate, 0)
+			for _, cert := range c.candidates {
+				candidates = append(candidates, cert.Cert)
+			}
+			expected := ""
+			for _, cert := range c.expected {
+				expected += cert.PEM()
+			}
+
+			chain := string(BuildChain(issued, candidates))
+			if chain != expected {
+				t.Fatalf("unexpected chain:\n%s\nexpected:\n%s", chain, expected)
+			}
+		})
+	}
+}
+
+func TestIsSelfSigned(t *testing.T) {
+	root := testcert.Issue(testcert.Template("Test Root", true), nil, nil)
+	leaf := testcert.Issue(testcert.Template("leaf.example.com", false), nil, &root)
+	// A certificate whose subject is its issuer, signed by another key, is not self-signed
+	impostor := testcert.Issue(testcert.Template("Test Root", true), nil, &root)
+
+	if !IsSelfSigned(root.Cert) {
+		t.Errorf("root is not detected as self-signed")
+	}
+	if IsSelfSigned(leaf.Cert) || IsSelfSigned(impostor.Cert) {
+		t.Errorf("certificate signed by another key detected as self-signed")
+	}
+	if FindIssuer(impostor.Cert, []*x509.Certificate{impostor.Cert, root.Cert}) != root.Cert {
+		t.Errorf("issuer not found by signature")
+	}
+}
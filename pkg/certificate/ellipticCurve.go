@@ -20,6 +20,12 @@ const (
 	EllipticCurveP384
 	// EllipticCurveED25519 represents the ED25519 curve
 	EllipticCurveED25519
+	// EllipticCurveBrainpoolP256r1 represents the brainpoolP256r1 curve of RFC 5639
+	EllipticCurveBrainpoolP256r1
+	// EllipticCurveBrainpoolP384r1 represents the brainpoolP384r1 curve of RFC 5639
+	EllipticCurveBrainpoolP384r1
+	// EllipticCurveBrainpoolP512r1 represents the brainpoolP512r1 curve of RFC 5639
+	EllipticCurveBrainpoolP512r1
 	// EllipticCurveDefault represents the default curve value
 	EllipticCurveDefault = EllipticCurveP256
 
@@ -28,6 +34,10 @@ const (
 	strEccP384    = "P384"
 	strEccP521    = "P521"
 	strEccED25519 = "ED25519"
+
+	strEccBrainpoolP256r1 = "brainpoolP256r1"
+	strEccBrainpoolP384r1 = "brainpoolP384r1"
+	strEccBrainpoolP512r1 = "brainpoolP512r1"
 )
 
 func (ec *EllipticCurve) String() string {
@@ -40,39 +50,40 @@ func (ec *EllipticCurve) String() string {
 		return strEccP256
 	case EllipticCurveED25519:
 		return strEccED25519
+	case EllipticCurveBrainpoolP256r1:
+		return strEccBrainpoolP256r1
+	case EllipticCurveBrainpoolP384r1:
+		return strEccBrainpoolP384r1
+	case EllipticCurveBrainpoolP512r1:
+		return strEccBrainpoolP512r1
 	default:
 		return ""
 	}
 }
 
-// Set EllipticCurve value via a string
+// Set EllipticCurve value via a string. Besides the names used by the Venafi platforms, the SEC 2 and
+// ANSI X9.62 names of the NIST curves are accepted, i.e. secp384r1 and prime256v1
 func (ec *EllipticCurve) Set(value string) error {
-	switch strings.ToUpper(value) {
-	case strEccP521, "P-521":
-		*ec = EllipticCurveP521
-	case strEccP384, "P-384":
-		*ec = EllipticCurveP384
-	case strEccP256, "P-256":
-		*ec = EllipticCurveP256
-	case strEccED25519:
-		*ec = EllipticCurveED25519
-	default:
-		*ec = EllipticCurveDefault
-	}
-
+	*ec = parseEllipticCurve(value)
 	return nil
 }
 
 func parseEllipticCurve(value string) EllipticCurve {
 	switch strings.ToUpper(value) {
-	case strEccP256, "P-256":
+	case strEccP256, "P-256", "SECP256R1", "PRIME256V1":
 		return EllipticCurveP256
-	case strEccP384, "P-384":
+	case strEccP384, "P-384", "SECP384R1":
 		return EllipticCurveP384
-	case strEccP521, "P-521":
+	case strEccP521, "P-521", "SECP521R1":
 		return EllipticCurveP521
 	case strEccED25519:
 		return EllipticCurveED25519
+	case strings.ToUpper(strEccBrainpoolP256r1):
+		return EllipticCurveBrainpoolP256r1
+	case strings.ToUpper(strEccBrainpoolP384r1):
+		return EllipticCurveBrainpoolP384r1
+	case strings.ToUpper(strEccBrainpoolP512r1):
+		return EllipticCurveBrainpoolP512r1
 	default:
 		return EllipticCurveDefault
 	}
@@ -98,5 +109,6 @@ func (ec *EllipticCurve) UnmarshalYAML(value *yaml.Node) error {
 }
 
 func AllSupportedCurves() []EllipticCurve {
-	return []EllipticCurve{EllipticCurveP521, EllipticCurveP256, EllipticCurveP384, EllipticCurveED25519}
+	return []EllipticCurve{EllipticCurveP521, EllipticCurveP256, EllipticCurveP384, EllipticCurveED25519,
+		EllipticCurveBrainpoolP256r1, EllipticCurveBrainpoolP384r1, EllipticCurveBrainpoolP512r1}
 }
@@ -26,6 +26,9 @@ func (s *EllipticCurveSuite) SetupTest() {
 		{keyCurve: EllipticCurveP384, strValue: strEccP384},
 		{keyCurve: EllipticCurveP521, strValue: strEccP521},
 		{keyCurve: EllipticCurveED25519, strValue: strEccED25519},
+		{keyCurve: EllipticCurveBrainpoolP256r1, strValue: strEccBrainpoolP256r1},
+		{keyCurve: EllipticCurveBrainpoolP384r1, strValue: strEccBrainpoolP384r1},
+		{keyCurve: EllipticCurveBrainpoolP512r1, strValue: strEccBrainpoolP512r1},
 	}
 
 	s.testYaml = `---
@@ -72,3 +75,20 @@ func (s *EllipticCurveSuite) TestEllipticCurve_UnmarshalYAML() {
 		})
 	}
 }
+
+func (s *EllipticCurveSuite) TestEllipticCurve_SetAliases() {
+	aliases := map[string]EllipticCurve{
+		"secp256r1":       EllipticCurveP256,
+		"prime256v1":      EllipticCurveP256,
+		"secp384r1":       EllipticCurveP384,
+		"secp521r1":       EllipticCurveP521,
+		"BRAINPOOLP256R1": EllipticCurveBrainpoolP256r1,
+	}
+	for alias, expected := range aliases {
+		s.Run(alias, func() {
+			var curve EllipticCurve
+			s.Nil(curve.Set(alias))
+			s.Equal(expected, curve)
+		})
+	}
+}
@@ -0,0 +1,131 @@
+/*
+ * Copyright 2023 Venafi, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *  http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package certificate
+
+import (
+	"crypto/x509/pkix"
+	"encoding/asn1"
+	"encoding/base64"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/Venafi/vcert/v5/pkg/verror"
+)
+
+var oidExtensionExtendedKeyUsage = asn1.ObjectIdentifier{2, 5, 29, 37}
+
+// extKeyUsageOIDs holds the extended key usages that can be requested by name, in lower case
+var extKeyUsageOIDs = map[string]asn1.ObjectIdentifier{
+	"anyextendedkeyusage": {2, 5, 29, 37, 0},
+	"serverauth":          {1, 3, 6, 1, 5, 5, 7, 3, 1},
+	"clientauth":          {1, 3, 6, 1, 5, 5, 7, 3, 2},
+	"codesigning":         {1, 3, 6, 1, 5, 5, 7, 3, 3},
+	"emailprotection":     {1, 3, 6, 1, 5, 5, 7, 3, 4},
+	"ipsecendsystem":      {1, 3, 6, 1, 5, 5, 7, 3, 5},
+	"ipsectunnel":         {1, 3, 6, 1, 5, 5, 7, 3, 6},
+	"ipsecuser":           {1, 3, 6, 1, 5, 5, 7, 3, 7},
+	"timestamping":        {1, 3, 6, 1, 5, 5, 7, 3, 8},
+	"ocspsigning":         {1, 3, 6, 1, 5, 5, 7, 3, 9},
+	"eapoverppp":          {1, 3, 6, 1, 5, 5, 7, 3, 13},
+	"eapoverlan":          {1, 3, 6, 1, 5, 5, 7, 3, 14},
+	"pkinitclientauth":    {1, 3, 6, 1, 5, 2, 3, 4},
+	"pkinitkdc":           {1, 3, 6, 1, 5, 2, 3, 5},
+	"smartcardlogon":      {1, 3, 6, 1, 4, 1, 311, 20, 2, 2},
+}
+
+// ParseOID parses an object identifier in dotted notation, i.e. 1.3.6.1.4.1.311.20.2.2
+func ParseOID(s string) (asn1.ObjectIdentifier, error) {
+	arcs := strings.Split(strings.TrimSpace(s), ".")
+	if len(arcs) < 2 {
+		return nil, fmt.Errorf("%w: invalid OID %q", verror.UserDataError, s)
+	}
+	oid := make(asn1.ObjectIdentifier, len(arcs))
+	for i, arc := range arcs {
+		n, err := strconv.Atoi(arc)
+		if err != nil || n < 0 {
+			return nil, fmt.Errorf("%w: invalid OID %q", verror.UserDataError, s)
+		}
+		oid[i] = n
+	}
+	// The first arc is 0, 1 or 2, and the second one is below 40 unless the first one is 2
+	if oid[0] > 2 || (oid[0] < 2 && oid[1] >= 40) {
+		return nil, fmt.Errorf("%w: invalid OID %q", verror.UserDataError, s)
+	}
+	return oid, nil
+}
+
+// ParseExtKeyUsage returns the OID of an extended key usage given by name, case insensitively, or as an OID in dotted
+// notation. The names are anyExtendedKeyUsage, serverAuth, clientAuth, codeSigning, emailProtection, ipsecEndSystem,
+// ipsecTunnel, ipsecUser, timeStamping, ocspSigning, eapOverPPP, eapOverLAN, pkinitClientAuth, pkinitKDC and
+// smartcardLogon
+func ParseExtKeyUsage(s string) (asn1.ObjectIdentifier, error) {
+	if oid, found := extKeyUsageOIDs[strings.ToLower(strings.TrimSpace(s))]; found {
+		return oid, nil
+	}
+	oid, err := ParseOID(s)
+	if err != nil {
+		return nil, fmt.Errorf("%w: unknown extended key usage %q", verror.UserDataError, s)
+	}
+	return oid, nil
+}
+
+// ParseExtension returns the extension identified by oid, in dotted notation, whose value is the base64 encoding of
+// its DER value
+func ParseExtension(oid string, critical bool, value string) (pkix.Extension, error) {
+	id, err := ParseOID(oid)
+	if err != nil {
+		return pkix.Extension{}, err
+	}
+	der, err := base64.StdEncoding.DecodeString(strings.TrimSpace(value))
+	if err != nil {
+		return pkix.Extension{}, fmt.Errorf("%w: value of extension %s is not base64 encoded: %s", verror.UserDataError, oid, err)
+	}
+	return pkix.Extension{Id: id, Critical: critical, Value: der}, nil
+}
+
+// csrExtensions returns the extensions of the CSR other than the subject alternative names: an extended key usage
+// extension holding ExtKeyUsages, followed by Extensions
+func (request *Request) csrExtensions() ([]pkix.Extension, error) {
+	var extensions []pkix.Extension
+	if len(request.ExtKeyUsages) > 0 {
+		value, err := asn1.Marshal(request.ExtKeyUsages)
+		if err != nil {
+			return nil, fmt.Errorf("%w: invalid extended key usages: %s", verror.UserDataError, err)
+		}
+		extensions = append(extensions, pkix.Extension{Id: oidExtensionExtendedKeyUsage, Value: value})
+	}
+
+	for _, ext := range request.Extensions {
+		if ext.Id.Equal(oidExtensionSubjectAltName) {
+			return nil, fmt.Errorf("%w: subject alternative names cannot be set as an extension", verror.UserDataError)
+		}
+		for _, other := range extensions {
+			if ext.Id.Equal(other.Id) {
+				return nil, fmt.Errorf("%w: extension %s is set more than once", verror.UserDataError, ext.Id)
+			}
+		}
+		// The value must be a single DER encoded value
+		var raw asn1.RawValue
+		rest, err := asn1.Unmarshal(ext.Value, &raw)
+		if err != nil || len(rest) > 0 {
+			return nil, fmt.Errorf("%w: value of extension %s is not DER encoded", verror.UserDataError, ext.Id)
+		}
+		extensions = append(extensions, ext)
+	}
+	return extensions, nil
+}
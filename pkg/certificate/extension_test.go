@@ -0,0 +1,144 @@
+/*
+ * Copyright 2023 Venafi, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *  http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package certificate
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/asn1"
+	"encoding/pem"
+	"errors"
+	"testing"
+
+	"github.com/Venafi/vcert/v5/pkg/verror"
+)
+
+var oidMicrosoftCertificateTemplate = asn1.ObjectIdentifier{1, 3, 6, 1, 4, 1, 311, 21, 7}
+
+func TestParseExtKeyUsage(t *testing.T) {
+	cases := map[string]asn1.ObjectIdentifier{
+		"clientAuth":     {1, 3, 6, 1, 5, 5, 7, 3, 2},
+		"SmartCardLogon": {1, 3, 6, 1, 4, 1, 311, 20, 2, 2},
+		"eapOverLAN":     {1, 3, 6, 1, 5, 5, 7, 3, 14},
+		"1.2.3.4":        {1, 2, 3, 4},
+	}
+	for s, expected := range cases {
+		oid, err := ParseExtKeyUsage(s)
+		if err != nil {
+			t.Fatalf("could not parse %s: %s", s, err)
+		}
+		if !oid.Equal(expected) {
+			t.Fatalf("expected %s for %s, got %s", expected, s, oid)
+		}
+	}
+
+	for _, s := range []string{"", "smartcard", "1", "1.50.3", "3.1", "1.2.-3", "1..2"} {
+		_, err := ParseExtKeyUsage(s)
+		if !errors.Is(err, verror.UserDataError) {
+			t.Fatalf("expected a user data error for %q, got %v", s, err)
+		}
+	}
+}
+
+func TestParseExtension(t *testing.T) {
+	// The certificate template extension of Microsoft CAs, for template 1.2.3.4 version 100
+	ext, err := ParseExtension("1.3.6.1.4.1.311.21.7", true, "MAgGAyoDBAIBZA==")
+	if err != nil {
+		t.Fatalf("could not parse extension: %s", err)
+	}
+	if !ext.Id.Equal(oidMicrosoftCertificateTemplate) || !ext.Critical || len(ext.Value) != 10 {
+		t.Fatalf("unexpected extension %v", ext)
+	}
+
+	_, err = ParseExtension("1.3.6.1.4.1.311.21.7", false, "not base64!")
+	if !errors.Is(err, verror.UserDataError) {
+		t.Fatalf("expected a user data error, got %v", err)
+	}
+}
+
+func TestGenerateCertificateRequestWithExtensions(t *testing.T) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	smartcardLogon, _ := ParseExtKeyUsage("smartcardLogon")
+	req := Request{
+		Subject:      pkix.Name{CommonName: "smartcard.venafi.example"},
+		UPNs:         []string{"user@venafi.example"},
+		PrivateKey:   key,
+		ExtKeyUsages: []asn1.ObjectIdentifier{{1, 3, 6, 1, 5, 5, 7, 3, 2}, smartcardLogon},
+		Extensions:   []pkix.Extension{{Id: oidMicrosoftCertificateTemplate, Value: []byte{0x05, 0x00}}},
+	}
+	err = req.GenerateCSR()
+	if err != nil {
+		t.Fatalf("could not generate CSR: %s", err)
+	}
+
+	pemBlock, _ := pem.Decode(req.GetCSR())
+	csr, err := x509.ParseCertificateRequest(pemBlock.Bytes)
+	if err != nil {
+		t.Fatalf("could not parse CSR: %s", err)
+	}
+
+	found := map[string]pkix.Extension{}
+	for _, ext := range csr.Extensions {
+		found[ext.Id.String()] = ext
+	}
+	if _, ok := found[oidExtensionSubjectAltName.String()]; !ok {
+		t.Fatal("subject alternative names missing from CSR")
+	}
+	if ext, ok := found[oidMicrosoftCertificateTemplate.String()]; !ok || string(ext.Value) != "\x05\x00" {
+		t.Fatal("custom extension missing from CSR")
+	}
+	ext, ok := found[oidExtensionExtendedKeyUsage.String()]
+	if !ok {
+		t.Fatal("extended key usage missing from CSR")
+	}
+	var usages []asn1.ObjectIdentifier
+	if _, err = asn1.Unmarshal(ext.Value, &usages); err != nil {
+		t.Fatal(err)
+	}
+	if len(usages) != 2 || !usages[1].Equal(smartcardLogon) {
+		t.Fatalf("unexpected extended key usages %v", usages)
+	}
+}
+
+func TestGenerateCertificateRequestWithInvalidExtensions(t *testing.T) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cases := map[string]Request{
+		"not DER":   {Extensions: []pkix.Extension{{Id: oidMicrosoftCertificateTemplate, Value: []byte("text")}}},
+		"SAN":       {Extensions: []pkix.Extension{{Id: oidExtensionSubjectAltName, Value: []byte{0x30, 0x00}}}},
+		"duplicate": {Extensions: []pkix.Extension{{Id: oidMicrosoftCertificateTemplate, Value: []byte{0x05, 0x00}}, {Id: oidMicrosoftCertificateTemplate, Value: []byte{0x05, 0x00}}}},
+		"EKU twice": {
+			ExtKeyUsages: []asn1.ObjectIdentifier{{1, 3, 6, 1, 5, 5, 7, 3, 2}},
+			Extensions:   []pkix.Extension{{Id: oidExtensionExtendedKeyUsage, Value: []byte{0x30, 0x00}}},
+		},
+	}
+	for name, req := range cases {
+		req.PrivateKey = key
+		err = req.GenerateCSR()
+		if !errors.Is(err, verror.UserDataError) {
+			t.Errorf("%s: expected a user data error, got %v", name, err)
+		}
+	}
+}
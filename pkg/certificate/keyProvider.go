@@ -0,0 +1,68 @@
+/*
+ * Copyright 2023 Venafi, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *  http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package certificate
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/rsa"
+	"fmt"
+
+	"github.com/Venafi/vcert/v5/pkg/verror"
+)
+
+// KeyProvider generates the private key used to sign a certificate request, based on the
+// KeyType, KeyLength and KeyCurve of the Request. The returned crypto.Signer may be backed by a
+// hardware device like an HSM, in which case the private key never leaves the device and
+// IsKeyExportable reports false for it
+type KeyProvider interface {
+	GenerateKey(request *Request) (crypto.Signer, error)
+}
+
+// SoftwareKeyProvider generates private keys in memory. It is used when the Request has no KeyProvider
+type SoftwareKeyProvider struct{}
+
+// GenerateKey creates a new in-memory private key of the type requested
+func (SoftwareKeyProvider) GenerateKey(request *Request) (crypto.Signer, error) {
+	switch request.KeyType {
+	case KeyTypeECDSA:
+		return GenerateECDSAPrivateKey(request.KeyCurve)
+	case KeyTypeED25519:
+		return GenerateED25519PrivateKey()
+	case KeyTypeRSA:
+		return GenerateRSAPrivateKey(request.KeyLength)
+	case KeyTypeMLDSA44, KeyTypeMLDSA65, KeyTypeMLDSA87:
+		if err := checkPostQuantumEnabled(request.KeyType); err != nil {
+			return nil, err
+		}
+		return GenerateMLDSAPrivateKey(request.KeyType)
+	default:
+		return nil, fmt.Errorf("%w: unable to generate certificate request, key type %s is not supported", verror.VcertError, request.KeyType.String())
+	}
+}
+
+// IsKeyExportable returns true when the private key material is held in memory and can be encoded,
+// as opposed to keys that live in a hardware device
+func IsKeyExportable(key crypto.Signer) bool {
+	switch key.(type) {
+	case *rsa.PrivateKey, *ecdsa.PrivateKey, ed25519.PrivateKey:
+		return true
+	default:
+		return isMLDSAPrivateKey(key)
+	}
+}
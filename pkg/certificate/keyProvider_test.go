@@ -0,0 +1,113 @@
+/*
+ * Copyright 2023 Venafi, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *  http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package certificate
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"io"
+	"testing"
+)
+
+// deviceKey emulates a private key held by an HSM: it signs but cannot be exported
+type deviceKey struct {
+	key *ecdsa.PrivateKey
+}
+
+func (k deviceKey) Public() crypto.PublicKey {
+	return k.key.Public()
+}
+
+func (k deviceKey) Sign(rand io.Reader, digest []byte, opts crypto.SignerOpts) ([]byte, error) {
+	return k.key.Sign(rand, digest, opts)
+}
+
+type deviceKeyProvider struct {
+	calls int
+}
+
+func (p *deviceKeyProvider) GenerateKey(_ *Request) (crypto.Signer, error) {
+	p.calls++
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		return nil, err
+	}
+	return deviceKey{key: key}, nil
+}
+
+func TestGeneratePrivateKeyWithKeyProvider(t *testing.T) {
+	provider := &deviceKeyProvider{}
+	req := Request{
+		Subject:     pkix.Name{CommonName: "hsm.venafi.example"},
+		KeyType:     KeyTypeECDSA,
+		KeyProvider: provider,
+	}
+
+	err := req.GeneratePrivateKey()
+	if err != nil {
+		t.Fatalf("failed to generate private key: %s", err)
+	}
+	if provider.calls != 1 {
+		t.Fatalf("expected the key provider to be called once, got %d", provider.calls)
+	}
+	if IsKeyExportable(req.PrivateKey) {
+		t.Fatalf("expected a non exportable private key")
+	}
+
+	err = req.GenerateCSR()
+	if err != nil {
+		t.Fatalf("failed to generate CSR: %s", err)
+	}
+	block, _ := pem.Decode(req.GetCSR())
+	csr, err := x509.ParseCertificateRequest(block.Bytes)
+	if err != nil {
+		t.Fatalf("failed to parse CSR: %s", err)
+	}
+	if err = csr.CheckSignature(); err != nil {
+		t.Fatalf("CSR signature does not verify: %s", err)
+	}
+
+	col, err := NewPEMCollection(nil, req.PrivateKey, []byte("password"))
+	if err != nil {
+		t.Fatalf("failed to create PEM collection: %s", err)
+	}
+	err = col.AddPrivateKey(req.PrivateKey, nil)
+	if err != nil {
+		t.Fatalf("failed to add private key: %s", err)
+	}
+	if col.PrivateKey != "" {
+		t.Fatalf("a non exportable private key should not be added to the PEM collection")
+	}
+}
+
+func TestIsKeyExportable(t *testing.T) {
+	for _, keyType := range []KeyType{KeyTypeRSA, KeyTypeECDSA, KeyTypeED25519} {
+		req := Request{KeyType: keyType, KeyLength: 2048}
+		err := req.GeneratePrivateKey()
+		if err != nil {
+			t.Fatalf("failed to generate %s private key: %s", keyType.String(), err)
+		}
+		if !IsKeyExportable(req.PrivateKey) {
+			t.Fatalf("expected %s private key to be exportable", keyType.String())
+		}
+	}
+}
@@ -20,11 +20,20 @@ const (
 	KeyTypeECDSA
 	// KeyTypeED25519 represents a key type of ED25519
 	KeyTypeED25519
+	// KeyTypeMLDSA44 represents a post-quantum ML-DSA-44 key. It is experimental, see PostQuantumEnabled
+	KeyTypeMLDSA44
+	// KeyTypeMLDSA65 represents a post-quantum ML-DSA-65 key. It is experimental, see PostQuantumEnabled
+	KeyTypeMLDSA65
+	// KeyTypeMLDSA87 represents a post-quantum ML-DSA-87 key. It is experimental, see PostQuantumEnabled
+	KeyTypeMLDSA87
 
 	// String representations of the KeyType types
 	strKeyTypeECDSA   = "ECDSA"
 	strKeyTypeRSA     = "RSA"
 	strKeyTypeED25519 = "ED25519"
+	strKeyTypeMLDSA44 = "ML-DSA-44"
+	strKeyTypeMLDSA65 = "ML-DSA-65"
+	strKeyTypeMLDSA87 = "ML-DSA-87"
 )
 
 // String returns a string representation of this object
@@ -36,6 +45,12 @@ func (kt *KeyType) String() string {
 		return strKeyTypeECDSA
 	case KeyTypeED25519:
 		return strKeyTypeED25519
+	case KeyTypeMLDSA44:
+		return strKeyTypeMLDSA44
+	case KeyTypeMLDSA65:
+		return strKeyTypeMLDSA65
+	case KeyTypeMLDSA87:
+		return strKeyTypeMLDSA87
 	default:
 		return ""
 	}
@@ -49,10 +64,21 @@ func (kt *KeyType) X509Type() x509.PublicKeyAlgorithm {
 		return x509.ECDSA
 	case KeyTypeED25519:
 		return x509.Ed25519
+	case KeyTypeMLDSA44, KeyTypeMLDSA65, KeyTypeMLDSA87:
+		return x509MLDSA
 	}
 	return x509.UnknownPublicKeyAlgorithm
 }
 
+// IsPostQuantum returns true for the experimental post-quantum key types
+func (kt *KeyType) IsPostQuantum() bool {
+	switch *kt {
+	case KeyTypeMLDSA44, KeyTypeMLDSA65, KeyTypeMLDSA87:
+		return true
+	}
+	return false
+}
+
 // Set the key type via a string
 func (kt *KeyType) Set(value, curveValue string) error {
 	switch strings.ToUpper(value) {
@@ -72,6 +98,13 @@ func (kt *KeyType) Set(value, curveValue string) error {
 		*kt = KeyTypeECDSA
 		return nil
 	}
+	if pq, ok := parsePostQuantumKeyType(value); ok {
+		if err := checkPostQuantumEnabled(pq); err != nil {
+			return err
+		}
+		*kt = pq
+		return nil
+	}
 	return fmt.Errorf("%w: unknown key type: %s", verror.VcertError, value) //todo: check all calls
 }
 
@@ -83,9 +116,28 @@ func parseKeyType(value string) (KeyType, error) {
 		return KeyTypeRSA, nil
 	case strKeyTypeED25519:
 		return KeyTypeED25519, nil
-	default:
-		return -1, fmt.Errorf("%w: unknown key type: %s", verror.VcertError, value)
 	}
+	if pq, ok := parsePostQuantumKeyType(value); ok {
+		if err := checkPostQuantumEnabled(pq); err != nil {
+			return -1, err
+		}
+		return pq, nil
+	}
+	return -1, fmt.Errorf("%w: unknown key type: %s", verror.VcertError, value)
+}
+
+// parsePostQuantumKeyType parses the ML-DSA key types, with or without their dashes, i.e. ML-DSA-65 or MLDSA65
+func parsePostQuantumKeyType(value string) (KeyType, bool) {
+	normalized := strings.NewReplacer("-", "", "_", "").Replace(strings.ToUpper(value))
+	switch normalized {
+	case "MLDSA44":
+		return KeyTypeMLDSA44, true
+	case "MLDSA65":
+		return KeyTypeMLDSA65, true
+	case "MLDSA87":
+		return KeyTypeMLDSA87, true
+	}
+	return -1, false
 }
 
 // MarshalYAML customizes the behavior of ChainOption when being marshaled into a YAML document.
@@ -2,6 +2,7 @@ package certificate
 
 import (
 	"fmt"
+	"strings"
 	"testing"
 
 	"github.com/stretchr/testify/suite"
@@ -71,3 +72,33 @@ func (s *KeyTypeSuite) TestEllipticCurve_UnmarshalYAML() {
 		})
 	}
 }
+
+func TestKeyType_SetPostQuantum(t *testing.T) {
+	var kt KeyType
+	t.Setenv(ExperimentalPostQuantumEnv, "")
+	err := kt.Set("ML-DSA-65", "")
+	if err == nil || !strings.Contains(err.Error(), ExperimentalPostQuantumEnv) {
+		t.Fatalf("expected an error pointing to %s, got %v", ExperimentalPostQuantumEnv, err)
+	}
+	_, err = parseKeyType("ml-dsa-65")
+	if err == nil {
+		t.Fatal("expected an error parsing an ML-DSA key type while the experimental flag is disabled")
+	}
+
+	t.Setenv(ExperimentalPostQuantumEnv, "true")
+	for value, expected := range map[string]KeyType{"ml-dsa-44": KeyTypeMLDSA44, "MLDSA65": KeyTypeMLDSA65, "ML_DSA_87": KeyTypeMLDSA87} {
+		if err := kt.Set(value, ""); err != nil {
+			t.Fatalf("could not set key type %s: %s", value, err)
+		}
+		if kt != expected || !kt.IsPostQuantum() {
+			t.Fatalf("unexpected key type %s for %s", kt.String(), value)
+		}
+	}
+	parsed, err := parseKeyType(strKeyTypeMLDSA87)
+	if err != nil || parsed != KeyTypeMLDSA87 {
+		t.Fatalf("unexpected key type %s parsing %s: %v", parsed.String(), strKeyTypeMLDSA87, err)
+	}
+	if rsa := KeyTypeRSA; rsa.IsPostQuantum() {
+		t.Fatal("RSA is not a post-quantum key type")
+	}
+}
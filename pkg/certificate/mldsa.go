@@ -0,0 +1,68 @@
+//go:build go1.27
+
+/*
+ * Copyright 2023 Venafi, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *  http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package certificate
+
+import (
+	"crypto"
+	"crypto/mldsa"
+	"crypto/x509"
+	"fmt"
+
+	"github.com/Venafi/vcert/v5/pkg/verror"
+)
+
+const x509MLDSA = x509.MLDSA
+
+// GenerateMLDSAPrivateKey generates a new post-quantum ML-DSA private key of the key type specified
+func GenerateMLDSAPrivateKey(keyType KeyType) (crypto.Signer, error) {
+	var params mldsa.Parameters
+	switch keyType {
+	case KeyTypeMLDSA44:
+		params = mldsa.MLDSA44()
+	case KeyTypeMLDSA65:
+		params = mldsa.MLDSA65()
+	case KeyTypeMLDSA87:
+		params = mldsa.MLDSA87()
+	default:
+		return nil, fmt.Errorf("%w: %s is not an ML-DSA key type", verror.VcertError, keyType.String())
+	}
+	return mldsa.GenerateKey(params)
+}
+
+// mldsaKeyType returns the key type of an ML-DSA public key
+func mldsaKeyType(pub crypto.PublicKey) (KeyType, bool) {
+	key, ok := pub.(*mldsa.PublicKey)
+	if !ok {
+		return -1, false
+	}
+	switch key.Parameters() {
+	case mldsa.MLDSA44():
+		return KeyTypeMLDSA44, true
+	case mldsa.MLDSA65():
+		return KeyTypeMLDSA65, true
+	case mldsa.MLDSA87():
+		return KeyTypeMLDSA87, true
+	}
+	return -1, false
+}
+
+func isMLDSAPrivateKey(key crypto.Signer) bool {
+	_, ok := key.(*mldsa.PrivateKey)
+	return ok
+}
@@ -0,0 +1,125 @@
+//go:build go1.27
+
+/*
+ * Copyright 2023 Venafi, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *  http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package certificate
+
+import (
+	"crypto"
+	"crypto/rand"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"math/big"
+	"testing"
+	"time"
+)
+
+func TestGenerateCertificateRequestWithMLDSAKey(t *testing.T) {
+	t.Setenv(ExperimentalPostQuantumEnv, "true")
+	req := getCertificateRequestForTest()
+	req.KeyType = KeyTypeMLDSA65
+	err := req.GeneratePrivateKey()
+	if err != nil {
+		t.Fatalf("Error generating ML-DSA Private Key\nError: %s", err)
+	}
+	if !IsKeyExportable(req.PrivateKey) {
+		t.Fatal("expected ML-DSA keys generated in memory to be exportable")
+	}
+
+	err = req.GenerateCSR()
+	if err != nil {
+		t.Fatalf("Error generating Certificate Request\nError: %s", err)
+	}
+	pemBlock, _ := pem.Decode(req.GetCSR())
+	if pemBlock == nil {
+		t.Fatalf("Failed to decode CSR as PEM")
+	}
+	parsedReq, err := x509.ParseCertificateRequest(pemBlock.Bytes)
+	if err != nil {
+		t.Fatalf("Error parsing generated Certificate Request\nError: %s", err)
+	}
+	if parsedReq.PublicKeyAlgorithm != x509.MLDSA {
+		t.Fatalf("unexpected public key algorithm %s", parsedReq.PublicKeyAlgorithm)
+	}
+	err = parsedReq.CheckSignature()
+	if err != nil {
+		t.Fatalf("Error checking signature of generated Certificate Request\nError: %s", err)
+	}
+
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(1),
+		Subject:      pkix.Name{CommonName: "vcert.test.vfidev.com"},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, req.PrivateKey.Public(), req.PrivateKey)
+	if err != nil {
+		t.Fatalf("could not create certificate: %s", err)
+	}
+	err = req.CheckCertificate(string(pem.EncodeToMemory(GetCertificatePEMBlock(der))))
+	if err != nil {
+		t.Fatalf("certificate should match the ML-DSA request: %s", err)
+	}
+
+	other, err := GenerateMLDSAPrivateKey(KeyTypeMLDSA65)
+	if err != nil {
+		t.Fatalf("could not generate ML-DSA key: %s", err)
+	}
+	mismatched := Request{KeyType: KeyTypeMLDSA65, PrivateKey: other}
+	if err = mismatched.CheckCertificate(string(pem.EncodeToMemory(GetCertificatePEMBlock(der)))); err == nil {
+		t.Fatal("expected an error checking a certificate issued for another ML-DSA key")
+	}
+
+	parsedCert, err := x509.ParseCertificate(der)
+	if err != nil {
+		t.Fatalf("could not parse certificate: %s", err)
+	}
+	if kt := NewRequest(parsedCert).KeyType; kt != KeyTypeMLDSA65 {
+		t.Fatalf("unexpected key type %s for an ML-DSA-65 certificate", kt.String())
+	}
+}
+
+func TestGetPrivateKeyPEMBockWithMLDSAKey(t *testing.T) {
+	key, err := GenerateMLDSAPrivateKey(KeyTypeMLDSA44)
+	if err != nil {
+		t.Fatalf("could not generate ML-DSA key: %s", err)
+	}
+
+	block, err := GetPrivateKeyPEMBock(key)
+	if err != nil {
+		t.Fatalf("could not encode ML-DSA key: %s", err)
+	}
+	parsed, err := x509.ParsePKCS8PrivateKey(block.Bytes)
+	if err != nil {
+		t.Fatalf("could not parse ML-DSA key: %s", err)
+	}
+	var request Request
+	request.SetPrivateKey(parsed.(crypto.Signer))
+	if request.KeyType != KeyTypeMLDSA44 {
+		t.Fatalf("unexpected key type %s for an ML-DSA-44 key", request.KeyType.String())
+	}
+
+	block, err = GetEncryptedPrivateKeyPEMBock(key, []byte("secret!"))
+	if err != nil || block.Type != "ENCRYPTED PRIVATE KEY" {
+		t.Fatalf("could not encrypt ML-DSA key: %v", err)
+	}
+	_, err = GetPrivateKeyPEMBock(key, "legacy-pem")
+	if err == nil {
+		t.Fatal("expected an error encoding an ML-DSA key in the legacy format")
+	}
+}
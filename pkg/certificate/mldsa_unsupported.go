@@ -0,0 +1,43 @@
+//go:build !go1.27
+
+/*
+ * Copyright 2023 Venafi, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *  http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package certificate
+
+import (
+	"crypto"
+	"crypto/x509"
+	"fmt"
+
+	"github.com/Venafi/vcert/v5/pkg/verror"
+)
+
+// crypto/x509 only supports ML-DSA keys since Go 1.27
+const x509MLDSA = x509.UnknownPublicKeyAlgorithm
+
+// GenerateMLDSAPrivateKey is not supported by this build, ML-DSA keys require a vcert built with Go 1.27 or later
+func GenerateMLDSAPrivateKey(keyType KeyType) (crypto.Signer, error) {
+	return nil, fmt.Errorf("%w: %s keys require a vcert built with Go 1.27 or later", verror.VcertError, keyType.String())
+}
+
+func mldsaKeyType(_ crypto.PublicKey) (KeyType, bool) {
+	return -1, false
+}
+
+func isMLDSAPrivateKey(_ crypto.Signer) bool {
+	return false
+}
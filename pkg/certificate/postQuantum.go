@@ -0,0 +1,53 @@
+/*
+ * Copyright 2023 Venafi, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *  http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package certificate
+
+import (
+	"crypto/x509"
+	"fmt"
+	"os"
+	"strconv"
+
+	"github.com/Venafi/vcert/v5/pkg/verror"
+)
+
+// ExperimentalPostQuantumEnv is the environment variable enabling the experimental post-quantum key types. ML-DSA
+// keys are only accepted when it is set to true, as few CAs issue certificates for them yet.
+//
+// ML-KEM keys cannot sign a CSR, so they cannot be requested. Hybrid CSRs, holding both a classical and a
+// post-quantum key, are not generated by vcert either, but a hybrid CSR provided by the user is sent as is to the CA
+const ExperimentalPostQuantumEnv = "VCERT_EXPERIMENTAL_PQ"
+
+// PostQuantumEnabled returns true when the experimental post-quantum key types are enabled through the
+// VCERT_EXPERIMENTAL_PQ environment variable
+func PostQuantumEnabled() bool {
+	enabled, err := strconv.ParseBool(os.Getenv(ExperimentalPostQuantumEnv))
+	return err == nil && enabled
+}
+
+func checkPostQuantumEnabled(kt KeyType) error {
+	if !PostQuantumEnabled() {
+		return fmt.Errorf("%w: %s keys are experimental. Set %s=true to enable them", verror.VcertError, kt.String(), ExperimentalPostQuantumEnv)
+	}
+	return nil
+}
+
+// IsPostQuantumAlgorithm returns true when the experimental post-quantum key types are enabled and algo is the
+// algorithm of an ML-DSA key, or one crypto/x509 does not know, like the composite keys of hybrid CSRs
+func IsPostQuantumAlgorithm(algo x509.PublicKeyAlgorithm) bool {
+	return PostQuantumEnabled() && (algo == x509MLDSA || algo == x509.UnknownPublicKeyAlgorithm)
+}
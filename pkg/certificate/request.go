@@ -8,6 +8,7 @@ import (
 	"crypto/rsa"
 	"crypto/x509"
 	"crypto/x509/pkix"
+	"encoding/asn1"
 	"encoding/pem"
 	"fmt"
 	"net"
@@ -30,6 +31,7 @@ type Request struct {
 	IPAddresses        []net.IP
 	URIs               []*url.URL
 	UPNs               []string
+	RegisteredIDs      []asn1.ObjectIdentifier
 	Attributes         []pkix.AttributeTypeAndValueSET
 	SignatureAlgorithm x509.SignatureAlgorithm
 	FriendlyName       string
@@ -38,22 +40,40 @@ type Request struct {
 	KeyCurve           EllipticCurve
 	csr                []byte // should be a PEM-encoded CSR
 	PrivateKey         crypto.Signer
-	CsrOrigin          CSrOriginOption
-	PickupID           string
+	// KeyProvider generates PrivateKey when it is not set. Private keys are generated in memory when it is nil
+	KeyProvider KeyProvider
+	CsrOrigin   CSrOriginOption
+	PickupID    string
 	//Cloud Certificate ID
 	CertID          string
 	ChainOption     ChainOption
 	KeyPassword     string
 	FetchPrivateKey bool
+	// RetrievePKCS12 downloads the certificate and its private key as a PKCS#12 keystore encrypted with KeyPassword,
+	// instead of PEM. Only used by TPP when the private key is retrieved
+	RetrievePKCS12 bool
 	/*	Thumbprint is here because *Request is used in RetrieveCertificate().
 		Code should be refactored so that RetrieveCertificate() uses some abstract search object, instead of *Request{PickupID} */
-	Thumbprint       string
-	Timeout          time.Duration
-	CustomFields     []CustomField
-	Location         *Location
+	Thumbprint   string
+	SerialNumber string // hexadecimal, finds the certificate to retrieve like Thumbprint
+	Timeout      time.Duration
+	CustomFields []CustomField
+	Location     *Location
+	// ApplicationDNs are the DNs of existing Application objects the certificate is associated with, so that TPP
+	// provisions it to them once issued. Only used by TPP
+	ApplicationDNs   []string
 	ValidityDuration *time.Duration
 	ValidityPeriod   string //represents the validity of the certificate expressed as an ISO 8601 duration
-	IssuerHint       util.IssuerHint
+	// NotAfter is the date the certificate is requested to expire. It takes precedence over ValidityDuration
+	NotAfter   *time.Time
+	IssuerHint util.IssuerHint
+
+	// Extensions are added as is to the CSR, along with the subject alternative names. Their Value is the DER
+	// encoding of the extension value. They are not supported when the CSR is generated by the service
+	Extensions []pkix.Extension
+	// ExtKeyUsages are the OIDs of the extended key usages requested in the CSR. They are not supported when the
+	// CSR is generated by the service
+	ExtKeyUsages []asn1.ObjectIdentifier
 
 	// Deprecated: use ValidityDuration instead, this field is ignored if ValidityDuration is set
 	ValidityHours int
@@ -90,12 +110,26 @@ func (request *Request) GetCSR() []byte {
 func (request *Request) GenerateCSR() error {
 	certificateRequest := x509.CertificateRequest{}
 	certificateRequest.Subject = request.Subject
+	extensions, err := request.csrExtensions()
+	if err != nil {
+		return err
+	}
+	certificateRequest.ExtraExtensions = extensions
 	if !request.OmitSANs {
-		addSubjectAltNames(&certificateRequest, request.DNSNames, request.EmailAddresses, request.IPAddresses, request.URIs, request.UPNs)
+		addSubjectAltNames(&certificateRequest, request.DNSNames, request.EmailAddresses, request.IPAddresses, request.URIs, request.UPNs, request.RegisteredIDs)
 	}
 	certificateRequest.Attributes = request.Attributes
 
-	csr, err := x509.CreateCertificateRequest(rand.Reader, &certificateRequest, request.PrivateKey)
+	var csr []byte
+	if key, curve, ok := isBrainpoolKey(request.PrivateKey); ok {
+		csr, err = createBrainpoolCertificateRequest(&certificateRequest, key, curve)
+		if err == nil {
+			// SetCSR does not parse PEM requests, which crypto/x509 would fail to do for brainpool keys
+			csr = pem.EncodeToMemory(GetCertificateRequestPEMBlock(csr))
+		}
+	} else {
+		csr, err = x509.CreateCertificateRequest(rand.Reader, &certificateRequest, request.PrivateKey)
+	}
 	if err != nil {
 		csr = nil
 	}
@@ -104,29 +138,74 @@ func (request *Request) GenerateCSR() error {
 	return err
 }
 
-// GeneratePrivateKey creates private key (if it doesn`t already exist) based on request.KeyType, request.KeyLength and request.KeyCurve fileds
+// GeneratePrivateKey creates private key (if it doesn`t already exist) based on request.KeyType, request.KeyLength and request.KeyCurve fileds.
+// The key is created by request.KeyProvider when set
 func (request *Request) GeneratePrivateKey() error {
 	if request.PrivateKey != nil {
 		return nil
 	}
-	var err error
-	switch request.KeyType {
-	case KeyTypeECDSA:
-		request.PrivateKey, err = GenerateECDSAPrivateKey(request.KeyCurve)
-	case KeyTypeED25519:
-		request.PrivateKey, err = GenerateED25519PrivateKey()
-	case KeyTypeRSA:
+	if request.KeyType == KeyTypeRSA {
 		if request.KeyLength == 0 {
 			request.KeyLength = DefaultRSAlength
 		}
 		if request.KeyLength < AllSupportedKeySizes()[0] {
 			return fmt.Errorf("key Size must be %d or greater. But it is %d", AllSupportedKeySizes()[0], request.KeyLength)
 		}
-		request.PrivateKey, err = GenerateRSAPrivateKey(request.KeyLength)
+	}
+
+	var provider KeyProvider = SoftwareKeyProvider{}
+	if request.KeyProvider != nil {
+		provider = request.KeyProvider
+	}
+	key, err := provider.GenerateKey(request)
+	if err != nil {
+		return err
+	}
+	request.PrivateKey = key
+	return nil
+}
+
+// SetPrivateKey sets an existing private key to sign the CSR with, instead of generating a new one, along with the
+// key type, size and curve matching it
+func (request *Request) SetPrivateKey(key crypto.Signer) {
+	request.PrivateKey = key
+	switch pub := key.Public().(type) {
+	case *rsa.PublicKey:
+		request.KeyType = KeyTypeRSA
+		request.KeyLength = pub.N.BitLen()
+		request.KeyCurve = EllipticCurveNotSet
+	case *ecdsa.PublicKey:
+		request.KeyType = KeyTypeECDSA
+		request.KeyLength = 0
+		_ = request.KeyCurve.Set(pub.Curve.Params().Name)
+	case ed25519.PublicKey:
+		request.KeyType = KeyTypeED25519
+		request.KeyLength = 0
+		request.KeyCurve = EllipticCurveED25519
 	default:
-		return fmt.Errorf("%w: unable to generate certificate request, key type %s is not supported", verror.VcertError, request.KeyType.String())
+		if kt, ok := mldsaKeyType(pub); ok {
+			request.KeyType = kt
+			request.KeyLength = 0
+			request.KeyCurve = EllipticCurveNotSet
+		}
 	}
-	return err
+}
+
+// GetValidityDuration returns the validity requested for the certificate, counted from now: the time left until
+// NotAfter, ValidityDuration or ValidityHours, in that order. Returns nil when no validity is requested
+func (request *Request) GetValidityDuration() *time.Duration {
+	if request.NotAfter != nil {
+		validity := time.Until(*request.NotAfter)
+		return &validity
+	}
+	if request.ValidityDuration != nil {
+		return request.ValidityDuration
+	}
+	if request.ValidityHours > 0 { //nolint:staticcheck
+		validity := time.Duration(request.ValidityHours) * time.Hour //nolint:staticcheck
+		return &validity
+	}
+	return nil
 }
 
 // CheckCertificate validate that certificate returned by server matches data in request object. It can be used for control server.
@@ -138,6 +217,17 @@ func (request *Request) CheckCertificate(certPEM string) error {
 	if pemBlock.Type != "CERTIFICATE" {
 		return fmt.Errorf("%w: invalid pem type %s (expect CERTIFICATE)", verror.CertificateCheckError, pemBlock.Type)
 	}
+	if key, curve, ok := isBrainpoolKey(request.PrivateKey); ok {
+		// crypto/x509 does not parse certificates with brainpool keys
+		matches, err := brainpoolKeyMatchesCertificate(pemBlock.Bytes, key, curve)
+		if err != nil {
+			return err
+		}
+		if !matches {
+			return fmt.Errorf("%w: unmatched %s public key", verror.CertificateCheckError, curve.params.Name)
+		}
+		return nil
+	}
 	cert, err := x509.ParseCertificate(pemBlock.Bytes)
 	if err != nil {
 		return err
@@ -175,6 +265,11 @@ func (request *Request) CheckCertificate(certPEM string) error {
 			if !certPubkey.Equal(reqPubkey) {
 				return fmt.Errorf("%w: unmatched elliptic ed25519 keys", verror.CertificateCheckError)
 			}
+		case x509MLDSA:
+			reqPubKey, ok := request.PrivateKey.Public().(interface{ Equal(crypto.PublicKey) bool })
+			if !ok || !reqPubKey.Equal(cert.PublicKey) {
+				return fmt.Errorf("%w: unmatched %s keys", verror.CertificateCheckError, request.KeyType.String())
+			}
 		default:
 			return fmt.Errorf("%w: unknown key algorythm %d", verror.CertificateCheckError, cert.PublicKeyAlgorithm)
 		}
@@ -0,0 +1,285 @@
+/*
+ * Copyright 2023 Venafi, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *  http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package certificate
+
+import (
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/Venafi/vcert/v5/pkg/policy"
+	"github.com/Venafi/vcert/v5/pkg/verror"
+)
+
+// policyValues are the values of a request checked against a policy, read from its CSR or from its fields
+type policyValues struct {
+	commonName  string
+	dnsNames    []string
+	emails      []string
+	ips         []string
+	uris        []string
+	upns        []string
+	orgs        []string
+	orgUnits    []string
+	localities  []string
+	states      []string
+	countries   []string
+	keyType     KeyType
+	keySize     int
+	keyCurve    EllipticCurve
+	keyNotFound bool
+}
+
+// ValidateAgainstPolicy checks the request against the policy of its zone before it is submitted, so a request the CA
+// would reject fails fast. The CSR is checked when it is set, i.e. generated locally or provided by the user, and the
+// fields it is generated from otherwise.
+//
+// Every violation is reported, wrapping verror.PolicyValidationError. Post-quantum keys are not checked, as policies
+// have no configuration for them
+func (request *Request) ValidateAgainstPolicy(ps *policy.PolicySpecification) error {
+	if ps == nil || ps.Policy == nil {
+		return nil
+	}
+	values, err := request.policyValues()
+	if err != nil {
+		return err
+	}
+
+	var violations []error
+	violation := func(format string, args ...interface{}) {
+		violations = append(violations, fmt.Errorf("%w: %s", verror.PolicyValidationError, fmt.Sprintf(format, args...)))
+	}
+	p := ps.Policy
+
+	// Domains and wildcards
+	names := values.dnsNames
+	if values.commonName != "" {
+		names = append([]string{values.commonName}, names...)
+	}
+	for _, name := range names {
+		if len(p.Domains) > 0 && !inPolicyDomains(name, p.Domains) {
+			violation("%s is not in the domains %v allowed by the policy", name, p.Domains)
+		}
+		if strings.HasPrefix(name, "*.") && p.WildcardAllowed != nil && !*p.WildcardAllowed {
+			violation("wildcard %s is not allowed by the policy", name)
+		}
+	}
+
+	// Subject
+	if p.Subject != nil {
+		checkSubject := func(field string, requested, allowed []string) {
+			for _, value := range requested {
+				if !inPolicyValues(value, allowed) {
+					violation("%s %q is not in the values %v allowed by the policy", field, value, allowed)
+				}
+			}
+		}
+		checkSubject("organization", values.orgs, p.Subject.Orgs)
+		checkSubject("organizational unit", values.orgUnits, p.Subject.OrgUnits)
+		checkSubject("locality", values.localities, p.Subject.Localities)
+		checkSubject("state", values.states, p.Subject.States)
+		checkSubject("country", values.countries, p.Subject.Countries)
+	}
+
+	// Subject alternative names
+	if sans := p.SubjectAltNames; sans != nil {
+		checkSANs := func(sanType string, allowed *bool, requested []string) {
+			if allowed != nil && !*allowed && len(requested) > 0 {
+				violation("%s SANs %v are not allowed by the policy", sanType, requested)
+			}
+		}
+		checkSANs("DNS", sans.DnsAllowed, values.dnsNames)
+		checkSANs("IP", sans.IpAllowed, values.ips)
+		checkSANs("email", sans.EmailAllowed, values.emails)
+		checkSANs("URI", sans.UriAllowed, values.uris)
+		checkSANs("UPN", sans.UpnAllowed, values.upns)
+	}
+
+	// Key
+	if kp := p.KeyPair; kp != nil {
+		serviceGenerated := kp.ServiceGenerated != nil && *kp.ServiceGenerated
+		if serviceGenerated && request.CsrOrigin != ServiceGeneratedCSR {
+			violation("the policy requires the key and the CSR to be generated by the service")
+		}
+		if !values.keyNotFound && !values.keyType.IsPostQuantum() {
+			if len(kp.KeyTypes) > 0 && !keyTypeInPolicy(values.keyType, kp.KeyTypes) {
+				violation("key type %s is not in the key types %v allowed by the policy", values.keyType.String(), kp.KeyTypes)
+			}
+			if values.keyType == KeyTypeRSA && len(kp.RsaKeySizes) > 0 && !intInPolicy(values.keySize, kp.RsaKeySizes) {
+				violation("RSA key size %d is not in the sizes %v allowed by the policy", values.keySize, kp.RsaKeySizes)
+			}
+			if values.keyType != KeyTypeRSA && len(kp.EllipticCurves) > 0 && !inPolicyValues(values.keyCurve.String(), kp.EllipticCurves) {
+				violation("elliptic curve %s is not in the curves %v allowed by the policy", values.keyCurve.String(), kp.EllipticCurves)
+			}
+		}
+	}
+
+	// Validity
+	if p.MaxValidDays != nil && *p.MaxValidDays > 0 {
+		maxValidity := time.Duration(*p.MaxValidDays) * 24 * time.Hour
+		if validity := request.GetValidityDuration(); validity != nil && *validity > maxValidity {
+			violation("the requested validity of %s exceeds the maximum of %d days allowed by the policy",
+				validity.Round(time.Second), *p.MaxValidDays)
+		}
+	}
+
+	return errors.Join(violations...)
+}
+
+// policyValues reads the values checked against a policy from the CSR of the request, or from its fields when the CSR
+// is not set or cannot be parsed by crypto/x509, as with brainpool keys
+func (request *Request) policyValues() (*policyValues, error) {
+	_, _, brainpool := isBrainpoolKey(request.PrivateKey)
+	if len(request.csr) == 0 || brainpool {
+		values := &policyValues{
+			commonName: request.Subject.CommonName,
+			dnsNames:   request.DNSNames,
+			emails:     request.EmailAddresses,
+			upns:       request.UPNs,
+			orgs:       request.Subject.Organization,
+			orgUnits:   request.Subject.OrganizationalUnit,
+			localities: request.Subject.Locality,
+			states:     request.Subject.Province,
+			countries:  request.Subject.Country,
+			keyType:    request.KeyType,
+			keySize:    request.KeyLength,
+			keyCurve:   request.KeyCurve,
+		}
+		for _, ip := range request.IPAddresses {
+			values.ips = append(values.ips, ip.String())
+		}
+		for _, uri := range request.URIs {
+			values.uris = append(values.uris, uri.String())
+		}
+		if values.keyType == KeyTypeRSA && values.keySize == 0 {
+			values.keySize = DefaultRSAlength
+		}
+		if values.keyType == KeyTypeED25519 {
+			values.keyCurve = EllipticCurveED25519
+		} else if values.keyType == KeyTypeECDSA && values.keyCurve == EllipticCurveNotSet {
+			values.keyCurve = EllipticCurveDefault
+		}
+		return values, nil
+	}
+
+	pemBlock, _ := pem.Decode(request.csr)
+	if pemBlock == nil {
+		return nil, fmt.Errorf("%w: CSR is not PEM encoded", verror.UserDataError)
+	}
+	csr, err := x509.ParseCertificateRequest(pemBlock.Bytes)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %w", verror.UserDataError, err)
+	}
+	upns, err := getUserPrincipalNameSANs(&x509.Certificate{Extensions: csr.Extensions})
+	if err != nil {
+		return nil, fmt.Errorf("%w: %w", verror.UserDataError, err)
+	}
+	values := &policyValues{
+		commonName: csr.Subject.CommonName,
+		dnsNames:   csr.DNSNames,
+		emails:     csr.EmailAddresses,
+		upns:       upns,
+		orgs:       csr.Subject.Organization,
+		orgUnits:   csr.Subject.OrganizationalUnit,
+		localities: csr.Subject.Locality,
+		states:     csr.Subject.Province,
+		countries:  csr.Subject.Country,
+	}
+	for _, ip := range csr.IPAddresses {
+		values.ips = append(values.ips, ip.String())
+	}
+	for _, uri := range csr.URIs {
+		values.uris = append(values.uris, uri.String())
+	}
+	switch key := csr.PublicKey.(type) {
+	case *rsa.PublicKey:
+		values.keyType = KeyTypeRSA
+		values.keySize = key.Size() * 8
+	case *ecdsa.PublicKey:
+		values.keyType = KeyTypeECDSA
+		values.keyCurve = parseEllipticCurve(key.Curve.Params().Name)
+	case ed25519.PublicKey:
+		values.keyType = KeyTypeED25519
+		values.keyCurve = EllipticCurveED25519
+	default:
+		// Post-quantum and unknown keys are left to the CA
+		values.keyNotFound = true
+	}
+	return values, nil
+}
+
+// inPolicyDomains returns true when name is one of the domains, or a subdomain of one of them
+func inPolicyDomains(name string, domains []string) bool {
+	name = strings.ToLower(strings.TrimPrefix(name, "*."))
+	for _, domain := range domains {
+		domain = strings.ToLower(strings.TrimPrefix(domain, "."))
+		if domain == policy.AllowAll || name == domain || strings.HasSuffix(name, "."+domain) {
+			return true
+		}
+	}
+	return false
+}
+
+// inPolicyValues returns true when allowed is empty, allows all values, or contains value
+func inPolicyValues(value string, allowed []string) bool {
+	if len(allowed) == 0 {
+		return true
+	}
+	for _, a := range allowed {
+		if a == policy.AllowAll || strings.EqualFold(a, value) {
+			return true
+		}
+	}
+	return false
+}
+
+func intInPolicy(value int, allowed []int) bool {
+	for _, a := range allowed {
+		if a == value {
+			return true
+		}
+	}
+	return false
+}
+
+// keyTypeInPolicy returns true when the key type is one of the allowed key types. ED25519 keys are also allowed by
+// the EC key type, as VaaS handles ED25519 as an elliptic curve
+func keyTypeInPolicy(kt KeyType, allowed []string) bool {
+	for _, a := range allowed {
+		switch strings.ToUpper(a) {
+		case strKeyTypeRSA:
+			if kt == KeyTypeRSA {
+				return true
+			}
+		case strKeyTypeECDSA, "EC", "ECC":
+			if kt == KeyTypeECDSA || kt == KeyTypeED25519 {
+				return true
+			}
+		case strKeyTypeED25519:
+			if kt == KeyTypeED25519 {
+				return true
+			}
+		}
+	}
+	return false
+}
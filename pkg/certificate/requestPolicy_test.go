@@ -0,0 +1,181 @@
+/*
+ * Copyright 2023 Venafi, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *  http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package certificate
+
+import (
+	"crypto/x509/pkix"
+	"errors"
+	"net"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/Venafi/vcert/v5/pkg/policy"
+	"github.com/Venafi/vcert/v5/pkg/verror"
+)
+
+func TestValidateAgainstPolicy(t *testing.T) {
+	falseValue := false
+	trueValue := true
+	maxValidDays := 90
+	ps := &policy.PolicySpecification{
+		Policy: &policy.Policy{
+			Domains:         []string{"venafi.example"},
+			WildcardAllowed: &falseValue,
+			MaxValidDays:    &maxValidDays,
+			Subject: &policy.Subject{
+				Orgs:      []string{"Venafi, Inc."},
+				Countries: []string{"US"},
+			},
+			KeyPair: &policy.KeyPair{
+				KeyTypes:    []string{"RSA"},
+				RsaKeySizes: []int{2048, 4096},
+			},
+			SubjectAltNames: &policy.SubjectAltNames{IpAllowed: &falseValue},
+		},
+	}
+
+	newRequest := func() *Request {
+		return &Request{
+			Subject: pkix.Name{
+				CommonName:   "www.venafi.example",
+				Organization: []string{"Venafi, Inc."},
+				Country:      []string{"US"},
+			},
+			DNSNames:  []string{"api.venafi.example"},
+			KeyType:   KeyTypeRSA,
+			KeyLength: 2048,
+		}
+	}
+
+	cases := map[string]struct {
+		request    func() *Request
+		violations []string
+	}{
+		"Compliant": {
+			request: newRequest,
+		},
+		"CompliantCSR": {
+			request: func() *Request {
+				request := newRequest()
+				generateCSR(t, request)
+				return request
+			},
+		},
+		"Domains": {
+			request: func() *Request {
+				request := newRequest()
+				request.DNSNames = append(request.DNSNames, "*.venafi.example", "www.example.com")
+				return request
+			},
+			violations: []string{"wildcard *.venafi.example is not allowed", "www.example.com is not in the domains"},
+		},
+		"SubjectFromCSR": {
+			request: func() *Request {
+				request := newRequest()
+				request.Subject.Country = []string{"CA"}
+				generateCSR(t, request)
+				// The CSR is checked instead of the fields once generated
+				request.Subject.Organization = []string{"Acme"}
+				return request
+			},
+			violations: []string{`country "CA" is not in the values [US]`},
+		},
+		"SANs": {
+			request: func() *Request {
+				request := newRequest()
+				request.IPAddresses = []net.IP{net.ParseIP("10.0.0.1")}
+				return request
+			},
+			violations: []string{"IP SANs [10.0.0.1] are not allowed"},
+		},
+		"Key": {
+			request: func() *Request {
+				request := newRequest()
+				request.KeyLength = 3072
+				generateCSR(t, request)
+				return request
+			},
+			violations: []string{"RSA key size 3072 is not in the sizes [2048 4096]"},
+		},
+		"KeyType": {
+			request: func() *Request {
+				request := newRequest()
+				request.KeyType = KeyTypeECDSA
+				request.KeyCurve = EllipticCurveP384
+				return request
+			},
+			violations: []string{"key type ECDSA is not in the key types [RSA]"},
+		},
+		"Validity": {
+			request: func() *Request {
+				request := newRequest()
+				validity := 365 * 24 * time.Hour
+				request.ValidityDuration = &validity
+				return request
+			},
+			violations: []string{"exceeds the maximum of 90 days"},
+		},
+	}
+
+	for name, c := range cases {
+		t.Run(name, func(t *testing.T) {
+			err := c.request().ValidateAgainstPolicy(ps)
+			if len(c.violations) == 0 {
+				if err != nil {
+					t.Fatalf("unexpected error: %s", err)
+				}
+				return
+			}
+			if !errors.Is(err, verror.PolicyValidationError) {
+				t.Fatalf("expected a policy validation error, got %v", err)
+			}
+			for _, violation := range c.violations {
+				if !strings.Contains(err.Error(), violation) {
+					t.Fatalf("expected violation %q in %s", violation, err)
+				}
+			}
+			if n := strings.Count(err.Error(), "policy doesn't match request"); n != len(c.violations) {
+				t.Fatalf("expected %d violations, got %d: %s", len(c.violations), n, err)
+			}
+		})
+	}
+
+	// The service generates the key when the policy requires it
+	ps.Policy.KeyPair.ServiceGenerated = &trueValue
+	err := newRequest().ValidateAgainstPolicy(ps)
+	if err == nil || !strings.Contains(err.Error(), "generated by the service") {
+		t.Fatalf("expected a violation of the service generated key, got %v", err)
+	}
+	request := newRequest()
+	request.CsrOrigin = ServiceGeneratedCSR
+	if err = request.ValidateAgainstPolicy(ps); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+}
+
+func generateCSR(t *testing.T, request *Request) {
+	t.Helper()
+	err := request.GeneratePrivateKey()
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = request.GenerateCSR()
+	if err != nil {
+		t.Fatal(err)
+	}
+}
@@ -0,0 +1,247 @@
+/*
+ * Copyright 2023 Venafi, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *  http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package certificate
+
+import (
+	"bytes"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/asn1"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"math/big"
+	"time"
+
+	"golang.org/x/crypto/cryptobyte"
+)
+
+// oidExtensionSCTList is the extension holding the SCTs embedded in a certificate (RFC 6962, section 3.3)
+var oidExtensionSCTList = asn1.ObjectIdentifier{1, 3, 6, 1, 4, 1, 11129, 2, 4, 2}
+
+const (
+	sctVersion1         = 0
+	sctCertificateStamp = 0
+	sctPrecertEntry     = 1
+	sctHashSHA256       = 4
+	sctSignatureRSA     = 1
+	sctSignatureECDSA   = 3
+)
+
+// SignedCertificateTimestamp is the promise of a Certificate Transparency log to include a certificate, embedded in the
+// certificate by the CA that submitted it to the log
+type SignedCertificateTimestamp struct {
+	// LogID is the base64 encoded SHA-256 hash of the log public key
+	LogID string
+	// LogDescription is the name of the log, set when the SCT is verified
+	LogDescription string `json:",omitempty"`
+	Timestamp      time.Time
+	// Verified is true when the SCT signature has been checked with the key of the log
+	Verified bool
+
+	logID      []byte
+	extensions []byte
+	hashAlg    uint8
+	sigAlg     uint8
+	signature  []byte
+}
+
+// CTLog is a Certificate Transparency log trusted to verify SCTs
+type CTLog struct {
+	ID          []byte
+	Description string
+	PublicKey   crypto.PublicKey
+}
+
+// ParseSCTs returns the SCTs embedded in cert. Returns an empty list when the certificate has none
+func ParseSCTs(cert *x509.Certificate) ([]SignedCertificateTimestamp, error) {
+	var value []byte
+	for _, ext := range cert.Extensions {
+		if ext.Id.Equal(oidExtensionSCTList) {
+			value = ext.Value
+			break
+		}
+	}
+	if value == nil {
+		return nil, nil
+	}
+
+	var list []byte
+	_, err := asn1.Unmarshal(value, &list)
+	if err != nil {
+		return nil, fmt.Errorf("invalid SCT list extension: %w", err)
+	}
+
+	var entries cryptobyte.String
+	s := cryptobyte.String(list)
+	if !s.ReadUint16LengthPrefixed(&entries) || !s.Empty() {
+		return nil, fmt.Errorf("invalid SCT list")
+	}
+
+	var scts []SignedCertificateTimestamp
+	for !entries.Empty() {
+		var entry cryptobyte.String
+		if !entries.ReadUint16LengthPrefixed(&entry) {
+			return nil, fmt.Errorf("invalid SCT list")
+		}
+		sct, err := parseSCT(entry)
+		if err != nil {
+			return nil, err
+		}
+		scts = append(scts, *sct)
+	}
+	return scts, nil
+}
+
+func parseSCT(s cryptobyte.String) (*SignedCertificateTimestamp, error) {
+	var version uint8
+	var timestamp uint64
+	var extensions, signature cryptobyte.String
+	sct := &SignedCertificateTimestamp{}
+	if !s.ReadUint8(&version) || version != sctVersion1 || !s.ReadBytes(&sct.logID, sha256.Size) ||
+		!s.ReadUint64(&timestamp) || !s.ReadUint16LengthPrefixed(&extensions) || !s.ReadUint8(&sct.hashAlg) ||
+		!s.ReadUint8(&sct.sigAlg) || !s.ReadUint16LengthPrefixed(&signature) || !s.Empty() {
+		return nil, fmt.Errorf("invalid or unsupported SCT")
+	}
+	sct.extensions = extensions
+	sct.signature = signature
+	sct.LogID = base64.StdEncoding.EncodeToString(sct.logID)
+	sct.Timestamp = time.UnixMilli(int64(timestamp)).UTC()
+	return sct, nil
+}
+
+// Verify checks the signature of the SCT embedded in cert with the key of the log that issued it, and sets the
+// description of the log. issuer is the certificate of the CA that signed cert
+func (sct *SignedCertificateTimestamp) Verify(cert *x509.Certificate, issuer *x509.Certificate, logs []CTLog) error {
+	var log *CTLog
+	for i := range logs {
+		if bytes.Equal(logs[i].ID, sct.logID) {
+			log = &logs[i]
+			break
+		}
+	}
+	if log == nil {
+		return fmt.Errorf("SCT issued by unknown log %s", sct.LogID)
+	}
+	sct.LogDescription = log.Description
+
+	if sct.hashAlg != sctHashSHA256 {
+		return fmt.Errorf("unsupported SCT hash algorithm %d", sct.hashAlg)
+	}
+	tbs, err := precertTBS(cert)
+	if err != nil {
+		return err
+	}
+
+	// digitally-signed struct of RFC 6962, section 3.2, for a precertificate entry
+	b := cryptobyte.NewBuilder(nil)
+	b.AddUint8(sctVersion1)
+	b.AddUint8(sctCertificateStamp)
+	b.AddUint64(uint64(sct.Timestamp.UnixMilli()))
+	b.AddUint16(sctPrecertEntry)
+	issuerKeyHash := sha256.Sum256(issuer.RawSubjectPublicKeyInfo)
+	b.AddBytes(issuerKeyHash[:])
+	b.AddUint24LengthPrefixed(func(b *cryptobyte.Builder) { b.AddBytes(tbs) })
+	b.AddUint16LengthPrefixed(func(b *cryptobyte.Builder) { b.AddBytes(sct.extensions) })
+	signed, err := b.Bytes()
+	if err != nil {
+		return err
+	}
+	digest := sha256.Sum256(signed)
+
+	switch key := log.PublicKey.(type) {
+	case *ecdsa.PublicKey:
+		if sct.sigAlg != sctSignatureECDSA || !ecdsa.VerifyASN1(key, digest[:], sct.signature) {
+			return fmt.Errorf("invalid signature of SCT issued by %s", log.Description)
+		}
+	case *rsa.PublicKey:
+		if sct.sigAlg != sctSignatureRSA || rsa.VerifyPKCS1v15(key, crypto.SHA256, digest[:], sct.signature) != nil {
+			return fmt.Errorf("invalid signature of SCT issued by %s", log.Description)
+		}
+	default:
+		return fmt.Errorf("unsupported key of log %s", log.Description)
+	}
+	sct.Verified = true
+	return nil
+}
+
+// tbsCertificate is used to rebuild the precertificate logged in CT, which is the certificate without its signature
+// and SCT list
+type tbsCertificate struct {
+	Raw                asn1.RawContent
+	Version            int `asn1:"optional,explicit,default:0,tag:0"`
+	SerialNumber       *big.Int
+	SignatureAlgorithm asn1.RawValue
+	Issuer             asn1.RawValue
+	Validity           asn1.RawValue
+	Subject            asn1.RawValue
+	PublicKey          asn1.RawValue
+	UniqueID           asn1.BitString   `asn1:"optional,tag:1"`
+	SubjectUniqueID    asn1.BitString   `asn1:"optional,tag:2"`
+	Extensions         []pkix.Extension `asn1:"optional,explicit,tag:3"`
+}
+
+func precertTBS(cert *x509.Certificate) ([]byte, error) {
+	var tbs tbsCertificate
+	_, err := asn1.Unmarshal(cert.RawTBSCertificate, &tbs)
+	if err != nil {
+		return nil, fmt.Errorf("could not parse certificate: %w", err)
+	}
+	extensions := make([]pkix.Extension, 0, len(tbs.Extensions))
+	for _, ext := range tbs.Extensions {
+		if !ext.Id.Equal(oidExtensionSCTList) {
+			extensions = append(extensions, ext)
+		}
+	}
+	tbs.Extensions = extensions
+	tbs.Raw = nil
+	return asn1.Marshal(tbs)
+}
+
+// ParseCTLogList returns the logs of a Certificate Transparency log list in the v3 JSON format published by Google,
+// i.e. https://www.gstatic.com/ct/log_list/v3/log_list.json
+func ParseCTLogList(data []byte) ([]CTLog, error) {
+	list := struct {
+		Operators []struct {
+			Logs []struct {
+				Description string `json:"description"`
+				LogID       []byte `json:"log_id"`
+				Key         []byte `json:"key"`
+			} `json:"logs"`
+		} `json:"operators"`
+	}{}
+	err := json.Unmarshal(data, &list)
+	if err != nil {
+		return nil, fmt.Errorf("invalid CT log list: %w", err)
+	}
+
+	var logs []CTLog
+	for _, operator := range list.Operators {
+		for _, l := range operator.Logs {
+			key, err := x509.ParsePKIXPublicKey(l.Key)
+			if err != nil {
+				return nil, fmt.Errorf("invalid key of CT log %s: %w", l.Description, err)
+			}
+			logs = append(logs, CTLog{ID: l.LogID, Description: l.Description, PublicKey: key})
+		}
+	}
+	return logs, nil
+}
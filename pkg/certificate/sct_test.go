@@ -0,0 +1,142 @@
+/*
+ * Copyright 2023 Venafi, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *  http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package certificate
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/asn1"
+	"encoding/base64"
+	"fmt"
+	"math/big"
+	"testing"
+	"time"
+
+	"golang.org/x/crypto/cryptobyte"
+)
+
+func TestParseAndVerifySCTs(t *testing.T) {
+	caKey, _ := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	logKey, _ := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	leafKey, _ := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+
+	now := time.Now().Truncate(time.Second)
+	caTemplate := &x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{CommonName: "Test CA"},
+		NotBefore:             now.Add(-time.Hour),
+		NotAfter:              now.AddDate(1, 0, 0),
+		IsCA:                  true,
+		BasicConstraintsValid: true,
+	}
+	caDER, err := x509.CreateCertificate(rand.Reader, caTemplate, caTemplate, &caKey.PublicKey, caKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ca, _ := x509.ParseCertificate(caDER)
+
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(2),
+		Subject:      pkix.Name{CommonName: "www.venafi.example"},
+		DNSNames:     []string{"www.venafi.example"},
+		NotBefore:    now.Add(-time.Hour),
+		NotAfter:     now.AddDate(0, 0, 90),
+	}
+	// the precertificate logged in CT is the certificate without the SCT list
+	precertDER, err := x509.CreateCertificate(rand.Reader, template, ca, &leafKey.PublicKey, caKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	precert, _ := x509.ParseCertificate(precertDER)
+
+	logKeyDER, _ := x509.MarshalPKIXPublicKey(&logKey.PublicKey)
+	logID := sha256.Sum256(logKeyDER)
+	timestamp := uint64(now.UnixMilli())
+
+	signed := cryptobyte.NewBuilder(nil)
+	signed.AddUint8(sctVersion1)
+	signed.AddUint8(sctCertificateStamp)
+	signed.AddUint64(timestamp)
+	signed.AddUint16(sctPrecertEntry)
+	issuerKeyHash := sha256.Sum256(ca.RawSubjectPublicKeyInfo)
+	signed.AddBytes(issuerKeyHash[:])
+	signed.AddUint24LengthPrefixed(func(b *cryptobyte.Builder) { b.AddBytes(precert.RawTBSCertificate) })
+	signed.AddUint16(0)
+	digest := sha256.Sum256(signed.BytesOrPanic())
+	signature, _ := ecdsa.SignASN1(rand.Reader, logKey, digest[:])
+
+	list := cryptobyte.NewBuilder(nil)
+	list.AddUint16LengthPrefixed(func(b *cryptobyte.Builder) {
+		b.AddUint16LengthPrefixed(func(b *cryptobyte.Builder) {
+			b.AddUint8(sctVersion1)
+			b.AddBytes(logID[:])
+			b.AddUint64(timestamp)
+			b.AddUint16(0)
+			b.AddUint8(sctHashSHA256)
+			b.AddUint8(sctSignatureECDSA)
+			b.AddUint16LengthPrefixed(func(b *cryptobyte.Builder) { b.AddBytes(signature) })
+		})
+	})
+	value, _ := asn1.Marshal(list.BytesOrPanic())
+	template.ExtraExtensions = []pkix.Extension{{Id: oidExtensionSCTList, Value: value}}
+	certDER, err := x509.CreateCertificate(rand.Reader, template, ca, &leafKey.PublicKey, caKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cert, _ := x509.ParseCertificate(certDER)
+
+	scts, err := ParseSCTs(cert)
+	if err != nil {
+		t.Fatalf("could not parse SCTs: %s", err)
+	}
+	if len(scts) != 1 || scts[0].LogID != base64.StdEncoding.EncodeToString(logID[:]) || !scts[0].Timestamp.Equal(now) {
+		t.Fatalf("unexpected SCTs: %+v", scts)
+	}
+
+	logList := fmt.Sprintf(`{"operators": [{"name": "Test", "logs": [{"description": "Test Log", "log_id": "%s", "key": "%s"}]}]}`,
+		scts[0].LogID, base64.StdEncoding.EncodeToString(logKeyDER))
+	logs, err := ParseCTLogList([]byte(logList))
+	if err != nil {
+		t.Fatalf("could not parse log list: %s", err)
+	}
+
+	err = scts[0].Verify(cert, ca, logs)
+	if err != nil {
+		t.Fatalf("could not verify SCT: %s", err)
+	}
+	if !scts[0].Verified || scts[0].LogDescription != "Test Log" {
+		t.Fatalf("expected SCT verified by Test Log, got %+v", scts[0])
+	}
+
+	sct := scts[0]
+	sct.Verified = false
+	if err = sct.Verify(cert, cert, logs); err == nil || sct.Verified {
+		t.Fatal("expected an error verifying the SCT with the wrong issuer")
+	}
+	if err = sct.Verify(cert, ca, nil); err == nil {
+		t.Fatal("expected an error verifying the SCT of an unknown log")
+	}
+
+	scts, err = ParseSCTs(ca)
+	if err != nil || len(scts) != 0 {
+		t.Fatalf("expected no SCTs, got %v: %v", scts, err)
+	}
+}
@@ -48,11 +48,12 @@ const (
 	nameTypeDNS   = 2
 	nameTypeURI   = 6
 	nameTypeIP    = 7
+	nameTypeRID   = 8
 )
 
-// Workaround for lack of User Principal Name SAN support and ability to control SAN extension criticality in crypto/x509 package
-func addSubjectAltNames(req *x509.CertificateRequest, dnsNames []string, emailAddrs []string, ipAddrs []net.IP, URIs []*url.URL, UPNs []string) {
-	sanBytes, err := marshalSANs(dnsNames, emailAddrs, ipAddrs, URIs, UPNs)
+// Workaround for lack of User Principal Name and Registered ID SAN support and ability to control SAN extension criticality in crypto/x509 package
+func addSubjectAltNames(req *x509.CertificateRequest, dnsNames []string, emailAddrs []string, ipAddrs []net.IP, URIs []*url.URL, UPNs []string, RIDs []asn1.ObjectIdentifier) {
+	sanBytes, err := marshalSANs(dnsNames, emailAddrs, ipAddrs, URIs, UPNs, RIDs)
 	if err != nil {
 		log.Fatal(err)
 	}
@@ -84,9 +85,9 @@ func addSubjectAltNames(req *x509.CertificateRequest, dnsNames []string, emailAd
 	req.URIs = nil
 }
 
-// Enhance crypto/x509 marshalSANs method to additionally support User Principal Name SANs
+// Enhance crypto/x509 marshalSANs method to additionally support User Principal Name and Registered ID SANs
 // Based on https://github.com/golang/go/blob/master/src/crypto/x509/x509.go#L1656-L1678
-func marshalSANs(dnsNames, emailAddresses []string, ipAddresses []net.IP, uris []*url.URL, uPNames []string) (derBytes []byte, err error) {
+func marshalSANs(dnsNames, emailAddresses []string, ipAddresses []net.IP, uris []*url.URL, uPNames []string, rIDs []asn1.ObjectIdentifier) (derBytes []byte, err error) {
 	var rawValues []asn1.RawValue
 	for _, name := range dnsNames {
 		rawValues = append(rawValues, asn1.RawValue{Tag: nameTypeDNS, Class: 2, Bytes: []byte(name)})
@@ -119,12 +120,57 @@ func marshalSANs(dnsNames, emailAddresses []string, ipAddresses []net.IP, uris [
 		}
 		rawValues = append(rawValues, asn1.RawValue{Tag: nameTypeOther, Class: 2, IsCompound: true, Bytes: raw.Bytes})
 	}
+	for _, rid := range rIDs {
+		// registeredID is an implicitly tagged OBJECT IDENTIFIER, so only its content octets are kept
+		var raw asn1.RawValue
+		oid, err := asn1.Marshal(rid)
+		if err != nil {
+			return nil, fmt.Errorf("could not marshal registeredID SAN %s: %v", rid, err)
+		}
+		_, err = asn1.Unmarshal(oid, &raw)
+		if err != nil {
+			return nil, fmt.Errorf("could not parse registeredID SAN: %v", err)
+		}
+		rawValues = append(rawValues, asn1.RawValue{Tag: nameTypeRID, Class: 2, Bytes: raw.Bytes})
+	}
 
 	return asn1.Marshal(rawValues)
 }
 
 // Since crypto/x509 package is not aware of UPN SANs, implement our own parsing method
 func getUserPrincipalNameSANs(cert *x509.Certificate) (ret []string, err error) {
+	err = forEachSAN(cert, func(v asn1.RawValue) error {
+		upn, err := parseUserPrincipalNameSAN(v.Tag, v.FullBytes)
+		if err != nil {
+			return err
+		}
+		if upn != "" {
+			ret = append(ret, upn)
+		}
+		return nil
+	})
+	return ret, err
+}
+
+// Since crypto/x509 package is not aware of Registered ID SANs either, implement our own parsing method
+func getRegisteredIDSANs(cert *x509.Certificate) (ret []asn1.ObjectIdentifier, err error) {
+	err = forEachSAN(cert, func(v asn1.RawValue) error {
+		if v.Class != 2 || v.Tag != nameTypeRID {
+			return nil
+		}
+		var rid asn1.ObjectIdentifier
+		_, err := asn1.UnmarshalWithParams(v.FullBytes, &rid, "tag:8")
+		if err != nil {
+			return fmt.Errorf("could not parse registeredID SAN: %v", err)
+		}
+		ret = append(ret, rid)
+		return nil
+	})
+	return ret, err
+}
+
+// forEachSAN calls f with every general name of the subjectAltName extensions of cert
+func forEachSAN(cert *x509.Certificate, f func(v asn1.RawValue) error) error {
 	for _, ext := range cert.Extensions {
 		if !ext.Id.Equal(oidExtensionSubjectAltName) {
 			continue
@@ -133,12 +179,12 @@ func getUserPrincipalNameSANs(cert *x509.Certificate) (ret []string, err error)
 		var seq asn1.RawValue
 		rest, err := asn1.Unmarshal(ext.Value, &seq)
 		if err != nil {
-			return nil, err
+			return err
 		} else if len(rest) != 0 {
-			return nil, fmt.Errorf("unexpected trailing data after X.509 extension")
+			return fmt.Errorf("unexpected trailing data after X.509 extension")
 		}
 		if !seq.IsCompound || seq.Tag != 16 || seq.Class != 0 {
-			return nil, asn1.StructuralError{Msg: "bad ASN.1 sequence for SAN"}
+			return asn1.StructuralError{Msg: "bad ASN.1 sequence for SAN"}
 		}
 
 		rest = seq.Bytes
@@ -146,20 +192,17 @@ func getUserPrincipalNameSANs(cert *x509.Certificate) (ret []string, err error)
 			var v asn1.RawValue
 			rest, err = asn1.Unmarshal(rest, &v)
 			if err != nil {
-				return nil, err
+				return err
 			}
 
-			upn, err := parseUserPrincipalNameSAN(v.Tag, v.FullBytes)
+			err = f(v)
 			if err != nil {
-				return nil, err
-			}
-			if upn != "" {
-				ret = append(ret, upn)
+				return err
 			}
 		}
 	}
 
-	return ret, nil
+	return nil
 }
 
 func parseUserPrincipalNameSAN(tag int, data []byte) (name string, err error) {
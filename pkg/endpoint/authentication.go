@@ -16,8 +16,13 @@
 
 package endpoint
 
+import (
+	"crypto/tls"
+)
+
 // Authentication provides a struct for authentication data. Either specify User and Password for Trust Protection Platform
-// or Firefly or ClientId and ClientSecret for Firefly or specify an APIKey for TLS Protect Cloud.
+// or Firefly or ClientId and ClientSecret for Firefly or specify an APIKey for TLS Protect Cloud. TLS Protect Cloud also
+// accepts a service account, either as an ExternalJWT or as a ClientId and PrivateKey, exchanged at TokenURL for an access token.
 type Authentication struct {
 	User         string `yaml:"user,omitempty"`
 	Password     string `yaml:"password,omitempty"`
@@ -28,8 +33,27 @@ type Authentication struct {
 	ClientSecret string `yaml:"clientSecret,omitempty"`
 	AccessToken  string `yaml:"accessToken,omitempty"`
 	ClientPKCS12 bool   `yaml:"-"`
+	// ClientCertificate is the TLS client certificate used to get a Trust Protection Platform token with the certificate grant.
+	// When ClientPKCS12 is set instead, the client certificate configured in the TLS settings of the HTTP transport is used
+	ClientCertificate *tls.Certificate `yaml:"-"`
+	// OnTokenRefresh is called with the new token pair when the TPP connector refreshes the access token with
+	// RefreshToken, so the rotated tokens can be persisted. When LockTokens is set, it is called with the lock held. Optional
+	OnTokenRefresh func(accessToken string, refreshToken string) error `yaml:"-"`
+	// LockTokens is called by the TPP connector before it refreshes the access token with RefreshToken. It locks the
+	// storage the tokens are persisted to, against other processes refreshing them, and returns the token pair
+	// persisted there along with the function releasing the lock. The connector uses the persisted pair, rather than
+	// refreshing its own, when another process already replaced it. Optional
+	LockTokens func() (accessToken string, refreshToken string, unlock func() error, err error) `yaml:"-"`
+	// ExternalJWT is a JWT issued by an external identity provider and trusted by a TLS Protect Cloud service account
+	ExternalJWT string `yaml:"externalJWT,omitempty"`
+	// PrivateKey is the PEM private key of a TLS Protect Cloud service account, used along with ClientId to sign a JWT
+	PrivateKey string `yaml:"privateKey,omitempty"`
+	// TokenURL is the TLS Protect Cloud endpoint where the service account JWT is exchanged for an access token
+	TokenURL string `yaml:"tokenURL,omitempty"`
 	// IdentityProvider specify the OAuth 2.0 which VCert will be working for authorization purposes
 	IdentityProvider *OAuthProvider `yaml:"idP,omitempty"`
+	// ACMEAccount specify the account used to request certificates from an ACME server
+	ACMEAccount *ACMEAccount `yaml:"acme,omitempty"`
 }
 
 // OAuthProvider provides a struct for the OAuth 2.0 providers information
@@ -38,3 +62,15 @@ type OAuthProvider struct {
 	TokenURL  string `yaml:"tokenURL,omitempty"`
 	Audience  string `yaml:"audience,omitempty"`
 }
+
+// ACMEAccount provides a struct for the ACME account information
+type ACMEAccount struct {
+	// Email is the contact address registered with the account
+	Email string `yaml:"email,omitempty"`
+	// KeyFile is the path to the PEM private key of the account. A new key is created and saved there when the file does not exist
+	KeyFile string `yaml:"keyFile,omitempty"`
+	// EABKeyID is the key identifier for External Account Binding, required by some ACME servers
+	EABKeyID string `yaml:"eabKeyId,omitempty"`
+	// EABHMACKey is the base64url encoded HMAC key for External Account Binding
+	EABHMACKey string `yaml:"eabHmacKey,omitempty"`
+}
@@ -17,6 +17,7 @@
 package endpoint
 
 import (
+	"context"
 	"crypto/ecdsa"
 	"crypto/ed25519"
 	"crypto/rsa"
@@ -50,6 +51,12 @@ const (
 	ConnectorTypeTPP
 	// ConnectorTypeFirefly represents the Firefly connector type
 	ConnectorTypeFirefly
+	// ConnectorTypeACME represents the ACME (RFC 8555) connector type
+	ConnectorTypeACME
+	// ConnectorTypeEST represents the EST (RFC 7030) connector type
+	ConnectorTypeEST
+	// ConnectorTypeSCEP represents the SCEP (RFC 8894) connector type
+	ConnectorTypeSCEP
 )
 
 func init() {
@@ -69,55 +76,66 @@ func (t ConnectorType) String() string {
 		return "Trust Protection Platform"
 	case ConnectorTypeFirefly:
 		return "Firefly"
+	case ConnectorTypeACME:
+		return "ACME"
+	case ConnectorTypeEST:
+		return "EST"
+	case ConnectorTypeSCEP:
+		return "SCEP"
 	default:
 		return fmt.Sprintf("unexpected connector type: %d", t)
 	}
 }
 
 // Connector provides a common interface for external communications with TPP or Venafi Cloud
+// The methods calling the platform take a ctx, which cancels the requests in progress and the wait for certificates
 type Connector interface {
 	// GetType returns a connector type (cloud/TPP/fake). Can be useful because some features are not supported by a Cloud connection.
 	GetType() ConnectorType
 	// SetZone sets a zone (by name) for requests with this connector.
 	SetZone(z string)
 	// GetZonesByParent returns a list of valid zones specified by parent
-	GetZonesByParent(parent string) ([]string, error)
-	Ping() (err error)
+	GetZonesByParent(ctx context.Context, parent string) ([]string, error)
+	Ping(ctx context.Context) (err error)
 	// Authenticate is usually called by NewClient and it is not required that you manually call it.
-	Authenticate(auth *Authentication) (err error)
+	Authenticate(ctx context.Context, auth *Authentication) (err error)
 	// ReadPolicyConfiguration returns information about zone policies. It can be used for checking request compatibility with policies.
-	ReadPolicyConfiguration() (policy *Policy, err error)
+	ReadPolicyConfiguration(ctx context.Context) (policy *Policy, err error)
 	// ReadZoneConfiguration returns the zone configuration. A zone configuration includes zone policy and additional zone information.
-	ReadZoneConfiguration() (config *ZoneConfiguration, err error)
+	ReadZoneConfiguration(ctx context.Context) (config *ZoneConfiguration, err error)
 	// GenerateRequest update certificate.Request with data from zone configuration.
-	GenerateRequest(config *ZoneConfiguration, req *certificate.Request) (err error)
+	GenerateRequest(ctx context.Context, config *ZoneConfiguration, req *certificate.Request) (err error)
 	// ResetCertificate resets the state of a certificate.
 	// This function is idempotent, i.e., it won't fail if there is nothing to be reset.
-	ResetCertificate(req *certificate.Request, restart bool) (err error)
+	ResetCertificate(ctx context.Context, req *certificate.Request, restart bool) (err error)
 	// RequestCertificate makes a request to the server with data for enrolling the certificate.
-	RequestCertificate(req *certificate.Request) (requestID string, err error)
+	RequestCertificate(ctx context.Context, req *certificate.Request) (requestID string, err error)
 	// SynchronousRequestCertificate makes a request to the server with data for enrolling the certificate and returns the enrolled certificate.
-	SynchronousRequestCertificate(req *certificate.Request) (certificates *certificate.PEMCollection, err error)
+	SynchronousRequestCertificate(ctx context.Context, req *certificate.Request) (certificates *certificate.PEMCollection, err error)
 	// SupportSynchronousRequestCertificate returns if the connector support synchronous calls to request a certificate.
 	SupportSynchronousRequestCertificate() bool
 	// RetrieveCertificate immediately returns an enrolled certificate. Otherwise, RetrieveCertificate waits and retries during req.Timeout.
-	RetrieveCertificate(req *certificate.Request) (certificates *certificate.PEMCollection, err error)
-	IsCSRServiceGenerated(req *certificate.Request) (bool, error)
-	RevokeCertificate(req *certificate.RevocationRequest) error
-	RenewCertificate(req *certificate.RenewalRequest) (requestID string, err error)
-	RetireCertificate(req *certificate.RetireRequest) error
+	RetrieveCertificate(ctx context.Context, req *certificate.Request) (certificates *certificate.PEMCollection, err error)
+	IsCSRServiceGenerated(ctx context.Context, req *certificate.Request) (bool, error)
+	RevokeCertificate(ctx context.Context, req *certificate.RevocationRequest) error
+	RenewCertificate(ctx context.Context, req *certificate.RenewalRequest) (requestID string, err error)
+	RetireCertificate(ctx context.Context, req *certificate.RetireRequest) error
 	// ImportCertificate adds an existing certificate to Venafi Platform even if the certificate was not issued by Venafi Cloud or Venafi Platform. For information purposes.
-	ImportCertificate(req *certificate.ImportRequest) (*certificate.ImportResponse, error)
+	ImportCertificate(ctx context.Context, req *certificate.ImportRequest) (*certificate.ImportResponse, error)
 	// SetHTTPClient allows to set custom http.Client to this Connector.
 	SetHTTPClient(client *http.Client)
 	// ListCertificates
-	ListCertificates(filter Filter) ([]certificate.CertificateInfo, error)
-	SetPolicy(name string, ps *policy.PolicySpecification) (string, error)
-	GetPolicy(name string) (*policy.PolicySpecification, error)
-	RequestSSHCertificate(req *certificate.SshCertRequest) (response *certificate.SshCertificateObject, err error)
-	RetrieveSSHCertificate(req *certificate.SshCertRequest) (response *certificate.SshCertificateObject, err error)
-	RetrieveSshConfig(ca *certificate.SshCaTemplateRequest) (*certificate.SshConfig, error)
-	SearchCertificates(req *certificate.SearchRequest) (*certificate.CertSearchResponse, error)
+	ListCertificates(ctx context.Context, filter Filter) ([]certificate.CertificateInfo, error)
+	SetPolicy(ctx context.Context, name string, ps *policy.PolicySpecification) (string, error)
+	GetPolicy(ctx context.Context, name string) (*policy.PolicySpecification, error)
+	RequestSSHCertificate(ctx context.Context, req *certificate.SshCertRequest) (response *certificate.SshCertificateObject, err error)
+	RetrieveSSHCertificate(ctx context.Context, req *certificate.SshCertRequest) (response *certificate.SshCertificateObject, err error)
+	// RenewSSHCertificate requests a new SSH certificate that replaces the one identified by req.PickupID or req.Guid
+	RenewSSHCertificate(ctx context.Context, req *certificate.SshCertRequest) (response *certificate.SshCertificateObject, err error)
+	// RevokeSSHCertificate revokes the SSH certificate identified by req.PickupID or req.Guid
+	RevokeSSHCertificate(ctx context.Context, req *certificate.SshCertRequest) error
+	RetrieveSshConfig(ctx context.Context, ca *certificate.SshCaTemplateRequest) (*certificate.SshConfig, error)
+	SearchCertificates(ctx context.Context, req *certificate.SearchRequest) (*certificate.CertSearchResponse, error)
 	// Returns a valid certificate
 	//
 	// If it returns no error, the certificate returned should be the latest [1]
@@ -128,11 +146,11 @@ type Connector interface {
 	// validityEnd for VaaS
 	// [2] application name for VaaS
 	// [3] an array of strings representing the DNS names
-	SearchCertificate(zone string, cn string, sans *certificate.Sans, certMinTimeLeft time.Duration) (*certificate.CertificateInfo, error)
-	RetrieveAvailableSSHTemplates() ([]certificate.SshAvaliableTemplate, error)
-	RetrieveCertificateMetaData(dn string) (*certificate.CertificateMetaData, error)
-	RetrieveSystemVersion() (string, error)
-	WriteLog(req *LogRequest) error
+	SearchCertificate(ctx context.Context, zone string, cn string, sans *certificate.Sans, certMinTimeLeft time.Duration) (*certificate.CertificateInfo, error)
+	RetrieveAvailableSSHTemplates(ctx context.Context) ([]certificate.SshAvaliableTemplate, error)
+	RetrieveCertificateMetaData(ctx context.Context, dn string) (*certificate.CertificateMetaData, error)
+	RetrieveSystemVersion(ctx context.Context) (string, error)
+	WriteLog(ctx context.Context, req *LogRequest) error
 }
 
 type Filter struct {
@@ -197,6 +215,8 @@ type Policy struct {
 	UpnSanRegExs   []string
 	AllowWildcards bool
 	AllowKeyReuse  bool
+	// MaxValidity is the longest validity the certificates can be requested with. Zero means no limit
+	MaxValidity time.Duration
 }
 
 // ZoneConfiguration provides a common structure for certificate request data provided by the remote endpoint
@@ -256,7 +276,16 @@ func (p *Policy) ValidateCertificateRequest(request *certificate.Request) error
 	if err != nil {
 		return err
 	}
-	csr := request.GetCSR()
+	if validity := request.GetValidityDuration(); validity != nil {
+		if *validity <= 0 {
+			return fmt.Errorf("the requested validity ends in the past")
+		}
+		if p.MaxValidity > 0 && *validity > p.MaxValidity {
+			return fmt.Errorf("the requested validity of %s exceeds the maximum of %s allowed by the policy",
+				validity.Round(time.Second), p.MaxValidity)
+		}
+	}
+	csr := validatedCSR(request)
 	if len(csr) > 0 {
 		pemBlock, _ := pem.Decode(csr)
 		parsedCSR, err := x509.ParseCertificateRequest(pemBlock.Bytes)
@@ -322,6 +351,9 @@ func (p *Policy) ValidateCertificateRequest(request *certificate.Request) error
 				} else {
 					return fmt.Errorf("invalid key in csr")
 				}
+			} else if certificate.IsPostQuantumAlgorithm(parsedCSR.PublicKeyAlgorithm) {
+				// zones have no configuration for post-quantum and hybrid keys, the CA is left to validate them
+				keyValid = true
 			}
 			if !keyValid {
 				return fmt.Errorf(keyError)
@@ -358,7 +390,7 @@ func (p *Policy) ValidateCertificateRequest(request *certificate.Request) error
 
 // SimpleValidateCertificateRequest functions just check Common Name and SANs mathching with policies
 func (p *Policy) SimpleValidateCertificateRequest(request certificate.Request) error {
-	csr := request.GetCSR()
+	csr := validatedCSR(&request)
 	const (
 		cnError   = "common name %s is not allowed in this policy: %v"
 		SANsError = "DNS SANs %v do not match regular expressions: %v"
@@ -386,7 +418,23 @@ func (p *Policy) SimpleValidateCertificateRequest(request certificate.Request) e
 	return nil
 }
 
+// validatedCSR returns the CSR of the request, unless it was generated locally with a brainpool key. crypto/x509
+// cannot parse such a CSR, so the request fields it was generated from are validated instead
+func validatedCSR(request *certificate.Request) []byte {
+	if request.PrivateKey != nil {
+		switch request.KeyCurve {
+		case certificate.EllipticCurveBrainpoolP256r1, certificate.EllipticCurveBrainpoolP384r1, certificate.EllipticCurveBrainpoolP512r1:
+			return nil
+		}
+	}
+	return request.GetCSR()
+}
+
 func checkKey(kt certificate.KeyType, bitsize int, curveStr string, allowed []AllowedKeyConfiguration) (valid bool) {
+	if kt.IsPostQuantum() {
+		// zones have no configuration for post-quantum keys, the CA is left to validate them
+		return true
+	}
 	for _, allowedKey := range allowed {
 		if allowedKey.KeyType == kt {
 			switch allowedKey.KeyType {
@@ -3,6 +3,7 @@ package endpoint
 import (
 	"crypto/x509/pkix"
 	"testing"
+	"time"
 
 	"github.com/Venafi/vcert/v5/pkg/certificate"
 )
@@ -33,9 +34,30 @@ var cases = []validationTestCase{
 		certificate.Request{Subject: pkix.Name{CommonName: "test.example.com", Organization: []string{"Venafi", "Mozilla"}}},
 		Policy{SubjectCNRegexes: any, SubjectORegexes: []string{"^Venafi$", "TestCo"}, SubjectCRegexes: any, SubjectLRegexes: any, SubjectOURegexes: any, SubjectSTRegexes: any},
 		false,
+	}, {
+		certificate.Request{Subject: pkix.Name{CommonName: "test.example.com"}, ValidityDuration: validity(12 * time.Hour)},
+		Policy{SubjectCNRegexes: any, SubjectORegexes: any, SubjectCRegexes: any, SubjectLRegexes: any, SubjectOURegexes: any, SubjectSTRegexes: any, MaxValidity: 24 * time.Hour},
+		true,
+	}, {
+		certificate.Request{Subject: pkix.Name{CommonName: "test.example.com"}, ValidityHours: 48},
+		Policy{SubjectCNRegexes: any, SubjectORegexes: any, SubjectCRegexes: any, SubjectLRegexes: any, SubjectOURegexes: any, SubjectSTRegexes: any, MaxValidity: 24 * time.Hour},
+		false,
+	}, {
+		certificate.Request{Subject: pkix.Name{CommonName: "test.example.com"}, NotAfter: notAfter(-time.Hour)},
+		Policy{SubjectCNRegexes: any, SubjectORegexes: any, SubjectCRegexes: any, SubjectLRegexes: any, SubjectOURegexes: any, SubjectSTRegexes: any},
+		false,
 	},
 }
 
+func validity(d time.Duration) *time.Duration {
+	return &d
+}
+
+func notAfter(d time.Duration) *time.Time {
+	t := time.Now().Add(d)
+	return &t
+}
+
 const csr1 = `-----BEGIN CERTIFICATE REQUEST-----
 MIIBozCCAQUCAQAwYDELMAkGA1UEBhMCQVUxEzARBgNVBAgMClNvbWUtU3RhdGUx
 ITAfBgNVBAoMGEludGVybmV0IFdpZGdpdHMgUHR5IEx0ZDEZMBcGA1UEAwwQdGVz
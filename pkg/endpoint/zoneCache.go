@@ -0,0 +1,100 @@
+/*
+ * Copyright 2023 Venafi, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *  http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package endpoint
+
+import (
+	"sync"
+	"time"
+)
+
+// ZoneConfigurationCache keeps the zone configurations read by the TPP and Venafi as a Service connectors, so
+// repeated certificate requests to the same zone reuse them instead of reading the policy again.
+// A ZoneConfigurationCache is safe for concurrent use and is meant to be shared by every connector of the process
+type ZoneConfigurationCache struct {
+	ttl time.Duration
+
+	mu      sync.Mutex
+	entries map[zoneCacheKey]zoneCacheEntry
+}
+
+type zoneCacheKey struct {
+	baseURL string
+	zone    string
+}
+
+type zoneCacheEntry struct {
+	config  ZoneConfiguration
+	expires time.Time
+}
+
+// NewZoneConfigurationCache returns a cache whose zone configurations expire ttl after being read.
+// Nothing is cached when ttl is not positive
+func NewZoneConfigurationCache(ttl time.Duration) *ZoneConfigurationCache {
+	return &ZoneConfigurationCache{
+		ttl:     ttl,
+		entries: make(map[zoneCacheKey]zoneCacheEntry),
+	}
+}
+
+// TTL returns how long the zone configurations are cached
+func (c *ZoneConfigurationCache) TTL() time.Duration {
+	return c.ttl
+}
+
+// Get returns a copy of the zone configuration cached for the zone of the platform at baseURL, if it has not expired.
+// The slices and maps of the copy are shared with the cache, so they must not be modified
+func (c *ZoneConfigurationCache) Get(baseURL string, zone string) (*ZoneConfiguration, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	key := zoneCacheKey{baseURL: baseURL, zone: zone}
+	entry, ok := c.entries[key]
+	if !ok {
+		return nil, false
+	}
+	if time.Now().After(entry.expires) {
+		delete(c.entries, key)
+		return nil, false
+	}
+	config := entry.config
+	return &config, true
+}
+
+// Set caches a copy of config for the zone of the platform at baseURL
+func (c *ZoneConfigurationCache) Set(baseURL string, zone string, config *ZoneConfiguration) {
+	if c.ttl <= 0 || config == nil {
+		return
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.entries[zoneCacheKey{baseURL: baseURL, zone: zone}] = zoneCacheEntry{config: *config, expires: time.Now().Add(c.ttl)}
+}
+
+// Invalidate removes the zone configuration cached for the zone of the platform at baseURL, i.e. after its policy
+// has been changed
+func (c *ZoneConfigurationCache) Invalidate(baseURL string, zone string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	delete(c.entries, zoneCacheKey{baseURL: baseURL, zone: zone})
+}
+
+// InvalidateAll removes every cached zone configuration
+func (c *ZoneConfigurationCache) InvalidateAll() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.entries = make(map[zoneCacheKey]zoneCacheEntry)
+}
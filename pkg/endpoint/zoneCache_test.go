@@ -0,0 +1,80 @@
+/*
+ * Copyright 2023 Venafi, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *  http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package endpoint
+
+import (
+	"testing"
+	"time"
+)
+
+func TestZoneConfigurationCache(t *testing.T) {
+	cache := NewZoneConfigurationCache(time.Minute)
+	_, ok := cache.Get("https://tpp.example.com", "zone")
+	if ok {
+		t.Fatalf("empty cache returned a zone configuration")
+	}
+
+	cache.Set("https://tpp.example.com", "zone", &ZoneConfiguration{Organization: "Venafi"})
+	zc, ok := cache.Get("https://tpp.example.com", "zone")
+	if !ok || zc.Organization != "Venafi" {
+		t.Fatalf("cached zone configuration was not returned: %v", zc)
+	}
+	zc.Organization = "Changed"
+	zc, _ = cache.Get("https://tpp.example.com", "zone")
+	if zc.Organization != "Venafi" {
+		t.Fatalf("cached zone configuration was modified through a copy")
+	}
+
+	_, ok = cache.Get("https://tpp.example.com", "other zone")
+	if ok {
+		t.Fatalf("zone configuration returned for another zone")
+	}
+	_, ok = cache.Get("https://other.example.com", "zone")
+	if ok {
+		t.Fatalf("zone configuration returned for another platform")
+	}
+
+	cache.Invalidate("https://tpp.example.com", "zone")
+	_, ok = cache.Get("https://tpp.example.com", "zone")
+	if ok {
+		t.Fatalf("invalidated zone configuration was returned")
+	}
+
+	cache.Set("https://tpp.example.com", "zone", &ZoneConfiguration{})
+	cache.InvalidateAll()
+	_, ok = cache.Get("https://tpp.example.com", "zone")
+	if ok {
+		t.Fatalf("zone configuration was returned after invalidating the cache")
+	}
+}
+
+func TestZoneConfigurationCacheExpiry(t *testing.T) {
+	cache := NewZoneConfigurationCache(10 * time.Millisecond)
+	cache.Set("https://tpp.example.com", "zone", &ZoneConfiguration{})
+	time.Sleep(20 * time.Millisecond)
+	_, ok := cache.Get("https://tpp.example.com", "zone")
+	if ok {
+		t.Fatalf("expired zone configuration was returned")
+	}
+
+	disabled := NewZoneConfigurationCache(0)
+	disabled.Set("https://tpp.example.com", "zone", &ZoneConfiguration{})
+	_, ok = disabled.Get("https://tpp.example.com", "zone")
+	if ok {
+		t.Fatalf("zone configuration was cached with no TTL")
+	}
+}
@@ -0,0 +1,132 @@
+//go:build cgo
+
+/*
+ * Copyright 2023 Venafi, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *  http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package pkcs11
+
+import (
+	"crypto"
+	"crypto/elliptic"
+	"crypto/rand"
+	"fmt"
+
+	"github.com/ThalesIgnite/crypto11"
+
+	"github.com/Venafi/vcert/v5/pkg/certificate"
+	"github.com/Venafi/vcert/v5/pkg/verror"
+)
+
+// KeyProvider generates key pairs in a PKCS#11 token. Close must be called once the certificate
+// requests have been signed, to log out of the token and unload the module
+type KeyProvider struct {
+	ctx      *crypto11.Context
+	keyLabel string
+}
+
+// NewKeyProvider loads the PKCS#11 module and logs in to the token set in config
+func NewKeyProvider(config Config) (*KeyProvider, error) {
+	err := config.IsValid()
+	if err != nil {
+		return nil, err
+	}
+
+	ctx, err := crypto11.Configure(&crypto11.Config{
+		Path:       config.Module,
+		SlotNumber: config.Slot,
+		TokenLabel: config.TokenLabel,
+		Pin:        config.PIN,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("%w: failed to open PKCS#11 token: %v", verror.VcertError, err)
+	}
+
+	return &KeyProvider{ctx: ctx, keyLabel: config.KeyLabel}, nil
+}
+
+// GenerateKey creates a new RSA or ECDSA key pair in the token and returns a crypto.Signer for it
+func (p *KeyProvider) GenerateKey(request *certificate.Request) (crypto.Signer, error) {
+	id := make([]byte, 16)
+	_, err := rand.Read(id)
+	if err != nil {
+		return nil, err
+	}
+	label := p.label(request)
+
+	var signer crypto.Signer
+	switch request.KeyType {
+	case certificate.KeyTypeRSA:
+		signer, err = p.ctx.GenerateRSAKeyPairWithLabel(id, []byte(label), request.KeyLength)
+	case certificate.KeyTypeECDSA:
+		var curve elliptic.Curve
+		curve, err = getCurve(request.KeyCurve)
+		if err != nil {
+			return nil, err
+		}
+		signer, err = p.ctx.GenerateECDSAKeyPairWithLabel(id, []byte(label), curve)
+	default:
+		return nil, fmt.Errorf("%w: key type %s is not supported by the PKCS#11 key provider", verror.UserDataError, request.KeyType.String())
+	}
+	if err != nil {
+		return nil, fmt.Errorf("%w: failed to generate key pair in PKCS#11 token: %v", verror.VcertError, err)
+	}
+	return signer, nil
+}
+
+// FindKey returns the key pair of the token holding the private key of publicKey, among the ones with the label
+// GenerateKey gives to the key pairs of request, so a renewal can reuse it. Returns nil when the token has no such
+// key pair
+func (p *KeyProvider) FindKey(request *certificate.Request, publicKey crypto.PublicKey) (crypto.Signer, error) {
+	signers, err := p.ctx.FindKeyPairs(nil, []byte(p.label(request)))
+	if err != nil {
+		return nil, fmt.Errorf("%w: failed to find key pair in PKCS#11 token: %v", verror.VcertError, err)
+	}
+	for _, signer := range signers {
+		if public, ok := signer.Public().(interface{ Equal(crypto.PublicKey) bool }); ok && public.Equal(publicKey) {
+			return signer, nil
+		}
+	}
+	return nil, nil
+}
+
+// label returns the label of the key pairs of request: the key label of the config, or the common name of request
+func (p *KeyProvider) label(request *certificate.Request) string {
+	if p.keyLabel != "" {
+		return p.keyLabel
+	}
+	return request.Subject.CommonName
+}
+
+// Close logs out of the token and releases the module
+func (p *KeyProvider) Close() error {
+	return p.ctx.Close()
+}
+
+func getCurve(curve certificate.EllipticCurve) (elliptic.Curve, error) {
+	if curve == certificate.EllipticCurveNotSet {
+		curve = certificate.EllipticCurveDefault
+	}
+	switch curve {
+	case certificate.EllipticCurveP256:
+		return elliptic.P256(), nil
+	case certificate.EllipticCurveP384:
+		return elliptic.P384(), nil
+	case certificate.EllipticCurveP521:
+		return elliptic.P521(), nil
+	default:
+		return nil, fmt.Errorf("%w: curve %s is not supported by the PKCS#11 key provider", verror.UserDataError, curve.String())
+	}
+}
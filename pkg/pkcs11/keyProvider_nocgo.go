@@ -0,0 +1,54 @@
+//go:build !cgo
+
+/*
+ * Copyright 2023 Venafi, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *  http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package pkcs11
+
+import (
+	"crypto"
+	"fmt"
+
+	"github.com/Venafi/vcert/v5/pkg/certificate"
+	"github.com/Venafi/vcert/v5/pkg/verror"
+)
+
+// KeyProvider is not available in binaries built without cgo, as PKCS#11 modules cannot be loaded
+type KeyProvider struct{}
+
+// NewKeyProvider always fails in binaries built without cgo
+func NewKeyProvider(config Config) (*KeyProvider, error) {
+	err := config.IsValid()
+	if err != nil {
+		return nil, err
+	}
+	return nil, fmt.Errorf("%w: PKCS#11 support requires a vcert binary built with cgo enabled", verror.VcertError)
+}
+
+// GenerateKey always fails in binaries built without cgo
+func (p *KeyProvider) GenerateKey(_ *certificate.Request) (crypto.Signer, error) {
+	return nil, fmt.Errorf("%w: PKCS#11 support requires a vcert binary built with cgo enabled", verror.VcertError)
+}
+
+// FindKey always fails in binaries built without cgo
+func (p *KeyProvider) FindKey(_ *certificate.Request, _ crypto.PublicKey) (crypto.Signer, error) {
+	return nil, fmt.Errorf("%w: PKCS#11 support requires a vcert binary built with cgo enabled", verror.VcertError)
+}
+
+// Close does nothing in binaries built without cgo
+func (p *KeyProvider) Close() error {
+	return nil
+}
@@ -0,0 +1,77 @@
+//go:build cgo
+
+/*
+ * Copyright 2023 Venafi, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *  http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package pkcs11
+
+import (
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"os"
+	"testing"
+
+	"github.com/Venafi/vcert/v5/pkg/certificate"
+)
+
+// TestGenerateKeyInToken needs an initialized token, e.g. from SoftHSM:
+//
+//	softhsm2-util --init-token --free --label vcert --pin 1234 --so-pin 5678
+//	PKCS11_MODULE=/usr/lib/softhsm/libsofthsm2.so PKCS11_TOKEN_LABEL=vcert PKCS11_PIN=1234 go test ./pkg/pkcs11
+func TestGenerateKeyInToken(t *testing.T) {
+	config := Config{
+		Module:     os.Getenv("PKCS11_MODULE"),
+		TokenLabel: os.Getenv("PKCS11_TOKEN_LABEL"),
+		PIN:        os.Getenv("PKCS11_PIN"),
+	}
+	if config.Module == "" {
+		t.Skip("PKCS11_MODULE is not set")
+	}
+
+	provider, err := NewKeyProvider(config)
+	if err != nil {
+		t.Fatalf("failed to open token: %s", err)
+	}
+	defer provider.Close()
+
+	for _, keyType := range []certificate.KeyType{certificate.KeyTypeRSA, certificate.KeyTypeECDSA} {
+		req := certificate.Request{
+			Subject:     pkix.Name{CommonName: "hsm.venafi.example"},
+			KeyType:     keyType,
+			KeyProvider: provider,
+		}
+		err = req.GeneratePrivateKey()
+		if err != nil {
+			t.Fatalf("failed to generate %s key: %s", keyType.String(), err)
+		}
+		if certificate.IsKeyExportable(req.PrivateKey) {
+			t.Fatalf("a key generated in the token should not be exportable")
+		}
+		err = req.GenerateCSR()
+		if err != nil {
+			t.Fatalf("failed to sign CSR: %s", err)
+		}
+		block, _ := pem.Decode(req.GetCSR())
+		csr, err := x509.ParseCertificateRequest(block.Bytes)
+		if err != nil {
+			t.Fatalf("failed to parse CSR: %s", err)
+		}
+		if err = csr.CheckSignature(); err != nil {
+			t.Fatalf("CSR signature does not verify: %s", err)
+		}
+	}
+}
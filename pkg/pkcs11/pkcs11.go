@@ -0,0 +1,58 @@
+/*
+ * Copyright 2023 Venafi, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *  http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+// Package pkcs11 provides a certificate.KeyProvider that generates private keys inside a PKCS#11 token,
+// like an HSM. The private key never leaves the token and the certificate request is signed on the device.
+//
+// PKCS#11 modules are loaded dynamically, so this support is only available in binaries built with cgo enabled.
+package pkcs11
+
+import (
+	"fmt"
+
+	"github.com/Venafi/vcert/v5/pkg/verror"
+)
+
+// Config holds the settings needed to open a session on a PKCS#11 token
+type Config struct {
+	// Module is the path to the PKCS#11 library of the HSM vendor, e.g. /usr/lib/softhsm/libsofthsm2.so
+	Module string `yaml:"module,omitempty"`
+	// Slot is the number of the slot holding the token. Either Slot or TokenLabel must be set
+	Slot *int `yaml:"slot,omitempty"`
+	// TokenLabel selects the token by its label. Either Slot or TokenLabel must be set
+	TokenLabel string `yaml:"tokenLabel,omitempty"`
+	// PIN is the user PIN used to log in to the token
+	PIN string `yaml:"pin,omitempty"`
+	// KeyLabel is the label of the generated key pairs. The common name of the request is used when empty
+	KeyLabel string `yaml:"keyLabel,omitempty"`
+}
+
+// IsValid returns an error when the Config misses any setting required to open the token
+func (c Config) IsValid() error {
+	if c.Module == "" {
+		return fmt.Errorf("%w: PKCS#11 module path is required", verror.UserDataError)
+	}
+	if c.Slot == nil && c.TokenLabel == "" {
+		return fmt.Errorf("%w: either a PKCS#11 slot or a token label is required", verror.UserDataError)
+	}
+	if c.Slot != nil && c.TokenLabel != "" {
+		return fmt.Errorf("%w: PKCS#11 slot and token label cannot be used together", verror.UserDataError)
+	}
+	if c.PIN == "" {
+		return fmt.Errorf("%w: PKCS#11 PIN is required", verror.UserDataError)
+	}
+	return nil
+}
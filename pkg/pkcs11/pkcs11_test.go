@@ -0,0 +1,49 @@
+/*
+ * Copyright 2023 Venafi, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *  http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package pkcs11
+
+import (
+	"testing"
+)
+
+func TestConfigIsValid(t *testing.T) {
+	slot := 0
+	cases := []struct {
+		name   string
+		config Config
+		valid  bool
+	}{
+		{"TokenLabel", Config{Module: "/usr/lib/softhsm/libsofthsm2.so", TokenLabel: "vcert", PIN: "1234"}, true},
+		{"Slot", Config{Module: "/usr/lib/softhsm/libsofthsm2.so", Slot: &slot, PIN: "1234"}, true},
+		{"NoModule", Config{TokenLabel: "vcert", PIN: "1234"}, false},
+		{"NoToken", Config{Module: "/usr/lib/softhsm/libsofthsm2.so", PIN: "1234"}, false},
+		{"SlotAndTokenLabel", Config{Module: "/usr/lib/softhsm/libsofthsm2.so", Slot: &slot, TokenLabel: "vcert", PIN: "1234"}, false},
+		{"NoPIN", Config{Module: "/usr/lib/softhsm/libsofthsm2.so", TokenLabel: "vcert"}, false},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			err := c.config.IsValid()
+			if c.valid && err != nil {
+				t.Fatalf("expected a valid config, got: %s", err)
+			}
+			if !c.valid && err == nil {
+				t.Fatalf("expected an invalid config")
+			}
+		})
+	}
+}
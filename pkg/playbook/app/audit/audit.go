@@ -0,0 +1,204 @@
+/*
+ * Copyright 2023 Venafi, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *  http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+// Package audit records the operations that change certificates, or the hosts they are installed in, to an
+// append-only log kept apart from the debug logs, for compliance teams to know who did what, when and where.
+//
+// Every enrollment, renewal, revocation, installation and after-install action is written as a JSON line, with its
+// parameters and its result
+package audit
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"os/user"
+	"strings"
+	"sync"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+const (
+	// OperationEnroll is the request of a certificate where none was installed
+	OperationEnroll = "enroll"
+	// OperationRenew is the request of a certificate replacing the one installed
+	OperationRenew = "renew"
+	// OperationRevoke is the revocation of a certificate
+	OperationRevoke = "revoke"
+	// OperationInstall is the installation of a certificate in a location
+	OperationInstall = "install"
+	// OperationAfterAction is the run of the after-install actions of a location
+	OperationAfterAction = "afterAction"
+	// OperationAdopt is the search of an installed certificate in the Venafi platform, to manage it from then on
+	OperationAdopt = "adopt"
+
+	// ResultSuccess is the result of the operations that succeeded
+	ResultSuccess = "success"
+	// ResultFailure is the result of the operations that failed
+	ResultFailure = "failure"
+
+	// SyslogLocal sends the audit events to the local syslog daemon
+	SyslogLocal = "local"
+)
+
+// Options defines where the audit events are written. Both destinations may be used at once
+type Options struct {
+	// File is the path of the file the events are appended to. It is never truncated nor rotated by vcert
+	File string `yaml:"file,omitempty"`
+	// Syslog is either local, for the local syslog daemon, or the address of a remote one as udp://host:port or
+	// tcp://host:port. Not supported on Windows
+	Syslog string `yaml:"syslog,omitempty"`
+}
+
+// IsEmpty returns true when no destination is set
+func (o Options) IsEmpty() bool {
+	return o.File == "" && o.Syslog == ""
+}
+
+// IsValid returns an error when the syslog destination is malformed
+func (o Options) IsValid() error {
+	_, _, err := o.syslogAddress()
+	return err
+}
+
+// syslogAddress returns the network and address of the syslog daemon, both empty for the local one
+func (o Options) syslogAddress() (string, string, error) {
+	if o.Syslog == "" || strings.EqualFold(o.Syslog, SyslogLocal) {
+		return "", "", nil
+	}
+	network, address, found := strings.Cut(o.Syslog, "://")
+	if !found || (network != "udp" && network != "tcp") || address == "" {
+		return "", "", fmt.Errorf("invalid syslog destination %q. Should be %s, udp://host:port or tcp://host:port", o.Syslog, SyslogLocal)
+	}
+	return network, address, nil
+}
+
+// Event is an operation recorded in the audit log
+type Event struct {
+	Time      time.Time `json:"time"`
+	Operation string    `json:"operation"`
+	// Result is either ResultSuccess or ResultFailure. It is set from Error when empty
+	Result string `json:"result"`
+	// User and Host identify who ran the operation, and where
+	User string `json:"user"`
+	Host string `json:"host"`
+	// Task is the playbook task the operation belongs to. It is empty for the operations of the vcert commands
+	Task     string `json:"task,omitempty"`
+	Platform string `json:"platform,omitempty"`
+	Zone     string `json:"zone,omitempty"`
+	// Location is where the certificate was installed, for install and after-install actions
+	Location   string `json:"location,omitempty"`
+	CommonName string `json:"commonName,omitempty"`
+	// Parameters are the parameters of the request, or the actions run
+	Parameters map[string]string `json:"parameters,omitempty"`
+	PickupID   string            `json:"pickupId,omitempty"`
+	Serial     string            `json:"serial,omitempty"`
+	Thumbprint string            `json:"thumbprint,omitempty"`
+	Error      string            `json:"error,omitempty"`
+}
+
+// Log writes audit events to its destinations. It is safe for concurrent use, and a nil Log records nothing
+type Log struct {
+	mu      sync.Mutex
+	writers []io.WriteCloser
+	user    string
+	host    string
+}
+
+// Open opens the destinations of opts. The file is created when it does not exist
+func Open(opts Options) (*Log, error) {
+	err := opts.IsValid()
+	if err != nil {
+		return nil, err
+	}
+
+	l := &Log{}
+	if opts.File != "" {
+		f, err := os.OpenFile(opts.File, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0600)
+		if err != nil {
+			return nil, fmt.Errorf("failed to open audit log file: %w", err)
+		}
+		l.writers = append(l.writers, f)
+	}
+	if opts.Syslog != "" {
+		network, address, _ := opts.syslogAddress()
+		w, err := dialSyslog(network, address)
+		if err != nil {
+			_ = l.Close()
+			return nil, fmt.Errorf("failed to connect to syslog: %w", err)
+		}
+		l.writers = append(l.writers, w)
+	}
+
+	if u, err := user.Current(); err == nil {
+		l.user = u.Username
+	}
+	l.host, _ = os.Hostname()
+	return l, nil
+}
+
+// Record writes the event to every destination. Failures are logged, as they must not stop the operation audited
+func (l *Log) Record(event Event) {
+	if l == nil {
+		return
+	}
+	if event.Time.IsZero() {
+		event.Time = time.Now().UTC()
+	}
+	if event.Result == "" {
+		event.Result = ResultSuccess
+		if event.Error != "" {
+			event.Result = ResultFailure
+		}
+	}
+	event.User = l.user
+	event.Host = l.host
+
+	data, err := json.Marshal(event)
+	if err != nil {
+		zap.L().Error("failed to serialize audit event", zap.String("operation", event.Operation), zap.Error(err))
+		return
+	}
+	data = append(data, '\n')
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	for _, w := range l.writers {
+		_, err = w.Write(data)
+		if err != nil {
+			zap.L().Error("failed to write audit event", zap.String("operation", event.Operation), zap.Error(err))
+		}
+	}
+}
+
+// Close closes the destinations of the log
+func (l *Log) Close() error {
+	if l == nil {
+		return nil
+	}
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	var errs error
+	for _, w := range l.writers {
+		errs = errors.Join(errs, w.Close())
+	}
+	l.writers = nil
+	return errs
+}
@@ -0,0 +1,87 @@
+/*
+ * Copyright 2023 Venafi, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *  http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package audit
+
+import (
+	"bufio"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLog_Record(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "audit.log")
+
+	l, err := Open(Options{File: path})
+	require.NoError(t, err)
+	l.Record(Event{Operation: OperationEnroll, Task: "web", Zone: "Open Source\\vcert", CommonName: "web.example.com",
+		Parameters: map[string]string{"keyType": "RSA"}})
+	require.NoError(t, l.Close())
+
+	// The file is appended to, never truncated
+	l, err = Open(Options{File: path})
+	require.NoError(t, err)
+	l.Record(Event{Operation: OperationInstall, Task: "web", Location: "/etc/ssl/web.pem", Error: "permission denied"})
+	require.NoError(t, l.Close())
+
+	info, err := os.Stat(path)
+	require.NoError(t, err)
+	if filepath.Separator == '/' {
+		assert.Equal(t, os.FileMode(0600), info.Mode().Perm())
+	}
+
+	f, err := os.Open(path)
+	require.NoError(t, err)
+	defer f.Close()
+	var events []Event
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		var event Event
+		require.NoError(t, json.Unmarshal(scanner.Bytes(), &event))
+		events = append(events, event)
+	}
+	require.Len(t, events, 2)
+
+	assert.Equal(t, OperationEnroll, events[0].Operation)
+	assert.Equal(t, ResultSuccess, events[0].Result)
+	assert.Equal(t, "RSA", events[0].Parameters["keyType"])
+	assert.False(t, events[0].Time.IsZero())
+	assert.NotEmpty(t, events[0].Host)
+
+	assert.Equal(t, OperationInstall, events[1].Operation)
+	assert.Equal(t, ResultFailure, events[1].Result)
+	assert.Equal(t, "permission denied", events[1].Error)
+}
+
+func TestLog_Nil(t *testing.T) {
+	var l *Log
+	l.Record(Event{Operation: OperationRevoke})
+	assert.NoError(t, l.Close())
+}
+
+func TestOptions_IsValid(t *testing.T) {
+	for _, syslog := range []string{"", "local", "udp://syslog.example.com:514", "tcp://10.0.0.1:601"} {
+		assert.NoError(t, Options{Syslog: syslog}.IsValid(), syslog)
+	}
+	for _, syslog := range []string{"syslog.example.com:514", "http://syslog.example.com", "udp://"} {
+		assert.Error(t, Options{Syslog: syslog}.IsValid(), syslog)
+	}
+}
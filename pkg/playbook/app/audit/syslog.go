@@ -0,0 +1,30 @@
+//go:build !windows
+
+/*
+ * Copyright 2023 Venafi, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *  http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package audit
+
+import (
+	"io"
+	"log/syslog"
+)
+
+// dialSyslog connects to the syslog daemon at address, or to the local one when network is empty.
+// Events are sent with the auth facility, which syslog daemons usually keep apart for security messages
+func dialSyslog(network string, address string) (io.WriteCloser, error) {
+	return syslog.Dial(network, address, syslog.LOG_NOTICE|syslog.LOG_AUTH, "vcert")
+}
@@ -0,0 +1,242 @@
+/*
+ * Copyright 2023 Venafi, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *  http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+// Package condition evaluates the when expressions of the playbook tasks, so a playbook distributed to several hosts
+// runs the tasks relevant to each of them only.
+//
+// An expression compares strings with == and != or matches them against a regular expression with =~, and combines
+// the comparisons with &&, || and !, grouped by parentheses. Strings are quoted with double or single quotes.
+// The variables os, arch and hostname hold the operating system, the architecture and the host name vcert runs on,
+// env("NAME") returns the value of an environment variable and exists("/path") tells whether a file exists:
+//
+//	os == "linux" && env("ROLE") == "edge"
+//	hostname =~ "^web-[0-9]+$" || exists("/etc/nginx/nginx.conf")
+package condition
+
+import (
+	"fmt"
+	"os"
+	"regexp"
+	"runtime"
+)
+
+// Environment holds the values an expression is evaluated against
+type Environment struct {
+	// Variables holds the value of os, arch and hostname
+	Variables map[string]string
+	// Getenv returns the value of an environment variable
+	Getenv func(name string) string
+	// FileExists returns true when the file at path exists
+	FileExists func(path string) bool
+}
+
+// HostEnvironment returns the Environment of the host vcert runs on
+func HostEnvironment() Environment {
+	hostname, _ := os.Hostname()
+	return Environment{
+		Variables: map[string]string{
+			varOS:       runtime.GOOS,
+			varArch:     runtime.GOARCH,
+			varHostname: hostname,
+		},
+		Getenv: os.Getenv,
+		FileExists: func(path string) bool {
+			_, err := os.Stat(path)
+			return err == nil
+		},
+	}
+}
+
+const (
+	varOS       = "os"
+	varArch     = "arch"
+	varHostname = "hostname"
+
+	funcEnv    = "env"
+	funcExists = "exists"
+)
+
+// Condition is a parsed when expression
+type Condition struct {
+	expression string
+	root       node
+}
+
+// Parse parses expression, returning an error when it is malformed or refers to an unknown variable or function
+func Parse(expression string) (*Condition, error) {
+	p := &parser{expression: expression}
+	err := p.tokenize()
+	if err != nil {
+		return nil, err
+	}
+	if len(p.tokens) == 0 {
+		return nil, fmt.Errorf("empty expression")
+	}
+	root, err := p.parseOr()
+	if err != nil {
+		return nil, err
+	}
+	if p.pos < len(p.tokens) {
+		return nil, fmt.Errorf("unexpected %s at position %d", p.tokens[p.pos], p.tokens[p.pos].pos+1)
+	}
+	if root.isString() {
+		return nil, fmt.Errorf("expression %q is not a condition", expression)
+	}
+	return &Condition{expression: expression, root: root}, nil
+}
+
+// Evaluate returns the result of the condition in env
+func (c *Condition) Evaluate(env Environment) (bool, error) {
+	v, err := c.root.eval(env)
+	if err != nil {
+		return false, fmt.Errorf("could not evaluate %q: %w", c.expression, err)
+	}
+	return v.(bool), nil
+}
+
+// String returns the expression of the condition
+func (c *Condition) String() string {
+	return c.expression
+}
+
+// node is an operation of the expression. It evaluates to a string or a bool
+type node interface {
+	eval(env Environment) (interface{}, error)
+	// isString returns true when the node evaluates to a string
+	isString() bool
+}
+
+type literalNode struct {
+	value interface{}
+}
+
+func (n literalNode) eval(_ Environment) (interface{}, error) {
+	return n.value, nil
+}
+
+func (n literalNode) isString() bool {
+	_, ok := n.value.(string)
+	return ok
+}
+
+type variableNode struct {
+	name string
+}
+
+func (n variableNode) eval(env Environment) (interface{}, error) {
+	return env.Variables[n.name], nil
+}
+
+func (n variableNode) isString() bool {
+	return true
+}
+
+type callNode struct {
+	name string
+	arg  node
+}
+
+func (n callNode) eval(env Environment) (interface{}, error) {
+	v, err := n.arg.eval(env)
+	if err != nil {
+		return nil, err
+	}
+	arg := v.(string)
+	if n.name == funcEnv {
+		if env.Getenv == nil {
+			return "", nil
+		}
+		return env.Getenv(arg), nil
+	}
+	return env.FileExists != nil && env.FileExists(arg), nil
+}
+
+func (n callNode) isString() bool {
+	return n.name == funcEnv
+}
+
+type notNode struct {
+	operand node
+}
+
+func (n notNode) eval(env Environment) (interface{}, error) {
+	v, err := n.operand.eval(env)
+	if err != nil {
+		return nil, err
+	}
+	return !v.(bool), nil
+}
+
+func (n notNode) isString() bool {
+	return false
+}
+
+// logicalNode is a && or || operation. The right operand is not evaluated when the left one decides the result
+type logicalNode struct {
+	and         bool
+	left, right node
+}
+
+func (n logicalNode) eval(env Environment) (interface{}, error) {
+	v, err := n.left.eval(env)
+	if err != nil {
+		return nil, err
+	}
+	if v.(bool) != n.and {
+		return v, nil
+	}
+	return n.right.eval(env)
+}
+
+func (n logicalNode) isString() bool {
+	return false
+}
+
+type comparisonNode struct {
+	operator    string
+	left, right node
+	// pattern is the compiled regular expression of =~ when its right operand is a literal
+	pattern *regexp.Regexp
+}
+
+func (n comparisonNode) eval(env Environment) (interface{}, error) {
+	left, err := n.left.eval(env)
+	if err != nil {
+		return nil, err
+	}
+	right, err := n.right.eval(env)
+	if err != nil {
+		return nil, err
+	}
+	switch n.operator {
+	case opEqual:
+		return left == right, nil
+	case opNotEqual:
+		return left != right, nil
+	}
+	pattern := n.pattern
+	if pattern == nil {
+		pattern, err = regexp.Compile(right.(string))
+		if err != nil {
+			return nil, fmt.Errorf("invalid regular expression %q: %w", right, err)
+		}
+	}
+	return pattern.MatchString(left.(string)), nil
+}
+
+func (n comparisonNode) isString() bool {
+	return false
+}
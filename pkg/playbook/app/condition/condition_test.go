@@ -0,0 +1,107 @@
+/*
+ * Copyright 2023 Venafi, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *  http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package condition
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/suite"
+)
+
+type ConditionSuite struct {
+	suite.Suite
+	env Environment
+}
+
+func (s *ConditionSuite) SetupTest() {
+	s.env = Environment{
+		Variables: map[string]string{varOS: "linux", varArch: "amd64", varHostname: "web-01"},
+		Getenv: func(name string) string {
+			return map[string]string{"ROLE": "edge", "PATTERN": "^ed"}[name]
+		},
+		FileExists: func(path string) bool {
+			return path == "/etc/nginx/nginx.conf"
+		},
+	}
+}
+
+func TestCondition(t *testing.T) {
+	suite.Run(t, new(ConditionSuite))
+}
+
+func (s *ConditionSuite) TestCondition_Evaluate() {
+	testCases := []struct {
+		name       string
+		expression string
+		expected   bool
+	}{
+		{name: "Equal", expression: `os == "linux"`, expected: true},
+		{name: "NotEqual", expression: `os != 'linux'`, expected: false},
+		{name: "And", expression: `os == "linux" && env("ROLE") == "edge"`, expected: true},
+		{name: "AndFalse", expression: `os == "linux" && env("ROLE") == "core"`, expected: false},
+		{name: "Or", expression: `os == "windows" || arch == "amd64"`, expected: true},
+		{name: "Precedence", expression: `os == "windows" && arch == "arm64" || hostname == "web-01"`, expected: true},
+		{name: "Parentheses", expression: `os == "windows" && (arch == "arm64" || hostname == "web-01")`, expected: false},
+		{name: "Not", expression: `!(os == "windows")`, expected: true},
+		{name: "Match", expression: `hostname =~ "^web-[0-9]+$"`, expected: true},
+		{name: "MatchVariable", expression: `env("ROLE") =~ env("PATTERN")`, expected: true},
+		{name: "Exists", expression: `exists("/etc/nginx/nginx.conf")`, expected: true},
+		{name: "NotExists", expression: `!exists("/etc/apache2/apache2.conf")`, expected: true},
+		{name: "UnsetEnv", expression: `env("MISSING") == ""`, expected: true},
+		{name: "Literal", expression: `false || true`, expected: true},
+		{name: "BoolComparison", expression: `exists("/etc/nginx/nginx.conf") == true`, expected: true},
+		{name: "EscapedQuote", expression: `"a\"b" == 'a"b'`, expected: true},
+	}
+
+	for _, tc := range testCases {
+		s.Run(tc.name, func() {
+			c, err := Parse(tc.expression)
+			s.Require().NoError(err)
+			result, err := c.Evaluate(s.env)
+			s.Require().NoError(err)
+			s.Equal(tc.expected, result)
+		})
+	}
+}
+
+func (s *ConditionSuite) TestParse_Invalid() {
+	testCases := []struct {
+		name       string
+		expression string
+		errMsg     string
+	}{
+		{name: "Empty", expression: "  ", errMsg: "empty expression"},
+		{name: "UnknownVariable", expression: `platform == "linux"`, errMsg: "unknown variable or function 'platform'"},
+		{name: "UnterminatedString", expression: `os == "linux`, errMsg: "unterminated string"},
+		{name: "MissingParenthesis", expression: `(os == "linux"`, errMsg: "missing ')'"},
+		{name: "TrailingToken", expression: `os == "linux" "windows"`, errMsg: "unexpected \"windows\""},
+		{name: "String", expression: `os`, errMsg: "is not a condition"},
+		{name: "StringOperand", expression: `os && arch == "amd64"`, errMsg: "must be conditions"},
+		{name: "MixedComparison", expression: `os == true`, errMsg: "both be strings"},
+		{name: "InvalidPattern", expression: `os =~ "[a-"`, errMsg: "invalid regular expression"},
+		{name: "UnexpectedCharacter", expression: `os = "linux"`, errMsg: "unexpected character '='"},
+		{name: "TooManyArguments", expression: `env("A", "B") == ""`, errMsg: "expected ')'"},
+	}
+
+	for _, tc := range testCases {
+		s.Run(tc.name, func() {
+			_, err := Parse(tc.expression)
+			s.Require().Error(err)
+			s.Contains(err.Error(), tc.errMsg)
+		})
+	}
+}
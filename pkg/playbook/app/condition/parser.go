@@ -0,0 +1,298 @@
+/*
+ * Copyright 2023 Venafi, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *  http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package condition
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+	"unicode"
+)
+
+const (
+	opAnd      = "&&"
+	opOr       = "||"
+	opNot      = "!"
+	opEqual    = "=="
+	opNotEqual = "!="
+	opMatch    = "=~"
+)
+
+type tokenKind int
+
+const (
+	tokenOperator tokenKind = iota
+	tokenString
+	tokenIdentifier
+	tokenOpenParen
+	tokenCloseParen
+	tokenComma
+)
+
+type token struct {
+	kind  tokenKind
+	value string
+	pos   int
+}
+
+// String returns the token as written in the expression, for error messages
+func (t token) String() string {
+	if t.kind == tokenString {
+		return fmt.Sprintf("%q", t.value)
+	}
+	return fmt.Sprintf("'%s'", t.value)
+}
+
+type parser struct {
+	expression string
+	tokens     []token
+	pos        int
+}
+
+// tokenize splits the expression into tokens
+func (p *parser) tokenize() error {
+	s := p.expression
+	for i := 0; i < len(s); {
+		c := rune(s[i])
+		switch {
+		case unicode.IsSpace(c):
+			i++
+		case c == '(':
+			p.tokens = append(p.tokens, token{kind: tokenOpenParen, value: "(", pos: i})
+			i++
+		case c == ')':
+			p.tokens = append(p.tokens, token{kind: tokenCloseParen, value: ")", pos: i})
+			i++
+		case c == ',':
+			p.tokens = append(p.tokens, token{kind: tokenComma, value: ",", pos: i})
+			i++
+		case c == '"' || c == '\'':
+			value, end, err := readString(s, i)
+			if err != nil {
+				return err
+			}
+			p.tokens = append(p.tokens, token{kind: tokenString, value: value, pos: i})
+			i = end
+		case isIdentifierRune(c):
+			start := i
+			for i < len(s) && (isIdentifierRune(rune(s[i])) || unicode.IsDigit(rune(s[i]))) {
+				i++
+			}
+			p.tokens = append(p.tokens, token{kind: tokenIdentifier, value: s[start:i], pos: start})
+		default:
+			operator := ""
+			for _, op := range []string{opAnd, opOr, opEqual, opNotEqual, opMatch, opNot} {
+				if strings.HasPrefix(s[i:], op) {
+					operator = op
+					break
+				}
+			}
+			if operator == "" {
+				return fmt.Errorf("unexpected character %q at position %d", c, i+1)
+			}
+			p.tokens = append(p.tokens, token{kind: tokenOperator, value: operator, pos: i})
+			i += len(operator)
+		}
+	}
+	return nil
+}
+
+// readString reads the string quoted at s[start], returning its value and the position after its closing quote.
+// A backslash escapes the next character
+func readString(s string, start int) (string, int, error) {
+	quote := s[start]
+	var b strings.Builder
+	for i := start + 1; i < len(s); i++ {
+		switch s[i] {
+		case '\\':
+			if i+1 < len(s) {
+				i++
+				b.WriteByte(s[i])
+			}
+		case quote:
+			return b.String(), i + 1, nil
+		default:
+			b.WriteByte(s[i])
+		}
+	}
+	return "", 0, fmt.Errorf("unterminated string at position %d", start+1)
+}
+
+func isIdentifierRune(c rune) bool {
+	return c == '_' || (c < unicode.MaxASCII && unicode.IsLetter(c))
+}
+
+func (p *parser) peek() *token {
+	if p.pos < len(p.tokens) {
+		return &p.tokens[p.pos]
+	}
+	return nil
+}
+
+func (p *parser) peekOperator(operators ...string) string {
+	t := p.peek()
+	if t == nil || t.kind != tokenOperator {
+		return ""
+	}
+	for _, op := range operators {
+		if t.value == op {
+			return op
+		}
+	}
+	return ""
+}
+
+func (p *parser) expect(kind tokenKind, value string) error {
+	t := p.peek()
+	if t == nil {
+		return fmt.Errorf("missing '%s' at the end of the expression", value)
+	}
+	if t.kind != kind {
+		return fmt.Errorf("expected '%s' at position %d, found %s", value, t.pos+1, t)
+	}
+	p.pos++
+	return nil
+}
+
+// parseOr parses: and ('||' and)*
+func (p *parser) parseOr() (node, error) {
+	return p.parseLogical(opOr, p.parseAnd)
+}
+
+// parseAnd parses: unary ('&&' unary)*
+func (p *parser) parseAnd() (node, error) {
+	return p.parseLogical(opAnd, p.parseUnary)
+}
+
+func (p *parser) parseLogical(operator string, parseOperand func() (node, error)) (node, error) {
+	left, err := parseOperand()
+	if err != nil {
+		return nil, err
+	}
+	for p.peekOperator(operator) != "" {
+		p.pos++
+		right, err := parseOperand()
+		if err != nil {
+			return nil, err
+		}
+		if left.isString() || right.isString() {
+			return nil, fmt.Errorf("the operands of '%s' must be conditions, not strings", operator)
+		}
+		left = logicalNode{and: operator == opAnd, left: left, right: right}
+	}
+	return left, nil
+}
+
+// parseUnary parses: '!' unary | comparison
+func (p *parser) parseUnary() (node, error) {
+	if p.peekOperator(opNot) != "" {
+		p.pos++
+		operand, err := p.parseUnary()
+		if err != nil {
+			return nil, err
+		}
+		if operand.isString() {
+			return nil, fmt.Errorf("the operand of '!' must be a condition, not a string")
+		}
+		return notNode{operand: operand}, nil
+	}
+	return p.parseComparison()
+}
+
+// parseComparison parses: primary (('==' | '!=' | '=~') primary)?
+func (p *parser) parseComparison() (node, error) {
+	left, err := p.parsePrimary()
+	if err != nil {
+		return nil, err
+	}
+	operator := p.peekOperator(opEqual, opNotEqual, opMatch)
+	if operator == "" {
+		return left, nil
+	}
+	p.pos++
+	right, err := p.parsePrimary()
+	if err != nil {
+		return nil, err
+	}
+
+	comparison := comparisonNode{operator: operator, left: left, right: right}
+	if operator == opMatch {
+		if !left.isString() || !right.isString() {
+			return nil, fmt.Errorf("the operands of '%s' must be strings", operator)
+		}
+		if literal, ok := right.(literalNode); ok {
+			comparison.pattern, err = regexp.Compile(literal.value.(string))
+			if err != nil {
+				return nil, fmt.Errorf("invalid regular expression %q: %w", literal.value, err)
+			}
+		}
+	} else if left.isString() != right.isString() {
+		return nil, fmt.Errorf("the operands of '%s' must both be strings or both be conditions", operator)
+	}
+	return comparison, nil
+}
+
+// parsePrimary parses: '(' or ')' | string | 'true' | 'false' | variable | function '(' or ')'
+func (p *parser) parsePrimary() (node, error) {
+	t := p.peek()
+	if t == nil {
+		return nil, fmt.Errorf("unexpected end of the expression")
+	}
+	p.pos++
+	switch t.kind {
+	case tokenString:
+		return literalNode{value: t.value}, nil
+	case tokenOpenParen:
+		n, err := p.parseOr()
+		if err != nil {
+			return nil, err
+		}
+		return n, p.expect(tokenCloseParen, ")")
+	case tokenIdentifier:
+		switch t.value {
+		case "true":
+			return literalNode{value: true}, nil
+		case "false":
+			return literalNode{value: false}, nil
+		case varOS, varArch, varHostname:
+			return variableNode{name: t.value}, nil
+		case funcEnv, funcExists:
+			return p.parseCall(t.value)
+		}
+		return nil, fmt.Errorf("unknown variable or function '%s' at position %d. Should be one of %s, %s, %s, %s() or %s()",
+			t.value, t.pos+1, varOS, varArch, varHostname, funcEnv, funcExists)
+	}
+	return nil, fmt.Errorf("unexpected %s at position %d", t, t.pos+1)
+}
+
+// parseCall parses the argument of a function, which takes a single string
+func (p *parser) parseCall(name string) (node, error) {
+	if err := p.expect(tokenOpenParen, "("); err != nil {
+		return nil, err
+	}
+	arg, err := p.parseOr()
+	if err != nil {
+		return nil, err
+	}
+	if !arg.isString() {
+		return nil, fmt.Errorf("the argument of %s() must be a string", name)
+	}
+	if err = p.expect(tokenCloseParen, ")"); err != nil {
+		return nil, err
+	}
+	return callNode{name: name, arg: arg}, nil
+}
@@ -0,0 +1,179 @@
+/*
+ * Copyright 2023 Venafi, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *  http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package domain
+
+import (
+	"fmt"
+	"net/url"
+	"regexp"
+	"runtime"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// serviceNameRegex restricts service and systemd unit names to the characters allowed by systemd and the Windows SCM,
+// so they can be passed safely to systemctl and PowerShell
+var serviceNameRegex = regexp.MustCompile(`^[A-Za-z0-9@:_\-\.]+$`)
+
+// AfterInstallAction is an action run after a certificate is installed. Exactly one of its fields must be set.
+//
+// In a playbook, an action is either a string, which is run as a script, or a mapping with one of the
+// restartService, reloadSystemdUnit, restartIISSite, httpPost or script keys
+type AfterInstallAction struct {
+	// HTTPPost sends a POST request, i.e. to notify a webhook
+	HTTPPost *HTTPPostAction `yaml:"httpPost,omitempty"`
+	// ReloadSystemdUnit is the name of the systemd unit to reload with systemctl. Not supported on Windows
+	ReloadSystemdUnit string `yaml:"reloadSystemdUnit,omitempty"`
+	// RestartIISSite is the name of the IIS site to stop and start again. Only supported on Windows
+	RestartIISSite string `yaml:"restartIISSite,omitempty"`
+	// RestartService is the name of the service to restart, using systemctl on *nix and the service manager on Windows
+	RestartService string `yaml:"restartService,omitempty"`
+	// Script is run with /bin/sh on *nix and with powershell on Windows
+	Script string `yaml:"script,omitempty"`
+}
+
+// HTTPPostAction describes the request sent by an httpPost after-install action.
+// Environment variables in the form $VAR or ${VAR} are expanded in the Headers and the Body
+type HTTPPostAction struct {
+	Body    string            `yaml:"body,omitempty"`
+	Headers map[string]string `yaml:"headers,omitempty"`
+	URL     string            `yaml:"url"`
+}
+
+// afterInstallActionFields has the fields of AfterInstallAction without its yaml (un)marshalling methods
+type afterInstallActionFields AfterInstallAction
+
+// AfterInstallActions is the list of actions run, in order, after a certificate is installed
+type AfterInstallActions []AfterInstallAction
+
+// String returns a description of the action, suitable for logs
+func (a AfterInstallAction) String() string {
+	switch {
+	case a.HTTPPost != nil:
+		return fmt.Sprintf("httpPost: %s", a.HTTPPost.URL)
+	case a.ReloadSystemdUnit != "":
+		return fmt.Sprintf("reloadSystemdUnit: %s", a.ReloadSystemdUnit)
+	case a.RestartIISSite != "":
+		return fmt.Sprintf("restartIISSite: %s", a.RestartIISSite)
+	case a.RestartService != "":
+		return fmt.Sprintf("restartService: %s", a.RestartService)
+	default:
+		return a.Script
+	}
+}
+
+// IsValid returns an error if the action does not define exactly one operation,
+// or the operation is not supported in the current platform
+func (a AfterInstallAction) IsValid() error {
+	defined := 0
+	for _, isSet := range []bool{a.HTTPPost != nil, a.ReloadSystemdUnit != "", a.RestartIISSite != "", a.RestartService != "", a.Script != ""} {
+		if isSet {
+			defined++
+		}
+	}
+	if defined != 1 {
+		return ErrInvalidAfterInstallAction
+	}
+
+	switch {
+	case a.HTTPPost != nil:
+		u, err := url.Parse(a.HTTPPost.URL)
+		if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+			return ErrInvalidHTTPPostURL
+		}
+	case a.ReloadSystemdUnit != "":
+		if runtime.GOOS == "windows" {
+			return ErrReloadSystemdUnitOnWindows
+		}
+		if !serviceNameRegex.MatchString(a.ReloadSystemdUnit) {
+			return ErrInvalidServiceName
+		}
+	case a.RestartIISSite != "":
+		if runtime.GOOS != "windows" {
+			return ErrRestartIISSiteOnNonWindows
+		}
+		if strings.TrimSpace(a.RestartIISSite) == "" || !capiValueRegex.MatchString(a.RestartIISSite) {
+			return ErrInvalidIISSiteName
+		}
+	case a.RestartService != "":
+		if !serviceNameRegex.MatchString(a.RestartService) {
+			return ErrInvalidServiceName
+		}
+	}
+	return nil
+}
+
+// MarshalYAML writes the actions that only define a script as a plain string, like afterInstallAction used to be.
+// The returned value is marshaled in place of the original value implementing Marshaller
+func (a AfterInstallAction) MarshalYAML() (interface{}, error) {
+	if a == (AfterInstallAction{Script: a.Script}) {
+		return a.Script, nil
+	}
+	return afterInstallActionFields(a), nil
+}
+
+// UnmarshalYAML reads an action written either as a script string or as a mapping
+func (a *AfterInstallAction) UnmarshalYAML(value *yaml.Node) error {
+	if value.Kind == yaml.ScalarNode {
+		*a = AfterInstallAction{}
+		return value.Decode(&a.Script)
+	}
+	return value.Decode((*afterInstallActionFields)(a))
+}
+
+// String returns a description of the actions, suitable for logs
+func (actions AfterInstallActions) String() string {
+	descriptions := make([]string, 0, len(actions))
+	for _, a := range actions {
+		descriptions = append(descriptions, a.String())
+	}
+	return strings.Join(descriptions, "; ")
+}
+
+// IsValid returns an error if any of the actions is not valid
+func (actions AfterInstallActions) IsValid() error {
+	for _, a := range actions {
+		if err := a.IsValid(); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// MarshalYAML writes a single action without the enclosing list.
+// The returned value is marshaled in place of the original value implementing Marshaller
+func (actions AfterInstallActions) MarshalYAML() (interface{}, error) {
+	if len(actions) == 1 {
+		return actions[0], nil
+	}
+	return []AfterInstallAction(actions), nil
+}
+
+// UnmarshalYAML reads either a list of actions or a single action
+func (actions *AfterInstallActions) UnmarshalYAML(value *yaml.Node) error {
+	if value.Kind == yaml.SequenceNode {
+		return value.Decode((*[]AfterInstallAction)(actions))
+	}
+	var a AfterInstallAction
+	err := value.Decode(&a)
+	if err != nil {
+		return err
+	}
+	*actions = AfterInstallActions{a}
+	return nil
+}
@@ -0,0 +1,109 @@
+/*
+ * Copyright 2023 Venafi, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *  http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package domain
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/suite"
+	"gopkg.in/yaml.v3"
+)
+
+type AfterInstallActionSuite struct {
+	suite.Suite
+	testCases []struct {
+		name     string
+		yaml     string
+		expected AfterInstallActions
+	}
+}
+
+func (s *AfterInstallActionSuite) SetupTest() {
+	s.testCases = []struct {
+		name     string
+		yaml     string
+		expected AfterInstallActions
+	}{
+		{
+			name:     "Script",
+			yaml:     `afterInstallAction: systemctl reload nginx` + "\n",
+			expected: AfterInstallActions{{Script: "systemctl reload nginx"}},
+		},
+		{
+			name:     "SingleAction",
+			yaml:     "afterInstallAction:\n    restartService: nginx\n",
+			expected: AfterInstallActions{{RestartService: "nginx"}},
+		},
+		{
+			name: "ActionList",
+			yaml: `afterInstallAction:
+    - reloadSystemdUnit: haproxy.service
+    - echo done
+    - httpPost:
+        body: '{"thumbprint":"${VCERT_TASK_THUMBPRINT}"}'
+        headers:
+            Authorization: Bearer ${HOOK_TOKEN}
+        url: https://hooks.example.com/certs
+`,
+			expected: AfterInstallActions{
+				{ReloadSystemdUnit: "haproxy.service"},
+				{Script: "echo done"},
+				{HTTPPost: &HTTPPostAction{
+					Body:    `{"thumbprint":"${VCERT_TASK_THUMBPRINT}"}`,
+					Headers: map[string]string{"Authorization": "Bearer ${HOOK_TOKEN}"},
+					URL:     "https://hooks.example.com/certs",
+				}},
+			},
+		},
+	}
+}
+
+func TestAfterInstallAction(t *testing.T) {
+	suite.Run(t, new(AfterInstallActionSuite))
+}
+
+func (s *AfterInstallActionSuite) TestAfterInstallAction_UnmarshalYAML() {
+	for _, tc := range s.testCases {
+		s.Run(tc.name, func() {
+			var i Installation
+			err := yaml.Unmarshal([]byte(tc.yaml), &i)
+
+			s.Nil(err)
+			s.Equal(tc.expected, i.AfterAction)
+		})
+	}
+}
+
+func (s *AfterInstallActionSuite) TestAfterInstallAction_MarshalYAML() {
+	for _, tc := range s.testCases {
+		s.Run(tc.name, func() {
+			data, err := yaml.Marshal(Installation{AfterAction: tc.expected})
+
+			s.Nil(err)
+			s.Equal(tc.yaml, string(data))
+		})
+	}
+}
+
+func (s *AfterInstallActionSuite) TestAfterInstallAction_IsValid() {
+	s.Nil(AfterInstallActions{{RestartService: "nginx"}, {Script: "echo done"}}.IsValid())
+	s.ErrorIs(AfterInstallActions{{}}.IsValid(), ErrInvalidAfterInstallAction)
+	s.ErrorIs(AfterInstallActions{{RestartService: "nginx", Script: "echo done"}}.IsValid(), ErrInvalidAfterInstallAction)
+	s.ErrorIs(AfterInstallActions{{RestartService: "nginx'; rm -rf /"}}.IsValid(), ErrInvalidServiceName)
+	s.ErrorIs(AfterInstallActions{{HTTPPost: &HTTPPostAction{URL: "ftp://hooks.example.com"}}}.IsValid(), ErrInvalidHTTPPostURL)
+	s.ErrorIs(AfterInstallActions{{HTTPPost: &HTTPPostAction{}}}.IsValid(), ErrInvalidHTTPPostURL)
+}
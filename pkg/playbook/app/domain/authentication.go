@@ -25,21 +25,58 @@ import (
 )
 
 const (
-	accessToken  = "accessToken"
-	apiKey       = "apiKey"
-	clientID     = "clientId"
-	clientSecret = "clientSecret"
-	refreshToken = "refreshToken"
-	p12Task      = "p12Task"
-	scope        = "scope"
-	idPTokenURL  = "tokenURL"
-	idPAudience  = "audience"
+	accessToken       = "accessToken"
+	acmeAccount       = "acme"
+	apiKey            = "apiKey"
+	clientCertFile    = "clientCertFile"
+	clientID          = "clientId"
+	clientKeyFile     = "clientKeyFile"
+	clientP12File     = "clientP12File"
+	clientP12Password = "clientP12Password"
+	clientSecret      = "clientSecret"
+	refreshToken      = "refreshToken"
+	p12Task           = "p12Task"
+	password          = "password"
+	scope             = "scope"
+	user              = "user"
+	idPTokenURL       = "tokenURL"
+	idPAudience       = "audience"
 )
 
 // Authentication holds the credentials to connect to Venafi platforms: TPP and TLSPC
 type Authentication struct {
 	endpoint.Authentication `yaml:"-"`
-	P12Task                 string `yaml:"p12Task,omitempty"`
+	// ClientCertFile is the PEM client certificate used for mutual TLS and the TPP certificate token grant
+	ClientCertFile string `yaml:"clientCertFile,omitempty"`
+	// ClientKeyFile is the PEM private key of ClientCertFile
+	ClientKeyFile string `yaml:"clientKeyFile,omitempty"`
+	// ClientP12File is a PKCS#12 archive with the client certificate, as an alternative to ClientCertFile and ClientKeyFile
+	ClientP12File     string `yaml:"clientP12File,omitempty"`
+	ClientP12Password string `yaml:"clientP12Password,omitempty"`
+	P12Task           string `yaml:"p12Task,omitempty"`
+}
+
+// HasClientCertificate returns true when a client certificate is set to authenticate, either from files
+// or from the PKCS#12 installation of a certificate task
+func (a Authentication) HasClientCertificate() bool {
+	return a.ClientCertFile != "" || a.ClientP12File != "" || a.P12Task != ""
+}
+
+// validateClientCertificate checks the client certificate files are complete and only one source is set
+func (a Authentication) validateClientCertificate() error {
+	if (a.ClientCertFile == "") != (a.ClientKeyFile == "") {
+		return ErrIncompleteClientCertificate
+	}
+	sources := 0
+	for _, source := range []string{a.ClientCertFile, a.ClientP12File, a.P12Task} {
+		if source != "" {
+			sources++
+		}
+	}
+	if sources > 1 {
+		return ErrMultipleClientCertificates
+	}
+	return nil
 }
 
 // MarshalYAML customizes the behavior of Authentication when being marshaled into a YAML document.
@@ -50,12 +87,27 @@ func (a Authentication) MarshalYAML() (interface{}, error) {
 	if a.AccessToken != "" {
 		values[accessToken] = a.AccessToken
 	}
+	if a.ACMEAccount != nil {
+		values[acmeAccount] = a.ACMEAccount
+	}
 	if a.APIKey != "" {
 		values[apiKey] = a.APIKey
 	}
+	if a.ClientCertFile != "" {
+		values[clientCertFile] = a.ClientCertFile
+	}
 	if a.ClientId != "" {
 		values[clientID] = a.ClientId
 	}
+	if a.ClientKeyFile != "" {
+		values[clientKeyFile] = a.ClientKeyFile
+	}
+	if a.ClientP12File != "" {
+		values[clientP12File] = a.ClientP12File
+	}
+	if a.ClientP12Password != "" {
+		values[clientP12Password] = a.ClientP12Password
+	}
 	if a.ClientSecret != "" {
 		values[clientSecret] = a.ClientSecret
 	}
@@ -74,9 +126,15 @@ func (a Authentication) MarshalYAML() (interface{}, error) {
 	if a.P12Task != "" {
 		values[p12Task] = a.P12Task
 	}
+	if a.Password != "" {
+		values[password] = a.Password
+	}
 	if a.Scope != "" {
 		values[scope] = a.Scope
 	}
+	if a.User != "" {
+		values[user] = a.User
+	}
 
 	return values, nil
 }
@@ -95,9 +153,21 @@ func (a *Authentication) UnmarshalYAML(value *yaml.Node) error {
 	if val, found := authMap[apiKey]; found {
 		a.APIKey = val.(string)
 	}
+	if val, found := authMap[clientCertFile]; found {
+		a.ClientCertFile = val.(string)
+	}
 	if val, found := authMap[clientID]; found {
 		a.ClientId = val.(string)
 	}
+	if val, found := authMap[clientKeyFile]; found {
+		a.ClientKeyFile = val.(string)
+	}
+	if val, found := authMap[clientP12File]; found {
+		a.ClientP12File = val.(string)
+	}
+	if val, found := authMap[clientP12Password]; found {
+		a.ClientP12Password = val.(string)
+	}
 	if val, found := authMap[clientSecret]; found {
 		a.ClientSecret = val.(string)
 	}
@@ -110,6 +180,12 @@ func (a *Authentication) UnmarshalYAML(value *yaml.Node) error {
 	if val, found := authMap[scope]; found {
 		a.Scope = val.(string)
 	}
+	if val, found := authMap[user]; found {
+		a.User = val.(string)
+	}
+	if val, found := authMap[password]; found {
+		a.Password = val.(string)
+	}
 
 	provider, err := unmarshallIdP(authMap)
 	if err != nil {
@@ -117,6 +193,17 @@ func (a *Authentication) UnmarshalYAML(value *yaml.Node) error {
 	}
 	a.IdentityProvider = provider
 
+	if _, found := authMap[acmeAccount]; found {
+		acme := struct {
+			Account *endpoint.ACMEAccount `yaml:"acme"`
+		}{}
+		err = value.Decode(&acme)
+		if err != nil {
+			return err
+		}
+		a.ACMEAccount = acme.Account
+	}
+
 	return nil
 }
 
@@ -19,37 +19,254 @@ package domain
 import (
 	"errors"
 	"fmt"
+	"strings"
+	"time"
+
+	"github.com/Venafi/vcert/v5/pkg/certificate"
+	"github.com/Venafi/vcert/v5/pkg/playbook/app/condition"
+	"github.com/Venafi/vcert/v5/pkg/playbook/app/scheduler"
+	"github.com/Venafi/vcert/v5/pkg/tpm"
 )
 
 // CertificateTask represents a task to be run:
-// A certificate to be requested/renewed and installed in one (or more) location(s)
+// A certificate to be requested/renewed and installed in one (or more) location(s), a certificate to be revoked,
+// or an SSH certificate to be requested/renewed and installed
 type CertificateTask struct {
-	Name          string          `yaml:"name,omitempty"`
+	Name string `yaml:"name,omitempty"`
+	// Action is one of ActionEnroll, the default, ActionRevoke or ActionSSHCertificate
+	Action string `yaml:"action,omitempty"`
+	// Connection is the name of the connection of config.connection the task uses. Defaults to the first connection
+	Connection    string          `yaml:"connection,omitempty"`
 	Request       PlaybookRequest `yaml:"request,omitempty"`
 	Installations Installations   `yaml:"installations,omitempty"`
 	RenewBefore   string          `yaml:"renewBefore,omitempty"`
+	Schedule      string          `yaml:"schedule,omitempty"`
 	SetEnvVars    []string        `yaml:"setEnvVars,omitempty"`
+	Retries       int             `yaml:"retries,omitempty"`
+	Backoff       string          `yaml:"backoff,omitempty"`
+	// Timeout bounds the whole run of the task, as a duration. The task is cancelled when it runs longer
+	Timeout string `yaml:"timeout,omitempty"`
+	// Revoke identifies the certificate to revoke when Action is ActionRevoke
+	Revoke RevokeRequest `yaml:"revoke,omitempty"`
+	// SSH defines the SSH certificate to request when Action is ActionSSHCertificate
+	SSH SSHRequest `yaml:"ssh,omitempty"`
+	// When is an expression the task only runs on hosts it is true for, i.e. os == "linux" && env("ROLE") == "edge"
+	When string `yaml:"when,omitempty"`
+	// Adopt searches the Venafi platform for the certificate found installed on the first run of the task, and records
+	// it in the state file instead of requesting a new one. The certificate adopted is renewed when it expires
+	Adopt bool `yaml:"adopt,omitempty"`
 }
 
 // CertificateTasks is a slice of CertificateTask
 type CertificateTasks []CertificateTask
 
+// IsRevocation returns true when the task revokes a certificate instead of requesting one
+func (task CertificateTask) IsRevocation() bool {
+	return strings.EqualFold(task.Action, ActionRevoke)
+}
+
+// IsSSHCertificate returns true when the task requests an SSH certificate instead of an X.509 one
+func (task CertificateTask) IsSSHCertificate() bool {
+	return strings.EqualFold(task.Action, ActionSSHCertificate)
+}
+
+// GetTimeout returns the Timeout of the task, or 0 when the task has no timeout
+func (task CertificateTask) GetTimeout() time.Duration {
+	// The timeout is checked when the playbook is validated
+	timeout, err := time.ParseDuration(task.Timeout)
+	if err != nil || timeout < 0 {
+		return 0
+	}
+	return timeout
+}
+
+// GetCondition returns the parsed When expression of the task, or nil when the task always runs
+func (task CertificateTask) GetCondition() (*condition.Condition, error) {
+	if strings.TrimSpace(task.When) == "" {
+		return nil, nil
+	}
+	return condition.Parse(task.When)
+}
+
 // IsValid returns true if the CertificateTask has the minimum required fields to be run
 func (task CertificateTask) IsValid() (bool, error) {
+	rValid, rErr := task.isValidAction()
+	if _, err := task.GetCondition(); err != nil {
+		rValid = false
+		rErr = errors.Join(rErr, fmt.Errorf("\t\t%w: %w", ErrInvalidWhen, err))
+	}
+	return rValid, rErr
+}
+
+// isValidAction validates the fields of the task used by its action
+func (task CertificateTask) isValidAction() (bool, error) {
+	if task.IsRevocation() {
+		return task.isValidRevocation()
+	}
+	if task.IsSSHCertificate() {
+		return task.isValidSSHCertificate()
+	}
+	if task.Action != "" && !strings.EqualFold(task.Action, ActionEnroll) {
+		return false, fmt.Errorf("\t\t%w", ErrInvalidTaskAction)
+	}
+
 	var rErr error = nil
 	rValid := true
 
-	// Each certificate request needs a zone, required field
-	if task.Request.Zone == "" {
+	// Each certificate request needs a zone, required field. Additional zones are used for failover
+	if len(task.Request.GetZones()) == 0 {
 		rValid = false
 		rErr = errors.Join(rErr, fmt.Errorf("\t\t%w", ErrNoRequestZone))
 	}
+	for i, zone := range task.Request.Zones {
+		if strings.TrimSpace(zone) == "" {
+			rValid = false
+			rErr = errors.Join(rErr, fmt.Errorf("\t\t%w: request.zones[%d]", ErrEmptyRequestZone, i))
+		}
+	}
 
 	if task.Request.Subject.CommonName == "" {
 		rValid = false
 		rErr = errors.Join(rErr, fmt.Errorf("\t\t%w", ErrNoRequestCN))
 	}
 
+	// A user provided CSR is read from csrFile, or from the path after "file:". The private key is not available to vcert
+	csrOrigin := strings.ToLower(task.Request.CsrOrigin)
+	isUserProvidedCSR := csrOrigin == certificate.StrUserProvidedCSR ||
+		strings.HasPrefix(csrOrigin, certificate.StrUserProvidedCSR+":")
+	if csrOrigin == certificate.StrUserProvidedCSR && task.Request.CsrFile == "" {
+		rValid = false
+		rErr = errors.Join(rErr, fmt.Errorf("\t\t%w", ErrNoCSRFile))
+	}
+
+	// Keys generated in a PKCS#11 token are only usable by a locally generated CSR, and cannot be written to bundles
+	usesPKCS11 := task.Request.PKCS11 != nil
+	if usesPKCS11 {
+		err := task.Request.PKCS11.IsValid()
+		if err != nil {
+			rValid = false
+			rErr = errors.Join(rErr, fmt.Errorf("\t\t%w", err))
+		}
+		if csrOrigin != "" && csrOrigin != certificate.StrLocalGeneratedCSR {
+			rValid = false
+			rErr = errors.Join(rErr, fmt.Errorf("\t\t%w", ErrPKCS11CSROrigin))
+		}
+	}
+
+	// Keys generated in the TPM are bound to the host, so they can only be used by PEM files or the CAPI store
+	usesTPM := task.Request.TPM != nil
+	if usesTPM {
+		err := task.Request.TPM.IsValid()
+		if err != nil {
+			rValid = false
+			rErr = errors.Join(rErr, fmt.Errorf("\t\t%w", err))
+		}
+		if csrOrigin != "" && csrOrigin != certificate.StrLocalGeneratedCSR {
+			rValid = false
+			rErr = errors.Join(rErr, fmt.Errorf("\t\t%w", ErrTPMCSROrigin))
+		}
+		if usesPKCS11 {
+			rValid = false
+			rErr = errors.Join(rErr, fmt.Errorf("\t\t%w", ErrTPMWithPKCS11))
+		}
+	}
+
+	// The validity is requested as a number of days, a number of hours or an expiration date
+	validities := 0
+	for _, isSet := range []bool{task.Request.ValidDays != "", task.Request.ValidityHours != 0, task.Request.NotAfter != ""} {
+		if isSet {
+			validities++
+		}
+	}
+	if validities > 1 {
+		rValid = false
+		rErr = errors.Join(rErr, fmt.Errorf("\t\t%w", ErrValidityConflict))
+	}
+	if task.Request.ValidityHours < 0 {
+		rValid = false
+		rErr = errors.Join(rErr, fmt.Errorf("\t\t%w", ErrInvalidValidityHours))
+	}
+	if notAfter, err := task.Request.GetNotAfter(); err != nil {
+		rValid = false
+		rErr = errors.Join(rErr, fmt.Errorf("\t\t%w", err))
+	} else if notAfter != nil && !notAfter.After(time.Now()) {
+		rValid = false
+		rErr = errors.Join(rErr, fmt.Errorf("\t\t%w", ErrNotAfterPast))
+	}
+
+	// The private key reused is read from the installations, or found in the PKCS#11 token or the TPM, to sign a local CSR
+	if task.Request.ReuseKey {
+		if csrOrigin != "" && csrOrigin != certificate.StrLocalGeneratedCSR {
+			rValid = false
+			rErr = errors.Join(rErr, fmt.Errorf("\t\t%w", ErrReuseKeyCSROrigin))
+		}
+		if !usesPKCS11 && !usesTPM && !task.storesPrivateKey() {
+			rValid = false
+			rErr = errors.Join(rErr, fmt.Errorf("\t\t%w", ErrReuseKeyFormat))
+		}
+	}
+
+	// The private key generated by the service is downloaded encrypted with the keystore password, in the keystore format
+	keystoreFormat := strings.ToLower(task.Request.KeystoreFormat)
+	if keystoreFormat != "" && keystoreFormat != KeystoreFormatPEM && keystoreFormat != KeystoreFormatPKCS12 {
+		rValid = false
+		rErr = errors.Join(rErr, fmt.Errorf("\t\t%w: %s", ErrInvalidKeystoreFormat, task.Request.KeystoreFormat))
+	}
+	if (keystoreFormat != "" || task.Request.KeystorePassword != "") && csrOrigin != certificate.StrServiceGeneratedCSR {
+		rValid = false
+		rErr = errors.Join(rErr, fmt.Errorf("\t\t%w", ErrKeystoreCSROrigin))
+	}
+
+	// Extensions are only added to a locally generated CSR. The platforms have no field for them
+	if len(task.Request.Extensions) > 0 || len(task.Request.ExtKeyUsages) > 0 {
+		_, _, err := task.Request.GetExtensions()
+		if err != nil {
+			rValid = false
+			rErr = errors.Join(rErr, fmt.Errorf("\t\t%w", err))
+		}
+		if csrOrigin != "" && csrOrigin != certificate.StrLocalGeneratedCSR {
+			rValid = false
+			rErr = errors.Join(rErr, fmt.Errorf("\t\t%w", ErrExtensionsCSROrigin))
+		}
+	}
+
+	// Schedule is only used in daemon mode, but it should be valid regardless
+	if task.Schedule != "" {
+		_, err := scheduler.ParseSchedule(task.Schedule)
+		if err != nil {
+			rValid = false
+			rErr = errors.Join(rErr, fmt.Errorf("\t\t%w: %w", ErrInvalidSchedule, err))
+		}
+	}
+
+	if task.RenewBefore != "" {
+		_, err := ParseRenewBefore(task.RenewBefore)
+		if err != nil {
+			rValid = false
+			rErr = errors.Join(rErr, fmt.Errorf("\t\t%w", err))
+		}
+	}
+
+	// Retries are only made on transient errors, starting after backoff and doubling it every time
+	if task.Retries < 0 {
+		rValid = false
+		rErr = errors.Join(rErr, fmt.Errorf("\t\t%w", ErrInvalidRetries))
+	}
+	if task.Backoff != "" {
+		backoff, err := time.ParseDuration(task.Backoff)
+		if err != nil || backoff <= 0 {
+			rValid = false
+			rErr = errors.Join(rErr, fmt.Errorf("\t\t%w", ErrInvalidBackoff))
+		}
+	}
+	if task.Timeout != "" {
+		timeout, err := time.ParseDuration(task.Timeout)
+		if err != nil || timeout <= 0 {
+			rValid = false
+			rErr = errors.Join(rErr, fmt.Errorf("\t\t%w", ErrInvalidTaskTimeout))
+		}
+	}
+
 	// This task has no installations defined
 	if task.Installations == nil || len(task.Installations) < 1 {
 		rValid = false
@@ -63,6 +280,161 @@ func (task CertificateTask) IsValid() (bool, error) {
 			rErr = errors.Join(rErr, fmt.Errorf("\t\tinstallations[%d]:\n%w", i, err))
 			rValid = false
 		}
+		if isUserProvidedCSR && installation.Type != FormatPEM {
+			rErr = errors.Join(rErr, fmt.Errorf("\t\tinstallations[%d]:\n\t\t\t%w", i, ErrUserProvidedCSRFormat))
+			rValid = false
+		}
+		if usesPKCS11 && installation.Type != FormatPEM {
+			rErr = errors.Join(rErr, fmt.Errorf("\t\tinstallations[%d]:\n\t\t\t%w", i, ErrPKCS11Format))
+			rValid = false
+		}
+		if usesTPM {
+			err = task.validateTPMInstallation(installation)
+			if err != nil {
+				rErr = errors.Join(rErr, fmt.Errorf("\t\tinstallations[%d]:\n\t\t\t%w", i, err))
+				rValid = false
+			}
+		}
+		if installation.Type.IsSSH() {
+			rErr = errors.Join(rErr, fmt.Errorf("\t\tinstallations[%d]:\n\t\t\t%w", i, ErrSSHFormatNotInSSHTask))
+			rValid = false
+		}
+	}
+
+	return rValid, rErr
+}
+
+// validateTPMInstallation returns an error when installation cannot use a key of the TPM: the certificate is written
+// alone to PEM files, or bound in the CAPI store to the key, which lives in the store location of the key
+func (task CertificateTask) validateTPMInstallation(installation Installation) error {
+	switch installation.Type {
+	case FormatPEM:
+		return nil
+	case FormatCAPI:
+		if installation.CAPIKeyStorageProvider != "" && installation.CAPIKeyStorageProvider != tpm.PlatformCryptoProvider {
+			return ErrTPMCAPIKeyStorageProvider
+		}
+		location := installation.CAPILocation
+		if location == "" {
+			location = installation.Location
+		}
+		storeLocation := capiLocationCurrentUser
+		if task.Request.TPM.MachineKey {
+			storeLocation = capiLocationLocalMachine
+		}
+		if !strings.HasPrefix(strings.ToLower(location), storeLocation+"\\") {
+			return ErrTPMCAPILocation
+		}
+		return nil
+	default:
+		return ErrTPMFormat
+	}
+}
+
+// storesPrivateKey returns true when any installation of the task writes the private key to a file vcert can read back
+func (task CertificateTask) storesPrivateKey() bool {
+	for _, installation := range task.Installations {
+		switch installation.Type {
+		case FormatPEM, FormatPKCS12, FormatJKS:
+			return true
+		}
+	}
+	return false
+}
+
+// isValidRevocation returns true if the CertificateTask identifies the certificate to revoke.
+// The request is only used for its zone, and there is nothing to install
+func (task CertificateTask) isValidRevocation() (bool, error) {
+	var rErr error = nil
+	rValid := true
+
+	if err := task.Revoke.IsValid(); err != nil {
+		rValid = false
+		rErr = errors.Join(rErr, fmt.Errorf("\t\t%w", err))
+	}
+
+	if len(task.Installations) > 0 {
+		rValid = false
+		rErr = errors.Join(rErr, fmt.Errorf("\t\t%w", ErrRevokeInstallations))
+	}
+
+	if task.Schedule != "" {
+		_, err := scheduler.ParseSchedule(task.Schedule)
+		if err != nil {
+			rValid = false
+			rErr = errors.Join(rErr, fmt.Errorf("\t\t%w: %w", ErrInvalidSchedule, err))
+		}
+	}
+	if task.Timeout != "" {
+		timeout, err := time.ParseDuration(task.Timeout)
+		if err != nil || timeout <= 0 {
+			rValid = false
+			rErr = errors.Join(rErr, fmt.Errorf("\t\t%w", ErrInvalidTaskTimeout))
+		}
+	}
+
+	return rValid, rErr
+}
+
+// isValidSSHCertificate returns true if the CertificateTask defines the SSH certificate to request, and installs it
+// only in SSH formats. The request is not used
+func (task CertificateTask) isValidSSHCertificate() (bool, error) {
+	var rErr error = nil
+	rValid := true
+
+	if err := task.SSH.IsValid(); err != nil {
+		rValid = false
+		rErr = errors.Join(rErr, fmt.Errorf("\t\t%w", err))
+	}
+
+	if task.Schedule != "" {
+		_, err := scheduler.ParseSchedule(task.Schedule)
+		if err != nil {
+			rValid = false
+			rErr = errors.Join(rErr, fmt.Errorf("\t\t%w: %w", ErrInvalidSchedule, err))
+		}
+	}
+	if task.RenewBefore != "" {
+		_, err := ParseRenewBefore(task.RenewBefore)
+		if err != nil {
+			rValid = false
+			rErr = errors.Join(rErr, fmt.Errorf("\t\t%w", err))
+		}
+	}
+	if task.Timeout != "" {
+		timeout, err := time.ParseDuration(task.Timeout)
+		if err != nil || timeout <= 0 {
+			rValid = false
+			rErr = errors.Join(rErr, fmt.Errorf("\t\t%w", ErrInvalidTaskTimeout))
+		}
+	}
+
+	if len(task.Installations) < 1 {
+		rValid = false
+		rErr = errors.Join(rErr, fmt.Errorf("\t\t%w", ErrNoInstallations))
+	}
+
+	installsCertificate := false
+	for i, installation := range task.Installations {
+		if !installation.Type.IsSSH() {
+			rErr = errors.Join(rErr, fmt.Errorf("\t\tinstallations[%d]:\n\t\t\t%w", i, ErrSSHInstallationFormat))
+			rValid = false
+			continue
+		}
+		_, err := installation.IsValid()
+		if err != nil {
+			rErr = errors.Join(rErr, fmt.Errorf("\t\tinstallations[%d]:\n%w", i, err))
+			rValid = false
+		}
+		if installation.Type == FormatSSHCert {
+			installsCertificate = true
+		}
+	}
+
+	// The key id identifies the certificate in TPP. Tasks that only distribute the public key of the CA request nothing
+	if installsCertificate && strings.TrimSpace(task.SSH.KeyID) == "" {
+		rValid = false
+		rErr = errors.Join(rErr, fmt.Errorf("\t\t%w", ErrNoSSHKeyID))
 	}
 
 	return rValid, rErr
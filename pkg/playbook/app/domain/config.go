@@ -16,13 +16,189 @@
 
 package domain
 
+import (
+	"fmt"
+
+	"gopkg.in/yaml.v3"
+
+	"github.com/Venafi/vcert/v5/pkg/playbook/app/audit"
+	"github.com/Venafi/vcert/v5/pkg/playbook/app/report"
+	"github.com/Venafi/vcert/v5/pkg/playbook/app/state"
+	"github.com/Venafi/vcert/v5/pkg/util"
+	"github.com/Venafi/vcert/v5/pkg/venafi"
+)
+
 // Config contains all the values necessary to connect to a given Venafi platform: TPP or TLSPC
 type Config struct {
+	// Audit defines where the operations changing certificates are recorded, apart from the logs
+	Audit *audit.Options `yaml:"audit,omitempty"`
+	// AuditLog records the operations of the run, when set. It is opened from Audit
+	AuditLog *audit.Log `yaml:"-"`
+	// ChainTrustBundle is a PEM bundle of the roots the certificate chains are verified against before they are
+	// installed. The self-signed roots of the chains, and the roots trusted by the system, are used when not set
+	ChainTrustBundle string `yaml:"chainTrustBundle,omitempty"`
+	// Concurrency is the maximum number of certificate tasks to run in parallel. Defaults to 1
+	Concurrency int `yaml:"concurrency,omitempty"`
+	// Connection is the connection of the tasks that reference none. When config.connection is a list, it is
+	// the first of Connections
 	Connection Connection `yaml:"connection,omitempty"`
-	ForceRenew bool       `yaml:"-"`
+	// Connections are the named connections of config.connection, when it is a list. Each task uses the one
+	// named by CertificateTask.Connection, so a playbook may request certificates from several platforms
+	Connections []Connection `yaml:"-"`
+	// DryRun reports the actions the playbook would take, without requesting or installing any certificate
+	DryRun     bool `yaml:"-"`
+	ForceRenew bool `yaml:"-"`
+	// IntermediatesDir is the directory caching the issuers downloaded to complete the chains returned without them.
+	// Defaults to the vcert/intermediates directory in the cache directory of the user
+	IntermediatesDir string `yaml:"intermediatesDir,omitempty"`
+	// Log defines the format, level and destination of the logs. The log flags of vcert run take precedence
+	Log *util.LogOptions `yaml:"log,omitempty"`
+	// Notifications are sent when certificates are enrolled, when tasks fail and when certificates are about to expire
+	Notifications []Notification `yaml:"notifications,omitempty"`
+	// Report records what every task did in the run, when set
+	Report *report.Report `yaml:"-"`
+	// RequireCompleteChain fails the tasks whose certificate chain does not reach a root, even after downloading the
+	// missing issuers, or does not validate, instead of installing the chain
+	RequireCompleteChain bool `yaml:"requireCompleteChain,omitempty"`
+	// State records the certificates issued by every task. It is loaded from StateFile, when set
+	State *state.State `yaml:"-"`
+	// StateFile is the path of the file recording the certificates issued and the pickup IDs of the requests
+	// not retrieved yet. It is written as YAML when its extension is .yaml or .yml, and as JSON otherwise
+	StateFile string `yaml:"stateFile,omitempty"`
 }
 
 // IsValid Ensures the provided connection configuration is valid and logical
 func (c Config) IsValid() (bool, error) {
-	return c.Connection.IsValid()
+	if c.Concurrency < 0 {
+		return false, ErrInvalidConcurrency
+	}
+	if c.Audit != nil {
+		err := c.Audit.IsValid()
+		if err != nil {
+			return false, fmt.Errorf("%w: %w", ErrInvalidAudit, err)
+		}
+	}
+	if c.Log != nil {
+		err := c.Log.IsValid()
+		if err != nil {
+			return false, fmt.Errorf("%w: %w", ErrInvalidLog, err)
+		}
+	}
+	for i, n := range c.Notifications {
+		err := n.IsValid()
+		if err != nil {
+			return false, fmt.Errorf("%w: notifications[%d]: %w", ErrInvalidNotification, i, err)
+		}
+	}
+	if len(c.Connections) == 0 {
+		return c.Connection.IsValid()
+	}
+
+	names := make(map[string]bool)
+	for i, conn := range c.Connections {
+		if conn.Name == "" {
+			return false, fmt.Errorf("%w: connection[%d]", ErrNoConnectionName, i)
+		}
+		if names[conn.Name] {
+			return false, fmt.Errorf("%w: %s", ErrDuplicateConnectionName, conn.Name)
+		}
+		names[conn.Name] = true
+		_, err := conn.IsValid()
+		if err != nil {
+			return false, fmt.Errorf("connection %s: %w", conn.Name, err)
+		}
+	}
+	return true, nil
+}
+
+// GetConnection returns the connection named name, or the default connection when name is empty
+func (c Config) GetConnection(name string) (Connection, bool) {
+	if name == "" {
+		return c.Connection, true
+	}
+	for _, conn := range c.Connections {
+		if conn.Name == name {
+			return conn, true
+		}
+	}
+	return Connection{}, false
+}
+
+// ForTask returns the Config with the connection used by task. The default connection is kept when the task
+// references an unknown one, which the validation of the playbook reports
+func (c Config) ForTask(task CertificateTask) Config {
+	if conn, found := c.GetConnection(task.Connection); found {
+		c.Connection = conn
+	}
+	return c
+}
+
+// UsesPlatform returns true when any connection of the config is to platform
+func (c Config) UsesPlatform(platform venafi.Platform) bool {
+	if c.Connection.Platform == platform {
+		return true
+	}
+	for _, conn := range c.Connections {
+		if conn.Platform == platform {
+			return true
+		}
+	}
+	return false
+}
+
+// UnmarshalYAML reads config.connection either as a single connection, or as a list of named connections
+func (c *Config) UnmarshalYAML(value *yaml.Node) error {
+	type plainConfig Config
+
+	// The list is set apart, so the rest of the config is decoded as usual
+	node := *value
+	var list *yaml.Node
+	if value.Kind == yaml.MappingNode {
+		node.Content = make([]*yaml.Node, 0, len(value.Content))
+		for i := 0; i+1 < len(value.Content); i += 2 {
+			key, v := value.Content[i], value.Content[i+1]
+			if key.Value == "connection" && v.Kind == yaml.SequenceNode {
+				list = v
+				continue
+			}
+			node.Content = append(node.Content, key, v)
+		}
+	}
+	err := node.Decode((*plainConfig)(c))
+	if err != nil || list == nil {
+		return err
+	}
+
+	err = list.Decode(&c.Connections)
+	if err != nil {
+		return err
+	}
+	if len(c.Connections) > 0 {
+		c.Connection = c.Connections[0]
+	}
+	return nil
+}
+
+// MarshalYAML writes config.connection as a list when the config has named connections
+func (c Config) MarshalYAML() (interface{}, error) {
+	type plainConfig Config
+	node := &yaml.Node{}
+	err := node.Encode(plainConfig(c))
+	if err != nil || len(c.Connections) == 0 {
+		return node, err
+	}
+
+	list := &yaml.Node{}
+	err = list.Encode(c.Connections)
+	if err != nil {
+		return nil, err
+	}
+	for i := 0; i+1 < len(node.Content); i += 2 {
+		if node.Content[i].Value == "connection" {
+			node.Content[i+1] = list
+			return node, nil
+		}
+	}
+	node.Content = append(node.Content, &yaml.Node{Kind: yaml.ScalarNode, Value: "connection"}, list)
+	return node, nil
 }
@@ -17,27 +17,61 @@
 package domain
 
 import (
+	"crypto/tls"
 	"errors"
 	"fmt"
 	"os"
+	"strings"
+	"time"
 
 	"github.com/Venafi/vcert/v5/pkg/endpoint"
+	"github.com/Venafi/vcert/v5/pkg/playbook/util/dns"
+	"github.com/Venafi/vcert/v5/pkg/util"
 	"github.com/Venafi/vcert/v5/pkg/venafi"
+	"github.com/Venafi/vcert/v5/pkg/venafi/acme"
 )
 
 // Connection represents the issuer that vCert will connect to
 // in order to issue certificates
 type Connection struct {
-	Credentials     Authentication  `yaml:"credentials,omitempty"`
-	Insecure        bool            `yaml:"insecure,omitempty"`
-	Platform        venafi.Platform `yaml:"platform,omitempty"`
-	TrustBundlePath string          `yaml:"trustBundle,omitempty"`
-	URL             string          `yaml:"url,omitempty"`
+	// Name identifies the connection when config.connection is a list, so tasks reference it by CertificateTask.Connection
+	Name string `yaml:"name,omitempty"`
+	// ACMEChallenge describes how the challenges of the ACME server are fulfilled. Only used by the ACME platform
+	ACMEChallenge *acme.ChallengeConfig `yaml:"acmeChallenge,omitempty"`
+	Credentials   Authentication        `yaml:"credentials,omitempty"`
+	// DNSProvider publishes the TXT records of dns-01 challenges in a DNS service. Only used by the ACME platform
+	DNSProvider *dns.Config     `yaml:"dnsProvider,omitempty"`
+	Insecure    bool            `yaml:"insecure,omitempty"`
+	Platform    venafi.Platform `yaml:"platform,omitempty"`
+	// Proxy defines the proxy the requests to the platform are sent through. The proxy of the HTTP_PROXY,
+	// HTTPS_PROXY and NO_PROXY environment variables is used when not set
+	Proxy util.ProxyConfig `yaml:"proxy,omitempty"`
+	// Retry defines how requests rate limited by the platform, or failing with transient errors, are retried.
+	// Only used by the TPP and TLSPC platforms
+	Retry util.RetryPolicy `yaml:"retry,omitempty"`
+	// TLSConfig holds the TLS settings of the requests to the platform, built from Insecure and the client certificate
+	// of Credentials when the playbook is loaded. Not read from the playbook file
+	TLSConfig *tls.Config `yaml:"-"`
+	// Transport tunes the HTTP transport of the requests to the platform: connection pooling, HTTP/2 and TLS
+	// session resumption. The tasks of the playbook reuse the same connections. Defaults are used when not set
+	Transport       util.TransportConfig `yaml:"transport,omitempty"`
+	TrustBundlePath string               `yaml:"trustBundle,omitempty"`
+	URL             string               `yaml:"url,omitempty"`
+	// ZoneCacheTTL is how long the zone configurations read from the platform are reused by the certificate
+	// requests of other tasks. Zone configurations are read on every request when not set. Only used by the
+	// TPP and TLSPC platforms
+	ZoneCacheTTL time.Duration `yaml:"zoneCacheTTL,omitempty"`
 }
 
 // GetConnectorType returns the type of vcert Connector this config will create
 func (c Connection) GetConnectorType() endpoint.ConnectorType {
 	switch c.Platform {
+	case venafi.ACME:
+		return endpoint.ConnectorTypeACME
+	case venafi.EST:
+		return endpoint.ConnectorTypeEST
+	case venafi.SCEP:
+		return endpoint.ConnectorTypeSCEP
 	case venafi.Firefly:
 		return endpoint.ConnectorTypeFirefly
 	case venafi.TPP:
@@ -63,6 +97,22 @@ func (c Connection) validateTrustBundle() error {
 // IsValid returns true if the Connection is supported by vcert
 // and has the necessary values to connect to the given platform
 func (c Connection) IsValid() (bool, error) {
+	err := c.Retry.IsValid()
+	if err != nil {
+		return false, fmt.Errorf("%w: %w", ErrInvalidRetry, err)
+	}
+	err = c.Proxy.IsValid()
+	if err != nil {
+		return false, fmt.Errorf("%w: %w", ErrInvalidProxy, err)
+	}
+	err = c.Transport.IsValid()
+	if err != nil {
+		return false, fmt.Errorf("%w: %w", ErrInvalidTransport, err)
+	}
+	if c.ZoneCacheTTL < 0 {
+		return false, ErrInvalidZoneCacheTTL
+	}
+
 	switch c.Platform {
 	case venafi.TPP:
 		return isValidTpp(c)
@@ -70,6 +120,12 @@ func (c Connection) IsValid() (bool, error) {
 		return isValidVaaS(c)
 	case venafi.Firefly:
 		return isValidFirefly(c)
+	case venafi.ACME:
+		return isValidACME(c)
+	case venafi.EST:
+		return isValidEST(c)
+	case venafi.SCEP:
+		return isValidSCEP(c)
 	default:
 		return false, fmt.Errorf("invalid connection type %v", c.Platform)
 	}
@@ -80,11 +136,17 @@ func isValidTpp(c Connection) (bool, error) {
 	rValid := true
 
 	// Credentials are not empty
-	if c.Credentials.AccessToken == "" && c.Credentials.RefreshToken == "" && c.Credentials.P12Task == "" {
+	if c.Credentials.AccessToken == "" && c.Credentials.RefreshToken == "" && !c.Credentials.HasClientCertificate() {
 		rValid = false
 		rErr = errors.Join(rErr, ErrNoCredentials)
 	}
 
+	err := c.Credentials.validateClientCertificate()
+	if err != nil {
+		rValid = false
+		rErr = errors.Join(rErr, err)
+	}
+
 	// TPP connector requires a url
 	if c.URL == "" {
 		rValid = false
@@ -93,7 +155,7 @@ func isValidTpp(c Connection) (bool, error) {
 
 	// If specified, ensure TrustBundle exists
 	if c.TrustBundlePath != "" {
-		err := c.validateTrustBundle()
+		err = c.validateTrustBundle()
 		if err != nil {
 			rValid = false
 			rErr = errors.Join(rErr, err)
@@ -157,3 +219,84 @@ func isValidFirefly(c Connection) (bool, error) {
 
 	return true, nil
 }
+
+func isValidACME(c Connection) (bool, error) {
+	// The url is optional: Let's Encrypt is used by default
+	if c.DNSProvider != nil {
+		err := c.DNSProvider.IsValid()
+		if err != nil {
+			return false, fmt.Errorf("%w: %w", ErrInvalidDNSProvider, err)
+		}
+		// The DNS provider replaces the challenge command
+		if c.ACMEChallenge != nil && (c.ACMEChallenge.DNSCommand != "" ||
+			(c.ACMEChallenge.Type != "" && !strings.EqualFold(c.ACMEChallenge.Type, acme.ChallengeDNS01))) {
+			return false, ErrDNSProviderChallenge
+		}
+	} else if c.ACMEChallenge != nil {
+		err := c.ACMEChallenge.IsValid()
+		if err != nil {
+			return false, fmt.Errorf("%w: %w", ErrInvalidACMEChallenge, err)
+		}
+	}
+
+	account := c.Credentials.ACMEAccount
+	if account != nil && account.EABKeyID != "" && account.EABHMACKey == "" {
+		return false, ErrNoACMEEABHMACKey
+	}
+
+	if c.TrustBundlePath != "" {
+		err := c.validateTrustBundle()
+		if err != nil {
+			return false, err
+		}
+	}
+
+	return true, nil
+}
+
+func isValidEST(c Connection) (bool, error) {
+	if c.URL == "" {
+		return false, ErrNoESTURL
+	}
+
+	// Auth methods: HTTP basic authentication and TLS client certificate
+	if c.Credentials.User == "" && !c.Credentials.HasClientCertificate() {
+		return false, ErrNoCredentials
+	}
+	err := c.Credentials.validateClientCertificate()
+	if err != nil {
+		return false, err
+	}
+	if c.Credentials.User != "" && c.Credentials.Password == "" {
+		return false, ErrNoESTPassword
+	}
+
+	if c.TrustBundlePath != "" {
+		err := c.validateTrustBundle()
+		if err != nil {
+			return false, err
+		}
+	}
+
+	return true, nil
+}
+
+func isValidSCEP(c Connection) (bool, error) {
+	if c.URL == "" {
+		return false, ErrNoSCEPURL
+	}
+
+	// The only credential is the optional challenge password, set in credentials.password
+	if c.Credentials.User != "" || c.Credentials.HasClientCertificate() {
+		return false, ErrSCEPCredentials
+	}
+
+	if c.TrustBundlePath != "" {
+		err := c.validateTrustBundle()
+		if err != nil {
+			return false, err
+		}
+	}
+
+	return true, nil
+}
@@ -19,11 +19,15 @@ package domain
 import (
 	"fmt"
 	"testing"
+	"time"
 
 	"github.com/Venafi/vcert/v5/pkg/venafi"
 	"github.com/stretchr/testify/suite"
 
 	"github.com/Venafi/vcert/v5/pkg/endpoint"
+	"github.com/Venafi/vcert/v5/pkg/playbook/util/dns"
+	"github.com/Venafi/vcert/v5/pkg/util"
+	"github.com/Venafi/vcert/v5/pkg/venafi/acme"
 )
 
 type ConnectionSuite struct {
@@ -166,6 +170,46 @@ func (s *ConnectionSuite) SetupTest() {
 			expectedCType: endpoint.ConnectorTypeTPP,
 			expectedValid: true,
 		},
+		{
+			name: "TPP_valid_client_certificate",
+			c: Connection{
+				Platform: venafi.TPP,
+				Credentials: Authentication{
+					ClientCertFile: "client.crt",
+					ClientKeyFile:  "client.key",
+				},
+				URL: "https://my.tpp.instance.com",
+			},
+			expectedCType: endpoint.ConnectorTypeTPP,
+			expectedValid: true,
+		},
+		{
+			name: "TPP_invalid_incomplete_client_certificate",
+			c: Connection{
+				Platform: venafi.TPP,
+				Credentials: Authentication{
+					ClientCertFile: "client.crt",
+				},
+				URL: "https://my.tpp.instance.com",
+			},
+			expectedCType: endpoint.ConnectorTypeTPP,
+			expectedValid: false,
+			expectedErr:   ErrIncompleteClientCertificate,
+		},
+		{
+			name: "TPP_invalid_multiple_client_certificates",
+			c: Connection{
+				Platform: venafi.TPP,
+				Credentials: Authentication{
+					ClientP12File: "client.p12",
+					P12Task:       "myTask",
+				},
+				URL: "https://my.tpp.instance.com",
+			},
+			expectedCType: endpoint.ConnectorTypeTPP,
+			expectedValid: false,
+			expectedErr:   ErrMultipleClientCertificates,
+		},
 		{
 			name: "TPP_invalid_empty_credentials",
 			c: Connection{
@@ -231,6 +275,330 @@ func (s *ConnectionSuite) SetupTest() {
 			expectedValid: false,
 			expectedErr:   ErrNoCredentials,
 		},
+		// ACME USE CASES
+		{
+			name: "ACME_valid_default",
+			c: Connection{
+				Platform: venafi.ACME,
+			},
+			expectedCType: endpoint.ConnectorTypeACME,
+			expectedValid: true,
+			expectedErr:   nil,
+		},
+		{
+			name: "ACME_valid_dns01",
+			c: Connection{
+				Platform:      venafi.ACME,
+				URL:           "https://acme.example.com/directory",
+				ACMEChallenge: &acme.ChallengeConfig{Type: acme.ChallengeDNS01, DNSCommand: "/usr/local/bin/dns-hook.sh"},
+				Credentials: Authentication{
+					Authentication: endpoint.Authentication{
+						ACMEAccount: &endpoint.ACMEAccount{EABKeyID: "kid", EABHMACKey: "c2VjcmV0"},
+					},
+				},
+			},
+			expectedCType: endpoint.ConnectorTypeACME,
+			expectedValid: true,
+			expectedErr:   nil,
+		},
+		{
+			name: "ACME_invalid_challenge",
+			c: Connection{
+				Platform:      venafi.ACME,
+				ACMEChallenge: &acme.ChallengeConfig{Type: acme.ChallengeDNS01},
+			},
+			expectedCType: endpoint.ConnectorTypeACME,
+			expectedValid: false,
+			expectedErr:   ErrInvalidACMEChallenge,
+		},
+		{
+			name: "ACME_valid_dns_provider",
+			c: Connection{
+				Platform:      venafi.ACME,
+				ACMEChallenge: &acme.ChallengeConfig{Type: acme.ChallengeDNS01, DNSPropagationWait: time.Minute},
+				DNSProvider:   &dns.Config{Provider: dns.ProviderCloudflare, CloudflareAPIToken: "token"},
+			},
+			expectedCType: endpoint.ConnectorTypeACME,
+			expectedValid: true,
+			expectedErr:   nil,
+		},
+		{
+			name: "ACME_invalid_dns_provider",
+			c: Connection{
+				Platform:    venafi.ACME,
+				DNSProvider: &dns.Config{Provider: dns.ProviderGCPDNS, GCPProject: "my-project"},
+			},
+			expectedCType: endpoint.ConnectorTypeACME,
+			expectedValid: false,
+			expectedErr:   ErrInvalidDNSProvider,
+		},
+		{
+			name: "ACME_invalid_dns_provider_challenge",
+			c: Connection{
+				Platform:      venafi.ACME,
+				ACMEChallenge: &acme.ChallengeConfig{Type: acme.ChallengeHTTP01},
+				DNSProvider:   &dns.Config{Provider: dns.ProviderRoute53},
+			},
+			expectedCType: endpoint.ConnectorTypeACME,
+			expectedValid: false,
+			expectedErr:   ErrDNSProviderChallenge,
+		},
+		{
+			name: "ACME_invalid_no_eab_hmac",
+			c: Connection{
+				Platform: venafi.ACME,
+				Credentials: Authentication{
+					Authentication: endpoint.Authentication{
+						ACMEAccount: &endpoint.ACMEAccount{EABKeyID: "kid"},
+					},
+				},
+			},
+			expectedCType: endpoint.ConnectorTypeACME,
+			expectedValid: false,
+			expectedErr:   ErrNoACMEEABHMACKey,
+		},
+		// EST USE CASES
+		{
+			name: "EST_valid_user_password",
+			c: Connection{
+				Platform: venafi.EST,
+				URL:      "https://est.example.com",
+				Credentials: Authentication{
+					Authentication: endpoint.Authentication{
+						User:     "device",
+						Password: "secret",
+					},
+				},
+			},
+			expectedCType: endpoint.ConnectorTypeEST,
+			expectedValid: true,
+			expectedErr:   nil,
+		},
+		{
+			name: "EST_valid_p12task",
+			c: Connection{
+				Platform:    venafi.EST,
+				URL:         "https://est.example.com",
+				Credentials: Authentication{P12Task: "bootstrap"},
+			},
+			expectedCType: endpoint.ConnectorTypeEST,
+			expectedValid: true,
+			expectedErr:   nil,
+		},
+		{
+			name: "EST_invalid_no_url",
+			c: Connection{
+				Platform:    venafi.EST,
+				Credentials: Authentication{P12Task: "bootstrap"},
+			},
+			expectedCType: endpoint.ConnectorTypeEST,
+			expectedValid: false,
+			expectedErr:   ErrNoESTURL,
+		},
+		{
+			name: "EST_invalid_empty_credentials",
+			c: Connection{
+				Platform: venafi.EST,
+				URL:      "https://est.example.com",
+			},
+			expectedCType: endpoint.ConnectorTypeEST,
+			expectedValid: false,
+			expectedErr:   ErrNoCredentials,
+		},
+		{
+			name: "EST_invalid_no_password",
+			c: Connection{
+				Platform: venafi.EST,
+				URL:      "https://est.example.com",
+				Credentials: Authentication{
+					Authentication: endpoint.Authentication{
+						User: "device",
+					},
+				},
+			},
+			expectedCType: endpoint.ConnectorTypeEST,
+			expectedValid: false,
+			expectedErr:   ErrNoESTPassword,
+		},
+		// SCEP USE CASES
+		{
+			name: "SCEP_valid_challenge",
+			c: Connection{
+				Platform: venafi.SCEP,
+				URL:      "http://scep.example.com/cgi-bin/pkiclient.exe",
+				Credentials: Authentication{
+					Authentication: endpoint.Authentication{
+						Password: "challenge",
+					},
+				},
+			},
+			expectedCType: endpoint.ConnectorTypeSCEP,
+			expectedValid: true,
+			expectedErr:   nil,
+		},
+		{
+			name: "SCEP_valid_no_challenge",
+			c: Connection{
+				Platform: venafi.SCEP,
+				URL:      "http://scep.example.com/cgi-bin/pkiclient.exe",
+			},
+			expectedCType: endpoint.ConnectorTypeSCEP,
+			expectedValid: true,
+			expectedErr:   nil,
+		},
+		{
+			name: "SCEP_invalid_no_url",
+			c: Connection{
+				Platform: venafi.SCEP,
+			},
+			expectedCType: endpoint.ConnectorTypeSCEP,
+			expectedValid: false,
+			expectedErr:   ErrNoSCEPURL,
+		},
+		{
+			name: "SCEP_invalid_user",
+			c: Connection{
+				Platform: venafi.SCEP,
+				URL:      "http://scep.example.com/cgi-bin/pkiclient.exe",
+				Credentials: Authentication{
+					Authentication: endpoint.Authentication{
+						User:     "device",
+						Password: "challenge",
+					},
+				},
+			},
+			expectedCType: endpoint.ConnectorTypeSCEP,
+			expectedValid: false,
+			expectedErr:   ErrSCEPCredentials,
+		},
+		// RETRY USE CASES
+		{
+			name: "TPP_valid_retry",
+			c: Connection{
+				Platform: venafi.TPP,
+				URL:      "https://my.tpp.instance.com",
+				Credentials: Authentication{
+					Authentication: endpoint.Authentication{
+						AccessToken: "123abc###",
+					},
+				},
+				Retry: util.RetryPolicy{MaxRetries: 5, Backoff: 2 * time.Second, MaxRetryAfter: 5 * time.Minute},
+			},
+			expectedCType: endpoint.ConnectorTypeTPP,
+			expectedValid: true,
+		},
+		{
+			name: "VaaS_invalid_retry",
+			c: Connection{
+				Platform: venafi.TLSPCloud,
+				Credentials: Authentication{
+					Authentication: endpoint.Authentication{
+						APIKey: "xxx-XXX-xxx",
+					},
+				},
+				Retry: util.RetryPolicy{Backoff: -time.Second},
+			},
+			expectedCType: endpoint.ConnectorTypeCloud,
+			expectedValid: false,
+			expectedErr:   ErrInvalidRetry,
+		},
+		// PROXY USE CASES
+		{
+			name: "TPP_valid_proxy",
+			c: Connection{
+				Platform: venafi.TPP,
+				URL:      "https://my.tpp.instance.com",
+				Credentials: Authentication{
+					Authentication: endpoint.Authentication{
+						AccessToken: "123abc###",
+					},
+				},
+				Proxy: util.ProxyConfig{
+					URL:     "socks5://jumphost.example.com:1080",
+					User:    "proxyuser",
+					NoProxy: []string{".internal.example.com"},
+				},
+			},
+			expectedCType: endpoint.ConnectorTypeTPP,
+			expectedValid: true,
+		},
+		{
+			name: "VaaS_invalid_proxy",
+			c: Connection{
+				Platform: venafi.TLSPCloud,
+				Credentials: Authentication{
+					Authentication: endpoint.Authentication{
+						APIKey: "xxx-XXX-xxx",
+					},
+				},
+				Proxy: util.ProxyConfig{URL: "ftp://proxy.example.com"},
+			},
+			expectedCType: endpoint.ConnectorTypeCloud,
+			expectedValid: false,
+			expectedErr:   ErrInvalidProxy,
+		},
+		// ZONE CACHE USE CASES
+		{
+			name: "TPP_valid_zone_cache",
+			c: Connection{
+				Platform: venafi.TPP,
+				URL:      "https://my.tpp.instance.com",
+				Credentials: Authentication{
+					Authentication: endpoint.Authentication{
+						AccessToken: "123abc###",
+					},
+				},
+				ZoneCacheTTL: 10 * time.Minute,
+			},
+			expectedCType: endpoint.ConnectorTypeTPP,
+			expectedValid: true,
+		},
+		{
+			name: "VaaS_invalid_zone_cache",
+			c: Connection{
+				Platform: venafi.TLSPCloud,
+				Credentials: Authentication{
+					Authentication: endpoint.Authentication{
+						APIKey: "xxx-XXX-xxx",
+					},
+				},
+				ZoneCacheTTL: -time.Minute,
+			},
+			expectedCType: endpoint.ConnectorTypeCloud,
+			expectedValid: false,
+			expectedErr:   ErrInvalidZoneCacheTTL,
+		},
+		// TRANSPORT USE CASES
+		{
+			name: "TPP_valid_transport",
+			c: Connection{
+				Platform: venafi.TPP,
+				URL:      "https://my.tpp.instance.com",
+				Credentials: Authentication{
+					Authentication: endpoint.Authentication{
+						AccessToken: "123abc###",
+					},
+				},
+				Transport: util.TransportConfig{MaxIdleConnsPerHost: 32, IdleConnTimeout: 2 * time.Minute, DisableHTTP2: true},
+			},
+			expectedCType: endpoint.ConnectorTypeTPP,
+			expectedValid: true,
+		},
+		{
+			name: "VaaS_invalid_transport",
+			c: Connection{
+				Platform: venafi.TLSPCloud,
+				Credentials: Authentication{
+					Authentication: endpoint.Authentication{
+						APIKey: "xxx-XXX-xxx",
+					},
+				},
+				Transport: util.TransportConfig{MaxConnsPerHost: -1},
+			},
+			expectedCType: endpoint.ConnectorTypeCloud,
+			expectedValid: false,
+			expectedErr:   ErrInvalidTransport,
+		},
 		// UNKNOWN USE CASES
 		{
 			name: "Unknown_invalid",
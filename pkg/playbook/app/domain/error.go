@@ -16,20 +16,140 @@
 
 package domain
 
-import "fmt"
+import (
+	"fmt"
+
+	"github.com/Venafi/vcert/v5/pkg/tpm"
+)
 
 var (
 	// ErrNoConfig is thrown when the Playbook has no config section
 	ErrNoConfig = fmt.Errorf("no config found on playbook")
+	// ErrInvalidConcurrency is thrown when config.concurrency is a negative number
+	ErrInvalidConcurrency = fmt.Errorf("invalid concurrency. Should be a positive number")
+	// ErrNoConnectionName is thrown when a connection of the config.connection list has no name
+	ErrNoConnectionName = fmt.Errorf("connections of a config.connection list must have a name")
+	// ErrDuplicateConnectionName is thrown when two connections of the config.connection list have the same name
+	ErrDuplicateConnectionName = fmt.Errorf("connection defined multiple times")
+	// ErrUnknownConnection is thrown when a task references a connection not defined in config.connection
+	ErrUnknownConnection = fmt.Errorf("connection not defined in config.connection")
+	// ErrInvalidLog is thrown when config.log has an unsupported format, level or rotation setting
+	ErrInvalidLog = fmt.Errorf("invalid config.log")
+	// ErrInvalidAudit is thrown when config.audit has a malformed syslog destination
+	ErrInvalidAudit = fmt.Errorf("invalid config.audit")
 	// ErrNoTasks is thrown when the Playbook has no certificateTasks section
 	ErrNoTasks = fmt.Errorf("no certificate tasks found on playbook")
+	// ErrDuplicateTaskName is thrown when two tasks of the certificateTasks section have the same name
+	ErrDuplicateTaskName = fmt.Errorf("certificate task defined multiple times")
 	// ErrNoInstallations is thrown when any task (item in Certificates section) has no installations defined
 	ErrNoInstallations = fmt.Errorf("no installations found on certificate task")
 
 	// ErrNoRequestZone is thrown when a certificate request is specified without a zone
-	ErrNoRequestZone = fmt.Errorf("request.zone is required and was not found")
+	ErrNoRequestZone = fmt.Errorf("request.zone or request.zones is required and was not found")
+	// ErrEmptyRequestZone is thrown when a certificate request has an empty item in its zones list
+	ErrEmptyRequestZone = fmt.Errorf("empty zone found")
+	// ErrInvalidSchedule is thrown when a certificate task has a schedule that cannot be parsed
+	ErrInvalidSchedule = fmt.Errorf("invalid schedule. Should be a duration (i.e. '12h'), '@every <duration>', a predefined schedule (i.e. '@daily') or a 5-field cron expression")
+	// ErrInvalidRetries is thrown when a certificate task has a negative number of retries
+	ErrInvalidRetries = fmt.Errorf("invalid retries. Should be 0 or a positive number")
+	// ErrInvalidBackoff is thrown when a certificate task has a backoff that cannot be parsed
+	ErrInvalidBackoff = fmt.Errorf("invalid backoff. Should be a positive duration (i.e. '30s')")
+	// ErrInvalidTaskTimeout is thrown when a certificate task has a timeout that cannot be parsed
+	ErrInvalidTaskTimeout = fmt.Errorf("invalid timeout. Should be a positive duration (i.e. '10m')")
+	// ErrInvalidWhen is thrown when the when expression of a certificate task cannot be parsed
+	ErrInvalidWhen = fmt.Errorf("invalid when expression")
+	// ErrInvalidRenewBefore is thrown when a certificate task has a renewBefore that cannot be parsed
+	ErrInvalidRenewBefore = fmt.Errorf("invalid renewBefore. Should be a number of days (i.e. '30' or '30d'), a percentage of the certificate lifetime below 100 (i.e. '15%%'), a duration (i.e. '10h'), or 'disabled'")
+	// ErrNoCSRFile is thrown when a certificate request has csr 'file' but no csrFile
+	ErrNoCSRFile = fmt.Errorf("request.csrFile is required when request.csr is 'file'")
+	// ErrUserProvidedCSRFormat is thrown when a certificate request has csr 'file' and an installation requires the private key
+	ErrUserProvidedCSRFormat = fmt.Errorf("only PEM installations are supported when request.csr is 'file', as the private key is not available to vcert")
+	// ErrPKCS11Format is thrown when a certificate request has a pkcs11 key store and an installation requires the private key
+	ErrPKCS11Format = fmt.Errorf("only PEM installations are supported when request.pkcs11 is set, as the private key does not leave the PKCS#11 token")
+	// ErrPKCS11CSROrigin is thrown when a certificate request has a pkcs11 key store and the CSR is not generated locally
+	ErrPKCS11CSROrigin = fmt.Errorf("request.csr must be 'local' when request.pkcs11 is set")
+	// ErrTPMFormat is thrown when a certificate request has a tpm key store and an installation requires the private key
+	ErrTPMFormat = fmt.Errorf("only PEM and CAPI installations are supported when request.tpm is set, as the private key does not leave the TPM")
+	// ErrTPMCSROrigin is thrown when a certificate request has a tpm key store and the CSR is not generated locally
+	ErrTPMCSROrigin = fmt.Errorf("request.csr must be 'local' when request.tpm is set")
+	// ErrTPMWithPKCS11 is thrown when a certificate request has both a tpm and a pkcs11 key store
+	ErrTPMWithPKCS11 = fmt.Errorf("request.tpm and request.pkcs11 cannot be used together")
+	// ErrTPMCAPILocation is thrown when a certificate request has a tpm key store and a CAPI installation is not in the
+	// store location of the key
+	ErrTPMCAPILocation = fmt.Errorf("CAPI location must be LocalMachine when request.tpm.machineKey is true, and CurrentUser otherwise")
+	// ErrTPMCAPIKeyStorageProvider is thrown when a certificate request has a tpm key store and a CAPI installation
+	// sets a different key storage provider
+	ErrTPMCAPIKeyStorageProvider = fmt.Errorf("capiKeyStorageProvider must be empty or '%s' when request.tpm is set", tpm.PlatformCryptoProvider)
+	// ErrInvalidNotAfter is thrown when request.notAfter is not an RFC 3339 date and time
+	ErrInvalidNotAfter = fmt.Errorf("invalid request.notAfter. Should be an RFC 3339 date and time, i.e. 2025-12-31T18:00:00Z")
+	// ErrNotAfterPast is thrown when request.notAfter is not in the future
+	ErrNotAfterPast = fmt.Errorf("request.notAfter is in the past")
+	// ErrInvalidValidityHours is thrown when request.validityHours is a negative number
+	ErrInvalidValidityHours = fmt.Errorf("invalid request.validityHours. Should be a positive number")
+	// ErrValidityConflict is thrown when the validity of the certificate is requested by more than one field
+	ErrValidityConflict = fmt.Errorf("only one of request.validDays, request.validityHours and request.notAfter can be set")
+	// ErrReuseKeyCSROrigin is thrown when a certificate request reuses the installed private key and the CSR is not generated locally
+	ErrReuseKeyCSROrigin = fmt.Errorf("request.csr must be 'local' when request.reuseKey is set")
+	// ErrReuseKeyFormat is thrown when a certificate request reuses the installed private key and no installation stores it
+	ErrReuseKeyFormat = fmt.Errorf("request.reuseKey requires a PEM, PKCS12 or JKS installation to read the private key from, unless request.pkcs11 is set")
+	// ErrInvalidExtension is thrown when an entry of request.extensions has an invalid oid or a value that is not base64 encoded
+	ErrInvalidExtension = fmt.Errorf("invalid extension")
+	// ErrInvalidExtKeyUsage is thrown when an entry of request.extKeyUsages is neither a known name nor an OID
+	ErrInvalidExtKeyUsage = fmt.Errorf("invalid extKeyUsage")
+	// ErrExtensionsCSROrigin is thrown when a certificate request has extensions or extKeyUsages and the CSR is not generated locally
+	ErrExtensionsCSROrigin = fmt.Errorf("request.csr must be 'local' when request.extensions or request.extKeyUsages are set")
+	// ErrCSROriginConflict is thrown when a certificate request sets both csr and csrOrigin, to different values
+	ErrCSROriginConflict = fmt.Errorf("request.csr and request.csrOrigin are set to different values")
+	// ErrInvalidKeystoreFormat is thrown when a certificate request has a keystoreFormat other than pem or pkcs12
+	ErrInvalidKeystoreFormat = fmt.Errorf("invalid request.keystoreFormat. Should be pem or pkcs12")
+	// ErrKeystoreCSROrigin is thrown when a certificate request sets keystoreFormat or keystorePassword and the CSR is not generated by the service
+	ErrKeystoreCSROrigin = fmt.Errorf("request.csrOrigin must be 'service' when request.keystoreFormat or request.keystorePassword are set")
 	// ErrNoRequestCN si thrown when a certificate request does not contain subject.CommonName
 	ErrNoRequestCN = fmt.Errorf("request.subject.commonName is required and was not found")
+	// ErrInvalidTaskAction is thrown when a certificate task has an action other than 'enroll' or 'revoke'
+	ErrInvalidTaskAction = fmt.Errorf("invalid action. Should be one of 'enroll', 'revoke' or 'sshCertificate'")
+	// ErrNoRevokeTarget is thrown when a revoke task does not identify the certificate to revoke
+	ErrNoRevokeTarget = fmt.Errorf("one of revoke.thumbprint, revoke.serial or revoke.pickupId is required when action is 'revoke'")
+	// ErrMultipleRevokeTargets is thrown when a revoke task identifies the certificate to revoke in more than one way
+	ErrMultipleRevokeTargets = fmt.Errorf("only one of revoke.thumbprint, revoke.serial or revoke.pickupId can be set")
+	// ErrInvalidRevokeSerial is thrown when a revoke task has a serial number that is not hexadecimal
+	ErrInvalidRevokeSerial = fmt.Errorf("invalid revoke.serial. Should be the hexadecimal serial number of the certificate, optionally with colons")
+	// ErrInvalidRevocationReason is thrown when a revoke task has a reason that is not supported
+	ErrInvalidRevocationReason = fmt.Errorf("invalid revoke.reason. Should be one of 'none', 'key-compromise', 'ca-compromise', 'affiliation-changed', 'superseded', 'cessation-of-operation', or their RFC 5280 code from 0 to 5")
+	// ErrRevokeInstallations is thrown when a revoke task has installations
+	ErrRevokeInstallations = fmt.Errorf("installations are not allowed when action is 'revoke'")
+	// ErrRevokeNotSupported is thrown when a revoke task is declared for a platform that does not support revocation
+	ErrRevokeNotSupported = fmt.Errorf("action 'revoke' is only supported by the TPP and VaaS platforms")
+	// ErrRevokeSerialNotSupported is thrown when a revoke task identifies the certificate by serial number on VaaS
+	ErrRevokeSerialNotSupported = fmt.Errorf("revoke.serial is not supported by VaaS. Use revoke.thumbprint or revoke.pickupId instead")
+
+	// ErrNoSSHTemplate is thrown when an sshCertificate task does not set ssh.template
+	ErrNoSSHTemplate = fmt.Errorf("ssh.template is required when action is 'sshCertificate'")
+	// ErrNoSSHKeyID is thrown when an sshCertificate task has SSHCERT installations but does not set ssh.keyId
+	ErrNoSSHKeyID = fmt.Errorf("ssh.keyId is required when installing an SSH certificate")
+	// ErrInvalidSSHKeySize is thrown when an sshCertificate task has a ssh.keySize lower than 2048
+	ErrInvalidSSHKeySize = fmt.Errorf("invalid ssh.keySize. Should be at least 2048")
+	// ErrInvalidSSHValidHours is thrown when an sshCertificate task has a negative ssh.validHours
+	ErrInvalidSSHValidHours = fmt.Errorf("invalid ssh.validHours. Should be a positive number of hours")
+	// ErrSSHInstallationFormat is thrown when an sshCertificate task has an installation that is not in an SSH format
+	ErrSSHInstallationFormat = fmt.Errorf("only SSHCERT, SSHKNOWNHOSTS and SSHCAPUB installations are supported when action is 'sshCertificate'")
+	// ErrSSHFormatNotInSSHTask is thrown when a task whose action is not sshCertificate has an installation in an SSH format
+	ErrSSHFormatNotInSSHTask = fmt.Errorf("SSHCERT, SSHKNOWNHOSTS and SSHCAPUB installations are only supported when action is 'sshCertificate'")
+	// ErrSSHNotSupported is thrown when an sshCertificate task is declared for a platform other than TPP
+	ErrSSHNotSupported = fmt.Errorf("action 'sshCertificate' is only supported by the TPP platform")
+	// ErrApplicationDNsNotSupported is thrown when a task request has applicationDNs for a platform other than TPP
+	ErrApplicationDNsNotSupported = fmt.Errorf("request.applicationDNs is only supported by the TPP platform")
+	// ErrPKCS12KeystoreNotSupported is thrown when a task request has keystoreFormat pkcs12 for a platform other than TPP
+	ErrPKCS12KeystoreNotSupported = fmt.Errorf("request.keystoreFormat 'pkcs12' is only supported by the TPP platform")
+	// ErrAdoptNotSupported is thrown when a task has adopt set for a platform other than TPP and VaaS
+	ErrAdoptNotSupported = fmt.Errorf("adopt is only supported by the TPP and VaaS platforms")
+	// ErrAdoptAction is thrown when a revoke or sshCertificate task has adopt set
+	ErrAdoptAction = fmt.Errorf("adopt is only supported by tasks whose action is enroll")
+	// ErrAdoptNoStateFile is thrown when a task has adopt set and the config has no stateFile to record the adopted certificate
+	ErrAdoptNoStateFile = fmt.Errorf("adopt requires config.stateFile to record the certificate adopted")
+	// ErrEnforceNoStateFile is thrown when an installation has enforce set and the config has no stateFile to record
+	// the certificates installed
+	ErrEnforceNoStateFile = fmt.Errorf("enforce requires config.stateFile to record the certificates installed")
 
 	// ErrNoCredentials is thrown when the Playbook has no config section
 	ErrNoCredentials = fmt.Errorf("no credentials defined on playbook")
@@ -39,6 +159,14 @@ var (
 	ErrNoTPPURL = fmt.Errorf("no url defined. TPP platform requires an url to the TPP instance")
 	// ErrTrustBundleNotExist is thrown when config.trustBundle is set but the path does not exist or cannot be read
 	ErrTrustBundleNotExist = fmt.Errorf("trustBundle path does not exist")
+	// ErrInvalidRetry is thrown when config.connection.retry has a negative delay
+	ErrInvalidRetry = fmt.Errorf("invalid retry")
+	// ErrInvalidProxy is thrown when config.connection.proxy has an invalid url, or credentials without url
+	ErrInvalidProxy = fmt.Errorf("invalid proxy")
+	// ErrInvalidTransport is thrown when config.connection.transport has a negative connection limit or timeout
+	ErrInvalidTransport = fmt.Errorf("invalid transport")
+	// ErrInvalidZoneCacheTTL is thrown when config.connection.zoneCacheTTL is negative
+	ErrInvalidZoneCacheTTL = fmt.Errorf("zoneCacheTTL cannot be negative")
 
 	// ErrNoJKSAlias is thrown when certificates.installations[].type is JKS but no jksAlias is set
 	ErrNoJKSAlias = fmt.Errorf("jksAlias should not be empty when installing a certificate in JKS format")
@@ -46,16 +174,41 @@ var (
 	ErrNoJKSPassword = fmt.Errorf("jksPassword should not be empty when installing a certificate in JKS format")
 	// ErrJKSPasswordLength is thrown when certificates.installations[].type is JKS but the jksPassword length is shorter than the minimum required
 	ErrJKSPasswordLength = fmt.Errorf("jksPassword must be at least 6 characters long")
+	// ErrTruststoreFileIsKeystore is thrown when certificates.installations[].truststoreFile is the same as file
+	ErrTruststoreFileIsKeystore = fmt.Errorf("truststoreFile must be different from file")
+	// ErrNoTruststorePassword is thrown when certificates.installations[].truststoreFile is set but no truststorePassword is set
+	ErrNoTruststorePassword = fmt.Errorf("truststorePassword should not be empty when truststoreFile is set")
+	// ErrTruststorePasswordLength is thrown when certificates.installations[].truststorePassword is shorter than the minimum required
+	ErrTruststorePasswordLength = fmt.Errorf("truststorePassword must be at least 6 characters long")
+	// ErrInvalidTruststoreFormat is thrown when certificates.installations[].truststoreFormat is not a supported format
+	ErrInvalidTruststoreFormat = fmt.Errorf("invalid truststoreFormat. Valid options are 'jks' and 'pkcs12'")
 	// ErrKeyPasswordLength is thrown when certificates.installations[].type is JKS but the keyPassword length is shorter than the minimum required
 	ErrKeyPasswordLength = fmt.Errorf("keyPassword must be at least 6 characters long")
 
 	// ErrNoP12Password is thrown when certificates.installations[].type is JKS but no jksPassword is set
 	ErrNoP12Password = fmt.Errorf("p12Password should not be empty when installing a certificate in PKCS12 format")
+	// ErrInvalidP12Encryption is thrown when certificates.installations[].type is PKCS12 but p12Encryption is not a supported value
+	ErrInvalidP12Encryption = fmt.Errorf("invalid p12Encryption. Should be either 'legacy' or 'modern'")
+	// ErrP12EncryptionConflict is thrown when an installation sets both p12Encryption and encryption, to different values
+	ErrP12EncryptionConflict = fmt.Errorf("certificates.installations[].p12Encryption and encryption are set to different values")
 
 	// ErrNoChainFile is thrown when certificates.installations[].type is PEM but no pemChainFilename is set
 	ErrNoChainFile = fmt.Errorf("chainFile should not be empty when installing a certificate in PEM format")
 	// ErrNoKeyFile is thrown when certificates.installations[].type is PEM but no pemKeyFilename is set
 	ErrNoKeyFile = fmt.Errorf("keyFile should not be empty when installing a certificate in PEM format")
+	// ErrInvalidKeyFormat is thrown when certificates.installations[].type is PEM but keyFormat is not a supported value
+	ErrInvalidKeyFormat = fmt.Errorf("invalid keyFormat. Should be either 'pkcs1' or 'pkcs8'")
+	// ErrInvalidChainOrder is thrown when certificates.installations[].chainOrder is not a supported value
+	ErrInvalidChainOrder = fmt.Errorf("invalid chainOrder. Should be either 'root-first' or 'root-last'")
+	// ErrInvalidPEMBundle is thrown when certificates.installations[].pemBundle is not a supported value
+	ErrInvalidPEMBundle = fmt.Errorf("invalid pemBundle. Should be either 'cert+chain' or 'cert+key+chain'")
+	// ErrInvalidFileMode is thrown when certificates.installations[].mode is not a valid octal file mode
+	ErrInvalidFileMode = fmt.Errorf("invalid mode. Should be an octal file mode between 0000 and 0777 (i.e. '0640')")
+	// ErrFileOwnershipNotSupported is thrown when certificates.installations[].owner or group are set on Windows
+	ErrFileOwnershipNotSupported = fmt.Errorf("owner and group are not supported on Windows")
+
+	// ErrInvalidSSHHostPattern is thrown when an entry of certificates.installations[].sshHostPatterns is empty or has whitespace
+	ErrInvalidSSHHostPattern = fmt.Errorf("invalid sshHostPatterns. Each pattern should be a host name or a wildcard pattern without whitespace (i.e. '*.example.com')")
 
 	// ErrUndefinedInstallationFormat is thrown when certificates.installations[].type is unknown
 	ErrUndefinedInstallationFormat = fmt.Errorf("unknown installation format specified")
@@ -71,17 +224,181 @@ var (
 	// ErrInvalidCAPILocation is thrown when certificates.installations[].type is CAPI but the location is malformed
 	ErrInvalidCAPILocation = fmt.Errorf("invalid CAPI location. Should be either 'LocalMachine' or 'CurrentUser' (i.e. 'LocalMachine\\My')")
 	// ErrInvalidCAPIStoreName is thrown when certificates.installations[].type is CAPI but the location is malformed
-	ErrInvalidCAPIStoreName = fmt.Errorf("invalid CAPI store name. Should contain a valid storeName after the '\\' (i.e. 'LocalMachine\\My')")
+	ErrInvalidCAPIStoreName = fmt.Errorf("invalid CAPI store name. Should contain a valid storeName after the '\\' (i.e. 'LocalMachine\\My'), using only letters, numbers, spaces, '-', '_' and '.'")
+	// ErrInvalidCAPIKeyStorageProvider is thrown when certificates.installations[].capiKeyStorageProvider contains invalid characters
+	ErrInvalidCAPIKeyStorageProvider = fmt.Errorf("invalid CAPI key storage provider. Should contain only letters, numbers, spaces, '-', '_' and '.' (i.e. 'Microsoft Platform Crypto Provider')")
+	// ErrBindNotInCAPI is thrown when certificates.installations[].bindIIS or bindRDP is set on an installation that is not CAPI
+	ErrBindNotInCAPI = fmt.Errorf("bindIIS and bindRDP are only supported by CAPI installations")
+	// ErrBindNotInLocalMachine is thrown when certificates.installations[].bindIIS or bindRDP is set but the CAPI location is not LocalMachine
+	ErrBindNotInLocalMachine = fmt.Errorf("bindIIS and bindRDP require a LocalMachine CAPI location (i.e. 'LocalMachine\\My')")
+	// ErrInvalidBindIIS is thrown when certificates.installations[].bindIIS is not in the form site:port
+	ErrInvalidBindIIS = fmt.Errorf("invalid bindIIS. Should be in the form site:port (i.e. 'Default Web Site:443'), using only letters, numbers, spaces, '-', '_' and '.' for the site")
 	// WarningLocationFieldDeprecated is thrown when certificates.installations[].type is CAPI but the deprecated location field is set
 	WarningLocationFieldDeprecated = "location field is deprecated and will be removed in a future release. Use capiLocation instead"
 	// WarningNoCAPIFriendlyName is thrown when certificates.installations[].type is CAPI but no friendlyName is set
 	WarningNoCAPIFriendlyName = "no capiFriendlyName defined. It is strongly recommended to define a " +
 		"capiFriendlyName for CAPI installation type. This will become required in a future release"
 
+	// ErrInvalidAfterInstallAction is thrown when an entry of certificates.installations[].afterInstallAction defines no operation or more than one
+	ErrInvalidAfterInstallAction = fmt.Errorf("invalid afterInstallAction. Each action should define exactly one of restartService, reloadSystemdUnit, restartIISSite, httpPost or script")
+	// ErrInvalidServiceName is thrown when the restartService or reloadSystemdUnit after-install action has an invalid name
+	ErrInvalidServiceName = fmt.Errorf("invalid service name. Should contain only letters, numbers, '@', ':', '-', '_' and '.'")
+	// ErrReloadSystemdUnitOnWindows is thrown when the reloadSystemdUnit after-install action is used on Windows
+	ErrReloadSystemdUnitOnWindows = fmt.Errorf("reloadSystemdUnit after-install action is not supported on Windows")
+	// ErrRestartIISSiteOnNonWindows is thrown when the restartIISSite after-install action is used on a non-windows system
+	ErrRestartIISSiteOnNonWindows = fmt.Errorf("restartIISSite after-install action is only supported on Windows")
+	// ErrInvalidIISSiteName is thrown when the restartIISSite after-install action has an invalid site name
+	ErrInvalidIISSiteName = fmt.Errorf("invalid IIS site name. Should contain only letters, numbers, spaces, '-', '_' and '.'")
+	// ErrInvalidHTTPPostURL is thrown when the httpPost after-install action has no url, or the url is not http or https
+	ErrInvalidHTTPPostURL = fmt.Errorf("invalid httpPost url. Should be an absolute http or https URL")
+
+	// ErrInvalidBackupRetention is thrown when certificates.installations[].backupRetention is negative
+	ErrInvalidBackupRetention = fmt.Errorf("invalid backupRetention. Should be a positive number of backups")
+
+	// ErrInvalidVerifyTLS is thrown when certificates.installations[].verifyTLS is not in the form host:port
+	ErrInvalidVerifyTLS = fmt.Errorf("invalid verifyTLS. Should be in the form host:port (i.e. 'localhost:443')")
+
+	// ErrMirrorsNotSupported is thrown when certificates.installations[].mirrors is set for a format other than PEM, PKCS12 or JKS
+	ErrMirrorsNotSupported = fmt.Errorf("mirrors are only supported by the PEM, PKCS12 and JKS formats")
+	// ErrInvalidMirror is thrown when an entry of certificates.installations[].mirrors is empty, repeated or a directory
+	// the installation already writes to
+	ErrInvalidMirror = fmt.Errorf("invalid mirror. Should be a directory other than those of file, keyFile, chainFile and truststoreFile")
+	// ErrMirrorFileNames is thrown when certificates.installations[].mirrors is set and two files of the installation
+	// share a name, so they would overwrite each other in the mirrors
+	ErrMirrorFileNames = fmt.Errorf("file, keyFile, chainFile and truststoreFile should have different names when mirrors are set")
+
+	// ErrNoK8sSecretName is thrown when certificates.installations[].format is K8SSECRET but no k8sSecretName is set
+	ErrNoK8sSecretName = fmt.Errorf("k8sSecretName should not be empty when installing a certificate as a Kubernetes Secret")
+
+	// ErrNoAWSRegion is thrown when certificates.installations[].format is AWSACM but no awsRegion is set
+	ErrNoAWSRegion = fmt.Errorf("awsRegion should not be empty when installing a certificate in AWS Certificate Manager")
+	// ErrNoAWSCertificate is thrown when certificates.installations[].format is AWSACM but neither awsCertificateArn nor awsCertName are set
+	ErrNoAWSCertificate = fmt.Errorf("either awsCertificateArn or awsCertName should be set when installing a certificate in AWS Certificate Manager")
+
+	// ErrNoAzureVaultURI is thrown when certificates.installations[].format is AZUREKEYVAULT but no azureVaultUri is set
+	ErrNoAzureVaultURI = fmt.Errorf("azureVaultUri should not be empty when installing a certificate in Azure Key Vault")
+	// ErrNoAzureCertName is thrown when certificates.installations[].format is AZUREKEYVAULT but no azureCertName is set
+	ErrNoAzureCertName = fmt.Errorf("azureCertName should not be empty when installing a certificate in Azure Key Vault")
+	// ErrIncompleteAzureClientSecret is thrown when azureClientSecret is set but azureTenantId or azureClientId are missing
+	ErrIncompleteAzureClientSecret = fmt.Errorf("azureTenantId and azureClientId are required when azureClientSecret is set")
+
+	// ErrNoVaultAddress is thrown when certificates.installations[].format is VAULTKV but no vaultAddress is set
+	ErrNoVaultAddress = fmt.Errorf("vaultAddress should not be empty when installing a certificate in HashiCorp Vault")
+	// ErrNoVaultPath is thrown when certificates.installations[].format is VAULTKV but no vaultPath is set
+	ErrNoVaultPath = fmt.Errorf("vaultPath should not be empty when installing a certificate in HashiCorp Vault")
+	// ErrNoVaultAuth is thrown when certificates.installations[].format is VAULTKV but no auth method is configured
+	ErrNoVaultAuth = fmt.Errorf("one of vaultToken, vaultRoleId or vaultK8sRole should be set when installing a certificate in HashiCorp Vault")
+	// ErrNoVaultSecretID is thrown when vaultRoleId is set but vaultSecretId is missing
+	ErrNoVaultSecretID = fmt.Errorf("vaultSecretId is required when vaultRoleId is set")
+
+	// ErrNoGCPProject is thrown when certificates.installations[].format is GCP but no gcpProject is set
+	ErrNoGCPProject = fmt.Errorf("gcpProject should not be empty when installing a certificate in GCP")
+	// ErrNoGCPCertName is thrown when certificates.installations[].format is GCP but no gcpCertName is set
+	ErrNoGCPCertName = fmt.Errorf("gcpCertName should not be empty when installing a certificate in GCP")
+	// ErrInvalidGCPTarget is thrown when certificates.installations[].gcpTarget is not a supported value
+	ErrInvalidGCPTarget = fmt.Errorf("invalid gcpTarget. Should be either 'certificateManager' or 'secretManager'")
+
+	// ErrNoF5Address is thrown when certificates.installations[].format is F5 but no f5Address is set
+	ErrNoF5Address = fmt.Errorf("f5Address should not be empty when installing a certificate in F5 BIG-IP")
+	// ErrNoF5CertName is thrown when certificates.installations[].format is F5 but no f5CertName is set
+	ErrNoF5CertName = fmt.Errorf("f5CertName should not be empty when installing a certificate in F5 BIG-IP")
+	// ErrNoF5Credentials is thrown when certificates.installations[].format is F5 but f5Username or f5Password are not set
+	ErrNoF5Credentials = fmt.Errorf("f5Username and f5Password should be set when installing a certificate in F5 BIG-IP")
+	// ErrInvalidF5Name is thrown when f5CertName, f5Partition or f5Profile have characters not allowed in BIG-IP object names
+	ErrInvalidF5Name = fmt.Errorf("invalid f5CertName, f5Partition or f5Profile. Only letters, digits, '.', '_' and '-' are allowed")
+
+	// ErrNoCitrixAddress is thrown when certificates.installations[].format is CITRIXADC but no citrixAddress is set
+	ErrNoCitrixAddress = fmt.Errorf("citrixAddress should not be empty when installing a certificate in Citrix ADC")
+	// ErrNoCitrixCertKey is thrown when certificates.installations[].format is CITRIXADC but no citrixCertKey is set
+	ErrNoCitrixCertKey = fmt.Errorf("citrixCertKey should not be empty when installing a certificate in Citrix ADC")
+	// ErrNoCitrixCredentials is thrown when certificates.installations[].format is CITRIXADC but citrixUsername or citrixPassword are not set
+	ErrNoCitrixCredentials = fmt.Errorf("citrixUsername and citrixPassword should be set when installing a certificate in Citrix ADC")
+	// ErrInvalidCitrixCertKey is thrown when citrixCertKey is longer than 31 characters or has characters not allowed in certkey names
+	ErrInvalidCitrixCertKey = fmt.Errorf("invalid citrixCertKey. Only up to 31 letters, digits, '.', '_' and '-' are allowed")
+	// ErrEmptyCitrixVServer is thrown when an entry of certificates.installations[].citrixVservers is empty
+	ErrEmptyCitrixVServer = fmt.Errorf("citrixVservers should not have empty entries")
+	// ErrNoDockerSecretName is thrown when certificates.installations[].format is DOCKERSECRET but no dockerSecretName is set
+	ErrNoDockerSecretName = fmt.Errorf("dockerSecretName should not be empty when installing a certificate in Docker secrets")
+	// ErrInvalidDockerSecretName is thrown when dockerSecretName is longer than 51 characters or has characters not allowed in secret names
+	ErrInvalidDockerSecretName = fmt.Errorf("invalid dockerSecretName. Only up to 51 letters, digits, '.', '_' and '-' are allowed")
+	// ErrInvalidDockerHost is thrown when dockerHost is neither a unix:// socket nor a tcp:// address
+	ErrInvalidDockerHost = fmt.Errorf("invalid dockerHost. Should be a unix:// socket or a tcp:// address")
+	// ErrEmptyDockerService is thrown when an entry of certificates.installations[].dockerServices is empty
+	ErrEmptyDockerService = fmt.Errorf("dockerServices should not have empty entries")
+	// ErrNoNomadPath is thrown when certificates.installations[].format is NOMADVARIABLE but no nomadPath is set
+	ErrNoNomadPath = fmt.Errorf("nomadPath should not be empty when installing a certificate in a Nomad variable")
+	// ErrInvalidNomadPath is thrown when nomadPath has characters not allowed in the paths of Nomad variables
+	ErrInvalidNomadPath = fmt.Errorf("invalid nomadPath. Only letters, digits, '-', '_' and '~' are allowed, in segments separated by '/'")
+	// ErrNoDBDataDir is thrown when certificates.installations[].format is POSTGRESQL or MYSQL but neither dbDataDir nor file and keyFile are set
+	ErrNoDBDataDir = fmt.Errorf("dbDataDir, or file and keyFile, should not be empty when installing the certificate of a database server")
+	// ErrInvalidDBAddress is thrown when dbAddress is neither a host:port address nor the absolute path of a Unix socket
+	ErrInvalidDBAddress = fmt.Errorf("invalid dbAddress. Should be a host:port address or the absolute path of a Unix socket")
+	// ErrDBKeyPassword is thrown when keyPassword is set for POSTGRESQL or MYSQL, whose servers cannot reload encrypted keys
+	ErrDBKeyPassword = fmt.Errorf("keyPassword is not supported when installing the certificate of a database server, which cannot reload an encrypted private key")
+	// ErrNoSystemdCredential is thrown when certificates.installations[].format is SYSTEMDCREDENTIAL but no systemdCredential is set
+	ErrNoSystemdCredential = fmt.Errorf("systemdCredential should not be empty when installing a certificate in a systemd credstore")
+	// ErrInvalidSystemdCredential is thrown when systemdCredential has characters not allowed in the names of credentials
+	ErrInvalidSystemdCredential = fmt.Errorf("invalid systemdCredential. Only letters, digits, '.', '_' and '-' are allowed")
+	// ErrInvalidSystemdCredstore is thrown when systemdCredstore is not an absolute path
+	ErrInvalidSystemdCredstore = fmt.Errorf("invalid systemdCredstore. Should be an absolute path")
+	// ErrSystemdCredentialKeyPassword is thrown when keyPassword is set for SYSTEMDCREDENTIAL, as services load the credentials unattended
+	ErrSystemdCredentialKeyPassword = fmt.Errorf("keyPassword is not supported when installing a certificate in a systemd credstore. Use systemdEncrypt instead")
+	// ErrSystemdCredentialOnWindows is thrown when certificates.installations[].format is SYSTEMDCREDENTIAL on Windows
+	ErrSystemdCredentialOnWindows = fmt.Errorf("SYSTEMDCREDENTIAL installations are not supported on Windows")
+
+	// ErrNoPlugin is thrown when certificates.installations[].format is PLUGIN but no plugin is set
+	ErrNoPlugin = fmt.Errorf("plugin should not be empty when installing a certificate with a plugin")
+	// ErrInvalidPlugin is thrown when plugin has characters not allowed in the names of plugins
+	ErrInvalidPlugin = fmt.Errorf("invalid plugin. Only letters, digits, '.', '_' and '-' are allowed")
+	// ErrInvalidPluginDir is thrown when pluginDir is not an absolute path
+	ErrInvalidPluginDir = fmt.Errorf("invalid pluginDir. Should be an absolute path")
+
+	// ErrNoWebServerConfig is thrown when certificates.installations[].format is NGINX or APACHE but no webServerConfig is set
+	ErrNoWebServerConfig = fmt.Errorf("webServerConfig should not be empty when installing the certificate of a web server")
+	// ErrNoWebServerFiles is thrown when certificates.installations[].format is NGINX or APACHE but file or keyFile are not set
+	ErrNoWebServerFiles = fmt.Errorf("file and keyFile should not be empty when installing the certificate of a web server")
+	// ErrInvalidWebServerFile is thrown when file, keyFile or chainFile of NGINX or APACHE are not absolute paths
+	ErrInvalidWebServerFile = fmt.Errorf("invalid file, keyFile or chainFile. Should be absolute paths, as they are written to the configuration of the web server")
+	// ErrWebServerKeyPassword is thrown when keyPassword is set for NGINX or APACHE, which are reloaded unattended
+	ErrWebServerKeyPassword = fmt.Errorf("keyPassword is not supported when installing the certificate of a web server, which cannot be reloaded unattended with an encrypted private key")
+
+	// ErrIncompleteClientCertificate is thrown when only one of config.credentials.clientCertFile and clientKeyFile is set
+	ErrIncompleteClientCertificate = fmt.Errorf("clientCertFile and clientKeyFile must be set together")
+	// ErrMultipleClientCertificates is thrown when more than one of config.credentials.clientCertFile, clientP12File and p12Task is set
+	ErrMultipleClientCertificates = fmt.Errorf("only one of clientCertFile, clientP12File or p12Task can be set")
+
 	// ErrNoFireflyURL is thrown when platform is Firefly but no url is specified inf config.credentials
 	ErrNoFireflyURL = fmt.Errorf("no url defined. Firefly platform requires an url to the Firefly instance")
 	// ErrNoClientId is thrown when platform is Firefly and no config.credentials.clientId is defined
 	ErrNoClientId = fmt.Errorf("no cliendId defined. Firefly platform requires a clientId to request OAuth2 token")
 	// ErrNoIdentityProviderURL is thrown when platform is Firefly and no config.credentials.tokenURL is defined to request an OAuth2 Token
 	ErrNoIdentityProviderURL = fmt.Errorf("no tokenURL defined in credentials. tokenURL is required to request OAuth2 token")
+
+	// ErrInvalidNotification is thrown when an entry of config.notifications is not valid
+	ErrInvalidNotification = fmt.Errorf("invalid notification")
+	// ErrInvalidNotificationType is thrown when a notification type is not webhook, slack or teams
+	ErrInvalidNotificationType = fmt.Errorf("invalid type. Should be webhook, slack or teams")
+	// ErrInvalidNotificationURL is thrown when a notification url is not an http or https URL
+	ErrInvalidNotificationURL = fmt.Errorf("url should be an http or https URL")
+	// ErrInvalidNotificationEvent is thrown when a notification event is not success, failure, expiring or drift
+	ErrInvalidNotificationEvent = fmt.Errorf("invalid event. Should be success, failure, expiring or drift")
+
+	// ErrInvalidACMEChallenge is thrown when platform is ACME and config.connection.acmeChallenge is not valid
+	ErrInvalidACMEChallenge = fmt.Errorf("invalid acmeChallenge")
+	// ErrNoACMEEABHMACKey is thrown when platform is ACME and config.credentials.acme.eabKeyId is set without eabHmacKey
+	ErrNoACMEEABHMACKey = fmt.Errorf("eabHmacKey is required when eabKeyId is set")
+	// ErrInvalidDNSProvider is thrown when platform is ACME and config.connection.dnsProvider is not valid
+	ErrInvalidDNSProvider = fmt.Errorf("invalid dnsProvider")
+	// ErrDNSProviderChallenge is thrown when config.connection.dnsProvider is set along with an acmeChallenge that is not dns-01, or has a dnsCommand
+	ErrDNSProviderChallenge = fmt.Errorf("dnsProvider requires a dns-01 acmeChallenge without dnsCommand")
+
+	// ErrNoESTURL is thrown when platform is EST but no url is specified in config.connection
+	ErrNoESTURL = fmt.Errorf("no url defined. EST platform requires an url to the EST server")
+	// ErrNoESTPassword is thrown when platform is EST and config.credentials.user is set without a password
+	ErrNoESTPassword = fmt.Errorf("password is required when user is set")
+
+	// ErrNoSCEPURL is thrown when platform is SCEP but no url is specified in config.connection
+	ErrNoSCEPURL = fmt.Errorf("no url defined. SCEP platform requires an url to the SCEP server")
+	// ErrSCEPCredentials is thrown when platform is SCEP and config.credentials has a user or a client certificate
+	ErrSCEPCredentials = fmt.Errorf("SCEP platform only supports the challenge password in credentials.password")
 )
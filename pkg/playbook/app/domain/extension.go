@@ -0,0 +1,55 @@
+/*
+ * Copyright 2023 Venafi, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *  http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package domain
+
+import (
+	"crypto/x509/pkix"
+	"encoding/asn1"
+	"fmt"
+
+	"github.com/Venafi/vcert/v5/pkg/certificate"
+)
+
+// Extension represents a custom X.509 extension requested in the CSR of the certificate
+type Extension struct {
+	OID      string `yaml:"oid"`
+	Critical bool   `yaml:"critical,omitempty"`
+	// Value is the DER encoding of the extension value, in base64
+	Value string `yaml:"value"`
+}
+
+// GetExtensions returns the custom extensions and the OIDs of the extended key usages of the request
+func (request PlaybookRequest) GetExtensions() ([]pkix.Extension, []asn1.ObjectIdentifier, error) {
+	var extensions []pkix.Extension
+	for i, e := range request.Extensions {
+		ext, err := certificate.ParseExtension(e.OID, e.Critical, e.Value)
+		if err != nil {
+			return nil, nil, fmt.Errorf("%w: request.extensions[%d]: %w", ErrInvalidExtension, i, err)
+		}
+		extensions = append(extensions, ext)
+	}
+
+	var usages []asn1.ObjectIdentifier
+	for i, name := range request.ExtKeyUsages {
+		oid, err := certificate.ParseExtKeyUsage(name)
+		if err != nil {
+			return nil, nil, fmt.Errorf("%w: request.extKeyUsages[%d]: %w", ErrInvalidExtKeyUsage, i, err)
+		}
+		usages = append(usages, oid)
+	}
+	return extensions, usages, nil
+}
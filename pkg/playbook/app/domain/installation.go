@@ -18,47 +18,464 @@ package domain
 
 import (
 	"fmt"
+	"net"
+	"os"
+	"path/filepath"
+	"regexp"
 	"runtime"
+	"strconv"
 	"strings"
 
 	"go.uber.org/zap"
+	"gopkg.in/yaml.v3"
 )
 
 const (
 	// JKSMinPasswordLength represents the minimum length a JKS password must have per the JKS specification
 	JKSMinPasswordLength = 6
 
+	// KeyFormatPKCS1 writes PEM private keys in the traditional format, using legacy PEM encryption when keyPassword is set
+	KeyFormatPKCS1 = "pkcs1"
+	// KeyFormatPKCS8 writes PEM private keys in PKCS8 format, encrypted with AES-256-CBC and PBKDF2 when keyPassword is set
+	KeyFormatPKCS8 = "pkcs8"
+
+	// ChainOrderRootFirst writes the chain of PEM installations starting with the root certificate
+	ChainOrderRootFirst = "root-first"
+	// ChainOrderRootLast writes the chain of PEM installations starting with the issuer of the certificate
+	ChainOrderRootLast = "root-last"
+
+	// PEMBundleCertChain writes the certificate followed by its chain to the file of PEM installations
+	PEMBundleCertChain = "cert+chain"
+	// PEMBundleCertKeyChain writes the certificate, its private key and its chain to the file of PEM installations
+	PEMBundleCertKeyChain = "cert+key+chain"
+
+	// P12EncryptionLegacy encrypts PKCS12 bundles using the legacy RC2/3DES algorithms and SHA-1 MACs
+	P12EncryptionLegacy = "legacy"
+	// P12EncryptionModern encrypts PKCS12 bundles using AES-256-CBC with PBKDF2 and SHA-256 MACs
+	P12EncryptionModern = "modern"
+
+	// TruststoreFormatJKS writes the truststore of JKS installations as a Java KeyStore
+	TruststoreFormatJKS = "jks"
+	// TruststoreFormatPKCS12 writes the truststore of JKS installations as a PKCS12 bundle
+	TruststoreFormatPKCS12 = "pkcs12"
+
+	// DefaultK8sNamespace is the namespace used for K8SSECRET installations when k8sNamespace is not set
+	DefaultK8sNamespace = "default"
+
+	// DefaultBackupRetention is the number of backups kept for each installed file when backupRetention is not set
+	DefaultBackupRetention = 5
+
+	// DefaultVaultMount is the KV v2 mount used for VAULTKV installations when vaultMount is not set
+	DefaultVaultMount = "secret"
+	// DefaultVaultCertField is the secret field that holds the certificate when vaultCertField is not set
+	DefaultVaultCertField = "certificate"
+	// DefaultVaultKeyField is the secret field that holds the private key when vaultKeyField is not set
+	DefaultVaultKeyField = "private_key"
+	// DefaultVaultChainField is the secret field that holds the chain when vaultChainField is not set
+	DefaultVaultChainField = "chain"
+
+	// GCPTargetCertificateManager uploads GCP installations to Certificate Manager, for use by load balancers
+	GCPTargetCertificateManager = "certificateManager"
+	// GCPTargetSecretManager stores the PEM bundle of GCP installations in a Secret Manager secret
+	GCPTargetSecretManager = "secretManager"
+	// DefaultGCPLocation is the Certificate Manager location used for GCP installations when gcpLocation is not set
+	DefaultGCPLocation = "global"
+
+	// DefaultDockerHost is the Docker Engine used for DOCKERSECRET installations when dockerHost is not set
+	DefaultDockerHost = "unix:///var/run/docker.sock"
+
+	// DefaultNomadAddress is the Nomad agent used for NOMADVARIABLE installations when nomadAddress is not set
+	DefaultNomadAddress = "http://127.0.0.1:4646"
+	// DefaultNomadNamespace is the namespace of the variable of NOMADVARIABLE installations when nomadNamespace is not set
+	DefaultNomadNamespace = "default"
+
+	// DefaultPostgreSQLAddress is the server reloaded by POSTGRESQL installations when dbAddress is not set
+	DefaultPostgreSQLAddress = "localhost:5432"
+	// DefaultPostgreSQLUsername is the user connecting to the server of POSTGRESQL installations when dbUsername is not set
+	DefaultPostgreSQLUsername = "postgres"
+	// DefaultPostgreSQLDatabase is the database connected to by POSTGRESQL installations when dbName is not set
+	DefaultPostgreSQLDatabase = "postgres"
+	// DefaultMySQLAddress is the server reloaded by MYSQL installations when dbAddress is not set
+	DefaultMySQLAddress = "localhost:3306"
+	// DefaultMySQLUsername is the user connecting to the server of MYSQL installations when dbUsername is not set
+	DefaultMySQLUsername = "root"
+
+	postgreSQLCertFile = "server.crt"
+	postgreSQLKeyFile  = "server.key"
+	mySQLCertFile      = "server-cert.pem"
+	mySQLKeyFile       = "server-key.pem"
+
+	// DefaultSystemdCredstore is the credstore of SYSTEMDCREDENTIAL installations when systemdCredstore is not set.
+	// It lives in /run, which is a tmpfs on every systemd host, so the private key never reaches a persistent disk
+	DefaultSystemdCredstore = "/run/credstore"
+	// systemdEncryptedSuffix is the suffix of the credstore holding the credentials encrypted with systemd-creds,
+	// which systemd searches for LoadCredentialEncrypted
+	systemdEncryptedSuffix = ".encrypted"
+	systemdCertExtension   = ".crt"
+	systemdKeyExtension    = ".key"
+
+	// DefaultPluginDir is the directory the binaries of PLUGIN installations are found in when pluginDir is not set
+	DefaultPluginDir = "/usr/local/lib/vcert/plugins"
+	// DefaultWindowsPluginDir is the directory the binaries of PLUGIN installations are found in on Windows when
+	// pluginDir is not set
+	DefaultWindowsPluginDir = `C:\ProgramData\Venafi\vcert\plugins`
+	// pluginBinaryPrefix is the prefix of the name of the binaries of PLUGIN installations, i.e. vcert-plugin-appliance
+	pluginBinaryPrefix = "vcert-plugin-"
+
+	// DefaultNginxCommand is the binary testing and reloading the configuration of NGINX installations when
+	// webServerCommand is not set
+	DefaultNginxCommand = "nginx"
+	// DefaultApacheCommand is the binary testing and reloading the configuration of APACHE installations when
+	// webServerCommand is not set
+	DefaultApacheCommand = "apachectl"
+
+	// DefaultSSHHostPattern is the host pattern of the @cert-authority entry of SSHKNOWNHOSTS installations
+	// when sshHostPatterns is not set
+	DefaultSSHHostPattern = "*"
+
+	// DefaultF5Partition is the BIG-IP partition used for F5 installations when f5Partition is not set
+	DefaultF5Partition = "Common"
+
+	// citrixCertKeyMaxLength is the maximum length of certkey names allowed by the ADC
+	citrixCertKeyMaxLength = 31
+
+	// dockerSecretNameMaxLength is the maximum length of dockerSecretName, so the names of the secrets created,
+	// which have a suffix of 13 characters, fit in the 64 characters allowed by Docker
+	dockerSecretNameMaxLength = 51
+
 	capiLocationCurrentUser  = "currentuser"
 	capiLocationLocalMachine = "localmachine"
 )
 
-var validStoreNames = []string{"addressbook", "authroot", "certificateauthority", "disallowed", "my", "root",
-	"trustedpeople", "trustedpublisher"}
+// knownStoreNames are the system stores available in every Windows host. Custom stores (i.e. WebHosting) are also allowed
+var knownStoreNames = []string{"addressbook", "authroot", "ca", "certificateauthority", "disallowed", "my", "root",
+	"trustedpeople", "trustedpublisher", "webhosting"}
+
+// objectNameRegex restricts the names of the objects created in a BIG-IP or a Citrix ADC, which are also used in REST paths
+var objectNameRegex = regexp.MustCompile(`^[A-Za-z0-9][A-Za-z0-9._-]*$`)
+
+// nomadPathRegex restricts the paths of Nomad variables to the characters allowed by Nomad
+var nomadPathRegex = regexp.MustCompile(`^[A-Za-z0-9_~-]+(/[A-Za-z0-9_~-]+)*$`)
+
+// capiValueRegex restricts the characters of values passed to PowerShell to prevent command injection
+var capiValueRegex = regexp.MustCompile(`^[A-Za-z0-9\s\-_\.]+$`)
 
 // Installation represents a location in which a certificate will be installed,
 // along with the format in which it will be installed
 type Installation struct {
-	AfterAction         string `yaml:"afterInstallAction,omitempty"`
-	BackupFiles         bool   `yaml:"backupFiles,omitempty"`
+	AfterAction       AfterInstallActions `yaml:"afterInstallAction,omitempty"`
+	AWSCertificateARN string              `yaml:"awsCertificateArn,omitempty"`
+	AWSCertName       string              `yaml:"awsCertName,omitempty"`
+	AWSProfile        string              `yaml:"awsProfile,omitempty"`
+	AWSRegion         string              `yaml:"awsRegion,omitempty"`
+	AzureCertName     string              `yaml:"azureCertName,omitempty"`
+	AzureClientID     string              `yaml:"azureClientId,omitempty"`
+	AzureClientSecret string              `yaml:"azureClientSecret,omitempty"`
+	AzureTenantID     string              `yaml:"azureTenantId,omitempty"`
+	AzureVaultURI     string              `yaml:"azureVaultUri,omitempty"`
+	BackupFiles       bool                `yaml:"backupFiles,omitempty"`
+	// BackupRetention is the number of timestamped backups kept for each file of the installation when BackupFiles
+	// is set. Defaults to DefaultBackupRetention
+	BackupRetention int `yaml:"backupRetention,omitempty"`
+	// BindIIS is the IIS site, and the port of its HTTPS binding, in the form site:port. The binding is updated to
+	// the installed certificate. Only for CAPI
+	BindIIS string `yaml:"bindIIS,omitempty"`
+	// BindRDP sets the installed certificate as the certificate of the RDP listener. Only for CAPI
+	BindRDP             bool   `yaml:"bindRDP,omitempty"`
 	CAPIFriendlyName    string `yaml:"capiFriendlyName,omitempty"` // In a future version of vCert this will become REQUIRED!
 	CAPIIsNonExportable bool   `yaml:"capiIsNonExportable,omitempty"`
-	CAPILocation        string `yaml:"capiLocation,omitempty"` // This is an alias for Location
-	ChainFile           string `yaml:"chainFile,omitempty"`
-	File                string `yaml:"file,omitempty"`
-	InstallValidation   string `yaml:"installValidationAction,omitempty"`
-	JKSAlias            string `yaml:"jksAlias,omitempty"`
-	JKSPassword         string `yaml:"jksPassword,omitempty"`
-	KeyFile             string `yaml:"keyFile,omitempty"`
-	KeyPassword         string `yaml:"keyPassword,omitempty"`
+	// CAPIKeyStorageProvider is the CNG Key Storage Provider used to store the private key,
+	// i.e. "Microsoft Platform Crypto Provider" for TPM-backed keys
+	CAPIKeyStorageProvider string `yaml:"capiKeyStorageProvider,omitempty"`
+	CAPILocation           string `yaml:"capiLocation,omitempty"` // This is an alias for Location
+	ChainFile              string `yaml:"chainFile,omitempty"`
+	// ChainOrder is either root-first or root-last. Only for PEM. The chain is written as received when not set
+	ChainOrder string `yaml:"chainOrder,omitempty"`
+	// CitrixAddress is the host, and optionally the port, of the ADC management interface. Only for CITRIXADC
+	CitrixAddress string `yaml:"citrixAddress,omitempty"`
+	// CitrixCACert is a PEM bundle used to verify the certificate of the ADC management interface. Only for CITRIXADC
+	CitrixCACert string `yaml:"citrixCaCert,omitempty"`
+	// CitrixCertKey is the name of the certkey updated in place on every renewal. Only for CITRIXADC
+	CitrixCertKey string `yaml:"citrixCertKey,omitempty"`
+	// CitrixInsecure skips the verification of the certificate of the ADC management interface. Only for CITRIXADC
+	CitrixInsecure bool   `yaml:"citrixInsecure,omitempty"`
+	CitrixPassword string `yaml:"citrixPassword,omitempty"`
+	CitrixUsername string `yaml:"citrixUsername,omitempty"`
+	// CitrixVServers are the SSL virtual servers the certkey is bound to. Only for CITRIXADC
+	CitrixVServers []string `yaml:"citrixVservers,omitempty"`
+	// DBAddress is the host:port address, or the path of the Unix socket, of the database server reloaded after the
+	// installation. Only for POSTGRESQL and MYSQL
+	DBAddress string `yaml:"dbAddress,omitempty"`
+	// DBDataDir is the data directory of the database server, where the certificate and the private key are written
+	// with the default names of the server. Only for POSTGRESQL and MYSQL
+	DBDataDir string `yaml:"dbDataDir,omitempty"`
+	// DBName is the database connected to. Defaults to DefaultPostgreSQLDatabase. Only for POSTGRESQL
+	DBName     string `yaml:"dbName,omitempty"`
+	DBPassword string `yaml:"dbPassword,omitempty"`
+	// DBUsername is the user reloading the server. Defaults to DefaultPostgreSQLUsername or DefaultMySQLUsername.
+	// Only for POSTGRESQL and MYSQL
+	DBUsername string `yaml:"dbUsername,omitempty"`
+	// DockerCertPath is the directory of the ca.pem, cert.pem and key.pem files used to connect to a tcp:// DockerHost
+	// with TLS. Only for DOCKERSECRET
+	DockerCertPath string `yaml:"dockerCertPath,omitempty"`
+	// DockerHost is the unix:// socket or the tcp:// address of the Docker Engine. Defaults to DefaultDockerHost.
+	// Only for DOCKERSECRET
+	DockerHost string `yaml:"dockerHost,omitempty"`
+	// DockerSecretName is the base name of the secrets created for every certificate. Only for DOCKERSECRET
+	DockerSecretName string `yaml:"dockerSecretName,omitempty"`
+	// DockerServices are the Swarm services updated to use the secrets of the installed certificate. Only for DOCKERSECRET
+	DockerServices []string `yaml:"dockerServices,omitempty"`
+	// Enforce replaces the certificate found at the location of the installation when it is not the one vcert
+	// installed there, as recorded in the state file. Such a certificate is only reported when not set
+	Enforce bool `yaml:"enforce,omitempty"`
+	// ExcludeRoot leaves the self-signed root certificate out of the chain. Only for PEM
+	ExcludeRoot bool `yaml:"excludeRoot,omitempty"`
+	// F5Address is the host, and optionally the port, of the BIG-IP management interface. Only for F5
+	F5Address string `yaml:"f5Address,omitempty"`
+	// F5CACert is a PEM bundle used to verify the certificate of the BIG-IP management interface. Only for F5
+	F5CACert string `yaml:"f5CaCert,omitempty"`
+	// F5CertName is the base name of the certificate, key and chain objects created in the BIG-IP. Only for F5
+	F5CertName string `yaml:"f5CertName,omitempty"`
+	// F5Insecure skips the verification of the certificate of the BIG-IP management interface. Only for F5
+	F5Insecure bool `yaml:"f5Insecure,omitempty"`
+	// F5Partition is the partition the objects are created in. Defaults to DefaultF5Partition. Only for F5
+	F5Partition string `yaml:"f5Partition,omitempty"`
+	F5Password  string `yaml:"f5Password,omitempty"`
+	// F5Profile is the client SSL profile updated to use the installed certificate. Only for F5
+	F5Profile  string `yaml:"f5Profile,omitempty"`
+	F5Username string `yaml:"f5Username,omitempty"`
+	File       string `yaml:"file,omitempty"`
+	// GCPCertName is the id of the Certificate Manager certificate or the Secret Manager secret. Only for GCP
+	GCPCertName string `yaml:"gcpCertName,omitempty"`
+	// GCPCredentialsFile is a service account key or user credentials file. The Application Default Credentials
+	// are used when not set. Only for GCP
+	GCPCredentialsFile string `yaml:"gcpCredentialsFile,omitempty"`
+	// GCPLocation is the Certificate Manager location. Defaults to DefaultGCPLocation. Only for GCP
+	GCPLocation string `yaml:"gcpLocation,omitempty"`
+	// GCPProject is the id of the Google Cloud project. Only for GCP
+	GCPProject string `yaml:"gcpProject,omitempty"`
+	// GCPTarget is either certificateManager or secretManager. Defaults to certificateManager. Only for GCP
+	GCPTarget string `yaml:"gcpTarget,omitempty"`
+	// Group is the name or id of the group that owns the installed files. Only for PEM, PKCS12, JKS, the SSH formats,
+	// POSTGRESQL, MYSQL and SYSTEMDCREDENTIAL
+	Group             string `yaml:"group,omitempty"`
+	InstallValidation string `yaml:"installValidationAction,omitempty"`
+	JKSAlias          string `yaml:"jksAlias,omitempty"`
+	JKSPassword       string `yaml:"jksPassword,omitempty"`
+	K8sContext        string `yaml:"k8sContext,omitempty"`
+	K8sKubeconfig     string `yaml:"k8sKubeconfig,omitempty"`
+	K8sNamespace      string `yaml:"k8sNamespace,omitempty"`
+	K8sSecretName     string `yaml:"k8sSecretName,omitempty"`
+	KeyFile           string `yaml:"keyFile,omitempty"`
+	KeyFormat         string `yaml:"keyFormat,omitempty"`
+	KeyPassword       string `yaml:"keyPassword,omitempty"`
 	// Deprecated: Location is deprecated in favor of CAPILocation. It will be removed on a future release
-	Location    string             `yaml:"location,omitempty"`
-	P12Password string             `yaml:"p12Password,omitempty"`
-	Type        InstallationFormat `yaml:"format,omitempty"`
+	Location string `yaml:"location,omitempty"`
+	// Mirrors are directories the files of the installation are also written to, under the same names, i.e. an NFS
+	// share. Either every location is updated or none is. Only for PEM, PKCS12 and JKS
+	Mirrors []string `yaml:"mirrors,omitempty"`
+	// Mode is the octal permission mode of the installed files, i.e. "0640". Only for PEM, PKCS12, JKS, the SSH formats,
+	// POSTGRESQL, MYSQL and SYSTEMDCREDENTIAL. For SSHCERT, it only applies to the private key
+	Mode string `yaml:"mode,omitempty"`
+	// NomadAddress is the address of the Nomad agent. Defaults to DefaultNomadAddress. Only for NOMADVARIABLE
+	NomadAddress string `yaml:"nomadAddress,omitempty"`
+	// NomadCACert is a PEM bundle used to verify the certificate of the Nomad agent. Only for NOMADVARIABLE
+	NomadCACert string `yaml:"nomadCaCert,omitempty"`
+	// NomadNamespace is the namespace of the variable. Defaults to DefaultNomadNamespace. Only for NOMADVARIABLE
+	NomadNamespace string `yaml:"nomadNamespace,omitempty"`
+	// NomadPath is the path of the variable, i.e. nomad/jobs/web. Only for NOMADVARIABLE
+	NomadPath string `yaml:"nomadPath,omitempty"`
+	// NomadToken is the ACL token used to write the variable. Only for NOMADVARIABLE
+	NomadToken string `yaml:"nomadToken,omitempty"`
+	// Owner is the name or id of the user that owns the installed files. Only for PEM, PKCS12, JKS, the SSH formats,
+	// POSTGRESQL, MYSQL and SYSTEMDCREDENTIAL
+	Owner         string `yaml:"owner,omitempty"`
+	P12Encryption string `yaml:"p12Encryption,omitempty"`
+	P12Password   string `yaml:"p12Password,omitempty"`
+	// PEMBundle combines the chain, and optionally the private key, with the certificate in File.
+	// Either cert+chain or cert+key+chain. Only for PEM
+	PEMBundle string `yaml:"pemBundle,omitempty"`
+	// Plugin is the name of the plugin making the installation, run from the vcert-plugin-<name> binary of PluginDir.
+	// Only for PLUGIN
+	Plugin string `yaml:"plugin,omitempty"`
+	// PluginConfig is passed as is to the plugin, to tell it where and how to install the certificate. Only for PLUGIN
+	PluginConfig map[string]string `yaml:"pluginConfig,omitempty"`
+	// PluginDir is the directory of the plugin binaries. Defaults to DefaultPluginDir, or DefaultWindowsPluginDir on
+	// Windows. Only for PLUGIN
+	PluginDir string `yaml:"pluginDir,omitempty"`
+	// SSHHostPatterns are the hosts, or wildcard patterns, whose host certificates are trusted when signed by the
+	// SSH CA. Defaults to DefaultSSHHostPattern. Only for SSHKNOWNHOSTS
+	SSHHostPatterns []string `yaml:"sshHostPatterns,omitempty"`
+	// SystemdCredential is the name of the credentials written to the credstore: <name>.crt, holding the certificate
+	// followed by its chain, and <name>.key. Only for SYSTEMDCREDENTIAL
+	SystemdCredential string `yaml:"systemdCredential,omitempty"`
+	// SystemdCredstore is the directory the credentials are written to. Defaults to DefaultSystemdCredstore.
+	// Only for SYSTEMDCREDENTIAL
+	SystemdCredstore string `yaml:"systemdCredstore,omitempty"`
+	// SystemdEncrypt encrypts the private key with systemd-creds, bound to the host key and the TPM when available,
+	// and writes it to the encrypted credstore next to SystemdCredstore instead. Only for SYSTEMDCREDENTIAL
+	SystemdEncrypt bool `yaml:"systemdEncrypt,omitempty"`
+	// TruststoreFile is the path of a truststore written along with the keystore, holding only the chain
+	// certificates as trusted entries. Only for JKS
+	TruststoreFile string `yaml:"truststoreFile,omitempty"`
+	// TruststoreFormat is either jks or pkcs12. Defaults to TruststoreFormatJKS. Only for JKS
+	TruststoreFormat string `yaml:"truststoreFormat,omitempty"`
+	// TruststorePassword protects the integrity of the truststore. Only for JKS
+	TruststorePassword string             `yaml:"truststorePassword,omitempty"`
+	Type               InstallationFormat `yaml:"format,omitempty"`
+	// ValidateRevocation checks the installed certificate against OCSP, or CRL as fallback,
+	// and renews it when it has been revoked
+	ValidateRevocation bool   `yaml:"validateRevocation,omitempty"`
+	VaultAddress       string `yaml:"vaultAddress,omitempty"`
+	VaultAuthMount     string `yaml:"vaultAuthMount,omitempty"`
+	VaultCACert        string `yaml:"vaultCaCert,omitempty"`
+	VaultCertField     string `yaml:"vaultCertField,omitempty"`
+	VaultChainField    string `yaml:"vaultChainField,omitempty"`
+	VaultK8sRole       string `yaml:"vaultK8sRole,omitempty"`
+	VaultKeyField      string `yaml:"vaultKeyField,omitempty"`
+	VaultMount         string `yaml:"vaultMount,omitempty"`
+	VaultNamespace     string `yaml:"vaultNamespace,omitempty"`
+	VaultPath          string `yaml:"vaultPath,omitempty"`
+	VaultRoleID        string `yaml:"vaultRoleId,omitempty"`
+	VaultSecretID      string `yaml:"vaultSecretId,omitempty"`
+	VaultToken         string `yaml:"vaultToken,omitempty"`
+	// VerifyTLS is the host:port of a TLS endpoint that must serve the installed certificate. It is checked
+	// after the after-install actions run, and the installation fails when the endpoint serves a different certificate
+	VerifyTLS string `yaml:"verifyTLS,omitempty"`
+	// VerifyTLSServerName is the server name sent by the VerifyTLS handshake. Defaults to the host of VerifyTLS
+	VerifyTLSServerName string `yaml:"verifyTLSServerName,omitempty"`
+	// WebServerCommand is the binary testing and reloading the configuration of the web server. Defaults to
+	// DefaultNginxCommand or DefaultApacheCommand. Only for NGINX and APACHE
+	WebServerCommand string `yaml:"webServerCommand,omitempty"`
+	// WebServerConfig is the configuration file, i.e. the virtual host, whose certificate directives are updated to
+	// the installed files. Only for NGINX and APACHE
+	WebServerConfig string `yaml:"webServerConfig,omitempty"`
 }
 
 // Installations is a slice of Installation
 type Installations []Installation
 
+// GetBackupRetention returns the number of backups kept for each file of the installation
+func (installation Installation) GetBackupRetention() int {
+	if installation.BackupRetention == 0 {
+		return DefaultBackupRetention
+	}
+	return installation.BackupRetention
+}
+
+// GetDatabaseFiles returns the files the certificate and the private key of POSTGRESQL and MYSQL installations are
+// written to: file and keyFile when set, or the default files of the server in dbDataDir
+func (installation Installation) GetDatabaseFiles() (string, string) {
+	certFile, keyFile := postgreSQLCertFile, postgreSQLKeyFile
+	if installation.Type == FormatMySQL {
+		certFile, keyFile = mySQLCertFile, mySQLKeyFile
+	}
+	if installation.File != "" {
+		certFile = installation.File
+	} else {
+		certFile = filepath.Join(installation.DBDataDir, certFile)
+	}
+	if installation.KeyFile != "" {
+		keyFile = installation.KeyFile
+	} else {
+		keyFile = filepath.Join(installation.DBDataDir, keyFile)
+	}
+	return certFile, keyFile
+}
+
+// UnmarshalYAML reads the encryption of PKCS12 bundles from either p12Encryption or encryption
+func (installation *Installation) UnmarshalYAML(value *yaml.Node) error {
+	type plainInstallation Installation
+	aux := struct {
+		plainInstallation `yaml:",inline"`
+		Encryption        string `yaml:"encryption,omitempty"`
+	}{}
+	err := value.Decode(&aux)
+	if err != nil {
+		return err
+	}
+	*installation = Installation(aux.plainInstallation)
+	if aux.Encryption == "" {
+		return nil
+	}
+	if installation.P12Encryption != "" && !strings.EqualFold(installation.P12Encryption, aux.Encryption) {
+		return fmt.Errorf("%w: p12Encryption %q, encryption %q", ErrP12EncryptionConflict, installation.P12Encryption, aux.Encryption)
+	}
+	installation.P12Encryption = aux.Encryption
+	return nil
+}
+
+// GetPluginFile returns the path of the binary of the plugin of PLUGIN installations
+func (installation Installation) GetPluginFile() string {
+	dir := installation.PluginDir
+	if dir == "" {
+		dir = DefaultPluginDir
+		if runtime.GOOS == "windows" {
+			dir = DefaultWindowsPluginDir
+		}
+	}
+	binary := pluginBinaryPrefix + installation.Plugin
+	if runtime.GOOS == "windows" {
+		binary += ".exe"
+	}
+	return filepath.Join(dir, binary)
+}
+
+// GetSystemdCredentialFiles returns the files the certificate and the private key of SYSTEMDCREDENTIAL installations
+// are written to. The private key is written to the encrypted credstore when systemdEncrypt is set
+func (installation Installation) GetSystemdCredentialFiles() (string, string) {
+	credstore := installation.SystemdCredstore
+	if credstore == "" {
+		credstore = DefaultSystemdCredstore
+	}
+	credstore = filepath.Clean(credstore)
+	keyStore := credstore
+	if installation.SystemdEncrypt {
+		keyStore = credstore + systemdEncryptedSuffix
+	}
+	return filepath.Join(credstore, installation.SystemdCredential+systemdCertExtension),
+		filepath.Join(keyStore, installation.SystemdCredential+systemdKeyExtension)
+}
+
+// GetWebServerCommand returns the binary testing and reloading the configuration of NGINX and APACHE installations
+func (installation Installation) GetWebServerCommand() string {
+	if installation.WebServerCommand != "" {
+		return installation.WebServerCommand
+	}
+	if installation.Type == FormatApache {
+		return DefaultApacheCommand
+	}
+	return DefaultNginxCommand
+}
+
+// GetFiles returns the files written by the installation: file, keyFile, chainFile and truststoreFile, when set
+func (installation Installation) GetFiles() []string {
+	files := make([]string, 0, 4)
+	for _, file := range []string{installation.File, installation.KeyFile, installation.ChainFile, installation.TruststoreFile} {
+		if file != "" {
+			files = append(files, file)
+		}
+	}
+	return files
+}
+
+// Mirror returns a copy of the installation whose files are written to dir instead, under the same names
+func (installation Installation) Mirror(dir string) Installation {
+	mirror := installation
+	mirror.Mirrors = nil
+	rebase := func(file string) string {
+		if file == "" {
+			return ""
+		}
+		return filepath.Join(dir, filepath.Base(file))
+	}
+	mirror.File = rebase(installation.File)
+	mirror.KeyFile = rebase(installation.KeyFile)
+	mirror.ChainFile = rebase(installation.ChainFile)
+	mirror.TruststoreFile = rebase(installation.TruststoreFile)
+	return mirror
+}
+
 // IsValid returns true if the Installation type is supported by vcert
 func (installation Installation) IsValid() (bool, error) {
 	switch installation.Type {
@@ -78,15 +495,353 @@ func (installation Installation) IsValid() (bool, error) {
 		if err := validateCAPI(installation); err != nil {
 			return false, fmt.Errorf("\t\t\t%w", err)
 		}
+	case FormatK8sSecret:
+		if err := validateK8sSecret(installation); err != nil {
+			return false, fmt.Errorf("\t\t\t%w", err)
+		}
+	case FormatAzureKeyVault:
+		if err := validateAzureKeyVault(installation); err != nil {
+			return false, fmt.Errorf("\t\t\t%w", err)
+		}
+	case FormatAWSACM:
+		if err := validateAWSACM(installation); err != nil {
+			return false, fmt.Errorf("\t\t\t%w", err)
+		}
+	case FormatVaultKV:
+		if err := validateVaultKV(installation); err != nil {
+			return false, fmt.Errorf("\t\t\t%w", err)
+		}
+	case FormatGCP:
+		if err := validateGCP(installation); err != nil {
+			return false, fmt.Errorf("\t\t\t%w", err)
+		}
+	case FormatF5:
+		if err := validateF5(installation); err != nil {
+			return false, fmt.Errorf("\t\t\t%w", err)
+		}
+	case FormatCitrixADC:
+		if err := validateCitrixADC(installation); err != nil {
+			return false, fmt.Errorf("\t\t\t%w", err)
+		}
+	case FormatDockerSecret:
+		if err := validateDockerSecret(installation); err != nil {
+			return false, fmt.Errorf("\t\t\t%w", err)
+		}
+	case FormatNomadVariable:
+		if err := validateNomadVariable(installation); err != nil {
+			return false, fmt.Errorf("\t\t\t%w", err)
+		}
+	case FormatSSHCert, FormatSSHCAPub:
+		if err := validateSSHFile(installation); err != nil {
+			return false, fmt.Errorf("\t\t\t%w", err)
+		}
+	case FormatSSHKnownHosts:
+		if err := validateSSHKnownHosts(installation); err != nil {
+			return false, fmt.Errorf("\t\t\t%w", err)
+		}
+	case FormatPostgreSQL, FormatMySQL:
+		if err := validateDatabase(installation); err != nil {
+			return false, fmt.Errorf("\t\t\t%w", err)
+		}
+	case FormatSystemdCredential:
+		if err := validateSystemdCredential(installation); err != nil {
+			return false, fmt.Errorf("\t\t\t%w", err)
+		}
+	case FormatPlugin:
+		if err := validatePlugin(installation); err != nil {
+			return false, fmt.Errorf("\t\t\t%w", err)
+		}
+	case FormatNginx, FormatApache:
+		if err := validateWebServer(installation); err != nil {
+			return false, fmt.Errorf("\t\t\t%w", err)
+		}
 	case FormatUnknown:
 		fallthrough
 	default:
 		return false, fmt.Errorf("\t\t\t%w", ErrUndefinedInstallationFormat)
 	}
 
+	if (installation.BindIIS != "" || installation.BindRDP) && installation.Type != FormatCAPI {
+		return false, fmt.Errorf("\t\t\t%w", ErrBindNotInCAPI)
+	}
+
+	if err := installation.AfterAction.IsValid(); err != nil {
+		return false, fmt.Errorf("\t\t\t%w", err)
+	}
+
+	if installation.BackupRetention < 0 {
+		return false, fmt.Errorf("\t\t\t%w", ErrInvalidBackupRetention)
+	}
+
+	if err := validateMirrors(installation); err != nil {
+		return false, fmt.Errorf("\t\t\t%w", err)
+	}
+
+	if installation.VerifyTLS != "" {
+		if _, port, err := net.SplitHostPort(installation.VerifyTLS); err != nil || port == "" {
+			return false, fmt.Errorf("\t\t\t%w", ErrInvalidVerifyTLS)
+		}
+	}
+
 	return true, nil
 }
 
+func validateAWSACM(installation Installation) error {
+	if installation.AWSRegion == "" {
+		return ErrNoAWSRegion
+	}
+	if installation.AWSCertificateARN == "" && installation.AWSCertName == "" {
+		return ErrNoAWSCertificate
+	}
+	return nil
+}
+
+func validateAzureKeyVault(installation Installation) error {
+	if installation.AzureVaultURI == "" {
+		return ErrNoAzureVaultURI
+	}
+	if installation.AzureCertName == "" {
+		return ErrNoAzureCertName
+	}
+
+	if installation.AzureClientSecret == "" {
+		zap.L().Info("no azureClientSecret set. Using managed identity to authenticate to Azure Key Vault")
+	} else if installation.AzureTenantID == "" || installation.AzureClientID == "" {
+		return ErrIncompleteAzureClientSecret
+	}
+
+	return nil
+}
+
+func validateVaultKV(installation Installation) error {
+	if installation.VaultAddress == "" {
+		return ErrNoVaultAddress
+	}
+	if installation.VaultPath == "" {
+		return ErrNoVaultPath
+	}
+
+	switch {
+	case installation.VaultToken != "":
+	case installation.VaultRoleID != "":
+		if installation.VaultSecretID == "" {
+			return ErrNoVaultSecretID
+		}
+	case installation.VaultK8sRole != "":
+	default:
+		return ErrNoVaultAuth
+	}
+
+	if installation.VaultMount == "" {
+		zap.L().Info(fmt.Sprintf("no vaultMount set. Using mount '%s'", DefaultVaultMount))
+	}
+
+	return nil
+}
+
+func validateGCP(installation Installation) error {
+	if installation.GCPProject == "" {
+		return ErrNoGCPProject
+	}
+	if installation.GCPCertName == "" {
+		return ErrNoGCPCertName
+	}
+
+	switch strings.ToLower(installation.GCPTarget) {
+	case "", strings.ToLower(GCPTargetCertificateManager):
+	case strings.ToLower(GCPTargetSecretManager):
+		if installation.GCPLocation != "" {
+			zap.L().Warn("gcpLocation is ignored when gcpTarget is secretManager")
+		}
+	default:
+		return ErrInvalidGCPTarget
+	}
+
+	if installation.GCPCredentialsFile == "" {
+		zap.L().Info("no gcpCredentialsFile set. Using Application Default Credentials to authenticate to GCP")
+	}
+
+	return nil
+}
+
+func validateF5(installation Installation) error {
+	if installation.F5Address == "" {
+		return ErrNoF5Address
+	}
+	if installation.F5CertName == "" {
+		return ErrNoF5CertName
+	}
+	if installation.F5Username == "" || installation.F5Password == "" {
+		return ErrNoF5Credentials
+	}
+
+	for _, name := range []string{installation.F5CertName, installation.F5Partition, installation.F5Profile} {
+		if name != "" && !objectNameRegex.MatchString(name) {
+			return ErrInvalidF5Name
+		}
+	}
+
+	if installation.F5Profile == "" {
+		zap.L().Info("no f5Profile set. The certificate will be uploaded without being assigned to a client SSL profile")
+	}
+	if installation.F5Insecure {
+		zap.L().Warn("f5Insecure is set. The certificate of the BIG-IP management interface will not be verified")
+	}
+
+	return nil
+}
+
+func validateCitrixADC(installation Installation) error {
+	if installation.CitrixAddress == "" {
+		return ErrNoCitrixAddress
+	}
+	if installation.CitrixCertKey == "" {
+		return ErrNoCitrixCertKey
+	}
+	if installation.CitrixUsername == "" || installation.CitrixPassword == "" {
+		return ErrNoCitrixCredentials
+	}
+	if len(installation.CitrixCertKey) > citrixCertKeyMaxLength || !objectNameRegex.MatchString(installation.CitrixCertKey) {
+		return ErrInvalidCitrixCertKey
+	}
+	for _, vserver := range installation.CitrixVServers {
+		if strings.TrimSpace(vserver) == "" {
+			return ErrEmptyCitrixVServer
+		}
+	}
+
+	if len(installation.CitrixVServers) == 0 {
+		zap.L().Info("no citrixVservers set. The certificate will be installed without being bound to a virtual server")
+	}
+	if installation.CitrixInsecure {
+		zap.L().Warn("citrixInsecure is set. The certificate of the ADC management interface will not be verified")
+	}
+
+	return nil
+}
+
+func validateDockerSecret(installation Installation) error {
+	if installation.DockerSecretName == "" {
+		return ErrNoDockerSecretName
+	}
+	if len(installation.DockerSecretName) > dockerSecretNameMaxLength || !objectNameRegex.MatchString(installation.DockerSecretName) {
+		return ErrInvalidDockerSecretName
+	}
+	if installation.DockerHost != "" {
+		scheme, _, found := strings.Cut(installation.DockerHost, "://")
+		if !found || (scheme != "unix" && scheme != "tcp") {
+			return ErrInvalidDockerHost
+		}
+	}
+	for _, service := range installation.DockerServices {
+		if strings.TrimSpace(service) == "" {
+			return ErrEmptyDockerService
+		}
+	}
+
+	if len(installation.DockerServices) == 0 {
+		zap.L().Info("no dockerServices set. The secrets will be created without being rolled out to a service")
+	}
+
+	return nil
+}
+
+func validateNomadVariable(installation Installation) error {
+	if installation.NomadPath == "" {
+		return ErrNoNomadPath
+	}
+	if !nomadPathRegex.MatchString(installation.NomadPath) {
+		return ErrInvalidNomadPath
+	}
+
+	if installation.NomadAddress == "" {
+		zap.L().Info(fmt.Sprintf("no nomadAddress set. Using address '%s'", DefaultNomadAddress))
+	}
+
+	return nil
+}
+
+func validateDatabase(installation Installation) error {
+	if installation.DBDataDir == "" && (installation.File == "" || installation.KeyFile == "") {
+		return ErrNoDBDataDir
+	}
+	if installation.DBAddress != "" && !strings.HasPrefix(installation.DBAddress, "/") {
+		if _, port, err := net.SplitHostPort(installation.DBAddress); err != nil || port == "" {
+			return ErrInvalidDBAddress
+		}
+	}
+	if installation.KeyPassword != "" {
+		return ErrDBKeyPassword
+	}
+	return validateFilePermissions(installation)
+}
+
+func validateSystemdCredential(installation Installation) error {
+	if runtime.GOOS == "windows" {
+		return ErrSystemdCredentialOnWindows
+	}
+	if installation.SystemdCredential == "" {
+		return ErrNoSystemdCredential
+	}
+	if !objectNameRegex.MatchString(installation.SystemdCredential) {
+		return ErrInvalidSystemdCredential
+	}
+	if installation.SystemdCredstore != "" && !filepath.IsAbs(installation.SystemdCredstore) {
+		return ErrInvalidSystemdCredstore
+	}
+	if installation.KeyPassword != "" {
+		return ErrSystemdCredentialKeyPassword
+	}
+	return validateFilePermissions(installation)
+}
+
+func validatePlugin(installation Installation) error {
+	if installation.Plugin == "" {
+		return ErrNoPlugin
+	}
+	if !objectNameRegex.MatchString(installation.Plugin) {
+		return ErrInvalidPlugin
+	}
+	if installation.PluginDir != "" && !filepath.IsAbs(installation.PluginDir) {
+		return ErrInvalidPluginDir
+	}
+	return nil
+}
+
+func validateWebServer(installation Installation) error {
+	if installation.WebServerConfig == "" {
+		return ErrNoWebServerConfig
+	}
+	if installation.File == "" || installation.KeyFile == "" {
+		return ErrNoWebServerFiles
+	}
+	for _, location := range []string{installation.File, installation.KeyFile, installation.ChainFile} {
+		if location != "" && !filepath.IsAbs(location) {
+			return ErrInvalidWebServerFile
+		}
+	}
+	if installation.KeyPassword != "" {
+		return ErrWebServerKeyPassword
+	}
+	return validateFilePermissions(installation)
+}
+
+func validateSSHFile(installation Installation) error {
+	if installation.File == "" {
+		return ErrNoInstallationFile
+	}
+	return validateFilePermissions(installation)
+}
+
+func validateSSHKnownHosts(installation Installation) error {
+	for _, pattern := range installation.SSHHostPatterns {
+		if pattern == "" || strings.ContainsAny(pattern, " \t\r\n,") {
+			return ErrInvalidSSHHostPattern
+		}
+	}
+	return validateSSHFile(installation)
+}
+
 func validateCAPI(installation Installation) error {
 	if runtime.GOOS != "windows" {
 		return ErrCAPIOnNonWindows
@@ -125,23 +880,58 @@ func validateCAPI(installation Installation) error {
 		return ErrInvalidCAPILocation
 	}
 
-	// valid store names from https://learn.microsoft.com/en-us/dotnet/api/system.security.cryptography.x509certificates.storename?view=net-7.0
-	// Although it is unlikely that you'd want to install a certificate and private key in anything but "my", here for completeness
-	isValidStoreName := false
-	for _, v := range validStoreNames {
-		if v == strings.ToLower(segments[1]) {
-			isValidStoreName = true
+	// Known store names from https://learn.microsoft.com/en-us/dotnet/api/system.security.cryptography.x509certificates.storename?view=net-7.0
+	// Other stores (i.e. WebHosting) can be used as long as the name is safe to pass to PowerShell
+	storeName := segments[1]
+	isKnownStoreName := false
+	for _, v := range knownStoreNames {
+		if v == strings.ToLower(storeName) {
+			isKnownStoreName = true
 			break
 		}
 	}
 
-	if !isValidStoreName {
-		return ErrInvalidCAPIStoreName
+	if !isKnownStoreName {
+		if strings.TrimSpace(storeName) == "" || !capiValueRegex.MatchString(storeName) {
+			return ErrInvalidCAPIStoreName
+		}
+		zap.L().Warn(fmt.Sprintf("using custom CAPI store '%s'. It will be created if it does not exist", storeName))
+	}
+
+	if installation.CAPIKeyStorageProvider != "" && !capiValueRegex.MatchString(installation.CAPIKeyStorageProvider) {
+		return ErrInvalidCAPIKeyStorageProvider
+	}
+
+	// IIS and the RDP listener run as services, so they only use certificates from the LocalMachine stores
+	if (installation.BindIIS != "" || installation.BindRDP) && capiLocation != capiLocationLocalMachine {
+		return ErrBindNotInLocalMachine
+	}
+	if installation.BindIIS != "" {
+		if _, _, err := ParseBindIIS(installation.BindIIS); err != nil {
+			return err
+		}
 	}
 
 	return nil
 }
 
+// ParseBindIIS returns the site and port of an Installation.BindIIS value, in the form site:port
+func ParseBindIIS(value string) (string, int, error) {
+	i := strings.LastIndex(value, ":")
+	if i < 0 {
+		return "", 0, ErrInvalidBindIIS
+	}
+	site := value[:i]
+	if strings.TrimSpace(site) == "" || !capiValueRegex.MatchString(site) {
+		return "", 0, ErrInvalidBindIIS
+	}
+	port, err := strconv.Atoi(value[i+1:])
+	if err != nil || port < 1 || port > 65535 {
+		return "", 0, ErrInvalidBindIIS
+	}
+	return site, port, nil
+}
+
 func validateJKS(installation Installation) error {
 	if installation.File == "" {
 		return ErrNoInstallationFile
@@ -165,6 +955,42 @@ func validateJKS(installation Installation) error {
 		}
 	}
 
+	if installation.TruststoreFile != "" {
+		if err := validateTruststore(installation); err != nil {
+			return err
+		}
+	}
+
+	return validateFilePermissions(installation)
+}
+
+func validateTruststore(installation Installation) error {
+	if installation.TruststoreFile == installation.File {
+		return ErrTruststoreFileIsKeystore
+	}
+	if installation.TruststorePassword == "" {
+		return ErrNoTruststorePassword
+	}
+	if len(installation.TruststorePassword) < JKSMinPasswordLength {
+		return ErrTruststorePasswordLength
+	}
+	switch strings.ToLower(installation.TruststoreFormat) {
+	case "", TruststoreFormatJKS, TruststoreFormatPKCS12:
+	default:
+		return ErrInvalidTruststoreFormat
+	}
+	return nil
+}
+
+func validateK8sSecret(installation Installation) error {
+	if installation.K8sSecretName == "" {
+		return ErrNoK8sSecretName
+	}
+
+	if installation.K8sNamespace == "" {
+		zap.L().Warn(fmt.Sprintf("no k8sNamespace set. Using namespace '%s'", DefaultK8sNamespace))
+	}
+
 	return nil
 }
 
@@ -173,13 +999,30 @@ func validatePEM(installation Installation) error {
 		return ErrNoInstallationFile
 	}
 
-	if installation.ChainFile == "" {
+	// The chain, and the key, can be written to File instead of their own files
+	bundle := strings.ToLower(installation.PEMBundle)
+	switch bundle {
+	case "", PEMBundleCertChain, PEMBundleCertKeyChain:
+	default:
+		return ErrInvalidPEMBundle
+	}
+	if installation.ChainFile == "" && bundle == "" {
 		return ErrNoChainFile
 	}
-	if installation.KeyFile == "" {
+	if installation.KeyFile == "" && bundle != PEMBundleCertKeyChain {
 		return ErrNoKeyFile
 	}
-	return nil
+	switch strings.ToLower(installation.ChainOrder) {
+	case "", ChainOrderRootFirst, ChainOrderRootLast:
+	default:
+		return ErrInvalidChainOrder
+	}
+	switch strings.ToLower(installation.KeyFormat) {
+	case "", KeyFormatPKCS1, KeyFormatPKCS8:
+	default:
+		return ErrInvalidKeyFormat
+	}
+	return validateFilePermissions(installation)
 }
 
 func validateP12(installation Installation) error {
@@ -189,5 +1032,63 @@ func validateP12(installation Installation) error {
 	if installation.P12Password == "" {
 		return ErrNoP12Password
 	}
+	switch strings.ToLower(installation.P12Encryption) {
+	case "", P12EncryptionLegacy, P12EncryptionModern:
+	default:
+		return ErrInvalidP12Encryption
+	}
+	return validateFilePermissions(installation)
+}
+
+func validateMirrors(installation Installation) error {
+	if len(installation.Mirrors) == 0 {
+		return nil
+	}
+	switch installation.Type {
+	case FormatPEM, FormatPKCS12, FormatJKS:
+	default:
+		return ErrMirrorsNotSupported
+	}
+
+	// Files are written to the mirrors by name, and a mirror in the directory of a file of the installation would
+	// overwrite it
+	dirs := make(map[string]bool)
+	names := make(map[string]string)
+	for _, file := range installation.GetFiles() {
+		name := filepath.Base(file)
+		if other, ok := names[name]; ok && filepath.Clean(other) != filepath.Clean(file) {
+			return ErrMirrorFileNames
+		}
+		names[name] = file
+		dirs[filepath.Clean(filepath.Dir(file))] = true
+	}
+	for _, mirror := range installation.Mirrors {
+		dir := filepath.Clean(mirror)
+		if mirror == "" || dirs[dir] {
+			return fmt.Errorf("%w: %q", ErrInvalidMirror, mirror)
+		}
+		dirs[dir] = true
+	}
 	return nil
 }
+
+func validateFilePermissions(installation Installation) error {
+	if installation.Mode != "" {
+		if _, err := ParseFileMode(installation.Mode); err != nil {
+			return err
+		}
+	}
+	if runtime.GOOS == "windows" && (installation.Owner != "" || installation.Group != "") {
+		return ErrFileOwnershipNotSupported
+	}
+	return nil
+}
+
+// ParseFileMode returns the file mode represented by the octal string mode, i.e. "0640"
+func ParseFileMode(mode string) (os.FileMode, error) {
+	value, err := strconv.ParseUint(strings.TrimPrefix(mode, "0o"), 8, 32)
+	if err != nil || value > 0777 {
+		return 0, ErrInvalidFileMode
+	}
+	return os.FileMode(value), nil
+}
@@ -23,7 +23,8 @@ import (
 )
 
 // InstallationFormat represents the type of installation to be done:
-// PEM, PKCS12, JKS or CAPI (only on Windows environments)
+// PEM, PKCS12, JKS, CAPI (only on Windows environments), K8SSECRET, AZUREKEYVAULT, AWSACM, VAULTKV, GCP, F5, CITRIXADC,
+// DOCKERSECRET, NOMADVARIABLE, POSTGRESQL, MYSQL, SYSTEMDCREDENTIAL, PLUGIN, NGINX or APACHE
 type InstallationFormat int64
 
 const (
@@ -37,13 +38,69 @@ const (
 	FormatPEM
 	// FormatPKCS12 represents an installation with the PKCS12 format
 	FormatPKCS12
+	// FormatK8sSecret represents an installation in a kubernetes.io/tls Secret
+	FormatK8sSecret
+	// FormatAzureKeyVault represents an installation in an Azure Key Vault certificate object
+	FormatAzureKeyVault
+	// FormatAWSACM represents an installation in AWS Certificate Manager
+	FormatAWSACM
+	// FormatVaultKV represents an installation in a HashiCorp Vault KV v2 secret
+	FormatVaultKV
+	// FormatGCP represents an installation in Google Certificate Manager or Secret Manager
+	FormatGCP
+	// FormatF5 represents an installation in an F5 BIG-IP, optionally assigned to a client SSL profile
+	FormatF5
+	// FormatCitrixADC represents an installation in a Citrix ADC (NetScaler), optionally bound to SSL virtual servers
+	FormatCitrixADC
+	// FormatDockerSecret represents an installation in Docker Swarm secrets, optionally rolled out to Swarm services
+	FormatDockerSecret
+	// FormatNomadVariable represents an installation in a HashiCorp Nomad variable
+	FormatNomadVariable
+	// FormatSSHCert represents an installation of an SSH certificate along with its key pair, in OpenSSH format
+	FormatSSHCert
+	// FormatSSHKnownHosts represents an installation of the public key of an SSH CA as a @cert-authority entry of a known_hosts file
+	FormatSSHKnownHosts
+	// FormatSSHCAPub represents an installation of the public key of an SSH CA in a file, i.e. for TrustedUserCAKeys
+	FormatSSHCAPub
+	// FormatPostgreSQL represents an installation of the server certificate of PostgreSQL, reloaded with pg_reload_conf()
+	FormatPostgreSQL
+	// FormatMySQL represents an installation of the server certificate of MySQL or MariaDB, reloaded without a restart
+	FormatMySQL
+	// FormatSystemdCredential represents an installation in a systemd credstore, loaded by services with LoadCredential
+	FormatSystemdCredential
+	// FormatPlugin represents an installation made by an external plugin binary, exchanging JSON over stdin and stdout
+	FormatPlugin
+	// FormatNginx represents an installation of the certificate of NGINX, whose configuration is updated to the
+	// installed files, tested and reloaded
+	FormatNginx
+	// FormatApache represents an installation of the certificate of Apache HTTP Server, whose configuration is updated
+	// to the installed files, tested and reloaded
+	FormatApache
 
 	// String representations of the InstallationFormat types
-	stringCAPI    = "CAPI"
-	stringJKS     = "JKS"
-	stringPEM     = "PEM"
-	stringPKCS12  = "PKCS12"
-	stringUnknown = "Unknown"
+	stringApache            = "APACHE"
+	stringAWSACM            = "AWSACM"
+	stringAzureKeyVault     = "AZUREKEYVAULT"
+	stringCAPI              = "CAPI"
+	stringCitrixADC         = "CITRIXADC"
+	stringDockerSecret      = "DOCKERSECRET"
+	stringF5                = "F5"
+	stringGCP               = "GCP"
+	stringJKS               = "JKS"
+	stringK8sSecret         = "K8SSECRET"
+	stringMySQL             = "MYSQL"
+	stringNginx             = "NGINX"
+	stringNomadVariable     = "NOMADVARIABLE"
+	stringPEM               = "PEM"
+	stringPKCS12            = "PKCS12"
+	stringPlugin            = "PLUGIN"
+	stringPostgreSQL        = "POSTGRESQL"
+	stringSSHCAPub          = "SSHCAPUB"
+	stringSSHCert           = "SSHCERT"
+	stringSSHKnownHosts     = "SSHKNOWNHOSTS"
+	stringSystemdCredential = "SYSTEMDCREDENTIAL"
+	stringUnknown           = "Unknown"
+	stringVaultKV           = "VAULTKV"
 )
 
 // String returns a string representation of this object
@@ -57,11 +114,62 @@ func (it *InstallationFormat) String() string {
 		return stringJKS
 	case FormatCAPI:
 		return stringCAPI
+	case FormatK8sSecret:
+		return stringK8sSecret
+	case FormatAzureKeyVault:
+		return stringAzureKeyVault
+	case FormatAWSACM:
+		return stringAWSACM
+	case FormatVaultKV:
+		return stringVaultKV
+	case FormatGCP:
+		return stringGCP
+	case FormatF5:
+		return stringF5
+	case FormatCitrixADC:
+		return stringCitrixADC
+	case FormatDockerSecret:
+		return stringDockerSecret
+	case FormatNomadVariable:
+		return stringNomadVariable
+	case FormatSSHCert:
+		return stringSSHCert
+	case FormatSSHKnownHosts:
+		return stringSSHKnownHosts
+	case FormatSSHCAPub:
+		return stringSSHCAPub
+	case FormatPostgreSQL:
+		return stringPostgreSQL
+	case FormatMySQL:
+		return stringMySQL
+	case FormatSystemdCredential:
+		return stringSystemdCredential
+	case FormatPlugin:
+		return stringPlugin
+	case FormatNginx:
+		return stringNginx
+	case FormatApache:
+		return stringApache
 	default:
 		return stringUnknown
 	}
 }
 
+// IsDatabase returns true for the formats installing the server certificate of a database
+func (it InstallationFormat) IsDatabase() bool {
+	return it == FormatPostgreSQL || it == FormatMySQL
+}
+
+// IsWebServer returns true for the formats installing the certificate of a web server from its configuration file
+func (it InstallationFormat) IsWebServer() bool {
+	return it == FormatNginx || it == FormatApache
+}
+
+// IsSSH returns true for the formats installed by sshCertificate tasks
+func (it InstallationFormat) IsSSH() bool {
+	return it == FormatSSHCert || it == FormatSSHKnownHosts || it == FormatSSHCAPub
+}
+
 // MarshalYAML customizes the behavior of ChainOption when being marshaled into a YAML document.
 // The returned value is marshaled in place of the original value implementing Marshaller
 func (it InstallationFormat) MarshalYAML() (interface{}, error) {
@@ -84,14 +192,50 @@ func (it *InstallationFormat) UnmarshalYAML(value *yaml.Node) error {
 
 func parseInstallationType(installationType string) (InstallationFormat, error) {
 	switch strings.ToUpper(installationType) {
+	case stringApache:
+		return FormatApache, nil
+	case stringAWSACM:
+		return FormatAWSACM, nil
+	case stringAzureKeyVault:
+		return FormatAzureKeyVault, nil
 	case stringCAPI:
 		return FormatCAPI, nil
+	case stringCitrixADC:
+		return FormatCitrixADC, nil
+	case stringDockerSecret:
+		return FormatDockerSecret, nil
+	case stringF5:
+		return FormatF5, nil
+	case stringGCP:
+		return FormatGCP, nil
 	case stringJKS:
 		return FormatJKS, nil
+	case stringK8sSecret:
+		return FormatK8sSecret, nil
+	case stringMySQL:
+		return FormatMySQL, nil
+	case stringNginx:
+		return FormatNginx, nil
+	case stringNomadVariable:
+		return FormatNomadVariable, nil
 	case stringPEM:
 		return FormatPEM, nil
 	case stringPKCS12:
 		return FormatPKCS12, nil
+	case stringPlugin:
+		return FormatPlugin, nil
+	case stringPostgreSQL:
+		return FormatPostgreSQL, nil
+	case stringSSHCAPub:
+		return FormatSSHCAPub, nil
+	case stringSSHCert:
+		return FormatSSHCert, nil
+	case stringSSHKnownHosts:
+		return FormatSSHKnownHosts, nil
+	case stringSystemdCredential:
+		return FormatSystemdCredential, nil
+	case stringVaultKV:
+		return FormatVaultKV, nil
 	default:
 		return FormatUnknown, nil
 	}
@@ -40,9 +40,27 @@ func (s *InstallationTypeSuite) SetupTest() {
 	}{
 		{it: FormatCAPI, strValue: stringCAPI},
 		{it: FormatJKS, strValue: stringJKS},
+		{it: FormatK8sSecret, strValue: stringK8sSecret},
+		{it: FormatAzureKeyVault, strValue: stringAzureKeyVault},
+		{it: FormatAWSACM, strValue: stringAWSACM},
 		{it: FormatPEM, strValue: stringPEM},
 		{it: FormatPKCS12, strValue: stringPKCS12},
 		{it: FormatUnknown, strValue: stringUnknown},
+		{it: FormatVaultKV, strValue: stringVaultKV},
+		{it: FormatGCP, strValue: stringGCP},
+		{it: FormatF5, strValue: stringF5},
+		{it: FormatCitrixADC, strValue: stringCitrixADC},
+		{it: FormatDockerSecret, strValue: stringDockerSecret},
+		{it: FormatNomadVariable, strValue: stringNomadVariable},
+		{it: FormatSSHCert, strValue: stringSSHCert},
+		{it: FormatSSHKnownHosts, strValue: stringSSHKnownHosts},
+		{it: FormatSSHCAPub, strValue: stringSSHCAPub},
+		{it: FormatPostgreSQL, strValue: stringPostgreSQL},
+		{it: FormatMySQL, strValue: stringMySQL},
+		{it: FormatSystemdCredential, strValue: stringSystemdCredential},
+		{it: FormatPlugin, strValue: stringPlugin},
+		{it: FormatNginx, strValue: stringNginx},
+		{it: FormatApache, strValue: stringApache},
 	}
 
 	s.testYaml = `---
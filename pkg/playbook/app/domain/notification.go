@@ -0,0 +1,91 @@
+/*
+ * Copyright 2023 Venafi, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *  http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package domain
+
+import (
+	"fmt"
+	"net/url"
+	"strings"
+)
+
+const (
+	// NotificationWebhook posts the event as JSON, or the rendered template, to a URL
+	NotificationWebhook = "webhook"
+	// NotificationSlack posts the message to a Slack incoming webhook
+	NotificationSlack = "slack"
+	// NotificationTeams posts the message to a Microsoft Teams incoming webhook
+	NotificationTeams = "teams"
+
+	// EventSuccess is fired when a certificate is enrolled and installed
+	EventSuccess = "success"
+	// EventFailure is fired when a task fails
+	EventFailure = "failure"
+	// EventExpiring is fired when an installed certificate is found within the renewal window of its task
+	EventExpiring = "expiring"
+	// EventDrift is fired when an installed certificate is not the one vcert installed, as recorded in the state file
+	EventDrift = "drift"
+)
+
+// Notification describes where and when the results of the certificate tasks are sent
+type Notification struct {
+	// Events are the events that trigger the notification. All of them do when empty
+	Events []string `yaml:"events,omitempty"`
+	// Headers are added to the request. Environment variables in the form $VAR or ${VAR} are expanded
+	Headers map[string]string `yaml:"headers,omitempty"`
+	// Template is the message, or the body of a webhook. The fields of the event in the form ${name}, i.e.
+	// ${commonName}, and environment variables are expanded
+	Template string `yaml:"template,omitempty"`
+	// Type is either NotificationWebhook, NotificationSlack or NotificationTeams
+	Type string `yaml:"type"`
+	URL  string `yaml:"url"`
+}
+
+// IsValid returns an error if the notification type, URL or events are not valid
+func (n Notification) IsValid() error {
+	switch strings.ToLower(n.Type) {
+	case NotificationWebhook, NotificationSlack, NotificationTeams:
+	default:
+		return ErrInvalidNotificationType
+	}
+
+	u, err := url.Parse(n.URL)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return ErrInvalidNotificationURL
+	}
+
+	for _, event := range n.Events {
+		switch strings.ToLower(event) {
+		case EventSuccess, EventFailure, EventExpiring, EventDrift:
+		default:
+			return fmt.Errorf("%w: %s", ErrInvalidNotificationEvent, event)
+		}
+	}
+	return nil
+}
+
+// NotifiesOn returns true when event triggers the notification
+func (n Notification) NotifiesOn(event string) bool {
+	if len(n.Events) == 0 {
+		return true
+	}
+	for _, e := range n.Events {
+		if strings.EqualFold(e, event) {
+			return true
+		}
+	}
+	return false
+}
@@ -79,7 +79,7 @@ func (p Playbook) IsValid() (bool, error) {
 		if !taskNames[t.Name] {
 			taskNames[t.Name] = true
 		} else {
-			rErr = errors.Join(rErr, fmt.Errorf("task '%s' is defined multiple times", t.Name))
+			rErr = errors.Join(rErr, fmt.Errorf("%w: %s", ErrDuplicateTaskName, t.Name))
 			rValid = false
 		}
 
@@ -88,6 +88,59 @@ func (p Playbook) IsValid() (bool, error) {
 			rErr = errors.Join(rErr, fmt.Errorf("task '%s' is invalid: %w", t.Name, err))
 			rValid = false
 		}
+
+		connection, found := p.Config.GetConnection(t.Connection)
+		if !found {
+			rErr = errors.Join(rErr, fmt.Errorf("task '%s' is invalid: %w: %s", t.Name, ErrUnknownConnection, t.Connection))
+			rValid = false
+			connection = p.Config.Connection
+		}
+
+		// Revocation is implemented by the TPP connector. VaaS retires the certificate instead
+		platform := connection.Platform
+		if t.IsRevocation() && platform != venafi.TPP && platform != venafi.TLSPCloud {
+			rErr = errors.Join(rErr, fmt.Errorf("task '%s' is invalid: %w", t.Name, ErrRevokeNotSupported))
+			rValid = false
+		}
+		if t.IsRevocation() && platform == venafi.TLSPCloud && t.Revoke.Serial != "" {
+			rErr = errors.Join(rErr, fmt.Errorf("task '%s' is invalid: %w", t.Name, ErrRevokeSerialNotSupported))
+			rValid = false
+		}
+		// SSH certificates are only issued by TPP SSH CAs
+		if t.IsSSHCertificate() && platform != venafi.TPP {
+			rErr = errors.Join(rErr, fmt.Errorf("task '%s' is invalid: %w", t.Name, ErrSSHNotSupported))
+			rValid = false
+		}
+		// Certificates are provisioned to Application objects by TPP
+		if len(t.Request.ApplicationDNs) > 0 && platform != venafi.TPP {
+			rErr = errors.Join(rErr, fmt.Errorf("task '%s' is invalid: %w", t.Name, ErrApplicationDNsNotSupported))
+			rValid = false
+		}
+		if t.Request.IsPKCS12Keystore() && platform != venafi.TPP {
+			rErr = errors.Join(rErr, fmt.Errorf("task '%s' is invalid: %w", t.Name, ErrPKCS12KeystoreNotSupported))
+			rValid = false
+		}
+		// Installed certificates are adopted by searching them in the platform, and remembered in the state file
+		if t.Adopt {
+			if t.IsRevocation() || t.IsSSHCertificate() {
+				rErr = errors.Join(rErr, fmt.Errorf("task '%s' is invalid: %w", t.Name, ErrAdoptAction))
+				rValid = false
+			} else if platform != venafi.TPP && platform != venafi.TLSPCloud {
+				rErr = errors.Join(rErr, fmt.Errorf("task '%s' is invalid: %w", t.Name, ErrAdoptNotSupported))
+				rValid = false
+			}
+			if p.Config.StateFile == "" {
+				rErr = errors.Join(rErr, fmt.Errorf("task '%s' is invalid: %w", t.Name, ErrAdoptNoStateFile))
+				rValid = false
+			}
+		}
+		// The certificates installed are compared with the ones recorded in the state file
+		for i, installation := range t.Installations {
+			if installation.Enforce && p.Config.StateFile == "" {
+				rErr = errors.Join(rErr, fmt.Errorf("task '%s' is invalid: installations[%d]: %w", t.Name, i, ErrEnforceNoStateFile))
+				rValid = false
+			}
+		}
 	}
 
 	return rValid, rErr
@@ -17,19 +17,39 @@
 package domain
 
 import (
+	"crypto"
+	"fmt"
+	"strings"
+	"time"
+
 	"github.com/Venafi/vcert/v5/pkg/certificate"
+	"github.com/Venafi/vcert/v5/pkg/pkcs11"
+	"github.com/Venafi/vcert/v5/pkg/tpm"
 	"github.com/Venafi/vcert/v5/pkg/util"
+	"gopkg.in/yaml.v3"
+)
+
+const (
+	// KeystoreFormatPEM retrieves the private key generated by the service as an encrypted PEM block, the default
+	KeystoreFormatPEM = "pem"
+	// KeystoreFormatPKCS12 retrieves the private key generated by the service inside a PKCS#12 keystore
+	KeystoreFormatPKCS12 = "pkcs12"
 )
 
 // PlaybookRequest Contains data needed to generate a certificate request
 // CSR is a PEM-encoded Certificate Signing PlaybookRequest
 type PlaybookRequest struct {
+	// ApplicationDNs are the TPP Application objects the certificate is associated with, which TPP provisions it to
+	ApplicationDNs []string                  `yaml:"applicationDNs,omitempty"`
 	CADN           string                    `yaml:"cadn,omitempty"`
 	ChainOption    certificate.ChainOption   `yaml:"chain,omitempty"`
+	CsrFile        string                    `yaml:"csrFile,omitempty"`
 	CsrOrigin      string                    `yaml:"csr,omitempty"`
 	CustomFields   []certificate.CustomField `yaml:"fields,omitempty"`
 	DNSNames       []string                  `yaml:"sanDNS,omitempty"`
 	EmailAddresses []string                  `yaml:"sanEmail,omitempty"`
+	ExtKeyUsages   []string                  `yaml:"extKeyUsages,omitempty"`
+	Extensions     []Extension               `yaml:"extensions,omitempty"`
 	FriendlyName   string                    `yaml:"nickname,omitempty"`
 	IPAddresses    []string                  `yaml:"sanIP,omitempty"`
 	IssuerHint     util.IssuerHint           `yaml:"issuerHint,omitempty"`
@@ -37,13 +57,82 @@ type PlaybookRequest struct {
 	KeyLength      int                       `yaml:"keySize,omitempty"`
 	KeyPassword    string                    `yaml:"-"`
 	KeyType        certificate.KeyType       `yaml:"keyType,omitempty"`
-	Location       certificate.Location      `yaml:"location,omitempty"`
-	OmitSANs       bool                      `yaml:"omitSans,omitempty"`
-	Origin         string                    `yaml:"appInfo,omitempty"`
-	Subject        Subject                   `yaml:"subject,omitempty"`
-	Timeout        int                       `yaml:"timeout,omitempty"`
-	UPNs           []string                  `yaml:"sanUPN,omitempty"`
-	URIs           []string                  `yaml:"sanURI,omitempty"`
-	ValidDays      string                    `yaml:"validDays,omitempty"`
-	Zone           string                    `yaml:"zone,omitempty"`
+	// KeystoreFormat is the format the private key generated by the service is downloaded in, pem or pkcs12
+	KeystoreFormat string `yaml:"keystoreFormat,omitempty"`
+	// KeystorePassword protects the private key generated by the service while it is downloaded.
+	// A random password is used when it is not set
+	KeystorePassword string               `yaml:"keystorePassword,omitempty"`
+	Location         certificate.Location `yaml:"location,omitempty"`
+	// NotAfter is the RFC 3339 date and time the certificate is requested to expire
+	NotAfter   string           `yaml:"notAfter,omitempty"`
+	OmitSANs   bool             `yaml:"omitSans,omitempty"`
+	Origin     string           `yaml:"appInfo,omitempty"`
+	PKCS11     *pkcs11.Config   `yaml:"pkcs11,omitempty"`
+	PrivateKey crypto.Signer    `yaml:"-"`
+	PublicKey  crypto.PublicKey `yaml:"-"`
+	ReuseKey   bool             `yaml:"reuseKey,omitempty"`
+	Subject    Subject          `yaml:"subject,omitempty"`
+	Timeout    int              `yaml:"timeout,omitempty"`
+	TPM        *tpm.Config      `yaml:"tpm,omitempty"`
+	UPNs       []string         `yaml:"sanUPN,omitempty"`
+	URIs       []string         `yaml:"sanURI,omitempty"`
+	ValidDays  string           `yaml:"validDays,omitempty"`
+	// ValidityHours is the number of hours the certificate is requested to be valid, for short-lived certificates
+	ValidityHours int      `yaml:"validityHours,omitempty"`
+	Zone          string   `yaml:"zone,omitempty"`
+	Zones         []string `yaml:"zones,omitempty"`
+}
+
+// GetZones returns the zones to enroll the certificate in, by order of preference: Zone followed by Zones.
+// The next zone is only used when enrollment fails in the previous one. Repeated zones are only returned once
+func (request PlaybookRequest) GetZones() []string {
+	zones := make([]string, 0, len(request.Zones)+1)
+	seen := make(map[string]bool)
+	for _, zone := range append([]string{request.Zone}, request.Zones...) {
+		if zone == "" || seen[zone] {
+			continue
+		}
+		seen[zone] = true
+		zones = append(zones, zone)
+	}
+	return zones
+}
+
+// GetNotAfter returns the date the certificate is requested to expire, or nil when NotAfter is not set
+func (request PlaybookRequest) GetNotAfter() (*time.Time, error) {
+	if request.NotAfter == "" {
+		return nil, nil
+	}
+	notAfter, err := time.Parse(time.RFC3339, request.NotAfter)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrInvalidNotAfter, err)
+	}
+	return &notAfter, nil
+}
+
+// IsPKCS12Keystore returns true when the private key generated by the service is downloaded as a PKCS#12 keystore
+func (request PlaybookRequest) IsPKCS12Keystore() bool {
+	return strings.EqualFold(request.KeystoreFormat, KeystoreFormatPKCS12)
+}
+
+// UnmarshalYAML reads the origin of the CSR from either csr or csrOrigin
+func (request *PlaybookRequest) UnmarshalYAML(value *yaml.Node) error {
+	type plainRequest PlaybookRequest
+	aux := struct {
+		plainRequest `yaml:",inline"`
+		CsrOrigin    string `yaml:"csrOrigin,omitempty"`
+	}{}
+	err := value.Decode(&aux)
+	if err != nil {
+		return err
+	}
+	*request = PlaybookRequest(aux.plainRequest)
+	if aux.CsrOrigin == "" {
+		return nil
+	}
+	if request.CsrOrigin != "" && !strings.EqualFold(request.CsrOrigin, aux.CsrOrigin) {
+		return fmt.Errorf("%w: csr %q, csrOrigin %q", ErrCSROriginConflict, request.CsrOrigin, aux.CsrOrigin)
+	}
+	request.CsrOrigin = aux.CsrOrigin
+	return nil
 }
@@ -22,8 +22,12 @@ import (
 	"testing"
 
 	"github.com/Venafi/vcert/v5/pkg/endpoint"
+	"github.com/Venafi/vcert/v5/pkg/pkcs11"
+	"github.com/Venafi/vcert/v5/pkg/tpm"
+	"github.com/Venafi/vcert/v5/pkg/util"
 	"github.com/Venafi/vcert/v5/pkg/venafi"
 	"github.com/stretchr/testify/suite"
+	"gopkg.in/yaml.v3"
 )
 
 type PlaybookSuite struct {
@@ -59,6 +63,18 @@ func (s *PlaybookSuite) SetupTest() {
 		},
 	}
 
+	tppConfig := Config{
+		Connection: Connection{
+			Platform: venafi.TPP,
+			URL:      "https://tpp.venafi.example",
+			Credentials: Authentication{
+				Authentication: endpoint.Authentication{
+					AccessToken: "foobarGibberish123",
+				},
+			},
+		},
+	}
+
 	s.testCases = []testCase{
 		{
 			err:  ErrNoConfig,
@@ -67,6 +83,46 @@ func (s *PlaybookSuite) SetupTest() {
 				Config: Config{},
 			},
 		},
+		{
+			err:  ErrInvalidConcurrency,
+			name: "InvalidConcurrency",
+			pb: Playbook{
+				Config: Config{
+					Concurrency: -1,
+					Connection:  config.Connection,
+				},
+			},
+		},
+		{
+			err:  ErrInvalidLog,
+			name: "InvalidLog",
+			pb: Playbook{
+				Config: Config{
+					Connection: config.Connection,
+					Log:        &util.LogOptions{Format: "xml"},
+				},
+			},
+		},
+		{
+			err:  ErrInvalidNotificationURL,
+			name: "InvalidNotificationURL",
+			pb: Playbook{
+				Config: Config{
+					Connection:    config.Connection,
+					Notifications: []Notification{{Type: NotificationSlack, URL: "hooks.slack.com/services/T0/B0/X"}},
+				},
+			},
+		},
+		{
+			err:  ErrInvalidNotificationEvent,
+			name: "InvalidNotificationEvent",
+			pb: Playbook{
+				Config: Config{
+					Connection:    config.Connection,
+					Notifications: []Notification{{Type: NotificationTeams, URL: "https://teams.example.com/webhook", Events: []string{"renewed"}}},
+				},
+			},
+		},
 		{
 			err:  ErrNoCredentials,
 			name: "EmptyCredentials",
@@ -161,49 +217,69 @@ func (s *PlaybookSuite) SetupTest() {
 				},
 			},
 		},
-
 		{
-			err:  ErrNoInstallations,
-			name: "NoInstallationInTask",
+			err:  ErrNoCSRFile,
+			name: "NoCSRFile",
 			pb: Playbook{
 				Config: config,
 				CertificateTasks: CertificateTasks{
 					{
-						Request: req,
+						Request: PlaybookRequest{
+							Zone:      "My\\App",
+							Subject:   Subject{CommonName: "foo.bar.venafi.com"},
+							CsrOrigin: "file",
+						},
+						Installations: Installations{
+							{
+								Type: FormatPEM,
+								File: "/foo/bar/pem/cer.cer",
+							},
+						},
 					},
 				},
 			},
 		},
 		{
-			err:  ErrUndefinedInstallationFormat,
-			name: "InvalidInstallationType",
+			err:  ErrUserProvidedCSRFormat,
+			name: "UserProvidedCSRFormat",
 			pb: Playbook{
 				Config: config,
 				CertificateTasks: CertificateTasks{
 					{
-						Request: req,
+						Request: PlaybookRequest{
+							Zone:      "My\\App",
+							Subject:   Subject{CommonName: "foo.bar.venafi.com"},
+							CsrOrigin: "file",
+							CsrFile:   "/foo/bar/request.csr",
+						},
 						Installations: Installations{
 							{
-								Type: FormatUnknown,
-								File: "something",
+								Type:        FormatPKCS12,
+								File:        "/foo/bar/cert.p12",
+								P12Password: "foobar123",
 							},
 						},
 					},
 				},
 			},
 		},
-
 		{
-			err:  ErrNoInstallationFile,
-			name: "NoJKSLocation",
+			err:  ErrPKCS11Format,
+			name: "PKCS11Format",
 			pb: Playbook{
 				Config: config,
 				CertificateTasks: CertificateTasks{
 					{
-						Request: req,
+						Request: PlaybookRequest{
+							Zone:    "My\\App",
+							Subject: Subject{CommonName: "foo.bar.venafi.com"},
+							PKCS11:  &pkcs11.Config{Module: "/usr/lib/softhsm/libsofthsm2.so", TokenLabel: "vcert", PIN: "1234"},
+						},
 						Installations: Installations{
 							{
-								Type: FormatJKS,
+								Type:        FormatPKCS12,
+								File:        "/foo/bar/cert.p12",
+								P12Password: "foobar123",
 							},
 						},
 					},
@@ -211,18 +287,24 @@ func (s *PlaybookSuite) SetupTest() {
 			},
 		},
 		{
-			err:  ErrNoJKSAlias,
-			name: "NoJKSAlias",
+			err:  ErrPKCS11CSROrigin,
+			name: "PKCS11CSROrigin",
 			pb: Playbook{
 				Config: config,
 				CertificateTasks: CertificateTasks{
-					CertificateTask{
-						Name:    "testTask",
-						Request: req,
+					{
+						Request: PlaybookRequest{
+							Zone:      "My\\App",
+							Subject:   Subject{CommonName: "foo.bar.venafi.com"},
+							CsrOrigin: "service",
+							PKCS11:    &pkcs11.Config{Module: "/usr/lib/softhsm/libsofthsm2.so", TokenLabel: "vcert", PIN: "1234"},
+						},
 						Installations: Installations{
-							Installation{
-								Type: FormatJKS,
-								File: "somewhere",
+							{
+								Type:      FormatPEM,
+								File:      "/foo/bar/pem/cert.cer",
+								ChainFile: "/foo/bar/pem/chain.cer",
+								KeyFile:   "/foo/bar/pem/key.pem",
 							},
 						},
 					},
@@ -230,19 +312,24 @@ func (s *PlaybookSuite) SetupTest() {
 			},
 		},
 		{
-			err:  ErrNoJKSPassword,
-			name: "NoJKSPassword",
+			err:  ErrValidityConflict,
+			name: "ValidityConflict",
 			pb: Playbook{
 				Config: config,
 				CertificateTasks: CertificateTasks{
-					CertificateTask{
-						Name:    "testTask",
-						Request: req,
+					{
+						Request: PlaybookRequest{
+							Zone:          "My\\App",
+							Subject:       Subject{CommonName: "foo.bar.venafi.com"},
+							ValidDays:     "30",
+							ValidityHours: 12,
+						},
 						Installations: Installations{
-							Installation{
-								Type:     FormatJKS,
-								File:     "somewhere",
-								JKSAlias: "someAlias",
+							{
+								Type:      FormatPEM,
+								File:      "/foo/bar/pem/cert.cer",
+								ChainFile: "/foo/bar/pem/chain.cer",
+								KeyFile:   "/foo/bar/pem/key.pem",
 							},
 						},
 					},
@@ -250,38 +337,47 @@ func (s *PlaybookSuite) SetupTest() {
 			},
 		},
 		{
-			err:  ErrJKSPasswordLength,
-			name: "JKSPasswordTooShort",
+			err:  ErrInvalidValidityHours,
+			name: "InvalidValidityHours",
 			pb: Playbook{
 				Config: config,
 				CertificateTasks: CertificateTasks{
-					CertificateTask{
-						Name:    "testTask",
-						Request: req,
+					{
+						Request: PlaybookRequest{
+							Zone:          "My\\App",
+							Subject:       Subject{CommonName: "foo.bar.venafi.com"},
+							ValidityHours: -1,
+						},
 						Installations: Installations{
-							Installation{
-								Type:        FormatJKS,
-								File:        "somewhere",
-								JKSAlias:    "alias",
-								JKSPassword: "abc12",
+							{
+								Type:      FormatPEM,
+								File:      "/foo/bar/pem/cert.cer",
+								ChainFile: "/foo/bar/pem/chain.cer",
+								KeyFile:   "/foo/bar/pem/key.pem",
 							},
 						},
 					},
 				},
 			},
 		},
-
 		{
-			err:  ErrNoInstallationFile,
-			name: "NoPEMLocation",
+			err:  ErrInvalidNotAfter,
+			name: "InvalidNotAfter",
 			pb: Playbook{
 				Config: config,
 				CertificateTasks: CertificateTasks{
 					{
-						Request: req,
+						Request: PlaybookRequest{
+							Zone:     "My\\App",
+							Subject:  Subject{CommonName: "foo.bar.venafi.com"},
+							NotAfter: "2030-06-30",
+						},
 						Installations: Installations{
 							{
-								Type: FormatPEM,
+								Type:      FormatPEM,
+								File:      "/foo/bar/pem/cert.cer",
+								ChainFile: "/foo/bar/pem/chain.cer",
+								KeyFile:   "/foo/bar/pem/key.pem",
 							},
 						},
 					},
@@ -289,18 +385,23 @@ func (s *PlaybookSuite) SetupTest() {
 			},
 		},
 		{
-			err:  ErrNoChainFile,
-			name: "NoPEMChainFile",
+			err:  ErrNotAfterPast,
+			name: "NotAfterPast",
 			pb: Playbook{
 				Config: config,
 				CertificateTasks: CertificateTasks{
-					CertificateTask{
-						Name:    "testTask",
-						Request: req,
+					{
+						Request: PlaybookRequest{
+							Zone:     "My\\App",
+							Subject:  Subject{CommonName: "foo.bar.venafi.com"},
+							NotAfter: "2020-06-30T12:00:00Z",
+						},
 						Installations: Installations{
-							Installation{
-								Type: FormatPEM,
-								File: "path/to/cert.cer",
+							{
+								Type:      FormatPEM,
+								File:      "/foo/bar/pem/cert.cer",
+								ChainFile: "/foo/bar/pem/chain.cer",
+								KeyFile:   "/foo/bar/pem/key.pem",
 							},
 						},
 					},
@@ -308,58 +409,69 @@ func (s *PlaybookSuite) SetupTest() {
 			},
 		},
 		{
-			err:  ErrNoKeyFile,
-			name: "NoPEMKeyFile",
+			err:  ErrReuseKeyCSROrigin,
+			name: "ReuseKeyCSROrigin",
 			pb: Playbook{
 				Config: config,
 				CertificateTasks: CertificateTasks{
-					CertificateTask{
-						Name:    "testTask",
-						Request: req,
+					{
+						Request: PlaybookRequest{
+							Zone:      "My\\App",
+							Subject:   Subject{CommonName: "foo.bar.venafi.com"},
+							CsrOrigin: "service",
+							ReuseKey:  true,
+						},
 						Installations: Installations{
-							Installation{
+							{
 								Type:      FormatPEM,
-								File:      "somewhere",
-								ChainFile: "chain.pem",
+								File:      "/foo/bar/pem/cert.cer",
+								ChainFile: "/foo/bar/pem/chain.cer",
+								KeyFile:   "/foo/bar/pem/key.pem",
 							},
 						},
 					},
 				},
 			},
 		},
-
 		{
-			err:  ErrNoInstallationFile,
-			name: "NoPKCS12Location",
+			err:  ErrReuseKeyFormat,
+			name: "ReuseKeyFormat",
 			pb: Playbook{
 				Config: config,
 				CertificateTasks: CertificateTasks{
 					{
-						Request: req,
+						Request: PlaybookRequest{
+							Zone:     "My\\App",
+							Subject:  Subject{CommonName: "foo.bar.venafi.com"},
+							ReuseKey: true,
+						},
 						Installations: Installations{
 							{
-								Type: FormatPKCS12,
+								Type:          FormatK8sSecret,
+								K8sSecretName: "foo-bar-tls",
 							},
 						},
 					},
 				},
 			},
 		},
-
 		{
-			err:  nil,
-			name: "ValidPEMConfig",
+			err:  ErrInvalidExtKeyUsage,
+			name: "InvalidExtKeyUsage",
 			pb: Playbook{
 				Config: config,
 				CertificateTasks: CertificateTasks{
 					{
-						Name:    "testTask",
-						Request: req,
+						Request: PlaybookRequest{
+							Zone:         "My\\App",
+							Subject:      Subject{CommonName: "foo.bar.venafi.com"},
+							ExtKeyUsages: []string{"clientAuth", "smartcard"},
+						},
 						Installations: Installations{
 							{
 								Type:      FormatPEM,
-								File:      "/foo/bar/pem/cer.cer",
-								ChainFile: "/foo/bar/pem/chain.pem",
+								File:      "/foo/bar/pem/cert.cer",
+								ChainFile: "/foo/bar/pem/chain.cer",
 								KeyFile:   "/foo/bar/pem/key.pem",
 							},
 						},
@@ -368,20 +480,24 @@ func (s *PlaybookSuite) SetupTest() {
 			},
 		},
 		{
-			err:  nil,
-			name: "ValidJKSConfig",
+			err:  ErrExtensionsCSROrigin,
+			name: "ExtensionsCSROrigin",
 			pb: Playbook{
 				Config: config,
 				CertificateTasks: CertificateTasks{
 					{
-						Name:    "testTask",
-						Request: req,
+						Request: PlaybookRequest{
+							Zone:       "My\\App",
+							Subject:    Subject{CommonName: "foo.bar.venafi.com"},
+							CsrOrigin:  "service",
+							Extensions: []Extension{{OID: "1.3.6.1.4.1.311.20.2", Value: "HgoAVQBzAGUAcgA="}},
+						},
 						Installations: Installations{
 							{
-								Type:        FormatJKS,
-								File:        "somewhere",
-								JKSAlias:    "alias",
-								JKSPassword: "abc123",
+								Type:      FormatPEM,
+								File:      "/foo/bar/pem/cert.cer",
+								ChainFile: "/foo/bar/pem/chain.cer",
+								KeyFile:   "/foo/bar/pem/key.pem",
 							},
 						},
 					},
@@ -389,39 +505,39 @@ func (s *PlaybookSuite) SetupTest() {
 			},
 		},
 		{
-			err:  nil,
-			name: "ValidPKCS12Config",
+			err:  ErrInvalidSchedule,
+			name: "InvalidSchedule",
 			pb: Playbook{
 				Config: config,
 				CertificateTasks: CertificateTasks{
 					{
-						Name:    "testTask",
-						Request: req,
+						Request:  req,
+						Schedule: "every day",
 						Installations: Installations{
 							{
 								Type:        FormatPKCS12,
 								File:        "somewhere",
-								P12Password: "foo123",
+								P12Password: "abc123",
 							},
 						},
 					},
 				},
 			},
 		},
-	}
-
-	s.nonWindowsTestCases = []testCase{
 		{
-			err:  ErrNoCAPILocation,
-			name: "NoCAPILocation",
+			err:  ErrInvalidSchedule,
+			name: "ScheduleNeverFires",
 			pb: Playbook{
 				Config: config,
 				CertificateTasks: CertificateTasks{
 					{
-						Request: req,
+						Request:  req,
+						Schedule: "0 0 31 2 *",
 						Installations: Installations{
 							{
-								Type: FormatCAPI,
+								Type:        FormatPKCS12,
+								File:        "somewhere",
+								P12Password: "abc123",
 							},
 						},
 					},
@@ -429,38 +545,40 @@ func (s *PlaybookSuite) SetupTest() {
 			},
 		},
 		{
-			err:  ErrCAPIOnNonWindows,
-			name: "CAPIOnNonWindows",
+			err:  ErrInvalidRetries,
+			name: "InvalidRetries",
 			pb: Playbook{
 				Config: config,
 				CertificateTasks: CertificateTasks{
-					CertificateTask{
-						Name:    "testTask",
+					{
 						Request: req,
+						Retries: -1,
 						Installations: Installations{
-							Installation{
-								Type:     FormatCAPI,
-								Location: "somewhere",
+							{
+								Type: FormatPEM,
+								File: "somewhere",
 							},
 						},
 					},
 				},
 			},
 		},
-	}
-
-	s.windowsTestCases = []testCase{
 		{
-			err:  ErrNoCAPILocation,
-			name: "NoCAPILocation",
+			err:  ErrEmptyRequestZone,
+			name: "EmptyRequestZone",
 			pb: Playbook{
 				Config: config,
 				CertificateTasks: CertificateTasks{
 					{
-						Request: req,
+						Request: PlaybookRequest{
+							Zone:    "My\\App",
+							Zones:   []string{"My\\Failover", ""},
+							Subject: Subject{CommonName: "foo.bar.venafi.com"},
+						},
 						Installations: Installations{
 							{
-								Type: FormatCAPI,
+								Type: FormatPEM,
+								File: "somewhere",
 							},
 						},
 					},
@@ -468,18 +586,22 @@ func (s *PlaybookSuite) SetupTest() {
 			},
 		},
 		{
-			err:  ErrMalformedCAPILocation,
-			name: "MalformedCAPILocation",
+			err:  nil,
+			name: "ValidRequestZones",
 			pb: Playbook{
 				Config: config,
 				CertificateTasks: CertificateTasks{
-					CertificateTask{
-						Name:    "testTask",
-						Request: req,
+					{
+						Request: PlaybookRequest{
+							Zones:   []string{"My\\App", "My\\Failover"},
+							Subject: Subject{CommonName: "foo.bar.venafi.com"},
+						},
 						Installations: Installations{
-							Installation{
-								Type:     FormatCAPI,
-								Location: "somewhere",
+							{
+								Type:      FormatPEM,
+								File:      "somewhere",
+								ChainFile: "chain.pem",
+								KeyFile:   "key.pem",
 							},
 						},
 					},
@@ -487,18 +609,18 @@ func (s *PlaybookSuite) SetupTest() {
 			},
 		},
 		{
-			err:  ErrInvalidCAPILocation,
-			name: "InvalidCAPILocation",
+			err:  ErrInvalidRenewBefore,
+			name: "InvalidRenewBefore",
 			pb: Playbook{
 				Config: config,
 				CertificateTasks: CertificateTasks{
-					CertificateTask{
-						Name:    "testTask",
-						Request: req,
+					{
+						Request:     req,
+						RenewBefore: "120%",
 						Installations: Installations{
-							Installation{
-								Type:     FormatCAPI,
-								Location: "somewhere\\MY",
+							{
+								Type: FormatPEM,
+								File: "somewhere",
 							},
 						},
 					},
@@ -506,18 +628,18 @@ func (s *PlaybookSuite) SetupTest() {
 			},
 		},
 		{
-			err:  ErrInvalidCAPIStoreName,
-			name: "InvalidCAPIStoreName",
+			err:  ErrInvalidBackoff,
+			name: "InvalidBackoff",
 			pb: Playbook{
 				Config: config,
 				CertificateTasks: CertificateTasks{
-					CertificateTask{
-						Name:    "testTask",
+					{
 						Request: req,
+						Backoff: "10 seconds",
 						Installations: Installations{
-							Installation{
-								Type:     FormatCAPI,
-								Location: "LocalMachine\\foo",
+							{
+								Type: FormatPEM,
+								File: "somewhere",
 							},
 						},
 					},
@@ -525,27 +647,2839 @@ func (s *PlaybookSuite) SetupTest() {
 			},
 		},
 		{
-			err:  nil,
-			name: "ValidCAPIConfig",
+			err:  ErrInvalidTaskTimeout,
+			name: "InvalidTaskTimeout",
 			pb: Playbook{
 				Config: config,
 				CertificateTasks: CertificateTasks{
-					CertificateTask{
-						Name:    "testTask",
+					{
 						Request: req,
+						Timeout: "ten minutes",
 						Installations: Installations{
-							Installation{
-								Type:     FormatCAPI,
-								Location: "LocalMachine\\MY",
+							{
+								Type: FormatPEM,
+								File: "somewhere",
 							},
 						},
 					},
 				},
 			},
 		},
-	}
-}
-
+		{
+			err:  ErrInvalidWhen,
+			name: "InvalidWhen",
+			pb: Playbook{
+				Config: config,
+				CertificateTasks: CertificateTasks{
+					{
+						Request: req,
+						When:    `os == "linux" && role == "edge"`,
+						Installations: Installations{
+							{
+								Type: FormatPEM,
+								File: "somewhere",
+							},
+						},
+					},
+				},
+			},
+		},
+		{
+			err:  ErrInvalidBackoff,
+			name: "NegativeBackoff",
+			pb: Playbook{
+				Config: config,
+				CertificateTasks: CertificateTasks{
+					{
+						Request: req,
+						Backoff: "-5s",
+						Installations: Installations{
+							{
+								Type: FormatPEM,
+								File: "somewhere",
+							},
+						},
+					},
+				},
+			},
+		},
+
+		{
+			err:  ErrNoInstallations,
+			name: "NoInstallationInTask",
+			pb: Playbook{
+				Config: config,
+				CertificateTasks: CertificateTasks{
+					{
+						Request: req,
+					},
+				},
+			},
+		},
+		{
+			err:  ErrUndefinedInstallationFormat,
+			name: "InvalidInstallationType",
+			pb: Playbook{
+				Config: config,
+				CertificateTasks: CertificateTasks{
+					{
+						Request: req,
+						Installations: Installations{
+							{
+								Type: FormatUnknown,
+								File: "something",
+							},
+						},
+					},
+				},
+			},
+		},
+
+		{
+			err:  ErrNoInstallationFile,
+			name: "NoJKSLocation",
+			pb: Playbook{
+				Config: config,
+				CertificateTasks: CertificateTasks{
+					{
+						Request: req,
+						Installations: Installations{
+							{
+								Type: FormatJKS,
+							},
+						},
+					},
+				},
+			},
+		},
+		{
+			err:  ErrNoJKSAlias,
+			name: "NoJKSAlias",
+			pb: Playbook{
+				Config: config,
+				CertificateTasks: CertificateTasks{
+					CertificateTask{
+						Name:    "testTask",
+						Request: req,
+						Installations: Installations{
+							Installation{
+								Type: FormatJKS,
+								File: "somewhere",
+							},
+						},
+					},
+				},
+			},
+		},
+		{
+			err:  ErrNoJKSPassword,
+			name: "NoJKSPassword",
+			pb: Playbook{
+				Config: config,
+				CertificateTasks: CertificateTasks{
+					CertificateTask{
+						Name:    "testTask",
+						Request: req,
+						Installations: Installations{
+							Installation{
+								Type:     FormatJKS,
+								File:     "somewhere",
+								JKSAlias: "someAlias",
+							},
+						},
+					},
+				},
+			},
+		},
+		{
+			err:  ErrJKSPasswordLength,
+			name: "JKSPasswordTooShort",
+			pb: Playbook{
+				Config: config,
+				CertificateTasks: CertificateTasks{
+					CertificateTask{
+						Name:    "testTask",
+						Request: req,
+						Installations: Installations{
+							Installation{
+								Type:        FormatJKS,
+								File:        "somewhere",
+								JKSAlias:    "alias",
+								JKSPassword: "abc12",
+							},
+						},
+					},
+				},
+			},
+		},
+		{
+			err:  ErrNoTruststorePassword,
+			name: "JKSTruststoreNoPassword",
+			pb: Playbook{
+				Config: config,
+				CertificateTasks: CertificateTasks{
+					CertificateTask{
+						Name:    "testTask",
+						Request: req,
+						Installations: Installations{
+							Installation{
+								Type:           FormatJKS,
+								File:           "somewhere",
+								JKSAlias:       "alias",
+								JKSPassword:    "abc123",
+								TruststoreFile: "truststore.jks",
+							},
+						},
+					},
+				},
+			},
+		},
+		{
+			err:  ErrInvalidTruststoreFormat,
+			name: "JKSTruststoreInvalidFormat",
+			pb: Playbook{
+				Config: config,
+				CertificateTasks: CertificateTasks{
+					CertificateTask{
+						Name:    "testTask",
+						Request: req,
+						Installations: Installations{
+							Installation{
+								Type:               FormatJKS,
+								File:               "somewhere",
+								JKSAlias:           "alias",
+								JKSPassword:        "abc123",
+								TruststoreFile:     "truststore.jks",
+								TruststoreFormat:   "pem",
+								TruststorePassword: "abc123",
+							},
+						},
+					},
+				},
+			},
+		},
+		{
+			err:  ErrKeyPasswordLength,
+			name: "JKSKeyPasswordTooShort",
+			pb: Playbook{
+				Config: config,
+				CertificateTasks: CertificateTasks{
+					CertificateTask{
+						Name:    "testTask",
+						Request: req,
+						Installations: Installations{
+							Installation{
+								Type:        FormatJKS,
+								File:        "somewhere",
+								JKSAlias:    "alias",
+								JKSPassword: "abc123",
+								KeyPassword: "abc12",
+							},
+						},
+					},
+				},
+			},
+		},
+
+		{
+			err:  ErrInvalidP12Encryption,
+			name: "InvalidP12Encryption",
+			pb: Playbook{
+				Config: config,
+				CertificateTasks: CertificateTasks{
+					CertificateTask{
+						Name:    "testTask",
+						Request: req,
+						Installations: Installations{
+							Installation{
+								Type:          FormatPKCS12,
+								File:          "somewhere",
+								P12Password:   "abc123",
+								P12Encryption: "aes",
+							},
+						},
+					},
+				},
+			},
+		},
+		{
+			err:  ErrNoK8sSecretName,
+			name: "NoK8sSecretName",
+			pb: Playbook{
+				Config: config,
+				CertificateTasks: CertificateTasks{
+					CertificateTask{
+						Name:    "testTask",
+						Request: req,
+						Installations: Installations{
+							Installation{
+								Type:         FormatK8sSecret,
+								K8sNamespace: "venafi",
+							},
+						},
+					},
+				},
+			},
+		},
+		{
+			err:  ErrNoAWSRegion,
+			name: "NoAWSRegion",
+			pb: Playbook{
+				Config: config,
+				CertificateTasks: CertificateTasks{
+					CertificateTask{
+						Name:    "testTask",
+						Request: req,
+						Installations: Installations{
+							Installation{
+								Type:        FormatAWSACM,
+								AWSCertName: "my-cert",
+							},
+						},
+					},
+				},
+			},
+		},
+		{
+			err:  ErrNoAWSCertificate,
+			name: "NoAWSCertificate",
+			pb: Playbook{
+				Config: config,
+				CertificateTasks: CertificateTasks{
+					CertificateTask{
+						Name:    "testTask",
+						Request: req,
+						Installations: Installations{
+							Installation{
+								Type:      FormatAWSACM,
+								AWSRegion: "us-east-1",
+							},
+						},
+					},
+				},
+			},
+		},
+		{
+			err:  ErrNoAzureVaultURI,
+			name: "NoAzureVaultURI",
+			pb: Playbook{
+				Config: config,
+				CertificateTasks: CertificateTasks{
+					CertificateTask{
+						Name:    "testTask",
+						Request: req,
+						Installations: Installations{
+							Installation{
+								Type:          FormatAzureKeyVault,
+								AzureCertName: "my-cert",
+							},
+						},
+					},
+				},
+			},
+		},
+		{
+			err:  ErrNoAzureCertName,
+			name: "NoAzureCertName",
+			pb: Playbook{
+				Config: config,
+				CertificateTasks: CertificateTasks{
+					CertificateTask{
+						Name:    "testTask",
+						Request: req,
+						Installations: Installations{
+							Installation{
+								Type:          FormatAzureKeyVault,
+								AzureVaultURI: "https://my-vault.vault.azure.net",
+							},
+						},
+					},
+				},
+			},
+		},
+		{
+			err:  ErrIncompleteAzureClientSecret,
+			name: "IncompleteAzureClientSecret",
+			pb: Playbook{
+				Config: config,
+				CertificateTasks: CertificateTasks{
+					CertificateTask{
+						Name:    "testTask",
+						Request: req,
+						Installations: Installations{
+							Installation{
+								Type:              FormatAzureKeyVault,
+								AzureVaultURI:     "https://my-vault.vault.azure.net",
+								AzureCertName:     "my-cert",
+								AzureClientSecret: "secret",
+							},
+						},
+					},
+				},
+			},
+		},
+		{
+			err:  ErrNoVaultAddress,
+			name: "NoVaultAddress",
+			pb: Playbook{
+				Config: config,
+				CertificateTasks: CertificateTasks{
+					CertificateTask{
+						Name:    "testTask",
+						Request: req,
+						Installations: Installations{
+							Installation{
+								Type:       FormatVaultKV,
+								VaultPath:  "app/tls",
+								VaultToken: "token",
+							},
+						},
+					},
+				},
+			},
+		},
+		{
+			err:  ErrNoVaultPath,
+			name: "NoVaultPath",
+			pb: Playbook{
+				Config: config,
+				CertificateTasks: CertificateTasks{
+					CertificateTask{
+						Name:    "testTask",
+						Request: req,
+						Installations: Installations{
+							Installation{
+								Type:         FormatVaultKV,
+								VaultAddress: "https://vault.example.com:8200",
+								VaultToken:   "token",
+							},
+						},
+					},
+				},
+			},
+		},
+		{
+			err:  ErrNoDockerSecretName,
+			name: "NoDockerSecretName",
+			pb: Playbook{
+				Config: config,
+				CertificateTasks: CertificateTasks{
+					CertificateTask{
+						Name:    "testTask",
+						Request: req,
+						Installations: Installations{
+							Installation{
+								Type:           FormatDockerSecret,
+								DockerServices: []string{"web"},
+							},
+						},
+					},
+				},
+			},
+		},
+		{
+			err:  ErrInvalidDockerSecretName,
+			name: "InvalidDockerSecretName",
+			pb: Playbook{
+				Config: config,
+				CertificateTasks: CertificateTasks{
+					CertificateTask{
+						Name:    "testTask",
+						Request: req,
+						Installations: Installations{
+							Installation{
+								Type:             FormatDockerSecret,
+								DockerSecretName: "web tls",
+							},
+						},
+					},
+				},
+			},
+		},
+		{
+			err:  ErrInvalidDockerHost,
+			name: "InvalidDockerHost",
+			pb: Playbook{
+				Config: config,
+				CertificateTasks: CertificateTasks{
+					CertificateTask{
+						Name:    "testTask",
+						Request: req,
+						Installations: Installations{
+							Installation{
+								Type:             FormatDockerSecret,
+								DockerHost:       "https://docker.example.com:2376",
+								DockerSecretName: "web-tls",
+							},
+						},
+					},
+				},
+			},
+		},
+		{
+			err:  ErrEmptyDockerService,
+			name: "EmptyDockerService",
+			pb: Playbook{
+				Config: config,
+				CertificateTasks: CertificateTasks{
+					CertificateTask{
+						Name:    "testTask",
+						Request: req,
+						Installations: Installations{
+							Installation{
+								Type:             FormatDockerSecret,
+								DockerSecretName: "web-tls",
+								DockerServices:   []string{"web", ""},
+							},
+						},
+					},
+				},
+			},
+		},
+		{
+			err:  ErrNoNomadPath,
+			name: "NoNomadPath",
+			pb: Playbook{
+				Config: config,
+				CertificateTasks: CertificateTasks{
+					CertificateTask{
+						Name:    "testTask",
+						Request: req,
+						Installations: Installations{
+							Installation{
+								Type:         FormatNomadVariable,
+								NomadAddress: "https://nomad.example.com:4646",
+							},
+						},
+					},
+				},
+			},
+		},
+		{
+			err:  ErrInvalidNomadPath,
+			name: "InvalidNomadPath",
+			pb: Playbook{
+				Config: config,
+				CertificateTasks: CertificateTasks{
+					CertificateTask{
+						Name:    "testTask",
+						Request: req,
+						Installations: Installations{
+							Installation{
+								Type:         FormatNomadVariable,
+								NomadAddress: "https://nomad.example.com:4646",
+								NomadPath:    "nomad/jobs/web tls",
+							},
+						},
+					},
+				},
+			},
+		},
+		{
+			err:  ErrNoDBDataDir,
+			name: "NoDBDataDir",
+			pb: Playbook{
+				Config: config,
+				CertificateTasks: CertificateTasks{
+					CertificateTask{
+						Name:    "testTask",
+						Request: req,
+						Installations: Installations{
+							Installation{
+								Type: FormatPostgreSQL,
+							},
+						},
+					},
+				},
+			},
+		},
+		{
+			err:  ErrInvalidDBAddress,
+			name: "InvalidDBAddress",
+			pb: Playbook{
+				Config: config,
+				CertificateTasks: CertificateTasks{
+					CertificateTask{
+						Name:    "testTask",
+						Request: req,
+						Installations: Installations{
+							Installation{
+								Type:      FormatMySQL,
+								DBAddress: "db.example.com",
+								DBDataDir: "/var/lib/mysql",
+							},
+						},
+					},
+				},
+			},
+		},
+		{
+			err:  ErrDBKeyPassword,
+			name: "DBKeyPassword",
+			pb: Playbook{
+				Config: config,
+				CertificateTasks: CertificateTasks{
+					CertificateTask{
+						Name:    "testTask",
+						Request: req,
+						Installations: Installations{
+							Installation{
+								Type:        FormatPostgreSQL,
+								DBDataDir:   "/var/lib/postgresql/16/main",
+								KeyPassword: "secret",
+							},
+						},
+					},
+				},
+			},
+		},
+		{
+			err:  ErrNoSystemdCredential,
+			name: "NoSystemdCredential",
+			pb: Playbook{
+				Config: config,
+				CertificateTasks: CertificateTasks{
+					CertificateTask{
+						Name:    "testTask",
+						Request: req,
+						Installations: Installations{
+							Installation{
+								Type: FormatSystemdCredential,
+							},
+						},
+					},
+				},
+			},
+		},
+		{
+			err:  ErrInvalidSystemdCredential,
+			name: "InvalidSystemdCredential",
+			pb: Playbook{
+				Config: config,
+				CertificateTasks: CertificateTasks{
+					CertificateTask{
+						Name:    "testTask",
+						Request: req,
+						Installations: Installations{
+							Installation{
+								Type:              FormatSystemdCredential,
+								SystemdCredential: "../web",
+							},
+						},
+					},
+				},
+			},
+		},
+		{
+			err:  ErrInvalidSystemdCredstore,
+			name: "InvalidSystemdCredstore",
+			pb: Playbook{
+				Config: config,
+				CertificateTasks: CertificateTasks{
+					CertificateTask{
+						Name:    "testTask",
+						Request: req,
+						Installations: Installations{
+							Installation{
+								Type:              FormatSystemdCredential,
+								SystemdCredential: "web",
+								SystemdCredstore:  "run/credstore",
+							},
+						},
+					},
+				},
+			},
+		},
+		{
+			err:  ErrSystemdCredentialKeyPassword,
+			name: "SystemdCredentialKeyPassword",
+			pb: Playbook{
+				Config: config,
+				CertificateTasks: CertificateTasks{
+					CertificateTask{
+						Name:    "testTask",
+						Request: req,
+						Installations: Installations{
+							Installation{
+								Type:              FormatSystemdCredential,
+								SystemdCredential: "web",
+								KeyPassword:       "secret",
+							},
+						},
+					},
+				},
+			},
+		},
+		{
+			err:  ErrNoVaultAuth,
+			name: "NoVaultAuth",
+			pb: Playbook{
+				Config: config,
+				CertificateTasks: CertificateTasks{
+					CertificateTask{
+						Name:    "testTask",
+						Request: req,
+						Installations: Installations{
+							Installation{
+								Type:         FormatVaultKV,
+								VaultAddress: "https://vault.example.com:8200",
+								VaultPath:    "app/tls",
+							},
+						},
+					},
+				},
+			},
+		},
+		{
+			err:  ErrNoGCPProject,
+			name: "NoGCPProject",
+			pb: Playbook{
+				Config: config,
+				CertificateTasks: CertificateTasks{
+					CertificateTask{
+						Name:    "testTask",
+						Request: req,
+						Installations: Installations{
+							Installation{
+								Type:        FormatGCP,
+								GCPCertName: "my-cert",
+							},
+						},
+					},
+				},
+			},
+		},
+		{
+			err:  ErrNoGCPCertName,
+			name: "NoGCPCertName",
+			pb: Playbook{
+				Config: config,
+				CertificateTasks: CertificateTasks{
+					CertificateTask{
+						Name:    "testTask",
+						Request: req,
+						Installations: Installations{
+							Installation{
+								Type:       FormatGCP,
+								GCPProject: "my-project",
+							},
+						},
+					},
+				},
+			},
+		},
+		{
+			err:  ErrInvalidGCPTarget,
+			name: "InvalidGCPTarget",
+			pb: Playbook{
+				Config: config,
+				CertificateTasks: CertificateTasks{
+					CertificateTask{
+						Name:    "testTask",
+						Request: req,
+						Installations: Installations{
+							Installation{
+								Type:        FormatGCP,
+								GCPProject:  "my-project",
+								GCPCertName: "my-cert",
+								GCPTarget:   "cloudStorage",
+							},
+						},
+					},
+				},
+			},
+		},
+		{
+			err:  nil,
+			name: "ValidGCPSecretManager",
+			pb: Playbook{
+				Config: config,
+				CertificateTasks: CertificateTasks{
+					CertificateTask{
+						Name:    "testTask",
+						Request: req,
+						Installations: Installations{
+							Installation{
+								Type:        FormatGCP,
+								GCPProject:  "my-project",
+								GCPCertName: "my-cert",
+								GCPTarget:   GCPTargetSecretManager,
+							},
+						},
+					},
+				},
+			},
+		},
+		{
+			err:  ErrNoF5CertName,
+			name: "NoF5CertName",
+			pb: Playbook{
+				Config: config,
+				CertificateTasks: CertificateTasks{
+					CertificateTask{
+						Name:    "testTask",
+						Request: req,
+						Installations: Installations{
+							Installation{
+								Type:       FormatF5,
+								F5Address:  "bigip.example.com",
+								F5Username: "admin",
+								F5Password: "secret",
+							},
+						},
+					},
+				},
+			},
+		},
+		{
+			err:  ErrNoF5Credentials,
+			name: "NoF5Credentials",
+			pb: Playbook{
+				Config: config,
+				CertificateTasks: CertificateTasks{
+					CertificateTask{
+						Name:    "testTask",
+						Request: req,
+						Installations: Installations{
+							Installation{
+								Type:       FormatF5,
+								F5Address:  "bigip.example.com",
+								F5CertName: "www.example.com",
+								F5Username: "admin",
+							},
+						},
+					},
+				},
+			},
+		},
+		{
+			err:  ErrInvalidF5Name,
+			name: "InvalidF5Profile",
+			pb: Playbook{
+				Config: config,
+				CertificateTasks: CertificateTasks{
+					CertificateTask{
+						Name:    "testTask",
+						Request: req,
+						Installations: Installations{
+							Installation{
+								Type:       FormatF5,
+								F5Address:  "bigip.example.com",
+								F5CertName: "www.example.com",
+								F5Username: "admin",
+								F5Password: "secret",
+								F5Profile:  "../clientssl",
+							},
+						},
+					},
+				},
+			},
+		},
+		{
+			err:  nil,
+			name: "ValidF5",
+			pb: Playbook{
+				Config: config,
+				CertificateTasks: CertificateTasks{
+					CertificateTask{
+						Name:    "testTask",
+						Request: req,
+						Installations: Installations{
+							Installation{
+								Type:        FormatF5,
+								F5Address:   "bigip.example.com:8443",
+								F5CertName:  "www.example.com",
+								F5Partition: "Apps",
+								F5Username:  "admin",
+								F5Password:  "secret",
+								F5Profile:   "www_clientssl",
+							},
+						},
+					},
+				},
+			},
+		},
+		{
+			err:  ErrNoCitrixCertKey,
+			name: "NoCitrixCertKey",
+			pb: Playbook{
+				Config: config,
+				CertificateTasks: CertificateTasks{
+					CertificateTask{
+						Name:    "testTask",
+						Request: req,
+						Installations: Installations{
+							Installation{
+								Type:           FormatCitrixADC,
+								CitrixAddress:  "adc.example.com",
+								CitrixUsername: "nsroot",
+								CitrixPassword: "secret",
+							},
+						},
+					},
+				},
+			},
+		},
+		{
+			err:  ErrInvalidCitrixCertKey,
+			name: "InvalidCitrixCertKey",
+			pb: Playbook{
+				Config: config,
+				CertificateTasks: CertificateTasks{
+					CertificateTask{
+						Name:    "testTask",
+						Request: req,
+						Installations: Installations{
+							Installation{
+								Type:           FormatCitrixADC,
+								CitrixAddress:  "adc.example.com",
+								CitrixCertKey:  "www.example.com-certificate-key-pair",
+								CitrixUsername: "nsroot",
+								CitrixPassword: "secret",
+							},
+						},
+					},
+				},
+			},
+		},
+		{
+			err:  ErrEmptyCitrixVServer,
+			name: "EmptyCitrixVServer",
+			pb: Playbook{
+				Config: config,
+				CertificateTasks: CertificateTasks{
+					CertificateTask{
+						Name:    "testTask",
+						Request: req,
+						Installations: Installations{
+							Installation{
+								Type:           FormatCitrixADC,
+								CitrixAddress:  "adc.example.com",
+								CitrixCertKey:  "www.example.com",
+								CitrixUsername: "nsroot",
+								CitrixPassword: "secret",
+								CitrixVServers: []string{"vs_www", " "},
+							},
+						},
+					},
+				},
+			},
+		},
+		{
+			err:  nil,
+			name: "ValidCitrixADC",
+			pb: Playbook{
+				Config: config,
+				CertificateTasks: CertificateTasks{
+					CertificateTask{
+						Name:    "testTask",
+						Request: req,
+						Installations: Installations{
+							Installation{
+								Type:           FormatCitrixADC,
+								CitrixAddress:  "adc.example.com:8443",
+								CitrixCertKey:  "www.example.com",
+								CitrixUsername: "nsroot",
+								CitrixPassword: "secret",
+								CitrixVServers: []string{"vs_www", "vs_www_alt"},
+							},
+						},
+					},
+				},
+			},
+		},
+		{
+			err:  ErrInvalidTaskAction,
+			name: "InvalidTaskAction",
+			pb: Playbook{
+				Config: config,
+				CertificateTasks: CertificateTasks{
+					CertificateTask{
+						Name:    "testTask",
+						Action:  "renew",
+						Request: req,
+					},
+				},
+			},
+		},
+		{
+			err:  ErrRevokeNotSupported,
+			name: "RevokeNotSupported",
+			pb: Playbook{
+				Config: Config{
+					Connection: Connection{
+						Platform: venafi.Firefly,
+						URL:      "https://firefly.venafi.example",
+						Credentials: Authentication{
+							Authentication: endpoint.Authentication{
+								AccessToken: "foobarGibberish123",
+							},
+						},
+					},
+				},
+				CertificateTasks: CertificateTasks{
+					CertificateTask{
+						Name:   "testTask",
+						Action: ActionRevoke,
+						Revoke: RevokeRequest{Thumbprint: "A1B2C3D4"},
+					},
+				},
+			},
+		},
+		{
+			err:  ErrRevokeSerialNotSupported,
+			name: "RevokeSerialNotSupported",
+			pb: Playbook{
+				Config: config,
+				CertificateTasks: CertificateTasks{
+					CertificateTask{
+						Name:   "testTask",
+						Action: ActionRevoke,
+						Revoke: RevokeRequest{Serial: "0A1B"},
+					},
+				},
+			},
+		},
+		{
+			err:  ErrNoRevokeTarget,
+			name: "NoRevokeTarget",
+			pb: Playbook{
+				Config: tppConfig,
+				CertificateTasks: CertificateTasks{
+					CertificateTask{
+						Name:   "testTask",
+						Action: ActionRevoke,
+						Revoke: RevokeRequest{Reason: "superseded"},
+					},
+				},
+			},
+		},
+		{
+			err:  ErrMultipleRevokeTargets,
+			name: "MultipleRevokeTargets",
+			pb: Playbook{
+				Config: tppConfig,
+				CertificateTasks: CertificateTasks{
+					CertificateTask{
+						Name:   "testTask",
+						Action: ActionRevoke,
+						Revoke: RevokeRequest{Thumbprint: "A1B2C3D4", PickupID: "\\VED\\Policy\\My\\App\\foo.bar.venafi.com"},
+					},
+				},
+			},
+		},
+		{
+			err:  ErrInvalidRevocationReason,
+			name: "InvalidRevocationReason",
+			pb: Playbook{
+				Config: tppConfig,
+				CertificateTasks: CertificateTasks{
+					CertificateTask{
+						Name:   "testTask",
+						Action: ActionRevoke,
+						Revoke: RevokeRequest{Thumbprint: "A1B2C3D4", Reason: "6"},
+					},
+				},
+			},
+		},
+		{
+			err:  ErrInvalidRevokeSerial,
+			name: "InvalidRevokeSerial",
+			pb: Playbook{
+				Config: tppConfig,
+				CertificateTasks: CertificateTasks{
+					CertificateTask{
+						Name:   "testTask",
+						Action: ActionRevoke,
+						Revoke: RevokeRequest{Serial: "not-a-serial"},
+					},
+				},
+			},
+		},
+		{
+			err:  ErrRevokeInstallations,
+			name: "RevokeInstallations",
+			pb: Playbook{
+				Config: tppConfig,
+				CertificateTasks: CertificateTasks{
+					CertificateTask{
+						Name:   "testTask",
+						Action: ActionRevoke,
+						Revoke: RevokeRequest{Thumbprint: "A1B2C3D4"},
+						Installations: Installations{
+							Installation{
+								Type: FormatPEM,
+								File: "path/to/my/file.cert",
+							},
+						},
+					},
+				},
+			},
+		},
+		{
+			err:  nil,
+			name: "ValidRevoke",
+			pb: Playbook{
+				Config: tppConfig,
+				CertificateTasks: CertificateTasks{
+					CertificateTask{
+						Name:    "testTask",
+						Action:  ActionRevoke,
+						Request: PlaybookRequest{Zone: "My\\App"},
+						Revoke:  RevokeRequest{Serial: "0a:1b:2c", Reason: "key-compromise", Comments: "decommissioned"},
+					},
+				},
+			},
+		},
+		{
+			err:  nil,
+			name: "ValidRevokeVaaS",
+			pb: Playbook{
+				Config: config,
+				CertificateTasks: CertificateTasks{
+					CertificateTask{
+						Name:    "testTask",
+						Action:  ActionRevoke,
+						Request: PlaybookRequest{Zone: "My App\\My CIT"},
+						Revoke:  RevokeRequest{Thumbprint: "A1B2C3D4", Disable: true},
+					},
+				},
+			},
+		},
+		{
+			err:  ErrApplicationDNsNotSupported,
+			name: "ApplicationDNsNotSupported",
+			pb: Playbook{
+				Config: config,
+				CertificateTasks: CertificateTasks{
+					CertificateTask{
+						Name: "testTask",
+						Request: PlaybookRequest{
+							Subject:        Subject{CommonName: "foo.bar.com"},
+							Zone:           "My App\\My CIT",
+							ApplicationDNs: []string{"Servers\\web01\\Apache"},
+						},
+						Installations: Installations{
+							{
+								Type:      FormatPEM,
+								File:      "/foo/bar/pem/cert.cer",
+								ChainFile: "/foo/bar/pem/chain.cer",
+								KeyFile:   "/foo/bar/pem/key.pem",
+							},
+						},
+					},
+				},
+			},
+		},
+		{
+			err:  nil,
+			name: "ValidApplicationDNs",
+			pb: Playbook{
+				Config: tppConfig,
+				CertificateTasks: CertificateTasks{
+					CertificateTask{
+						Name: "testTask",
+						Request: PlaybookRequest{
+							Subject:        Subject{CommonName: "foo.bar.com"},
+							Zone:           "My\\App",
+							ApplicationDNs: []string{"Servers\\web01\\Apache"},
+						},
+						Installations: Installations{
+							{
+								Type:      FormatPEM,
+								File:      "/foo/bar/pem/cert.cer",
+								ChainFile: "/foo/bar/pem/chain.cer",
+								KeyFile:   "/foo/bar/pem/key.pem",
+							},
+						},
+					},
+				},
+			},
+		},
+		{
+			err:  ErrNoPlugin,
+			name: "NoPlugin",
+			pb: Playbook{
+				Config: config,
+				CertificateTasks: CertificateTasks{
+					CertificateTask{
+						Name:    "testTask",
+						Request: req,
+						Installations: Installations{
+							Installation{
+								Type: FormatPlugin,
+							},
+						},
+					},
+				},
+			},
+		},
+		{
+			err:  ErrInvalidPlugin,
+			name: "InvalidPlugin",
+			pb: Playbook{
+				Config: config,
+				CertificateTasks: CertificateTasks{
+					CertificateTask{
+						Name:    "testTask",
+						Request: req,
+						Installations: Installations{
+							Installation{
+								Type:   FormatPlugin,
+								Plugin: "../appliance",
+							},
+						},
+					},
+				},
+			},
+		},
+		{
+			err:  ErrInvalidPluginDir,
+			name: "InvalidPluginDir",
+			pb: Playbook{
+				Config: config,
+				CertificateTasks: CertificateTasks{
+					CertificateTask{
+						Name:    "testTask",
+						Request: req,
+						Installations: Installations{
+							Installation{
+								Type:      FormatPlugin,
+								Plugin:    "appliance",
+								PluginDir: "plugins",
+							},
+						},
+					},
+				},
+			},
+		},
+		{
+			err:  nil,
+			name: "ValidPlugin",
+			pb: Playbook{
+				Config: config,
+				CertificateTasks: CertificateTasks{
+					CertificateTask{
+						Name:    "testTask",
+						Request: req,
+						Installations: Installations{
+							Installation{
+								Type:         FormatPlugin,
+								Plugin:       "appliance",
+								PluginConfig: map[string]string{"address": "appliance-01.example.com"},
+							},
+						},
+					},
+				},
+			},
+		},
+		{
+			err:  ErrNoWebServerConfig,
+			name: "NoWebServerConfig",
+			pb: Playbook{
+				Config: config,
+				CertificateTasks: CertificateTasks{
+					CertificateTask{
+						Name:    "testTask",
+						Request: req,
+						Installations: Installations{
+							Installation{
+								Type:    FormatNginx,
+								File:    "/etc/nginx/ssl/example.crt",
+								KeyFile: "/etc/nginx/ssl/example.key",
+							},
+						},
+					},
+				},
+			},
+		},
+		{
+			err:  ErrNoWebServerFiles,
+			name: "NoWebServerFiles",
+			pb: Playbook{
+				Config: config,
+				CertificateTasks: CertificateTasks{
+					CertificateTask{
+						Name:    "testTask",
+						Request: req,
+						Installations: Installations{
+							Installation{
+								Type:            FormatApache,
+								File:            "/etc/apache2/ssl/example.crt",
+								WebServerConfig: "/etc/apache2/sites-available/example.conf",
+							},
+						},
+					},
+				},
+			},
+		},
+		{
+			err:  ErrInvalidWebServerFile,
+			name: "InvalidWebServerFile",
+			pb: Playbook{
+				Config: config,
+				CertificateTasks: CertificateTasks{
+					CertificateTask{
+						Name:    "testTask",
+						Request: req,
+						Installations: Installations{
+							Installation{
+								Type:            FormatNginx,
+								File:            "ssl/example.crt",
+								KeyFile:         "/etc/nginx/ssl/example.key",
+								WebServerConfig: "/etc/nginx/sites-available/example.conf",
+							},
+						},
+					},
+				},
+			},
+		},
+		{
+			err:  ErrWebServerKeyPassword,
+			name: "WebServerKeyPassword",
+			pb: Playbook{
+				Config: config,
+				CertificateTasks: CertificateTasks{
+					CertificateTask{
+						Name:    "testTask",
+						Request: req,
+						Installations: Installations{
+							Installation{
+								Type:            FormatApache,
+								File:            "/etc/apache2/ssl/example.crt",
+								KeyFile:         "/etc/apache2/ssl/example.key",
+								KeyPassword:     "secret",
+								WebServerConfig: "/etc/apache2/sites-available/example.conf",
+							},
+						},
+					},
+				},
+			},
+		},
+		{
+			err:  nil,
+			name: "ValidWebServer",
+			pb: Playbook{
+				Config: config,
+				CertificateTasks: CertificateTasks{
+					CertificateTask{
+						Name:    "testTask",
+						Request: req,
+						Installations: Installations{
+							Installation{
+								Type:            FormatNginx,
+								File:            "/etc/nginx/ssl/example.crt",
+								KeyFile:         "/etc/nginx/ssl/example.key",
+								ChainFile:       "/etc/nginx/ssl/example-chain.crt",
+								WebServerConfig: "/etc/nginx/sites-available/example.conf",
+							},
+						},
+					},
+				},
+			},
+		},
+		{
+			err:  ErrInvalidKeystoreFormat,
+			name: "InvalidKeystoreFormat",
+			pb: Playbook{
+				Config: tppConfig,
+				CertificateTasks: CertificateTasks{
+					CertificateTask{
+						Name: "testTask",
+						Request: PlaybookRequest{
+							Subject:        Subject{CommonName: "foo.bar.com"},
+							Zone:           "My\\App",
+							CsrOrigin:      "service",
+							KeystoreFormat: "jks",
+						},
+						Installations: Installations{
+							{
+								Type:      FormatPEM,
+								File:      "/foo/bar/pem/cert.cer",
+								ChainFile: "/foo/bar/pem/chain.cer",
+								KeyFile:   "/foo/bar/pem/key.pem",
+							},
+						},
+					},
+				},
+			},
+		},
+		{
+			err:  ErrKeystoreCSROrigin,
+			name: "KeystoreCSROrigin",
+			pb: Playbook{
+				Config: tppConfig,
+				CertificateTasks: CertificateTasks{
+					CertificateTask{
+						Name: "testTask",
+						Request: PlaybookRequest{
+							Subject:          Subject{CommonName: "foo.bar.com"},
+							Zone:             "My\\App",
+							CsrOrigin:        "local",
+							KeystorePassword: "newPassw0rd!",
+						},
+						Installations: Installations{
+							{
+								Type:      FormatPEM,
+								File:      "/foo/bar/pem/cert.cer",
+								ChainFile: "/foo/bar/pem/chain.cer",
+								KeyFile:   "/foo/bar/pem/key.pem",
+							},
+						},
+					},
+				},
+			},
+		},
+		{
+			err:  ErrPKCS12KeystoreNotSupported,
+			name: "PKCS12KeystoreNotSupported",
+			pb: Playbook{
+				Config: config,
+				CertificateTasks: CertificateTasks{
+					CertificateTask{
+						Name: "testTask",
+						Request: PlaybookRequest{
+							Subject:        Subject{CommonName: "foo.bar.com"},
+							Zone:           "My\\App",
+							CsrOrigin:      "service",
+							KeystoreFormat: "pkcs12",
+						},
+						Installations: Installations{
+							{
+								Type:      FormatPEM,
+								File:      "/foo/bar/pem/cert.cer",
+								ChainFile: "/foo/bar/pem/chain.cer",
+								KeyFile:   "/foo/bar/pem/key.pem",
+							},
+						},
+					},
+				},
+			},
+		},
+		{
+			err:  nil,
+			name: "ValidPKCS12Keystore",
+			pb: Playbook{
+				Config: tppConfig,
+				CertificateTasks: CertificateTasks{
+					CertificateTask{
+						Name: "testTask",
+						Request: PlaybookRequest{
+							Subject:          Subject{CommonName: "foo.bar.com"},
+							Zone:             "My\\App",
+							CsrOrigin:        "service",
+							KeystoreFormat:   "pkcs12",
+							KeystorePassword: "newPassw0rd!",
+						},
+						Installations: Installations{
+							{
+								Type:      FormatPEM,
+								File:      "/foo/bar/pem/cert.cer",
+								ChainFile: "/foo/bar/pem/chain.cer",
+								KeyFile:   "/foo/bar/pem/key.pem",
+							},
+						},
+					},
+				},
+			},
+		},
+		{
+			err:  nil,
+			name: "ValidAdopt",
+			pb: Playbook{
+				Config: Config{Connection: tppConfig.Connection, StateFile: "/var/lib/vcert/state.json"},
+				CertificateTasks: CertificateTasks{
+					CertificateTask{
+						Name:  "testTask",
+						Adopt: true,
+						Request: PlaybookRequest{
+							Subject: Subject{CommonName: "foo.bar.com"},
+							Zone:    "My\\App",
+						},
+						Installations: Installations{
+							{
+								Type:      FormatPEM,
+								File:      "/foo/bar/pem/cert.cer",
+								ChainFile: "/foo/bar/pem/chain.cer",
+								KeyFile:   "/foo/bar/pem/key.pem",
+							},
+						},
+					},
+				},
+			},
+		},
+		{
+			err:  ErrAdoptNoStateFile,
+			name: "AdoptNoStateFile",
+			pb: Playbook{
+				Config: config,
+				CertificateTasks: CertificateTasks{
+					CertificateTask{
+						Name:  "testTask",
+						Adopt: true,
+						Request: PlaybookRequest{
+							Subject: Subject{CommonName: "foo.bar.com"},
+							Zone:    "My\\App",
+						},
+						Installations: Installations{
+							{
+								Type:      FormatPEM,
+								File:      "/foo/bar/pem/cert.cer",
+								ChainFile: "/foo/bar/pem/chain.cer",
+								KeyFile:   "/foo/bar/pem/key.pem",
+							},
+						},
+					},
+				},
+			},
+		},
+		{
+			err:  nil,
+			name: "ValidEnforce",
+			pb: Playbook{
+				Config: Config{Connection: config.Connection, StateFile: "/var/lib/vcert/state.json"},
+				CertificateTasks: CertificateTasks{
+					CertificateTask{
+						Name: "testTask",
+						Request: PlaybookRequest{
+							Subject: Subject{CommonName: "foo.bar.com"},
+							Zone:    "My\\App",
+						},
+						Installations: Installations{
+							{
+								Type:      FormatPEM,
+								File:      "/foo/bar/pem/cert.cer",
+								ChainFile: "/foo/bar/pem/chain.cer",
+								KeyFile:   "/foo/bar/pem/key.pem",
+								Enforce:   true,
+							},
+						},
+					},
+				},
+			},
+		},
+		{
+			err:  ErrEnforceNoStateFile,
+			name: "EnforceNoStateFile",
+			pb: Playbook{
+				Config: config,
+				CertificateTasks: CertificateTasks{
+					CertificateTask{
+						Name: "testTask",
+						Request: PlaybookRequest{
+							Subject: Subject{CommonName: "foo.bar.com"},
+							Zone:    "My\\App",
+						},
+						Installations: Installations{
+							{
+								Type:      FormatPEM,
+								File:      "/foo/bar/pem/cert.cer",
+								ChainFile: "/foo/bar/pem/chain.cer",
+								KeyFile:   "/foo/bar/pem/key.pem",
+								Enforce:   true,
+							},
+						},
+					},
+				},
+			},
+		},
+		{
+			err:  ErrAdoptNotSupported,
+			name: "AdoptNotSupported",
+			pb: Playbook{
+				Config: Config{
+					Connection: Connection{
+						Platform: venafi.Firefly,
+						URL:      "https://firefly.venafi.example",
+						Credentials: Authentication{
+							Authentication: endpoint.Authentication{
+								AccessToken: "foobarGibberish123",
+							},
+						},
+					},
+					StateFile: "/var/lib/vcert/state.json",
+				},
+				CertificateTasks: CertificateTasks{
+					CertificateTask{
+						Name:  "testTask",
+						Adopt: true,
+						Request: PlaybookRequest{
+							Subject: Subject{CommonName: "foo.bar.com"},
+							Zone:    "My\\App",
+						},
+						Installations: Installations{
+							{
+								Type:      FormatPEM,
+								File:      "/foo/bar/pem/cert.cer",
+								ChainFile: "/foo/bar/pem/chain.cer",
+								KeyFile:   "/foo/bar/pem/key.pem",
+							},
+						},
+					},
+				},
+			},
+		},
+		{
+			err:  ErrAdoptAction,
+			name: "AdoptRevoke",
+			pb: Playbook{
+				Config: Config{Connection: tppConfig.Connection, StateFile: "/var/lib/vcert/state.json"},
+				CertificateTasks: CertificateTasks{
+					CertificateTask{
+						Name:   "testTask",
+						Action: ActionRevoke,
+						Adopt:  true,
+						Revoke: RevokeRequest{Thumbprint: "A1B2C3D4"},
+					},
+				},
+			},
+		},
+		{
+			err:  nil,
+			name: "ValidNamedConnections",
+			pb: Playbook{
+				Config: Config{
+					Connection: Connection{Name: "tpp-prod", Platform: venafi.TPP, URL: tppConfig.Connection.URL,
+						Credentials: tppConfig.Connection.Credentials},
+					Connections: []Connection{
+						{Name: "tpp-prod", Platform: venafi.TPP, URL: tppConfig.Connection.URL,
+							Credentials: tppConfig.Connection.Credentials},
+						{Name: "vaas-dev", Platform: venafi.TLSPCloud, Credentials: config.Connection.Credentials},
+					},
+				},
+				CertificateTasks: CertificateTasks{
+					CertificateTask{
+						Name:       "testTask",
+						Connection: "vaas-dev",
+						Request: PlaybookRequest{
+							Subject: Subject{CommonName: "foo.bar.com"},
+							Zone:    "My\\App",
+						},
+						Installations: Installations{
+							{
+								Type:      FormatPEM,
+								File:      "/foo/bar/pem/cert.cer",
+								ChainFile: "/foo/bar/pem/chain.cer",
+								KeyFile:   "/foo/bar/pem/key.pem",
+							},
+						},
+					},
+				},
+			},
+		},
+		{
+			err:  ErrUnknownConnection,
+			name: "UnknownConnection",
+			pb: Playbook{
+				Config: config,
+				CertificateTasks: CertificateTasks{
+					CertificateTask{
+						Name:       "testTask",
+						Connection: "tpp-prod",
+						Request: PlaybookRequest{
+							Subject: Subject{CommonName: "foo.bar.com"},
+							Zone:    "My\\App",
+						},
+						Installations: Installations{
+							{
+								Type:      FormatPEM,
+								File:      "/foo/bar/pem/cert.cer",
+								ChainFile: "/foo/bar/pem/chain.cer",
+								KeyFile:   "/foo/bar/pem/key.pem",
+							},
+						},
+					},
+				},
+			},
+		},
+		{
+			err:  ErrSSHNotSupported,
+			name: "SSHNotSupported",
+			pb: Playbook{
+				Config: config,
+				CertificateTasks: CertificateTasks{
+					CertificateTask{
+						Name:   "testTask",
+						Action: ActionSSHCertificate,
+						SSH:    SSHRequest{Template: "Ansible-SSH-CA", KeyID: "web-01"},
+						Installations: Installations{
+							Installation{Type: FormatSSHCert, File: "/home/alice/.ssh/id_rsa"},
+						},
+					},
+				},
+			},
+		},
+		{
+			err:  ErrNoSSHTemplate,
+			name: "NoSSHTemplate",
+			pb: Playbook{
+				Config: tppConfig,
+				CertificateTasks: CertificateTasks{
+					CertificateTask{
+						Name:   "testTask",
+						Action: ActionSSHCertificate,
+						SSH:    SSHRequest{KeyID: "web-01"},
+						Installations: Installations{
+							Installation{Type: FormatSSHCert, File: "/home/alice/.ssh/id_rsa"},
+						},
+					},
+				},
+			},
+		},
+		{
+			err:  ErrNoSSHKeyID,
+			name: "NoSSHKeyID",
+			pb: Playbook{
+				Config: tppConfig,
+				CertificateTasks: CertificateTasks{
+					CertificateTask{
+						Name:   "testTask",
+						Action: ActionSSHCertificate,
+						SSH:    SSHRequest{Template: "Ansible-SSH-CA"},
+						Installations: Installations{
+							Installation{Type: FormatSSHCert, File: "/home/alice/.ssh/id_rsa"},
+						},
+					},
+				},
+			},
+		},
+		{
+			err:  ErrInvalidSSHKeySize,
+			name: "InvalidSSHKeySize",
+			pb: Playbook{
+				Config: tppConfig,
+				CertificateTasks: CertificateTasks{
+					CertificateTask{
+						Name:   "testTask",
+						Action: ActionSSHCertificate,
+						SSH:    SSHRequest{Template: "Ansible-SSH-CA", KeyID: "web-01", KeySize: 1024},
+						Installations: Installations{
+							Installation{Type: FormatSSHCert, File: "/home/alice/.ssh/id_rsa"},
+						},
+					},
+				},
+			},
+		},
+		{
+			err:  ErrSSHInstallationFormat,
+			name: "SSHInstallationFormat",
+			pb: Playbook{
+				Config: tppConfig,
+				CertificateTasks: CertificateTasks{
+					CertificateTask{
+						Name:   "testTask",
+						Action: ActionSSHCertificate,
+						SSH:    SSHRequest{Template: "Ansible-SSH-CA", KeyID: "web-01"},
+						Installations: Installations{
+							Installation{Type: FormatPEM, File: "/etc/ssl/cert.pem", KeyFile: "/etc/ssl/key.pem", ChainFile: "/etc/ssl/chain.pem"},
+						},
+					},
+				},
+			},
+		},
+		{
+			err:  ErrSSHFormatNotInSSHTask,
+			name: "SSHFormatNotInSSHTask",
+			pb: Playbook{
+				Config: config,
+				CertificateTasks: CertificateTasks{
+					CertificateTask{
+						Name:    "testTask",
+						Request: req,
+						Installations: Installations{
+							Installation{Type: FormatSSHCAPub, File: "/etc/ssh/ca.pub"},
+						},
+					},
+				},
+			},
+		},
+		{
+			err:  ErrInvalidSSHHostPattern,
+			name: "InvalidSSHHostPattern",
+			pb: Playbook{
+				Config: tppConfig,
+				CertificateTasks: CertificateTasks{
+					CertificateTask{
+						Name:   "testTask",
+						Action: ActionSSHCertificate,
+						SSH:    SSHRequest{Template: "Ansible-SSH-CA"},
+						Installations: Installations{
+							Installation{Type: FormatSSHKnownHosts, File: "/etc/ssh/ssh_known_hosts", SSHHostPatterns: []string{"*.example.com web"}},
+						},
+					},
+				},
+			},
+		},
+		{
+			err:  nil,
+			name: "ValidSSHCertificate",
+			pb: Playbook{
+				Config: tppConfig,
+				CertificateTasks: CertificateTasks{
+					CertificateTask{
+						Name:        "testTask",
+						Action:      ActionSSHCertificate,
+						RenewBefore: "25%",
+						SSH: SSHRequest{
+							Template:   "Ansible-SSH-CA",
+							KeyID:      "web-01",
+							Principals: []string{"alice"},
+							ValidHours: 8,
+						},
+						Installations: Installations{
+							Installation{Type: FormatSSHCert, File: "/home/alice/.ssh/id_rsa", Mode: "0600"},
+							Installation{Type: FormatSSHKnownHosts, File: "/home/alice/.ssh/known_hosts", SSHHostPatterns: []string{"*.example.com"}},
+						},
+					},
+				},
+			},
+		},
+		{
+			err:  nil,
+			name: "ValidSSHCAPublicKey",
+			pb: Playbook{
+				Config: tppConfig,
+				CertificateTasks: CertificateTasks{
+					CertificateTask{
+						Name:   "testTask",
+						Action: ActionSSHCertificate,
+						SSH:    SSHRequest{Template: "Ansible-SSH-CA"},
+						Installations: Installations{
+							Installation{Type: FormatSSHCAPub, File: "/etc/ssh/trusted_user_ca_keys.pub"},
+						},
+					},
+				},
+			},
+		},
+		{
+			err:  ErrNoVaultSecretID,
+			name: "NoVaultSecretID",
+			pb: Playbook{
+				Config: config,
+				CertificateTasks: CertificateTasks{
+					CertificateTask{
+						Name:    "testTask",
+						Request: req,
+						Installations: Installations{
+							Installation{
+								Type:         FormatVaultKV,
+								VaultAddress: "https://vault.example.com:8200",
+								VaultPath:    "app/tls",
+								VaultRoleID:  "role-id",
+							},
+						},
+					},
+				},
+			},
+		},
+		{
+			err:  ErrNoInstallationFile,
+			name: "NoPEMLocation",
+			pb: Playbook{
+				Config: config,
+				CertificateTasks: CertificateTasks{
+					{
+						Request: req,
+						Installations: Installations{
+							{
+								Type: FormatPEM,
+							},
+						},
+					},
+				},
+			},
+		},
+		{
+			err:  ErrNoChainFile,
+			name: "NoPEMChainFile",
+			pb: Playbook{
+				Config: config,
+				CertificateTasks: CertificateTasks{
+					CertificateTask{
+						Name:    "testTask",
+						Request: req,
+						Installations: Installations{
+							Installation{
+								Type: FormatPEM,
+								File: "path/to/cert.cer",
+							},
+						},
+					},
+				},
+			},
+		},
+		{
+			err:  ErrNoKeyFile,
+			name: "NoPEMKeyFile",
+			pb: Playbook{
+				Config: config,
+				CertificateTasks: CertificateTasks{
+					CertificateTask{
+						Name:    "testTask",
+						Request: req,
+						Installations: Installations{
+							Installation{
+								Type:      FormatPEM,
+								File:      "somewhere",
+								ChainFile: "chain.pem",
+							},
+						},
+					},
+				},
+			},
+		},
+		{
+			err:  ErrNoKeyFile,
+			name: "NoPEMKeyFileCertChainBundle",
+			pb: Playbook{
+				Config: config,
+				CertificateTasks: CertificateTasks{
+					CertificateTask{
+						Name:    "testTask",
+						Request: req,
+						Installations: Installations{
+							Installation{
+								Type:      FormatPEM,
+								File:      "somewhere",
+								PEMBundle: PEMBundleCertChain,
+							},
+						},
+					},
+				},
+			},
+		},
+		{
+			err:  nil,
+			name: "ValidPEMCertKeyChainBundle",
+			pb: Playbook{
+				Config: config,
+				CertificateTasks: CertificateTasks{
+					CertificateTask{
+						Name:    "testTask",
+						Request: req,
+						Installations: Installations{
+							Installation{
+								Type:      FormatPEM,
+								File:      "somewhere",
+								PEMBundle: PEMBundleCertKeyChain,
+							},
+						},
+					},
+				},
+			},
+		},
+		{
+			err:  ErrInvalidPEMBundle,
+			name: "InvalidPEMBundle",
+			pb: Playbook{
+				Config: config,
+				CertificateTasks: CertificateTasks{
+					CertificateTask{
+						Name:    "testTask",
+						Request: req,
+						Installations: Installations{
+							Installation{
+								Type:      FormatPEM,
+								File:      "somewhere",
+								KeyFile:   "key.pem",
+								PEMBundle: "key+cert",
+							},
+						},
+					},
+				},
+			},
+		},
+		{
+			err:  ErrInvalidChainOrder,
+			name: "InvalidPEMChainOrder",
+			pb: Playbook{
+				Config: config,
+				CertificateTasks: CertificateTasks{
+					CertificateTask{
+						Name:    "testTask",
+						Request: req,
+						Installations: Installations{
+							Installation{
+								Type:       FormatPEM,
+								File:       "somewhere",
+								ChainFile:  "chain.pem",
+								KeyFile:    "key.pem",
+								ChainOrder: "leaf-last",
+							},
+						},
+					},
+				},
+			},
+		},
+		{
+			err:  ErrInvalidAfterInstallAction,
+			name: "InvalidAfterInstallAction",
+			pb: Playbook{
+				Config: config,
+				CertificateTasks: CertificateTasks{
+					CertificateTask{
+						Name:    "testTask",
+						Request: req,
+						Installations: Installations{
+							Installation{
+								Type:        FormatPEM,
+								File:        "somewhere",
+								ChainFile:   "chain.pem",
+								KeyFile:     "key.pem",
+								AfterAction: AfterInstallActions{{RestartService: "nginx", ReloadSystemdUnit: "nginx"}},
+							},
+						},
+					},
+				},
+			},
+		},
+		{
+			err:  ErrInvalidVerifyTLS,
+			name: "InvalidVerifyTLS",
+			pb: Playbook{
+				Config: config,
+				CertificateTasks: CertificateTasks{
+					CertificateTask{
+						Name:    "testTask",
+						Request: req,
+						Installations: Installations{
+							Installation{
+								Type:      FormatPEM,
+								File:      "somewhere",
+								ChainFile: "chain.pem",
+								KeyFile:   "key.pem",
+								VerifyTLS: "localhost",
+							},
+						},
+					},
+				},
+			},
+		},
+		{
+			err:  ErrInvalidBackupRetention,
+			name: "InvalidBackupRetention",
+			pb: Playbook{
+				Config: config,
+				CertificateTasks: CertificateTasks{
+					CertificateTask{
+						Name:    "testTask",
+						Request: req,
+						Installations: Installations{
+							Installation{
+								Type:            FormatPEM,
+								File:            "somewhere",
+								ChainFile:       "chain.pem",
+								KeyFile:         "key.pem",
+								BackupFiles:     true,
+								BackupRetention: -1,
+							},
+						},
+					},
+				},
+			},
+		},
+		{
+			err:  nil,
+			name: "PEMMirrors",
+			pb: Playbook{
+				Config: config,
+				CertificateTasks: CertificateTasks{
+					CertificateTask{
+						Name:    "testTask",
+						Request: req,
+						Installations: Installations{
+							Installation{
+								Type:      FormatPEM,
+								File:      "/etc/ssl/certs/example.pem",
+								ChainFile: "/etc/ssl/certs/chain.pem",
+								KeyFile:   "/etc/ssl/private/example.key",
+								Mirrors:   []string{"/mnt/nfs/ssl", "/mnt/backup/ssl"},
+							},
+						},
+					},
+				},
+			},
+		},
+		{
+			err:  ErrMirrorsNotSupported,
+			name: "MirrorsNotSupported",
+			pb: Playbook{
+				Config: config,
+				CertificateTasks: CertificateTasks{
+					CertificateTask{
+						Name:    "testTask",
+						Request: req,
+						Installations: Installations{
+							Installation{
+								Type:            FormatNginx,
+								File:            "/etc/nginx/ssl/example.crt",
+								KeyFile:         "/etc/nginx/ssl/example.key",
+								WebServerConfig: "/etc/nginx/sites-available/example.conf",
+								Mirrors:         []string{"/mnt/nfs/ssl"},
+							},
+						},
+					},
+				},
+			},
+		},
+		{
+			err:  ErrInvalidMirror,
+			name: "MirrorInFileDirectory",
+			pb: Playbook{
+				Config: config,
+				CertificateTasks: CertificateTasks{
+					CertificateTask{
+						Name:    "testTask",
+						Request: req,
+						Installations: Installations{
+							Installation{
+								Type:      FormatPEM,
+								File:      "/etc/ssl/certs/example.pem",
+								ChainFile: "/etc/ssl/certs/chain.pem",
+								KeyFile:   "/etc/ssl/private/example.key",
+								Mirrors:   []string{"/etc/ssl/certs/"},
+							},
+						},
+					},
+				},
+			},
+		},
+		{
+			err:  ErrInvalidMirror,
+			name: "RepeatedMirror",
+			pb: Playbook{
+				Config: config,
+				CertificateTasks: CertificateTasks{
+					CertificateTask{
+						Name:    "testTask",
+						Request: req,
+						Installations: Installations{
+							Installation{
+								Type:        FormatPKCS12,
+								File:        "/etc/ssl/example.p12",
+								P12Password: "password",
+								Mirrors:     []string{"/mnt/nfs/ssl", "/mnt/nfs/ssl"},
+							},
+						},
+					},
+				},
+			},
+		},
+		{
+			err:  ErrMirrorFileNames,
+			name: "MirrorFileNames",
+			pb: Playbook{
+				Config: config,
+				CertificateTasks: CertificateTasks{
+					CertificateTask{
+						Name:    "testTask",
+						Request: req,
+						Installations: Installations{
+							Installation{
+								Type:      FormatPEM,
+								File:      "/etc/ssl/certs/example.pem",
+								ChainFile: "/etc/ssl/certs/chain.pem",
+								KeyFile:   "/etc/ssl/private/example.pem",
+								Mirrors:   []string{"/mnt/nfs/ssl"},
+							},
+						},
+					},
+				},
+			},
+		},
+		{
+			err:  ErrBindNotInCAPI,
+			name: "BindIISNotInCAPI",
+			pb: Playbook{
+				Config: config,
+				CertificateTasks: CertificateTasks{
+					CertificateTask{
+						Name:    "testTask",
+						Request: req,
+						Installations: Installations{
+							Installation{
+								Type:      FormatPEM,
+								File:      "somewhere",
+								ChainFile: "chain.pem",
+								KeyFile:   "key.pem",
+								BindIIS:   "Default Web Site:443",
+							},
+						},
+					},
+				},
+			},
+		},
+		{
+			err:  ErrInvalidKeyFormat,
+			name: "InvalidPEMKeyFormat",
+			pb: Playbook{
+				Config: config,
+				CertificateTasks: CertificateTasks{
+					CertificateTask{
+						Name:    "testTask",
+						Request: req,
+						Installations: Installations{
+							Installation{
+								Type:      FormatPEM,
+								File:      "somewhere",
+								ChainFile: "chain.pem",
+								KeyFile:   "key.pem",
+								KeyFormat: "pkcs12",
+							},
+						},
+					},
+				},
+			},
+		},
+
+		{
+			err:  ErrInvalidFileMode,
+			name: "InvalidPEMFileMode",
+			pb: Playbook{
+				Config: config,
+				CertificateTasks: CertificateTasks{
+					CertificateTask{
+						Name:    "testTask",
+						Request: req,
+						Installations: Installations{
+							Installation{
+								Type:      FormatPEM,
+								File:      "somewhere",
+								ChainFile: "chain.pem",
+								KeyFile:   "key.pem",
+								Mode:      "0999",
+							},
+						},
+					},
+				},
+			},
+		},
+		{
+			err:  ErrNoInstallationFile,
+			name: "NoPKCS12Location",
+			pb: Playbook{
+				Config: config,
+				CertificateTasks: CertificateTasks{
+					{
+						Request: req,
+						Installations: Installations{
+							{
+								Type: FormatPKCS12,
+							},
+						},
+					},
+				},
+			},
+		},
+
+		{
+			err:  nil,
+			name: "ValidPEMConfig",
+			pb: Playbook{
+				Config: config,
+				CertificateTasks: CertificateTasks{
+					{
+						Name:    "testTask",
+						Request: req,
+						Installations: Installations{
+							{
+								Type:      FormatPEM,
+								File:      "/foo/bar/pem/cer.cer",
+								ChainFile: "/foo/bar/pem/chain.pem",
+								KeyFile:   "/foo/bar/pem/key.pem",
+							},
+						},
+					},
+				},
+			},
+		},
+		{
+			err:  nil,
+			name: "ValidJKSConfig",
+			pb: Playbook{
+				Config: config,
+				CertificateTasks: CertificateTasks{
+					{
+						Name:    "testTask",
+						Request: req,
+						Installations: Installations{
+							{
+								Type:        FormatJKS,
+								File:        "somewhere",
+								JKSAlias:    "alias",
+								JKSPassword: "abc123",
+							},
+						},
+					},
+				},
+			},
+		},
+		{
+			err:  nil,
+			name: "ValidAWSACMConfig",
+			pb: Playbook{
+				Config: config,
+				CertificateTasks: CertificateTasks{
+					{
+						Name:    "testTask",
+						Request: req,
+						Installations: Installations{
+							{
+								Type:              FormatAWSACM,
+								AWSRegion:         "us-east-1",
+								AWSCertificateARN: "arn:aws:acm:us-east-1:123456789012:certificate/12345678-1234-1234-1234-123456789012",
+							},
+						},
+					},
+				},
+			},
+		},
+		{
+			err:  nil,
+			name: "ValidAzureKeyVaultConfig",
+			pb: Playbook{
+				Config: config,
+				CertificateTasks: CertificateTasks{
+					{
+						Name:    "testTask",
+						Request: req,
+						Installations: Installations{
+							{
+								Type:          FormatAzureKeyVault,
+								AzureVaultURI: "https://my-vault.vault.azure.net",
+								AzureCertName: "my-cert",
+								AzureClientID: "00000000-0000-0000-0000-000000000000",
+							},
+						},
+					},
+				},
+			},
+		},
+		{
+			err:  nil,
+			name: "ValidVaultKVConfig",
+			pb: Playbook{
+				Config: config,
+				CertificateTasks: CertificateTasks{
+					CertificateTask{
+						Name:    "testTask",
+						Request: req,
+						Installations: Installations{
+							Installation{
+								Type:         FormatVaultKV,
+								VaultAddress: "https://vault.example.com:8200",
+								VaultPath:    "app/tls",
+								VaultK8sRole: "vcert",
+							},
+						},
+					},
+				},
+			},
+		},
+		{
+			err:  nil,
+			name: "ValidDockerSecretConfig",
+			pb: Playbook{
+				Config: config,
+				CertificateTasks: CertificateTasks{
+					CertificateTask{
+						Name:    "testTask",
+						Request: req,
+						Installations: Installations{
+							Installation{
+								Type:             FormatDockerSecret,
+								DockerHost:       "tcp://docker.example.com:2376",
+								DockerCertPath:   "/etc/docker/certs",
+								DockerSecretName: "web-tls",
+								DockerServices:   []string{"web"},
+							},
+						},
+					},
+				},
+			},
+		},
+		{
+			err:  nil,
+			name: "ValidNomadVariableConfig",
+			pb: Playbook{
+				Config: config,
+				CertificateTasks: CertificateTasks{
+					CertificateTask{
+						Name:    "testTask",
+						Request: req,
+						Installations: Installations{
+							Installation{
+								Type:         FormatNomadVariable,
+								NomadAddress: "https://nomad.example.com:4646",
+								NomadPath:    "nomad/jobs/web",
+								NomadToken:   "token",
+							},
+						},
+					},
+				},
+			},
+		},
+		{
+			err:  nil,
+			name: "ValidUserProvidedCSRConfig",
+			pb: Playbook{
+				Config: config,
+				CertificateTasks: CertificateTasks{
+					{
+						Name: "testTask",
+						Request: PlaybookRequest{
+							Zone:      "My\\App",
+							Subject:   Subject{CommonName: "foo.bar.venafi.com"},
+							CsrOrigin: "file",
+							CsrFile:   "-",
+						},
+						Installations: Installations{
+							{
+								Type:      FormatPEM,
+								File:      "/foo/bar/pem/cer.cer",
+								ChainFile: "/foo/bar/pem/chain.cer",
+								KeyFile:   "/foo/bar/pem/key.pem",
+							},
+						},
+					},
+				},
+			},
+		},
+		{
+			err:  nil,
+			name: "ValidK8sSecretConfig",
+			pb: Playbook{
+				Config: config,
+				CertificateTasks: CertificateTasks{
+					{
+						Name:    "testTask",
+						Request: req,
+						Installations: Installations{
+							{
+								Type:          FormatK8sSecret,
+								K8sNamespace:  "venafi",
+								K8sSecretName: "my-tls",
+							},
+						},
+					},
+				},
+			},
+		},
+		{
+			err:  nil,
+			name: "ValidPKCS12Config",
+			pb: Playbook{
+				Config: config,
+				CertificateTasks: CertificateTasks{
+					{
+						Name:    "testTask",
+						Request: req,
+						Installations: Installations{
+							{
+								Type:        FormatPKCS12,
+								File:        "somewhere",
+								P12Password: "foo123",
+							},
+						},
+					},
+				},
+			},
+		},
+	}
+
+	s.nonWindowsTestCases = []testCase{
+		{
+			err:  ErrNoCAPILocation,
+			name: "NoCAPILocation",
+			pb: Playbook{
+				Config: config,
+				CertificateTasks: CertificateTasks{
+					{
+						Request: req,
+						Installations: Installations{
+							{
+								Type: FormatCAPI,
+							},
+						},
+					},
+				},
+			},
+		},
+		{
+			err:  ErrCAPIOnNonWindows,
+			name: "CAPIOnNonWindows",
+			pb: Playbook{
+				Config: config,
+				CertificateTasks: CertificateTasks{
+					CertificateTask{
+						Name:    "testTask",
+						Request: req,
+						Installations: Installations{
+							Installation{
+								Type:     FormatCAPI,
+								Location: "somewhere",
+							},
+						},
+					},
+				},
+			},
+		},
+		{
+			err:  nil,
+			name: "ValidPEMFilePermissions",
+			pb: Playbook{
+				Config: config,
+				CertificateTasks: CertificateTasks{
+					CertificateTask{
+						Name:    "testTask",
+						Request: req,
+						Installations: Installations{
+							Installation{
+								Type:      FormatPEM,
+								File:      "somewhere",
+								ChainFile: "chain.pem",
+								KeyFile:   "key.pem",
+								Mode:      "0640",
+								Owner:     "root",
+								Group:     "0",
+							},
+						},
+					},
+				},
+			},
+		},
+		{
+			err:  nil,
+			name: "TPM",
+			pb: Playbook{
+				Config: config,
+				CertificateTasks: CertificateTasks{
+					CertificateTask{
+						Name: "testTask",
+						Request: PlaybookRequest{
+							Zone:    "My\\App",
+							Subject: Subject{CommonName: "foo.bar.venafi.com"},
+							TPM:     &tpm.Config{TokenLabel: "vcert", PIN: "1234"},
+						},
+						Installations: Installations{
+							Installation{
+								Type:      FormatPEM,
+								File:      "/foo/bar/pem/cert.cer",
+								ChainFile: "/foo/bar/pem/chain.cer",
+								KeyFile:   "/foo/bar/pem/key.pem",
+							},
+						},
+					},
+				},
+			},
+		},
+		{
+			err:  ErrTPMFormat,
+			name: "TPMFormat",
+			pb: Playbook{
+				Config: config,
+				CertificateTasks: CertificateTasks{
+					CertificateTask{
+						Name: "testTask",
+						Request: PlaybookRequest{
+							Zone:    "My\\App",
+							Subject: Subject{CommonName: "foo.bar.venafi.com"},
+							TPM:     &tpm.Config{TokenLabel: "vcert", PIN: "1234"},
+						},
+						Installations: Installations{
+							Installation{
+								Type:        FormatPKCS12,
+								File:        "/foo/bar/cert.p12",
+								P12Password: "foobar123",
+							},
+						},
+					},
+				},
+			},
+		},
+		{
+			err:  ErrTPMCSROrigin,
+			name: "TPMCSROrigin",
+			pb: Playbook{
+				Config: config,
+				CertificateTasks: CertificateTasks{
+					CertificateTask{
+						Name: "testTask",
+						Request: PlaybookRequest{
+							Zone:      "My\\App",
+							Subject:   Subject{CommonName: "foo.bar.venafi.com"},
+							CsrOrigin: "service",
+							TPM:       &tpm.Config{TokenLabel: "vcert", PIN: "1234"},
+						},
+						Installations: Installations{
+							Installation{
+								Type:      FormatPEM,
+								File:      "/foo/bar/pem/cert.cer",
+								ChainFile: "/foo/bar/pem/chain.cer",
+								KeyFile:   "/foo/bar/pem/key.pem",
+							},
+						},
+					},
+				},
+			},
+		},
+		{
+			err:  ErrTPMWithPKCS11,
+			name: "TPMWithPKCS11",
+			pb: Playbook{
+				Config: config,
+				CertificateTasks: CertificateTasks{
+					CertificateTask{
+						Name: "testTask",
+						Request: PlaybookRequest{
+							Zone:    "My\\App",
+							Subject: Subject{CommonName: "foo.bar.venafi.com"},
+							TPM:     &tpm.Config{TokenLabel: "vcert", PIN: "1234"},
+							PKCS11:  &pkcs11.Config{Module: "/usr/lib/softhsm/libsofthsm2.so", TokenLabel: "vcert", PIN: "1234"},
+						},
+						Installations: Installations{
+							Installation{
+								Type:      FormatPEM,
+								File:      "/foo/bar/pem/cert.cer",
+								ChainFile: "/foo/bar/pem/chain.cer",
+								KeyFile:   "/foo/bar/pem/key.pem",
+							},
+						},
+					},
+				},
+			},
+		},
+	}
+
+	s.windowsTestCases = []testCase{
+		{
+			err:  ErrNoCAPILocation,
+			name: "NoCAPILocation",
+			pb: Playbook{
+				Config: config,
+				CertificateTasks: CertificateTasks{
+					{
+						Request: req,
+						Installations: Installations{
+							{
+								Type: FormatCAPI,
+							},
+						},
+					},
+				},
+			},
+		},
+		{
+			err:  ErrMalformedCAPILocation,
+			name: "MalformedCAPILocation",
+			pb: Playbook{
+				Config: config,
+				CertificateTasks: CertificateTasks{
+					CertificateTask{
+						Name:    "testTask",
+						Request: req,
+						Installations: Installations{
+							Installation{
+								Type:     FormatCAPI,
+								Location: "somewhere",
+							},
+						},
+					},
+				},
+			},
+		},
+		{
+			err:  ErrInvalidCAPILocation,
+			name: "InvalidCAPILocation",
+			pb: Playbook{
+				Config: config,
+				CertificateTasks: CertificateTasks{
+					CertificateTask{
+						Name:    "testTask",
+						Request: req,
+						Installations: Installations{
+							Installation{
+								Type:     FormatCAPI,
+								Location: "somewhere\\MY",
+							},
+						},
+					},
+				},
+			},
+		},
+		{
+			err:  ErrInvalidCAPIStoreName,
+			name: "InvalidCAPIStoreName",
+			pb: Playbook{
+				Config: config,
+				CertificateTasks: CertificateTasks{
+					CertificateTask{
+						Name:    "testTask",
+						Request: req,
+						Installations: Installations{
+							Installation{
+								Type:     FormatCAPI,
+								Location: "LocalMachine\\foo;bar",
+							},
+						},
+					},
+				},
+			},
+		},
+		{
+			err:  nil,
+			name: "ValidCAPIConfig",
+			pb: Playbook{
+				Config: config,
+				CertificateTasks: CertificateTasks{
+					CertificateTask{
+						Name:    "testTask",
+						Request: req,
+						Installations: Installations{
+							Installation{
+								Type:     FormatCAPI,
+								Location: "LocalMachine\\MY",
+							},
+						},
+					},
+				},
+			},
+		},
+		{
+			err:  ErrInvalidCAPIKeyStorageProvider,
+			name: "InvalidCAPIKeyStorageProvider",
+			pb: Playbook{
+				Config: config,
+				CertificateTasks: CertificateTasks{
+					CertificateTask{
+						Name:    "testTask",
+						Request: req,
+						Installations: Installations{
+							Installation{
+								Type:                   FormatCAPI,
+								CAPILocation:           "LocalMachine\\MY",
+								CAPIKeyStorageProvider: "Microsoft Software Key Storage Provider\"; whoami",
+							},
+						},
+					},
+				},
+			},
+		},
+		{
+			err:  nil,
+			name: "ValidCAPICustomStore",
+			pb: Playbook{
+				Config: config,
+				CertificateTasks: CertificateTasks{
+					CertificateTask{
+						Name:    "testTask",
+						Request: req,
+						Installations: Installations{
+							Installation{
+								Type:                   FormatCAPI,
+								CAPILocation:           "LocalMachine\\WebHosting",
+								CAPIKeyStorageProvider: "Microsoft Platform Crypto Provider",
+							},
+						},
+					},
+				},
+			},
+		},
+		{
+			err:  ErrFileOwnershipNotSupported,
+			name: "PEMFileOwnershipOnWindows",
+			pb: Playbook{
+				Config: config,
+				CertificateTasks: CertificateTasks{
+					CertificateTask{
+						Name:    "testTask",
+						Request: req,
+						Installations: Installations{
+							Installation{
+								Type:      FormatPEM,
+								File:      "somewhere",
+								ChainFile: "chain.pem",
+								KeyFile:   "key.pem",
+								Owner:     "root",
+							},
+						},
+					},
+				},
+			},
+		},
+		{
+			err:  nil,
+			name: "TPMCAPI",
+			pb: Playbook{
+				Config: config,
+				CertificateTasks: CertificateTasks{
+					CertificateTask{
+						Name: "testTask",
+						Request: PlaybookRequest{
+							Zone:    "My\\App",
+							Subject: Subject{CommonName: "foo.bar.venafi.com"},
+							TPM:     &tpm.Config{MachineKey: true, AttestationField: "Key Attestation"},
+						},
+						Installations: Installations{
+							Installation{
+								Type:         FormatCAPI,
+								CAPILocation: "LocalMachine\\My",
+							},
+						},
+					},
+				},
+			},
+		},
+		{
+			err:  ErrTPMCAPILocation,
+			name: "TPMCAPILocation",
+			pb: Playbook{
+				Config: config,
+				CertificateTasks: CertificateTasks{
+					CertificateTask{
+						Name: "testTask",
+						Request: PlaybookRequest{
+							Zone:    "My\\App",
+							Subject: Subject{CommonName: "foo.bar.venafi.com"},
+							TPM:     &tpm.Config{},
+						},
+						Installations: Installations{
+							Installation{
+								Type:         FormatCAPI,
+								CAPILocation: "LocalMachine\\My",
+							},
+						},
+					},
+				},
+			},
+		},
+		{
+			err:  ErrTPMCAPIKeyStorageProvider,
+			name: "TPMCAPIKeyStorageProvider",
+			pb: Playbook{
+				Config: config,
+				CertificateTasks: CertificateTasks{
+					CertificateTask{
+						Name: "testTask",
+						Request: PlaybookRequest{
+							Zone:    "My\\App",
+							Subject: Subject{CommonName: "foo.bar.venafi.com"},
+							TPM:     &tpm.Config{},
+						},
+						Installations: Installations{
+							Installation{
+								Type:                   FormatCAPI,
+								CAPILocation:           "CurrentUser\\My",
+								CAPIKeyStorageProvider: "Microsoft Software Key Storage Provider",
+							},
+						},
+					},
+				},
+			},
+		},
+	}
+}
+
 func TestPlaybook(t *testing.T) {
 	suite.Run(t, new(PlaybookSuite))
 }
@@ -578,3 +3512,164 @@ func (s *PlaybookSuite) TestPlaybook_IsValid() {
 		})
 	}
 }
+
+func (s *PlaybookSuite) TestPlaybook_IsValid_DuplicateTaskName() {
+	task := CertificateTask{
+		Name: "web",
+		Request: PlaybookRequest{
+			Zone:    "My\\App",
+			Subject: Subject{CommonName: "foo.bar.venafi.com"},
+		},
+		Installations: Installations{{Type: FormatPEM, File: "cert.pem", ChainFile: "chain.pem", KeyFile: "key.pem"}},
+	}
+	other := task
+	other.Name = "api"
+	config := Config{Connection: Connection{
+		Platform:    venafi.TLSPCloud,
+		Credentials: Authentication{Authentication: endpoint.Authentication{APIKey: "foobarGibberish123"}},
+	}}
+
+	pb := Playbook{Config: config, CertificateTasks: CertificateTasks{task, other}}
+	valid, err := pb.IsValid()
+	s.NoError(err)
+	s.True(valid)
+
+	pb.CertificateTasks = CertificateTasks{task, other, task}
+	valid, err = pb.IsValid()
+	s.ErrorIs(err, ErrDuplicateTaskName)
+	s.ErrorContains(err, "certificate task defined multiple times: web")
+	s.False(valid)
+}
+
+func (s *PlaybookSuite) TestRevokeRequest_GetReason() {
+	s.Equal("key-compromise", RevokeRequest{Reason: "1"}.GetReason())
+	s.Equal("cessation-of-operation", RevokeRequest{Reason: " Cessation-Of-Operation "}.GetReason())
+	s.Equal("", RevokeRequest{}.GetReason())
+	s.Equal("6", RevokeRequest{Reason: "6"}.GetReason())
+	s.Equal("0A1B2C", RevokeRequest{Serial: "0a:1b:2c"}.GetSerial())
+}
+
+func (s *PlaybookSuite) TestPlaybookRequest_GetZones() {
+	req := PlaybookRequest{Zone: "My\\App", Zones: []string{"My\\Failover", "My\\App", "My\\Other"}}
+	s.Equal([]string{"My\\App", "My\\Failover", "My\\Other"}, req.GetZones())
+
+	req = PlaybookRequest{Zones: []string{"My\\Failover"}}
+	s.Equal([]string{"My\\Failover"}, req.GetZones())
+
+	s.Empty(PlaybookRequest{}.GetZones())
+}
+
+func (s *PlaybookSuite) TestPlaybookRequest_GetExtensions() {
+	req := PlaybookRequest{
+		ExtKeyUsages: []string{"clientAuth", "1.3.6.1.4.1.311.20.2.2"},
+		Extensions:   []Extension{{OID: "1.3.6.1.4.1.311.20.2", Critical: true, Value: "HgoAVQBzAGUAcgA="}},
+	}
+	extensions, usages, err := req.GetExtensions()
+	s.NoError(err)
+	s.Len(extensions, 1)
+	s.Equal("1.3.6.1.4.1.311.20.2", extensions[0].Id.String())
+	s.True(extensions[0].Critical)
+	s.Len(usages, 2)
+	s.Equal("1.3.6.1.5.5.7.3.2", usages[0].String())
+
+	req = PlaybookRequest{Extensions: []Extension{{OID: "1.3.6.1.4.1.311.20.2", Value: "HgoAVQBzAGUAcgA"}}}
+	_, _, err = req.GetExtensions()
+	s.ErrorIs(err, ErrInvalidExtension)
+}
+
+func (s *PlaybookSuite) TestPlaybookRequest_UnmarshalYAML() {
+	var req PlaybookRequest
+	err := yaml.Unmarshal([]byte("zone: My\\App\ncsrOrigin: service\nkeystoreFormat: pkcs12\n"), &req)
+	s.NoError(err)
+	s.Equal("My\\App", req.Zone)
+	s.Equal("service", req.CsrOrigin)
+	s.True(req.IsPKCS12Keystore())
+
+	req = PlaybookRequest{}
+	err = yaml.Unmarshal([]byte("csr: service\n"), &req)
+	s.NoError(err)
+	s.Equal("service", req.CsrOrigin)
+
+	err = yaml.Unmarshal([]byte("csr: local\ncsrOrigin: service\n"), &req)
+	s.ErrorIs(err, ErrCSROriginConflict)
+}
+
+func (s *PlaybookSuite) TestInstallation_UnmarshalYAML() {
+	var installation Installation
+	err := yaml.Unmarshal([]byte("format: PKCS12\nfile: cert.p12\np12Password: abc123\nencryption: modern\n"), &installation)
+	s.NoError(err)
+	s.Equal(P12EncryptionModern, installation.P12Encryption)
+	s.Equal("cert.p12", installation.File)
+
+	installation = Installation{}
+	err = yaml.Unmarshal([]byte("p12Encryption: Modern\nencryption: modern\n"), &installation)
+	s.NoError(err)
+
+	err = yaml.Unmarshal([]byte("p12Encryption: legacy\nencryption: modern\n"), &installation)
+	s.ErrorIs(err, ErrP12EncryptionConflict)
+
+	err = yaml.Unmarshal([]byte("format: PKCS12\nfile: cert.p12\np12Password: abc123\nencryption: aes\n"), &installation)
+	s.NoError(err)
+	_, err = installation.IsValid()
+	s.ErrorIs(err, ErrInvalidP12Encryption)
+}
+
+func (s *PlaybookSuite) TestConfig_Connections() {
+	data := `connection:
+  - name: tpp-prod
+    platform: tpp
+    url: https://tpp.venafi.example
+    credentials:
+      accessToken: foobarGibberish123
+  - name: vaas-dev
+    platform: vaas
+    credentials:
+      apiKey: foobarGibberish123
+stateFile: /var/lib/vcert/state.json
+`
+	var config Config
+	err := yaml.Unmarshal([]byte(data), &config)
+	s.Require().NoError(err)
+	s.Len(config.Connections, 2)
+	s.Equal("tpp-prod", config.Connection.Name)
+	s.Equal("/var/lib/vcert/state.json", config.StateFile)
+	s.True(config.UsesPlatform(venafi.TPP))
+	s.True(config.UsesPlatform(venafi.TLSPCloud))
+	s.False(config.UsesPlatform(venafi.Firefly))
+
+	s.Equal(venafi.TLSPCloud, config.ForTask(CertificateTask{Connection: "vaas-dev"}).Connection.Platform)
+	s.Equal(venafi.TPP, config.ForTask(CertificateTask{}).Connection.Platform)
+	_, found := config.GetConnection("unknown")
+	s.False(found)
+
+	// The list is written back as a list
+	out, err := yaml.Marshal(config)
+	s.Require().NoError(err)
+	var written Config
+	s.Require().NoError(yaml.Unmarshal(out, &written))
+	s.Len(written.Connections, 2)
+	s.Equal("vaas-dev", written.Connections[1].Name)
+
+	config = Config{}
+	err = yaml.Unmarshal([]byte("connection:\n  platform: vaas\n"), &config)
+	s.Require().NoError(err)
+	s.Empty(config.Connections)
+	s.Equal(venafi.TLSPCloud, config.Connection.Platform)
+
+	config = Config{Connections: []Connection{written.Connections[0], written.Connections[0]}}
+	_, err = config.IsValid()
+	s.ErrorIs(err, ErrDuplicateConnectionName)
+	config = Config{Connections: []Connection{{Platform: venafi.TLSPCloud}}}
+	_, err = config.IsValid()
+	s.ErrorIs(err, ErrNoConnectionName)
+}
+
+func (s *PlaybookSuite) TestNotification_IsValid() {
+	n := Notification{Type: "Slack", URL: "https://hooks.slack.com/services/T0/B0/X", Events: []string{"Success", EventFailure}}
+	s.NoError(n.IsValid())
+	s.True(n.NotifiesOn(EventSuccess))
+	s.False(n.NotifiesOn(EventExpiring))
+	s.True(Notification{}.NotifiesOn(EventExpiring))
+
+	s.ErrorIs(Notification{Type: "email", URL: n.URL}.IsValid(), ErrInvalidNotificationType)
+}
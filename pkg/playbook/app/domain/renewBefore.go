@@ -0,0 +1,85 @@
+/*
+ * Copyright 2023 Venafi, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *  http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package domain
+
+import (
+	"strconv"
+	"strings"
+	"time"
+)
+
+// DefaultRenewBefore is the renewal window used when a task does not set renewBefore
+const DefaultRenewBefore = "10%"
+
+const dayDuration = 24 * time.Hour
+
+// RenewWindow is the period before the expiration of a certificate in which it gets renewed
+type RenewWindow struct {
+	// Disabled is true when the certificate is never renewed automatically
+	Disabled bool
+	// Percent of the certificate lifetime, between 0 and 100. Only used when Duration is 0
+	Percent float64
+	// Duration before the expiration date
+	Duration time.Duration
+}
+
+// ParseRenewBefore parses the renewBefore value of a task, which is one of:
+//   - a number of days, either as is or followed by d, i.e. "30" or "30d"
+//   - a percentage of the certificate lifetime, i.e. "15%" or "12.5%"
+//   - a duration, i.e. "10h" or "36h30m"
+//   - "0" or "disabled", to disable automatic renewal
+func ParseRenewBefore(value string) (RenewWindow, error) {
+	value = strings.TrimSpace(value)
+	if strings.EqualFold(value, "disabled") {
+		return RenewWindow{Disabled: true}, nil
+	}
+
+	if pct, found := strings.CutSuffix(value, "%"); found {
+		percent, err := strconv.ParseFloat(pct, 64)
+		if err != nil || percent < 0 || percent >= 100 {
+			return RenewWindow{}, ErrInvalidRenewBefore
+		}
+		return RenewWindow{Disabled: percent == 0, Percent: percent}, nil
+	}
+
+	days, err := strconv.ParseInt(strings.TrimSuffix(value, "d"), 10, 32)
+	if err == nil {
+		if days < 0 {
+			return RenewWindow{}, ErrInvalidRenewBefore
+		}
+		return RenewWindow{Disabled: days == 0, Duration: time.Duration(days) * dayDuration}, nil
+	}
+
+	d, err := time.ParseDuration(value)
+	if err != nil || d < 0 {
+		return RenewWindow{}, ErrInvalidRenewBefore
+	}
+	return RenewWindow{Disabled: d == 0, Duration: d}, nil
+}
+
+// RenewalDate returns the date from which a certificate valid between notBefore and notAfter is renewed.
+// It is the zero time when renewal is disabled
+func (w RenewWindow) RenewalDate(notBefore time.Time, notAfter time.Time) time.Time {
+	if w.Disabled {
+		return time.Time{}
+	}
+	if w.Duration > 0 {
+		return notAfter.Add(-w.Duration)
+	}
+	lifetime := notAfter.Sub(notBefore)
+	return notAfter.Add(-time.Duration(float64(lifetime) * w.Percent / 100))
+}
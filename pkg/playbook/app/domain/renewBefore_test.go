@@ -0,0 +1,82 @@
+/*
+ * Copyright 2023 Venafi, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *  http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package domain
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/suite"
+)
+
+type RenewBeforeSuite struct {
+	suite.Suite
+	notBefore time.Time
+	notAfter  time.Time
+}
+
+func (s *RenewBeforeSuite) SetupTest() {
+	s.notBefore = time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	s.notAfter = s.notBefore.Add(100 * dayDuration)
+}
+
+func TestRenewBefore(t *testing.T) {
+	suite.Run(t, new(RenewBeforeSuite))
+}
+
+func (s *RenewBeforeSuite) TestRenewBefore_RenewalDate() {
+	testCases := []struct {
+		value    string
+		expected time.Time
+	}{
+		{value: "30", expected: s.notAfter.Add(-30 * dayDuration)},
+		{value: "30d", expected: s.notAfter.Add(-30 * dayDuration)},
+		{value: "10h", expected: s.notAfter.Add(-10 * time.Hour)},
+		{value: "36h30m", expected: s.notAfter.Add(-36*time.Hour - 30*time.Minute)},
+		{value: "15%", expected: s.notAfter.Add(-15 * dayDuration)},
+		{value: "12.5%", expected: s.notAfter.Add(-12*dayDuration - 12*time.Hour)},
+	}
+
+	for _, tc := range testCases {
+		s.Run(tc.value, func() {
+			window, err := ParseRenewBefore(tc.value)
+			s.Require().NoError(err)
+			s.False(window.Disabled)
+			s.Equal(tc.expected, window.RenewalDate(s.notBefore, s.notAfter))
+		})
+	}
+}
+
+func (s *RenewBeforeSuite) TestRenewBefore_Disabled() {
+	for _, value := range []string{"0", "0d", "0%", "disabled", "DISABLED"} {
+		s.Run(value, func() {
+			window, err := ParseRenewBefore(value)
+			s.Require().NoError(err)
+			s.True(window.Disabled)
+			s.True(window.RenewalDate(s.notBefore, s.notAfter).IsZero())
+		})
+	}
+}
+
+func (s *RenewBeforeSuite) TestRenewBefore_Invalid() {
+	for _, value := range []string{"", "-5d", "100%", "ten%", "30 days", "-1h"} {
+		s.Run(value, func() {
+			_, err := ParseRenewBefore(value)
+			s.ErrorIs(err, ErrInvalidRenewBefore)
+		})
+	}
+}
@@ -0,0 +1,97 @@
+/*
+ * Copyright 2023 Venafi, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *  http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package domain
+
+import (
+	"math/big"
+	"strconv"
+	"strings"
+
+	"github.com/Venafi/vcert/v5/pkg/venafi/tpp"
+)
+
+const (
+	// ActionEnroll is the action of the tasks that request a certificate and install it. It is the default action
+	ActionEnroll = "enroll"
+	// ActionRevoke is the action of the tasks that revoke a certificate
+	ActionRevoke = "revoke"
+)
+
+// revocationReasonCodes maps the RFC 5280 reason codes supported by the Venafi platform to their names
+var revocationReasonCodes = []string{"none", "key-compromise", "ca-compromise", "affiliation-changed", "superseded",
+	"cessation-of-operation"}
+
+// RevokeRequest identifies the certificate revoked by a task whose action is revoke.
+// Exactly one of Thumbprint, Serial or PickupID is set
+type RevokeRequest struct {
+	Comments string `yaml:"comments,omitempty"`
+	// Disable prevents the certificate from being requested again by the Venafi platform. On VaaS, the retired
+	// certificate is added to the blocklist
+	Disable bool `yaml:"disable,omitempty"`
+	// PickupID is the DN of the certificate in TPP, or the ID of the certificate or its request in VaaS
+	PickupID string `yaml:"pickupId,omitempty"`
+	// Reason is either the name or the RFC 5280 code of the revocation reason, i.e. 'key-compromise' or '1'
+	Reason string `yaml:"reason,omitempty"`
+	// Serial is the serial number of the certificate, in hexadecimal. Colons are ignored
+	Serial string `yaml:"serial,omitempty"`
+	// Thumbprint is the SHA-1 thumbprint of the certificate
+	Thumbprint string `yaml:"thumbprint,omitempty"`
+}
+
+// IsValid returns an error when the RevokeRequest does not identify exactly one certificate, or its reason is not supported
+func (r RevokeRequest) IsValid() error {
+	targets := 0
+	for _, target := range []string{r.Thumbprint, r.Serial, r.PickupID} {
+		if strings.TrimSpace(target) != "" {
+			targets++
+		}
+	}
+	if targets == 0 {
+		return ErrNoRevokeTarget
+	}
+	if targets > 1 {
+		return ErrMultipleRevokeTargets
+	}
+
+	if r.Serial != "" {
+		if _, ok := new(big.Int).SetString(r.GetSerial(), 16); !ok {
+			return ErrInvalidRevokeSerial
+		}
+	}
+
+	if _, ok := tpp.RevocationReasonsMap[r.GetReason()]; !ok {
+		return ErrInvalidRevocationReason
+	}
+	return nil
+}
+
+// GetReason returns the name of the revocation reason, translating RFC 5280 codes
+func (r RevokeRequest) GetReason() string {
+	reason := strings.ToLower(strings.TrimSpace(r.Reason))
+	if code, err := strconv.Atoi(reason); err == nil {
+		if code < 0 || code >= len(revocationReasonCodes) {
+			return reason
+		}
+		return revocationReasonCodes[code]
+	}
+	return reason
+}
+
+// GetSerial returns the serial number in uppercase hexadecimal, without colons
+func (r RevokeRequest) GetSerial() string {
+	return strings.ToUpper(strings.ReplaceAll(strings.TrimSpace(r.Serial), ":", ""))
+}
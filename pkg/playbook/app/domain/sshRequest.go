@@ -0,0 +1,83 @@
+/*
+ * Copyright 2023 Venafi, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *  http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package domain
+
+import (
+	"strings"
+)
+
+const (
+	// ActionSSHCertificate is the action of the tasks that request an SSH certificate from a TPP SSH CA template
+	// and install it, along with the public key of the CA, in OpenSSH formats
+	ActionSSHCertificate = "sshCertificate"
+
+	// DefaultSSHKeySize is the size of the RSA key pair generated for SSH certificates when keySize is not set
+	DefaultSSHKeySize = 3072
+	// sshMinKeySize is the minimum size allowed for the RSA key pair of SSH certificates
+	sshMinKeySize = 2048
+)
+
+// SSHRequest represents the SSH certificate requested by a task whose action is sshCertificate.
+// The key pair is generated locally and only the public key is sent to TPP
+type SSHRequest struct {
+	// DestinationAddresses are the hosts the certificate is meant to be used against. Informational only
+	DestinationAddresses []string `yaml:"destinationAddresses,omitempty"`
+	// Extensions are the certificate extensions, i.e. 'permit-pty' or 'login@github.com:alice'
+	Extensions []string `yaml:"extensions,omitempty"`
+	// Folder is the policy folder of the certificate object. The default folder of the template is used when not set
+	Folder string `yaml:"folder,omitempty"`
+	// ForceCommand is the command executed by the SSH server instead of the one requested by the client
+	ForceCommand string `yaml:"forceCommand,omitempty"`
+	// KeyID is the identifier of the certificate, logged by the SSH server on every authentication
+	KeyID string `yaml:"keyId,omitempty"`
+	// KeyPassphrase encrypts the private key written by SSHCERT installations
+	KeyPassphrase string `yaml:"keyPassphrase,omitempty"`
+	// KeySize is the size of the RSA key pair. Defaults to DefaultSSHKeySize
+	KeySize int `yaml:"keySize,omitempty"`
+	// ObjectName is the name of the certificate object in TPP. Defaults to KeyID
+	ObjectName string `yaml:"objectName,omitempty"`
+	// Principals are the users, for user certificates, or host names, for host certificates, the certificate is valid for
+	Principals []string `yaml:"principals,omitempty"`
+	// SourceAddresses are the addresses, in CIDR notation, the certificate can be used from
+	SourceAddresses []string `yaml:"sourceAddresses,omitempty"`
+	// Template is the name, or the DN, of the SSH CA template that issues the certificate
+	Template string `yaml:"template,omitempty"`
+	// ValidHours is the validity period of the certificate. The validity of the template is used when not set
+	ValidHours int `yaml:"validHours,omitempty"`
+}
+
+// IsValid returns an error when the SSHRequest is missing the SSH CA template, or its key size or validity are not valid
+func (r SSHRequest) IsValid() error {
+	if strings.TrimSpace(r.Template) == "" {
+		return ErrNoSSHTemplate
+	}
+	if r.KeySize != 0 && r.KeySize < sshMinKeySize {
+		return ErrInvalidSSHKeySize
+	}
+	if r.ValidHours < 0 {
+		return ErrInvalidSSHValidHours
+	}
+	return nil
+}
+
+// GetKeySize returns the size of the RSA key pair of the certificate
+func (r SSHRequest) GetKeySize() int {
+	if r.KeySize == 0 {
+		return DefaultSSHKeySize
+	}
+	return r.KeySize
+}
@@ -0,0 +1,197 @@
+/*
+ * Copyright 2023 Venafi, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *  http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package installer
+
+import (
+	"context"
+	"crypto/x509"
+	"fmt"
+	"strings"
+
+	"go.uber.org/zap"
+
+	"github.com/Venafi/vcert/v5/pkg/certificate"
+	"github.com/Venafi/vcert/v5/pkg/playbook/app/domain"
+	"github.com/Venafi/vcert/v5/pkg/playbook/util"
+	"github.com/Venafi/vcert/v5/pkg/playbook/util/aws"
+)
+
+// acmTagName is the tag used to find the certificate in ACM when awsCertificateArn is not set
+const acmTagName = "Name"
+
+// AWSACMInstaller represents an installation that will import the certificate bundle in AWS Certificate Manager
+type AWSACMInstaller struct {
+	domain.Installation
+}
+
+// NewAWSACMInstaller returns a new installer of type AWSACM with the values defined in inst
+func NewAWSACMInstaller(inst domain.Installation) AWSACMInstaller {
+	return AWSACMInstaller{inst}
+}
+
+// Check is the method in charge of making the validations to install a new certificate:
+// 1. Does the certificate exists? > Install if it doesn't.
+// 2. Does the certificate is about to expire? Renew if about to expire.
+// Returns true if the certificate needs to be installed, along with the certificate currently installed, if any.
+func (r AWSACMInstaller) Check(ctx context.Context, renewBefore string, _ domain.PlaybookRequest) (bool, *x509.Certificate, error) {
+	zap.L().Info("checking certificate health", zap.String("format", r.Type.String()), zap.String("location", r.location()))
+
+	client, err := r.getClient(ctx)
+	if err != nil {
+		return false, nil, err
+	}
+
+	arn, err := r.getCertificateARN(ctx, client)
+	if err != nil {
+		return false, nil, err
+	}
+	if arn == "" {
+		zap.L().Debug("certificate does not exist", zap.String("location", r.location()))
+		return true, nil, nil
+	}
+
+	acmCert, err := client.GetCertificate(ctx, arn)
+	if err != nil {
+		return false, nil, err
+	}
+	if acmCert == nil {
+		zap.L().Debug("certificate does not exist", zap.String("location", arn))
+		return true, nil, nil
+	}
+
+	// Load Certificate
+	cert, err := parsePEMCertificate([]byte(acmCert.Certificate))
+	if err != nil {
+		return false, nil, err
+	}
+
+	// Check certificate expiration
+	renew := needRenewal(cert, renewBefore)
+
+	// Check certificate revocation
+	if !renew && r.ValidateRevocation {
+		renew = isRevoked(cert, parsePEMCertificates([]byte(acmCert.CertificateChain)))
+	}
+
+	return renew, cert, nil
+}
+
+// Backup is a no-op for ACM. Certificates are re-imported in place and ACM does not allow exporting the private key
+// of imported certificates, so there is nothing to back up
+func (r AWSACMInstaller) Backup(_ context.Context) error {
+	zap.L().Info("ACM does not allow exporting imported certificates, no back up taken", zap.String("location", r.location()))
+	return nil
+}
+
+// Install takes the certificate bundle and moves it to the location specified in the installer.
+//
+// When the certificate already exists in ACM, it is re-imported under the same ARN, so that the services
+// using it (i.e. load balancers) pick up the renewed certificate automatically
+func (r AWSACMInstaller) Install(ctx context.Context, pcc certificate.PEMCollection) error {
+	zap.L().Debug("installing certificate", zap.String("location", r.location()))
+
+	if len(pcc.Certificate) == 0 || len(pcc.PrivateKey) == 0 {
+		return fmt.Errorf("certificate and Private Key are required for AWS Certificate Manager")
+	}
+
+	// ACM requires an unencrypted private key
+	privateKey, err := MarshalPKCS8PrivateKey(pcc.PrivateKey, "")
+	if err != nil {
+		zap.L().Error("could not prepare private key for ACM", zap.Error(err))
+		return err
+	}
+
+	client, err := r.getClient(ctx)
+	if err != nil {
+		return err
+	}
+
+	arn, err := r.getCertificateARN(ctx, client)
+	if err != nil {
+		return err
+	}
+
+	tags := []aws.Tag{{Key: "managed-by", Value: "vcert"}}
+	if r.AWSCertName != "" {
+		tags = append(tags, aws.Tag{Key: acmTagName, Value: r.AWSCertName})
+	}
+
+	arn, err = client.ImportCertificate(ctx, arn, pcc.Certificate, privateKey, strings.Join(pcc.Chain, ""), tags)
+	if err != nil {
+		zap.L().Error("could not import certificate to ACM", zap.String("location", r.location()), zap.Error(err))
+		return err
+	}
+
+	zap.L().Info("certificate imported to ACM", zap.String("arn", arn))
+	return nil
+}
+
+// Rollback is not supported for ACM, as no backup can be taken of imported certificates
+func (r AWSACMInstaller) Rollback(_ context.Context) error {
+	zap.L().Warn("ACM certificates cannot be rolled back", zap.String("location", r.location()))
+	return nil
+}
+
+// AfterInstallActions runs the actions declared in the Installer, in order: scripts run on a terminal,
+// while services, sites and webhooks are handled natively.
+//
+// No validations happen over the content of the AfterAction scripts, so caution is advised
+func (r AWSACMInstaller) AfterInstallActions(ctx context.Context) (string, error) {
+	zap.L().Debug("running after-install actions", zap.String("location", r.location()))
+
+	result, err := runAfterInstallActions(ctx, r.AfterAction)
+	return result, err
+}
+
+// InstallValidationActions runs any instructions declared in the Installer on a terminal and expects
+// "0" for successful validation and "1" for a validation failure
+// No validations happen over the content of the InstallValidation string, so caution is advised
+func (r AWSACMInstaller) InstallValidationActions(ctx context.Context) (string, error) {
+	zap.L().Debug("running install validation actions", zap.String("location", r.location()))
+
+	validationResult, err := util.ExecuteScript(ctx, r.InstallValidation)
+	if err != nil {
+		return "", err
+	}
+
+	return validationResult, err
+}
+
+func (r AWSACMInstaller) getClient(ctx context.Context) (*aws.ACMClient, error) {
+	client, err := aws.NewACMClient(r.AWSRegion, r.AWSProfile)
+	if err != nil {
+		zap.L().Error("could not load AWS credentials", zap.Error(err))
+		return nil, err
+	}
+	return client, nil
+}
+
+// getCertificateARN returns awsCertificateArn if set. Otherwise, it looks for the certificate tagged with awsCertName.
+// Returns an empty string if the certificate has not been imported yet
+func (r AWSACMInstaller) getCertificateARN(ctx context.Context, client *aws.ACMClient) (string, error) {
+	if r.AWSCertificateARN != "" {
+		return r.AWSCertificateARN, nil
+	}
+	return client.FindCertificateByTag(ctx, aws.Tag{Key: acmTagName, Value: r.AWSCertName})
+}
+
+func (r AWSACMInstaller) location() string {
+	if r.AWSCertificateARN != "" {
+		return r.AWSCertificateARN
+	}
+	return fmt.Sprintf("acm:%s:%s", r.AWSRegion, r.AWSCertName)
+}
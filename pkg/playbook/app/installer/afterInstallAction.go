@@ -0,0 +1,117 @@
+/*
+ * Copyright 2023 Venafi, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *  http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package installer
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"os/exec"
+	"strings"
+	"time"
+
+	"go.uber.org/zap"
+
+	"github.com/Venafi/vcert/v5/pkg/playbook/app/domain"
+	"github.com/Venafi/vcert/v5/pkg/playbook/util"
+)
+
+const (
+	httpPostTimeout  = 30 * time.Second
+	httpPostMaxBytes = 1024 * 1024
+)
+
+// runAfterInstallActions runs the actions in order and stops at the first one that fails.
+// It returns the output of the scripts, which is used to tell whether they succeeded
+func runAfterInstallActions(ctx context.Context, actions domain.AfterInstallActions) (string, error) {
+	var output strings.Builder
+	for _, action := range actions {
+		zap.L().Debug("running after-install action", zap.Stringer("action", action))
+
+		var err error
+		switch {
+		case action.HTTPPost != nil:
+			err = postWebhook(ctx, *action.HTTPPost)
+		case action.ReloadSystemdUnit != "":
+			err = runCommand(ctx, "systemctl", "reload", action.ReloadSystemdUnit)
+		case action.RestartIISSite != "":
+			err = restartIISSite(ctx, action.RestartIISSite)
+		case action.RestartService != "":
+			err = restartService(ctx, action.RestartService)
+		default:
+			var result string
+			result, err = util.ExecuteScript(ctx, action.Script)
+			output.WriteString(result)
+		}
+		if err != nil {
+			return output.String(), fmt.Errorf("after-install action %q failed: %w", action.String(), err)
+		}
+	}
+	return output.String(), nil
+}
+
+// runCommand runs name with args, without a shell in between, and returns its error output when it fails
+func runCommand(ctx context.Context, name string, args ...string) error {
+	zap.L().Debug("running command", zap.String("command", name), zap.Strings("args", args))
+
+	cmd := exec.CommandContext(ctx, name, args...)
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+	err := cmd.Run()
+	if err != nil {
+		if msg := strings.TrimSpace(stderr.String()); msg != "" {
+			return fmt.Errorf("%w: %s", err, msg)
+		}
+		return err
+	}
+	return nil
+}
+
+// postWebhook sends the request described by action and fails when the server does not answer with a 2xx status.
+// The Content-Type defaults to application/json when the request has a body
+func postWebhook(ctx context.Context, action domain.HTTPPostAction) error {
+	body := os.ExpandEnv(action.Body)
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, action.URL, strings.NewReader(body))
+	if err != nil {
+		return err
+	}
+	for name, value := range action.Headers {
+		req.Header.Set(name, os.ExpandEnv(value))
+	}
+	if body != "" && req.Header.Get("Content-Type") == "" {
+		req.Header.Set("Content-Type", "application/json")
+	}
+
+	client := http.Client{Timeout: httpPostTimeout}
+	resp, err := client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		_ = resp.Body.Close()
+	}()
+	// Drain the body so the connection can be reused
+	_, _ = io.Copy(io.Discard, io.LimitReader(resp.Body, httpPostMaxBytes))
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return fmt.Errorf("unexpected status %s", resp.Status)
+	}
+	return nil
+}
@@ -0,0 +1,200 @@
+/*
+ * Copyright 2023 Venafi, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *  http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package installer
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/asn1"
+	"encoding/hex"
+	"encoding/pem"
+	"os"
+	"path/filepath"
+
+	"go.uber.org/zap"
+)
+
+// maxChainLength is the maximum number of issuers followed from the certificate, to stop on loops of cross-signed
+// certificates
+const maxChainLength = 10
+
+var oidSignedData = asn1.ObjectIdentifier{1, 2, 840, 113549, 1, 7, 2}
+
+// DefaultIntermediatesDir returns the directory the issuers downloaded from the Authority Information Access
+// extension of the certificates are cached in, under the cache directory of the user
+func DefaultIntermediatesDir() string {
+	dir, err := os.UserCacheDir()
+	if err != nil {
+		return ""
+	}
+	return filepath.Join(dir, "vcert", "intermediates")
+}
+
+// CompleteChain returns the chain of certPEM completed with the issuers missing from chainPEMs, and whether the
+// completed chain reaches a root, either self-signed or trusted by the system.
+//
+// The missing issuers are looked for in cacheDir and, if not found there, downloaded from the CA Issuers URLs of
+// the Authority Information Access extension and cached in cacheDir. They are appended to the chain or, when
+// rootFirst is true, prepended to it. Nothing is cached when cacheDir is empty
+func CompleteChain(certPEM string, chainPEMs []string, cacheDir string, rootFirst bool) ([]string, bool) {
+	leaf, err := parsePEMCertificate([]byte(certPEM))
+	if err != nil {
+		zap.L().Warn("could not parse certificate, chain will be installed as received", zap.Error(err))
+		return chainPEMs, false
+	}
+	chain := make([]*x509.Certificate, 0, len(chainPEMs))
+	for _, p := range chainPEMs {
+		chain = append(chain, parsePEMCertificates([]byte(p))...)
+	}
+
+	added := make([]string, 0)
+	current := leaf
+	for i := 0; i < maxChainLength && !isSelfSigned(current); i++ {
+		issuer := findChainIssuer(current, chain)
+		if issuer == nil {
+			issuer = fetchIssuer(current, cacheDir)
+			if issuer == nil {
+				break
+			}
+			zap.L().Info("added missing issuer to the certificate chain", zap.String("issuer", issuer.Subject.String()))
+			chain = append(chain, issuer)
+			added = append(added, string(pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: issuer.Raw})))
+		}
+		current = issuer
+	}
+	complete := isSelfSigned(current) || isSystemTrusted(current)
+
+	if len(added) == 0 {
+		return chainPEMs, complete
+	}
+	if rootFirst {
+		result := make([]string, 0, len(added)+len(chainPEMs))
+		for i := len(added) - 1; i >= 0; i-- {
+			result = append(result, added[i])
+		}
+		return append(result, chainPEMs...), complete
+	}
+	return append(append([]string{}, chainPEMs...), added...), complete
+}
+
+func findChainIssuer(cert *x509.Certificate, chain []*x509.Certificate) *x509.Certificate {
+	for _, c := range chain {
+		if bytes.Equal(cert.RawIssuer, c.RawSubject) && cert.CheckSignatureFrom(c) == nil {
+			return c
+		}
+	}
+	return nil
+}
+
+// fetchIssuer returns the issuer of cert from cacheDir or, if it is not cached, from the CA Issuers URLs of cert
+func fetchIssuer(cert *x509.Certificate, cacheDir string) *x509.Certificate {
+	cacheFile := ""
+	if cacheDir != "" {
+		sum := sha256.Sum256(cert.RawIssuer)
+		cacheFile = filepath.Join(cacheDir, hex.EncodeToString(sum[:])+".pem")
+		data, err := os.ReadFile(cacheFile)
+		if err == nil {
+			if issuer := findChainIssuer(cert, parsePEMCertificates(data)); issuer != nil {
+				zap.L().Debug("issuer found in cache", zap.String("file", cacheFile))
+				return issuer
+			}
+		}
+	}
+
+	for _, url := range cert.IssuingCertificateURL {
+		data, err := httpGet(url)
+		if err != nil {
+			zap.L().Debug("could not download issuer certificate", zap.String("url", url), zap.Error(err))
+			continue
+		}
+		issuer := findChainIssuer(cert, parseIssuerCertificates(data))
+		if issuer == nil {
+			zap.L().Debug("no issuer certificate found", zap.String("url", url))
+			continue
+		}
+
+		if cacheFile != "" {
+			err = cacheIssuer(cacheFile, issuer)
+			if err != nil {
+				zap.L().Warn("could not cache issuer certificate", zap.String("file", cacheFile), zap.Error(err))
+			}
+		}
+		return issuer
+	}
+	return nil
+}
+
+func cacheIssuer(cacheFile string, issuer *x509.Certificate) error {
+	err := os.MkdirAll(filepath.Dir(cacheFile), 0700)
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(cacheFile, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: issuer.Raw}), 0644)
+}
+
+// parseIssuerCertificates returns the certificates served at a CA Issuers URL, which are either a DER certificate,
+// PEM certificates or a DER PKCS#7 certs-only message (RFC 5280, section 4.2.2.1)
+func parseIssuerCertificates(data []byte) []*x509.Certificate {
+	if certs := parsePEMCertificates(data); len(certs) > 0 {
+		return certs
+	}
+	if cert, err := x509.ParseCertificate(data); err == nil {
+		return []*x509.Certificate{cert}
+	}
+
+	var info struct {
+		ContentType asn1.ObjectIdentifier
+		Content     asn1.RawValue `asn1:"explicit,optional,tag:0"`
+	}
+	_, err := asn1.Unmarshal(data, &info)
+	if err != nil || !info.ContentType.Equal(oidSignedData) {
+		return nil
+	}
+	var signedData struct {
+		Version          int
+		DigestAlgorithms asn1.RawValue
+		ContentInfo      asn1.RawValue
+		Certificates     asn1.RawValue `asn1:"optional,tag:0"`
+		CRLs             asn1.RawValue `asn1:"optional,tag:1"`
+		SignerInfos      asn1.RawValue
+	}
+	_, err = asn1.Unmarshal(info.Content.Bytes, &signedData)
+	if err != nil {
+		return nil
+	}
+	certs, err := x509.ParseCertificates(signedData.Certificates.Bytes)
+	if err != nil {
+		zap.L().Debug("could not parse PKCS#7 certificates", zap.Error(err))
+		return nil
+	}
+	return certs
+}
+
+// isSystemTrusted returns true when cert is issued by a root of the system trust store
+func isSystemTrusted(cert *x509.Certificate) bool {
+	roots, err := x509.SystemCertPool()
+	if err != nil {
+		return false
+	}
+	_, err = cert.Verify(x509.VerifyOptions{
+		Roots:       roots,
+		CurrentTime: cert.NotBefore,
+		KeyUsages:   []x509.ExtKeyUsage{x509.ExtKeyUsageAny},
+	})
+	return err == nil
+}
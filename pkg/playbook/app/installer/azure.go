@@ -0,0 +1,209 @@
+/*
+ * Copyright 2023 Venafi, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *  http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package installer
+
+import (
+	"context"
+	"crypto/x509"
+	"fmt"
+	"strings"
+
+	"go.uber.org/zap"
+
+	"github.com/Venafi/vcert/v5/pkg/certificate"
+	"github.com/Venafi/vcert/v5/pkg/playbook/app/domain"
+	"github.com/Venafi/vcert/v5/pkg/playbook/util"
+	"github.com/Venafi/vcert/v5/pkg/playbook/util/azure"
+)
+
+const (
+	azureTagManagedBy       = "managed-by"
+	azureTagPreviousVersion = "vcert-previous-version"
+)
+
+// AzureKeyVaultInstaller represents an installation that will import the certificate bundle in an Azure Key Vault
+type AzureKeyVaultInstaller struct {
+	domain.Installation
+}
+
+// NewAzureKeyVaultInstaller returns a new installer of type AZUREKEYVAULT with the values defined in inst
+func NewAzureKeyVaultInstaller(inst domain.Installation) AzureKeyVaultInstaller {
+	return AzureKeyVaultInstaller{inst}
+}
+
+// Check is the method in charge of making the validations to install a new certificate:
+// 1. Does the certificate exists? > Install if it doesn't.
+// 2. Does the certificate is about to expire? Renew if about to expire.
+// Returns true if the certificate needs to be installed, along with the certificate currently installed, if any.
+func (r AzureKeyVaultInstaller) Check(ctx context.Context, renewBefore string, _ domain.PlaybookRequest) (bool, *x509.Certificate, error) {
+	zap.L().Info("checking certificate health", zap.String("format", r.Type.String()), zap.String("location", r.location()))
+
+	client, err := r.getClient(ctx)
+	if err != nil {
+		return false, nil, err
+	}
+
+	azureCert, err := client.GetCertificate(ctx, r.AzureCertName)
+	if err != nil {
+		return false, nil, err
+	}
+	if azureCert == nil || len(azureCert.CER) == 0 {
+		zap.L().Debug("certificate does not exist", zap.String("location", r.location()))
+		return true, nil, nil
+	}
+
+	cert, err := x509.ParseCertificate(azureCert.CER)
+	if err != nil {
+		return false, nil, fmt.Errorf("could not parse certificate to X509 object: %w", err)
+	}
+
+	// Check certificate expiration
+	renew := needRenewal(cert, renewBefore)
+
+	// Check certificate revocation
+	if !renew && r.ValidateRevocation {
+		renew = isRevoked(cert, nil)
+	}
+
+	return renew, cert, nil
+}
+
+// Backup is a no-op for Azure Key Vault, as every import creates a new version of the certificate
+// and previous versions are kept by the vault
+func (r AzureKeyVaultInstaller) Backup(_ context.Context) error {
+	zap.L().Debug("certificate versions are kept by Azure Key Vault, no back up taken", zap.String("location", r.location()))
+	return nil
+}
+
+// Install takes the certificate bundle and moves it to the location specified in the installer
+func (r AzureKeyVaultInstaller) Install(ctx context.Context, pcc certificate.PEMCollection) error {
+	zap.L().Debug("installing certificate", zap.String("location", r.location()))
+
+	if len(pcc.Certificate) == 0 || len(pcc.PrivateKey) == 0 {
+		return fmt.Errorf("certificate and Private Key are required for Azure Key Vault")
+	}
+
+	// Azure Key Vault requires an unencrypted PKCS8 private key for PEM imports
+	privateKey, err := MarshalPKCS8PrivateKey(pcc.PrivateKey, "")
+	if err != nil {
+		zap.L().Error("could not prepare private key for Azure Key Vault", zap.Error(err))
+		return err
+	}
+	bundle := privateKey + pcc.Certificate + strings.Join(pcc.Chain, "")
+
+	client, err := r.getClient(ctx)
+	if err != nil {
+		return err
+	}
+
+	// Keep track of the replaced version, so it can be restored by Rollback
+	tags := map[string]string{azureTagManagedBy: "vcert"}
+	current, err := client.GetCertificate(ctx, r.AzureCertName)
+	if err != nil {
+		return err
+	}
+	if current != nil {
+		tags[azureTagPreviousVersion] = current.Version()
+	}
+
+	imported, err := client.ImportCertificate(ctx, r.AzureCertName, bundle, azure.ContentTypePEM, tags)
+	if err != nil {
+		zap.L().Error("could not import certificate to Azure Key Vault", zap.String("location", r.location()), zap.Error(err))
+		return err
+	}
+
+	zap.L().Debug("certificate imported", zap.String("location", r.location()), zap.String("version", imported.Version()))
+	return nil
+}
+
+// Rollback restores the certificate version replaced by Install, importing it again as the current version
+func (r AzureKeyVaultInstaller) Rollback(ctx context.Context) error {
+	zap.L().Debug("rolling back certificate", zap.String("location", r.location()))
+
+	client, err := r.getClient(ctx)
+	if err != nil {
+		return err
+	}
+
+	current, err := client.GetCertificate(ctx, r.AzureCertName)
+	if err != nil {
+		return err
+	}
+	if current == nil || current.Tags[azureTagPreviousVersion] == "" {
+		zap.L().Info("no previous version found, nothing to restore", zap.String("location", r.location()))
+		return nil
+	}
+	previousVersion := current.Tags[azureTagPreviousVersion]
+
+	secret, err := client.GetSecret(ctx, r.AzureCertName, previousVersion)
+	if err != nil {
+		return err
+	}
+
+	_, err = client.ImportCertificate(ctx, r.AzureCertName, secret.Value, secret.ContentType,
+		map[string]string{azureTagManagedBy: "vcert"})
+	if err != nil {
+		return err
+	}
+
+	zap.L().Info("certificate restored from previous version", zap.String("location", r.location()),
+		zap.String("version", previousVersion))
+	return nil
+}
+
+// AfterInstallActions runs the actions declared in the Installer, in order: scripts run on a terminal,
+// while services, sites and webhooks are handled natively.
+//
+// No validations happen over the content of the AfterAction scripts, so caution is advised
+func (r AzureKeyVaultInstaller) AfterInstallActions(ctx context.Context) (string, error) {
+	zap.L().Debug("running after-install actions", zap.String("location", r.location()))
+
+	result, err := runAfterInstallActions(ctx, r.AfterAction)
+	return result, err
+}
+
+// InstallValidationActions runs any instructions declared in the Installer on a terminal and expects
+// "0" for successful validation and "1" for a validation failure
+// No validations happen over the content of the InstallValidation string, so caution is advised
+func (r AzureKeyVaultInstaller) InstallValidationActions(ctx context.Context) (string, error) {
+	zap.L().Debug("running install validation actions", zap.String("location", r.location()))
+
+	validationResult, err := util.ExecuteScript(ctx, r.InstallValidation)
+	if err != nil {
+		return "", err
+	}
+
+	return validationResult, err
+}
+
+func (r AzureKeyVaultInstaller) getClient(ctx context.Context) (*azure.Client, error) {
+	credentials := azure.Credentials{
+		TenantID:     r.AzureTenantID,
+		ClientID:     r.AzureClientID,
+		ClientSecret: r.AzureClientSecret,
+	}
+	client, err := azure.NewClient(r.AzureVaultURI, credentials)
+	if err != nil {
+		zap.L().Error("could not authenticate to Azure Key Vault", zap.Error(err))
+		return nil, err
+	}
+	return client, nil
+}
+
+func (r AzureKeyVaultInstaller) location() string {
+	return fmt.Sprintf("%s/certificates/%s", strings.TrimSuffix(r.AzureVaultURI, "/"), r.AzureCertName)
+}
@@ -19,16 +19,22 @@
 package installer
 
 import (
+	"context"
+	"crypto/sha1"
+	"crypto/x509"
+	"encoding/hex"
 	"fmt"
 	"strings"
 
 	"go.uber.org/zap"
+	"software.sslmate.com/src/go-pkcs12"
 
 	"github.com/Venafi/vcert/v5/pkg/certificate"
 	"github.com/Venafi/vcert/v5/pkg/playbook/app/domain"
 	"github.com/Venafi/vcert/v5/pkg/playbook/app/vcertutil"
 	"github.com/Venafi/vcert/v5/pkg/playbook/util"
 	"github.com/Venafi/vcert/v5/pkg/playbook/util/capistore"
+	"github.com/Venafi/vcert/v5/pkg/tpm"
 )
 
 // CAPIInstaller represents an installation that will happen in the Windows CAPI store
@@ -44,8 +50,8 @@ func NewCAPIInstaller(inst domain.Installation) CAPIInstaller {
 // Check is the method in charge of making the validations to install a new certificate:
 // 1. Does the certificate exists? > Install if it doesn't.
 // 2. Does the certificate is about to expire? Renew if about to expire.
-// Returns true if the certificate needs to be installed.
-func (r CAPIInstaller) Check(renewBefore string, request domain.PlaybookRequest) (bool, error) {
+// Returns true if the certificate needs to be installed, along with the certificate currently installed, if any.
+func (r CAPIInstaller) Check(_ context.Context, renewBefore string, request domain.PlaybookRequest) (bool, *x509.Certificate, error) {
 	zap.L().Info("checking certificate health", zap.String("format", r.Type.String()), zap.String("location", r.CAPILocation))
 
 	// Get friendly name. If no friendly name is set, get CN from request as friendly name.
@@ -64,7 +70,7 @@ func (r CAPIInstaller) Check(renewBefore string, request domain.PlaybookRequest)
 	storeLocation, storeName, err := getCertStore(location)
 	if err != nil {
 		zap.L().Error("failed to get certificate store", zap.Error(err))
-		return true, err
+		return true, nil, err
 	}
 
 	config := capistore.InstallationConfig{
@@ -78,47 +84,44 @@ func (r CAPIInstaller) Check(renewBefore string, request domain.PlaybookRequest)
 	certPem, err := ps.RetrieveCertificateFromCAPI(config)
 	if err != nil {
 		zap.L().Error("failed to retrieve certificate from CAPI store", zap.Error(err))
-		return true, err
+		return true, nil, err
 	}
 
 	// Certificate was not found.
 	if certPem == "" {
 		zap.L().Info("certificate not found")
-		return true, nil
+		return true, nil, nil
 	}
 
 	// Check certificate expiration
 	cert, err := parsePEMCertificate([]byte(certPem))
 	if err != nil {
-		return false, err
+		return false, nil, err
 	}
 
 	// Check certificate expiration
 	renew := needRenewal(cert, renewBefore)
 
-	return renew, nil
+	// Check certificate revocation
+	if !renew && r.ValidateRevocation {
+		renew = isRevoked(cert, nil)
+	}
+
+	return renew, cert, nil
 }
 
 // Backup takes the certificate request and backs up the current version prior to overwriting
-func (r CAPIInstaller) Backup() error {
+func (r CAPIInstaller) Backup(_ context.Context) error {
 	zap.L().Debug("certificate is backed up by default for CAPI")
 	return nil
 }
 
 // Install takes the certificate bundle and moves it to the location specified in the installer
-func (r CAPIInstaller) Install(pcc certificate.PEMCollection) error {
+func (r CAPIInstaller) Install(ctx context.Context, pcc certificate.PEMCollection) error {
 	zap.L().Debug("installing certificate", zap.String("location", r.CAPILocation))
 
-	// Generate random password for temporary P12 bundle
-	bundlePassword := vcertutil.GeneratePassword()
-
-	content, err := packageAsPKCS12(pcc, bundlePassword)
-	if err != nil {
-		zap.L().Error("could not package certificate as PKCS12", zap.Error(err))
-		return err
-	}
-
 	// Get friendly name. If no friendly name is set, get CN from certificate as friendly name
+	var err error
 	friendlyName := r.CAPIFriendlyName
 	if friendlyName == "" {
 		friendlyName, err = getCertFriendlyName([]byte(pcc.Certificate))
@@ -139,13 +142,28 @@ func (r CAPIInstaller) Install(pcc certificate.PEMCollection) error {
 		return err
 	}
 
+	// The private key generated in the TPM does not leave it, so the certificate is bound to the key instead
+	if pcc.PrivateKey == "" {
+		return r.installForTPMKey(ctx, pcc, friendlyName, storeLocation, storeName)
+	}
+
+	// Generate random password for temporary P12 bundle
+	bundlePassword := vcertutil.GeneratePassword()
+
+	content, err := packageAsPKCS12(pcc, bundlePassword, pkcs12.LegacyRC2)
+	if err != nil {
+		zap.L().Error("could not package certificate as PKCS12", zap.Error(err))
+		return err
+	}
+
 	config := capistore.InstallationConfig{
-		PFX:             content,
-		FriendlyName:    friendlyName,
-		IsNonExportable: r.CAPIIsNonExportable,
-		Password:        bundlePassword,
-		StoreLocation:   storeLocation,
-		StoreName:       storeName,
+		PFX:                content,
+		FriendlyName:       friendlyName,
+		IsNonExportable:    r.CAPIIsNonExportable,
+		KeyStorageProvider: r.CAPIKeyStorageProvider,
+		Password:           bundlePassword,
+		StoreLocation:      storeLocation,
+		StoreName:          storeName,
 	}
 
 	ps := capistore.NewPowerShell()
@@ -156,25 +174,99 @@ func (r CAPIInstaller) Install(pcc certificate.PEMCollection) error {
 		return err
 	}
 
+	return r.bind(ctx, pcc, storeName)
+}
+
+// installForTPMKey installs the certificate of pcc, whose private key was generated in the TPM, and binds it to the key
+func (r CAPIInstaller) installForTPMKey(ctx context.Context, pcc certificate.PEMCollection, friendlyName, storeLocation, storeName string) error {
+	cert, err := parsePEMCertificate([]byte(pcc.Certificate))
+	if err != nil {
+		return err
+	}
+	chain, err := getX509CertChain(pcc.Chain)
+	if err != nil {
+		return err
+	}
+
+	config := capistore.InstallationConfig{
+		Certificate:        cert.Raw,
+		FriendlyName:       friendlyName,
+		KeyStorageProvider: tpm.PlatformCryptoProvider,
+		StoreLocation:      storeLocation,
+		StoreName:          storeName,
+	}
+	for _, chainCert := range chain {
+		config.Chain = append(config.Chain, chainCert.Raw)
+	}
+
+	err = capistore.NewPowerShell().InstallCertificateForKeyToCAPI(config)
+	if err != nil {
+		zap.L().Error("failed to install certificate in CAPI store", zap.Error(err))
+		return err
+	}
+
+	return r.bind(ctx, pcc, storeName)
+}
+
+// bind sets the installed certificate in the IIS site binding and the RDP listener declared in the installation
+func (r CAPIInstaller) bind(ctx context.Context, pcc certificate.PEMCollection, storeName string) error {
+	if r.BindIIS == "" && !r.BindRDP {
+		return nil
+	}
+
+	cert, err := parsePEMCertificate([]byte(pcc.Certificate))
+	if err != nil {
+		return err
+	}
+	thumbprint := sha1.Sum(cert.Raw)
+	hexThumbprint := strings.ToUpper(hex.EncodeToString(thumbprint[:]))
+
+	if r.BindIIS != "" {
+		site, port, err := domain.ParseBindIIS(r.BindIIS)
+		if err != nil {
+			return err
+		}
+		zap.L().Info("binding certificate to IIS site", zap.String("site", site), zap.Int("port", port),
+			zap.String("thumbprint", hexThumbprint))
+		err = bindIISSite(ctx, site, port, hexThumbprint, storeName)
+		if err != nil {
+			return fmt.Errorf("failed to bind certificate to IIS site %s: %w", r.BindIIS, err)
+		}
+	}
+
+	if r.BindRDP {
+		zap.L().Info("binding certificate to RDP listener", zap.String("thumbprint", hexThumbprint))
+		err = bindRDP(ctx, hexThumbprint)
+		if err != nil {
+			return fmt.Errorf("failed to bind certificate to RDP listener: %w", err)
+		}
+	}
+	return nil
+}
+
+// Rollback is a no-op for CAPI, as installing a certificate does not remove the previous one from the store
+func (r CAPIInstaller) Rollback(_ context.Context) error {
+	zap.L().Debug("certificate rollback is not needed for CAPI")
 	return nil
 }
 
-// AfterInstallActions runs any instructions declared in the Installer on a terminal.
+// AfterInstallActions runs the actions declared in the Installer, in order: scripts run on a terminal,
+// while services, sites and webhooks are handled natively.
 //
-// No validations happen over the content of the AfterAction string, so caution is advised
-func (r CAPIInstaller) AfterInstallActions() (string, error) {
+// No validations happen over the content of the AfterAction scripts, so caution is advised
+func (r CAPIInstaller) AfterInstallActions(ctx context.Context) (string, error) {
 	zap.L().Debug("running after-install actions", zap.String("location", r.CAPILocation))
 
-	result, err := util.ExecuteScript(r.AfterAction)
+	result, err := runAfterInstallActions(ctx, r.AfterAction)
 	return result, err
 }
 
 // InstallValidationActions runs any instructions declared in the Installer on a terminal and expects
 // "0" for successful validation and "1" for a validation failure
 // No validations happen over the content of the InstallValidation string, so caution is advised
-func (r CAPIInstaller) InstallValidationActions() (string, error) {
+func (r CAPIInstaller) InstallValidationActions(ctx context.Context) (string, error) {
 	zap.L().Debug("running install validation actions", zap.String("location", r.CAPILocation))
-	validationResult, err := util.ExecuteScript(r.InstallValidation)
+	validationResult, err := util.ExecuteScript(ctx, r.InstallValidation)
 	if err != nil {
 		return "", err
 	}
@@ -0,0 +1,177 @@
+/*
+ * Copyright 2023 Venafi, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *  http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package installer
+
+import (
+	"bytes"
+	"crypto/x509"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	"go.uber.org/zap"
+
+	"github.com/Venafi/vcert/v5/pkg/playbook/app/domain"
+)
+
+type chainCertificate struct {
+	pem  string
+	cert *x509.Certificate
+}
+
+// OrderChain returns the chain of certPEM in the given order, without the self-signed root when excludeRoot is true.
+// The chain returned by the Venafi platform is kept as is when order is empty, or when it cannot be parsed
+func OrderChain(certPEM string, chainPEMs []string, order string, excludeRoot bool) []string {
+	if len(chainPEMs) == 0 || (order == "" && !excludeRoot) {
+		return chainPEMs
+	}
+
+	leaf, err := parsePEMCertificate([]byte(certPEM))
+	if err != nil {
+		zap.L().Warn("could not parse certificate, chain will be installed as received", zap.Error(err))
+		return chainPEMs
+	}
+	chain := make([]chainCertificate, 0, len(chainPEMs))
+	for _, p := range chainPEMs {
+		cert, err := parsePEMCertificate([]byte(p))
+		if err != nil {
+			zap.L().Warn("could not parse chain certificate, chain will be installed as received", zap.Error(err))
+			return chainPEMs
+		}
+		chain = append(chain, chainCertificate{pem: p, cert: cert})
+	}
+
+	if order != "" {
+		chain = sortRootLast(leaf, chain)
+		if strings.ToLower(order) == domain.ChainOrderRootFirst {
+			for i, j := 0, len(chain)-1; i < j; i, j = i+1, j-1 {
+				chain[i], chain[j] = chain[j], chain[i]
+			}
+		}
+	}
+
+	result := make([]string, 0, len(chain))
+	for _, c := range chain {
+		if excludeRoot && isSelfSigned(c.cert) {
+			continue
+		}
+		result = append(result, c.pem)
+	}
+	return result
+}
+
+// VerifyChain checks that chainPEMs builds a valid path from certPEM to a trusted root: a self-signed root of the chain
+// or a root trusted by the system or, when trustBundle is set, only a root of the PEM bundle at trustBundle.
+// Signatures, validity periods and constraints of the path are verified
+func VerifyChain(certPEM string, chainPEMs []string, trustBundle string) error {
+	leaf, err := parsePEMCertificate([]byte(certPEM))
+	if err != nil {
+		return err
+	}
+
+	var roots *x509.CertPool
+	if trustBundle != "" {
+		data, err := os.ReadFile(trustBundle)
+		if err != nil {
+			return fmt.Errorf("could not read chain trust bundle: %w", err)
+		}
+		roots = x509.NewCertPool()
+		if !roots.AppendCertsFromPEM(data) {
+			return fmt.Errorf("no certificates found in chain trust bundle %s", trustBundle)
+		}
+	} else {
+		if isSelfSigned(leaf) {
+			return nil
+		}
+		roots, err = x509.SystemCertPool()
+		if err != nil {
+			roots = x509.NewCertPool()
+		}
+	}
+
+	intermediates := x509.NewCertPool()
+	for _, p := range chainPEMs {
+		for _, cert := range parsePEMCertificates([]byte(p)) {
+			if !isSelfSigned(cert) {
+				intermediates.AddCert(cert)
+			} else if trustBundle == "" {
+				roots.AddCert(cert)
+			}
+		}
+	}
+
+	// The certificate may have been issued with a NotBefore slightly ahead of the local clock
+	now := time.Now()
+	if now.Before(leaf.NotBefore) {
+		now = leaf.NotBefore
+	}
+	_, err = leaf.Verify(x509.VerifyOptions{
+		Roots:         roots,
+		Intermediates: intermediates,
+		CurrentTime:   now,
+		KeyUsages:     []x509.ExtKeyUsage{x509.ExtKeyUsageAny},
+	})
+	return err
+}
+
+// sortRootLast follows the issuers from leaf up to the root. Certificates that are not part of that path are kept at the end
+func sortRootLast(leaf *x509.Certificate, chain []chainCertificate) []chainCertificate {
+	sorted := make([]chainCertificate, 0, len(chain))
+	used := make([]bool, len(chain))
+	current := leaf
+	for len(sorted) < len(chain) && !isSelfSigned(current) {
+		next := -1
+		for i, c := range chain {
+			if !used[i] && bytes.Equal(c.cert.RawSubject, current.RawIssuer) {
+				next = i
+				break
+			}
+		}
+		if next < 0 {
+			break
+		}
+		used[next] = true
+		sorted = append(sorted, chain[next])
+		current = chain[next].cert
+	}
+	for i, c := range chain {
+		if !used[i] {
+			sorted = append(sorted, c)
+		}
+	}
+	return sorted
+}
+
+func isSelfSigned(cert *x509.Certificate) bool {
+	return bytes.Equal(cert.RawSubject, cert.RawIssuer) && cert.CheckSignatureFrom(cert) == nil
+}
+
+// joinPEM concatenates the PEM blocks, making sure each one ends with a new line
+func joinPEM(blocks ...string) string {
+	sb := strings.Builder{}
+	for _, b := range blocks {
+		if b == "" {
+			continue
+		}
+		sb.WriteString(b)
+		if !strings.HasSuffix(b, "\n") {
+			sb.WriteString("\n")
+		}
+	}
+	return sb.String()
+}
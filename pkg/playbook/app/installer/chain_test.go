@@ -0,0 +1,110 @@
+/*
+ * Copyright 2023 Venafi, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *  http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package installer
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/Venafi/vcert/v5/pkg/playbook/app/domain"
+	"github.com/Venafi/vcert/v5/test/testcert"
+)
+
+func TestOrderChain(t *testing.T) {
+	root := testcert.Issue(testcert.Template("Test Root", true), nil, nil)
+	intermediate := testcert.Issue(testcert.Template("Test Intermediate", true), nil, &root)
+	issuing := testcert.Issue(testcert.Template("Test Issuing CA", true), nil, &intermediate)
+	leaf := testcert.Issue(testcert.Template("leaf.example.com", false), nil, &issuing)
+	unrelated := testcert.Issue(testcert.Template("Unrelated CA", true), nil, nil)
+
+	// The chain is received in no particular order
+	received := []string{intermediate.PEM(), root.PEM(), issuing.PEM()}
+
+	cases := []struct {
+		name        string
+		chain       []string
+		order       string
+		excludeRoot bool
+		expected    []string
+	}{
+		{name: "AsReceived", chain: received, expected: received},
+		{name: "RootLast", chain: received, order: domain.ChainOrderRootLast,
+			expected: []string{issuing.PEM(), intermediate.PEM(), root.PEM()}},
+		{name: "RootFirst", chain: received, order: "Root-First",
+			expected: []string{root.PEM(), intermediate.PEM(), issuing.PEM()}},
+		{name: "ExcludeRoot", chain: received, excludeRoot: true,
+			expected: []string{intermediate.PEM(), issuing.PEM()}},
+		{name: "RootLastExcludeRoot", chain: received, order: domain.ChainOrderRootLast, excludeRoot: true,
+			expected: []string{issuing.PEM(), intermediate.PEM()}},
+		{name: "UnrelatedKeptLast", chain: []string{unrelated.PEM(), root.PEM(), issuing.PEM(), intermediate.PEM()}, order: domain.ChainOrderRootLast,
+			expected: []string{issuing.PEM(), intermediate.PEM(), root.PEM(), unrelated.PEM()}},
+		{name: "Unparsable", chain: []string{"not a certificate", root.PEM()}, order: domain.ChainOrderRootFirst, excludeRoot: true,
+			expected: []string{"not a certificate", root.PEM()}},
+		{name: "Empty", chain: nil, order: domain.ChainOrderRootFirst, expected: nil},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			ordered := OrderChain(leaf.PEM(), c.chain, c.order, c.excludeRoot)
+			if strings.Join(ordered, "") != strings.Join(c.expected, "") {
+				t.Fatalf("unexpected chain order: got %s, expected %s", subjects(t, ordered), subjects(t, c.expected))
+			}
+		})
+	}
+}
+
+func TestVerifyChain(t *testing.T) {
+	root := testcert.Issue(testcert.Template("Test Root", true), nil, nil)
+	intermediate := testcert.Issue(testcert.Template("Test Intermediate", true), nil, &root)
+	leaf := testcert.Issue(testcert.Template("leaf.example.com", false), nil, &intermediate)
+	otherRoot := testcert.Issue(testcert.Template("Other Root", true), nil, nil)
+
+	err := VerifyChain(leaf.PEM(), []string{root.PEM(), intermediate.PEM()}, "")
+	if err != nil {
+		t.Fatalf("valid chain rejected: %s", err)
+	}
+	err = VerifyChain(leaf.PEM(), []string{root.PEM()}, "")
+	if err == nil {
+		t.Fatalf("chain without its intermediate accepted")
+	}
+
+	bundle := t.TempDir() + "/bundle.pem"
+	writeTestFile(t, bundle, otherRoot.PEM())
+	err = VerifyChain(leaf.PEM(), []string{intermediate.PEM(), root.PEM()}, bundle)
+	if err == nil {
+		t.Fatalf("chain accepted although its root is not in the trust bundle")
+	}
+	writeTestFile(t, bundle, otherRoot.PEM()+root.PEM())
+	err = VerifyChain(leaf.PEM(), []string{intermediate.PEM()}, bundle)
+	if err != nil {
+		t.Fatalf("chain to a root of the trust bundle rejected: %s", err)
+	}
+}
+
+// subjects returns the common names of the PEM certificates, to make the order of a chain readable
+func subjects(t *testing.T, pems []string) []string {
+	t.Helper()
+	names := make([]string, 0, len(pems))
+	for _, p := range pems {
+		cert, err := parsePEMCertificate([]byte(p))
+		if err != nil {
+			names = append(names, p)
+			continue
+		}
+		names = append(names, cert.Subject.CommonName)
+	}
+	return names
+}
@@ -0,0 +1,490 @@
+/*
+ * Copyright 2023 Venafi, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *  http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package installer
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha1"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/hex"
+	"fmt"
+	"math/big"
+	"path"
+	"regexp"
+	"strings"
+	"time"
+
+	"go.uber.org/zap"
+
+	"github.com/Venafi/vcert/v5/pkg/certificate"
+	"github.com/Venafi/vcert/v5/pkg/playbook/app/domain"
+	"github.com/Venafi/vcert/v5/pkg/playbook/util"
+	"github.com/Venafi/vcert/v5/pkg/playbook/util/citrix"
+)
+
+const (
+	// citrixCAPrefix is the prefix of the certkeys created for the certificates of the chain. They are named after
+	// their thumbprint, so they are shared by all the certkeys they issue
+	citrixCAPrefix = "vcert_ca_"
+
+	// citrixTimeLayout is the layout of the validity dates of certkeys returned by the Nitro API
+	citrixTimeLayout = "Jan _2 15:04:05 2006 MST"
+)
+
+// CitrixADCInstaller represents an installation that will upload the certificate, its private key and its chain to a
+// Citrix ADC (NetScaler), and bind them to SSL virtual servers.
+//
+// The certkey is created on the first installation and updated in place on renewals, so its bindings are kept. The files
+// of the previous certificate are kept in the ADC, so the certkey can be switched back to them on Rollback
+type CitrixADCInstaller struct {
+	domain.Installation
+}
+
+// NewCitrixADCInstaller returns a new installer of type CITRIXADC with the values defined in inst
+func NewCitrixADCInstaller(inst domain.Installation) CitrixADCInstaller {
+	return CitrixADCInstaller{inst}
+}
+
+// Check is the method in charge of making the validations to install a new certificate:
+// 1. Does the certificate exists? > Install if it doesn't.
+// 2. Does the certificate is about to expire? Renew if about to expire.
+// 3. Is the certificate bound to all the virtual servers? Install if it isn't.
+// Returns true if the certificate needs to be installed, along with the certificate currently installed, if any.
+//
+// The ADC does not return the certificate itself, only its details
+func (r CitrixADCInstaller) Check(ctx context.Context, renewBefore string, _ domain.PlaybookRequest) (bool, *x509.Certificate, error) {
+	zap.L().Info("checking certificate health", zap.String("format", r.Type.String()), zap.String("location", r.location()))
+
+	client, err := r.getClient(ctx)
+	if err != nil {
+		return false, nil, err
+	}
+	defer r.logout(ctx, client)
+
+	certKey, err := client.GetCertKey(ctx, r.CitrixCertKey)
+	if err != nil {
+		return false, nil, err
+	}
+	if certKey == nil {
+		zap.L().Debug("certificate does not exist", zap.String("location", r.location()))
+		return true, nil, nil
+	}
+	if r.ValidateRevocation {
+		zap.L().Warn("validateRevocation is not supported for Citrix ADC installations", zap.String("location", r.location()))
+	}
+
+	cert := citrixX509Certificate(*certKey)
+	if needRenewal(cert, renewBefore) {
+		return true, cert, nil
+	}
+
+	for _, vserver := range r.CitrixVServers {
+		bound, err := r.isBound(ctx, client, vserver)
+		if err != nil {
+			return false, nil, err
+		}
+		if !bound {
+			zap.L().Info("certificate is not bound to virtual server", zap.String("location", r.location()),
+				zap.String("vserver", vserver))
+			return true, cert, nil
+		}
+	}
+	return false, cert, nil
+}
+
+// Backup is a no-op for Citrix ADC. Install uploads new files instead of replacing the current ones,
+// so the files of the previous certificate are kept in the ADC
+func (r CitrixADCInstaller) Backup(_ context.Context) error {
+	zap.L().Debug("previous certificate files are kept in the ADC, no back up taken", zap.String("location", r.location()))
+	return nil
+}
+
+// Install takes the certificate bundle and moves it to the location specified in the installer.
+//
+// The certificate and private key are uploaded as new files, and the certkey is created from them or, when it already
+// exists, updated in place. The certificates of the chain are installed as certkeys linked to each other, and the
+// certkey is then bound to the virtual servers, replacing the server certificate they had
+func (r CitrixADCInstaller) Install(ctx context.Context, pcc certificate.PEMCollection) error {
+	zap.L().Debug("installing certificate", zap.String("location", r.location()))
+
+	if len(pcc.Certificate) == 0 || len(pcc.PrivateKey) == 0 {
+		return fmt.Errorf("certificate and Private Key are required for Citrix ADC")
+	}
+
+	cert, err := parsePEMCertificate([]byte(pcc.Certificate))
+	if err != nil {
+		return err
+	}
+	privateKey, err := MarshalPKCS8PrivateKey(pcc.PrivateKey, "")
+	if err != nil {
+		zap.L().Error("could not prepare private key for Citrix ADC", zap.Error(err))
+		return err
+	}
+
+	client, err := r.getClient(ctx)
+	if err != nil {
+		return err
+	}
+	defer r.logout(ctx, client)
+
+	err = r.installCertKey(ctx, client, cert, pcc.Certificate, privateKey)
+	if err != nil {
+		return err
+	}
+	err = r.installChain(ctx, client, cert, pcc)
+	if err != nil {
+		return err
+	}
+	for _, vserver := range r.CitrixVServers {
+		err = r.bind(ctx, client, vserver)
+		if err != nil {
+			return err
+		}
+	}
+
+	err = client.SaveConfig(ctx)
+	if err != nil {
+		zap.L().Error("could not save Citrix ADC configuration", zap.Error(err))
+		return err
+	}
+	return nil
+}
+
+// installCertKey uploads the certificate and private key files, and creates or updates the certkey with them.
+//
+// Only the files of the current certificate are kept beforehand, so there are at most two certificates in the ADC:
+// the one installed and the previous one. The new files are removed if the certkey could not be updated
+func (r CitrixADCInstaller) installCertKey(ctx context.Context, client *citrix.Client, cert *x509.Certificate, certPEM string, keyPEM string) error {
+	current, err := client.GetCertKey(ctx, r.CitrixCertKey)
+	if err != nil {
+		return err
+	}
+
+	name := r.fileName(cert)
+	certFile, keyFile := name+".crt", name+".key"
+	if current != nil && path.Base(current.Cert) == certFile {
+		zap.L().Debug("certkey already uses the certificate", zap.String("location", r.location()), zap.String("file", certFile))
+		return nil
+	}
+
+	keep := make(map[string]bool)
+	if current != nil {
+		keep[path.Base(current.Cert)] = true
+		keep[path.Base(current.Key)] = true
+	}
+	err = r.removeFiles(ctx, client, keep)
+	if err != nil {
+		return err
+	}
+
+	err = r.upload(ctx, client, keyFile, keyPEM)
+	if err == nil {
+		err = r.upload(ctx, client, certFile, certPEM)
+	}
+	if err == nil {
+		if current == nil {
+			err = client.AddCertKey(ctx, citrix.CertKey{CertKey: r.CitrixCertKey, Cert: certFile, Key: keyFile})
+		} else {
+			err = client.UpdateCertKey(ctx, r.CitrixCertKey, certFile, keyFile)
+		}
+	}
+	if err != nil {
+		zap.L().Error("could not install certkey in Citrix ADC", zap.String("location", r.location()), zap.Error(err))
+		for _, file := range []string{certFile, keyFile} {
+			if deleteErr := client.DeleteFile(ctx, file); deleteErr != nil {
+				zap.L().Warn("could not remove file from Citrix ADC", zap.String("file", file), zap.Error(deleteErr))
+			}
+		}
+		return err
+	}
+
+	zap.L().Debug("certkey installed in Citrix ADC", zap.String("location", r.location()), zap.String("file", certFile))
+	return nil
+}
+
+// installChain creates a certkey for every certificate of the chain that does not have one yet, and links every
+// certificate to its issuer, starting with the certkey of the installation
+func (r CitrixADCInstaller) installChain(ctx context.Context, client *citrix.Client, cert *x509.Certificate, pcc certificate.PEMCollection) error {
+	child, childName := cert, r.CitrixCertKey
+	for _, chainPEM := range OrderChain(pcc.Certificate, pcc.Chain, domain.ChainOrderRootLast, false) {
+		issuer, err := parsePEMCertificate([]byte(chainPEM))
+		if err != nil {
+			return err
+		}
+		if !bytes.Equal(child.RawIssuer, issuer.RawSubject) {
+			zap.L().Warn("chain certificate is not the issuer of the previous one, chain is not linked further",
+				zap.String("subject", issuer.Subject.String()))
+			return nil
+		}
+
+		thumbprint := sha1.Sum(issuer.Raw)
+		issuerName := citrixCAPrefix + hex.EncodeToString(thumbprint[:4])
+		existing, err := client.GetCertKey(ctx, issuerName)
+		if err != nil {
+			return err
+		}
+		if existing == nil {
+			err = r.upload(ctx, client, issuerName+".crt", chainPEM)
+			if err != nil {
+				return err
+			}
+			err = client.AddCertKey(ctx, citrix.CertKey{CertKey: issuerName, Cert: issuerName + ".crt"})
+			if err != nil {
+				zap.L().Error("could not install chain certificate in Citrix ADC", zap.String("certkey", issuerName), zap.Error(err))
+				return err
+			}
+		}
+
+		err = r.link(ctx, client, childName, issuerName)
+		if err != nil {
+			return err
+		}
+		if isSelfSigned(issuer) {
+			return nil
+		}
+		child, childName = issuer, issuerName
+	}
+	return nil
+}
+
+// link links the certkey name to issuer, replacing the link it had to another certkey
+func (r CitrixADCInstaller) link(ctx context.Context, client *citrix.Client, name string, issuer string) error {
+	certKey, err := client.GetCertKey(ctx, name)
+	if err != nil {
+		return err
+	}
+	if certKey != nil && certKey.LinkCertKeyName == issuer {
+		return nil
+	}
+	if certKey != nil && certKey.LinkCertKeyName != "" {
+		err = client.UnlinkCertKey(ctx, name)
+		if err != nil {
+			return err
+		}
+	}
+	err = client.LinkCertKey(ctx, name, issuer)
+	if err != nil {
+		zap.L().Error("could not link certkey to its issuer", zap.String("certkey", name), zap.String("issuer", issuer), zap.Error(err))
+		return err
+	}
+	return nil
+}
+
+// bind makes the certkey the server certificate of vserver, removing the server certificate bound to it before
+func (r CitrixADCInstaller) bind(ctx context.Context, client *citrix.Client, vserver string) error {
+	bindings, err := client.GetVServerBindings(ctx, vserver)
+	if err != nil {
+		return err
+	}
+	for _, binding := range bindings {
+		if binding.CA || binding.SNICert {
+			continue
+		}
+		if binding.CertKeyName == r.CitrixCertKey {
+			return nil
+		}
+		err = client.UnbindVServer(ctx, vserver, binding.CertKeyName)
+		if err != nil {
+			zap.L().Error("could not unbind certificate from virtual server", zap.String("vserver", vserver),
+				zap.String("certkey", binding.CertKeyName), zap.Error(err))
+			return err
+		}
+	}
+
+	err = client.BindVServer(ctx, vserver, r.CitrixCertKey)
+	if err != nil {
+		zap.L().Error("could not bind certificate to virtual server", zap.String("vserver", vserver), zap.Error(err))
+		return err
+	}
+	zap.L().Debug("certificate bound to virtual server", zap.String("location", r.location()), zap.String("vserver", vserver))
+	return nil
+}
+
+func (r CitrixADCInstaller) isBound(ctx context.Context, client *citrix.Client, vserver string) (bool, error) {
+	bindings, err := client.GetVServerBindings(ctx, vserver)
+	if err != nil {
+		return false, err
+	}
+	for _, binding := range bindings {
+		if binding.CertKeyName == r.CitrixCertKey && !binding.CA {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+// upload sends data to the ADC as the file name, replacing the file if it already exists
+func (r CitrixADCInstaller) upload(ctx context.Context, client *citrix.Client, name string, data string) error {
+	err := client.DeleteFile(ctx, name)
+	if err == nil {
+		err = client.UploadFile(ctx, name, []byte(data))
+	}
+	if err != nil {
+		zap.L().Error("could not upload file to Citrix ADC", zap.String("file", name), zap.Error(err))
+		return err
+	}
+	return nil
+}
+
+// removeFiles removes the certificate and key files uploaded by vcert for this installation, except those in keep
+func (r CitrixADCInstaller) removeFiles(ctx context.Context, client *citrix.Client, keep map[string]bool) error {
+	files, err := r.managedFiles(ctx, client)
+	if err != nil {
+		return err
+	}
+	for _, file := range files {
+		if keep[file] {
+			continue
+		}
+		err = client.DeleteFile(ctx, file)
+		if err != nil {
+			zap.L().Warn("could not remove file from Citrix ADC", zap.String("file", file), zap.Error(err))
+		}
+	}
+	return nil
+}
+
+// Rollback updates the certkey back to the files of the previous certificate. Nothing is restored when there is none
+func (r CitrixADCInstaller) Rollback(ctx context.Context) error {
+	zap.L().Debug("rolling back certificate", zap.String("location", r.location()))
+
+	client, err := r.getClient(ctx)
+	if err != nil {
+		return err
+	}
+	defer r.logout(ctx, client)
+
+	certKey, err := client.GetCertKey(ctx, r.CitrixCertKey)
+	if err != nil {
+		return err
+	}
+	files, err := r.managedFiles(ctx, client)
+	if err != nil {
+		return err
+	}
+
+	previous := ""
+	for _, file := range files {
+		if strings.HasSuffix(file, ".crt") && (certKey == nil || file != path.Base(certKey.Cert)) {
+			previous = strings.TrimSuffix(file, ".crt")
+		}
+	}
+	if certKey == nil || previous == "" {
+		zap.L().Info("no previous certificate found, nothing to restore", zap.String("location", r.location()))
+		return nil
+	}
+
+	err = client.UpdateCertKey(ctx, r.CitrixCertKey, previous+".crt", previous+".key")
+	if err != nil {
+		return err
+	}
+	err = client.SaveConfig(ctx)
+	if err != nil {
+		return err
+	}
+
+	zap.L().Info("certkey restored to previous certificate", zap.String("location", r.location()),
+		zap.String("file", previous+".crt"))
+	return nil
+}
+
+// AfterInstallActions runs the actions declared in the Installer, in order: scripts run on a terminal,
+// while services, sites and webhooks are handled natively.
+//
+// No validations happen over the content of the AfterAction scripts, so caution is advised
+func (r CitrixADCInstaller) AfterInstallActions(ctx context.Context) (string, error) {
+	zap.L().Debug("running after-install actions", zap.String("location", r.location()))
+
+	result, err := runAfterInstallActions(ctx, r.AfterAction)
+	return result, err
+}
+
+// InstallValidationActions runs any instructions declared in the Installer on a terminal and expects
+// "0" for successful validation and "1" for a validation failure
+// No validations happen over the content of the InstallValidation string, so caution is advised
+func (r CitrixADCInstaller) InstallValidationActions(ctx context.Context) (string, error) {
+	zap.L().Debug("running install validation actions", zap.String("location", r.location()))
+
+	validationResult, err := util.ExecuteScript(ctx, r.InstallValidation)
+	if err != nil {
+		return "", err
+	}
+
+	return validationResult, err
+}
+
+// managedFiles returns the certificate and key files uploaded by vcert for this installation
+func (r CitrixADCInstaller) managedFiles(ctx context.Context, client *citrix.Client) ([]string, error) {
+	all, err := client.ListFiles(ctx)
+	if err != nil {
+		return nil, err
+	}
+	managed := regexp.MustCompile(`^` + regexp.QuoteMeta(r.CitrixCertKey) + `_[0-9a-f]{8}\.(crt|key)$`)
+	files := make([]string, 0)
+	for _, file := range all {
+		if managed.MatchString(file) {
+			files = append(files, file)
+		}
+	}
+	return files, nil
+}
+
+// fileName returns the base name of the files uploaded for cert, i.e. www.example.com_1a2b3c4d
+func (r CitrixADCInstaller) fileName(cert *x509.Certificate) string {
+	thumbprint := sha1.Sum(cert.Raw)
+	return fmt.Sprintf("%s_%s", r.CitrixCertKey, hex.EncodeToString(thumbprint[:4]))
+}
+
+func (r CitrixADCInstaller) getClient(ctx context.Context) (*citrix.Client, error) {
+	client, err := citrix.NewClient(ctx, r.CitrixAddress, r.CitrixUsername, r.CitrixPassword, r.CitrixCACert, r.CitrixInsecure)
+	if err != nil {
+		zap.L().Error("could not authenticate to Citrix ADC", zap.Error(err))
+		return nil, err
+	}
+	return client, nil
+}
+
+func (r CitrixADCInstaller) logout(ctx context.Context, client *citrix.Client) {
+	if err := client.Logout(ctx); err != nil {
+		zap.L().Debug("could not log out from Citrix ADC", zap.Error(err))
+	}
+}
+
+func (r CitrixADCInstaller) location() string {
+	return fmt.Sprintf("%s/%s", strings.TrimSuffix(r.CitrixAddress, "/"), r.CitrixCertKey)
+}
+
+// citrixX509Certificate returns an x509.Certificate with the details the ADC holds about certKey
+func citrixX509Certificate(certKey citrix.CertKey) *x509.Certificate {
+	serialNumber, _ := new(big.Int).SetString(strings.ReplaceAll(certKey.Serial, ":", ""), 16)
+	return &x509.Certificate{
+		SerialNumber: serialNumber,
+		Subject:      pkix.Name{CommonName: certKey.Subject},
+		NotBefore:    parseCitrixTime(certKey.NotBefore),
+		NotAfter:     parseCitrixTime(certKey.NotAfter),
+	}
+}
+
+func parseCitrixTime(value string) time.Time {
+	t, err := time.Parse(citrixTimeLayout, strings.Join(strings.Fields(value), " "))
+	if err != nil {
+		return time.Time{}
+	}
+	return t
+}
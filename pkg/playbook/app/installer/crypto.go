@@ -17,31 +17,42 @@
 package installer
 
 import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/rsa"
 	"crypto/sha1"
 	"crypto/x509"
 	"encoding/hex"
 	"encoding/pem"
 	"fmt"
 	"os"
-	"strconv"
 	"strings"
 	"time"
 
+	"github.com/youmark/pkcs8"
 	"go.uber.org/zap"
 
 	"github.com/Venafi/vcert/v5/pkg/certificate"
-	"github.com/Venafi/vcert/v5/pkg/playbook/app/vcertutil"
+	"github.com/Venafi/vcert/v5/pkg/playbook/app/domain"
 	"github.com/Venafi/vcert/v5/pkg/util"
 )
 
-// DayDuration represents a day (24 hours) in the Duration type
-const DayDuration = time.Hour * 24
+const (
+	// DayDuration represents a day (24 hours) in the Duration type
+	DayDuration = time.Hour * 24
+
+	// pkcs8IterationCount is the number of PBKDF2 iterations used to encrypt PKCS8 private keys
+	pkcs8IterationCount = 100000
+)
 
 type Certificate struct {
 	X509cert   x509.Certificate
 	Thumbprint string
 }
 
+// getPrivateKey returns the private key in privateKeyStr, decrypted with keyPassword when it is encrypted. Unencrypted
+// keys, keys encrypted in PKCS#8, and PKCS#1 or SEC 1 keys with the legacy PEM encryption (DES, 3DES or AES) are
+// supported alike, so installations do not depend on the format the key was issued in
 func getPrivateKey(privateKeyStr string, keyPassword string) (interface{}, error) {
 	//Getting Private Key
 	pkBlock, _ := pem.Decode([]byte(privateKeyStr))
@@ -49,38 +60,66 @@ func getPrivateKey(privateKeyStr string, keyPassword string) (interface{}, error
 		return nil, fmt.Errorf("missing Private Key PEM")
 	}
 
-	var err error
-	//Decrypting Private Key
-	pkDER := pkBlock.Bytes
-	if util.X509IsEncryptedPEMBlock(pkBlock) {
-		pkDER, err = util.X509DecryptPEMBlock(pkBlock, []byte(keyPassword))
-		if err != nil {
-			return nil, fmt.Errorf("private key decryption error: %w", err)
-		}
+	return util.ParsePrivateKeyBlock(pkBlock, keyPassword)
+}
+
+// decryptPrivateKey returns the private key in privateKeyStr as an unencrypted PEM block, whatever the encryption it
+// came with. RSA and EC keys are returned in the PKCS#1 and SEC 1 formats, and other keys in PKCS#8
+func decryptPrivateKey(privateKeyStr string, keyPassword string) (string, error) {
+	privateKey, err := getPrivateKey(privateKeyStr, keyPassword)
+	if err != nil {
+		return "", err
 	}
 
-	//Unmarshalling the Private Key
-	var privateKey interface{}
-	switch pkBlock.Type {
-	case "EC PRIVATE KEY":
-		privateKey, err = x509.ParseECPrivateKey(pkDER)
+	var block *pem.Block
+	switch key := privateKey.(type) {
+	case *rsa.PrivateKey:
+		block = &pem.Block{Type: "RSA PRIVATE KEY", Bytes: x509.MarshalPKCS1PrivateKey(key)}
+	case *ecdsa.PrivateKey:
+		der, err := x509.MarshalECPrivateKey(key)
 		if err != nil {
-			privateKey, err = x509.ParsePKCS8PrivateKey(pkDER)
+			return "", fmt.Errorf("error marshalling the private key: %w", err)
 		}
-	case "RSA PRIVATE KEY":
-		privateKey, err = x509.ParsePKCS1PrivateKey(pkDER)
+		block = &pem.Block{Type: "EC PRIVATE KEY", Bytes: der}
+	default:
+		der, err := x509.MarshalPKCS8PrivateKey(key)
 		if err != nil {
-			privateKey, err = x509.ParsePKCS8PrivateKey(pkDER)
+			return "", fmt.Errorf("error marshalling the private key to PKCS8: %w", err)
 		}
-	default:
-		return nil, fmt.Errorf("unexpected Private Key type: %s", pkBlock.Type)
+		block = &pem.Block{Type: "PRIVATE KEY", Bytes: der}
 	}
+	return string(pem.EncodeToMemory(block)), nil
+}
 
+// MarshalPKCS8PrivateKey takes a decrypted private key in PEM format and returns it as a PKCS8 PEM block.
+// When password is not empty, the key is encrypted using AES-256-CBC with a PBKDF2 derived key
+func MarshalPKCS8PrivateKey(privateKeyStr string, password string) (string, error) {
+	privateKey, err := getPrivateKey(privateKeyStr, "")
 	if err != nil {
-		return nil, fmt.Errorf("private key error: %w", err)
+		return "", err
 	}
 
-	return privateKey, nil
+	if password == "" {
+		pkcs8DER, err := x509.MarshalPKCS8PrivateKey(privateKey)
+		if err != nil {
+			return "", fmt.Errorf("error marshalling the private key to PKCS8: %w", err)
+		}
+		return string(pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: pkcs8DER})), nil
+	}
+
+	opts := &pkcs8.Opts{
+		Cipher: pkcs8.AES256CBC,
+		KDFOpts: pkcs8.PBKDF2Opts{
+			SaltSize:       16,
+			IterationCount: pkcs8IterationCount,
+			HMACHash:       crypto.SHA256,
+		},
+	}
+	pkcs8DER, err := pkcs8.MarshalPrivateKey(privateKey, []byte(password), opts)
+	if err != nil {
+		return "", fmt.Errorf("error encrypting the private key to PKCS8: %w", err)
+	}
+	return string(pem.EncodeToMemory(&pem.Block{Type: "ENCRYPTED PRIVATE KEY", Bytes: pkcs8DER})), nil
 }
 
 func prepareCertificateForBundle(request certificate.Request, pcc certificate.PEMCollection, decryptPK bool) (*certificate.PEMCollection, error) {
@@ -96,7 +135,7 @@ func prepareCertificateForBundle(request certificate.Request, pcc certificate.PE
 	//Key needs to be decrypted in order to create the bundle (PKCS12, JKS)
 	// Firefly does not encrypt Private Keys. Thus, Private Key should not be decrypted in that scenario
 	if pcc.PrivateKey != "" && decryptPK {
-		privateKey, err := vcertutil.DecryptPrivateKey(pcc.PrivateKey, request.KeyPassword)
+		privateKey, err := decryptPrivateKey(pcc.PrivateKey, request.KeyPassword)
 		if err != nil {
 			return nil, err
 		}
@@ -117,6 +156,59 @@ func loadPEMCertificate(certFile string) (*x509.Certificate, error) {
 	return parsePEMCertificate(certData)
 }
 
+// loadPEMPrivateKey returns the first private key found in keyFile, decrypted with keyPassword when encrypted.
+// Returns nil if keyFile has no private key
+func loadPEMPrivateKey(keyFile string, keyPassword string) (interface{}, error) {
+	data, err := os.ReadFile(keyFile)
+	if err != nil {
+		return nil, err
+	}
+
+	for block, rest := pem.Decode(data); block != nil; block, rest = pem.Decode(rest) {
+		if strings.HasSuffix(block.Type, "PRIVATE KEY") {
+			return getPrivateKey(string(pem.EncodeToMemory(block)), keyPassword)
+		}
+	}
+	return nil, nil
+}
+
+// asSigner returns privateKey as a crypto.Signer. Returns nil when privateKey is nil
+func asSigner(privateKey interface{}) (crypto.Signer, error) {
+	if privateKey == nil {
+		return nil, nil
+	}
+	signer, ok := privateKey.(crypto.Signer)
+	if !ok {
+		return nil, fmt.Errorf("unsupported private key type %T", privateKey)
+	}
+	return signer, nil
+}
+
+// keyMismatch returns true when privateKey, installed along with cert, is not the private key of cert.
+// A private key that could not be loaded (loadErr), that is missing, or whose type is not supported is not checked,
+// and a warning is logged instead: renewing the certificate would not fix it, and would happen on every run
+func keyMismatch(cert *x509.Certificate, privateKey interface{}, loadErr error, fields ...zap.Field) bool {
+	if loadErr != nil {
+		zap.L().Warn("could not load private key, key match not checked", append(fields, zap.Error(loadErr))...)
+		return false
+	}
+	if privateKey == nil {
+		zap.L().Debug("no private key installed, key match not checked", fields...)
+		return false
+	}
+
+	if _, ok := privateKey.(crypto.Signer); !ok {
+		zap.L().Warn("unsupported private key type, key match not checked",
+			append(fields, zap.String("type", fmt.Sprintf("%T", privateKey)))...)
+		return false
+	}
+	if !PrivateKeyMatches(cert, privateKey) {
+		zap.L().Warn("private key does not match the certificate", fields...)
+		return true
+	}
+	return false
+}
+
 func parsePEMCertificate(certData []byte) (*x509.Certificate, error) {
 	p, _ := pem.Decode(certData)
 	if p == nil {
@@ -134,32 +226,20 @@ func parsePEMCertificate(certData []byte) (*x509.Certificate, error) {
 	return cert, nil
 }
 
+// needRenewal returns true when the certificate is expired or in the renewal window defined by renewBefore.
+// See domain.ParseRenewBefore for the supported values
 func needRenewal(cert *x509.Certificate, renewBefore string) bool {
-	// if duration is 0 anything, then return false, auto-renewal is disabled
-	if renewBefore == "0" || strings.ToLower(renewBefore) == "disabled" {
-		zap.L().Warn("certificate expiring soon but automatic renewal disabled",
-			zap.String("certificate", cert.Subject.CommonName),
-			zap.String("expirationDate", cert.NotAfter.String()))
-		return false
-	}
-
-	timePostfix := renewBefore[len(renewBefore)-1:]
-
-	renew := renewBefore[:len(renewBefore)-1]
-	renewValue, err := strconv.ParseInt(renew, 10, 32)
+	window, err := domain.ParseRenewBefore(renewBefore)
 	if err != nil {
-		zap.L().Error("could not parse renewBefore value. Using default value [10%] instead",
+		zap.L().Error(fmt.Sprintf("could not parse renewBefore value. Using default value [%s] instead", domain.DefaultRenewBefore),
 			zap.String("renewBefore", renewBefore), zap.Error(err))
-		// TODO: use real global default duration
-		timePostfix = "%"
-		renewValue = 10
+		window, _ = domain.ParseRenewBefore(domain.DefaultRenewBefore)
 	}
 
-	// if duration is 0 anything, then return false, auto-renewal is disabled
-	if renewValue == 0 {
-		zap.L().Warn("certificate expiring soon but automatic renewal disabled",
+	if window.Disabled {
+		zap.L().Warn("automatic renewal disabled",
 			zap.String("certificate", cert.Subject.CommonName),
-			zap.String("expirationDate", cert.NotAfter.String()))
+			zap.Time("expirationDate", cert.NotAfter))
 		return false
 	}
 
@@ -169,46 +249,16 @@ func needRenewal(cert *x509.Certificate, renewBefore string) bool {
 		return true
 	}
 
-	var timeToRenew time.Time
-
-	switch timePostfix {
-	case "d":
-		// operation happens in integers to avoid issues with linter and time.Duration struct
-		ns := DayDuration.Nanoseconds()
-		renewDuration := time.Duration(ns * renewValue)
-		timeToRenew = cert.NotAfter.Add(-renewDuration)
-	case "h":
-		ns := time.Hour.Nanoseconds()
-		renewDuration := time.Duration(ns * renewValue)
-		timeToRenew = cert.NotAfter.Add(-renewDuration)
-	case "%":
-		// Total # of ns in the whole certificate lifetime
-		nsCertValidity := cert.NotAfter.Sub(cert.NotBefore).Nanoseconds()
-
-		// if 10%, then renew when 90% of the validity time has elapsed
-		pct := float64(renewValue) / 100
-		renewDuration := time.Duration(float64(nsCertValidity) * pct)
-		timeToRenew = cert.NotAfter.Add(-renewDuration)
-	default:
-		zap.L().Warn("unknown duration postfix. Valid postfixes are: d (Days) and h (Hours): Using default [10%]",
-			zap.String("postfix", timePostfix))
-		// Total # of ns in the whole certificate lifetime
-		nsCertValidity := cert.NotAfter.Sub(cert.NotBefore).Nanoseconds()
-
-		// TODO: Respect global DefaultRenewal
-		renewDuration := time.Duration(float64(nsCertValidity) * float64(0.10))
-		timeToRenew = cert.NotAfter.Add(-renewDuration)
-	}
-
 	// Check certificate renew window
-	//Time now + renew window is bigger than cert expiration day? Then renew
-	if time.Now().After(timeToRenew) {
-		zap.L().Debug("certificate in renew window", zap.String("certificate", cert.Subject.CommonName))
+	renewalDate := window.RenewalDate(cert.NotBefore, cert.NotAfter)
+	if time.Now().After(renewalDate) {
+		zap.L().Debug("certificate in renew window", zap.String("certificate", cert.Subject.CommonName),
+			zap.Time("expirationDate", cert.NotAfter), zap.Time("renewalDate", renewalDate))
 		return true
 	}
 
-	zap.L().Info(fmt.Sprintf("cert expires on %s and will auto-renew on %s", cert.NotAfter, timeToRenew),
-		zap.String("expirationDate", cert.NotAfter.String()), zap.String("renewDate", timeToRenew.String()))
+	zap.L().Info(fmt.Sprintf("cert expires on %s and will auto-renew on %s", cert.NotAfter, renewalDate),
+		zap.Time("expirationDate", cert.NotAfter), zap.Time("renewalDate", renewalDate))
 	return false
 }
 
@@ -0,0 +1,176 @@
+/*
+ * Copyright 2023 Venafi, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *  http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package installer
+
+import (
+	"context"
+	"crypto"
+	"crypto/x509"
+	"fmt"
+	"path/filepath"
+
+	"go.uber.org/zap"
+
+	"github.com/Venafi/vcert/v5/pkg/certificate"
+	"github.com/Venafi/vcert/v5/pkg/playbook/app/domain"
+	"github.com/Venafi/vcert/v5/pkg/playbook/util"
+	"github.com/Venafi/vcert/v5/pkg/playbook/util/mysql"
+	"github.com/Venafi/vcert/v5/pkg/playbook/util/postgres"
+)
+
+// databaseFileMode is the mode of the files of database installations when mode is not set. PostgreSQL refuses to
+// load a private key readable by other users
+const databaseFileMode = "0600"
+
+// DatabaseInstaller represents an installation of the server certificate of PostgreSQL, MySQL or MariaDB. The
+// certificate, followed by its chain, and the private key are written to the data directory of the server, which is
+// then told to reload them over a database connection, so the new certificate is used without a restart
+type DatabaseInstaller struct {
+	domain.Installation
+}
+
+// NewDatabaseInstaller returns a new installer of type POSTGRESQL or MYSQL with the values defined in inst
+func NewDatabaseInstaller(inst domain.Installation) DatabaseInstaller {
+	return DatabaseInstaller{inst}
+}
+
+// Check is the method in charge of making the validations to install a new certificate:
+// 1. Does the certificate exists? > Install if it doesn't.
+// 2. Does the certificate is about to expire? Renew if about to expire.
+// Returns true if the certificate needs to be installed, along with the certificate currently installed, if any.
+func (r DatabaseInstaller) Check(ctx context.Context, renewBefore string, request domain.PlaybookRequest) (bool, *x509.Certificate, error) {
+	return r.files().Check(ctx, renewBefore, request)
+}
+
+// Backup takes the certificate request and backs up the current version prior to overwriting
+func (r DatabaseInstaller) Backup(ctx context.Context) error {
+	return r.files().Backup(ctx)
+}
+
+// Install writes the certificate and the private key to the files of the server, and makes the server reload them
+func (r DatabaseInstaller) Install(ctx context.Context, pcc certificate.PEMCollection) error {
+	files := r.files()
+	if files.Owner == "" && files.Group == "" {
+		// The server only reads files it owns, or owned by root
+		dir := filepath.Dir(files.File)
+		var err error
+		files.Owner, files.Group, err = util.GetFileOwnership(dir)
+		if err != nil {
+			return fmt.Errorf("could not read the owner of the data directory %s: %w", dir, err)
+		}
+	}
+
+	err := files.Install(ctx, pcc)
+	if err != nil {
+		return err
+	}
+	return r.reload(ctx)
+}
+
+// Rollback restores the version of the certificate backed up by Backup, and makes the server reload it
+func (r DatabaseInstaller) Rollback(ctx context.Context) error {
+	err := r.files().Rollback(ctx)
+	if err != nil {
+		return err
+	}
+	return r.reload(ctx)
+}
+
+// AfterInstallActions runs the actions declared in the Installer, in order: scripts run on a terminal,
+// while services, sites and webhooks are handled natively.
+//
+// No validations happen over the content of the AfterAction scripts, so caution is advised
+func (r DatabaseInstaller) AfterInstallActions(ctx context.Context) (string, error) {
+	return r.files().AfterInstallActions(ctx)
+}
+
+// InstallValidationActions runs any instructions declared in the Installer on a terminal and expects
+// "0" for successful validation and "1" for a validation failure
+// No validations happen over the content of the InstallValidation string, so caution is advised
+func (r DatabaseInstaller) InstallValidationActions(ctx context.Context) (string, error) {
+	return r.files().InstallValidationActions(ctx)
+}
+
+// PrivateKey returns the private key installed, or nil when nothing is installed
+func (r DatabaseInstaller) PrivateKey(ctx context.Context) (crypto.Signer, error) {
+	return r.files().PrivateKey(ctx)
+}
+
+// files returns the PEM installation of the files of the server: the certificate followed by its chain, without the
+// root, and the unencrypted private key
+func (r DatabaseInstaller) files() PEMInstaller {
+	certFile, keyFile := r.GetDatabaseFiles()
+	mode := r.Mode
+	if mode == "" {
+		mode = databaseFileMode
+	}
+	return NewPEMInstaller(domain.Installation{
+		AfterAction:        r.AfterAction,
+		BackupRetention:    r.BackupRetention,
+		ChainOrder:         domain.ChainOrderRootLast,
+		ExcludeRoot:        true,
+		File:               certFile,
+		Group:              r.Group,
+		InstallValidation:  r.InstallValidation,
+		KeyFile:            keyFile,
+		Mode:               mode,
+		Owner:              r.Owner,
+		PEMBundle:          domain.PEMBundleCertChain,
+		Type:               domain.FormatPEM,
+		ValidateRevocation: r.ValidateRevocation,
+	})
+}
+
+// reload makes the server load the installed certificate and private key for its new connections
+func (r DatabaseInstaller) reload(ctx context.Context) error {
+	address := r.DBAddress
+	username := r.DBUsername
+	var err error
+	switch r.Type {
+	case domain.FormatPostgreSQL:
+		if address == "" {
+			address = domain.DefaultPostgreSQLAddress
+		}
+		if username == "" {
+			username = domain.DefaultPostgreSQLUsername
+		}
+		database := r.DBName
+		if database == "" {
+			database = domain.DefaultPostgreSQLDatabase
+		}
+		client := postgres.NewClient(address, username, r.DBPassword, database)
+		err = client.ReloadConfiguration(ctx)
+	case domain.FormatMySQL:
+		if address == "" {
+			address = domain.DefaultMySQLAddress
+		}
+		if username == "" {
+			username = domain.DefaultMySQLUsername
+		}
+		client := mysql.NewClient(address, username, r.DBPassword)
+		err = client.ReloadTLS(ctx)
+	default:
+		return fmt.Errorf("%s is not a database installation", r.Type.String())
+	}
+	if err != nil {
+		return fmt.Errorf("could not reload the certificate of the %s server at %s: %w", r.Type.String(), address, err)
+	}
+
+	zap.L().Info("database server reloaded the certificate", zap.String("format", r.Type.String()),
+		zap.String("address", address))
+	return nil
+}
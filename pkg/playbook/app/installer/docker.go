@@ -0,0 +1,434 @@
+/*
+ * Copyright 2023 Venafi, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *  http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package installer
+
+import (
+	"context"
+	"crypto/sha1"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/hex"
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+
+	"go.uber.org/zap"
+
+	"github.com/Venafi/vcert/v5/pkg/certificate"
+	"github.com/Venafi/vcert/v5/pkg/playbook/app/domain"
+	"github.com/Venafi/vcert/v5/pkg/playbook/util"
+	"github.com/Venafi/vcert/v5/pkg/playbook/util/docker"
+)
+
+const (
+	// dockerLabelName is the label of the secrets created by vcert, set to the DockerSecretName of the installation
+	dockerLabelName = "com.venafi.vcert.name"
+	// dockerLabelCertificate is the label of the certificate secrets holding the certificate in base64 DER, as the
+	// Docker Engine does not return the data of the secrets
+	dockerLabelCertificate = "com.venafi.vcert.certificate"
+
+	dockerCertSuffix = ".crt"
+	dockerKeySuffix  = ".key"
+)
+
+// DockerSecretInstaller represents an installation that will store the certificate and its chain, and the private
+// key, in two Docker Swarm secrets, and roll them out to Swarm services.
+//
+// Swarm secrets cannot be updated, so every certificate is stored in new secrets, named after DockerSecretName and the
+// certificate thumbprint. The services are switched from the previous secrets to the new ones, and can be switched
+// back on Rollback
+type DockerSecretInstaller struct {
+	domain.Installation
+}
+
+// NewDockerSecretInstaller returns a new installer of type DOCKERSECRET with the values defined in inst
+func NewDockerSecretInstaller(inst domain.Installation) DockerSecretInstaller {
+	return DockerSecretInstaller{inst}
+}
+
+// Check is the method in charge of making the validations to install a new certificate:
+// 1. Does the certificate exists? > Install if it doesn't.
+// 2. Does the certificate is about to expire? Renew if about to expire.
+// Returns true if the certificate needs to be installed, along with the certificate currently installed, if any.
+//
+// The certificate currently installed is the one used by the first service or, when no service is set, the latest
+// one created by vcert
+func (r DockerSecretInstaller) Check(ctx context.Context, renewBefore string, _ domain.PlaybookRequest) (bool, *x509.Certificate, error) {
+	zap.L().Info("checking certificate health", zap.String("format", r.Type.String()), zap.String("location", r.location()))
+
+	client, err := r.getClient(ctx)
+	if err != nil {
+		return false, nil, err
+	}
+
+	current, err := r.currentSecret(ctx, client)
+	if err != nil {
+		return false, nil, err
+	}
+	if current == nil {
+		zap.L().Debug("certificate does not exist", zap.String("location", r.location()))
+		return true, nil, nil
+	}
+
+	der, err := base64.StdEncoding.DecodeString(current.Spec.Labels[dockerLabelCertificate])
+	if err != nil {
+		return false, nil, fmt.Errorf("could not decode certificate label of secret %s: %w", current.Spec.Name, err)
+	}
+	cert, err := x509.ParseCertificate(der)
+	if err != nil {
+		return false, nil, fmt.Errorf("could not parse certificate label of secret %s: %w", current.Spec.Name, err)
+	}
+	if r.ValidateRevocation {
+		zap.L().Warn("validateRevocation is not supported for Docker secret installations", zap.String("location", r.location()))
+	}
+
+	return needRenewal(cert, renewBefore), cert, nil
+}
+
+// Backup is a no-op for Docker secrets. Install creates new secrets instead of replacing the current ones,
+// so the previous certificate is kept in the swarm
+func (r DockerSecretInstaller) Backup(_ context.Context) error {
+	zap.L().Debug("previous secrets are kept in the swarm, no back up taken", zap.String("location", r.location()))
+	return nil
+}
+
+// Install takes the certificate bundle and moves it to the location specified in the installer.
+//
+// The certificate followed by its chain, and the private key, are stored in new secrets, which then replace the
+// secrets previously installed by vcert in the services. The services without them get them as
+// /run/secrets/<dockerSecretName>.crt and /run/secrets/<dockerSecretName>.key
+func (r DockerSecretInstaller) Install(ctx context.Context, pcc certificate.PEMCollection) error {
+	zap.L().Debug("installing certificate", zap.String("location", r.location()))
+
+	if len(pcc.Certificate) == 0 || len(pcc.PrivateKey) == 0 {
+		return fmt.Errorf("certificate and Private Key are required for Docker secrets")
+	}
+
+	cert, err := parsePEMCertificate([]byte(pcc.Certificate))
+	if err != nil {
+		return err
+	}
+	privateKey := pcc.PrivateKey
+	if strings.ToLower(r.KeyFormat) == domain.KeyFormatPKCS8 {
+		privateKey, err = MarshalPKCS8PrivateKey(pcc.PrivateKey, "")
+		if err != nil {
+			zap.L().Error("failed to prepare PrivateKey in PKCS8 format", zap.Error(err))
+			return err
+		}
+	}
+
+	client, err := r.getClient(ctx)
+	if err != nil {
+		return err
+	}
+
+	secrets, err := r.managedSecrets(ctx, client)
+	if err != nil {
+		return err
+	}
+
+	// The secrets may exist already when a previous run failed to update the services
+	name := r.objectName(cert)
+	certSecret, err := r.createSecret(ctx, client, secrets, name+dockerCertSuffix, pcc.Certificate+strings.Join(pcc.Chain, ""),
+		map[string]string{dockerLabelCertificate: base64.StdEncoding.EncodeToString(cert.Raw)})
+	if err != nil {
+		return err
+	}
+	keySecret, err := r.createSecret(ctx, client, secrets, name+dockerKeySuffix, privateKey, nil)
+	if err != nil {
+		return err
+	}
+	zap.L().Debug("certificate stored in Docker secrets", zap.String("location", r.location()), zap.String("secret", certSecret.SecretName))
+
+	for _, service := range r.DockerServices {
+		err = r.assignToService(ctx, client, service, certSecret, keySecret)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// createSecret creates the secret name with the data, unless it is one of the existing secrets, and returns the
+// reference to it
+func (r DockerSecretInstaller) createSecret(ctx context.Context, client *docker.Client, existing []docker.Secret, name string, data string, labels map[string]string) (docker.SecretReference, error) {
+	for _, secret := range existing {
+		if secret.Spec.Name == name {
+			return docker.SecretReference{SecretID: secret.ID, SecretName: name}, nil
+		}
+	}
+
+	spec := docker.SecretSpec{
+		Name:   name,
+		Labels: map[string]string{dockerLabelName: r.DockerSecretName},
+		Data:   base64.StdEncoding.EncodeToString([]byte(data)),
+	}
+	for k, v := range labels {
+		spec.Labels[k] = v
+	}
+	id, err := client.CreateSecret(ctx, spec)
+	if err != nil {
+		zap.L().Error("could not create Docker secret", zap.String("secret", name), zap.Error(err))
+		return docker.SecretReference{}, err
+	}
+	return docker.SecretReference{SecretID: id, SecretName: name}, nil
+}
+
+// assignToService replaces the secrets installed by vcert in the service with certSecret and keySecret, keeping the
+// files they were exposed as. The secrets are added to the service when it has none of them
+func (r DockerSecretInstaller) assignToService(ctx context.Context, client *docker.Client, name string, certSecret docker.SecretReference, keySecret docker.SecretReference) error {
+	service, err := client.GetService(ctx, name)
+	if err != nil {
+		return err
+	}
+	if service == nil {
+		return fmt.Errorf("docker service %s not found", name)
+	}
+	refs, err := service.Secrets()
+	if err != nil {
+		return err
+	}
+
+	certSecret.File = &docker.SecretFile{Name: r.DockerSecretName + dockerCertSuffix, UID: "0", GID: "0", Mode: 0444}
+	keySecret.File = &docker.SecretFile{Name: r.DockerSecretName + dockerKeySuffix, UID: "0", GID: "0", Mode: 0400}
+	updated := make([]docker.SecretReference, 0, len(refs))
+	for _, ref := range refs {
+		if !r.isManagedSecret(ref.SecretName) {
+			updated = append(updated, ref)
+			continue
+		}
+		if ref.SecretName == certSecret.SecretName || ref.SecretName == keySecret.SecretName {
+			zap.L().Debug("service already uses the certificate", zap.String("service", name))
+			return nil
+		}
+		if strings.HasSuffix(ref.SecretName, dockerCertSuffix) {
+			certSecret.File = ref.File
+		} else {
+			keySecret.File = ref.File
+		}
+	}
+	updated = append(updated, certSecret, keySecret)
+
+	err = service.SetSecrets(updated)
+	if err != nil {
+		return err
+	}
+	err = client.UpdateService(ctx, service)
+	if err != nil {
+		zap.L().Error("could not update Docker service", zap.String("service", name), zap.Error(err))
+		return err
+	}
+	zap.L().Debug("Docker service updated", zap.String("service", name), zap.String("secret", certSecret.SecretName))
+	return nil
+}
+
+// Rollback switches the services using the latest certificate back to the certificate installed before it.
+// Nothing is restored when no service is set
+func (r DockerSecretInstaller) Rollback(ctx context.Context) error {
+	if len(r.DockerServices) == 0 {
+		zap.L().Warn("no dockerServices set, nothing to restore", zap.String("location", r.location()))
+		return nil
+	}
+	zap.L().Debug("rolling back certificate", zap.String("location", r.location()))
+
+	client, err := r.getClient(ctx)
+	if err != nil {
+		return err
+	}
+
+	certs, err := r.managedCertificateSecrets(ctx, client)
+	if err != nil {
+		return err
+	}
+	if len(certs) < 2 {
+		zap.L().Info("no previous certificate found, nothing to restore", zap.String("location", r.location()))
+		return nil
+	}
+	secrets, err := r.managedSecrets(ctx, client)
+	if err != nil {
+		return err
+	}
+	previousName := strings.TrimSuffix(certs[1].Spec.Name, dockerCertSuffix)
+	previousCert := docker.SecretReference{SecretID: certs[1].ID, SecretName: certs[1].Spec.Name}
+	previousKey := docker.SecretReference{SecretName: previousName + dockerKeySuffix}
+	for _, secret := range secrets {
+		if secret.Spec.Name == previousKey.SecretName {
+			previousKey.SecretID = secret.ID
+		}
+	}
+	if previousKey.SecretID == "" {
+		return fmt.Errorf("private key secret %s of the previous certificate not found", previousKey.SecretName)
+	}
+
+	for _, name := range r.DockerServices {
+		service, err := client.GetService(ctx, name)
+		if err != nil {
+			return err
+		}
+		if service == nil {
+			return fmt.Errorf("docker service %s not found", name)
+		}
+		refs, err := service.Secrets()
+		if err != nil {
+			return err
+		}
+		usesLatest := false
+		for _, ref := range refs {
+			if ref.SecretName == certs[0].Spec.Name {
+				usesLatest = true
+			}
+		}
+		if !usesLatest {
+			zap.L().Info("service does not use the latest certificate, nothing to restore", zap.String("service", name))
+			continue
+		}
+
+		err = r.assignToService(ctx, client, name, previousCert, previousKey)
+		if err != nil {
+			return err
+		}
+		zap.L().Info("service restored to previous certificate", zap.String("service", name),
+			zap.String("secret", previousCert.SecretName))
+	}
+	return nil
+}
+
+// AfterInstallActions runs the actions declared in the Installer, in order: scripts run on a terminal,
+// while services, sites and webhooks are handled natively.
+//
+// No validations happen over the content of the AfterAction scripts, so caution is advised
+func (r DockerSecretInstaller) AfterInstallActions(ctx context.Context) (string, error) {
+	zap.L().Debug("running after-install actions", zap.String("location", r.location()))
+
+	result, err := runAfterInstallActions(ctx, r.AfterAction)
+	return result, err
+}
+
+// InstallValidationActions runs any instructions declared in the Installer on a terminal and expects
+// "0" for successful validation and "1" for a validation failure
+// No validations happen over the content of the InstallValidation string, so caution is advised
+func (r DockerSecretInstaller) InstallValidationActions(ctx context.Context) (string, error) {
+	zap.L().Debug("running install validation actions", zap.String("location", r.location()))
+
+	validationResult, err := util.ExecuteScript(ctx, r.InstallValidation)
+	if err != nil {
+		return "", err
+	}
+
+	return validationResult, err
+}
+
+// currentSecret returns the certificate secret installed by vcert that is used by the first service or, when no
+// service is set, the latest certificate secret installed by vcert. Returns nil if there is none
+func (r DockerSecretInstaller) currentSecret(ctx context.Context, client *docker.Client) (*docker.Secret, error) {
+	certs, err := r.managedCertificateSecrets(ctx, client)
+	if err != nil || len(certs) == 0 {
+		return nil, err
+	}
+	if len(r.DockerServices) == 0 {
+		return &certs[0], nil
+	}
+
+	service, err := client.GetService(ctx, r.DockerServices[0])
+	if err != nil {
+		return nil, err
+	}
+	if service == nil {
+		return nil, fmt.Errorf("docker service %s not found", r.DockerServices[0])
+	}
+	refs, err := service.Secrets()
+	if err != nil {
+		return nil, err
+	}
+	for _, ref := range refs {
+		for i := range certs {
+			if certs[i].Spec.Name == ref.SecretName {
+				return &certs[i], nil
+			}
+		}
+	}
+	zap.L().Debug("service does not use a certificate installed by vcert", zap.String("service", r.DockerServices[0]))
+	return nil, nil
+}
+
+// managedSecrets returns the certificate and private key secrets installed by vcert for this installation
+func (r DockerSecretInstaller) managedSecrets(ctx context.Context, client *docker.Client) ([]docker.Secret, error) {
+	all, err := client.ListSecrets(ctx, fmt.Sprintf("%s=%s", dockerLabelName, r.DockerSecretName))
+	if err != nil {
+		return nil, err
+	}
+	secrets := make([]docker.Secret, 0)
+	for _, secret := range all {
+		if r.isManagedSecret(secret.Spec.Name) {
+			secrets = append(secrets, secret)
+		}
+	}
+	return secrets, nil
+}
+
+// managedCertificateSecrets returns the certificate secrets installed by vcert for this installation, the latest first
+func (r DockerSecretInstaller) managedCertificateSecrets(ctx context.Context, client *docker.Client) ([]docker.Secret, error) {
+	secrets, err := r.managedSecrets(ctx, client)
+	if err != nil {
+		return nil, err
+	}
+	certs := make([]docker.Secret, 0)
+	for _, secret := range secrets {
+		if strings.HasSuffix(secret.Spec.Name, dockerCertSuffix) {
+			certs = append(certs, secret)
+		}
+	}
+	sort.SliceStable(certs, func(i, j int) bool {
+		return certs[i].CreatedAt.After(certs[j].CreatedAt)
+	})
+	return certs, nil
+}
+
+func (r DockerSecretInstaller) isManagedSecret(name string) bool {
+	return regexp.MustCompile(`^` + regexp.QuoteMeta(r.DockerSecretName) + `_[0-9a-f]{8}\.(crt|key)$`).MatchString(name)
+}
+
+// objectName returns the base name of the secrets created for cert, i.e. www.example.com_1a2b3c4d
+func (r DockerSecretInstaller) objectName(cert *x509.Certificate) string {
+	thumbprint := sha1.Sum(cert.Raw)
+	return fmt.Sprintf("%s_%s", r.DockerSecretName, hex.EncodeToString(thumbprint[:4]))
+}
+
+func (r DockerSecretInstaller) getClient(ctx context.Context) (*docker.Client, error) {
+	client, err := docker.NewClient(r.host(), r.DockerCertPath)
+	if err != nil {
+		zap.L().Error("could not connect to the Docker Engine", zap.Error(err))
+		return nil, err
+	}
+	return client, nil
+}
+
+func (r DockerSecretInstaller) host() string {
+	if r.DockerHost == "" {
+		return domain.DefaultDockerHost
+	}
+	return r.DockerHost
+}
+
+func (r DockerSecretInstaller) location() string {
+	location := fmt.Sprintf("%s/%s", r.host(), r.DockerSecretName)
+	if len(r.DockerServices) > 0 {
+		location = fmt.Sprintf("%s (services %s)", location, strings.Join(r.DockerServices, ", "))
+	}
+	return location
+}
@@ -0,0 +1,402 @@
+/*
+ * Copyright 2023 Venafi, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *  http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package installer
+
+import (
+	"context"
+	"crypto/sha1"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/hex"
+	"fmt"
+	"math/big"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+
+	"go.uber.org/zap"
+
+	"github.com/Venafi/vcert/v5/pkg/certificate"
+	"github.com/Venafi/vcert/v5/pkg/playbook/app/domain"
+	"github.com/Venafi/vcert/v5/pkg/playbook/util"
+	"github.com/Venafi/vcert/v5/pkg/playbook/util/f5"
+)
+
+// f5ProfileDefaultEntry is the certKeyChain entry of the client SSL profiles created by the BIG-IP
+const f5ProfileDefaultEntry = "default"
+
+// F5Installer represents an installation that will upload the certificate, its private key and its chain to an
+// F5 BIG-IP, and assign them to a client SSL profile.
+//
+// Every certificate is uploaded as new objects, named after F5CertName and the certificate thumbprint, so the profile
+// is switched from the previous certificate to the new one in a single update, and can be switched back on Rollback
+type F5Installer struct {
+	domain.Installation
+}
+
+// NewF5Installer returns a new installer of type F5 with the values defined in inst
+func NewF5Installer(inst domain.Installation) F5Installer {
+	return F5Installer{inst}
+}
+
+// Check is the method in charge of making the validations to install a new certificate:
+// 1. Does the certificate exists? > Install if it doesn't.
+// 2. Does the certificate is about to expire? Renew if about to expire.
+// Returns true if the certificate needs to be installed, along with the certificate currently installed, if any.
+//
+// The BIG-IP does not return the certificate itself, only its details. The time the certificate was uploaded
+// stands for its start date when the renewal window is a percentage of the certificate lifetime
+func (r F5Installer) Check(ctx context.Context, renewBefore string, _ domain.PlaybookRequest) (bool, *x509.Certificate, error) {
+	zap.L().Info("checking certificate health", zap.String("format", r.Type.String()), zap.String("location", r.location()))
+
+	client, err := r.getClient(ctx)
+	if err != nil {
+		return false, nil, err
+	}
+
+	current, err := r.currentCertificate(ctx, client)
+	if err != nil {
+		return false, nil, err
+	}
+	if current == nil {
+		zap.L().Debug("certificate does not exist", zap.String("location", r.location()))
+		return true, nil, nil
+	}
+	if r.ValidateRevocation {
+		zap.L().Warn("validateRevocation is not supported for F5 installations", zap.String("location", r.location()))
+	}
+
+	cert := f5X509Certificate(*current)
+	return needRenewal(cert, renewBefore), cert, nil
+}
+
+// Backup is a no-op for F5. Install uploads new objects instead of replacing the current ones,
+// so the previous certificate is kept in the BIG-IP
+func (r F5Installer) Backup(_ context.Context) error {
+	zap.L().Debug("previous certificates are kept in the BIG-IP, no back up taken", zap.String("location", r.location()))
+	return nil
+}
+
+// Install takes the certificate bundle and moves it to the location specified in the installer.
+//
+// The private key, the certificate and its chain are uploaded as new objects, and then assigned to the client SSL
+// profile, replacing the certificate previously installed by vcert or, the first time, the default certificate of the profile
+func (r F5Installer) Install(ctx context.Context, pcc certificate.PEMCollection) error {
+	zap.L().Debug("installing certificate", zap.String("location", r.location()))
+
+	if len(pcc.Certificate) == 0 || len(pcc.PrivateKey) == 0 {
+		return fmt.Errorf("certificate and Private Key are required for F5 BIG-IP")
+	}
+
+	cert, err := parsePEMCertificate([]byte(pcc.Certificate))
+	if err != nil {
+		return err
+	}
+	// The BIG-IP does not support encrypted PKCS8 private keys
+	privateKey, err := MarshalPKCS8PrivateKey(pcc.PrivateKey, "")
+	if err != nil {
+		zap.L().Error("could not prepare private key for F5 BIG-IP", zap.Error(err))
+		return err
+	}
+
+	client, err := r.getClient(ctx)
+	if err != nil {
+		return err
+	}
+
+	entry := r.certKeyChain(r.objectName(cert))
+	err = r.upload(ctx, client, entry.Key, privateKey, client.InstallKey)
+	if err != nil {
+		return err
+	}
+	err = r.upload(ctx, client, entry.Cert, pcc.Certificate, client.InstallCertificate)
+	if err != nil {
+		return err
+	}
+	if len(pcc.Chain) > 0 {
+		err = r.upload(ctx, client, entry.Chain, strings.Join(pcc.Chain, ""), client.InstallCertificate)
+		if err != nil {
+			return err
+		}
+	} else {
+		entry.Chain = ""
+	}
+	zap.L().Debug("certificate uploaded to F5 BIG-IP", zap.String("location", r.location()), zap.String("certificate", entry.Cert))
+
+	if r.F5Profile == "" {
+		return nil
+	}
+	return r.assignToProfile(ctx, client, entry)
+}
+
+// upload sends the PEM data to the BIG-IP and installs it as the object fullPath. The uploaded file is removed
+// afterwards, as it may hold a private key
+func (r F5Installer) upload(ctx context.Context, client *f5.Client, fullPath string, data string, install func(context.Context, string, string, string) error) error {
+	name := fullPath[strings.LastIndex(fullPath, "/")+1:]
+	localFile, err := client.UploadFile(ctx, name, []byte(data))
+	if err != nil {
+		zap.L().Error("could not upload file to F5 BIG-IP", zap.String("file", name), zap.Error(err))
+		return err
+	}
+	defer func() {
+		if err := client.DeleteFile(ctx, localFile); err != nil {
+			zap.L().Warn("could not remove uploaded file from F5 BIG-IP", zap.String("file", localFile), zap.Error(err))
+		}
+	}()
+
+	err = install(ctx, r.partition(), name, localFile)
+	if err != nil {
+		zap.L().Error("could not install object in F5 BIG-IP", zap.String("object", fullPath), zap.Error(err))
+		return err
+	}
+	return nil
+}
+
+// assignToProfile replaces the entry of the client SSL profile holding a certificate installed by vcert, or the
+// default entry, with entry. The entry is added to the profile when there is none of them
+func (r F5Installer) assignToProfile(ctx context.Context, client *f5.Client, entry f5.CertKeyChain) error {
+	profile, err := client.GetClientSSLProfile(ctx, r.partition(), r.F5Profile)
+	if err != nil {
+		return err
+	}
+	if profile == nil {
+		return fmt.Errorf("client SSL profile %s not found", f5.FullPath(r.partition(), r.F5Profile))
+	}
+
+	index := r.managedEntry(*profile)
+	if index < 0 {
+		for i, e := range profile.CertKeyChain {
+			if e.Name == f5ProfileDefaultEntry {
+				index = i
+			}
+		}
+	}
+	certKeyChain := append([]f5.CertKeyChain{}, profile.CertKeyChain...)
+	if index < 0 {
+		entry.Name = entry.Cert[strings.LastIndex(entry.Cert, "/")+1:]
+		certKeyChain = append(certKeyChain, entry)
+	} else {
+		entry.Name = certKeyChain[index].Name
+		certKeyChain[index] = entry
+	}
+
+	err = client.SetClientSSLCertKeyChain(ctx, r.partition(), r.F5Profile, certKeyChain)
+	if err != nil {
+		zap.L().Error("could not update client SSL profile", zap.String("profile", profile.FullPath), zap.Error(err))
+		return err
+	}
+	zap.L().Debug("client SSL profile updated", zap.String("profile", profile.FullPath), zap.String("certificate", entry.Cert))
+	return nil
+}
+
+// Rollback assigns the certificate installed before the current one back to the client SSL profile.
+// Nothing is restored when no client SSL profile is set, or when the profile does not use the latest certificate
+func (r F5Installer) Rollback(ctx context.Context) error {
+	if r.F5Profile == "" {
+		zap.L().Warn("no f5Profile set, nothing to restore", zap.String("location", r.location()))
+		return nil
+	}
+	zap.L().Debug("rolling back certificate", zap.String("location", r.location()))
+
+	client, err := r.getClient(ctx)
+	if err != nil {
+		return err
+	}
+
+	certs, err := r.managedCertificates(ctx, client)
+	if err != nil {
+		return err
+	}
+	profile, err := client.GetClientSSLProfile(ctx, r.partition(), r.F5Profile)
+	if err != nil {
+		return err
+	}
+	if profile == nil {
+		return fmt.Errorf("client SSL profile %s not found", f5.FullPath(r.partition(), r.F5Profile))
+	}
+
+	index := r.managedEntry(*profile)
+	if index < 0 || len(certs) < 2 || profile.CertKeyChain[index].Cert != certs[0].FullPath {
+		zap.L().Info("client SSL profile does not use the latest certificate, nothing to restore", zap.String("location", r.location()))
+		return nil
+	}
+
+	previous := r.certKeyChain(strings.TrimSuffix(certs[1].Name, ".crt"))
+	chain, err := client.GetCertificate(ctx, r.partition(), previous.Chain[strings.LastIndex(previous.Chain, "/")+1:])
+	if err != nil {
+		return err
+	}
+	if chain == nil {
+		previous.Chain = ""
+	}
+	previous.Name = profile.CertKeyChain[index].Name
+	certKeyChain := append([]f5.CertKeyChain{}, profile.CertKeyChain...)
+	certKeyChain[index] = previous
+
+	err = client.SetClientSSLCertKeyChain(ctx, r.partition(), r.F5Profile, certKeyChain)
+	if err != nil {
+		return err
+	}
+
+	zap.L().Info("client SSL profile restored to previous certificate", zap.String("location", r.location()),
+		zap.String("certificate", previous.Cert))
+	return nil
+}
+
+// AfterInstallActions runs the actions declared in the Installer, in order: scripts run on a terminal,
+// while services, sites and webhooks are handled natively.
+//
+// No validations happen over the content of the AfterAction scripts, so caution is advised
+func (r F5Installer) AfterInstallActions(ctx context.Context) (string, error) {
+	zap.L().Debug("running after-install actions", zap.String("location", r.location()))
+
+	result, err := runAfterInstallActions(ctx, r.AfterAction)
+	return result, err
+}
+
+// InstallValidationActions runs any instructions declared in the Installer on a terminal and expects
+// "0" for successful validation and "1" for a validation failure
+// No validations happen over the content of the InstallValidation string, so caution is advised
+func (r F5Installer) InstallValidationActions(ctx context.Context) (string, error) {
+	zap.L().Debug("running install validation actions", zap.String("location", r.location()))
+
+	validationResult, err := util.ExecuteScript(ctx, r.InstallValidation)
+	if err != nil {
+		return "", err
+	}
+
+	return validationResult, err
+}
+
+// currentCertificate returns the certificate installed by vcert that is assigned to the client SSL profile or,
+// when no profile is set, the latest certificate installed by vcert. Returns nil if there is none
+func (r F5Installer) currentCertificate(ctx context.Context, client *f5.Client) (*f5.SSLCert, error) {
+	if r.F5Profile == "" {
+		certs, err := r.managedCertificates(ctx, client)
+		if err != nil || len(certs) == 0 {
+			return nil, err
+		}
+		return &certs[0], nil
+	}
+
+	profile, err := client.GetClientSSLProfile(ctx, r.partition(), r.F5Profile)
+	if err != nil {
+		return nil, err
+	}
+	if profile == nil {
+		return nil, fmt.Errorf("client SSL profile %s not found", f5.FullPath(r.partition(), r.F5Profile))
+	}
+	index := r.managedEntry(*profile)
+	if index < 0 {
+		zap.L().Debug("client SSL profile does not use a certificate installed by vcert", zap.String("profile", profile.FullPath))
+		return nil, nil
+	}
+	cert := profile.CertKeyChain[index].Cert
+	return client.GetCertificate(ctx, r.partition(), cert[strings.LastIndex(cert, "/")+1:])
+}
+
+// managedCertificates returns the certificates installed by vcert for this installation, the latest first
+func (r F5Installer) managedCertificates(ctx context.Context, client *f5.Client) ([]f5.SSLCert, error) {
+	all, err := client.ListCertificates(ctx, r.partition())
+	if err != nil {
+		return nil, err
+	}
+	certs := make([]f5.SSLCert, 0)
+	for _, cert := range all {
+		if r.isManagedCertificate(cert.Name) {
+			certs = append(certs, cert)
+		}
+	}
+	sort.SliceStable(certs, func(i, j int) bool {
+		return parseF5Time(certs[i].CreateTime).After(parseF5Time(certs[j].CreateTime))
+	})
+	return certs, nil
+}
+
+// managedEntry returns the index of the certKeyChain entry of profile that holds a certificate installed by vcert
+// for this installation, or -1
+func (r F5Installer) managedEntry(profile f5.ClientSSLProfile) int {
+	for i, entry := range profile.CertKeyChain {
+		if r.isManagedCertificate(entry.Cert[strings.LastIndex(entry.Cert, "/")+1:]) {
+			return i
+		}
+	}
+	return -1
+}
+
+func (r F5Installer) isManagedCertificate(name string) bool {
+	return regexp.MustCompile(`^` + regexp.QuoteMeta(r.F5CertName) + `_[0-9a-f]{8}\.crt$`).MatchString(name)
+}
+
+// objectName returns the base name of the objects created for cert, i.e. www.example.com_1a2b3c4d
+func (r F5Installer) objectName(cert *x509.Certificate) string {
+	thumbprint := sha1.Sum(cert.Raw)
+	return fmt.Sprintf("%s_%s", r.F5CertName, hex.EncodeToString(thumbprint[:4]))
+}
+
+// certKeyChain returns the full paths of the certificate, key and chain objects with the base name
+func (r F5Installer) certKeyChain(name string) f5.CertKeyChain {
+	return f5.CertKeyChain{
+		Cert:  f5.FullPath(r.partition(), name+".crt"),
+		Key:   f5.FullPath(r.partition(), name+".key"),
+		Chain: f5.FullPath(r.partition(), name+"-chain.crt"),
+	}
+}
+
+func (r F5Installer) getClient(ctx context.Context) (*f5.Client, error) {
+	client, err := f5.NewClient(ctx, r.F5Address, r.F5Username, r.F5Password, r.F5CACert, r.F5Insecure)
+	if err != nil {
+		zap.L().Error("could not authenticate to F5 BIG-IP", zap.Error(err))
+		return nil, err
+	}
+	return client, nil
+}
+
+func (r F5Installer) partition() string {
+	if r.F5Partition == "" {
+		return domain.DefaultF5Partition
+	}
+	return r.F5Partition
+}
+
+func (r F5Installer) location() string {
+	location := fmt.Sprintf("%s/%s/%s", strings.TrimSuffix(r.F5Address, "/"), r.partition(), r.F5CertName)
+	if r.F5Profile != "" {
+		location = fmt.Sprintf("%s (profile %s)", location, r.F5Profile)
+	}
+	return location
+}
+
+// f5X509Certificate returns an x509.Certificate with the details the BIG-IP holds about cert
+func f5X509Certificate(cert f5.SSLCert) *x509.Certificate {
+	serialNumber, _ := new(big.Int).SetString(strings.ReplaceAll(cert.SerialNumber, ":", ""), 16)
+	return &x509.Certificate{
+		SerialNumber: serialNumber,
+		Subject:      pkix.Name{CommonName: cert.Subject},
+		NotBefore:    parseF5Time(cert.CreateTime),
+		NotAfter:     time.Unix(cert.ExpirationDate, 0),
+	}
+}
+
+func parseF5Time(value string) time.Time {
+	t, err := time.Parse(time.RFC3339, value)
+	if err != nil {
+		return time.Time{}
+	}
+	return t
+}
@@ -0,0 +1,296 @@
+/*
+ * Copyright 2023 Venafi, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *  http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package installer
+
+import (
+	"context"
+	"crypto/x509"
+	"fmt"
+	"strings"
+
+	"go.uber.org/zap"
+
+	"github.com/Venafi/vcert/v5/pkg/certificate"
+	"github.com/Venafi/vcert/v5/pkg/playbook/app/domain"
+	"github.com/Venafi/vcert/v5/pkg/playbook/util"
+	"github.com/Venafi/vcert/v5/pkg/playbook/util/gcp"
+)
+
+const (
+	gcpLabelManagedBy            = "managed-by"
+	gcpAnnotationPreviousVersion = "vcert-previous-version"
+	gcpLatestVersion             = "latest"
+)
+
+// GCPInstaller represents an installation that will upload the certificate to Google Certificate Manager,
+// or store the PEM bundle in a Google Secret Manager secret
+type GCPInstaller struct {
+	domain.Installation
+}
+
+// NewGCPInstaller returns a new installer of type GCP with the values defined in inst
+func NewGCPInstaller(inst domain.Installation) GCPInstaller {
+	return GCPInstaller{inst}
+}
+
+// Check is the method in charge of making the validations to install a new certificate:
+// 1. Does the certificate exists? > Install if it doesn't.
+// 2. Does the certificate is about to expire? Renew if about to expire.
+// Returns true if the certificate needs to be installed, along with the certificate currently installed, if any.
+func (r GCPInstaller) Check(ctx context.Context, renewBefore string, _ domain.PlaybookRequest) (bool, *x509.Certificate, error) {
+	zap.L().Info("checking certificate health", zap.String("format", r.Type.String()), zap.String("location", r.location()))
+
+	client, err := r.getClient(ctx)
+	if err != nil {
+		return false, nil, err
+	}
+
+	var certPEM []byte
+	if r.isSecretManager() {
+		certPEM, err = client.AccessSecretVersion(ctx, r.GCPCertName, gcpLatestVersion)
+	} else {
+		var gcpCert *gcp.Certificate
+		gcpCert, err = client.GetCertificate(ctx, r.gcpLocation(), r.GCPCertName)
+		if gcpCert != nil {
+			certPEM = []byte(gcpCert.PEMCertificate)
+		}
+	}
+	if err != nil {
+		return false, nil, err
+	}
+	if len(certPEM) == 0 {
+		zap.L().Debug("certificate does not exist", zap.String("location", r.location()))
+		return true, nil, nil
+	}
+
+	// The certificate is the first block of the bundle, followed by its chain
+	chain := parsePEMCertificates(certPEM)
+	if len(chain) == 0 {
+		return false, nil, fmt.Errorf("could not find a certificate in %s", r.location())
+	}
+	cert := chain[0]
+
+	// Check certificate expiration
+	renew := needRenewal(cert, renewBefore)
+
+	// Check certificate revocation
+	if !renew && r.ValidateRevocation {
+		renew = isRevoked(cert, chain)
+	}
+
+	return renew, cert, nil
+}
+
+// Backup is a no-op for GCP. Secret Manager keeps the previous versions of the secret, while
+// Certificate Manager does not allow exporting the private key of the certificate
+func (r GCPInstaller) Backup(_ context.Context) error {
+	if r.isSecretManager() {
+		zap.L().Debug("secret versions are kept by Secret Manager, no back up taken", zap.String("location", r.location()))
+		return nil
+	}
+	zap.L().Info("Certificate Manager does not allow exporting private keys, no back up taken", zap.String("location", r.location()))
+	return nil
+}
+
+// Install takes the certificate bundle and moves it to the location specified in the installer.
+//
+// When the certificate already exists in Certificate Manager, it is updated in place, so that the load balancers
+// using it pick up the renewed certificate automatically. In Secret Manager, the bundle is added as a new version
+// of the secret
+func (r GCPInstaller) Install(ctx context.Context, pcc certificate.PEMCollection) error {
+	zap.L().Debug("installing certificate", zap.String("location", r.location()))
+
+	if len(pcc.Certificate) == 0 || len(pcc.PrivateKey) == 0 {
+		return fmt.Errorf("certificate and Private Key are required for GCP")
+	}
+
+	// GCP requires an unencrypted private key
+	privateKey, err := MarshalPKCS8PrivateKey(pcc.PrivateKey, "")
+	if err != nil {
+		zap.L().Error("could not prepare private key for GCP", zap.Error(err))
+		return err
+	}
+	certChain := joinPEM(pcc.Certificate, strings.Join(pcc.Chain, ""))
+
+	client, err := r.getClient(ctx)
+	if err != nil {
+		return err
+	}
+
+	if r.isSecretManager() {
+		err = r.installSecret(ctx, client, joinPEM(certChain, privateKey))
+	} else {
+		err = r.installCertificate(ctx, client, certChain, privateKey)
+	}
+	if err != nil {
+		zap.L().Error("could not install certificate in GCP", zap.String("location", r.location()), zap.Error(err))
+		return err
+	}
+	return nil
+}
+
+func (r GCPInstaller) installCertificate(ctx context.Context, client *gcp.Client, certChain string, privateKey string) error {
+	current, err := client.GetCertificate(ctx, r.gcpLocation(), r.GCPCertName)
+	if err != nil {
+		return err
+	}
+
+	if current == nil {
+		err = client.CreateCertificate(ctx, r.gcpLocation(), r.GCPCertName, certChain, privateKey,
+			map[string]string{gcpLabelManagedBy: "vcert"})
+	} else {
+		err = client.UpdateCertificate(ctx, r.gcpLocation(), r.GCPCertName, certChain, privateKey)
+	}
+	if err != nil {
+		return err
+	}
+
+	zap.L().Debug("certificate uploaded to Certificate Manager", zap.String("location", r.location()))
+	return nil
+}
+
+func (r GCPInstaller) installSecret(ctx context.Context, client *gcp.Client, bundle string) error {
+	secret, err := client.GetSecret(ctx, r.GCPCertName)
+	if err != nil {
+		return err
+	}
+	if secret == nil {
+		err = client.CreateSecret(ctx, r.GCPCertName, map[string]string{gcpLabelManagedBy: "vcert"})
+		if err != nil {
+			return err
+		}
+	}
+
+	// Keep track of the replaced version, so Rollback can tell whether a new version was added
+	current, err := client.GetSecretVersion(ctx, r.GCPCertName, gcpLatestVersion)
+	if err != nil {
+		return err
+	}
+	previousVersion := ""
+	if current != nil {
+		previousVersion = current.Version()
+	}
+	annotations := map[string]string{}
+	if secret != nil {
+		for k, v := range secret.Annotations {
+			annotations[k] = v
+		}
+	}
+	annotations[gcpAnnotationPreviousVersion] = previousVersion
+	err = client.SetSecretAnnotations(ctx, r.GCPCertName, annotations)
+	if err != nil {
+		return err
+	}
+
+	added, err := client.AddSecretVersion(ctx, r.GCPCertName, []byte(bundle))
+	if err != nil {
+		return err
+	}
+
+	zap.L().Debug("certificate stored in Secret Manager", zap.String("location", r.location()), zap.String("version", added.Version()))
+	return nil
+}
+
+// Rollback disables the secret version added by Install, so the previous version becomes the latest again.
+// Certificate Manager certificates cannot be rolled back
+func (r GCPInstaller) Rollback(ctx context.Context) error {
+	if !r.isSecretManager() {
+		zap.L().Warn("Certificate Manager certificates cannot be rolled back", zap.String("location", r.location()))
+		return nil
+	}
+	zap.L().Debug("rolling back certificate", zap.String("location", r.location()))
+
+	client, err := r.getClient(ctx)
+	if err != nil {
+		return err
+	}
+
+	secret, err := client.GetSecret(ctx, r.GCPCertName)
+	if err != nil {
+		return err
+	}
+	current, err := client.GetSecretVersion(ctx, r.GCPCertName, gcpLatestVersion)
+	if err != nil {
+		return err
+	}
+	if secret == nil || current == nil || secret.Annotations[gcpAnnotationPreviousVersion] == "" ||
+		secret.Annotations[gcpAnnotationPreviousVersion] == current.Version() {
+		zap.L().Info("no previous version found, nothing to restore", zap.String("location", r.location()))
+		return nil
+	}
+
+	err = client.DisableSecretVersion(ctx, r.GCPCertName, current.Version())
+	if err != nil {
+		return err
+	}
+
+	zap.L().Info("certificate restored from previous version", zap.String("location", r.location()),
+		zap.String("version", secret.Annotations[gcpAnnotationPreviousVersion]))
+	return nil
+}
+
+// AfterInstallActions runs the actions declared in the Installer, in order: scripts run on a terminal,
+// while services, sites and webhooks are handled natively.
+//
+// No validations happen over the content of the AfterAction scripts, so caution is advised
+func (r GCPInstaller) AfterInstallActions(ctx context.Context) (string, error) {
+	zap.L().Debug("running after-install actions", zap.String("location", r.location()))
+
+	result, err := runAfterInstallActions(ctx, r.AfterAction)
+	return result, err
+}
+
+// InstallValidationActions runs any instructions declared in the Installer on a terminal and expects
+// "0" for successful validation and "1" for a validation failure
+// No validations happen over the content of the InstallValidation string, so caution is advised
+func (r GCPInstaller) InstallValidationActions(ctx context.Context) (string, error) {
+	zap.L().Debug("running install validation actions", zap.String("location", r.location()))
+
+	validationResult, err := util.ExecuteScript(ctx, r.InstallValidation)
+	if err != nil {
+		return "", err
+	}
+
+	return validationResult, err
+}
+
+func (r GCPInstaller) getClient(ctx context.Context) (*gcp.Client, error) {
+	client, err := gcp.NewClient(r.GCPProject, r.GCPCredentialsFile)
+	if err != nil {
+		zap.L().Error("could not authenticate to GCP", zap.Error(err))
+		return nil, err
+	}
+	return client, nil
+}
+
+func (r GCPInstaller) isSecretManager() bool {
+	return strings.EqualFold(r.GCPTarget, domain.GCPTargetSecretManager)
+}
+
+func (r GCPInstaller) gcpLocation() string {
+	if r.GCPLocation == "" {
+		return domain.DefaultGCPLocation
+	}
+	return r.GCPLocation
+}
+
+func (r GCPInstaller) location() string {
+	if r.isSecretManager() {
+		return fmt.Sprintf("projects/%s/secrets/%s", r.GCPProject, r.GCPCertName)
+	}
+	return fmt.Sprintf("projects/%s/locations/%s/certificates/%s", r.GCPProject, r.gcpLocation(), r.GCPCertName)
+}
@@ -17,8 +17,22 @@
 package installer
 
 import (
+	"context"
+	"crypto"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"go.uber.org/zap"
+
 	"github.com/Venafi/vcert/v5/pkg/certificate"
 	"github.com/Venafi/vcert/v5/pkg/playbook/app/domain"
+	"github.com/Venafi/vcert/v5/pkg/playbook/util"
 )
 
 // Installer represents the interface for all installers.
@@ -28,22 +42,141 @@ type Installer interface {
 	// Check is the method in charge of making the validations to install a new certificate:
 	// 1. Does the certificate exists? > Install if it doesn't.
 	// 2. Does the certificate is about to expire? Renew if about to expire.
-	// Returns true if the certificate needs to be installed.
-	Check(renewBefore string, request domain.PlaybookRequest) (bool, error)
+	// Returns true if the certificate needs to be installed, along with the certificate currently installed, if any.
+	Check(ctx context.Context, renewBefore string, request domain.PlaybookRequest) (bool, *x509.Certificate, error)
 
 	// Backup takes the certificate request and backs up the current version prior to overwriting
-	Backup() error
+	Backup(ctx context.Context) error
 
 	// Install takes the certificate bundle and moves it to the location specified in the installer
-	Install(pcc certificate.PEMCollection) error
+	Install(ctx context.Context, pcc certificate.PEMCollection) error
+
+	// Rollback restores the version of the certificate backed up by Backup, overwriting the installed one.
+	// It is used to undo an installation when another installation of the same task fails
+	Rollback(ctx context.Context) error
 
-	// AfterInstallActions runs any instructions declared in the Installer on a terminal.
+	// AfterInstallActions runs the actions declared in the Installer, in order: scripts run on a terminal,
+	// while services, sites and webhooks are handled natively.
 	//
-	// No validations happen over the content of the AfterAction string, so caution is advised
-	AfterInstallActions() (string, error)
+	// No validations happen over the content of the AfterAction scripts, so caution is advised
+	AfterInstallActions(ctx context.Context) (string, error)
 
 	// InstallValidationActions runs any instructions declared in the Installer on a terminal and expects
 	// "0" for successful validation and "1" for a validation failure
 	// No validations happen over the content of the InstallValidation string, so caution is advised
-	InstallValidationActions() (string, error)
+	InstallValidationActions(ctx context.Context) (string, error)
+}
+
+// KeyReader is implemented by the installers writing the private key to a file they can read back, so a renewal can
+// reuse it
+type KeyReader interface {
+	// PrivateKey returns the private key installed, or nil when nothing is installed
+	PrivateKey(ctx context.Context) (crypto.Signer, error)
+}
+
+// backupTimeFormat is the timestamp in the name of the backups, i.e. cert.pem.2024-05-01T10-00-00.bak.
+// It sorts chronologically and is valid in file names on Windows
+const backupTimeFormat = "2006-01-02T15-04-05"
+
+// newBackupTimestamp returns the timestamp of the backups taken now. All the files backed up by an installer share
+// it, so they can be restored together
+func newBackupTimestamp() string {
+	return time.Now().UTC().Format(backupTimeFormat)
+}
+
+// backupFile copies the file at location to a backup named after timestamp, next to it, and removes the oldest
+// backups of the file so only retention backups are kept
+func backupFile(location string, timestamp string, retention int) (string, error) {
+	backupLocation := fmt.Sprintf("%s.%s.bak", location, timestamp)
+	err := util.CopyFile(location, backupLocation)
+	if err != nil {
+		return "", err
+	}
+
+	backups, err := listBackups(location)
+	if err != nil {
+		return "", err
+	}
+	for len(backups) > retention {
+		err = os.Remove(backups[0])
+		if err != nil {
+			return "", fmt.Errorf("could not remove old backup %s: %w", backups[0], err)
+		}
+		zap.L().Info("old backup removed", zap.String("location", location), zap.String("backupLocation", backups[0]))
+		backups = backups[1:]
+	}
+	return backupLocation, nil
+}
+
+// listBackups returns the timestamped backups of the file at location, oldest first
+func listBackups(location string) ([]string, error) {
+	entries, err := os.ReadDir(filepath.Dir(location))
+	if err != nil {
+		return nil, err
+	}
+
+	prefix := filepath.Base(location) + "."
+	var backups []string
+	for _, entry := range entries {
+		timestamp, found := strings.CutPrefix(entry.Name(), prefix)
+		if !found || entry.IsDir() {
+			continue
+		}
+		timestamp, found = strings.CutSuffix(timestamp, ".bak")
+		if _, err = time.Parse(backupTimeFormat, timestamp); !found || err != nil {
+			continue
+		}
+		backups = append(backups, filepath.Join(filepath.Dir(location), entry.Name()))
+	}
+	sort.Strings(backups)
+	return backups, nil
+}
+
+// restoreBackup copies the latest backup taken for the given location back to it. Backups taken by previous versions
+// of vcert, named location.bak, are restored when there is no timestamped backup.
+// Nothing is restored when no backup exists for the location
+func restoreBackup(location string) error {
+	backups, err := listBackups(location)
+	if err != nil && !errors.Is(err, os.ErrNotExist) {
+		return err
+	}
+
+	backupLocation := fmt.Sprintf("%s.bak", location)
+	if len(backups) > 0 {
+		backupLocation = backups[len(backups)-1]
+	}
+
+	backupExists, err := util.FileExists(backupLocation)
+	if err != nil {
+		return err
+	}
+	if !backupExists {
+		zap.L().Info("no backup found, nothing to restore", zap.String("location", location))
+		return nil
+	}
+
+	err = util.CopyFile(backupLocation, location)
+	if err != nil {
+		return err
+	}
+
+	zap.L().Info("certificate resource restored from backup", zap.String("location", location),
+		zap.String("backupLocation", backupLocation))
+	return nil
+}
+
+// applyFilePermissions sets the mode, owner and group declared in the installation to the file at location
+func applyFilePermissions(installation domain.Installation, location string) error {
+	if installation.Mode != "" {
+		mode, err := domain.ParseFileMode(installation.Mode)
+		if err != nil {
+			return err
+		}
+		err = util.SetFileMode(location, mode)
+		if err != nil {
+			return err
+		}
+	}
+
+	return util.SetFileOwnership(location, installation.Owner, installation.Group)
 }
@@ -18,14 +18,19 @@ package installer
 
 import (
 	"bytes"
+	"context"
+	"crypto"
 	"crypto/x509"
 	"encoding/pem"
+	"errors"
 	"fmt"
 	"os"
+	"strings"
 	"time"
 
 	"github.com/pavel-v-chernykh/keystore-go/v4"
 	"go.uber.org/zap"
+	"software.sslmate.com/src/go-pkcs12"
 
 	"github.com/Venafi/vcert/v5/pkg/certificate"
 	"github.com/Venafi/vcert/v5/pkg/playbook/app/domain"
@@ -45,17 +50,17 @@ func NewJKSInstaller(inst domain.Installation) JKSInstaller {
 // Check is the method in charge of making the validations to install a new certificate:
 // 1. Does the certificate exists? > Install if it doesn't.
 // 2. Does the certificate is about to expire? Renew if about to expire.
-// Returns true if the certificate needs to be installed.
-func (r JKSInstaller) Check(renewBefore string, _ domain.PlaybookRequest) (bool, error) {
+// Returns true if the certificate needs to be installed, along with the certificate currently installed, if any.
+func (r JKSInstaller) Check(_ context.Context, renewBefore string, _ domain.PlaybookRequest) (bool, *x509.Certificate, error) {
 	zap.L().Info("checking certificate health", zap.String("format", r.Type.String()), zap.String("location", r.File))
 
 	// Check certificate file exists
 	certExists, err := util.FileExists(r.File)
 	if err != nil {
-		return false, err
+		return false, nil, err
 	}
 	if !certExists {
-		return true, nil
+		return true, nil, nil
 	}
 
 	keyPassword := r.KeyPassword
@@ -64,23 +69,74 @@ func (r JKSInstaller) Check(renewBefore string, _ domain.PlaybookRequest) (bool,
 	}
 
 	// Load Certificate
-	cert, err := loadJKS(r.File, r.JKSAlias, r.JKSPassword, keyPassword)
+	cert, privateKey, err := loadJKS(r.File, r.JKSAlias, r.JKSPassword, keyPassword)
 	if err != nil {
-		return false, err
+		return false, nil, err
+	}
+
+	// Check the private key belongs to the certificate
+	if keyMismatch(cert, privateKey, nil, zap.String("location", r.File), zap.String("jksAlias", r.JKSAlias)) {
+		return true, cert, nil
+	}
+
+	// The truststore is written along with the keystore, so a missing truststore requires a new installation
+	if r.TruststoreFile != "" {
+		truststoreExists, err := util.FileExists(r.TruststoreFile)
+		if err != nil {
+			return false, nil, err
+		}
+		if !truststoreExists {
+			zap.L().Info("truststore not found", zap.String("location", r.TruststoreFile))
+			return true, cert, nil
+		}
 	}
 
 	// Check certificate expiration
 	renew := needRenewal(cert, renewBefore)
 
-	return renew, nil
+	// Check certificate revocation
+	if !renew && r.ValidateRevocation {
+		renew = isRevoked(cert, nil)
+	}
+
+	return renew, cert, nil
+}
+
+// PrivateKey returns the private key installed, or nil when nothing is installed
+func (r JKSInstaller) PrivateKey(_ context.Context) (crypto.Signer, error) {
+	certExists, err := util.FileExists(r.File)
+	if err != nil || !certExists {
+		return nil, err
+	}
+	keyPassword := r.KeyPassword
+	if keyPassword == "" {
+		keyPassword = r.JKSPassword
+	}
+	_, privateKey, err := loadJKS(r.File, r.JKSAlias, r.JKSPassword, keyPassword)
+	if err != nil {
+		return nil, err
+	}
+	return asSigner(privateKey)
 }
 
 // Backup takes the certificate request and backs up the current version prior to overwriting
-func (r JKSInstaller) Backup() error {
-	zap.L().Debug("backing up certificate", zap.String("location", r.File))
+func (r JKSInstaller) Backup(_ context.Context) error {
+	timestamp := newBackupTimestamp()
+	err := backupJKSFile(r.File, timestamp, r.GetBackupRetention())
+	if err != nil {
+		return err
+	}
+	if r.TruststoreFile != "" {
+		return backupJKSFile(r.TruststoreFile, timestamp, r.GetBackupRetention())
+	}
+	return nil
+}
+
+func backupJKSFile(location string, timestamp string, retention int) error {
+	zap.L().Debug("backing up certificate", zap.String("location", location))
 
 	// Check certificate file exists
-	certExists, err := util.FileExists(r.File)
+	certExists, err := util.FileExists(location)
 	if err != nil {
 		return err
 	}
@@ -89,19 +145,17 @@ func (r JKSInstaller) Backup() error {
 		return nil
 	}
 
-	newLocation := fmt.Sprintf("%s.bak", r.File)
-
-	err = util.CopyFile(r.File, newLocation)
+	newLocation, err := backupFile(location, timestamp, retention)
 	if err != nil {
 		return err
 	}
 
-	zap.L().Info("certificate backed up", zap.String("location", r.File), zap.String("backupLocation", newLocation))
+	zap.L().Info("certificate backed up", zap.String("location", location), zap.String("backupLocation", newLocation))
 	return nil
 }
 
 // Install takes the certificate bundle and moves it to the location specified in the installer
-func (r JKSInstaller) Install(pcc certificate.PEMCollection) error {
+func (r JKSInstaller) Install(_ context.Context, pcc certificate.PEMCollection) error {
 	zap.L().Debug("installing certificate", zap.String("location", r.File))
 
 	// If no password is set for the Private Key, use the JKSPassword
@@ -110,7 +164,7 @@ func (r JKSInstaller) Install(pcc certificate.PEMCollection) error {
 		keyPassword = r.JKSPassword
 	}
 
-	content, err := packageAsJKS(pcc, keyPassword, r.JKSAlias, r.JKSPassword)
+	content, err := PackageAsJKS(pcc, keyPassword, r.JKSAlias, r.JKSPassword)
 	if err != nil {
 		zap.L().Error("could not package certificate as JKS", zap.Error(err))
 		return err
@@ -121,26 +175,69 @@ func (r JKSInstaller) Install(pcc certificate.PEMCollection) error {
 		return err
 	}
 
+	err = applyFilePermissions(r.Installation, r.File)
+	if err != nil {
+		return err
+	}
+
+	if r.TruststoreFile != "" {
+		return r.installTruststore(pcc)
+	}
 	return nil
 }
 
-// AfterInstallActions runs any instructions declared in the Installer on a terminal.
+// installTruststore writes the chain of the certificate to TruststoreFile, as trusted certificate entries
+func (r JKSInstaller) installTruststore(pcc certificate.PEMCollection) error {
+	zap.L().Debug("installing truststore", zap.String("location", r.TruststoreFile))
+
+	var content []byte
+	var err error
+	if strings.ToLower(r.TruststoreFormat) == domain.TruststoreFormatPKCS12 {
+		content, err = packageAsPKCS12Truststore(pcc.Chain, r.TruststorePassword, getPKCS12Encoder(r.P12Encryption))
+	} else {
+		content, err = packageAsJKSTruststore(pcc.Chain, r.JKSAlias, r.TruststorePassword)
+	}
+	if err != nil {
+		zap.L().Error("could not package truststore", zap.Error(err))
+		return err
+	}
+
+	err = util.WriteFile(r.TruststoreFile, content)
+	if err != nil {
+		return err
+	}
+
+	return applyFilePermissions(r.Installation, r.TruststoreFile)
+}
+
+// Rollback restores the version of the certificate backed up by Backup, overwriting the installed one
+func (r JKSInstaller) Rollback(_ context.Context) error {
+	zap.L().Debug("rolling back certificate", zap.String("location", r.File))
+	err := restoreBackup(r.File)
+	if r.TruststoreFile != "" {
+		err = errors.Join(err, restoreBackup(r.TruststoreFile))
+	}
+	return err
+}
+
+// AfterInstallActions runs the actions declared in the Installer, in order: scripts run on a terminal,
+// while services, sites and webhooks are handled natively.
 //
-// No validations happen over the content of the AfterAction string, so caution is advised
-func (r JKSInstaller) AfterInstallActions() (string, error) {
+// No validations happen over the content of the AfterAction scripts, so caution is advised
+func (r JKSInstaller) AfterInstallActions(ctx context.Context) (string, error) {
 	zap.L().Debug("running after-install actions", zap.String("location", r.File))
 
-	result, err := util.ExecuteScript(r.AfterAction)
+	result, err := runAfterInstallActions(ctx, r.AfterAction)
 	return result, err
 }
 
 // InstallValidationActions runs any instructions declared in the Installer on a terminal and expects
 // "0" for successful validation and "1" for a validation failure
 // No validations happen over the content of the InstallValidation string, so caution is advised
-func (r JKSInstaller) InstallValidationActions() (string, error) {
+func (r JKSInstaller) InstallValidationActions(ctx context.Context) (string, error) {
 	zap.L().Debug("running install validation actions", zap.String("location", r.File))
 
-	validationResult, err := util.ExecuteScript(r.InstallValidation)
+	validationResult, err := util.ExecuteScript(ctx, r.InstallValidation)
 	if err != nil {
 		return "", err
 	}
@@ -148,45 +245,32 @@ func (r JKSInstaller) InstallValidationActions() (string, error) {
 	return validationResult, err
 }
 
-func loadJKS(jksFile string, jksAlias string, jksPassword string, pkPassword string) (*x509.Certificate, error) {
+// loadJKS returns the certificate and the private key stored under jksAlias in the Java Keystore.
+// The private key is nil when it cannot be parsed
+func loadJKS(jksFile string, jksAlias string, jksPassword string, pkPassword string) (*x509.Certificate, interface{}, error) {
 	//Open file
-	f, err := os.Open(jksFile)
+	data, err := os.ReadFile(jksFile)
 	if err != nil {
 		zap.L().Error("could not read JKS file", zap.String("jksFile", jksFile), zap.Error(err))
-		return nil, err
+		return nil, nil, err
 	}
-	defer func() {
-		if err = f.Close(); err != nil {
-			zap.L().Fatal("could not close JKS file", zap.String("jksFile", jksFile))
-		}
-	}()
 
-	// Load JKS
-	ks := keystore.New()
-	err = ks.Load(f, []byte(jksPassword))
-	if err != nil {
-		zap.L().Error("could not load JKS resource", zap.String("jksFile", jksFile))
-		return nil, err
-	}
-
-	//Load Private Key and Certificate chain
-	pkEntry, err := ks.GetPrivateKeyEntry(jksAlias, []byte(pkPassword))
-	if err != nil {
-		zap.L().Error("could not retrieve Private Key from JKS", zap.String("jksAlias", jksAlias))
-		return nil, err
+	lc, err := decodeJKS(data, jksAlias, jksPassword, pkPassword)
+	if errors.Is(err, errUnreadablePrivateKey) {
+		zap.L().Warn("could not parse Private Key from JKS", zap.String("jksAlias", jksAlias), zap.Error(err))
+		return lc.Certificate, nil, nil
 	}
-
-	certData := pkEntry.CertificateChain[0]
-
-	cert, err := x509.ParseCertificate(certData.Content)
 	if err != nil {
-		return nil, fmt.Errorf("could not parse certificate: %w", err)
+		zap.L().Error("could not load JKS resource", zap.String("jksFile", jksFile), zap.Error(err))
+		return nil, nil, err
 	}
 
-	return cert, nil
+	return lc.Certificate, lc.PrivateKey, nil
 }
 
-func packageAsJKS(pcc certificate.PEMCollection, keyPassword string, jksAlias string, jksPassword string) ([]byte, error) {
+// PackageAsJKS returns the certificate, chain and private key of pcc as a Java KeyStore holding a single private key
+// entry named jksAlias. The private key is protected with keyPassword, and the store with jksPassword or keyPassword when empty
+func PackageAsJKS(pcc certificate.PEMCollection, keyPassword string, jksAlias string, jksPassword string) ([]byte, error) {
 	if len(pcc.Certificate) == 0 || len(pcc.PrivateKey) == 0 {
 		return nil, fmt.Errorf("certificate and Private Key are required for JKS")
 	}
@@ -251,6 +335,51 @@ func packageAsJKS(pcc certificate.PEMCollection, keyPassword string, jksAlias st
 	return buffer.Bytes(), nil
 }
 
+// packageAsJKSTruststore returns a Java KeyStore holding the chain certificates as trusted entries,
+// named after jksAlias, i.e. myalias-ca1 for the issuer of the certificate
+func packageAsJKSTruststore(chain []string, jksAlias string, password string) ([]byte, error) {
+	if len(chain) == 0 {
+		return nil, fmt.Errorf("chain certificates are required for the truststore")
+	}
+
+	keyStore := keystore.New()
+	for i, c := range getJKSCertChain(chain) {
+		entry := keystore.TrustedCertificateEntry{
+			CreationTime: time.Now(),
+			Certificate:  c,
+		}
+		err := keyStore.SetTrustedCertificateEntry(fmt.Sprintf("%s-ca%d", jksAlias, i+1), entry)
+		if err != nil {
+			return nil, fmt.Errorf("JKS truststore entry error: %w", err)
+		}
+	}
+
+	buffer := new(bytes.Buffer)
+	err := keyStore.Store(buffer, []byte(password))
+	if err != nil {
+		return nil, fmt.Errorf("JKS truststore error: %w", err)
+	}
+	return buffer.Bytes(), nil
+}
+
+// packageAsPKCS12Truststore returns a PKCS12 bundle holding the chain certificates as trusted entries, as read by Java
+func packageAsPKCS12Truststore(chain []string, password string, encoder *pkcs12.Encoder) ([]byte, error) {
+	if len(chain) == 0 {
+		return nil, fmt.Errorf("chain certificates are required for the truststore")
+	}
+
+	chainList, err := getX509CertChain(chain)
+	if err != nil {
+		return nil, err
+	}
+
+	content, err := encoder.EncodeTrustStore(chainList, password)
+	if err != nil {
+		return nil, fmt.Errorf("PKCS12 truststore error: %w", err)
+	}
+	return content, nil
+}
+
 func getJKSCertChain(chain []string) []keystore.Certificate {
 	certificateChain := make([]keystore.Certificate, 0)
 	//Getting each certificate in the chain and adding their bytes to the JKS chain
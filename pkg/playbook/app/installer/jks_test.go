@@ -0,0 +1,113 @@
+/*
+ * Copyright 2023 Venafi, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *  http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package installer
+
+import (
+	"bytes"
+	"context"
+	"encoding/pem"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/pavel-v-chernykh/keystore-go/v4"
+
+	"github.com/Venafi/vcert/v5/pkg/playbook/app/domain"
+)
+
+func TestJKSInstallerCheckPrivateKey(t *testing.T) {
+	pcc := newTestPEMCollection(t)
+
+	matching, err := PackageAsJKS(pcc, "secret", "vcert", "secret")
+	if err != nil {
+		t.Fatal(err)
+	}
+	mismatched, err := PackageAsJKS(newTestMismatchedPEMCollection(t), "secret", "vcert", "secret")
+	if err != nil {
+		t.Fatal(err)
+	}
+	otherKeyPassword, err := PackageAsJKS(pcc, "another", "vcert", "secret")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// A key the keystore decrypts but that is not PKCS#8, as happens with key types Go cannot parse
+	certBlock, _ := pem.Decode([]byte(pcc.Certificate))
+	ks := keystore.New()
+	err = ks.SetPrivateKeyEntry("vcert", keystore.PrivateKeyEntry{
+		CreationTime:     time.Now(),
+		PrivateKey:       []byte("not a PKCS#8 private key"),
+		CertificateChain: []keystore.Certificate{{Type: "X509", Content: certBlock.Bytes}},
+	}, []byte("secret"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	buffer := new(bytes.Buffer)
+	err = ks.Store(buffer, []byte("secret"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	unparsable := buffer.Bytes()
+
+	// The certificate chain of a private key entry is stored along with the key, so it cannot be read without
+	// the password of the key
+	tests := []struct {
+		name          string
+		content       []byte
+		expectInstall bool
+		expectErr     bool
+	}{
+		{name: "matching key", content: matching},
+		{name: "mismatched key", content: mismatched, expectInstall: true},
+		{name: "unreadable key", content: otherKeyPassword, expectErr: true},
+		{name: "nil key", content: unparsable},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			location := filepath.Join(t.TempDir(), "cert.jks")
+			err := os.WriteFile(location, tt.content, 0600)
+			if err != nil {
+				t.Fatal(err)
+			}
+			inst := NewJKSInstaller(domain.Installation{
+				Type:        domain.FormatJKS,
+				File:        location,
+				JKSAlias:    "vcert",
+				JKSPassword: "secret",
+			})
+
+			install, cert, err := inst.Check(context.Background(), "10%", domain.PlaybookRequest{})
+			if tt.expectErr {
+				if err == nil {
+					t.Fatal("expected an error")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %s", err)
+			}
+			if install != tt.expectInstall {
+				t.Fatalf("expected install %t, got %t", tt.expectInstall, install)
+			}
+			if cert == nil {
+				t.Fatal("installed certificate not returned")
+			}
+		})
+	}
+}
@@ -0,0 +1,262 @@
+/*
+ * Copyright 2023 Venafi, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *  http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package installer
+
+import (
+	"context"
+	"crypto/x509"
+	"fmt"
+	"strings"
+
+	"go.uber.org/zap"
+
+	"github.com/Venafi/vcert/v5/pkg/certificate"
+	"github.com/Venafi/vcert/v5/pkg/playbook/app/domain"
+	"github.com/Venafi/vcert/v5/pkg/playbook/util"
+	"github.com/Venafi/vcert/v5/pkg/playbook/util/k8s"
+)
+
+// K8sSecretInstaller represents an installation that will store the certificate bundle in a kubernetes.io/tls Secret
+type K8sSecretInstaller struct {
+	domain.Installation
+}
+
+// NewK8sSecretInstaller returns a new installer of type K8SSECRET with the values defined in inst
+func NewK8sSecretInstaller(inst domain.Installation) K8sSecretInstaller {
+	return K8sSecretInstaller{inst}
+}
+
+// Check is the method in charge of making the validations to install a new certificate:
+// 1. Does the certificate exists? > Install if it doesn't.
+// 2. Does the certificate is about to expire? Renew if about to expire.
+// Returns true if the certificate needs to be installed, along with the certificate currently installed, if any.
+func (r K8sSecretInstaller) Check(ctx context.Context, renewBefore string, _ domain.PlaybookRequest) (bool, *x509.Certificate, error) {
+	zap.L().Info("checking certificate health", zap.String("format", r.Type.String()), zap.String("location", r.location()))
+
+	client, err := r.getClient(ctx)
+	if err != nil {
+		return false, nil, err
+	}
+
+	secret, err := client.GetSecret(ctx, r.namespace(), r.K8sSecretName)
+	if err != nil {
+		return false, nil, err
+	}
+	if secret == nil {
+		zap.L().Debug("secret does not exist", zap.String("location", r.location()))
+		return true, nil, nil
+	}
+
+	certData, found := secret.Data[k8s.TLSCertKey]
+	if !found || len(certData) == 0 {
+		zap.L().Info("secret has no certificate", zap.String("location", r.location()))
+		return true, nil, nil
+	}
+
+	// Load Certificate
+	cert, err := parsePEMCertificate(certData)
+	if err != nil {
+		return false, nil, err
+	}
+
+	// Check certificate expiration
+	renew := needRenewal(cert, renewBefore)
+
+	// Check certificate revocation
+	if !renew && r.ValidateRevocation {
+		renew = isRevoked(cert, r.loadChain(secret))
+	}
+
+	return renew, cert, nil
+}
+
+// Backup takes the certificate request and backs up the current version prior to overwriting
+func (r K8sSecretInstaller) Backup(ctx context.Context) error {
+	zap.L().Debug("backing up certificate", zap.String("location", r.location()))
+
+	client, err := r.getClient(ctx)
+	if err != nil {
+		return err
+	}
+
+	secret, err := client.GetSecret(ctx, r.namespace(), r.K8sSecretName)
+	if err != nil {
+		return err
+	}
+	if secret == nil {
+		zap.L().Info("new certificate location specified, no back up taken")
+		return nil
+	}
+
+	backup := k8s.Secret{
+		Metadata: k8s.ObjectMeta{
+			Name:      fmt.Sprintf("%s.bak", r.K8sSecretName),
+			Namespace: r.namespace(),
+		},
+		Type: secret.Type,
+		Data: secret.Data,
+	}
+
+	err = client.ApplySecret(ctx, backup)
+	if err != nil {
+		return err
+	}
+
+	zap.L().Info("certificate backed up", zap.String("location", r.location()),
+		zap.String("backupLocation", fmt.Sprintf("%s/%s", backup.Metadata.Namespace, backup.Metadata.Name)))
+	return nil
+}
+
+// Install takes the certificate bundle and moves it to the location specified in the installer
+func (r K8sSecretInstaller) Install(ctx context.Context, pcc certificate.PEMCollection) error {
+	zap.L().Debug("installing certificate", zap.String("location", r.location()))
+
+	if len(pcc.Certificate) == 0 || len(pcc.PrivateKey) == 0 {
+		return fmt.Errorf("certificate and Private Key are required for Kubernetes TLS Secret")
+	}
+
+	// kubernetes.io/tls Secrets require an unencrypted private key
+	privateKey, err := MarshalPKCS8PrivateKey(pcc.PrivateKey, "")
+	if err != nil {
+		zap.L().Error("could not prepare private key for Kubernetes Secret", zap.Error(err))
+		return err
+	}
+
+	chain := strings.Join(pcc.Chain, "")
+	data := map[string][]byte{
+		k8s.TLSCertKey:       []byte(pcc.Certificate + chain),
+		k8s.TLSPrivateKeyKey: []byte(privateKey),
+	}
+	if chain != "" {
+		data[k8s.CACertKey] = []byte(chain)
+	}
+
+	secret := k8s.Secret{
+		Metadata: k8s.ObjectMeta{
+			Name:      r.K8sSecretName,
+			Namespace: r.namespace(),
+			Labels:    map[string]string{"app.kubernetes.io/managed-by": "vcert"},
+		},
+		Type: k8s.SecretTypeTLS,
+		Data: data,
+	}
+
+	client, err := r.getClient(ctx)
+	if err != nil {
+		return err
+	}
+
+	err = client.ApplySecret(ctx, secret)
+	if err != nil {
+		zap.L().Error("could not write Kubernetes Secret", zap.String("location", r.location()), zap.Error(err))
+		return err
+	}
+
+	return nil
+}
+
+// Rollback restores the version of the certificate backed up by Backup, overwriting the installed one
+func (r K8sSecretInstaller) Rollback(ctx context.Context) error {
+	zap.L().Debug("rolling back certificate", zap.String("location", r.location()))
+
+	client, err := r.getClient(ctx)
+	if err != nil {
+		return err
+	}
+
+	backupName := fmt.Sprintf("%s.bak", r.K8sSecretName)
+	backup, err := client.GetSecret(ctx, r.namespace(), backupName)
+	if err != nil {
+		return err
+	}
+	if backup == nil {
+		zap.L().Info("no backup found, nothing to restore", zap.String("location", r.location()))
+		return nil
+	}
+
+	secret := k8s.Secret{
+		Metadata: k8s.ObjectMeta{
+			Name:      r.K8sSecretName,
+			Namespace: r.namespace(),
+			Labels:    map[string]string{"app.kubernetes.io/managed-by": "vcert"},
+		},
+		Type: backup.Type,
+		Data: backup.Data,
+	}
+
+	err = client.ApplySecret(ctx, secret)
+	if err != nil {
+		return err
+	}
+
+	zap.L().Info("certificate restored from backup", zap.String("location", r.location()),
+		zap.String("backupLocation", fmt.Sprintf("%s/%s", r.namespace(), backupName)))
+	return nil
+}
+
+// AfterInstallActions runs the actions declared in the Installer, in order: scripts run on a terminal,
+// while services, sites and webhooks are handled natively.
+//
+// No validations happen over the content of the AfterAction scripts, so caution is advised
+func (r K8sSecretInstaller) AfterInstallActions(ctx context.Context) (string, error) {
+	zap.L().Debug("running after-install actions", zap.String("location", r.location()))
+
+	result, err := runAfterInstallActions(ctx, r.AfterAction)
+	return result, err
+}
+
+// InstallValidationActions runs any instructions declared in the Installer on a terminal and expects
+// "0" for successful validation and "1" for a validation failure
+// No validations happen over the content of the InstallValidation string, so caution is advised
+func (r K8sSecretInstaller) InstallValidationActions(ctx context.Context) (string, error) {
+	zap.L().Debug("running install validation actions", zap.String("location", r.location()))
+
+	validationResult, err := util.ExecuteScript(ctx, r.InstallValidation)
+	if err != nil {
+		return "", err
+	}
+
+	return validationResult, err
+}
+
+func (r K8sSecretInstaller) getClient(ctx context.Context) (*k8s.Client, error) {
+	config, err := k8s.LoadConfig(ctx, r.K8sKubeconfig, r.K8sContext)
+	if err != nil {
+		zap.L().Error("could not load Kubernetes configuration", zap.Error(err))
+		return nil, err
+	}
+	client := k8s.NewClient(config)
+	return client, nil
+}
+
+func (r K8sSecretInstaller) namespace() string {
+	if r.K8sNamespace == "" {
+		return domain.DefaultK8sNamespace
+	}
+	return r.K8sNamespace
+}
+
+func (r K8sSecretInstaller) location() string {
+	return fmt.Sprintf("%s/%s", r.namespace(), r.K8sSecretName)
+}
+
+// loadChain returns the certificates stored in the secret along with the end-entity certificate.
+// Used to find the issuer when checking the certificate revocation status
+func (r K8sSecretInstaller) loadChain(secret *k8s.Secret) []*x509.Certificate {
+	chain := parsePEMCertificates(secret.Data[k8s.TLSCertKey])
+	return append(chain, parsePEMCertificates(secret.Data[k8s.CACertKey])...)
+}
@@ -0,0 +1,200 @@
+/*
+ * Copyright 2023 Venafi, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *  http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package installer
+
+import (
+	"bytes"
+	"crypto"
+	"crypto/x509"
+	"encoding/binary"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/pavel-v-chernykh/keystore-go/v4"
+	"software.sslmate.com/src/go-pkcs12"
+
+	"github.com/Venafi/vcert/v5/pkg/util"
+)
+
+const (
+	// FormatPEM is the format of the certificates loaded from PEM data
+	FormatPEM = "PEM"
+	// FormatDER is the format of a single certificate loaded from DER data
+	FormatDER = "DER"
+	// FormatPKCS12 is the format of the certificates loaded from a PKCS#12 bundle
+	FormatPKCS12 = "PKCS#12"
+	// FormatJKS is the format of the certificates loaded from a Java KeyStore
+	FormatJKS = "JKS"
+
+	// jksMagic are the first bytes of every Java KeyStore
+	jksMagic = 0xFEEDFEED
+)
+
+// errUnreadablePrivateKey is returned along with the certificate of a JKS entry whose private key cannot be parsed
+var errUnreadablePrivateKey = errors.New("could not parse private key")
+
+// LoadedCertificate is a certificate read from PEM, DER, PKCS#12 or JKS data, with the chain and the private key
+// found alongside it
+type LoadedCertificate struct {
+	Format      string
+	Certificate *x509.Certificate
+	Chain       []*x509.Certificate
+	// PrivateKey is nil when the data has no private key
+	PrivateKey interface{}
+}
+
+// LoadCertificate detects the format of data and reads the certificate, its chain and its private key from it, as the
+// installers do with the files they install. jksAlias selects the entry of a Java KeyStore, the first private key entry
+// being used when empty. The PKCS#12 bundles and the Java KeyStores are opened with storePassword, and the private keys
+// decrypted with keyPassword, or storePassword when empty for the JKS entries
+func LoadCertificate(data []byte, jksAlias string, storePassword string, keyPassword string) (*LoadedCertificate, error) {
+	if block, _ := pem.Decode(data); block != nil {
+		return decodePEM(data, keyPassword)
+	}
+	if len(data) >= 4 && binary.BigEndian.Uint32(data) == jksMagic {
+		return decodeJKS(data, jksAlias, storePassword, keyPassword)
+	}
+	if cert, err := x509.ParseCertificate(data); err == nil {
+		return &LoadedCertificate{Format: FormatDER, Certificate: cert}, nil
+	}
+
+	lc, err := decodePKCS12(data, storePassword)
+	if err != nil {
+		return nil, fmt.Errorf("unrecognized certificate format or wrong password: %w", err)
+	}
+	return lc, nil
+}
+
+// PrivateKeyMatches returns true when privateKey is the private key of cert
+func PrivateKeyMatches(cert *x509.Certificate, privateKey interface{}) bool {
+	signer, ok := privateKey.(crypto.Signer)
+	if !ok {
+		return false
+	}
+	publicKey, ok := signer.Public().(interface{ Equal(crypto.PublicKey) bool })
+	return ok && publicKey.Equal(cert.PublicKey)
+}
+
+// decodePEM returns the certificates and the last private key of the PEM data. The end-entity certificate is the first
+// one that is not a CA, so chains written with the root first are read as well
+func decodePEM(data []byte, keyPassword string) (*LoadedCertificate, error) {
+	lc := &LoadedCertificate{Format: FormatPEM}
+	var certs []*x509.Certificate
+	for block, rest := pem.Decode(data); block != nil; block, rest = pem.Decode(rest) {
+		switch {
+		case block.Type == "CERTIFICATE":
+			cert, err := x509.ParseCertificate(block.Bytes)
+			if err != nil {
+				return nil, fmt.Errorf("could not parse certificate: %w", err)
+			}
+			certs = append(certs, cert)
+		case strings.HasSuffix(block.Type, "PRIVATE KEY"):
+			key, err := util.ParsePrivateKeyBlock(block, keyPassword)
+			if err != nil {
+				return nil, err
+			}
+			lc.PrivateKey = key
+		}
+	}
+	if len(certs) == 0 {
+		return nil, fmt.Errorf("no certificate found in PEM data")
+	}
+
+	leaf := 0
+	for i, cert := range certs {
+		if !cert.IsCA {
+			leaf = i
+			break
+		}
+	}
+	lc.Certificate = certs[leaf]
+	lc.Chain = append(certs[:leaf:leaf], certs[leaf+1:]...)
+	return lc, nil
+}
+
+// decodePKCS12 returns the certificate, chain and private key of the PKCS#12 bundle
+func decodePKCS12(data []byte, password string) (*LoadedCertificate, error) {
+	privateKey, cert, chain, err := pkcs12.DecodeChain(data, password)
+	if err != nil {
+		return nil, err
+	}
+	return &LoadedCertificate{Format: FormatPKCS12, Certificate: cert, Chain: chain, PrivateKey: privateKey}, nil
+}
+
+// decodeJKS returns the certificate, chain and private key of the entry jksAlias of the Java KeyStore. When the
+// private key cannot be parsed, the certificate is returned along with errUnreadablePrivateKey
+func decodeJKS(data []byte, jksAlias string, storePassword string, keyPassword string) (*LoadedCertificate, error) {
+	ks := keystore.New()
+	err := ks.Load(bytes.NewReader(data), []byte(storePassword))
+	if err != nil {
+		return nil, fmt.Errorf("could not load JKS: %w", err)
+	}
+
+	alias := jksAlias
+	if alias == "" {
+		for _, a := range ks.Aliases() {
+			if ks.IsPrivateKeyEntry(a) {
+				alias = a
+				break
+			}
+		}
+	}
+
+	if ks.IsTrustedCertificateEntry(alias) {
+		entry, err := ks.GetTrustedCertificateEntry(alias)
+		if err != nil {
+			return nil, err
+		}
+		cert, err := x509.ParseCertificate(entry.Certificate.Content)
+		if err != nil {
+			return nil, fmt.Errorf("could not parse certificate: %w", err)
+		}
+		return &LoadedCertificate{Format: FormatJKS, Certificate: cert}, nil
+	}
+
+	if keyPassword == "" {
+		keyPassword = storePassword
+	}
+	entry, err := ks.GetPrivateKeyEntry(alias, []byte(keyPassword))
+	if err != nil {
+		return nil, fmt.Errorf("could not retrieve entry %q from JKS: %w", alias, err)
+	}
+	if len(entry.CertificateChain) == 0 {
+		return nil, fmt.Errorf("entry %q of JKS has no certificate", alias)
+	}
+
+	lc := &LoadedCertificate{Format: FormatJKS}
+	for i, c := range entry.CertificateChain {
+		cert, err := x509.ParseCertificate(c.Content)
+		if err != nil {
+			return nil, fmt.Errorf("could not parse certificate: %w", err)
+		}
+		if i == 0 {
+			lc.Certificate = cert
+		} else {
+			lc.Chain = append(lc.Chain, cert)
+		}
+	}
+	lc.PrivateKey, err = x509.ParsePKCS8PrivateKey(entry.PrivateKey)
+	if err != nil {
+		lc.PrivateKey = nil
+		return lc, fmt.Errorf("%w of entry %q: %s", errUnreadablePrivateKey, alias, err)
+	}
+	return lc, nil
+}
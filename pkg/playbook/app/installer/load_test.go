@@ -0,0 +1,125 @@
+/*
+ * Copyright 2023 Venafi, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *  http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package installer
+
+import (
+	"bytes"
+	"encoding/pem"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/pavel-v-chernykh/keystore-go/v4"
+)
+
+func TestLoadCertificate(t *testing.T) {
+	pcc := newTestPEMCollection(t)
+	certBlock, _ := pem.Decode([]byte(pcc.Certificate))
+
+	p12, err := PackageAsPKCS12(pcc, "secret", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	jks, err := PackageAsJKS(pcc, "keypass", "vcert", "secret")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ks := keystore.New()
+	err = ks.SetTrustedCertificateEntry("trusted", keystore.TrustedCertificateEntry{
+		CreationTime: time.Now(),
+		Certificate:  keystore.Certificate{Type: "X509", Content: certBlock.Bytes},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	buffer := new(bytes.Buffer)
+	err = ks.Store(buffer, []byte("secret"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	trusted := buffer.Bytes()
+
+	tests := []struct {
+		name         string
+		data         []byte
+		jksAlias     string
+		keyPassword  string
+		expectFormat string
+		expectChain  int
+		expectKey    bool
+		expectErr    bool
+	}{
+		// The root is written first, the end-entity certificate is still found
+		{name: "PEM", data: []byte(pcc.Chain[0] + pcc.Certificate + pcc.PrivateKey), expectFormat: FormatPEM, expectChain: 1, expectKey: true},
+		{name: "DER", data: certBlock.Bytes, expectFormat: FormatDER},
+		{name: "PKCS#12", data: p12, expectFormat: FormatPKCS12, expectChain: 1, expectKey: true},
+		{name: "JKS default alias", data: jks, keyPassword: "keypass", expectFormat: FormatJKS, expectChain: 1, expectKey: true},
+		{name: "JKS trusted certificate", data: trusted, jksAlias: "trusted", expectFormat: FormatJKS},
+		{name: "JKS wrong key password", data: jks, expectErr: true},
+		{name: "unrecognized", data: []byte("not a certificate"), expectErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			lc, err := LoadCertificate(tt.data, tt.jksAlias, "secret", tt.keyPassword)
+			if tt.expectErr {
+				if err == nil {
+					t.Fatal("expected an error")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %s", err)
+			}
+			if lc.Format != tt.expectFormat || lc.Certificate.Subject.CommonName != "leaf.example.com" || len(lc.Chain) != tt.expectChain {
+				t.Fatalf("unexpected certificate %s in %s format, with %d chain certificates", lc.Certificate.Subject, lc.Format, len(lc.Chain))
+			}
+			if tt.expectKey != (lc.PrivateKey != nil) || tt.expectKey && !PrivateKeyMatches(lc.Certificate, lc.PrivateKey) {
+				t.Fatalf("unexpected private key %T", lc.PrivateKey)
+			}
+		})
+	}
+}
+
+func TestLoadCertificateUnreadableJKSKey(t *testing.T) {
+	pcc := newTestPEMCollection(t)
+	certBlock, _ := pem.Decode([]byte(pcc.Certificate))
+
+	ks := keystore.New()
+	err := ks.SetPrivateKeyEntry("vcert", keystore.PrivateKeyEntry{
+		CreationTime:     time.Now(),
+		PrivateKey:       []byte("not a PKCS#8 private key"),
+		CertificateChain: []keystore.Certificate{{Type: "X509", Content: certBlock.Bytes}},
+	}, []byte("secret"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	buffer := new(bytes.Buffer)
+	err = ks.Store(buffer, []byte("secret"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	lc, err := LoadCertificate(buffer.Bytes(), "", "secret", "")
+	if !errors.Is(err, errUnreadablePrivateKey) {
+		t.Fatalf("expected an unreadable private key, got %v", err)
+	}
+	if lc == nil || lc.Certificate == nil || lc.PrivateKey != nil {
+		t.Fatal("expected the certificate without its private key")
+	}
+}
@@ -0,0 +1,197 @@
+/*
+ * Copyright 2023 Venafi, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *  http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package installer
+
+import (
+	"context"
+	"crypto"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"os"
+
+	"go.uber.org/zap"
+
+	"github.com/Venafi/vcert/v5/pkg/certificate"
+	"github.com/Venafi/vcert/v5/pkg/playbook/app/domain"
+)
+
+// MirroredInstaller represents a file installation that is also written to each of its mirror directories, under the
+// same names. Either every location is updated or none is: when writing to a location fails, the locations already
+// written are restored to their previous content
+type MirroredInstaller struct {
+	domain.Installation
+	// locations holds the installer of the installation itself, followed by the installers of its mirrors
+	locations []Installer
+}
+
+// NewMirroredInstaller returns a new installer writing the files of inst to the locations of inst and its mirrors
+func NewMirroredInstaller(inst domain.Installation) MirroredInstaller {
+	primary := inst
+	primary.Mirrors = nil
+	locations := []Installer{GetInstaller(primary)}
+	for _, dir := range inst.Mirrors {
+		locations = append(locations, GetInstaller(inst.Mirror(dir)))
+	}
+	return MirroredInstaller{Installation: inst, locations: locations}
+}
+
+// Check is the method in charge of making the validations to install a new certificate:
+// 1. Does the certificate exists? > Install if it doesn't.
+// 2. Does the certificate is about to expire? Renew if about to expire.
+// Returns true if the certificate needs to be installed, along with the certificate currently installed, if any.
+// The certificate is installed again when any of the locations needs it
+func (r MirroredInstaller) Check(ctx context.Context, renewBefore string, request domain.PlaybookRequest) (bool, *x509.Certificate, error) {
+	install, cert, err := r.locations[0].Check(ctx, renewBefore, request)
+	if err != nil || install {
+		return install, cert, err
+	}
+	for i, location := range r.locations[1:] {
+		mirrorInstall, _, err := location.Check(ctx, renewBefore, request)
+		if err != nil {
+			return false, nil, fmt.Errorf("mirror %s: %w", r.Mirrors[i], err)
+		}
+		if mirrorInstall {
+			zap.L().Info("certificate needs to be installed on mirror", zap.String("mirror", r.Mirrors[i]))
+			return true, cert, nil
+		}
+	}
+	return false, cert, nil
+}
+
+// Backup takes the certificate request and backs up the current version prior to overwriting, on every location
+func (r MirroredInstaller) Backup(ctx context.Context) error {
+	for _, location := range r.locations {
+		err := location.Backup(ctx)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Install takes the certificate bundle and moves it to the locations specified in the installer. When a location
+// fails, the content the locations had before the installation is put back
+func (r MirroredInstaller) Install(ctx context.Context, pcc certificate.PEMCollection) error {
+	installations := []domain.Installation{r.Installation}
+	for _, dir := range r.Mirrors {
+		installations = append(installations, r.Mirror(dir))
+	}
+	snapshots := make([]fileSnapshot, 0)
+	for _, installation := range installations {
+		for _, file := range installation.GetFiles() {
+			snapshot, err := takeFileSnapshot(file)
+			if err != nil {
+				return fmt.Errorf("could not read %s before installing the certificate: %w", file, err)
+			}
+			snapshots = append(snapshots, snapshot)
+		}
+	}
+
+	for i, location := range r.locations {
+		err := location.Install(ctx, pcc)
+		if err == nil {
+			continue
+		}
+		if i > 0 {
+			err = fmt.Errorf("mirror %s: %w", r.Mirrors[i-1], err)
+		}
+		zap.L().Error("could not install certificate on every location, restoring previous content", zap.Error(err))
+		for _, snapshot := range snapshots {
+			err = errors.Join(err, snapshot.restore())
+		}
+		return err
+	}
+	return nil
+}
+
+// Rollback restores the version of the certificate backed up by Backup on every location, overwriting the installed one
+func (r MirroredInstaller) Rollback(ctx context.Context) error {
+	var err error
+	for _, location := range r.locations {
+		err = errors.Join(err, location.Rollback(ctx))
+	}
+	return err
+}
+
+// AfterInstallActions runs the actions declared in the Installer, in order: scripts run on a terminal,
+// while services, sites and webhooks are handled natively. They run once, for every location.
+//
+// No validations happen over the content of the AfterAction scripts, so caution is advised
+func (r MirroredInstaller) AfterInstallActions(ctx context.Context) (string, error) {
+	return r.locations[0].AfterInstallActions(ctx)
+}
+
+// InstallValidationActions runs any instructions declared in the Installer on a terminal and expects
+// "0" for successful validation and "1" for a validation failure
+// No validations happen over the content of the InstallValidation string, so caution is advised
+func (r MirroredInstaller) InstallValidationActions(ctx context.Context) (string, error) {
+	return r.locations[0].InstallValidationActions(ctx)
+}
+
+// PrivateKey returns the private key installed on the location of the installation itself, or nil when nothing is
+// installed
+func (r MirroredInstaller) PrivateKey(ctx context.Context) (crypto.Signer, error) {
+	reader, ok := r.locations[0].(KeyReader)
+	if !ok {
+		return nil, nil
+	}
+	return reader.PrivateKey(ctx)
+}
+
+// fileSnapshot is the content of a file before an installation, used to put it back when the installation fails
+type fileSnapshot struct {
+	location string
+	exists   bool
+	content  []byte
+	mode     os.FileMode
+}
+
+// takeFileSnapshot returns the current content of the file at location, which may not exist
+func takeFileSnapshot(location string) (fileSnapshot, error) {
+	snapshot := fileSnapshot{location: location}
+	info, err := os.Stat(location)
+	if errors.Is(err, os.ErrNotExist) {
+		return snapshot, nil
+	} else if err != nil {
+		return snapshot, err
+	}
+	snapshot.content, err = os.ReadFile(location)
+	if err != nil {
+		return snapshot, err
+	}
+	snapshot.exists = true
+	snapshot.mode = info.Mode().Perm()
+	return snapshot, nil
+}
+
+// restore puts back the content of the file, removing it when it did not exist
+func (s fileSnapshot) restore() error {
+	if !s.exists {
+		err := os.Remove(s.location)
+		if err != nil && !errors.Is(err, os.ErrNotExist) {
+			return fmt.Errorf("could not remove %s: %w", s.location, err)
+		}
+		return nil
+	}
+	err := os.WriteFile(s.location, s.content, s.mode)
+	if err != nil {
+		return fmt.Errorf("could not restore %s: %w", s.location, err)
+	}
+	zap.L().Info("file restored to its content before the installation", zap.String("location", s.location))
+	return nil
+}
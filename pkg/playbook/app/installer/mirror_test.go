@@ -0,0 +1,152 @@
+/*
+ * Copyright 2023 Venafi, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *  http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package installer
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/x509"
+	"encoding/pem"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/Venafi/vcert/v5/pkg/certificate"
+	"github.com/Venafi/vcert/v5/pkg/playbook/app/domain"
+	"github.com/Venafi/vcert/v5/test/testcert"
+)
+
+func writeTestFile(t *testing.T, location string, content string) {
+	t.Helper()
+	err := os.WriteFile(location, []byte(content), 0600)
+	if err != nil {
+		t.Fatal(err)
+	}
+}
+
+func readTestFile(t *testing.T, location string) string {
+	t.Helper()
+	data, err := os.ReadFile(location)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return string(data)
+}
+
+// newTestPEMCollection returns the collection of a leaf certificate issued by a root, with its private key
+func newTestPEMCollection(t *testing.T) certificate.PEMCollection {
+	t.Helper()
+	root := testcert.Issue(testcert.Template("Test Root", true), nil, nil)
+	leaf := testcert.Issue(testcert.Template("leaf.example.com", false), nil, &root)
+	der, err := x509.MarshalECPrivateKey(leaf.Key.(*ecdsa.PrivateKey))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return certificate.PEMCollection{
+		Certificate: leaf.PEM(),
+		PrivateKey:  string(pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: der})),
+		Chain:       []string{root.PEM()},
+	}
+}
+
+func newTestMirroredInstallation(dir string, mirrors ...string) domain.Installation {
+	return domain.Installation{
+		Type:      domain.FormatPEM,
+		File:      filepath.Join(dir, "cert.pem"),
+		KeyFile:   filepath.Join(dir, "key.pem"),
+		ChainFile: filepath.Join(dir, "chain.pem"),
+		Mirrors:   mirrors,
+	}
+}
+
+func TestMirroredInstall(t *testing.T) {
+	dir := t.TempDir()
+	primary, mirror := filepath.Join(dir, "primary"), filepath.Join(dir, "mirror")
+	inst := NewMirroredInstaller(newTestMirroredInstallation(primary, mirror))
+	pcc := newTestPEMCollection(t)
+
+	install, _, err := inst.Check(context.Background(), "10%", domain.PlaybookRequest{})
+	if err != nil || !install {
+		t.Fatalf("expected missing files to be installed but got %t, %v", install, err)
+	}
+
+	err = inst.Install(context.Background(), pcc)
+	if err != nil {
+		t.Fatalf("failed to install certificate: %s", err)
+	}
+	for _, location := range []string{primary, mirror} {
+		if readTestFile(t, filepath.Join(location, "cert.pem")) != pcc.Certificate ||
+			readTestFile(t, filepath.Join(location, "key.pem")) != pcc.PrivateKey ||
+			readTestFile(t, filepath.Join(location, "chain.pem")) != pcc.Chain[0] {
+			t.Fatalf("certificate not installed in %s", location)
+		}
+	}
+
+	install, _, err = inst.Check(context.Background(), "10%", domain.PlaybookRequest{})
+	if err != nil || install {
+		t.Fatalf("expected installed certificate to be kept but got %t, %v", install, err)
+	}
+
+	// A mirror missing its files gets the certificate installed again
+	err = os.Remove(filepath.Join(mirror, "cert.pem"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	install, _, err = inst.Check(context.Background(), "10%", domain.PlaybookRequest{})
+	if err != nil || !install {
+		t.Fatalf("expected certificate missing on the mirror to be installed but got %t, %v", install, err)
+	}
+}
+
+func TestMirroredInstallRestoresOnPartialFailure(t *testing.T) {
+	dir := t.TempDir()
+	primary, mirror, broken := filepath.Join(dir, "primary"), filepath.Join(dir, "mirror"), filepath.Join(dir, "broken")
+	for _, location := range []string{primary, mirror, broken} {
+		err := os.Mkdir(location, 0700)
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	// The primary location has a certificate and chain installed, without a key file. The mirrors are empty
+	writeTestFile(t, filepath.Join(primary, "cert.pem"), "previous certificate")
+	writeTestFile(t, filepath.Join(primary, "chain.pem"), "previous chain")
+	// The chain file of the last mirror cannot be written, since it links to a directory that does not exist.
+	// It is the last file written, so the other files of the mirror are written before the failure
+	err := os.Symlink(filepath.Join(dir, "missing", "chain.pem"), filepath.Join(broken, "chain.pem"))
+	if err != nil {
+		t.Skipf("cannot create symbolic link: %s", err)
+	}
+
+	inst := NewMirroredInstaller(newTestMirroredInstallation(primary, mirror, broken))
+	err = inst.Install(context.Background(), newTestPEMCollection(t))
+	if err == nil || !strings.Contains(err.Error(), "mirror "+broken) {
+		t.Fatalf("expected installation on the broken mirror to fail but got %v", err)
+	}
+
+	if readTestFile(t, filepath.Join(primary, "cert.pem")) != "previous certificate" ||
+		readTestFile(t, filepath.Join(primary, "chain.pem")) != "previous chain" {
+		t.Fatalf("previous content of the primary location not restored")
+	}
+	for _, location := range []string{filepath.Join(primary, "key.pem"), filepath.Join(mirror, "cert.pem"), filepath.Join(mirror, "key.pem"),
+		filepath.Join(mirror, "chain.pem"), filepath.Join(broken, "cert.pem"), filepath.Join(broken, "key.pem")} {
+		if _, err := os.Stat(location); !errors.Is(err, os.ErrNotExist) {
+			t.Fatalf("file %s written by the failed installation not removed", location)
+		}
+	}
+}
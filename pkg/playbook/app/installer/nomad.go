@@ -0,0 +1,277 @@
+/*
+ * Copyright 2023 Venafi, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *  http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package installer
+
+import (
+	"context"
+	"crypto/x509"
+	"fmt"
+	"strings"
+
+	"go.uber.org/zap"
+
+	"github.com/Venafi/vcert/v5/pkg/certificate"
+	"github.com/Venafi/vcert/v5/pkg/playbook/app/domain"
+	"github.com/Venafi/vcert/v5/pkg/playbook/util"
+	"github.com/Venafi/vcert/v5/pkg/playbook/util/nomad"
+)
+
+const (
+	nomadCertItem  = "certificate"
+	nomadKeyItem   = "private_key"
+	nomadChainItem = "chain"
+
+	// nomadBackupSuffix is appended to the path of the variable to get the path of its backup
+	nomadBackupSuffix = "-vcert-backup"
+)
+
+// NomadVariableInstaller represents an installation that will write the certificate bundle in a HashiCorp Nomad
+// variable, to be rendered in the tasks by their template blocks
+type NomadVariableInstaller struct {
+	domain.Installation
+}
+
+// NewNomadVariableInstaller returns a new installer of type NOMADVARIABLE with the values defined in inst
+func NewNomadVariableInstaller(inst domain.Installation) NomadVariableInstaller {
+	return NomadVariableInstaller{inst}
+}
+
+// Check is the method in charge of making the validations to install a new certificate:
+// 1. Does the certificate exists? > Install if it doesn't.
+// 2. Does the certificate is about to expire? Renew if about to expire.
+// Returns true if the certificate needs to be installed, along with the certificate currently installed, if any.
+func (r NomadVariableInstaller) Check(ctx context.Context, renewBefore string, _ domain.PlaybookRequest) (bool, *x509.Certificate, error) {
+	zap.L().Info("checking certificate health", zap.String("format", r.Type.String()), zap.String("location", r.location()))
+
+	client, err := r.getClient(ctx)
+	if err != nil {
+		return false, nil, err
+	}
+
+	variable, err := client.GetVariable(ctx, r.NomadPath)
+	if err != nil {
+		return false, nil, err
+	}
+	if variable == nil {
+		zap.L().Debug("variable does not exist", zap.String("location", r.location()))
+		return true, nil, nil
+	}
+	if variable.Items[nomadCertItem] == "" {
+		zap.L().Info("variable has no certificate", zap.String("location", r.location()), zap.String("item", nomadCertItem))
+		return true, nil, nil
+	}
+
+	// Load Certificate
+	cert, err := parsePEMCertificate([]byte(variable.Items[nomadCertItem]))
+	if err != nil {
+		return false, nil, err
+	}
+
+	// Check certificate expiration
+	renew := needRenewal(cert, renewBefore)
+
+	// Check certificate revocation
+	if !renew && r.ValidateRevocation {
+		renew = isRevoked(cert, parsePEMCertificates([]byte(variable.Items[nomadChainItem])))
+	}
+
+	return renew, cert, nil
+}
+
+// Backup copies the current variable to the same path with the -vcert-backup suffix.
+// Nomad does not keep previous versions of the variables
+func (r NomadVariableInstaller) Backup(ctx context.Context) error {
+	zap.L().Debug("backing up certificate", zap.String("location", r.location()))
+
+	client, err := r.getClient(ctx)
+	if err != nil {
+		return err
+	}
+
+	variable, err := client.GetVariable(ctx, r.NomadPath)
+	if err != nil {
+		return err
+	}
+	if variable == nil {
+		zap.L().Info("variable does not exist, no back up taken", zap.String("location", r.location()))
+		return nil
+	}
+
+	backup, err := client.GetVariable(ctx, r.backupPath())
+	if err != nil {
+		return err
+	}
+	var modifyIndex uint64
+	if backup != nil {
+		modifyIndex = backup.ModifyIndex
+	}
+	_, err = client.PutVariable(ctx, r.backupPath(), variable.Items, modifyIndex)
+	if err != nil {
+		return err
+	}
+
+	zap.L().Info("certificate backed up", zap.String("location", r.location()), zap.String("backupPath", r.backupPath()))
+	return nil
+}
+
+// Install takes the certificate bundle and moves it to the location specified in the installer.
+//
+// Items of the variable other than the certificate, private_key and chain items are kept
+func (r NomadVariableInstaller) Install(ctx context.Context, pcc certificate.PEMCollection) error {
+	zap.L().Debug("installing certificate", zap.String("location", r.location()))
+
+	if len(pcc.Certificate) == 0 || len(pcc.PrivateKey) == 0 {
+		return fmt.Errorf("certificate and Private Key are required for HashiCorp Nomad")
+	}
+
+	privateKey := pcc.PrivateKey
+	var err error
+	if strings.ToLower(r.KeyFormat) == domain.KeyFormatPKCS8 {
+		privateKey, err = MarshalPKCS8PrivateKey(pcc.PrivateKey, "")
+		if err != nil {
+			zap.L().Error("failed to prepare PrivateKey in PKCS8 format", zap.Error(err))
+			return err
+		}
+	}
+
+	client, err := r.getClient(ctx)
+	if err != nil {
+		return err
+	}
+
+	items := make(map[string]string)
+	var modifyIndex uint64
+	current, err := client.GetVariable(ctx, r.NomadPath)
+	if err != nil {
+		return err
+	}
+	if current != nil {
+		for k, v := range current.Items {
+			items[k] = v
+		}
+		modifyIndex = current.ModifyIndex
+	}
+	items[nomadCertItem] = pcc.Certificate
+	items[nomadKeyItem] = privateKey
+	items[nomadChainItem] = strings.Join(pcc.Chain, "")
+
+	_, err = client.PutVariable(ctx, r.NomadPath, items, modifyIndex)
+	if err != nil {
+		zap.L().Error("could not write certificate to HashiCorp Nomad", zap.String("location", r.location()), zap.Error(err))
+		return err
+	}
+
+	zap.L().Debug("certificate written", zap.String("location", r.location()))
+	return nil
+}
+
+// Rollback writes the backup taken by Backup back to the variable and removes it.
+// Nothing is restored when no backup exists
+func (r NomadVariableInstaller) Rollback(ctx context.Context) error {
+	zap.L().Debug("rolling back certificate", zap.String("location", r.location()))
+
+	client, err := r.getClient(ctx)
+	if err != nil {
+		return err
+	}
+
+	backup, err := client.GetVariable(ctx, r.backupPath())
+	if err != nil {
+		return err
+	}
+	if backup == nil {
+		zap.L().Info("no backup found, nothing to restore", zap.String("location", r.location()))
+		return nil
+	}
+
+	current, err := client.GetVariable(ctx, r.NomadPath)
+	if err != nil {
+		return err
+	}
+	var modifyIndex uint64
+	if current != nil {
+		modifyIndex = current.ModifyIndex
+	}
+	_, err = client.PutVariable(ctx, r.NomadPath, backup.Items, modifyIndex)
+	if err != nil {
+		return err
+	}
+
+	err = client.DeleteVariable(ctx, r.backupPath())
+	if err != nil {
+		zap.L().Warn("could not remove backup variable", zap.String("backupPath", r.backupPath()), zap.Error(err))
+	}
+
+	zap.L().Info("certificate restored from backup", zap.String("location", r.location()))
+	return nil
+}
+
+// AfterInstallActions runs the actions declared in the Installer, in order: scripts run on a terminal,
+// while services, sites and webhooks are handled natively.
+//
+// No validations happen over the content of the AfterAction scripts, so caution is advised
+func (r NomadVariableInstaller) AfterInstallActions(ctx context.Context) (string, error) {
+	zap.L().Debug("running after-install actions", zap.String("location", r.location()))
+
+	result, err := runAfterInstallActions(ctx, r.AfterAction)
+	return result, err
+}
+
+// InstallValidationActions runs any instructions declared in the Installer on a terminal and expects
+// "0" for successful validation and "1" for a validation failure
+// No validations happen over the content of the InstallValidation string, so caution is advised
+func (r NomadVariableInstaller) InstallValidationActions(ctx context.Context) (string, error) {
+	zap.L().Debug("running install validation actions", zap.String("location", r.location()))
+
+	validationResult, err := util.ExecuteScript(ctx, r.InstallValidation)
+	if err != nil {
+		return "", err
+	}
+
+	return validationResult, err
+}
+
+func (r NomadVariableInstaller) getClient(ctx context.Context) (*nomad.Client, error) {
+	client, err := nomad.NewClient(r.address(), r.NomadToken, r.namespace(), r.NomadCACert)
+	if err != nil {
+		zap.L().Error("could not connect to HashiCorp Nomad", zap.Error(err))
+		return nil, err
+	}
+	return client, nil
+}
+
+func (r NomadVariableInstaller) address() string {
+	if r.NomadAddress == "" {
+		return domain.DefaultNomadAddress
+	}
+	return r.NomadAddress
+}
+
+func (r NomadVariableInstaller) namespace() string {
+	if r.NomadNamespace == "" {
+		return domain.DefaultNomadNamespace
+	}
+	return r.NomadNamespace
+}
+
+func (r NomadVariableInstaller) backupPath() string {
+	return strings.TrimSuffix(r.NomadPath, "/") + nomadBackupSuffix
+}
+
+func (r NomadVariableInstaller) location() string {
+	return fmt.Sprintf("%s/%s/%s", strings.TrimSuffix(r.address(), "/"), r.namespace(), strings.Trim(r.NomadPath, "/"))
+}
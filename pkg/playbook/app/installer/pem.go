@@ -17,7 +17,12 @@
 package installer
 
 import (
+	"context"
+	"crypto"
+	"crypto/x509"
+	"encoding/pem"
 	"fmt"
+	"os"
 	"strings"
 
 	"go.uber.org/zap"
@@ -41,33 +46,43 @@ func NewPEMInstaller(inst domain.Installation) PEMInstaller {
 // Check is the method in charge of making the validations to install a new certificate:
 // 1. Does the certificate exists? > Install if it doesn't.
 // 2. Does the certificate is about to expire? Renew if about to expire.
-// Returns true if the certificate needs to be installed.
-func (r PEMInstaller) Check(renewBefore string, _ domain.PlaybookRequest) (bool, error) {
+// Returns true if the certificate needs to be installed, along with the certificate currently installed, if any.
+func (r PEMInstaller) Check(_ context.Context, renewBefore string, _ domain.PlaybookRequest) (bool, *x509.Certificate, error) {
 	zap.L().Info("checking certificate health", zap.String("format", r.Type.String()), zap.String("location", r.File))
 
 	// Check certificate bundle file exists
 	certExists, err := util.FileExists(r.File)
 	if err != nil {
-		return false, err
+		return false, nil, err
 	}
 	if !certExists {
-		return true, nil
+		return true, nil, nil
 	}
 
 	// Load Certificate
 	cert, err := loadPEMCertificate(r.File)
 	if err != nil {
-		return false, err
+		return false, nil, err
+	}
+
+	// Check the private key belongs to the certificate
+	if r.keyMismatch(cert) {
+		return true, cert, nil
 	}
 
 	// Check certificate expiration
 	renew := needRenewal(cert, renewBefore)
 
-	return renew, nil
+	// Check certificate revocation
+	if !renew && r.ValidateRevocation {
+		renew = isRevoked(cert, r.loadChain())
+	}
+
+	return renew, cert, nil
 }
 
 // Backup takes the certificate request and backs up the current version prior to overwriting
-func (r PEMInstaller) Backup() error {
+func (r PEMInstaller) Backup(_ context.Context) error {
 	zap.L().Debug("backing up certificate", zap.String("location", r.File))
 
 	// Check certificate file exists
@@ -82,44 +97,48 @@ func (r PEMInstaller) Backup() error {
 		return nil
 	}
 
-	resources := []struct {
-		oldLocation string
-		newLocation string
-	}{
-		{oldLocation: r.File, newLocation: fmt.Sprintf("%s.bak", r.File)},
-		{oldLocation: r.KeyFile, newLocation: fmt.Sprintf("%s.bak", r.KeyFile)},
-		{oldLocation: r.ChainFile, newLocation: fmt.Sprintf("%s.bak", r.ChainFile)},
-	}
-
-	for _, resource := range resources {
-		fileExists, err := util.FileExists(resource.oldLocation)
+	timestamp := newBackupTimestamp()
+	for _, location := range []string{r.File, r.KeyFile, r.ChainFile} {
+		if location == "" {
+			continue
+		}
+		fileExists, err := util.FileExists(location)
 		if err != nil {
 			return err
 		} else if !fileExists {
-			zap.L().Info(fmt.Sprintf("file %s does not exist, no backup taken", resource.oldLocation))
+			zap.L().Info(fmt.Sprintf("file %s does not exist, no backup taken", location))
 			continue
 		}
-		err = util.CopyFile(resource.oldLocation, resource.newLocation)
+		backupLocation, err := backupFile(location, timestamp, r.GetBackupRetention())
 		if err != nil {
 			return err
 		}
-		zap.L().Info("certificate resource backed up", zap.String("location", resource.oldLocation),
-			zap.String("backupLocation", resource.newLocation))
+		zap.L().Info("certificate resource backed up", zap.String("location", location),
+			zap.String("backupLocation", backupLocation))
 	}
 
 	return nil
 }
 
 // Install takes the certificate bundle and moves it to the location specified in the installer
-func (r PEMInstaller) Install(pcc certificate.PEMCollection) error {
+func (r PEMInstaller) Install(_ context.Context, pcc certificate.PEMCollection) error {
 	zap.L().Debug("installing certificate", zap.String("location", r.File))
 
-	//TODO: should we add support for PEM bundle?
-
 	preppedPK := pcc.PrivateKey
 	var err error
-	// Needs to be encrypted again using legacy PEM
-	if r.KeyPassword != "" {
+	if pcc.PrivateKey != "" && strings.ToLower(r.KeyFormat) == domain.KeyFormatPKCS8 {
+		// Encrypted using AES-256 when a password is provided
+		preppedPK, err = MarshalPKCS8PrivateKey(pcc.PrivateKey, r.KeyPassword)
+		if err != nil {
+			zap.L().Error("failed to prepare PrivateKey in PKCS8 format", zap.Error(err))
+			return err
+		}
+	} else if pcc.PrivateKey != "" && r.KeyPassword != "" {
+		// Needs to be encrypted again using legacy PEM, which only exists for PKCS#1 and SEC 1 keys
+		if block, _ := pem.Decode([]byte(pcc.PrivateKey)); block == nil || block.Type == "PRIVATE KEY" {
+			return fmt.Errorf("legacy PEM encryption is only supported for RSA and EC private keys. Set keyFormat to %s",
+				domain.KeyFormatPKCS8)
+		}
 		preppedPK, err = vcertutil.EncryptPrivateKeyPKCS1(pcc.PrivateKey, r.KeyPassword)
 		if err != nil {
 			zap.L().Error("failed to encrypt PrivateKey", zap.Error(err))
@@ -127,45 +146,75 @@ func (r PEMInstaller) Install(pcc certificate.PEMCollection) error {
 		}
 	}
 
+	chain := joinPEM(OrderChain(pcc.Certificate, pcc.Chain, r.ChainOrder, r.ExcludeRoot)...)
+	certContent := pcc.Certificate
+	switch strings.ToLower(r.PEMBundle) {
+	case domain.PEMBundleCertChain:
+		certContent = joinPEM(pcc.Certificate, chain)
+	case domain.PEMBundleCertKeyChain:
+		certContent = joinPEM(pcc.Certificate, preppedPK, chain)
+	}
+
 	resources := []struct {
 		path    string
 		content []byte
 	}{
-		{path: r.File, content: []byte(pcc.Certificate)},
+		{path: r.File, content: []byte(certContent)},
 		{path: r.KeyFile, content: []byte(preppedPK)},
-		{path: r.ChainFile, content: []byte(strings.Join(pcc.Chain, ""))},
+		{path: r.ChainFile, content: []byte(chain)},
 	}
 
 	for _, resource := range resources {
-		if len(resource.content) == 0 {
+		if resource.path == "" || len(resource.content) == 0 {
 			continue
 		}
 		err = util.WriteFile(resource.path, resource.content)
 		if err != nil {
 			return err
 		}
+		err = applyFilePermissions(r.Installation, resource.path)
+		if err != nil {
+			return err
+		}
 	}
 
 	return nil
 }
 
-// AfterInstallActions runs any instructions declared in the Installer on a terminal.
+// Rollback restores the version of the certificate backed up by Backup, overwriting the installed one
+func (r PEMInstaller) Rollback(_ context.Context) error {
+	zap.L().Debug("rolling back certificate", zap.String("location", r.File))
+
+	for _, location := range []string{r.File, r.KeyFile, r.ChainFile} {
+		if location == "" {
+			continue
+		}
+		err := restoreBackup(location)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// AfterInstallActions runs the actions declared in the Installer, in order: scripts run on a terminal,
+// while services, sites and webhooks are handled natively.
 //
-// No validations happen over the content of the AfterAction string, so caution is advised
-func (r PEMInstaller) AfterInstallActions() (string, error) {
+// No validations happen over the content of the AfterAction scripts, so caution is advised
+func (r PEMInstaller) AfterInstallActions(ctx context.Context) (string, error) {
 	zap.L().Debug("running after-install actions", zap.String("location", r.File))
 
-	result, err := util.ExecuteScript(r.AfterAction)
+	result, err := runAfterInstallActions(ctx, r.AfterAction)
 	return result, err
 }
 
 // InstallValidationActions runs any instructions declared in the Installer on a terminal and expects
 // "0" for successful validation and "1" for a validation failure
 // No validations happen over the content of the InstallValidation string, so caution is advised
-func (r PEMInstaller) InstallValidationActions() (string, error) {
+func (r PEMInstaller) InstallValidationActions(ctx context.Context) (string, error) {
 	zap.L().Debug("running install validation actions", zap.String("location", r.File))
 
-	validationResult, err := util.ExecuteScript(r.InstallValidation)
+	validationResult, err := util.ExecuteScript(ctx, r.InstallValidation)
 	if err != nil {
 		return "", err
 	}
@@ -173,9 +222,67 @@ func (r PEMInstaller) InstallValidationActions() (string, error) {
 	return validationResult, err
 }
 
+// keyMismatch returns true when the installed private key does not belong to cert. Nothing is checked when no private
+// key is installed, as happens when the CSR is provided by the user or the key is kept in a PKCS#11 token.
+// The private key is read from keyFile, or from file when pemBundle includes it
+func (r PEMInstaller) keyMismatch(cert *x509.Certificate) bool {
+	keyFile := r.privateKeyFile()
+	keyExists, err := util.FileExists(keyFile)
+	if keyFile == "" || err != nil || !keyExists {
+		zap.L().Debug("no private key installed, key match not checked", zap.String("location", keyFile))
+		return false
+	}
+
+	privateKey, err := loadPEMPrivateKey(keyFile, r.KeyPassword)
+	return keyMismatch(cert, privateKey, err, zap.String("location", keyFile))
+}
+
+// PrivateKey returns the private key installed, or nil when nothing is installed
+func (r PEMInstaller) PrivateKey(_ context.Context) (crypto.Signer, error) {
+	keyFile := r.privateKeyFile()
+	if keyFile == "" {
+		return nil, nil
+	}
+	keyExists, err := util.FileExists(keyFile)
+	if err != nil || !keyExists {
+		return nil, err
+	}
+	privateKey, err := loadPEMPrivateKey(keyFile, r.KeyPassword)
+	if err != nil {
+		return nil, fmt.Errorf("could not load private key from %s: %w", keyFile, err)
+	}
+	return asSigner(privateKey)
+}
+
+// privateKeyFile returns the file the private key is installed in
+func (r PEMInstaller) privateKeyFile() string {
+	if strings.ToLower(r.PEMBundle) == domain.PEMBundleCertKeyChain {
+		return r.File
+	}
+	return r.KeyFile
+}
+
 func (r PEMInstaller) installAsBundle() bool {
 	if r.KeyFile != "" && r.ChainFile != "" {
 		return true
 	}
 	return false
 }
+
+// loadChain returns the certificates installed along with the end-entity certificate.
+// Used to find the issuer when checking the certificate revocation status
+func (r PEMInstaller) loadChain() []*x509.Certificate {
+	chain := make([]*x509.Certificate, 0)
+	for _, file := range []string{r.File, r.ChainFile} {
+		if file == "" {
+			continue
+		}
+		data, err := os.ReadFile(file)
+		if err != nil {
+			zap.L().Debug("could not read certificate chain", zap.String("location", file), zap.Error(err))
+			continue
+		}
+		chain = append(chain, parsePEMCertificates(data)...)
+	}
+	return chain
+}
@@ -0,0 +1,99 @@
+/*
+ * Copyright 2023 Venafi, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *  http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package installer
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"encoding/pem"
+	"path/filepath"
+	"testing"
+
+	"github.com/Venafi/vcert/v5/pkg/certificate"
+	"github.com/Venafi/vcert/v5/pkg/playbook/app/domain"
+	"github.com/Venafi/vcert/v5/pkg/playbook/app/vcertutil"
+)
+
+// newTestPrivateKey returns the PEM of a P-256 private key unrelated to any certificate
+func newTestPrivateKey(t *testing.T) string {
+	t.Helper()
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	der, err := x509.MarshalECPrivateKey(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return string(pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: der}))
+}
+
+func TestPEMInstallerCheckPrivateKey(t *testing.T) {
+	pcc := newTestPEMCollection(t)
+	encryptedKey, err := vcertutil.EncryptPrivateKeyPKCS1(pcc.PrivateKey, "secret")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name          string
+		key           string
+		keyPassword   string
+		expectInstall bool
+	}{
+		{name: "matching key", key: pcc.PrivateKey},
+		{name: "mismatched key", key: newTestPrivateKey(t), expectInstall: true},
+		{name: "unreadable key", key: encryptedKey, keyPassword: "wrong"},
+		{name: "nil key", key: pcc.Certificate},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := t.TempDir()
+			inst := NewPEMInstaller(domain.Installation{
+				Type:        domain.FormatPEM,
+				File:        filepath.Join(dir, "cert.pem"),
+				KeyFile:     filepath.Join(dir, "key.pem"),
+				KeyPassword: tt.keyPassword,
+			})
+			writeTestFile(t, inst.File, pcc.Certificate)
+			writeTestFile(t, inst.KeyFile, tt.key)
+
+			install, cert, err := inst.Check(context.Background(), "10%", domain.PlaybookRequest{})
+			if err != nil {
+				t.Fatalf("unexpected error: %s", err)
+			}
+			if install != tt.expectInstall {
+				t.Fatalf("expected install %t, got %t", tt.expectInstall, install)
+			}
+			if cert == nil {
+				t.Fatal("installed certificate not returned")
+			}
+		})
+	}
+}
+
+// newTestMismatchedPEMCollection returns the collection of newTestPEMCollection with an unrelated private key
+func newTestMismatchedPEMCollection(t *testing.T) certificate.PEMCollection {
+	t.Helper()
+	pcc := newTestPEMCollection(t)
+	pcc.PrivateKey = newTestPrivateKey(t)
+	return pcc
+}
@@ -17,11 +17,13 @@
 package installer
 
 import (
-	"crypto/rand"
+	"context"
+	"crypto"
 	"crypto/x509"
 	"encoding/pem"
 	"fmt"
 	"os"
+	"strings"
 
 	"go.uber.org/zap"
 	"software.sslmate.com/src/go-pkcs12"
@@ -44,33 +46,56 @@ func NewPKCS12Installer(inst domain.Installation) PKCS12Installer {
 // Check is the method in charge of making the validations to install a new certificate:
 // 1. Does the certificate exists? > Install if it doesn't.
 // 2. Does the certificate is about to expire? Renew if about to expire.
-// Returns true if the certificate needs to be installed.
-func (r PKCS12Installer) Check(renewBefore string, _ domain.PlaybookRequest) (bool, error) {
+// Returns true if the certificate needs to be installed, along with the certificate currently installed, if any.
+func (r PKCS12Installer) Check(_ context.Context, renewBefore string, _ domain.PlaybookRequest) (bool, *x509.Certificate, error) {
 	zap.L().Info("checking certificate health", zap.String("format", r.Type.String()), zap.String("location", r.File))
 
 	// Check certificate file exists
 	certExists, err := util.FileExists(r.File)
 	if err != nil {
-		return false, err
+		return false, nil, err
 	}
 	if !certExists {
-		return true, nil
+		return true, nil, nil
 	}
 
 	// Load Certificate
-	cert, err := loadPKCS12(r.File, r.P12Password)
+	cert, privateKey, err := loadPKCS12(r.File, r.P12Password)
 	if err != nil {
-		return false, err
+		return false, nil, err
+	}
+
+	// Check the private key belongs to the certificate
+	if keyMismatch(cert, privateKey, nil, zap.String("location", r.File)) {
+		return true, cert, nil
 	}
 
 	// Check certificate expiration
 	renew := needRenewal(cert, renewBefore)
 
-	return renew, nil
+	// Check certificate revocation
+	if !renew && r.ValidateRevocation {
+		renew = isRevoked(cert, nil)
+	}
+
+	return renew, cert, nil
+}
+
+// PrivateKey returns the private key installed, or nil when nothing is installed
+func (r PKCS12Installer) PrivateKey(_ context.Context) (crypto.Signer, error) {
+	certExists, err := util.FileExists(r.File)
+	if err != nil || !certExists {
+		return nil, err
+	}
+	_, privateKey, err := loadPKCS12(r.File, r.P12Password)
+	if err != nil {
+		return nil, err
+	}
+	return asSigner(privateKey)
 }
 
 // Backup takes the certificate request and backs up the current version prior to overwriting
-func (r PKCS12Installer) Backup() error {
+func (r PKCS12Installer) Backup(_ context.Context) error {
 	zap.L().Debug("backing up certificate", zap.String("location", r.File))
 
 	// Check certificate file exists
@@ -83,26 +108,24 @@ func (r PKCS12Installer) Backup() error {
 		return nil
 	}
 
-	newLocation := fmt.Sprintf("%s.bak", r.File)
-
-	err = util.CopyFile(r.File, newLocation)
+	newLocation, err := backupFile(r.File, newBackupTimestamp(), r.GetBackupRetention())
 	if err != nil {
 		return err
 	}
 
 	zap.L().Info("certificate backed up", zap.String("location", r.File), zap.String("backupLocation", newLocation))
-	return err
+	return nil
 }
 
 // Install takes the certificate bundle and moves it to the location specified in the installer
-func (r PKCS12Installer) Install(pcc certificate.PEMCollection) error {
+func (r PKCS12Installer) Install(_ context.Context, pcc certificate.PEMCollection) error {
 	zap.L().Debug("installing certificate", zap.String("location", r.File))
 
 	if r.P12Password == "" {
 		return domain.ErrNoP12Password
 	}
 
-	content, err := packageAsPKCS12(pcc, r.P12Password)
+	content, err := packageAsPKCS12(pcc, r.P12Password, getPKCS12Encoder(r.P12Encryption))
 	if err != nil {
 		zap.L().Error("could not package certificate as PKCS12")
 		return err
@@ -113,26 +136,38 @@ func (r PKCS12Installer) Install(pcc certificate.PEMCollection) error {
 		return err
 	}
 
+	err = applyFilePermissions(r.Installation, r.File)
+	if err != nil {
+		return err
+	}
+
 	return nil
 }
 
-// AfterInstallActions runs any instructions declared in the Installer on a terminal.
+// Rollback restores the version of the certificate backed up by Backup, overwriting the installed one
+func (r PKCS12Installer) Rollback(_ context.Context) error {
+	zap.L().Debug("rolling back certificate", zap.String("location", r.File))
+	return restoreBackup(r.File)
+}
+
+// AfterInstallActions runs the actions declared in the Installer, in order: scripts run on a terminal,
+// while services, sites and webhooks are handled natively.
 //
-// No validations happen over the content of the AfterAction string, so caution is advised
-func (r PKCS12Installer) AfterInstallActions() (string, error) {
+// No validations happen over the content of the AfterAction scripts, so caution is advised
+func (r PKCS12Installer) AfterInstallActions(ctx context.Context) (string, error) {
 	zap.L().Debug("running after-install actions", zap.String("location", r.File))
 
-	result, err := util.ExecuteScript(r.AfterAction)
+	result, err := runAfterInstallActions(ctx, r.AfterAction)
 	return result, err
 }
 
 // InstallValidationActions runs any instructions declared in the Installer on a terminal and expects
 // "0" for successful validation and "1" for a validation failure
 // No validations happen over the content of the InstallValidation string, so caution is advised
-func (r PKCS12Installer) InstallValidationActions() (string, error) {
+func (r PKCS12Installer) InstallValidationActions(ctx context.Context) (string, error) {
 	zap.L().Debug("running install validation actions", zap.String("location", r.File))
 
-	validationResult, err := util.ExecuteScript(r.InstallValidation)
+	validationResult, err := util.ExecuteScript(ctx, r.InstallValidation)
 	if err != nil {
 		return "", err
 	}
@@ -140,24 +175,38 @@ func (r PKCS12Installer) InstallValidationActions() (string, error) {
 	return validationResult, err
 }
 
-func loadPKCS12(pkcs12File string, keyPassword string) (*x509.Certificate, error) {
+// loadPKCS12 returns the certificate and the private key stored in the PKCS12 bundle
+func loadPKCS12(pkcs12File string, keyPassword string) (*x509.Certificate, interface{}, error) {
 	//Open file
 	data, err := os.ReadFile(pkcs12File)
 	if err != nil {
 		zap.L().Error("could not read PKCS12 file", zap.String("location", pkcs12File))
-		return nil, err
+		return nil, nil, err
 	}
 
-	// Due to limitations in pkcs12
-	_, cert, _, err := pkcs12.DecodeChain(data, keyPassword)
+	lc, err := decodePKCS12(data, keyPassword)
 	if err != nil {
-		return nil, err
+		return nil, nil, err
+	}
+
+	return lc.Certificate, lc.PrivateKey, nil
+}
+
+// getPKCS12Encoder returns the PKCS12 encoder matching the given encryption. Defaults to the legacy encoder
+func getPKCS12Encoder(encryption string) *pkcs12.Encoder {
+	if strings.ToLower(encryption) == domain.P12EncryptionModern {
+		return pkcs12.Modern
 	}
+	return pkcs12.LegacyRC2
+}
 
-	return cert, nil
+// PackageAsPKCS12 returns the certificate, chain and private key of pcc as a PKCS12 bundle protected with password.
+// encryption is either legacy or modern, and defaults to legacy when empty
+func PackageAsPKCS12(pcc certificate.PEMCollection, password string, encryption string) ([]byte, error) {
+	return packageAsPKCS12(pcc, password, getPKCS12Encoder(encryption))
 }
 
-func packageAsPKCS12(pcc certificate.PEMCollection, keyPassword string) ([]byte, error) {
+func packageAsPKCS12(pcc certificate.PEMCollection, keyPassword string, encoder *pkcs12.Encoder) ([]byte, error) {
 	if len(pcc.Certificate) == 0 || len(pcc.PrivateKey) == 0 {
 		return nil, fmt.Errorf("certificate and Private Key are required for PKCS12")
 	}
@@ -186,7 +235,7 @@ func packageAsPKCS12(pcc certificate.PEMCollection, keyPassword string) ([]byte,
 		return nil, err
 	}
 
-	bytes, err := pkcs12.Encode(rand.Reader, privateKey, cert, chainList, keyPassword)
+	bytes, err := encoder.Encode(privateKey, cert, chainList, keyPassword)
 	if err != nil {
 		return nil, fmt.Errorf("PKCS12 encode error: %w", err)
 	}
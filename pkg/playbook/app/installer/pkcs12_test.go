@@ -0,0 +1,93 @@
+/*
+ * Copyright 2023 Venafi, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *  http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package installer
+
+import (
+	"context"
+	"crypto/x509"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"software.sslmate.com/src/go-pkcs12"
+
+	"github.com/Venafi/vcert/v5/pkg/playbook/app/domain"
+)
+
+func TestPKCS12InstallerCheckPrivateKey(t *testing.T) {
+	pcc := newTestPEMCollection(t)
+	leaf, err := parsePEMCertificate([]byte(pcc.Certificate))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	matching, err := packageAsPKCS12(pcc, "secret", pkcs12.Modern)
+	if err != nil {
+		t.Fatal(err)
+	}
+	mismatched, err := packageAsPKCS12(newTestMismatchedPEMCollection(t), "secret", pkcs12.Modern)
+	if err != nil {
+		t.Fatal(err)
+	}
+	otherPassword, err := packageAsPKCS12(pcc, "other", pkcs12.Modern)
+	if err != nil {
+		t.Fatal(err)
+	}
+	certOnly, err := pkcs12.Modern.EncodeTrustStore([]*x509.Certificate{leaf}, "secret")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// The certificate of a bundle is encrypted along with its key, so a bundle whose key cannot be read cannot be
+	// checked at all, and go-pkcs12 rejects bundles without key
+	tests := []struct {
+		name          string
+		content       []byte
+		expectInstall bool
+		expectErr     bool
+	}{
+		{name: "matching key", content: matching},
+		{name: "mismatched key", content: mismatched, expectInstall: true},
+		{name: "unreadable key", content: otherPassword, expectErr: true},
+		{name: "nil key", content: certOnly, expectErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			location := filepath.Join(t.TempDir(), "cert.p12")
+			err := os.WriteFile(location, tt.content, 0600)
+			if err != nil {
+				t.Fatal(err)
+			}
+			inst := NewPKCS12Installer(domain.Installation{Type: domain.FormatPKCS12, File: location, P12Password: "secret"})
+
+			install, _, err := inst.Check(context.Background(), "10%", domain.PlaybookRequest{})
+			if tt.expectErr {
+				if err == nil {
+					t.Fatal("expected an error")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %s", err)
+			}
+			if install != tt.expectInstall {
+				t.Fatalf("expected install %t, got %t", tt.expectInstall, install)
+			}
+		})
+	}
+}
@@ -0,0 +1,163 @@
+/*
+ * Copyright 2023 Venafi, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *  http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package installer
+
+import (
+	"context"
+	"crypto/x509"
+	"fmt"
+	"strings"
+
+	"go.uber.org/zap"
+
+	"github.com/Venafi/vcert/v5/pkg/certificate"
+	"github.com/Venafi/vcert/v5/pkg/playbook/app/domain"
+	"github.com/Venafi/vcert/v5/pkg/playbook/util"
+	"github.com/Venafi/vcert/v5/pkg/playbook/util/plugin"
+)
+
+// PluginInstaller represents an installation made by an external plugin binary, for installation targets vcert does
+// not support natively. The plugin receives every operation as a JSON document on its stdin, and answers on its stdout
+type PluginInstaller struct {
+	domain.Installation
+}
+
+// NewPluginInstaller returns a new installer of type PLUGIN with the values defined in inst
+func NewPluginInstaller(inst domain.Installation) PluginInstaller {
+	return PluginInstaller{inst}
+}
+
+// Check is the method in charge of making the validations to install a new certificate:
+// 1. Does the certificate exists? > Install if it doesn't.
+// 2. Does the certificate is about to expire? Renew if about to expire.
+// Returns true if the certificate needs to be installed, along with the certificate currently installed, if any.
+func (r PluginInstaller) Check(ctx context.Context, renewBefore string, _ domain.PlaybookRequest) (bool, *x509.Certificate, error) {
+	zap.L().Info("checking certificate health", zap.String("format", r.Type.String()), zap.String("plugin", r.Plugin))
+
+	response, err := r.getClient().Run(ctx, plugin.OperationCheck, r.PluginConfig, nil)
+	if err != nil {
+		return false, nil, err
+	}
+	if response.Certificate == "" {
+		zap.L().Info("plugin has no certificate installed", zap.String("plugin", r.Plugin))
+		return true, nil, nil
+	}
+
+	// Load Certificate
+	cert, err := parsePEMCertificate([]byte(response.Certificate))
+	if err != nil {
+		return false, nil, err
+	}
+
+	// Check certificate expiration
+	renew := needRenewal(cert, renewBefore)
+
+	// Check certificate revocation
+	if !renew && r.ValidateRevocation {
+		renew = isRevoked(cert, parsePEMCertificates([]byte(strings.Join(response.Chain, ""))))
+	}
+
+	return renew, cert, nil
+}
+
+// Backup asks the plugin to back up the certificate currently installed
+func (r PluginInstaller) Backup(ctx context.Context) error {
+	zap.L().Debug("backing up certificate", zap.String("plugin", r.Plugin))
+
+	_, err := r.getClient().Run(ctx, plugin.OperationBackup, r.PluginConfig, nil)
+	if err != nil {
+		return err
+	}
+
+	zap.L().Info("certificate backed up", zap.String("plugin", r.Plugin))
+	return nil
+}
+
+// Install takes the certificate bundle and sends it to the plugin, which installs it where its config tells
+func (r PluginInstaller) Install(ctx context.Context, pcc certificate.PEMCollection) error {
+	zap.L().Debug("installing certificate", zap.String("plugin", r.Plugin))
+
+	if len(pcc.Certificate) == 0 {
+		return fmt.Errorf("certificate is required for plugin %s", r.Plugin)
+	}
+
+	privateKey := pcc.PrivateKey
+	var err error
+	if privateKey != "" && strings.ToLower(r.KeyFormat) == domain.KeyFormatPKCS8 {
+		privateKey, err = MarshalPKCS8PrivateKey(pcc.PrivateKey, "")
+		if err != nil {
+			zap.L().Error("failed to prepare PrivateKey in PKCS8 format", zap.Error(err))
+			return err
+		}
+	}
+
+	bundle := &plugin.Bundle{
+		Certificate: pcc.Certificate,
+		PrivateKey:  privateKey,
+		Chain:       pcc.Chain,
+	}
+	_, err = r.getClient().Run(ctx, plugin.OperationInstall, r.PluginConfig, bundle)
+	if err != nil {
+		zap.L().Error("plugin could not install certificate", zap.String("plugin", r.Plugin), zap.Error(err))
+		return err
+	}
+
+	zap.L().Debug("certificate installed", zap.String("plugin", r.Plugin))
+	return nil
+}
+
+// Rollback asks the plugin to restore the certificate backed up by Backup
+func (r PluginInstaller) Rollback(ctx context.Context) error {
+	zap.L().Debug("rolling back certificate", zap.String("plugin", r.Plugin))
+
+	_, err := r.getClient().Run(ctx, plugin.OperationRollback, r.PluginConfig, nil)
+	if err != nil {
+		return err
+	}
+
+	zap.L().Info("certificate restored from backup", zap.String("plugin", r.Plugin))
+	return nil
+}
+
+// AfterInstallActions runs the actions declared in the Installer, in order: scripts run on a terminal,
+// while services, sites and webhooks are handled natively.
+//
+// No validations happen over the content of the AfterAction scripts, so caution is advised
+func (r PluginInstaller) AfterInstallActions(ctx context.Context) (string, error) {
+	zap.L().Debug("running after-install actions", zap.String("plugin", r.Plugin))
+
+	result, err := runAfterInstallActions(ctx, r.AfterAction)
+	return result, err
+}
+
+// InstallValidationActions runs any instructions declared in the Installer on a terminal and expects
+// "0" for successful validation and "1" for a validation failure
+// No validations happen over the content of the InstallValidation string, so caution is advised
+func (r PluginInstaller) InstallValidationActions(ctx context.Context) (string, error) {
+	zap.L().Debug("running install validation actions", zap.String("plugin", r.Plugin))
+
+	validationResult, err := util.ExecuteScript(ctx, r.InstallValidation)
+	if err != nil {
+		return "", err
+	}
+
+	return validationResult, err
+}
+
+func (r PluginInstaller) getClient() *plugin.Client {
+	return plugin.NewClient(r.Plugin, r.GetPluginFile())
+}
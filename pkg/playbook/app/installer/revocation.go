@@ -0,0 +1,233 @@
+/*
+ * Copyright 2023 Venafi, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *  http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package installer
+
+import (
+	"bytes"
+	"crypto/x509"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"time"
+
+	"go.uber.org/zap"
+	"golang.org/x/crypto/ocsp"
+)
+
+const (
+	revocationTimeout  = 10 * time.Second
+	ocspRequestType    = "application/ocsp-request"
+	revocationMaxBytes = 10 * 1024 * 1024
+)
+
+// isRevoked checks the revocation status of cert using OCSP and, if the OCSP responder is not available or does not
+// know the certificate, the CRL distribution points. The issuer is looked for in chain and, if not found there,
+// downloaded from the Authority Information Access extension of the certificate.
+//
+// Errors are logged and the certificate is considered not revoked, so that an unreachable responder does not
+// trigger a renewal on every run
+func isRevoked(cert *x509.Certificate, chain []*x509.Certificate) bool {
+	zap.L().Info("checking certificate revocation status", zap.String("serial", cert.SerialNumber.Text(16)))
+
+	issuer, err := findIssuer(cert, chain)
+	if err != nil {
+		zap.L().Warn("could not check certificate revocation status", zap.Error(err))
+		return false
+	}
+
+	revoked, err := checkOCSP(cert, issuer)
+	if err == nil {
+		return revoked
+	}
+	zap.L().Debug("OCSP check failed, using CRL instead", zap.Error(err))
+
+	revoked, err = checkCRL(cert, issuer)
+	if err != nil {
+		zap.L().Warn("could not check certificate revocation status", zap.Error(err))
+		return false
+	}
+	return revoked
+}
+
+func findIssuer(cert *x509.Certificate, chain []*x509.Certificate) (*x509.Certificate, error) {
+	for _, c := range chain {
+		if cert.CheckSignatureFrom(c) == nil {
+			return c, nil
+		}
+	}
+
+	for _, url := range cert.IssuingCertificateURL {
+		data, err := httpGet(url)
+		if err != nil {
+			zap.L().Debug("could not download issuer certificate", zap.String("url", url), zap.Error(err))
+			continue
+		}
+		issuers := parsePEMCertificates(data)
+		if len(issuers) == 0 {
+			issuer, err := x509.ParseCertificate(data)
+			if err != nil {
+				zap.L().Debug("could not parse issuer certificate", zap.String("url", url), zap.Error(err))
+				continue
+			}
+			issuers = append(issuers, issuer)
+		}
+		for _, issuer := range issuers {
+			if cert.CheckSignatureFrom(issuer) == nil {
+				return issuer, nil
+			}
+		}
+	}
+
+	return nil, fmt.Errorf("issuer of certificate %s not found", cert.Subject.CommonName)
+}
+
+// checkOCSP returns an error when the revocation status cannot be determined using OCSP
+func checkOCSP(cert *x509.Certificate, issuer *x509.Certificate) (bool, error) {
+	if len(cert.OCSPServer) == 0 {
+		return false, fmt.Errorf("certificate does not declare an OCSP responder")
+	}
+
+	request, err := ocsp.CreateRequest(cert, issuer, nil)
+	if err != nil {
+		return false, fmt.Errorf("could not create OCSP request: %w", err)
+	}
+
+	var errs []error
+	for _, server := range cert.OCSPServer {
+		response, err := httpPost(server, request)
+		if err != nil {
+			errs = append(errs, err)
+			continue
+		}
+
+		ocspResponse, err := ocsp.ParseResponseForCert(response, cert, issuer)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("could not parse OCSP response from %s: %w", server, err))
+			continue
+		}
+
+		switch ocspResponse.Status {
+		case ocsp.Good:
+			zap.L().Debug("certificate is not revoked", zap.String("ocsp", server))
+			return false, nil
+		case ocsp.Revoked:
+			zap.L().Info("certificate is revoked", zap.String("ocsp", server),
+				zap.Time("revokedAt", ocspResponse.RevokedAt))
+			return true, nil
+		default:
+			errs = append(errs, fmt.Errorf("OCSP responder %s does not know the certificate", server))
+		}
+	}
+
+	return false, fmt.Errorf("OCSP check failed: %w", errors.Join(errs...))
+}
+
+// checkCRL returns an error when the revocation status cannot be determined using the CRL distribution points
+func checkCRL(cert *x509.Certificate, issuer *x509.Certificate) (bool, error) {
+	if len(cert.CRLDistributionPoints) == 0 {
+		return false, fmt.Errorf("certificate does not declare CRL distribution points")
+	}
+
+	var errs []error
+	for _, url := range cert.CRLDistributionPoints {
+		data, err := httpGet(url)
+		if err != nil {
+			errs = append(errs, err)
+			continue
+		}
+
+		if block, _ := pem.Decode(data); block != nil {
+			data = block.Bytes
+		}
+		crl, err := x509.ParseRevocationList(data)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("could not parse CRL from %s: %w", url, err))
+			continue
+		}
+		err = crl.CheckSignatureFrom(issuer)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("invalid CRL signature from %s: %w", url, err))
+			continue
+		}
+
+		for _, revoked := range crl.RevokedCertificates {
+			if revoked.SerialNumber.Cmp(cert.SerialNumber) == 0 {
+				zap.L().Info("certificate is revoked", zap.String("crl", url),
+					zap.Time("revokedAt", revoked.RevocationTime))
+				return true, nil
+			}
+		}
+		zap.L().Debug("certificate is not revoked", zap.String("crl", url))
+		return false, nil
+	}
+
+	return false, fmt.Errorf("CRL check failed: %w", errors.Join(errs...))
+}
+
+// parsePEMCertificates returns all the certificates found in data. Blocks that cannot be parsed are ignored
+func parsePEMCertificates(data []byte) []*x509.Certificate {
+	certs := make([]*x509.Certificate, 0)
+	for {
+		var block *pem.Block
+		block, data = pem.Decode(data)
+		if block == nil {
+			break
+		}
+		if block.Type != "CERTIFICATE" {
+			continue
+		}
+		cert, err := x509.ParseCertificate(block.Bytes)
+		if err != nil {
+			continue
+		}
+		certs = append(certs, cert)
+	}
+	return certs
+}
+
+func httpGet(url string) ([]byte, error) {
+	client := &http.Client{Timeout: revocationTimeout}
+	res, err := client.Get(url)
+	if err != nil {
+		return nil, err
+	}
+	return readResponse(url, res)
+}
+
+func httpPost(url string, body []byte) ([]byte, error) {
+	client := &http.Client{Timeout: revocationTimeout}
+	res, err := client.Post(url, ocspRequestType, bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+	return readResponse(url, res)
+}
+
+func readResponse(url string, res *http.Response) ([]byte, error) {
+	defer res.Body.Close()
+
+	data, err := io.ReadAll(io.LimitReader(res.Body, revocationMaxBytes))
+	if err != nil {
+		return nil, err
+	}
+	if res.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("unexpected status code from %s: %d", url, res.StatusCode)
+	}
+	return data, nil
+}
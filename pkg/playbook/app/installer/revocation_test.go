@@ -0,0 +1,149 @@
+/*
+ * Copyright 2023 Venafi, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *  http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package installer
+
+import (
+	"crypto/rand"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"io"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"golang.org/x/crypto/ocsp"
+
+	"github.com/Venafi/vcert/v5/test/testcert"
+)
+
+// revocationServer mocks the OCSP responder, the CRL distribution point and the issuer download of a CA
+type revocationServer struct {
+	ca *testcert.Certificate
+	// ocspStatus is the status returned by the OCSP responder. It fails when negative
+	ocspStatus int
+	// revoked holds the serial numbers listed in the CRL
+	revoked []*big.Int
+	// crlSigner signs the CRL. Defaults to ca
+	crlSigner *testcert.Certificate
+}
+
+func (s *revocationServer) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	switch r.URL.Path {
+	case "/ocsp":
+		body, _ := io.ReadAll(r.Body)
+		req, err := ocsp.ParseRequest(body)
+		if err != nil || s.ocspStatus < 0 {
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+		response, err := ocsp.CreateResponse(s.ca.Cert, s.ca.Cert, ocsp.Response{
+			Status:       s.ocspStatus,
+			SerialNumber: req.SerialNumber,
+			ThisUpdate:   time.Now().Add(-time.Hour),
+			NextUpdate:   time.Now().Add(time.Hour),
+			RevokedAt:    time.Now().Add(-time.Minute),
+		}, s.ca.Key)
+		if err != nil {
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+		_, _ = w.Write(response)
+	case "/crl":
+		signer := s.ca
+		if s.crlSigner != nil {
+			signer = s.crlSigner
+		}
+		entries := make([]pkix.RevokedCertificate, 0)
+		for _, serial := range s.revoked {
+			entries = append(entries, pkix.RevokedCertificate{SerialNumber: serial, RevocationTime: time.Now().Add(-time.Minute)})
+		}
+		crl, err := x509.CreateRevocationList(rand.Reader, &x509.RevocationList{
+			Number:              big.NewInt(1),
+			ThisUpdate:          time.Now().Add(-time.Hour),
+			NextUpdate:          time.Now().Add(time.Hour),
+			RevokedCertificates: entries,
+		}, signer.Cert, signer.Key)
+		if err != nil {
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+		_, _ = w.Write(crl)
+	case "/ca.crt":
+		_, _ = w.Write(s.ca.Cert.Raw)
+	default:
+		w.WriteHeader(http.StatusNotFound)
+	}
+}
+
+func TestIsRevoked(t *testing.T) {
+	ca := testcert.Issue(testcert.Template("Test CA", true), nil, nil)
+	otherCA := testcert.Issue(testcert.Template("Other CA", true), nil, nil)
+
+	cases := []struct {
+		name       string
+		ocsp       bool
+		crl        bool
+		aia        bool
+		inChain    bool
+		ocspStatus int
+		crlRevoked bool
+		crlSigner  *testcert.Certificate
+		expected   bool
+	}{
+		{name: "OCSPGood", ocsp: true, crl: true, inChain: true, ocspStatus: ocsp.Good, crlRevoked: true, expected: false},
+		{name: "OCSPRevoked", ocsp: true, inChain: true, ocspStatus: ocsp.Revoked, expected: true},
+		{name: "OCSPUnknownCRLRevoked", ocsp: true, crl: true, inChain: true, ocspStatus: ocsp.Unknown, crlRevoked: true, expected: true},
+		{name: "OCSPFailureCRLGood", ocsp: true, crl: true, inChain: true, ocspStatus: -1, expected: false},
+		{name: "CRLOnlyRevoked", crl: true, inChain: true, crlRevoked: true, expected: true},
+		{name: "CRLSignedByOtherCA", crl: true, inChain: true, crlRevoked: true, crlSigner: &otherCA, expected: false},
+		{name: "NoRevocationInformation", inChain: true, expected: false},
+		{name: "IssuerDownloaded", ocsp: true, aia: true, ocspStatus: ocsp.Revoked, expected: true},
+		{name: "IssuerNotFound", ocsp: true, ocspStatus: ocsp.Revoked, expected: false},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			handler := &revocationServer{ca: &ca, ocspStatus: c.ocspStatus, crlSigner: c.crlSigner}
+			server := httptest.NewServer(handler)
+			defer server.Close()
+
+			template := testcert.Template("leaf.example.com", false)
+			if c.ocsp {
+				template.OCSPServer = []string{server.URL + "/ocsp"}
+			}
+			if c.crl {
+				template.CRLDistributionPoints = []string{server.URL + "/crl"}
+			}
+			if c.aia {
+				template.IssuingCertificateURL = []string{server.URL + "/ca.crt"}
+			}
+			leaf := testcert.Issue(template, nil, &ca)
+			if c.crlRevoked {
+				handler.revoked = []*big.Int{leaf.Cert.SerialNumber}
+			}
+			chain := []*x509.Certificate{otherCA.Cert}
+			if c.inChain {
+				chain = append(chain, ca.Cert)
+			}
+
+			if revoked := isRevoked(leaf.Cert, chain); revoked != c.expected {
+				t.Fatalf("expected revoked to be %t but got %t", c.expected, revoked)
+			}
+		})
+	}
+}
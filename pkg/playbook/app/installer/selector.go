@@ -26,15 +26,46 @@ import (
 	"github.com/Venafi/vcert/v5/pkg/playbook/app/domain"
 )
 
-// GetInstaller returns a proper installer according to the type defined in inst
+// GetInstaller returns a proper installer according to the type defined in inst. Installations with mirrors are
+// written to every location by a MirroredInstaller
 func GetInstaller(inst domain.Installation) Installer {
+	if len(inst.Mirrors) > 0 {
+		return NewMirroredInstaller(inst)
+	}
+
 	switch inst.Type {
+	case domain.FormatAWSACM:
+		return NewAWSACMInstaller(inst)
+	case domain.FormatAzureKeyVault:
+		return NewAzureKeyVaultInstaller(inst)
+	case domain.FormatCitrixADC:
+		return NewCitrixADCInstaller(inst)
+	case domain.FormatDockerSecret:
+		return NewDockerSecretInstaller(inst)
+	case domain.FormatF5:
+		return NewF5Installer(inst)
+	case domain.FormatGCP:
+		return NewGCPInstaller(inst)
 	case domain.FormatJKS:
 		return NewJKSInstaller(inst)
+	case domain.FormatK8sSecret:
+		return NewK8sSecretInstaller(inst)
+	case domain.FormatNginx, domain.FormatApache:
+		return NewWebServerInstaller(inst)
+	case domain.FormatNomadVariable:
+		return NewNomadVariableInstaller(inst)
+	case domain.FormatPostgreSQL, domain.FormatMySQL:
+		return NewDatabaseInstaller(inst)
 	case domain.FormatPEM:
 		return NewPEMInstaller(inst)
 	case domain.FormatPKCS12:
 		return NewPKCS12Installer(inst)
+	case domain.FormatPlugin:
+		return NewPluginInstaller(inst)
+	case domain.FormatSystemdCredential:
+		return NewSystemdCredentialInstaller(inst)
+	case domain.FormatVaultKV:
+		return NewVaultKVInstaller(inst)
 	default:
 		zap.L().Fatal(fmt.Sprintf("runner not found for installation type: %s", inst.Type.String()))
 		return nil
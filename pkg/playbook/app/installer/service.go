@@ -0,0 +1,34 @@
+//go:build !windows
+
+/*
+ * Copyright 2023 Venafi, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *  http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package installer
+
+import (
+	"context"
+	"github.com/Venafi/vcert/v5/pkg/playbook/app/domain"
+)
+
+// restartService restarts the systemd service name
+func restartService(ctx context.Context, name string) error {
+	return runCommand(ctx, "systemctl", "restart", name)
+}
+
+// restartIISSite is only supported on Windows. The playbook validation rejects it on other systems
+func restartIISSite(_ context.Context, _ string) error {
+	return domain.ErrRestartIISSiteOnNonWindows
+}
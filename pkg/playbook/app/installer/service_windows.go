@@ -0,0 +1,58 @@
+//go:build windows
+
+/*
+ * Copyright 2023 Venafi, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *  http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package installer
+
+import (
+	"context"
+	"fmt"
+)
+
+// restartService restarts the Windows service name.
+// The name is checked against an allow-list of characters when the playbook is validated, so it is safe to quote
+func restartService(ctx context.Context, name string) error {
+	return runPowerShell(ctx, fmt.Sprintf("Restart-Service -Name '%s' -Force", name))
+}
+
+// restartIISSite stops and starts the IIS site name.
+// The name is checked against an allow-list of characters when the playbook is validated, so it is safe to quote
+func restartIISSite(ctx context.Context, name string) error {
+	return runPowerShell(ctx, fmt.Sprintf("Import-Module WebAdministration; Stop-Website -Name '%[1]s'; Start-Website -Name '%[1]s'", name))
+}
+
+// bindIISSite sets the certificate with the given thumbprint, in the LocalMachine store storeName, in the HTTPS bindings
+// of the IIS site name on port, which updates the http.sys SSL bindings.
+// The name is checked against an allow-list of characters when the playbook is validated, so it is safe to quote
+func bindIISSite(ctx context.Context, name string, port int, thumbprint string, storeName string) error {
+	return runPowerShell(ctx, fmt.Sprintf("Import-Module WebAdministration; "+
+		"$bindings = @(Get-WebBinding -Name '%[1]s' -Protocol https -Port %[2]d); "+
+		"if ($bindings.Count -eq 0) { throw 'no https binding found for site %[1]s on port %[2]d' }; "+
+		"foreach ($binding in $bindings) { $binding.AddSslCertificate('%[3]s', '%[4]s') }", name, port, thumbprint, storeName))
+}
+
+// bindRDP sets the certificate with the given thumbprint, in the LocalMachine\My store, as the certificate of the
+// RDP-Tcp listener, which is stored in its registry key
+func bindRDP(ctx context.Context, thumbprint string) error {
+	return runPowerShell(ctx, fmt.Sprintf("$setting = Get-CimInstance -Namespace root/cimv2/TerminalServices "+
+		"-ClassName Win32_TSGeneralSetting -Filter \"TerminalName='RDP-tcp'\"; "+
+		"Set-CimInstance -InputObject $setting -Property @{SSLCertificateSHA1Hash='%s'}", thumbprint))
+}
+
+func runPowerShell(ctx context.Context, command string) error {
+	return runCommand(ctx, "powershell.exe", "-NoProfile", "-NonInteractive", "-Command", command)
+}
@@ -0,0 +1,498 @@
+/*
+ * Copyright 2023 Venafi, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *  http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package installer
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	"go.uber.org/zap"
+	"golang.org/x/crypto/ssh"
+
+	"github.com/Venafi/vcert/v5/pkg/playbook/app/domain"
+	"github.com/Venafi/vcert/v5/pkg/playbook/util"
+)
+
+const (
+	// sshPublicFileMode is the mode of the SSH files that hold no secret: public keys, certificates and known_hosts
+	sshPublicFileMode = 0644
+
+	// sshKnownHostsMarker is the comment of the known_hosts entries written by vcert. Only those entries are replaced
+	sshKnownHostsMarker = "vcert"
+	sshCertAuthority    = "@cert-authority"
+)
+
+// SSHBundle holds the files of an SSH certificate, and the public key of the CA that issued it, in OpenSSH formats
+type SSHBundle struct {
+	// PrivateKey is the PEM encoded private key, encrypted when a passphrase is set
+	PrivateKey string
+	// PublicKey is the public key in authorized_keys format
+	PublicKey string
+	// Certificate is the certificate in authorized_keys format, as written to -cert.pub files
+	Certificate string
+	// CAPublicKey is the public key of the CA in authorized_keys format
+	CAPublicKey string
+}
+
+// SSHInstaller represents the interface for the installers of sshCertificate tasks.
+// A new SSHInstaller must implement this interface to be picked up.
+type SSHInstaller interface {
+
+	// Check returns true when the installation needs to be installed: the certificate is missing, about to expire,
+	// or was not issued by the CA whose public key is caPublicKey; or the public key of the CA is missing.
+	// caPublicKey is only set when the task has installations of the public key of the CA
+	Check(ctx context.Context, renewBefore string, request domain.SSHRequest, caPublicKey string) (bool, error)
+
+	// Backup backs up the files of the installation prior to overwriting them
+	Backup(ctx context.Context) error
+
+	// Install writes the files of the installation from the bundle
+	Install(ctx context.Context, bundle SSHBundle) error
+
+	// Rollback restores the files backed up by Backup, overwriting the installed ones
+	Rollback(ctx context.Context) error
+
+	// AfterInstallActions runs the actions declared in the Installer, in order: scripts run on a terminal,
+	// while services, sites and webhooks are handled natively.
+	//
+	// No validations happen over the content of the AfterAction scripts, so caution is advised
+	AfterInstallActions(ctx context.Context) (string, error)
+
+	// InstallValidationActions runs any instructions declared in the Installer on a terminal and expects
+	// "0" for successful validation and "1" for a validation failure
+	// No validations happen over the content of the InstallValidation string, so caution is advised
+	InstallValidationActions(ctx context.Context) (string, error)
+}
+
+// GetSSHInstaller returns a proper installer according to the SSH format defined in inst
+func GetSSHInstaller(inst domain.Installation) SSHInstaller {
+	switch inst.Type {
+	case domain.FormatSSHCAPub:
+		return NewSSHCAPubInstaller(inst)
+	case domain.FormatSSHCert:
+		return NewSSHCertInstaller(inst)
+	case domain.FormatSSHKnownHosts:
+		return NewSSHKnownHostsInstaller(inst)
+	default:
+		zap.L().Fatal(fmt.Sprintf("runner not found for SSH installation type: %s", inst.Type.String()))
+		return nil
+	}
+}
+
+// sshFileInstaller holds the behavior shared by the SSH installers, which write plain files
+type sshFileInstaller struct {
+	domain.Installation
+}
+
+// files returns the files written by the installer
+func (r sshFileInstaller) files() []string {
+	if r.Type == domain.FormatSSHCert {
+		return []string{r.File, sshPublicKeyFile(r.File), sshCertificateFile(r.File)}
+	}
+	return []string{r.File}
+}
+
+// Backup backs up the files of the installation prior to overwriting them
+func (r sshFileInstaller) Backup(_ context.Context) error {
+	zap.L().Debug("backing up SSH files", zap.String("location", r.File))
+
+	timestamp := newBackupTimestamp()
+	for _, location := range r.files() {
+		fileExists, err := util.FileExists(location)
+		if err != nil {
+			return err
+		} else if !fileExists {
+			zap.L().Info(fmt.Sprintf("file %s does not exist, no backup taken", location))
+			continue
+		}
+		backupLocation, err := backupFile(location, timestamp, r.GetBackupRetention())
+		if err != nil {
+			return err
+		}
+		zap.L().Info("SSH file backed up", zap.String("location", location), zap.String("backupLocation", backupLocation))
+	}
+	return nil
+}
+
+// Rollback restores the files backed up by Backup, overwriting the installed ones
+func (r sshFileInstaller) Rollback(_ context.Context) error {
+	zap.L().Debug("rolling back SSH files", zap.String("location", r.File))
+
+	for _, location := range r.files() {
+		err := restoreBackup(location)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// AfterInstallActions runs the actions declared in the Installer, in order: scripts run on a terminal,
+// while services, sites and webhooks are handled natively.
+//
+// No validations happen over the content of the AfterAction scripts, so caution is advised
+func (r sshFileInstaller) AfterInstallActions(ctx context.Context) (string, error) {
+	zap.L().Debug("running after-install actions", zap.String("location", r.File))
+
+	return runAfterInstallActions(ctx, r.AfterAction)
+}
+
+// InstallValidationActions runs any instructions declared in the Installer on a terminal and expects
+// "0" for successful validation and "1" for a validation failure
+// No validations happen over the content of the InstallValidation string, so caution is advised
+func (r sshFileInstaller) InstallValidationActions(ctx context.Context) (string, error) {
+	zap.L().Debug("running install validation actions", zap.String("location", r.File))
+
+	return util.ExecuteScript(ctx, r.InstallValidation)
+}
+
+// writePublicFile writes content to location, readable by everyone unless the installation sets a mode
+func (r sshFileInstaller) writePublicFile(location string, content []byte) error {
+	err := util.WriteFile(location, content)
+	if err != nil {
+		return err
+	}
+	if r.Mode == "" {
+		err = util.SetFileMode(location, sshPublicFileMode)
+		if err != nil {
+			return err
+		}
+	}
+	return applyFilePermissions(r.Installation, location)
+}
+
+// SSHCertInstaller represents an installation of an SSH certificate with its key pair, using the OpenSSH file names:
+// the private key is written to File, the public key to File.pub and the certificate to File-cert.pub
+type SSHCertInstaller struct {
+	sshFileInstaller
+}
+
+// NewSSHCertInstaller returns a new installer of type SSHCERT with the values defined in inst
+func NewSSHCertInstaller(inst domain.Installation) SSHCertInstaller {
+	return SSHCertInstaller{sshFileInstaller{inst}}
+}
+
+// Check returns true when the certificate is missing, about to expire, does not match the private key,
+// or was not issued by the CA whose public key is caPublicKey
+func (r SSHCertInstaller) Check(_ context.Context, renewBefore string, request domain.SSHRequest, caPublicKey string) (bool, error) {
+	zap.L().Info("checking SSH certificate health", zap.String("format", r.Type.String()), zap.String("location", r.File))
+
+	certFile := sshCertificateFile(r.File)
+	for _, location := range []string{r.File, certFile} {
+		exists, err := util.FileExists(location)
+		if err != nil {
+			return false, err
+		}
+		if !exists {
+			return true, nil
+		}
+	}
+
+	cert, err := loadSSHCertificate(certFile)
+	if err != nil {
+		return false, err
+	}
+
+	// Check the private key belongs to the certificate. The passphrase is the one of the task,
+	// so the key is renewed when the passphrase changes
+	keyData, err := os.ReadFile(r.File)
+	if err != nil {
+		return false, err
+	}
+	signer, err := parseSSHPrivateKey(keyData, request.KeyPassphrase)
+	if err != nil {
+		zap.L().Info("SSH private key cannot be read, the certificate will be renewed", zap.String("location", r.File),
+			zap.Error(err))
+		return true, nil
+	}
+	if !bytes.Equal(signer.PublicKey().Marshal(), cert.Key.Marshal()) {
+		zap.L().Info("SSH private key does not match the certificate", zap.String("location", r.File))
+		return true, nil
+	}
+
+	if caPublicKey != "" {
+		ca, _, _, _, err := ssh.ParseAuthorizedKey([]byte(caPublicKey))
+		if err != nil {
+			return false, fmt.Errorf("failed to parse the public key of the SSH CA: %w", err)
+		}
+		if !bytes.Equal(ca.Marshal(), cert.SignatureKey.Marshal()) {
+			zap.L().Info("SSH certificate was not issued by the current CA", zap.String("location", certFile))
+			return true, nil
+		}
+	}
+
+	return sshNeedRenewal(cert, renewBefore), nil
+}
+
+// Install writes the private key, the public key and the certificate of the bundle
+func (r SSHCertInstaller) Install(_ context.Context, bundle SSHBundle) error {
+	zap.L().Debug("installing SSH certificate", zap.String("location", r.File))
+
+	// The private key is written with mode 0600 by default, as required by ssh
+	err := util.WriteFile(r.File, []byte(bundle.PrivateKey))
+	if err != nil {
+		return err
+	}
+	err = applyFilePermissions(r.Installation, r.File)
+	if err != nil {
+		return err
+	}
+
+	// The mode of the installation is only meant for the private key
+	public := sshFileInstaller{r.Installation}
+	public.Mode = ""
+	err = public.writePublicFile(sshPublicKeyFile(r.File), []byte(ensureNewline(bundle.PublicKey)))
+	if err != nil {
+		return err
+	}
+	return public.writePublicFile(sshCertificateFile(r.File), []byte(ensureNewline(bundle.Certificate)))
+}
+
+// SSHKnownHostsInstaller represents an installation of the public key of an SSH CA as a @cert-authority entry of
+// the known_hosts file File, so the host certificates it issues are trusted for the hosts in SSHHostPatterns
+type SSHKnownHostsInstaller struct {
+	sshFileInstaller
+}
+
+// NewSSHKnownHostsInstaller returns a new installer of type SSHKNOWNHOSTS with the values defined in inst
+func NewSSHKnownHostsInstaller(inst domain.Installation) SSHKnownHostsInstaller {
+	return SSHKnownHostsInstaller{sshFileInstaller{inst}}
+}
+
+// Check returns true when the known_hosts file has no entry trusting the CA whose public key is caPublicKey
+// for the host patterns of the installation
+func (r SSHKnownHostsInstaller) Check(_ context.Context, _ string, _ domain.SSHRequest, caPublicKey string) (bool, error) {
+	zap.L().Info("checking SSH CA in known_hosts", zap.String("format", r.Type.String()), zap.String("location", r.File))
+
+	entry, err := r.entry(caPublicKey)
+	if err != nil {
+		return false, err
+	}
+	lines, err := readLines(r.File)
+	if err != nil {
+		return false, err
+	}
+	for _, line := range lines {
+		if line == entry {
+			return false, nil
+		}
+	}
+	return true, nil
+}
+
+// Install replaces the entry written by vcert for the host patterns of the installation, or appends it.
+// Any other entry of the known_hosts file is kept
+func (r SSHKnownHostsInstaller) Install(_ context.Context, bundle SSHBundle) error {
+	zap.L().Debug("installing SSH CA in known_hosts", zap.String("location", r.File))
+
+	entry, err := r.entry(bundle.CAPublicKey)
+	if err != nil {
+		return err
+	}
+	lines, err := readLines(r.File)
+	if err != nil {
+		return err
+	}
+
+	replaced := false
+	content := make([]string, 0, len(lines)+1)
+	for _, line := range lines {
+		if r.isManaged(line) {
+			if replaced {
+				continue
+			}
+			line = entry
+			replaced = true
+		}
+		content = append(content, line)
+	}
+	if !replaced {
+		content = append(content, entry)
+	}
+
+	return r.writePublicFile(r.File, []byte(strings.Join(content, "\n")+"\n"))
+}
+
+// patterns returns the host patterns of the entry, as written in the known_hosts file
+func (r SSHKnownHostsInstaller) patterns() string {
+	if len(r.SSHHostPatterns) == 0 {
+		return domain.DefaultSSHHostPattern
+	}
+	return strings.Join(r.SSHHostPatterns, ",")
+}
+
+// entry returns the known_hosts line trusting the CA whose public key is caPublicKey
+func (r SSHKnownHostsInstaller) entry(caPublicKey string) (string, error) {
+	ca, _, _, _, err := ssh.ParseAuthorizedKey([]byte(caPublicKey))
+	if err != nil {
+		return "", fmt.Errorf("failed to parse the public key of the SSH CA: %w", err)
+	}
+	key := strings.TrimSpace(string(ssh.MarshalAuthorizedKey(ca)))
+	return fmt.Sprintf("%s %s %s %s", sshCertAuthority, r.patterns(), key, sshKnownHostsMarker), nil
+}
+
+// isManaged returns true when line is the entry written by vcert for the host patterns of the installation
+func (r SSHKnownHostsInstaller) isManaged(line string) bool {
+	fields := strings.Fields(line)
+	return len(fields) == 5 && fields[0] == sshCertAuthority && fields[1] == r.patterns() &&
+		fields[4] == sshKnownHostsMarker
+}
+
+// SSHCAPubInstaller represents an installation of the public key of an SSH CA in the file File, i.e. for the
+// TrustedUserCAKeys option of sshd
+type SSHCAPubInstaller struct {
+	sshFileInstaller
+}
+
+// NewSSHCAPubInstaller returns a new installer of type SSHCAPUB with the values defined in inst
+func NewSSHCAPubInstaller(inst domain.Installation) SSHCAPubInstaller {
+	return SSHCAPubInstaller{sshFileInstaller{inst}}
+}
+
+// Check returns true when the file does not hold the public key of the CA whose public key is caPublicKey
+func (r SSHCAPubInstaller) Check(_ context.Context, _ string, _ domain.SSHRequest, caPublicKey string) (bool, error) {
+	zap.L().Info("checking SSH CA public key", zap.String("format", r.Type.String()), zap.String("location", r.File))
+
+	exists, err := util.FileExists(r.File)
+	if err != nil {
+		return false, err
+	}
+	if !exists {
+		return true, nil
+	}
+
+	ca, _, _, _, err := ssh.ParseAuthorizedKey([]byte(caPublicKey))
+	if err != nil {
+		return false, fmt.Errorf("failed to parse the public key of the SSH CA: %w", err)
+	}
+	data, err := os.ReadFile(r.File)
+	if err != nil {
+		return false, err
+	}
+	installed, _, _, _, err := ssh.ParseAuthorizedKey(data)
+	if err != nil {
+		zap.L().Info("SSH CA public key cannot be read, it will be installed again", zap.String("location", r.File),
+			zap.Error(err))
+		return true, nil
+	}
+	return !bytes.Equal(ca.Marshal(), installed.Marshal()), nil
+}
+
+// Install writes the public key of the CA
+func (r SSHCAPubInstaller) Install(_ context.Context, bundle SSHBundle) error {
+	zap.L().Debug("installing SSH CA public key", zap.String("location", r.File))
+
+	return r.writePublicFile(r.File, []byte(ensureNewline(bundle.CAPublicKey)))
+}
+
+// sshPublicKeyFile returns the location of the public key of the private key at keyFile, as named by ssh-keygen
+func sshPublicKeyFile(keyFile string) string {
+	return keyFile + ".pub"
+}
+
+// sshCertificateFile returns the location of the certificate of the private key at keyFile, as looked up by ssh
+func sshCertificateFile(keyFile string) string {
+	return keyFile + "-cert.pub"
+}
+
+// loadSSHCertificate reads the SSH certificate in authorized_keys format at location
+func loadSSHCertificate(location string) (*ssh.Certificate, error) {
+	data, err := os.ReadFile(location)
+	if err != nil {
+		return nil, err
+	}
+	key, _, _, _, err := ssh.ParseAuthorizedKey(data)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse SSH certificate %s: %w", location, err)
+	}
+	cert, ok := key.(*ssh.Certificate)
+	if !ok {
+		return nil, fmt.Errorf("%s is not an SSH certificate", location)
+	}
+	return cert, nil
+}
+
+// parseSSHPrivateKey parses the PEM encoded private key, decrypting it with passphrase when set
+func parseSSHPrivateKey(data []byte, passphrase string) (ssh.Signer, error) {
+	if passphrase != "" {
+		return ssh.ParsePrivateKeyWithPassphrase(data, []byte(passphrase))
+	}
+	return ssh.ParsePrivateKey(data)
+}
+
+// sshNeedRenewal returns true when the SSH certificate is expired or in its renew window.
+// Certificates valid forever are never renewed
+func sshNeedRenewal(cert *ssh.Certificate, renewBefore string) bool {
+	if cert.ValidBefore == ssh.CertTimeInfinity {
+		return false
+	}
+
+	window, err := domain.ParseRenewBefore(renewBefore)
+	if err != nil {
+		zap.L().Error(fmt.Sprintf("could not parse renewBefore value. Using default value [%s] instead", domain.DefaultRenewBefore),
+			zap.String("renewBefore", renewBefore), zap.Error(err))
+		window, _ = domain.ParseRenewBefore(domain.DefaultRenewBefore)
+	}
+	if window.Disabled {
+		zap.L().Warn("automatic renewal disabled", zap.String("keyId", cert.KeyId))
+		return false
+	}
+
+	validAfter := time.Unix(int64(cert.ValidAfter), 0)
+	validBefore := time.Unix(int64(cert.ValidBefore), 0)
+	if validBefore.Before(time.Now()) {
+		zap.L().Debug("SSH certificate is expired", zap.String("keyId", cert.KeyId))
+		return true
+	}
+
+	renewalDate := window.RenewalDate(validAfter, validBefore)
+	if time.Now().After(renewalDate) {
+		zap.L().Debug("SSH certificate in renew window", zap.String("keyId", cert.KeyId),
+			zap.Time("expirationDate", validBefore), zap.Time("renewalDate", renewalDate))
+		return true
+	}
+
+	zap.L().Debug("SSH certificate is still valid", zap.String("keyId", cert.KeyId),
+		zap.Time("expirationDate", validBefore), zap.Time("renewalDate", renewalDate))
+	return false
+}
+
+// readLines returns the lines of the file at location, without the trailing empty line.
+// A missing file has no lines
+func readLines(location string) ([]string, error) {
+	data, err := os.ReadFile(location)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	content := strings.TrimRight(strings.ReplaceAll(string(data), "\r\n", "\n"), "\n")
+	if content == "" {
+		return nil, nil
+	}
+	return strings.Split(content, "\n"), nil
+}
+
+// ensureNewline returns content ending with a newline, as expected by the OpenSSH files
+func ensureNewline(content string) string {
+	return strings.TrimRight(content, "\r\n") + "\n"
+}
@@ -0,0 +1,239 @@
+/*
+ * Copyright 2023 Venafi, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *  http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package installer
+
+import (
+	"bytes"
+	"context"
+	"crypto"
+	"crypto/x509"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+
+	"go.uber.org/zap"
+
+	"github.com/Venafi/vcert/v5/pkg/certificate"
+	"github.com/Venafi/vcert/v5/pkg/playbook/app/domain"
+	"github.com/Venafi/vcert/v5/pkg/playbook/util"
+)
+
+const (
+	// systemdCredentialFileMode is the mode of the credentials when mode is not set. systemd loads them as root, so
+	// nobody else needs to read them
+	systemdCredentialFileMode = "0600"
+
+	// systemdCredsCommand is the tool used to encrypt and decrypt the private key when systemdEncrypt is set
+	systemdCredsCommand = "systemd-creds"
+)
+
+// SystemdCredentialInstaller represents an installation in a systemd credstore. The certificate, followed by its
+// chain, and the private key are written as credentials that services load at start with LoadCredential (or
+// LoadCredentialEncrypted), so they only ever read them from their own, non-swappable, $CREDENTIALS_DIRECTORY.
+// The default credstore lives in /run, so the private key never touches a persistent disk
+type SystemdCredentialInstaller struct {
+	domain.Installation
+}
+
+// NewSystemdCredentialInstaller returns a new installer of type SYSTEMDCREDENTIAL with the values defined in inst
+func NewSystemdCredentialInstaller(inst domain.Installation) SystemdCredentialInstaller {
+	return SystemdCredentialInstaller{inst}
+}
+
+// Check is the method in charge of making the validations to install a new certificate:
+// 1. Does the certificate exists? > Install if it doesn't.
+// 2. Does the certificate is about to expire? Renew if about to expire.
+// Returns true if the certificate needs to be installed, along with the certificate currently installed, if any.
+func (r SystemdCredentialInstaller) Check(ctx context.Context, renewBefore string, request domain.PlaybookRequest) (bool, *x509.Certificate, error) {
+	renew, cert, err := r.files().Check(ctx, renewBefore, request)
+	if err != nil || renew || cert == nil || !r.SystemdEncrypt {
+		return renew, cert, err
+	}
+
+	// The encrypted private key is not read by the PEM installation
+	signer, err := r.PrivateKey(ctx)
+	// A nil crypto.Signer is passed on as a nil interface{}
+	var privateKey interface{}
+	if signer != nil {
+		privateKey = signer
+	}
+	return keyMismatch(cert, privateKey, err, zap.String("credential", r.SystemdCredential)), cert, nil
+}
+
+// Backup takes the certificate request and backs up the current version prior to overwriting
+func (r SystemdCredentialInstaller) Backup(ctx context.Context) error {
+	err := r.files().Backup(ctx)
+	if err != nil || !r.SystemdEncrypt {
+		return err
+	}
+
+	_, keyFile := r.GetSystemdCredentialFiles()
+	keyExists, err := util.FileExists(keyFile)
+	if err != nil || !keyExists {
+		return err
+	}
+	backupLocation, err := backupFile(keyFile, newBackupTimestamp(), r.GetBackupRetention())
+	if err != nil {
+		return err
+	}
+	zap.L().Info("certificate resource backed up", zap.String("location", keyFile),
+		zap.String("backupLocation", backupLocation))
+	return nil
+}
+
+// Install writes the certificate and the private key to the credstore. When systemdEncrypt is set, the private key
+// is handed to systemd-creds through its standard input, so it is only written encrypted
+func (r SystemdCredentialInstaller) Install(ctx context.Context, pcc certificate.PEMCollection) error {
+	zap.L().Debug("installing certificate", zap.String("format", r.Type.String()),
+		zap.String("credential", r.SystemdCredential))
+
+	privateKey := pcc.PrivateKey
+	if r.SystemdEncrypt {
+		pcc.PrivateKey = ""
+	}
+	err := r.files().Install(ctx, pcc)
+	if err != nil {
+		return err
+	}
+	if !r.SystemdEncrypt || privateKey == "" {
+		return nil
+	}
+
+	_, keyFile := r.GetSystemdCredentialFiles()
+	err = os.MkdirAll(filepath.Dir(keyFile), 0700)
+	if err != nil {
+		return err
+	}
+	err = runSystemdCreds(ctx, strings.NewReader(privateKey), nil, "encrypt", credentialNameArg(keyFile), "-", keyFile)
+	if err != nil {
+		return fmt.Errorf("could not encrypt the private key to %s: %w", keyFile, err)
+	}
+	return applyFilePermissions(r.permissions(), keyFile)
+}
+
+// Rollback restores the version of the certificate backed up by Backup, overwriting the installed one
+func (r SystemdCredentialInstaller) Rollback(ctx context.Context) error {
+	err := r.files().Rollback(ctx)
+	if err != nil || !r.SystemdEncrypt {
+		return err
+	}
+	_, keyFile := r.GetSystemdCredentialFiles()
+	return restoreBackup(keyFile)
+}
+
+// AfterInstallActions runs the actions declared in the Installer, in order: scripts run on a terminal,
+// while services, sites and webhooks are handled natively.
+//
+// No validations happen over the content of the AfterAction scripts, so caution is advised
+func (r SystemdCredentialInstaller) AfterInstallActions(ctx context.Context) (string, error) {
+	return r.files().AfterInstallActions(ctx)
+}
+
+// InstallValidationActions runs any instructions declared in the Installer on a terminal and expects
+// "0" for successful validation and "1" for a validation failure
+// No validations happen over the content of the InstallValidation string, so caution is advised
+func (r SystemdCredentialInstaller) InstallValidationActions(ctx context.Context) (string, error) {
+	return r.files().InstallValidationActions(ctx)
+}
+
+// PrivateKey returns the private key installed, or nil when nothing is installed. An encrypted private key is
+// decrypted with systemd-creds, without writing it to disk
+func (r SystemdCredentialInstaller) PrivateKey(ctx context.Context) (crypto.Signer, error) {
+	if !r.SystemdEncrypt {
+		return r.files().PrivateKey(ctx)
+	}
+
+	_, keyFile := r.GetSystemdCredentialFiles()
+	keyExists, err := util.FileExists(keyFile)
+	if err != nil || !keyExists {
+		return nil, err
+	}
+	var decrypted bytes.Buffer
+	err = runSystemdCreds(ctx, nil, &decrypted, "decrypt", credentialNameArg(keyFile), keyFile, "-")
+	if err != nil {
+		return nil, fmt.Errorf("could not decrypt the private key from %s: %w", keyFile, err)
+	}
+	privateKey, err := getPrivateKey(decrypted.String(), "")
+	if err != nil {
+		return nil, fmt.Errorf("could not load private key from %s: %w", keyFile, err)
+	}
+	return asSigner(privateKey)
+}
+
+// files returns the PEM installation of the credentials: the certificate followed by its chain, with the root last,
+// and the unencrypted private key, unless it is encrypted by systemd-creds
+func (r SystemdCredentialInstaller) files() PEMInstaller {
+	certFile, keyFile := r.GetSystemdCredentialFiles()
+	if r.SystemdEncrypt {
+		keyFile = ""
+	}
+	inst := r.permissions()
+	inst.AfterAction = r.AfterAction
+	inst.BackupRetention = r.BackupRetention
+	inst.ChainOrder = domain.ChainOrderRootLast
+	inst.File = certFile
+	inst.InstallValidation = r.InstallValidation
+	inst.KeyFile = keyFile
+	inst.PEMBundle = domain.PEMBundleCertChain
+	inst.Type = domain.FormatPEM
+	inst.ValidateRevocation = r.ValidateRevocation
+	return NewPEMInstaller(inst)
+}
+
+// permissions returns the mode, owner and group of the credentials
+func (r SystemdCredentialInstaller) permissions() domain.Installation {
+	mode := r.Mode
+	if mode == "" {
+		mode = systemdCredentialFileMode
+	}
+	return domain.Installation{
+		Group: r.Group,
+		Mode:  mode,
+		Owner: r.Owner,
+	}
+}
+
+// credentialNameArg returns the --name argument of systemd-creds. The name is embedded in the encrypted credential and
+// must match the name it is loaded with, which is the name of the file in the credstore
+func credentialNameArg(keyFile string) string {
+	return "--name=" + filepath.Base(keyFile)
+}
+
+// runSystemdCreds runs systemd-creds with args, feeding it stdin and writing its output to stdout
+func runSystemdCreds(ctx context.Context, stdin *strings.Reader, stdout *bytes.Buffer, args ...string) error {
+	zap.L().Debug("running command", zap.String("command", systemdCredsCommand), zap.Strings("args", args))
+
+	cmd := exec.CommandContext(ctx, systemdCredsCommand, args...)
+	if stdin != nil {
+		cmd.Stdin = stdin
+	}
+	if stdout != nil {
+		cmd.Stdout = stdout
+	}
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+	err := cmd.Run()
+	if err != nil {
+		if msg := strings.TrimSpace(stderr.String()); msg != "" {
+			return fmt.Errorf("%w: %s", err, msg)
+		}
+		return err
+	}
+	return nil
+}
@@ -0,0 +1,299 @@
+/*
+ * Copyright 2023 Venafi, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *  http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package installer
+
+import (
+	"context"
+	"crypto/x509"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"go.uber.org/zap"
+
+	"github.com/Venafi/vcert/v5/pkg/certificate"
+	"github.com/Venafi/vcert/v5/pkg/playbook/app/domain"
+	"github.com/Venafi/vcert/v5/pkg/playbook/util"
+	"github.com/Venafi/vcert/v5/pkg/playbook/util/vault"
+)
+
+// vaultBackupVersion is the custom metadata key that holds the secret version to restore on Rollback
+const vaultBackupVersion = "vcert-backup-version"
+
+// VaultKVInstaller represents an installation that will write the certificate bundle in a HashiCorp Vault KV v2 secret
+type VaultKVInstaller struct {
+	domain.Installation
+}
+
+// NewVaultKVInstaller returns a new installer of type VAULTKV with the values defined in inst
+func NewVaultKVInstaller(inst domain.Installation) VaultKVInstaller {
+	return VaultKVInstaller{inst}
+}
+
+// Check is the method in charge of making the validations to install a new certificate:
+// 1. Does the certificate exists? > Install if it doesn't.
+// 2. Does the certificate is about to expire? Renew if about to expire.
+// Returns true if the certificate needs to be installed, along with the certificate currently installed, if any.
+func (r VaultKVInstaller) Check(ctx context.Context, renewBefore string, _ domain.PlaybookRequest) (bool, *x509.Certificate, error) {
+	zap.L().Info("checking certificate health", zap.String("format", r.Type.String()), zap.String("location", r.location()))
+
+	client, err := r.getClient(ctx)
+	if err != nil {
+		return false, nil, err
+	}
+
+	secret, err := client.ReadSecret(ctx, r.VaultPath, 0)
+	if err != nil {
+		return false, nil, err
+	}
+	if secret == nil {
+		zap.L().Debug("secret does not exist", zap.String("location", r.location()))
+		return true, nil, nil
+	}
+
+	certData := secretField(secret, r.certField())
+	if certData == "" {
+		zap.L().Info("secret has no certificate", zap.String("location", r.location()), zap.String("field", r.certField()))
+		return true, nil, nil
+	}
+
+	// Load Certificate
+	cert, err := parsePEMCertificate([]byte(certData))
+	if err != nil {
+		return false, nil, err
+	}
+
+	// Check certificate expiration
+	renew := needRenewal(cert, renewBefore)
+
+	// Check certificate revocation
+	if !renew && r.ValidateRevocation {
+		renew = isRevoked(cert, parsePEMCertificates([]byte(secretField(secret, r.chainField()))))
+	}
+
+	return renew, cert, nil
+}
+
+// Backup records the current version of the secret in its custom metadata. KV v2 keeps previous versions of
+// the secret, so no copy of the data is needed
+func (r VaultKVInstaller) Backup(ctx context.Context) error {
+	zap.L().Debug("backing up certificate", zap.String("location", r.location()))
+
+	client, err := r.getClient(ctx)
+	if err != nil {
+		return err
+	}
+
+	metadata, err := client.ReadMetadata(ctx, r.VaultPath)
+	if err != nil {
+		return err
+	}
+	if metadata == nil || metadata.CurrentVersion == 0 {
+		zap.L().Info("secret does not exist, no back up taken", zap.String("location", r.location()))
+		return nil
+	}
+
+	customMetadata := metadata.CustomMetadata
+	if customMetadata == nil {
+		customMetadata = make(map[string]string)
+	}
+	customMetadata[vaultBackupVersion] = strconv.Itoa(metadata.CurrentVersion)
+
+	err = client.WriteCustomMetadata(ctx, r.VaultPath, customMetadata)
+	if err != nil {
+		return err
+	}
+
+	zap.L().Info("certificate backed up", zap.String("location", r.location()),
+		zap.Int("version", metadata.CurrentVersion))
+	return nil
+}
+
+// Install takes the certificate bundle and moves it to the location specified in the installer.
+//
+// Fields of the secret other than the certificate, private key and chain fields are kept
+func (r VaultKVInstaller) Install(ctx context.Context, pcc certificate.PEMCollection) error {
+	zap.L().Debug("installing certificate", zap.String("location", r.location()))
+
+	if len(pcc.Certificate) == 0 || len(pcc.PrivateKey) == 0 {
+		return fmt.Errorf("certificate and Private Key are required for HashiCorp Vault")
+	}
+
+	privateKey := pcc.PrivateKey
+	var err error
+	if strings.ToLower(r.KeyFormat) == domain.KeyFormatPKCS8 {
+		privateKey, err = MarshalPKCS8PrivateKey(pcc.PrivateKey, "")
+		if err != nil {
+			zap.L().Error("failed to prepare PrivateKey in PKCS8 format", zap.Error(err))
+			return err
+		}
+	}
+
+	client, err := r.getClient(ctx)
+	if err != nil {
+		return err
+	}
+
+	data := make(map[string]interface{})
+	current, err := client.ReadSecret(ctx, r.VaultPath, 0)
+	if err != nil {
+		return err
+	}
+	if current != nil {
+		for k, v := range current.Data {
+			data[k] = v
+		}
+	}
+	data[r.certField()] = pcc.Certificate
+	data[r.keyField()] = privateKey
+	data[r.chainField()] = strings.Join(pcc.Chain, "")
+
+	version, err := client.WriteSecret(ctx, r.VaultPath, data)
+	if err != nil {
+		zap.L().Error("could not write certificate to HashiCorp Vault", zap.String("location", r.location()), zap.Error(err))
+		return err
+	}
+
+	zap.L().Debug("certificate written", zap.String("location", r.location()), zap.Int("version", version))
+	return nil
+}
+
+// Rollback writes the version of the secret recorded by Backup as a new version.
+// Nothing is restored when Install did not create a new version
+func (r VaultKVInstaller) Rollback(ctx context.Context) error {
+	zap.L().Debug("rolling back certificate", zap.String("location", r.location()))
+
+	client, err := r.getClient(ctx)
+	if err != nil {
+		return err
+	}
+
+	metadata, err := client.ReadMetadata(ctx, r.VaultPath)
+	if err != nil {
+		return err
+	}
+	if metadata == nil || metadata.CustomMetadata[vaultBackupVersion] == "" {
+		zap.L().Info("no backup version found, nothing to restore", zap.String("location", r.location()))
+		return nil
+	}
+
+	backupVersion, err := strconv.Atoi(metadata.CustomMetadata[vaultBackupVersion])
+	if err != nil {
+		return fmt.Errorf("invalid backup version %q in secret metadata: %w", metadata.CustomMetadata[vaultBackupVersion], err)
+	}
+	if backupVersion == metadata.CurrentVersion {
+		zap.L().Info("secret has not changed, nothing to restore", zap.String("location", r.location()))
+		return nil
+	}
+
+	secret, err := client.ReadSecret(ctx, r.VaultPath, backupVersion)
+	if err != nil {
+		return err
+	}
+	if secret == nil {
+		return fmt.Errorf("backup version %d of secret %s not found", backupVersion, r.location())
+	}
+
+	_, err = client.WriteSecret(ctx, r.VaultPath, secret.Data)
+	if err != nil {
+		return err
+	}
+
+	zap.L().Info("certificate restored from backup version", zap.String("location", r.location()),
+		zap.Int("version", backupVersion))
+	return nil
+}
+
+// AfterInstallActions runs the actions declared in the Installer, in order: scripts run on a terminal,
+// while services, sites and webhooks are handled natively.
+//
+// No validations happen over the content of the AfterAction scripts, so caution is advised
+func (r VaultKVInstaller) AfterInstallActions(ctx context.Context) (string, error) {
+	zap.L().Debug("running after-install actions", zap.String("location", r.location()))
+
+	result, err := runAfterInstallActions(ctx, r.AfterAction)
+	return result, err
+}
+
+// InstallValidationActions runs any instructions declared in the Installer on a terminal and expects
+// "0" for successful validation and "1" for a validation failure
+// No validations happen over the content of the InstallValidation string, so caution is advised
+func (r VaultKVInstaller) InstallValidationActions(ctx context.Context) (string, error) {
+	zap.L().Debug("running install validation actions", zap.String("location", r.location()))
+
+	validationResult, err := util.ExecuteScript(ctx, r.InstallValidation)
+	if err != nil {
+		return "", err
+	}
+
+	return validationResult, err
+}
+
+func (r VaultKVInstaller) getClient(ctx context.Context) (*vault.Client, error) {
+	credentials := vault.Credentials{
+		Token:     r.VaultToken,
+		RoleID:    r.VaultRoleID,
+		SecretID:  r.VaultSecretID,
+		K8sRole:   r.VaultK8sRole,
+		AuthMount: r.VaultAuthMount,
+	}
+	client, err := vault.NewClient(ctx, r.VaultAddress, r.VaultNamespace, r.mount(), r.VaultCACert, credentials)
+	if err != nil {
+		zap.L().Error("could not authenticate to HashiCorp Vault", zap.Error(err))
+		return nil, err
+	}
+	return client, nil
+}
+
+func (r VaultKVInstaller) mount() string {
+	if r.VaultMount == "" {
+		return domain.DefaultVaultMount
+	}
+	return r.VaultMount
+}
+
+func (r VaultKVInstaller) certField() string {
+	if r.VaultCertField == "" {
+		return domain.DefaultVaultCertField
+	}
+	return r.VaultCertField
+}
+
+func (r VaultKVInstaller) keyField() string {
+	if r.VaultKeyField == "" {
+		return domain.DefaultVaultKeyField
+	}
+	return r.VaultKeyField
+}
+
+func (r VaultKVInstaller) chainField() string {
+	if r.VaultChainField == "" {
+		return domain.DefaultVaultChainField
+	}
+	return r.VaultChainField
+}
+
+func (r VaultKVInstaller) location() string {
+	return fmt.Sprintf("%s/%s/%s", strings.TrimSuffix(r.VaultAddress, "/"), strings.Trim(r.mount(), "/"),
+		strings.Trim(r.VaultPath, "/"))
+}
+
+// secretField returns the value of the given field of the secret, or an empty string if it is not a string
+func secretField(secret *vault.Secret, field string) string {
+	value, _ := secret.Data[field].(string)
+	return value
+}
@@ -0,0 +1,297 @@
+/*
+ * Copyright 2023 Venafi, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *  http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package installer
+
+import (
+	"context"
+	"crypto"
+	"crypto/x509"
+	"fmt"
+	"os"
+	"regexp"
+	"strings"
+
+	"go.uber.org/zap"
+
+	"github.com/Venafi/vcert/v5/pkg/certificate"
+	"github.com/Venafi/vcert/v5/pkg/playbook/app/domain"
+	"github.com/Venafi/vcert/v5/pkg/playbook/util"
+)
+
+// WebServerInstaller represents an installation of the certificate of NGINX or Apache HTTP Server. The certificate,
+// followed by its chain, and the private key are written to PEM files, the certificate directives of the configuration
+// file of the server are pointed to them when they point elsewhere, and the server is reloaded once its configuration
+// passes its own test
+type WebServerInstaller struct {
+	domain.Installation
+}
+
+// NewWebServerInstaller returns a new installer of type NGINX or APACHE with the values defined in inst
+func NewWebServerInstaller(inst domain.Installation) WebServerInstaller {
+	return WebServerInstaller{inst}
+}
+
+// webServerDirective is a directive of the configuration of a web server holding the path of an installed file
+type webServerDirective struct {
+	name     string
+	location string
+	required bool
+}
+
+// Check is the method in charge of making the validations to install a new certificate:
+// 1. Does the certificate exists? > Install if it doesn't.
+// 2. Does the certificate is about to expire? Renew if about to expire.
+// Returns true if the certificate needs to be installed, along with the certificate currently installed, if any.
+func (r WebServerInstaller) Check(ctx context.Context, renewBefore string, request domain.PlaybookRequest) (bool, *x509.Certificate, error) {
+	return r.files().Check(ctx, renewBefore, request)
+}
+
+// Backup takes the certificate request and backs up the current version prior to overwriting, along with the
+// configuration file of the server
+func (r WebServerInstaller) Backup(ctx context.Context) error {
+	err := r.files().Backup(ctx)
+	if err != nil {
+		return err
+	}
+
+	configExists, err := util.FileExists(r.WebServerConfig)
+	if err != nil {
+		return err
+	}
+	if !configExists {
+		return nil
+	}
+	backupLocation, err := backupFile(r.WebServerConfig, newBackupTimestamp(), r.GetBackupRetention())
+	if err != nil {
+		return err
+	}
+	zap.L().Info("web server configuration backed up", zap.String("location", r.WebServerConfig),
+		zap.String("backupLocation", backupLocation))
+	return nil
+}
+
+// Install writes the certificate and the private key to their files, points the configuration of the server to them,
+// and reloads the server once the configuration passes its test. The configuration is restored when it does not
+func (r WebServerInstaller) Install(ctx context.Context, pcc certificate.PEMCollection) error {
+	err := r.files().Install(ctx, pcc)
+	if err != nil {
+		return err
+	}
+
+	previous, changed, err := r.updateConfig()
+	if err != nil {
+		return err
+	}
+
+	err = r.testConfig(ctx)
+	if err != nil {
+		if changed {
+			if restoreErr := writeConfigFile(r.WebServerConfig, previous); restoreErr != nil {
+				return fmt.Errorf("%w. The configuration could not be restored: %w", err, restoreErr)
+			}
+			zap.L().Info("web server configuration restored", zap.String("location", r.WebServerConfig))
+		}
+		return err
+	}
+	return r.reload(ctx)
+}
+
+// Rollback restores the version of the certificate and of the configuration backed up by Backup, and reloads the server
+func (r WebServerInstaller) Rollback(ctx context.Context) error {
+	err := r.files().Rollback(ctx)
+	if err != nil {
+		return err
+	}
+	err = restoreBackup(r.WebServerConfig)
+	if err != nil {
+		return err
+	}
+
+	err = r.testConfig(ctx)
+	if err != nil {
+		return err
+	}
+	return r.reload(ctx)
+}
+
+// AfterInstallActions runs the actions declared in the Installer, in order: scripts run on a terminal,
+// while services, sites and webhooks are handled natively.
+//
+// No validations happen over the content of the AfterAction scripts, so caution is advised
+func (r WebServerInstaller) AfterInstallActions(ctx context.Context) (string, error) {
+	return r.files().AfterInstallActions(ctx)
+}
+
+// InstallValidationActions runs any instructions declared in the Installer on a terminal and expects
+// "0" for successful validation and "1" for a validation failure
+// No validations happen over the content of the InstallValidation string, so caution is advised
+func (r WebServerInstaller) InstallValidationActions(ctx context.Context) (string, error) {
+	return r.files().InstallValidationActions(ctx)
+}
+
+// PrivateKey returns the private key installed, or nil when nothing is installed
+func (r WebServerInstaller) PrivateKey(ctx context.Context) (crypto.Signer, error) {
+	return r.files().PrivateKey(ctx)
+}
+
+// files returns the PEM installation of the files of the server: the certificate followed by its chain, the
+// unencrypted private key and, optionally, the chain alone
+func (r WebServerInstaller) files() PEMInstaller {
+	return NewPEMInstaller(domain.Installation{
+		AfterAction:        r.AfterAction,
+		BackupRetention:    r.BackupRetention,
+		ChainFile:          r.ChainFile,
+		ChainOrder:         domain.ChainOrderRootLast,
+		ExcludeRoot:        r.ExcludeRoot,
+		File:               r.File,
+		Group:              r.Group,
+		InstallValidation:  r.InstallValidation,
+		KeyFile:            r.KeyFile,
+		Mode:               r.Mode,
+		Owner:              r.Owner,
+		PEMBundle:          domain.PEMBundleCertChain,
+		Type:               domain.FormatPEM,
+		ValidateRevocation: r.ValidateRevocation,
+	})
+}
+
+// directives returns the directives of the configuration of the server pointing to the installed files. The
+// directive of the chain is only updated when the configuration already has it
+func (r WebServerInstaller) directives() []webServerDirective {
+	if r.Type == domain.FormatApache {
+		return []webServerDirective{
+			{name: "SSLCertificateFile", location: r.File, required: true},
+			{name: "SSLCertificateKeyFile", location: r.KeyFile, required: true},
+			{name: "SSLCertificateChainFile", location: r.ChainFile},
+		}
+	}
+	return []webServerDirective{
+		{name: "ssl_certificate", location: r.File, required: true},
+		{name: "ssl_certificate_key", location: r.KeyFile, required: true},
+		{name: "ssl_trusted_certificate", location: r.ChainFile},
+	}
+}
+
+// updateConfig points the directives of the configuration file of the server to the installed files. It returns the
+// previous content of the file, and whether it was rewritten
+func (r WebServerInstaller) updateConfig() ([]byte, bool, error) {
+	previous, err := os.ReadFile(r.WebServerConfig)
+	if err != nil {
+		return nil, false, fmt.Errorf("could not read the web server configuration %s: %w", r.WebServerConfig, err)
+	}
+
+	config := string(previous)
+	changed := false
+	for _, directive := range r.directives() {
+		if directive.location == "" {
+			continue
+		}
+		var found, rewritten bool
+		config, found, rewritten = rewriteDirective(config, directive.name, directive.location,
+			r.Type == domain.FormatApache)
+		if !found && directive.required {
+			return nil, false, fmt.Errorf("no %s directive found in the web server configuration %s", directive.name,
+				r.WebServerConfig)
+		}
+		changed = changed || rewritten
+	}
+
+	if !changed {
+		zap.L().Debug("web server configuration is up to date", zap.String("location", r.WebServerConfig))
+		return previous, false, nil
+	}
+	err = writeConfigFile(r.WebServerConfig, []byte(config))
+	if err != nil {
+		return nil, false, err
+	}
+	zap.L().Info("web server configuration updated", zap.String("location", r.WebServerConfig))
+	return previous, true, nil
+}
+
+// testConfig runs the configuration test of the server, so a configuration it would not load is never reloaded
+func (r WebServerInstaller) testConfig(ctx context.Context) error {
+	args := []string{"-t"}
+	if r.Type == domain.FormatApache {
+		args = []string{"configtest"}
+	}
+	err := runCommand(ctx, r.GetWebServerCommand(), args...)
+	if err != nil {
+		return fmt.Errorf("the configuration of the %s server did not pass its test: %w", r.Type.String(), err)
+	}
+	return nil
+}
+
+// reload makes the server load the installed certificate and private key, without dropping its connections
+func (r WebServerInstaller) reload(ctx context.Context) error {
+	args := []string{"-s", "reload"}
+	if r.Type == domain.FormatApache {
+		args = []string{"graceful"}
+	}
+	err := runCommand(ctx, r.GetWebServerCommand(), args...)
+	if err != nil {
+		return fmt.Errorf("could not reload the %s server: %w", r.Type.String(), err)
+	}
+
+	zap.L().Info("web server reloaded the certificate", zap.String("format", r.Type.String()))
+	return nil
+}
+
+// rewriteDirective sets the value of every occurrence of the directive name in config to location. Apache directives
+// are case-insensitive. It returns the updated configuration, whether the directive was found, and whether any
+// occurrence pointed somewhere else
+func rewriteDirective(config string, name string, location string, apache bool) (string, bool, bool) {
+	var re *regexp.Regexp
+	if apache {
+		re = regexp.MustCompile(`(?mi)^([ \t]*` + regexp.QuoteMeta(name) + `[ \t]+)("[^"\n]*"|\S+)`)
+	} else {
+		re = regexp.MustCompile(`(?m)^([ \t]*` + regexp.QuoteMeta(name) + `[ \t]+)("[^"\n]*"|'[^'\n]*'|[^\s;]+)`)
+	}
+
+	matches := re.FindAllStringSubmatchIndex(config, -1)
+	var updated strings.Builder
+	last := 0
+	rewritten := false
+	for _, match := range matches {
+		value := strings.Trim(config[match[4]:match[5]], `"'`)
+		if value == location {
+			continue
+		}
+		updated.WriteString(config[last:match[4]])
+		if strings.ContainsAny(location, " \t;#{}'") {
+			updated.WriteString(`"` + location + `"`)
+		} else {
+			updated.WriteString(location)
+		}
+		last = match[5]
+		rewritten = true
+	}
+	updated.WriteString(config[last:])
+	return updated.String(), len(matches) > 0, rewritten
+}
+
+// writeConfigFile overwrites the configuration file at location with content, keeping its mode and ownership
+func writeConfigFile(location string, content []byte) error {
+	info, err := os.Stat(location)
+	if err != nil {
+		return err
+	}
+	err = os.WriteFile(location, content, info.Mode().Perm())
+	if err != nil {
+		return fmt.Errorf("could not write the web server configuration %s: %w", location, err)
+	}
+	return nil
+}
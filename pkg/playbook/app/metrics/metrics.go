@@ -0,0 +1,145 @@
+/*
+ * Copyright 2023 Venafi, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *  http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+// Package metrics exposes Prometheus metrics about the certificate tasks run by the playbook
+package metrics
+
+import (
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/collectors"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+const (
+	namespace = "vcert"
+	subsystem = "playbook"
+)
+
+var (
+	enrollments = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Subsystem: subsystem,
+		Name:      "enrollments_total",
+		Help:      "Number of certificates successfully enrolled, by task.",
+	}, []string{"task"})
+
+	renewals = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Subsystem: subsystem,
+		Name:      "renewals_total",
+		Help:      "Number of enrollments that replaced an installed certificate, by task.",
+	}, []string{"task"})
+
+	failures = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Subsystem: subsystem,
+		Name:      "failures_total",
+		Help:      "Number of task runs that failed, by task.",
+	}, []string{"task"})
+
+	expiries = newExpiryCollector()
+
+	registry = newRegistry()
+)
+
+func newRegistry() *prometheus.Registry {
+	r := prometheus.NewRegistry()
+	r.MustRegister(
+		collectors.NewGoCollector(),
+		collectors.NewProcessCollector(collectors.ProcessCollectorOpts{}),
+		enrollments,
+		renewals,
+		failures,
+		expiries,
+	)
+	return r
+}
+
+// Handler returns the http.Handler that serves the metrics in the Prometheus exposition format
+func Handler() http.Handler {
+	return promhttp.HandlerFor(registry, promhttp.HandlerOpts{})
+}
+
+// CertificateEnrolled records a successful enrollment for the task.
+// renewal is true when the new certificate replaces one that was already installed
+func CertificateEnrolled(task string, renewal bool) {
+	enrollments.WithLabelValues(task).Inc()
+	if renewal {
+		renewals.WithLabelValues(task).Inc()
+	}
+}
+
+// TaskFailed records a failed run of the task
+func TaskFailed(task string) {
+	failures.WithLabelValues(task).Inc()
+}
+
+// CertificateInstalled records the expiration date of the certificate found at the location of an installation
+func CertificateInstalled(task string, installationType string, location string, notAfter time.Time) {
+	expiries.set(expiryKey{task: task, installationType: installationType, location: location}, notAfter)
+}
+
+type expiryKey struct {
+	task             string
+	installationType string
+	location         string
+}
+
+// expiryCollector reports the days until expiry of every installed certificate.
+// The value is computed when the metrics are collected, so it stays accurate between task runs
+type expiryCollector struct {
+	desc     *prometheus.Desc
+	mu       sync.Mutex
+	notAfter map[expiryKey]time.Time
+	now      func() time.Time
+}
+
+func newExpiryCollector() *expiryCollector {
+	return &expiryCollector{
+		desc: prometheus.NewDesc(prometheus.BuildFQName(namespace, subsystem, "certificate_days_until_expiry"),
+			"Days until the installed certificate expires, by task, installation type and location.",
+			[]string{"task", "type", "location"}, nil),
+		notAfter: make(map[expiryKey]time.Time),
+		now:      time.Now,
+	}
+}
+
+func (c *expiryCollector) set(key expiryKey, notAfter time.Time) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.notAfter[key] = notAfter
+}
+
+// Describe implements prometheus.Collector
+func (c *expiryCollector) Describe(ch chan<- *prometheus.Desc) {
+	ch <- c.desc
+}
+
+// Collect implements prometheus.Collector
+func (c *expiryCollector) Collect(ch chan<- prometheus.Metric) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	now := c.now()
+	for key, notAfter := range c.notAfter {
+		days := notAfter.Sub(now).Hours() / 24
+		ch <- prometheus.MustNewConstMetric(c.desc, prometheus.GaugeValue, days, key.task, key.installationType, key.location)
+	}
+}
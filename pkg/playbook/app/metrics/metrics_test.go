@@ -0,0 +1,47 @@
+/*
+ * Copyright 2023 Venafi, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *  http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package metrics
+
+import (
+	"io"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestHandler(t *testing.T) {
+	now := time.Date(2023, 10, 1, 0, 0, 0, 0, time.UTC)
+	expiries.now = func() time.Time { return now }
+	defer func() { expiries.now = time.Now }()
+
+	CertificateEnrolled("web", false)
+	CertificateEnrolled("web", true)
+	TaskFailed("db")
+	CertificateInstalled("web", "PEM", "/etc/ssl/web.crt", now.Add(36*time.Hour))
+
+	recorder := httptest.NewRecorder()
+	Handler().ServeHTTP(recorder, httptest.NewRequest("GET", "/metrics", nil))
+	body, _ := io.ReadAll(recorder.Body)
+	output := string(body)
+
+	assert.Contains(t, output, `vcert_playbook_enrollments_total{task="web"} 2`)
+	assert.Contains(t, output, `vcert_playbook_renewals_total{task="web"} 1`)
+	assert.Contains(t, output, `vcert_playbook_failures_total{task="db"} 1`)
+	assert.Contains(t, output, `vcert_playbook_certificate_days_until_expiry{location="/etc/ssl/web.crt",task="web",type="PEM"} 1.5`)
+}
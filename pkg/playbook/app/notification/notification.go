@@ -0,0 +1,196 @@
+/*
+ * Copyright 2023 Venafi, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *  http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+// Package notification sends the results of the certificate tasks to webhooks, Slack and Microsoft Teams
+package notification
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"go.uber.org/zap"
+
+	"github.com/Venafi/vcert/v5/pkg/playbook/app/domain"
+)
+
+const (
+	sendTimeout  = 30 * time.Second
+	maxBodyBytes = 1024 * 1024
+)
+
+// defaultTemplates build the message of Slack and Teams notifications, when they define no template
+var defaultTemplates = map[string]string{
+	domain.EventSuccess: "Certificate ${commonName} was enrolled by task ${task}. Serial: ${serial}, expires on ${notAfter}",
+	domain.EventFailure: "Task ${task} failed for certificate ${commonName}: ${error}",
+	domain.EventExpiring: "Certificate ${commonName} of task ${task} expires on ${notAfter}, in ${daysRemaining} days. " +
+		"Serial: ${serial}",
+	domain.EventDrift: "Certificate ${commonName} of task ${task} was replaced out of band at ${location}. " +
+		"Thumbprint found: ${thumbprint}",
+}
+
+var titles = map[string]string{
+	domain.EventSuccess:  "Certificate enrolled",
+	domain.EventFailure:  "Certificate task failed",
+	domain.EventExpiring: "Certificate about to expire",
+	domain.EventDrift:    "Certificate replaced out of band",
+}
+
+// Event is what happened to the certificate of a task. It is posted as JSON by webhooks without a template, and
+// its fields are expanded in the templates by their JSON name, i.e. ${commonName}
+type Event struct {
+	Event         string `json:"event"`
+	Task          string `json:"task"`
+	CommonName    string `json:"commonName"`
+	Serial        string `json:"serial,omitempty"`
+	Thumbprint    string `json:"thumbprint,omitempty"`
+	NotAfter      string `json:"notAfter,omitempty"`
+	DaysRemaining int    `json:"daysRemaining,omitempty"`
+	Zone          string `json:"zone,omitempty"`
+	Location      string `json:"location,omitempty"`
+	Error         string `json:"error,omitempty"`
+	Time          string `json:"time"`
+}
+
+// Send fires the notifications triggered by event. Failures are logged, as they do not affect the task
+func Send(logger *zap.Logger, notifications []domain.Notification, event Event) {
+	if event.Time == "" {
+		event.Time = time.Now().UTC().Format(time.RFC3339)
+	}
+	for _, n := range notifications {
+		if !n.NotifiesOn(event.Event) {
+			continue
+		}
+		err := send(n, event)
+		if err != nil {
+			logger.Warn("failed to send notification", zap.String("type", n.Type), zap.String("event", event.Event),
+				zap.Error(err))
+			continue
+		}
+		logger.Debug("notification sent", zap.String("type", n.Type), zap.String("event", event.Event))
+	}
+}
+
+func send(n domain.Notification, event Event) error {
+	body, err := buildBody(n, event)
+	if err != nil {
+		return err
+	}
+
+	req, err := http.NewRequest(http.MethodPost, n.URL, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	for name, value := range n.Headers {
+		req.Header.Set(name, os.ExpandEnv(value))
+	}
+	if req.Header.Get("Content-Type") == "" {
+		req.Header.Set("Content-Type", "application/json")
+	}
+
+	client := http.Client{Timeout: sendTimeout}
+	resp, err := client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		_ = resp.Body.Close()
+	}()
+	// Drain the body so the connection can be reused
+	_, _ = io.Copy(io.Discard, io.LimitReader(resp.Body, maxBodyBytes))
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return fmt.Errorf("unexpected status %s", resp.Status)
+	}
+	return nil
+}
+
+// buildBody returns the payload of the notification. Webhooks post the rendered template as is, or the event as
+// JSON without a template. Slack and Teams post the rendered template, or a default message, in their own format
+func buildBody(n domain.Notification, event Event) ([]byte, error) {
+	kind := strings.ToLower(n.Type)
+	if kind == domain.NotificationWebhook && n.Template == "" {
+		return json.Marshal(event)
+	}
+
+	text := n.Template
+	if text == "" {
+		text = defaultTemplates[event.Event]
+	}
+	// Webhook templates are usually JSON documents, whose strings must stay valid once expanded
+	escapeJSON := kind == domain.NotificationWebhook && isJSON(n)
+	message := expand(text, event, escapeJSON)
+
+	switch kind {
+	case domain.NotificationSlack:
+		return json.Marshal(map[string]string{"text": message})
+	case domain.NotificationTeams:
+		return json.Marshal(map[string]string{
+			"@type":    "MessageCard",
+			"@context": "https://schema.org/extensions",
+			"summary":  titles[event.Event],
+			"title":    titles[event.Event],
+			"text":     message,
+		})
+	default:
+		return []byte(message), nil
+	}
+}
+
+// expand replaces ${name} and $name in text by the field of event with that JSON name, or by the environment
+// variable name otherwise. Values are escaped to be included in JSON strings when escapeJSON is true
+func expand(text string, event Event, escapeJSON bool) string {
+	fields := map[string]string{
+		"event":         event.Event,
+		"task":          event.Task,
+		"commonName":    event.CommonName,
+		"serial":        event.Serial,
+		"thumbprint":    event.Thumbprint,
+		"notAfter":      event.NotAfter,
+		"daysRemaining": strconv.Itoa(event.DaysRemaining),
+		"zone":          event.Zone,
+		"location":      event.Location,
+		"error":         event.Error,
+		"time":          event.Time,
+	}
+	return os.Expand(text, func(name string) string {
+		value, found := fields[name]
+		if !found {
+			value = os.Getenv(name)
+		}
+		if escapeJSON {
+			quoted, _ := json.Marshal(value)
+			value = string(quoted[1 : len(quoted)-1])
+		}
+		return value
+	})
+}
+
+// isJSON returns true when the body of the webhook is sent as JSON, which is the default
+func isJSON(n domain.Notification) bool {
+	for name, value := range n.Headers {
+		if strings.EqualFold(name, "Content-Type") {
+			return strings.Contains(strings.ToLower(value), "json")
+		}
+	}
+	return true
+}
@@ -0,0 +1,139 @@
+/*
+ * Copyright 2023 Venafi, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *  http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package notification
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"go.uber.org/zap"
+
+	"github.com/Venafi/vcert/v5/pkg/playbook/app/domain"
+)
+
+type received struct {
+	body    []byte
+	headers http.Header
+}
+
+func newServer(t *testing.T, status int) (*httptest.Server, *[]received) {
+	t.Helper()
+	requests := &[]received{}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		*requests = append(*requests, received{body: body, headers: r.Header})
+		w.WriteHeader(status)
+	}))
+	t.Cleanup(server.Close)
+	return server, requests
+}
+
+var testEvent = Event{
+	Event:         domain.EventExpiring,
+	Task:          "mytask",
+	CommonName:    "myapp.venafi.example",
+	Serial:        "1234",
+	NotAfter:      "2030-01-02T03:04:05Z",
+	DaysRemaining: 12,
+}
+
+func TestSendSlackAndTeams(t *testing.T) {
+	server, requests := newServer(t, http.StatusOK)
+	notifications := []domain.Notification{
+		{Type: domain.NotificationSlack, URL: server.URL},
+		{Type: "Teams", URL: server.URL, Template: "${commonName} expires in ${daysRemaining} days"},
+	}
+	Send(zap.NewNop(), notifications, testEvent)
+
+	if len(*requests) != 2 {
+		t.Fatalf("expected 2 requests, got %d", len(*requests))
+	}
+	slack := map[string]string{}
+	if err := json.Unmarshal((*requests)[0].body, &slack); err != nil {
+		t.Fatal(err)
+	}
+	expected := "Certificate myapp.venafi.example of task mytask expires on 2030-01-02T03:04:05Z, in 12 days. Serial: 1234"
+	if slack["text"] != expected {
+		t.Fatalf("unexpected Slack message %q", slack["text"])
+	}
+
+	teams := map[string]string{}
+	if err := json.Unmarshal((*requests)[1].body, &teams); err != nil {
+		t.Fatal(err)
+	}
+	if teams["@type"] != "MessageCard" || teams["title"] != "Certificate about to expire" ||
+		teams["text"] != "myapp.venafi.example expires in 12 days" {
+		t.Fatalf("unexpected Teams message %v", teams)
+	}
+}
+
+func TestSendWebhook(t *testing.T) {
+	server, requests := newServer(t, http.StatusAccepted)
+	t.Setenv("NOTIFICATION_TOKEN", "secret")
+	notifications := []domain.Notification{
+		{Type: domain.NotificationWebhook, URL: server.URL, Headers: map[string]string{"Authorization": "Bearer ${NOTIFICATION_TOKEN}"}},
+		{Type: domain.NotificationWebhook, URL: server.URL, Template: "${task},${serial}", Headers: map[string]string{"Content-Type": "text/csv"}},
+		// Not sent, as it is only fired on failures
+		{Type: domain.NotificationWebhook, URL: server.URL, Events: []string{domain.EventFailure}},
+	}
+	Send(zap.NewNop(), notifications, testEvent)
+
+	if len(*requests) != 2 {
+		t.Fatalf("expected 2 requests, got %d", len(*requests))
+	}
+	event := Event{}
+	if err := json.Unmarshal((*requests)[0].body, &event); err != nil {
+		t.Fatal(err)
+	}
+	if event.Event != domain.EventExpiring || event.Serial != "1234" || event.Time == "" {
+		t.Fatalf("unexpected event %v", event)
+	}
+	if (*requests)[0].headers.Get("Authorization") != "Bearer secret" || (*requests)[0].headers.Get("Content-Type") != "application/json" {
+		t.Fatalf("unexpected headers %v", (*requests)[0].headers)
+	}
+	if string((*requests)[1].body) != "mytask,1234" || (*requests)[1].headers.Get("Content-Type") != "text/csv" {
+		t.Fatalf("unexpected templated request %s", (*requests)[1].body)
+	}
+}
+
+func TestSendFailure(t *testing.T) {
+	server, _ := newServer(t, http.StatusInternalServerError)
+	err := send(domain.Notification{Type: domain.NotificationSlack, URL: server.URL}, testEvent)
+	if err == nil {
+		t.Fatal("expected an error for a 500 status")
+	}
+}
+
+func TestBuildBodyEscapesJSON(t *testing.T) {
+	t.Setenv("NOTIFICATION_SOURCE", "vcert")
+	event := Event{Event: domain.EventFailure, Task: "mytask", Error: `zone "My\App" not found`}
+	n := domain.Notification{Type: domain.NotificationWebhook, Template: `{"task": "${task}", "error": "${error}", "source": "$NOTIFICATION_SOURCE"}`}
+	body, err := buildBody(n, event)
+	if err != nil {
+		t.Fatal(err)
+	}
+	decoded := map[string]string{}
+	if err = json.Unmarshal(body, &decoded); err != nil {
+		t.Fatalf("invalid JSON %s: %s", body, err)
+	}
+	if decoded["error"] != event.Error || decoded["source"] != "vcert" {
+		t.Fatalf("unexpected body %s", body)
+	}
+}
@@ -29,4 +29,10 @@ var (
 	ErrTextTplParsing = fmt.Errorf("failed to parse the playbook file")
 	// ErrFileUnmarshall is thrown when the content of the Playbook file cannot be successfully unmarshalled into a domain.Playbook object
 	ErrFileUnmarshall = fmt.Errorf("failed to unmarshal the playbook file")
+	// ErrSchemaValidation is thrown when the Playbook file has unknown fields, values of the wrong type or missing required fields
+	ErrSchemaValidation = fmt.Errorf("playbook file does not match the playbook schema")
+	// ErrSecretResolution is thrown when a secret reference in the Playbook file cannot be resolved.
+	//
+	// E.g. secretRef:vault:secret/vcert/tpp#password
+	ErrSecretResolution = fmt.Errorf("failed to resolve a secret in the playbook file")
 )
@@ -18,6 +18,8 @@ package parser
 
 import (
 	"bytes"
+	"context"
+	"errors"
 	"fmt"
 	"os"
 	"strings"
@@ -27,6 +29,7 @@ import (
 	"gopkg.in/yaml.v3"
 
 	"github.com/Venafi/vcert/v5/pkg/playbook/app/domain"
+	"github.com/Venafi/vcert/v5/pkg/playbook/app/secret"
 )
 
 var errorTemplate = "%w: %s"
@@ -51,7 +54,27 @@ func ReadPlaybook(location string) (domain.Playbook, error) {
 		return playbook, fmt.Errorf(errorTemplate, ErrTextTplParsing, err.Error())
 	}
 
-	err = yaml.Unmarshal(data, &playbook)
+	// Checked before unmarshalling, which ignores unknown fields
+	err = ValidateSchema(data)
+	var schemaErr *SchemaError
+	if errors.As(err, &schemaErr) {
+		return playbook, fmt.Errorf(errorTemplate, ErrSchemaValidation, err.Error())
+	} else if err != nil {
+		return playbook, fmt.Errorf(errorTemplate, ErrFileUnmarshall, err.Error())
+	}
+
+	node := &yaml.Node{}
+	err = yaml.Unmarshal(data, node)
+	if err != nil {
+		return playbook, fmt.Errorf(errorTemplate, ErrFileUnmarshall, err.Error())
+	}
+
+	err = resolveSecrets(node, secret.NewResolver(context.Background()))
+	if err != nil {
+		return playbook, fmt.Errorf(errorTemplate, ErrSecretResolution, err.Error())
+	}
+
+	err = node.Decode(&playbook)
 	if err != nil {
 		return playbook, fmt.Errorf(errorTemplate, ErrFileUnmarshall, err.Error())
 	}
@@ -69,16 +92,57 @@ func ReadPlaybookRaw(location string) (map[string]interface{}, error) {
 		return nil, err
 	}
 
+	node := &yaml.Node{}
+	err = yaml.Unmarshal(data, node)
+	if err != nil {
+		return nil, fmt.Errorf(errorTemplate, ErrFileUnmarshall, err.Error())
+	}
 	dataMap := make(map[string]interface{})
-	err = yaml.Unmarshal(data, &dataMap)
+	err = node.Decode(&dataMap)
 	if err != nil {
 		return nil, fmt.Errorf(errorTemplate, ErrFileUnmarshall, err.Error())
 	}
+	keepEncrypted(node, dataMap)
 
 	zap.L().Info("playbook data successfully parsed")
 	return dataMap, nil
 }
 
+// keepEncrypted replaces the values tagged !encrypted in value, decoded from node, with secret.Encrypted values, so
+// they stay encrypted when the playbook is written back
+func keepEncrypted(node *yaml.Node, value interface{}) interface{} {
+	switch node.Kind {
+	case yaml.DocumentNode:
+		if len(node.Content) == 1 {
+			return keepEncrypted(node.Content[0], value)
+		}
+	case yaml.ScalarNode:
+		if node.Tag == secret.Tag {
+			return secret.Encrypted(node.Value)
+		}
+	case yaml.MappingNode:
+		m, ok := value.(map[string]interface{})
+		if !ok {
+			return value
+		}
+		for i := 0; i+1 < len(node.Content); i += 2 {
+			key := node.Content[i].Value
+			if v, found := m[key]; found {
+				m[key] = keepEncrypted(node.Content[i+1], v)
+			}
+		}
+	case yaml.SequenceNode:
+		s, ok := value.([]interface{})
+		if !ok || len(s) != len(node.Content) {
+			return value
+		}
+		for i := range s {
+			s[i] = keepEncrypted(node.Content[i], s[i])
+		}
+	}
+	return value
+}
+
 func readFile(location string) ([]byte, error) {
 	var data []byte
 
@@ -97,6 +161,40 @@ func readFile(location string) ([]byte, error) {
 	return data, nil
 }
 
+// resolveSecrets replaces the secret references in the values of node with the secrets they point to, and the
+// values tagged !encrypted with their plaintext.
+// Secrets are resolved after the templates, so their values are never parsed as templates nor shown in the errors
+func resolveSecrets(node *yaml.Node, resolver *secret.Resolver) error {
+	if node.Kind == yaml.ScalarNode && node.Tag == secret.Tag {
+		value, err := resolver.Decrypt(node.Value)
+		if err != nil {
+			return fmt.Errorf("line %d: %w", node.Line, err)
+		}
+		node.Tag = "!!str"
+		node.Value = value
+		return nil
+	}
+	if node.Kind == yaml.ScalarNode {
+		if !secret.IsRef(node.Value) {
+			return nil
+		}
+		value, err := resolver.Resolve(node.Value)
+		if err != nil {
+			return fmt.Errorf("line %d: %w", node.Line, err)
+		}
+		node.Value = value
+		return nil
+	}
+
+	for _, child := range node.Content {
+		err := resolveSecrets(child, resolver)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
 func parseConfigTemplate(b []byte) ([]byte, error) {
 	// Valid functions for the config file template
 	fm := template.FuncMap{
@@ -107,6 +205,15 @@ func parseConfigTemplate(b []byte) ([]byte, error) {
 			}
 			return "", fmt.Errorf("environment variable not defined: %s", e)
 		},
+		// File returns the content of the file at path, without the trailing line breaks.
+		// Useful to read secrets mounted as files, i.e. Kubernetes or Docker secrets
+		"File": func(path string) (string, error) {
+			data, err := os.ReadFile(path)
+			if err != nil {
+				return "", fmt.Errorf("could not read file %s: %w", path, err)
+			}
+			return strings.TrimRight(string(data), "\r\n"), nil
+		},
 		"Hostname": func() string {
 			hostname, err := os.Hostname()
 			if err != nil {
@@ -17,6 +17,7 @@
 package parser
 
 import (
+	"fmt"
 	"math/rand"
 	"net/http"
 	"os"
@@ -25,6 +26,8 @@ import (
 	"time"
 
 	"github.com/stretchr/testify/suite"
+
+	"github.com/Venafi/vcert/v5/pkg/playbook/app/secret"
 )
 
 type ReaderSuite struct {
@@ -101,6 +104,142 @@ func (s *ReaderSuite) TestReader_ReadPlaybookTpl() {
 
 }
 
+func (s *ReaderSuite) TestReader_ReadPlaybookFileTpl() {
+	dir := s.T().TempDir()
+	secretFile := filepath.Join(dir, "apikey")
+	err := os.WriteFile(secretFile, []byte(s.accessToken+"\n"), 0600)
+	s.Nil(err)
+
+	content := fmt.Sprintf(`config:
+  connection:
+    platform: vaas
+    credentials:
+      apiKey: '{{ File "%s" }}'
+certificateTasks:
+  - name: testTask
+    request:
+      zone: '{{ Env "TPP_REFRESH_TOKEN" }}'
+`, filepath.ToSlash(secretFile))
+	playbookFile := filepath.Join(dir, "playbook.yaml")
+	err = os.WriteFile(playbookFile, []byte(content), 0600)
+	s.Nil(err)
+
+	pb, err := ReadPlaybook(playbookFile)
+	s.Nil(err)
+	s.Equal(s.accessToken, pb.Config.Connection.Credentials.APIKey)
+	s.Equal(s.refreshToken, pb.CertificateTasks[0].Request.Zone)
+
+	s.Run("FileNotFound", func() {
+		err = os.WriteFile(playbookFile, []byte(`config: '{{ File "/foo/bar/missing" }}'`), 0600)
+		s.Nil(err)
+
+		_, err = ReadPlaybook(playbookFile)
+		s.ErrorIs(err, ErrTextTplParsing)
+	})
+}
+
+func (s *ReaderSuite) TestReader_ReadPlaybookSecretRef() {
+	dir := s.T().TempDir()
+	s.T().Setenv("VCERT_TEST_P12_PASSWORD", "{{ not a template }}")
+
+	content := `config:
+  connection:
+    platform: tpp
+    credentials:
+      accessToken: secretRef:env:TPP_ACCESS_TOKEN
+      refreshToken: '{{ Env "TPP_REFRESH_TOKEN" }}'
+certificateTasks:
+  - name: testTask
+    request:
+      zone: secretRef:env:TPP_ACCESS_TOKEN
+    installations:
+      - format: PKCS12
+        file: /path/to/cert.p12
+        p12Password: "secretRef:env:VCERT_TEST_P12_PASSWORD"
+`
+	playbookFile := filepath.Join(dir, "playbook.yaml")
+	err := os.WriteFile(playbookFile, []byte(content), 0600)
+	s.Nil(err)
+
+	pb, err := ReadPlaybook(playbookFile)
+	s.Nil(err)
+	s.Equal(s.accessToken, pb.Config.Connection.Credentials.AccessToken)
+	s.Equal(s.refreshToken, pb.Config.Connection.Credentials.RefreshToken)
+	s.Equal(s.accessToken, pb.CertificateTasks[0].Request.Zone)
+	s.Equal("{{ not a template }}", pb.CertificateTasks[0].Installations[0].P12Password)
+
+	s.Run("UndefinedSecret", func() {
+		err = os.WriteFile(playbookFile, []byte(`config:
+  connection:
+    platform: vaas
+    credentials:
+      apiKey: secretRef:env:VCERT_TEST_UNDEFINED
+`), 0600)
+		s.Nil(err)
+
+		_, err = ReadPlaybook(playbookFile)
+		s.ErrorIs(err, ErrSecretResolution)
+		s.ErrorContains(err, "line 5")
+	})
+}
+
+func (s *ReaderSuite) TestReader_ReadPlaybookEncrypted() {
+	dir := s.T().TempDir()
+	s.T().Setenv(secret.EnvPassphrase, "correct horse")
+	accessToken, err := secret.Encrypt(s.accessToken, secret.KeyPassphrase, "correct horse")
+	s.Nil(err)
+	p12Password, err := secret.Encrypt("{{ not a template }}", secret.KeyPassphrase, "correct horse")
+	s.Nil(err)
+
+	content := fmt.Sprintf(`config:
+  connection:
+    platform: tpp
+    credentials:
+      accessToken: !encrypted %s
+certificateTasks:
+  - name: testTask
+    request:
+      zone: Open Source
+    installations:
+      - format: PKCS12
+        file: /path/to/cert.p12
+        p12Password: !encrypted "%s"
+`, accessToken, p12Password)
+	playbookFile := filepath.Join(dir, "playbook.yaml")
+	err = os.WriteFile(playbookFile, []byte(content), 0600)
+	s.Nil(err)
+
+	pb, err := ReadPlaybook(playbookFile)
+	s.Nil(err)
+	s.Equal(s.accessToken, pb.Config.Connection.Credentials.AccessToken)
+	s.Equal("{{ not a template }}", pb.CertificateTasks[0].Installations[0].P12Password)
+
+	s.Run("WrittenBackEncrypted", func() {
+		dataMap, err := ReadPlaybookRaw(playbookFile)
+		s.Nil(err)
+		credentials := dataMap["config"].(map[string]interface{})["connection"].(map[string]interface{})["credentials"].(map[string]interface{})
+		s.Equal(secret.Encrypted(accessToken), credentials["accessToken"])
+
+		err = WritePlaybook(dataMap, playbookFile)
+		s.Nil(err)
+		data, err := os.ReadFile(playbookFile)
+		s.Nil(err)
+		s.NotContains(string(data), s.accessToken)
+
+		pb, err = ReadPlaybook(playbookFile)
+		s.Nil(err)
+		s.Equal(s.accessToken, pb.Config.Connection.Credentials.AccessToken)
+		s.Equal("{{ not a template }}", pb.CertificateTasks[0].Installations[0].P12Password)
+	})
+
+	s.Run("NoPassphrase", func() {
+		s.T().Setenv(secret.EnvPassphrase, "")
+		_, err = ReadPlaybook(playbookFile)
+		s.ErrorIs(err, ErrSecretResolution)
+		s.ErrorContains(err, secret.EnvPassphrase)
+	})
+}
+
 func (s *ReaderSuite) TestReader_ReadPlaybookRaw() {
 	dataMap, err := ReadPlaybookRaw(filepath.Join(s.playbookFolder, "sample_tpl.yaml"))
 	s.Nil(err)
@@ -0,0 +1,372 @@
+/*
+ * Copyright 2023 Venafi, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *  http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package parser
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+	"time"
+
+	"go.uber.org/zap"
+	"gopkg.in/yaml.v3"
+
+	"github.com/Venafi/vcert/v5/pkg/endpoint"
+	"github.com/Venafi/vcert/v5/pkg/playbook/app/domain"
+)
+
+var (
+	stringType   = reflect.TypeOf("")
+	durationType = reflect.TypeOf(time.Duration(0))
+
+	authenticationType      = reflect.TypeOf(domain.Authentication{})
+	afterInstallActionType  = reflect.TypeOf(domain.AfterInstallAction{})
+	afterInstallActionsType = reflect.TypeOf(domain.AfterInstallActions{})
+	connectionType          = reflect.TypeOf(domain.Connection{})
+	unmarshalerType         = reflect.TypeOf((*yaml.Unmarshaler)(nil)).Elem()
+)
+
+// authenticationFields are the keys read by domain.Authentication, which are not the yaml tags of its fields
+var authenticationFields = map[string]reflect.Type{
+	"accessToken":       stringType,
+	"acme":              reflect.TypeOf(endpoint.ACMEAccount{}),
+	"apiKey":            stringType,
+	"audience":          stringType,
+	"clientCertFile":    stringType,
+	"clientId":          stringType,
+	"clientKeyFile":     stringType,
+	"clientP12File":     stringType,
+	"clientP12Password": stringType,
+	"clientSecret":      stringType,
+	"p12Task":           stringType,
+	"password":          stringType,
+	"refreshToken":      stringType,
+	"scope":             stringType,
+	"tokenURL":          stringType,
+	"user":              stringType,
+}
+
+// requiredFields are the keys every mapping of the type must have, whatever the values of the other keys.
+// Keys required depending on other values, like the installation file, are checked by domain.Playbook.IsValid
+var requiredFields = map[reflect.Type][]string{
+	reflect.TypeOf(domain.CertificateTask{}): {"name"},
+	reflect.TypeOf(domain.Installation{}):    {"format"},
+}
+
+// ignoredFields are the keys still accepted for compatibility with older playbooks, which have no effect.
+// A warning with the given hint is logged when they are found
+var ignoredFields = map[reflect.Type]map[string]string{
+	reflect.TypeOf(domain.PlaybookRequest{}): {"keyPassword": "set keyPassword, jksPassword or p12Password in the installations instead"},
+}
+
+// SchemaError is a difference between the playbook file and the fields of domain.Playbook
+type SchemaError struct {
+	// Line and Column of the value in the playbook file
+	Line   int
+	Column int
+	// Path of the value, i.e. certificateTasks[0].installations[1].format
+	Path    string
+	Message string
+}
+
+func (e *SchemaError) Error() string {
+	return fmt.Sprintf("line %d: %s: %s", e.Line, e.Path, e.Message)
+}
+
+// ValidateSchema checks the playbook in data against the fields of domain.Playbook, and returns an error for every
+// unknown field, value of the wrong type and missing required field found, in file order.
+//
+// Unknown fields are otherwise ignored when reading the playbook, so a typo like aferInstallAction goes unnoticed
+func ValidateSchema(data []byte) error {
+	var root yaml.Node
+	err := yaml.Unmarshal(data, &root)
+	if err != nil {
+		return err
+	}
+	if len(root.Content) == 0 {
+		return nil
+	}
+
+	errs := make([]*SchemaError, 0)
+	validateNode(root.Content[0], reflect.TypeOf(domain.Playbook{}), "", &errs)
+
+	sort.SliceStable(errs, func(i, j int) bool {
+		if errs[i].Line != errs[j].Line {
+			return errs[i].Line < errs[j].Line
+		}
+		return errs[i].Column < errs[j].Column
+	})
+	var rErr error
+	for _, e := range errs {
+		rErr = errors.Join(rErr, e)
+	}
+	return rErr
+}
+
+func validateNode(node *yaml.Node, t reflect.Type, path string, errs *[]*SchemaError) {
+	if node.Kind == yaml.AliasNode {
+		node = node.Alias
+	}
+	for t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+	// Null values leave the field empty, whatever its type
+	if node.Kind == yaml.ScalarNode && node.Tag == "!!null" {
+		return
+	}
+	addError := func(format string, args ...interface{}) {
+		*errs = append(*errs, &SchemaError{Line: node.Line, Column: node.Column, Path: path, Message: fmt.Sprintf(format, args...)})
+	}
+
+	switch {
+	case t == authenticationType:
+		if node.Kind != yaml.MappingNode {
+			addError("expected a mapping, got %s", nodeDescription(node))
+			return
+		}
+		validateMapping(node, authenticationFields, nil, nil, path, errs)
+		return
+	case t == afterInstallActionsType:
+		// Either a list of actions or a single action
+		if node.Kind == yaml.SequenceNode {
+			for i, item := range node.Content {
+				validateNode(item, afterInstallActionType, fmt.Sprintf("%s[%d]", path, i), errs)
+			}
+			return
+		}
+		validateNode(node, afterInstallActionType, path, errs)
+		return
+	case t == connectionType && node.Kind == yaml.SequenceNode:
+		// Either a single connection or a list of named ones
+		for i, item := range node.Content {
+			if item.Kind == yaml.AliasNode {
+				item = item.Alias
+			}
+			itemPath := fmt.Sprintf("%s[%d]", path, i)
+			if item.Kind != yaml.MappingNode {
+				*errs = append(*errs, &SchemaError{Line: item.Line, Column: item.Column, Path: itemPath,
+					Message: fmt.Sprintf("expected a mapping, got %s", nodeDescription(item))})
+				continue
+			}
+			validateMapping(item, structFields(t), []string{"name"}, nil, itemPath, errs)
+		}
+		return
+	case t == afterInstallActionType:
+		// Either a script or a mapping
+		if node.Kind == yaml.ScalarNode {
+			return
+		}
+	case t == durationType:
+		if node.Kind != yaml.ScalarNode {
+			addError("expected a duration, got %s", nodeDescription(node))
+		}
+		return
+	case t.Kind() != reflect.Struct && reflect.PointerTo(t).Implements(unmarshalerType):
+		// Enumerations, like the installation format, are read from strings
+		if node.Kind != yaml.ScalarNode {
+			addError("expected a string, got %s", nodeDescription(node))
+		}
+		return
+	}
+
+	switch t.Kind() {
+	case reflect.Struct:
+		if node.Kind != yaml.MappingNode {
+			addError("expected a mapping, got %s", nodeDescription(node))
+			return
+		}
+		validateMapping(node, structFields(t), requiredFields[t], ignoredFields[t], path, errs)
+	case reflect.Slice, reflect.Array:
+		if node.Kind != yaml.SequenceNode {
+			addError("expected a list, got %s", nodeDescription(node))
+			return
+		}
+		for i, item := range node.Content {
+			validateNode(item, t.Elem(), fmt.Sprintf("%s[%d]", path, i), errs)
+		}
+	case reflect.Map:
+		if node.Kind != yaml.MappingNode {
+			addError("expected a mapping, got %s", nodeDescription(node))
+			return
+		}
+		for i := 0; i+1 < len(node.Content); i += 2 {
+			validateNode(node.Content[i+1], t.Elem(), joinPath(path, node.Content[i].Value), errs)
+		}
+	case reflect.Bool:
+		if node.Kind != yaml.ScalarNode || node.Tag != "!!bool" {
+			addError("expected true or false, got %s", nodeDescription(node))
+		}
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		if node.Kind != yaml.ScalarNode || node.Tag != "!!int" {
+			addError("expected an integer, got %s", nodeDescription(node))
+		}
+	case reflect.Float32, reflect.Float64:
+		if node.Kind != yaml.ScalarNode || (node.Tag != "!!int" && node.Tag != "!!float") {
+			addError("expected a number, got %s", nodeDescription(node))
+		}
+	case reflect.String:
+		if node.Kind != yaml.ScalarNode {
+			addError("expected a string, got %s", nodeDescription(node))
+		}
+	}
+}
+
+// validateMapping checks the keys of node are in fields or ignored, and that it has all the required keys.
+// Merge keys (<<) are validated as part of node
+func validateMapping(node *yaml.Node, fields map[string]reflect.Type, required []string, ignored map[string]string, path string, errs *[]*SchemaError) {
+	found := make(map[string]bool)
+	for i := 0; i+1 < len(node.Content); i += 2 {
+		key, value := node.Content[i], node.Content[i+1]
+		if key.Tag == "!!merge" {
+			merged := []*yaml.Node{value}
+			if value.Kind == yaml.SequenceNode {
+				merged = value.Content
+			}
+			for _, m := range merged {
+				if m.Kind == yaml.AliasNode {
+					m = m.Alias
+				}
+				for j := 0; j+1 < len(m.Content); j += 2 {
+					found[m.Content[j].Value] = true
+				}
+				validateMapping(m, fields, nil, ignored, path, errs)
+			}
+			continue
+		}
+
+		found[key.Value] = true
+		if hint, ok := ignored[key.Value]; ok {
+			zap.L().Warn("playbook field is ignored", zap.String("field", joinPath(path, key.Value)),
+				zap.Int("line", key.Line), zap.String("hint", hint))
+			continue
+		}
+		fieldType, ok := fields[key.Value]
+		if !ok {
+			message := fmt.Sprintf("unknown field %q", key.Value)
+			if suggestion := closestField(key.Value, fields); suggestion != "" {
+				message = fmt.Sprintf("%s, did you mean %q?", message, suggestion)
+			}
+			*errs = append(*errs, &SchemaError{Line: key.Line, Column: key.Column, Path: joinPath(path, key.Value), Message: message})
+			continue
+		}
+		validateNode(value, fieldType, joinPath(path, key.Value), errs)
+	}
+
+	for _, name := range required {
+		if !found[name] {
+			*errs = append(*errs, &SchemaError{Line: node.Line, Column: node.Column, Path: path,
+				Message: fmt.Sprintf("missing required field %q", name)})
+		}
+	}
+}
+
+// structFields returns the keys of the struct type t, named like yaml.v3 does: after the yaml tag of the field or
+// its name in lower case. Inline fields are flattened and fields tagged "-" are left out
+func structFields(t reflect.Type) map[string]reflect.Type {
+	fields := make(map[string]reflect.Type)
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		if !field.IsExported() {
+			continue
+		}
+		tag := field.Tag.Get("yaml")
+		if tag == "-" {
+			continue
+		}
+		name, options, _ := strings.Cut(tag, ",")
+		if strings.Contains(options, "inline") {
+			for k, v := range structFields(field.Type) {
+				fields[k] = v
+			}
+			continue
+		}
+		if name == "" {
+			name = strings.ToLower(field.Name)
+		}
+		fields[name] = field.Type
+	}
+	return fields
+}
+
+// closestField returns the field closest to name, when it is close enough to be a typo of it
+func closestField(name string, fields map[string]reflect.Type) string {
+	closest := ""
+	closestDistance := len(name)/3 + 1
+	for field := range fields {
+		if strings.EqualFold(field, name) {
+			return field
+		}
+		distance := levenshtein(strings.ToLower(name), strings.ToLower(field))
+		if distance < closestDistance || (distance == closestDistance && closest != "" && field < closest) {
+			closest = field
+			closestDistance = distance
+		}
+	}
+	return closest
+}
+
+// levenshtein returns the number of single character edits needed to turn a into b
+func levenshtein(a string, b string) int {
+	previous := make([]int, len(b)+1)
+	for j := range previous {
+		previous[j] = j
+	}
+	for i := 1; i <= len(a); i++ {
+		current := make([]int, len(b)+1)
+		current[0] = i
+		for j := 1; j <= len(b); j++ {
+			cost := 1
+			if a[i-1] == b[j-1] {
+				cost = 0
+			}
+			current[j] = minInt(previous[j]+1, current[j-1]+1, previous[j-1]+cost)
+		}
+		previous = current
+	}
+	return previous[len(b)]
+}
+
+func minInt(values ...int) int {
+	m := values[0]
+	for _, v := range values[1:] {
+		if v < m {
+			m = v
+		}
+	}
+	return m
+}
+
+func joinPath(path string, key string) string {
+	if path == "" {
+		return key
+	}
+	return path + "." + key
+}
+
+func nodeDescription(node *yaml.Node) string {
+	switch node.Kind {
+	case yaml.MappingNode:
+		return "a mapping"
+	case yaml.SequenceNode:
+		return "a list"
+	default:
+		return fmt.Sprintf("%q", node.Value)
+	}
+}
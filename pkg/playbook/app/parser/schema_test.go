@@ -0,0 +1,230 @@
+/*
+ * Copyright 2023 Venafi, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *  http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package parser
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/suite"
+)
+
+type SchemaSuite struct {
+	suite.Suite
+	testCases []struct {
+		name   string
+		yaml   string
+		errors []string
+	}
+}
+
+func (s *SchemaSuite) SetupTest() {
+	s.testCases = []struct {
+		name   string
+		yaml   string
+		errors []string
+	}{
+		{
+			name: "Valid",
+			yaml: `config:
+  connection:
+    platform: tpp
+    url: https://tpp.venafi.example
+    credentials:
+      accessToken: foo
+      tokenURL: https://idp.example
+    retry:
+      maxRetries: 3
+      backoff: 2s
+certificateTasks:
+  - name: myTask
+    renewBefore: 30d
+    request:
+      zone: my\zone
+      keySize: 2048
+      keyPassword: ignored
+      subject:
+        commonName: foo.venafi.example
+    installations:
+      - format: PEM
+        file: /tmp/cert.pem
+        keyFile: /tmp/key.pem
+        afterInstallAction: echo done
+      - format: PKCS12
+        file: /tmp/cert.p12
+        p12Password: foo
+        afterInstallAction:
+          - restartService: nginx
+          - echo done
+`,
+		},
+		{
+			name: "ValidAnchors",
+			yaml: `certificateTasks:
+  - name: myTask
+    installations:
+      - &pem
+        format: PEM
+        file: /tmp/cert.pem
+      - *pem
+      - <<: *pem
+        file: /tmp/other.pem
+`,
+		},
+		{
+			name: "UnknownFields",
+			yaml: `config:
+  conection:
+    platform: tpp
+certificateTasks:
+  - name: myTask
+    request:
+      zone: my\zone
+    installations:
+      - format: PEM
+        file: /tmp/cert.pem
+        aferInstallAction: echo done
+        foo: bar
+`,
+			errors: []string{
+				`line 2: config.conection: unknown field "conection", did you mean "connection"?`,
+				`line 11: certificateTasks[0].installations[0].aferInstallAction: unknown field "aferInstallAction", did you mean "afterInstallAction"?`,
+				`line 12: certificateTasks[0].installations[0].foo: unknown field "foo"`,
+			},
+		},
+		{
+			name: "WrongTypes",
+			yaml: `config:
+  concurrency: many
+  connection:
+    credentials: foo
+certificateTasks:
+  name: myTask
+  installations:
+    - format: [PEM]
+      backupFiles: maybe
+`,
+			errors: []string{
+				`line 2: config.concurrency: expected an integer, got "many"`,
+				`line 4: config.connection.credentials: expected a mapping, got "foo"`,
+				`line 6: certificateTasks: expected a list, got a mapping`,
+			},
+		},
+		{
+			name: "WrongTypesInTask",
+			yaml: `certificateTasks:
+  - name: myTask
+    installations:
+      - format: [PEM]
+        backupFiles: maybe
+`,
+			errors: []string{
+				`line 4: certificateTasks[0].installations[0].format: expected a string, got a list`,
+				`line 5: certificateTasks[0].installations[0].backupFiles: expected true or false, got "maybe"`,
+			},
+		},
+		{
+			name: "ConnectionList",
+			yaml: `config:
+  connection:
+    - name: tpp-prod
+      platform: tpp
+      url: https://tpp.venafi.example
+    - platform: vaas
+      credentials:
+        apiKey: foo
+        apikey: foo
+certificateTasks:
+  - name: myTask
+    connection: tpp-prod
+`,
+			errors: []string{
+				`line 6: config.connection[1]: missing required field "name"`,
+				`line 9: config.connection[1].credentials.apikey: unknown field "apikey", did you mean "apiKey"?`,
+			},
+		},
+		{
+			name: "MissingRequiredFields",
+			yaml: `certificateTasks:
+  - request:
+      zone: my\zone
+    installations:
+      - file: /tmp/cert.pem
+`,
+			errors: []string{
+				`line 2: certificateTasks[0]: missing required field "name"`,
+				`line 5: certificateTasks[0].installations[0]: missing required field "format"`,
+			},
+		},
+	}
+}
+
+func TestSchema(t *testing.T) {
+	suite.Run(t, new(SchemaSuite))
+}
+
+func (s *SchemaSuite) TestSchema_ValidateSchema() {
+	for _, tc := range s.testCases {
+		s.Run(tc.name, func() {
+			err := ValidateSchema([]byte(tc.yaml))
+			if len(tc.errors) == 0 {
+				s.Nil(err)
+				return
+			}
+
+			s.NotNil(err)
+			var schemaErr *SchemaError
+			s.True(errors.As(err, &schemaErr))
+			s.Equal(tc.errors, splitLines(err.Error()))
+		})
+	}
+}
+
+func (s *SchemaSuite) TestSchema_Examples() {
+	files, err := filepath.Glob("../../../../examples/playbook/*.yaml")
+	s.Nil(err)
+	s.NotEmpty(files)
+
+	for _, file := range files {
+		data, err := os.ReadFile(file)
+		s.Nil(err)
+		s.Nil(ValidateSchema(data), file)
+	}
+}
+
+func (s *SchemaSuite) TestSchema_ReadPlaybook() {
+	playbookFile := filepath.Join(s.T().TempDir(), "playbook.yaml")
+	err := os.WriteFile(playbookFile, []byte(s.testCases[2].yaml), 0600)
+	s.Nil(err)
+
+	_, err = ReadPlaybook(playbookFile)
+	s.ErrorIs(err, ErrSchemaValidation)
+}
+
+func splitLines(text string) []string {
+	lines := make([]string, 0)
+	start := 0
+	for i := range text {
+		if text[i] == '\n' {
+			lines = append(lines, text[start:i])
+			start = i + 1
+		}
+	}
+	return append(lines, text[start:])
+}
@@ -19,18 +19,55 @@ package parser
 import (
 	"fmt"
 	"os"
+	"path/filepath"
 
 	"gopkg.in/yaml.v3"
 )
 
-// WritePlaybook takes an object and serializes it to a file in the given location
+// WritePlaybook takes an object and serializes it to a file in the given location.
+//
+// The file is replaced atomically: the data is written to a temporary file of the same directory, which is then
+// renamed over location, so a crash during the write never leaves a truncated playbook behind. The permissions of
+// an existing file are kept
 func WritePlaybook(object interface{}, location string) error {
 	data, err := yaml.Marshal(object)
 	if err != nil {
 		return fmt.Errorf("could not marshall playbook object: %w", err)
 	}
 
-	err = os.WriteFile(location, data, 0600)
+	// The target of a symbolic link is replaced, rather than the link itself
+	if target, err := filepath.EvalSymlinks(location); err == nil {
+		location = target
+	}
+
+	perm := os.FileMode(0600)
+	if info, err := os.Stat(location); err == nil {
+		perm = info.Mode().Perm()
+	}
+
+	tmp, err := os.CreateTemp(filepath.Dir(location), filepath.Base(location)+".*.tmp")
+	if err != nil {
+		return fmt.Errorf("could not write playbook file: %w", err)
+	}
+	defer func() {
+		_ = os.Remove(tmp.Name())
+	}()
+
+	_, err = tmp.Write(data)
+	if err == nil {
+		err = tmp.Chmod(perm)
+	}
+	if err == nil {
+		err = tmp.Sync()
+	}
+	if closeErr := tmp.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		return fmt.Errorf("could not write playbook file: %w", err)
+	}
+
+	err = os.Rename(tmp.Name(), location)
 	if err != nil {
 		return fmt.Errorf("could not write playbook file: %w", err)
 	}
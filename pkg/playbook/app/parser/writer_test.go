@@ -19,6 +19,8 @@ package parser
 import (
 	"fmt"
 	"os"
+	"path/filepath"
+	"runtime"
 	"testing"
 
 	"github.com/stretchr/testify/suite"
@@ -62,7 +64,7 @@ func (s *WriterSuite) SetupTest() {
 					{
 						Type:        domain.FormatPEM,
 						File:        "path/to/my/pem/folder",
-						AfterAction: "echo Success!",
+						AfterAction: domain.AfterInstallActions{{Script: "echo Success!"}},
 						KeyPassword: "foo123",
 					},
 				},
@@ -149,3 +151,27 @@ func (s *WriterSuite) TestWriter_WritePlaybook() {
 	s.Equal(inst.KeyPassword, targetInst.KeyPassword)
 	s.Equal(inst.AfterAction, targetInst.AfterAction)
 }
+
+func (s *WriterSuite) TestWriter_WritePlaybook_Replace() {
+	dir := s.T().TempDir()
+	location := filepath.Join(dir, "playbook.yaml")
+	s.Require().NoError(os.WriteFile(location, []byte("config: {}\n"), 0640))
+
+	s.Require().NoError(WritePlaybook(s.playbook, location))
+	pb, err := ReadPlaybook(location)
+	s.Require().NoError(err)
+	s.Equal(s.playbook.Config.Connection.Credentials.RefreshToken, pb.Config.Connection.Credentials.RefreshToken)
+
+	info, err := os.Stat(location)
+	s.Require().NoError(err)
+	if runtime.GOOS != "windows" {
+		s.Equal(os.FileMode(0640), info.Mode().Perm(), "the permissions of the playbook file must be kept")
+	}
+
+	entries, err := os.ReadDir(dir)
+	s.Require().NoError(err)
+	s.Len(entries, 1, "no temporary file must be left behind")
+
+	// TearDownTest removes the playbook location
+	s.Require().NoError(WritePlaybook(s.playbook, s.playbook.Location))
+}
@@ -0,0 +1,126 @@
+/*
+ * Copyright 2023 Venafi, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *  http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+// Package report records what every playbook task did in a run, and why, so tools wrapping vcert can act on the
+// result of the run without parsing its logs
+package report
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+	"time"
+)
+
+const (
+	// ActionInstalled is the action of a task that installed a certificate where none was installed
+	ActionInstalled = "installed"
+	// ActionRenewed is the action of a task that replaced the certificate installed
+	ActionRenewed = "renewed"
+	// ActionRevoked is the action of a revoke task that revoked its certificate
+	ActionRevoked = "revoked"
+	// ActionSkipped is the action of a task that had nothing to do, or of any task in a dry run
+	ActionSkipped = "skipped"
+	// ActionFailed is the action of a task that failed
+	ActionFailed = "failed"
+)
+
+// TaskResult is what a task did in the run
+type TaskResult struct {
+	Task string `json:"task"`
+	// Action is one of ActionInstalled, ActionRenewed, ActionRevoked, ActionSkipped or ActionFailed
+	Action string `json:"action"`
+	// Reason explains why the certificate was, or was not, requested
+	Reason string `json:"reason,omitempty"`
+	DryRun bool   `json:"dryRun,omitempty"`
+	// Serial, Thumbprint, NotAfter and RenewAt describe the certificate installed by the task, or the one already
+	// installed when the task was skipped. Serial is in decimal
+	Serial     string     `json:"serial,omitempty"`
+	Thumbprint string     `json:"thumbprint,omitempty"`
+	NotAfter   *time.Time `json:"notAfter,omitempty"`
+	RenewAt    *time.Time `json:"renewAt,omitempty"`
+	// Zone is the zone the certificate was requested in, out of the zones of the task
+	Zone       string    `json:"zone,omitempty"`
+	Errors     []string  `json:"errors,omitempty"`
+	StartedAt  time.Time `json:"startedAt"`
+	FinishedAt time.Time `json:"finishedAt"`
+}
+
+// Report holds the results of the tasks run by a playbook. It is safe for concurrent use
+type Report struct {
+	StartedAt  time.Time      `json:"startedAt"`
+	FinishedAt time.Time      `json:"finishedAt"`
+	Summary    map[string]int `json:"summary"`
+	Tasks      []TaskResult   `json:"tasks"`
+	mu         sync.Mutex
+}
+
+// New returns an empty Report of a run starting now
+func New() *Report {
+	return &Report{
+		StartedAt: time.Now(),
+		Summary:   make(map[string]int),
+		Tasks:     make([]TaskResult, 0),
+	}
+}
+
+// Add records the result of a task
+func (r *Report) Add(result TaskResult) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.Tasks = append(r.Tasks, result)
+	r.Summary[result.Action]++
+}
+
+// Write saves the report to path as JSON, with the tasks sorted by name. The report is written to a temporary file
+// first, so tools reading path never find it half written
+func (r *Report) Write(path string) error {
+	r.mu.Lock()
+	r.FinishedAt = time.Now()
+	sort.SliceStable(r.Tasks, func(i, j int) bool {
+		return r.Tasks[i].Task < r.Tasks[j].Task
+	})
+	data, err := json.MarshalIndent(r, "", "  ")
+	r.mu.Unlock()
+	if err != nil {
+		return fmt.Errorf("failed to serialize report: %w", err)
+	}
+
+	tmp, err := os.CreateTemp(filepath.Dir(path), filepath.Base(path)+".*.tmp")
+	if err != nil {
+		return fmt.Errorf("failed to write report file: %w", err)
+	}
+	defer func() {
+		_ = os.Remove(tmp.Name())
+	}()
+
+	_, err = tmp.Write(data)
+	if closeErr := tmp.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		return fmt.Errorf("failed to write report file: %w", err)
+	}
+
+	err = os.Rename(tmp.Name(), path)
+	if err != nil {
+		return fmt.Errorf("failed to write report file: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,51 @@
+/*
+ * Copyright 2023 Venafi, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *  http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package report
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestReportWrite(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "report.json")
+
+	r := New()
+	r.Add(TaskResult{Task: "second", Action: ActionSkipped, Reason: "certificate in good health", Serial: "1234"})
+	r.Add(TaskResult{Task: "first", Action: ActionFailed, Errors: []string{"error requesting certificate first"}})
+	require.NoError(t, r.Write(path))
+
+	data, err := os.ReadFile(path)
+	require.NoError(t, err)
+	written := Report{}
+	require.NoError(t, json.Unmarshal(data, &written))
+	require.Len(t, written.Tasks, 2)
+	assert.Equal(t, "first", written.Tasks[0].Task, "tasks are sorted by name")
+	assert.Equal(t, []string{"error requesting certificate first"}, written.Tasks[0].Errors)
+	assert.Equal(t, "1234", written.Tasks[1].Serial)
+	assert.Equal(t, map[string]int{ActionSkipped: 1, ActionFailed: 1}, written.Summary)
+	assert.False(t, written.FinishedAt.IsZero())
+
+	entries, err := os.ReadDir(filepath.Dir(path))
+	require.NoError(t, err)
+	assert.Len(t, entries, 1, "temporary files should be removed")
+}
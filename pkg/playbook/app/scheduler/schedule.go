@@ -0,0 +1,291 @@
+/*
+ * Copyright 2023 Venafi, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *  http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package scheduler
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	everyPrefix = "@every "
+
+	// maxSearchYears limits how far in the future a cron expression is evaluated before giving up
+	maxSearchYears = 5
+)
+
+var predefinedSchedules = map[string]string{
+	"@hourly":   "0 * * * *",
+	"@daily":    "0 0 * * *",
+	"@midnight": "0 0 * * *",
+	"@weekly":   "0 0 * * 0",
+	"@monthly":  "0 0 1 * *",
+	"@yearly":   "0 0 1 1 *",
+	"@annually": "0 0 1 1 *",
+}
+
+// Schedule determines when a job should run
+type Schedule interface {
+	// Next returns the next activation time after t. A zero time means the schedule will never activate again
+	Next(t time.Time) time.Time
+}
+
+// ParseSchedule takes a schedule specification and returns the Schedule it represents.
+//
+// Supported specifications are:
+//   - a duration, e.g. "12h" or "@every 12h"
+//   - a predefined schedule: @hourly, @daily (or @midnight), @weekly, @monthly, @yearly (or @annually)
+//   - a standard 5-field cron expression: minute hour day-of-month month day-of-week, e.g. "30 2 * * 1-5"
+func ParseSchedule(spec string) (Schedule, error) {
+	spec = strings.TrimSpace(spec)
+	if spec == "" {
+		return nil, fmt.Errorf("empty schedule")
+	}
+
+	if strings.HasPrefix(spec, everyPrefix) {
+		return parseInterval(strings.TrimSpace(spec[len(everyPrefix):]))
+	}
+
+	if cronSpec, found := predefinedSchedules[strings.ToLower(spec)]; found {
+		spec = cronSpec
+	}
+
+	fields := strings.Fields(spec)
+	if len(fields) == 1 {
+		return parseInterval(spec)
+	}
+
+	return parseCron(fields)
+}
+
+type intervalSchedule struct {
+	interval time.Duration
+}
+
+// Next returns t plus the interval of the schedule
+func (s intervalSchedule) Next(t time.Time) time.Time {
+	return t.Add(s.interval)
+}
+
+func parseInterval(value string) (Schedule, error) {
+	interval, err := time.ParseDuration(value)
+	if err != nil {
+		return nil, fmt.Errorf("invalid schedule interval %q: %w", value, err)
+	}
+	if interval < time.Minute {
+		return nil, fmt.Errorf("invalid schedule interval %q: must be at least 1m", value)
+	}
+	return intervalSchedule{interval: interval}, nil
+}
+
+// bitset holds the allowed values of a cron field
+type bitset uint64
+
+func (b bitset) has(value int) bool {
+	return b&(1<<uint(value)) != 0
+}
+
+type cronSchedule struct {
+	minute, hour, dayOfMonth, month, dayOfWeek bitset
+	// a wildcard day field does not restrict the other day field
+	dayOfMonthWildcard, dayOfWeekWildcard bool
+}
+
+type fieldBounds struct {
+	name     string
+	min, max int
+}
+
+var cronFields = []fieldBounds{
+	{name: "minute", min: 0, max: 59},
+	{name: "hour", min: 0, max: 23},
+	{name: "day-of-month", min: 1, max: 31},
+	{name: "month", min: 1, max: 12},
+	{name: "day-of-week", min: 0, max: 7},
+}
+
+func parseCron(fields []string) (Schedule, error) {
+	if len(fields) != len(cronFields) {
+		return nil, fmt.Errorf("invalid cron expression %q: expected %d fields but found %d",
+			strings.Join(fields, " "), len(cronFields), len(fields))
+	}
+
+	sets := make([]bitset, len(fields))
+	for i, field := range fields {
+		set, err := parseCronField(field, cronFields[i])
+		if err != nil {
+			return nil, fmt.Errorf("invalid cron expression %q: %w", strings.Join(fields, " "), err)
+		}
+		sets[i] = set
+	}
+
+	// Sunday can be expressed as either 0 or 7
+	dayOfWeek := sets[4]
+	if dayOfWeek.has(7) {
+		dayOfWeek |= 1
+	}
+
+	schedule := cronSchedule{
+		minute:             sets[0],
+		hour:               sets[1],
+		dayOfMonth:         sets[2],
+		month:              sets[3],
+		dayOfWeek:          dayOfWeek,
+		dayOfMonthWildcard: fields[2] == "*" || fields[2] == "?",
+		dayOfWeekWildcard:  fields[4] == "*" || fields[4] == "?",
+	}
+	if !schedule.matchesAnyDay() {
+		return nil, fmt.Errorf("invalid cron expression %q: no month has the days of the day-of-month field",
+			strings.Join(fields, " "))
+	}
+	return schedule, nil
+}
+
+// daysInMonth holds the number of days of each month, counting February 29 of the leap years
+var daysInMonth = [13]int{0, 31, 29, 31, 30, 31, 30, 31, 31, 30, 31, 30, 31}
+
+// matchesAnyDay returns false when the day-of-month field only allows days the allowed months do not have,
+// like February 30, so the schedule would never fire
+func (s cronSchedule) matchesAnyDay() bool {
+	// Every day of the week is in every month, and matching either day field is enough when both are restricted
+	if s.dayOfMonthWildcard || !s.dayOfWeekWildcard {
+		return true
+	}
+	for month := 1; month <= 12; month++ {
+		if !s.month.has(month) {
+			continue
+		}
+		for day := 1; day <= daysInMonth[month]; day++ {
+			if s.dayOfMonth.has(day) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+func parseCronField(field string, bounds fieldBounds) (bitset, error) {
+	var set bitset
+	for _, part := range strings.Split(field, ",") {
+		step := 1
+		if idx := strings.Index(part, "/"); idx >= 0 {
+			var err error
+			step, err = strconv.Atoi(part[idx+1:])
+			if err != nil || step < 1 {
+				return 0, fmt.Errorf("invalid step in %s field: %q", bounds.name, part)
+			}
+			part = part[:idx]
+		}
+
+		start, end := bounds.min, bounds.max
+		switch {
+		case part == "*" || part == "?":
+		case strings.Contains(part, "-"):
+			limits := strings.SplitN(part, "-", 2)
+			var err error
+			start, err = strconv.Atoi(limits[0])
+			if err != nil {
+				return 0, fmt.Errorf("invalid range in %s field: %q", bounds.name, part)
+			}
+			end, err = strconv.Atoi(limits[1])
+			if err != nil {
+				return 0, fmt.Errorf("invalid range in %s field: %q", bounds.name, part)
+			}
+		default:
+			value, err := strconv.Atoi(part)
+			if err != nil {
+				return 0, fmt.Errorf("invalid value in %s field: %q", bounds.name, part)
+			}
+			start = value
+			// a single value with a step means "from value to max"
+			if step == 1 {
+				end = value
+			}
+		}
+
+		if start < bounds.min || end > bounds.max || start > end {
+			return 0, fmt.Errorf("%s field out of range [%d-%d]: %q", bounds.name, bounds.min, bounds.max, part)
+		}
+
+		for v := start; v <= end; v += step {
+			set |= 1 << uint(v)
+		}
+	}
+	return set, nil
+}
+
+// Next returns the first time after t that matches the cron expression
+func (s cronSchedule) Next(t time.Time) time.Time {
+	loc := t.Location()
+	// Start at the beginning of the next minute
+	t = time.Date(t.Year(), t.Month(), t.Day(), t.Hour(), t.Minute(), 0, 0, loc).Add(time.Minute)
+	yearLimit := t.Year() + maxSearchYears
+
+WRAP:
+	if t.Year() > yearLimit {
+		return time.Time{}
+	}
+
+	for !s.month.has(int(t.Month())) {
+		t = time.Date(t.Year(), t.Month()+1, 1, 0, 0, 0, 0, loc)
+		if t.Month() == time.January {
+			goto WRAP
+		}
+	}
+
+	for !s.dayMatches(t) {
+		t = time.Date(t.Year(), t.Month(), t.Day()+1, 0, 0, 0, 0, loc)
+		if t.Day() == 1 {
+			goto WRAP
+		}
+	}
+
+	for !s.hour.has(t.Hour()) {
+		t = time.Date(t.Year(), t.Month(), t.Day(), t.Hour()+1, 0, 0, 0, loc)
+		if t.Hour() == 0 {
+			goto WRAP
+		}
+	}
+
+	for !s.minute.has(t.Minute()) {
+		t = t.Add(time.Minute)
+		if t.Minute() == 0 {
+			goto WRAP
+		}
+	}
+
+	return t
+}
+
+func (s cronSchedule) dayMatches(t time.Time) bool {
+	domMatch := s.dayOfMonth.has(t.Day())
+	dowMatch := s.dayOfWeek.has(int(t.Weekday()))
+
+	switch {
+	case s.dayOfMonthWildcard && s.dayOfWeekWildcard:
+		return true
+	case s.dayOfMonthWildcard:
+		return dowMatch
+	case s.dayOfWeekWildcard:
+		return domMatch
+	default:
+		// When both day fields are restricted, matching either of them is enough
+		return domMatch || dowMatch
+	}
+}
@@ -0,0 +1,169 @@
+/*
+ * Copyright 2023 Venafi, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *  http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package scheduler
+
+import (
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/suite"
+)
+
+type ScheduleSuite struct {
+	suite.Suite
+	from time.Time
+}
+
+func (s *ScheduleSuite) SetupTest() {
+	// Wednesday
+	s.from = time.Date(2023, time.October, 18, 10, 17, 42, 0, time.UTC)
+}
+
+func TestSchedule(t *testing.T) {
+	suite.Run(t, new(ScheduleSuite))
+}
+
+func (s *ScheduleSuite) TestParseSchedule_Next() {
+	testCases := []struct {
+		name     string
+		spec     string
+		expected time.Time
+	}{
+		{name: "Duration", spec: "12h", expected: s.from.Add(12 * time.Hour)},
+		{name: "Every", spec: "@every 30m", expected: s.from.Add(30 * time.Minute)},
+		{name: "Hourly", spec: "@hourly", expected: time.Date(2023, time.October, 18, 11, 0, 0, 0, time.UTC)},
+		{name: "Daily", spec: "@daily", expected: time.Date(2023, time.October, 19, 0, 0, 0, 0, time.UTC)},
+		{name: "Weekly", spec: "@weekly", expected: time.Date(2023, time.October, 22, 0, 0, 0, 0, time.UTC)},
+		{name: "Monthly", spec: "@monthly", expected: time.Date(2023, time.November, 1, 0, 0, 0, 0, time.UTC)},
+		{name: "EveryMinute", spec: "* * * * *", expected: time.Date(2023, time.October, 18, 10, 18, 0, 0, time.UTC)},
+		{name: "Step", spec: "*/15 * * * *", expected: time.Date(2023, time.October, 18, 10, 30, 0, 0, time.UTC)},
+		{name: "SameDayLater", spec: "30 14 * * *", expected: time.Date(2023, time.October, 18, 14, 30, 0, 0, time.UTC)},
+		{name: "NextDay", spec: "30 2 * * *", expected: time.Date(2023, time.October, 19, 2, 30, 0, 0, time.UTC)},
+		{name: "Weekdays", spec: "0 3 * * 1-5", expected: time.Date(2023, time.October, 19, 3, 0, 0, 0, time.UTC)},
+		{name: "SundayAsSeven", spec: "0 3 * * 7", expected: time.Date(2023, time.October, 22, 3, 0, 0, 0, time.UTC)},
+		{name: "List", spec: "0 9,21 * * *", expected: time.Date(2023, time.October, 18, 21, 0, 0, 0, time.UTC)},
+		{name: "NextYear", spec: "0 0 1 1 *", expected: time.Date(2024, time.January, 1, 0, 0, 0, 0, time.UTC)},
+		{name: "LeapDay", spec: "0 0 29 2 *", expected: time.Date(2024, time.February, 29, 0, 0, 0, 0, time.UTC)},
+		{name: "DayOfMonthOrWeek", spec: "0 0 1 * 5", expected: time.Date(2023, time.October, 20, 0, 0, 0, 0, time.UTC)},
+		{name: "MissingDayOrWeekday", spec: "0 0 31 2 5", expected: time.Date(2024, time.February, 2, 0, 0, 0, 0, time.UTC)},
+		{name: "MissingDayInSomeMonths", spec: "0 0 31 2,3 *", expected: time.Date(2024, time.March, 31, 0, 0, 0, 0, time.UTC)},
+	}
+
+	for _, tc := range testCases {
+		s.Run(tc.name, func() {
+			schedule, err := ParseSchedule(tc.spec)
+			s.NoError(err)
+			s.Equal(tc.expected, schedule.Next(s.from))
+		})
+	}
+}
+
+func (s *ScheduleSuite) TestParseSchedule_Invalid() {
+	specs := []string{"", "foo", "@every bar", "30s", "* * * *", "60 * * * *", "* 24 * * *", "* * 0 * *",
+		"* * * 13 *", "* * * * 8", "*/0 * * * *", "5-1 * * * *", "0 0 31 2 *", "0 0 30-31 2 *", "0 0 31 4,6,9,11 *"}
+
+	for _, spec := range specs {
+		s.Run(spec, func() {
+			_, err := ParseSchedule(spec)
+			s.Error(err)
+		})
+	}
+}
+
+type fixedSchedule struct {
+	interval time.Duration
+}
+
+func (f fixedSchedule) Next(t time.Time) time.Time {
+	return t.Add(f.interval)
+}
+
+func (s *ScheduleSuite) TestScheduler_RunAndStop() {
+	var runs int32
+	sch := NewScheduler(0)
+	s.Require().NoError(sch.Add(Job{
+		Name:     "job",
+		Schedule: fixedSchedule{interval: 10 * time.Millisecond},
+		Run: func() {
+			atomic.AddInt32(&runs, 1)
+		},
+	}))
+	sch.Start()
+	time.Sleep(100 * time.Millisecond)
+	sch.Stop()
+
+	stoppedRuns := atomic.LoadInt32(&runs)
+	s.Greater(stoppedRuns, int32(0))
+
+	time.Sleep(50 * time.Millisecond)
+	s.Equal(stoppedRuns, atomic.LoadInt32(&runs))
+}
+
+func (s *ScheduleSuite) TestScheduler_AddDuplicate() {
+	var first, second int32
+	sch := NewScheduler(0)
+	s.Require().NoError(sch.Add(Job{
+		Name:     "job",
+		Schedule: fixedSchedule{interval: 10 * time.Millisecond},
+		Run: func() {
+			atomic.AddInt32(&first, 1)
+		},
+	}))
+	err := sch.Add(Job{
+		Name:     "job",
+		Schedule: fixedSchedule{interval: 10 * time.Millisecond},
+		Run: func() {
+			atomic.AddInt32(&second, 1)
+		},
+	})
+	s.ErrorIs(err, ErrDuplicateJob)
+
+	sch.Start()
+	time.Sleep(50 * time.Millisecond)
+	sch.Stop()
+	s.Greater(atomic.LoadInt32(&first), int32(0))
+	s.Equal(int32(0), atomic.LoadInt32(&second), "the duplicate job must not replace the first one")
+}
+
+func (s *ScheduleSuite) TestScheduler_Replace() {
+	var first, second int32
+	sch := NewScheduler(0)
+	sch.Start()
+	sch.Replace(Job{
+		Name:     "job",
+		Schedule: fixedSchedule{interval: 10 * time.Millisecond},
+		Run: func() {
+			atomic.AddInt32(&first, 1)
+		},
+	})
+	time.Sleep(50 * time.Millisecond)
+	sch.Replace(Job{
+		Name:     "job",
+		Schedule: fixedSchedule{interval: 10 * time.Millisecond},
+		Run: func() {
+			atomic.AddInt32(&second, 1)
+		},
+	})
+	replacedRuns := atomic.LoadInt32(&first)
+	time.Sleep(50 * time.Millisecond)
+	sch.Stop()
+
+	s.Greater(replacedRuns, int32(0))
+	s.Equal(replacedRuns, atomic.LoadInt32(&first), "the replaced job must not run anymore")
+	s.Greater(atomic.LoadInt32(&second), int32(0))
+}
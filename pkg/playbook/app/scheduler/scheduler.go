@@ -0,0 +1,179 @@
+/*
+ * Copyright 2023 Venafi, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *  http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package scheduler
+
+import (
+	"fmt"
+	"math/rand"
+	"sync"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+// ErrDuplicateJob is returned by Scheduler.Add when a job with the same name is already registered
+var ErrDuplicateJob = fmt.Errorf("job already registered")
+
+// Job represents a unit of work that the Scheduler runs according to its Schedule
+type Job struct {
+	Name     string
+	Schedule Schedule
+	Run      func()
+}
+
+type entry struct {
+	job  Job
+	stop chan struct{}
+}
+
+// Scheduler runs jobs on their schedules until stopped.
+//
+// Each job runs on its own goroutine, so a job never overlaps with itself,
+// while different jobs may run at the same time.
+type Scheduler struct {
+	jitter  time.Duration
+	entries map[string]*entry
+	mu      sync.Mutex
+	running sync.WaitGroup
+	started bool
+}
+
+// NewScheduler returns a Scheduler that delays every activation by a random duration in the range [0, jitter)
+func NewScheduler(jitter time.Duration) *Scheduler {
+	return &Scheduler{
+		jitter:  jitter,
+		entries: make(map[string]*entry),
+	}
+}
+
+// Add registers a job in the Scheduler. Returns ErrDuplicateJob if a job with the same name exists.
+//
+// If the Scheduler is already started, the job is scheduled right away
+func (s *Scheduler) Add(job Job) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, found := s.entries[job.Name]; found {
+		return fmt.Errorf("%w: %s", ErrDuplicateJob, job.Name)
+	}
+	s.register(job)
+	return nil
+}
+
+// Replace registers a job in the Scheduler. If a job with the same name exists, it is unscheduled first.
+// A run in progress of the replaced job is allowed to finish
+func (s *Scheduler) Replace(job Job) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if current, found := s.entries[job.Name]; found {
+		close(current.stop)
+	}
+	s.register(job)
+}
+
+// register adds the entry of job, and launches it when the Scheduler is started. s.mu must be held
+func (s *Scheduler) register(job Job) {
+	e := &entry{job: job, stop: make(chan struct{})}
+	s.entries[job.Name] = e
+	if s.started {
+		s.launch(e)
+	}
+}
+
+// Remove unregisters the job with the given name. A run in progress is allowed to finish
+func (s *Scheduler) Remove(name string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if current, found := s.entries[name]; found {
+		close(current.stop)
+		delete(s.entries, name)
+	}
+}
+
+// Start begins scheduling all registered jobs
+func (s *Scheduler) Start() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.started {
+		return
+	}
+	s.started = true
+	for _, e := range s.entries {
+		s.launch(e)
+	}
+}
+
+// Stop prevents any further job activation and waits for the runs in progress to finish
+func (s *Scheduler) Stop() {
+	s.mu.Lock()
+	for name, e := range s.entries {
+		close(e.stop)
+		delete(s.entries, name)
+	}
+	s.started = false
+	s.mu.Unlock()
+
+	s.running.Wait()
+}
+
+func (s *Scheduler) launch(e *entry) {
+	s.running.Add(1)
+	go func() {
+		defer s.running.Done()
+		s.loop(e)
+	}()
+}
+
+func (s *Scheduler) loop(e *entry) {
+	for {
+		now := time.Now()
+		next := e.job.Schedule.Next(now)
+		if next.IsZero() {
+			zap.L().Warn("job schedule has no further activations", zap.String("job", e.job.Name))
+			return
+		}
+		next = next.Add(s.getJitter())
+		zap.L().Info("next job run scheduled", zap.String("job", e.job.Name), zap.String("time", next.String()))
+
+		timer := time.NewTimer(next.Sub(now))
+		select {
+		case <-e.stop:
+			timer.Stop()
+			return
+		case <-timer.C:
+		}
+
+		// Job might have been stopped while the timer fired
+		select {
+		case <-e.stop:
+			return
+		default:
+		}
+
+		e.job.Run()
+	}
+}
+
+func (s *Scheduler) getJitter() time.Duration {
+	if s.jitter <= 0 {
+		return 0
+	}
+	return time.Duration(rand.Int63n(int64(s.jitter))) // #nosec G404 -- jitter does not need a secure source
+}
@@ -0,0 +1,230 @@
+/*
+ * Copyright 2023 Venafi, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *  http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package secret
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"runtime"
+	"strings"
+
+	"golang.org/x/crypto/scrypt"
+	"gopkg.in/yaml.v3"
+)
+
+// Tag marks the playbook values encrypted with vcert encrypt-secret, i.e. password: !encrypted passphrase:<ciphertext>.
+//
+// An encrypted value has the form <key>:<base64 ciphertext>, where key tells how it was encrypted:
+//   - dpapi: with the Data Protection API of Windows, for the user that encrypted it
+//   - keyring: with an AES key kept in the keyring of the user by libsecret, on Linux
+//   - passphrase: with an AES key derived from a passphrase, read from VCERT_SECRET_PASSPHRASE when decrypting
+const Tag = "!encrypted"
+
+const (
+	// KeyMachine encrypts a value with the key of the machine: DPAPI on Windows and the libsecret keyring elsewhere
+	KeyMachine = "machine"
+	// KeyPassphrase encrypts a value with a key derived from a passphrase
+	KeyPassphrase = "passphrase"
+
+	// EnvPassphrase is the environment variable holding the passphrase that decrypts the values of the playbook
+	EnvPassphrase = "VCERT_SECRET_PASSPHRASE"
+
+	sourceDPAPI      = "dpapi"
+	sourceKeyring    = "keyring"
+	sourcePassphrase = "passphrase"
+
+	saltSize = 16
+	keySize  = 32
+	// scrypt parameters recommended for interactive logins
+	scryptN = 1 << 15
+	scryptR = 8
+	scryptP = 1
+)
+
+// ErrPassphraseRequired is returned when decrypting a value encrypted with a passphrase without one
+var ErrPassphraseRequired = errors.New("a passphrase is required to decrypt the value")
+
+// Encrypt encrypts plaintext with the key of the machine or with passphrase, and returns the value to set after the
+// !encrypted tag in a playbook
+func Encrypt(plaintext string, key string, passphrase string) (string, error) {
+	var source string
+	var ciphertext []byte
+	var err error
+	switch key {
+	case KeyMachine:
+		source = machineKeySource
+		ciphertext, err = sealWithMachineKey([]byte(plaintext))
+	case KeyPassphrase:
+		if passphrase == "" {
+			return "", fmt.Errorf("the passphrase cannot be empty")
+		}
+		source = sourcePassphrase
+		ciphertext, err = sealWithPassphrase([]byte(plaintext), passphrase)
+	default:
+		return "", fmt.Errorf("unknown key %q. Options include: %s | %s", key, KeyMachine, KeyPassphrase)
+	}
+	if err != nil {
+		return "", err
+	}
+	return fmt.Sprintf("%s:%s", source, base64.StdEncoding.EncodeToString(ciphertext)), nil
+}
+
+// Decrypt returns the plaintext of a value returned by Encrypt. passphrase is only used by the values encrypted with
+// a passphrase. Errors never include the plaintext
+func Decrypt(value string, passphrase string) (string, error) {
+	source, encoded, found := strings.Cut(strings.TrimSpace(value), ":")
+	if !found {
+		return "", fmt.Errorf("invalid encrypted value. Should be <key>:<ciphertext>")
+	}
+	ciphertext, err := base64.StdEncoding.DecodeString(encoded)
+	if err != nil {
+		return "", fmt.Errorf("invalid encrypted value: %w", err)
+	}
+
+	var plaintext []byte
+	switch {
+	case source == machineKeySource:
+		plaintext, err = openWithMachineKey(ciphertext)
+	case source == sourcePassphrase:
+		if passphrase == "" {
+			return "", ErrPassphraseRequired
+		}
+		plaintext, err = openWithPassphrase(ciphertext, passphrase)
+	case source == sourceDPAPI || source == sourceKeyring:
+		// the key of the other platforms
+		return "", fmt.Errorf("values encrypted with the %s key cannot be decrypted on %s", source, runtime.GOOS)
+	default:
+		return "", fmt.Errorf("unknown key %q in encrypted value", source)
+	}
+	if err != nil {
+		return "", err
+	}
+	return string(plaintext), nil
+}
+
+// Decrypt returns the plaintext of a value tagged !encrypted in the playbook. Like secret references, each value is
+// decrypted once and cached by the Resolver
+func (r *Resolver) Decrypt(value string) (string, error) {
+	cacheKey := Tag + " " + value
+	if plaintext, found := r.cache[cacheKey]; found {
+		return plaintext, nil
+	}
+
+	plaintext, err := Decrypt(value, os.Getenv(EnvPassphrase))
+	if errors.Is(err, ErrPassphraseRequired) {
+		return "", fmt.Errorf("%w. Set it in the %s environment variable", err, EnvPassphrase)
+	} else if err != nil {
+		return "", fmt.Errorf("could not decrypt %s value: %w", Tag, err)
+	}
+	r.cache[cacheKey] = plaintext
+	return plaintext, nil
+}
+
+// Encrypted is a value tagged !encrypted in the playbook, that keeps its tag when the playbook is written back
+type Encrypted string
+
+// MarshalYAML writes e with the !encrypted tag
+func (e Encrypted) MarshalYAML() (interface{}, error) {
+	return &yaml.Node{Kind: yaml.ScalarNode, Tag: Tag, Value: string(e)}, nil
+}
+
+// Replace returns plaintext encrypted with the same key as e, i.e. to store a refreshed token. The passphrase of the
+// values encrypted with a passphrase is read from the VCERT_SECRET_PASSPHRASE environment variable
+func (e Encrypted) Replace(plaintext string) (Encrypted, error) {
+	source, _, _ := strings.Cut(strings.TrimSpace(string(e)), ":")
+	key := KeyMachine
+	if source == sourcePassphrase {
+		key = KeyPassphrase
+	} else if source != machineKeySource {
+		return "", fmt.Errorf("values encrypted with the %s key cannot be encrypted on %s", source, runtime.GOOS)
+	}
+	value, err := Encrypt(plaintext, key, os.Getenv(EnvPassphrase))
+	return Encrypted(value), err
+}
+
+func sealWithPassphrase(plaintext []byte, passphrase string) ([]byte, error) {
+	salt := make([]byte, saltSize)
+	_, err := io.ReadFull(rand.Reader, salt)
+	if err != nil {
+		return nil, err
+	}
+	key, err := scrypt.Key([]byte(passphrase), salt, scryptN, scryptR, scryptP, keySize)
+	if err != nil {
+		return nil, err
+	}
+	ciphertext, err := seal(key, plaintext, []byte(sourcePassphrase))
+	if err != nil {
+		return nil, err
+	}
+	return append(salt, ciphertext...), nil
+}
+
+func openWithPassphrase(ciphertext []byte, passphrase string) ([]byte, error) {
+	if len(ciphertext) < saltSize {
+		return nil, fmt.Errorf("invalid encrypted value: too short")
+	}
+	key, err := scrypt.Key([]byte(passphrase), ciphertext[:saltSize], scryptN, scryptR, scryptP, keySize)
+	if err != nil {
+		return nil, err
+	}
+	plaintext, err := open(key, ciphertext[saltSize:], []byte(sourcePassphrase))
+	if err != nil {
+		return nil, fmt.Errorf("wrong passphrase or corrupted value")
+	}
+	return plaintext, nil
+}
+
+// seal encrypts plaintext with AES-GCM and returns the nonce followed by the ciphertext. additionalData binds the
+// ciphertext to the kind of key, so it cannot be decrypted as another kind
+func seal(key []byte, plaintext []byte, additionalData []byte) ([]byte, error) {
+	aead, err := newAEAD(key)
+	if err != nil {
+		return nil, err
+	}
+	nonce := make([]byte, aead.NonceSize(), aead.NonceSize()+len(plaintext)+aead.Overhead())
+	_, err = io.ReadFull(rand.Reader, nonce)
+	if err != nil {
+		return nil, err
+	}
+	return aead.Seal(nonce, nonce, plaintext, additionalData), nil
+}
+
+func open(key []byte, ciphertext []byte, additionalData []byte) ([]byte, error) {
+	aead, err := newAEAD(key)
+	if err != nil {
+		return nil, err
+	}
+	if len(ciphertext) < aead.NonceSize() {
+		return nil, fmt.Errorf("invalid encrypted value: too short")
+	}
+	nonce := ciphertext[:aead.NonceSize()]
+	return aead.Open(nil, nonce, ciphertext[aead.NonceSize():], additionalData)
+}
+
+func newAEAD(key []byte) (cipher.AEAD, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
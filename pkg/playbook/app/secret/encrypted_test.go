@@ -0,0 +1,92 @@
+/*
+ * Copyright 2023 Venafi, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *  http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package secret
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gopkg.in/yaml.v3"
+)
+
+func TestEncryptWithPassphrase(t *testing.T) {
+	value, err := Encrypt("s3cr3t!", KeyPassphrase, "correct horse")
+	require.NoError(t, err)
+	assert.True(t, strings.HasPrefix(value, "passphrase:"), value)
+	assert.NotContains(t, value, "s3cr3t!")
+
+	other, err := Encrypt("s3cr3t!", KeyPassphrase, "correct horse")
+	require.NoError(t, err)
+	assert.NotEqual(t, value, other, "values should be encrypted with a random salt and nonce")
+
+	plaintext, err := Decrypt(value, "correct horse")
+	require.NoError(t, err)
+	assert.Equal(t, "s3cr3t!", plaintext)
+
+	_, err = Decrypt(value, "battery staple")
+	assert.ErrorContains(t, err, "wrong passphrase")
+	_, err = Decrypt(value, "")
+	assert.ErrorIs(t, err, ErrPassphraseRequired)
+
+	_, err = Encrypt("s3cr3t!", KeyPassphrase, "")
+	assert.Error(t, err)
+	_, err = Encrypt("s3cr3t!", "foo", "correct horse")
+	assert.ErrorContains(t, err, `unknown key "foo"`)
+
+	for _, invalid := range []string{"no key", "passphrase:not base64!", "passphrase:AAAA", "foo:AAAA"} {
+		_, err = Decrypt(invalid, "correct horse")
+		assert.Error(t, err, invalid)
+	}
+}
+
+func TestResolverDecrypt(t *testing.T) {
+	value, err := Encrypt("s3cr3t!", KeyPassphrase, "correct horse")
+	require.NoError(t, err)
+
+	r := NewResolver(context.Background())
+	_, err = r.Decrypt(value)
+	assert.ErrorContains(t, err, EnvPassphrase)
+
+	t.Setenv(EnvPassphrase, "correct horse")
+	plaintext, err := r.Decrypt(value)
+	require.NoError(t, err)
+	assert.Equal(t, "s3cr3t!", plaintext)
+
+	t.Setenv(EnvPassphrase, "battery staple")
+	plaintext, err = r.Decrypt(value)
+	require.NoError(t, err, "decrypted values should be cached")
+	assert.Equal(t, "s3cr3t!", plaintext)
+}
+
+func TestEncryptedReplace(t *testing.T) {
+	t.Setenv(EnvPassphrase, "correct horse")
+	value, err := Encrypt("old token", KeyPassphrase, "correct horse")
+	require.NoError(t, err)
+
+	replaced, err := Encrypted(value).Replace("new token")
+	require.NoError(t, err)
+	plaintext, err := Decrypt(string(replaced), "correct horse")
+	require.NoError(t, err)
+	assert.Equal(t, "new token", plaintext)
+
+	data, err := yaml.Marshal(map[string]interface{}{"accessToken": replaced})
+	require.NoError(t, err)
+	assert.Equal(t, "accessToken: "+Tag+" "+string(replaced)+"\n", string(data))
+}
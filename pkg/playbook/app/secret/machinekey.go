@@ -0,0 +1,118 @@
+//go:build !windows
+
+/*
+ * Copyright 2023 Venafi, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *  http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package secret
+
+import (
+	"bytes"
+	"crypto/rand"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"io"
+	"os/exec"
+	"strings"
+)
+
+const machineKeySource = sourceKeyring
+
+// secretTool is the command line tool of libsecret, that stores the AES key of the keyring values in the keyring of
+// the user, like GNOME Keyring or KWallet
+var secretTool = "secret-tool"
+
+var (
+	keyringAttributes = []string{"service", "vcert", "key", "playbook-secrets"}
+	keyringLabel      = "VCert playbook secrets"
+
+	errNoKeyringKey = errors.New("no key in the keyring")
+)
+
+func sealWithMachineKey(plaintext []byte) ([]byte, error) {
+	key, err := readKeyringKey()
+	if errors.Is(err, errNoKeyringKey) {
+		key, err = createKeyringKey()
+	}
+	if err != nil {
+		return nil, err
+	}
+	return seal(key, plaintext, []byte(sourceKeyring))
+}
+
+func openWithMachineKey(ciphertext []byte) ([]byte, error) {
+	key, err := readKeyringKey()
+	if errors.Is(err, errNoKeyringKey) {
+		return nil, fmt.Errorf("the keyring of the user has no vcert key. Values encrypted with the keyring key " +
+			"can only be decrypted by the user and machine that encrypted them")
+	} else if err != nil {
+		return nil, err
+	}
+	plaintext, err := open(key, ciphertext, []byte(sourceKeyring))
+	if err != nil {
+		return nil, fmt.Errorf("the value was not encrypted with the key in the keyring of this user")
+	}
+	return plaintext, nil
+}
+
+func readKeyringKey() ([]byte, error) {
+	var stdout bytes.Buffer
+	cmd := exec.Command(secretTool, append([]string{"lookup"}, keyringAttributes...)...)
+	cmd.Stdout = &stdout
+	err := cmd.Run()
+	var exitErr *exec.ExitError
+	// secret-tool lookup exits with 1 and prints nothing when there is no such secret
+	if errors.As(err, &exitErr) && exitErr.ExitCode() == 1 && stdout.Len() == 0 {
+		return nil, errNoKeyringKey
+	} else if err != nil {
+		return nil, keyringError(err)
+	}
+
+	key, err := base64.StdEncoding.DecodeString(strings.TrimSpace(stdout.String()))
+	if err != nil || len(key) != keySize {
+		return nil, fmt.Errorf("the vcert key in the keyring is invalid")
+	}
+	return key, nil
+}
+
+func createKeyringKey() ([]byte, error) {
+	key := make([]byte, keySize)
+	_, err := io.ReadFull(rand.Reader, key)
+	if err != nil {
+		return nil, err
+	}
+
+	var stderr bytes.Buffer
+	cmd := exec.Command(secretTool, append([]string{"store", "--label", keyringLabel}, keyringAttributes...)...)
+	cmd.Stdin = strings.NewReader(base64.StdEncoding.EncodeToString(key))
+	cmd.Stderr = &stderr
+	err = cmd.Run()
+	if err != nil {
+		if msg := strings.TrimSpace(stderr.String()); msg != "" {
+			err = fmt.Errorf("%w: %s", err, msg)
+		}
+		return nil, keyringError(err)
+	}
+	return key, nil
+}
+
+func keyringError(err error) error {
+	if errors.Is(err, exec.ErrNotFound) {
+		return fmt.Errorf("the machine key is kept in the keyring with %s, which was not found. Install libsecret "+
+			"or encrypt the value with a passphrase", secretTool)
+	}
+	return fmt.Errorf("could not access the keyring: %w", err)
+}
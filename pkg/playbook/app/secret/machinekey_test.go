@@ -0,0 +1,77 @@
+//go:build !windows
+
+/*
+ * Copyright 2023 Venafi, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *  http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package secret
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// fakeSecretTool replaces secret-tool with a script keeping the secret in a file
+func fakeSecretTool(t *testing.T) string {
+	dir := t.TempDir()
+	store := filepath.Join(dir, "keyring")
+	script := `#!/bin/sh
+case "$1" in
+lookup) [ -f "` + store + `" ] || exit 1; cat "` + store + `" ;;
+store) cat > "` + store + `" ;;
+*) exit 2 ;;
+esac
+`
+	tool := filepath.Join(dir, "secret-tool")
+	require.NoError(t, os.WriteFile(tool, []byte(script), 0700))
+
+	previous := secretTool
+	secretTool = tool
+	t.Cleanup(func() { secretTool = previous })
+	return store
+}
+
+func TestEncryptWithKeyring(t *testing.T) {
+	store := fakeSecretTool(t)
+
+	value, err := Encrypt("s3cr3t!", KeyMachine, "")
+	require.NoError(t, err)
+	assert.Regexp(t, "^keyring:", value)
+	assert.FileExists(t, store, "the key should be created in the keyring")
+
+	other, err := Encrypt("other", KeyMachine, "")
+	require.NoError(t, err)
+
+	for expected, v := range map[string]string{"s3cr3t!": value, "other": other} {
+		plaintext, err := Decrypt(v, "")
+		require.NoError(t, err)
+		assert.Equal(t, expected, plaintext)
+	}
+
+	_, err = Decrypt("dpapi:AAAA", "")
+	assert.ErrorContains(t, err, "cannot be decrypted")
+
+	require.NoError(t, os.Remove(store))
+	_, err = Decrypt(value, "")
+	assert.ErrorContains(t, err, "has no vcert key")
+
+	secretTool = "vcert-missing-secret-tool"
+	_, err = Encrypt("s3cr3t!", KeyMachine, "")
+	assert.ErrorContains(t, err, "Install libsecret")
+}
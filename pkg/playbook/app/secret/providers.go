@@ -0,0 +1,205 @@
+/*
+ * Copyright 2023 Venafi, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *  http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package secret
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/Venafi/vcert/v5/pkg/playbook/util/aws"
+	"github.com/Venafi/vcert/v5/pkg/playbook/util/azure"
+	"github.com/Venafi/vcert/v5/pkg/playbook/util/vault"
+)
+
+const (
+	// ProviderEnv reads secrets from environment variables. Reference: <variable>
+	ProviderEnv = "env"
+	// ProviderVault reads secrets from the KV v2 secrets engine of HashiCorp Vault. Reference: <mount>/<path>[#<field>]
+	ProviderVault = "vault"
+	// ProviderAWSSecretsManager reads secrets from AWS Secrets Manager. Reference: <name or ARN>[#<JSON key>]
+	ProviderAWSSecretsManager = "awssm"
+	// ProviderAzureKeyVault reads secrets from Azure Key Vault. Reference: <vault name>/<secret>[/<version>]
+	ProviderAzureKeyVault = "azkv"
+
+	envVaultAddress   = "VAULT_ADDR"
+	envVaultToken     = "VAULT_TOKEN"
+	envVaultNamespace = "VAULT_NAMESPACE"
+	envVaultCACert    = "VAULT_CACERT"
+	envVaultRoleID    = "VAULT_ROLE_ID"
+	envVaultSecretID  = "VAULT_SECRET_ID"
+	envVaultK8sRole   = "VAULT_K8S_ROLE"
+	envVaultAuthMount = "VAULT_AUTH_MOUNT"
+
+	envAWSRegion        = "AWS_REGION"
+	envAWSDefaultRegion = "AWS_DEFAULT_REGION"
+
+	envAzureTenantID     = "AZURE_TENANT_ID"
+	envAzureClientID     = "AZURE_CLIENT_ID"
+	envAzureClientSecret = "AZURE_CLIENT_SECRET"
+	azureKeyVaultDomain  = "vault.azure.net"
+
+	fieldSeparator = "#"
+)
+
+func init() {
+	RegisterProvider(ProviderEnv, ProviderFunc(resolveEnv))
+	RegisterProvider(ProviderVault, ProviderFunc(resolveVault))
+	RegisterProvider(ProviderAWSSecretsManager, ProviderFunc(resolveAWSSecretsManager))
+	RegisterProvider(ProviderAzureKeyVault, ProviderFunc(resolveAzureKeyVault))
+}
+
+func resolveEnv(_ context.Context, ref string) (string, error) {
+	value, found := os.LookupEnv(ref)
+	if !found {
+		return "", fmt.Errorf("environment variable not defined: %s", ref)
+	}
+	return value, nil
+}
+
+// resolveVault reads the field of a KV v2 secret. The field can be omitted when the secret has a single one.
+// The Vault address and credentials are read from the same environment variables the Vault CLI uses, with
+// VAULT_ROLE_ID and VAULT_SECRET_ID for AppRole and VAULT_K8S_ROLE for Kubernetes authentication
+func resolveVault(ctx context.Context, ref string) (string, error) {
+	address := os.Getenv(envVaultAddress)
+	if address == "" {
+		return "", fmt.Errorf("%s environment variable is required to read secrets from Vault", envVaultAddress)
+	}
+	path, field, _ := strings.Cut(ref, fieldSeparator)
+	mount, path, found := strings.Cut(strings.Trim(path, "/"), "/")
+	if !found || path == "" {
+		return "", fmt.Errorf("invalid Vault secret reference %q. Should be <mount>/<path>[#<field>]", ref)
+	}
+
+	credentials := vault.Credentials{
+		Token:     os.Getenv(envVaultToken),
+		RoleID:    os.Getenv(envVaultRoleID),
+		SecretID:  os.Getenv(envVaultSecretID),
+		K8sRole:   os.Getenv(envVaultK8sRole),
+		AuthMount: os.Getenv(envVaultAuthMount),
+	}
+	client, err := vault.NewClient(ctx, address, os.Getenv(envVaultNamespace), mount, os.Getenv(envVaultCACert), credentials)
+	if err != nil {
+		return "", err
+	}
+
+	secret, err := client.ReadSecret(ctx, path, 0)
+	if err != nil {
+		return "", err
+	}
+	if secret == nil {
+		return "", fmt.Errorf("secret %s not found in Vault mount %s", path, mount)
+	}
+	return getField(secret.Data, field)
+}
+
+// resolveAWSSecretsManager reads a secret from AWS Secrets Manager. When a key is given, the secret is parsed as a
+// JSON object and the value of the key is returned. The region is taken from the ARN, or from the AWS_REGION and
+// AWS_DEFAULT_REGION environment variables
+func resolveAWSSecretsManager(ctx context.Context, ref string) (string, error) {
+	id, key, hasKey := strings.Cut(ref, fieldSeparator)
+
+	region := os.Getenv(envAWSRegion)
+	if region == "" {
+		region = os.Getenv(envAWSDefaultRegion)
+	}
+	// arn:aws:secretsmanager:<region>:<account>:secret:<name>
+	if parts := strings.Split(id, ":"); len(parts) > 3 && parts[0] == "arn" {
+		region = parts[3]
+	}
+	if region == "" {
+		return "", fmt.Errorf("the AWS region of secret %s is required. Use its ARN or set %s", id, envAWSRegion)
+	}
+
+	client, err := aws.NewSecretsManagerClient(region, "")
+	if err != nil {
+		return "", err
+	}
+
+	value, err := client.GetSecretValue(ctx, id)
+	if err != nil {
+		return "", err
+	}
+	if !hasKey {
+		return value, nil
+	}
+
+	data := make(map[string]interface{})
+	err = json.Unmarshal([]byte(value), &data)
+	if err != nil {
+		return "", fmt.Errorf("secret %s is not a JSON object, so key %s cannot be read", id, key)
+	}
+	return getField(data, key)
+}
+
+// resolveAzureKeyVault reads a secret from Azure Key Vault, authenticating with the service principal set in the
+// AZURE_TENANT_ID, AZURE_CLIENT_ID and AZURE_CLIENT_SECRET environment variables, or with a managed identity when
+// AZURE_CLIENT_SECRET is not set. The vault may be given by name or by its host name
+func resolveAzureKeyVault(ctx context.Context, ref string) (string, error) {
+	parts := strings.Split(strings.Trim(ref, "/"), "/")
+	if len(parts) < 2 || len(parts) > 3 || parts[0] == "" || parts[1] == "" {
+		return "", fmt.Errorf("invalid Azure Key Vault secret reference %q. Should be <vault name>/<secret>[/<version>]", ref)
+	}
+	host := parts[0]
+	if !strings.Contains(host, ".") {
+		host = fmt.Sprintf("%s.%s", host, azureKeyVaultDomain)
+	}
+	version := ""
+	if len(parts) == 3 {
+		version = parts[2]
+	}
+
+	credentials := azure.Credentials{
+		TenantID:     os.Getenv(envAzureTenantID),
+		ClientID:     os.Getenv(envAzureClientID),
+		ClientSecret: os.Getenv(envAzureClientSecret),
+	}
+	client, err := azure.NewClient("https://"+host, credentials)
+	if err != nil {
+		return "", err
+	}
+
+	secret, err := client.GetSecret(ctx, parts[1], version)
+	if err != nil {
+		return "", err
+	}
+	return secret.Value, nil
+}
+
+// getField returns the string value of field in data. field may be empty when data has a single value
+func getField(data map[string]interface{}, field string) (string, error) {
+	if field == "" {
+		if len(data) != 1 {
+			return "", fmt.Errorf("the secret has %d fields. Use %s<field> to select one", len(data), fieldSeparator)
+		}
+		for k := range data {
+			field = k
+		}
+	}
+
+	value, found := data[field]
+	if !found {
+		return "", fmt.Errorf("field %s not found in secret", field)
+	}
+	s, ok := value.(string)
+	if !ok {
+		return "", fmt.Errorf("field %s of secret is not a string", field)
+	}
+	return s, nil
+}
@@ -0,0 +1,113 @@
+/*
+ * Copyright 2023 Venafi, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *  http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+// Package secret resolves the secret references set as values in the playbook, so credentials are read at runtime
+// from a secrets store instead of being written in the file.
+//
+// A reference has the form secretRef:<provider>:<reference>, where provider is the name of a registered Provider,
+// i.e. secretRef:env:TPP_PASSWORD or secretRef:vault:secret/vcert/tpp#password
+package secret
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"sync"
+)
+
+// Prefix identifies the playbook values that are secret references
+const Prefix = "secretRef:"
+
+// Provider retrieves secrets from a secrets store
+type Provider interface {
+	// Resolve returns the value of the secret identified by ref. The format of ref depends on the provider
+	Resolve(ctx context.Context, ref string) (string, error)
+}
+
+// ProviderFunc is a function used as a Provider
+type ProviderFunc func(ctx context.Context, ref string) (string, error)
+
+// Resolve calls f(ctx, ref)
+func (f ProviderFunc) Resolve(ctx context.Context, ref string) (string, error) {
+	return f(ctx, ref)
+}
+
+var (
+	providers     = make(map[string]Provider)
+	providersLock sync.RWMutex
+)
+
+// RegisterProvider makes provider available to the secret references with the given name.
+// A provider already registered with the same name is replaced
+func RegisterProvider(name string, provider Provider) {
+	providersLock.Lock()
+	defer providersLock.Unlock()
+	providers[name] = provider
+}
+
+func getProvider(name string) (Provider, bool) {
+	providersLock.RLock()
+	defer providersLock.RUnlock()
+	provider, found := providers[name]
+	return provider, found
+}
+
+// IsRef returns true if value is a secret reference
+func IsRef(value string) bool {
+	return strings.HasPrefix(value, Prefix)
+}
+
+// Resolver resolves secret references. Each reference is retrieved once and cached by the Resolver, so a secret used
+// in several places of the playbook is only read once from its store
+type Resolver struct {
+	ctx   context.Context
+	cache map[string]string
+}
+
+// NewResolver returns a Resolver that retrieves the secrets using ctx
+func NewResolver(ctx context.Context) *Resolver {
+	return &Resolver{
+		ctx:   ctx,
+		cache: make(map[string]string),
+	}
+}
+
+// Resolve returns the value of the secret referenced by value, or value itself when it is not a secret reference.
+// Errors never include the value of the secret
+func (r *Resolver) Resolve(value string) (string, error) {
+	if !IsRef(value) {
+		return value, nil
+	}
+	if resolved, found := r.cache[value]; found {
+		return resolved, nil
+	}
+
+	name, ref, found := strings.Cut(strings.TrimPrefix(value, Prefix), ":")
+	if !found || name == "" || ref == "" {
+		return "", fmt.Errorf("invalid secret reference %q. Should be %s<provider>:<reference>", value, Prefix)
+	}
+	provider, found := getProvider(name)
+	if !found {
+		return "", fmt.Errorf("unknown secret provider %q in %s", name, value)
+	}
+
+	resolved, err := provider.Resolve(r.ctx, ref)
+	if err != nil {
+		return "", fmt.Errorf("could not resolve %s: %w", value, err)
+	}
+	r.cache[value] = resolved
+	return resolved, nil
+}
@@ -0,0 +1,104 @@
+/*
+ * Copyright 2023 Venafi, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *  http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package secret
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestResolver(t *testing.T) {
+	t.Setenv("VCERT_TEST_SECRET", "s3cr3t!")
+	calls := 0
+	RegisterProvider("test", ProviderFunc(func(_ context.Context, ref string) (string, error) {
+		calls++
+		if ref == "missing" {
+			return "", errors.New("secret not found")
+		}
+		return "value of " + ref, nil
+	}))
+
+	r := NewResolver(context.Background())
+
+	value, err := r.Resolve("plain value")
+	require.NoError(t, err)
+	assert.Equal(t, "plain value", value)
+
+	value, err = r.Resolve("secretRef:env:VCERT_TEST_SECRET")
+	require.NoError(t, err)
+	assert.Equal(t, "s3cr3t!", value)
+
+	for i := 0; i < 2; i++ {
+		value, err = r.Resolve("secretRef:test:my/secret:with:colons")
+		require.NoError(t, err)
+		assert.Equal(t, "value of my/secret:with:colons", value)
+	}
+	assert.Equal(t, 1, calls, "resolved secrets should be cached")
+
+	for _, ref := range []string{"secretRef:env:VCERT_TEST_UNDEFINED", "secretRef:test:missing", "secretRef:foo:bar", "secretRef:env", "secretRef::bar"} {
+		_, err = r.Resolve(ref)
+		assert.Error(t, err, ref)
+	}
+}
+
+func TestResolveVault(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("X-Vault-Token") != "root" {
+			w.WriteHeader(http.StatusForbidden)
+			return
+		}
+		var data map[string]interface{}
+		switch r.URL.Path {
+		case "/v1/kv/data/vcert/tpp":
+			data = map[string]interface{}{"username": "admin", "password": "p@ss"}
+		case "/v1/kv/data/vcert/apikey":
+			data = map[string]interface{}{"apiKey": "abcd-1234"}
+		default:
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		_ = json.NewEncoder(w).Encode(map[string]interface{}{"data": map[string]interface{}{"data": data}})
+	}))
+	defer server.Close()
+	t.Setenv(envVaultAddress, server.URL)
+	t.Setenv(envVaultToken, "root")
+
+	r := NewResolver(context.Background())
+
+	value, err := r.Resolve("secretRef:vault:kv/vcert/tpp#password")
+	require.NoError(t, err)
+	assert.Equal(t, "p@ss", value)
+
+	value, err = r.Resolve("secretRef:vault:kv/vcert/apikey")
+	require.NoError(t, err)
+	assert.Equal(t, "abcd-1234", value)
+
+	for _, ref := range []string{"secretRef:vault:kv/vcert/tpp", "secretRef:vault:kv/vcert/tpp#foo", "secretRef:vault:kv/vcert/missing#password", "secretRef:vault:kv"} {
+		_, err = r.Resolve(ref)
+		assert.Error(t, err, ref)
+		if err != nil {
+			assert.NotContains(t, err.Error(), "p@ss")
+		}
+	}
+}
@@ -0,0 +1,97 @@
+/*
+ * Copyright 2023 Venafi, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *  http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package service
+
+import (
+	"context"
+
+	"go.uber.org/zap"
+
+	"github.com/Venafi/vcert/v5/pkg/playbook/app/audit"
+	"github.com/Venafi/vcert/v5/pkg/playbook/app/domain"
+	"github.com/Venafi/vcert/v5/pkg/playbook/app/report"
+	"github.com/Venafi/vcert/v5/pkg/playbook/app/state"
+	"github.com/Venafi/vcert/v5/pkg/playbook/app/vcertutil"
+)
+
+// adoptCertificate searches the Venafi platform for the certificate installed, described by result, and records it
+// in the state file as issued to the task. It only runs once: tasks already in the state file are managed by vcert.
+// A certificate not found is not an error, the task requests a new certificate when it needs one
+func adoptCertificate(ctx context.Context, logger *zap.Logger, config domain.Config, rec auditRecorder, task domain.CertificateTask, result report.TaskResult) {
+	if config.State == nil || result.Thumbprint == "" {
+		return
+	}
+	if _, found := config.State.Task(task.Name); found {
+		return
+	}
+
+	zone := task.Request.Zone
+	if zones := task.Request.GetZones(); len(zones) > 0 {
+		zone = zones[0]
+	}
+	if config.DryRun {
+		logger.Info("dry run: certificate installed would be searched in the Venafi platform to be adopted",
+			zap.String("thumbprint", result.Thumbprint), zap.String("zone", zone))
+		return
+	}
+
+	event := audit.Event{Operation: audit.OperationAdopt, Zone: zone, CommonName: task.Request.Subject.CommonName,
+		Serial: result.Serial, Thumbprint: result.Thumbprint}
+	pickupID, err := vcertutil.FindCertificate(ctx, config, zone, result.Thumbprint)
+	if err != nil {
+		rec.record(event, err)
+		logger.Warn("certificate installed not found in the Venafi platform. It is not adopted",
+			zap.String("thumbprint", result.Thumbprint), zap.Error(err))
+		return
+	}
+	event.PickupID = pickupID
+	rec.record(event, nil)
+	logger.Info("adopted certificate installed", zap.String("certificate", task.Request.Subject.CommonName),
+		zap.String("pickupID", pickupID))
+	recordAdopted(logger, config, task, pickupID, zone, result)
+}
+
+// adoptedPickupID returns the ID of the certificate adopted by the task, to be renewed instead of requesting a new one,
+// and the zone it was adopted in
+func adoptedPickupID(config domain.Config, task domain.CertificateTask) (string, string) {
+	if !task.Adopt || config.State == nil {
+		return "", ""
+	}
+	ts, found := config.State.Task(task.Name)
+	if !found || !ts.Adopted || ts.Status != state.StatusIssued || ts.PickupID == "" {
+		return "", ""
+	}
+	return ts.PickupID, ts.Zone
+}
+
+// recordAdopted saves the certificate installed, described by result, as issued to the task with pickupID
+func recordAdopted(logger *zap.Logger, config domain.Config, task domain.CertificateTask, pickupID string, zone string, result report.TaskResult) {
+	// The certificate was not retrieved by vcert, so IssuedAt is left unset
+	err := config.State.SetTask(task.Name, state.TaskState{
+		Status:     state.StatusIssued,
+		PickupID:   pickupID,
+		Zone:       zone,
+		Serial:     result.Serial,
+		Thumbprint: result.Thumbprint,
+		NotAfter:   result.NotAfter,
+		RenewAt:    result.RenewAt,
+		Adopted:    true,
+	})
+	if err != nil {
+		logger.Warn("failed to record adopted certificate in state file", zap.Error(err))
+	}
+}
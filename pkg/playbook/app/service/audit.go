@@ -0,0 +1,93 @@
+/*
+ * Copyright 2023 Venafi, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *  http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package service
+
+import (
+	"strconv"
+	"strings"
+
+	"github.com/Venafi/vcert/v5/pkg/certificate"
+	"github.com/Venafi/vcert/v5/pkg/playbook/app/audit"
+	"github.com/Venafi/vcert/v5/pkg/playbook/app/domain"
+)
+
+// auditRecorder records the operations of a task in the audit log of the run, when there is one
+type auditRecorder struct {
+	log      *audit.Log
+	task     string
+	platform string
+}
+
+func newAuditRecorder(config domain.Config, task domain.CertificateTask) auditRecorder {
+	return auditRecorder{log: config.AuditLog, task: task.Name, platform: config.Connection.Platform.String()}
+}
+
+// record writes event, failed with err when it is not nil
+func (r auditRecorder) record(event audit.Event, err error) {
+	if r.log == nil {
+		return
+	}
+	event.Task = r.task
+	event.Platform = r.platform
+	if err != nil {
+		event.Error = err.Error()
+	}
+	r.log.Record(event)
+}
+
+// requestParameters returns the parameters of the certificate request of the task recorded in the audit log.
+// Secrets, like the key password, are left out
+func requestParameters(request domain.PlaybookRequest) map[string]string {
+	params := map[string]string{"keyType": request.KeyType.String()}
+	if request.CsrOrigin != "" {
+		params["csrOrigin"] = request.CsrOrigin
+	}
+	if request.KeystoreFormat != "" {
+		params["keystoreFormat"] = strings.ToLower(request.KeystoreFormat)
+	}
+	if request.KeyLength > 0 {
+		params["keySize"] = strconv.Itoa(request.KeyLength)
+	}
+	if request.KeyCurve != certificate.EllipticCurveNotSet {
+		params["keyCurve"] = request.KeyCurve.String()
+	}
+	if request.ReuseKey {
+		params["reuseKey"] = "true"
+	}
+	if request.ValidDays != "" {
+		params["validDays"] = request.ValidDays
+	}
+	if request.ValidityHours > 0 {
+		params["validityHours"] = strconv.Itoa(request.ValidityHours)
+	}
+	if request.NotAfter != "" {
+		params["notAfter"] = request.NotAfter
+	}
+	for name, values := range map[string][]string{
+		"sanDNS":         request.DNSNames,
+		"sanIP":          request.IPAddresses,
+		"sanEmail":       request.EmailAddresses,
+		"sanURI":         request.URIs,
+		"sanUPN":         request.UPNs,
+		"applicationDNs": request.ApplicationDNs,
+	} {
+		if len(values) > 0 {
+			params[name] = strings.Join(values, ",")
+		}
+	}
+	return params
+}
@@ -0,0 +1,147 @@
+/*
+ * Copyright 2023 Venafi, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *  http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package service
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/zap"
+
+	"github.com/Venafi/vcert/v5/pkg/certificate"
+	"github.com/Venafi/vcert/v5/pkg/playbook/app/domain"
+	"github.com/Venafi/vcert/v5/test/testcert"
+)
+
+// newTestCA issues a CA certificate signed by parent, whose issuer can be downloaded from aia when set
+func newTestCA(cn string, parent *testcert.Certificate, aia string) *testcert.Certificate {
+	template := testcert.Template(cn, true)
+	if aia != "" {
+		template.IssuingCertificateURL = []string{aia}
+	}
+	ca := testcert.Issue(template, nil, parent)
+	return &ca
+}
+
+func TestCompleteChain(t *testing.T) {
+	downloads := 0
+	var root, intermediate *testcert.Certificate
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		downloads++
+		switch r.URL.Path {
+		case "/root.crt":
+			_, _ = w.Write(root.Cert.Raw)
+		case "/intermediate.crt":
+			_, _ = w.Write(intermediate.Cert.Raw)
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer server.Close()
+
+	root = newTestCA("Test Root", nil, "")
+	intermediate = newTestCA("Test Intermediate", root, server.URL+"/root.crt")
+	leaf := newTestCA("leaf.example.com", intermediate, server.URL+"/intermediate.crt")
+
+	task := domain.CertificateTask{Request: domain.PlaybookRequest{Subject: domain.Subject{CommonName: "leaf.example.com"}}}
+	config := domain.Config{IntermediatesDir: t.TempDir(), RequireCompleteChain: true}
+
+	// The issuers are downloaded and appended after the chain returned
+	pcc := &certificate.PEMCollection{Certificate: leaf.PEM()}
+	err := completeChain(zap.NewNop(), config, task, pcc)
+	assert.NoError(t, err)
+	assert.Equal(t, []string{intermediate.PEM(), root.PEM()}, pcc.Chain)
+	assert.Equal(t, 2, downloads)
+
+	// The chain is complete, nothing is downloaded
+	err = completeChain(zap.NewNop(), config, task, pcc)
+	assert.NoError(t, err)
+	assert.Equal(t, 2, downloads)
+
+	// The cached issuers are used, and prepended when the root goes first
+	task.Request.ChainOption = certificate.ChainOptionRootFirst
+	pcc = &certificate.PEMCollection{Certificate: leaf.PEM()}
+	err = completeChain(zap.NewNop(), config, task, pcc)
+	assert.NoError(t, err)
+	assert.Equal(t, []string{root.PEM(), intermediate.PEM()}, pcc.Chain)
+	assert.Equal(t, 2, downloads)
+
+	// Chains are left alone when they are ignored
+	task.Request.ChainOption = certificate.ChainOptionIgnore
+	pcc = &certificate.PEMCollection{Certificate: leaf.PEM()}
+	err = completeChain(zap.NewNop(), config, task, pcc)
+	assert.NoError(t, err)
+	assert.Empty(t, pcc.Chain)
+}
+
+func TestCompleteChainIncomplete(t *testing.T) {
+	root := newTestCA("Test Root", nil, "")
+	intermediate := newTestCA("Test Intermediate", root, "")
+	leaf := newTestCA("leaf.example.com", intermediate, "")
+
+	dir := t.TempDir()
+	task := domain.CertificateTask{Request: domain.PlaybookRequest{Subject: domain.Subject{CommonName: "leaf.example.com"}}}
+	pcc := &certificate.PEMCollection{Certificate: leaf.PEM(), Chain: []string{intermediate.PEM()}}
+
+	err := completeChain(zap.NewNop(), domain.Config{IntermediatesDir: dir}, task, pcc)
+	assert.NoError(t, err)
+	assert.Equal(t, []string{intermediate.PEM()}, pcc.Chain)
+
+	err = completeChain(zap.NewNop(), domain.Config{IntermediatesDir: dir, RequireCompleteChain: true}, task, pcc)
+	assert.Error(t, err)
+
+	entries, err := os.ReadDir(dir)
+	assert.NoError(t, err)
+	assert.Empty(t, entries)
+}
+
+func TestCompleteChainOrderAndTrust(t *testing.T) {
+	root := newTestCA("Test Root", nil, "")
+	intermediate := newTestCA("Test Intermediate", root, "")
+	leaf := newTestCA("leaf.example.com", intermediate, "")
+	otherRoot := newTestCA("Other Root", nil, "")
+
+	dir := t.TempDir()
+	task := domain.CertificateTask{Request: domain.PlaybookRequest{Subject: domain.Subject{CommonName: "leaf.example.com"}}}
+	config := domain.Config{IntermediatesDir: dir, RequireCompleteChain: true}
+
+	// A chain returned out of order is put in the order of the request
+	pcc := &certificate.PEMCollection{Certificate: leaf.PEM(), Chain: []string{root.PEM(), intermediate.PEM()}}
+	err := completeChain(zap.NewNop(), config, task, pcc)
+	assert.NoError(t, err)
+	assert.Equal(t, []string{intermediate.PEM(), root.PEM()}, pcc.Chain)
+
+	// The chain must validate against the trust bundle, when set
+	bundle := filepath.Join(dir, "roots.pem")
+	assert.NoError(t, os.WriteFile(bundle, []byte(otherRoot.PEM()), 0600))
+	config.ChainTrustBundle = bundle
+	err = completeChain(zap.NewNop(), config, task, pcc)
+	assert.ErrorContains(t, err, "does not validate")
+
+	config.RequireCompleteChain = false
+	err = completeChain(zap.NewNop(), config, task, pcc)
+	assert.NoError(t, err)
+
+	assert.NoError(t, os.WriteFile(bundle, []byte(otherRoot.PEM()+root.PEM()), 0600))
+	config.RequireCompleteChain = true
+	err = completeChain(zap.NewNop(), config, task, pcc)
+	assert.NoError(t, err)
+}
@@ -0,0 +1,337 @@
+/*
+ * Copyright 2023 Venafi, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *  http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package service
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+	"strings"
+	"sync"
+	"time"
+
+	"go.uber.org/zap"
+
+	"github.com/Venafi/vcert/v5/pkg/playbook/app/domain"
+	"github.com/Venafi/vcert/v5/pkg/playbook/app/scheduler"
+	"github.com/Venafi/vcert/v5/pkg/venafi"
+	"github.com/Venafi/vcert/v5/pkg/venafi/acme"
+)
+
+const (
+	// DefaultSchedule represents the schedule used in daemon mode for certificate tasks that do not declare one
+	DefaultSchedule = "@every 1h"
+	// DefaultJitter represents the maximum random delay added to every scheduled task run in daemon mode
+	DefaultJitter = time.Minute
+)
+
+// Daemon keeps a playbook resident and runs each of its certificate tasks according to the task schedule
+type Daemon struct {
+	playbook  domain.Playbook
+	scheduler *scheduler.Scheduler
+	mu        sync.Mutex
+	// slots limits the number of tasks running at the same time to config.concurrency
+	slots chan struct{}
+	// taskLocks prevents two runs of the same task from overlapping, which may happen when Reload modifies a running task
+	taskLocks map[string]*sync.Mutex
+	// reloadRuns tracks the task runs started by Reload, which Stop waits for
+	reloadRuns sync.WaitGroup
+	// ctx is the context given to Start, used by every task run
+	ctx context.Context
+	// releaseHTTPServer stops the built-in http-01 challenge server held by the daemon, if any
+	releaseHTTPServer func() error
+}
+
+// NewDaemon returns a Daemon for the given playbook.
+//
+// jitter is the maximum random delay added to every scheduled run, so that many hosts sharing
+// the same playbook do not hit the Venafi platform at the same time
+func NewDaemon(playbook domain.Playbook, jitter time.Duration) (*Daemon, error) {
+	concurrency := playbook.Config.Concurrency
+	if concurrency < 1 {
+		concurrency = 1
+	}
+
+	d := &Daemon{
+		playbook:  playbook,
+		scheduler: scheduler.NewScheduler(jitter),
+		slots:     make(chan struct{}, concurrency),
+		taskLocks: make(map[string]*sync.Mutex),
+	}
+
+	for _, task := range playbook.CertificateTasks {
+		job, err := d.newJob(task)
+		if err != nil {
+			return nil, err
+		}
+		err = d.scheduler.Add(job)
+		if err != nil {
+			return nil, fmt.Errorf("%w: %s", domain.ErrDuplicateTaskName, task.Name)
+		}
+	}
+
+	return d, nil
+}
+
+// Start runs every certificate task once and then schedules them.
+// Once ctx is done, the task runs in progress are cancelled and the tasks not started yet fail
+func (d *Daemon) Start(ctx context.Context) {
+	zap.L().Info("starting playbook daemon", zap.Int("tasks", len(d.playbook.CertificateTasks)))
+	d.ctx = ctx
+
+	// The http-01 challenge server listens as long as the daemon runs, instead of claiming the port on every renewal
+	if address, ok := httpChallengeAddress(d.playbook.Config.Connection); ok {
+		release, err := acme.HoldHTTPServer(address)
+		if err != nil {
+			zap.L().Error("could not start http-01 challenge server", zap.String("address", address), zap.Error(err))
+		} else {
+			zap.L().Info("http-01 challenge server listening", zap.String("address", address))
+			d.releaseHTTPServer = release
+		}
+	}
+
+	// The first run of every task goes through runTask, like the scheduled ones, so it takes the task lock and a
+	// concurrency slot. The tasks are queued in the order they are declared
+	queue := make(chan domain.CertificateTask)
+	wg := sync.WaitGroup{}
+	for i := 0; i < cap(d.slots); i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for task := range queue {
+				d.runTask(task, true)
+			}
+		}()
+	}
+	d.mu.Lock()
+	tasks := d.playbook.CertificateTasks
+	d.mu.Unlock()
+	for _, task := range tasks {
+		queue <- task
+	}
+	close(queue)
+	wg.Wait()
+
+	d.scheduler.Start()
+}
+
+// Stop prevents any further task run and waits for the runs in progress to finish
+func (d *Daemon) Stop() {
+	zap.L().Info("stopping playbook daemon. Waiting for running tasks to finish")
+	d.scheduler.Stop()
+	d.reloadRuns.Wait()
+	if d.releaseHTTPServer != nil {
+		err := d.releaseHTTPServer()
+		if err != nil {
+			zap.L().Warn("could not stop http-01 challenge server", zap.Error(err))
+		}
+	}
+	zap.L().Info("playbook daemon stopped")
+}
+
+// Reload replaces the playbook of the Daemon with the given one, which must be valid.
+//
+// Tasks removed from the playbook are unscheduled, while new and modified tasks are scheduled and run right away.
+// Unchanged tasks keep their schedule, and the task runs in progress are allowed to finish.
+// The concurrency of the Daemon and the address of the http-01 challenge server are set when it is started,
+// so changing them requires a restart
+func (d *Daemon) Reload(playbook domain.Playbook) error {
+	d.mu.Lock()
+	current := d.playbook
+	d.mu.Unlock()
+
+	// Tasks are scheduled by name, so a duplicate name would replace the job of the first task with the second one
+	names := make(map[string]bool, len(playbook.CertificateTasks))
+	for _, task := range playbook.CertificateTasks {
+		if names[task.Name] {
+			return fmt.Errorf("%w: %s", domain.ErrDuplicateTaskName, task.Name)
+		}
+		names[task.Name] = true
+	}
+
+	changed, removed := diffTasks(current.CertificateTasks, playbook.CertificateTasks)
+
+	// Jobs are created before any change is made, so an invalid schedule leaves the Daemon as it is
+	jobs := make([]scheduler.Job, 0, len(changed))
+	for _, task := range changed {
+		job, err := d.newJob(task)
+		if err != nil {
+			return err
+		}
+		jobs = append(jobs, job)
+	}
+
+	if playbook.Config.Concurrency != current.Config.Concurrency {
+		zap.L().Warn("concurrency changes are applied when the playbook daemon is restarted",
+			zap.Int("concurrency", current.Config.Concurrency))
+	}
+	oldAddress, _ := httpChallengeAddress(current.Config.Connection)
+	if newAddress, ok := httpChallengeAddress(playbook.Config.Connection); ok && newAddress != oldAddress {
+		zap.L().Warn("http-01 challenge server changes are applied when the playbook daemon is restarted",
+			zap.String("address", oldAddress))
+	}
+
+	d.mu.Lock()
+	d.playbook = playbook
+	d.mu.Unlock()
+
+	for _, name := range removed {
+		zap.L().Info("removing playbook task", zap.String("task", name))
+		d.scheduler.Remove(name)
+	}
+	for i, job := range jobs {
+		zap.L().Info("scheduling new or modified playbook task", zap.String("task", job.Name))
+		d.scheduler.Replace(job)
+
+		// Tasks are not run before the Daemon is started, as Start runs every task
+		if d.ctx != nil {
+			task := changed[i]
+			d.reloadRuns.Add(1)
+			go func() {
+				defer d.reloadRuns.Done()
+				d.runTask(task, false)
+			}()
+		}
+	}
+
+	zap.L().Info("playbook reloaded", zap.Int("changed", len(changed)), zap.Int("removed", len(removed)))
+	return nil
+}
+
+// diffTasks returns the tasks in newTasks that are not in oldTasks or that are different,
+// and the names of the tasks in oldTasks that are not in newTasks
+func diffTasks(oldTasks domain.CertificateTasks, newTasks domain.CertificateTasks) (domain.CertificateTasks, []string) {
+	oldByName := make(map[string]domain.CertificateTask, len(oldTasks))
+	for _, task := range oldTasks {
+		oldByName[task.Name] = task
+	}
+
+	changed := make(domain.CertificateTasks, 0)
+	newNames := make(map[string]bool, len(newTasks))
+	for _, task := range newTasks {
+		newNames[task.Name] = true
+		oldTask, found := oldByName[task.Name]
+		if !found || !reflect.DeepEqual(oldTask, task) {
+			changed = append(changed, task)
+		}
+	}
+
+	removed := make([]string, 0)
+	for _, task := range oldTasks {
+		if !newNames[task.Name] {
+			removed = append(removed, task.Name)
+		}
+	}
+	return changed, removed
+}
+
+func (d *Daemon) newJob(task domain.CertificateTask) (scheduler.Job, error) {
+	spec := task.Schedule
+	if spec == "" {
+		spec = DefaultSchedule
+	}
+
+	schedule, err := scheduler.ParseSchedule(spec)
+	if err != nil {
+		return scheduler.Job{}, fmt.Errorf("invalid schedule for task %s: %w", task.Name, err)
+	}
+
+	job := scheduler.Job{
+		Name:     task.Name,
+		Schedule: schedule,
+		Run: func() {
+			d.runTask(task, false)
+		},
+	}
+	return job, nil
+}
+
+// runTask runs task once no other run of it is in progress and a concurrency slot is free.
+// The force-renew flag of the playbook only applies to the first run of the task, run by Start
+func (d *Daemon) runTask(task domain.CertificateTask, firstRun bool) {
+	lock := d.getTaskLock(task.Name)
+	lock.Lock()
+	defer lock.Unlock()
+
+	d.slots <- struct{}{}
+	defer func() { <-d.slots }()
+
+	if d.ctx.Err() != nil {
+		return
+	}
+
+	config, err := d.getConfig()
+	if err != nil {
+		zap.L().Error("could not prepare connection for task", zap.String("task", task.Name), zap.Error(err))
+		return
+	}
+	config.ForceRenew = config.ForceRenew && firstRun
+
+	zap.L().Info("running playbook task", zap.String("task", task.Name))
+	errors := Execute(d.ctx, config, task)
+	for _, err2 := range errors {
+		zap.L().Error("error running task", zap.String("task", task.Name), zap.Error(err2))
+	}
+}
+
+func (d *Daemon) getTaskLock(name string) *sync.Mutex {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	lock, found := d.taskLocks[name]
+	if !found {
+		lock = &sync.Mutex{}
+		d.taskLocks[name] = lock
+	}
+	return lock
+}
+
+// httpChallengeAddress returns the address of the built-in http-01 challenge server used by connection.
+// ok is false when the connection does not fulfill challenges with it
+func httpChallengeAddress(connection domain.Connection) (address string, ok bool) {
+	if connection.Platform != venafi.ACME || connection.DNSProvider != nil {
+		return "", false
+	}
+
+	challenge := connection.ACMEChallenge
+	if challenge == nil {
+		return acme.DefaultHTTPAddress, true
+	}
+	if (challenge.Type != "" && !strings.EqualFold(challenge.Type, acme.ChallengeHTTP01)) || challenge.Webroot != "" {
+		return "", false
+	}
+	if challenge.HTTPAddress == "" {
+		return acme.DefaultHTTPAddress, true
+	}
+	return challenge.HTTPAddress, true
+}
+
+// getConfig returns the connection configuration for the next task run.
+// TPP tokens are validated, and refreshed if needed, since they may expire while the daemon is running
+func (d *Daemon) getConfig() (domain.Config, error) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	if d.playbook.Config.UsesPlatform(venafi.TPP) {
+		err := ValidateTPPCredentials(d.ctx, &d.playbook)
+		if err != nil {
+			return domain.Config{}, err
+		}
+	}
+
+	return d.playbook.Config, nil
+}
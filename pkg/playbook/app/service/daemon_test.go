@@ -0,0 +1,150 @@
+/*
+ * Copyright 2023 Venafi, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *  http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package service
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/Venafi/vcert/v5/pkg/playbook/app/domain"
+	"github.com/Venafi/vcert/v5/pkg/venafi"
+	"github.com/Venafi/vcert/v5/pkg/venafi/acme"
+)
+
+func TestDiffTasks(t *testing.T) {
+	oldTasks := domain.CertificateTasks{
+		{Name: "unchanged", Schedule: "@daily"},
+		{Name: "modified", Schedule: "@daily"},
+		{Name: "removed", Schedule: "@daily"},
+	}
+	newTasks := domain.CertificateTasks{
+		{Name: "added", Schedule: "@daily"},
+		{Name: "modified", Schedule: "@hourly"},
+		{Name: "unchanged", Schedule: "@daily"},
+	}
+
+	changed, removed := diffTasks(oldTasks, newTasks)
+	require.Len(t, changed, 2)
+	assert.Equal(t, "added", changed[0].Name)
+	assert.Equal(t, "modified", changed[1].Name)
+	assert.Equal(t, "@hourly", changed[1].Schedule)
+	assert.Equal(t, []string{"removed"}, removed)
+
+	changed, removed = diffTasks(oldTasks, oldTasks)
+	assert.Empty(t, changed)
+	assert.Empty(t, removed)
+}
+
+func TestDaemonReload(t *testing.T) {
+	playbook := domain.Playbook{
+		CertificateTasks: domain.CertificateTasks{{Name: "first", Schedule: "@daily"}},
+	}
+	d, err := NewDaemon(playbook, time.Minute)
+	require.NoError(t, err)
+
+	reloaded := domain.Playbook{
+		CertificateTasks: domain.CertificateTasks{{Name: "second", Schedule: "@hourly"}},
+		Config:           domain.Config{StateFile: "state.json"},
+	}
+	require.NoError(t, d.Reload(reloaded))
+	assert.Equal(t, reloaded, d.playbook)
+
+	invalid := domain.Playbook{
+		CertificateTasks: domain.CertificateTasks{{Name: "third", Schedule: "not a schedule"}},
+	}
+	assert.Error(t, d.Reload(invalid))
+	assert.Equal(t, reloaded, d.playbook, "the playbook must not change when the reload fails")
+
+	d.Stop()
+}
+
+func TestDaemonDuplicateTaskName(t *testing.T) {
+	duplicated := domain.Playbook{
+		CertificateTasks: domain.CertificateTasks{
+			{Name: "web", Schedule: "@daily"},
+			{Name: "web", Schedule: "@hourly"},
+		},
+	}
+	_, err := NewDaemon(duplicated, time.Minute)
+	assert.ErrorIs(t, err, domain.ErrDuplicateTaskName)
+
+	playbook := domain.Playbook{
+		CertificateTasks: domain.CertificateTasks{{Name: "web", Schedule: "@daily"}},
+	}
+	d, err := NewDaemon(playbook, time.Minute)
+	require.NoError(t, err)
+	assert.ErrorIs(t, d.Reload(duplicated), domain.ErrDuplicateTaskName)
+	assert.Equal(t, playbook, d.playbook, "the playbook must not change when the reload fails")
+
+	d.Stop()
+}
+
+func TestDaemonStartTakesTaskLock(t *testing.T) {
+	playbook := domain.Playbook{
+		CertificateTasks: domain.CertificateTasks{{Name: "web", Schedule: "@daily"}},
+	}
+	d, err := NewDaemon(playbook, time.Minute)
+	require.NoError(t, err)
+	defer d.Stop()
+
+	// The first run of the task must wait for the run in progress, as a reload or a scheduled run would
+	lock := d.getTaskLock("web")
+	lock.Lock()
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	started := make(chan struct{})
+	go func() {
+		d.Start(ctx)
+		close(started)
+	}()
+
+	select {
+	case <-started:
+		t.Fatal("the first run did not wait for the run in progress")
+	case <-time.After(100 * time.Millisecond):
+	}
+	lock.Unlock()
+
+	select {
+	case <-started:
+	case <-time.After(5 * time.Second):
+		t.Fatal("the daemon did not start once the run in progress finished")
+	}
+}
+
+func TestHTTPChallengeAddress(t *testing.T) {
+	address, ok := httpChallengeAddress(domain.Connection{Platform: venafi.ACME})
+	assert.True(t, ok)
+	assert.Equal(t, acme.DefaultHTTPAddress, address)
+
+	address, ok = httpChallengeAddress(domain.Connection{Platform: venafi.ACME, ACMEChallenge: &acme.ChallengeConfig{HTTPAddress: ":8080"}})
+	assert.True(t, ok)
+	assert.Equal(t, ":8080", address)
+
+	_, ok = httpChallengeAddress(domain.Connection{Platform: venafi.ACME, ACMEChallenge: &acme.ChallengeConfig{Webroot: "/var/www"}})
+	assert.False(t, ok, "webroot challenges are served by the existing web server")
+
+	_, ok = httpChallengeAddress(domain.Connection{Platform: venafi.ACME, ACMEChallenge: &acme.ChallengeConfig{Type: acme.ChallengeDNS01, DNSCommand: "hook.sh"}})
+	assert.False(t, ok)
+
+	_, ok = httpChallengeAddress(domain.Connection{Platform: venafi.TPP})
+	assert.False(t, ok)
+}
@@ -0,0 +1,82 @@
+/*
+ * Copyright 2023 Venafi, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *  http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package service
+
+import (
+	"crypto/sha1"
+	"crypto/x509"
+	"encoding/hex"
+	"time"
+
+	"go.uber.org/zap"
+
+	"github.com/Venafi/vcert/v5/pkg/playbook/app/domain"
+)
+
+// isDrifted returns true when cert, found at the location of an installation of the task, is not the certificate
+// vcert installed there, as recorded in the state file. Locations vcert has not installed a certificate to yet
+// are not checked
+func isDrifted(config domain.Config, task domain.CertificateTask, location string, cert x509.Certificate) bool {
+	if config.State == nil {
+		return false
+	}
+	ts, found := config.State.Task(task.Name)
+	if !found {
+		return false
+	}
+	installed, found := ts.Installed[location]
+	return found && installed != thumbprint(cert)
+}
+
+// reportDrift warns that the certificate found at the location of installation was replaced out of band
+func reportDrift(logger *zap.Logger, config domain.Config, task domain.CertificateTask, installation domain.Installation,
+	location string, cert x509.Certificate, now time.Time) {
+	ts, _ := config.State.Task(task.Name)
+	msg := "certificate installed was replaced out of band. It is kept, as enforce is not set"
+	if installation.Enforce {
+		msg = "certificate installed was replaced out of band. It is replaced, as enforce is set"
+	}
+	logger.Warn(msg, zap.String("location", location), zap.String("installedThumbprint", ts.Installed[location]),
+		zap.String("foundThumbprint", thumbprint(cert)), zap.String("foundSubject", cert.Subject.String()))
+
+	event := certificateEvent(domain.EventDrift, task, cert, now)
+	event.Location = location
+	notify(logger, config, event)
+}
+
+// recordInstalled saves the thumbprint of the certificate installed at the location of every installation of the task,
+// so the certificates replaced out of band are detected by the next runs
+func recordInstalled(logger *zap.Logger, config domain.Config, task domain.CertificateTask, installedThumbprint string) {
+	if config.State == nil {
+		return
+	}
+	ts, _ := config.State.Task(task.Name)
+	ts.Installed = make(map[string]string, len(task.Installations))
+	for _, installation := range task.Installations {
+		ts.Installed[getInstallationLocationString(installation)] = installedThumbprint
+	}
+	err := config.State.SetTask(task.Name, ts)
+	if err != nil {
+		logger.Warn("failed to record installed certificate in state file", zap.Error(err))
+	}
+}
+
+// thumbprint returns the SHA-1 thumbprint of cert, as recorded in the state file
+func thumbprint(cert x509.Certificate) string {
+	sum := sha1.Sum(cert.Raw) // #nosec G401 -- the SHA-1 thumbprint identifies certificates in the Venafi platform
+	return hex.EncodeToString(sum[:])
+}
@@ -0,0 +1,137 @@
+/*
+ * Copyright 2023 Venafi, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *  http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"go.uber.org/zap"
+
+	"github.com/Venafi/vcert/v5/pkg/certificate"
+	"github.com/Venafi/vcert/v5/pkg/playbook/app/domain"
+	"github.com/Venafi/vcert/v5/pkg/playbook/app/vcertutil"
+)
+
+// enroll requests the certificate of the task in the first of its zones that issues it, retrying on transient
+// errors before failing over to the next zone. A request left pending by an interrupted run is retrieved first,
+// from the zone it was made in.
+//
+// Returns the zone the certificate was enrolled in
+func enroll(ctx context.Context, logger *zap.Logger, config domain.Config, task domain.CertificateTask, csrOrigin certificate.CSrOriginOption) (*certificate.PEMCollection, *certificate.Request, string, error) {
+	resumedID, resumedZone := pendingPickupID(logger, config, task, csrOrigin)
+	adoptedID, adoptedZone := adoptedPickupID(config, task)
+	zones := enrollmentZones(task.Request.GetZones(), resumedZone)
+	if len(zones) == 0 {
+		zones = []string{task.Request.Zone}
+	}
+
+	var rErr error
+	for i, zone := range zones {
+		enrollment := vcertutil.Enrollment{}
+		if zone == resumedZone {
+			enrollment.PickupID = resumedID
+		}
+		if zone == adoptedZone && enrollment.PickupID == "" {
+			enrollment.RenewID = adoptedID
+		}
+		pcc, certRequest, err := enrollInZone(ctx, logger.With(zap.String("zone", zone)), config, task, zone, enrollment)
+		if err == nil {
+			return pcc, certRequest, zone, nil
+		}
+		if len(zones) == 1 {
+			return nil, nil, "", err
+		}
+
+		rErr = errors.Join(rErr, fmt.Errorf("zone %s: %w", zone, err))
+		if i < len(zones)-1 {
+			logger.Warn("failed to enroll certificate. Failing over to next zone", zap.String("zone", zone),
+				zap.String("nextZone", zones[i+1]), zap.Error(err))
+		}
+	}
+	return nil, nil, "", rErr
+}
+
+// enrollInZone requests the certificate of the task in zone, or retrieves the certificate requested with
+// enrollment.PickupID when it is set, retrying on transient errors. The certificate adopted with enrollment.RenewID,
+// when it is set, is renewed instead of requesting a new one
+func enrollInZone(ctx context.Context, logger *zap.Logger, config domain.Config, task domain.CertificateTask, zone string, enrollment vcertutil.Enrollment) (*certificate.PEMCollection, *certificate.Request, error) {
+	request := task.Request
+	request.Zone = zone
+
+	pickupID := enrollment.PickupID
+	enrollment.OnRequested = func(pickupID string) {
+		// Retries after this point retrieve the request already made
+		enrollment.PickupID = pickupID
+		recordRequested(logger, config, task, pickupID, zone, enrollment.RenewID != "")
+	}
+	var pcc *certificate.PEMCollection
+	var certRequest *certificate.Request
+	err := withRetries(ctx, logger, task, func() error {
+		var enrollErr error
+		pcc, certRequest, enrollErr = vcertutil.EnrollCertificateResumable(ctx, config, request, enrollment)
+		return enrollErr
+	})
+	if err == nil || isTransientError(err) {
+		return pcc, certRequest, err
+	}
+
+	switch {
+	case pickupID != "" && enrollment.PickupID == pickupID:
+		logger.Warn("failed to retrieve pending certificate request. Requesting a new certificate",
+			zap.String("pickupID", enrollment.PickupID), zap.Error(err))
+		enrollment.PickupID = ""
+	case enrollment.RenewID != "" && enrollment.PickupID == "":
+		logger.Warn("failed to renew adopted certificate. Requesting a new certificate",
+			zap.String("pickupID", enrollment.RenewID), zap.Error(err))
+		enrollment.RenewID = ""
+	default:
+		return pcc, certRequest, err
+	}
+	err = withRetries(ctx, logger, task, func() error {
+		var enrollErr error
+		pcc, certRequest, enrollErr = vcertutil.EnrollCertificateResumable(ctx, config, request, enrollment)
+		return enrollErr
+	})
+	return pcc, certRequest, err
+}
+
+// enrollmentZones returns zones with preferred moved first, so a request pending in a failover zone is
+// retrieved before new requests are made in the zones preceding it
+func enrollmentZones(zones []string, preferred string) []string {
+	if preferred == "" || !containsZone(zones, preferred) {
+		return zones
+	}
+	ordered := make([]string, 0, len(zones))
+	ordered = append(ordered, preferred)
+	for _, zone := range zones {
+		if zone != preferred {
+			ordered = append(ordered, zone)
+		}
+	}
+	return ordered
+}
+
+func containsZone(zones []string, zone string) bool {
+	for _, z := range zones {
+		if z == zone {
+			return true
+		}
+	}
+	return false
+}
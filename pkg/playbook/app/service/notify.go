@@ -0,0 +1,58 @@
+/*
+ * Copyright 2023 Venafi, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *  http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package service
+
+import (
+	"crypto/sha1"
+	"crypto/x509"
+	"encoding/hex"
+	"time"
+
+	"go.uber.org/zap"
+
+	"github.com/Venafi/vcert/v5/pkg/playbook/app/domain"
+	"github.com/Venafi/vcert/v5/pkg/playbook/app/notification"
+)
+
+// notify sends the notifications of config triggered by event. Nothing is sent in dry-run mode
+func notify(logger *zap.Logger, config domain.Config, event notification.Event) {
+	if config.DryRun || len(config.Notifications) == 0 {
+		return
+	}
+	notification.Send(logger, config.Notifications, event)
+}
+
+// certificateEvent returns the event of the task about cert, at the time now
+func certificateEvent(event string, task domain.CertificateTask, cert x509.Certificate, now time.Time) notification.Event {
+	thumbprint := sha1.Sum(cert.Raw) // #nosec G401 -- the SHA-1 thumbprint identifies certificates in the Venafi platform
+	return notification.Event{
+		Event:         event,
+		Task:          task.Name,
+		CommonName:    cert.Subject.CommonName,
+		Serial:        cert.SerialNumber.String(),
+		Thumbprint:    hex.EncodeToString(thumbprint[:]),
+		NotAfter:      cert.NotAfter.UTC().Format(time.RFC3339),
+		DaysRemaining: int(cert.NotAfter.Sub(now).Hours() / 24),
+		Time:          now.UTC().Format(time.RFC3339),
+	}
+}
+
+// isExpiring returns true when cert is within the renewal window of the task at the time now
+func isExpiring(task domain.CertificateTask, cert x509.Certificate, now time.Time) bool {
+	renewAt := renewalDate(task, cert)
+	return !renewAt.IsZero() && !now.Before(renewAt)
+}
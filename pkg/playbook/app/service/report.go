@@ -0,0 +1,75 @@
+/*
+ * Copyright 2023 Venafi, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *  http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package service
+
+import (
+	"crypto/sha1"
+	"crypto/x509"
+	"encoding/hex"
+	"fmt"
+	"time"
+
+	"github.com/Venafi/vcert/v5/pkg/playbook/app/domain"
+	"github.com/Venafi/vcert/v5/pkg/playbook/app/report"
+)
+
+// recordResult adds the result of the task to the report of the run, when there is one
+func recordResult(config domain.Config, result report.TaskResult, errorList []error) {
+	if config.Report == nil {
+		return
+	}
+	result.FinishedAt = time.Now()
+	switch {
+	case len(errorList) > 0:
+		result.Action = report.ActionFailed
+		for _, err := range errorList {
+			result.Errors = append(result.Errors, err.Error())
+		}
+	case result.Action == "":
+		// Tasks that did not act, including every task of a dry run, are skipped
+		result.Action = report.ActionSkipped
+	}
+	config.Report.Add(result)
+}
+
+// setResultCertificate records the details of cert in result
+func setResultCertificate(result *report.TaskResult, task domain.CertificateTask, cert x509.Certificate) {
+	thumbprint := sha1.Sum(cert.Raw)
+	notAfter := cert.NotAfter
+	result.Serial = cert.SerialNumber.String()
+	result.Thumbprint = hex.EncodeToString(thumbprint[:])
+	result.NotAfter = &notAfter
+	result.RenewAt = nil
+	if renewAt := renewalDate(task, cert); !renewAt.IsZero() {
+		result.RenewAt = &renewAt
+	}
+}
+
+// changeReason returns why the certificate installed at location, if any, needs to be replaced
+func changeReason(task domain.CertificateTask, location string, cert *x509.Certificate, now time.Time) string {
+	switch {
+	case cert == nil:
+		return fmt.Sprintf("certificate not found at %s", location)
+	case !now.Before(cert.NotAfter):
+		return fmt.Sprintf("certificate at %s expired on %s", location, cert.NotAfter.UTC().Format(time.RFC3339))
+	case isExpiring(task, *cert, now):
+		return fmt.Sprintf("certificate at %s in its renewal window since %s", location,
+			renewalDate(task, *cert).UTC().Format(time.RFC3339))
+	default:
+		return fmt.Sprintf("certificate at %s does not match the task, its private key, or is revoked", location)
+	}
+}
@@ -0,0 +1,125 @@
+/*
+ * Copyright 2023 Venafi, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *  http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package service
+
+import (
+	"context"
+	"errors"
+	"math/rand"
+	"net"
+	"regexp"
+	"strings"
+	"time"
+
+	"go.uber.org/zap"
+
+	"github.com/Venafi/vcert/v5/pkg/endpoint"
+	"github.com/Venafi/vcert/v5/pkg/playbook/app/domain"
+	"github.com/Venafi/vcert/v5/pkg/util"
+	"github.com/Venafi/vcert/v5/pkg/verror"
+)
+
+const (
+	// DefaultBackoff is the delay before the first retry of a task that sets retries but no backoff
+	DefaultBackoff = 10 * time.Second
+	// maxBackoff caps the delay between two attempts, regardless of the number of retries
+	maxBackoff = 5 * time.Minute
+)
+
+// sleep is replaced by tests to avoid waiting for the backoff
+var sleep = util.Sleep
+
+// serverErrorStatus matches the HTTP 5xx status line most connectors include in their errors, i.e. "503 Service Unavailable"
+var serverErrorStatus = regexp.MustCompile(`\b5\d\d [A-Z]`)
+
+// transientErrorMessages are found in network errors that connectors do not wrap
+var transientErrorMessages = []string{
+	"i/o timeout",
+	"Client.Timeout exceeded",
+	"TLS handshake timeout",
+	"connection refused",
+	"connection reset by peer",
+}
+
+// withRetries calls fn until it succeeds, fails with an error that is not transient, or task.Retries retries
+// have been made. The delay between attempts doubles every time, starting from task.Backoff, plus a random jitter
+func withRetries(ctx context.Context, logger *zap.Logger, task domain.CertificateTask, fn func() error) error {
+	backoff := DefaultBackoff
+	if task.Backoff != "" {
+		// The backoff is checked when the playbook is validated
+		if d, err := time.ParseDuration(task.Backoff); err == nil && d > 0 {
+			backoff = d
+		}
+	}
+
+	for attempt := 0; ; attempt++ {
+		err := fn()
+		if err == nil || attempt >= task.Retries || !isTransientError(err) {
+			return err
+		}
+		delay := backoffDelay(backoff, attempt)
+		logger.Warn("transient error, retrying", zap.Int("retry", attempt+1), zap.Int("retries", task.Retries),
+			zap.Duration("delay", delay), zap.Error(err))
+		if sleepErr := sleep(ctx, delay); sleepErr != nil {
+			return err
+		}
+	}
+}
+
+// backoffDelay returns base * 2^attempt, capped to maxBackoff, plus a random jitter of up to half that delay
+func backoffDelay(base time.Duration, attempt int) time.Duration {
+	delay := base
+	for i := 0; i < attempt && delay < maxBackoff; i++ {
+		delay *= 2
+	}
+	if delay > maxBackoff {
+		delay = maxBackoff
+	}
+	if half := int64(delay / 2); half > 0 {
+		delay += time.Duration(rand.Int63n(half)) // #nosec G404 -- jitter does not need a secure source
+	}
+	return delay
+}
+
+// isTransientError returns true for errors worth retrying: the server was unreachable or answered
+// with a 5xx status, the connection timed out, or the certificate was not issued in time
+func isTransientError(err error) bool {
+	if errors.Is(err, verror.ServerUnavailableError) || errors.Is(err, context.DeadlineExceeded) {
+		return true
+	}
+
+	var timeoutErr endpoint.ErrRetrieveCertificateTimeout
+	if errors.As(err, &timeoutErr) {
+		return true
+	}
+
+	var netErr net.Error
+	if errors.As(err, &netErr) && netErr.Timeout() {
+		return true
+	}
+
+	msg := err.Error()
+	if serverErrorStatus.MatchString(msg) {
+		return true
+	}
+	for _, m := range transientErrorMessages {
+		if strings.Contains(msg, m) {
+			return true
+		}
+	}
+	return false
+}
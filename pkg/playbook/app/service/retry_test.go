@@ -0,0 +1,135 @@
+/*
+ * Copyright 2023 Venafi, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *  http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/zap"
+
+	"github.com/Venafi/vcert/v5/pkg/endpoint"
+	"github.com/Venafi/vcert/v5/pkg/playbook/app/domain"
+	"github.com/Venafi/vcert/v5/pkg/util"
+	"github.com/Venafi/vcert/v5/pkg/verror"
+)
+
+func TestIsTransientError(t *testing.T) {
+	testCases := []struct {
+		err       error
+		transient bool
+	}{
+		{fmt.Errorf("%w: connection lost", verror.ServerUnavailableError), true},
+		{fmt.Errorf("%w: try later", verror.ServerTemporaryUnavailableError), true},
+		{fmt.Errorf("request failed: %w", endpoint.ErrRetrieveCertificateTimeout{CertificateID: "\\VED\\Policy\\cert"}), true},
+		{fmt.Errorf("unexpected status code on TPP Certificate Request. Status: 503 Service Unavailable"), true},
+		{fmt.Errorf("Post \"https://tpp/vedsdk\": dial tcp 10.0.0.1:443: i/o timeout"), true},
+		{fmt.Errorf("Post \"https://tpp/vedsdk\": dial tcp 10.0.0.1:443: connect: connection refused"), true},
+		{fmt.Errorf("%w: zone not found", verror.UserDataError), false},
+		{fmt.Errorf("%w: 401 Unauthorized", verror.AuthError), false},
+		{fmt.Errorf("unexpected status code on TPP Certificate Request. Status: 400 Bad Request"), false},
+	}
+
+	for _, tc := range testCases {
+		assert.Equal(t, tc.transient, isTransientError(tc.err), tc.err.Error())
+	}
+}
+
+func TestBackoffDelay(t *testing.T) {
+	base := 10 * time.Second
+	for attempt, expected := range []time.Duration{10 * time.Second, 20 * time.Second, 40 * time.Second, 80 * time.Second} {
+		delay := backoffDelay(base, attempt)
+		assert.GreaterOrEqual(t, delay, expected)
+		assert.Less(t, delay, expected+expected/2)
+	}
+
+	delay := backoffDelay(base, 100)
+	assert.GreaterOrEqual(t, delay, maxBackoff)
+	assert.Less(t, delay, maxBackoff+maxBackoff/2)
+}
+
+func TestWithRetries(t *testing.T) {
+	var delays []time.Duration
+	sleep = func(_ context.Context, d time.Duration) error {
+		delays = append(delays, d)
+		return nil
+	}
+	defer func() { sleep = util.Sleep }()
+
+	task := domain.CertificateTask{Retries: 3, Backoff: "1s"}
+
+	// Succeeds on the third attempt
+	calls := 0
+	err := withRetries(context.Background(), zap.NewNop(), task, func() error {
+		calls++
+		if calls < 3 {
+			return verror.ServerUnavailableError
+		}
+		return nil
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, 3, calls)
+	assert.Len(t, delays, 2)
+	assert.GreaterOrEqual(t, delays[0], time.Second)
+	assert.GreaterOrEqual(t, delays[1], 2*time.Second)
+
+	// Gives up after all retries
+	calls, delays = 0, nil
+	err = withRetries(context.Background(), zap.NewNop(), task, func() error {
+		calls++
+		return verror.ServerUnavailableError
+	})
+	assert.True(t, errors.Is(err, verror.ServerUnavailableError))
+	assert.Equal(t, 4, calls)
+	assert.Len(t, delays, 3)
+
+	// Does not retry errors that are not transient
+	calls, delays = 0, nil
+	err = withRetries(context.Background(), zap.NewNop(), task, func() error {
+		calls++
+		return verror.UserDataError
+	})
+	assert.True(t, errors.Is(err, verror.UserDataError))
+	assert.Equal(t, 1, calls)
+	assert.Empty(t, delays)
+
+	// No retries by default
+	calls = 0
+	_ = withRetries(context.Background(), zap.NewNop(), domain.CertificateTask{}, func() error {
+		calls++
+		return verror.ServerUnavailableError
+	})
+	assert.Equal(t, 1, calls)
+}
+
+func TestWithRetriesCancelled(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	// The backoff is not waited for once the context is done
+	calls := 0
+	err := withRetries(ctx, zap.NewNop(), domain.CertificateTask{Retries: 3, Backoff: "1h"}, func() error {
+		calls++
+		return verror.ServerUnavailableError
+	})
+	assert.True(t, errors.Is(err, verror.ServerUnavailableError))
+	assert.Equal(t, 1, calls)
+}
@@ -0,0 +1,118 @@
+/*
+ * Copyright 2023 Venafi, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *  http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package service
+
+import (
+	"context"
+	"fmt"
+	"math/big"
+	"time"
+
+	"go.uber.org/zap"
+
+	"github.com/Venafi/vcert/v5/pkg/playbook/app/audit"
+	"github.com/Venafi/vcert/v5/pkg/playbook/app/domain"
+	"github.com/Venafi/vcert/v5/pkg/playbook/app/report"
+	"github.com/Venafi/vcert/v5/pkg/playbook/app/state"
+	"github.com/Venafi/vcert/v5/pkg/playbook/app/vcertutil"
+)
+
+// executeRevocation revokes the certificate identified by the task. A certificate already revoked by a previous
+// run of the task, according to the state file, is not revoked again
+func executeRevocation(ctx context.Context, logger *zap.Logger, config domain.Config, rec auditRecorder, task domain.CertificateTask, result *report.TaskResult) []error {
+	target := revokeTarget(task.Revoke)
+	result.Serial = revokedSerial(task.Revoke)
+	result.Thumbprint = task.Revoke.Thumbprint
+	result.Zone = task.Request.Zone
+	if isRevoked(config, task) {
+		logger.Info("certificate already revoked. No actions needed", target)
+		result.Reason = "certificate already revoked"
+		return nil
+	}
+
+	if config.DryRun {
+		result.Reason = "dry run: certificate would be revoked"
+		logger.Info("[dry-run] certificate would be revoked", target,
+			zap.String("reason", task.Revoke.GetReason()))
+		return nil
+	}
+
+	err := vcertutil.RevokeCertificate(ctx, config, task.Request.Zone, task.Revoke)
+	rec.record(audit.Event{Operation: audit.OperationRevoke, Zone: task.Request.Zone, PickupID: task.Revoke.PickupID,
+		Serial: result.Serial, Thumbprint: task.Revoke.Thumbprint,
+		Parameters: map[string]string{"reason": task.Revoke.GetReason()}}, err)
+	if err != nil {
+		return []error{fmt.Errorf("error revoking certificate %s: %w", task.Name, err)}
+	}
+	logger.Info("successfully revoked certificate", target,
+		zap.String("reason", task.Revoke.GetReason()))
+	recordRevoked(logger, config, task)
+	result.Action = report.ActionRevoked
+	result.Reason = "certificate revoked"
+	return nil
+}
+
+// revokeTarget returns the field identifying the certificate revoked by request, for logging
+func revokeTarget(request domain.RevokeRequest) zap.Field {
+	switch {
+	case request.Thumbprint != "":
+		return zap.String("thumbprint", request.Thumbprint)
+	case request.Serial != "":
+		return zap.String("serial", request.GetSerial())
+	default:
+		return zap.String("pickupID", request.PickupID)
+	}
+}
+
+// isRevoked returns true when the state file records the certificate identified by the task as revoked
+func isRevoked(config domain.Config, task domain.CertificateTask) bool {
+	if config.State == nil {
+		return false
+	}
+	ts, found := config.State.Task(task.Name)
+	return found && ts.Status == state.StatusRevoked && ts.Thumbprint == task.Revoke.Thumbprint &&
+		ts.Serial == revokedSerial(task.Revoke) && ts.PickupID == task.Revoke.PickupID
+}
+
+// recordRevoked saves the certificate revoked by the task, so it is not revoked again by the next run
+func recordRevoked(logger *zap.Logger, config domain.Config, task domain.CertificateTask) {
+	if config.State == nil {
+		return
+	}
+	now := time.Now()
+	err := config.State.SetTask(task.Name, state.TaskState{
+		Status:     state.StatusRevoked,
+		PickupID:   task.Revoke.PickupID,
+		Zone:       task.Request.Zone,
+		Serial:     revokedSerial(task.Revoke),
+		Thumbprint: task.Revoke.Thumbprint,
+		RevokedAt:  &now,
+	})
+	if err != nil {
+		logger.Warn("failed to record revoked certificate in state file", zap.Error(err))
+	}
+}
+
+// revokedSerial returns the serial number of the certificate revoked by request in decimal, as serial numbers are
+// recorded in the state file, or an empty string when the certificate is not identified by its serial number
+func revokedSerial(request domain.RevokeRequest) string {
+	serial, ok := new(big.Int).SetString(request.GetSerial(), 16)
+	if !ok {
+		return ""
+	}
+	return serial.String()
+}
@@ -0,0 +1,110 @@
+/*
+ * Copyright 2023 Venafi, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *  http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+
+	"github.com/Venafi/vcert/v5/pkg/playbook/app/audit"
+	"github.com/Venafi/vcert/v5/pkg/playbook/app/domain"
+	"github.com/Venafi/vcert/v5/pkg/playbook/app/state"
+)
+
+func TestExecuteRevocation(t *testing.T) {
+	task := domain.CertificateTask{
+		Name:   "myRevocation",
+		Action: domain.ActionRevoke,
+		Revoke: domain.RevokeRequest{Thumbprint: "A1B2C3", Reason: "key-compromise"},
+	}
+
+	st, err := state.Load(filepath.Join(t.TempDir(), "state.json"))
+	require.NoError(t, err)
+	config := domain.Config{State: st}
+
+	errs := Execute(context.Background(), domain.Config{State: st, DryRun: true}, task)
+	assert.Empty(t, errs)
+	_, found := st.Task(task.Name)
+	assert.False(t, found, "nothing is recorded on dry runs")
+
+	// The fake connector used in tests does not support revocation
+	errs = Execute(context.Background(), config, task)
+	require.Len(t, errs, 1)
+	assert.Contains(t, errs[0].Error(), "error revoking certificate myRevocation")
+	_, found = st.Task(task.Name)
+	assert.False(t, found, "failed revocations are not recorded")
+
+	task.Revoke = domain.RevokeRequest{Serial: "0a:1B", Reason: "1"}
+	recordRevoked(zap.NewNop(), config, task)
+	ts, found := st.Task(task.Name)
+	require.True(t, found)
+	assert.Equal(t, state.StatusRevoked, ts.Status)
+	assert.Equal(t, "2587", ts.Serial, "serial numbers are recorded in decimal")
+	assert.NotNil(t, ts.RevokedAt)
+
+	errs = Execute(context.Background(), config, task)
+	assert.Empty(t, errs, "certificate already revoked")
+	assert.True(t, isRevoked(config, task))
+
+	task.Revoke = domain.RevokeRequest{Serial: "0a:1C"}
+	assert.False(t, isRevoked(config, task), "a different certificate is not revoked yet")
+}
+
+func TestExecuteRevocationAudit(t *testing.T) {
+	task := domain.CertificateTask{
+		Name:    "myRevocation",
+		Action:  domain.ActionRevoke,
+		Request: domain.PlaybookRequest{Zone: "Open Source\\vcert"},
+		Revoke:  domain.RevokeRequest{Thumbprint: "A1B2C3", Reason: "key-compromise"},
+	}
+
+	file := filepath.Join(t.TempDir(), "audit.log")
+	auditLog, err := audit.Open(audit.Options{File: file})
+	require.NoError(t, err)
+	config := domain.Config{AuditLog: auditLog}
+
+	errs := Execute(context.Background(), domain.Config{AuditLog: auditLog, DryRun: true}, task)
+	assert.Empty(t, errs)
+
+	// The fake connector used in tests does not support revocation
+	errs = Execute(context.Background(), config, task)
+	require.Len(t, errs, 1)
+	require.NoError(t, auditLog.Close())
+
+	data, err := os.ReadFile(file)
+	require.NoError(t, err)
+	lines := strings.Split(strings.TrimSpace(string(data)), "\n")
+	require.Len(t, lines, 1, "dry runs are not audited")
+
+	var event audit.Event
+	require.NoError(t, json.Unmarshal([]byte(lines[0]), &event))
+	assert.Equal(t, audit.OperationRevoke, event.Operation)
+	assert.Equal(t, audit.ResultFailure, event.Result)
+	assert.Equal(t, task.Name, event.Task)
+	assert.Equal(t, task.Request.Zone, event.Zone)
+	assert.Equal(t, "A1B2C3", event.Thumbprint)
+	assert.Equal(t, "key-compromise", event.Parameters["reason"])
+	assert.NotEmpty(t, event.Error)
+}
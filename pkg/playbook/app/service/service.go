@@ -17,61 +17,158 @@
 package service
 
 import (
+	"context"
+	"crypto/x509"
+	"errors"
 	"fmt"
 	"os"
 	"strings"
+	"sync"
+	"time"
 
 	"go.uber.org/zap"
 
 	"github.com/Venafi/vcert/v5/pkg/certificate"
+	"github.com/Venafi/vcert/v5/pkg/playbook/app/audit"
+	"github.com/Venafi/vcert/v5/pkg/playbook/app/condition"
 	"github.com/Venafi/vcert/v5/pkg/playbook/app/domain"
 	"github.com/Venafi/vcert/v5/pkg/playbook/app/installer"
+	"github.com/Venafi/vcert/v5/pkg/playbook/app/metrics"
+	"github.com/Venafi/vcert/v5/pkg/playbook/app/notification"
+	"github.com/Venafi/vcert/v5/pkg/playbook/app/report"
 	"github.com/Venafi/vcert/v5/pkg/playbook/app/vcertutil"
 	"github.com/Venafi/vcert/v5/pkg/venafi"
 )
 
 // DefaultRenew represents the duration before certificate expiration in which renewal should be attempted
 const (
-	DefaultRenew = "10%"
+	DefaultRenew = domain.DefaultRenewBefore
 
 	envVarThumbprint = "thumbprint"
 	envVarSerial     = "serial"
 	envVarBase64     = "base64"
 )
 
+var errBackup = errors.New("error backing up certificate")
+
 // Execute takes the task and requests the certificate specified,
-// then it installs it in the locations defined by the installers.
+// then it installs it in the locations defined by the installers. Tasks whose action is revoke revoke the
+// certificate specified instead, and tasks whose action is sshCertificate request an SSH certificate.
 //
 // Config is used to make the connection to the Venafi platform for the certificate request.
-func Execute(config domain.Config, task domain.CertificateTask) []error {
+//
+// The task stops when ctx is done, or when its timeout expires. Cancelling ctx aborts the requests in progress,
+// including the wait for the certificate to be issued.
+func Execute(ctx context.Context, config domain.Config, task domain.CertificateTask) (errorList []error) {
+	// Every message carries the task name, so logs stay readable when tasks run concurrently
+	logger := zap.L().With(zap.String("task", task.Name))
+
+	// The task connects to the platform of the connection it references
+	config = config.ForTask(task)
+
+	if timeout := task.GetTimeout(); timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, timeout)
+		defer cancel()
+	}
+
+	// The result of the task is recorded once it is done, whatever the branch it returns from
+	result := report.TaskResult{Task: task.Name, DryRun: config.DryRun, StartedAt: time.Now()}
+	defer func() {
+		recordResult(config, result, errorList)
+		if len(errorList) > 0 {
+			metrics.TaskFailed(task.Name)
+			notify(logger, config, notification.Event{
+				Event:      domain.EventFailure,
+				Task:       task.Name,
+				CommonName: task.Request.Subject.CommonName,
+				Zone:       task.Request.Zone,
+				Error:      errors.Join(errorList...).Error(),
+			})
+		}
+	}()
+
+	// Tasks meant for other hosts are skipped
+	run, err := isTaskRelevant(task)
+	if err != nil {
+		return []error{err}
+	}
+	if !run {
+		logger.Info("when expression is false on this host. Skipping task", zap.String("when", task.When))
+		result.Reason = fmt.Sprintf("when expression %q is false", task.When)
+		return nil
+	}
+
+	// Revoke tasks have nothing to install
+	rec := newAuditRecorder(config, task)
+	if task.IsRevocation() {
+		return executeRevocation(ctx, logger, config, rec, task, &result)
+	}
+	if task.IsSSHCertificate() {
+		return executeSSHCertificate(ctx, logger, config, rec, task, &result)
+	}
+
 	// Check if certificate needs action
-	changed, err := isCertificateChanged(config, task)
+	changed, installed, err := isCertificateChanged(ctx, logger, config, task, &result)
 	if err != nil {
-		zap.L().Error("error checking certificate in task", zap.String("task", task.Name), zap.Error(err))
+		logger.Error("error checking certificate in task", zap.Error(err))
 		return []error{err}
 	}
 
+	// A certificate installed before the task managed it is adopted on the first run, so it is renewed instead of duplicated
+	if task.Adopt && installed {
+		adoptCertificate(ctx, logger, config, rec, task, result)
+	}
+
 	// Config has not changed. Do nothing
 	if !changed {
-		zap.L().Info("certificate in good health. No actions needed",
+		logger.Info("certificate in good health. No actions needed",
 			zap.String("certificate", task.Request.Subject.CommonName))
 		return nil
 	}
-	zap.L().Info("certificate needs action", zap.String("certificate", task.Request.Subject.CommonName))
+	logger.Info("certificate needs action", zap.String("certificate", task.Request.Subject.CommonName))
+
+	if config.DryRun {
+		result.Reason = "dry run: " + result.Reason
+		reportDryRun(logger, task)
+		return nil
+	}
 
 	// Ensure there is a keyPassword in the request when origin is service
 	csrOrigin := certificate.ParseCSROrigin(task.Request.CsrOrigin)
 	if csrOrigin == certificate.ServiceGeneratedCSR {
-		zap.L().Info("csr option is 'service'. Generating random password for certificate request")
-		task.Request.KeyPassword = vcertutil.GeneratePassword()
+		if task.Request.KeystorePassword != "" {
+			logger.Info("csr option is 'service'. Using keystorePassword for certificate request")
+			task.Request.KeyPassword = task.Request.KeystorePassword
+		} else {
+			logger.Info("csr option is 'service'. Generating random password for certificate request")
+			task.Request.KeyPassword = vcertutil.GeneratePassword()
+		}
+	}
+
+	// The renewal signs the CSR with the private key installed, when there is one
+	if task.Request.ReuseKey && installed {
+		err = setReusedKey(ctx, logger, &task)
+		if err != nil {
+			return []error{fmt.Errorf("error reading private key of %s: %w", task.Name, err)}
+		}
 	}
 
-	// Config changed or certificate needs renewal. Do request
-	pcc, certRequest, err := vcertutil.EnrollCertificate(config, task.Request)
+	// Config changed or certificate needs renewal. Do request, retrying on transient errors and
+	// failing over to the next zone of the task when enrollment fails in a zone
+	pcc, certRequest, zone, err := enroll(ctx, logger, config, task, csrOrigin)
+	operation := audit.OperationEnroll
+	if installed {
+		operation = audit.OperationRenew
+	}
+	enrollEvent := audit.Event{Operation: operation, Zone: zone, CommonName: task.Request.Subject.CommonName,
+		Parameters: requestParameters(task.Request)}
 	if err != nil {
+		rec.record(enrollEvent, err)
 		return []error{fmt.Errorf("error requesting certificate %s: %w", task.Name, err)}
 	}
-	zap.L().Info("successfully enrolled certificate", zap.String("certificate", task.Request.Subject.CommonName))
+	logger.Info("successfully enrolled certificate", zap.String("certificate", task.Request.Subject.CommonName),
+		zap.String("zone", zone))
 
 	// Private Key should not be decrypted when csrOrigin is service and Platform is Firefly.
 	// Firefly does not support encryption of private keys
@@ -83,36 +180,199 @@ func Execute(config domain.Config, task domain.CertificateTask) []error {
 	// This function will add the private key to the PCC when csrOrigin is local.
 	// It will also decrypt the Private Key if it is encrypted
 	x509Certificate, prepedPcc, err := installer.CreateX509Cert(pcc, certRequest, decryptPK)
+	enrollEvent.PickupID = certRequest.PickupID
 	if err != nil {
+		// The certificate was issued, even though it cannot be installed
+		rec.record(enrollEvent, nil)
 		e := "error preparing certificate for installation"
-		zap.L().Error(e, zap.Error(err))
+		logger.Error(e, zap.Error(err))
 		return []error{fmt.Errorf("%s: %w", e, err)}
 	}
-	zap.L().Info("successfully prepared certificate for installation")
+	logger.Info("successfully prepared certificate for installation",
+		zap.Time("expirationDate", x509Certificate.X509cert.NotAfter),
+		zap.Time("renewalDate", renewalDate(task, x509Certificate.X509cert)))
+	recordIssued(logger, config, task, certRequest.PickupID, zone, x509Certificate)
+	setResultCertificate(&result, task, x509Certificate.X509cert)
+	enrollEvent.Serial = result.Serial
+	enrollEvent.Thumbprint = result.Thumbprint
+	rec.record(enrollEvent, nil)
+	result.Zone = zone
+
+	// The issuers missing from the chain returned by the platform are downloaded, so servers send the full chain
+	err = completeChain(logger, config, task, prepedPcc)
+	if err != nil {
+		logger.Error("incomplete certificate chain", zap.Error(err))
+		return []error{err}
+	}
 
 	// Set certificate to environment variables
 	if task.SetEnvVars != nil {
-		zap.L().Debug("setting environment variables")
-		setEnvVars(task, x509Certificate, prepedPcc)
+		logger.Debug("setting environment variables")
+		setEnvVars(logger, task, x509Certificate, prepedPcc)
 	}
 
-	// Install certificate on locations
-	errorList := make([]error, 0)
+	// Install certificate on locations.
+	// If any installation fails, the installations already run are rolled back to avoid a mixed state
+	processed := make([]domain.Installation, 0, len(task.Installations))
 	for _, installation := range task.Installations {
-		e := runInstaller(installation, prepedPcc)
+		e := runInstaller(ctx, logger, rec, installation, prepedPcc)
+		// An installation that failed to back up has not been modified, and must not be restored from an older backup
+		if e == nil || !errors.Is(e, errBackup) {
+			processed = append(processed, installation)
+		}
 		if e != nil {
-			errorList = append(errorList, e)
+			errorList = []error{e}
+			// The rollback runs even when ctx is done, so a cancelled task does not leave the installations in a mixed state
+			errorList = append(errorList, rollbackInstallations(context.Background(), logger, processed)...)
+			return errorList
 		}
 	}
-	return errorList
 
+	recordInstalled(logger, config, task, x509Certificate.Thumbprint)
+	metrics.CertificateEnrolled(task.Name, installed)
+	result.Action = report.ActionInstalled
+	if installed {
+		result.Action = report.ActionRenewed
+	}
+	for _, installation := range task.Installations {
+		metrics.CertificateInstalled(task.Name, installation.Type.String(), getInstallationLocationString(installation),
+			x509Certificate.X509cert.NotAfter)
+	}
+
+	event := certificateEvent(domain.EventSuccess, task, x509Certificate.X509cert, time.Now())
+	event.Zone = zone
+	notify(logger, config, event)
+	return nil
 }
 
-func isCertificateChanged(config domain.Config, task domain.CertificateTask) (bool, error) {
+// completeChain adds the issuers missing from the chain of pcc, following the Authority Information Access extension
+// of the certificates, puts the chain in the order of the request, and verifies it. An error is returned when the chain
+// does not reach a root, or does not validate, and config.RequireCompleteChain is set
+func completeChain(logger *zap.Logger, config domain.Config, task domain.CertificateTask, pcc *certificate.PEMCollection) error {
+	if task.Request.ChainOption == certificate.ChainOptionIgnore {
+		return nil
+	}
+
+	cacheDir := config.IntermediatesDir
+	if cacheDir == "" {
+		cacheDir = installer.DefaultIntermediatesDir()
+	}
+	rootFirst := task.Request.ChainOption == certificate.ChainOptionRootFirst
+	chain, complete := installer.CompleteChain(pcc.Certificate, pcc.Chain, cacheDir, rootFirst)
+
+	order := domain.ChainOrderRootLast
+	if rootFirst {
+		order = domain.ChainOrderRootFirst
+	}
+	ordered := installer.OrderChain(pcc.Certificate, chain, order, false)
+	if !sameChain(chain, ordered) {
+		logger.Warn("certificate chain returned out of order. Reordering it", zap.String("certificate",
+			task.Request.Subject.CommonName), zap.String("order", order))
+	}
+	pcc.Chain = ordered
+
+	var err error
+	if !complete {
+		err = fmt.Errorf("chain of certificate %s does not reach a root certificate", task.Request.Subject.CommonName)
+	} else if verifyErr := installer.VerifyChain(pcc.Certificate, pcc.Chain, config.ChainTrustBundle); verifyErr != nil {
+		err = fmt.Errorf("chain of certificate %s does not validate: %w", task.Request.Subject.CommonName, verifyErr)
+	}
+	if err == nil {
+		return nil
+	}
+
+	if config.RequireCompleteChain {
+		return fmt.Errorf("%w and requireCompleteChain is set", err)
+	}
+	logger.Warn("invalid certificate chain. Installing it as is", zap.Error(err))
+	return nil
+}
+
+// sameChain returns true when both chains have the same certificates in the same order
+func sameChain(a []string, b []string) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
+
+// isTaskRelevant returns true when the task has no when expression, or when it is true on this host
+func isTaskRelevant(task domain.CertificateTask) (bool, error) {
+	c, err := task.GetCondition()
+	if err != nil {
+		return false, fmt.Errorf("%w: %w", domain.ErrInvalidWhen, err)
+	}
+	if c == nil {
+		return true, nil
+	}
+	return c.Evaluate(condition.HostEnvironment())
+}
+
+// ExecuteTasks runs Execute for every task, using up to config.Concurrency tasks in parallel.
+// Tasks run in the order they are declared when concurrency is 1 or less. The tasks not started yet
+// fail with the error of ctx once it is done.
+//
+// Returns the errors of every failed task, by task name
+func ExecuteTasks(ctx context.Context, config domain.Config, tasks domain.CertificateTasks) map[string][]error {
+	workers := config.Concurrency
+	if workers < 1 {
+		workers = 1
+	}
+	if workers > len(tasks) {
+		workers = len(tasks)
+	}
+
+	results := make(map[string][]error)
+	mu := sync.Mutex{}
+	queue := make(chan domain.CertificateTask)
+	wg := sync.WaitGroup{}
+
+	for i := 0; i < workers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for task := range queue {
+				var errorList []error
+				if err := ctx.Err(); err != nil {
+					errorList = []error{fmt.Errorf("task %s not run: %w", task.Name, err)}
+					recordResult(config, report.TaskResult{Task: task.Name, DryRun: config.DryRun, StartedAt: time.Now()}, errorList)
+				} else {
+					zap.L().Info("running playbook task", zap.String("task", task.Name))
+					errorList = Execute(ctx, config, task)
+				}
+				if len(errorList) == 0 {
+					continue
+				}
+				mu.Lock()
+				results[task.Name] = errorList
+				mu.Unlock()
+			}
+		}()
+	}
+
+	for _, task := range tasks {
+		queue <- task
+	}
+	close(queue)
+	wg.Wait()
+
+	return results
+}
+
+// isCertificateChanged returns true when any installation of the task needs a new certificate,
+// and whether a certificate was found installed in any of them. The reason of the decision, and the certificate
+// found installed, are recorded in result
+func isCertificateChanged(ctx context.Context, logger *zap.Logger, config domain.Config, task domain.CertificateTask, result *report.TaskResult) (bool, bool, error) {
 	//If forceRenew is set, then no need to check the certificate status
 	if config.ForceRenew {
-		zap.L().Info("Flag [force-renew] is set. All certificates will be requested/renewed regardless of status")
-		return true, nil
+		logger.Info("Flag [force-renew] is set. All certificates will be requested/renewed regardless of status")
+		result.Reason = "force-renew is set"
+		return true, isCertificateInstalled(ctx, task, result), nil
 	}
 	renewBefore := DefaultRenew
 	if task.RenewBefore != "" {
@@ -120,81 +380,277 @@ func isCertificateChanged(config domain.Config, task domain.CertificateTask) (bo
 	}
 
 	changed := false
+	installed := false
+	var expiring *x509.Certificate
+	var current *x509.Certificate
+	reasons := make([]string, 0)
+	drifts := make([]string, 0)
+	now := time.Now()
 	// check if any installs have changed
 	for _, install := range task.Installations {
-		isChanged, err := installer.GetInstaller(install).Check(renewBefore, task.Request)
+		location := getInstallationLocationString(install)
+		isChanged, cert, err := installer.GetInstaller(install).Check(ctx, renewBefore, task.Request)
 		if err != nil {
-			return false, fmt.Errorf("error checking for certificate %s: %w", task.Name, err)
+			return false, false, fmt.Errorf("error checking for certificate %s: %w", task.Name, err)
+		}
+
+		// A certificate replaced out of band is reported, and only replaced when the installation enforces it
+		drift := ""
+		if cert != nil && isDrifted(config, task, location, *cert) {
+			drift = fmt.Sprintf("certificate at %s was replaced out of band", location)
+			drifts = append(drifts, drift)
+			reportDrift(logger, config, task, install, location, *cert, now)
 		}
-		if isChanged {
+		switch {
+		case isChanged:
 			changed = true
+			reasons = append(reasons, changeReason(task, location, cert, now))
+		case drift != "" && install.Enforce:
+			changed = true
+			reasons = append(reasons, drift)
+		}
+
+		if cert != nil {
+			installed = true
+			if current == nil {
+				current = cert
+			}
+			metrics.CertificateInstalled(task.Name, install.Type.String(), location, cert.NotAfter)
+			if expiring == nil && isExpiring(task, *cert, now) {
+				expiring = cert
+			}
 		}
 	}
 
-	return changed, nil
+	// Expiring certificates are renewed by this run. The notification is sent first, so it is not lost if the renewal fails
+	if expiring != nil {
+		notify(logger, config, certificateEvent(domain.EventExpiring, task, *expiring, now))
+	}
+
+	if current != nil {
+		setResultCertificate(result, task, *current)
+	}
+	switch {
+	case changed:
+		result.Reason = strings.Join(reasons, "; ")
+	case len(drifts) > 0:
+		result.Reason = strings.Join(drifts, "; ")
+	case result.RenewAt != nil:
+		result.Reason = fmt.Sprintf("certificate in good health, renews on %s", result.RenewAt.UTC().Format(time.RFC3339))
+	default:
+		result.Reason = "certificate in good health"
+	}
+
+	return changed, installed, nil
 }
 
-func runInstaller(installation domain.Installation, prepedPcc *certificate.PEMCollection) error {
+// isCertificateInstalled returns true when a certificate is found installed in any installation of the task, and
+// records it in result. Installations that cannot be checked are ignored, as the certificate is requested regardless
+func isCertificateInstalled(ctx context.Context, task domain.CertificateTask, result *report.TaskResult) bool {
+	renewBefore := DefaultRenew
+	if task.RenewBefore != "" {
+		renewBefore = task.RenewBefore
+	}
+	for _, install := range task.Installations {
+		_, cert, err := installer.GetInstaller(install).Check(ctx, renewBefore, task.Request)
+		if err == nil && cert != nil {
+			setResultCertificate(result, task, *cert)
+			return true
+		}
+	}
+	return false
+}
+
+// setReusedKey sets the private key installed in the request of the task, so the renewal reuses it. Keys stored in a
+// PKCS#11 token or in the TPM are found at enrollment, by the public key of the certificate installed. A new private
+// key is generated when none is found
+func setReusedKey(ctx context.Context, logger *zap.Logger, task *domain.CertificateTask) error {
+	if task.Request.PKCS11 != nil || task.Request.TPM != nil {
+		renewBefore := DefaultRenew
+		if task.RenewBefore != "" {
+			renewBefore = task.RenewBefore
+		}
+		for _, install := range task.Installations {
+			_, cert, err := installer.GetInstaller(install).Check(ctx, renewBefore, task.Request)
+			if err == nil && cert != nil {
+				task.Request.PublicKey = cert.PublicKey
+				return nil
+			}
+		}
+		logger.Warn("no certificate found installed. A new private key will be generated")
+		return nil
+	}
+
+	for _, install := range task.Installations {
+		reader, ok := installer.GetInstaller(install).(installer.KeyReader)
+		if !ok {
+			continue
+		}
+		key, err := reader.PrivateKey(ctx)
+		if err != nil {
+			return fmt.Errorf("%s: %w", getInstallationLocationString(install), err)
+		}
+		if key != nil {
+			logger.Info("reusing installed private key", zap.String("location", getInstallationLocationString(install)))
+			task.Request.PrivateKey = key
+			return nil
+		}
+	}
+	logger.Warn("no private key found installed. A new private key will be generated")
+	return nil
+}
+
+// reportDryRun logs the actions Execute would take for the task once the certificate is enrolled
+func reportDryRun(logger *zap.Logger, task domain.CertificateTask) {
+	logger.Info("[dry-run] certificate would be requested", zap.String("certificate", task.Request.Subject.CommonName),
+		zap.Strings("zones", task.Request.GetZones()))
+
+	for _, envVar := range task.SetEnvVars {
+		logger.Info("[dry-run] environment variable would be set", zap.String("envVar", envVar))
+	}
+
+	for _, installation := range task.Installations {
+		location := getInstallationLocationString(installation)
+		if installation.BackupFiles {
+			logger.Info("[dry-run] certificate would be backed up", zap.String("installer", installation.Type.String()),
+				zap.String("location", location))
+		}
+		logger.Info("[dry-run] certificate would be installed", zap.String("installer", installation.Type.String()),
+			zap.String("location", location))
+		if len(installation.Mirrors) > 0 {
+			logger.Info("[dry-run] certificate would also be installed on mirrors", zap.String("location", location),
+				zap.Strings("mirrors", installation.Mirrors))
+		}
+		if len(installation.AfterAction) > 0 {
+			logger.Info("[dry-run] after-install actions would run", zap.String("location", location),
+				zap.Stringer("afterAction", installation.AfterAction))
+		}
+		if installation.InstallValidation != "" {
+			logger.Info("[dry-run] installation validation actions would run", zap.String("location", location),
+				zap.String("installValidationAction", installation.InstallValidation))
+		}
+		if installation.VerifyTLS != "" {
+			logger.Info("[dry-run] served certificate would be verified", zap.String("location", location),
+				zap.String("verifyTLS", installation.VerifyTLS))
+		}
+	}
+}
+
+func runInstaller(ctx context.Context, logger *zap.Logger, rec auditRecorder, installation domain.Installation, prepedPcc *certificate.PEMCollection) error {
 	location := getInstallationLocationString(installation)
+	installEvent := audit.Event{Operation: audit.OperationInstall, Location: location,
+		Parameters: map[string]string{"format": installation.Type.String()}}
 
 	instlr := installer.GetInstaller(installation)
-	zap.L().Info("running Installer", zap.String("installer", installation.Type.String()),
+	logger.Info("running Installer", zap.String("installer", installation.Type.String()),
 		zap.String("location", location))
 
 	var err error
 
 	if installation.BackupFiles {
-		zap.L().Info("backing up certificate for Installer", zap.String("installer", installation.Type.String()),
+		logger.Info("backing up certificate for Installer", zap.String("installer", installation.Type.String()),
 			zap.String("location", location))
-		err = instlr.Backup()
+		err = instlr.Backup(ctx)
 		if err != nil {
-			e := "error backing up certificate"
-			zap.L().Error(e, zap.String("location", location), zap.Error(err))
-			return fmt.Errorf("%s at location %s: %w", e, location, err)
+			logger.Error(errBackup.Error(), zap.String("location", location), zap.Error(err))
+			return fmt.Errorf("%w at location %s: %w", errBackup, location, err)
 		}
 	}
 
-	err = instlr.Install(*prepedPcc)
+	err = instlr.Install(ctx, *prepedPcc)
+	rec.record(installEvent, err)
 	if err != nil {
 		e := "error installing certificate"
-		zap.L().Error(e, zap.String("location", location), zap.Error(err))
+		logger.Error(e, zap.String("location", location), zap.Error(err))
 		return fmt.Errorf("%s at location %s: %w", e, location, err)
 	}
-	zap.L().Info("successfully installed certificate", zap.String("location", location))
+	logger.Info("successfully installed certificate", zap.String("location", location))
+
+	err = runInstallerActions(ctx, logger, rec, instlr, installation, location)
+	if err != nil {
+		return err
+	}
+
+	if installation.VerifyTLS == "" {
+		return nil
+	}
+
+	err = verifyServedCertificate(ctx, logger, installation, prepedPcc.Certificate)
+	if err != nil {
+		e := "error verifying the certificate served by the endpoint"
+		logger.Error(e, zap.String("location", location), zap.String("verifyTLS", installation.VerifyTLS), zap.Error(err))
+		return fmt.Errorf("%s %s for location %s: %w", e, installation.VerifyTLS, location, err)
+	}
+	return nil
+}
+
+// installerActions runs the actions that follow an installation. It is implemented by Installer and SSHInstaller
+type installerActions interface {
+	AfterInstallActions(ctx context.Context) (string, error)
+	InstallValidationActions(ctx context.Context) (string, error)
+}
 
-	if installation.AfterAction == "" {
+// runInstallerActions runs the after-install actions of the installation and, when they are set, its validation actions
+func runInstallerActions(ctx context.Context, logger *zap.Logger, rec auditRecorder, instlr installerActions, installation domain.Installation, location string) error {
+	if len(installation.AfterAction) == 0 {
 		return nil
 	}
 
-	result, err := instlr.AfterInstallActions()
+	result, err := instlr.AfterInstallActions(ctx)
+	rec.record(audit.Event{Operation: audit.OperationAfterAction, Location: location,
+		Parameters: map[string]string{"actions": installation.AfterAction.String()}}, err)
 	if err != nil {
 		e := "error running after-install actions"
-		zap.L().Error(e, zap.String("location", location), zap.Error(err))
+		logger.Error(e, zap.String("location", location), zap.Error(err))
 		return fmt.Errorf("%s at location %s: %w", e, location, err)
 	} else if strings.TrimSpace(result) == "1" {
-		zap.L().Info("after-install actions failed")
+		logger.Info("after-install actions failed")
 	}
-	zap.L().Info("successfully executed after-install actions")
+	logger.Info("successfully executed after-install actions")
 
 	if installation.InstallValidation == "" {
 		return nil
 	}
 
-	validationResults, err := instlr.InstallValidationActions()
+	validationResults, err := instlr.InstallValidationActions(ctx)
 
 	if err != nil {
 		e := "error running installation validation actions"
-		zap.L().Error(e, zap.String("location", location), zap.Error(err))
+		logger.Error(e, zap.String("location", location), zap.Error(err))
 		return fmt.Errorf("%s at location %s: %w", e, location, err)
 	} else if strings.TrimSpace(validationResults) == "1" {
-		zap.L().Info("installation validation actions failed")
+		logger.Info("installation validation actions failed")
 	}
-	zap.L().Info("successfully executed installation validation actions")
+	logger.Info("successfully executed installation validation actions")
 
 	return nil
 }
 
-func setEnvVars(task domain.CertificateTask, cert *installer.Certificate, prepedPcc *certificate.PEMCollection) {
+// rollbackInstallations restores the backups taken for the given installations.
+// Installations without backupFiles enabled have no backup to restore, so they are left as they are
+func rollbackInstallations(ctx context.Context, logger *zap.Logger, installations []domain.Installation) []error {
+	errorList := make([]error, 0)
+	for _, installation := range installations {
+		location := getInstallationLocationString(installation)
+		if !installation.BackupFiles {
+			logger.Warn("backupFiles is not enabled, certificate cannot be rolled back",
+				zap.String("installer", installation.Type.String()), zap.String("location", location))
+			continue
+		}
+
+		logger.Info("rolling back certificate for Installer", zap.String("installer", installation.Type.String()),
+			zap.String("location", location))
+		err := installer.GetInstaller(installation).Rollback(ctx)
+		if err != nil {
+			e := "error rolling back certificate"
+			logger.Error(e, zap.String("location", location), zap.Error(err))
+			errorList = append(errorList, fmt.Errorf("%s at location %s: %w", e, location, err))
+		}
+	}
+	return errorList
+}
+
+func setEnvVars(logger *zap.Logger, task domain.CertificateTask, cert *installer.Certificate, prepedPcc *certificate.PEMCollection) {
 	//todo case sensitivity. upper the name
 	for _, envVar := range task.SetEnvVars {
 		varName := ""
@@ -210,23 +666,108 @@ func setEnvVars(task domain.CertificateTask, cert *installer.Certificate, preped
 			varName = fmt.Sprintf("VCERT_%s_BASE64", strings.ToUpper(task.Name))
 			varValue = prepedPcc.Certificate
 		default:
-			zap.L().Error("environment variable not supported", zap.String("envVar", envVar))
+			logger.Error("environment variable not supported", zap.String("envVar", envVar))
 			continue
 		}
 
 		if varValue == "" {
-			zap.L().Error("environment variable value not found", zap.String("envVar", varName))
+			logger.Error("environment variable value not found", zap.String("envVar", varName))
 			continue
 		}
 
 		err := os.Setenv(varName, varValue)
 		if err != nil {
-			zap.L().Error("failed to set environment variable", zap.String("envVar", varName), zap.Error(err))
+			logger.Error("failed to set environment variable", zap.String("envVar", varName), zap.Error(err))
 		}
 	}
 }
 
 func getInstallationLocationString(installation domain.Installation) string {
+	if installation.Type == domain.FormatAWSACM {
+		if installation.AWSCertificateARN != "" {
+			return installation.AWSCertificateARN
+		}
+		return fmt.Sprintf("acm:%s:%s", installation.AWSRegion, installation.AWSCertName)
+	}
+
+	if installation.Type == domain.FormatAzureKeyVault {
+		return fmt.Sprintf("%s/certificates/%s", strings.TrimSuffix(installation.AzureVaultURI, "/"), installation.AzureCertName)
+	}
+
+	if installation.Type == domain.FormatGCP {
+		if strings.EqualFold(installation.GCPTarget, domain.GCPTargetSecretManager) {
+			return fmt.Sprintf("projects/%s/secrets/%s", installation.GCPProject, installation.GCPCertName)
+		}
+		location := installation.GCPLocation
+		if location == "" {
+			location = domain.DefaultGCPLocation
+		}
+		return fmt.Sprintf("projects/%s/locations/%s/certificates/%s", installation.GCPProject, location, installation.GCPCertName)
+	}
+
+	if installation.Type == domain.FormatF5 {
+		partition := installation.F5Partition
+		if partition == "" {
+			partition = domain.DefaultF5Partition
+		}
+		return fmt.Sprintf("%s/%s/%s", strings.TrimSuffix(installation.F5Address, "/"), partition, installation.F5CertName)
+	}
+
+	if installation.Type == domain.FormatCitrixADC {
+		return fmt.Sprintf("%s/%s", strings.TrimSuffix(installation.CitrixAddress, "/"), installation.CitrixCertKey)
+	}
+
+	if installation.Type == domain.FormatVaultKV {
+		mount := installation.VaultMount
+		if mount == "" {
+			mount = domain.DefaultVaultMount
+		}
+		return fmt.Sprintf("%s/%s/%s", strings.TrimSuffix(installation.VaultAddress, "/"), strings.Trim(mount, "/"),
+			strings.Trim(installation.VaultPath, "/"))
+	}
+
+	if installation.Type == domain.FormatDockerSecret {
+		host := installation.DockerHost
+		if host == "" {
+			host = domain.DefaultDockerHost
+		}
+		return fmt.Sprintf("%s/%s", host, installation.DockerSecretName)
+	}
+
+	if installation.Type == domain.FormatNomadVariable {
+		address := installation.NomadAddress
+		if address == "" {
+			address = domain.DefaultNomadAddress
+		}
+		namespace := installation.NomadNamespace
+		if namespace == "" {
+			namespace = domain.DefaultNomadNamespace
+		}
+		return fmt.Sprintf("%s/%s/%s", strings.TrimSuffix(address, "/"), namespace, strings.Trim(installation.NomadPath, "/"))
+	}
+
+	if installation.Type.IsDatabase() {
+		certFile, _ := installation.GetDatabaseFiles()
+		return certFile
+	}
+
+	if installation.Type == domain.FormatSystemdCredential {
+		certFile, _ := installation.GetSystemdCredentialFiles()
+		return certFile
+	}
+
+	if installation.Type == domain.FormatPlugin {
+		return fmt.Sprintf("plugin:%s", installation.Plugin)
+	}
+
+	if installation.Type == domain.FormatK8sSecret {
+		namespace := installation.K8sNamespace
+		if namespace == "" {
+			namespace = domain.DefaultK8sNamespace
+		}
+		return fmt.Sprintf("%s/%s", namespace, installation.K8sSecretName)
+	}
+
 	if installation.Type != domain.FormatCAPI {
 		return installation.File
 	}
@@ -17,18 +17,29 @@
 package service
 
 import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
 	"os"
+	"path/filepath"
+	"sync"
 	"testing"
 
 	"github.com/stretchr/testify/suite"
 
 	"github.com/Venafi/vcert/v5/pkg/certificate"
 	"github.com/Venafi/vcert/v5/pkg/playbook/app/domain"
+	"github.com/Venafi/vcert/v5/pkg/playbook/app/notification"
+	"github.com/Venafi/vcert/v5/pkg/playbook/app/report"
+	"github.com/Venafi/vcert/v5/pkg/playbook/app/state"
 	"github.com/Venafi/vcert/v5/pkg/util"
 )
 
 type ServiceSuite struct {
 	suite.Suite
+	request   domain.PlaybookRequest
 	testCases []struct {
 		name   string
 		config domain.Config
@@ -68,6 +79,8 @@ func (s *ServiceSuite) SetupTest() {
 		Zone:      "",
 	}
 
+	s.request = request
+
 	s.testCases = []struct {
 		name   string
 		config domain.Config
@@ -85,13 +98,32 @@ func (s *ServiceSuite) SetupTest() {
 						File:        "./pem/cert.cert",
 						ChainFile:   "./pem/cert.chain",
 						KeyFile:     "./pem/pk.pem",
-						AfterAction: "echo Success!",
+						AfterAction: domain.AfterInstallActions{{Script: "echo Success!"}},
 					},
 				},
 				RenewBefore: "30d",
 				SetEnvVars:  []string{envVarThumbprint, envVarBase64, envVarSerial},
 			},
 		},
+		{
+			name:   "PEMPKCS8",
+			config: domain.Config{ForceRenew: true},
+			task: domain.CertificateTask{
+				Name:    "testcertpempkcs8",
+				Request: request,
+				Installations: domain.Installations{
+					{
+						Type:        domain.FormatPEM,
+						File:        "./pem/cert8.cert",
+						ChainFile:   "./pem/cert8.chain",
+						KeyFile:     "./pem/pk8.pem",
+						KeyFormat:   domain.KeyFormatPKCS8,
+						KeyPassword: "foobar123",
+					},
+				},
+				RenewBefore: "30d",
+			},
+		},
 		{
 			name:   "JKS",
 			config: domain.Config{},
@@ -102,7 +134,6 @@ func (s *ServiceSuite) SetupTest() {
 					{
 						Type:        domain.FormatJKS,
 						File:        "./jks/testjks.jks",
-						AfterAction: "",
 						BackupFiles: true,
 						JKSPassword: "foobar123",
 					},
@@ -120,13 +151,29 @@ func (s *ServiceSuite) SetupTest() {
 						Type:        domain.FormatPKCS12,
 						File:        "./pkcs12/testp12.p12",
 						P12Password: "foobar123",
-						AfterAction: "",
 					},
 				},
 				RenewBefore: "30d",
 				SetEnvVars:  []string{"hostname"},
 			},
 		},
+		{
+			name:   "PKCS12Modern",
+			config: domain.Config{ForceRenew: true},
+			task: domain.CertificateTask{
+				Name:    "testcertp12modern",
+				Request: request,
+				Installations: domain.Installations{
+					{
+						Type:          domain.FormatPKCS12,
+						File:          "./pkcs12/testp12modern.p12",
+						P12Password:   "foobar123",
+						P12Encryption: domain.P12EncryptionModern,
+					},
+				},
+				RenewBefore: "30d",
+			},
+		},
 		{
 			name:   "Multicert",
 			config: domain.Config{},
@@ -138,13 +185,11 @@ func (s *ServiceSuite) SetupTest() {
 						Type:        domain.FormatPKCS12,
 						File:        "./pkcs12/testp12.p12",
 						P12Password: "foobar123",
-						AfterAction: "",
 					},
 					{
 						Type:        domain.FormatJKS,
 						File:        "./jks/testjks.jks",
 						JKSPassword: "foobar123",
-						AfterAction: "",
 					},
 				},
 				RenewBefore: "30d",
@@ -160,12 +205,432 @@ func TestService(t *testing.T) {
 func (s *ServiceSuite) TestService_Execute() {
 	for _, tc := range s.testCases {
 		s.Run(tc.name, func() {
-			err := Execute(tc.config, tc.task)
+			err := Execute(context.Background(), tc.config, tc.task)
 			s.Empty(err)
 		})
 	}
 }
 
+func (s *ServiceSuite) TestService_ExecuteTasks() {
+	tasks := domain.CertificateTasks{}
+	for i := 0; i < 4; i++ {
+		tasks = append(tasks, domain.CertificateTask{
+			Name:    fmt.Sprintf("testconcurrent%d", i),
+			Request: s.request,
+			Installations: domain.Installations{
+				{
+					Type:      domain.FormatPEM,
+					File:      fmt.Sprintf("./pem/cert%d.cert", i),
+					ChainFile: fmt.Sprintf("./pem/cert%d.chain", i),
+					KeyFile:   fmt.Sprintf("./pem/pk%d.pem", i),
+				},
+			},
+		})
+	}
+
+	results := ExecuteTasks(context.Background(), domain.Config{ForceRenew: true, Concurrency: 3}, tasks)
+	s.Empty(results)
+
+	for i := range tasks {
+		s.FileExists(fmt.Sprintf("./pem/cert%d.cert", i))
+	}
+}
+
+func (s *ServiceSuite) TestService_ExecuteTasksCancelled() {
+	task := domain.CertificateTask{
+		Name:    "testcancelled",
+		Request: s.request,
+		Installations: domain.Installations{
+			{
+				Type:      domain.FormatPEM,
+				File:      "./pem/cancelled.cert",
+				ChainFile: "./pem/cancelled.chain",
+				KeyFile:   "./pem/cancelled.pem",
+			},
+		},
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	results := ExecuteTasks(ctx, domain.Config{ForceRenew: true}, domain.CertificateTasks{task})
+	s.Require().Len(results[task.Name], 1)
+	s.ErrorIs(results[task.Name][0], context.Canceled)
+	s.NoFileExists("./pem/cancelled.cert")
+}
+
+func (s *ServiceSuite) TestService_ExecuteRollback() {
+	oldContent := []byte("previous certificate")
+	err := os.MkdirAll("./pem", 0750)
+	s.Require().NoError(err)
+	for _, file := range []string{"./pem/cert.cert", "./pem/cert.chain", "./pem/pk.pem"} {
+		err = os.WriteFile(file, oldContent, 0600)
+		s.Require().NoError(err)
+	}
+
+	task := domain.CertificateTask{
+		Name:    "testrollback",
+		Request: s.request,
+		Installations: domain.Installations{
+			{
+				Type:        domain.FormatPEM,
+				File:        "./pem/cert.cert",
+				ChainFile:   "./pem/cert.chain",
+				KeyFile:     "./pem/pk.pem",
+				BackupFiles: true,
+			},
+			{
+				// Parent folder is a file, so installation fails
+				Type:        domain.FormatPKCS12,
+				File:        "./pem/cert.cert/testp12.p12",
+				P12Password: "foobar123",
+			},
+		},
+	}
+
+	errs := Execute(context.Background(), domain.Config{ForceRenew: true}, task)
+	s.Len(errs, 1)
+
+	for _, file := range []string{"./pem/cert.cert", "./pem/cert.chain", "./pem/pk.pem"} {
+		content, err := os.ReadFile(file)
+		s.NoError(err)
+		s.Equal(oldContent, content)
+	}
+}
+
+func (s *ServiceSuite) TestService_ExecuteBackupRetention() {
+	err := os.MkdirAll("./pkcs12", 0750)
+	s.Require().NoError(err)
+	err = os.WriteFile("./pkcs12/backup.p12", []byte("previous certificate"), 0600)
+	s.Require().NoError(err)
+	oldBackups := []string{
+		"./pkcs12/backup.p12.2021-01-01T00-00-00.bak",
+		"./pkcs12/backup.p12.2022-01-01T00-00-00.bak",
+		"./pkcs12/backup.p12.2023-01-01T00-00-00.bak",
+	}
+	for _, file := range oldBackups {
+		err = os.WriteFile(file, []byte("old certificate"), 0600)
+		s.Require().NoError(err)
+	}
+
+	task := domain.CertificateTask{
+		Name:    "testbackupretention",
+		Request: s.request,
+		Installations: domain.Installations{
+			{
+				Type:            domain.FormatPKCS12,
+				File:            "./pkcs12/backup.p12",
+				P12Password:     "foobar123",
+				BackupFiles:     true,
+				BackupRetention: 2,
+			},
+		},
+	}
+
+	errs := Execute(context.Background(), domain.Config{ForceRenew: true}, task)
+	s.Empty(errs)
+
+	backups, err := filepath.Glob("./pkcs12/backup.p12.*.bak")
+	s.Require().NoError(err)
+	s.Require().Len(backups, 2)
+	s.Equal(filepath.Clean(oldBackups[2]), backups[0])
+	content, err := os.ReadFile(backups[1])
+	s.NoError(err)
+	s.Equal([]byte("previous certificate"), content)
+}
+
+func (s *ServiceSuite) TestService_ExecuteDryRun() {
+	task := domain.CertificateTask{
+		Name:    "testdryrun",
+		Request: s.request,
+		Installations: domain.Installations{
+			{
+				Type:        domain.FormatPEM,
+				File:        "./pem/cert.cert",
+				ChainFile:   "./pem/cert.chain",
+				KeyFile:     "./pem/pk.pem",
+				AfterAction: domain.AfterInstallActions{{Script: "echo foo > ./pem/afteraction.txt"}},
+			},
+		},
+		SetEnvVars: []string{"thumbprint"},
+	}
+
+	errs := Execute(context.Background(), domain.Config{ForceRenew: true, DryRun: true}, task)
+	s.Empty(errs)
+
+	s.NoFileExists("./pem/cert.cert")
+	s.NoFileExists("./pem/afteraction.txt")
+	s.Empty(os.Getenv("VCERT_TESTDRYRUN_THUMBPRINT"))
+}
+
+func (s *ServiceSuite) TestService_ExecuteReport() {
+	task := domain.CertificateTask{
+		Name:    "testreport",
+		Request: s.request,
+		Installations: domain.Installations{
+			{
+				Type:      domain.FormatPEM,
+				File:      "./pem/cert.cert",
+				ChainFile: "./pem/cert.chain",
+				KeyFile:   "./pem/pk.pem",
+			},
+		},
+	}
+	config := domain.Config{Report: report.New()}
+
+	errs := Execute(context.Background(), config, task)
+	s.Empty(errs)
+	s.Require().Len(config.Report.Tasks, 1)
+	installed := config.Report.Tasks[0]
+	s.Equal(report.ActionInstalled, installed.Action)
+	s.Equal("certificate not found at ./pem/cert.cert", installed.Reason)
+	s.NotEmpty(installed.Serial)
+	s.NotNil(installed.NotAfter)
+
+	errs = Execute(context.Background(), config, task)
+	s.Empty(errs)
+	s.Require().Len(config.Report.Tasks, 2)
+	skipped := config.Report.Tasks[1]
+	s.Equal(report.ActionSkipped, skipped.Action)
+	s.Contains(skipped.Reason, "certificate in good health")
+	s.Equal(installed.Serial, skipped.Serial, "skipped tasks report the certificate installed")
+
+	config.ForceRenew = true
+	errs = Execute(context.Background(), config, task)
+	s.Empty(errs)
+	s.Require().Len(config.Report.Tasks, 3)
+	s.Equal(report.ActionRenewed, config.Report.Tasks[2].Action)
+	s.Equal("force-renew is set", config.Report.Tasks[2].Reason)
+
+	// Parent folder is a file, so installation fails
+	task.Installations[0].File = "./pem/cert.cert/cert.cert"
+	errs = Execute(context.Background(), config, task)
+	s.Len(errs, 1)
+	s.Require().Len(config.Report.Tasks, 4)
+	s.Equal(report.ActionFailed, config.Report.Tasks[3].Action)
+	s.Len(config.Report.Tasks[3].Errors, 1)
+	s.Equal(map[string]int{report.ActionInstalled: 1, report.ActionSkipped: 1, report.ActionRenewed: 1,
+		report.ActionFailed: 1}, config.Report.Summary)
+}
+
+func (s *ServiceSuite) TestService_ExecuteWhen() {
+	task := domain.CertificateTask{
+		Name:    "testwhen",
+		Request: s.request,
+		When:    `env("VCERT_TEST_ROLE") == "edge"`,
+		Installations: domain.Installations{
+			{
+				Type:      domain.FormatPEM,
+				File:      "./pem/cert.cert",
+				ChainFile: "./pem/cert.chain",
+				KeyFile:   "./pem/pk.pem",
+			},
+		},
+	}
+	config := domain.Config{Report: report.New()}
+
+	s.T().Setenv("VCERT_TEST_ROLE", "core")
+	errs := Execute(context.Background(), config, task)
+	s.Empty(errs)
+	s.NoFileExists("./pem/cert.cert")
+	s.Require().Len(config.Report.Tasks, 1)
+	s.Equal(report.ActionSkipped, config.Report.Tasks[0].Action)
+	s.Contains(config.Report.Tasks[0].Reason, "is false")
+
+	s.T().Setenv("VCERT_TEST_ROLE", "edge")
+	errs = Execute(context.Background(), config, task)
+	s.Empty(errs)
+	s.FileExists("./pem/cert.cert")
+}
+
+func (s *ServiceSuite) TestService_ExecuteReuseKey() {
+	task := domain.CertificateTask{
+		Name:    "testreusekey",
+		Request: s.request,
+		Installations: domain.Installations{
+			{
+				Type:      domain.FormatPEM,
+				File:      "./pem/cert.cert",
+				ChainFile: "./pem/cert.chain",
+				KeyFile:   "./pem/pk.pem",
+			},
+		},
+	}
+	task.Request.CsrOrigin = certificate.StrLocalGeneratedCSR
+	config := domain.Config{ForceRenew: true}
+
+	errs := Execute(context.Background(), config, task)
+	s.Empty(errs)
+	key, err := os.ReadFile("./pem/pk.pem")
+	s.Require().NoError(err)
+
+	task.Request.ReuseKey = true
+	errs = Execute(context.Background(), config, task)
+	s.Empty(errs)
+	renewedKey, err := os.ReadFile("./pem/pk.pem")
+	s.Require().NoError(err)
+	s.Equal(string(key), string(renewedKey), "the renewal reuses the private key installed")
+
+	task.Request.ReuseKey = false
+	errs = Execute(context.Background(), config, task)
+	s.Empty(errs)
+	renewedKey, err = os.ReadFile("./pem/pk.pem")
+	s.Require().NoError(err)
+	s.NotEqual(string(key), string(renewedKey), "the renewal generates a new private key")
+}
+
+func (s *ServiceSuite) TestService_ExecuteNotifications() {
+	var events []notification.Event
+	mu := sync.Mutex{}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		event := notification.Event{}
+		s.NoError(json.NewDecoder(r.Body).Decode(&event))
+		mu.Lock()
+		events = append(events, event)
+		mu.Unlock()
+	}))
+	defer server.Close()
+
+	config := domain.Config{
+		ForceRenew:    true,
+		Notifications: []domain.Notification{{Type: domain.NotificationWebhook, URL: server.URL}},
+	}
+	task := domain.CertificateTask{
+		Name:        "testnotifications",
+		Request:     s.request,
+		RenewBefore: "99%",
+		Installations: domain.Installations{
+			{
+				Type:      domain.FormatPEM,
+				File:      "./pem/cert.cert",
+				ChainFile: "./pem/cert.chain",
+				KeyFile:   "./pem/pk.pem",
+			},
+		},
+	}
+
+	errs := Execute(context.Background(), config, task)
+	s.Empty(errs)
+	s.Require().Len(events, 1)
+	s.Equal(domain.EventSuccess, events[0].Event)
+	s.Equal("foo.bar.rvela.com", events[0].CommonName)
+	s.NotEmpty(events[0].Serial)
+	s.NotEmpty(events[0].NotAfter)
+
+	// The certificate just installed is within 99% of its lifetime from expiration
+	events = nil
+	config.ForceRenew = false
+	errs = Execute(context.Background(), config, task)
+	s.Empty(errs)
+	s.Require().Len(events, 2)
+	s.Equal(domain.EventExpiring, events[0].Event)
+	s.Equal(domain.EventSuccess, events[1].Event)
+
+	// Parent folder is a file, so installation fails
+	events = nil
+	config.ForceRenew = true
+	task.Installations[0].File = "./pem/cert.cert/cert.cert"
+	errs = Execute(context.Background(), config, task)
+	s.Len(errs, 1)
+	s.Require().Len(events, 1)
+	s.Equal(domain.EventFailure, events[0].Event)
+	s.NotEmpty(events[0].Error)
+}
+
+func (s *ServiceSuite) TestService_ExecuteDrift() {
+	var events []notification.Event
+	mu := sync.Mutex{}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		event := notification.Event{}
+		s.NoError(json.NewDecoder(r.Body).Decode(&event))
+		mu.Lock()
+		events = append(events, event)
+		mu.Unlock()
+	}))
+	defer server.Close()
+
+	st, err := state.Load(filepath.Join(s.T().TempDir(), "state.json"))
+	s.Require().NoError(err)
+	config := domain.Config{
+		State:         st,
+		Report:        report.New(),
+		Notifications: []domain.Notification{{Type: domain.NotificationWebhook, URL: server.URL, Events: []string{domain.EventDrift}}},
+	}
+	task := domain.CertificateTask{
+		Name:    "testdrift",
+		Request: s.request,
+		Installations: domain.Installations{
+			{
+				Type:      domain.FormatPEM,
+				File:      "./pem/cert.cert",
+				ChainFile: "./pem/cert.chain",
+				KeyFile:   "./pem/pk.pem",
+			},
+		},
+	}
+
+	errs := Execute(context.Background(), config, task)
+	s.Empty(errs)
+	installed := config.Report.Tasks[0]
+	ts, found := st.Task(task.Name)
+	s.Require().True(found)
+	s.Equal(map[string]string{"./pem/cert.cert": installed.Thumbprint}, ts.Installed)
+
+	// The certificate and its key are replaced by another certificate matching the task
+	other := task
+	other.Name = "testdriftother"
+	other.Installations = domain.Installations{
+		{
+			Type:      domain.FormatPEM,
+			File:      "./pem/other.cert",
+			ChainFile: "./pem/other.chain",
+			KeyFile:   "./pem/otherpk.pem",
+		},
+	}
+	errs = Execute(context.Background(), domain.Config{}, other)
+	s.Empty(errs)
+	for from, to := range map[string]string{"./pem/other.cert": "./pem/cert.cert", "./pem/other.chain": "./pem/cert.chain", "./pem/otherpk.pem": "./pem/pk.pem"} {
+		data, err := os.ReadFile(from)
+		s.Require().NoError(err)
+		s.Require().NoError(os.WriteFile(to, data, 0600))
+	}
+	replaced, err := os.ReadFile("./pem/cert.cert")
+	s.Require().NoError(err)
+
+	// Without enforce, the certificate is only reported
+	errs = Execute(context.Background(), config, task)
+	s.Empty(errs)
+	s.Require().Len(config.Report.Tasks, 2)
+	s.Equal(report.ActionSkipped, config.Report.Tasks[1].Action)
+	s.Equal("certificate at ./pem/cert.cert was replaced out of band", config.Report.Tasks[1].Reason)
+	s.Require().Len(events, 1)
+	s.Equal(domain.EventDrift, events[0].Event)
+	s.Equal("./pem/cert.cert", events[0].Location)
+	s.NotEqual(installed.Thumbprint, events[0].Thumbprint)
+	current, err := os.ReadFile("./pem/cert.cert")
+	s.Require().NoError(err)
+	s.Equal(string(replaced), string(current))
+
+	// With enforce, a new certificate replaces it
+	task.Installations[0].Enforce = true
+	errs = Execute(context.Background(), config, task)
+	s.Empty(errs)
+	s.Require().Len(config.Report.Tasks, 3)
+	s.Equal(report.ActionRenewed, config.Report.Tasks[2].Action)
+	s.Equal("certificate at ./pem/cert.cert was replaced out of band", config.Report.Tasks[2].Reason)
+	current, err = os.ReadFile("./pem/cert.cert")
+	s.Require().NoError(err)
+	s.NotEqual(string(replaced), string(current))
+	ts, _ = st.Task(task.Name)
+	s.Equal(map[string]string{"./pem/cert.cert": config.Report.Tasks[2].Thumbprint}, ts.Installed)
+
+	events = nil
+	errs = Execute(context.Background(), config, task)
+	s.Empty(errs)
+	s.Require().Len(config.Report.Tasks, 4)
+	s.Equal(report.ActionSkipped, config.Report.Tasks[3].Action)
+	s.Empty(events)
+}
+
 // this function executes after each test case
 func (s *ServiceSuite) TearDownTest() {
 	err := os.RemoveAll("./jks")
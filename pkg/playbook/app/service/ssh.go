@@ -0,0 +1,236 @@
+/*
+ * Copyright 2023 Venafi, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *  http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"go.uber.org/zap"
+
+	"github.com/Venafi/vcert/v5/pkg/playbook/app/audit"
+	"github.com/Venafi/vcert/v5/pkg/playbook/app/domain"
+	"github.com/Venafi/vcert/v5/pkg/playbook/app/installer"
+	"github.com/Venafi/vcert/v5/pkg/playbook/app/notification"
+	"github.com/Venafi/vcert/v5/pkg/playbook/app/report"
+	"github.com/Venafi/vcert/v5/pkg/playbook/app/vcertutil"
+)
+
+// executeSSHCertificate requests the SSH certificate of the task, when any SSHCERT installation needs it,
+// and installs it along with the public key of the SSH CA
+func executeSSHCertificate(ctx context.Context, logger *zap.Logger, config domain.Config, rec auditRecorder, task domain.CertificateTask, result *report.TaskResult) []error {
+	var bundle installer.SSHBundle
+	installsCertificate := false
+	installsCA := false
+	for _, installation := range task.Installations {
+		if installation.Type == domain.FormatSSHCert {
+			installsCertificate = true
+		} else {
+			installsCA = true
+		}
+	}
+
+	// The public key of the CA is needed to check every installation, so it is retrieved on every run
+	if installsCA {
+		caPublicKey, err := vcertutil.RetrieveSSHCAPublicKey(ctx, config, task.SSH.Template)
+		if err != nil {
+			return []error{fmt.Errorf("error retrieving public key of SSH CA %s: %w", task.SSH.Template, err)}
+		}
+		bundle.CAPublicKey = caPublicKey
+	}
+
+	changed, err := isSSHCertificateChanged(ctx, logger, config, task, bundle.CAPublicKey)
+	if err != nil {
+		logger.Error("error checking SSH certificate in task", zap.Error(err))
+		return []error{err}
+	}
+	if !changed {
+		logger.Info("SSH certificate in good health. No actions needed", zap.String("keyId", task.SSH.KeyID))
+		result.Reason = "SSH certificate in good health"
+		return nil
+	}
+	logger.Info("SSH certificate needs action", zap.String("keyId", task.SSH.KeyID))
+	result.Reason = "SSH certificate or SSH CA public key needs to be installed"
+	if config.ForceRenew {
+		result.Reason = "force-renew is set"
+	}
+
+	if config.DryRun {
+		result.Reason = "dry run: " + result.Reason
+		reportSSHDryRun(logger, task, installsCertificate)
+		return nil
+	}
+
+	var event *notification.Event
+	if installsCertificate {
+		data, err := vcertutil.EnrollSSHCertificate(ctx, config, task.SSH)
+		enrollEvent := audit.Event{Operation: audit.OperationEnroll, CommonName: task.SSH.KeyID,
+			Parameters: map[string]string{"certificateType": "ssh", "template": task.SSH.Template}}
+		if err == nil {
+			enrollEvent.PickupID = data.DN
+			enrollEvent.Serial = data.CertificateDetails.SerialNumber
+		}
+		rec.record(enrollEvent, err)
+		if err != nil {
+			return []error{fmt.Errorf("error requesting SSH certificate %s: %w", task.Name, err)}
+		}
+		validTo := time.Unix(data.CertificateDetails.ValidTo, 0)
+		logger.Info("successfully enrolled SSH certificate", zap.String("keyId", data.CertificateDetails.KeyID),
+			zap.String("pickupID", data.DN), zap.Time("expirationDate", validTo))
+
+		result.Serial = data.CertificateDetails.SerialNumber
+		result.NotAfter = &validTo
+
+		bundle.PrivateKey = data.PrivateKeyData
+		bundle.PublicKey = data.PublicKeyData
+		bundle.Certificate = data.CertificateData
+		event = &notification.Event{
+			Event:      domain.EventSuccess,
+			Task:       task.Name,
+			CommonName: data.CertificateDetails.KeyID,
+			Serial:     data.CertificateDetails.SerialNumber,
+			NotAfter:   validTo.UTC().Format(time.RFC3339),
+		}
+	}
+
+	// If any installation fails, the installations already run are rolled back to avoid a mixed state
+	processed := make([]domain.Installation, 0, len(task.Installations))
+	for _, installation := range task.Installations {
+		e := runSSHInstaller(ctx, logger, rec, installation, bundle)
+		if e == nil || !errors.Is(e, errBackup) {
+			processed = append(processed, installation)
+		}
+		if e != nil {
+			errorList := []error{e}
+			errorList = append(errorList, rollbackSSHInstallations(context.Background(), logger, processed)...)
+			return errorList
+		}
+	}
+
+	// SSH installers report no certificate found installed, so every SSH certificate issued is reported as installed
+	result.Action = report.ActionInstalled
+	if event != nil {
+		notify(logger, config, *event)
+	}
+	return nil
+}
+
+// isSSHCertificateChanged returns true when any installation of the task needs to be installed
+func isSSHCertificateChanged(ctx context.Context, logger *zap.Logger, config domain.Config, task domain.CertificateTask, caPublicKey string) (bool, error) {
+	if config.ForceRenew {
+		logger.Info("Flag [force-renew] is set. All certificates will be requested/renewed regardless of status")
+		return true, nil
+	}
+	renewBefore := DefaultRenew
+	if task.RenewBefore != "" {
+		renewBefore = task.RenewBefore
+	}
+
+	changed := false
+	for _, installation := range task.Installations {
+		isChanged, err := installer.GetSSHInstaller(installation).Check(ctx, renewBefore, task.SSH, caPublicKey)
+		if err != nil {
+			return false, fmt.Errorf("error checking for SSH certificate %s: %w", task.Name, err)
+		}
+		if isChanged {
+			changed = true
+		}
+	}
+	return changed, nil
+}
+
+// reportSSHDryRun logs the actions executeSSHCertificate would take for the task
+func reportSSHDryRun(logger *zap.Logger, task domain.CertificateTask, installsCertificate bool) {
+	if installsCertificate {
+		logger.Info("[dry-run] SSH certificate would be requested", zap.String("keyId", task.SSH.KeyID),
+			zap.String("template", task.SSH.Template))
+	}
+
+	for _, installation := range task.Installations {
+		location := getInstallationLocationString(installation)
+		if installation.BackupFiles {
+			logger.Info("[dry-run] SSH files would be backed up", zap.String("installer", installation.Type.String()),
+				zap.String("location", location))
+		}
+		logger.Info("[dry-run] SSH files would be installed", zap.String("installer", installation.Type.String()),
+			zap.String("location", location))
+		if len(installation.AfterAction) > 0 {
+			logger.Info("[dry-run] after-install actions would run", zap.String("location", location),
+				zap.Stringer("afterAction", installation.AfterAction))
+		}
+		if installation.InstallValidation != "" {
+			logger.Info("[dry-run] installation validation actions would run", zap.String("location", location),
+				zap.String("installValidationAction", installation.InstallValidation))
+		}
+	}
+}
+
+func runSSHInstaller(ctx context.Context, logger *zap.Logger, rec auditRecorder, installation domain.Installation, bundle installer.SSHBundle) error {
+	location := getInstallationLocationString(installation)
+
+	instlr := installer.GetSSHInstaller(installation)
+	logger.Info("running Installer", zap.String("installer", installation.Type.String()),
+		zap.String("location", location))
+
+	if installation.BackupFiles {
+		logger.Info("backing up SSH files for Installer", zap.String("installer", installation.Type.String()),
+			zap.String("location", location))
+		err := instlr.Backup(ctx)
+		if err != nil {
+			logger.Error(errBackup.Error(), zap.String("location", location), zap.Error(err))
+			return fmt.Errorf("%w at location %s: %w", errBackup, location, err)
+		}
+	}
+
+	err := instlr.Install(ctx, bundle)
+	rec.record(audit.Event{Operation: audit.OperationInstall, Location: location,
+		Parameters: map[string]string{"format": installation.Type.String()}}, err)
+	if err != nil {
+		e := "error installing SSH files"
+		logger.Error(e, zap.String("location", location), zap.Error(err))
+		return fmt.Errorf("%s at location %s: %w", e, location, err)
+	}
+	logger.Info("successfully installed SSH files", zap.String("location", location))
+
+	return runInstallerActions(ctx, logger, rec, instlr, installation, location)
+}
+
+// rollbackSSHInstallations restores the backups taken for the given installations.
+// Installations without backupFiles enabled have no backup to restore, so they are left as they are
+func rollbackSSHInstallations(ctx context.Context, logger *zap.Logger, installations []domain.Installation) []error {
+	errorList := make([]error, 0)
+	for _, installation := range installations {
+		location := getInstallationLocationString(installation)
+		if !installation.BackupFiles {
+			logger.Warn("backupFiles is not enabled, SSH files cannot be rolled back",
+				zap.String("installer", installation.Type.String()), zap.String("location", location))
+			continue
+		}
+
+		logger.Info("rolling back SSH files for Installer", zap.String("installer", installation.Type.String()),
+			zap.String("location", location))
+		err := installer.GetSSHInstaller(installation).Rollback(ctx)
+		if err != nil {
+			e := "error rolling back SSH files"
+			logger.Error(e, zap.String("location", location), zap.Error(err))
+			errorList = append(errorList, fmt.Errorf("%s at location %s: %w", e, location, err))
+		}
+	}
+	return errorList
+}
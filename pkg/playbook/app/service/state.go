@@ -0,0 +1,122 @@
+/*
+ * Copyright 2023 Venafi, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *  http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package service
+
+import (
+	"crypto/x509"
+	"time"
+
+	"go.uber.org/zap"
+
+	"github.com/Venafi/vcert/v5/pkg/certificate"
+	"github.com/Venafi/vcert/v5/pkg/playbook/app/domain"
+	"github.com/Venafi/vcert/v5/pkg/playbook/app/installer"
+	"github.com/Venafi/vcert/v5/pkg/playbook/app/state"
+)
+
+// pendingPickupID returns the pickup ID of a certificate requested by a previous run of the task that was never
+// retrieved, and the zone it was requested in. Only requests whose key is generated by the Venafi platform can be resumed:
+// a key generated locally by the interrupted run is lost, so the certificate is requested again
+func pendingPickupID(logger *zap.Logger, config domain.Config, task domain.CertificateTask, csrOrigin certificate.CSrOriginOption) (string, string) {
+	if config.State == nil {
+		return "", ""
+	}
+	ts, found := config.State.Task(task.Name)
+	if !found || ts.Status != state.StatusPending || ts.PickupID == "" {
+		return "", ""
+	}
+	if csrOrigin != certificate.ServiceGeneratedCSR {
+		logger.Info("pending certificate request cannot be resumed, its private key was generated locally. Requesting a new certificate",
+			zap.String("pickupID", ts.PickupID))
+		return "", ""
+	}
+
+	// Requests recorded before zones were tracked were made in the primary zone
+	zones := task.Request.GetZones()
+	zone := ts.Zone
+	if zone == "" && len(zones) > 0 {
+		zone = zones[0]
+	}
+	if !containsZone(zones, zone) {
+		logger.Info("pending certificate request was made in a zone no longer used by the task. Requesting a new certificate",
+			zap.String("pickupID", ts.PickupID), zap.String("zone", zone))
+		return "", ""
+	}
+	logger.Info("resuming retrieval of pending certificate request", zap.String("pickupID", ts.PickupID), zap.String("zone", zone))
+	return ts.PickupID, zone
+}
+
+// recordRequested saves the pickup ID of a certificate request, so it can be retrieved by the next run if this one is interrupted.
+// Renewals of an adopted certificate stay adopted
+func recordRequested(logger *zap.Logger, config domain.Config, task domain.CertificateTask, pickupID string, zone string, adopted bool) {
+	if config.State == nil {
+		return
+	}
+	// The certificates installed are kept until the requested one replaces them
+	ts, _ := config.State.Task(task.Name)
+	now := time.Now()
+	err := config.State.SetTask(task.Name, state.TaskState{
+		Status:      state.StatusPending,
+		PickupID:    pickupID,
+		Zone:        zone,
+		RequestedAt: &now,
+		Adopted:     adopted,
+		Installed:   ts.Installed,
+	})
+	if err != nil {
+		logger.Warn("failed to record certificate request in state file", zap.Error(err))
+	}
+}
+
+// recordIssued saves the details of the certificate retrieved for the task
+func recordIssued(logger *zap.Logger, config domain.Config, task domain.CertificateTask, pickupID string, zone string, cert *installer.Certificate) {
+	if config.State == nil {
+		return
+	}
+	ts, _ := config.State.Task(task.Name)
+	now := time.Now()
+	notAfter := cert.X509cert.NotAfter
+	ts.Status = state.StatusIssued
+	ts.PickupID = pickupID
+	ts.Zone = zone
+	ts.IssuedAt = &now
+	ts.Serial = cert.X509cert.SerialNumber.String()
+	ts.Thumbprint = cert.Thumbprint
+	ts.NotAfter = &notAfter
+	ts.RenewAt = nil
+	if renewalDate := renewalDate(task, cert.X509cert); !renewalDate.IsZero() {
+		ts.RenewAt = &renewalDate
+	}
+	err := config.State.SetTask(task.Name, ts)
+	if err != nil {
+		logger.Warn("failed to record issued certificate in state file", zap.Error(err))
+	}
+}
+
+// renewalDate returns the date from which cert is renewed according to the renewBefore of the task,
+// or the zero time when automatic renewal is disabled
+func renewalDate(task domain.CertificateTask, cert x509.Certificate) time.Time {
+	renewBefore := DefaultRenew
+	if task.RenewBefore != "" {
+		renewBefore = task.RenewBefore
+	}
+	window, err := domain.ParseRenewBefore(renewBefore)
+	if err != nil {
+		return time.Time{}
+	}
+	return window.RenewalDate(cert.NotBefore, cert.NotAfter)
+}
@@ -0,0 +1,139 @@
+/*
+ * Copyright 2023 Venafi, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *  http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package service
+
+import (
+	"crypto/x509"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+
+	"github.com/Venafi/vcert/v5/pkg/certificate"
+	"github.com/Venafi/vcert/v5/pkg/playbook/app/domain"
+	"github.com/Venafi/vcert/v5/pkg/playbook/app/report"
+	"github.com/Venafi/vcert/v5/pkg/playbook/app/state"
+)
+
+func TestPendingPickupID(t *testing.T) {
+	logger := zap.NewNop()
+	task := domain.CertificateTask{Name: "myTask", Request: domain.PlaybookRequest{Zone: "Primary", Zones: []string{"Secondary"}}}
+
+	st, err := state.Load(filepath.Join(t.TempDir(), "state.json"))
+	require.NoError(t, err)
+	config := domain.Config{State: st}
+
+	pickupID, _ := pendingPickupID(logger, domain.Config{}, task, certificate.ServiceGeneratedCSR)
+	assert.Empty(t, pickupID, "no state file")
+	pickupID, _ = pendingPickupID(logger, config, task, certificate.ServiceGeneratedCSR)
+	assert.Empty(t, pickupID, "task not in state file")
+
+	recordRequested(logger, config, task, "pickup-1", "Secondary", false)
+	pickupID, zone := pendingPickupID(logger, config, task, certificate.ServiceGeneratedCSR)
+	assert.Equal(t, "pickup-1", pickupID)
+	assert.Equal(t, "Secondary", zone)
+	pickupID, _ = pendingPickupID(logger, config, task, certificate.LocalGeneratedCSR)
+	assert.Empty(t, pickupID, "local keys are lost")
+
+	require.NoError(t, st.SetTask(task.Name, state.TaskState{Status: state.StatusPending, PickupID: "pickup-2"}))
+	pickupID, zone = pendingPickupID(logger, config, task, certificate.ServiceGeneratedCSR)
+	assert.Equal(t, "pickup-2", pickupID)
+	assert.Equal(t, "Primary", zone, "requests without zone were made in the primary zone")
+
+	require.NoError(t, st.SetTask(task.Name, state.TaskState{Status: state.StatusPending, PickupID: "pickup-3", Zone: "Removed"}))
+	pickupID, _ = pendingPickupID(logger, config, task, certificate.ServiceGeneratedCSR)
+	assert.Empty(t, pickupID, "zone no longer used by the task")
+
+	require.NoError(t, st.SetTask(task.Name, state.TaskState{Status: state.StatusIssued, PickupID: "pickup-1"}))
+	pickupID, _ = pendingPickupID(logger, config, task, certificate.ServiceGeneratedCSR)
+	assert.Empty(t, pickupID, "certificate already issued")
+}
+
+func TestEnrollmentZones(t *testing.T) {
+	zones := []string{"Primary", "Secondary", "Tertiary"}
+
+	assert.Equal(t, zones, enrollmentZones(zones, ""))
+	assert.Equal(t, zones, enrollmentZones(zones, "Primary"))
+	assert.Equal(t, []string{"Tertiary", "Primary", "Secondary"}, enrollmentZones(zones, "Tertiary"))
+	assert.Equal(t, zones, enrollmentZones(zones, "Unknown"))
+}
+
+func TestAdoptedPickupID(t *testing.T) {
+	logger := zap.NewNop()
+	task := domain.CertificateTask{Name: "myTask", Adopt: true, Request: domain.PlaybookRequest{Zone: "Primary"}}
+
+	st, err := state.Load(filepath.Join(t.TempDir(), "state.json"))
+	require.NoError(t, err)
+	config := domain.Config{State: st}
+
+	pickupID, _ := adoptedPickupID(config, task)
+	assert.Empty(t, pickupID, "task not in state file")
+
+	recordAdopted(logger, config, task, "pickup-1", "Primary", report.TaskResult{Serial: "1", Thumbprint: "abcd"})
+	ts, found := st.Task(task.Name)
+	require.True(t, found)
+	assert.Equal(t, state.StatusIssued, ts.Status)
+	assert.Equal(t, "abcd", ts.Thumbprint)
+	assert.True(t, ts.Adopted)
+	pickupID, zone := adoptedPickupID(config, task)
+	assert.Equal(t, "pickup-1", pickupID)
+	assert.Equal(t, "Primary", zone)
+
+	notAdopting := task
+	notAdopting.Adopt = false
+	pickupID, _ = adoptedPickupID(config, notAdopting)
+	assert.Empty(t, pickupID, "adopt no longer set")
+
+	recordRequested(logger, config, task, "pickup-2", "Primary", true)
+	pickupID, _ = adoptedPickupID(config, task)
+	assert.Empty(t, pickupID, "renewal pending")
+	ts, _ = st.Task(task.Name)
+	assert.True(t, ts.Adopted, "renewals stay adopted")
+
+	require.NoError(t, st.SetTask(task.Name, state.TaskState{Status: state.StatusIssued, PickupID: "pickup-3"}))
+	pickupID, _ = adoptedPickupID(config, task)
+	assert.Empty(t, pickupID, "certificate requested by the task")
+}
+
+func TestRecordInstalled(t *testing.T) {
+	logger := zap.NewNop()
+	task := domain.CertificateTask{Name: "myTask", Installations: domain.Installations{
+		{Type: domain.FormatPEM, File: "/etc/ssl/cert.pem"},
+		{Type: domain.FormatPKCS12, File: "/etc/ssl/cert.p12"},
+	}}
+	cert := x509.Certificate{Raw: []byte("installed")}
+	replaced := x509.Certificate{Raw: []byte("replaced")}
+
+	assert.False(t, isDrifted(domain.Config{}, task, "/etc/ssl/cert.pem", replaced), "no state file")
+
+	st, err := state.Load(filepath.Join(t.TempDir(), "state.json"))
+	require.NoError(t, err)
+	config := domain.Config{State: st}
+	assert.False(t, isDrifted(config, task, "/etc/ssl/cert.pem", replaced), "task not in state file")
+
+	recordInstalled(logger, config, task, thumbprint(cert))
+	ts, _ := st.Task(task.Name)
+	assert.Equal(t, map[string]string{"/etc/ssl/cert.pem": thumbprint(cert), "/etc/ssl/cert.p12": thumbprint(cert)}, ts.Installed)
+	assert.False(t, isDrifted(config, task, "/etc/ssl/cert.pem", cert))
+	assert.True(t, isDrifted(config, task, "/etc/ssl/cert.pem", replaced))
+	assert.False(t, isDrifted(config, task, "/etc/ssl/new.pem", replaced), "nothing installed there yet")
+
+	recordRequested(logger, config, task, "pickup-1", "Primary", false)
+	assert.True(t, isDrifted(config, task, "/etc/ssl/cert.p12", replaced), "the certificates installed are kept while a renewal is pending")
+}
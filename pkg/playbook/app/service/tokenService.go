@@ -17,13 +17,18 @@
 package service
 
 import (
+	"context"
 	"fmt"
+	"os"
 
 	"go.uber.org/zap"
 
 	"github.com/Venafi/vcert/v5/pkg/playbook/app/domain"
 	"github.com/Venafi/vcert/v5/pkg/playbook/app/parser"
+	"github.com/Venafi/vcert/v5/pkg/playbook/app/secret"
 	"github.com/Venafi/vcert/v5/pkg/playbook/app/vcertutil"
+	"github.com/Venafi/vcert/v5/pkg/util"
+	"github.com/Venafi/vcert/v5/pkg/venafi"
 )
 
 // ValidateTPPCredentials checks that the TPP credentials are not expired.
@@ -31,10 +36,35 @@ import (
 // If expired, it will try to get a new token pair using the refreshToken.
 //
 // If the refreshing is successful it will save the new token pair in the playbook file.
-func ValidateTPPCredentials(playbook *domain.Playbook) error {
+// When config.connection is a list, the credentials of every TPP connection are checked
+func ValidateTPPCredentials(ctx context.Context, playbook *domain.Playbook) error {
+	if len(playbook.Config.Connections) == 0 {
+		return validateTPPConnection(ctx, playbook, &playbook.Config.Connection)
+	}
+
+	for i := range playbook.Config.Connections {
+		connection := &playbook.Config.Connections[i]
+		if connection.Platform != venafi.TPP {
+			continue
+		}
+		err := validateTPPConnection(ctx, playbook, connection)
+		if err != nil {
+			return fmt.Errorf("connection %s: %w", connection.Name, err)
+		}
+	}
+	// The default connection is the first of the list, and gets its refreshed tokens too
+	playbook.Config.Connection = playbook.Config.Connections[0]
+	return nil
+}
+
+// validateTPPConnection checks the credentials of connection, one of the connections of playbook, and refreshes them if needed
+func validateTPPConnection(ctx context.Context, playbook *domain.Playbook, connection *domain.Connection) error {
+	config := playbook.Config
+	config.Connection = *connection
+
 	//Validate TPP tokens
-	if playbook.Config.Connection.Credentials.AccessToken != "" {
-		isValid, err := vcertutil.IsValidAccessToken(playbook.Config)
+	if connection.Credentials.AccessToken != "" {
+		isValid, err := vcertutil.IsValidAccessToken(ctx, config)
 		// Return any error besides 401 Unauthorized - need to properly handle errors unrelated to the state of the token (connectivity)
 		if err != nil && err.Error() != "failed to verify token. Message: 401 Unauthorized" {
 			return err
@@ -45,12 +75,22 @@ func ValidateTPPCredentials(playbook *domain.Playbook) error {
 	}
 
 	zap.L().Info("access token is invalid, missing, or expired")
-	if playbook.Config.Connection.Credentials.RefreshToken == "" && playbook.Config.Connection.Credentials.P12Task == "" {
+	if connection.Credentials.RefreshToken == "" && !connection.Credentials.HasClientCertificate() {
 		return fmt.Errorf("access token no longer valid and no authorization methods specified - cannot get a new access token")
 	}
 
 	zap.L().Info("using refresh token")
 
+	// The playbook file is locked while its tokens are refreshed, so concurrent runs of the playbook do not refresh
+	// the same refresh token twice, nor overwrite the tokens written by each other
+	unlock, err := util.LockFile(playbook.Location)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		_ = unlock()
+	}()
+
 	// Read the playbook first, to make sure we can, before refreshing the tokens
 	// and blowing things up!
 	pbData, err := parser.ReadPlaybookRaw(playbook.Location)
@@ -58,17 +98,30 @@ func ValidateTPPCredentials(playbook *domain.Playbook) error {
 		return err
 	}
 
-	accessToken, refreshToken, err := vcertutil.RefreshTPPTokens(playbook.Config)
+	err = checkTokensWritable(pbData, connection.Name)
+	if err != nil {
+		return err
+	}
+
+	// Another run may have refreshed the tokens since the playbook was parsed
+	if accessToken, refreshToken, ok := refreshedTokens(ctx, config, pbData, connection.Name); ok {
+		zap.L().Info("using the tokens refreshed by another run of the playbook")
+		connection.Credentials.AccessToken = accessToken
+		connection.Credentials.RefreshToken = refreshToken
+		return nil
+	}
+
+	accessToken, refreshToken, err := vcertutil.RefreshTPPTokens(ctx, config)
 	if err != nil {
 		zap.L().Error("failed to refresh TPP Tokens", zap.Error(err))
 		return err
 	}
 	zap.L().Info("successfully retrieved new refresh token")
 
-	playbook.Config.Connection.Credentials.AccessToken = accessToken
-	playbook.Config.Connection.Credentials.RefreshToken = refreshToken
+	connection.Credentials.AccessToken = accessToken
+	connection.Credentials.RefreshToken = refreshToken
 
-	err = replaceTokensInFile(pbData, accessToken, refreshToken)
+	err = replaceTokensInFile(pbData, connection.Name, accessToken, refreshToken)
 	if err != nil {
 		zap.L().Error("failed to replace tokens in playbook file", zap.Error(err))
 		return err
@@ -83,29 +136,127 @@ func ValidateTPPCredentials(playbook *domain.Playbook) error {
 	return nil
 }
 
-func replaceTokensInFile(playbook map[string]interface{}, accessToken string, refreshToken string) error {
+// refreshedTokens returns the tokens of the connection named name in the playbook data, when they are not those of
+// config and its access token is valid, as happens when another run of the playbook refreshed them
+func refreshedTokens(ctx context.Context, config domain.Config, playbook map[string]interface{}, name string) (string, string, bool) {
+	credsMap, err := connectionCredentials(playbook, name)
+	if err != nil {
+		return "", "", false
+	}
+	accessToken, err := tokenValue(credsMap["accessToken"])
+	if err != nil {
+		return "", "", false
+	}
+	refreshToken, err := tokenValue(credsMap["refreshToken"])
+	if err != nil || refreshToken == config.Connection.Credentials.RefreshToken || accessToken == "" {
+		return "", "", false
+	}
+
+	config.Connection.Credentials.AccessToken = accessToken
+	config.Connection.Credentials.RefreshToken = refreshToken
+	isValid, err := vcertutil.IsValidAccessToken(ctx, config)
+	if err != nil || !isValid {
+		return "", "", false
+	}
+	return accessToken, refreshToken, true
+}
+
+// tokenValue returns the plaintext of a token of the playbook data, which may be tagged !encrypted
+func tokenValue(value interface{}) (string, error) {
+	switch token := value.(type) {
+	case secret.Encrypted:
+		return secret.Decrypt(string(token), os.Getenv(secret.EnvPassphrase))
+	case string:
+		return token, nil
+	default:
+		return "", nil
+	}
+}
 
+// replaceTokensInFile sets the tokens of the connection of the playbook data. When config.connection is a list,
+// the tokens of the connection named name are set
+func replaceTokensInFile(playbook map[string]interface{}, name string, accessToken string, refreshToken string) error {
+	credsMap, err := connectionCredentials(playbook, name)
+	if err != nil {
+		return err
+	}
+
+	err = checkTokensWritable(playbook, name)
+	if err != nil {
+		return err
+	}
+
+	if ref, ok := credsMap["accessToken"].(string); ok && secret.IsRef(ref) {
+		// The secret reference is kept, the access token it resolves to is refreshed again on the next run
+		zap.L().Warn("access token of the connection is a secret reference, the refreshed access token is not written to the playbook",
+			zap.String("connection", name), zap.String("reference", ref))
+	} else {
+		credsMap["accessToken"], err = replaceToken(credsMap["accessToken"], accessToken)
+		if err != nil {
+			return fmt.Errorf("could not encrypt the new access token: %w", err)
+		}
+	}
+	credsMap["refreshToken"], err = replaceToken(credsMap["refreshToken"], refreshToken)
+	if err != nil {
+		return fmt.Errorf("could not encrypt the new refresh token: %w", err)
+	}
+
+	return nil
+}
+
+// checkTokensWritable returns an error when the refresh token of the connection of the playbook data is a secret
+// reference. TPP invalidates the refresh token it rotates, and the new one cannot be written back to the secret store,
+// so refreshing it would leave the playbook with no valid refresh token
+func checkTokensWritable(playbook map[string]interface{}, name string) error {
+	credsMap, err := connectionCredentials(playbook, name)
+	if err != nil {
+		return err
+	}
+	if ref, ok := credsMap["refreshToken"].(string); ok && secret.IsRef(ref) {
+		return fmt.Errorf("refresh token of the connection is the secret reference %s: the refreshed tokens cannot be written back to the secret store", ref)
+	}
+	return nil
+}
+
+// connectionCredentials returns the credentials of the connection of the playbook data. When config.connection is a
+// list, the credentials of the connection named name are returned
+func connectionCredentials(playbook map[string]interface{}, name string) (map[string]interface{}, error) {
 	if playbook == nil {
-		return fmt.Errorf("playbook data is nil")
+		return nil, fmt.Errorf("playbook data is nil")
 	}
 	cfg, found := playbook["config"]
 	if !found {
-		return fmt.Errorf("no config found in Playbook data")
+		return nil, fmt.Errorf("no config found in Playbook data")
 	}
 
 	conn, found := cfg.(map[string]interface{})["connection"]
 	if !found {
-		return fmt.Errorf("no connection found in Playbook data")
+		return nil, fmt.Errorf("no connection found in Playbook data")
+	}
+	if list, ok := conn.([]interface{}); ok {
+		conn = nil
+		for _, item := range list {
+			if c, ok := item.(map[string]interface{}); ok && c["name"] == name {
+				conn = c
+			}
+		}
+		if conn == nil {
+			return nil, fmt.Errorf("no connection %s found in Playbook data", name)
+		}
 	}
 
 	creds, found := conn.(map[string]interface{})["credentials"]
 	if !found {
-		return fmt.Errorf("no credentials found in Playbook data")
+		return nil, fmt.Errorf("no credentials found in Playbook data")
 	}
 
-	credsMap := creds.(map[string]interface{})
-	credsMap["accessToken"] = accessToken
-	credsMap["refreshToken"] = refreshToken
+	return creds.(map[string]interface{}), nil
+}
 
-	return nil
+// replaceToken returns token encrypted with the same key as old when old was tagged !encrypted in the playbook
+func replaceToken(old interface{}, token string) (interface{}, error) {
+	if encrypted, ok := old.(secret.Encrypted); ok {
+		return encrypted.Replace(token)
+	}
+	return token, nil
 }
@@ -0,0 +1,106 @@
+/*
+ * Copyright 2023 Venafi, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *  http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package service
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestReplaceTokensInFile(t *testing.T) {
+	tests := []struct {
+		name            string
+		accessToken     interface{}
+		refreshToken    interface{}
+		expectedAccess  interface{}
+		expectedRefresh interface{}
+		expectErr       bool
+	}{
+		{
+			name:            "plaintext tokens",
+			accessToken:     "access-1",
+			refreshToken:    "refresh-1",
+			expectedAccess:  "access-2",
+			expectedRefresh: "refresh-2",
+		},
+		{
+			name:            "access token secret reference is kept",
+			accessToken:     "secretRef:vault:secret/data/tpp#accessToken",
+			refreshToken:    "refresh-1",
+			expectedAccess:  "secretRef:vault:secret/data/tpp#accessToken",
+			expectedRefresh: "refresh-2",
+		},
+		{
+			name:            "refresh token secret reference",
+			accessToken:     "access-1",
+			refreshToken:    "secretRef:vault:secret/data/tpp#refreshToken",
+			expectedAccess:  "access-1",
+			expectedRefresh: "secretRef:vault:secret/data/tpp#refreshToken",
+			expectErr:       true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			credentials := map[string]interface{}{
+				"accessToken":  tt.accessToken,
+				"refreshToken": tt.refreshToken,
+			}
+			playbook := map[string]interface{}{
+				"config": map[string]interface{}{
+					"connection": map[string]interface{}{
+						"platform":    "tpp",
+						"credentials": credentials,
+					},
+				},
+			}
+
+			err := replaceTokensInFile(playbook, "", "access-2", "refresh-2")
+			if tt.expectErr {
+				assert.Error(t, err)
+			} else {
+				require.NoError(t, err)
+			}
+			assert.Equal(t, tt.expectedAccess, credentials["accessToken"])
+			assert.Equal(t, tt.expectedRefresh, credentials["refreshToken"])
+		})
+	}
+}
+
+func TestCheckTokensWritable(t *testing.T) {
+	playbook := map[string]interface{}{
+		"config": map[string]interface{}{
+			"connection": []interface{}{
+				map[string]interface{}{
+					"name":        "plain",
+					"credentials": map[string]interface{}{"refreshToken": "refresh-1"},
+				},
+				map[string]interface{}{
+					"name":        "vault",
+					"credentials": map[string]interface{}{"refreshToken": "secretRef:vault:secret/data/tpp#refreshToken"},
+				},
+			},
+		},
+	}
+
+	assert.NoError(t, checkTokensWritable(playbook, "plain"))
+	err := checkTokensWritable(playbook, "vault")
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "secretRef:vault:secret/data/tpp#refreshToken")
+}
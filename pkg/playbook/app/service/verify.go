@@ -0,0 +1,115 @@
+/*
+ * Copyright 2023 Venafi, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *  http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package service
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/hex"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"net"
+	"time"
+
+	"go.uber.org/zap"
+
+	"github.com/Venafi/vcert/v5/pkg/playbook/app/domain"
+)
+
+const (
+	// verifyTLSAttempts is the number of handshakes made before the verification fails.
+	// Servers may take a few seconds to pick up the new certificate after being reloaded
+	verifyTLSAttempts = 5
+	verifyTLSDelay    = 2 * time.Second
+	verifyTLSTimeout  = 10 * time.Second
+)
+
+var errServedCertificateMismatch = errors.New("the endpoint does not serve the installed certificate")
+
+// verifyServedCertificate makes a TLS handshake against installation.VerifyTLS and checks that the leaf certificate
+// served is certPEM. The handshake is retried a few times, so the server has time to load the new certificate
+func verifyServedCertificate(ctx context.Context, logger *zap.Logger, installation domain.Installation, certPEM string) error {
+	block, _ := pem.Decode([]byte(certPEM))
+	if block == nil {
+		return fmt.Errorf("installed certificate is not a valid PEM block")
+	}
+	expected, err := x509.ParseCertificate(block.Bytes)
+	if err != nil {
+		return fmt.Errorf("failed to parse installed certificate: %w", err)
+	}
+
+	serverName := installation.VerifyTLSServerName
+	if serverName == "" {
+		serverName, _, _ = net.SplitHostPort(installation.VerifyTLS)
+	}
+
+	for attempt := 1; ; attempt++ {
+		var served *x509.Certificate
+		served, err = fetchServedCertificate(ctx, installation.VerifyTLS, serverName)
+		if err == nil {
+			if bytes.Equal(served.Raw, expected.Raw) {
+				logger.Info("endpoint serves the installed certificate", zap.String("verifyTLS", installation.VerifyTLS),
+					zap.String("serial", expected.SerialNumber.Text(16)), zap.String("fingerprint", fingerprint(expected)))
+				return nil
+			}
+			err = fmt.Errorf("%w: expected serial %s (SHA-256 %s), got serial %s (SHA-256 %s)", errServedCertificateMismatch,
+				expected.SerialNumber.Text(16), fingerprint(expected), served.SerialNumber.Text(16), fingerprint(served))
+		}
+		if attempt >= verifyTLSAttempts {
+			return err
+		}
+		logger.Debug("endpoint verification failed, retrying", zap.String("verifyTLS", installation.VerifyTLS),
+			zap.Int("attempt", attempt), zap.Error(err))
+		if sleepErr := sleep(ctx, verifyTLSDelay); sleepErr != nil {
+			return err
+		}
+	}
+}
+
+// fetchServedCertificate returns the leaf certificate presented by the TLS server at address
+func fetchServedCertificate(ctx context.Context, address string, serverName string) (*x509.Certificate, error) {
+	dialer := &tls.Dialer{
+		NetDialer: &net.Dialer{Timeout: verifyTLSTimeout},
+		Config: &tls.Config{
+			ServerName: serverName,
+			// The certificate is compared with the installed one, so it does not need to be trusted
+			InsecureSkipVerify: true, // #nosec G402
+		},
+	}
+	conn, err := dialer.DialContext(ctx, "tcp", address)
+	if err != nil {
+		return nil, err
+	}
+	defer func() {
+		_ = conn.Close()
+	}()
+
+	certs := conn.(*tls.Conn).ConnectionState().PeerCertificates
+	if len(certs) == 0 {
+		return nil, fmt.Errorf("no certificate served by %s", address)
+	}
+	return certs[0], nil
+}
+
+func fingerprint(cert *x509.Certificate) string {
+	sum := sha256.Sum256(cert.Raw)
+	return hex.EncodeToString(sum[:])
+}
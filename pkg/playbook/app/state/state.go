@@ -0,0 +1,169 @@
+/*
+ * Copyright 2023 Venafi, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *  http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+// Package state persists the certificates issued and revoked by the playbook tasks, and the pickup IDs of the
+// requests still waiting to be retrieved, so they survive across runs
+package state
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"gopkg.in/yaml.v3"
+)
+
+const (
+	// StatusPending is the status of a task whose certificate was requested but not retrieved yet
+	StatusPending = "pending"
+	// StatusIssued is the status of a task whose certificate was retrieved
+	StatusIssued = "issued"
+	// StatusRevoked is the status of a revoke task whose certificate was revoked
+	StatusRevoked = "revoked"
+)
+
+// TaskState is what is known about the last certificate requested by a task
+type TaskState struct {
+	// Status is either StatusPending or StatusIssued
+	Status string `json:"status" yaml:"status"`
+	// PickupID identifies the certificate request in the Venafi platform
+	PickupID string `json:"pickupId,omitempty" yaml:"pickupId,omitempty"`
+	// Zone is the zone the certificate was requested in, out of the zones of the task
+	Zone string `json:"zone,omitempty" yaml:"zone,omitempty"`
+	// RequestedAt is the time the certificate was requested
+	RequestedAt *time.Time `json:"requestedAt,omitempty" yaml:"requestedAt,omitempty"`
+	// IssuedAt is the time the certificate was retrieved
+	IssuedAt *time.Time `json:"issuedAt,omitempty" yaml:"issuedAt,omitempty"`
+	// Serial is the serial number of the certificate, in decimal
+	Serial string `json:"serial,omitempty" yaml:"serial,omitempty"`
+	// Thumbprint is the SHA-1 thumbprint of the certificate
+	Thumbprint string `json:"thumbprint,omitempty" yaml:"thumbprint,omitempty"`
+	// NotAfter is the expiration date of the certificate
+	NotAfter *time.Time `json:"notAfter,omitempty" yaml:"notAfter,omitempty"`
+	// RenewAt is the date from which the certificate is renewed, according to the renewBefore of the task.
+	// It is not set when automatic renewal is disabled
+	RenewAt *time.Time `json:"renewAt,omitempty" yaml:"renewAt,omitempty"`
+	// RevokedAt is the time the certificate was revoked
+	RevokedAt *time.Time `json:"revokedAt,omitempty" yaml:"revokedAt,omitempty"`
+	// Adopted is true when the certificate was found installed and adopted from the Venafi platform, instead of
+	// being requested by the task. Renewals of adopted certificates renew the certificate found in the platform
+	Adopted bool `json:"adopted,omitempty" yaml:"adopted,omitempty"`
+	// Installed maps the location of every installation of the task to the SHA-1 thumbprint of the certificate
+	// installed there, so a certificate replaced out of band is detected by the next run
+	Installed map[string]string `json:"installed,omitempty" yaml:"installed,omitempty"`
+}
+
+// State holds the TaskState of every task, by task name. It is safe for concurrent use
+type State struct {
+	Tasks map[string]TaskState `json:"tasks" yaml:"tasks"`
+
+	mu   sync.Mutex
+	path string
+}
+
+// Load reads the state file at path. The file is written as YAML when its extension is .yaml or .yml,
+// and as JSON otherwise. An empty State is returned when the file does not exist yet
+func Load(path string) (*State, error) {
+	s := &State{
+		Tasks: make(map[string]TaskState),
+		path:  path,
+	}
+
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return s, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read state file: %w", err)
+	}
+
+	if s.isYAML() {
+		err = yaml.Unmarshal(data, s)
+	} else {
+		err = json.Unmarshal(data, s)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse state file %s: %w", path, err)
+	}
+	if s.Tasks == nil {
+		s.Tasks = make(map[string]TaskState)
+	}
+	return s, nil
+}
+
+// Task returns the state recorded for the task name, if any
+func (s *State) Task(name string) (TaskState, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	t, found := s.Tasks[name]
+	return t, found
+}
+
+// SetTask records the state of the task name and saves the state file
+func (s *State) SetTask(name string, t TaskState) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.Tasks[name] = t
+	return s.save()
+}
+
+// save writes the state to a temporary file first, so the state file is never left half written
+func (s *State) save() error {
+	var data []byte
+	var err error
+	if s.isYAML() {
+		data, err = yaml.Marshal(s)
+	} else {
+		data, err = json.MarshalIndent(s, "", "  ")
+	}
+	if err != nil {
+		return fmt.Errorf("failed to serialize state: %w", err)
+	}
+
+	tmp, err := os.CreateTemp(filepath.Dir(s.path), filepath.Base(s.path)+".*.tmp")
+	if err != nil {
+		return fmt.Errorf("failed to write state file: %w", err)
+	}
+	defer func() {
+		_ = os.Remove(tmp.Name())
+	}()
+
+	_, err = tmp.Write(data)
+	if closeErr := tmp.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		return fmt.Errorf("failed to write state file: %w", err)
+	}
+
+	err = os.Rename(tmp.Name(), s.path)
+	if err != nil {
+		return fmt.Errorf("failed to write state file: %w", err)
+	}
+	return nil
+}
+
+func (s *State) isYAML() bool {
+	ext := strings.ToLower(filepath.Ext(s.path))
+	return ext == ".yaml" || ext == ".yml"
+}
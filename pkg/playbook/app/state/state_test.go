@@ -0,0 +1,64 @@
+/*
+ * Copyright 2023 Venafi, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *  http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package state
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestState(t *testing.T) {
+	for _, name := range []string{"state.json", "state.yaml"} {
+		t.Run(name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), name)
+
+			s, err := Load(path)
+			require.NoError(t, err)
+			_, found := s.Task("myTask")
+			assert.False(t, found)
+
+			requested := time.Date(2023, 10, 1, 12, 0, 0, 0, time.UTC)
+			err = s.SetTask("myTask", TaskState{Status: StatusPending, PickupID: `\VED\Policy\cert`, RequestedAt: &requested})
+			require.NoError(t, err)
+
+			notAfter := requested.Add(90 * 24 * time.Hour)
+			err = s.SetTask("otherTask", TaskState{Status: StatusIssued, Serial: "1234", Thumbprint: "ABCD", NotAfter: &notAfter})
+			require.NoError(t, err)
+
+			loaded, err := Load(path)
+			require.NoError(t, err)
+			assert.Equal(t, s.Tasks, loaded.Tasks)
+
+			entries, err := os.ReadDir(filepath.Dir(path))
+			require.NoError(t, err)
+			assert.Len(t, entries, 1, "temporary files should be removed")
+		})
+	}
+}
+
+func TestLoadInvalidState(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "state.json")
+	require.NoError(t, os.WriteFile(path, []byte("not json"), 0600))
+
+	_, err := Load(path)
+	assert.Error(t, err)
+}
@@ -19,6 +19,7 @@ package vcertutil
 import (
 	"encoding/pem"
 	"fmt"
+	"io"
 	"net"
 	"net/url"
 	"os"
@@ -44,6 +45,7 @@ const (
 	OriginName = "Venafi VCert Playbook"
 
 	userProvidedCSRPrefix = "file:"
+	csrFromStdin          = "-"
 )
 
 func loadTrustBundle(path string) string {
@@ -97,6 +99,8 @@ func setKeyType(request domain.PlaybookRequest, vcertRequest *certificate.Reques
 	case certificate.KeyTypeED25519:
 		vcertRequest.KeyType = request.KeyType
 		vcertRequest.KeyCurve = certificate.EllipticCurveED25519
+	case certificate.KeyTypeMLDSA44, certificate.KeyTypeMLDSA65, certificate.KeyTypeMLDSA87:
+		vcertRequest.KeyType = request.KeyType
 	default:
 		vcertRequest.KeyType = certificate.KeyTypeRSA
 		vcertRequest.KeyLength = DefaultRSALength
@@ -118,6 +122,18 @@ func setOrigin(request domain.PlaybookRequest, vcertRequest *certificate.Request
 }
 
 func setValidity(request domain.PlaybookRequest, vcertRequest *certificate.Request) {
+	if request.ValidityHours > 0 {
+		validity := time.Duration(request.ValidityHours) * time.Hour
+		vcertRequest.ValidityDuration = &validity
+	}
+	// The request is validated before it is built, so NotAfter is known to be valid
+	if notAfter, _ := request.GetNotAfter(); notAfter != nil {
+		vcertRequest.NotAfter = notAfter
+	}
+	if request.IssuerHint != util.IssuerHintGeneric {
+		vcertRequest.IssuerHint = request.IssuerHint
+	}
+
 	if request.ValidDays == "" {
 		return
 	}
@@ -184,27 +200,30 @@ func setTimeout(playbookRequest domain.PlaybookRequest, vcertRequest *certificat
 	vcertRequest.Timeout = time.Duration(timeout) * time.Second
 }
 
-func setCSR(playbookRequest domain.PlaybookRequest, vcertRequest *certificate.Request) {
+func setCSR(playbookRequest domain.PlaybookRequest, vcertRequest *certificate.Request) error {
 	vcertRequest.CsrOrigin = certificate.LocalGeneratedCSR
 
-	//CSR is user provided. Load CSR from file
-	if strings.HasPrefix(playbookRequest.CsrOrigin, userProvidedCSRPrefix) {
-		file := playbookRequest.CsrOrigin[len(userProvidedCSRPrefix):]
+	// CSR is user provided. Load CSR from csrFile or, for backwards compatibility, from the path after "file:".
+	// Falling back to a local CSR would generate a private key vcert should not have, so errors are returned
+	file := ""
+	if strings.ToLower(playbookRequest.CsrOrigin) == certificate.StrUserProvidedCSR {
+		file = playbookRequest.CsrFile
+	} else if strings.HasPrefix(playbookRequest.CsrOrigin, userProvidedCSRPrefix) {
+		file = playbookRequest.CsrOrigin[len(userProvidedCSRPrefix):]
+	}
+
+	if file != "" {
 		csr, err := readCSRFromFile(file)
 		if err != nil {
-			zap.L().Warn("failed to read CSR from file", zap.String("file", file), zap.Error(err))
-			vcertRequest.CsrOrigin = certificate.LocalGeneratedCSR
-			return
+			return fmt.Errorf("failed to read CSR from file %s: %w", file, err)
 		}
 		err = vcertRequest.SetCSR(csr)
 		if err != nil {
-			zap.L().Warn("failed to set CSR", zap.Error(err))
-			vcertRequest.CsrOrigin = certificate.LocalGeneratedCSR
-			return
+			return fmt.Errorf("failed to set CSR from file %s: %w", file, err)
 		}
 
 		vcertRequest.CsrOrigin = certificate.UserProvidedCSR
-		return
+		return nil
 	}
 
 	origin := certificate.ParseCSROrigin(playbookRequest.CsrOrigin)
@@ -213,10 +232,18 @@ func setCSR(playbookRequest domain.PlaybookRequest, vcertRequest *certificate.Re
 	} else {
 		vcertRequest.CsrOrigin = origin
 	}
+	return nil
 }
 
+// readCSRFromFile returns the first PEM encoded CSR found in fileName. The CSR is read from stdin when fileName is "-"
 func readCSRFromFile(fileName string) ([]byte, error) {
-	bytes, err := os.ReadFile(fileName)
+	var bytes []byte
+	var err error
+	if fileName == csrFromStdin {
+		bytes, err = io.ReadAll(os.Stdin)
+	} else {
+		bytes, err = os.ReadFile(fileName)
+	}
 	if err != nil {
 		return nil, err
 	}
@@ -0,0 +1,118 @@
+/*
+ * Copyright 2023 Venafi, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *  http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package vcertutil
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"go.uber.org/zap"
+
+	"github.com/Venafi/vcert/v5/pkg/certificate"
+	"github.com/Venafi/vcert/v5/pkg/playbook/app/domain"
+	"github.com/Venafi/vcert/v5/pkg/util"
+)
+
+// sshRetrieveTimeout is the time to wait for TPP to issue an SSH certificate that is pending issue
+const sshRetrieveTimeout = 180 * time.Second
+
+// EnrollSSHCertificate generates an RSA key pair and requests an SSH certificate for its public key to the TPP
+// SSH CA template defined by request.
+//
+// Returns the certificate along with the private key, encrypted with request.KeyPassphrase when set, and the
+// public key, all of them in OpenSSH formats
+func EnrollSSHCertificate(ctx context.Context, config domain.Config, request domain.SSHRequest) (*certificate.SshCertificateObject, error) {
+	client, err := buildClient(ctx, config, "")
+	if err != nil {
+		return nil, err
+	}
+	// The SSH requests reuse the HTTP client created by the first request to TPP
+	err = client.Ping(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	privateKey, publicKey, err := util.GenerateSshKeyPair(request.GetKeySize(), request.KeyPassphrase, request.KeyID, util.LegacyPem)
+	if err != nil {
+		return nil, fmt.Errorf("failed to generate SSH key pair: %w", err)
+	}
+
+	objectName := request.ObjectName
+	if objectName == "" {
+		objectName = request.KeyID
+	}
+	sshRequest := &certificate.SshCertRequest{
+		Template:             request.Template,
+		PolicyDN:             request.Folder,
+		ObjectName:           objectName,
+		DestinationAddresses: request.DestinationAddresses,
+		KeyId:                request.KeyID,
+		Principals:           request.Principals,
+		PublicKeyData:        string(publicKey),
+		Extensions:           request.Extensions,
+		ForceCommand:         request.ForceCommand,
+		SourceAddresses:      request.SourceAddresses,
+	}
+	if request.ValidHours > 0 {
+		sshRequest.ValidityPeriod = strconv.Itoa(request.ValidHours) + "h"
+	}
+
+	data, err := client.RequestSSHCertificate(ctx, sshRequest)
+	if err != nil {
+		return nil, err
+	}
+	zap.L().Debug("successfully requested SSH certificate", zap.String("pickupID", data.DN))
+
+	// 'Rejected' status is handled by the connector
+	if data.ProcessingDetails.Status == "Pending Issue" || data.CertificateData == "" {
+		data, err = client.RetrieveSSHCertificate(ctx, &certificate.SshCertRequest{
+			PickupID:                  data.DN,
+			IncludeCertificateDetails: true,
+			Timeout:                   sshRetrieveTimeout,
+		})
+		if err != nil {
+			return nil, fmt.Errorf("failed to retrieve SSH certificate: %w", err)
+		}
+	}
+	zap.L().Debug("successfully retrieved SSH certificate", zap.String("keyId", data.CertificateDetails.KeyID))
+
+	// The key pair is generated locally, so TPP returns no private key
+	data.PrivateKeyData = string(privateKey)
+	data.PublicKeyData = string(publicKey)
+	return data, nil
+}
+
+// RetrieveSSHCAPublicKey returns the public key of the CA of the TPP SSH CA template, in authorized_keys format
+func RetrieveSSHCAPublicKey(ctx context.Context, config domain.Config, template string) (string, error) {
+	client, err := buildClient(ctx, config, "")
+	if err != nil {
+		return "", err
+	}
+
+	sshConfig, err := client.RetrieveSshConfig(ctx, &certificate.SshCaTemplateRequest{Template: template})
+	if err != nil {
+		return "", err
+	}
+	caPublicKey := strings.TrimSpace(sshConfig.CaPublicKey)
+	if caPublicKey == "" {
+		return "", fmt.Errorf("no public key found for SSH CA template %s", template)
+	}
+	return caPublicKey, nil
+}
@@ -17,6 +17,7 @@
 package vcertutil
 
 import (
+	"context"
 	"crypto/rand"
 	"crypto/x509/pkix"
 	"errors"
@@ -28,49 +29,163 @@ import (
 	"github.com/Venafi/vcert/v5"
 	"github.com/Venafi/vcert/v5/pkg/certificate"
 	"github.com/Venafi/vcert/v5/pkg/endpoint"
+	"github.com/Venafi/vcert/v5/pkg/pkcs11"
 	"github.com/Venafi/vcert/v5/pkg/playbook/app/domain"
+	"github.com/Venafi/vcert/v5/pkg/playbook/util/dns"
+	"github.com/Venafi/vcert/v5/pkg/tpm"
 	"github.com/Venafi/vcert/v5/pkg/util"
+	"github.com/Venafi/vcert/v5/pkg/venafi"
+	"github.com/Venafi/vcert/v5/pkg/venafi/acme"
 	"github.com/Venafi/vcert/v5/pkg/venafi/tpp"
 )
 
+// Enrollment tracks the certificate request made by EnrollCertificateResumable
+type Enrollment struct {
+	// PickupID is the ID of a certificate request made by a previous run. When set, the certificate is
+	// retrieved with it instead of being requested again
+	PickupID string
+	// RenewID is the ID of a certificate in the Venafi platform to renew. When set, the certificate is requested
+	// as a renewal of it, so the platform keeps managing it under the same ID
+	RenewID string
+	// OnRequested is called with the ID of the certificate request as soon as it is made, before the
+	// certificate is retrieved
+	OnRequested func(pickupID string)
+}
+
 // EnrollCertificate takes a Request object and requests a certificate to the Venafi platform defined by config.
 //
 // Then it retrieves the certificate and returns it along with the certificate chain and the private key used.
-func EnrollCertificate(config domain.Config, request domain.PlaybookRequest) (*certificate.PEMCollection, *certificate.Request, error) {
-	client, err := buildClient(config, request.Zone)
+// Cancelling ctx aborts the requests to the Venafi platform, and the wait for the certificate to be issued.
+func EnrollCertificate(ctx context.Context, config domain.Config, request domain.PlaybookRequest) (*certificate.PEMCollection, *certificate.Request, error) {
+	return EnrollCertificateResumable(ctx, config, request, Enrollment{})
+}
+
+// EnrollCertificateResumable works like EnrollCertificate, but retrieves the certificate requested by a previous
+// run when enrollment.PickupID is set, and reports the ID of new certificate requests to enrollment.OnRequested
+func EnrollCertificateResumable(ctx context.Context, config domain.Config, request domain.PlaybookRequest, enrollment Enrollment) (*certificate.PEMCollection, *certificate.Request, error) {
+	client, err := buildClient(ctx, config, request.Zone)
 	if err != nil {
 		return nil, nil, err
 	}
 
-	vRequest := buildRequest(request)
+	vRequest, err := buildRequest(request)
+	if err != nil {
+		return nil, nil, err
+	}
+	if request.PKCS11 != nil {
+		provider, err := pkcs11.NewKeyProvider(*request.PKCS11)
+		if err != nil {
+			return nil, nil, err
+		}
+		defer func() {
+			_ = provider.Close()
+		}()
+		vRequest.KeyProvider = provider
+		zap.L().Debug("private key will be generated in PKCS#11 token", zap.String("module", request.PKCS11.Module))
+
+		if request.ReuseKey && request.PublicKey != nil {
+			key, err := provider.FindKey(&vRequest, request.PublicKey)
+			if err != nil {
+				return nil, nil, err
+			}
+			if key != nil {
+				vRequest.SetPrivateKey(key)
+				zap.L().Debug("reusing private key stored in PKCS#11 token", zap.String("module", request.PKCS11.Module))
+			} else {
+				zap.L().Warn("private key of the installed certificate not found in PKCS#11 token. A new private key will be generated")
+			}
+		}
+	}
+	var tpmProvider *tpm.KeyProvider
+	if request.TPM != nil {
+		tpmProvider, err = tpm.NewKeyProvider(*request.TPM)
+		if err != nil {
+			return nil, nil, err
+		}
+		defer func() {
+			_ = tpmProvider.Close()
+		}()
+		vRequest.KeyProvider = tpmProvider
+		zap.L().Debug("private key will be generated in TPM")
+
+		if request.ReuseKey && request.PublicKey != nil {
+			key, err := tpmProvider.FindKey(&vRequest, request.PublicKey)
+			if err != nil {
+				return nil, nil, err
+			}
+			if key != nil {
+				vRequest.SetPrivateKey(key)
+				zap.L().Debug("reusing private key stored in TPM")
+			} else {
+				zap.L().Warn("private key of the installed certificate not found in TPM. A new private key will be generated")
+			}
+		}
+	}
+	if request.PrivateKey != nil {
+		vRequest.SetPrivateKey(request.PrivateKey)
+		zap.L().Debug("reusing installed private key")
+	}
 
-	zoneCfg, err := client.ReadZoneConfiguration()
+	zoneCfg, err := client.ReadZoneConfiguration(ctx)
 	if err != nil {
 		return nil, nil, err
 	}
 	zap.L().Debug("successfully read zone config", zap.String("zone", request.Zone))
 
-	err = client.GenerateRequest(zoneCfg, &vRequest)
+	err = client.GenerateRequest(ctx, zoneCfg, &vRequest)
 	if err != nil {
 		return nil, nil, err
 	}
 	zap.L().Debug("successfully updated Request with zone config values")
 
+	// The attestation travels in a custom field, which only TPP receives
+	if tpmProvider != nil && request.TPM.AttestationField != "" && enrollment.PickupID == "" {
+		if client.GetType() == endpoint.ConnectorTypeTPP {
+			err = tpmProvider.AddAttestation(&vRequest, vRequest.PrivateKey)
+			if err != nil {
+				return nil, nil, err
+			}
+			zap.L().Debug("added TPM key attestation to request", zap.String("field", request.TPM.AttestationField))
+		} else {
+			zap.L().Warn("TPM key attestation is only sent to TPP. The certificate is requested without it")
+		}
+	}
+
 	var pcc *certificate.PEMCollection
 
-	if client.SupportSynchronousRequestCertificate() {
-		pcc, err = client.SynchronousRequestCertificate(&vRequest)
-	} else {
-		reqID, reqErr := client.RequestCertificate(&vRequest)
+	switch {
+	case enrollment.PickupID != "":
+		zap.L().Debug("retrieving certificate requested by a previous run", zap.String("requestID", enrollment.PickupID))
+		vRequest.PickupID = enrollment.PickupID
+		vRequest.Timeout = 180 * time.Second
+
+		pcc, err = client.RetrieveCertificate(ctx, &vRequest)
+	case client.SupportSynchronousRequestCertificate():
+		pcc, err = client.SynchronousRequestCertificate(ctx, &vRequest)
+	default:
+		var reqID string
+		var reqErr error
+		if enrollment.RenewID != "" {
+			zap.L().Debug("renewing certificate", zap.String("certificateID", enrollment.RenewID))
+			reqID, reqErr = client.RenewCertificate(ctx, &certificate.RenewalRequest{
+				CertificateDN:      enrollment.RenewID,
+				CertificateRequest: &vRequest,
+			})
+		} else {
+			reqID, reqErr = client.RequestCertificate(ctx, &vRequest)
+		}
 		if reqErr != nil {
 			return nil, nil, reqErr
 		}
 		zap.L().Debug("successfully requested certificate", zap.String("requestID", reqID))
+		if enrollment.OnRequested != nil {
+			enrollment.OnRequested(reqID)
+		}
 
 		vRequest.PickupID = reqID
 		vRequest.Timeout = 180 * time.Second
 
-		pcc, err = client.RetrieveCertificate(&vRequest)
+		pcc, err = client.RetrieveCertificate(ctx, &vRequest)
 	}
 
 	if err != nil {
@@ -81,7 +196,83 @@ func EnrollCertificate(config domain.Config, request domain.PlaybookRequest) (*c
 	return pcc, &vRequest, nil
 }
 
-func buildClient(config domain.Config, zone string) (endpoint.Connector, error) {
+// FindCertificate returns the pickup ID of the certificate with the SHA-1 thumbprint in the Venafi platform defined by config
+func FindCertificate(ctx context.Context, config domain.Config, zone string, thumbprint string) (string, error) {
+	client, err := buildClient(ctx, config, zone)
+	if err != nil {
+		return "", err
+	}
+
+	// The connectors fill the pickup ID of the certificate they find by thumbprint, before downloading it
+	req := &certificate.Request{
+		Thumbprint:  thumbprint,
+		ChainOption: certificate.ChainOptionIgnore,
+		Timeout:     30 * time.Second,
+	}
+	_, err = client.RetrieveCertificate(ctx, req)
+	if req.PickupID == "" {
+		if err == nil {
+			// VaaS finds certificates imported without a certificate request, which cannot be renewed by ID
+			err = fmt.Errorf("certificate with thumbprint %s has no certificate request in the Venafi platform", thumbprint)
+		}
+		return "", err
+	}
+	return req.PickupID, nil
+}
+
+// RevokeCertificate revokes the certificate identified by request in the Venafi platform defined by config.
+// A certificate identified by its serial number is searched first, as the platform revokes certificates by DN or thumbprint.
+// VaaS does not revoke certificates, so the certificate is retired instead
+func RevokeCertificate(ctx context.Context, config domain.Config, zone string, request domain.RevokeRequest) error {
+	client, err := buildClient(ctx, config, zone)
+	if err != nil {
+		return err
+	}
+
+	if client.GetType() == endpoint.ConnectorTypeCloud {
+		return client.RetireCertificate(ctx, &certificate.RetireRequest{
+			CertificateDN:  request.PickupID,
+			Thumbprint:     request.Thumbprint,
+			Description:    request.Comments,
+			AddToBlocklist: request.Disable,
+		})
+	}
+
+	revReq := &certificate.RevocationRequest{
+		CertificateDN: request.PickupID,
+		Thumbprint:    request.Thumbprint,
+		Reason:        request.GetReason(),
+		Comments:      request.Comments,
+		Disable:       request.Disable,
+	}
+	if request.Serial != "" {
+		revReq.CertificateDN, err = searchCertificateBySerial(ctx, client, request.GetSerial())
+		if err != nil {
+			return err
+		}
+		zap.L().Debug("found certificate by serial number", zap.String("serial", request.GetSerial()),
+			zap.String("pickupID", revReq.CertificateDN))
+	}
+
+	return client.RevokeCertificate(ctx, revReq)
+}
+
+// searchCertificateBySerial returns the DN of the only certificate with the serial number
+func searchCertificateBySerial(ctx context.Context, client endpoint.Connector, serial string) (string, error) {
+	result, err := client.SearchCertificates(ctx, &certificate.SearchRequest{"Serial=" + serial})
+	if err != nil {
+		return "", fmt.Errorf("failed to search certificate with serial number %s: %w", serial, err)
+	}
+	if len(result.Certificates) == 0 {
+		return "", fmt.Errorf("no certificate found with serial number %s", serial)
+	}
+	if len(result.Certificates) > 1 {
+		return "", fmt.Errorf("more than one certificate found with serial number %s", serial)
+	}
+	return result.Certificates[0].CertificateRequestId, nil
+}
+
+func buildClient(ctx context.Context, config domain.Config, zone string) (endpoint.Connector, error) {
 	vConfig := &vcert.Config{
 		ConnectorType: config.Connection.GetConnectorType(),
 		BaseUrl:       config.Connection.URL,
@@ -95,21 +286,59 @@ func buildClient(config domain.Config, zone string) (endpoint.Connector, error)
 		},
 		ConnectionTrust: loadTrustBundle(config.Connection.TrustBundlePath),
 		LogVerbose:      false,
+		RetryPolicy:     config.Connection.Retry,
+		Proxy:           config.Connection.Proxy,
+		Transport:       config.Connection.Transport,
+		TLSConfig:       config.Connection.TLSConfig,
+		ZoneCache:       getZoneCache(config.Connection.ZoneCacheTTL),
 	}
 
 	if config.Connection.Credentials.IdentityProvider != nil {
 		vConfig.Credentials.IdentityProvider = config.Connection.Credentials.IdentityProvider
 	}
 
-	client, err := vcert.NewClient(vConfig)
+	var solver acme.Solver
+	if config.Connection.Platform == venafi.ACME {
+		vConfig.Credentials.ACMEAccount = config.Connection.Credentials.ACMEAccount
+		vConfig.ACMEChallenge = config.Connection.ACMEChallenge
+
+		if config.Connection.DNSProvider != nil {
+			provider, err := dns.NewProvider(*config.Connection.DNSProvider)
+			if err != nil {
+				return nil, fmt.Errorf("could not create DNS provider: %w", err)
+			}
+			var wait time.Duration
+			if vConfig.ACMEChallenge != nil {
+				wait = vConfig.ACMEChallenge.DNSPropagationWait
+			}
+			solver = dns.NewSolver(provider, wait)
+			// The connector is created with the default solver, replaced by the DNS provider one
+			vConfig.ACMEChallenge = nil
+		}
+	}
+
+	if config.Connection.Platform == venafi.EST {
+		vConfig.Credentials.User = config.Connection.Credentials.User
+		vConfig.Credentials.Password = config.Connection.Credentials.Password
+	}
+	if config.Connection.Platform == venafi.SCEP {
+		// The password is the challenge password added to the CSR
+		vConfig.Credentials.Password = config.Connection.Credentials.Password
+	}
+
+	client, err := vcert.NewClientContext(ctx, vConfig)
 	if err != nil {
 		return nil, err
 	}
 
+	if acmeConnector, ok := client.(*acme.Connector); ok && solver != nil {
+		acmeConnector.SetSolver(solver)
+	}
+
 	return client, nil
 }
 
-func buildRequest(request domain.PlaybookRequest) certificate.Request {
+func buildRequest(request domain.PlaybookRequest) (certificate.Request, error) {
 
 	vcertRequest := certificate.Request{
 		CADN: request.CADN,
@@ -131,6 +360,8 @@ func buildRequest(request domain.PlaybookRequest) certificate.Request {
 		ChainOption:    request.ChainOption,
 		KeyPassword:    request.KeyPassword,
 		CustomFields:   request.CustomFields,
+		ApplicationDNs: request.ApplicationDNs,
+		RetrievePKCS12: request.IsPKCS12Keystore(),
 	}
 
 	// Set timeout for cert retrieval
@@ -144,17 +375,17 @@ func buildRequest(request domain.PlaybookRequest) certificate.Request {
 	//Set Validity
 	setValidity(request, &vcertRequest)
 	//Set CSR
-	setCSR(request, &vcertRequest)
-
-	return vcertRequest
-}
+	err := setCSR(request, &vcertRequest)
+	if err != nil {
+		return vcertRequest, err
+	}
+	//Set Extensions
+	vcertRequest.Extensions, vcertRequest.ExtKeyUsages, err = request.GetExtensions()
+	if err != nil {
+		return vcertRequest, err
+	}
 
-// DecryptPrivateKey takes an encrypted private key and decrypts it using the given password.
-//
-// The private key must be in PKCS8 format.
-func DecryptPrivateKey(privateKey string, password string) (string, error) {
-	privateKey, err := util.DecryptPkcs8PrivateKey(privateKey, password)
-	return privateKey, err
+	return vcertRequest, nil
 }
 
 // EncryptPrivateKeyPKCS1 takes a decrypted PKCS8 private key and encrypts it back in PKCS1 format
@@ -164,7 +395,7 @@ func EncryptPrivateKeyPKCS1(privateKey string, password string) (string, error)
 }
 
 // IsValidAccessToken checks that the accessToken in config is not expired.
-func IsValidAccessToken(config domain.Config) (bool, error) {
+func IsValidAccessToken(ctx context.Context, config domain.Config) (bool, error) {
 	// No access token provided. Use refresh token to get new access token right away
 	if config.Connection.Credentials.AccessToken == "" {
 		return false, fmt.Errorf("an access token was not provided for connection to TPP")
@@ -180,20 +411,23 @@ func IsValidAccessToken(config domain.Config) (bool, error) {
 		},
 		ConnectionTrust: loadTrustBundle(config.Connection.TrustBundlePath),
 		LogVerbose:      false,
+		Proxy:           config.Connection.Proxy,
+		Transport:       config.Connection.Transport,
+		TLSConfig:       config.Connection.TLSConfig,
 	}
 
-	client, err := vcert.NewClient(vConfig, false)
+	client, err := vcert.NewClientContext(ctx, vConfig, false)
 	if err != nil {
 		return false, err
 	}
 
-	_, err = client.(*tpp.Connector).VerifyAccessToken(vConfig.Credentials)
+	_, err = client.(*tpp.Connector).VerifyAccessToken(ctx, vConfig.Credentials)
 
 	return err == nil, err
 }
 
 // RefreshTPPTokens uses the refreshToken in config to request a new pair of tokens
-func RefreshTPPTokens(config domain.Config) (string, string, error) {
+func RefreshTPPTokens(ctx context.Context, config domain.Config) (string, string, error) {
 	vConfig := &vcert.Config{
 		ConnectorType: config.Connection.GetConnectorType(),
 		BaseUrl:       config.Connection.URL,
@@ -203,26 +437,29 @@ func RefreshTPPTokens(config domain.Config) (string, string, error) {
 		},
 		ConnectionTrust: loadTrustBundle(config.Connection.TrustBundlePath),
 		LogVerbose:      false,
+		Proxy:           config.Connection.Proxy,
+		Transport:       config.Connection.Transport,
+		TLSConfig:       config.Connection.TLSConfig,
 	}
 
 	//Creating an empty client
-	client, err := vcert.NewClient(vConfig, false)
+	client, err := vcert.NewClientContext(ctx, vConfig, false)
 	if err != nil {
 		return "", "", err
 	}
 
 	auth := endpoint.Authentication{
 		RefreshToken: config.Connection.Credentials.RefreshToken,
-		ClientPKCS12: config.Connection.Credentials.P12Task != "",
+		ClientPKCS12: config.Connection.Credentials.HasClientCertificate(),
 		Scope:        config.Connection.Credentials.Scope,
 		ClientId:     config.Connection.Credentials.ClientId,
 	}
 
 	if auth.RefreshToken != "" {
-		resp, err := client.(*tpp.Connector).RefreshAccessToken(&auth)
+		resp, err := client.(*tpp.Connector).RefreshAccessToken(ctx, &auth)
 		if err != nil {
 			if auth.ClientPKCS12 {
-				resp, err2 := client.(*tpp.Connector).GetRefreshToken(&auth)
+				resp, err2 := client.(*tpp.Connector).GetRefreshToken(ctx, &auth)
 				if err2 != nil {
 					return "", "", errors.Join(err2, err)
 				}
@@ -233,7 +470,7 @@ func RefreshTPPTokens(config domain.Config) (string, string, error) {
 		return resp.Access_token, resp.Refresh_token, nil
 	} else if auth.ClientPKCS12 {
 		auth.RefreshToken = ""
-		resp, err := client.(*tpp.Connector).GetRefreshToken(&auth)
+		resp, err := client.(*tpp.Connector).GetRefreshToken(ctx, &auth)
 		if err != nil {
 			return "", "", err
 		}
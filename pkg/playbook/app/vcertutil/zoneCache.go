@@ -0,0 +1,54 @@
+/*
+ * Copyright 2023 Venafi, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *  http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package vcertutil
+
+import (
+	"sync"
+	"time"
+
+	"github.com/Venafi/vcert/v5/pkg/endpoint"
+)
+
+// zoneCache is shared by the connectors of every task, so the tasks requesting certificates to the same zone
+// read its configuration once per TTL
+var zoneCache struct {
+	sync.Mutex
+	cache *endpoint.ZoneConfigurationCache
+}
+
+// getZoneCache returns the zone configuration cache for the given TTL, or nil when ttl is not set.
+// The cache is replaced when the TTL changes, i.e. after the playbook is reloaded
+func getZoneCache(ttl time.Duration) *endpoint.ZoneConfigurationCache {
+	if ttl <= 0 {
+		return nil
+	}
+	zoneCache.Lock()
+	defer zoneCache.Unlock()
+	if zoneCache.cache == nil || zoneCache.cache.TTL() != ttl {
+		zoneCache.cache = endpoint.NewZoneConfigurationCache(ttl)
+	}
+	return zoneCache.cache
+}
+
+// InvalidateZoneCache removes every cached zone configuration, so the next certificate requests read them again
+func InvalidateZoneCache() {
+	zoneCache.Lock()
+	defer zoneCache.Unlock()
+	if zoneCache.cache != nil {
+		zoneCache.cache.InvalidateAll()
+	}
+}
@@ -0,0 +1,251 @@
+/*
+ * Copyright 2023 Venafi, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *  http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package aws
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/Venafi/vcert/v5/pkg/playbook/util"
+)
+
+const (
+	acmService       = "acm"
+	acmTargetPrefix  = "CertificateManager."
+	acmContentType   = "application/x-amz-json-1.1"
+	envACMEndpoint   = "AWS_ENDPOINT_URL_ACM"
+	envEndpoint      = "AWS_ENDPOINT_URL"
+	defaultTimeout   = 30 * time.Second
+	metadataTimeout  = 5 * time.Second
+	errCodeNotFound  = "ResourceNotFoundException"
+	listCertPageSize = 100
+)
+
+// Tag represents an ACM certificate tag
+type Tag struct {
+	Key   string `json:"Key"`
+	Value string `json:"Value,omitempty"`
+}
+
+// ACMCertificate holds the PEM encoded certificate and chain stored in ACM
+type ACMCertificate struct {
+	Certificate      string `json:"Certificate"`
+	CertificateChain string `json:"CertificateChain"`
+}
+
+type importCertificateRequest struct {
+	CertificateArn   string `json:"CertificateArn,omitempty"`
+	Certificate      []byte `json:"Certificate"`
+	PrivateKey       []byte `json:"PrivateKey"`
+	CertificateChain []byte `json:"CertificateChain,omitempty"`
+	Tags             []Tag  `json:"Tags,omitempty"`
+}
+
+type apiError struct {
+	Type    string `json:"__type"`
+	Message string `json:"message"`
+}
+
+// ACMClient is a minimal client for the AWS Certificate Manager API
+type ACMClient struct {
+	region      string
+	endpoint    string
+	credentials Credentials
+	httpClient  *http.Client
+}
+
+// NewACMClient returns an ACMClient for the given region. See LoadCredentials for the credentials resolution order
+func NewACMClient(region string, profile string) (*ACMClient, error) {
+	creds, err := LoadCredentials(profile)
+	if err != nil {
+		return nil, err
+	}
+
+	return &ACMClient{
+		region:      region,
+		endpoint:    serviceEndpoint(acmService, region, envACMEndpoint),
+		credentials: *creds,
+		httpClient:  &http.Client{Timeout: defaultTimeout},
+	}, nil
+}
+
+// GetCertificate retrieves the certificate and chain identified by arn. Returns nil if the certificate does not exist
+func (c *ACMClient) GetCertificate(ctx context.Context, arn string) (*ACMCertificate, error) {
+	cert := &ACMCertificate{}
+	err := c.call(ctx, "GetCertificate", map[string]string{"CertificateArn": arn}, cert)
+	if err != nil {
+		if util.IsNotFound(err, errCodeNotFound) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return cert, nil
+}
+
+// ImportCertificate imports the PEM encoded certificate, private key and chain in ACM.
+// When arn is not empty, the existing certificate is re-imported, keeping its ARN and associations.
+// Tags can only be set on new certificates. Returns the ARN of the certificate
+func (c *ACMClient) ImportCertificate(ctx context.Context, arn string, certificate string, privateKey string, chain string, tags []Tag) (string, error) {
+	data := importCertificateRequest{
+		CertificateArn:   arn,
+		Certificate:      []byte(certificate),
+		PrivateKey:       []byte(privateKey),
+		CertificateChain: []byte(chain),
+	}
+	if arn == "" {
+		data.Tags = tags
+	}
+
+	response := struct {
+		CertificateArn string `json:"CertificateArn"`
+	}{}
+	err := c.call(ctx, "ImportCertificate", data, &response)
+	if err != nil {
+		return "", err
+	}
+	return response.CertificateArn, nil
+}
+
+// FindCertificateByTag returns the ARN of the first imported certificate that has the given tag.
+// Returns an empty string if no certificate matches
+func (c *ACMClient) FindCertificateByTag(ctx context.Context, tag Tag) (string, error) {
+	request := struct {
+		Includes struct {
+			KeyTypes []string `json:"keyTypes"`
+		} `json:"Includes"`
+		MaxItems  int    `json:"MaxItems"`
+		NextToken string `json:"NextToken,omitempty"`
+	}{MaxItems: listCertPageSize}
+	// By default, ACM only lists RSA_2048 certificates
+	request.Includes.KeyTypes = []string{"RSA_1024", "RSA_2048", "RSA_3072", "RSA_4096", "EC_prime256v1",
+		"EC_secp384r1", "EC_secp521r1"}
+
+	for {
+		response := struct {
+			CertificateSummaryList []struct {
+				CertificateArn string `json:"CertificateArn"`
+				Type           string `json:"Type"`
+			} `json:"CertificateSummaryList"`
+			NextToken string `json:"NextToken"`
+		}{}
+		err := c.call(ctx, "ListCertificates", request, &response)
+		if err != nil {
+			return "", err
+		}
+
+		for _, summary := range response.CertificateSummaryList {
+			if summary.Type != "" && summary.Type != "IMPORTED" {
+				continue
+			}
+			tags, err := c.listTags(ctx, summary.CertificateArn)
+			if err != nil {
+				return "", err
+			}
+			for _, t := range tags {
+				if t.Key == tag.Key && t.Value == tag.Value {
+					return summary.CertificateArn, nil
+				}
+			}
+		}
+
+		if response.NextToken == "" {
+			return "", nil
+		}
+		request.NextToken = response.NextToken
+	}
+}
+
+func (c *ACMClient) listTags(ctx context.Context, arn string) ([]Tag, error) {
+	response := struct {
+		Tags []Tag `json:"Tags"`
+	}{}
+	err := c.call(ctx, "ListTagsForCertificate", map[string]string{"CertificateArn": arn}, &response)
+	if err != nil {
+		return nil, err
+	}
+	return response.Tags, nil
+}
+
+func (c *ACMClient) call(ctx context.Context, operation string, data interface{}, result interface{}) error {
+	payload, err := json.Marshal(data)
+	if err != nil {
+		return err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, c.endpoint+"/", bytes.NewReader(payload))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", acmContentType)
+	req.Header.Set("X-Amz-Target", acmTargetPrefix+operation)
+	signRequest(req, payload, c.credentials, c.region, acmService, time.Now())
+
+	res, err := c.httpClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer res.Body.Close()
+
+	body, err := io.ReadAll(res.Body)
+	if err != nil {
+		return err
+	}
+
+	if res.StatusCode != http.StatusOK {
+		apiErr := apiError{}
+		_ = json.Unmarshal(body, &apiErr)
+		return &util.HTTPError{Service: "ACM", Method: http.MethodPost, Path: operation, StatusCode: res.StatusCode,
+			Code: apiErr.code(), Message: apiErr.Message}
+	}
+
+	if result != nil {
+		err = json.Unmarshal(body, result)
+		if err != nil {
+			return fmt.Errorf("could not parse ACM %s response: %w", operation, err)
+		}
+	}
+	return nil
+}
+
+// code removes the namespace prefix AWS adds to error types, i.e. "com.amazonaws.acm#ResourceNotFoundException"
+func (e apiError) code() string {
+	return e.Type[strings.LastIndex(e.Type, "#")+1:]
+}
+
+// serviceEndpoint returns the endpoint of service in region, unless overridden by the envServiceEndpoint or
+// AWS_ENDPOINT_URL environment variables
+func serviceEndpoint(service string, region string, envServiceEndpoint string) string {
+	endpoint := os.Getenv(envServiceEndpoint)
+	if endpoint == "" {
+		endpoint = os.Getenv(envEndpoint)
+	}
+	if endpoint == "" {
+		domain := "amazonaws.com"
+		if strings.HasPrefix(region, "cn-") {
+			domain = "amazonaws.com.cn"
+		}
+		endpoint = fmt.Sprintf("https://%s.%s.%s", service, region, domain)
+	}
+	return strings.TrimSuffix(endpoint, "/")
+}
@@ -0,0 +1,166 @@
+/*
+ * Copyright 2023 Venafi, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *  http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package aws
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+// acmServer mocks the ACM operations used by ACMClient. The certificates are listed one per page
+type acmServer struct {
+	t     *testing.T
+	certs map[string]*importCertificateRequest
+	arns  []string
+}
+
+func (s *acmServer) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	fail := func(errType string, message string) {
+		w.WriteHeader(http.StatusBadRequest)
+		_, _ = fmt.Fprintf(w, `{"__type":"com.amazonaws.acm#%s","message":%q}`, errType, message)
+	}
+	if !strings.HasPrefix(r.Header.Get("Authorization"), "AWS4-HMAC-SHA256 Credential=AKIDEXAMPLE/") ||
+		!strings.Contains(r.Header.Get("Authorization"), "/us-west-2/acm/aws4_request") {
+		s.t.Errorf("unexpected authorization %q", r.Header.Get("Authorization"))
+	}
+	if r.Header.Get("Content-Type") != acmContentType {
+		s.t.Errorf("unexpected content type %q", r.Header.Get("Content-Type"))
+	}
+
+	request := struct {
+		importCertificateRequest
+		NextToken string `json:"NextToken"`
+	}{}
+	_ = json.NewDecoder(r.Body).Decode(&request)
+	encode := func(v interface{}) {
+		w.Header().Set("Content-Type", acmContentType)
+		_ = json.NewEncoder(w).Encode(v)
+	}
+
+	switch strings.TrimPrefix(r.Header.Get("X-Amz-Target"), acmTargetPrefix) {
+	case "ImportCertificate":
+		arn := request.CertificateArn
+		if arn == "" {
+			arn = fmt.Sprintf("arn:aws:acm:us-west-2:123456789012:certificate/%d", len(s.arns)+1)
+			s.arns = append(s.arns, arn)
+		} else if s.certs[arn] == nil {
+			fail(errCodeNotFound, "Could not find certificate "+arn)
+			return
+		} else if len(request.Tags) > 0 {
+			fail("ValidationException", "Tags cannot be set when re-importing a certificate")
+			return
+		} else {
+			request.Tags = s.certs[arn].Tags
+		}
+		s.certs[arn] = &request.importCertificateRequest
+		encode(map[string]string{"CertificateArn": arn})
+	case "GetCertificate":
+		cert := s.certs[request.CertificateArn]
+		if cert == nil {
+			fail(errCodeNotFound, "Could not find certificate "+request.CertificateArn)
+			return
+		}
+		encode(ACMCertificate{Certificate: string(cert.Certificate), CertificateChain: string(cert.CertificateChain)})
+	case "ListCertificates":
+		index := 0
+		if request.NextToken != "" {
+			_, _ = fmt.Sscanf(request.NextToken, "page-%d", &index)
+		}
+		response := map[string]interface{}{"CertificateSummaryList": []map[string]string{}}
+		if index < len(s.arns) {
+			response["CertificateSummaryList"] = []map[string]string{{"CertificateArn": s.arns[index], "Type": "IMPORTED"}}
+		}
+		if index+1 < len(s.arns) {
+			response["NextToken"] = fmt.Sprintf("page-%d", index+1)
+		}
+		encode(response)
+	case "ListTagsForCertificate":
+		encode(map[string]interface{}{"Tags": s.certs[request.CertificateArn].Tags})
+	default:
+		fail("UnknownOperationException", "unknown operation "+r.Header.Get("X-Amz-Target"))
+	}
+}
+
+func TestACMClient(t *testing.T) {
+	ctx := context.Background()
+	acm := &acmServer{t: t, certs: map[string]*importCertificateRequest{}}
+	server := httptest.NewServer(acm)
+	defer server.Close()
+	t.Setenv(envAccessKeyID, "AKIDEXAMPLE")
+	t.Setenv(envSecretAccessKey, "wJalrXUtnFEMI/K7MDENG+bPxRfiCYEXAMPLEKEY")
+	t.Setenv(envACMEndpoint, server.URL)
+
+	client, err := NewACMClient("us-west-2", "")
+	if err != nil {
+		t.Fatalf("failed to create client: %s", err)
+	}
+
+	// Not found
+	cert, err := client.GetCertificate(ctx, "arn:aws:acm:us-west-2:123456789012:certificate/missing")
+	if err != nil || cert != nil {
+		t.Fatalf("expected missing certificate to return nil, got %v, %v", cert, err)
+	}
+	_, err = client.ImportCertificate(ctx, "arn:aws:acm:us-west-2:123456789012:certificate/missing", "cert", "key", "", nil)
+	if err == nil || !strings.Contains(err.Error(), "400 Could not find certificate") {
+		t.Fatalf("expected re-import of a missing certificate to fail, got %v", err)
+	}
+	arn, err := client.FindCertificateByTag(ctx, Tag{Key: "vcert", Value: "web"})
+	if err != nil || arn != "" {
+		t.Fatalf("expected no certificate to match, got %q, %v", arn, err)
+	}
+
+	// Create
+	_, err = client.ImportCertificate(ctx, "", "other-cert", "other-key", "", []Tag{{Key: "vcert", Value: "api"}})
+	if err != nil {
+		t.Fatalf("failed to import certificate: %s", err)
+	}
+	arn, err = client.ImportCertificate(ctx, "", "cert-1", "key-1", "chain-1", []Tag{{Key: "vcert", Value: "web"}})
+	if err != nil {
+		t.Fatalf("failed to import certificate: %s", err)
+	}
+	found, err := client.FindCertificateByTag(ctx, Tag{Key: "vcert", Value: "web"})
+	if err != nil {
+		t.Fatalf("failed to find certificate: %s", err)
+	}
+	if found != arn {
+		t.Fatalf("expected certificate %s on the second page, got %q", arn, found)
+	}
+
+	// Update
+	reimported, err := client.ImportCertificate(ctx, arn, "cert-2", "key-2", "chain-2", []Tag{{Key: "ignored"}})
+	if err != nil {
+		t.Fatalf("failed to re-import certificate: %s", err)
+	}
+	if reimported != arn {
+		t.Fatalf("expected re-import to keep the ARN %s, got %s", arn, reimported)
+	}
+	cert, err = client.GetCertificate(ctx, arn)
+	if err != nil {
+		t.Fatalf("failed to get certificate: %s", err)
+	}
+	if cert.Certificate != "cert-2" || cert.CertificateChain != "chain-2" {
+		t.Fatalf("unexpected certificate %v", cert)
+	}
+	if tags := acm.certs[arn].Tags; len(tags) != 1 || tags[0].Value != "web" {
+		t.Fatalf("expected the tags to be kept on re-import, got %v", tags)
+	}
+}
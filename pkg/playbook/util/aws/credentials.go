@@ -0,0 +1,208 @@
+/*
+ * Copyright 2023 Venafi, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *  http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package aws
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"go.uber.org/zap"
+	"gopkg.in/ini.v1"
+)
+
+const (
+	envAccessKeyID          = "AWS_ACCESS_KEY_ID"
+	envSecretAccessKey      = "AWS_SECRET_ACCESS_KEY"
+	envSessionToken         = "AWS_SESSION_TOKEN"
+	envProfile              = "AWS_PROFILE"
+	envSharedCredentials    = "AWS_SHARED_CREDENTIALS_FILE"
+	envContainerRelativeURI = "AWS_CONTAINER_CREDENTIALS_RELATIVE_URI"
+	envContainerFullURI     = "AWS_CONTAINER_CREDENTIALS_FULL_URI"
+	envContainerToken       = "AWS_CONTAINER_AUTHORIZATION_TOKEN"
+
+	defaultProfile       = "default"
+	containerCredentials = "http://169.254.170.2"
+	imdsEndpoint         = "http://169.254.169.254"
+	imdsTokenTTL         = "21600"
+)
+
+// Credentials holds the AWS keys used to sign requests
+type Credentials struct {
+	AccessKeyID     string `json:"AccessKeyId"`
+	SecretAccessKey string `json:"SecretAccessKey"`
+	SessionToken    string `json:"Token"`
+}
+
+// LoadCredentials resolves the AWS credentials in the same order as the AWS CLI:
+//  1. AWS_ACCESS_KEY_ID, AWS_SECRET_ACCESS_KEY and AWS_SESSION_TOKEN environment variables
+//  2. the shared credentials file (~/.aws/credentials), using profile, AWS_PROFILE or "default"
+//  3. the ECS container credentials endpoint
+//  4. the EC2 instance profile, using IMDSv2
+func LoadCredentials(profile string) (*Credentials, error) {
+	if os.Getenv(envAccessKeyID) != "" && os.Getenv(envSecretAccessKey) != "" {
+		zap.L().Debug("using AWS credentials from environment variables")
+		return &Credentials{
+			AccessKeyID:     os.Getenv(envAccessKeyID),
+			SecretAccessKey: os.Getenv(envSecretAccessKey),
+			SessionToken:    os.Getenv(envSessionToken),
+		}, nil
+	}
+
+	creds, err := loadSharedCredentials(profile)
+	if err != nil {
+		return nil, err
+	}
+	if creds != nil {
+		return creds, nil
+	}
+
+	// An explicit profile must be found in the shared credentials file
+	if profile != "" {
+		return nil, fmt.Errorf("AWS profile %s not found in shared credentials file", profile)
+	}
+
+	if os.Getenv(envContainerRelativeURI) != "" || os.Getenv(envContainerFullURI) != "" {
+		zap.L().Debug("using AWS credentials from container credentials endpoint")
+		return loadContainerCredentials()
+	}
+
+	zap.L().Debug("using AWS credentials from EC2 instance profile")
+	return loadInstanceCredentials()
+}
+
+func loadSharedCredentials(profile string) (*Credentials, error) {
+	if profile == "" {
+		profile = os.Getenv(envProfile)
+	}
+	if profile == "" {
+		profile = defaultProfile
+	}
+
+	path := os.Getenv(envSharedCredentials)
+	if path == "" {
+		home, err := os.UserHomeDir()
+		if err != nil {
+			return nil, nil
+		}
+		path = filepath.Join(home, ".aws", "credentials")
+	}
+
+	if _, err := os.Stat(path); err != nil {
+		return nil, nil
+	}
+
+	file, err := ini.Load(path)
+	if err != nil {
+		return nil, fmt.Errorf("could not read AWS shared credentials file %s: %w", path, err)
+	}
+	section, err := file.GetSection(profile)
+	if err != nil {
+		return nil, nil
+	}
+
+	zap.L().Debug("using AWS credentials from shared credentials file", zap.String("profile", profile))
+	return &Credentials{
+		AccessKeyID:     section.Key("aws_access_key_id").String(),
+		SecretAccessKey: section.Key("aws_secret_access_key").String(),
+		SessionToken:    section.Key("aws_session_token").String(),
+	}, nil
+}
+
+func loadContainerCredentials() (*Credentials, error) {
+	endpoint := os.Getenv(envContainerFullURI)
+	if relativeURI := os.Getenv(envContainerRelativeURI); relativeURI != "" {
+		endpoint = containerCredentials + relativeURI
+	}
+
+	headers := map[string]string{}
+	if token := os.Getenv(envContainerToken); token != "" {
+		headers["Authorization"] = token
+	}
+
+	body, err := doMetadataRequest(http.MethodGet, endpoint, headers)
+	if err != nil {
+		return nil, fmt.Errorf("could not get AWS container credentials: %w", err)
+	}
+	return parseCredentials(body)
+}
+
+func loadInstanceCredentials() (*Credentials, error) {
+	token, err := doMetadataRequest(http.MethodPut, imdsEndpoint+"/latest/api/token",
+		map[string]string{"X-aws-ec2-metadata-token-ttl-seconds": imdsTokenTTL})
+	if err != nil {
+		return nil, fmt.Errorf("could not get EC2 metadata token: %w", err)
+	}
+	headers := map[string]string{"X-aws-ec2-metadata-token": string(token)}
+
+	roles, err := doMetadataRequest(http.MethodGet, imdsEndpoint+"/latest/meta-data/iam/security-credentials/", headers)
+	if err != nil {
+		return nil, fmt.Errorf("could not get EC2 instance profile: %w", err)
+	}
+	role := strings.TrimSpace(strings.Split(string(roles), "\n")[0])
+	if role == "" {
+		return nil, fmt.Errorf("no EC2 instance profile found")
+	}
+
+	body, err := doMetadataRequest(http.MethodGet, imdsEndpoint+"/latest/meta-data/iam/security-credentials/"+role, headers)
+	if err != nil {
+		return nil, fmt.Errorf("could not get EC2 instance profile credentials: %w", err)
+	}
+	return parseCredentials(body)
+}
+
+func parseCredentials(body []byte) (*Credentials, error) {
+	creds := &Credentials{}
+	err := json.Unmarshal(body, creds)
+	if err != nil {
+		return nil, fmt.Errorf("could not parse AWS credentials: %w", err)
+	}
+	if creds.AccessKeyID == "" || creds.SecretAccessKey == "" {
+		return nil, fmt.Errorf("AWS credentials response does not contain access keys")
+	}
+	return creds, nil
+}
+
+func doMetadataRequest(method string, url string, headers map[string]string) ([]byte, error) {
+	req, err := http.NewRequest(method, url, nil)
+	if err != nil {
+		return nil, err
+	}
+	for name, value := range headers {
+		req.Header.Set(name, value)
+	}
+
+	httpClient := &http.Client{Timeout: metadataTimeout}
+	res, err := httpClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer res.Body.Close()
+
+	body, err := io.ReadAll(res.Body)
+	if err != nil {
+		return nil, err
+	}
+	if res.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("unexpected status code: %d %s", res.StatusCode, string(body))
+	}
+	return body, nil
+}
@@ -0,0 +1,227 @@
+/*
+ * Copyright 2023 Venafi, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *  http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package aws
+
+import (
+	"bytes"
+	"context"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	route53Service     = "route53"
+	route53Region      = "us-east-1"
+	route53APIVersion  = "2013-04-01"
+	route53Namespace   = "https://route53.amazonaws.com/doc/2013-04-01/"
+	envRoute53Endpoint = "AWS_ENDPOINT_URL_ROUTE_53"
+	defaultRoute53URL  = "https://route53.amazonaws.com"
+)
+
+type route53RecordSet struct {
+	Name            string `xml:"Name"`
+	Type            string `xml:"Type"`
+	TTL             int    `xml:"TTL,omitempty"`
+	ResourceRecords []struct {
+		Value string `xml:"Value"`
+	} `xml:"ResourceRecords>ResourceRecord"`
+}
+
+type route53ChangeRequest struct {
+	XMLName xml.Name `xml:"ChangeResourceRecordSetsRequest"`
+	Xmlns   string   `xml:"xmlns,attr"`
+	Changes []struct {
+		Action            string           `xml:"Action"`
+		ResourceRecordSet route53RecordSet `xml:"ResourceRecordSet"`
+	} `xml:"ChangeBatch>Changes>Change"`
+}
+
+type route53Error struct {
+	Code    string `xml:"Error>Code"`
+	Message string `xml:"Error>Message"`
+}
+
+// Route53Client is a minimal client for the TXT records of the Amazon Route 53 API
+type Route53Client struct {
+	endpoint    string
+	credentials Credentials
+	httpClient  *http.Client
+}
+
+// NewRoute53Client returns a Route53Client. See LoadCredentials for the credentials resolution order
+func NewRoute53Client(profile string) (*Route53Client, error) {
+	creds, err := LoadCredentials(profile)
+	if err != nil {
+		return nil, err
+	}
+
+	endpoint := os.Getenv(envRoute53Endpoint)
+	if endpoint == "" {
+		endpoint = os.Getenv(envEndpoint)
+	}
+	if endpoint == "" {
+		endpoint = defaultRoute53URL
+	}
+
+	return &Route53Client{
+		endpoint:    strings.TrimSuffix(endpoint, "/"),
+		credentials: *creds,
+		httpClient:  &http.Client{Timeout: defaultTimeout},
+	}, nil
+}
+
+// FindHostedZone returns the ID of the hosted zone for the domain name, i.e. "example.com".
+// Returns an empty string if there is no such hosted zone
+func (c *Route53Client) FindHostedZone(ctx context.Context, name string) (string, error) {
+	name = strings.TrimSuffix(name, ".") + "."
+	query := url.Values{}
+	query.Set("dnsname", name)
+	query.Set("maxitems", "1")
+
+	response := struct {
+		HostedZones []struct {
+			ID   string `xml:"Id"`
+			Name string `xml:"Name"`
+		} `xml:"HostedZones>HostedZone"`
+	}{}
+	err := c.call(ctx, http.MethodGet, "/hostedzonesbyname?"+query.Encode(), nil, &response)
+	if err != nil {
+		return "", err
+	}
+
+	// Hosted zones are listed from the given name, the first one is not necessarily a match
+	for _, zone := range response.HostedZones {
+		if strings.EqualFold(zone.Name, name) {
+			return strings.TrimPrefix(zone.ID, "/hostedzone/"), nil
+		}
+	}
+	return "", nil
+}
+
+// GetTXTRecord returns the values and the TTL of the TXT record name, a fully qualified name, in the hosted zone.
+// Returns nil values if the record does not exist
+func (c *Route53Client) GetTXTRecord(ctx context.Context, hostedZoneID string, name string) ([]string, int, error) {
+	name = strings.TrimSuffix(name, ".") + "."
+	query := url.Values{}
+	query.Set("name", name)
+	query.Set("type", "TXT")
+	query.Set("maxitems", "1")
+
+	response := struct {
+		RecordSets []route53RecordSet `xml:"ResourceRecordSets>ResourceRecordSet"`
+	}{}
+	err := c.call(ctx, http.MethodGet, fmt.Sprintf("/hostedzone/%s/rrset?%s", url.PathEscape(hostedZoneID), query.Encode()), nil, &response)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	// Record sets are listed from the given name, the first one is not necessarily a match
+	for _, recordSet := range response.RecordSets {
+		if recordSet.Type != "TXT" || !strings.EqualFold(recordSet.Name, name) {
+			continue
+		}
+		values := make([]string, 0, len(recordSet.ResourceRecords))
+		for _, record := range recordSet.ResourceRecords {
+			value := record.Value
+			if unquoted, err := strconv.Unquote(value); err == nil {
+				value = unquoted
+			}
+			values = append(values, value)
+		}
+		return values, recordSet.TTL, nil
+	}
+	return nil, 0, nil
+}
+
+// SetTXTRecord creates or replaces the TXT record name in the hosted zone with values
+func (c *Route53Client) SetTXTRecord(ctx context.Context, hostedZoneID string, name string, values []string, ttl int) error {
+	return c.changeTXTRecord(ctx, hostedZoneID, "UPSERT", name, values, ttl)
+}
+
+// DeleteTXTRecord deletes the TXT record name in the hosted zone. values and ttl must match the current record
+func (c *Route53Client) DeleteTXTRecord(ctx context.Context, hostedZoneID string, name string, values []string, ttl int) error {
+	return c.changeTXTRecord(ctx, hostedZoneID, "DELETE", name, values, ttl)
+}
+
+func (c *Route53Client) changeTXTRecord(ctx context.Context, hostedZoneID string, action string, name string, values []string, ttl int) error {
+	recordSet := route53RecordSet{Name: strings.TrimSuffix(name, ".") + ".", Type: "TXT", TTL: ttl}
+	for _, value := range values {
+		recordSet.ResourceRecords = append(recordSet.ResourceRecords, struct {
+			Value string `xml:"Value"`
+		}{Value: strconv.Quote(value)})
+	}
+
+	request := route53ChangeRequest{Xmlns: route53Namespace}
+	request.Changes = append(request.Changes, struct {
+		Action            string           `xml:"Action"`
+		ResourceRecordSet route53RecordSet `xml:"ResourceRecordSet"`
+	}{Action: action, ResourceRecordSet: recordSet})
+
+	return c.call(ctx, http.MethodPost, fmt.Sprintf("/hostedzone/%s/rrset", url.PathEscape(hostedZoneID)), request, nil)
+}
+
+func (c *Route53Client) call(ctx context.Context, method string, path string, data interface{}, result interface{}) error {
+	var payload []byte
+	if data != nil {
+		var err error
+		payload, err = xml.Marshal(data)
+		if err != nil {
+			return err
+		}
+	}
+
+	req, err := http.NewRequestWithContext(ctx, method, fmt.Sprintf("%s/%s%s", c.endpoint, route53APIVersion, path), bytes.NewReader(payload))
+	if err != nil {
+		return err
+	}
+	if data != nil {
+		req.Header.Set("Content-Type", "application/xml")
+	}
+	signRequest(req, payload, c.credentials, route53Region, route53Service, time.Now())
+
+	res, err := c.httpClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer res.Body.Close()
+
+	body, err := io.ReadAll(res.Body)
+	if err != nil {
+		return err
+	}
+
+	if res.StatusCode != http.StatusOK {
+		apiErr := route53Error{}
+		_ = xml.Unmarshal(body, &apiErr)
+		return fmt.Errorf("route 53 request %s %s failed: %d %s: %s", method, path, res.StatusCode, apiErr.Code, apiErr.Message)
+	}
+
+	if result != nil {
+		err = xml.Unmarshal(body, result)
+		if err != nil {
+			return fmt.Errorf("could not parse route 53 response: %w", err)
+		}
+	}
+	return nil
+}
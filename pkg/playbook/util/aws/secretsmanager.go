@@ -0,0 +1,113 @@
+/*
+ * Copyright 2023 Venafi, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *  http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package aws
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"time"
+)
+
+const (
+	secretsManagerService      = "secretsmanager"
+	secretsManagerTargetPrefix = "secretsmanager."
+	envSecretsManagerEndpoint  = "AWS_ENDPOINT_URL_SECRETS_MANAGER"
+)
+
+// SecretsManagerClient is a minimal client for the AWS Secrets Manager API
+type SecretsManagerClient struct {
+	region      string
+	endpoint    string
+	credentials Credentials
+	httpClient  *http.Client
+}
+
+// NewSecretsManagerClient returns a SecretsManagerClient for the given region. See LoadCredentials for the
+// credentials resolution order
+func NewSecretsManagerClient(region string, profile string) (*SecretsManagerClient, error) {
+	creds, err := LoadCredentials(profile)
+	if err != nil {
+		return nil, err
+	}
+
+	return &SecretsManagerClient{
+		region:      region,
+		endpoint:    serviceEndpoint(secretsManagerService, region, envSecretsManagerEndpoint),
+		credentials: *creds,
+		httpClient:  &http.Client{Timeout: defaultTimeout},
+	}, nil
+}
+
+// GetSecretValue returns the current value of the secret identified by secretID, its name or ARN.
+// Binary secrets are returned as is
+func (c *SecretsManagerClient) GetSecretValue(ctx context.Context, secretID string) (string, error) {
+	response := struct {
+		SecretString string `json:"SecretString"`
+		SecretBinary []byte `json:"SecretBinary"`
+	}{}
+	err := c.call(ctx, "GetSecretValue", map[string]string{"SecretId": secretID}, &response)
+	if err != nil {
+		return "", err
+	}
+	if response.SecretString == "" && response.SecretBinary != nil {
+		return string(response.SecretBinary), nil
+	}
+	return response.SecretString, nil
+}
+
+func (c *SecretsManagerClient) call(ctx context.Context, operation string, data interface{}, result interface{}) error {
+	payload, err := json.Marshal(data)
+	if err != nil {
+		return err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, c.endpoint+"/", bytes.NewReader(payload))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", acmContentType)
+	req.Header.Set("X-Amz-Target", secretsManagerTargetPrefix+operation)
+	signRequest(req, payload, c.credentials, c.region, secretsManagerService, time.Now())
+
+	res, err := c.httpClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer res.Body.Close()
+
+	body, err := io.ReadAll(res.Body)
+	if err != nil {
+		return err
+	}
+
+	if res.StatusCode != http.StatusOK {
+		apiErr := apiError{}
+		_ = json.Unmarshal(body, &apiErr)
+		return fmt.Errorf("Secrets Manager %s failed: %d %s: %s", operation, res.StatusCode, apiErr.code(), apiErr.Message)
+	}
+
+	// The response is not included in errors, as it holds the secret value
+	err = json.Unmarshal(body, result)
+	if err != nil {
+		return fmt.Errorf("could not parse Secrets Manager %s response", operation)
+	}
+	return nil
+}
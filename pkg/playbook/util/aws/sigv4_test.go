@@ -0,0 +1,91 @@
+/*
+ * Copyright 2023 Venafi, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *  http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package aws
+
+import (
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+)
+
+// TestSignRequest checks the signatures of the AWS Signature Version 4 test suite
+// (https://docs.aws.amazon.com/general/latest/gr/signature-v4-test-suite.html)
+func TestSignRequest(t *testing.T) {
+	creds := Credentials{AccessKeyID: "AKIDEXAMPLE", SecretAccessKey: "wJalrXUtnFEMI/K7MDENG+bPxRfiCYEXAMPLEKEY"}
+	now := time.Date(2015, 8, 30, 12, 36, 0, 0, time.UTC)
+
+	cases := []struct {
+		name        string
+		method      string
+		url         string
+		contentType string
+		payload     string
+		signature   string
+	}{
+		{name: "get-vanilla", method: http.MethodGet, url: "https://example.amazonaws.com/",
+			signature: "5fa00fa31553b73ebf1942676e86291e8372ff2a2260956d9b8aae1d763fbf31"},
+		{name: "get-vanilla-query-order-key-case", method: http.MethodGet, url: "https://example.amazonaws.com/?Param2=value2&Param1=value1",
+			signature: "b97d918cfa904a5beff61c982a1b6f458b799221646efd99d3219ec94cdf2500"},
+		{name: "get-vanilla-query-unreserved", method: http.MethodGet,
+			url:       "https://example.amazonaws.com/?-._~0123456789ABCDEFGHIJKLMNOPQRSTUVWXYZabcdefghijklmnopqrstuvwxyz=-._~0123456789ABCDEFGHIJKLMNOPQRSTUVWXYZabcdefghijklmnopqrstuvwxyz",
+			signature: "9c3e54bfcdf0b19771a7f523ee5669cdf59bc7cc0884027167c21bb143a40197"},
+		{name: "get-vanilla-utf8-query", method: http.MethodGet, url: "https://example.amazonaws.com/?ሴ=bar",
+			signature: "2cdec8eed098649ff3a119c94853b13c643bcf08f8b0a1d91e12c9027818dd04"},
+		{name: "post-vanilla-query", method: http.MethodPost, url: "https://example.amazonaws.com/?Param1=value1",
+			signature: "28038455d6de14eafc1f9222cf5aa6f1a96197d7deb8263271d420d138af7f11"},
+		{name: "post-x-www-form-urlencoded", method: http.MethodPost, url: "https://example.amazonaws.com/",
+			contentType: "application/x-www-form-urlencoded", payload: "Param1=value1",
+			signature: "ff11897932ad3f4e8b18135d722051e5ac45fc38421b1da7b9d196a0fe09473a"},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			req, err := http.NewRequest(c.method, c.url, strings.NewReader(c.payload))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if c.contentType != "" {
+				req.Header.Set("Content-Type", c.contentType)
+			}
+			signRequest(req, []byte(c.payload), creds, "us-east-1", "service", now)
+
+			signature := req.Header.Get("Authorization")
+			signature = signature[strings.LastIndex(signature, "=")+1:]
+			if signature != c.signature {
+				t.Fatalf("expected signature %s but got %s\n%s", c.signature, signature, req.Header.Get("Authorization"))
+			}
+		})
+	}
+}
+
+func TestCanonicalQuery(t *testing.T) {
+	cases := map[string]string{
+		"":                          "",
+		"b=2&a=1":                   "a=1&b=2",
+		"a=2&a=1":                   "a=1&a=2",
+		"a-b=1&a=2":                 "a=2&a-b=1",
+		"flag&a=1":                  "a=1&flag=",
+		"q=a+b&r=c%2Fd":             "q=a%20b&r=c%2Fd",
+		"path=/x/y&star=*":          "path=%2Fx%2Fy&star=%2A",
+		"Action=ListCertificates&x": "Action=ListCertificates&x=",
+	}
+	for query, expected := range cases {
+		if actual := canonicalQuery(query); actual != expected {
+			t.Errorf("canonical query of %q: expected %q but got %q", query, expected, actual)
+		}
+	}
+}
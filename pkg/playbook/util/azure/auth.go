@@ -0,0 +1,147 @@
+/*
+ * Copyright 2023 Venafi, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *  http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package azure
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+
+	"go.uber.org/zap"
+	"golang.org/x/oauth2/clientcredentials"
+)
+
+const (
+	keyVaultResource   = "https://vault.azure.net"
+	managementResource = "https://management.azure.com"
+
+	defaultAuthorityHost = "https://login.microsoftonline.com"
+	authorityHostEnvVar  = "AZURE_AUTHORITY_HOST"
+
+	imdsAPIVersion = "2018-02-01"
+
+	// App Service and Azure Functions expose managed identities through these variables instead of IMDS
+	identityEndpointEnvVar = "IDENTITY_ENDPOINT"
+	identityHeaderEnvVar   = "IDENTITY_HEADER"
+	identityAPIVersion     = "2019-08-01"
+)
+
+// Credentials holds the values used to authenticate to Azure Key Vault and Azure DNS.
+//
+// When ClientSecret is set, a service principal (client secret) is used and TenantID and ClientID are required.
+// Otherwise, a managed identity is used. ClientID selects a user-assigned identity and may be empty for the
+// system-assigned one
+type Credentials struct {
+	TenantID     string
+	ClientID     string
+	ClientSecret string
+}
+
+// imdsEndpoint is the token endpoint of the Azure Instance Metadata Service. Overridden by tests
+var imdsEndpoint = "http://169.254.169.254/metadata/identity/oauth2/token"
+
+type tokenResponse struct {
+	AccessToken string `json:"access_token"`
+}
+
+// GetToken returns an access token for Azure Key Vault
+func GetToken(credentials Credentials) (string, error) {
+	return getResourceToken(credentials, keyVaultResource)
+}
+
+// getResourceToken returns an access token for the Azure API at resource, i.e. https://management.azure.com
+func getResourceToken(credentials Credentials, resource string) (string, error) {
+	if credentials.ClientSecret != "" {
+		zap.L().Debug("requesting Azure token using client secret", zap.String("clientId", credentials.ClientID),
+			zap.String("resource", resource))
+		return getClientSecretToken(credentials, resource)
+	}
+
+	zap.L().Debug("requesting Azure token using managed identity", zap.String("clientId", credentials.ClientID),
+		zap.String("resource", resource))
+	return getManagedIdentityToken(credentials.ClientID, resource)
+}
+
+func getClientSecretToken(credentials Credentials, resource string) (string, error) {
+	authorityHost := os.Getenv(authorityHostEnvVar)
+	if authorityHost == "" {
+		authorityHost = defaultAuthorityHost
+	}
+
+	config := clientcredentials.Config{
+		ClientID:     credentials.ClientID,
+		ClientSecret: credentials.ClientSecret,
+		TokenURL:     fmt.Sprintf("%s/%s/oauth2/v2.0/token", strings.TrimSuffix(authorityHost, "/"), url.PathEscape(credentials.TenantID)),
+		Scopes:       []string{resource + "/.default"},
+	}
+
+	token, err := config.Token(context.Background())
+	if err != nil {
+		return "", fmt.Errorf("could not get Azure token using client secret: %w", err)
+	}
+	return token.AccessToken, nil
+}
+
+func getManagedIdentityToken(clientID string, resource string) (string, error) {
+	params := url.Values{}
+	params.Set("resource", resource)
+	if clientID != "" {
+		params.Set("client_id", clientID)
+	}
+
+	endpoint := imdsEndpoint
+	headerName, headerValue := "Metadata", "true"
+	params.Set("api-version", imdsAPIVersion)
+	if identityEndpoint := os.Getenv(identityEndpointEnvVar); identityEndpoint != "" {
+		endpoint = identityEndpoint
+		headerName, headerValue = "X-IDENTITY-HEADER", os.Getenv(identityHeaderEnvVar)
+		params.Set("api-version", identityAPIVersion)
+	}
+
+	req, err := http.NewRequest(http.MethodGet, fmt.Sprintf("%s?%s", endpoint, params.Encode()), nil)
+	if err != nil {
+		return "", err
+	}
+	req.Header.Set(headerName, headerValue)
+
+	httpClient := &http.Client{Timeout: defaultTimeout}
+	res, err := httpClient.Do(req)
+	if err != nil {
+		return "", fmt.Errorf("could not get Azure token using managed identity: %w", err)
+	}
+	defer res.Body.Close()
+
+	body, err := io.ReadAll(res.Body)
+	if err != nil {
+		return "", err
+	}
+	if res.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("could not get Azure token using managed identity: %d %s", res.StatusCode, string(body))
+	}
+
+	token := tokenResponse{}
+	err = json.Unmarshal(body, &token)
+	if err != nil {
+		return "", fmt.Errorf("could not parse Azure managed identity token: %w", err)
+	}
+	return token.AccessToken, nil
+}
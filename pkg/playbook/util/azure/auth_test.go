@@ -0,0 +1,125 @@
+/*
+ * Copyright 2023 Venafi, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *  http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package azure
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestGetTokenClientSecret(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/tenant-1/oauth2/v2.0/token" {
+			t.Errorf("unexpected token path %s", r.URL.Path)
+		}
+		err := r.ParseForm()
+		if err != nil {
+			t.Error(err)
+		}
+		clientID, clientSecret, ok := r.BasicAuth()
+		if !ok {
+			clientID, clientSecret = r.PostForm.Get("client_id"), r.PostForm.Get("client_secret")
+		}
+		if r.PostForm.Get("grant_type") != "client_credentials" || clientID != "client-1" || clientSecret != "secret-1" {
+			w.WriteHeader(http.StatusUnauthorized)
+			_, _ = w.Write([]byte(`{"error":"invalid_client"}`))
+			return
+		}
+		if r.PostForm.Get("scope") != keyVaultResource+"/.default" {
+			t.Errorf("unexpected scope %q", r.PostForm.Get("scope"))
+		}
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write([]byte(`{"access_token":"sp-token","token_type":"Bearer","expires_in":3600}`))
+	}))
+	defer server.Close()
+	t.Setenv(authorityHostEnvVar, server.URL)
+
+	token, err := GetToken(Credentials{TenantID: "tenant-1", ClientID: "client-1", ClientSecret: "secret-1"})
+	if err != nil {
+		t.Fatalf("failed to get token: %s", err)
+	}
+	if token != "sp-token" {
+		t.Fatalf("unexpected token %q", token)
+	}
+
+	_, err = GetToken(Credentials{TenantID: "tenant-1", ClientID: "client-1", ClientSecret: "wrong"})
+	if err == nil {
+		t.Fatalf("expected invalid client secret to fail")
+	}
+}
+
+// managedIdentityHandler mocks the token endpoint of IMDS, or of App Service when header is set
+func managedIdentityHandler(t *testing.T, headerName string, headerValue string, version string) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get(headerName) != headerValue {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		q := r.URL.Query()
+		if q.Get("api-version") != version || q.Get("resource") != keyVaultResource {
+			t.Errorf("unexpected query %s", r.URL.RawQuery)
+		}
+		token := "system-token"
+		if q.Get("client_id") != "" {
+			token = "user-token-" + q.Get("client_id")
+		}
+		_ = json.NewEncoder(w).Encode(tokenResponse{AccessToken: token})
+	}
+}
+
+func TestGetTokenManagedIdentity(t *testing.T) {
+	server := httptest.NewServer(managedIdentityHandler(t, "Metadata", "true", imdsAPIVersion))
+	defer server.Close()
+	defaultEndpoint := imdsEndpoint
+	imdsEndpoint = server.URL
+	defer func() { imdsEndpoint = defaultEndpoint }()
+	t.Setenv(identityEndpointEnvVar, "")
+
+	cases := map[string]string{"": "system-token", "identity-1": "user-token-identity-1"}
+	for clientID, expected := range cases {
+		token, err := GetToken(Credentials{ClientID: clientID})
+		if err != nil {
+			t.Fatalf("failed to get token: %s", err)
+		}
+		if token != expected {
+			t.Fatalf("expected token %q but got %q", expected, token)
+		}
+	}
+}
+
+func TestGetTokenAppServiceIdentity(t *testing.T) {
+	server := httptest.NewServer(managedIdentityHandler(t, "X-IDENTITY-HEADER", "header-1", identityAPIVersion))
+	defer server.Close()
+	t.Setenv(identityEndpointEnvVar, server.URL)
+	t.Setenv(identityHeaderEnvVar, "header-1")
+
+	token, err := GetToken(Credentials{})
+	if err != nil {
+		t.Fatalf("failed to get token: %s", err)
+	}
+	if token != "system-token" {
+		t.Fatalf("unexpected token %q", token)
+	}
+
+	t.Setenv(identityHeaderEnvVar, "wrong")
+	_, err = GetToken(Credentials{})
+	if err == nil {
+		t.Fatalf("expected wrong identity header to fail")
+	}
+}
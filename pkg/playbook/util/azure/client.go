@@ -0,0 +1,218 @@
+/*
+ * Copyright 2023 Venafi, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *  http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+// Package azure is a minimal client of the Azure Key Vault and Azure DNS REST APIs, used by the AZUREKEYVAULT
+// installer, the azkv secret provider and the azuredns DNS provider.
+//
+// It calls the REST APIs directly instead of the Azure SDK for Go (azidentity, azcertificates, azsecrets and armdns),
+// which is not among the dependencies of vcert yet. Until it is, the differences with the SDK are:
+//   - only the client secret of a service principal and managed identities (IMDS, App Service and Azure Functions)
+//     authenticate. Workload identity, certificate credentials and the Azure CLI credentials of DefaultAzureCredential
+//     are not supported
+//   - a token is requested for each client and not cached or refreshed, which is enough for the short lived clients
+//     of a playbook run
+//   - throttled (429) and failed requests are not retried
+package azure
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+)
+
+const (
+	// ContentTypePEM is the content type of certificates imported as a PEM bundle
+	ContentTypePEM = "application/x-pem-file"
+	// ContentTypePKCS12 is the content type of certificates imported as a PKCS12 bundle
+	ContentTypePKCS12 = "application/x-pkcs12"
+
+	apiVersion     = "7.4"
+	defaultTimeout = 30 * time.Second
+)
+
+// CertificateAttributes represents the attributes of a Key Vault certificate version
+type CertificateAttributes struct {
+	Enabled bool  `json:"enabled"`
+	Created int64 `json:"created,omitempty"`
+	Expires int64 `json:"exp,omitempty"`
+}
+
+// Certificate represents a Key Vault certificate version. CER holds the DER encoded certificate
+type Certificate struct {
+	ID         string                `json:"id"`
+	CER        []byte                `json:"cer,omitempty"`
+	Attributes CertificateAttributes `json:"attributes"`
+	Tags       map[string]string     `json:"tags,omitempty"`
+}
+
+// Version returns the version identifier of the certificate, which is the last segment of its ID
+func (c Certificate) Version() string {
+	return c.ID[strings.LastIndex(c.ID, "/")+1:]
+}
+
+// Secret represents the Key Vault secret that holds the certificate bundle, including the private key
+type Secret struct {
+	Value       string `json:"value"`
+	ContentType string `json:"contentType"`
+}
+
+type importRequest struct {
+	Value  string            `json:"value"`
+	Policy importPolicy      `json:"policy"`
+	Tags   map[string]string `json:"tags,omitempty"`
+}
+
+type importPolicy struct {
+	SecretProperties struct {
+		ContentType string `json:"contentType"`
+	} `json:"secret_props"`
+}
+
+// Client is a minimal client for the Azure Key Vault REST API
+type Client struct {
+	vaultURI   string
+	token      string
+	httpClient *http.Client
+}
+
+// NewClient returns a Client for the vault at vaultURI, authenticated with the given credentials
+func NewClient(vaultURI string, credentials Credentials) (*Client, error) {
+	token, err := GetToken(credentials)
+	if err != nil {
+		return nil, err
+	}
+
+	return &Client{
+		vaultURI:   strings.TrimSuffix(vaultURI, "/"),
+		token:      token,
+		httpClient: &http.Client{Timeout: defaultTimeout},
+	}, nil
+}
+
+// GetCertificate retrieves the current version of the certificate name. Returns nil if the certificate does not exist
+func (c *Client) GetCertificate(ctx context.Context, name string) (*Certificate, error) {
+	return c.GetCertificateVersion(ctx, name, "")
+}
+
+// GetCertificateVersion retrieves the given version of the certificate name. Returns nil if it does not exist
+func (c *Client) GetCertificateVersion(ctx context.Context, name string, version string) (*Certificate, error) {
+	statusCode, body, err := c.request(ctx, http.MethodGet, objectPath("certificates", name, version), nil)
+	if err != nil {
+		return nil, err
+	}
+
+	switch statusCode {
+	case http.StatusOK:
+		cert := &Certificate{}
+		err = json.Unmarshal(body, cert)
+		if err != nil {
+			return nil, fmt.Errorf("could not parse certificate %s: %w", name, err)
+		}
+		return cert, nil
+	case http.StatusNotFound:
+		return nil, nil
+	default:
+		return nil, fmt.Errorf("unexpected status code retrieving certificate %s: %d %s", name, statusCode, string(body))
+	}
+}
+
+// GetSecret retrieves the given version of the secret that backs the certificate name
+func (c *Client) GetSecret(ctx context.Context, name string, version string) (*Secret, error) {
+	statusCode, body, err := c.request(ctx, http.MethodGet, objectPath("secrets", name, version), nil)
+	if err != nil {
+		return nil, err
+	}
+	if statusCode != http.StatusOK {
+		return nil, fmt.Errorf("unexpected status code retrieving secret %s: %d %s", name, statusCode, string(body))
+	}
+
+	secret := &Secret{}
+	err = json.Unmarshal(body, secret)
+	if err != nil {
+		return nil, fmt.Errorf("could not parse secret %s: %w", name, err)
+	}
+	return secret, nil
+}
+
+// ImportCertificate imports the certificate bundle in value as a new version of the certificate name.
+// contentType is either ContentTypePEM or ContentTypePKCS12 (base64 encoded)
+func (c *Client) ImportCertificate(ctx context.Context, name string, value string, contentType string, tags map[string]string) (*Certificate, error) {
+	data := importRequest{Value: value, Tags: tags}
+	data.Policy.SecretProperties.ContentType = contentType
+
+	statusCode, body, err := c.request(ctx, http.MethodPost, objectPath("certificates", name, "import"), data)
+	if err != nil {
+		return nil, err
+	}
+	if statusCode != http.StatusOK {
+		return nil, fmt.Errorf("unexpected status code importing certificate %s: %d %s", name, statusCode, string(body))
+	}
+
+	cert := &Certificate{}
+	err = json.Unmarshal(body, cert)
+	if err != nil {
+		return nil, fmt.Errorf("could not parse imported certificate %s: %w", name, err)
+	}
+	return cert, nil
+}
+
+func (c *Client) request(ctx context.Context, method string, path string, data interface{}) (int, []byte, error) {
+	var payload io.Reader
+	if data != nil {
+		b, err := json.Marshal(data)
+		if err != nil {
+			return 0, nil, err
+		}
+		payload = bytes.NewReader(b)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, method, fmt.Sprintf("%s%s?api-version=%s", c.vaultURI, path, apiVersion), payload)
+	if err != nil {
+		return 0, nil, err
+	}
+	req.Header.Set("Accept", "application/json")
+	if payload != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	req.Header.Set("Authorization", fmt.Sprintf("Bearer %s", c.token))
+
+	res, err := c.httpClient.Do(req)
+	if err != nil {
+		return 0, nil, err
+	}
+	defer res.Body.Close()
+
+	body, err := io.ReadAll(res.Body)
+	if err != nil {
+		return res.StatusCode, nil, err
+	}
+
+	return res.StatusCode, body, nil
+}
+
+func objectPath(collection string, name string, version string) string {
+	path := fmt.Sprintf("/%s/%s", collection, url.PathEscape(name))
+	if version != "" {
+		path = fmt.Sprintf("%s/%s", path, url.PathEscape(version))
+	}
+	return path
+}
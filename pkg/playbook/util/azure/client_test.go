@@ -0,0 +1,104 @@
+/*
+ * Copyright 2023 Venafi, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *  http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package azure
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+// newTestClient returns a Client for a mock vault, authenticated with a token from a mock authority
+func newTestClient(t *testing.T, vault http.Handler) *Client {
+	t.Helper()
+	authority := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write([]byte(`{"access_token":"vault-token","token_type":"Bearer","expires_in":3600}`))
+	}))
+	t.Cleanup(authority.Close)
+	t.Setenv(authorityHostEnvVar, authority.URL)
+
+	server := httptest.NewServer(vault)
+	t.Cleanup(server.Close)
+	client, err := NewClient(server.URL+"/", Credentials{TenantID: "tenant-1", ClientID: "client-1", ClientSecret: "secret-1"})
+	if err != nil {
+		t.Fatalf("failed to create client: %s", err)
+	}
+	return client
+}
+
+func TestImportCertificate(t *testing.T) {
+	ctx := context.Background()
+	var imported importRequest
+	client := newTestClient(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != "Bearer vault-token" || r.URL.Query().Get("api-version") != apiVersion {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		switch {
+		case r.Method == http.MethodGet && r.URL.Path == "/certificates/missing":
+			w.WriteHeader(http.StatusNotFound)
+			_, _ = w.Write([]byte(`{"error":{"code":"CertificateNotFound"}}`))
+		case r.Method == http.MethodPost && r.URL.Path == "/certificates/web/import":
+			err := json.NewDecoder(r.Body).Decode(&imported)
+			if err != nil {
+				w.WriteHeader(http.StatusBadRequest)
+				return
+			}
+			_, _ = w.Write([]byte(`{"id":"https://vault/certificates/web/v2","cer":"MAo=","attributes":{"enabled":true}}`))
+		case r.Method == http.MethodGet && r.URL.Path == "/certificates/web/v2":
+			_, _ = w.Write([]byte(`{"id":"https://vault/certificates/web/v2","attributes":{"enabled":true},"tags":{"owner":"vcert"}}`))
+		case r.Method == http.MethodGet && r.URL.Path == "/secrets/web/v2":
+			_, _ = w.Write([]byte(`{"value":"bundle","contentType":"application/x-pem-file"}`))
+		default:
+			w.WriteHeader(http.StatusInternalServerError)
+		}
+	}))
+
+	cert, err := client.GetCertificate(ctx, "missing")
+	if err != nil || cert != nil {
+		t.Fatalf("expected missing certificate to be nil but got %v, %v", cert, err)
+	}
+
+	cert, err = client.ImportCertificate(ctx, "web", "bundle", ContentTypePEM, map[string]string{"owner": "vcert"})
+	if err != nil {
+		t.Fatalf("failed to import certificate: %s", err)
+	}
+	if cert.Version() != "v2" || len(cert.CER) != 2 {
+		t.Fatalf("unexpected imported certificate %+v", cert)
+	}
+	if imported.Value != "bundle" || imported.Policy.SecretProperties.ContentType != ContentTypePEM || imported.Tags["owner"] != "vcert" {
+		t.Fatalf("unexpected import request %+v", imported)
+	}
+
+	cert, err = client.GetCertificateVersion(ctx, "web", "v2")
+	if err != nil || cert == nil || cert.Tags["owner"] != "vcert" {
+		t.Fatalf("unexpected certificate version %+v, %v", cert, err)
+	}
+	secret, err := client.GetSecret(ctx, "web", "v2")
+	if err != nil || secret.Value != "bundle" || secret.ContentType != ContentTypePEM {
+		t.Fatalf("unexpected secret %+v, %v", secret, err)
+	}
+
+	_, err = client.ImportCertificate(ctx, "other", "bundle", ContentTypePKCS12, nil)
+	if err == nil || !strings.Contains(err.Error(), "500") {
+		t.Fatalf("expected failed import to return the status code but got %v", err)
+	}
+}
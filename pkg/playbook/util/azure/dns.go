@@ -0,0 +1,155 @@
+/*
+ * Copyright 2023 Venafi, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *  http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package azure
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+)
+
+const dnsAPIVersion = "2018-05-01"
+
+type txtRecordSet struct {
+	Properties struct {
+		TTL        int `json:"TTL"`
+		TXTRecords []struct {
+			Value []string `json:"value"`
+		} `json:"TXTRecords"`
+	} `json:"properties"`
+}
+
+// DNSClient is a minimal client for the TXT record sets of the Azure DNS REST API
+type DNSClient struct {
+	baseURL    string
+	token      string
+	httpClient *http.Client
+}
+
+// NewDNSClient returns a DNSClient for the zones of the resource group, authenticated with the given credentials
+func NewDNSClient(subscriptionID string, resourceGroup string, credentials Credentials) (*DNSClient, error) {
+	token, err := getResourceToken(credentials, managementResource)
+	if err != nil {
+		return nil, err
+	}
+
+	return &DNSClient{
+		baseURL: fmt.Sprintf("%s/subscriptions/%s/resourceGroups/%s/providers/Microsoft.Network/dnsZones",
+			managementResource, url.PathEscape(subscriptionID), url.PathEscape(resourceGroup)),
+		token:      token,
+		httpClient: &http.Client{Timeout: defaultTimeout},
+	}, nil
+}
+
+// GetTXTRecord returns the values of the TXT record set name in zone. name is relative to the zone.
+// Returns nil if the record set does not exist
+func (c *DNSClient) GetTXTRecord(ctx context.Context, zone string, name string) ([]string, error) {
+	statusCode, body, err := c.request(ctx, http.MethodGet, zone, name, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	switch statusCode {
+	case http.StatusOK:
+		recordSet := txtRecordSet{}
+		err = json.Unmarshal(body, &recordSet)
+		if err != nil {
+			return nil, fmt.Errorf("could not parse TXT record %s: %w", name, err)
+		}
+		values := make([]string, 0, len(recordSet.Properties.TXTRecords))
+		for _, record := range recordSet.Properties.TXTRecords {
+			values = append(values, strings.Join(record.Value, ""))
+		}
+		return values, nil
+	case http.StatusNotFound:
+		return nil, nil
+	default:
+		return nil, fmt.Errorf("unexpected status code retrieving TXT record %s: %d %s", name, statusCode, string(body))
+	}
+}
+
+// SetTXTRecord creates or replaces the TXT record set name in zone with values
+func (c *DNSClient) SetTXTRecord(ctx context.Context, zone string, name string, values []string, ttl int) error {
+	recordSet := txtRecordSet{}
+	recordSet.Properties.TTL = ttl
+	for _, value := range values {
+		recordSet.Properties.TXTRecords = append(recordSet.Properties.TXTRecords, struct {
+			Value []string `json:"value"`
+		}{Value: []string{value}})
+	}
+
+	statusCode, body, err := c.request(ctx, http.MethodPut, zone, name, recordSet)
+	if err != nil {
+		return err
+	}
+	if statusCode != http.StatusOK && statusCode != http.StatusCreated {
+		return fmt.Errorf("unexpected status code setting TXT record %s: %d %s", name, statusCode, string(body))
+	}
+	return nil
+}
+
+// DeleteTXTRecord deletes the TXT record set name in zone. Deleting a record set that does not exist is not an error
+func (c *DNSClient) DeleteTXTRecord(ctx context.Context, zone string, name string) error {
+	statusCode, body, err := c.request(ctx, http.MethodDelete, zone, name, nil)
+	if err != nil {
+		return err
+	}
+	if statusCode != http.StatusOK && statusCode != http.StatusNoContent && statusCode != http.StatusNotFound {
+		return fmt.Errorf("unexpected status code deleting TXT record %s: %d %s", name, statusCode, string(body))
+	}
+	return nil
+}
+
+func (c *DNSClient) request(ctx context.Context, method string, zone string, name string, data interface{}) (int, []byte, error) {
+	var payload io.Reader
+	if data != nil {
+		b, err := json.Marshal(data)
+		if err != nil {
+			return 0, nil, err
+		}
+		payload = bytes.NewReader(b)
+	}
+
+	u := fmt.Sprintf("%s/%s/TXT/%s?api-version=%s", c.baseURL, url.PathEscape(zone), url.PathEscape(name), dnsAPIVersion)
+	req, err := http.NewRequestWithContext(ctx, method, u, payload)
+	if err != nil {
+		return 0, nil, err
+	}
+	req.Header.Set("Accept", "application/json")
+	if payload != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	req.Header.Set("Authorization", fmt.Sprintf("Bearer %s", c.token))
+
+	res, err := c.httpClient.Do(req)
+	if err != nil {
+		return 0, nil, err
+	}
+	defer res.Body.Close()
+
+	body, err := io.ReadAll(res.Body)
+	if err != nil {
+		return res.StatusCode, nil, err
+	}
+
+	return res.StatusCode, body, nil
+}
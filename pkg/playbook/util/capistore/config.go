@@ -19,10 +19,15 @@
 package capistore
 
 type InstallationConfig struct {
-	PFX             []byte
-	FriendlyName    string
-	IsNonExportable bool
-	Password        string
-	StoreLocation   string
-	StoreName       string
+	PFX []byte
+	// Certificate is the DER end-entity certificate installed by InstallCertificateForKeyToCAPI, whose private key
+	// already lives in KeyStorageProvider. Chain holds its DER chain certificates
+	Certificate        []byte
+	Chain              [][]byte
+	FriendlyName       string
+	IsNonExportable    bool
+	KeyStorageProvider string
+	Password           string
+	StoreLocation      string
+	StoreName          string
 }
@@ -9,8 +9,12 @@
     A boolean that controls whether or not the certificate should be exportable after it has been installed into the CAPI store
 .PARAMETER password
     The string password that was used to encrypt the private key
-.PARAMETER certStore
-    The location to store the certificate in CAPI
+.PARAMETER storeName
+    The store where the end-entity certificate is installed. Custom stores (i.e. WebHosting) are created if they do not exist
+.PARAMETER storeLocation
+    The location of the store, either LocalMachine or CurrentUser
+.PARAMETER keyStorageProvider
+    The optional CNG Key Storage Provider in which the private key is stored (i.e. Microsoft Platform Crypto Provider)
 ##################>
 function install-cert {
     [CmdletBinding()]
@@ -40,9 +44,16 @@ function install-cert {
                 throw "unable to read PFX from '$($_)'"
             }
         })]
-        [string] $certPath
+        [string] $certPath,
+
+        [string] $keyStorageProvider
     )
 
+    if ($keyStorageProvider) {
+        install-cert-ksp -friendlyName $friendlyName -storeName $storeName -storeLocation $storeLocation -isNonExportable $isNonExportable -password $password -certPath $certPath -keyStorageProvider $keyStorageProvider
+        return
+    }
+
     # Make the keyset accessible only to user when installing in CurrentUser
     $keyset = if ($storeLocation -eq [System.Security.Cryptography.X509Certificates.StoreLocation]::CurrentUser) {'UserKeySet'} else {'MachineKeyset'}
     $exportable = if (-not $isNonExportable) { 'Exportable,' }
@@ -105,7 +116,8 @@ function install-cert {
             }
         }
 
-        $capi = Get-Item "Cert:\$($storeLocation)\$($installToStore)"
+        # Opening the store with X509Store creates it when it does not exist, which allows installing into custom stores
+        $capi = New-Object System.Security.Cryptography.X509Certificates.X509Store($installToStore, $storeLocation)
         $capi.Open("ReadWrite")
         $capi.Add($cert)
         $capi.Close()
@@ -125,3 +137,188 @@ function install-cert {
         }
     }
 }
+
+<##################
+.DESCRIPTION
+    install-cert-ksp installs the PKCS#12 using certutil, so that the private key is stored in the given CNG
+    Key Storage Provider. Chain certificates are installed by certutil in the Root and CA stores
+##################>
+function install-cert-ksp {
+    [CmdletBinding()]
+    param (
+        [string] $friendlyName,
+        [string] $storeName,
+        [System.Security.Cryptography.X509Certificates.storeLocation] $storeLocation,
+        [bool] $isNonExportable,
+        [string] $password,
+        [string] $certPath,
+        [string] $keyStorageProvider
+    )
+
+    # Find the end-entity certificate without persisting its private key
+    $collection = New-Object System.Security.Cryptography.X509Certificates.X509Certificate2Collection
+    $collection.Import($certPath, $password, "DefaultKeySet")
+
+    $endEntity = $null
+    foreach ($cert in $collection.GetEnumerator())
+    {
+        $is_ca_cert = $false
+        foreach ($ext in $cert.Extensions)
+        {
+            if ($ext.GetType().Name -eq "X509BasicConstraintsExtension")
+            {
+                $is_ca_cert = $ext.CertificateAuthority
+                break
+            }
+        }
+        if (-not $is_ca_cert)
+        {
+            $endEntity = $cert
+        }
+    }
+
+    if ($null -eq $endEntity)
+    {
+        throw "PFX does not contain an end-entity certificate"
+    }
+
+    $certItem = "Cert:\$($storeLocation)\$($storeName)\$($endEntity.Thumbprint)"
+    if (Test-Path $certItem)
+    {
+        $existing = Get-Item $certItem
+        if ($existing.FriendlyName -ne $friendlyName)
+        {
+            throw "Certificate already installed but FriendlyName does not match - $($existing.FriendlyName)"
+        }
+        return
+    }
+
+    # Make sure custom stores exist before certutil imports into them
+    $store = New-Object System.Security.Cryptography.X509Certificates.X509Store($storeName, $storeLocation)
+    $store.Open("ReadWrite")
+    $store.Close()
+
+    $arguments = @("-f", "-p", $password, "-csp", $keyStorageProvider)
+    if ($storeLocation -eq [System.Security.Cryptography.X509Certificates.StoreLocation]::CurrentUser)
+    {
+        $arguments += "-user"
+    }
+    $arguments += @("-importpfx", $storeName, $certPath)
+    if ($isNonExportable)
+    {
+        $arguments += "NoExport"
+    }
+
+    $output = & certutil.exe $arguments
+    if ($LASTEXITCODE -ne 0)
+    {
+        throw "certutil failed to import certificate using key storage provider '$($keyStorageProvider)': $($output)"
+    }
+
+    # wait two seconds before checking to see the installation was successful
+    Start-Sleep -s 2
+
+    if (!(Test-Path $certItem))
+    {
+        throw "Could not install certificate on target system"
+    }
+
+    $installed = Get-Item $certItem
+    $installed.FriendlyName = $friendlyName
+}
+
+<##################
+.DESCRIPTION
+    install-cert-for-key installs an end-entity certificate whose private key already lives in a CNG Key Storage
+    Provider, like the keys generated in the TPM, and binds it to that key using certutil. Chain certificates are
+    installed in the Root and CA stores
+.PARAMETER certDir
+    The directory holding the end-entity certificate, cert.cer, and the chain certificates, chain-<n>.cer
+.PARAMETER keyStorageProvider
+    The CNG Key Storage Provider holding the private key of the certificate (i.e. Microsoft Platform Crypto Provider)
+##################>
+function install-cert-for-key {
+    [CmdletBinding()]
+    param (
+        [Parameter(Mandatory)]
+        [string] $friendlyName,
+
+        [Parameter(Mandatory)]
+        [string] $storeName,
+
+        [Parameter(Mandatory)]
+        [System.Security.Cryptography.X509Certificates.storeLocation] $storeLocation,
+
+        [Parameter(Mandatory)]
+        [ValidateNotNullOrEmpty()]
+        [string] $keyStorageProvider,
+
+        [Parameter(Mandatory)]
+        [ValidateScript( {
+            if (Test-Path -Path $_) {
+                $true
+            } else {
+                throw "unable to read certificates from '$($_)'"
+            }
+        })]
+        [string] $certDir
+    )
+
+    foreach ($chainFile in Get-ChildItem -Path $certDir -Filter "chain-*.cer")
+    {
+        $chainCert = New-Object System.Security.Cryptography.X509Certificates.X509Certificate2($chainFile.FullName)
+        $chainStore = if ($chainCert.Issuer -eq $chainCert.Subject) {"Root"} else {"CA"}
+        if (Test-Path "Cert:\$($storeLocation)\$($chainStore)\$($chainCert.Thumbprint)")
+        {
+            continue  # already in the CAPI store
+        }
+
+        $capi = New-Object System.Security.Cryptography.X509Certificates.X509Store($chainStore, $storeLocation)
+        $capi.Open("ReadWrite")
+        $capi.Add($chainCert)
+        $capi.Close()
+    }
+
+    $cert = New-Object System.Security.Cryptography.X509Certificates.X509Certificate2("$($certDir)\cert.cer")
+    $certItem = "Cert:\$($storeLocation)\$($storeName)\$($cert.Thumbprint)"
+    if (Test-Path $certItem)
+    {
+        $existing = Get-Item $certItem
+        if ($existing.FriendlyName -ne $friendlyName)
+        {
+            throw "Certificate already installed but FriendlyName does not match - $($existing.FriendlyName)"
+        }
+        if ($existing.HasPrivateKey)
+        {
+            return
+        }
+    }
+    else
+    {
+        # Opening the store with X509Store creates it when it does not exist, which allows installing into custom stores
+        $cert.FriendlyName = $friendlyName
+        $capi = New-Object System.Security.Cryptography.X509Certificates.X509Store($storeName, $storeLocation)
+        $capi.Open("ReadWrite")
+        $capi.Add($cert)
+        $capi.Close()
+    }
+
+    # certutil finds the key of the provider matching the public key of the certificate, and links them
+    $arguments = @("-csp", $keyStorageProvider)
+    if ($storeLocation -eq [System.Security.Cryptography.X509Certificates.StoreLocation]::CurrentUser)
+    {
+        $arguments += "-user"
+    }
+    $arguments += @("-repairstore", $storeName, $cert.Thumbprint)
+
+    $output = & certutil.exe $arguments
+    if ($LASTEXITCODE -ne 0)
+    {
+        throw "certutil failed to bind certificate to its private key in key storage provider '$($keyStorageProvider)': $($output)"
+    }
+
+    if (-not (Get-Item $certItem).HasPrivateKey)
+    {
+        throw "Certificate installed but its private key was not found in key storage provider '$($keyStorageProvider)'"
+    }
+}
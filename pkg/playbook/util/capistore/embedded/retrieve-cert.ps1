@@ -3,8 +3,10 @@
     retrieve-cert verifies an end-entity certificate is installed in the Personal CAPI store and saves it to a file
 .PARAMETER friendlyName
     A text string that is used to identify the certificate when extracting it from the CAPI store
-.PARAMETER certStore
-    The location to store the certificate in CAPI
+.PARAMETER storeName
+    The store where the certificate is installed. Custom store names (i.e. WebHosting) are supported
+.PARAMETER storeLocation
+    The location of the store, either LocalMachine or CurrentUser
  #>
 ##################>
 Set-StrictMode -Version Latest
@@ -15,13 +17,19 @@ function retrieve-cert {
         [Parameter(Mandatory)]
         [string] $friendlyName,
         [Parameter(Mandatory)]
-        [System.Security.Cryptography.X509Certificates.storeName] $storeName,
+        [string] $storeName,
         [Parameter(Mandatory)]
         [System.Security.Cryptography.X509Certificates.storeLocation] $storeLocation
     )
     # Get the certificate store
     $store = New-Object System.Security.Cryptography.X509Certificates.X509Store($storeName, $storeLocation)
-    $store.Open([System.Security.Cryptography.X509Certificates.OpenFlags]::ReadOnly)
+    try {
+        $store.Open([System.Security.Cryptography.X509Certificates.OpenFlags]::ReadOnly -bor [System.Security.Cryptography.X509Certificates.OpenFlags]::OpenExistingOnly)
+    } catch {
+        # Custom stores do not exist until the first certificate is installed
+        Write-Output -InputObject "certificate not found: $($friendlyName)"
+        return
+    }
 
     # Find unexpired certificates by friendly name
     $certs = $store.Certificates | Where-Object { ($_.FriendlyName -eq $friendlyName) -and ($_.NotAfter -gt (Get-Date)) } 
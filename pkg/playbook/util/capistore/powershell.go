@@ -24,6 +24,7 @@ import (
 	"fmt"
 	"os"
 	"os/exec"
+	"path/filepath"
 	"strings"
 
 	"github.com/google/uuid"
@@ -66,6 +67,20 @@ func (ps PowerShell) InstallCertificateToCAPI(config InstallationConfig) error {
 		return errors.WithMessagef(err, m)
 	}
 
+	// verify store name and key storage provider don't have command injection, as custom values are allowed for both
+	err = containsInjectableData(config.StoreName)
+	if err != nil {
+		m := "failed to install certificate because of invalid characters in storeName"
+		zap.L().Error(m)
+		return errors.WithMessagef(err, m)
+	}
+	err = containsInjectableData(config.KeyStorageProvider)
+	if err != nil {
+		m := "failed to install certificate because of invalid characters in keyStorageProvider"
+		zap.L().Error(m)
+		return errors.WithMessagef(err, m)
+	}
+
 	err = os.WriteFile(pfxPath, config.PFX, 0600)
 	if err != nil {
 		zap.L().Error("could not create certificate temp file", zap.Error(err))
@@ -87,6 +102,9 @@ func (ps PowerShell) InstallCertificateToCAPI(config InstallationConfig) error {
 		"storeName":       config.StoreName,
 		"storeLocation":   config.StoreLocation,
 	}
+	if config.KeyStorageProvider != "" {
+		params["keyStorageProvider"] = config.KeyStorageProvider
+	}
 
 	stdout, err := ps.executeScript(installCertScript, "install-cert", params)
 	if err != nil {
@@ -98,6 +116,64 @@ func (ps PowerShell) InstallCertificateToCAPI(config InstallationConfig) error {
 	return err
 }
 
+// InstallCertificateForKeyToCAPI installs config.Certificate in the CAPI store and binds it to its private key, which
+// already lives in config.KeyStorageProvider, like the keys generated in the TPM. The chain certificates are installed
+// in the Root and CA stores
+func (ps PowerShell) InstallCertificateForKeyToCAPI(config InstallationConfig) error {
+	for field, value := range map[string]string{
+		"friendlyName":       config.FriendlyName,
+		"storeName":          config.StoreName,
+		"keyStorageProvider": config.KeyStorageProvider,
+	} {
+		err := containsInjectableData(value)
+		if err != nil {
+			m := fmt.Sprintf("failed to install certificate because of invalid characters in %s", field)
+			zap.L().Error(m)
+			return errors.WithMessagef(err, m)
+		}
+	}
+
+	certDir, err := os.MkdirTemp("", "vcert-capi-")
+	if err != nil {
+		zap.L().Error("could not create certificate temp directory", zap.Error(err))
+		return err
+	}
+	defer func() {
+		if delErr := os.RemoveAll(certDir); delErr != nil {
+			zap.L().Warn("failed to delete temporary certificate directory", zap.Error(delErr))
+		}
+	}()
+
+	err = os.WriteFile(filepath.Join(certDir, "cert.cer"), config.Certificate, 0600)
+	if err != nil {
+		zap.L().Error("could not create certificate temp file", zap.Error(err))
+		return err
+	}
+	for i, chainCert := range config.Chain {
+		err = os.WriteFile(filepath.Join(certDir, fmt.Sprintf("chain-%d.cer", i)), chainCert, 0600)
+		if err != nil {
+			zap.L().Error("could not create chain certificate temp file", zap.Error(err))
+			return err
+		}
+	}
+
+	params := map[string]string{
+		"certDir":            certDir,
+		"friendlyName":       config.FriendlyName,
+		"keyStorageProvider": config.KeyStorageProvider,
+		"storeName":          config.StoreName,
+		"storeLocation":      config.StoreLocation,
+	}
+
+	stdout, err := ps.executeScript(installCertScript, "install-cert-for-key", params)
+	if err != nil {
+		m := "failed to install certificate into CAPI"
+		zap.L().Error(m, zap.String("stdout", stdout), zap.Error(err))
+		return errors.WithMessagef(err, "%s, stdout: '%s'", m, stdout)
+	}
+	return nil
+}
+
 // RetrieveCertificateFromCAPI looks for a certificate in the CAPI store config.CertStore that matches the given config.FriendlyName.
 // If found, it returns the certificate in PEM format as a string
 func (ps PowerShell) RetrieveCertificateFromCAPI(config InstallationConfig) (string, error) {
@@ -110,6 +186,12 @@ func (ps PowerShell) RetrieveCertificateFromCAPI(config InstallationConfig) (str
 		zap.L().Error(m)
 		return "", errors.WithMessagef(err, m)
 	}
+	err = containsInjectableData(config.StoreName)
+	if err != nil {
+		m := "failed to retrieve certificate because of invalid characters in storeName"
+		zap.L().Error(m)
+		return "", errors.WithMessagef(err, m)
+	}
 
 	params := map[string]string{
 		"friendlyName":  config.FriendlyName,
@@ -0,0 +1,328 @@
+/*
+ * Copyright 2023 Venafi, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *  http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package citrix
+
+import (
+	"bytes"
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/Venafi/vcert/v5/pkg/playbook/util"
+)
+
+const (
+	defaultTimeout = 30 * time.Second
+
+	// CertificateDirectory is where the ADC looks for the certificate and key files of the certkeys
+	CertificateDirectory = "/nsconfig/ssl"
+
+	// errorCodeNoSuchResource is the Nitro error code returned for resources that do not exist
+	errorCodeNoSuchResource = "258"
+)
+
+// CertKey represents a certificate-key pair of the ADC (sslcertkey)
+type CertKey struct {
+	CertKey         string `json:"certkey"`
+	Cert            string `json:"cert,omitempty"`
+	Key             string `json:"key,omitempty"`
+	LinkCertKeyName string `json:"linkcertkeyname,omitempty"`
+	Serial          string `json:"serial,omitempty"`
+	Subject         string `json:"subject,omitempty"`
+	NotBefore       string `json:"clientcertnotbefore,omitempty"`
+	NotAfter        string `json:"clientcertnotafter,omitempty"`
+}
+
+// VServerBinding is the binding of a certkey to an SSL virtual server (sslvserver_sslcertkey_binding)
+type VServerBinding struct {
+	VServerName string `json:"vservername"`
+	CertKeyName string `json:"certkeyname"`
+	CA          bool   `json:"ca,omitempty"`
+	SNICert     bool   `json:"snicert,omitempty"`
+}
+
+// Client is a minimal client for the Nitro API of Citrix ADC (NetScaler)
+type Client struct {
+	address    string
+	sessionID  string
+	httpClient *http.Client
+}
+
+// NewClient returns a Client for the ADC at address, authenticated with a session opened for username and password.
+// The session should be closed with Logout once done.
+//
+// caCert is the optional path to a PEM bundle used to verify the ADC management certificate. When insecure is true,
+// the management certificate is not verified at all
+func NewClient(ctx context.Context, address string, username string, password string, caCert string, insecure bool) (*Client, error) {
+	tlsConfig := &tls.Config{InsecureSkipVerify: insecure} //nolint:gosec
+	if caCert != "" {
+		data, err := os.ReadFile(caCert)
+		if err != nil {
+			return nil, fmt.Errorf("could not read Citrix ADC CA certificate: %w", err)
+		}
+		pool := x509.NewCertPool()
+		if !pool.AppendCertsFromPEM(data) {
+			return nil, fmt.Errorf("no certificates found in Citrix ADC CA certificate %s", caCert)
+		}
+		tlsConfig.RootCAs = pool
+	}
+
+	if !strings.Contains(address, "://") {
+		address = "https://" + address
+	}
+	client := &Client{
+		address: strings.TrimSuffix(address, "/"),
+		httpClient: &http.Client{
+			Timeout: defaultTimeout,
+			Transport: &http.Transport{
+				Proxy:           http.ProxyFromEnvironment,
+				TLSClientConfig: tlsConfig,
+			},
+		},
+	}
+
+	sessionID, err := client.login(ctx, username, password)
+	if err != nil {
+		return nil, err
+	}
+	client.sessionID = sessionID
+
+	return client, nil
+}
+
+func (c *Client) login(ctx context.Context, username string, password string) (string, error) {
+	data := map[string]interface{}{
+		"login": map[string]string{
+			"username": username,
+			"password": password,
+		},
+	}
+	response := struct {
+		SessionID string `json:"sessionid"`
+	}{}
+	err := c.do(ctx, http.MethodPost, "/nitro/v1/config/login", data, &response)
+	if err != nil {
+		return "", fmt.Errorf("could not log in to Citrix ADC: %w", err)
+	}
+	if response.SessionID == "" {
+		return "", fmt.Errorf("could not log in to Citrix ADC: no session returned")
+	}
+	return response.SessionID, nil
+}
+
+// Logout closes the session of the client
+func (c *Client) Logout(ctx context.Context) error {
+	data := map[string]interface{}{"logout": map[string]string{}}
+	return c.do(ctx, http.MethodPost, "/nitro/v1/config/logout", data, nil)
+}
+
+// ListFiles returns the names of the files of CertificateDirectory
+func (c *Client) ListFiles(ctx context.Context) ([]string, error) {
+	response := struct {
+		SystemFile []struct {
+			FileName string `json:"filename"`
+		} `json:"systemfile"`
+	}{}
+	err := c.do(ctx, http.MethodGet, "/nitro/v1/config/systemfile?"+fileLocationArgs(), nil, &response)
+	if err != nil {
+		return nil, err
+	}
+	names := make([]string, 0, len(response.SystemFile))
+	for _, file := range response.SystemFile {
+		names = append(names, file.FileName)
+	}
+	return names, nil
+}
+
+// UploadFile creates the file name in CertificateDirectory with data
+func (c *Client) UploadFile(ctx context.Context, name string, data []byte) error {
+	file := map[string]interface{}{
+		"systemfile": map[string]string{
+			"filename":     name,
+			"filelocation": CertificateDirectory,
+			"filecontent":  base64.StdEncoding.EncodeToString(data),
+			"fileencoding": "BASE64",
+		},
+	}
+	return c.do(ctx, http.MethodPost, "/nitro/v1/config/systemfile", file, nil)
+}
+
+// DeleteFile removes the file name from CertificateDirectory. No error is returned if it does not exist
+func (c *Client) DeleteFile(ctx context.Context, name string) error {
+	path := fmt.Sprintf("/nitro/v1/config/systemfile/%s?%s", url.PathEscape(name), fileLocationArgs())
+	err := c.do(ctx, http.MethodDelete, path, nil, nil)
+	if util.IsNotFound(err, errorCodeNoSuchResource) {
+		return nil
+	}
+	return err
+}
+
+// GetCertKey returns the certkey name. Returns nil if it does not exist
+func (c *Client) GetCertKey(ctx context.Context, name string) (*CertKey, error) {
+	response := struct {
+		CertKeys []CertKey `json:"sslcertkey"`
+	}{}
+	err := c.do(ctx, http.MethodGet, "/nitro/v1/config/sslcertkey/"+url.PathEscape(name), nil, &response)
+	if err != nil {
+		if util.IsNotFound(err, errorCodeNoSuchResource) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	if len(response.CertKeys) == 0 {
+		return nil, nil
+	}
+	return &response.CertKeys[0], nil
+}
+
+// AddCertKey creates a certkey from files of CertificateDirectory. key is empty for CA certificates
+func (c *Client) AddCertKey(ctx context.Context, certKey CertKey) error {
+	return c.do(ctx, http.MethodPost, "/nitro/v1/config/sslcertkey", map[string]interface{}{"sslcertkey": certKey}, nil)
+}
+
+// UpdateCertKey replaces the certificate and key files of an existing certkey. Virtual servers the certkey is bound to
+// use the new certificate right away
+func (c *Client) UpdateCertKey(ctx context.Context, name string, cert string, key string) error {
+	data := map[string]interface{}{
+		"sslcertkey": map[string]interface{}{
+			"certkey":       name,
+			"cert":          cert,
+			"key":           key,
+			"nodomaincheck": true,
+		},
+	}
+	return c.do(ctx, http.MethodPost, "/nitro/v1/config/sslcertkey?action=update", data, nil)
+}
+
+// LinkCertKey links the certkey name to the certkey of its issuer, so the ADC sends it as part of the chain
+func (c *Client) LinkCertKey(ctx context.Context, name string, issuer string) error {
+	data := map[string]interface{}{
+		"sslcertkey": map[string]string{
+			"certkey":         name,
+			"linkcertkeyname": issuer,
+		},
+	}
+	return c.do(ctx, http.MethodPost, "/nitro/v1/config/sslcertkey?action=link", data, nil)
+}
+
+// UnlinkCertKey removes the link of the certkey name to the certkey of its issuer
+func (c *Client) UnlinkCertKey(ctx context.Context, name string) error {
+	data := map[string]interface{}{"sslcertkey": map[string]string{"certkey": name}}
+	return c.do(ctx, http.MethodPost, "/nitro/v1/config/sslcertkey?action=unlink", data, nil)
+}
+
+// GetVServerBindings returns the certkeys bound to the SSL virtual server name
+func (c *Client) GetVServerBindings(ctx context.Context, name string) ([]VServerBinding, error) {
+	response := struct {
+		Bindings []VServerBinding `json:"sslvserver_sslcertkey_binding"`
+	}{}
+	err := c.do(ctx, http.MethodGet, "/nitro/v1/config/sslvserver_sslcertkey_binding/"+url.PathEscape(name), nil, &response)
+	if err != nil {
+		return nil, err
+	}
+	return response.Bindings, nil
+}
+
+// BindVServer binds the certkey to the SSL virtual server vserver as its server certificate
+func (c *Client) BindVServer(ctx context.Context, vserver string, certKey string) error {
+	data := map[string]interface{}{
+		"sslvserver_sslcertkey_binding": VServerBinding{VServerName: vserver, CertKeyName: certKey},
+	}
+	return c.do(ctx, http.MethodPut, "/nitro/v1/config/sslvserver_sslcertkey_binding", data, nil)
+}
+
+// UnbindVServer removes the binding of the certkey to the SSL virtual server vserver
+func (c *Client) UnbindVServer(ctx context.Context, vserver string, certKey string) error {
+	path := fmt.Sprintf("/nitro/v1/config/sslvserver_sslcertkey_binding/%s?args=certkeyname:%s",
+		url.PathEscape(vserver), url.QueryEscape(certKey))
+	return c.do(ctx, http.MethodDelete, path, nil, nil)
+}
+
+// SaveConfig saves the running configuration of the ADC, so the changes survive a reboot
+func (c *Client) SaveConfig(ctx context.Context) error {
+	data := map[string]interface{}{"nsconfig": map[string]string{}}
+	return c.do(ctx, http.MethodPost, "/nitro/v1/config/nsconfig?action=save", data, nil)
+}
+
+func (c *Client) do(ctx context.Context, method string, path string, data interface{}, result interface{}) error {
+	var body io.Reader
+	if data != nil {
+		payload, err := json.Marshal(data)
+		if err != nil {
+			return err
+		}
+		body = bytes.NewReader(payload)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, method, c.address+path, body)
+	if err != nil {
+		return err
+	}
+	if data != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	if c.sessionID != "" {
+		req.AddCookie(&http.Cookie{Name: "NITRO_AUTH_TOKEN", Value: c.sessionID})
+	}
+
+	res, err := c.httpClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer res.Body.Close()
+
+	resBody, err := io.ReadAll(res.Body)
+	if err != nil {
+		return err
+	}
+
+	if res.StatusCode < 200 || res.StatusCode > 299 {
+		apiErr := struct {
+			ErrorCode int    `json:"errorcode"`
+			Message   string `json:"message"`
+		}{}
+		_ = json.Unmarshal(resBody, &apiErr)
+		httpErr := &util.HTTPError{Service: "Citrix ADC", Method: method, Path: path, StatusCode: res.StatusCode,
+			Message: apiErr.Message}
+		if apiErr.ErrorCode != 0 {
+			httpErr.Code = strconv.Itoa(apiErr.ErrorCode)
+		}
+		return httpErr
+	}
+
+	if result != nil && len(resBody) > 0 {
+		err = json.Unmarshal(resBody, result)
+		if err != nil {
+			return fmt.Errorf("could not parse Citrix ADC response for %s: %w", path, err)
+		}
+	}
+	return nil
+}
+
+func fileLocationArgs() string {
+	return "args=filelocation:" + url.QueryEscape(CertificateDirectory)
+}
@@ -0,0 +1,218 @@
+/*
+ * Copyright 2023 Venafi, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *  http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package citrix
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+// nitroServer mocks the Nitro endpoints used by the client. Missing resources are reported the way the ADC does for
+// most of them: a 599 status with the errorcode 258
+type nitroServer struct {
+	t        *testing.T
+	files    map[string][]byte
+	certKeys map[string]CertKey
+	saved    bool
+}
+
+func (s *nitroServer) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	fail := func(status int, code int, message string) {
+		w.WriteHeader(status)
+		_, _ = fmt.Fprintf(w, `{"errorcode":%d,"message":%q,"severity":"ERROR"}`, code, message)
+	}
+	body, _ := io.ReadAll(r.Body)
+
+	if r.URL.Path == "/nitro/v1/config/login" {
+		login := struct {
+			Login map[string]string `json:"login"`
+		}{}
+		_ = json.Unmarshal(body, &login)
+		if login.Login["username"] != "nsroot" || login.Login["password"] != "secret" {
+			fail(http.StatusUnauthorized, 354, "Invalid username or password")
+			return
+		}
+		w.WriteHeader(http.StatusCreated)
+		_, _ = w.Write([]byte(`{"errorcode":0,"sessionid":"nitro-session"}`))
+		return
+	}
+	cookie, err := r.Cookie("NITRO_AUTH_TOKEN")
+	if err != nil || cookie.Value != "nitro-session" {
+		fail(http.StatusUnauthorized, 444, "Invalid session")
+		return
+	}
+
+	request := struct {
+		SystemFile map[string]string `json:"systemfile"`
+		CertKey    CertKey           `json:"sslcertkey"`
+	}{}
+	_ = json.Unmarshal(body, &request)
+
+	switch path := r.URL.Path; {
+	case path == "/nitro/v1/config/logout" || path == "/nitro/v1/config/nsconfig":
+		s.saved = s.saved || r.URL.Query().Get("action") == "save"
+	case path == "/nitro/v1/config/systemfile" && r.Method == http.MethodGet:
+		files := make([]map[string]string, 0)
+		for name := range s.files {
+			files = append(files, map[string]string{"filename": name})
+		}
+		_ = json.NewEncoder(w).Encode(map[string]interface{}{"systemfile": files})
+	case path == "/nitro/v1/config/systemfile" && r.Method == http.MethodPost:
+		if request.SystemFile["filelocation"] != CertificateDirectory || request.SystemFile["fileencoding"] != "BASE64" {
+			s.t.Errorf("unexpected file %v", request.SystemFile)
+		}
+		if _, found := s.files[request.SystemFile["filename"]]; found {
+			fail(http.StatusConflict, 1642, "Object already exists")
+			return
+		}
+		data, _ := base64.StdEncoding.DecodeString(request.SystemFile["filecontent"])
+		s.files[request.SystemFile["filename"]] = data
+		w.WriteHeader(http.StatusCreated)
+	case strings.HasPrefix(path, "/nitro/v1/config/systemfile/") && r.Method == http.MethodDelete:
+		name := strings.TrimPrefix(path, "/nitro/v1/config/systemfile/")
+		if _, found := s.files[name]; !found {
+			fail(599, 258, "No such resource")
+			return
+		}
+		delete(s.files, name)
+	case path == "/nitro/v1/config/sslcertkey" && r.Method == http.MethodPost:
+		if _, found := s.files[request.CertKey.Cert]; !found {
+			fail(599, 1540, "Certificate does not exist")
+			return
+		}
+		switch r.URL.Query().Get("action") {
+		case "":
+			if _, found := s.certKeys[request.CertKey.CertKey]; found {
+				fail(http.StatusConflict, 273, "Resource already exists")
+				return
+			}
+			s.certKeys[request.CertKey.CertKey] = request.CertKey
+			w.WriteHeader(http.StatusCreated)
+		case "update":
+			certKey, found := s.certKeys[request.CertKey.CertKey]
+			if !found {
+				fail(599, 258, "No such resource")
+				return
+			}
+			certKey.Cert, certKey.Key = request.CertKey.Cert, request.CertKey.Key
+			s.certKeys[request.CertKey.CertKey] = certKey
+		}
+	case strings.HasPrefix(path, "/nitro/v1/config/sslcertkey/") && r.Method == http.MethodGet:
+		certKey, found := s.certKeys[strings.TrimPrefix(path, "/nitro/v1/config/sslcertkey/")]
+		if !found {
+			fail(599, 258, "No such resource [certkey, web]")
+			return
+		}
+		_ = json.NewEncoder(w).Encode(map[string]interface{}{"sslcertkey": []CertKey{certKey}})
+	default:
+		fail(http.StatusNotFound, 344, "Unknown resource")
+	}
+}
+
+func TestClient(t *testing.T) {
+	ctx := context.Background()
+	nitro := &nitroServer{t: t, files: map[string][]byte{}, certKeys: map[string]CertKey{}}
+	server := httptest.NewServer(nitro)
+	defer server.Close()
+
+	_, err := NewClient(ctx, server.URL, "nsroot", "wrong", "", false)
+	if err == nil || !strings.Contains(err.Error(), "Invalid username or password") {
+		t.Fatalf("expected login with invalid password to fail, got %v", err)
+	}
+	client, err := NewClient(ctx, server.URL+"/", "nsroot", "secret", "", false)
+	if err != nil {
+		t.Fatalf("failed to create client: %s", err)
+	}
+
+	// Not found
+	certKey, err := client.GetCertKey(ctx, "web")
+	if err != nil || certKey != nil {
+		t.Fatalf("expected missing certkey to return nil, got %v, %v", certKey, err)
+	}
+	err = client.DeleteFile(ctx, "web.crt")
+	if err != nil {
+		t.Fatalf("expected delete of a missing file to succeed, got %s", err)
+	}
+	err = client.UpdateCertKey(ctx, "web", "web.crt", "web.key")
+	if err == nil || !strings.Contains(err.Error(), "(1540)") {
+		t.Fatalf("expected update with missing files to fail, got %v", err)
+	}
+
+	// Create
+	for name, data := range map[string]string{"web-1.crt": "cert-1", "web-1.key": "key-1"} {
+		err = client.UploadFile(ctx, name, []byte(data))
+		if err != nil {
+			t.Fatalf("failed to upload %s: %s", name, err)
+		}
+	}
+	err = client.UploadFile(ctx, "web-1.crt", []byte("cert-1"))
+	if err == nil || !strings.Contains(err.Error(), "409 Object already exists (1642)") {
+		t.Fatalf("expected upload of an existing file to fail, got %v", err)
+	}
+	err = client.AddCertKey(ctx, CertKey{CertKey: "web", Cert: "web-1.crt", Key: "web-1.key"})
+	if err != nil {
+		t.Fatalf("failed to add certkey: %s", err)
+	}
+
+	// Update
+	for name, data := range map[string]string{"web-2.crt": "cert-2", "web-2.key": "key-2"} {
+		err = client.UploadFile(ctx, name, []byte(data))
+		if err != nil {
+			t.Fatalf("failed to upload %s: %s", name, err)
+		}
+	}
+	err = client.UpdateCertKey(ctx, "web", "web-2.crt", "web-2.key")
+	if err != nil {
+		t.Fatalf("failed to update certkey: %s", err)
+	}
+	certKey, err = client.GetCertKey(ctx, "web")
+	if err != nil {
+		t.Fatalf("failed to get certkey: %s", err)
+	}
+	if certKey.Cert != "web-2.crt" || certKey.Key != "web-2.key" {
+		t.Fatalf("unexpected certkey %v", certKey)
+	}
+	for _, name := range []string{"web-1.crt", "web-1.key"} {
+		err = client.DeleteFile(ctx, name)
+		if err != nil {
+			t.Fatalf("failed to delete %s: %s", name, err)
+		}
+	}
+	files, err := client.ListFiles(ctx)
+	if err != nil {
+		t.Fatalf("failed to list files: %s", err)
+	}
+	if len(files) != 2 || string(nitro.files["web-2.crt"]) != "cert-2" {
+		t.Fatalf("unexpected files %v", files)
+	}
+
+	err = client.SaveConfig(ctx)
+	if err != nil || !nitro.saved {
+		t.Fatalf("failed to save configuration: %v", err)
+	}
+	err = client.Logout(ctx)
+	if err != nil {
+		t.Fatalf("failed to log out: %s", err)
+	}
+}
@@ -0,0 +1,170 @@
+/*
+ * Copyright 2023 Venafi, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *  http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package cloudflare
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+	"time"
+)
+
+const (
+	defaultBaseURL = "https://api.cloudflare.com/client/v4"
+	envBaseURL     = "CLOUDFLARE_API_URL"
+	defaultTimeout = 30 * time.Second
+)
+
+// Record represents a Cloudflare DNS record
+type Record struct {
+	ID      string `json:"id,omitempty"`
+	Type    string `json:"type"`
+	Name    string `json:"name"`
+	Content string `json:"content"`
+	TTL     int    `json:"ttl,omitempty"`
+}
+
+type response struct {
+	Success bool `json:"success"`
+	Errors  []struct {
+		Code    int    `json:"code"`
+		Message string `json:"message"`
+	} `json:"errors"`
+	Result json.RawMessage `json:"result"`
+}
+
+// Client is a minimal client for the zones and DNS records of the Cloudflare API
+type Client struct {
+	baseURL    string
+	apiToken   string
+	httpClient *http.Client
+}
+
+// NewClient returns a Client authenticated with the API token, which needs the Zone:Read and DNS:Edit permissions
+func NewClient(apiToken string) *Client {
+	baseURL := os.Getenv(envBaseURL)
+	if baseURL == "" {
+		baseURL = defaultBaseURL
+	}
+	return &Client{
+		baseURL:    strings.TrimSuffix(baseURL, "/"),
+		apiToken:   apiToken,
+		httpClient: &http.Client{Timeout: defaultTimeout},
+	}
+}
+
+// FindZone returns the ID of the zone for the domain name, i.e. "example.com". Returns an empty string if there is no such zone
+func (c *Client) FindZone(ctx context.Context, name string) (string, error) {
+	query := url.Values{}
+	query.Set("name", strings.TrimSuffix(name, "."))
+
+	var zones []struct {
+		ID string `json:"id"`
+	}
+	err := c.call(ctx, http.MethodGet, "/zones?"+query.Encode(), nil, &zones)
+	if err != nil {
+		return "", err
+	}
+	if len(zones) == 0 {
+		return "", nil
+	}
+	return zones[0].ID, nil
+}
+
+// ListTXTRecords returns the TXT records name, a fully qualified name, in the zone
+func (c *Client) ListTXTRecords(ctx context.Context, zoneID string, name string) ([]Record, error) {
+	query := url.Values{}
+	query.Set("type", "TXT")
+	query.Set("name", strings.TrimSuffix(name, "."))
+
+	var records []Record
+	err := c.call(ctx, http.MethodGet, fmt.Sprintf("/zones/%s/dns_records?%s", url.PathEscape(zoneID), query.Encode()), nil, &records)
+	if err != nil {
+		return nil, err
+	}
+	return records, nil
+}
+
+// CreateTXTRecord adds a TXT record name with the value content to the zone. Other TXT records with the same name are kept
+func (c *Client) CreateTXTRecord(ctx context.Context, zoneID string, name string, content string, ttl int) error {
+	record := Record{Type: "TXT", Name: strings.TrimSuffix(name, "."), Content: content, TTL: ttl}
+	return c.call(ctx, http.MethodPost, fmt.Sprintf("/zones/%s/dns_records", url.PathEscape(zoneID)), record, nil)
+}
+
+// DeleteRecord deletes the DNS record recordID from the zone
+func (c *Client) DeleteRecord(ctx context.Context, zoneID string, recordID string) error {
+	return c.call(ctx, http.MethodDelete, fmt.Sprintf("/zones/%s/dns_records/%s", url.PathEscape(zoneID), url.PathEscape(recordID)), nil, nil)
+}
+
+func (c *Client) call(ctx context.Context, method string, path string, data interface{}, result interface{}) error {
+	var payload io.Reader
+	if data != nil {
+		b, err := json.Marshal(data)
+		if err != nil {
+			return err
+		}
+		payload = bytes.NewReader(b)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, method, c.baseURL+path, payload)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Accept", "application/json")
+	if payload != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	req.Header.Set("Authorization", fmt.Sprintf("Bearer %s", c.apiToken))
+
+	res, err := c.httpClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer res.Body.Close()
+
+	body, err := io.ReadAll(res.Body)
+	if err != nil {
+		return err
+	}
+
+	r := response{}
+	err = json.Unmarshal(body, &r)
+	if err != nil {
+		return fmt.Errorf("could not parse Cloudflare response: %d %s", res.StatusCode, string(body))
+	}
+	if !r.Success || res.StatusCode >= http.StatusBadRequest {
+		messages := make([]string, 0, len(r.Errors))
+		for _, e := range r.Errors {
+			messages = append(messages, fmt.Sprintf("%d %s", e.Code, e.Message))
+		}
+		return fmt.Errorf("cloudflare request %s %s failed: %d %s", method, path, res.StatusCode, strings.Join(messages, ", "))
+	}
+
+	if result != nil {
+		err = json.Unmarshal(r.Result, result)
+		if err != nil {
+			return fmt.Errorf("could not parse Cloudflare result: %w", err)
+		}
+	}
+	return nil
+}
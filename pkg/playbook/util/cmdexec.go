@@ -20,6 +20,7 @@ package util
 
 import (
 	"bytes"
+	"context"
 	"os/exec"
 
 	"go.uber.org/zap"
@@ -28,10 +29,10 @@ import (
 // ExecuteScript takes the afterAction input and passes it to a Cmd struct to be executed.
 //
 // No validation is done over the afterAction string, so caution is advised.
-func ExecuteScript(afterAction string) (string, error) {
+func ExecuteScript(ctx context.Context, afterAction string) (string, error) {
 	zap.L().Debug("running script in shell", zap.String("action", afterAction))
 
-	cmd := exec.Command("sh", "-c", afterAction)
+	cmd := exec.CommandContext(ctx, "sh", "-c", afterAction)
 	var out bytes.Buffer
 	cmd.Stdout = &out
 	err := cmd.Run()
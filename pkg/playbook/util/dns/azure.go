@@ -0,0 +1,97 @@
+/*
+ * Copyright 2023 Venafi, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *  http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+package dns
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"sync"
+
+	"go.uber.org/zap"
+
+	"github.com/Venafi/vcert/v5/pkg/playbook/util/azure"
+)
+
+// azureProvider manages the TXT records of an Azure DNS zone
+type azureProvider struct {
+	client *azure.DNSClient
+	zone   string
+	ttl    int
+	mu     sync.Mutex
+}
+
+func newAzureProvider(cfg Config, ttl int) (*azureProvider, error) {
+	client, err := azure.NewDNSClient(cfg.AzureSubscriptionID, cfg.AzureResourceGroup, azure.Credentials{
+		TenantID:     cfg.AzureTenantID,
+		ClientID:     cfg.AzureClientID,
+		ClientSecret: cfg.AzureClientSecret,
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &azureProvider{client: client, zone: strings.TrimSuffix(cfg.Zone, "."), ttl: ttl}, nil
+}
+
+func (p *azureProvider) CreateTXTRecord(ctx context.Context, fqdn string, value string) error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	name, err := p.relativeName(fqdn)
+	if err != nil {
+		return err
+	}
+	values, err := p.client.GetTXTRecord(ctx, p.zone, name)
+	if err != nil {
+		return err
+	}
+	zap.L().Debug("creating TXT record", zap.String("provider", ProviderAzureDNS), zap.String("fqdn", fqdn))
+	return p.client.SetTXTRecord(ctx, p.zone, name, addValue(values, value), p.ttl)
+}
+
+func (p *azureProvider) DeleteTXTRecord(ctx context.Context, fqdn string, value string) error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	name, err := p.relativeName(fqdn)
+	if err != nil {
+		return err
+	}
+	values, err := p.client.GetTXTRecord(ctx, p.zone, name)
+	if err != nil || values == nil {
+		return err
+	}
+
+	zap.L().Debug("deleting TXT record", zap.String("provider", ProviderAzureDNS), zap.String("fqdn", fqdn))
+	remaining := removeValue(values, value)
+	if len(remaining) == 0 {
+		return p.client.DeleteTXTRecord(ctx, p.zone, name)
+	}
+	return p.client.SetTXTRecord(ctx, p.zone, name, remaining, p.ttl)
+}
+
+// relativeName returns the name of the record relative to the zone, as Azure DNS names its record sets
+func (p *azureProvider) relativeName(fqdn string) (string, error) {
+	name, zone := canonicalName(fqdn), canonicalName(p.zone)
+	if name == zone {
+		return "@", nil
+	}
+	relative, found := strings.CutSuffix(name, "."+zone)
+	if !found {
+		return "", fmt.Errorf("%s does not belong to the Azure DNS zone %s", fqdn, p.zone)
+	}
+	return relative, nil
+}
@@ -0,0 +1,109 @@
+/*
+ * Copyright 2023 Venafi, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *  http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+package dns
+
+import (
+	"context"
+	"fmt"
+	"sync"
+
+	"go.uber.org/zap"
+
+	"github.com/Venafi/vcert/v5/pkg/playbook/util/cloudflare"
+)
+
+// cloudflareProvider manages the TXT records of a Cloudflare zone. Cloudflare stores every value of a TXT record
+// as a record of its own
+type cloudflareProvider struct {
+	client *cloudflare.Client
+	zone   string
+	zoneID string
+	ttl    int
+	mu     sync.Mutex
+}
+
+func newCloudflareProvider(cfg Config, ttl int) *cloudflareProvider {
+	return &cloudflareProvider{client: cloudflare.NewClient(cfg.CloudflareAPIToken), zone: cfg.Zone, ttl: ttl}
+}
+
+func (p *cloudflareProvider) CreateTXTRecord(ctx context.Context, fqdn string, value string) error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	zoneID, err := p.findZone(ctx, fqdn)
+	if err != nil {
+		return err
+	}
+	records, err := p.client.ListTXTRecords(ctx, zoneID, fqdn)
+	if err != nil {
+		return err
+	}
+	for _, record := range records {
+		if record.Content == value {
+			return nil
+		}
+	}
+	zap.L().Debug("creating TXT record", zap.String("provider", ProviderCloudflare), zap.String("fqdn", fqdn))
+	return p.client.CreateTXTRecord(ctx, zoneID, fqdn, value, p.ttl)
+}
+
+func (p *cloudflareProvider) DeleteTXTRecord(ctx context.Context, fqdn string, value string) error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	zoneID, err := p.findZone(ctx, fqdn)
+	if err != nil {
+		return err
+	}
+	records, err := p.client.ListTXTRecords(ctx, zoneID, fqdn)
+	if err != nil {
+		return err
+	}
+	for _, record := range records {
+		if record.Content != value {
+			continue
+		}
+		zap.L().Debug("deleting TXT record", zap.String("provider", ProviderCloudflare), zap.String("fqdn", fqdn))
+		err = p.client.DeleteRecord(ctx, zoneID, record.ID)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// findZone returns the ID of the configured zone, or else of the closest zone the record belongs to
+func (p *cloudflareProvider) findZone(ctx context.Context, fqdn string) (string, error) {
+	if p.zoneID != "" {
+		return p.zoneID, nil
+	}
+
+	names := parentZones(fqdn)
+	if p.zone != "" {
+		names = []string{p.zone}
+	}
+	for _, name := range names {
+		id, err := p.client.FindZone(ctx, name)
+		if err != nil {
+			return "", err
+		}
+		if id != "" {
+			p.zoneID = id
+			return id, nil
+		}
+	}
+	return "", fmt.Errorf("no Cloudflare zone found for %s", fqdn)
+}
@@ -0,0 +1,72 @@
+/*
+ * Copyright 2023 Venafi, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *  http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+package dns
+
+import (
+	"context"
+	"sync"
+
+	"go.uber.org/zap"
+
+	"github.com/Venafi/vcert/v5/pkg/playbook/util/gcp"
+)
+
+// gcpProvider manages the TXT records of a Google Cloud DNS managed zone
+type gcpProvider struct {
+	client      *gcp.Client
+	managedZone string
+	ttl         int
+	mu          sync.Mutex
+}
+
+func newGCPProvider(cfg Config, ttl int) (*gcpProvider, error) {
+	client, err := gcp.NewClient(cfg.GCPProject, cfg.GCPCredentialsFile)
+	if err != nil {
+		return nil, err
+	}
+	return &gcpProvider{client: client, managedZone: cfg.GCPManagedZone, ttl: ttl}, nil
+}
+
+func (p *gcpProvider) CreateTXTRecord(ctx context.Context, fqdn string, value string) error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	name := canonicalName(fqdn)
+	values, err := p.client.GetTXTRecord(ctx, p.managedZone, name)
+	if err != nil {
+		return err
+	}
+	zap.L().Debug("creating TXT record", zap.String("provider", ProviderGCPDNS), zap.String("fqdn", fqdn))
+	return p.client.SetTXTRecord(ctx, p.managedZone, name, addValue(values, value), p.ttl)
+}
+
+func (p *gcpProvider) DeleteTXTRecord(ctx context.Context, fqdn string, value string) error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	name := canonicalName(fqdn)
+	values, err := p.client.GetTXTRecord(ctx, p.managedZone, name)
+	if err != nil || values == nil {
+		return err
+	}
+
+	zap.L().Debug("deleting TXT record", zap.String("provider", ProviderGCPDNS), zap.String("fqdn", fqdn))
+	remaining := removeValue(values, value)
+	if len(remaining) == 0 {
+		return p.client.DeleteTXTRecord(ctx, p.managedZone, name)
+	}
+	return p.client.SetTXTRecord(ctx, p.managedZone, name, remaining, p.ttl)
+}
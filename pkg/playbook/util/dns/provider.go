@@ -0,0 +1,184 @@
+/*
+ * Copyright 2023 Venafi, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *  http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+// Package dns creates and deletes the TXT records used to prove the control of a domain, like the ACME dns-01
+// challenges, in the DNS services supported by the playbook
+package dns
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+)
+
+const (
+	// ProviderRoute53 manages the records of an Amazon Route 53 hosted zone
+	ProviderRoute53 = "route53"
+	// ProviderCloudflare manages the records of a Cloudflare zone
+	ProviderCloudflare = "cloudflare"
+	// ProviderAzureDNS manages the records of an Azure DNS zone
+	ProviderAzureDNS = "azuredns"
+	// ProviderGCPDNS manages the records of a Google Cloud DNS managed zone
+	ProviderGCPDNS = "gcpdns"
+
+	// DefaultTTL is the TTL of the TXT records, in seconds, when Config.TTL is not set
+	DefaultTTL = 60
+)
+
+// Provider creates and deletes TXT records in a DNS service.
+// fqdn is the fully qualified name of the record, i.e. "_acme-challenge.example.com."
+type Provider interface {
+	// CreateTXTRecord adds value to the TXT record fqdn, keeping its other values
+	CreateTXTRecord(ctx context.Context, fqdn string, value string) error
+	// DeleteTXTRecord removes value from the TXT record fqdn, and deletes the record when it has no value left
+	DeleteTXTRecord(ctx context.Context, fqdn string, value string) error
+}
+
+// Config describes the DNS service a Provider manages the records of, and the credentials used to access it
+type Config struct {
+	// Provider is one of route53, cloudflare, azuredns or gcpdns
+	Provider string `yaml:"provider,omitempty"`
+	// Zone is the DNS name of the zone holding the records, i.e. "example.com". Required by azuredns.
+	// For route53 and cloudflare, the zone is found from the name of the records when not set
+	Zone string `yaml:"zone,omitempty"`
+	// TTL of the TXT records, in seconds. Defaults to DefaultTTL
+	TTL int `yaml:"ttl,omitempty"`
+
+	// AWSHostedZoneID is the ID of the Route 53 hosted zone. Found from Zone or the name of the records when not set
+	AWSHostedZoneID string `yaml:"awsHostedZoneId,omitempty"`
+	// AWSProfile is the profile of the AWS shared credentials file. See aws.LoadCredentials for the resolution order
+	AWSProfile string `yaml:"awsProfile,omitempty"`
+
+	// AzureClientID, AzureClientSecret and AzureTenantID identify the service principal. A managed identity is used
+	// when AzureClientSecret is not set
+	AzureClientID       string `yaml:"azureClientId,omitempty"`
+	AzureClientSecret   string `yaml:"azureClientSecret,omitempty"`
+	AzureResourceGroup  string `yaml:"azureResourceGroup,omitempty"`
+	AzureSubscriptionID string `yaml:"azureSubscriptionId,omitempty"`
+	AzureTenantID       string `yaml:"azureTenantId,omitempty"`
+
+	// CloudflareAPIToken needs the Zone:Read and DNS:Edit permissions on the zone
+	CloudflareAPIToken string `yaml:"cloudflareApiToken,omitempty"`
+
+	// GCPCredentialsFile is a service account key or user credentials file. The Application Default Credentials
+	// are used when not set
+	GCPCredentialsFile string `yaml:"gcpCredentialsFile,omitempty"`
+	// GCPManagedZone is the name of the Cloud DNS managed zone, which is not its DNS name
+	GCPManagedZone string `yaml:"gcpManagedZone,omitempty"`
+	GCPProject     string `yaml:"gcpProject,omitempty"`
+}
+
+// IsValid returns an error if the provider is not supported, or the fields it requires are not set
+func (cfg Config) IsValid() error {
+	if cfg.TTL < 0 {
+		return fmt.Errorf("ttl cannot be negative")
+	}
+
+	switch strings.ToLower(cfg.Provider) {
+	case ProviderRoute53:
+		return nil
+	case ProviderCloudflare:
+		if cfg.CloudflareAPIToken == "" {
+			return fmt.Errorf("cloudflareApiToken is required by the %s provider", ProviderCloudflare)
+		}
+		return nil
+	case ProviderAzureDNS:
+		var err error
+		if cfg.Zone == "" {
+			err = errors.Join(err, fmt.Errorf("zone is required by the %s provider", ProviderAzureDNS))
+		}
+		if cfg.AzureSubscriptionID == "" || cfg.AzureResourceGroup == "" {
+			err = errors.Join(err, fmt.Errorf("azureSubscriptionId and azureResourceGroup are required by the %s provider", ProviderAzureDNS))
+		}
+		if cfg.AzureClientSecret != "" && (cfg.AzureTenantID == "" || cfg.AzureClientID == "") {
+			err = errors.Join(err, fmt.Errorf("azureTenantId and azureClientId are required when azureClientSecret is set"))
+		}
+		return err
+	case ProviderGCPDNS:
+		if cfg.GCPProject == "" || cfg.GCPManagedZone == "" {
+			return fmt.Errorf("gcpProject and gcpManagedZone are required by the %s provider", ProviderGCPDNS)
+		}
+		return nil
+	case "":
+		return fmt.Errorf("a DNS provider is required. Should be one of %s", strings.Join(providers, ", "))
+	default:
+		return fmt.Errorf("unsupported DNS provider %q. Should be one of %s", cfg.Provider, strings.Join(providers, ", "))
+	}
+}
+
+var providers = []string{ProviderRoute53, ProviderCloudflare, ProviderAzureDNS, ProviderGCPDNS}
+
+// NewProvider returns the Provider described by cfg, authenticated to its DNS service
+func NewProvider(cfg Config) (Provider, error) {
+	err := cfg.IsValid()
+	if err != nil {
+		return nil, err
+	}
+
+	ttl := cfg.TTL
+	if ttl == 0 {
+		ttl = DefaultTTL
+	}
+
+	switch strings.ToLower(cfg.Provider) {
+	case ProviderRoute53:
+		return newRoute53Provider(cfg, ttl)
+	case ProviderCloudflare:
+		return newCloudflareProvider(cfg, ttl), nil
+	case ProviderAzureDNS:
+		return newAzureProvider(cfg, ttl)
+	default:
+		return newGCPProvider(cfg, ttl)
+	}
+}
+
+// canonicalName returns fqdn in lower case, with a trailing dot
+func canonicalName(fqdn string) string {
+	return strings.ToLower(strings.TrimSuffix(fqdn, ".")) + "."
+}
+
+// parentZones returns the names fqdn belongs to, from the longest to the shortest, excluding the top-level domain.
+// i.e. "example.com" and "sub.example.com" for "_acme-challenge.sub.example.com."
+func parentZones(fqdn string) []string {
+	labels := strings.Split(strings.TrimSuffix(canonicalName(fqdn), "."), ".")
+	zones := make([]string, 0, len(labels))
+	for i := 1; i < len(labels)-1; i++ {
+		zones = append(zones, strings.Join(labels[i:], "."))
+	}
+	return zones
+}
+
+// addValue returns values with value appended, unless it is already there
+func addValue(values []string, value string) []string {
+	for _, v := range values {
+		if v == value {
+			return values
+		}
+	}
+	return append(values, value)
+}
+
+// removeValue returns values without value
+func removeValue(values []string, value string) []string {
+	kept := make([]string, 0, len(values))
+	for _, v := range values {
+		if v != value {
+			kept = append(kept, v)
+		}
+	}
+	return kept
+}
@@ -0,0 +1,105 @@
+/*
+ * Copyright 2023 Venafi, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *  http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+package dns
+
+import (
+	"context"
+	"fmt"
+	"sync"
+
+	"go.uber.org/zap"
+
+	"github.com/Venafi/vcert/v5/pkg/playbook/util/aws"
+)
+
+// route53Provider manages the TXT records of an Amazon Route 53 hosted zone
+type route53Provider struct {
+	client       *aws.Route53Client
+	hostedZoneID string
+	zone         string
+	ttl          int
+	mu           sync.Mutex
+}
+
+func newRoute53Provider(cfg Config, ttl int) (*route53Provider, error) {
+	client, err := aws.NewRoute53Client(cfg.AWSProfile)
+	if err != nil {
+		return nil, err
+	}
+	return &route53Provider{client: client, hostedZoneID: cfg.AWSHostedZoneID, zone: cfg.Zone, ttl: ttl}, nil
+}
+
+func (p *route53Provider) CreateTXTRecord(ctx context.Context, fqdn string, value string) error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	zoneID, err := p.findHostedZone(ctx, fqdn)
+	if err != nil {
+		return err
+	}
+	values, _, err := p.client.GetTXTRecord(ctx, zoneID, fqdn)
+	if err != nil {
+		return err
+	}
+	zap.L().Debug("creating TXT record", zap.String("provider", ProviderRoute53), zap.String("fqdn", fqdn))
+	return p.client.SetTXTRecord(ctx, zoneID, fqdn, addValue(values, value), p.ttl)
+}
+
+func (p *route53Provider) DeleteTXTRecord(ctx context.Context, fqdn string, value string) error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	zoneID, err := p.findHostedZone(ctx, fqdn)
+	if err != nil {
+		return err
+	}
+	values, ttl, err := p.client.GetTXTRecord(ctx, zoneID, fqdn)
+	if err != nil || values == nil {
+		return err
+	}
+
+	zap.L().Debug("deleting TXT record", zap.String("provider", ProviderRoute53), zap.String("fqdn", fqdn))
+	remaining := removeValue(values, value)
+	if len(remaining) == 0 {
+		// Route 53 only deletes a record matching its current values and TTL
+		return p.client.DeleteTXTRecord(ctx, zoneID, fqdn, values, ttl)
+	}
+	return p.client.SetTXTRecord(ctx, zoneID, fqdn, remaining, ttl)
+}
+
+// findHostedZone returns the configured hosted zone, or the one for the configured zone, or else the closest
+// hosted zone the record belongs to
+func (p *route53Provider) findHostedZone(ctx context.Context, fqdn string) (string, error) {
+	if p.hostedZoneID != "" {
+		return p.hostedZoneID, nil
+	}
+
+	names := parentZones(fqdn)
+	if p.zone != "" {
+		names = []string{p.zone}
+	}
+	for _, name := range names {
+		id, err := p.client.FindHostedZone(ctx, name)
+		if err != nil {
+			return "", err
+		}
+		if id != "" {
+			p.hostedZoneID = id
+			return id, nil
+		}
+	}
+	return "", fmt.Errorf("no Route 53 hosted zone found for %s", fqdn)
+}
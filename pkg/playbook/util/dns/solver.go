@@ -0,0 +1,88 @@
+/*
+ * Copyright 2023 Venafi, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *  http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+package dns
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"go.uber.org/zap"
+
+	"github.com/Venafi/vcert/v5/pkg/venafi/acme"
+)
+
+const challengeLabel = "_acme-challenge"
+
+// Solver fulfills the dns-01 challenges of an ACME server by publishing their TXT records with a Provider
+type Solver struct {
+	provider Provider
+	wait     time.Duration
+}
+
+var _ acme.Solver = (*Solver)(nil)
+
+// NewSolver returns a Solver publishing the TXT records with provider. wait is the time given to the DNS servers
+// to publish the records, before the ACME server validates them. Defaults to acme.DefaultDNSPropagationWait
+func NewSolver(provider Provider, wait time.Duration) *Solver {
+	if wait == 0 {
+		wait = acme.DefaultDNSPropagationWait
+	}
+	return &Solver{provider: provider, wait: wait}
+}
+
+// ChallengeType implements acme.Solver
+func (s *Solver) ChallengeType() string {
+	return acme.ChallengeDNS01
+}
+
+// Present implements acme.Solver
+func (s *Solver) Present(ctx context.Context, identifier string, _ string, keyAuth string) error {
+	fqdn := challengeName(identifier)
+	err := s.provider.CreateTXTRecord(ctx, fqdn, keyAuth)
+	if err != nil {
+		return fmt.Errorf("could not create TXT record %s: %w", fqdn, err)
+	}
+	return nil
+}
+
+// CleanUp implements acme.Solver
+func (s *Solver) CleanUp(ctx context.Context, identifier string, _ string, keyAuth string) error {
+	fqdn := challengeName(identifier)
+	err := s.provider.DeleteTXTRecord(ctx, fqdn, keyAuth)
+	if err != nil {
+		return fmt.Errorf("could not delete TXT record %s: %w", fqdn, err)
+	}
+	return nil
+}
+
+// Wait gives the DNS servers time to publish the TXT records
+func (s *Solver) Wait(ctx context.Context) error {
+	zap.L().Info("waiting for dns-01 TXT records to propagate", zap.Duration("wait", s.wait))
+	select {
+	case <-time.After(s.wait):
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// challengeName returns the name of the TXT record of the dns-01 challenge for identifier. A wildcard identifier
+// shares the record of its base domain
+func challengeName(identifier string) string {
+	return fmt.Sprintf("%s.%s", challengeLabel, canonicalName(strings.TrimPrefix(identifier, "*.")))
+}
@@ -0,0 +1,282 @@
+/*
+ * Copyright 2023 Venafi, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *  http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package docker
+
+import (
+	"bytes"
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/Venafi/vcert/v5/pkg/playbook/util"
+)
+
+const (
+	defaultTimeout = 30 * time.Second
+
+	// apiVersion is the version of the Docker Engine API used by the client, supported since Docker 20.10
+	apiVersion = "v1.41"
+)
+
+// Secret is a Swarm secret. The Docker Engine never returns the data of the secrets
+type Secret struct {
+	ID        string     `json:"ID"`
+	CreatedAt time.Time  `json:"CreatedAt"`
+	Spec      SecretSpec `json:"Spec"`
+}
+
+// SecretSpec is the specification of a Swarm secret. Data is the base64 encoded value, only sent on creation
+type SecretSpec struct {
+	Name   string            `json:"Name"`
+	Labels map[string]string `json:"Labels,omitempty"`
+	Data   string            `json:"Data,omitempty"`
+}
+
+// SecretReference is a secret exposed to the containers of a service
+type SecretReference struct {
+	File       *SecretFile `json:"File,omitempty"`
+	SecretID   string      `json:"SecretID"`
+	SecretName string      `json:"SecretName"`
+}
+
+// SecretFile is the file in /run/secrets holding a secret in the containers of a service
+type SecretFile struct {
+	Name string `json:"Name"`
+	UID  string `json:"UID"`
+	GID  string `json:"GID"`
+	Mode uint32 `json:"Mode"`
+}
+
+// Service is a Swarm service. Spec holds the whole specification, so it is sent back unchanged on update
+// but for the secrets
+type Service struct {
+	ID      string `json:"ID"`
+	Version struct {
+		Index uint64 `json:"Index"`
+	} `json:"Version"`
+	Spec map[string]interface{} `json:"Spec"`
+}
+
+// Secrets returns the secrets exposed to the containers of the service
+func (s *Service) Secrets() ([]SecretReference, error) {
+	containerSpec, err := s.containerSpec()
+	if err != nil {
+		return nil, err
+	}
+	refs := make([]SecretReference, 0)
+	raw, found := containerSpec["Secrets"]
+	if !found || raw == nil {
+		return refs, nil
+	}
+	data, err := json.Marshal(raw)
+	if err != nil {
+		return nil, err
+	}
+	err = json.Unmarshal(data, &refs)
+	if err != nil {
+		return nil, fmt.Errorf("could not parse secrets of service %s: %w", s.ID, err)
+	}
+	return refs, nil
+}
+
+// SetSecrets replaces the secrets exposed to the containers of the service. The change is sent with UpdateService
+func (s *Service) SetSecrets(refs []SecretReference) error {
+	containerSpec, err := s.containerSpec()
+	if err != nil {
+		return err
+	}
+	containerSpec["Secrets"] = refs
+	return nil
+}
+
+func (s *Service) containerSpec() (map[string]interface{}, error) {
+	taskTemplate, _ := s.Spec["TaskTemplate"].(map[string]interface{})
+	containerSpec, _ := taskTemplate["ContainerSpec"].(map[string]interface{})
+	if containerSpec == nil {
+		return nil, fmt.Errorf("service %s has no container specification", s.ID)
+	}
+	return containerSpec, nil
+}
+
+// Client is a minimal client for the Swarm secrets and services of the Docker Engine API
+type Client struct {
+	address    string
+	httpClient *http.Client
+}
+
+// NewClient returns a Client for the Docker Engine at host, either a unix:// socket or a tcp:// address.
+//
+// certPath is the optional directory holding the ca.pem, cert.pem and key.pem files used to connect to a tcp://
+// address with TLS, the same as DOCKER_CERT_PATH
+func NewClient(host string, certPath string) (*Client, error) {
+	hostURL, err := url.Parse(host)
+	if err != nil {
+		return nil, fmt.Errorf("invalid Docker host %s: %w", host, err)
+	}
+
+	transport := &http.Transport{Proxy: http.ProxyFromEnvironment}
+	client := &Client{httpClient: &http.Client{Timeout: defaultTimeout, Transport: transport}}
+	switch hostURL.Scheme {
+	case "unix":
+		socket := hostURL.Path
+		transport.Proxy = nil
+		transport.DialContext = func(ctx context.Context, _, _ string) (net.Conn, error) {
+			return (&net.Dialer{}).DialContext(ctx, "unix", socket)
+		}
+		// The host is ignored when dialing the socket
+		client.address = "http://docker"
+	case "tcp":
+		scheme := "http"
+		if certPath != "" {
+			scheme = "https"
+			transport.TLSClientConfig, err = tlsConfig(certPath)
+			if err != nil {
+				return nil, err
+			}
+		}
+		client.address = fmt.Sprintf("%s://%s", scheme, hostURL.Host)
+	default:
+		return nil, fmt.Errorf("unsupported Docker host %s. Should be a unix:// socket or a tcp:// address", host)
+	}
+	return client, nil
+}
+
+// tlsConfig returns the TLS configuration built from the ca.pem, cert.pem and key.pem files of certPath
+func tlsConfig(certPath string) (*tls.Config, error) {
+	config := &tls.Config{MinVersion: tls.VersionTLS12}
+	data, err := os.ReadFile(filepath.Join(certPath, "ca.pem"))
+	if err != nil {
+		return nil, fmt.Errorf("could not read Docker CA certificate: %w", err)
+	}
+	pool := x509.NewCertPool()
+	if !pool.AppendCertsFromPEM(data) {
+		return nil, fmt.Errorf("no certificates found in Docker CA certificate %s", filepath.Join(certPath, "ca.pem"))
+	}
+	config.RootCAs = pool
+
+	cert, err := tls.LoadX509KeyPair(filepath.Join(certPath, "cert.pem"), filepath.Join(certPath, "key.pem"))
+	if err != nil {
+		return nil, fmt.Errorf("could not load Docker client certificate: %w", err)
+	}
+	config.Certificates = []tls.Certificate{cert}
+	return config, nil
+}
+
+// ListSecrets returns the secrets with the label, given as key=value
+func (c *Client) ListSecrets(ctx context.Context, label string) ([]Secret, error) {
+	filters, err := json.Marshal(map[string][]string{"label": {label}})
+	if err != nil {
+		return nil, err
+	}
+	secrets := make([]Secret, 0)
+	err = c.do(ctx, http.MethodGet, "/secrets?filters="+url.QueryEscape(string(filters)), nil, &secrets)
+	if err != nil {
+		return nil, err
+	}
+	return secrets, nil
+}
+
+// CreateSecret creates the secret defined by spec, and returns its ID
+func (c *Client) CreateSecret(ctx context.Context, spec SecretSpec) (string, error) {
+	response := struct {
+		ID string `json:"ID"`
+	}{}
+	err := c.do(ctx, http.MethodPost, "/secrets/create", spec, &response)
+	if err != nil {
+		return "", err
+	}
+	return response.ID, nil
+}
+
+// GetService returns the service with the name or ID. Returns nil if it does not exist
+func (c *Client) GetService(ctx context.Context, name string) (*Service, error) {
+	service := &Service{}
+	err := c.do(ctx, http.MethodGet, "/services/"+url.PathEscape(name), nil, service)
+	if err != nil {
+		if util.IsNotFound(err) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return service, nil
+}
+
+// UpdateService sends the specification of the service, which rolls out new tasks when it changed
+func (c *Client) UpdateService(ctx context.Context, service *Service) error {
+	path := fmt.Sprintf("/services/%s/update?version=%d", url.PathEscape(service.ID), service.Version.Index)
+	return c.do(ctx, http.MethodPost, path, service.Spec, nil)
+}
+
+func (c *Client) do(ctx context.Context, method string, path string, data interface{}, result interface{}) error {
+	var body io.Reader
+	if data != nil {
+		payload, err := json.Marshal(data)
+		if err != nil {
+			return err
+		}
+		body = bytes.NewReader(payload)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, method, c.address+"/"+apiVersion+path, body)
+	if err != nil {
+		return err
+	}
+	if data != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+
+	res, err := c.httpClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer res.Body.Close()
+
+	resBody, err := io.ReadAll(res.Body)
+	if err != nil {
+		return err
+	}
+
+	if res.StatusCode < 200 || res.StatusCode > 299 {
+		apiErr := struct {
+			Message string `json:"message"`
+		}{}
+		_ = json.Unmarshal(resBody, &apiErr)
+		return &util.HTTPError{Service: "Docker", Method: method, Path: strings.SplitN(path, "?", 2)[0],
+			StatusCode: res.StatusCode, Message: apiErr.Message}
+	}
+
+	if result != nil && len(resBody) > 0 {
+		// Numbers are kept as they are, so the specification of services is sent back unchanged
+		decoder := json.NewDecoder(bytes.NewReader(resBody))
+		decoder.UseNumber()
+		err = decoder.Decode(result)
+		if err != nil {
+			return fmt.Errorf("could not parse Docker response for %s: %w", path, err)
+		}
+	}
+	return nil
+}
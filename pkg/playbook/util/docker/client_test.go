@@ -0,0 +1,163 @@
+/*
+ * Copyright 2023 Venafi, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *  http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package docker
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+// swarmServer mocks the secrets and services endpoints of the Docker Engine API for a single service, web
+type swarmServer struct {
+	t       *testing.T
+	secrets []Secret
+	service Service
+}
+
+func (s *swarmServer) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	path, ok := strings.CutPrefix(r.URL.Path, "/"+apiVersion)
+	if !ok {
+		s.t.Errorf("unexpected API version in %s", r.URL.Path)
+	}
+	switch {
+	case r.Method == http.MethodGet && path == "/secrets":
+		filters := map[string][]string{}
+		_ = json.Unmarshal([]byte(r.URL.Query().Get("filters")), &filters)
+		secrets := make([]Secret, 0)
+		for _, secret := range s.secrets {
+			for _, label := range filters["label"] {
+				key, value, _ := strings.Cut(label, "=")
+				if secret.Spec.Labels[key] == value {
+					secrets = append(secrets, secret)
+				}
+			}
+		}
+		_ = json.NewEncoder(w).Encode(secrets)
+	case r.Method == http.MethodPost && path == "/secrets/create":
+		spec := SecretSpec{}
+		_ = json.NewDecoder(r.Body).Decode(&spec)
+		for _, secret := range s.secrets {
+			if secret.Spec.Name == spec.Name {
+				w.WriteHeader(http.StatusConflict)
+				_, _ = w.Write([]byte(`{"message":"secret ` + spec.Name + ` already exists"}`))
+				return
+			}
+		}
+		id := "secret-" + spec.Name
+		s.secrets = append(s.secrets, Secret{ID: id, Spec: SecretSpec{Name: spec.Name, Labels: spec.Labels}})
+		w.WriteHeader(http.StatusCreated)
+		_, _ = w.Write([]byte(`{"ID":"` + id + `"}`))
+	case r.Method == http.MethodGet && (path == "/services/web" || path == "/services/"+s.service.ID):
+		_ = json.NewEncoder(w).Encode(s.service)
+	case r.Method == http.MethodPost && path == "/services/"+s.service.ID+"/update":
+		if r.URL.Query().Get("version") != strconv.FormatUint(s.service.Version.Index, 10) {
+			w.WriteHeader(http.StatusInternalServerError)
+			_, _ = w.Write([]byte(`{"message":"update out of sequence"}`))
+			return
+		}
+		spec := map[string]interface{}{}
+		decoder := json.NewDecoder(r.Body)
+		decoder.UseNumber()
+		_ = decoder.Decode(&spec)
+		s.service.Spec = spec
+		s.service.Version.Index++
+	default:
+		w.WriteHeader(http.StatusNotFound)
+		_, _ = w.Write([]byte(`{"message":"no such object"}`))
+	}
+}
+
+func TestClient(t *testing.T) {
+	ctx := context.Background()
+	swarm := &swarmServer{t: t}
+	swarm.service.ID = "service-1"
+	swarm.service.Version.Index = 10
+	swarm.service.Spec = map[string]interface{}{
+		"Name":         "web",
+		"TaskTemplate": map[string]interface{}{"ContainerSpec": map[string]interface{}{"Image": "nginx"}},
+		"Mode":         map[string]interface{}{"Replicated": map[string]interface{}{"Replicas": 3}},
+	}
+	server := httptest.NewServer(swarm)
+	defer server.Close()
+
+	client, err := NewClient(strings.Replace(server.URL, "http://", "tcp://", 1), "")
+	if err != nil {
+		t.Fatalf("failed to create client: %s", err)
+	}
+
+	// Not found
+	service, err := client.GetService(ctx, "api")
+	if err != nil || service != nil {
+		t.Fatalf("expected missing service to return nil, got %v, %v", service, err)
+	}
+
+	// Create
+	id, err := client.CreateSecret(ctx, SecretSpec{Name: "web-tls", Labels: map[string]string{"vcert.managed": "web"}, Data: "Y2VydA=="})
+	if err != nil {
+		t.Fatalf("failed to create secret: %s", err)
+	}
+	_, err = client.CreateSecret(ctx, SecretSpec{Name: "web-tls"})
+	if err == nil || !strings.Contains(err.Error(), "409 secret web-tls already exists") {
+		t.Fatalf("expected conflict error, got %v", err)
+	}
+	secrets, err := client.ListSecrets(ctx, "vcert.managed=web")
+	if err != nil {
+		t.Fatalf("failed to list secrets: %s", err)
+	}
+	if len(secrets) != 1 || secrets[0].ID != id {
+		t.Fatalf("unexpected secrets %v", secrets)
+	}
+
+	// Update
+	service, err = client.GetService(ctx, "web")
+	if err != nil {
+		t.Fatalf("failed to get service: %s", err)
+	}
+	err = service.SetSecrets([]SecretReference{{SecretID: id, SecretName: "web-tls", File: &SecretFile{Name: "tls.crt", UID: "0", GID: "0", Mode: 0444}}})
+	if err != nil {
+		t.Fatalf("failed to set secrets: %s", err)
+	}
+	err = client.UpdateService(ctx, service)
+	if err != nil {
+		t.Fatalf("failed to update service: %s", err)
+	}
+	err = client.UpdateService(ctx, service)
+	if err == nil || !strings.Contains(err.Error(), "update out of sequence") {
+		t.Fatalf("expected update with an old version to fail, got %v", err)
+	}
+
+	service, err = client.GetService(ctx, "web")
+	if err != nil {
+		t.Fatalf("failed to get service: %s", err)
+	}
+	refs, err := service.Secrets()
+	if err != nil {
+		t.Fatalf("failed to read secrets of service: %s", err)
+	}
+	if len(refs) != 1 || refs[0].SecretID != id || refs[0].File.Name != "tls.crt" {
+		t.Fatalf("unexpected secret references %v", refs)
+	}
+	replicas := service.Spec["Mode"].(map[string]interface{})["Replicated"].(map[string]interface{})["Replicas"]
+	if replicas != json.Number("3") {
+		t.Fatalf("expected the specification to be sent back unchanged, got replicas %v", replicas)
+	}
+}
@@ -0,0 +1,290 @@
+/*
+ * Copyright 2023 Venafi, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *  http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package f5
+
+import (
+	"bytes"
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/Venafi/vcert/v5/pkg/playbook/util"
+)
+
+const (
+	defaultTimeout = 30 * time.Second
+
+	// uploadDirectory is where the files sent to the file-transfer endpoint are stored by the BIG-IP
+	uploadDirectory = "/var/config/rest/downloads"
+)
+
+// SSLCert represents a certificate file object of the BIG-IP (sys/file/ssl-cert)
+type SSLCert struct {
+	Name           string `json:"name"`
+	Partition      string `json:"partition"`
+	FullPath       string `json:"fullPath"`
+	CreateTime     string `json:"createTime,omitempty"`
+	ExpirationDate int64  `json:"expirationDate,omitempty"`
+	SerialNumber   string `json:"serialNumber,omitempty"`
+	Subject        string `json:"subject,omitempty"`
+}
+
+// CertKeyChain is an entry of the certKeyChain of a client SSL profile
+type CertKeyChain struct {
+	Name  string `json:"name"`
+	Cert  string `json:"cert"`
+	Key   string `json:"key"`
+	Chain string `json:"chain,omitempty"`
+}
+
+// ClientSSLProfile represents a client SSL profile (ltm/profile/client-ssl)
+type ClientSSLProfile struct {
+	Name         string         `json:"name"`
+	FullPath     string         `json:"fullPath"`
+	CertKeyChain []CertKeyChain `json:"certKeyChain"`
+}
+
+// Client is a minimal client for the iControl REST API of F5 BIG-IP
+type Client struct {
+	address    string
+	token      string
+	httpClient *http.Client
+}
+
+// NewClient returns a Client for the BIG-IP at address, authenticated with a token obtained for username and password.
+//
+// caCert is the optional path to a PEM bundle used to verify the BIG-IP management certificate. When insecure is true,
+// the management certificate is not verified at all
+func NewClient(ctx context.Context, address string, username string, password string, caCert string, insecure bool) (*Client, error) {
+	tlsConfig := &tls.Config{InsecureSkipVerify: insecure} //nolint:gosec
+	if caCert != "" {
+		data, err := os.ReadFile(caCert)
+		if err != nil {
+			return nil, fmt.Errorf("could not read BIG-IP CA certificate: %w", err)
+		}
+		pool := x509.NewCertPool()
+		if !pool.AppendCertsFromPEM(data) {
+			return nil, fmt.Errorf("no certificates found in BIG-IP CA certificate %s", caCert)
+		}
+		tlsConfig.RootCAs = pool
+	}
+
+	if !strings.Contains(address, "://") {
+		address = "https://" + address
+	}
+	client := &Client{
+		address: strings.TrimSuffix(address, "/"),
+		httpClient: &http.Client{
+			Timeout: defaultTimeout,
+			Transport: &http.Transport{
+				Proxy:           http.ProxyFromEnvironment,
+				TLSClientConfig: tlsConfig,
+			},
+		},
+	}
+
+	token, err := client.login(ctx, username, password)
+	if err != nil {
+		return nil, err
+	}
+	client.token = token
+
+	return client, nil
+}
+
+func (c *Client) login(ctx context.Context, username string, password string) (string, error) {
+	data := map[string]string{
+		"username":          username,
+		"password":          password,
+		"loginProviderName": "tmos",
+	}
+	response := struct {
+		Token struct {
+			Token string `json:"token"`
+		} `json:"token"`
+	}{}
+	err := c.do(ctx, http.MethodPost, "/mgmt/shared/authn/login", data, &response)
+	if err != nil {
+		return "", fmt.Errorf("could not log in to BIG-IP: %w", err)
+	}
+	if response.Token.Token == "" {
+		return "", fmt.Errorf("could not log in to BIG-IP: no token returned")
+	}
+	return response.Token.Token, nil
+}
+
+// ListCertificates returns the certificate file objects of the partition
+func (c *Client) ListCertificates(ctx context.Context, partition string) ([]SSLCert, error) {
+	response := struct {
+		Items []SSLCert `json:"items"`
+	}{}
+	path := fmt.Sprintf("/mgmt/tm/sys/file/ssl-cert?$filter=%s", url.QueryEscape("partition eq "+partition))
+	err := c.do(ctx, http.MethodGet, path, nil, &response)
+	if err != nil {
+		return nil, err
+	}
+	return response.Items, nil
+}
+
+// GetCertificate returns the certificate file object name of the partition. Returns nil if it does not exist
+func (c *Client) GetCertificate(ctx context.Context, partition string, name string) (*SSLCert, error) {
+	cert := &SSLCert{}
+	err := c.do(ctx, http.MethodGet, fmt.Sprintf("/mgmt/tm/sys/file/ssl-cert/%s", resourceID(partition, name)), nil, cert)
+	if err != nil {
+		if util.IsNotFound(err) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return cert, nil
+}
+
+// UploadFile sends data to the BIG-IP as the file name, and returns the path of the file in the BIG-IP
+func (c *Client) UploadFile(ctx context.Context, name string, data []byte) (string, error) {
+	path := fmt.Sprintf("/mgmt/shared/file-transfer/uploads/%s", url.PathEscape(name))
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, c.address+path, bytes.NewReader(data))
+	if err != nil {
+		return "", err
+	}
+	req.Header.Set("Content-Type", "application/octet-stream")
+	req.Header.Set("Content-Range", fmt.Sprintf("0-%d/%d", len(data)-1, len(data)))
+
+	err = c.send(req, path, nil)
+	if err != nil {
+		return "", err
+	}
+	return fmt.Sprintf("%s/%s", uploadDirectory, name), nil
+}
+
+// DeleteFile removes a file uploaded with UploadFile from the BIG-IP
+func (c *Client) DeleteFile(ctx context.Context, localFile string) error {
+	data := map[string]string{
+		"command":     "run",
+		"utilCmdArgs": localFile,
+	}
+	return c.do(ctx, http.MethodPost, "/mgmt/tm/util/unix-rm", data, nil)
+}
+
+// InstallCertificate creates, or replaces, the certificate name of the partition from a file uploaded with UploadFile
+func (c *Client) InstallCertificate(ctx context.Context, partition string, name string, localFile string) error {
+	return c.install(ctx, "/mgmt/tm/sys/crypto/cert", partition, name, localFile)
+}
+
+// InstallKey creates, or replaces, the private key name of the partition from a file uploaded with UploadFile
+func (c *Client) InstallKey(ctx context.Context, partition string, name string, localFile string) error {
+	return c.install(ctx, "/mgmt/tm/sys/crypto/key", partition, name, localFile)
+}
+
+func (c *Client) install(ctx context.Context, path string, partition string, name string, localFile string) error {
+	data := map[string]string{
+		"command":         "install",
+		"name":            FullPath(partition, name),
+		"from-local-file": localFile,
+	}
+	return c.do(ctx, http.MethodPost, path, data, nil)
+}
+
+// GetClientSSLProfile returns the client SSL profile name of the partition. Returns nil if it does not exist
+func (c *Client) GetClientSSLProfile(ctx context.Context, partition string, name string) (*ClientSSLProfile, error) {
+	profile := &ClientSSLProfile{}
+	err := c.do(ctx, http.MethodGet, fmt.Sprintf("/mgmt/tm/ltm/profile/client-ssl/%s", resourceID(partition, name)), nil, profile)
+	if err != nil {
+		if util.IsNotFound(err) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return profile, nil
+}
+
+// SetClientSSLCertKeyChain replaces the certKeyChain of the client SSL profile name of the partition
+func (c *Client) SetClientSSLCertKeyChain(ctx context.Context, partition string, name string, certKeyChain []CertKeyChain) error {
+	data := map[string]interface{}{"certKeyChain": certKeyChain}
+	return c.do(ctx, http.MethodPatch, fmt.Sprintf("/mgmt/tm/ltm/profile/client-ssl/%s", resourceID(partition, name)), data, nil)
+}
+
+func (c *Client) do(ctx context.Context, method string, path string, data interface{}, result interface{}) error {
+	var body io.Reader
+	if data != nil {
+		payload, err := json.Marshal(data)
+		if err != nil {
+			return err
+		}
+		body = bytes.NewReader(payload)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, method, c.address+path, body)
+	if err != nil {
+		return err
+	}
+	if data != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	return c.send(req, path, result)
+}
+
+func (c *Client) send(req *http.Request, path string, result interface{}) error {
+	if c.token != "" {
+		req.Header.Set("X-F5-Auth-Token", c.token)
+	}
+
+	res, err := c.httpClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer res.Body.Close()
+
+	resBody, err := io.ReadAll(res.Body)
+	if err != nil {
+		return err
+	}
+
+	if res.StatusCode < 200 || res.StatusCode > 299 {
+		apiErr := struct {
+			Message string `json:"message"`
+		}{}
+		_ = json.Unmarshal(resBody, &apiErr)
+		return &util.HTTPError{Service: "BIG-IP", Method: req.Method, Path: path, StatusCode: res.StatusCode,
+			Message: apiErr.Message}
+	}
+
+	if result != nil && len(resBody) > 0 {
+		err = json.Unmarshal(resBody, result)
+		if err != nil {
+			return fmt.Errorf("could not parse BIG-IP response for %s: %w", path, err)
+		}
+	}
+	return nil
+}
+
+// FullPath returns the full path of the object name of the partition, i.e. /Common/example.crt
+func FullPath(partition string, name string) string {
+	return fmt.Sprintf("/%s/%s", partition, name)
+}
+
+// resourceID returns the identifier of the object name of the partition in iControl REST URLs, i.e. ~Common~example.crt
+func resourceID(partition string, name string) string {
+	return url.PathEscape(fmt.Sprintf("~%s~%s", partition, name))
+}
@@ -0,0 +1,210 @@
+/*
+ * Copyright 2023 Venafi, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *  http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package f5
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+// bigIPServer mocks the iControl REST endpoints used by the client
+type bigIPServer struct {
+	t        *testing.T
+	uploads  map[string][]byte
+	certs    map[string]SSLCert
+	profiles map[string]*ClientSSLProfile
+}
+
+func (s *bigIPServer) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.URL.Path == "/mgmt/shared/authn/login" {
+		body := map[string]string{}
+		_ = json.NewDecoder(r.Body).Decode(&body)
+		if body["username"] != "admin" || body["password"] != "secret" {
+			w.WriteHeader(http.StatusUnauthorized)
+			_, _ = w.Write([]byte(`{"code":401,"message":"Authentication failed."}`))
+			return
+		}
+		_, _ = w.Write([]byte(`{"token":{"token":"f5-token"}}`))
+		return
+	}
+	if r.Header.Get("X-F5-Auth-Token") != "f5-token" {
+		w.WriteHeader(http.StatusUnauthorized)
+		_, _ = w.Write([]byte(`{"code":401,"message":"X-F5-Auth-Token does not exist."}`))
+		return
+	}
+
+	notFound := func() {
+		w.WriteHeader(http.StatusNotFound)
+		_, _ = w.Write([]byte(`{"code":404,"message":"01020036:3: The requested object was not found."}`))
+	}
+	body, _ := io.ReadAll(r.Body)
+	command := map[string]string{}
+	if r.Method == http.MethodPost && r.Header.Get("Content-Type") == "application/json" {
+		_ = json.Unmarshal(body, &command)
+	}
+
+	switch path := r.URL.Path; {
+	case strings.HasPrefix(path, "/mgmt/shared/file-transfer/uploads/"):
+		if r.Header.Get("Content-Range") != "0-3/4" {
+			s.t.Errorf("unexpected content range %q", r.Header.Get("Content-Range"))
+		}
+		s.uploads[uploadDirectory+"/"+strings.TrimPrefix(path, "/mgmt/shared/file-transfer/uploads/")] = body
+	case path == "/mgmt/tm/util/unix-rm":
+		delete(s.uploads, command["utilCmdArgs"])
+	case path == "/mgmt/tm/sys/crypto/cert" || path == "/mgmt/tm/sys/crypto/key":
+		if _, found := s.uploads[command["from-local-file"]]; !found || command["command"] != "install" {
+			w.WriteHeader(http.StatusBadRequest)
+			_, _ = w.Write([]byte(`{"code":400,"message":"file not found"}`))
+			return
+		}
+		if path == "/mgmt/tm/sys/crypto/cert" {
+			fullPath := command["name"]
+			parts := strings.Split(fullPath, "/")
+			s.certs["~"+parts[1]+"~"+parts[2]] = SSLCert{Name: parts[2], Partition: parts[1], FullPath: fullPath,
+				SerialNumber: string(s.uploads[command["from-local-file"]])}
+		}
+	case path == "/mgmt/tm/sys/file/ssl-cert":
+		if r.URL.Query().Get("$filter") != "partition eq Common" {
+			s.t.Errorf("unexpected filter %q", r.URL.Query().Get("$filter"))
+		}
+		items := make([]SSLCert, 0)
+		for _, cert := range s.certs {
+			items = append(items, cert)
+		}
+		_ = json.NewEncoder(w).Encode(map[string]interface{}{"items": items})
+	case strings.HasPrefix(path, "/mgmt/tm/sys/file/ssl-cert/"):
+		cert, found := s.certs[strings.TrimPrefix(path, "/mgmt/tm/sys/file/ssl-cert/")]
+		if !found {
+			notFound()
+			return
+		}
+		_ = json.NewEncoder(w).Encode(cert)
+	case strings.HasPrefix(path, "/mgmt/tm/ltm/profile/client-ssl/"):
+		profile := s.profiles[strings.TrimPrefix(path, "/mgmt/tm/ltm/profile/client-ssl/")]
+		if profile == nil {
+			notFound()
+			return
+		}
+		if r.Method == http.MethodPatch {
+			update := ClientSSLProfile{}
+			_ = json.Unmarshal(body, &update)
+			profile.CertKeyChain = update.CertKeyChain
+		}
+		_ = json.NewEncoder(w).Encode(profile)
+	default:
+		notFound()
+	}
+}
+
+func TestClient(t *testing.T) {
+	ctx := context.Background()
+	bigIP := &bigIPServer{t: t, uploads: map[string][]byte{}, certs: map[string]SSLCert{}, profiles: map[string]*ClientSSLProfile{
+		"~Common~web": {Name: "web", FullPath: "/Common/web", CertKeyChain: []CertKeyChain{{Name: "default", Cert: "/Common/default.crt", Key: "/Common/default.key"}}},
+	}}
+	server := httptest.NewServer(bigIP)
+	defer server.Close()
+
+	_, err := NewClient(ctx, server.URL, "admin", "wrong", "", false)
+	if err == nil || !strings.Contains(err.Error(), "Authentication failed.") {
+		t.Fatalf("expected login with invalid password to fail, got %v", err)
+	}
+	client, err := NewClient(ctx, server.URL+"/", "admin", "secret", "", false)
+	if err != nil {
+		t.Fatalf("failed to create client: %s", err)
+	}
+
+	// Not found
+	cert, err := client.GetCertificate(ctx, "Common", "web.crt")
+	if err != nil || cert != nil {
+		t.Fatalf("expected missing certificate to return nil, got %v, %v", cert, err)
+	}
+	profile, err := client.GetClientSSLProfile(ctx, "Common", "api")
+	if err != nil || profile != nil {
+		t.Fatalf("expected missing profile to return nil, got %v, %v", profile, err)
+	}
+
+	// Create
+	install := func(serial string) {
+		localFile, err := client.UploadFile(ctx, "web.crt", []byte(serial))
+		if err != nil {
+			t.Fatalf("failed to upload file: %s", err)
+		}
+		if localFile != uploadDirectory+"/web.crt" {
+			t.Fatalf("unexpected uploaded file %s", localFile)
+		}
+		err = client.InstallCertificate(ctx, "Common", "web.crt", localFile)
+		if err != nil {
+			t.Fatalf("failed to install certificate: %s", err)
+		}
+		err = client.DeleteFile(ctx, localFile)
+		if err != nil {
+			t.Fatalf("failed to delete file: %s", err)
+		}
+	}
+	install("0001")
+	if len(bigIP.uploads) != 0 {
+		t.Fatalf("expected uploaded file to be removed")
+	}
+	err = client.InstallKey(ctx, "Common", "web.key", uploadDirectory+"/missing.key")
+	if err == nil || !strings.Contains(err.Error(), "400 file not found") {
+		t.Fatalf("expected install of a missing file to fail, got %v", err)
+	}
+
+	// Update
+	install("0002")
+	cert, err = client.GetCertificate(ctx, "Common", "web.crt")
+	if err != nil {
+		t.Fatalf("failed to get certificate: %s", err)
+	}
+	if cert.FullPath != "/Common/web.crt" || cert.SerialNumber != "0002" {
+		t.Fatalf("unexpected certificate %v", cert)
+	}
+	certs, err := client.ListCertificates(ctx, "Common")
+	if err != nil {
+		t.Fatalf("failed to list certificates: %s", err)
+	}
+	if len(certs) != 1 {
+		t.Fatalf("unexpected certificates %v", certs)
+	}
+	profile, err = client.GetClientSSLProfile(ctx, "Common", "web")
+	if err != nil {
+		t.Fatalf("failed to get profile: %s", err)
+	}
+	if len(profile.CertKeyChain) != 1 || profile.CertKeyChain[0].Cert != "/Common/default.crt" {
+		t.Fatalf("unexpected profile %v", profile)
+	}
+	err = client.SetClientSSLCertKeyChain(ctx, "Common", "web", []CertKeyChain{{Name: "web", Cert: "/Common/web.crt", Key: "/Common/web.key"}})
+	if err != nil {
+		t.Fatalf("failed to update profile: %s", err)
+	}
+	profile, err = client.GetClientSSLProfile(ctx, "Common", "web")
+	if err != nil {
+		t.Fatalf("failed to get profile: %s", err)
+	}
+	if len(profile.CertKeyChain) != 1 || profile.CertKeyChain[0].Cert != "/Common/web.crt" {
+		t.Fatalf("unexpected updated profile %v", profile)
+	}
+	err = client.SetClientSSLCertKeyChain(ctx, "Common", "api", nil)
+	if err == nil || !strings.Contains(err.Error(), "404 01020036:3: The requested object was not found.") {
+		t.Fatalf("expected update of a missing profile to fail, got %v", err)
+	}
+}
@@ -58,6 +58,16 @@ func WriteFile(location string, content []byte) error {
 	return nil
 }
 
+// SetFileMode changes the permission mode of the file at location
+func SetFileMode(location string, mode os.FileMode) error {
+	err := os.Chmod(location, mode)
+	if err != nil {
+		zap.L().Error("could not change file mode", zap.String("file", location), zap.Error(err))
+		return err
+	}
+	return nil
+}
+
 // CopyFile makes a copy of the given source to the given destination using Go's native copy function io.Copy
 func CopyFile(source string, destination string) error {
 	zap.L().Debug("checking file", zap.String("location", source))
@@ -74,9 +84,9 @@ func CopyFile(source string, destination string) error {
 		return fmt.Errorf("%s: %s", m, source)
 	}
 
-	sourceFile, err := os.Create(source)
+	sourceFile, err := os.Open(source)
 	if err != nil {
-		zap.L().Error("failed to create/truncate file", zap.String("file", source), zap.Error(err))
+		zap.L().Error("failed to open file", zap.String("file", source), zap.Error(err))
 		return err
 	}
 	defer sourceFile.Close()
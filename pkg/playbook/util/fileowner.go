@@ -0,0 +1,96 @@
+//go:build !windows
+
+/*
+ * Copyright 2023 Venafi, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *  http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package util
+
+import (
+	"fmt"
+	"os"
+	"os/user"
+	"strconv"
+	"syscall"
+
+	"go.uber.org/zap"
+)
+
+// SetFileOwnership changes the owner and group of the file at location. owner and group may be names or numeric ids.
+// Empty values are left unchanged
+func SetFileOwnership(location string, owner string, group string) error {
+	if owner == "" && group == "" {
+		return nil
+	}
+
+	uid, gid := -1, -1
+	var err error
+	if owner != "" {
+		uid, err = lookupID(owner, func(name string) (string, error) {
+			u, err := user.Lookup(name)
+			if err != nil {
+				return "", err
+			}
+			return u.Uid, nil
+		})
+		if err != nil {
+			return fmt.Errorf("could not find owner %s: %w", owner, err)
+		}
+	}
+	if group != "" {
+		gid, err = lookupID(group, func(name string) (string, error) {
+			g, err := user.LookupGroup(name)
+			if err != nil {
+				return "", err
+			}
+			return g.Gid, nil
+		})
+		if err != nil {
+			return fmt.Errorf("could not find group %s: %w", group, err)
+		}
+	}
+
+	err = os.Chown(location, uid, gid)
+	if err != nil {
+		zap.L().Error("could not change file ownership", zap.String("file", location), zap.Error(err))
+		return err
+	}
+	return nil
+}
+
+// GetFileOwnership returns the numeric ids of the owner and group of the file at location
+func GetFileOwnership(location string) (string, string, error) {
+	info, err := os.Stat(location)
+	if err != nil {
+		return "", "", err
+	}
+	stat, ok := info.Sys().(*syscall.Stat_t)
+	if !ok {
+		return "", "", nil
+	}
+	return strconv.FormatUint(uint64(stat.Uid), 10), strconv.FormatUint(uint64(stat.Gid), 10), nil
+}
+
+// lookupID returns name as an id when it is numeric, or the id returned by lookup otherwise
+func lookupID(name string, lookup func(string) (string, error)) (int, error) {
+	if id, err := strconv.Atoi(name); err == nil {
+		return id, nil
+	}
+	id, err := lookup(name)
+	if err != nil {
+		return 0, err
+	}
+	return strconv.Atoi(id)
+}
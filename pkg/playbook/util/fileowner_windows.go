@@ -0,0 +1,36 @@
+//go:build windows
+
+/*
+ * Copyright 2023 Venafi, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *  http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package util
+
+import (
+	"fmt"
+)
+
+// SetFileOwnership is not supported on Windows. Returns an error when owner or group are set
+func SetFileOwnership(location string, owner string, group string) error {
+	if owner == "" && group == "" {
+		return nil
+	}
+	return fmt.Errorf("could not change ownership of file %s: owner and group are not supported on Windows", location)
+}
+
+// GetFileOwnership is not supported on Windows. Returns empty owner and group
+func GetFileOwnership(_ string) (string, string, error) {
+	return "", "", nil
+}
@@ -0,0 +1,184 @@
+/*
+ * Copyright 2023 Venafi, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *  http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package gcp
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"runtime"
+
+	"go.uber.org/zap"
+	"golang.org/x/oauth2"
+	"golang.org/x/oauth2/jwt"
+)
+
+const (
+	cloudPlatformScope = "https://www.googleapis.com/auth/cloud-platform"
+	defaultTokenURL    = "https://oauth2.googleapis.com/token"
+
+	envCredentials  = "GOOGLE_APPLICATION_CREDENTIALS"
+	envMetadataHost = "GCE_METADATA_HOST"
+
+	defaultMetadataHost = "metadata.google.internal"
+	metadataTokenPath   = "/computeMetadata/v1/instance/service-accounts/default/token"
+
+	credentialsTypeServiceAccount = "service_account"
+	credentialsTypeAuthorizedUser = "authorized_user"
+)
+
+// credentialsFile holds the fields used from service account keys and from the user credentials written by
+// gcloud auth application-default login
+type credentialsFile struct {
+	Type string `json:"type"`
+
+	// Service account keys
+	ClientEmail  string `json:"client_email"`
+	PrivateKey   string `json:"private_key"`
+	PrivateKeyID string `json:"private_key_id"`
+	TokenURI     string `json:"token_uri"`
+
+	// User credentials
+	ClientID     string `json:"client_id"`
+	ClientSecret string `json:"client_secret"`
+	RefreshToken string `json:"refresh_token"`
+}
+
+type tokenResponse struct {
+	AccessToken string `json:"access_token"`
+}
+
+// GetToken returns an access token for the Google Cloud APIs.
+//
+// When credentialsPath is empty, the Application Default Credentials are used, in the same order as the Google Cloud SDKs:
+//  1. the file set in the GOOGLE_APPLICATION_CREDENTIALS environment variable
+//  2. the user credentials written by gcloud auth application-default login
+//  3. the service account attached to the GCE instance, GKE node or Cloud Run service, through the metadata server
+func GetToken(credentialsPath string) (string, error) {
+	if credentialsPath == "" {
+		credentialsPath = os.Getenv(envCredentials)
+	}
+	if credentialsPath == "" {
+		wellKnown := wellKnownCredentialsPath()
+		if _, err := os.Stat(wellKnown); err == nil {
+			credentialsPath = wellKnown
+		}
+	}
+
+	if credentialsPath != "" {
+		zap.L().Debug("requesting GCP token using credentials file", zap.String("file", credentialsPath))
+		return getFileToken(credentialsPath)
+	}
+
+	zap.L().Debug("requesting GCP token from the metadata server")
+	return getMetadataToken()
+}
+
+func getFileToken(path string) (string, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return "", fmt.Errorf("could not read GCP credentials file: %w", err)
+	}
+	credentials := credentialsFile{}
+	err = json.Unmarshal(data, &credentials)
+	if err != nil {
+		return "", fmt.Errorf("could not parse GCP credentials file %s: %w", path, err)
+	}
+
+	var source oauth2.TokenSource
+	switch credentials.Type {
+	case credentialsTypeServiceAccount:
+		tokenURL := credentials.TokenURI
+		if tokenURL == "" {
+			tokenURL = defaultTokenURL
+		}
+		config := jwt.Config{
+			Email:        credentials.ClientEmail,
+			PrivateKey:   []byte(credentials.PrivateKey),
+			PrivateKeyID: credentials.PrivateKeyID,
+			Scopes:       []string{cloudPlatformScope},
+			TokenURL:     tokenURL,
+		}
+		source = config.TokenSource(context.Background())
+	case credentialsTypeAuthorizedUser:
+		config := oauth2.Config{
+			ClientID:     credentials.ClientID,
+			ClientSecret: credentials.ClientSecret,
+			Endpoint:     oauth2.Endpoint{TokenURL: defaultTokenURL},
+			Scopes:       []string{cloudPlatformScope},
+		}
+		source = config.TokenSource(context.Background(), &oauth2.Token{RefreshToken: credentials.RefreshToken})
+	default:
+		return "", fmt.Errorf("unsupported GCP credentials type %q in %s. Should be %s or %s", credentials.Type, path,
+			credentialsTypeServiceAccount, credentialsTypeAuthorizedUser)
+	}
+
+	token, err := source.Token()
+	if err != nil {
+		return "", fmt.Errorf("could not get GCP token using credentials file %s: %w", path, err)
+	}
+	return token.AccessToken, nil
+}
+
+func getMetadataToken() (string, error) {
+	host := os.Getenv(envMetadataHost)
+	if host == "" {
+		host = defaultMetadataHost
+	}
+
+	req, err := http.NewRequest(http.MethodGet, fmt.Sprintf("http://%s%s", host, metadataTokenPath), nil)
+	if err != nil {
+		return "", err
+	}
+	req.Header.Set("Metadata-Flavor", "Google")
+
+	httpClient := &http.Client{Timeout: defaultTimeout}
+	res, err := httpClient.Do(req)
+	if err != nil {
+		return "", fmt.Errorf("could not get GCP token from the metadata server. Set gcpCredentialsFile or %s when not running on GCP: %w",
+			envCredentials, err)
+	}
+	defer res.Body.Close()
+
+	body, err := io.ReadAll(res.Body)
+	if err != nil {
+		return "", err
+	}
+	if res.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("could not get GCP token from the metadata server: %d %s", res.StatusCode, string(body))
+	}
+
+	token := tokenResponse{}
+	err = json.Unmarshal(body, &token)
+	if err != nil {
+		return "", fmt.Errorf("could not parse GCP metadata server token: %w", err)
+	}
+	return token.AccessToken, nil
+}
+
+// wellKnownCredentialsPath returns the location of the credentials written by gcloud auth application-default login
+func wellKnownCredentialsPath() string {
+	if runtime.GOOS == "windows" {
+		return filepath.Join(os.Getenv("APPDATA"), "gcloud", "application_default_credentials.json")
+	}
+	home, _ := os.UserHomeDir()
+	return filepath.Join(home, ".config", "gcloud", "application_default_credentials.json")
+}
@@ -0,0 +1,376 @@
+/*
+ * Copyright 2023 Venafi, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *  http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package gcp
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/Venafi/vcert/v5/pkg/util"
+)
+
+const (
+	certificateManagerURL = "https://certificatemanager.googleapis.com/v1"
+	secretManagerURL      = "https://secretmanager.googleapis.com/v1"
+	cloudDNSURL           = "https://dns.googleapis.com/dns/v1"
+
+	defaultTimeout = 30 * time.Second
+
+	// operationPollInterval and operationPollAttempts bound the wait for Certificate Manager long-running operations
+	operationPollInterval = 2 * time.Second
+	operationPollAttempts = 60
+)
+
+// Certificate represents a Certificate Manager certificate. PEMCertificate holds the certificate followed by its chain
+type Certificate struct {
+	Name           string            `json:"name"`
+	PEMCertificate string            `json:"pemCertificate,omitempty"`
+	ExpireTime     string            `json:"expireTime,omitempty"`
+	Labels         map[string]string `json:"labels,omitempty"`
+}
+
+type selfManagedCertificate struct {
+	SelfManaged struct {
+		PEMCertificate string `json:"pemCertificate"`
+		PEMPrivateKey  string `json:"pemPrivateKey"`
+	} `json:"selfManaged"`
+	Labels map[string]string `json:"labels,omitempty"`
+}
+
+type operation struct {
+	Name  string `json:"name"`
+	Done  bool   `json:"done"`
+	Error *struct {
+		Code    int    `json:"code"`
+		Message string `json:"message"`
+	} `json:"error,omitempty"`
+}
+
+// Secret represents a Secret Manager secret
+type Secret struct {
+	Name        string            `json:"name"`
+	Labels      map[string]string `json:"labels,omitempty"`
+	Annotations map[string]string `json:"annotations,omitempty"`
+}
+
+// SecretVersion represents a version of a Secret Manager secret
+type SecretVersion struct {
+	Name  string `json:"name"`
+	State string `json:"state,omitempty"`
+}
+
+// Version returns the version number of the secret version, which is the last segment of its name
+func (v SecretVersion) Version() string {
+	return v.Name[strings.LastIndex(v.Name, "/")+1:]
+}
+
+type secretPayload struct {
+	Payload struct {
+		Data string `json:"data"`
+	} `json:"payload"`
+}
+
+// Client is a minimal client for the Certificate Manager, Secret Manager and Cloud DNS REST APIs
+type Client struct {
+	project    string
+	token      string
+	httpClient *http.Client
+}
+
+// NewClient returns a Client for the project, authenticated with the credentials file or the Application Default Credentials
+func NewClient(project string, credentialsPath string) (*Client, error) {
+	token, err := GetToken(credentialsPath)
+	if err != nil {
+		return nil, err
+	}
+
+	return &Client{
+		project:    project,
+		token:      token,
+		httpClient: &http.Client{Timeout: defaultTimeout},
+	}, nil
+}
+
+// GetCertificate retrieves the Certificate Manager certificate id. Returns nil if the certificate does not exist
+func (c *Client) GetCertificate(ctx context.Context, location string, id string) (*Certificate, error) {
+	statusCode, body, err := c.request(ctx, http.MethodGet, c.certificateURL(location, id), nil)
+	if err != nil {
+		return nil, err
+	}
+
+	switch statusCode {
+	case http.StatusOK:
+		cert := &Certificate{}
+		err = json.Unmarshal(body, cert)
+		if err != nil {
+			return nil, fmt.Errorf("could not parse certificate %s: %w", id, err)
+		}
+		return cert, nil
+	case http.StatusNotFound:
+		return nil, nil
+	default:
+		return nil, fmt.Errorf("unexpected status code retrieving certificate %s: %d %s", id, statusCode, string(body))
+	}
+}
+
+// CreateCertificate creates the self-managed Certificate Manager certificate id, and waits for the operation to finish
+func (c *Client) CreateCertificate(ctx context.Context, location string, id string, certPEM string, keyPEM string, labels map[string]string) error {
+	data := selfManagedCertificate{Labels: labels}
+	data.SelfManaged.PEMCertificate = certPEM
+	data.SelfManaged.PEMPrivateKey = keyPEM
+
+	u := fmt.Sprintf("%s/projects/%s/locations/%s/certificates?certificateId=%s", certificateManagerURL,
+		url.PathEscape(c.project), url.PathEscape(location), url.QueryEscape(id))
+	return c.runOperation(ctx, http.MethodPost, u, data, fmt.Sprintf("creating certificate %s", id))
+}
+
+// UpdateCertificate replaces the certificate and private key of the Certificate Manager certificate id, and waits for
+// the operation to finish. The certificate keeps its name, so the certificate maps and load balancers using it pick up
+// the new certificate
+func (c *Client) UpdateCertificate(ctx context.Context, location string, id string, certPEM string, keyPEM string) error {
+	data := selfManagedCertificate{}
+	data.SelfManaged.PEMCertificate = certPEM
+	data.SelfManaged.PEMPrivateKey = keyPEM
+
+	u := fmt.Sprintf("%s?updateMask=selfManaged", c.certificateURL(location, id))
+	return c.runOperation(ctx, http.MethodPatch, u, data, fmt.Sprintf("updating certificate %s", id))
+}
+
+// GetSecret retrieves the Secret Manager secret id. Returns nil if the secret does not exist
+func (c *Client) GetSecret(ctx context.Context, id string) (*Secret, error) {
+	statusCode, body, err := c.request(ctx, http.MethodGet, c.secretURL(id), nil)
+	if err != nil {
+		return nil, err
+	}
+
+	switch statusCode {
+	case http.StatusOK:
+		secret := &Secret{}
+		err = json.Unmarshal(body, secret)
+		if err != nil {
+			return nil, fmt.Errorf("could not parse secret %s: %w", id, err)
+		}
+		return secret, nil
+	case http.StatusNotFound:
+		return nil, nil
+	default:
+		return nil, fmt.Errorf("unexpected status code retrieving secret %s: %d %s", id, statusCode, string(body))
+	}
+}
+
+// CreateSecret creates the Secret Manager secret id, with automatic replication
+func (c *Client) CreateSecret(ctx context.Context, id string, labels map[string]string) error {
+	data := map[string]interface{}{
+		"replication": map[string]interface{}{"automatic": map[string]interface{}{}},
+		"labels":      labels,
+	}
+
+	u := fmt.Sprintf("%s/projects/%s/secrets?secretId=%s", secretManagerURL, url.PathEscape(c.project), url.QueryEscape(id))
+	statusCode, body, err := c.request(ctx, http.MethodPost, u, data)
+	if err != nil {
+		return err
+	}
+	if statusCode != http.StatusOK {
+		return fmt.Errorf("unexpected status code creating secret %s: %d %s", id, statusCode, string(body))
+	}
+	return nil
+}
+
+// SetSecretAnnotations replaces the annotations of the Secret Manager secret id
+func (c *Client) SetSecretAnnotations(ctx context.Context, id string, annotations map[string]string) error {
+	data := map[string]interface{}{"annotations": annotations}
+
+	statusCode, body, err := c.request(ctx, http.MethodPatch, fmt.Sprintf("%s?updateMask=annotations", c.secretURL(id)), data)
+	if err != nil {
+		return err
+	}
+	if statusCode != http.StatusOK {
+		return fmt.Errorf("unexpected status code updating secret %s: %d %s", id, statusCode, string(body))
+	}
+	return nil
+}
+
+// AddSecretVersion stores value as a new version of the Secret Manager secret id, which becomes its latest version
+func (c *Client) AddSecretVersion(ctx context.Context, id string, value []byte) (*SecretVersion, error) {
+	data := secretPayload{}
+	data.Payload.Data = base64.StdEncoding.EncodeToString(value)
+
+	statusCode, body, err := c.request(ctx, http.MethodPost, fmt.Sprintf("%s:addVersion", c.secretURL(id)), data)
+	if err != nil {
+		return nil, err
+	}
+	if statusCode != http.StatusOK {
+		return nil, fmt.Errorf("unexpected status code adding version to secret %s: %d %s", id, statusCode, string(body))
+	}
+
+	version := &SecretVersion{}
+	err = json.Unmarshal(body, version)
+	if err != nil {
+		return nil, fmt.Errorf("could not parse version of secret %s: %w", id, err)
+	}
+	return version, nil
+}
+
+// GetSecretVersion retrieves the metadata of the given version of the Secret Manager secret id.
+// version may be "latest". Returns nil if the version does not exist
+func (c *Client) GetSecretVersion(ctx context.Context, id string, version string) (*SecretVersion, error) {
+	statusCode, body, err := c.request(ctx, http.MethodGet, c.secretVersionURL(id, version), nil)
+	if err != nil {
+		return nil, err
+	}
+
+	switch statusCode {
+	case http.StatusOK:
+		v := &SecretVersion{}
+		err = json.Unmarshal(body, v)
+		if err != nil {
+			return nil, fmt.Errorf("could not parse version of secret %s: %w", id, err)
+		}
+		return v, nil
+	case http.StatusNotFound:
+		return nil, nil
+	default:
+		return nil, fmt.Errorf("unexpected status code retrieving version of secret %s: %d %s", id, statusCode, string(body))
+	}
+}
+
+// AccessSecretVersion returns the value of the given version of the Secret Manager secret id.
+// version may be "latest". Returns nil if the secret or the version do not exist
+func (c *Client) AccessSecretVersion(ctx context.Context, id string, version string) ([]byte, error) {
+	statusCode, body, err := c.request(ctx, http.MethodGet, fmt.Sprintf("%s:access", c.secretVersionURL(id, version)), nil)
+	if err != nil {
+		return nil, err
+	}
+
+	switch statusCode {
+	case http.StatusOK:
+		data := secretPayload{}
+		err = json.Unmarshal(body, &data)
+		if err != nil {
+			return nil, fmt.Errorf("could not parse secret %s: %w", id, err)
+		}
+		return base64.StdEncoding.DecodeString(data.Payload.Data)
+	case http.StatusNotFound:
+		return nil, nil
+	default:
+		return nil, fmt.Errorf("unexpected status code accessing secret %s: %d %s", id, statusCode, string(body))
+	}
+}
+
+// DisableSecretVersion disables the given version of the Secret Manager secret id, so it is no longer its latest version
+func (c *Client) DisableSecretVersion(ctx context.Context, id string, version string) error {
+	statusCode, body, err := c.request(ctx, http.MethodPost, fmt.Sprintf("%s:disable", c.secretVersionURL(id, version)), map[string]string{})
+	if err != nil {
+		return err
+	}
+	if statusCode != http.StatusOK {
+		return fmt.Errorf("unexpected status code disabling version %s of secret %s: %d %s", version, id, statusCode, string(body))
+	}
+	return nil
+}
+
+// runOperation sends a request that starts a long-running operation, and waits for the operation to finish
+func (c *Client) runOperation(ctx context.Context, method string, u string, data interface{}, description string) error {
+	statusCode, body, err := c.request(ctx, method, u, data)
+	if err != nil {
+		return err
+	}
+
+	for attempt := 0; ; attempt++ {
+		if statusCode != http.StatusOK {
+			return fmt.Errorf("unexpected status code %s: %d %s", description, statusCode, string(body))
+		}
+		op := operation{}
+		err = json.Unmarshal(body, &op)
+		if err != nil {
+			return fmt.Errorf("could not parse operation %s: %w", description, err)
+		}
+		if op.Done {
+			if op.Error != nil {
+				return fmt.Errorf("error %s: %d %s", description, op.Error.Code, op.Error.Message)
+			}
+			return nil
+		}
+		if attempt >= operationPollAttempts {
+			return fmt.Errorf("timed out %s. Operation %s is still running", description, op.Name)
+		}
+
+		err = util.Sleep(ctx, operationPollInterval)
+		if err != nil {
+			return err
+		}
+		statusCode, body, err = c.request(ctx, http.MethodGet, fmt.Sprintf("%s/%s", certificateManagerURL, op.Name), nil)
+		if err != nil {
+			return err
+		}
+	}
+}
+
+func (c *Client) request(ctx context.Context, method string, u string, data interface{}) (int, []byte, error) {
+	var payload io.Reader
+	if data != nil {
+		b, err := json.Marshal(data)
+		if err != nil {
+			return 0, nil, err
+		}
+		payload = bytes.NewReader(b)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, method, u, payload)
+	if err != nil {
+		return 0, nil, err
+	}
+	req.Header.Set("Accept", "application/json")
+	if payload != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	req.Header.Set("Authorization", fmt.Sprintf("Bearer %s", c.token))
+
+	res, err := c.httpClient.Do(req)
+	if err != nil {
+		return 0, nil, err
+	}
+	defer res.Body.Close()
+
+	body, err := io.ReadAll(res.Body)
+	if err != nil {
+		return res.StatusCode, nil, err
+	}
+
+	return res.StatusCode, body, nil
+}
+
+func (c *Client) certificateURL(location string, id string) string {
+	return fmt.Sprintf("%s/projects/%s/locations/%s/certificates/%s", certificateManagerURL, url.PathEscape(c.project),
+		url.PathEscape(location), url.PathEscape(id))
+}
+
+func (c *Client) secretURL(id string) string {
+	return fmt.Sprintf("%s/projects/%s/secrets/%s", secretManagerURL, url.PathEscape(c.project), url.PathEscape(id))
+}
+
+func (c *Client) secretVersionURL(id string, version string) string {
+	return fmt.Sprintf("%s/versions/%s", c.secretURL(id), url.PathEscape(version))
+}
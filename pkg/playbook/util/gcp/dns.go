@@ -0,0 +1,110 @@
+/*
+ * Copyright 2023 Venafi, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *  http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package gcp
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+)
+
+type resourceRecordSet struct {
+	Name    string   `json:"name"`
+	Type    string   `json:"type"`
+	TTL     int      `json:"ttl"`
+	RRDatas []string `json:"rrdatas"`
+}
+
+// GetTXTRecord returns the values of the TXT record set name, a fully qualified name ending with a dot,
+// in the Cloud DNS managed zone. Returns nil if the record set does not exist
+func (c *Client) GetTXTRecord(ctx context.Context, managedZone string, name string) ([]string, error) {
+	statusCode, body, err := c.request(ctx, http.MethodGet, c.txtRecordURL(managedZone, name), nil)
+	if err != nil {
+		return nil, err
+	}
+
+	switch statusCode {
+	case http.StatusOK:
+		recordSet := resourceRecordSet{}
+		err = json.Unmarshal(body, &recordSet)
+		if err != nil {
+			return nil, fmt.Errorf("could not parse TXT record %s: %w", name, err)
+		}
+		values := make([]string, 0, len(recordSet.RRDatas))
+		for _, data := range recordSet.RRDatas {
+			if value, err := strconv.Unquote(data); err == nil {
+				data = value
+			}
+			values = append(values, data)
+		}
+		return values, nil
+	case http.StatusNotFound:
+		return nil, nil
+	default:
+		return nil, fmt.Errorf("unexpected status code retrieving TXT record %s: %d %s", name, statusCode, string(body))
+	}
+}
+
+// SetTXTRecord creates or replaces the TXT record set name in the Cloud DNS managed zone with values
+func (c *Client) SetTXTRecord(ctx context.Context, managedZone string, name string, values []string, ttl int) error {
+	existing, err := c.GetTXTRecord(ctx, managedZone, name)
+	if err != nil {
+		return err
+	}
+
+	recordSet := resourceRecordSet{Name: name, Type: "TXT", TTL: ttl}
+	for _, value := range values {
+		recordSet.RRDatas = append(recordSet.RRDatas, strconv.Quote(value))
+	}
+
+	method, u := http.MethodPatch, c.txtRecordURL(managedZone, name)
+	if existing == nil {
+		method, u = http.MethodPost, fmt.Sprintf("%s/rrsets", c.managedZoneURL(managedZone))
+	}
+	statusCode, body, err := c.request(ctx, method, u, recordSet)
+	if err != nil {
+		return err
+	}
+	if statusCode != http.StatusOK {
+		return fmt.Errorf("unexpected status code setting TXT record %s: %d %s", name, statusCode, string(body))
+	}
+	return nil
+}
+
+// DeleteTXTRecord deletes the TXT record set name in the Cloud DNS managed zone.
+// Deleting a record set that does not exist is not an error
+func (c *Client) DeleteTXTRecord(ctx context.Context, managedZone string, name string) error {
+	statusCode, body, err := c.request(ctx, http.MethodDelete, c.txtRecordURL(managedZone, name), nil)
+	if err != nil {
+		return err
+	}
+	if statusCode != http.StatusOK && statusCode != http.StatusNoContent && statusCode != http.StatusNotFound {
+		return fmt.Errorf("unexpected status code deleting TXT record %s: %d %s", name, statusCode, string(body))
+	}
+	return nil
+}
+
+func (c *Client) managedZoneURL(managedZone string) string {
+	return fmt.Sprintf("%s/projects/%s/managedZones/%s", cloudDNSURL, url.PathEscape(c.project), url.PathEscape(managedZone))
+}
+
+func (c *Client) txtRecordURL(managedZone string, name string) string {
+	return fmt.Sprintf("%s/rrsets/%s/TXT", c.managedZoneURL(managedZone), url.PathEscape(name))
+}
@@ -0,0 +1,61 @@
+/*
+ * Copyright 2023 Venafi, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *  http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package util
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+)
+
+// HTTPError represents an error returned by the REST API of a service an installer talks to
+type HTTPError struct {
+	// Service is the name of the service, used in the error message. i.e. Vault, BIG-IP
+	Service    string
+	Method     string
+	Path       string
+	StatusCode int
+	// Code is the service specific error code of the response, if any
+	Code    string
+	Message string
+}
+
+func (e *HTTPError) Error() string {
+	msg := fmt.Sprintf("%s %s %s failed: %d %s", e.Service, e.Method, e.Path, e.StatusCode, e.Message)
+	if e.Code != "" {
+		msg = fmt.Sprintf("%s (%s)", msg, e.Code)
+	}
+	return msg
+}
+
+// IsNotFound returns true if err is an HTTPError for a resource that does not exist: its status code is 404 or its
+// Code is one of notFoundCodes, for the services reporting missing resources with another status code
+func IsNotFound(err error, notFoundCodes ...string) bool {
+	var httpErr *HTTPError
+	if !errors.As(err, &httpErr) {
+		return false
+	}
+	if httpErr.StatusCode == http.StatusNotFound {
+		return true
+	}
+	for _, code := range notFoundCodes {
+		if httpErr.Code != "" && httpErr.Code == code {
+			return true
+		}
+	}
+	return false
+}
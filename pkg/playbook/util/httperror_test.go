@@ -0,0 +1,59 @@
+/*
+ * Copyright 2023 Venafi, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *  http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package util
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"testing"
+)
+
+func TestIsNotFound(t *testing.T) {
+	notFound := &HTTPError{Service: "Vault", Method: http.MethodGet, Path: "secret/data/app", StatusCode: http.StatusNotFound}
+	nitroNotFound := &HTTPError{Service: "Citrix ADC", Method: http.MethodGet, Path: "/nitro/v1/config/sslcertkey/web",
+		StatusCode: 599, Code: "258", Message: "No such resource"}
+	forbidden := &HTTPError{Service: "Vault", Method: http.MethodGet, Path: "secret/data/app", StatusCode: http.StatusForbidden}
+
+	cases := []struct {
+		name     string
+		err      error
+		codes    []string
+		expected bool
+	}{
+		{name: "nil", err: nil, expected: false},
+		{name: "other error", err: errors.New("connection refused"), expected: false},
+		{name: "404", err: notFound, expected: true},
+		{name: "wrapped 404", err: fmt.Errorf("could not read secret: %w", notFound), expected: true},
+		{name: "other status", err: forbidden, codes: []string{""}, expected: false},
+		{name: "code", err: nitroNotFound, codes: []string{"258"}, expected: true},
+		{name: "other code", err: nitroNotFound, codes: []string{"1540"}, expected: false},
+		{name: "code not expected", err: nitroNotFound, expected: false},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			if IsNotFound(c.err, c.codes...) != c.expected {
+				t.Fatalf("expected IsNotFound to return %t for %v", c.expected, c.err)
+			}
+		})
+	}
+
+	expected := "Citrix ADC GET /nitro/v1/config/sslcertkey/web failed: 599 No such resource (258)"
+	if nitroNotFound.Error() != expected {
+		t.Fatalf("unexpected error message %q", nitroNotFound.Error())
+	}
+}
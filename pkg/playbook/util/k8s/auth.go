@@ -0,0 +1,158 @@
+/*
+ * Copyright 2023 Venafi, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *  http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package k8s
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"os/exec"
+	"strings"
+)
+
+const (
+	execInfoEnvVar = "KUBERNETES_EXEC_INFO"
+
+	execAPIVersionV1      = "client.authentication.k8s.io/v1"
+	execAPIVersionV1Beta1 = "client.authentication.k8s.io/v1beta1"
+)
+
+// execConfig is the exec section of a kubeconfig user, which runs a credential plugin such as aws-iam-authenticator,
+// gke-gcloud-auth-plugin or kubelogin to get the credentials of the user
+type execConfig struct {
+	APIVersion string   `yaml:"apiVersion"`
+	Command    string   `yaml:"command"`
+	Args       []string `yaml:"args"`
+	Env        []struct {
+		Name  string `yaml:"name"`
+		Value string `yaml:"value"`
+	} `yaml:"env"`
+	InstallHint        string `yaml:"installHint"`
+	ProvideClusterInfo bool   `yaml:"provideClusterInfo"`
+}
+
+// authProviderConfig is the auth-provider section of a kubeconfig user
+type authProviderConfig struct {
+	Name   string            `yaml:"name"`
+	Config map[string]string `yaml:"config"`
+}
+
+// execCluster is the cluster information passed to the credential plugins setting provideClusterInfo
+type execCluster struct {
+	Server                   string `json:"server"`
+	CertificateAuthorityData []byte `json:"certificate-authority-data,omitempty"`
+	InsecureSkipTLSVerify    bool   `json:"insecure-skip-tls-verify,omitempty"`
+}
+
+// execCredential is the object exchanged with the credential plugins, through KUBERNETES_EXEC_INFO and their output
+type execCredential struct {
+	APIVersion string `json:"apiVersion"`
+	Kind       string `json:"kind"`
+	Spec       struct {
+		Interactive bool         `json:"interactive"`
+		Cluster     *execCluster `json:"cluster,omitempty"`
+	} `json:"spec"`
+	Status *struct {
+		Token                 string `json:"token"`
+		ClientCertificateData string `json:"clientCertificateData"`
+		ClientKeyData         string `json:"clientKeyData"`
+	} `json:"status,omitempty"`
+}
+
+// runExecPlugin runs the credential plugin of config and returns the credentials it prints. vcert runs unattended,
+// so the plugin is never interactive
+func runExecPlugin(ctx context.Context, config execConfig, cluster execCluster) (token string, certData []byte, keyData []byte, err error) {
+	if config.APIVersion != execAPIVersionV1 && config.APIVersion != execAPIVersionV1Beta1 {
+		return "", nil, nil, fmt.Errorf("exec plugin %q has unsupported apiVersion %q, expected %s or %s",
+			config.Command, config.APIVersion, execAPIVersionV1, execAPIVersionV1Beta1)
+	}
+
+	info := execCredential{APIVersion: config.APIVersion, Kind: "ExecCredential"}
+	if config.ProvideClusterInfo {
+		info.Spec.Cluster = &cluster
+	}
+	infoData, err := json.Marshal(info)
+	if err != nil {
+		return "", nil, nil, err
+	}
+
+	// #nosec G204 -- the command is set by the owner of the kubeconfig file
+	cmd := exec.CommandContext(ctx, config.Command, config.Args...)
+	cmd.Env = os.Environ()
+	for _, e := range config.Env {
+		cmd.Env = append(cmd.Env, fmt.Sprintf("%s=%s", e.Name, e.Value))
+	}
+	cmd.Env = append(cmd.Env, fmt.Sprintf("%s=%s", execInfoEnvVar, infoData))
+	stdout, stderr := &bytes.Buffer{}, &bytes.Buffer{}
+	cmd.Stdout = stdout
+	cmd.Stderr = stderr
+
+	err = cmd.Run()
+	if err != nil {
+		if config.InstallHint != "" && isNotInstalled(err) {
+			return "", nil, nil, fmt.Errorf("could not run exec plugin %q: %w\n%s", config.Command, err, config.InstallHint)
+		}
+		return "", nil, nil, fmt.Errorf("exec plugin %q failed: %w: %s", config.Command, err, strings.TrimSpace(stderr.String()))
+	}
+
+	cred := execCredential{}
+	err = json.Unmarshal(stdout.Bytes(), &cred)
+	if err != nil {
+		return "", nil, nil, fmt.Errorf("could not parse the output of exec plugin %q: %w", config.Command, err)
+	}
+	if cred.APIVersion != config.APIVersion {
+		return "", nil, nil, fmt.Errorf("exec plugin %q returned apiVersion %q, expected %q", config.Command, cred.APIVersion, config.APIVersion)
+	}
+	if cred.Status == nil {
+		return "", nil, nil, fmt.Errorf("exec plugin %q returned no status", config.Command)
+	}
+	status := cred.Status
+	if (status.ClientCertificateData == "") != (status.ClientKeyData == "") {
+		return "", nil, nil, fmt.Errorf("exec plugin %q returned a client certificate without its key, or a key without its certificate", config.Command)
+	}
+	if status.Token == "" && status.ClientCertificateData == "" {
+		return "", nil, nil, fmt.Errorf("exec plugin %q returned no token and no client certificate", config.Command)
+	}
+	return status.Token, []byte(status.ClientCertificateData), []byte(status.ClientKeyData), nil
+}
+
+func isNotInstalled(err error) bool {
+	execErr, ok := err.(*exec.Error)
+	return ok && execErr.Err == exec.ErrNotFound
+}
+
+// authProviderToken returns the bearer token of the auth-provider of a kubeconfig user. Only the ID token of the oidc
+// provider is supported: vcert does not refresh it, and the gcp and azure providers were removed from Kubernetes in
+// favor of exec plugins
+func authProviderToken(provider authProviderConfig) (string, error) {
+	switch provider.Name {
+	case "oidc":
+		token := provider.Config["id-token"]
+		if token == "" {
+			return "", fmt.Errorf("the oidc auth-provider has no id-token: log in with kubectl first, or use an exec plugin such as kubelogin")
+		}
+		return token, nil
+	case "gcp":
+		return "", fmt.Errorf("the gcp auth-provider was removed from Kubernetes: use the gke-gcloud-auth-plugin exec plugin instead")
+	case "azure":
+		return "", fmt.Errorf("the azure auth-provider was removed from Kubernetes: use the kubelogin exec plugin instead")
+	default:
+		return "", fmt.Errorf("unsupported auth-provider %q: use an exec plugin instead", provider.Name)
+	}
+}
@@ -0,0 +1,213 @@
+/*
+ * Copyright 2023 Venafi, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *  http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+// Package k8s is a minimal client of the Kubernetes API server, enough to read and write the Secrets of the k8s
+// installer, without the dependencies of client-go.
+//
+// The connection is configured from the in-cluster service account or from a kubeconfig file: server, certificate
+// authority, insecure-skip-tls-verify, proxy-url, client certificates, token and tokenFile, and exec plugins. The token
+// files, including the projected service account token, are read again on every request, so rotated tokens are used.
+// Unlike client-go, it does not support:
+//   - auth-provider plugins, but for the id-token of the oidc provider, which is not refreshed when it expires
+//   - refreshing the credentials of exec plugins, which run once per connection, when the kubeconfig is loaded
+//   - the tls-server-name, impersonation and username/password settings of kubeconfig
+//   - the KUBECONFIG environment variable, as the kubeconfig file is the one given to LoadConfig
+package k8s
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+const (
+	// SecretTypeTLS is the Kubernetes Secret type used to store a certificate and its private key
+	SecretTypeTLS = "kubernetes.io/tls"
+
+	// TLSCertKey is the key for the certificate (and chain) in a kubernetes.io/tls Secret
+	TLSCertKey = "tls.crt"
+	// TLSPrivateKeyKey is the key for the private key in a kubernetes.io/tls Secret
+	TLSPrivateKeyKey = "tls.key"
+	// CACertKey is the key for the issuing chain in the Secret
+	CACertKey = "ca.crt"
+
+	defaultTimeout = 30 * time.Second
+)
+
+// ObjectMeta represents the metadata of a Kubernetes object
+type ObjectMeta struct {
+	Name            string            `json:"name"`
+	Namespace       string            `json:"namespace,omitempty"`
+	ResourceVersion string            `json:"resourceVersion,omitempty"`
+	Labels          map[string]string `json:"labels,omitempty"`
+	Annotations     map[string]string `json:"annotations,omitempty"`
+}
+
+// Secret represents a Kubernetes Secret object. Data values are base64 encoded by the JSON encoder
+type Secret struct {
+	APIVersion string            `json:"apiVersion"`
+	Kind       string            `json:"kind"`
+	Metadata   ObjectMeta        `json:"metadata"`
+	Type       string            `json:"type,omitempty"`
+	Data       map[string][]byte `json:"data,omitempty"`
+}
+
+// Client is a minimal client for the Kubernetes API server
+type Client struct {
+	config     *RestConfig
+	httpClient *http.Client
+}
+
+// NewClient returns a Client that connects to the API server defined in config
+func NewClient(config *RestConfig) *Client {
+	return &Client{
+		config: config,
+		httpClient: &http.Client{
+			Timeout:   defaultTimeout,
+			Transport: &http.Transport{TLSClientConfig: config.TLSConfig, Proxy: proxyFunc(config.ProxyURL)},
+		},
+	}
+}
+
+// GetSecret retrieves the Secret name in namespace. Returns nil if the Secret does not exist
+func (c *Client) GetSecret(ctx context.Context, namespace string, name string) (*Secret, error) {
+	statusCode, body, err := c.request(ctx, http.MethodGet, secretPath(namespace, name), nil)
+	if err != nil {
+		return nil, err
+	}
+
+	switch statusCode {
+	case http.StatusOK:
+		secret := &Secret{}
+		err = json.Unmarshal(body, secret)
+		if err != nil {
+			return nil, fmt.Errorf("could not parse secret %s/%s: %w", namespace, name, err)
+		}
+		return secret, nil
+	case http.StatusNotFound:
+		return nil, nil
+	default:
+		return nil, fmt.Errorf("unexpected status code retrieving secret %s/%s: %d %s", namespace, name, statusCode, string(body))
+	}
+}
+
+// ApplySecret creates the Secret if it does not exist. Otherwise, it replaces the existing one
+func (c *Client) ApplySecret(ctx context.Context, secret Secret) error {
+	secret.APIVersion = "v1"
+	secret.Kind = "Secret"
+	namespace, name := secret.Metadata.Namespace, secret.Metadata.Name
+
+	current, err := c.GetSecret(ctx, namespace, name)
+	if err != nil {
+		return err
+	}
+
+	method := http.MethodPost
+	path := secretPath(namespace, "")
+	expected := http.StatusCreated
+	if current != nil {
+		zap.L().Debug("secret exists, replacing", zap.String("namespace", namespace), zap.String("secret", name))
+		method = http.MethodPut
+		path = secretPath(namespace, name)
+		expected = http.StatusOK
+		secret.Metadata.ResourceVersion = current.Metadata.ResourceVersion
+	}
+
+	statusCode, body, err := c.request(ctx, method, path, secret)
+	if err != nil {
+		return err
+	}
+	if statusCode != expected {
+		return fmt.Errorf("unexpected status code writing secret %s/%s: %d %s", namespace, name, statusCode, string(body))
+	}
+
+	return nil
+}
+
+func (c *Client) request(ctx context.Context, method string, path string, data interface{}) (int, []byte, error) {
+	var payload io.Reader
+	if data != nil {
+		b, err := json.Marshal(data)
+		if err != nil {
+			return 0, nil, err
+		}
+		payload = bytes.NewReader(b)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, method, c.config.Host+path, payload)
+	if err != nil {
+		return 0, nil, err
+	}
+	req.Header.Set("Accept", "application/json")
+	if payload != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	if token := c.bearerToken(); token != "" {
+		req.Header.Set("Authorization", fmt.Sprintf("Bearer %s", token))
+	}
+
+	res, err := c.httpClient.Do(req)
+	if err != nil {
+		return 0, nil, err
+	}
+	defer res.Body.Close()
+
+	body, err := io.ReadAll(res.Body)
+	if err != nil {
+		return res.StatusCode, nil, err
+	}
+
+	return res.StatusCode, body, nil
+}
+
+// bearerToken returns the token of the current request, read again from BearerTokenFile when set
+func (c *Client) bearerToken() string {
+	if c.config.BearerTokenFile == "" {
+		return c.config.BearerToken
+	}
+	token, err := os.ReadFile(c.config.BearerTokenFile)
+	if err != nil {
+		zap.L().Warn("could not read token file, using the token read before", zap.String("file", c.config.BearerTokenFile), zap.Error(err))
+		return c.config.BearerToken
+	}
+	return strings.TrimSpace(string(token))
+}
+
+// proxyFunc returns the proxy of the requests to the API server: proxyURL when set, otherwise the one of the
+// environment, as kubectl does
+func proxyFunc(proxyURL *url.URL) func(*http.Request) (*url.URL, error) {
+	if proxyURL != nil {
+		return http.ProxyURL(proxyURL)
+	}
+	return http.ProxyFromEnvironment
+}
+
+func secretPath(namespace string, name string) string {
+	path := fmt.Sprintf("/api/v1/namespaces/%s/secrets", url.PathEscape(namespace))
+	if name != "" {
+		path = fmt.Sprintf("%s/%s", path, url.PathEscape(name))
+	}
+	return path
+}
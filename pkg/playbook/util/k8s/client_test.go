@@ -0,0 +1,186 @@
+/*
+ * Copyright 2023 Venafi, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *  http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package k8s
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+)
+
+// secretServer is a mock API server storing the secrets written to it
+type secretServer struct {
+	mu      sync.Mutex
+	secrets map[string]Secret
+	methods []string
+}
+
+func (s *secretServer) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.methods = append(s.methods, r.Method)
+
+	if r.Header.Get("Authorization") != "Bearer test-token" {
+		w.WriteHeader(http.StatusUnauthorized)
+		return
+	}
+	const prefix = "/api/v1/namespaces/default/secrets"
+	if !strings.HasPrefix(r.URL.Path, prefix) {
+		w.WriteHeader(http.StatusNotFound)
+		return
+	}
+	name := strings.TrimPrefix(strings.TrimPrefix(r.URL.Path, prefix), "/")
+
+	switch r.Method {
+	case http.MethodGet:
+		secret, ok := s.secrets[name]
+		if !ok {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		_ = json.NewEncoder(w).Encode(secret)
+	case http.MethodPost, http.MethodPut:
+		secret := Secret{}
+		err := json.NewDecoder(r.Body).Decode(&secret)
+		if err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		if r.Method == http.MethodPut && secret.Metadata.ResourceVersion != s.secrets[name].Metadata.ResourceVersion {
+			w.WriteHeader(http.StatusConflict)
+			return
+		}
+		secret.Metadata.ResourceVersion += "1"
+		s.secrets[secret.Metadata.Name] = secret
+		if r.Method == http.MethodPost {
+			w.WriteHeader(http.StatusCreated)
+		}
+		_ = json.NewEncoder(w).Encode(secret)
+	}
+}
+
+func newTestClient(t *testing.T, handler http.Handler, token string) *Client {
+	t.Helper()
+	server := httptest.NewTLSServer(handler)
+	t.Cleanup(server.Close)
+	pool := x509.NewCertPool()
+	pool.AddCert(server.Certificate())
+	return NewClient(&RestConfig{Host: server.URL, BearerToken: token, TLSConfig: &tls.Config{RootCAs: pool, MinVersion: tls.VersionTLS12}})
+}
+
+func TestApplySecret(t *testing.T) {
+	ctx := context.Background()
+	server := &secretServer{secrets: map[string]Secret{}}
+	client := newTestClient(t, server, "test-token")
+
+	secret, err := client.GetSecret(ctx, "default", "web")
+	if err != nil || secret != nil {
+		t.Fatalf("expected missing secret to be nil but got %v, %v", secret, err)
+	}
+
+	err = client.ApplySecret(ctx, Secret{Metadata: ObjectMeta{Name: "web", Namespace: "default"}, Type: SecretTypeTLS,
+		Data: map[string][]byte{TLSCertKey: []byte("cert-1")}})
+	if err != nil {
+		t.Fatalf("failed to create secret: %s", err)
+	}
+	err = client.ApplySecret(ctx, Secret{Metadata: ObjectMeta{Name: "web", Namespace: "default"}, Type: SecretTypeTLS,
+		Data: map[string][]byte{TLSCertKey: []byte("cert-2")}})
+	if err != nil {
+		t.Fatalf("failed to replace secret: %s", err)
+	}
+
+	secret, err = client.GetSecret(ctx, "default", "web")
+	if err != nil {
+		t.Fatalf("failed to get secret: %s", err)
+	}
+	if string(secret.Data[TLSCertKey]) != "cert-2" || secret.Kind != "Secret" || secret.APIVersion != "v1" {
+		t.Fatalf("unexpected secret %+v", secret)
+	}
+	expected := []string{http.MethodGet, http.MethodGet, http.MethodPost, http.MethodGet, http.MethodPut, http.MethodGet}
+	if strings.Join(server.methods, ",") != strings.Join(expected, ",") {
+		t.Fatalf("expected requests %v but got %v", expected, server.methods)
+	}
+}
+
+func TestApplySecretUnauthorized(t *testing.T) {
+	ctx := context.Background()
+	client := newTestClient(t, &secretServer{secrets: map[string]Secret{}}, "wrong-token")
+
+	_, err := client.GetSecret(ctx, "default", "web")
+	if err == nil || !strings.Contains(err.Error(), "401") {
+		t.Fatalf("expected unauthorized error but got %v", err)
+	}
+	err = client.ApplySecret(ctx, Secret{Metadata: ObjectMeta{Name: "web", Namespace: "default"}})
+	if err == nil {
+		t.Fatalf("expected unauthorized apply to fail")
+	}
+}
+
+func TestClientRotatedTokenFile(t *testing.T) {
+	ctx := context.Background()
+	tokenFile := filepath.Join(t.TempDir(), "token")
+	err := os.WriteFile(tokenFile, []byte("expired-token\n"), 0600)
+	if err != nil {
+		t.Fatal(err)
+	}
+	client := newTestClient(t, &secretServer{secrets: map[string]Secret{}}, "expired-token")
+	client.config.BearerTokenFile = tokenFile
+
+	_, err = client.GetSecret(ctx, "default", "web")
+	if err == nil || !strings.Contains(err.Error(), "401") {
+		t.Fatalf("expected unauthorized error but got %v", err)
+	}
+
+	// The kubelet replaces the projected token before it expires
+	err = os.WriteFile(tokenFile, []byte("test-token\n"), 0600)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = client.GetSecret(ctx, "default", "web")
+	if err != nil {
+		t.Fatalf("expected the rotated token to be used but got %s", err)
+	}
+}
+
+func TestClientProxyURL(t *testing.T) {
+	server := &secretServer{secrets: map[string]Secret{}}
+	proxy := httptest.NewServer(server)
+	defer proxy.Close()
+	proxyURL, err := url.Parse(proxy.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// The API server cannot be resolved, so the request only succeeds through the proxy
+	client := NewClient(&RestConfig{Host: "http://k8s.invalid", BearerToken: "test-token", ProxyURL: proxyURL})
+	_, err = client.GetSecret(context.Background(), "default", "web")
+	if err != nil {
+		t.Fatalf("expected the request to go through the proxy but got %s", err)
+	}
+	if len(server.methods) != 1 {
+		t.Fatalf("expected 1 request through the proxy but got %v", server.methods)
+	}
+}
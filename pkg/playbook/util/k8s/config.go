@@ -0,0 +1,298 @@
+/*
+ * Copyright 2023 Venafi, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *  http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package k8s
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/base64"
+	"fmt"
+	"net"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+const (
+	inClusterTokenFile  = "/var/run/secrets/kubernetes.io/serviceaccount/token"
+	inClusterCAFile     = "/var/run/secrets/kubernetes.io/serviceaccount/ca.crt"
+	inClusterHostEnvVar = "KUBERNETES_SERVICE_HOST"
+	inClusterPortEnvVar = "KUBERNETES_SERVICE_PORT"
+)
+
+// RestConfig holds the values required to connect to the Kubernetes API server
+type RestConfig struct {
+	Host        string
+	BearerToken string
+	// BearerTokenFile is read again on every request when set, as the kubelet rotates the projected service account
+	// tokens. BearerToken is used when it cannot be read
+	BearerTokenFile string
+	TLSConfig       *tls.Config
+	// ProxyURL is the proxy-url of the cluster. The HTTPS_PROXY and NO_PROXY environment variables apply when nil
+	ProxyURL *url.URL
+}
+
+// kubeConfig represents the subset of the kubeconfig file format used by vcert
+type kubeConfig struct {
+	CurrentContext string `yaml:"current-context"`
+	Clusters       []struct {
+		Name    string `yaml:"name"`
+		Cluster struct {
+			Server                   string `yaml:"server"`
+			CertificateAuthority     string `yaml:"certificate-authority"`
+			CertificateAuthorityData string `yaml:"certificate-authority-data"`
+			InsecureSkipTLSVerify    bool   `yaml:"insecure-skip-tls-verify"`
+			ProxyURL                 string `yaml:"proxy-url"`
+		} `yaml:"cluster"`
+	} `yaml:"clusters"`
+	Contexts []struct {
+		Name    string `yaml:"name"`
+		Context struct {
+			Cluster string `yaml:"cluster"`
+			User    string `yaml:"user"`
+		} `yaml:"context"`
+	} `yaml:"contexts"`
+	Users []struct {
+		Name string `yaml:"name"`
+		User struct {
+			ClientCertificate     string              `yaml:"client-certificate"`
+			ClientCertificateData string              `yaml:"client-certificate-data"`
+			ClientKey             string              `yaml:"client-key"`
+			ClientKeyData         string              `yaml:"client-key-data"`
+			Token                 string              `yaml:"token"`
+			TokenFile             string              `yaml:"tokenFile"`
+			Exec                  *execConfig         `yaml:"exec"`
+			AuthProvider          *authProviderConfig `yaml:"auth-provider"`
+		} `yaml:"user"`
+	} `yaml:"users"`
+}
+
+// LoadConfig returns the connection values for the Kubernetes API server.
+//
+// When kubeconfigPath is empty, the in-cluster service account configuration is used.
+// kubeContext selects a context from the kubeconfig file. If empty, current-context is used.
+// The exec plugin of the user, if any, runs under ctx, and relative paths are resolved from the directory of the kubeconfig file
+func LoadConfig(ctx context.Context, kubeconfigPath string, kubeContext string) (*RestConfig, error) {
+	if kubeconfigPath == "" {
+		return loadInClusterConfig()
+	}
+	return loadKubeConfig(ctx, kubeconfigPath, kubeContext)
+}
+
+func loadInClusterConfig() (*RestConfig, error) {
+	host, port := os.Getenv(inClusterHostEnvVar), os.Getenv(inClusterPortEnvVar)
+	if host == "" || port == "" {
+		return nil, fmt.Errorf("no kubeconfig provided and not running inside a Kubernetes cluster: %s and %s must be defined",
+			inClusterHostEnvVar, inClusterPortEnvVar)
+	}
+
+	token, err := os.ReadFile(inClusterTokenFile)
+	if err != nil {
+		return nil, fmt.Errorf("could not read service account token: %w", err)
+	}
+
+	caData, err := os.ReadFile(inClusterCAFile)
+	if err != nil {
+		return nil, fmt.Errorf("could not read service account CA bundle: %w", err)
+	}
+
+	tlsConfig, err := buildTLSConfig(caData, false)
+	if err != nil {
+		return nil, err
+	}
+
+	return &RestConfig{
+		Host:            "https://" + net.JoinHostPort(host, port),
+		BearerToken:     strings.TrimSpace(string(token)),
+		BearerTokenFile: inClusterTokenFile,
+		TLSConfig:       tlsConfig,
+	}, nil
+}
+
+func loadKubeConfig(ctx context.Context, location string, kubeContext string) (*RestConfig, error) {
+	data, err := os.ReadFile(location)
+	if err != nil {
+		return nil, fmt.Errorf("could not read kubeconfig file: %w", err)
+	}
+
+	kc := kubeConfig{}
+	err = yaml.Unmarshal(data, &kc)
+	if err != nil {
+		return nil, fmt.Errorf("could not parse kubeconfig file: %w", err)
+	}
+
+	if kubeContext == "" {
+		kubeContext = kc.CurrentContext
+	}
+	if kubeContext == "" {
+		return nil, fmt.Errorf("no context specified and kubeconfig has no current-context")
+	}
+
+	clusterName, userName := "", ""
+	found := false
+	for _, c := range kc.Contexts {
+		if c.Name == kubeContext {
+			clusterName = c.Context.Cluster
+			userName = c.Context.User
+			found = true
+			break
+		}
+	}
+	if !found {
+		return nil, fmt.Errorf("context %q not found in kubeconfig", kubeContext)
+	}
+
+	dir := filepath.Dir(location)
+	cfg := &RestConfig{}
+	var caData []byte
+	insecure := false
+	found = false
+	for _, c := range kc.Clusters {
+		if c.Name != clusterName {
+			continue
+		}
+		found = true
+		cfg.Host = strings.TrimSuffix(c.Cluster.Server, "/")
+		insecure = c.Cluster.InsecureSkipTLSVerify
+		if c.Cluster.ProxyURL != "" {
+			cfg.ProxyURL, err = url.Parse(c.Cluster.ProxyURL)
+			if err != nil {
+				return nil, fmt.Errorf("invalid cluster proxy-url: %w", err)
+			}
+		}
+		caData, err = dataOrFile(c.Cluster.CertificateAuthorityData, resolvePath(dir, c.Cluster.CertificateAuthority))
+		if err != nil {
+			return nil, fmt.Errorf("could not load cluster certificate authority: %w", err)
+		}
+		break
+	}
+	if !found {
+		return nil, fmt.Errorf("cluster %q not found in kubeconfig", clusterName)
+	}
+
+	tlsConfig, err := buildTLSConfig(caData, insecure)
+	if err != nil {
+		return nil, err
+	}
+	cfg.TLSConfig = tlsConfig
+
+	found = userName == ""
+	for _, u := range kc.Users {
+		if u.Name != userName {
+			continue
+		}
+		found = true
+		cfg.BearerToken = u.User.Token
+		if cfg.BearerToken == "" && u.User.TokenFile != "" {
+			cfg.BearerTokenFile = resolvePath(dir, u.User.TokenFile)
+			token, err := os.ReadFile(cfg.BearerTokenFile)
+			if err != nil {
+				return nil, fmt.Errorf("could not read user token file: %w", err)
+			}
+			cfg.BearerToken = strings.TrimSpace(string(token))
+		}
+
+		certData, err := dataOrFile(u.User.ClientCertificateData, resolvePath(dir, u.User.ClientCertificate))
+		if err != nil {
+			return nil, fmt.Errorf("could not load user client certificate: %w", err)
+		}
+		keyData, err := dataOrFile(u.User.ClientKeyData, resolvePath(dir, u.User.ClientKey))
+		if err != nil {
+			return nil, fmt.Errorf("could not load user client key: %w", err)
+		}
+
+		if u.User.Exec != nil && u.User.AuthProvider != nil {
+			return nil, fmt.Errorf("user %q sets both exec and auth-provider", userName)
+		}
+		if u.User.Exec != nil {
+			plugin := *u.User.Exec
+			// As kubectl does, a command with a path separator is relative to the kubeconfig file, otherwise it is looked up in PATH
+			if strings.ContainsRune(plugin.Command, '/') || strings.ContainsRune(plugin.Command, filepath.Separator) {
+				plugin.Command = resolvePath(dir, plugin.Command)
+			}
+			token, execCert, execKey, err := runExecPlugin(ctx, plugin, execCluster{Server: cfg.Host, CertificateAuthorityData: caData, InsecureSkipTLSVerify: insecure})
+			if err != nil {
+				return nil, err
+			}
+			// The static credentials of the user take precedence over the ones of its plugin
+			if cfg.BearerToken == "" {
+				cfg.BearerToken = token
+			}
+			if len(certData) == 0 && len(keyData) == 0 {
+				certData, keyData = execCert, execKey
+			}
+		}
+		if u.User.AuthProvider != nil && cfg.BearerToken == "" {
+			cfg.BearerToken, err = authProviderToken(*u.User.AuthProvider)
+			if err != nil {
+				return nil, err
+			}
+		}
+
+		if len(certData) > 0 && len(keyData) > 0 {
+			clientCert, err := tls.X509KeyPair(certData, keyData)
+			if err != nil {
+				return nil, fmt.Errorf("could not build user client certificate: %w", err)
+			}
+			cfg.TLSConfig.Certificates = []tls.Certificate{clientCert}
+		}
+		break
+	}
+	if !found {
+		return nil, fmt.Errorf("user %q not found in kubeconfig", userName)
+	}
+
+	return cfg, nil
+}
+
+func buildTLSConfig(caData []byte, insecure bool) (*tls.Config, error) {
+	tlsConfig := &tls.Config{
+		MinVersion:         tls.VersionTLS12,
+		InsecureSkipVerify: insecure, // #nosec G402
+	}
+	if len(caData) > 0 {
+		pool := x509.NewCertPool()
+		if !pool.AppendCertsFromPEM(caData) {
+			return nil, fmt.Errorf("could not parse cluster certificate authority")
+		}
+		tlsConfig.RootCAs = pool
+	}
+	return tlsConfig, nil
+}
+
+// resolvePath returns location relative to dir, the directory of the kubeconfig file, when it is not absolute
+func resolvePath(dir string, location string) string {
+	if location == "" || filepath.IsAbs(location) {
+		return location
+	}
+	return filepath.Join(dir, location)
+}
+
+// dataOrFile returns the base64 decoded value of data if set. Otherwise, the content of the file in location
+func dataOrFile(data string, location string) ([]byte, error) {
+	if data != "" {
+		return base64.StdEncoding.DecodeString(data)
+	}
+	if location != "" {
+		return os.ReadFile(location)
+	}
+	return nil, nil
+}
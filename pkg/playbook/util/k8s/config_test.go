@@ -0,0 +1,279 @@
+/*
+ * Copyright 2023 Venafi, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *  http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package k8s
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"fmt"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+const execPluginEnvVar = "VCERT_TEST_K8S_EXEC_PLUGIN"
+
+// TestExecPluginHelper is not a test: it is run by the exec plugin tests as a credential plugin, printing a token
+// made of the server of the cluster passed through KUBERNETES_EXEC_INFO
+func TestExecPluginHelper(t *testing.T) {
+	mode := os.Getenv(execPluginEnvVar)
+	if mode == "" {
+		return
+	}
+	info := execCredential{}
+	err := json.Unmarshal([]byte(os.Getenv(execInfoEnvVar)), &info)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "invalid %s: %s", execInfoEnvVar, err)
+		os.Exit(1)
+	}
+	server := "none"
+	if info.Spec.Cluster != nil {
+		server = info.Spec.Cluster.Server
+	}
+	switch mode {
+	case "token":
+		fmt.Printf(`{"apiVersion":%q,"kind":"ExecCredential","status":{"token":"exec-token@%s"}}`, info.APIVersion, server)
+	case "wrong-version":
+		fmt.Printf(`{"apiVersion":"client.authentication.k8s.io/v1alpha1","kind":"ExecCredential","status":{"token":"exec-token"}}`)
+	case "fail":
+		fmt.Fprint(os.Stderr, "credentials expired")
+		os.Exit(1)
+	}
+	os.Exit(0)
+}
+
+func writeFile(t *testing.T, location string, content string) {
+	t.Helper()
+	err := os.MkdirAll(filepath.Dir(location), 0700)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = os.WriteFile(location, []byte(content), 0600)
+	if err != nil {
+		t.Fatal(err)
+	}
+}
+
+// writeKubeConfig writes a kubeconfig whose user is user, a YAML fragment indented under the user key
+func writeKubeConfig(t *testing.T, dir string, cluster string, user string) string {
+	t.Helper()
+	location := filepath.Join(dir, "config")
+	writeFile(t, location, fmt.Sprintf(`apiVersion: v1
+kind: Config
+current-context: test
+clusters:
+- name: test
+  cluster:
+%s
+contexts:
+- name: test
+  context:
+    cluster: test
+    user: test
+users:
+- name: test
+  user:
+%s
+`, cluster, user))
+	return location
+}
+
+func serverCAPEM(server *httptest.Server) string {
+	return string(pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: server.Certificate().Raw}))
+}
+
+func TestLoadConfigRelativePaths(t *testing.T) {
+	server := httptest.NewTLSServer(nil)
+	defer server.Close()
+
+	dir := t.TempDir()
+	writeFile(t, filepath.Join(dir, "pki", "ca.crt"), serverCAPEM(server))
+	writeFile(t, filepath.Join(dir, "pki", "token"), "file-token\n")
+	location := writeKubeConfig(t, dir,
+		fmt.Sprintf("    server: %s\n    certificate-authority: pki/ca.crt", server.URL),
+		"    tokenFile: pki/token")
+
+	cfg, err := LoadConfig(context.Background(), location, "")
+	if err != nil {
+		t.Fatalf("failed to load kubeconfig: %s", err)
+	}
+	if cfg.BearerToken != "file-token" {
+		t.Fatalf("expected token from the file relative to the kubeconfig but got %q", cfg.BearerToken)
+	}
+	if cfg.BearerTokenFile != filepath.Join(dir, "pki", "token") {
+		t.Fatalf("expected the token file to be read again on requests but got %q", cfg.BearerTokenFile)
+	}
+	if cfg.TLSConfig.RootCAs == nil {
+		t.Fatalf("expected the certificate authority relative to the kubeconfig to be loaded")
+	}
+}
+
+func TestLoadConfigExecPlugin(t *testing.T) {
+	cases := []struct {
+		name        string
+		mode        string
+		apiVersion  string
+		clusterInfo bool
+		token       string
+		err         string
+	}{
+		{name: "V1", mode: "token", apiVersion: execAPIVersionV1, token: "exec-token@none"},
+		{name: "V1Beta1", mode: "token", apiVersion: execAPIVersionV1Beta1, token: "exec-token@none"},
+		{name: "ClusterInfo", mode: "token", apiVersion: execAPIVersionV1, clusterInfo: true, token: "exec-token@https://k8s.example.com:6443"},
+		{name: "UnsupportedVersion", mode: "token", apiVersion: "client.authentication.k8s.io/v1alpha1", err: "unsupported apiVersion"},
+		{name: "VersionMismatch", mode: "wrong-version", apiVersion: execAPIVersionV1, err: "returned apiVersion"},
+		{name: "Failure", mode: "fail", apiVersion: execAPIVersionV1, err: "credentials expired"},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			location := writeKubeConfig(t, t.TempDir(),
+				"    server: https://k8s.example.com:6443\n    insecure-skip-tls-verify: true",
+				fmt.Sprintf(`    exec:
+      apiVersion: %s
+      command: %s
+      args: ["-test.run=^TestExecPluginHelper$"]
+      env:
+      - name: %s
+        value: %s
+      provideClusterInfo: %t`, c.apiVersion, os.Args[0], execPluginEnvVar, c.mode, c.clusterInfo))
+
+			cfg, err := LoadConfig(context.Background(), location, "")
+			if c.err != "" {
+				if err == nil || !strings.Contains(err.Error(), c.err) {
+					t.Fatalf("expected error containing %q but got %v", c.err, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("failed to load kubeconfig: %s", err)
+			}
+			if cfg.BearerToken != c.token {
+				t.Fatalf("expected token %q but got %q", c.token, cfg.BearerToken)
+			}
+		})
+	}
+}
+
+func TestLoadConfigExecPluginRelativeCommand(t *testing.T) {
+	dir := t.TempDir()
+	err := os.Symlink(os.Args[0], filepath.Join(dir, "plugin"))
+	if err != nil {
+		t.Skipf("cannot link the test binary: %s", err)
+	}
+	location := writeKubeConfig(t, dir, "    server: https://k8s.example.com:6443",
+		fmt.Sprintf(`    exec:
+      apiVersion: %s
+      command: ./plugin
+      args: ["-test.run=^TestExecPluginHelper$"]
+      env:
+      - name: %s
+        value: token`, execAPIVersionV1, execPluginEnvVar))
+
+	cfg, err := LoadConfig(context.Background(), location, "")
+	if err != nil {
+		t.Fatalf("failed to run the exec plugin relative to the kubeconfig: %s", err)
+	}
+	if cfg.BearerToken != "exec-token@none" {
+		t.Fatalf("unexpected token %q", cfg.BearerToken)
+	}
+}
+
+func TestLoadConfigAuthProvider(t *testing.T) {
+	cases := []struct {
+		name  string
+		user  string
+		token string
+		err   string
+	}{
+		{name: "OIDC", user: "    auth-provider:\n      name: oidc\n      config:\n        id-token: oidc-token", token: "oidc-token"},
+		{name: "OIDCNoToken", user: "    auth-provider:\n      name: oidc\n      config:\n        refresh-token: r", err: "no id-token"},
+		{name: "GCP", user: "    auth-provider:\n      name: gcp", err: "gke-gcloud-auth-plugin"},
+		{name: "StaticToken", user: "    token: static-token\n    auth-provider:\n      name: gcp", token: "static-token"},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			location := writeKubeConfig(t, t.TempDir(), "    server: https://k8s.example.com:6443", c.user)
+			cfg, err := LoadConfig(context.Background(), location, "")
+			if c.err != "" {
+				if err == nil || !strings.Contains(err.Error(), c.err) {
+					t.Fatalf("expected error containing %q but got %v", c.err, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("failed to load kubeconfig: %s", err)
+			}
+			if cfg.BearerToken != c.token {
+				t.Fatalf("expected token %q but got %q", c.token, cfg.BearerToken)
+			}
+		})
+	}
+}
+
+func TestLoadConfigErrors(t *testing.T) {
+	dir := t.TempDir()
+	location := writeKubeConfig(t, dir, "    server: https://k8s.example.com:6443\n    certificate-authority-data: "+
+		base64.StdEncoding.EncodeToString([]byte("not a certificate")), "    token: t")
+	_, err := LoadConfig(context.Background(), location, "")
+	if err == nil {
+		t.Fatalf("expected invalid certificate authority to fail")
+	}
+
+	_, err = LoadConfig(context.Background(), location, "missing")
+	if err == nil || !strings.Contains(err.Error(), `context "missing" not found`) {
+		t.Fatalf("expected missing context to fail but got %v", err)
+	}
+
+	location = filepath.Join(dir, "nouser")
+	writeFile(t, location, `current-context: test
+clusters:
+- name: test
+  cluster:
+    server: https://k8s.example.com:6443
+contexts:
+- name: test
+  context:
+    cluster: test
+    user: missing
+`)
+	_, err = LoadConfig(context.Background(), location, "")
+	if err == nil || !strings.Contains(err.Error(), `user "missing" not found`) {
+		t.Fatalf("expected missing user to fail but got %v", err)
+	}
+}
+
+func TestLoadConfigProxyURL(t *testing.T) {
+	dir := t.TempDir()
+	location := writeKubeConfig(t, dir, "    server: https://k8s.example.com:6443\n    proxy-url: http://proxy.example.com:3128", "    token: t")
+	cfg, err := LoadConfig(context.Background(), location, "")
+	if err != nil {
+		t.Fatalf("failed to load kubeconfig: %s", err)
+	}
+	if cfg.ProxyURL == nil || cfg.ProxyURL.String() != "http://proxy.example.com:3128" {
+		t.Fatalf("expected the proxy-url of the cluster but got %v", cfg.ProxyURL)
+	}
+
+	location = writeKubeConfig(t, dir, "    server: https://k8s.example.com:6443\n    proxy-url: \"http://proxy example.com:%zz\"", "    token: t")
+	_, err = LoadConfig(context.Background(), location, "")
+	if err == nil || !strings.Contains(err.Error(), "invalid cluster proxy-url") {
+		t.Fatalf("expected invalid proxy-url to fail but got %v", err)
+	}
+}
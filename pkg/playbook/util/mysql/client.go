@@ -0,0 +1,407 @@
+/*
+ * Copyright 2023 Venafi, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *  http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+// Package mysql is a minimal client of the protocol of MySQL and MariaDB, enough to authenticate and run statements
+// like ALTER INSTANCE RELOAD TLS
+package mysql
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha1"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/binary"
+	"encoding/pem"
+	"fmt"
+	"io"
+	"net"
+	"strings"
+	"time"
+)
+
+const (
+	defaultTimeout = 30 * time.Second
+
+	clientLongPassword     = 0x00000001
+	clientProtocol41       = 0x00000200
+	clientTransactions     = 0x00002000
+	clientSecureConnection = 0x00008000
+	clientPluginAuth       = 0x00080000
+
+	maxPacketSize = 1 << 24
+	charsetUTF8   = 33
+
+	comQuit  = 0x01
+	comQuery = 0x03
+
+	packetOK         = 0x00
+	packetAuthMore   = 0x01
+	packetAuthSwitch = 0xfe
+	packetErr        = 0xff
+
+	pluginNativePassword = "mysql_native_password"
+	pluginCachingSHA2    = "caching_sha2_password"
+
+	cachingSHA2FastAuthOK      = 3
+	cachingSHA2FullAuth        = 4
+	cachingSHA2RequestKey byte = 2
+)
+
+// Error is an error packet sent by the server
+type Error struct {
+	Code     uint16
+	SQLState string
+	Message  string
+}
+
+func (e *Error) Error() string {
+	return fmt.Sprintf("MySQL error %d (%s): %s", e.Code, e.SQLState, e.Message)
+}
+
+// Client connects to a MySQL or MariaDB server
+type Client struct {
+	address  string
+	username string
+	password string
+}
+
+// NewClient returns a Client for the server at address, either host:port or the path of a Unix socket like
+// /var/run/mysqld/mysqld.sock. The user authenticates with the mysql_native_password or caching_sha2_password methods
+func NewClient(address string, username string, password string) *Client {
+	return &Client{
+		address:  address,
+		username: username,
+		password: password,
+	}
+}
+
+// ReloadTLS makes the server use the certificate and private key in its files for the new connections, with
+// ALTER INSTANCE RELOAD TLS on MySQL 8.0.16 and later, or FLUSH SSL on MariaDB 10.4 and later.
+// The user needs the CONNECTION_ADMIN privilege on MySQL, or RELOAD on MariaDB
+func (c *Client) ReloadTLS(ctx context.Context) error {
+	conn, err := c.connect(ctx)
+	if err != nil {
+		return err
+	}
+	defer conn.close()
+
+	statement := "ALTER INSTANCE RELOAD TLS"
+	if strings.Contains(conn.serverVersion, "MariaDB") {
+		statement = "FLUSH SSL"
+	}
+	return conn.exec(statement)
+}
+
+type connection struct {
+	conn          net.Conn
+	reader        *bufio.Reader
+	sequence      byte
+	secure        bool
+	serverVersion string
+}
+
+func (c *Client) connect(ctx context.Context) (*connection, error) {
+	network := "tcp"
+	if strings.HasPrefix(c.address, "/") {
+		network = "unix"
+	}
+
+	dialer := net.Dialer{Timeout: defaultTimeout}
+	conn, err := dialer.DialContext(ctx, network, c.address)
+	if err != nil {
+		return nil, fmt.Errorf("could not connect to MySQL at %s: %w", c.address, err)
+	}
+	deadline, ok := ctx.Deadline()
+	if !ok {
+		deadline = time.Now().Add(defaultTimeout)
+	}
+	_ = conn.SetDeadline(deadline)
+
+	// the password can be sent in clear over a Unix socket, which never leaves the machine
+	cn := &connection{conn: conn, reader: bufio.NewReader(conn), secure: network == "unix"}
+	err = c.authenticate(cn)
+	if err != nil {
+		_ = conn.Close()
+		return nil, err
+	}
+	return cn, nil
+}
+
+func (c *Client) authenticate(cn *connection) error {
+	handshake, err := cn.readPacket()
+	if err != nil {
+		return err
+	}
+	if len(handshake) > 0 && handshake[0] == packetErr {
+		return parseError(handshake)
+	}
+	plugin, scramble, err := cn.parseHandshake(handshake)
+	if err != nil {
+		return err
+	}
+	if plugin != pluginNativePassword && plugin != pluginCachingSHA2 {
+		// Ask for the native method, that the server switches to another one if needed
+		plugin = pluginNativePassword
+	}
+
+	authResponse, err := c.authResponse(plugin, scramble)
+	if err != nil {
+		return err
+	}
+	response := make([]byte, 4, 64)
+	binary.LittleEndian.PutUint32(response, clientLongPassword|clientProtocol41|clientTransactions|clientSecureConnection|clientPluginAuth)
+	response = binary.LittleEndian.AppendUint32(response, maxPacketSize)
+	response = append(response, charsetUTF8)
+	response = append(response, make([]byte, 23)...)
+	response = append(response, cstring(c.username)...)
+	response = append(response, byte(len(authResponse)))
+	response = append(response, authResponse...)
+	response = append(response, cstring(plugin)...)
+	err = cn.writePacket(response)
+	if err != nil {
+		return err
+	}
+
+	for {
+		packet, err := cn.readPacket()
+		if err != nil {
+			return err
+		}
+		if len(packet) == 0 {
+			return fmt.Errorf("empty packet from MySQL")
+		}
+		switch packet[0] {
+		case packetOK:
+			return nil
+		case packetErr:
+			return parseError(packet)
+		case packetAuthSwitch:
+			name, data, _ := bytes.Cut(packet[1:], []byte{0})
+			plugin = string(name)
+			scramble = bytes.TrimSuffix(data, []byte{0})
+			authResponse, err = c.authResponse(plugin, scramble)
+			if err != nil {
+				return err
+			}
+			err = cn.writePacket(authResponse)
+		case packetAuthMore:
+			if plugin != pluginCachingSHA2 || len(packet) < 2 {
+				return fmt.Errorf("unexpected authentication data from MySQL")
+			}
+			err = c.cachingSHA2More(cn, packet[1:], scramble)
+		default:
+			return fmt.Errorf("unexpected packet 0x%02x from MySQL during authentication", packet[0])
+		}
+		if err != nil {
+			return err
+		}
+	}
+}
+
+// cachingSHA2More answers the extra steps of caching_sha2_password. When the password is not cached by the server,
+// it is sent in clear over a Unix socket, and encrypted with the RSA key of the server otherwise
+func (c *Client) cachingSHA2More(cn *connection, data []byte, scramble []byte) error {
+	switch {
+	case data[0] == cachingSHA2FastAuthOK && len(data) == 1:
+		// an OK packet follows
+		return nil
+	case data[0] == cachingSHA2FullAuth && len(data) == 1:
+		if cn.secure {
+			return cn.writePacket(cstring(c.password))
+		}
+		return cn.writePacket([]byte{cachingSHA2RequestKey})
+	default:
+		block, _ := pem.Decode(data)
+		if block == nil {
+			return fmt.Errorf("unexpected authentication data from MySQL")
+		}
+		key, err := x509.ParsePKIXPublicKey(block.Bytes)
+		if err != nil {
+			return fmt.Errorf("invalid RSA key from MySQL: %w", err)
+		}
+		rsaKey, ok := key.(*rsa.PublicKey)
+		if !ok {
+			return fmt.Errorf("invalid RSA key from MySQL")
+		}
+		plain := cstring(c.password)
+		for i := range plain {
+			plain[i] ^= scramble[i%len(scramble)]
+		}
+		encrypted, err := rsa.EncryptOAEP(sha1.New(), rand.Reader, rsaKey, plain, nil)
+		if err != nil {
+			return err
+		}
+		return cn.writePacket(encrypted)
+	}
+}
+
+func (c *Client) authResponse(plugin string, scramble []byte) ([]byte, error) {
+	switch plugin {
+	case pluginNativePassword:
+		return nativePassword(c.password, scramble), nil
+	case pluginCachingSHA2:
+		return cachingSHA2Password(c.password, scramble), nil
+	default:
+		return nil, fmt.Errorf("unsupported MySQL authentication method %s", plugin)
+	}
+}
+
+// parseHandshake reads the version of the server, its authentication method and the scramble of the initial
+// handshake packet (protocol version 10)
+func (cn *connection) parseHandshake(packet []byte) (string, []byte, error) {
+	invalid := fmt.Errorf("invalid handshake from MySQL")
+	if len(packet) < 1 || packet[0] != 10 {
+		return "", nil, invalid
+	}
+	version, rest, found := bytes.Cut(packet[1:], []byte{0})
+	if !found || len(rest) < 4+8+1+2+1+2+2+1+10 {
+		return "", nil, invalid
+	}
+	cn.serverVersion = string(version)
+	rest = rest[4:] // connection id
+	scramble := append([]byte(nil), rest[:8]...)
+	rest = rest[9:]
+	capabilities := uint32(binary.LittleEndian.Uint16(rest))
+	rest = rest[2+1+2:] // capabilities, character set and status
+	capabilities |= uint32(binary.LittleEndian.Uint16(rest)) << 16
+	dataLength := int(rest[2])
+	rest = rest[3+10:]
+	if capabilities&clientSecureConnection != 0 {
+		length := dataLength - 8
+		if length < 13 {
+			length = 13
+		}
+		if len(rest) < length {
+			return "", nil, invalid
+		}
+		scramble = append(scramble, bytes.TrimSuffix(rest[:length], []byte{0})...)
+		rest = rest[length:]
+	}
+	plugin := ""
+	if capabilities&clientPluginAuth != 0 {
+		name, _, _ := bytes.Cut(rest, []byte{0})
+		plugin = string(name)
+	}
+	return plugin, scramble, nil
+}
+
+func (cn *connection) exec(statement string) error {
+	cn.sequence = 0
+	err := cn.writePacket(append([]byte{comQuery}, statement...))
+	if err != nil {
+		return err
+	}
+	packet, err := cn.readPacket()
+	if err != nil {
+		return err
+	}
+	if len(packet) > 0 && packet[0] == packetErr {
+		return parseError(packet)
+	}
+	if len(packet) == 0 || packet[0] != packetOK {
+		return fmt.Errorf("unexpected response from MySQL to %s", statement)
+	}
+	return nil
+}
+
+func (cn *connection) readPacket() ([]byte, error) {
+	header := make([]byte, 4)
+	_, err := io.ReadFull(cn.reader, header)
+	if err != nil {
+		return nil, fmt.Errorf("could not read from MySQL: %w", err)
+	}
+	length := int(header[0]) | int(header[1])<<8 | int(header[2])<<16
+	cn.sequence = header[3] + 1
+	packet := make([]byte, length)
+	_, err = io.ReadFull(cn.reader, packet)
+	if err != nil {
+		return nil, fmt.Errorf("could not read from MySQL: %w", err)
+	}
+	return packet, nil
+}
+
+func (cn *connection) writePacket(payload []byte) error {
+	if len(payload) >= maxPacketSize-1 {
+		return fmt.Errorf("packet too large for MySQL")
+	}
+	header := []byte{byte(len(payload)), byte(len(payload) >> 8), byte(len(payload) >> 16), cn.sequence}
+	cn.sequence++
+	_, err := cn.conn.Write(append(header, payload...))
+	return err
+}
+
+func (cn *connection) close() {
+	cn.sequence = 0
+	_ = cn.writePacket([]byte{comQuit})
+	_ = cn.conn.Close()
+}
+
+func cstring(s string) []byte {
+	return append([]byte(s), 0)
+}
+
+func parseError(packet []byte) error {
+	e := &Error{}
+	if len(packet) < 3 {
+		return fmt.Errorf("invalid error packet from MySQL")
+	}
+	e.Code = binary.LittleEndian.Uint16(packet[1:])
+	message := packet[3:]
+	if len(message) >= 6 && message[0] == '#' {
+		e.SQLState = string(message[1:6])
+		message = message[6:]
+	}
+	e.Message = string(message)
+	return e
+}
+
+// nativePassword returns SHA1(password) XOR SHA1(scramble + SHA1(SHA1(password)))
+func nativePassword(password string, scramble []byte) []byte {
+	if password == "" {
+		return nil
+	}
+	stage1 := sha1.Sum([]byte(password))
+	stage2 := sha1.Sum(stage1[:])
+	h := sha1.New()
+	h.Write(scramble)
+	h.Write(stage2[:])
+	return xor(stage1[:], h.Sum(nil))
+}
+
+// cachingSHA2Password returns SHA256(password) XOR SHA256(SHA256(SHA256(password)) + scramble)
+func cachingSHA2Password(password string, scramble []byte) []byte {
+	if password == "" {
+		return nil
+	}
+	stage1 := sha256.Sum256([]byte(password))
+	stage2 := sha256.Sum256(stage1[:])
+	h := sha256.New()
+	h.Write(stage2[:])
+	h.Write(scramble)
+	return xor(stage1[:], h.Sum(nil))
+}
+
+func xor(a []byte, b []byte) []byte {
+	result := make([]byte, len(a))
+	for i := range a {
+		result[i] = a[i] ^ b[i]
+	}
+	return result
+}
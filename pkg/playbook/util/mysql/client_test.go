@@ -0,0 +1,62 @@
+/*
+ * Copyright 2023 Venafi, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *  http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package mysql
+
+import (
+	"bytes"
+	"crypto/sha1"
+	"crypto/sha256"
+	"testing"
+)
+
+var scramble = []byte("0123456789abcdefghij")
+
+// TestNativePassword checks the token the way the server does with the double SHA1 hash it stores
+func TestNativePassword(t *testing.T) {
+	stage1 := sha1.Sum([]byte("secret"))
+	stored := sha1.Sum(stage1[:])
+
+	token := nativePassword("secret", scramble)
+	h := sha1.New()
+	h.Write(scramble)
+	h.Write(stored[:])
+	if !bytes.Equal(xor(token, h.Sum(nil)), stage1[:]) {
+		t.Fatalf("mysql_native_password token rejected")
+	}
+
+	if len(nativePassword("", scramble)) != 0 {
+		t.Fatalf("expected an empty token for an empty password")
+	}
+}
+
+// TestCachingSHA2Password checks the token the way the server does with the double SHA256 hash it caches
+func TestCachingSHA2Password(t *testing.T) {
+	stage1 := sha256.Sum256([]byte("secret"))
+	stored := sha256.Sum256(stage1[:])
+
+	token := cachingSHA2Password("secret", scramble)
+	h := sha256.New()
+	h.Write(stored[:])
+	h.Write(scramble)
+	if !bytes.Equal(xor(token, h.Sum(nil)), stage1[:]) {
+		t.Fatalf("caching_sha2_password token rejected")
+	}
+
+	if len(cachingSHA2Password("", scramble)) != 0 {
+		t.Fatalf("expected an empty token for an empty password")
+	}
+}
@@ -0,0 +1,185 @@
+/*
+ * Copyright 2023 Venafi, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *  http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package nomad
+
+import (
+	"bytes"
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/Venafi/vcert/v5/pkg/playbook/util"
+)
+
+const defaultTimeout = 30 * time.Second
+
+// Variable is a Nomad variable. ModifyIndex changes on every write, and is used for check-and-set operations
+type Variable struct {
+	Namespace   string            `json:"Namespace"`
+	Path        string            `json:"Path"`
+	Items       map[string]string `json:"Items"`
+	ModifyIndex uint64            `json:"ModifyIndex,omitempty"`
+}
+
+// Client is a minimal client for the Variables API of HashiCorp Nomad
+type Client struct {
+	address    string
+	token      string
+	namespace  string
+	httpClient *http.Client
+}
+
+// NewClient returns a Client for the Nomad agent at address, using the ACL token and the namespace of the variables.
+// token may be empty when the ACL system is disabled.
+//
+// caCert is the optional path to a PEM bundle used to verify the certificate of the agent
+func NewClient(address string, token string, namespace string, caCert string) (*Client, error) {
+	tlsConfig := &tls.Config{MinVersion: tls.VersionTLS12}
+	if caCert != "" {
+		data, err := os.ReadFile(caCert)
+		if err != nil {
+			return nil, fmt.Errorf("could not read Nomad CA certificate: %w", err)
+		}
+		pool := x509.NewCertPool()
+		if !pool.AppendCertsFromPEM(data) {
+			return nil, fmt.Errorf("no certificates found in Nomad CA certificate %s", caCert)
+		}
+		tlsConfig.RootCAs = pool
+	}
+
+	return &Client{
+		address:   strings.TrimSuffix(address, "/"),
+		token:     token,
+		namespace: namespace,
+		httpClient: &http.Client{
+			Timeout: defaultTimeout,
+			Transport: &http.Transport{
+				Proxy:           http.ProxyFromEnvironment,
+				TLSClientConfig: tlsConfig,
+			},
+		},
+	}, nil
+}
+
+// GetVariable returns the variable at path. Returns nil if it does not exist
+func (c *Client) GetVariable(ctx context.Context, path string) (*Variable, error) {
+	variable := &Variable{}
+	err := c.do(ctx, http.MethodGet, c.variableURL(path, nil), nil, variable)
+	if err != nil {
+		if util.IsNotFound(err) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return variable, nil
+}
+
+// PutVariable writes the items of the variable at path. The write only happens when the ModifyIndex of the variable
+// is still modifyIndex, or the variable does not exist when modifyIndex is 0, so concurrent changes are not overwritten
+func (c *Client) PutVariable(ctx context.Context, path string, items map[string]string, modifyIndex uint64) (*Variable, error) {
+	query := url.Values{"cas": {fmt.Sprintf("%d", modifyIndex)}}
+	data := Variable{Namespace: c.namespace, Path: path, Items: items}
+	variable := &Variable{}
+	err := c.do(ctx, http.MethodPut, c.variableURL(path, query), data, variable)
+	if err != nil {
+		var httpErr *util.HTTPError
+		if errors.As(err, &httpErr) && httpErr.StatusCode == http.StatusConflict {
+			return nil, fmt.Errorf("variable %s was modified during the installation: %w", path, err)
+		}
+		return nil, err
+	}
+	return variable, nil
+}
+
+// DeleteVariable removes the variable at path. No error is returned if it does not exist
+func (c *Client) DeleteVariable(ctx context.Context, path string) error {
+	err := c.do(ctx, http.MethodDelete, c.variableURL(path, nil), nil, nil)
+	if err != nil && !util.IsNotFound(err) {
+		return err
+	}
+	return nil
+}
+
+func (c *Client) variableURL(path string, query url.Values) string {
+	if query == nil {
+		query = url.Values{}
+	}
+	if c.namespace != "" {
+		query.Set("namespace", c.namespace)
+	}
+	escaped := make([]string, 0)
+	for _, segment := range strings.Split(strings.Trim(path, "/"), "/") {
+		escaped = append(escaped, url.PathEscape(segment))
+	}
+	return fmt.Sprintf("/v1/var/%s?%s", strings.Join(escaped, "/"), query.Encode())
+}
+
+func (c *Client) do(ctx context.Context, method string, path string, data interface{}, result interface{}) error {
+	var body io.Reader
+	if data != nil {
+		payload, err := json.Marshal(data)
+		if err != nil {
+			return err
+		}
+		body = bytes.NewReader(payload)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, method, c.address+path, body)
+	if err != nil {
+		return err
+	}
+	if data != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	if c.token != "" {
+		req.Header.Set("X-Nomad-Token", c.token)
+	}
+
+	res, err := c.httpClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer res.Body.Close()
+
+	resBody, err := io.ReadAll(res.Body)
+	if err != nil {
+		return err
+	}
+
+	if res.StatusCode < 200 || res.StatusCode > 299 {
+		// Nomad answers errors in plain text
+		return &util.HTTPError{Service: "Nomad", Method: method, Path: strings.SplitN(path, "?", 2)[0],
+			StatusCode: res.StatusCode, Message: strings.TrimSpace(string(resBody))}
+	}
+
+	if result != nil && len(resBody) > 0 {
+		err = json.Unmarshal(resBody, result)
+		if err != nil {
+			return fmt.Errorf("could not parse Nomad response for %s: %w", path, err)
+		}
+	}
+	return nil
+}
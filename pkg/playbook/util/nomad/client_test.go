@@ -0,0 +1,145 @@
+/*
+ * Copyright 2023 Venafi, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *  http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package nomad
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+// variablesServer mocks the Variables API of a Nomad agent with ACLs enabled
+type variablesServer struct {
+	t         *testing.T
+	variables map[string]*Variable
+	index     uint64
+}
+
+func (s *variablesServer) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Header.Get("X-Nomad-Token") != "acl-token" {
+		w.WriteHeader(http.StatusForbidden)
+		_, _ = w.Write([]byte("Permission denied\n"))
+		return
+	}
+	if r.URL.Query().Get("namespace") != "prod" {
+		s.t.Errorf("unexpected namespace in %s", r.URL.RawQuery)
+	}
+	path, ok := strings.CutPrefix(r.URL.Path, "/v1/var/")
+	if !ok {
+		w.WriteHeader(http.StatusNotFound)
+		return
+	}
+
+	variable := s.variables[path]
+	switch r.Method {
+	case http.MethodGet:
+		if variable == nil {
+			w.WriteHeader(http.StatusNotFound)
+			_, _ = w.Write([]byte("variable not found\n"))
+			return
+		}
+		_ = json.NewEncoder(w).Encode(variable)
+	case http.MethodPut:
+		cas, _ := strconv.ParseUint(r.URL.Query().Get("cas"), 10, 64)
+		if (variable == nil && cas != 0) || (variable != nil && variable.ModifyIndex != cas) {
+			w.WriteHeader(http.StatusConflict)
+			_ = json.NewEncoder(w).Encode(variable)
+			return
+		}
+		update := &Variable{}
+		_ = json.NewDecoder(r.Body).Decode(update)
+		if update.Path != path {
+			s.t.Errorf("expected variable path %s, got %s", path, update.Path)
+		}
+		s.index++
+		update.ModifyIndex = s.index
+		s.variables[path] = update
+		_ = json.NewEncoder(w).Encode(update)
+	case http.MethodDelete:
+		if variable == nil {
+			w.WriteHeader(http.StatusNotFound)
+			_, _ = w.Write([]byte("variable not found\n"))
+			return
+		}
+		delete(s.variables, path)
+		w.WriteHeader(http.StatusNoContent)
+	}
+}
+
+func TestClient(t *testing.T) {
+	ctx := context.Background()
+	server := httptest.NewServer(&variablesServer{t: t, variables: map[string]*Variable{}, index: 100})
+	defer server.Close()
+
+	client, err := NewClient(server.URL+"/", "acl-token", "prod", "")
+	if err != nil {
+		t.Fatalf("failed to create client: %s", err)
+	}
+
+	// Not found
+	variable, err := client.GetVariable(ctx, "nomad/jobs/web/tls")
+	if err != nil || variable != nil {
+		t.Fatalf("expected missing variable to return nil, got %v, %v", variable, err)
+	}
+
+	// Create
+	created, err := client.PutVariable(ctx, "nomad/jobs/web/tls", map[string]string{"cert": "cert-1"}, 0)
+	if err != nil {
+		t.Fatalf("failed to create variable: %s", err)
+	}
+	if created.ModifyIndex != 101 {
+		t.Fatalf("unexpected modify index %d", created.ModifyIndex)
+	}
+	_, err = client.PutVariable(ctx, "nomad/jobs/web/tls", map[string]string{"cert": "cert-other"}, 0)
+	if err == nil || !strings.Contains(err.Error(), "was modified during the installation") {
+		t.Fatalf("expected check-and-set conflict, got %v", err)
+	}
+
+	// Update
+	_, err = client.PutVariable(ctx, "nomad/jobs/web/tls", map[string]string{"cert": "cert-2"}, created.ModifyIndex)
+	if err != nil {
+		t.Fatalf("failed to update variable: %s", err)
+	}
+	variable, err = client.GetVariable(ctx, "nomad/jobs/web/tls")
+	if err != nil {
+		t.Fatalf("failed to get variable: %s", err)
+	}
+	if variable.Items["cert"] != "cert-2" || variable.ModifyIndex != 102 || variable.Namespace != "prod" {
+		t.Fatalf("unexpected variable %v", variable)
+	}
+
+	// Delete is idempotent
+	err = client.DeleteVariable(ctx, "nomad/jobs/web/tls")
+	if err != nil {
+		t.Fatalf("failed to delete variable: %s", err)
+	}
+	err = client.DeleteVariable(ctx, "nomad/jobs/web/tls")
+	if err != nil {
+		t.Fatalf("failed to delete missing variable: %s", err)
+	}
+
+	// Other errors are returned
+	client.token = "wrong"
+	_, err = client.GetVariable(ctx, "nomad/jobs/web/tls")
+	if err == nil || !strings.Contains(err.Error(), "403 Permission denied") {
+		t.Fatalf("expected permission denied error, got %v", err)
+	}
+}
@@ -0,0 +1,117 @@
+/*
+ * Copyright 2023 Venafi, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *  http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package plugin
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"os/exec"
+	"strings"
+)
+
+// ProtocolVersion is the version of the contract between vcert and the plugins, sent in every Request
+const ProtocolVersion = 1
+
+// Operations requested to the plugins. A plugin receives one operation per run
+const (
+	// OperationCheck asks the plugin for the certificate currently installed, if any
+	OperationCheck = "check"
+	// OperationBackup asks the plugin to back up the certificate currently installed, before it is overwritten
+	OperationBackup = "backup"
+	// OperationInstall asks the plugin to install the Bundle of the Request
+	OperationInstall = "install"
+	// OperationRollback asks the plugin to restore the certificate backed up, after another installation failed
+	OperationRollback = "rollback"
+)
+
+// Bundle is the certificate installed by a plugin. All its values are PEM encoded
+type Bundle struct {
+	Certificate string   `json:"certificate"`
+	PrivateKey  string   `json:"privateKey,omitempty"`
+	Chain       []string `json:"chain,omitempty"`
+}
+
+// Request is the JSON document written to the stdin of the plugin
+type Request struct {
+	Version   int               `json:"version"`
+	Operation string            `json:"operation"`
+	Config    map[string]string `json:"config,omitempty"`
+	// Bundle is only sent with OperationInstall
+	Bundle *Bundle `json:"bundle,omitempty"`
+}
+
+// Response is the JSON document the plugin writes to its stdout. An empty stdout is an empty Response
+type Response struct {
+	// Certificate is the PEM certificate currently installed, returned by OperationCheck. Empty when there is none
+	Certificate string `json:"certificate,omitempty"`
+	// Chain is the PEM chain of the certificate currently installed, returned by OperationCheck
+	Chain []string `json:"chain,omitempty"`
+	// Error fails the operation with the message of the plugin
+	Error string `json:"error,omitempty"`
+}
+
+// Client runs the binary of a plugin, once per operation
+type Client struct {
+	name string
+	path string
+}
+
+// NewClient returns a Client for the plugin name, run from the binary at path
+func NewClient(name string, path string) *Client {
+	return &Client{name: name, path: path}
+}
+
+// Run sends operation to the plugin along with its config and, for OperationInstall, the bundle to install.
+// An error is returned when the plugin exits with a non-zero status, writes an invalid Response or sets its Error
+func (c *Client) Run(ctx context.Context, operation string, config map[string]string, bundle *Bundle) (*Response, error) {
+	request, err := json.Marshal(Request{
+		Version:   ProtocolVersion,
+		Operation: operation,
+		Config:    config,
+		Bundle:    bundle,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	cmd := exec.CommandContext(ctx, c.path)
+	cmd.Stdin = bytes.NewReader(request)
+	var stdout, stderr bytes.Buffer
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+	err = cmd.Run()
+	if err != nil {
+		if msg := strings.TrimSpace(stderr.String()); msg != "" {
+			return nil, fmt.Errorf("plugin %s failed to %s: %w: %s", c.name, operation, err, msg)
+		}
+		return nil, fmt.Errorf("plugin %s failed to %s: %w", c.name, operation, err)
+	}
+
+	response := &Response{}
+	if len(bytes.TrimSpace(stdout.Bytes())) > 0 {
+		err = json.Unmarshal(stdout.Bytes(), response)
+		if err != nil {
+			return nil, fmt.Errorf("plugin %s returned an invalid response to %s: %w", c.name, operation, err)
+		}
+	}
+	if response.Error != "" {
+		return nil, fmt.Errorf("plugin %s failed to %s: %s", c.name, operation, response.Error)
+	}
+	return response, nil
+}
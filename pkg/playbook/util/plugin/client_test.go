@@ -0,0 +1,93 @@
+/*
+ * Copyright 2023 Venafi, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *  http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package plugin
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"strings"
+	"testing"
+)
+
+// helperEnv makes the test binary act as a plugin, answering according to its value
+const helperEnv = "VCERT_TEST_PLUGIN"
+
+func TestMain(m *testing.M) {
+	switch os.Getenv(helperEnv) {
+	case "echo":
+		// Returns the certificate of the request, as if it had been installed
+		var request Request
+		if err := json.NewDecoder(os.Stdin).Decode(&request); err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			os.Exit(2)
+		}
+		if request.Version != ProtocolVersion || request.Config["target"] != "appliance-01" {
+			fmt.Printf(`{"error":"unexpected request %s %v"}`, request.Operation, request.Config)
+			os.Exit(0)
+		}
+		if request.Bundle != nil {
+			_ = json.NewEncoder(os.Stdout).Encode(Response{Certificate: request.Bundle.Certificate})
+		}
+		os.Exit(0)
+	case "fail":
+		fmt.Fprintln(os.Stderr, "appliance unreachable")
+		os.Exit(1)
+	case "invalid":
+		fmt.Print("installed")
+		os.Exit(0)
+	default:
+		os.Exit(m.Run())
+	}
+}
+
+func TestClient_Run(t *testing.T) {
+	client := NewClient("test", os.Args[0])
+	config := map[string]string{"target": "appliance-01"}
+
+	t.Setenv(helperEnv, "echo")
+	response, err := client.Run(context.Background(), OperationInstall, config, &Bundle{Certificate: "CERT"})
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if response.Certificate != "CERT" {
+		t.Fatalf("unexpected certificate %q", response.Certificate)
+	}
+
+	response, err = client.Run(context.Background(), OperationBackup, config, nil)
+	if err != nil || response.Certificate != "" {
+		t.Fatalf("expected an empty response, got %v, %v", response, err)
+	}
+
+	_, err = client.Run(context.Background(), OperationCheck, nil, nil)
+	if err == nil || !strings.Contains(err.Error(), "unexpected request check") {
+		t.Fatalf("expected the error of the plugin, got %v", err)
+	}
+
+	t.Setenv(helperEnv, "fail")
+	_, err = client.Run(context.Background(), OperationCheck, config, nil)
+	if err == nil || !strings.Contains(err.Error(), "appliance unreachable") {
+		t.Fatalf("expected the stderr of the plugin, got %v", err)
+	}
+
+	t.Setenv(helperEnv, "invalid")
+	_, err = client.Run(context.Background(), OperationCheck, config, nil)
+	if err == nil || !strings.Contains(err.Error(), "invalid response") {
+		t.Fatalf("expected an invalid response error, got %v", err)
+	}
+}
@@ -0,0 +1,427 @@
+/*
+ * Copyright 2023 Venafi, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *  http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+// Package postgres is a minimal client of the frontend/backend protocol of PostgreSQL, enough to authenticate and run
+// simple queries like SELECT pg_reload_conf()
+package postgres
+
+import (
+	"bufio"
+	"context"
+	"crypto/hmac"
+	"crypto/md5"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"strconv"
+	"strings"
+	"time"
+
+	"golang.org/x/crypto/pbkdf2"
+)
+
+const (
+	defaultTimeout = 30 * time.Second
+
+	protocolVersion = 196608 // 3.0
+
+	authOK                = 0
+	authCleartextPassword = 3
+	authMD5Password       = 5
+	authSASL              = 10
+	authSASLContinue      = 11
+	authSASLFinal         = 12
+
+	scramSHA256 = "SCRAM-SHA-256"
+)
+
+// Error is an ErrorResponse sent by the server
+type Error struct {
+	Severity string
+	Code     string
+	Message  string
+}
+
+func (e *Error) Error() string {
+	return fmt.Sprintf("PostgreSQL %s %s: %s", e.Severity, e.Code, e.Message)
+}
+
+// Client connects to a PostgreSQL server
+type Client struct {
+	address  string
+	username string
+	password string
+	database string
+}
+
+// NewClient returns a Client for the server at address, either host:port or the path of a Unix socket like
+// /var/run/postgresql/.s.PGSQL.5432. The user authenticates with password, or with the trust and peer methods when
+// password is empty
+func NewClient(address string, username string, password string, database string) *Client {
+	return &Client{
+		address:  address,
+		username: username,
+		password: password,
+		database: database,
+	}
+}
+
+// ReloadConfiguration runs pg_reload_conf(), so the server reloads its configuration files and its certificate.
+// The user must be a superuser or have been granted EXECUTE on pg_reload_conf()
+func (c *Client) ReloadConfiguration(ctx context.Context) error {
+	value, err := c.QueryValue(ctx, "SELECT pg_reload_conf()")
+	if err != nil {
+		return err
+	}
+	if value != "t" {
+		return fmt.Errorf("pg_reload_conf() returned %q", value)
+	}
+	return nil
+}
+
+// QueryValue runs query and returns the first column of the first row it returns, in text format
+func (c *Client) QueryValue(ctx context.Context, query string) (string, error) {
+	conn, err := c.connect(ctx)
+	if err != nil {
+		return "", err
+	}
+	defer conn.close()
+
+	err = conn.send('Q', cstring(query))
+	if err != nil {
+		return "", err
+	}
+
+	var value string
+	var queryErr error
+	rows := 0
+	for {
+		msgType, payload, err := conn.receive()
+		if err != nil {
+			return "", err
+		}
+		switch msgType {
+		case 'D':
+			if rows == 0 {
+				value, err = firstColumn(payload)
+				if err != nil {
+					return "", err
+				}
+			}
+			rows++
+		case 'E':
+			queryErr = parseError(payload)
+		case 'Z':
+			if queryErr != nil {
+				return "", queryErr
+			}
+			if rows == 0 {
+				return "", fmt.Errorf("query returned no rows")
+			}
+			return value, nil
+		}
+	}
+}
+
+type connection struct {
+	conn   net.Conn
+	reader *bufio.Reader
+}
+
+// connect opens a connection and authenticates, returning once the server is ready for queries
+func (c *Client) connect(ctx context.Context) (*connection, error) {
+	network := "tcp"
+	if strings.HasPrefix(c.address, "/") {
+		network = "unix"
+	}
+
+	dialer := net.Dialer{Timeout: defaultTimeout}
+	conn, err := dialer.DialContext(ctx, network, c.address)
+	if err != nil {
+		return nil, fmt.Errorf("could not connect to PostgreSQL at %s: %w", c.address, err)
+	}
+	deadline, ok := ctx.Deadline()
+	if !ok {
+		deadline = time.Now().Add(defaultTimeout)
+	}
+	_ = conn.SetDeadline(deadline)
+
+	cn := &connection{conn: conn, reader: bufio.NewReader(conn)}
+	err = c.startup(cn)
+	if err != nil {
+		_ = conn.Close()
+		return nil, err
+	}
+	return cn, nil
+}
+
+func (c *Client) startup(cn *connection) error {
+	params := []byte{0, 0, 0, 0}
+	binary.BigEndian.PutUint32(params, protocolVersion)
+	for _, p := range [][2]string{{"user", c.username}, {"database", c.database}, {"application_name", "vcert"}} {
+		params = append(params, cstring(p[0])...)
+		params = append(params, cstring(p[1])...)
+	}
+	params = append(params, 0)
+	err := cn.sendStartup(params)
+	if err != nil {
+		return err
+	}
+
+	var scram *scramClient
+	for {
+		msgType, payload, err := cn.receive()
+		if err != nil {
+			return err
+		}
+		switch msgType {
+		case 'E':
+			return parseError(payload)
+		case 'Z':
+			return nil
+		case 'R':
+			if len(payload) < 4 {
+				return fmt.Errorf("invalid authentication request from PostgreSQL")
+			}
+			code := binary.BigEndian.Uint32(payload)
+			data := payload[4:]
+			switch code {
+			case authOK:
+			case authCleartextPassword:
+				err = cn.send('p', cstring(c.password))
+			case authMD5Password:
+				err = cn.send('p', cstring(md5Password(c.username, c.password, data)))
+			case authSASL:
+				if !containsMechanism(data, scramSHA256) {
+					return fmt.Errorf("PostgreSQL requested an unsupported SASL mechanism")
+				}
+				scram, err = newSCRAMClient(c.password)
+				if err != nil {
+					return err
+				}
+				first := scram.clientFirst()
+				msg := append(cstring(scramSHA256), 0, 0, 0, 0)
+				binary.BigEndian.PutUint32(msg[len(msg)-4:], uint32(len(first)))
+				err = cn.send('p', append(msg, first...))
+			case authSASLContinue:
+				if scram == nil {
+					return fmt.Errorf("unexpected SASL message from PostgreSQL")
+				}
+				var final string
+				final, err = scram.clientFinal(string(data))
+				if err == nil {
+					err = cn.send('p', []byte(final))
+				}
+			case authSASLFinal:
+				if scram == nil {
+					return fmt.Errorf("unexpected SASL message from PostgreSQL")
+				}
+				err = scram.verifyServerFinal(string(data))
+			default:
+				return fmt.Errorf("unsupported PostgreSQL authentication method %d", code)
+			}
+			if err != nil {
+				return err
+			}
+		}
+	}
+}
+
+func (cn *connection) sendStartup(payload []byte) error {
+	msg := make([]byte, 4, 4+len(payload))
+	binary.BigEndian.PutUint32(msg, uint32(4+len(payload)))
+	_, err := cn.conn.Write(append(msg, payload...))
+	return err
+}
+
+func (cn *connection) send(msgType byte, payload []byte) error {
+	msg := make([]byte, 5, 5+len(payload))
+	msg[0] = msgType
+	binary.BigEndian.PutUint32(msg[1:], uint32(4+len(payload)))
+	_, err := cn.conn.Write(append(msg, payload...))
+	return err
+}
+
+func (cn *connection) receive() (byte, []byte, error) {
+	header := make([]byte, 5)
+	_, err := io.ReadFull(cn.reader, header)
+	if err != nil {
+		return 0, nil, fmt.Errorf("could not read from PostgreSQL: %w", err)
+	}
+	length := binary.BigEndian.Uint32(header[1:])
+	if length < 4 || length > 1<<24 {
+		return 0, nil, fmt.Errorf("invalid message length %d from PostgreSQL", length)
+	}
+	payload := make([]byte, length-4)
+	_, err = io.ReadFull(cn.reader, payload)
+	if err != nil {
+		return 0, nil, fmt.Errorf("could not read from PostgreSQL: %w", err)
+	}
+	return header[0], payload, nil
+}
+
+func (cn *connection) close() {
+	_ = cn.send('X', nil)
+	_ = cn.conn.Close()
+}
+
+func cstring(s string) []byte {
+	return append([]byte(s), 0)
+}
+
+func parseError(payload []byte) error {
+	e := &Error{}
+	for len(payload) > 1 {
+		field := payload[0]
+		value, rest, found := strings.Cut(string(payload[1:]), "\x00")
+		if !found {
+			break
+		}
+		switch field {
+		case 'S':
+			e.Severity = value
+		case 'C':
+			e.Code = value
+		case 'M':
+			e.Message = value
+		}
+		payload = []byte(rest)
+	}
+	return e
+}
+
+// firstColumn returns the first column of a DataRow
+func firstColumn(payload []byte) (string, error) {
+	if len(payload) < 6 || binary.BigEndian.Uint16(payload) == 0 {
+		return "", fmt.Errorf("invalid data row from PostgreSQL")
+	}
+	length := int32(binary.BigEndian.Uint32(payload[2:]))
+	if length < 0 {
+		return "", nil
+	}
+	if int(length) > len(payload)-6 {
+		return "", fmt.Errorf("invalid data row from PostgreSQL")
+	}
+	return string(payload[6 : 6+length]), nil
+}
+
+func containsMechanism(data []byte, mechanism string) bool {
+	for _, m := range strings.Split(string(data), "\x00") {
+		if m == mechanism {
+			return true
+		}
+	}
+	return false
+}
+
+// md5Password returns the response to an MD5 password request: "md5" + md5(md5(password + username) + salt)
+func md5Password(username string, password string, salt []byte) string {
+	inner := md5.Sum([]byte(password + username))
+	outer := md5.Sum(append([]byte(hex.EncodeToString(inner[:])), salt...))
+	return "md5" + hex.EncodeToString(outer[:])
+}
+
+// scramClient authenticates with SCRAM-SHA-256, as described by RFC 5802 and RFC 7677, without channel binding
+type scramClient struct {
+	password        string
+	nonce           string
+	clientFirstBare string
+	serverSignature []byte
+}
+
+func newSCRAMClient(password string) (*scramClient, error) {
+	raw := make([]byte, 18)
+	_, err := rand.Read(raw)
+	if err != nil {
+		return nil, err
+	}
+	// PostgreSQL ignores the user name of the SCRAM messages, and uses the one of the startup message
+	return newSCRAMClientWithNonce(password, "", base64.StdEncoding.EncodeToString(raw)), nil
+}
+
+func newSCRAMClientWithNonce(password string, username string, nonce string) *scramClient {
+	return &scramClient{
+		password:        password,
+		nonce:           nonce,
+		clientFirstBare: fmt.Sprintf("n=%s,r=%s", username, nonce),
+	}
+}
+
+func (s *scramClient) clientFirst() string {
+	return "n,," + s.clientFirstBare
+}
+
+func (s *scramClient) clientFinal(serverFirst string) (string, error) {
+	var nonce, salt string
+	iterations := 0
+	for _, attr := range strings.Split(serverFirst, ",") {
+		key, value, _ := strings.Cut(attr, "=")
+		switch key {
+		case "r":
+			nonce = value
+		case "s":
+			salt = value
+		case "i":
+			iterations, _ = strconv.Atoi(value)
+		}
+	}
+	if !strings.HasPrefix(nonce, s.nonce) || len(nonce) == len(s.nonce) || iterations <= 0 {
+		return "", fmt.Errorf("invalid SCRAM message from PostgreSQL")
+	}
+	saltBytes, err := base64.StdEncoding.DecodeString(salt)
+	if err != nil {
+		return "", fmt.Errorf("invalid SCRAM salt from PostgreSQL: %w", err)
+	}
+
+	saltedPassword := pbkdf2.Key([]byte(s.password), saltBytes, iterations, sha256.Size, sha256.New)
+	clientKey := hmacSHA256(saltedPassword, "Client Key")
+	storedKey := sha256.Sum256(clientKey)
+	clientFinalWithoutProof := "c=biws,r=" + nonce
+	authMessage := s.clientFirstBare + "," + serverFirst + "," + clientFinalWithoutProof
+
+	clientSignature := hmacSHA256(storedKey[:], authMessage)
+	proof := make([]byte, len(clientKey))
+	for i := range clientKey {
+		proof[i] = clientKey[i] ^ clientSignature[i]
+	}
+	s.serverSignature = hmacSHA256(hmacSHA256(saltedPassword, "Server Key"), authMessage)
+
+	return clientFinalWithoutProof + ",p=" + base64.StdEncoding.EncodeToString(proof), nil
+}
+
+func (s *scramClient) verifyServerFinal(serverFinal string) error {
+	signature, found := strings.CutPrefix(serverFinal, "v=")
+	if !found {
+		return fmt.Errorf("SCRAM authentication failed: %s", serverFinal)
+	}
+	decoded, err := base64.StdEncoding.DecodeString(signature)
+	if err != nil || !hmac.Equal(decoded, s.serverSignature) {
+		return errors.New("invalid SCRAM server signature. The server does not know the password")
+	}
+	return nil
+}
+
+func hmacSHA256(key []byte, message string) []byte {
+	mac := hmac.New(sha256.New, key)
+	mac.Write([]byte(message))
+	return mac.Sum(nil)
+}
@@ -0,0 +1,56 @@
+/*
+ * Copyright 2023 Venafi, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *  http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package postgres
+
+import (
+	"testing"
+)
+
+// TestSCRAMClient runs the SCRAM-SHA-256 exchange of RFC 7677, section 3
+func TestSCRAMClient(t *testing.T) {
+	s := newSCRAMClientWithNonce("pencil", "user", "rOprNGfwEbeRWgbNEkqO")
+	if s.clientFirst() != "n,,n=user,r=rOprNGfwEbeRWgbNEkqO" {
+		t.Fatalf("unexpected client-first-message: %s", s.clientFirst())
+	}
+
+	serverFirst := "r=rOprNGfwEbeRWgbNEkqO%hvYDpWUa2RaTCAfuxFIlj)hNlF$k0,s=W22ZaJ0SNY7soEsUEjb6gQ==,i=4096"
+	clientFinal, err := s.clientFinal(serverFirst)
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	expected := "c=biws,r=rOprNGfwEbeRWgbNEkqO%hvYDpWUa2RaTCAfuxFIlj)hNlF$k0,p=dHzbZapWIk4jUhN+Ute9ytag9zjfMHgsqmmiz7AndVQ="
+	if clientFinal != expected {
+		t.Fatalf("unexpected client-final-message:\nexpected: %s\ngot:      %s", expected, clientFinal)
+	}
+
+	err = s.verifyServerFinal("v=6rriTRBi23WpRR/wtup+mMhUZUn/dB5nLTJRsjl95G4=")
+	if err != nil {
+		t.Fatalf("valid server signature rejected: %s", err)
+	}
+	err = s.verifyServerFinal("v=AAAATRBi23WpRR/wtup+mMhUZUn/dB5nLTJRsjl95G4=")
+	if err == nil {
+		t.Fatalf("invalid server signature accepted")
+	}
+}
+
+func TestSCRAMClientNonceMismatch(t *testing.T) {
+	s := newSCRAMClientWithNonce("pencil", "user", "rOprNGfwEbeRWgbNEkqO")
+	_, err := s.clientFinal("r=differentNonce,s=W22ZaJ0SNY7soEsUEjb6gQ==,i=4096")
+	if err == nil {
+		t.Fatalf("server nonce not starting with the client nonce accepted")
+	}
+}
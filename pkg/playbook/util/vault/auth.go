@@ -0,0 +1,95 @@
+/*
+ * Copyright 2023 Venafi, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *  http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package vault
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"os"
+	"strings"
+
+	"go.uber.org/zap"
+)
+
+const (
+	// DefaultAppRoleMount is the path where the AppRole auth method is enabled by default
+	DefaultAppRoleMount = "approle"
+	// DefaultK8sMount is the path where the Kubernetes auth method is enabled by default
+	DefaultK8sMount = "kubernetes"
+	// DefaultK8sTokenPath is the location of the service account token in a Kubernetes pod
+	DefaultK8sTokenPath = "/var/run/secrets/kubernetes.io/serviceaccount/token"
+)
+
+// Credentials holds the values used to authenticate to Vault. Only one auth method is used, in this order:
+//  1. Token, when set
+//  2. AppRole, when RoleID is set. SecretID is required
+//  3. Kubernetes, when K8sRole is set. The service account token is read from DefaultK8sTokenPath
+type Credentials struct {
+	Token     string
+	RoleID    string
+	SecretID  string
+	K8sRole   string
+	AuthMount string
+}
+
+type loginResponse struct {
+	Auth struct {
+		ClientToken string `json:"client_token"`
+	} `json:"auth"`
+}
+
+// login returns a Vault token using the given credentials
+func (c *Client) login(ctx context.Context, credentials Credentials) (string, error) {
+	if credentials.Token != "" {
+		zap.L().Debug("using Vault token")
+		return credentials.Token, nil
+	}
+
+	var mount string
+	var data map[string]string
+	switch {
+	case credentials.RoleID != "":
+		zap.L().Debug("logging in to Vault using AppRole")
+		mount = DefaultAppRoleMount
+		data = map[string]string{"role_id": credentials.RoleID, "secret_id": credentials.SecretID}
+	case credentials.K8sRole != "":
+		zap.L().Debug("logging in to Vault using Kubernetes auth", zap.String("role", credentials.K8sRole))
+		jwt, err := os.ReadFile(DefaultK8sTokenPath)
+		if err != nil {
+			return "", fmt.Errorf("could not read Kubernetes service account token: %w", err)
+		}
+		mount = DefaultK8sMount
+		data = map[string]string{"role": credentials.K8sRole, "jwt": strings.TrimSpace(string(jwt))}
+	default:
+		return "", fmt.Errorf("no Vault credentials provided")
+	}
+
+	if credentials.AuthMount != "" {
+		mount = credentials.AuthMount
+	}
+
+	response := loginResponse{}
+	err := c.do(ctx, http.MethodPost, fmt.Sprintf("auth/%s/login", strings.Trim(mount, "/")), data, &response)
+	if err != nil {
+		return "", fmt.Errorf("could not log in to Vault: %w", err)
+	}
+	if response.Auth.ClientToken == "" {
+		return "", fmt.Errorf("could not log in to Vault: no token returned")
+	}
+	return response.Auth.ClientToken, nil
+}
@@ -0,0 +1,210 @@
+/*
+ * Copyright 2023 Venafi, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *  http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package vault
+
+import (
+	"bytes"
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/Venafi/vcert/v5/pkg/playbook/util"
+)
+
+const defaultTimeout = 30 * time.Second
+
+// Secret represents a version of a KV v2 secret
+type Secret struct {
+	Data     map[string]interface{} `json:"data"`
+	Metadata struct {
+		Version int `json:"version"`
+	} `json:"metadata"`
+}
+
+// Metadata represents the metadata of a KV v2 secret
+type Metadata struct {
+	CurrentVersion int               `json:"current_version"`
+	CustomMetadata map[string]string `json:"custom_metadata"`
+}
+
+// Client is a minimal client for the KV v2 secrets engine of HashiCorp Vault
+type Client struct {
+	address    string
+	namespace  string
+	mount      string
+	token      string
+	httpClient *http.Client
+}
+
+// NewClient returns a Client for the KV v2 engine at mount, authenticated with credentials.
+//
+// caCert is the optional path to a PEM bundle used to verify the Vault server certificate
+func NewClient(ctx context.Context, address string, namespace string, mount string, caCert string, credentials Credentials) (*Client, error) {
+	httpClient := &http.Client{Timeout: defaultTimeout}
+	if caCert != "" {
+		data, err := os.ReadFile(caCert)
+		if err != nil {
+			return nil, fmt.Errorf("could not read Vault CA certificate: %w", err)
+		}
+		pool := x509.NewCertPool()
+		if !pool.AppendCertsFromPEM(data) {
+			return nil, fmt.Errorf("no certificates found in Vault CA certificate %s", caCert)
+		}
+		httpClient.Transport = &http.Transport{
+			Proxy:           http.ProxyFromEnvironment,
+			TLSClientConfig: &tls.Config{RootCAs: pool},
+		}
+	}
+
+	client := &Client{
+		address:    strings.TrimSuffix(address, "/"),
+		namespace:  namespace,
+		mount:      strings.Trim(mount, "/"),
+		httpClient: httpClient,
+	}
+
+	token, err := client.login(ctx, credentials)
+	if err != nil {
+		return nil, err
+	}
+	client.token = token
+
+	return client, nil
+}
+
+// ReadSecret returns the given version of the secret at path. Version 0 is the current version.
+// Returns nil if the secret, or the version, does not exist or has been deleted
+func (c *Client) ReadSecret(ctx context.Context, path string, version int) (*Secret, error) {
+	url := c.dataPath(path)
+	if version > 0 {
+		url = fmt.Sprintf("%s?version=%d", url, version)
+	}
+
+	response := struct {
+		Data *Secret `json:"data"`
+	}{}
+	err := c.do(ctx, http.MethodGet, url, nil, &response)
+	if err != nil {
+		if util.IsNotFound(err) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return response.Data, nil
+}
+
+// WriteSecret creates a new version of the secret at path with the given data
+func (c *Client) WriteSecret(ctx context.Context, path string, data map[string]interface{}) (int, error) {
+	response := struct {
+		Data struct {
+			Version int `json:"version"`
+		} `json:"data"`
+	}{}
+	err := c.do(ctx, http.MethodPost, c.dataPath(path), map[string]interface{}{"data": data}, &response)
+	if err != nil {
+		return 0, err
+	}
+	return response.Data.Version, nil
+}
+
+// ReadMetadata returns the metadata of the secret at path. Returns nil if the secret does not exist
+func (c *Client) ReadMetadata(ctx context.Context, path string) (*Metadata, error) {
+	response := struct {
+		Data *Metadata `json:"data"`
+	}{}
+	err := c.do(ctx, http.MethodGet, c.metadataPath(path), nil, &response)
+	if err != nil {
+		if util.IsNotFound(err) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return response.Data, nil
+}
+
+// WriteCustomMetadata replaces the custom metadata of the secret at path. Other metadata settings are not changed
+func (c *Client) WriteCustomMetadata(ctx context.Context, path string, customMetadata map[string]string) error {
+	return c.do(ctx, http.MethodPost, c.metadataPath(path), map[string]interface{}{"custom_metadata": customMetadata}, nil)
+}
+
+func (c *Client) dataPath(path string) string {
+	return fmt.Sprintf("%s/data/%s", c.mount, strings.Trim(path, "/"))
+}
+
+func (c *Client) metadataPath(path string) string {
+	return fmt.Sprintf("%s/metadata/%s", c.mount, strings.Trim(path, "/"))
+}
+
+func (c *Client) do(ctx context.Context, method string, path string, data interface{}, result interface{}) error {
+	var body io.Reader
+	if data != nil {
+		payload, err := json.Marshal(data)
+		if err != nil {
+			return err
+		}
+		body = bytes.NewReader(payload)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, method, fmt.Sprintf("%s/v1/%s", c.address, path), body)
+	if err != nil {
+		return err
+	}
+	if data != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	if c.token != "" {
+		req.Header.Set("X-Vault-Token", c.token)
+	}
+	if c.namespace != "" {
+		req.Header.Set("X-Vault-Namespace", c.namespace)
+	}
+
+	res, err := c.httpClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer res.Body.Close()
+
+	resBody, err := io.ReadAll(res.Body)
+	if err != nil {
+		return err
+	}
+
+	if res.StatusCode < 200 || res.StatusCode > 299 {
+		apiErr := struct {
+			Errors []string `json:"errors"`
+		}{}
+		_ = json.Unmarshal(resBody, &apiErr)
+		return &util.HTTPError{Service: "Vault", Method: method, Path: path, StatusCode: res.StatusCode,
+			Message: strings.Join(apiErr.Errors, ", ")}
+	}
+
+	if result != nil && len(resBody) > 0 {
+		err = json.Unmarshal(resBody, result)
+		if err != nil {
+			return fmt.Errorf("could not parse Vault response for %s: %w", path, err)
+		}
+	}
+	return nil
+}
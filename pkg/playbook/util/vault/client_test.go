@@ -0,0 +1,174 @@
+/*
+ * Copyright 2023 Venafi, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *  http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package vault
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+// kvServer mocks the KV v2 engine mounted at secret, and the AppRole login
+type kvServer struct {
+	t        *testing.T
+	versions map[string][]map[string]interface{}
+	custom   map[string]map[string]string
+}
+
+func newKVServer(t *testing.T) *httptest.Server {
+	kv := &kvServer{t: t, versions: map[string][]map[string]interface{}{}, custom: map[string]map[string]string{}}
+	return httptest.NewServer(kv)
+}
+
+func (kv *kvServer) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.URL.Path == "/v1/auth/approle/login" {
+		body := map[string]string{}
+		_ = json.NewDecoder(r.Body).Decode(&body)
+		if body["role_id"] != "role-1" || body["secret_id"] != "secret-1" {
+			w.WriteHeader(http.StatusBadRequest)
+			_, _ = w.Write([]byte(`{"errors":["invalid role or secret ID"]}`))
+			return
+		}
+		_, _ = w.Write([]byte(`{"auth":{"client_token":"approle-token"}}`))
+		return
+	}
+	if r.Header.Get("X-Vault-Token") != "approle-token" {
+		w.WriteHeader(http.StatusForbidden)
+		_, _ = w.Write([]byte(`{"errors":["permission denied"]}`))
+		return
+	}
+	if r.Header.Get("X-Vault-Namespace") != "ns1" {
+		kv.t.Errorf("unexpected namespace %q", r.Header.Get("X-Vault-Namespace"))
+	}
+
+	if path, ok := strings.CutPrefix(r.URL.Path, "/v1/secret/data/"); ok {
+		switch r.Method {
+		case http.MethodGet:
+			versions := kv.versions[path]
+			if len(versions) == 0 {
+				w.WriteHeader(http.StatusNotFound)
+				_, _ = w.Write([]byte(`{"errors":[]}`))
+				return
+			}
+			_ = json.NewEncoder(w).Encode(map[string]interface{}{"data": map[string]interface{}{
+				"data": versions[len(versions)-1], "metadata": map[string]interface{}{"version": len(versions)},
+			}})
+		case http.MethodPost:
+			body := struct {
+				Data map[string]interface{} `json:"data"`
+			}{}
+			_ = json.NewDecoder(r.Body).Decode(&body)
+			kv.versions[path] = append(kv.versions[path], body.Data)
+			_ = json.NewEncoder(w).Encode(map[string]interface{}{"data": map[string]interface{}{"version": len(kv.versions[path])}})
+		}
+		return
+	}
+	if path, ok := strings.CutPrefix(r.URL.Path, "/v1/secret/metadata/"); ok {
+		switch r.Method {
+		case http.MethodGet:
+			if len(kv.versions[path]) == 0 {
+				w.WriteHeader(http.StatusNotFound)
+				_, _ = w.Write([]byte(`{"errors":[]}`))
+				return
+			}
+			_ = json.NewEncoder(w).Encode(map[string]interface{}{"data": Metadata{
+				CurrentVersion: len(kv.versions[path]), CustomMetadata: kv.custom[path],
+			}})
+		case http.MethodPost:
+			body := struct {
+				CustomMetadata map[string]string `json:"custom_metadata"`
+			}{}
+			_ = json.NewDecoder(r.Body).Decode(&body)
+			kv.custom[path] = body.CustomMetadata
+			w.WriteHeader(http.StatusNoContent)
+		}
+		return
+	}
+	w.WriteHeader(http.StatusNotFound)
+}
+
+func TestClient(t *testing.T) {
+	ctx := context.Background()
+	server := newKVServer(t)
+	defer server.Close()
+
+	_, err := NewClient(ctx, server.URL, "ns1", "secret", "", Credentials{RoleID: "role-1", SecretID: "wrong"})
+	if err == nil {
+		t.Fatalf("expected login with invalid secret ID to fail")
+	}
+
+	client, err := NewClient(ctx, server.URL, "ns1", "/secret/", "", Credentials{RoleID: "role-1", SecretID: "secret-1"})
+	if err != nil {
+		t.Fatalf("failed to create client: %s", err)
+	}
+
+	// Not found
+	secret, err := client.ReadSecret(ctx, "app/tls", 0)
+	if err != nil || secret != nil {
+		t.Fatalf("expected missing secret to return nil, got %v, %v", secret, err)
+	}
+	metadata, err := client.ReadMetadata(ctx, "app/tls")
+	if err != nil || metadata != nil {
+		t.Fatalf("expected missing metadata to return nil, got %v, %v", metadata, err)
+	}
+
+	// Create
+	version, err := client.WriteSecret(ctx, "/app/tls/", map[string]interface{}{"cert": "cert-1"})
+	if err != nil {
+		t.Fatalf("failed to write secret: %s", err)
+	}
+	if version != 1 {
+		t.Fatalf("expected version 1, got %d", version)
+	}
+	err = client.WriteCustomMetadata(ctx, "app/tls", map[string]string{"managed-by": "vcert"})
+	if err != nil {
+		t.Fatalf("failed to write custom metadata: %s", err)
+	}
+
+	// Update
+	version, err = client.WriteSecret(ctx, "app/tls", map[string]interface{}{"cert": "cert-2"})
+	if err != nil {
+		t.Fatalf("failed to update secret: %s", err)
+	}
+	if version != 2 {
+		t.Fatalf("expected version 2, got %d", version)
+	}
+	secret, err = client.ReadSecret(ctx, "app/tls", 0)
+	if err != nil {
+		t.Fatalf("failed to read secret: %s", err)
+	}
+	if secret.Data["cert"] != "cert-2" || secret.Metadata.Version != 2 {
+		t.Fatalf("unexpected secret %v", secret)
+	}
+	metadata, err = client.ReadMetadata(ctx, "app/tls")
+	if err != nil {
+		t.Fatalf("failed to read metadata: %s", err)
+	}
+	if metadata.CurrentVersion != 2 || metadata.CustomMetadata["managed-by"] != "vcert" {
+		t.Fatalf("unexpected metadata %v", metadata)
+	}
+
+	// Other errors are returned
+	client.token = "expired"
+	_, err = client.ReadSecret(ctx, "app/tls", 0)
+	if err == nil || !strings.Contains(err.Error(), "403 permission denied") {
+		t.Fatalf("expected permission denied error, got %v", err)
+	}
+}
@@ -0,0 +1,324 @@
+package policy
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/Venafi/vcert/v5/pkg/util"
+)
+
+const (
+	tppKeyTypeECDSA = "ECDSA"
+	cloudKeyTypeEC  = "EC"
+	// tppCAPrefix starts the DN of every TPP CA template
+	tppCAPrefix = "\\VED\\"
+)
+
+// TranslateForCloud returns a copy of ps that can be applied to a VaaS Application and Issuing Template,
+// typically a policy specification retrieved from TPP.
+//
+// The attributes with an equivalent in VaaS are converted, i.e. the ECDSA key type becomes EC, and the ones
+// VaaS does not support are removed. The returned messages describe every change made to ps
+func TranslateForCloud(ps *PolicySpecification) (*PolicySpecification, []string, error) {
+	t, err := newPolicyTranslation(ps)
+	if err != nil {
+		return nil, nil, err
+	}
+	t.translateForCloud()
+	return t.ps, t.changes, nil
+}
+
+// TranslateForTpp returns a copy of ps that can be applied to a TPP policy folder, typically a policy
+// specification retrieved from VaaS.
+//
+// The attributes with an equivalent in TPP are converted, i.e. the EC key type becomes ECDSA, and the ones
+// TPP does not support, or that TPP can only lock to a single value, are removed. The returned messages
+// describe every change made to ps
+func TranslateForTpp(ps *PolicySpecification) (*PolicySpecification, []string, error) {
+	t, err := newPolicyTranslation(ps)
+	if err != nil {
+		return nil, nil, err
+	}
+	t.translateForTpp()
+	return t.ps, t.changes, nil
+}
+
+// policyTranslation holds the copy of the policy specification being translated, and the changes made to it
+type policyTranslation struct {
+	ps      *PolicySpecification
+	changes []string
+}
+
+func newPolicyTranslation(ps *PolicySpecification) (*policyTranslation, error) {
+	if ps == nil {
+		return nil, fmt.Errorf("policy specification is required")
+	}
+
+	// The copy is deep, so the original specification is never modified
+	data, err := json.Marshal(ps)
+	if err != nil {
+		return nil, fmt.Errorf("failed to copy policy specification: %w", err)
+	}
+	var psCopy PolicySpecification
+	err = json.Unmarshal(data, &psCopy)
+	if err != nil {
+		return nil, fmt.Errorf("failed to copy policy specification: %w", err)
+	}
+	return &policyTranslation{ps: &psCopy}, nil
+}
+
+func (t *policyTranslation) change(format string, args ...interface{}) {
+	t.changes = append(t.changes, fmt.Sprintf(format, args...))
+}
+
+func (t *policyTranslation) translateForCloud() {
+	t.removeUsers()
+
+	p := t.ps.Policy
+	if p != nil {
+		if p.CertificateAuthority != nil && *(p.CertificateAuthority) != "" {
+			_, err := GetCertAuthorityInfo(*(p.CertificateAuthority))
+			if err != nil || strings.HasPrefix(*(p.CertificateAuthority), tppCAPrefix) {
+				t.change("certificateAuthority %s is not a VaaS CA and was removed, so %s is used", *(p.CertificateAuthority), DefaultCA)
+				p.CertificateAuthority = nil
+			}
+		}
+		if p.AutoInstalled != nil {
+			t.change("autoInstalled is not supported by VaaS and was removed")
+			p.AutoInstalled = nil
+		}
+
+		if p.KeyPair != nil {
+			for i, keyType := range p.KeyPair.KeyTypes {
+				if strings.EqualFold(keyType, tppKeyTypeECDSA) {
+					t.change("keyTypes value %s was converted to %s", keyType, cloudKeyTypeEC)
+					p.KeyPair.KeyTypes[i] = cloudKeyTypeEC
+				}
+			}
+			p.KeyPair.RsaKeySizes = t.filterRsaKeySizes("rsaKeySizes", p.KeyPair.RsaKeySizes, CloudRsaKeySize)
+		}
+
+		if p.SubjectAltNames != nil {
+			san := p.SubjectAltNames
+			if san.UpnAllowed != nil && *(san.UpnAllowed) {
+				t.change("upnAllowed is not supported by VaaS and was set to false")
+				san.UpnAllowed = util.GetBooleanRef(false)
+			}
+			if san.UriAllowed != nil && *(san.UriAllowed) && len(san.UriProtocols) == 0 {
+				t.change("uriAllowed requires uriProtocols in VaaS and was set to false")
+				san.UriAllowed = util.GetBooleanRef(false)
+			}
+		}
+	}
+
+	d := t.ps.Default
+	if d != nil {
+		if d.AutoInstalled != nil {
+			t.change("default autoInstalled is not supported by VaaS and was removed")
+			d.AutoInstalled = nil
+		}
+		if d.KeyPair != nil {
+			if d.KeyPair.KeyType != nil && strings.EqualFold(*(d.KeyPair.KeyType), tppKeyTypeECDSA) {
+				t.change("default keyType %s was converted to %s", *(d.KeyPair.KeyType), cloudKeyTypeEC)
+				d.KeyPair.KeyType = stringRef(cloudKeyTypeEC)
+			}
+			if d.KeyPair.RsaKeySize != nil && *(d.KeyPair.RsaKeySize) != 0 && !existIntInArray([]int{*(d.KeyPair.RsaKeySize)}, CloudRsaKeySize) {
+				t.change("default rsaKeySize %d is not supported by VaaS and was removed", *(d.KeyPair.RsaKeySize))
+				d.KeyPair.RsaKeySize = nil
+			}
+		}
+	}
+	t.alignCloudDefaults()
+}
+
+func (t *policyTranslation) translateForTpp() {
+	t.removeUsers()
+
+	p := t.ps.Policy
+	if p != nil {
+		if p.CertificateAuthority != nil && *(p.CertificateAuthority) != "" && !strings.HasPrefix(*(p.CertificateAuthority), tppCAPrefix) {
+			t.change("certificateAuthority %s is not a TPP CA template and was removed", *(p.CertificateAuthority))
+			p.CertificateAuthority = nil
+		}
+		if p.MaxValidDays != nil {
+			t.change("maxValidDays is not supported by TPP policies and was removed")
+			p.MaxValidDays = nil
+		}
+
+		// VaaS allows any value with the .* regex, and values left empty, which mean no restriction in TPP
+		p.Domains = t.removeAllowAll("domains", p.Domains)
+		if p.Subject != nil {
+			s := p.Subject
+			s.Orgs = t.singleValue("orgs", t.removeAllowAll("orgs", s.Orgs))
+			s.OrgUnits = t.removeAllowAll("orgUnits", s.OrgUnits)
+			s.Localities = t.singleValue("localities", t.removeAllowAll("localities", s.Localities))
+			s.States = t.singleValue("states", t.removeAllowAll("states", s.States))
+			s.Countries = t.singleValue("countries", t.removeAllowAll("countries", s.Countries))
+		}
+
+		if p.KeyPair != nil {
+			kp := p.KeyPair
+			for i, keyType := range kp.KeyTypes {
+				if strings.EqualFold(keyType, cloudKeyTypeEC) {
+					t.change("keyTypes value %s was converted to %s", keyType, tppKeyTypeECDSA)
+					kp.KeyTypes[i] = tppKeyTypeECDSA
+				}
+			}
+			kp.KeyTypes = t.singleValue("keyTypes", kp.KeyTypes)
+
+			kp.RsaKeySizes = t.filterRsaKeySizes("rsaKeySizes", kp.RsaKeySizes, TppRsaKeySize)
+			if len(kp.RsaKeySizes) > 1 {
+				// TPP enforces the key bit strength as a minimum, so the smallest size keeps every size allowed
+				sort.Ints(kp.RsaKeySizes)
+				t.change("rsaKeySizes allows several sizes, which TPP cannot lock, so it was set to the minimum %d", kp.RsaKeySizes[0])
+				kp.RsaKeySizes = kp.RsaKeySizes[:1]
+			}
+
+			curves := make([]string, 0, len(kp.EllipticCurves))
+			for _, curve := range kp.EllipticCurves {
+				if existStringInArray([]string{curve}, TppEllipticCurves) {
+					curves = append(curves, curve)
+				} else {
+					t.change("ellipticCurves value %s is not supported by TPP and was removed", curve)
+				}
+			}
+			if len(curves) == 0 {
+				curves = nil
+			}
+			kp.EllipticCurves = t.singleValue("ellipticCurves", curves)
+		}
+
+		if p.SubjectAltNames != nil {
+			san := p.SubjectAltNames
+			if len(san.UriProtocols) > 0 {
+				t.change("uriProtocols is not supported by TPP and was removed")
+				san.UriProtocols = nil
+			}
+			if len(san.IpConstraints) > 0 {
+				t.change("ipConstraints is not supported by TPP and was removed")
+				san.IpConstraints = nil
+			}
+		}
+	}
+
+	d := t.ps.Default
+	if d != nil && d.KeyPair != nil {
+		if d.KeyPair.KeyType != nil && strings.EqualFold(*(d.KeyPair.KeyType), cloudKeyTypeEC) {
+			t.change("default keyType %s was converted to %s", *(d.KeyPair.KeyType), tppKeyTypeECDSA)
+			d.KeyPair.KeyType = stringRef(tppKeyTypeECDSA)
+		}
+	}
+	t.alignTppDefaults()
+}
+
+// alignCloudDefaults removes the key pair defaults that VaaS would reject because the policy does not allow them
+func (t *policyTranslation) alignCloudDefaults() {
+	if t.ps.Policy == nil || t.ps.Policy.KeyPair == nil || t.ps.Default == nil || t.ps.Default.KeyPair == nil {
+		return
+	}
+	kp, dkp := t.ps.Policy.KeyPair, t.ps.Default.KeyPair
+
+	if dkp.KeyType != nil && *(dkp.KeyType) != "" && len(kp.KeyTypes) > 0 && !existStringInArray([]string{*(dkp.KeyType)}, kp.KeyTypes) {
+		t.change("default keyType %s is not allowed by the policy and was removed", *(dkp.KeyType))
+		dkp.KeyType = nil
+	}
+	if dkp.RsaKeySize != nil && *(dkp.RsaKeySize) != 0 && len(kp.RsaKeySizes) > 0 && !existIntInArray([]int{*(dkp.RsaKeySize)}, kp.RsaKeySizes) {
+		t.change("default rsaKeySize %d is not allowed by the policy and was removed", *(dkp.RsaKeySize))
+		dkp.RsaKeySize = nil
+	}
+}
+
+// alignTppDefaults removes the defaults that TPP would reject because they differ from the value locked by the policy
+func (t *policyTranslation) alignTppDefaults() {
+	if t.ps.Policy == nil || t.ps.Default == nil {
+		return
+	}
+
+	if s, ds := t.ps.Policy.Subject, t.ps.Default.Subject; s != nil && ds != nil {
+		ds.Org = t.alignDefault("org", ds.Org, s.Orgs)
+		ds.Locality = t.alignDefault("locality", ds.Locality, s.Localities)
+		ds.State = t.alignDefault("state", ds.State, s.States)
+		ds.Country = t.alignDefault("country", ds.Country, s.Countries)
+	}
+
+	if kp, dkp := t.ps.Policy.KeyPair, t.ps.Default.KeyPair; kp != nil && dkp != nil {
+		dkp.KeyType = t.alignDefault("keyType", dkp.KeyType, kp.KeyTypes)
+		dkp.EllipticCurve = t.alignDefault("ellipticCurve", dkp.EllipticCurve, kp.EllipticCurves)
+		if len(kp.RsaKeySizes) > 0 && dkp.RsaKeySize != nil && *(dkp.RsaKeySize) != 0 && *(dkp.RsaKeySize) != kp.RsaKeySizes[0] {
+			t.change("default rsaKeySize %d does not match the policy and was removed", *(dkp.RsaKeySize))
+			dkp.RsaKeySize = nil
+		}
+	}
+}
+
+func (t *policyTranslation) alignDefault(name string, value *string, policyValues []string) *string {
+	if value == nil || *value == "" || len(policyValues) == 0 || policyValues[0] == *value {
+		return value
+	}
+	t.change("default %s %s does not match the policy and was removed", name, *value)
+	return nil
+}
+
+func (t *policyTranslation) removeUsers() {
+	if len(t.ps.Users) > 0 || len(t.ps.Owners) > 0 || len(t.ps.Approvers) > 0 || t.ps.UserAccess != "" {
+		t.change("users, owners, approvers and userAccess are identities of the source platform and were removed")
+		t.ps.Users = nil
+		t.ps.Owners = nil
+		t.ps.Approvers = nil
+		t.ps.UserAccess = ""
+	}
+}
+
+// removeAllowAll returns values without the entries that allow any value
+func (t *policyTranslation) removeAllowAll(name string, values []string) []string {
+	if values == nil {
+		return nil
+	}
+	result := make([]string, 0, len(values))
+	for _, value := range values {
+		if value == "" || value == AllowAll {
+			continue
+		}
+		result = append(result, value)
+	}
+	if len(result) < len(values) {
+		t.change("%s values allowing any value were removed", name)
+	}
+	if len(result) == 0 {
+		return nil
+	}
+	return result
+}
+
+// singleValue removes values when it has more than one, as TPP can only lock an attribute to a single value
+func (t *policyTranslation) singleValue(name string, values []string) []string {
+	if len(values) > 1 {
+		t.change("%s allows several values, which TPP cannot lock, and was removed", name)
+		return nil
+	}
+	return values
+}
+
+func (t *policyTranslation) filterRsaKeySizes(name string, sizes []int, supported []int) []int {
+	if sizes == nil {
+		return nil
+	}
+	result := make([]int, 0, len(sizes))
+	for _, size := range sizes {
+		if existIntInArray([]int{size}, supported) {
+			result = append(result, size)
+		} else {
+			t.change("%s value %d is not supported by the target platform and was removed", name, size)
+		}
+	}
+	if len(result) == 0 {
+		return nil
+	}
+	return result
+}
+
+func stringRef(s string) *string {
+	return &s
+}
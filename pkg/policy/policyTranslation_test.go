@@ -0,0 +1,95 @@
+package policy
+
+import (
+	"path/filepath"
+	"testing"
+
+	"github.com/smartystreets/assertions"
+)
+
+func TestTranslateForCloud(t *testing.T) {
+	a := assertions.New(t)
+	absPath, err := filepath.Abs("../../test-files/policy_specification_tpp.json")
+	if err != nil {
+		t.Fatalf("Error opening policy specification\nError: %s", err)
+	}
+
+	policySpecification := getPolicySpecificationFromFile(absPath)
+	policySpecification.Policy.KeyPair.KeyTypes = []string{"RSA", "ECDSA"}
+	policySpecification.Policy.KeyPair.RsaKeySizes = []int{512, 2048}
+
+	translated, changes, err := TranslateForCloud(policySpecification)
+	if err != nil {
+		t.Fatalf("Error translating policy specification\nError: %s", err)
+	}
+
+	err = ValidateCloudPolicySpecification(translated)
+	if err != nil {
+		t.Fatalf("Error validating translated policy specification\nError: %s", err)
+	}
+
+	a.So(translated.Policy.CertificateAuthority, assertions.ShouldBeNil)
+	a.So(translated.Policy.AutoInstalled, assertions.ShouldBeNil)
+	a.So(translated.Default.AutoInstalled, assertions.ShouldBeNil)
+	a.So(translated.Policy.KeyPair.KeyTypes, assertions.ShouldResemble, []string{"RSA", "EC"})
+	a.So(translated.Policy.KeyPair.RsaKeySizes, assertions.ShouldResemble, []int{2048})
+	a.So(changes, assertions.ShouldNotBeEmpty)
+
+	// the original policy specification is left untouched
+	a.So(*(policySpecification.Policy.CertificateAuthority), assertions.ShouldEqual, "\\VED\\Policy\\Open Source\\msca_template_ecdsa")
+	a.So(policySpecification.Policy.KeyPair.KeyTypes, assertions.ShouldResemble, []string{"RSA", "ECDSA"})
+}
+
+func TestTranslateForTpp(t *testing.T) {
+	a := assertions.New(t)
+	absPath, err := filepath.Abs("../../test-files/policy_specification_cloud.json")
+	if err != nil {
+		t.Fatalf("Error opening policy specification\nError: %s", err)
+	}
+
+	policySpecification := getPolicySpecificationFromFile(absPath)
+	policySpecification.Policy.Subject.Countries = []string{"US", "CA"}
+	policySpecification.Policy.KeyPair.RsaKeySizes = []int{4096, 2048}
+	policySpecification.Default.KeyPair.RsaKeySize = nil
+
+	translated, changes, err := TranslateForTpp(policySpecification)
+	if err != nil {
+		t.Fatalf("Error translating policy specification\nError: %s", err)
+	}
+
+	err = ValidateTppPolicySpecification(translated)
+	if err != nil {
+		t.Fatalf("Error validating translated policy specification\nError: %s", err)
+	}
+
+	a.So(translated.Policy.CertificateAuthority, assertions.ShouldBeNil)
+	a.So(translated.Policy.MaxValidDays, assertions.ShouldBeNil)
+	a.So(translated.Policy.Subject.Countries, assertions.ShouldBeNil)
+	a.So(*(translated.Default.Subject.Country), assertions.ShouldEqual, "US")
+	a.So(translated.Policy.KeyPair.RsaKeySizes, assertions.ShouldResemble, []int{2048})
+	a.So(translated.Policy.SubjectAltNames.UriProtocols, assertions.ShouldBeEmpty)
+	a.So(translated.Policy.SubjectAltNames.IpConstraints, assertions.ShouldBeEmpty)
+	a.So(changes, assertions.ShouldNotBeEmpty)
+}
+
+func TestTranslateForTppAllowAll(t *testing.T) {
+	a := assertions.New(t)
+	ps := &PolicySpecification{
+		Policy: &Policy{
+			Domains: []string{AllowAll},
+			KeyPair: &KeyPair{
+				KeyTypes:       []string{"EC"},
+				EllipticCurves: []string{"P256", "ED25519"},
+			},
+		},
+	}
+
+	translated, _, err := TranslateForTpp(ps)
+	if err != nil {
+		t.Fatalf("Error translating policy specification\nError: %s", err)
+	}
+
+	a.So(translated.Policy.Domains, assertions.ShouldBeNil)
+	a.So(translated.Policy.KeyPair.KeyTypes, assertions.ShouldResemble, []string{"ECDSA"})
+	a.So(translated.Policy.KeyPair.EllipticCurves, assertions.ShouldResemble, []string{"P256"})
+}
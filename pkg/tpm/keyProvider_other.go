@@ -0,0 +1,96 @@
+//go:build !windows
+
+/*
+ * Copyright 2023 Venafi, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *  http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package tpm
+
+import (
+	"crypto"
+	"fmt"
+
+	"github.com/Venafi/vcert/v5/pkg/certificate"
+	"github.com/Venafi/vcert/v5/pkg/pkcs11"
+	"github.com/Venafi/vcert/v5/pkg/verror"
+)
+
+// KeyProvider generates key pairs in the TPM through the tpm2-pkcs11 module. Close must be called once the
+// certificate requests have been signed, to log out of the token and unload the module
+type KeyProvider struct {
+	config Config
+	token  *pkcs11.KeyProvider
+}
+
+// NewKeyProvider loads the tpm2-pkcs11 module and logs in to the token set in config
+func NewKeyProvider(config Config) (*KeyProvider, error) {
+	err := config.IsValid()
+	if err != nil {
+		return nil, err
+	}
+
+	token, err := pkcs11.NewKeyProvider(pkcs11.Config{
+		Module:     config.GetModule(),
+		TokenLabel: config.TokenLabel,
+		PIN:        config.PIN,
+		KeyLabel:   config.KeyName,
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &KeyProvider{config: config, token: token}, nil
+}
+
+// GenerateKey creates a new RSA or ECDSA key pair in the TPM and returns a crypto.Signer for it
+func (p *KeyProvider) GenerateKey(request *certificate.Request) (crypto.Signer, error) {
+	err := checkKeyType(request)
+	if err != nil {
+		return nil, err
+	}
+	return p.token.GenerateKey(request)
+}
+
+// FindKey returns the key pair of the TPM holding the private key of publicKey, so a renewal can reuse it.
+// Returns nil when the TPM has no such key pair
+func (p *KeyProvider) FindKey(request *certificate.Request, publicKey crypto.PublicKey) (crypto.Signer, error) {
+	return p.token.FindKey(request, publicKey)
+}
+
+// Attest always fails, as tpm2-pkcs11 does not expose the attestation of its keys
+func (p *KeyProvider) Attest(_ crypto.Signer) ([]byte, error) {
+	return nil, fmt.Errorf("%w: TPM key attestation is only supported on Windows", verror.VcertError)
+}
+
+// Close logs out of the token and releases the module
+func (p *KeyProvider) Close() error {
+	return p.token.Close()
+}
+
+// checkKeyType rejects the keys a TPM 2.0 is not required to support, before the module fails with a vaguer error
+func checkKeyType(request *certificate.Request) error {
+	switch request.KeyType {
+	case certificate.KeyTypeRSA:
+		if request.KeyLength != 0 && request.KeyLength != 2048 {
+			return fmt.Errorf("%w: TPM only supports RSA keys of 2048 bits", verror.UserDataError)
+		}
+	case certificate.KeyTypeECDSA:
+		if request.KeyCurve != certificate.EllipticCurveNotSet && request.KeyCurve != certificate.EllipticCurveP256 {
+			return fmt.Errorf("%w: TPM only supports ECDSA keys on curve P256", verror.UserDataError)
+		}
+	default:
+		return fmt.Errorf("%w: key type %s is not supported by the TPM", verror.UserDataError, request.KeyType.String())
+	}
+	return nil
+}
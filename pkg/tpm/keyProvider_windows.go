@@ -0,0 +1,441 @@
+//go:build windows
+
+/*
+ * Copyright 2023 Venafi, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *  http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package tpm
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"encoding/asn1"
+	"encoding/binary"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"math/big"
+	"strings"
+	"unsafe"
+
+	"golang.org/x/sys/windows"
+
+	"github.com/Venafi/vcert/v5/pkg/certificate"
+	"github.com/Venafi/vcert/v5/pkg/verror"
+)
+
+var (
+	ncrypt = windows.NewLazySystemDLL("ncrypt.dll")
+
+	procNCryptOpenStorageProvider = ncrypt.NewProc("NCryptOpenStorageProvider")
+	procNCryptCreatePersistedKey  = ncrypt.NewProc("NCryptCreatePersistedKey")
+	procNCryptOpenKey             = ncrypt.NewProc("NCryptOpenKey")
+	procNCryptEnumKeys            = ncrypt.NewProc("NCryptEnumKeys")
+	procNCryptSetProperty         = ncrypt.NewProc("NCryptSetProperty")
+	procNCryptFinalizeKey         = ncrypt.NewProc("NCryptFinalizeKey")
+	procNCryptExportKey           = ncrypt.NewProc("NCryptExportKey")
+	procNCryptSignHash            = ncrypt.NewProc("NCryptSignHash")
+	procNCryptCreateClaim         = ncrypt.NewProc("NCryptCreateClaim")
+	procNCryptFreeBuffer          = ncrypt.NewProc("NCryptFreeBuffer")
+	procNCryptFreeObject          = ncrypt.NewProc("NCryptFreeObject")
+)
+
+// Constants of ncrypt.h and bcrypt.h
+const (
+	ncryptMachineKeyFlag = 0x20
+
+	ncryptClaimAuthorityAndSubject = 0x3
+
+	bcryptPadPKCS1 = 0x2
+	bcryptPadPSS   = 0x8
+
+	bcryptRSAPublicMagic  = 0x31415352
+	bcryptECDSAPublicP256 = 0x31534345
+	bcryptECDSAPublicP384 = 0x33534345
+	bcryptECDSAPublicP521 = 0x35534345
+
+	nteNoMoreItems = 0x8009002A
+
+	ncryptLengthProperty = "Length"
+	bcryptPublicKeyBlob  = "PUBLICBLOB"
+)
+
+// KeyProvider generates key pairs in the TPM through the Microsoft Platform Crypto Provider. Close must be called
+// once the certificate requests have been signed, to release the keys and the provider
+type KeyProvider struct {
+	config   Config
+	provider uintptr
+	keys     []*cngKey
+}
+
+// NewKeyProvider opens the Microsoft Platform Crypto Provider, which fails on hosts without a TPM 2.0
+func NewKeyProvider(config Config) (*KeyProvider, error) {
+	err := config.IsValid()
+	if err != nil {
+		return nil, err
+	}
+
+	var provider uintptr
+	err = call(procNCryptOpenStorageProvider, uintptr(unsafe.Pointer(&provider)),
+		uintptr(unsafe.Pointer(utf16(PlatformCryptoProvider))), 0)
+	if err != nil {
+		return nil, fmt.Errorf("%w: failed to open %s: %v", verror.VcertError, PlatformCryptoProvider, err)
+	}
+	return &KeyProvider{config: config, provider: provider}, nil
+}
+
+// GenerateKey creates a new persisted RSA or ECDSA key in the TPM and returns a crypto.Signer for it. The key is
+// named after the key name of the config with a random suffix, so the key of the certificate installed is kept
+func (p *KeyProvider) GenerateKey(request *certificate.Request) (crypto.Signer, error) {
+	algorithm, err := getAlgorithm(request)
+	if err != nil {
+		return nil, err
+	}
+	suffix := make([]byte, 4)
+	_, err = rand.Read(suffix)
+	if err != nil {
+		return nil, err
+	}
+	name := fmt.Sprintf("%s-%s", p.config.keyName(request), hex.EncodeToString(suffix))
+
+	var handle uintptr
+	err = call(procNCryptCreatePersistedKey, p.provider, uintptr(unsafe.Pointer(&handle)),
+		uintptr(unsafe.Pointer(utf16(algorithm))), uintptr(unsafe.Pointer(utf16(name))), 0, p.flags())
+	if err != nil {
+		return nil, fmt.Errorf("%w: failed to create key in TPM: %v", verror.VcertError, err)
+	}
+	if request.KeyType == certificate.KeyTypeRSA {
+		length := uint32(request.KeyLength)
+		err = call(procNCryptSetProperty, handle, uintptr(unsafe.Pointer(utf16(ncryptLengthProperty))),
+			uintptr(unsafe.Pointer(&length)), 4, 0)
+		if err != nil {
+			freeObject(handle)
+			return nil, fmt.Errorf("%w: failed to set key length in TPM: %v", verror.VcertError, err)
+		}
+	}
+	err = call(procNCryptFinalizeKey, handle, 0)
+	if err != nil {
+		freeObject(handle)
+		return nil, fmt.Errorf("%w: failed to generate key in TPM: %v", verror.VcertError, err)
+	}
+	return p.newKey(handle, name)
+}
+
+// FindKey returns the key of the TPM holding the private key of publicKey, among the ones GenerateKey named for
+// request, so a renewal can reuse it. Returns nil when the TPM has no such key
+func (p *KeyProvider) FindKey(request *certificate.Request, publicKey crypto.PublicKey) (crypto.Signer, error) {
+	names, err := p.keyNames(p.config.keyName(request) + "-")
+	if err != nil {
+		return nil, err
+	}
+	for _, name := range names {
+		var handle uintptr
+		err = call(procNCryptOpenKey, p.provider, uintptr(unsafe.Pointer(&handle)),
+			uintptr(unsafe.Pointer(utf16(name))), 0, p.flags())
+		if err != nil {
+			return nil, fmt.Errorf("%w: failed to open key %s in TPM: %v", verror.VcertError, name, err)
+		}
+		key, err := p.newKey(handle, name)
+		if err != nil {
+			return nil, err
+		}
+		if public, ok := key.Public().(interface{ Equal(crypto.PublicKey) bool }); ok && public.Equal(publicKey) {
+			return key, nil
+		}
+	}
+	return nil, nil
+}
+
+// Attest returns the claim, signed by the attestation identity key of the config, that the private key of signer
+// was generated in the TPM and cannot leave it
+func (p *KeyProvider) Attest(signer crypto.Signer) ([]byte, error) {
+	key, ok := signer.(*cngKey)
+	if !ok {
+		return nil, fmt.Errorf("%w: only keys generated in the TPM can be attested", verror.VcertError)
+	}
+
+	// Windows provisions its attestation identity key for the machine
+	var aik uintptr
+	err := call(procNCryptOpenKey, p.provider, uintptr(unsafe.Pointer(&aik)),
+		uintptr(unsafe.Pointer(utf16(p.config.GetAttestationKey()))), 0, ncryptMachineKeyFlag)
+	if err != nil {
+		return nil, fmt.Errorf("%w: failed to open attestation key %q in TPM: %v", verror.VcertError,
+			p.config.GetAttestationKey(), err)
+	}
+	defer freeObject(aik)
+
+	var size uint32
+	err = call(procNCryptCreateClaim, key.handle, aik, ncryptClaimAuthorityAndSubject, 0, 0, 0,
+		uintptr(unsafe.Pointer(&size)), 0)
+	if err != nil {
+		return nil, fmt.Errorf("%w: failed to attest key %s: %v", verror.VcertError, key.name, err)
+	}
+	claim := make([]byte, size)
+	err = call(procNCryptCreateClaim, key.handle, aik, ncryptClaimAuthorityAndSubject, 0,
+		uintptr(unsafe.Pointer(&claim[0])), uintptr(size), uintptr(unsafe.Pointer(&size)), 0)
+	if err != nil {
+		return nil, fmt.Errorf("%w: failed to attest key %s: %v", verror.VcertError, key.name, err)
+	}
+	return claim[:size], nil
+}
+
+// Close releases the keys opened by the provider and the provider itself. The keys are kept in the TPM
+func (p *KeyProvider) Close() error {
+	for _, key := range p.keys {
+		freeObject(key.handle)
+	}
+	p.keys = nil
+	freeObject(p.provider)
+	p.provider = 0
+	return nil
+}
+
+// newKey exports the public key of handle and tracks the key, so Close releases it
+func (p *KeyProvider) newKey(handle uintptr, name string) (*cngKey, error) {
+	public, err := exportPublicKey(handle)
+	if err != nil {
+		freeObject(handle)
+		return nil, err
+	}
+	key := &cngKey{handle: handle, name: name, public: public}
+	p.keys = append(p.keys, key)
+	return key, nil
+}
+
+// keyNames returns the names of the keys of the TPM starting with prefix
+func (p *KeyProvider) keyNames(prefix string) ([]string, error) {
+	type ncryptKeyName struct {
+		name          *uint16
+		algorithm     *uint16
+		legacyKeySpec uint32
+		flags         uint32
+	}
+
+	var names []string
+	var state uintptr
+	defer func() {
+		if state != 0 {
+			_, _, _ = procNCryptFreeBuffer.Call(state)
+		}
+	}()
+	for {
+		var keyName *ncryptKeyName
+		r, _, _ := procNCryptEnumKeys.Call(p.provider, 0, uintptr(unsafe.Pointer(&keyName)),
+			uintptr(unsafe.Pointer(&state)), p.flags())
+		if r == nteNoMoreItems {
+			return names, nil
+		}
+		if r != 0 {
+			return nil, fmt.Errorf("%w: failed to list keys in TPM: %v", verror.VcertError, windows.Errno(r))
+		}
+		name := windows.UTF16PtrToString(keyName.name)
+		_, _, _ = procNCryptFreeBuffer.Call(uintptr(unsafe.Pointer(keyName)))
+		if strings.HasPrefix(name, prefix) {
+			names = append(names, name)
+		}
+	}
+}
+
+// flags returns the flags selecting the keys of the machine or of the current user
+func (p *KeyProvider) flags() uintptr {
+	if p.config.MachineKey {
+		return ncryptMachineKeyFlag
+	}
+	return 0
+}
+
+// cngKey is a crypto.Signer for a key of the TPM, which signs the digests in the TPM
+type cngKey struct {
+	handle uintptr
+	name   string
+	public crypto.PublicKey
+}
+
+// Public returns the public key of the key
+func (k *cngKey) Public() crypto.PublicKey {
+	return k.public
+}
+
+// Sign signs digest in the TPM. RSA keys use PSS padding when opts is a *rsa.PSSOptions, PKCS#1 v1.5 otherwise
+func (k *cngKey) Sign(_ io.Reader, digest []byte, opts crypto.SignerOpts) ([]byte, error) {
+	switch k.public.(type) {
+	case *rsa.PublicKey:
+		algorithm, err := getHashAlgorithm(opts.HashFunc())
+		if err != nil {
+			return nil, err
+		}
+		if pssOpts, ok := opts.(*rsa.PSSOptions); ok {
+			saltLength := pssOpts.SaltLength
+			if saltLength == rsa.PSSSaltLengthAuto || saltLength == rsa.PSSSaltLengthEqualsHash {
+				saltLength = opts.HashFunc().Size()
+			}
+			padding := &struct {
+				algorithm  *uint16
+				saltLength uint32
+			}{utf16(algorithm), uint32(saltLength)}
+			return k.signHash(unsafe.Pointer(padding), digest, bcryptPadPSS)
+		}
+		padding := &struct {
+			algorithm *uint16
+		}{utf16(algorithm)}
+		return k.signHash(unsafe.Pointer(padding), digest, bcryptPadPKCS1)
+	case *ecdsa.PublicKey:
+		signature, err := k.signHash(nil, digest, 0)
+		if err != nil {
+			return nil, err
+		}
+		// CNG returns r and s concatenated, while Go expects the ASN.1 encoding of crypto/ecdsa
+		half := len(signature) / 2
+		return asn1.Marshal(struct {
+			R, S *big.Int
+		}{new(big.Int).SetBytes(signature[:half]), new(big.Int).SetBytes(signature[half:])})
+	default:
+		return nil, fmt.Errorf("%w: unsupported TPM key type %T", verror.VcertError, k.public)
+	}
+}
+
+func (k *cngKey) signHash(padding unsafe.Pointer, digest []byte, flags uintptr) ([]byte, error) {
+	var size uint32
+	err := call(procNCryptSignHash, k.handle, uintptr(padding), uintptr(unsafe.Pointer(&digest[0])), uintptr(len(digest)),
+		0, 0, uintptr(unsafe.Pointer(&size)), flags)
+	if err != nil {
+		return nil, fmt.Errorf("%w: failed to sign with key %s in TPM: %v", verror.VcertError, k.name, err)
+	}
+	signature := make([]byte, size)
+	err = call(procNCryptSignHash, k.handle, uintptr(padding), uintptr(unsafe.Pointer(&digest[0])), uintptr(len(digest)),
+		uintptr(unsafe.Pointer(&signature[0])), uintptr(size), uintptr(unsafe.Pointer(&size)), flags)
+	if err != nil {
+		return nil, fmt.Errorf("%w: failed to sign with key %s in TPM: %v", verror.VcertError, k.name, err)
+	}
+	return signature[:size], nil
+}
+
+// exportPublicKey reads the public key of handle from its BCRYPT_RSAKEY_BLOB or BCRYPT_ECCKEY_BLOB
+func exportPublicKey(handle uintptr) (crypto.PublicKey, error) {
+	var size uint32
+	err := call(procNCryptExportKey, handle, 0, uintptr(unsafe.Pointer(utf16(bcryptPublicKeyBlob))), 0, 0, 0,
+		uintptr(unsafe.Pointer(&size)), 0)
+	if err != nil {
+		return nil, fmt.Errorf("%w: failed to export public key from TPM: %v", verror.VcertError, err)
+	}
+	blob := make([]byte, size)
+	err = call(procNCryptExportKey, handle, 0, uintptr(unsafe.Pointer(utf16(bcryptPublicKeyBlob))), 0,
+		uintptr(unsafe.Pointer(&blob[0])), uintptr(size), uintptr(unsafe.Pointer(&size)), 0)
+	if err != nil {
+		return nil, fmt.Errorf("%w: failed to export public key from TPM: %v", verror.VcertError, err)
+	}
+	blob = blob[:size]
+
+	malformed := fmt.Errorf("%w: malformed public key exported from TPM", verror.VcertError)
+	if len(blob) < 8 {
+		return nil, malformed
+	}
+	switch magic := binary.LittleEndian.Uint32(blob); magic {
+	case bcryptRSAPublicMagic:
+		// Magic, BitLength, cbPublicExp, cbModulus, cbPrime1, cbPrime2, then the exponent and the modulus
+		if len(blob) < 24 {
+			return nil, malformed
+		}
+		expSize := int(binary.LittleEndian.Uint32(blob[8:]))
+		modSize := int(binary.LittleEndian.Uint32(blob[12:]))
+		if len(blob) < 24+expSize+modSize || expSize > 4 {
+			return nil, malformed
+		}
+		exponent := new(big.Int).SetBytes(blob[24 : 24+expSize])
+		modulus := new(big.Int).SetBytes(blob[24+expSize : 24+expSize+modSize])
+		return &rsa.PublicKey{N: modulus, E: int(exponent.Int64())}, nil
+	case bcryptECDSAPublicP256, bcryptECDSAPublicP384, bcryptECDSAPublicP521:
+		// Magic, cbKey, then the X and Y coordinates
+		keySize := int(binary.LittleEndian.Uint32(blob[4:]))
+		if len(blob) < 8+2*keySize {
+			return nil, malformed
+		}
+		curve := elliptic.P256()
+		if magic == bcryptECDSAPublicP384 {
+			curve = elliptic.P384()
+		} else if magic == bcryptECDSAPublicP521 {
+			curve = elliptic.P521()
+		}
+		return &ecdsa.PublicKey{
+			Curve: curve,
+			X:     new(big.Int).SetBytes(blob[8 : 8+keySize]),
+			Y:     new(big.Int).SetBytes(blob[8+keySize : 8+2*keySize]),
+		}, nil
+	default:
+		return nil, fmt.Errorf("%w: unsupported public key exported from TPM", verror.VcertError)
+	}
+}
+
+// getAlgorithm returns the CNG algorithm identifier of the key of request
+func getAlgorithm(request *certificate.Request) (string, error) {
+	switch request.KeyType {
+	case certificate.KeyTypeRSA:
+		return "RSA", nil
+	case certificate.KeyTypeECDSA:
+		switch request.KeyCurve {
+		case certificate.EllipticCurveNotSet, certificate.EllipticCurveP256:
+			return "ECDSA_P256", nil
+		case certificate.EllipticCurveP384:
+			return "ECDSA_P384", nil
+		default:
+			return "", fmt.Errorf("%w: curve %s is not supported by the TPM", verror.UserDataError, request.KeyCurve.String())
+		}
+	default:
+		return "", fmt.Errorf("%w: key type %s is not supported by the TPM", verror.UserDataError, request.KeyType.String())
+	}
+}
+
+// getHashAlgorithm returns the CNG algorithm identifier of hash
+func getHashAlgorithm(hash crypto.Hash) (string, error) {
+	switch hash {
+	case crypto.SHA1:
+		return "SHA1", nil
+	case crypto.SHA256:
+		return "SHA256", nil
+	case crypto.SHA384:
+		return "SHA384", nil
+	case crypto.SHA512:
+		return "SHA512", nil
+	default:
+		return "", fmt.Errorf("%w: hash %s is not supported by the TPM", verror.VcertError, hash.String())
+	}
+}
+
+// call runs an NCrypt function, turning the SECURITY_STATUS it returns into an error. Like proc.Call, it keeps the
+// pointers converted to uintptr in args alive until the function returns
+//
+//go:uintptrescapes
+func call(proc *windows.LazyProc, args ...uintptr) error {
+	r, _, _ := proc.Call(args...)
+	if r != 0 {
+		return windows.Errno(r)
+	}
+	return nil
+}
+
+// freeObject releases an NCrypt handle
+func freeObject(handle uintptr) {
+	if handle != 0 {
+		_, _, _ = procNCryptFreeObject.Call(handle)
+	}
+}
+
+// utf16 returns the NUL terminated UTF-16 encoding of s, as expected by NCrypt
+func utf16(s string) *uint16 {
+	return windows.StringToUTF16Ptr(s)
+}
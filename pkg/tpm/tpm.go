@@ -0,0 +1,134 @@
+/*
+ * Copyright 2023 Venafi, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *  http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+// Package tpm provides a certificate.KeyProvider that generates private keys inside the Trusted Platform Module of the
+// host. The private key is bound to the TPM, cannot be exported and the certificate request is signed by the TPM.
+//
+// On Windows, keys are created by the Microsoft Platform Crypto Provider of CNG, which can also attest that a key
+// lives in the TPM. Elsewhere, keys are created through the tpm2-pkcs11 module of tpm2-tss, so this support is only
+// available in binaries built with cgo enabled.
+package tpm
+
+import (
+	"crypto"
+	"encoding/base64"
+	"fmt"
+	"runtime"
+
+	"github.com/Venafi/vcert/v5/pkg/certificate"
+	"github.com/Venafi/vcert/v5/pkg/verror"
+)
+
+const (
+	// PlatformCryptoProvider is the CNG Key Storage Provider backed by the TPM on Windows
+	PlatformCryptoProvider = "Microsoft Platform Crypto Provider"
+	// DefaultModule is the tpm2-pkcs11 library of tpm2-tss, loaded from the library path
+	DefaultModule = "libtpm2_pkcs11.so.1"
+	// DefaultAttestationKey is the name of the attestation identity key Windows provisions in the TPM
+	DefaultAttestationKey = "Windows AIK"
+)
+
+// Config holds the settings needed to generate keys in the TPM
+type Config struct {
+	// KeyName is the name of the generated keys: the name of the persisted CNG key on Windows, the label of the
+	// key pair elsewhere. The common name of the request is used when empty
+	KeyName string `yaml:"keyName,omitempty"`
+	// Module is the path to the tpm2-pkcs11 library. Defaults to DefaultModule. Not used on Windows
+	Module string `yaml:"module,omitempty"`
+	// TokenLabel is the label of the tpm2-pkcs11 token, as created by tpm2_ptool addtoken. Not used on Windows
+	TokenLabel string `yaml:"tokenLabel,omitempty"`
+	// PIN is the user PIN of the tpm2-pkcs11 token. Not used on Windows
+	PIN string `yaml:"pin,omitempty"`
+	// MachineKey creates the keys for the local machine instead of the current user. Only on Windows
+	MachineKey bool `yaml:"machineKey,omitempty"`
+	// AttestationField is the name of the TPP custom field receiving the attestation of the key, base64 encoded.
+	// No attestation is sent when empty. Only on Windows
+	AttestationField string `yaml:"attestationField,omitempty"`
+	// AttestationKey is the name of the attestation identity key signing the attestation. Defaults to
+	// DefaultAttestationKey. Only on Windows
+	AttestationKey string `yaml:"attestationKey,omitempty"`
+}
+
+// IsValid returns an error when the Config misses any setting required to use the TPM of this platform
+func (c Config) IsValid() error {
+	return c.isValid(runtime.GOOS)
+}
+
+func (c Config) isValid(goos string) error {
+	if goos == "windows" {
+		if c.Module != "" || c.TokenLabel != "" || c.PIN != "" {
+			return fmt.Errorf("%w: TPM module, token label and PIN are not used on Windows", verror.UserDataError)
+		}
+		if c.AttestationKey != "" && c.AttestationField == "" {
+			return fmt.Errorf("%w: TPM attestation key requires an attestation field", verror.UserDataError)
+		}
+		return nil
+	}
+	if c.TokenLabel == "" {
+		return fmt.Errorf("%w: TPM token label is required", verror.UserDataError)
+	}
+	if c.PIN == "" {
+		return fmt.Errorf("%w: TPM PIN is required", verror.UserDataError)
+	}
+	if c.MachineKey {
+		return fmt.Errorf("%w: TPM machine keys are only supported on Windows", verror.UserDataError)
+	}
+	if c.AttestationField != "" || c.AttestationKey != "" {
+		return fmt.Errorf("%w: TPM key attestation is only supported on Windows", verror.UserDataError)
+	}
+	return nil
+}
+
+// GetModule returns the tpm2-pkcs11 library to load
+func (c Config) GetModule() string {
+	if c.Module == "" {
+		return DefaultModule
+	}
+	return c.Module
+}
+
+// GetAttestationKey returns the name of the attestation identity key signing the attestations
+func (c Config) GetAttestationKey() string {
+	if c.AttestationKey == "" {
+		return DefaultAttestationKey
+	}
+	return c.AttestationKey
+}
+
+// keyName returns the name of the keys of request: the key name of the config, or the common name of request
+func (c Config) keyName(request *certificate.Request) string {
+	if c.KeyName != "" {
+		return c.KeyName
+	}
+	return request.Subject.CommonName
+}
+
+// AddAttestation sets the attestation of key in the attestation field of the config, so TPP can verify the private
+// key of request was generated in the TPM. Does nothing when no attestation field is set
+func (p *KeyProvider) AddAttestation(request *certificate.Request, key crypto.Signer) error {
+	if p.config.AttestationField == "" {
+		return nil
+	}
+	attestation, err := p.Attest(key)
+	if err != nil {
+		return err
+	}
+	request.CustomFields = append(request.CustomFields, certificate.CustomField{
+		Name:  p.config.AttestationField,
+		Value: base64.StdEncoding.EncodeToString(attestation),
+	})
+	return nil
+}
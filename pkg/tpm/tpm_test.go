@@ -0,0 +1,53 @@
+/*
+ * Copyright 2023 Venafi, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *  http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package tpm
+
+import (
+	"testing"
+)
+
+func TestConfigIsValid(t *testing.T) {
+	cases := []struct {
+		name   string
+		goos   string
+		config Config
+		valid  bool
+	}{
+		{"Linux", "linux", Config{TokenLabel: "vcert", PIN: "1234"}, true},
+		{"LinuxModule", "linux", Config{Module: "/usr/lib/x86_64-linux-gnu/libtpm2_pkcs11.so.1", TokenLabel: "vcert", PIN: "1234"}, true},
+		{"LinuxNoToken", "linux", Config{PIN: "1234"}, false},
+		{"LinuxNoPIN", "linux", Config{TokenLabel: "vcert"}, false},
+		{"LinuxMachineKey", "linux", Config{TokenLabel: "vcert", PIN: "1234", MachineKey: true}, false},
+		{"LinuxAttestation", "linux", Config{TokenLabel: "vcert", PIN: "1234", AttestationField: "Key Attestation"}, false},
+		{"Windows", "windows", Config{}, true},
+		{"WindowsAttestation", "windows", Config{MachineKey: true, AttestationField: "Key Attestation"}, true},
+		{"WindowsPIN", "windows", Config{PIN: "1234"}, false},
+		{"WindowsAttestationKeyOnly", "windows", Config{AttestationKey: "Windows AIK"}, false},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			err := c.config.isValid(c.goos)
+			if c.valid && err != nil {
+				t.Fatalf("expected a valid config, got: %s", err)
+			}
+			if !c.valid && err == nil {
+				t.Fatalf("expected an invalid config")
+			}
+		})
+	}
+}
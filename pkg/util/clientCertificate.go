@@ -0,0 +1,60 @@
+/*
+ * Copyright 2023 Venafi, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *  http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package util
+
+import (
+	"crypto/tls"
+	"encoding/pem"
+	"fmt"
+	"os"
+
+	"golang.org/x/crypto/pkcs12"
+)
+
+// LoadClientCertificate returns the TLS client certificate read from the PEM files certFile and keyFile
+func LoadClientCertificate(certFile string, keyFile string) (*tls.Certificate, error) {
+	cert, err := tls.LoadX509KeyPair(certFile, keyFile)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load client certificate from %s and %s: %w", certFile, keyFile, err)
+	}
+	return &cert, nil
+}
+
+// LoadClientPKCS12 returns the TLS client certificate read from the PKCS#12 archive p12File.
+// The chain found in the archive, if any, is included in the certificate
+func LoadClientPKCS12(p12File string, password string) (*tls.Certificate, error) {
+	p12, err := os.ReadFile(p12File)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read PKCS#12 archive file: %w", err)
+	}
+
+	blocks, err := pkcs12.ToPEM(p12, password)
+	if err != nil {
+		return nil, fmt.Errorf("failed converting PKCS#12 archive file to PEM blocks: %w", err)
+	}
+
+	var pemData []byte
+	for _, b := range blocks {
+		pemData = append(pemData, pem.EncodeToMemory(b)...)
+	}
+
+	cert, err := tls.X509KeyPair(pemData, pemData)
+	if err != nil {
+		return nil, fmt.Errorf("failed reading PEM data to build X.509 certificate: %w", err)
+	}
+	return &cert, nil
+}
@@ -0,0 +1,46 @@
+//go:build !windows
+
+/*
+ * Copyright 2023 Venafi, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *  http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package util
+
+import (
+	"fmt"
+	"os"
+	"syscall"
+)
+
+// LockFile takes an exclusive lock on the file at path, waiting for any other process holding it. The lock is held on
+// a <path>.lock file next to it, so the file itself can be replaced while the lock is held. The returned function
+// releases the lock
+func LockFile(path string) (func() error, error) {
+	lockFile, err := os.OpenFile(path+".lock", os.O_CREATE|os.O_RDWR, 0600)
+	if err != nil {
+		return nil, fmt.Errorf("could not open lock file of %s: %w", path, err)
+	}
+
+	err = syscall.Flock(int(lockFile.Fd()), syscall.LOCK_EX)
+	if err != nil {
+		_ = lockFile.Close()
+		return nil, fmt.Errorf("could not lock %s: %w", path, err)
+	}
+
+	return func() error {
+		// Closing the lock file releases the lock
+		return lockFile.Close()
+	}, nil
+}
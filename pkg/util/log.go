@@ -16,20 +16,132 @@
 
 package util
 
-import "go.uber.org/zap"
+import (
+	"fmt"
+	"os"
+	"strings"
+
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+	"gopkg.in/natefinch/lumberjack.v2"
+)
+
+const (
+	// LogFormatConsole writes human-readable log lines. It is the default format
+	LogFormatConsole = "console"
+	// LogFormatJSON writes one JSON object per log entry, to be shipped to log aggregators
+	LogFormatJSON = "json"
+
+	// DefaultLogMaxSize is the size in megabytes a log file reaches before it gets rotated
+	DefaultLogMaxSize = 100
+)
+
+// LogOptions defines the format, level and destination of the logs
+type LogOptions struct {
+	// Format is either console or json. Defaults to console
+	Format string `yaml:"format,omitempty"`
+	// Level is one of debug, info, warn or error. Defaults to info
+	Level string `yaml:"level,omitempty"`
+	// File is the path of the file logs are written to instead of stderr
+	File string `yaml:"file,omitempty"`
+	// MaxSize is the size in megabytes File reaches before it gets rotated. Defaults to DefaultLogMaxSize
+	MaxSize int `yaml:"maxSize,omitempty"`
+	// MaxBackups is the number of rotated files kept. All of them are kept when 0
+	MaxBackups int `yaml:"maxBackups,omitempty"`
+	// MaxAge is the number of days rotated files are kept. They are not removed based on age when 0
+	MaxAge int `yaml:"maxAge,omitempty"`
+}
+
+// IsValid returns an error if the format or the level are not supported, or the rotation settings are negative
+func (o LogOptions) IsValid() error {
+	switch strings.ToLower(o.Format) {
+	case "", LogFormatConsole, LogFormatJSON:
+	default:
+		return fmt.Errorf("unsupported log format %q. Should be %s or %s", o.Format, LogFormatConsole, LogFormatJSON)
+	}
+
+	_, err := o.level()
+	if err != nil {
+		return err
+	}
+
+	if o.MaxSize < 0 || o.MaxBackups < 0 || o.MaxAge < 0 {
+		return fmt.Errorf("log file rotation settings cannot be negative")
+	}
+	return nil
+}
+
+func (o LogOptions) level() (zapcore.Level, error) {
+	if o.Level == "" {
+		return zapcore.InfoLevel, nil
+	}
+	var level zapcore.Level
+	err := level.UnmarshalText([]byte(strings.ToLower(o.Level)))
+	if err != nil {
+		return level, fmt.Errorf("unsupported log level %q. Should be debug, info, warn or error", o.Level)
+	}
+	return level, nil
+}
 
 // ConfigureLogger sets the default values for the cli logger
 func ConfigureLogger(debug bool) error {
-	zc := zap.NewDevelopmentConfig()
-	if !debug {
-		zc.Level = zap.NewAtomicLevelAt(zap.InfoLevel)
-		zc.DisableStacktrace = true
+	opts := LogOptions{}
+	if debug {
+		opts.Level = zapcore.DebugLevel.String()
 	}
-	l, err := zc.Build()
+	return ConfigureLoggerWithOptions(opts)
+}
+
+// ConfigureLoggerWithOptions replaces the global zap logger with one built from opts.
+// When the format is json or the logs go to a file, messages written with the standard library
+// log package are sent to the new logger as well, so every line has the same format and destination
+func ConfigureLoggerWithOptions(opts LogOptions) error {
+	err := opts.IsValid()
 	if err != nil {
 		return err
 	}
-	zap.ReplaceGlobals(l)
+	level, _ := opts.level()
+
+	var encoder zapcore.Encoder
+	if strings.ToLower(opts.Format) == LogFormatJSON {
+		ec := zap.NewProductionEncoderConfig()
+		ec.EncodeTime = zapcore.ISO8601TimeEncoder
+		encoder = zapcore.NewJSONEncoder(ec)
+	} else {
+		encoder = zapcore.NewConsoleEncoder(zap.NewDevelopmentEncoderConfig())
+	}
 
+	var sink zapcore.WriteSyncer = zapcore.Lock(os.Stderr)
+	if opts.File != "" {
+		maxSize := opts.MaxSize
+		if maxSize == 0 {
+			maxSize = DefaultLogMaxSize
+		}
+		sink = zapcore.AddSync(&lumberjack.Logger{
+			Filename:   opts.File,
+			MaxSize:    maxSize,
+			MaxBackups: opts.MaxBackups,
+			MaxAge:     opts.MaxAge,
+		})
+	}
+
+	options := []zap.Option{zap.AddCaller()}
+	if level == zapcore.DebugLevel {
+		options = append(options, zap.Development(), zap.AddStacktrace(zapcore.WarnLevel))
+	}
+	l := zap.New(zapcore.NewCore(encoder, sink, level), options...)
+
+	zap.ReplaceGlobals(l)
+	if opts.RedirectsStdLog() {
+		_, err = zap.RedirectStdLogAt(l, zapcore.InfoLevel)
+		if err != nil {
+			return err
+		}
+	}
 	return nil
 }
+
+// RedirectsStdLog returns true when the standard library log output is sent to the zap logger
+func (o LogOptions) RedirectsStdLog() bool {
+	return strings.ToLower(o.Format) == LogFormatJSON || o.File != ""
+}
@@ -0,0 +1,87 @@
+/*
+ * Copyright 2023 Venafi, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *  http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package util
+
+import (
+	"encoding/json"
+	"log"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"go.uber.org/zap"
+)
+
+func TestLogOptionsIsValid(t *testing.T) {
+	valid := []LogOptions{
+		{},
+		{Format: "JSON", Level: "warn"},
+		{Format: "console", Level: "debug", File: "vcert.log", MaxSize: 10, MaxBackups: 3, MaxAge: 7},
+	}
+	for _, opts := range valid {
+		if err := opts.IsValid(); err != nil {
+			t.Fatalf("expected %+v to be valid: %s", opts, err)
+		}
+	}
+
+	invalid := []LogOptions{
+		{Format: "xml"},
+		{Level: "verbose"},
+		{File: "vcert.log", MaxBackups: -1},
+	}
+	for _, opts := range invalid {
+		if err := opts.IsValid(); err == nil {
+			t.Fatalf("expected %+v to be invalid", opts)
+		}
+	}
+}
+
+func TestConfigureLoggerWithOptionsJSONFile(t *testing.T) {
+	file := filepath.Join(t.TempDir(), "vcert.log")
+	defer func() {
+		_ = ConfigureLogger(false)
+		log.SetOutput(os.Stderr)
+	}()
+
+	err := ConfigureLoggerWithOptions(LogOptions{Format: LogFormatJSON, Level: "warn", File: file})
+	if err != nil {
+		t.Fatalf("failed to configure logger: %s", err)
+	}
+	zap.L().Info("filtered out")
+	zap.L().Warn("certificate expiring", zap.String("task", "web"))
+	log.Printf("from the standard logger")
+	_ = zap.L().Sync()
+
+	data, err := os.ReadFile(file)
+	if err != nil {
+		t.Fatalf("failed to read log file: %s", err)
+	}
+	lines := strings.Split(strings.TrimSpace(string(data)), "\n")
+	if len(lines) != 1 {
+		t.Fatalf("expected 1 log line, got %d:\n%s", len(lines), data)
+	}
+
+	var entry map[string]interface{}
+	err = json.Unmarshal([]byte(lines[0]), &entry)
+	if err != nil {
+		t.Fatalf("log line is not JSON: %s", lines[0])
+	}
+	if entry["msg"] != "certificate expiring" || entry["task"] != "web" || entry["level"] != "warn" {
+		t.Fatalf("unexpected log entry: %v", entry)
+	}
+}
@@ -0,0 +1,267 @@
+package util
+
+import (
+	"crypto"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha512"
+	"encoding/binary"
+	"encoding/pem"
+	"fmt"
+	"math/big"
+	"strings"
+
+	"github.com/youmark/pkcs8"
+	"golang.org/x/crypto/blowfish"
+	"golang.org/x/crypto/ssh"
+)
+
+const (
+	// OpenSSHFormat writes SSH private keys in the format of ssh-keygen, the one expected by ssh and ssh-agent
+	OpenSSHFormat      = "openssh"
+	OpenSSHPrivKeyType = "OPENSSH PRIVATE KEY"
+
+	opensshMagic      = "openssh-key-v1\x00"
+	opensshCipher     = "aes256-ctr"
+	opensshKdf        = "bcrypt"
+	opensshKdfRounds  = 16
+	opensshSaltLength = 16
+)
+
+// opensshKey is the envelope of an OpenSSH private key, as described in PROTOCOL.key of OpenSSH
+type opensshKey struct {
+	CipherName   string
+	KdfName      string
+	KdfOpts      string
+	NumKeys      uint32
+	PubKey       []byte
+	PrivKeyBlock []byte
+}
+
+type opensshKdfOptions struct {
+	Salt   []byte
+	Rounds uint32
+}
+
+type opensshRSAKey struct {
+	N       *big.Int
+	E       *big.Int
+	D       *big.Int
+	Iqmp    *big.Int
+	P       *big.Int
+	Q       *big.Int
+	Comment string
+}
+
+type opensshECDSAKey struct {
+	Curve   string
+	Pub     []byte
+	D       *big.Int
+	Comment string
+}
+
+type opensshEd25519Key struct {
+	Pub     []byte
+	Priv    []byte
+	Comment string
+}
+
+// MarshalOpenSSHPrivateKey returns key, an RSA, ECDSA or Ed25519 private key, PEM encoded in the OpenSSH format.
+// The key is encrypted with aes256-ctr and a bcrypt derived key when passphrase is not empty, as ssh-keygen does
+func MarshalOpenSSHPrivateKey(key crypto.PrivateKey, comment, passphrase string) ([]byte, error) {
+	if k, ok := key.(*ed25519.PrivateKey); ok {
+		key = *k
+	}
+	signer, ok := key.(crypto.Signer)
+	if !ok {
+		return nil, fmt.Errorf("unsupported private key type %T", key)
+	}
+	pubKey, err := ssh.NewPublicKey(signer.Public())
+	if err != nil {
+		return nil, err
+	}
+
+	var keyFields []byte
+	switch k := key.(type) {
+	case *rsa.PrivateKey:
+		if len(k.Primes) != 2 {
+			return nil, fmt.Errorf("RSA keys with %d primes cannot be written in the OpenSSH format", len(k.Primes))
+		}
+		k.Precompute()
+		keyFields = ssh.Marshal(opensshRSAKey{
+			N:       k.N,
+			E:       big.NewInt(int64(k.E)),
+			D:       k.D,
+			Iqmp:    k.Precomputed.Qinv,
+			P:       k.Primes[0],
+			Q:       k.Primes[1],
+			Comment: comment,
+		})
+	case *ecdsa.PrivateKey:
+		curve, err := opensshCurveName(k.Curve)
+		if err != nil {
+			return nil, err
+		}
+		keyFields = ssh.Marshal(opensshECDSAKey{
+			Curve:   curve,
+			Pub:     elliptic.Marshal(k.Curve, k.X, k.Y), //nolint:staticcheck // the uncompressed point is the OpenSSH encoding
+			D:       k.D,
+			Comment: comment,
+		})
+	case ed25519.PrivateKey:
+		keyFields = ssh.Marshal(opensshEd25519Key{
+			Pub:     []byte(k.Public().(ed25519.PublicKey)),
+			Priv:    []byte(k),
+			Comment: comment,
+		})
+	default:
+		return nil, fmt.Errorf("unsupported private key type %T", key)
+	}
+
+	// Both check integers are equal, so that a wrong passphrase is detected when decrypting
+	check := make([]byte, 4)
+	_, err = rand.Read(check)
+	if err != nil {
+		return nil, err
+	}
+	block := append(append(append([]byte{}, check...), check...), ssh.Marshal(struct{ KeyType string }{pubKey.Type()})...)
+	block = append(block, keyFields...)
+
+	envelope := opensshKey{
+		CipherName: "none",
+		KdfName:    "none",
+		NumKeys:    1,
+		PubKey:     pubKey.Marshal(),
+	}
+	blockSize := 8
+	if passphrase != "" {
+		blockSize = aes.BlockSize
+	}
+	for i := 1; len(block)%blockSize != 0; i++ {
+		block = append(block, byte(i))
+	}
+
+	if passphrase != "" {
+		salt := make([]byte, opensshSaltLength)
+		_, err = rand.Read(salt)
+		if err != nil {
+			return nil, err
+		}
+		derived := bcryptPbkdf([]byte(passphrase), salt, opensshKdfRounds, 32+aes.BlockSize)
+		c, err := aes.NewCipher(derived[:32])
+		if err != nil {
+			return nil, err
+		}
+		cipher.NewCTR(c, derived[32:]).XORKeyStream(block, block)
+		envelope.CipherName = opensshCipher
+		envelope.KdfName = opensshKdf
+		envelope.KdfOpts = string(ssh.Marshal(opensshKdfOptions{Salt: salt, Rounds: opensshKdfRounds}))
+	}
+	envelope.PrivKeyBlock = block
+
+	return pem.EncodeToMemory(&pem.Block{
+		Type:  OpenSSHPrivKeyType,
+		Bytes: append([]byte(opensshMagic), ssh.Marshal(envelope)...),
+	}), nil
+}
+
+// ConvertToOpenSSHPrivateKey returns the PEM encoded private key, in the PKCS#1, PKCS#8, SEC 1 or OpenSSH formats,
+// encrypted with passphrase when it is not empty, in the OpenSSH format and encrypted with the same passphrase
+func ConvertToOpenSSHPrivateKey(privateKey []byte, comment, passphrase string) ([]byte, error) {
+	block, _ := pem.Decode([]byte(strings.ReplaceAll(string(privateKey), "\r\n", "\n")))
+	if block == nil {
+		return nil, fmt.Errorf("private key is not PEM encoded")
+	}
+
+	var key interface{}
+	var err error
+	switch {
+	case block.Type == OpenSSHPrivKeyType:
+		return pem.EncodeToMemory(block), nil
+	case block.Type == "ENCRYPTED PRIVATE KEY":
+		key, _, err = pkcs8.ParsePrivateKey(block.Bytes, []byte(passphrase))
+	case passphrase != "":
+		key, err = ssh.ParseRawPrivateKeyWithPassphrase(pem.EncodeToMemory(block), []byte(passphrase))
+	default:
+		key, err = ssh.ParseRawPrivateKey(pem.EncodeToMemory(block))
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse private key: %w", err)
+	}
+	return MarshalOpenSSHPrivateKey(key, comment, passphrase)
+}
+
+func opensshCurveName(curve elliptic.Curve) (string, error) {
+	switch curve {
+	case elliptic.P256():
+		return "nistp256", nil
+	case elliptic.P384():
+		return "nistp384", nil
+	case elliptic.P521():
+		return "nistp521", nil
+	}
+	return "", fmt.Errorf("elliptic curve %s is not supported by OpenSSH", curve.Params().Name)
+}
+
+// bcryptPbkdf derives a key of keyLen bytes from password and salt with bcrypt_pbkdf, the key derivation function
+// OpenSSH uses to encrypt private keys
+func bcryptPbkdf(password, salt []byte, rounds, keyLen int) []byte {
+	const blockSize = 32
+	numBlocks := (keyLen + blockSize - 1) / blockSize
+	key := make([]byte, numBlocks*blockSize)
+
+	h := sha512.New()
+	h.Write(password)
+	shaPass := h.Sum(nil)
+
+	shaSalt := make([]byte, 0, sha512.Size)
+	counter := make([]byte, 4)
+	tmp := make([]byte, blockSize)
+	for block := 1; block <= numBlocks; block++ {
+		h.Reset()
+		h.Write(salt)
+		binary.BigEndian.PutUint32(counter, uint32(block))
+		h.Write(counter)
+		bcryptHash(tmp, shaPass, h.Sum(shaSalt))
+
+		out := make([]byte, blockSize)
+		copy(out, tmp)
+		for i := 2; i <= rounds; i++ {
+			h.Reset()
+			h.Write(tmp)
+			bcryptHash(tmp, shaPass, h.Sum(shaSalt))
+			for j := range out {
+				out[j] ^= tmp[j]
+			}
+		}
+
+		// The output blocks are interleaved
+		for i, v := range out {
+			key[i*numBlocks+(block-1)] = v
+		}
+	}
+	return key[:keyLen]
+}
+
+func bcryptHash(out, shaPass, shaSalt []byte) {
+	c, _ := blowfish.NewSaltedCipher(shaPass, shaSalt)
+	for i := 0; i < 64; i++ {
+		blowfish.ExpandKey(shaSalt, c)
+		blowfish.ExpandKey(shaPass, c)
+	}
+	copy(out, "OxychromaticBlowfishSwatDynamite")
+	for i := 0; i < len(out); i += 8 {
+		for j := 0; j < 64; j++ {
+			c.Encrypt(out[i:i+8], out[i:i+8])
+		}
+	}
+	// Blowfish words are big-endian, bcrypt_pbkdf output is little-endian
+	for i := 0; i < len(out); i += 4 {
+		out[i], out[i+1], out[i+2], out[i+3] = out[i+3], out[i+2], out[i+1], out[i]
+	}
+}
@@ -0,0 +1,94 @@
+/*
+ * Copyright 2023 Venafi, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *  http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package util
+
+import (
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"golang.org/x/net/http/httpproxy"
+)
+
+// ProxyConfig defines the proxy the requests to a Venafi platform are sent through. The zero value uses the proxy
+// defined by the HTTP_PROXY, HTTPS_PROXY and NO_PROXY environment variables
+type ProxyConfig struct {
+	// URL of the proxy, i.e. http://proxy.example.com:3128 or socks5://jumphost.example.com:1080.
+	// The http, https and socks5 schemes are supported
+	URL string `yaml:"url,omitempty"`
+	// User and Password authenticate to the proxy. Optional
+	User     string `yaml:"user,omitempty"`
+	Password string `yaml:"password,omitempty"`
+	// NoProxy lists the hosts reached without the proxy, with the syntax of the NO_PROXY environment variable,
+	// i.e. example.com, .example.com, 10.0.0.0/8 or localhost:8443
+	NoProxy []string `yaml:"noProxy,omitempty"`
+}
+
+// IsValid returns an error if the URL of the proxy cannot be used
+func (p ProxyConfig) IsValid() error {
+	if p.URL == "" {
+		if p.User != "" || p.Password != "" || len(p.NoProxy) > 0 {
+			return fmt.Errorf("proxy url is required to set the proxy credentials or noProxy")
+		}
+		return nil
+	}
+	_, err := p.proxyURL()
+	return err
+}
+
+func (p ProxyConfig) proxyURL() (*url.URL, error) {
+	u, err := url.Parse(p.URL)
+	if err != nil {
+		return nil, fmt.Errorf("invalid proxy url %s: %w", p.URL, err)
+	}
+	switch strings.ToLower(u.Scheme) {
+	case "http", "https", "socks5":
+	default:
+		return nil, fmt.Errorf("invalid proxy url %s: scheme must be http, https or socks5", p.URL)
+	}
+	if u.Host == "" {
+		return nil, fmt.Errorf("invalid proxy url %s: host is required", p.URL)
+	}
+	if p.User != "" {
+		u.User = url.UserPassword(p.User, p.Password)
+	}
+	return u, nil
+}
+
+// ProxyFunc returns the function selecting the proxy of a request, to be set as http.Transport.Proxy.
+// It is http.ProxyFromEnvironment when no URL is set
+func (p ProxyConfig) ProxyFunc() func(*http.Request) (*url.URL, error) {
+	if p.URL == "" {
+		return http.ProxyFromEnvironment
+	}
+
+	u, err := p.proxyURL()
+	if err != nil {
+		return func(*http.Request) (*url.URL, error) {
+			return nil, err
+		}
+	}
+	proxyFunc := (&httpproxy.Config{
+		HTTPProxy:  u.String(),
+		HTTPSProxy: u.String(),
+		NoProxy:    strings.Join(p.NoProxy, ","),
+	}).ProxyFunc()
+	return func(r *http.Request) (*url.URL, error) {
+		return proxyFunc(r.URL)
+	}
+}
@@ -0,0 +1,246 @@
+/*
+ * Copyright 2023 Venafi, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *  http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package util
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"math/rand"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	// DefaultRetryMaxRetries is the number of times a request is retried when RetryPolicy.MaxRetries is not set
+	DefaultRetryMaxRetries = 3
+	// DefaultRetryBackoff is the delay before the first retry when RetryPolicy.Backoff is not set
+	DefaultRetryBackoff = time.Second
+	// DefaultRetryMaxBackoff caps the delay between two attempts when RetryPolicy.MaxBackoff is not set
+	DefaultRetryMaxBackoff = 30 * time.Second
+	// DefaultRetryMaxRetryAfter is the longest Retry-After honored when RetryPolicy.MaxRetryAfter is not set
+	DefaultRetryMaxRetryAfter = 2 * time.Minute
+)
+
+// RetryPolicy defines how a RetryTransport retries requests. The zero value uses the defaults
+type RetryPolicy struct {
+	// MaxRetries is the number of times a request is retried. Defaults to DefaultRetryMaxRetries.
+	// Retries are disabled when it is negative
+	MaxRetries int `yaml:"maxRetries,omitempty"`
+	// Backoff is the delay before the first retry, doubled on every retry. Defaults to DefaultRetryBackoff
+	Backoff time.Duration `yaml:"backoff,omitempty"`
+	// MaxBackoff caps the delay between two attempts. Defaults to DefaultRetryMaxBackoff
+	MaxBackoff time.Duration `yaml:"maxBackoff,omitempty"`
+	// MaxRetryAfter is the longest Retry-After header honored. The response is returned as is when the server
+	// asks to wait longer. Defaults to DefaultRetryMaxRetryAfter
+	MaxRetryAfter time.Duration `yaml:"maxRetryAfter,omitempty"`
+}
+
+// IsValid returns an error if any of the delays is negative
+func (p RetryPolicy) IsValid() error {
+	if p.Backoff < 0 || p.MaxBackoff < 0 || p.MaxRetryAfter < 0 {
+		return fmt.Errorf("retry delays cannot be negative")
+	}
+	return nil
+}
+
+func (p RetryPolicy) maxRetries() int {
+	if p.MaxRetries == 0 {
+		return DefaultRetryMaxRetries
+	}
+	if p.MaxRetries < 0 {
+		return 0
+	}
+	return p.MaxRetries
+}
+
+func (p RetryPolicy) maxRetryAfter() time.Duration {
+	if p.MaxRetryAfter == 0 {
+		return DefaultRetryMaxRetryAfter
+	}
+	return p.MaxRetryAfter
+}
+
+// backoff returns Backoff * 2^attempt, capped to MaxBackoff, plus a random jitter of up to half that delay
+func (p RetryPolicy) backoff(attempt int) time.Duration {
+	delay, maxDelay := p.Backoff, p.MaxBackoff
+	if delay == 0 {
+		delay = DefaultRetryBackoff
+	}
+	if maxDelay == 0 {
+		maxDelay = DefaultRetryMaxBackoff
+	}
+	for i := 0; i < attempt && delay < maxDelay; i++ {
+		delay *= 2
+	}
+	if delay > maxDelay {
+		delay = maxDelay
+	}
+	if half := int64(delay / 2); half > 0 {
+		delay += time.Duration(rand.Int63n(half)) // #nosec G404 -- jitter does not need a secure source
+	}
+	return delay
+}
+
+// RetryTransport is an http.RoundTripper retrying the requests rate limited by the server (429), after the delay
+// of its Retry-After header when set. Idempotent requests are also retried when they fail with a network error,
+// or a 502 or 503 status
+type RetryTransport struct {
+	// Base performs the requests. Defaults to http.DefaultTransport
+	Base   http.RoundTripper
+	Policy RetryPolicy
+	// Timeout limits every attempt, including the time spent reading the response body. Unlimited when 0
+	Timeout time.Duration
+}
+
+// RoundTrip implements http.RoundTripper
+func (t *RetryTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	base := t.Base
+	if base == nil {
+		base = http.DefaultTransport
+	}
+	maxRetries := t.Policy.maxRetries()
+
+	for attempt := 0; ; attempt++ {
+		attemptReq := req
+		if attempt > 0 && req.Body != nil && req.Body != http.NoBody {
+			body, err := req.GetBody()
+			if err != nil {
+				return nil, err
+			}
+			attemptReq = req.Clone(req.Context())
+			attemptReq.Body = body
+		}
+
+		res, err := t.roundTrip(base, attemptReq)
+		if attempt >= maxRetries || !isReplayable(req) {
+			return res, err
+		}
+		delay, retry := t.retryDelay(req, res, err, attempt)
+		if !retry {
+			return res, err
+		}
+		if res != nil {
+			// Drain the body so the connection can be reused
+			_, _ = io.Copy(io.Discard, res.Body)
+			_ = res.Body.Close()
+		}
+
+		timer := time.NewTimer(delay)
+		select {
+		case <-req.Context().Done():
+			timer.Stop()
+			return nil, req.Context().Err()
+		case <-timer.C:
+		}
+	}
+}
+
+// roundTrip performs a single attempt, bounded by the Timeout of the transport
+func (t *RetryTransport) roundTrip(base http.RoundTripper, req *http.Request) (*http.Response, error) {
+	if t.Timeout <= 0 {
+		return base.RoundTrip(req)
+	}
+	ctx, cancel := context.WithTimeout(req.Context(), t.Timeout)
+	res, err := base.RoundTrip(req.WithContext(ctx))
+	if err != nil {
+		cancel()
+		return nil, err
+	}
+	res.Body = &cancelOnClose{ReadCloser: res.Body, cancel: cancel}
+	return res, nil
+}
+
+// retryDelay returns how long to wait before retrying the request, and whether it should be retried at all
+func (t *RetryTransport) retryDelay(req *http.Request, res *http.Response, err error, attempt int) (time.Duration, bool) {
+	if err != nil {
+		// The caller gave up on the request
+		if req.Context().Err() != nil || !isIdempotent(req.Method) {
+			return 0, false
+		}
+		return t.Policy.backoff(attempt), true
+	}
+
+	switch res.StatusCode {
+	case http.StatusTooManyRequests:
+		// The server did not process the request, whatever its method
+	case http.StatusBadGateway, http.StatusServiceUnavailable:
+		if !isIdempotent(req.Method) {
+			return 0, false
+		}
+	default:
+		return 0, false
+	}
+
+	if after, ok := parseRetryAfter(res.Header.Get("Retry-After"), time.Now()); ok {
+		if after > t.Policy.maxRetryAfter() {
+			return 0, false
+		}
+		return after, true
+	}
+	return t.Policy.backoff(attempt), true
+}
+
+// parseRetryAfter parses the value of a Retry-After header, either a number of seconds or an HTTP date
+func parseRetryAfter(value string, now time.Time) (time.Duration, bool) {
+	value = strings.TrimSpace(value)
+	if value == "" {
+		return 0, false
+	}
+	if seconds, err := strconv.Atoi(value); err == nil {
+		if seconds < 0 {
+			return 0, false
+		}
+		return time.Duration(seconds) * time.Second, true
+	}
+	date, err := http.ParseTime(value)
+	if err != nil {
+		return 0, false
+	}
+	if after := date.Sub(now); after > 0 {
+		return after, true
+	}
+	return 0, true
+}
+
+func isIdempotent(method string) bool {
+	switch method {
+	case http.MethodGet, http.MethodHead, http.MethodOptions, http.MethodTrace, http.MethodPut, http.MethodDelete:
+		return true
+	default:
+		return false
+	}
+}
+
+// isReplayable returns true when the body of the request can be sent again
+func isReplayable(req *http.Request) bool {
+	return req.Body == nil || req.Body == http.NoBody || req.GetBody != nil
+}
+
+// cancelOnClose releases the context of an attempt once its response body is closed
+type cancelOnClose struct {
+	io.ReadCloser
+	cancel context.CancelFunc
+}
+
+func (c *cancelOnClose) Close() error {
+	err := c.ReadCloser.Close()
+	c.cancel()
+	return err
+}
@@ -0,0 +1,185 @@
+/*
+ * Copyright 2023 Venafi, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *  http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package util
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"gopkg.in/yaml.v3"
+)
+
+// newRetryServer answers every request with the status returned by handle for the attempt number, starting at 1
+func newRetryServer(t *testing.T, handle func(attempt int32, w http.ResponseWriter, r *http.Request) int) (*httptest.Server, *int32) {
+	t.Helper()
+	var attempts int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		status := handle(atomic.AddInt32(&attempts, 1), w, r)
+		w.WriteHeader(status)
+	}))
+	t.Cleanup(server.Close)
+	return server, &attempts
+}
+
+func newRetryClient(policy RetryPolicy, timeout time.Duration) *http.Client {
+	return &http.Client{Transport: &RetryTransport{Policy: policy, Timeout: timeout}}
+}
+
+func TestRetryTransportRateLimited(t *testing.T) {
+	server, attempts := newRetryServer(t, func(attempt int32, w http.ResponseWriter, r *http.Request) int {
+		body, _ := io.ReadAll(r.Body)
+		if string(body) != `{"name":"vcert"}` {
+			t.Errorf("unexpected body %q on attempt %d", body, attempt)
+		}
+		if attempt < 3 {
+			w.Header().Set("Retry-After", "0")
+			return http.StatusTooManyRequests
+		}
+		return http.StatusOK
+	})
+
+	// Rate limited requests are retried whatever their method, with the same body
+	client := newRetryClient(RetryPolicy{Backoff: time.Millisecond}, 0)
+	res, err := client.Post(server.URL, "application/json", strings.NewReader(`{"name":"vcert"}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	_ = res.Body.Close()
+	if res.StatusCode != http.StatusOK || *attempts != 3 {
+		t.Fatalf("expected 200 after 3 attempts, got %d after %d", res.StatusCode, *attempts)
+	}
+}
+
+func TestRetryTransportUnavailable(t *testing.T) {
+	server, attempts := newRetryServer(t, func(attempt int32, w http.ResponseWriter, r *http.Request) int {
+		return http.StatusServiceUnavailable
+	})
+	client := newRetryClient(RetryPolicy{MaxRetries: 2, Backoff: time.Millisecond}, 0)
+
+	res, err := client.Get(server.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_ = res.Body.Close()
+	if res.StatusCode != http.StatusServiceUnavailable || *attempts != 3 {
+		t.Fatalf("expected 503 after 3 attempts, got %d after %d", res.StatusCode, *attempts)
+	}
+
+	// POST requests are not idempotent, the server may have processed them
+	atomic.StoreInt32(attempts, 0)
+	res, err = client.Post(server.URL, "application/json", strings.NewReader("{}"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	_ = res.Body.Close()
+	if *attempts != 1 {
+		t.Fatalf("expected a single attempt for POST, got %d", *attempts)
+	}
+
+	// Retries are disabled with a negative MaxRetries
+	atomic.StoreInt32(attempts, 0)
+	res, err = newRetryClient(RetryPolicy{MaxRetries: -1}, 0).Get(server.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_ = res.Body.Close()
+	if *attempts != 1 {
+		t.Fatalf("expected a single attempt when retries are disabled, got %d", *attempts)
+	}
+}
+
+func TestRetryTransportRetryAfterTooLong(t *testing.T) {
+	server, attempts := newRetryServer(t, func(attempt int32, w http.ResponseWriter, r *http.Request) int {
+		w.Header().Set("Retry-After", "3600")
+		return http.StatusTooManyRequests
+	})
+
+	res, err := newRetryClient(RetryPolicy{}, 0).Get(server.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_ = res.Body.Close()
+	if res.StatusCode != http.StatusTooManyRequests || *attempts != 1 {
+		t.Fatalf("expected 429 after a single attempt, got %d after %d", res.StatusCode, *attempts)
+	}
+}
+
+func TestRetryTransportTimeout(t *testing.T) {
+	server, attempts := newRetryServer(t, func(attempt int32, w http.ResponseWriter, r *http.Request) int {
+		if attempt == 1 {
+			select {
+			case <-r.Context().Done():
+			case <-time.After(time.Second):
+			}
+		}
+		return http.StatusOK
+	})
+
+	// The first attempt times out, the second one succeeds
+	res, err := newRetryClient(RetryPolicy{Backoff: time.Millisecond}, 100*time.Millisecond).Get(server.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_ = res.Body.Close()
+	if res.StatusCode != http.StatusOK || *attempts != 2 {
+		t.Fatalf("expected 200 after 2 attempts, got %d after %d", res.StatusCode, *attempts)
+	}
+}
+
+func TestParseRetryAfter(t *testing.T) {
+	now := time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+	cases := []struct {
+		value    string
+		expected time.Duration
+		ok       bool
+	}{
+		{"120", 2 * time.Minute, true},
+		{"Mon, 01 Jan 2024 12:00:30 GMT", 30 * time.Second, true},
+		{"Mon, 01 Jan 2024 11:00:00 GMT", 0, true},
+		{"", 0, false},
+		{"-1", 0, false},
+		{"soon", 0, false},
+	}
+	for _, c := range cases {
+		after, ok := parseRetryAfter(c.value, now)
+		if after != c.expected || ok != c.ok {
+			t.Errorf("expected %s, %t for %q, got %s, %t", c.expected, c.ok, c.value, after, ok)
+		}
+	}
+}
+
+func TestRetryPolicyYAML(t *testing.T) {
+	var policy RetryPolicy
+	err := yaml.Unmarshal([]byte("maxRetries: 5\nbackoff: 2s\nmaxBackoff: 1m\n"), &policy)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if policy.MaxRetries != 5 || policy.Backoff != 2*time.Second || policy.MaxBackoff != time.Minute {
+		t.Fatalf("unexpected policy %+v", policy)
+	}
+	if err = policy.IsValid(); err != nil {
+		t.Fatal(err)
+	}
+	if err = (RetryPolicy{MaxRetryAfter: -time.Second}).IsValid(); err == nil {
+		t.Fatal("expected an error for a negative delay")
+	}
+}
@@ -115,7 +115,12 @@ func GenerateSshKeyPair(bitSize int, keyPassword, certId string, format ...strin
 		return nil, nil, err
 	}
 
-	privateKeyBytes, err := encodePrivKeyToPEM(privateKey, keyPassword, currentFormat)
+	var privateKeyBytes []byte
+	if currentFormat == OpenSSHFormat {
+		privateKeyBytes, err = MarshalOpenSSHPrivateKey(privateKey, certId, keyPassword)
+	} else {
+		privateKeyBytes, err = encodePrivKeyToPEM(privateKey, keyPassword, currentFormat)
+	}
 
 	if err != nil {
 		return nil, nil, err
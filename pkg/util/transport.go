@@ -0,0 +1,203 @@
+/*
+ * Copyright 2023 Venafi, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *  http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package util
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"net"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	// DefaultTransportMaxIdleConns is the number of idle connections kept to all hosts when
+	// TransportConfig.MaxIdleConns is not set
+	DefaultTransportMaxIdleConns = 100
+	// DefaultTransportMaxIdleConnsPerHost is the number of idle connections kept to every host when
+	// TransportConfig.MaxIdleConnsPerHost is not set. The one of net/http, 2, makes concurrent requests to a Venafi
+	// platform open new connections
+	DefaultTransportMaxIdleConnsPerHost = 16
+	// DefaultTransportIdleConnTimeout is how long an idle connection is kept when TransportConfig.IdleConnTimeout is not set
+	DefaultTransportIdleConnTimeout = 90 * time.Second
+	// DefaultTransportTLSSessionCacheSize is the number of TLS sessions kept for resumption when
+	// TransportConfig.TLSSessionCacheSize is not set
+	DefaultTransportTLSSessionCacheSize = 64
+)
+
+// TransportConfig tunes the HTTP transport the connectors send their requests with. The zero value uses the defaults
+type TransportConfig struct {
+	// MaxIdleConns is the number of idle connections kept to all hosts. Defaults to DefaultTransportMaxIdleConns
+	MaxIdleConns int `yaml:"maxIdleConns,omitempty"`
+	// MaxIdleConnsPerHost is the number of idle connections kept to every host. Defaults to
+	// DefaultTransportMaxIdleConnsPerHost
+	MaxIdleConnsPerHost int `yaml:"maxIdleConnsPerHost,omitempty"`
+	// MaxConnsPerHost limits the connections to every host, including those in use. Unlimited when not set
+	MaxConnsPerHost int `yaml:"maxConnsPerHost,omitempty"`
+	// IdleConnTimeout is how long an idle connection is kept. Defaults to DefaultTransportIdleConnTimeout
+	IdleConnTimeout time.Duration `yaml:"idleConnTimeout,omitempty"`
+	// TLSSessionCacheSize is the number of TLS sessions kept to resume them on new connections, saving full
+	// handshakes. Defaults to DefaultTransportTLSSessionCacheSize. Session resumption is disabled when it is negative
+	TLSSessionCacheSize int `yaml:"tlsSessionCacheSize,omitempty"`
+	// DisableHTTP2 sends the requests with HTTP/1.1 even if the server supports HTTP/2
+	DisableHTTP2 bool `yaml:"disableHTTP2,omitempty"`
+	// DisableKeepAlives opens a new connection for every request
+	DisableKeepAlives bool `yaml:"disableKeepAlives,omitempty"`
+}
+
+// IsValid returns an error if any of the limits or the timeout is negative
+func (t TransportConfig) IsValid() error {
+	if t.MaxIdleConns < 0 || t.MaxIdleConnsPerHost < 0 || t.MaxConnsPerHost < 0 {
+		return fmt.Errorf("transport connection limits cannot be negative")
+	}
+	if t.IdleConnTimeout < 0 {
+		return fmt.Errorf("transport idleConnTimeout cannot be negative")
+	}
+	return nil
+}
+
+func (t TransportConfig) maxIdleConns() int {
+	if t.MaxIdleConns == 0 {
+		return DefaultTransportMaxIdleConns
+	}
+	return t.MaxIdleConns
+}
+
+func (t TransportConfig) maxIdleConnsPerHost() int {
+	if t.MaxIdleConnsPerHost == 0 {
+		return DefaultTransportMaxIdleConnsPerHost
+	}
+	return t.MaxIdleConnsPerHost
+}
+
+func (t TransportConfig) idleConnTimeout() time.Duration {
+	if t.IdleConnTimeout == 0 {
+		return DefaultTransportIdleConnTimeout
+	}
+	return t.IdleConnTimeout
+}
+
+// sharedTransport is a transport returned by NewTransport, along with the settings it was built with
+type sharedTransport struct {
+	config    TransportConfig
+	proxy     string
+	trust     *x509.CertPool
+	base      *tls.Config
+	transport *http.Transport
+}
+
+var (
+	sharedTransportsMu sync.Mutex
+	sharedTransports   []sharedTransport
+)
+
+// NewTransport returns the transport of the requests to a Venafi platform, tuned with config, sent through proxy
+// and trusting the certificates of trust, or the ones of the system when it is nil. The TLS settings of
+// http.DefaultTransport, like the client certificate, are copied. Replace them, rather than changing them, for
+// the transports returned afterwards to use the new settings.
+//
+// Transports are shared: connectors built with the same settings get the same transport, so that the connectors
+// created for every request reuse the connections and TLS sessions of the previous ones instead of opening new ones
+func NewTransport(config TransportConfig, proxy ProxyConfig, trust *x509.CertPool) *http.Transport {
+	return NewTransportWithTLSConfig(config, proxy, trust, nil)
+}
+
+// NewTransportWithTLSConfig returns the transport NewTransport does, with the TLS settings of base instead of the
+// ones of http.DefaultTransport, unless base is nil. Transports are shared by the connectors given the same base,
+// so base must not be changed once used
+func NewTransportWithTLSConfig(config TransportConfig, proxy ProxyConfig, trust *x509.CertPool, base *tls.Config) *http.Transport {
+	if base == nil {
+		base = http.DefaultTransport.(*http.Transport).TLSClientConfig
+	}
+	proxyKey := strings.Join([]string{proxy.URL, proxy.User, proxy.Password, strings.Join(proxy.NoProxy, ",")}, "\x00")
+
+	sharedTransportsMu.Lock()
+	defer sharedTransportsMu.Unlock()
+	for _, s := range sharedTransports {
+		if s.config == config && s.proxy == proxyKey && s.base == base && certPoolsEqual(s.trust, trust) {
+			return s.transport
+		}
+	}
+
+	transport := newTransport(config, proxy, trust, base)
+	sharedTransports = append(sharedTransports, sharedTransport{
+		config:    config,
+		proxy:     proxyKey,
+		trust:     trust,
+		base:      base,
+		transport: transport,
+	})
+	return transport
+}
+
+// ResetTransports discards the transports shared by NewTransport, closing their idle connections. The transports
+// returned afterwards use the TLS settings http.DefaultTransport has at that time
+func ResetTransports() {
+	sharedTransportsMu.Lock()
+	defer sharedTransportsMu.Unlock()
+	for _, s := range sharedTransports {
+		s.transport.CloseIdleConnections()
+	}
+	sharedTransports = nil
+}
+
+func newTransport(config TransportConfig, proxy ProxyConfig, trust *x509.CertPool, base *tls.Config) *http.Transport {
+	var tlsConfig *tls.Config
+	if base == nil {
+		tlsConfig = &tls.Config{} // #nosec G402 -- the minimum TLS version of crypto/tls is used
+	} else {
+		tlsConfig = base.Clone()
+	}
+	if trust != nil {
+		tlsConfig.RootCAs = trust
+	}
+	if config.TLSSessionCacheSize >= 0 && tlsConfig.ClientSessionCache == nil {
+		size := config.TLSSessionCacheSize
+		if size == 0 {
+			size = DefaultTransportTLSSessionCacheSize
+		}
+		tlsConfig.ClientSessionCache = tls.NewLRUClientSessionCache(size)
+	}
+
+	return &http.Transport{
+		Proxy: proxy.ProxyFunc(),
+		DialContext: (&net.Dialer{
+			Timeout:   30 * time.Second,
+			KeepAlive: 30 * time.Second,
+		}).DialContext,
+		TLSClientConfig: tlsConfig,
+		// HTTP/2 does not allow the TLS renegotiation some TPP servers use to ask for client certificates
+		ForceAttemptHTTP2:     !config.DisableHTTP2 && tlsConfig.Renegotiation == tls.RenegotiateNever,
+		MaxIdleConns:          config.maxIdleConns(),
+		MaxIdleConnsPerHost:   config.maxIdleConnsPerHost(),
+		MaxConnsPerHost:       config.MaxConnsPerHost,
+		IdleConnTimeout:       config.idleConnTimeout(),
+		DisableKeepAlives:     config.DisableKeepAlives,
+		TLSHandshakeTimeout:   10 * time.Second,
+		ExpectContinueTimeout: 1 * time.Second,
+	}
+}
+
+func certPoolsEqual(a, b *x509.CertPool) bool {
+	if a == nil || b == nil {
+		return a == b
+	}
+	return a.Equal(b)
+}
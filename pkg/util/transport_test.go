@@ -0,0 +1,139 @@
+/*
+ * Copyright 2023 Venafi, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *  http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package util
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestTransportConfigIsValid(t *testing.T) {
+	if err := (TransportConfig{}).IsValid(); err != nil {
+		t.Fatalf("the zero value was expected to be valid: %s", err)
+	}
+	for _, config := range []TransportConfig{
+		{MaxIdleConns: -1},
+		{MaxIdleConnsPerHost: -1},
+		{MaxConnsPerHost: -1},
+		{IdleConnTimeout: -time.Second},
+	} {
+		if err := config.IsValid(); err == nil {
+			t.Fatalf("%+v was expected to be invalid", config)
+		}
+	}
+}
+
+func TestNewTransportDefaults(t *testing.T) {
+	transport := NewTransport(TransportConfig{}, ProxyConfig{}, nil)
+	if transport.MaxIdleConns != DefaultTransportMaxIdleConns || transport.MaxIdleConnsPerHost != DefaultTransportMaxIdleConnsPerHost ||
+		transport.IdleConnTimeout != DefaultTransportIdleConnTimeout {
+		t.Fatalf("unexpected connection pool settings: %d, %d, %s", transport.MaxIdleConns, transport.MaxIdleConnsPerHost, transport.IdleConnTimeout)
+	}
+	if !transport.ForceAttemptHTTP2 {
+		t.Fatalf("expected HTTP/2 to be enabled")
+	}
+	if transport.TLSClientConfig == nil || transport.TLSClientConfig.ClientSessionCache == nil {
+		t.Fatalf("expected a TLS session cache")
+	}
+
+	transport = NewTransport(TransportConfig{DisableHTTP2: true, TLSSessionCacheSize: -1, MaxConnsPerHost: 4}, ProxyConfig{}, nil)
+	if transport.ForceAttemptHTTP2 || transport.TLSClientConfig.ClientSessionCache != nil || transport.MaxConnsPerHost != 4 {
+		t.Fatalf("expected HTTP/2 and session resumption to be disabled, and the connections to be limited")
+	}
+}
+
+func TestNewTransportShared(t *testing.T) {
+	server := httptest.NewTLSServer(http.HandlerFunc(func(http.ResponseWriter, *http.Request) {}))
+	defer server.Close()
+	trust := x509.NewCertPool()
+	trust.AddCert(server.Certificate())
+	sameTrust := x509.NewCertPool()
+	sameTrust.AddCert(server.Certificate())
+
+	config := TransportConfig{MaxIdleConnsPerHost: 8}
+	transport := NewTransport(config, ProxyConfig{}, trust)
+	if NewTransport(config, ProxyConfig{}, sameTrust) != transport {
+		t.Fatalf("expected the transport to be shared by connectors with the same settings")
+	}
+	if NewTransport(TransportConfig{MaxIdleConnsPerHost: 9}, ProxyConfig{}, trust) == transport {
+		t.Fatalf("expected a new transport for other transport settings")
+	}
+	if NewTransport(config, ProxyConfig{URL: "http://proxy.example.com:3128"}, trust) == transport {
+		t.Fatalf("expected a new transport for another proxy")
+	}
+	if NewTransport(config, ProxyConfig{}, nil) == transport {
+		t.Fatalf("expected a new transport for another trust bundle")
+	}
+}
+
+func TestNewTransportWithTLSConfig(t *testing.T) {
+	base := &tls.Config{InsecureSkipVerify: true} // #nosec G402
+	transport := NewTransportWithTLSConfig(TransportConfig{}, ProxyConfig{}, nil, base)
+	if !transport.TLSClientConfig.InsecureSkipVerify {
+		t.Fatalf("expected the TLS settings of the base configuration")
+	}
+	if NewTransportWithTLSConfig(TransportConfig{}, ProxyConfig{}, nil, base) != transport {
+		t.Fatalf("expected the transport to be shared by connectors with the same TLS configuration")
+	}
+	if NewTransportWithTLSConfig(TransportConfig{}, ProxyConfig{}, nil, &tls.Config{}) == transport { // #nosec G402
+		t.Fatalf("expected a new transport for another TLS configuration")
+	}
+	if NewTransport(TransportConfig{}, ProxyConfig{}, nil) == transport {
+		t.Fatalf("expected a new transport for the TLS settings of http.DefaultTransport")
+	}
+}
+
+func TestNewTransportResumesTLSSessions(t *testing.T) {
+	var resumed atomic.Int32
+	server := httptest.NewUnstartedServer(http.HandlerFunc(func(_ http.ResponseWriter, r *http.Request) {
+		if r.TLS.DidResume {
+			resumed.Add(1)
+		}
+	}))
+	server.EnableHTTP2 = true
+	server.StartTLS()
+	defer server.Close()
+	trust := x509.NewCertPool()
+	trust.AddCert(server.Certificate())
+
+	// Every request opens a new connection, whose handshake resumes the session of the previous one
+	transport := NewTransport(TransportConfig{DisableKeepAlives: true}, ProxyConfig{}, trust)
+	client := &http.Client{Transport: transport}
+	for i := 0; i < 3; i++ {
+		resp, err := client.Get(server.URL)
+		if err != nil {
+			t.Fatal(err)
+		}
+		_ = resp.Body.Close()
+		if i == 0 && resp.ProtoMajor != 2 {
+			t.Fatalf("expected the request to use HTTP/2, got %s", resp.Proto)
+		}
+	}
+	if resumed.Load() != 2 {
+		t.Fatalf("expected 2 TLS sessions to be resumed, got %d", resumed.Load())
+	}
+
+	renegotiating := newTransport(TransportConfig{}, ProxyConfig{}, trust, &tls.Config{Renegotiation: tls.RenegotiateFreelyAsClient}) // #nosec G402
+	if renegotiating.ForceAttemptHTTP2 {
+		t.Fatalf("HTTP/2 was not expected to be enabled along with TLS renegotiation")
+	}
+}
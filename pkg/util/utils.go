@@ -1,10 +1,12 @@
 package util
 
 import (
+	"context"
 	"crypto/ecdsa"
 	"crypto/ed25519"
 	"crypto/rand"
 	"crypto/rsa"
+	"crypto/x509"
 	"encoding/json"
 	"encoding/pem"
 	"fmt"
@@ -59,6 +61,61 @@ func DecryptPkcs8PrivateKey(privateKey, password string) (string, error) {
 	return string(pemBytes), nil
 }
 
+// ParsePrivateKeyBlock returns the private key in block, decrypted with password when it is encrypted. Unencrypted
+// keys in the PKCS#1, SEC 1 and PKCS#8 formats, keys encrypted in PKCS#8, and PKCS#1 or SEC 1 keys with the legacy PEM
+// encryption (DES, 3DES or AES) are supported alike
+func ParsePrivateKeyBlock(block *pem.Block, password string) (interface{}, error) {
+	var err error
+	der := block.Bytes
+	encrypted := X509IsEncryptedPEMBlock(block)
+	if encrypted {
+		encryption, _, _ := strings.Cut(block.Headers["DEK-Info"], ",")
+		if password == "" {
+			return nil, fmt.Errorf("private key is encrypted with %s but no key password was provided", encryption)
+		}
+		der, err = X509DecryptPEMBlock(block, []byte(password))
+		if err != nil {
+			return nil, fmt.Errorf("could not decrypt the private key encrypted with %s: %w", encryption, err)
+		}
+	}
+
+	var privateKey interface{}
+	switch block.Type {
+	case "EC PRIVATE KEY":
+		privateKey, err = x509.ParseECPrivateKey(der)
+		if err != nil {
+			privateKey, err = x509.ParsePKCS8PrivateKey(der)
+		}
+	case "RSA PRIVATE KEY":
+		privateKey, err = x509.ParsePKCS1PrivateKey(der)
+		if err != nil {
+			privateKey, err = x509.ParsePKCS8PrivateKey(der)
+		}
+	case "PRIVATE KEY":
+		privateKey, err = x509.ParsePKCS8PrivateKey(der)
+	case "ENCRYPTED PRIVATE KEY":
+		if password == "" {
+			return nil, fmt.Errorf("private key is encrypted in PKCS#8 but no key password was provided")
+		}
+		privateKey, _, err = pkcs8.ParsePrivateKey(der, []byte(password))
+		if err != nil {
+			// The errors of the PKCS#8 library name the unsupported scheme, cipher or KDF
+			return nil, fmt.Errorf("could not decrypt the PKCS#8 private key: %w", err)
+		}
+	default:
+		return nil, fmt.Errorf("unexpected private key PEM type: %s", block.Type)
+	}
+
+	if err != nil && encrypted {
+		// The legacy PEM encryption cannot always detect a wrong password, which results in an unreadable key
+		return nil, fmt.Errorf("private key error, the key password may be incorrect: %w", err)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("private key error: %w", err)
+	}
+	return privateKey, nil
+}
+
 func EncryptPkcs1PrivateKey(privateKey, password string) (string, error) {
 
 	block, _ := pem.Decode([]byte(privateKey))
@@ -132,3 +189,15 @@ func NormalizeUrl(url string) string {
 	}
 	return modified
 }
+
+// Sleep pauses for d, or until ctx is done, in which case the error of ctx is returned
+func Sleep(ctx context.Context, d time.Duration) error {
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-timer.C:
+		return nil
+	}
+}
@@ -0,0 +1,99 @@
+/*
+ * Copyright 2023 Venafi, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *  http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package util
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/pem"
+	"strings"
+	"testing"
+
+	"github.com/youmark/pkcs8"
+)
+
+func TestParsePrivateKeyBlock(t *testing.T) {
+	rsaKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ecKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, edKey, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	sec1DER, err := x509.MarshalECPrivateKey(ecKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	pkcs8DER, err := x509.MarshalPKCS8PrivateKey(edKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	encryptedPKCS8DER, err := pkcs8.MarshalPrivateKey(ecKey, []byte("secret"), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	legacyBlock, err := X509EncryptPEMBlock(rand.Reader, "RSA PRIVATE KEY", x509.MarshalPKCS1PrivateKey(rsaKey), []byte("secret"), PEMCipherAES256)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	cases := []struct {
+		name     string
+		block    *pem.Block
+		password string
+		expected crypto.PrivateKey
+		err      string
+	}{
+		{"PKCS#1", &pem.Block{Type: "RSA PRIVATE KEY", Bytes: x509.MarshalPKCS1PrivateKey(rsaKey)}, "", rsaKey, ""},
+		{"SEC 1", &pem.Block{Type: "EC PRIVATE KEY", Bytes: sec1DER}, "", ecKey, ""},
+		{"PKCS#8", &pem.Block{Type: "PRIVATE KEY", Bytes: pkcs8DER}, "", edKey, ""},
+		{"encrypted PKCS#8", &pem.Block{Type: "ENCRYPTED PRIVATE KEY", Bytes: encryptedPKCS8DER}, "secret", ecKey, ""},
+		{"encrypted PKCS#8 without password", &pem.Block{Type: "ENCRYPTED PRIVATE KEY", Bytes: encryptedPKCS8DER}, "", nil, "no key password was provided"},
+		{"encrypted PKCS#8 with wrong password", &pem.Block{Type: "ENCRYPTED PRIVATE KEY", Bytes: encryptedPKCS8DER}, "wrong", nil, "could not decrypt the PKCS#8 private key"},
+		{"legacy encrypted PKCS#1", legacyBlock, "secret", rsaKey, ""},
+		{"legacy encrypted PKCS#1 without password", legacyBlock, "", nil, "encrypted with AES-256-CBC but no key password was provided"},
+		{"unexpected type", &pem.Block{Type: "CERTIFICATE", Bytes: []byte{1}}, "", nil, "unexpected private key PEM type: CERTIFICATE"},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			key, err := ParsePrivateKeyBlock(c.block, c.password)
+			if c.err != "" {
+				if err == nil || !strings.Contains(err.Error(), c.err) {
+					t.Fatalf("expected error containing %q but got %v", c.err, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("failed to parse private key: %s", err)
+			}
+			if !c.expected.(interface{ Equal(crypto.PrivateKey) bool }).Equal(key) {
+				t.Fatalf("expected the private key %T that was encoded but got %T", c.expected, key)
+			}
+		})
+	}
+}
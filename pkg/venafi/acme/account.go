@@ -0,0 +1,111 @@
+/*
+ * Copyright 2023 Venafi, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *  http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+package acme
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"go.uber.org/zap"
+)
+
+// loadAccountKey reads the ACME account key from keyFile. When keyFile does not exist, a new ECDSA P-256 key
+// is created and saved there so that the same account is used on every run.
+// When keyFile is empty, a temporary key is returned
+func loadAccountKey(keyFile string) (crypto.Signer, error) {
+	if keyFile == "" {
+		zap.L().Warn("no ACME account key file provided. A new account will be registered on every run", fieldPlatform)
+		return ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	}
+
+	data, err := os.ReadFile(keyFile)
+	if errors.Is(err, os.ErrNotExist) {
+		zap.L().Info("ACME account key not found. Creating a new one", fieldPlatform, zap.String("file", keyFile))
+		return createAccountKey(keyFile)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("could not read ACME account key: %w", err)
+	}
+
+	return parseAccountKey(data)
+}
+
+func createAccountKey(keyFile string) (crypto.Signer, error) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		return nil, err
+	}
+
+	der, err := x509.MarshalPKCS8PrivateKey(key)
+	if err != nil {
+		return nil, err
+	}
+
+	err = os.MkdirAll(filepath.Dir(keyFile), 0750)
+	if err != nil {
+		return nil, fmt.Errorf("could not create ACME account key directory: %w", err)
+	}
+	err = os.WriteFile(keyFile, pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: der}), 0600)
+	if err != nil {
+		return nil, fmt.Errorf("could not save ACME account key: %w", err)
+	}
+	return key, nil
+}
+
+// parseAccountKey parses a PEM encoded RSA or ECDSA private key, in PKCS8, PKCS1 or SEC1 format
+func parseAccountKey(data []byte) (crypto.Signer, error) {
+	block, _ := pem.Decode(data)
+	if block == nil {
+		return nil, fmt.Errorf("no PEM data found in ACME account key")
+	}
+
+	if key, err := x509.ParsePKCS8PrivateKey(block.Bytes); err == nil {
+		signer, ok := key.(crypto.Signer)
+		if !ok {
+			return nil, fmt.Errorf("unsupported ACME account key type %T", key)
+		}
+		return signer, nil
+	}
+	if key, err := x509.ParseECPrivateKey(block.Bytes); err == nil {
+		return key, nil
+	}
+	if key, err := x509.ParsePKCS1PrivateKey(block.Bytes); err == nil {
+		return key, nil
+	}
+	return nil, fmt.Errorf("could not parse ACME account key. Should be an unencrypted RSA or ECDSA private key")
+}
+
+// decodeEABKey decodes the External Account Binding HMAC key, which CAs provide base64url encoded
+func decodeEABKey(hmacKey string) ([]byte, error) {
+	if hmacKey == "" {
+		return nil, fmt.Errorf("an HMAC key is required for External Account Binding")
+	}
+	key, err := base64.RawURLEncoding.DecodeString(strings.TrimRight(hmacKey, "="))
+	if err != nil {
+		return nil, fmt.Errorf("could not decode External Account Binding HMAC key: %w", err)
+	}
+	return key, nil
+}
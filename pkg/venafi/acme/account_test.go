@@ -0,0 +1,61 @@
+/*
+ * Copyright 2023 Venafi, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *  http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+package acme
+
+import (
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/suite"
+)
+
+type AccountSuite struct {
+	suite.Suite
+}
+
+func TestAccountSuite(t *testing.T) {
+	suite.Run(t, new(AccountSuite))
+}
+
+func (s *AccountSuite) TestLoadAccountKey() {
+	keyFile := filepath.Join(s.T().TempDir(), "acme", "account.key")
+
+	key, err := loadAccountKey(keyFile)
+	s.Require().NoError(err)
+	s.FileExists(keyFile)
+
+	loaded, err := loadAccountKey(keyFile)
+	s.Require().NoError(err)
+	s.Equal(key.Public(), loaded.Public())
+
+	_, err = parseAccountKey([]byte("not a key"))
+	s.Error(err)
+}
+
+func (s *AccountSuite) TestDecodeEABKey() {
+	key, err := decodeEABKey("c2VjcmV0LWtleQ")
+	s.NoError(err)
+	s.Equal([]byte("secret-key"), key)
+
+	key, err = decodeEABKey("c2VjcmV0LWtleQ==")
+	s.NoError(err)
+	s.Equal([]byte("secret-key"), key)
+
+	_, err = decodeEABKey("")
+	s.Error(err)
+	_, err = decodeEABKey("not base64!")
+	s.Error(err)
+}
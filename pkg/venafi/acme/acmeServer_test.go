@@ -0,0 +1,223 @@
+/*
+ * Copyright 2023 Venafi, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *  http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+package acme
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"fmt"
+	"io"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"time"
+)
+
+// acmeMockServer is a minimal ACME server. JWS signatures are not verified.
+// http-01 challenges are validated by requesting the key authorization from challengeAddress
+type acmeMockServer struct {
+	server           *httptest.Server
+	challengeAddress string
+
+	mu          sync.Mutex
+	accounts    int
+	identifiers []string
+	authzValid  []bool
+	issued      bool
+	caKey       *ecdsa.PrivateKey
+	caCert      *x509.Certificate
+	certPEM     []byte
+}
+
+func newACMEMockServer(challengeAddress string) *acmeMockServer {
+	s := &acmeMockServer{challengeAddress: challengeAddress}
+	s.caKey, _ = ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	template := &x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{CommonName: "ACME Test CA"},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(24 * time.Hour),
+		IsCA:                  true,
+		BasicConstraintsValid: true,
+		KeyUsage:              x509.KeyUsageCertSign,
+	}
+	der, _ := x509.CreateCertificate(rand.Reader, template, template, &s.caKey.PublicKey, s.caKey)
+	s.caCert, _ = x509.ParseCertificate(der)
+
+	s.server = httptest.NewServer(http.HandlerFunc(s.handle))
+	return s
+}
+
+func (s *acmeMockServer) url(path string) string {
+	return s.server.URL + path
+}
+
+func (s *acmeMockServer) handle(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Replay-Nonce", fmt.Sprintf("nonce-%d", time.Now().UnixNano()))
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	payload := jwsPayload(r)
+	switch {
+	case r.URL.Path == "/directory":
+		writeJSON(w, http.StatusOK, map[string]string{
+			"newNonce":   s.url("/new-nonce"),
+			"newAccount": s.url("/new-account"),
+			"newOrder":   s.url("/new-order"),
+			"revokeCert": s.url("/revoke-cert"),
+			"keyChange":  s.url("/key-change"),
+		})
+	case r.URL.Path == "/new-nonce":
+		w.WriteHeader(http.StatusOK)
+	case r.URL.Path == "/new-account":
+		s.accounts++
+		w.Header().Set("Location", s.url("/account/1"))
+		writeJSON(w, http.StatusCreated, map[string]string{"status": "valid"})
+	case r.URL.Path == "/new-order":
+		request := struct {
+			Identifiers []struct {
+				Value string `json:"value"`
+			} `json:"identifiers"`
+		}{}
+		_ = json.Unmarshal(payload, &request)
+		s.identifiers = nil
+		for _, id := range request.Identifiers {
+			s.identifiers = append(s.identifiers, id.Value)
+		}
+		s.authzValid = make([]bool, len(s.identifiers))
+		s.issued = false
+		s.writeOrder(w, http.StatusCreated)
+	case r.URL.Path == "/order/1":
+		s.writeOrder(w, http.StatusOK)
+	case strings.HasPrefix(r.URL.Path, "/authz/"):
+		var i int
+		_, _ = fmt.Sscanf(r.URL.Path, "/authz/%d", &i)
+		writeJSON(w, http.StatusOK, s.authorization(i))
+	case strings.HasPrefix(r.URL.Path, "/challenge/"):
+		var i int
+		_, _ = fmt.Sscanf(r.URL.Path, "/challenge/%d", &i)
+		s.authzValid[i] = s.validateHTTP01(fmt.Sprintf("token%d", i))
+		writeJSON(w, http.StatusOK, s.authorization(i)["challenges"].([]map[string]string)[0])
+	case r.URL.Path == "/finalize/1":
+		request := struct {
+			CSR string `json:"csr"`
+		}{}
+		_ = json.Unmarshal(payload, &request)
+		der, _ := base64.RawURLEncoding.DecodeString(request.CSR)
+		s.issue(der)
+		s.writeOrder(w, http.StatusOK)
+	case r.URL.Path == "/cert/1":
+		w.Header().Set("Content-Type", "application/pem-certificate-chain")
+		_, _ = w.Write(s.certPEM)
+	default:
+		http.NotFound(w, r)
+	}
+}
+
+func (s *acmeMockServer) authorization(i int) map[string]interface{} {
+	status := "pending"
+	if s.authzValid[i] {
+		status = "valid"
+	}
+	return map[string]interface{}{
+		"status":     status,
+		"identifier": map[string]string{"type": "dns", "value": s.identifiers[i]},
+		"challenges": []map[string]string{
+			{"type": ChallengeHTTP01, "url": s.url(fmt.Sprintf("/challenge/%d", i)), "token": fmt.Sprintf("token%d", i), "status": status},
+			{"type": ChallengeDNS01, "url": s.url(fmt.Sprintf("/challenge/dns/%d", i)), "token": fmt.Sprintf("dnstoken%d", i), "status": "pending"},
+		},
+	}
+}
+
+func (s *acmeMockServer) writeOrder(w http.ResponseWriter, status int) {
+	authzURLs := make([]string, len(s.identifiers))
+	orderStatus := "ready"
+	for i := range s.identifiers {
+		authzURLs[i] = s.url(fmt.Sprintf("/authz/%d", i))
+		if !s.authzValid[i] {
+			orderStatus = "pending"
+		}
+	}
+	order := map[string]interface{}{
+		"status":         orderStatus,
+		"authorizations": authzURLs,
+		"finalize":       s.url("/finalize/1"),
+	}
+	if s.issued {
+		order["status"] = "valid"
+		order["certificate"] = s.url("/cert/1")
+	}
+	w.Header().Set("Location", s.url("/order/1"))
+	writeJSON(w, status, order)
+}
+
+func (s *acmeMockServer) validateHTTP01(token string) bool {
+	res, err := http.Get(fmt.Sprintf("http://%s/.well-known/acme-challenge/%s", s.challengeAddress, token))
+	if err != nil {
+		return false
+	}
+	defer res.Body.Close()
+	body, _ := io.ReadAll(res.Body)
+	return res.StatusCode == http.StatusOK && strings.HasPrefix(string(body), token+".")
+}
+
+func (s *acmeMockServer) issue(csrDER []byte) {
+	csr, err := x509.ParseCertificateRequest(csrDER)
+	if err != nil {
+		return
+	}
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(time.Now().UnixNano()),
+		Subject:      csr.Subject,
+		DNSNames:     csr.DNSNames,
+		NotBefore:    time.Now().Add(-time.Minute),
+		NotAfter:     time.Now().Add(time.Hour),
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, s.caCert, csr.PublicKey, s.caKey)
+	if err != nil {
+		return
+	}
+	s.certPEM = append(pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}),
+		pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: s.caCert.Raw})...)
+	s.issued = true
+}
+
+// jwsPayload returns the decoded payload of the JWS in the request body
+func jwsPayload(r *http.Request) []byte {
+	jws := struct {
+		Payload string `json:"payload"`
+	}{}
+	if err := json.NewDecoder(r.Body).Decode(&jws); err != nil {
+		return nil
+	}
+	payload, _ := base64.RawURLEncoding.DecodeString(jws.Payload)
+	return payload
+}
+
+func writeJSON(w http.ResponseWriter, status int, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	_ = json.NewEncoder(w).Encode(v)
+}
@@ -0,0 +1,513 @@
+/*
+ * Copyright 2023 Venafi, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *  http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+package acme
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/pem"
+	"fmt"
+	"net"
+	"net/http"
+	"strings"
+	"time"
+
+	"go.uber.org/zap"
+	xacme "golang.org/x/crypto/acme"
+
+	"github.com/Venafi/vcert/v5/pkg/certificate"
+	"github.com/Venafi/vcert/v5/pkg/endpoint"
+	"github.com/Venafi/vcert/v5/pkg/policy"
+	"github.com/Venafi/vcert/v5/pkg/util"
+	"github.com/Venafi/vcert/v5/pkg/venafi"
+	"github.com/Venafi/vcert/v5/pkg/verror"
+)
+
+const (
+	// DefaultDirectoryURL is the ACME directory used when no url is provided: Let's Encrypt production
+	DefaultDirectoryURL = xacme.LetsEncryptURL
+	// DefaultTimeout is the maximum time to wait for an order to be validated and issued when the request has no timeout
+	DefaultTimeout = 5 * time.Minute
+)
+
+var (
+	fieldPlatform = zap.String("platform", venafi.ACME.String())
+
+	errNotSupported = fmt.Errorf("operation is not supported by the ACME connector")
+)
+
+// Connector contains the base data needed to communicate with an ACME server
+type Connector struct {
+	directoryURL string
+	verbose      bool
+	trust        *x509.CertPool
+	client       *http.Client
+	proxy        util.ProxyConfig
+	transport    util.TransportConfig
+	tlsConfig    *tls.Config
+	zone         string
+	solver       Solver
+	acmeClient   *xacme.Client
+}
+
+// NewConnector creates a new ACME Connector object used to communicate with the ACME server at the given directory url.
+//
+// Challenges are fulfilled by the Solver described by challenge. Defaults to an http-01 Solver listening on port 80
+func NewConnector(url string, zone string, verbose bool, trust *x509.CertPool, challenge *ChallengeConfig) (*Connector, error) {
+	if url == "" {
+		url = DefaultDirectoryURL
+	}
+	if challenge == nil {
+		challenge = &ChallengeConfig{}
+	}
+
+	solver, err := NewSolver(*challenge)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", verror.UserDataError, err)
+	}
+
+	return &Connector{directoryURL: url, zone: zone, verbose: verbose, trust: trust, solver: solver}, nil
+}
+
+func (c *Connector) GetType() endpoint.ConnectorType {
+	return endpoint.ConnectorTypeACME
+}
+
+// SetZone is kept for compatibility. ACME servers have no concept of zones, so it is not used
+func (c *Connector) SetZone(zone string) {
+	c.zone = zone
+}
+
+// SetSolver replaces the Solver used to fulfill the challenges of the ACME server
+func (c *Connector) SetSolver(solver Solver) {
+	c.solver = solver
+}
+
+func (c *Connector) SetHTTPClient(client *http.Client) {
+	c.client = client
+}
+
+// SetProxy sets the proxy the requests to the ACME server are sent through, instead of the one of the environment variables.
+// It has no effect on the client set with SetHTTPClient, nor once the connector has sent its first request
+func (c *Connector) SetProxy(proxy util.ProxyConfig) {
+	c.proxy = proxy
+}
+
+// SetTransportConfig tunes the HTTP transport of the requests to the ACME server, shared with the other connectors built with the
+// same settings. It has no effect on the client set with SetHTTPClient, nor once the connector has sent its first request
+func (c *Connector) SetTransportConfig(config util.TransportConfig) {
+	c.transport = config
+}
+
+// SetTLSConfig sets the TLS settings of the requests to the ACME server, like the client certificate, instead of the ones of
+// http.DefaultTransport. The trust bundle of the connector replaces its root CAs. It has no effect on the client set
+// with SetHTTPClient, nor once the connector has sent its first request
+func (c *Connector) SetTLSConfig(config *tls.Config) {
+	c.tlsConfig = config
+}
+
+// Authenticate registers the ACME account, or finds the existing account for its key.
+//
+// The account key is read from auth.ACMEAccount.KeyFile, and created there if the file does not exist.
+// A temporary account is registered when no key file is provided
+func (c *Connector) Authenticate(ctx context.Context, auth *endpoint.Authentication) error {
+	account := &endpoint.ACMEAccount{}
+	if auth != nil && auth.ACMEAccount != nil {
+		account = auth.ACMEAccount
+	}
+
+	key, err := loadAccountKey(account.KeyFile)
+	if err != nil {
+		zap.L().Error("failed to load ACME account key", fieldPlatform, zap.Error(err))
+		return fmt.Errorf("%w: %v", verror.AuthError, err)
+	}
+
+	c.acmeClient = &xacme.Client{
+		Key:          key,
+		DirectoryURL: c.directoryURL,
+		HTTPClient:   c.getHTTPClient(),
+		UserAgent:    "vcert",
+	}
+
+	acct := &xacme.Account{}
+	if account.Email != "" {
+		acct.Contact = []string{fmt.Sprintf("mailto:%s", account.Email)}
+	}
+	if account.EABKeyID != "" {
+		hmacKey, err := decodeEABKey(account.EABHMACKey)
+		if err != nil {
+			return fmt.Errorf("%w: %v", verror.AuthError, err)
+		}
+		acct.ExternalAccountBinding = &xacme.ExternalAccountBinding{KID: account.EABKeyID, Key: hmacKey}
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, time.Minute)
+	defer cancel()
+
+	_, err = c.acmeClient.Register(ctx, acct, xacme.AcceptTOS)
+	if err != nil && err != xacme.ErrAccountAlreadyExists {
+		zap.L().Error("failed to register ACME account", fieldPlatform, zap.Error(err))
+		return fmt.Errorf("%w: failed to register ACME account: %v", verror.AuthError, err)
+	}
+
+	zap.L().Info("successfully authenticated", fieldPlatform)
+	return nil
+}
+
+// Ping checks the ACME directory can be reached
+func (c *Connector) Ping(ctx context.Context) error {
+	client := c.acmeClient
+	if client == nil {
+		client = &xacme.Client{DirectoryURL: c.directoryURL, HTTPClient: c.getHTTPClient()}
+	}
+	_, err := client.Discover(ctx)
+	return err
+}
+
+// ReadZoneConfiguration returns an empty zone configuration. ACME servers do not publish policies
+func (c *Connector) ReadZoneConfiguration(ctx context.Context) (config *endpoint.ZoneConfiguration, err error) {
+	return endpoint.NewZoneConfiguration(), nil
+}
+
+// GenerateRequest creates the private key and CSR when the csrOrigin is local.
+// Service generated CSRs are not supported by ACME
+func (c *Connector) GenerateRequest(ctx context.Context, _ *endpoint.ZoneConfiguration, req *certificate.Request) (err error) {
+	switch req.CsrOrigin {
+	case certificate.LocalGeneratedCSR:
+		err = req.GeneratePrivateKey()
+		if err != nil {
+			return err
+		}
+		return req.GenerateCSR()
+	case certificate.UserProvidedCSR:
+		if len(req.GetCSR()) == 0 {
+			return fmt.Errorf("%w: CSR was supposed to be provided by user, but it's empty", verror.UserDataError)
+		}
+		return nil
+	case certificate.ServiceGeneratedCSR:
+		return fmt.Errorf("%w: service generated CSR is not supported by ACME", verror.UserDataError)
+	default:
+		return fmt.Errorf("%w: unrecognised req.CsrOrigin %v", verror.UserDataError, req.CsrOrigin)
+	}
+}
+
+// SupportSynchronousRequestCertificate returns if the connector support synchronous calls to request a certificate.
+func (c *Connector) SupportSynchronousRequestCertificate() bool {
+	return true
+}
+
+// SynchronousRequestCertificate places an order for the identifiers of the CSR, fulfills its challenges
+// and returns the issued certificate along with its chain
+func (c *Connector) SynchronousRequestCertificate(ctx context.Context, req *certificate.Request) (certificates *certificate.PEMCollection, err error) {
+	if c.acmeClient == nil {
+		return nil, fmt.Errorf("%w: the ACME connector must be authenticated before requesting certificates", verror.AuthError)
+	}
+
+	block, _ := pem.Decode(req.GetCSR())
+	if block == nil {
+		return nil, fmt.Errorf("%w: could not decode the CSR", verror.UserDataError)
+	}
+	csr, err := x509.ParseCertificateRequest(block.Bytes)
+	if err != nil {
+		return nil, fmt.Errorf("%w: could not parse the CSR: %v", verror.UserDataError, err)
+	}
+
+	ids := getIdentifiers(csr)
+	if len(ids) == 0 {
+		return nil, fmt.Errorf("%w: the CSR has no DNS name or IP address to validate", verror.UserDataError)
+	}
+
+	timeout := req.Timeout
+	if timeout == 0 {
+		timeout = DefaultTimeout
+	}
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	zap.L().Info("requesting certificate", zap.String("cn", csr.Subject.CommonName), fieldPlatform)
+	order, err := c.acmeClient.AuthorizeOrder(ctx, ids)
+	if err != nil {
+		zap.L().Error("failed to create ACME order", fieldPlatform, zap.Error(err))
+		return nil, err
+	}
+
+	if order.Status == xacme.StatusPending {
+		err = c.solveAuthorizations(ctx, order.AuthzURLs)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	order, err = c.acmeClient.WaitOrder(ctx, order.URI)
+	if err != nil {
+		zap.L().Error("ACME order failed", fieldPlatform, zap.Error(err))
+		return nil, err
+	}
+
+	der, _, err := c.acmeClient.CreateOrderCert(ctx, order.FinalizeURL, block.Bytes, true)
+	if err != nil {
+		zap.L().Error("failed to finalize ACME order", fieldPlatform, zap.Error(err))
+		return nil, err
+	}
+
+	var chain []byte
+	for _, cert := range der {
+		chain = append(chain, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: cert})...)
+	}
+
+	// ACME servers return the chain from the leaf to the root. The output order is applied when the certificate is written
+	chainOption := certificate.ChainOptionRootLast
+	if req.ChainOption == certificate.ChainOptionIgnore {
+		chainOption = certificate.ChainOptionIgnore
+	}
+	certificates, err = certificate.PEMCollectionFromBytes(chain, chainOption)
+	if err != nil {
+		zap.L().Error("failed to create pem collection", fieldPlatform, zap.Error(err))
+		return nil, err
+	}
+
+	zap.L().Info("successfully requested certificate", fieldPlatform)
+	return certificates, nil
+}
+
+// solveAuthorizations presents the challenge of every pending authorization, asks the ACME server
+// to validate them and waits for the result. Challenge responses are always cleaned up
+func (c *Connector) solveAuthorizations(ctx context.Context, authzURLs []string) error {
+	type presented struct {
+		authzURL   string
+		identifier string
+		challenge  *xacme.Challenge
+		keyAuth    string
+	}
+	challenges := make([]presented, 0, len(authzURLs))
+
+	defer func() {
+		// The challenges are cleaned up even when ctx is cancelled, so no record or file is left behind
+		cleanupCtx, cancel := context.WithTimeout(context.Background(), time.Minute)
+		defer cancel()
+		for _, p := range challenges {
+			err := c.solver.CleanUp(cleanupCtx, p.identifier, p.challenge.Token, p.keyAuth)
+			if err != nil {
+				zap.L().Warn("failed to clean up ACME challenge", fieldPlatform,
+					zap.String("identifier", p.identifier), zap.Error(err))
+			}
+		}
+	}()
+
+	for _, authzURL := range authzURLs {
+		authz, err := c.acmeClient.GetAuthorization(ctx, authzURL)
+		if err != nil {
+			return err
+		}
+		if authz.Status != xacme.StatusPending {
+			continue
+		}
+
+		var challenge *xacme.Challenge
+		for _, chal := range authz.Challenges {
+			if chal.Type == c.solver.ChallengeType() {
+				challenge = chal
+				break
+			}
+		}
+		if challenge == nil {
+			return fmt.Errorf("ACME server does not offer a %s challenge for %s", c.solver.ChallengeType(), authz.Identifier.Value)
+		}
+
+		keyAuth, err := c.keyAuthorization(challenge)
+		if err != nil {
+			return err
+		}
+
+		zap.L().Info("presenting ACME challenge", fieldPlatform, zap.String("type", challenge.Type),
+			zap.String("identifier", authz.Identifier.Value))
+		err = c.solver.Present(ctx, authz.Identifier.Value, challenge.Token, keyAuth)
+		if err != nil {
+			return fmt.Errorf("failed to present %s challenge for %s: %w", challenge.Type, authz.Identifier.Value, err)
+		}
+		challenges = append(challenges, presented{authzURL: authzURL, identifier: authz.Identifier.Value,
+			challenge: challenge, keyAuth: keyAuth})
+	}
+
+	if w, ok := c.solver.(waiter); ok && len(challenges) > 0 {
+		err := w.Wait(ctx)
+		if err != nil {
+			return err
+		}
+	}
+
+	for _, p := range challenges {
+		_, err := c.acmeClient.Accept(ctx, p.challenge)
+		if err != nil {
+			return fmt.Errorf("failed to accept challenge for %s: %w", p.identifier, err)
+		}
+	}
+
+	for _, p := range challenges {
+		_, err := c.acmeClient.WaitAuthorization(ctx, p.authzURL)
+		if err != nil {
+			zap.L().Error("ACME authorization failed", fieldPlatform, zap.String("identifier", p.identifier), zap.Error(err))
+			return err
+		}
+		zap.L().Info("ACME authorization is valid", fieldPlatform, zap.String("identifier", p.identifier))
+	}
+	return nil
+}
+
+// keyAuthorization returns the value the ACME server expects for the challenge:
+// the key authorization for http-01 or the TXT record value for dns-01
+func (c *Connector) keyAuthorization(challenge *xacme.Challenge) (string, error) {
+	switch challenge.Type {
+	case ChallengeDNS01:
+		return c.acmeClient.DNS01ChallengeRecord(challenge.Token)
+	default:
+		return c.acmeClient.HTTP01ChallengeResponse(challenge.Token)
+	}
+}
+
+// getIdentifiers returns the DNS names, including the common name, and IP addresses of the CSR as ACME identifiers
+func getIdentifiers(csr *x509.CertificateRequest) []xacme.AuthzID {
+	var names, ips []string
+	seen := make(map[string]bool)
+	add := func(value string) {
+		value = strings.ToLower(strings.TrimSpace(value))
+		if value == "" || seen[value] {
+			return
+		}
+		seen[value] = true
+		if net.ParseIP(value) != nil {
+			ips = append(ips, value)
+		} else {
+			names = append(names, value)
+		}
+	}
+
+	add(csr.Subject.CommonName)
+	for _, name := range csr.DNSNames {
+		add(name)
+	}
+	for _, ip := range csr.IPAddresses {
+		add(ip.String())
+	}
+
+	return append(xacme.DomainIDs(names...), xacme.IPIDs(ips...)...)
+}
+
+func (c *Connector) getHTTPClient() *http.Client {
+	if c.client != nil {
+		return c.client
+	}
+
+	c.client = &http.Client{Transport: util.NewTransportWithTLSConfig(c.transport, c.proxy, c.trust, c.tlsConfig), Timeout: 30 * time.Second}
+	return c.client
+}
+
+func (c *Connector) GetZonesByParent(ctx context.Context, _ string) ([]string, error) {
+	return nil, errNotSupported
+}
+
+func (c *Connector) ReadPolicyConfiguration(ctx context.Context) (policy *endpoint.Policy, err error) {
+	return nil, errNotSupported
+}
+
+func (c *Connector) ResetCertificate(ctx context.Context, _ *certificate.Request, _ bool) (err error) {
+	return errNotSupported
+}
+
+func (c *Connector) RequestCertificate(ctx context.Context, _ *certificate.Request) (requestID string, err error) {
+	return "", errNotSupported
+}
+
+func (c *Connector) RetrieveCertificate(ctx context.Context, _ *certificate.Request) (certificates *certificate.PEMCollection, err error) {
+	return nil, errNotSupported
+}
+
+func (c *Connector) IsCSRServiceGenerated(ctx context.Context, _ *certificate.Request) (bool, error) {
+	return false, nil
+}
+
+func (c *Connector) RevokeCertificate(ctx context.Context, _ *certificate.RevocationRequest) error {
+	return errNotSupported
+}
+
+func (c *Connector) RenewCertificate(ctx context.Context, _ *certificate.RenewalRequest) (requestID string, err error) {
+	return "", errNotSupported
+}
+
+func (c *Connector) RetireCertificate(ctx context.Context, _ *certificate.RetireRequest) error {
+	return errNotSupported
+}
+
+func (c *Connector) ImportCertificate(ctx context.Context, _ *certificate.ImportRequest) (*certificate.ImportResponse, error) {
+	return nil, errNotSupported
+}
+
+func (c *Connector) ListCertificates(ctx context.Context, _ endpoint.Filter) ([]certificate.CertificateInfo, error) {
+	return nil, errNotSupported
+}
+
+func (c *Connector) SetPolicy(ctx context.Context, _ string, _ *policy.PolicySpecification) (string, error) {
+	return "", errNotSupported
+}
+
+func (c *Connector) GetPolicy(ctx context.Context, _ string) (*policy.PolicySpecification, error) {
+	return nil, errNotSupported
+}
+
+func (c *Connector) RequestSSHCertificate(ctx context.Context, _ *certificate.SshCertRequest) (response *certificate.SshCertificateObject, err error) {
+	return nil, errNotSupported
+}
+
+func (c *Connector) RetrieveSSHCertificate(ctx context.Context, _ *certificate.SshCertRequest) (response *certificate.SshCertificateObject, err error) {
+	return nil, errNotSupported
+}
+
+func (c *Connector) RenewSSHCertificate(ctx context.Context, _ *certificate.SshCertRequest) (response *certificate.SshCertificateObject, err error) {
+	return nil, errNotSupported
+}
+
+func (c *Connector) RevokeSSHCertificate(ctx context.Context, _ *certificate.SshCertRequest) error {
+	return errNotSupported
+}
+
+func (c *Connector) RetrieveSshConfig(ctx context.Context, _ *certificate.SshCaTemplateRequest) (*certificate.SshConfig, error) {
+	return nil, errNotSupported
+}
+
+func (c *Connector) SearchCertificates(ctx context.Context, _ *certificate.SearchRequest) (*certificate.CertSearchResponse, error) {
+	return nil, errNotSupported
+}
+
+func (c *Connector) SearchCertificate(ctx context.Context, _ string, _ string, _ *certificate.Sans, _ time.Duration) (*certificate.CertificateInfo, error) {
+	return nil, errNotSupported
+}
+
+func (c *Connector) RetrieveAvailableSSHTemplates(ctx context.Context) ([]certificate.SshAvaliableTemplate, error) {
+	return nil, errNotSupported
+}
+
+func (c *Connector) RetrieveCertificateMetaData(ctx context.Context, _ string) (*certificate.CertificateMetaData, error) {
+	return nil, errNotSupported
+}
+
+func (c *Connector) RetrieveSystemVersion(ctx context.Context) (string, error) {
+	return "", errNotSupported
+}
+
+func (c *Connector) WriteLog(ctx context.Context, _ *endpoint.LogRequest) error {
+	return errNotSupported
+}
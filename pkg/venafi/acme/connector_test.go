@@ -0,0 +1,143 @@
+/*
+ * Copyright 2023 Venafi, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *  http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+package acme
+
+import (
+	"context"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"net"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/suite"
+
+	"github.com/Venafi/vcert/v5/pkg/certificate"
+	"github.com/Venafi/vcert/v5/pkg/endpoint"
+)
+
+type ConnectorSuite struct {
+	suite.Suite
+	challengeAddress string
+	server           *acmeMockServer
+}
+
+func (s *ConnectorSuite) SetupTest() {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	s.Require().NoError(err)
+	s.challengeAddress = listener.Addr().String()
+	_ = listener.Close()
+
+	s.server = newACMEMockServer(s.challengeAddress)
+}
+
+func (s *ConnectorSuite) TearDownTest() {
+	s.server.server.Close()
+}
+
+func TestConnectorSuite(t *testing.T) {
+	suite.Run(t, new(ConnectorSuite))
+}
+
+func (s *ConnectorSuite) TestNewConnector() {
+	connector, err := NewConnector("", "", false, nil, nil)
+	s.NoError(err)
+	s.Equal(DefaultDirectoryURL, connector.directoryURL)
+	s.Equal(endpoint.ConnectorTypeACME, connector.GetType())
+	s.Equal(ChallengeHTTP01, connector.solver.ChallengeType())
+
+	_, err = NewConnector("", "", false, nil, &ChallengeConfig{Type: "tls-alpn-01"})
+	s.Error(err)
+
+	_, err = NewConnector("", "", false, nil, &ChallengeConfig{Type: ChallengeDNS01})
+	s.Error(err)
+}
+
+func (s *ConnectorSuite) TestGenerateRequest() {
+	ctx := context.Background()
+	connector, err := NewConnector("", "", false, nil, nil)
+	s.Require().NoError(err)
+
+	req := &certificate.Request{Subject: pkix.Name{CommonName: "example.com"}, CsrOrigin: certificate.ServiceGeneratedCSR}
+	s.Error(connector.GenerateRequest(ctx, nil, req))
+
+	req.CsrOrigin = certificate.LocalGeneratedCSR
+	s.NoError(connector.GenerateRequest(ctx, nil, req))
+	s.NotEmpty(req.GetCSR())
+}
+
+func (s *ConnectorSuite) TestGetIdentifiers() {
+	csr := &x509.CertificateRequest{
+		Subject:     pkix.Name{CommonName: "Example.com"},
+		DNSNames:    []string{"example.com", "www.example.com"},
+		IPAddresses: []net.IP{net.ParseIP("10.0.0.1")},
+	}
+
+	ids := getIdentifiers(csr)
+	s.Len(ids, 3)
+	s.Equal("example.com", ids[0].Value)
+	s.Equal("www.example.com", ids[1].Value)
+	s.Equal("ip", ids[2].Type)
+	s.Equal("10.0.0.1", ids[2].Value)
+}
+
+func (s *ConnectorSuite) TestSynchronousRequestCertificate() {
+	ctx := context.Background()
+	keyFile := filepath.Join(s.T().TempDir(), "account.key")
+	connector, err := NewConnector(s.server.url("/directory"), "", false, nil, &ChallengeConfig{HTTPAddress: s.challengeAddress})
+	s.Require().NoError(err)
+
+	err = connector.Authenticate(ctx, &endpoint.Authentication{
+		ACMEAccount: &endpoint.ACMEAccount{Email: "admin@example.com", KeyFile: keyFile},
+	})
+	s.Require().NoError(err)
+	s.FileExists(keyFile)
+
+	req := &certificate.Request{
+		Subject:     pkix.Name{CommonName: "example.com"},
+		DNSNames:    []string{"www.example.com"},
+		CsrOrigin:   certificate.LocalGeneratedCSR,
+		ChainOption: certificate.ChainOptionRootFirst,
+		Timeout:     30 * time.Second,
+	}
+	s.Require().NoError(connector.GenerateRequest(ctx, nil, req))
+
+	pcc, err := connector.SynchronousRequestCertificate(ctx, req)
+	s.Require().NoError(err)
+	s.Len(pcc.Chain, 1)
+
+	block, _ := pem.Decode([]byte(pcc.Certificate))
+	s.Require().NotNil(block)
+	cert, err := x509.ParseCertificate(block.Bytes)
+	s.Require().NoError(err)
+	s.Equal("example.com", cert.Subject.CommonName)
+	s.Equal([]string{"www.example.com"}, cert.DNSNames)
+
+	// The challenge server is stopped once the challenges are cleaned up
+	_, err = net.DialTimeout("tcp", s.challengeAddress, time.Second)
+	s.Error(err)
+}
+
+func (s *ConnectorSuite) TestSynchronousRequestCertificate_NotAuthenticated() {
+	ctx := context.Background()
+	connector, err := NewConnector(s.server.url("/directory"), "", false, nil, nil)
+	s.Require().NoError(err)
+
+	_, err = connector.SynchronousRequestCertificate(ctx, &certificate.Request{})
+	s.Error(err)
+}
@@ -0,0 +1,359 @@
+/*
+ * Copyright 2023 Venafi, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *  http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+package acme
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+const (
+	// ChallengeHTTP01 is the http-01 challenge type: the key authorization is served over HTTP on port 80
+	ChallengeHTTP01 = "http-01"
+	// ChallengeDNS01 is the dns-01 challenge type: the key authorization digest is published in a TXT record
+	ChallengeDNS01 = "dns-01"
+
+	// DefaultHTTPAddress is the address the built-in http-01 server listens on
+	DefaultHTTPAddress = ":80"
+	// DefaultDNSPropagationWait is the time waited for dns-01 TXT records to propagate before validation
+	DefaultDNSPropagationWait = 30 * time.Second
+
+	httpChallengePath = "/.well-known/acme-challenge/"
+	dnsChallengeLabel = "_acme-challenge"
+)
+
+// ChallengeConfig describes the Solver used to fulfill the challenges of the ACME server
+type ChallengeConfig struct {
+	// Type is the challenge type to fulfill, either http-01 or dns-01. Defaults to http-01
+	Type string `yaml:"type,omitempty"`
+	// HTTPAddress is the address the built-in http-01 server listens on. Defaults to ":80"
+	HTTPAddress string `yaml:"httpAddress,omitempty"`
+	// Webroot is the document root of an existing web server. When set, http-01 challenge files are written
+	// to <webroot>/.well-known/acme-challenge instead of starting the built-in server
+	Webroot string `yaml:"webroot,omitempty"`
+	// DNSCommand is the command that creates and deletes the TXT records of dns-01 challenges. It is called with
+	// the arguments "present" or "cleanup", followed by the FQDN and the value of the record
+	DNSCommand string `yaml:"dnsCommand,omitempty"`
+	// DNSPropagationWait is the time waited after creating the TXT records, before asking the server to validate them.
+	// Defaults to 30s
+	DNSPropagationWait time.Duration `yaml:"dnsPropagationWait,omitempty"`
+}
+
+// IsValid returns an error if the ChallengeConfig cannot be used to create a Solver
+func (cfg ChallengeConfig) IsValid() error {
+	switch strings.ToLower(cfg.Type) {
+	case "", ChallengeHTTP01:
+		return nil
+	case ChallengeDNS01:
+		if cfg.DNSCommand == "" {
+			return fmt.Errorf("a dnsCommand is required to fulfill %s challenges", ChallengeDNS01)
+		}
+		return nil
+	default:
+		return fmt.Errorf("unsupported ACME challenge type %q. Should be either %s or %s", cfg.Type, ChallengeHTTP01, ChallengeDNS01)
+	}
+}
+
+// Solver fulfills the challenges the ACME server uses to validate the control of an identifier
+type Solver interface {
+	// ChallengeType returns the type of challenge the Solver fulfills, i.e. "http-01"
+	ChallengeType() string
+	// Present makes keyAuth available to the ACME server for the identifier and challenge token.
+	// keyAuth is the key authorization for http-01 challenges and the TXT record value for dns-01 challenges
+	Present(ctx context.Context, identifier string, token string, keyAuth string) error
+	// CleanUp removes what Present made available, once the challenge has been validated
+	CleanUp(ctx context.Context, identifier string, token string, keyAuth string) error
+}
+
+// waiter is implemented by a Solver that needs time after Present before the ACME server can validate the challenges
+type waiter interface {
+	Wait(ctx context.Context) error
+}
+
+// NewSolver returns the built-in Solver described by cfg
+func NewSolver(cfg ChallengeConfig) (Solver, error) {
+	err := cfg.IsValid()
+	if err != nil {
+		return nil, err
+	}
+
+	if strings.ToLower(cfg.Type) == ChallengeDNS01 {
+		wait := cfg.DNSPropagationWait
+		if wait == 0 {
+			wait = DefaultDNSPropagationWait
+		}
+		return &dnsCommandSolver{command: strings.Fields(cfg.DNSCommand), wait: wait}, nil
+	}
+
+	if cfg.Webroot != "" {
+		return &webrootSolver{webroot: cfg.Webroot}, nil
+	}
+
+	address := cfg.HTTPAddress
+	if address == "" {
+		address = DefaultHTTPAddress
+	}
+	return &httpSolver{address: address, tokens: make(map[string]string)}, nil
+}
+
+// httpSolver serves http-01 challenges from the built-in HTTP server listening on address, which runs while any
+// challenge is presented
+type httpSolver struct {
+	address string
+	mu      sync.Mutex
+	// tokens are the challenges presented by this solver, the server being shared by every solver on the same address
+	tokens map[string]string
+}
+
+func (s *httpSolver) ChallengeType() string {
+	return ChallengeHTTP01
+}
+
+func (s *httpSolver) Present(_ context.Context, _ string, token string, keyAuth string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, found := s.tokens[token]; found {
+		return nil
+	}
+
+	server, err := acquireChallengeServer(s.address)
+	if err != nil {
+		return err
+	}
+	server.setToken(token, keyAuth)
+	s.tokens[token] = keyAuth
+	return nil
+}
+
+func (s *httpSolver) CleanUp(_ context.Context, _ string, token string, _ string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, found := s.tokens[token]; !found {
+		return nil
+	}
+	delete(s.tokens, token)
+
+	challengeServersMu.Lock()
+	server := challengeServers[s.address]
+	challengeServersMu.Unlock()
+	if server == nil {
+		return nil
+	}
+	server.deleteToken(token)
+	return server.release()
+}
+
+// HoldHTTPServer starts the built-in http-01 challenge server on address, if it is not running yet, and keeps it
+// listening until release is called. Solvers on the same address then serve their challenges from it, which lets a
+// long-running process claim the port once instead of on every certificate request
+func HoldHTTPServer(address string) (release func() error, err error) {
+	if address == "" {
+		address = DefaultHTTPAddress
+	}
+
+	server, err := acquireChallengeServer(address)
+	if err != nil {
+		return nil, err
+	}
+
+	var once sync.Once
+	release = func() error {
+		var err2 error
+		once.Do(func() {
+			err2 = server.release()
+		})
+		return err2
+	}
+	return release, nil
+}
+
+var (
+	// challengeServersMu protects challengeServers and the reference count of every challengeServer
+	challengeServersMu sync.Mutex
+	// challengeServers are the running http-01 challenge servers, by listening address
+	challengeServers = make(map[string]*challengeServer)
+)
+
+// challengeServer is a built-in http-01 challenge server, shared by every solver and holder of its address
+type challengeServer struct {
+	address string
+	server  *http.Server
+	// refs is the number of presented challenges and holds keeping the server running
+	refs int
+
+	mu     sync.Mutex
+	tokens map[string]string
+}
+
+// acquireChallengeServer returns the challenge server listening on address, starting it if needed.
+// Every call must be matched by a call to release
+func acquireChallengeServer(address string) (*challengeServer, error) {
+	challengeServersMu.Lock()
+	defer challengeServersMu.Unlock()
+
+	if cs, found := challengeServers[address]; found {
+		cs.refs++
+		return cs, nil
+	}
+
+	listener, err := net.Listen("tcp", address)
+	if err != nil {
+		return nil, fmt.Errorf("could not start http-01 challenge server: %w", err)
+	}
+
+	cs := &challengeServer{address: address, refs: 1, tokens: make(map[string]string)}
+	cs.server = &http.Server{Handler: http.HandlerFunc(cs.serveHTTP), ReadHeaderTimeout: 10 * time.Second}
+	go func(server *http.Server) {
+		err := server.Serve(listener)
+		if err != nil && !errors.Is(err, http.ErrServerClosed) {
+			zap.L().Error("http-01 challenge server failed", fieldPlatform, zap.Error(err))
+		}
+	}(cs.server)
+	zap.L().Debug("started http-01 challenge server", fieldPlatform, zap.String("address", listener.Addr().String()))
+
+	challengeServers[address] = cs
+	return cs, nil
+}
+
+// release stops the server once nothing holds it anymore
+func (cs *challengeServer) release() error {
+	challengeServersMu.Lock()
+	cs.refs--
+	if cs.refs > 0 {
+		challengeServersMu.Unlock()
+		return nil
+	}
+	delete(challengeServers, cs.address)
+	challengeServersMu.Unlock()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	zap.L().Debug("stopping http-01 challenge server", fieldPlatform, zap.String("address", cs.address))
+	return cs.server.Shutdown(ctx)
+}
+
+func (cs *challengeServer) setToken(token string, keyAuth string) {
+	cs.mu.Lock()
+	defer cs.mu.Unlock()
+	cs.tokens[token] = keyAuth
+}
+
+func (cs *challengeServer) deleteToken(token string) {
+	cs.mu.Lock()
+	defer cs.mu.Unlock()
+	delete(cs.tokens, token)
+}
+
+func (cs *challengeServer) serveHTTP(w http.ResponseWriter, r *http.Request) {
+	if !strings.HasPrefix(r.URL.Path, httpChallengePath) {
+		http.NotFound(w, r)
+		return
+	}
+
+	cs.mu.Lock()
+	keyAuth, ok := cs.tokens[strings.TrimPrefix(r.URL.Path, httpChallengePath)]
+	cs.mu.Unlock()
+	if !ok {
+		http.NotFound(w, r)
+		return
+	}
+
+	w.Header().Set("Content-Type", "text/plain")
+	_, _ = w.Write([]byte(keyAuth))
+}
+
+// webrootSolver writes http-01 challenges as files served by an existing web server
+type webrootSolver struct {
+	webroot string
+}
+
+func (s *webrootSolver) ChallengeType() string {
+	return ChallengeHTTP01
+}
+
+func (s *webrootSolver) Present(_ context.Context, _ string, token string, keyAuth string) error {
+	dir := filepath.Join(s.webroot, filepath.FromSlash(httpChallengePath))
+	err := os.MkdirAll(dir, 0755)
+	if err != nil {
+		return err
+	}
+	// The file must be readable by the web server
+	return os.WriteFile(filepath.Join(dir, token), []byte(keyAuth), 0644) // #nosec G306
+}
+
+func (s *webrootSolver) CleanUp(_ context.Context, _ string, token string, _ string) error {
+	err := os.Remove(filepath.Join(s.webroot, filepath.FromSlash(httpChallengePath), token))
+	if errors.Is(err, os.ErrNotExist) {
+		return nil
+	}
+	return err
+}
+
+// dnsCommandSolver fulfills dns-01 challenges by running a command that manages the TXT records
+type dnsCommandSolver struct {
+	command []string
+	wait    time.Duration
+}
+
+func (s *dnsCommandSolver) ChallengeType() string {
+	return ChallengeDNS01
+}
+
+func (s *dnsCommandSolver) Present(ctx context.Context, identifier string, _ string, keyAuth string) error {
+	return s.run(ctx, "present", identifier, keyAuth)
+}
+
+func (s *dnsCommandSolver) CleanUp(ctx context.Context, identifier string, _ string, keyAuth string) error {
+	return s.run(ctx, "cleanup", identifier, keyAuth)
+}
+
+// Wait gives the DNS servers time to publish the TXT records
+func (s *dnsCommandSolver) Wait(ctx context.Context) error {
+	zap.L().Info("waiting for dns-01 TXT records to propagate", fieldPlatform, zap.Duration("wait", s.wait))
+	select {
+	case <-time.After(s.wait):
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+func (s *dnsCommandSolver) run(ctx context.Context, action string, identifier string, value string) error {
+	fqdn := fmt.Sprintf("%s.%s.", dnsChallengeLabel, strings.TrimSuffix(strings.TrimPrefix(identifier, "*."), "."))
+
+	args := append(append([]string{}, s.command[1:]...), action, fqdn, value)
+	// #nosec G204 -- the command is defined by the user in the challenge configuration
+	cmd := exec.CommandContext(ctx, s.command[0], args...)
+	output, err := cmd.CombinedOutput()
+	if err != nil {
+		return fmt.Errorf("dns-01 command failed on %s %s: %w: %s", action, fqdn, err, strings.TrimSpace(string(output)))
+	}
+	zap.L().Debug("dns-01 command succeeded", fieldPlatform, zap.String("action", action), zap.String("fqdn", fqdn))
+	return nil
+}
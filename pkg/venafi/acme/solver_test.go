@@ -0,0 +1,169 @@
+/*
+ * Copyright 2023 Venafi, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *  http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+package acme
+
+import (
+	"context"
+	"io"
+	"net"
+	"net/http"
+	"os"
+	"path/filepath"
+	"runtime"
+	"testing"
+
+	"github.com/stretchr/testify/suite"
+)
+
+type SolverSuite struct {
+	suite.Suite
+}
+
+func TestSolverSuite(t *testing.T) {
+	suite.Run(t, new(SolverSuite))
+}
+
+func (s *SolverSuite) TestChallengeConfig_IsValid() {
+	s.NoError(ChallengeConfig{}.IsValid())
+	s.NoError(ChallengeConfig{Type: "HTTP-01"}.IsValid())
+	s.NoError(ChallengeConfig{Type: ChallengeDNS01, DNSCommand: "hook.sh"}.IsValid())
+	s.Error(ChallengeConfig{Type: ChallengeDNS01}.IsValid())
+	s.Error(ChallengeConfig{Type: "tls-alpn-01"}.IsValid())
+}
+
+func (s *SolverSuite) TestHTTPSolver() {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	s.Require().NoError(err)
+	address := listener.Addr().String()
+	_ = listener.Close()
+
+	solver, err := NewSolver(ChallengeConfig{HTTPAddress: address})
+	s.Require().NoError(err)
+
+	s.Require().NoError(solver.Present(context.Background(), "example.com", "token", "token.thumbprint"))
+
+	res, err := http.Get("http://" + address + "/.well-known/acme-challenge/token")
+	s.Require().NoError(err)
+	body, _ := io.ReadAll(res.Body)
+	_ = res.Body.Close()
+	s.Equal(http.StatusOK, res.StatusCode)
+	s.Equal("token.thumbprint", string(body))
+
+	res, err = http.Get("http://" + address + "/.well-known/acme-challenge/other")
+	s.Require().NoError(err)
+	_ = res.Body.Close()
+	s.Equal(http.StatusNotFound, res.StatusCode)
+
+	s.NoError(solver.CleanUp(context.Background(), "example.com", "token", "token.thumbprint"))
+}
+
+func (s *SolverSuite) TestHTTPSolver_SharedServer() {
+	address := s.freeAddress()
+
+	first, err := NewSolver(ChallengeConfig{HTTPAddress: address})
+	s.Require().NoError(err)
+	second, err := NewSolver(ChallengeConfig{HTTPAddress: address})
+	s.Require().NoError(err)
+
+	s.Require().NoError(first.Present(context.Background(), "a.example.com", "first", "first.thumbprint"))
+	s.Require().NoError(second.Present(context.Background(), "b.example.com", "second", "second.thumbprint"))
+	s.Equal(http.StatusOK, s.getChallenge(address, "first"))
+	s.Equal(http.StatusOK, s.getChallenge(address, "second"))
+
+	// The server keeps running while the second solver presents its challenge
+	s.NoError(first.CleanUp(context.Background(), "a.example.com", "first", "first.thumbprint"))
+	s.Equal(http.StatusNotFound, s.getChallenge(address, "first"))
+	s.Equal(http.StatusOK, s.getChallenge(address, "second"))
+
+	s.NoError(second.CleanUp(context.Background(), "b.example.com", "second", "second.thumbprint"))
+	_, err = http.Get("http://" + address + "/.well-known/acme-challenge/second")
+	s.Error(err)
+}
+
+func (s *SolverSuite) TestHoldHTTPServer() {
+	address := s.freeAddress()
+
+	release, err := HoldHTTPServer(address)
+	s.Require().NoError(err)
+
+	solver, err := NewSolver(ChallengeConfig{HTTPAddress: address})
+	s.Require().NoError(err)
+	s.Require().NoError(solver.Present(context.Background(), "example.com", "token", "token.thumbprint"))
+	s.Equal(http.StatusOK, s.getChallenge(address, "token"))
+	s.NoError(solver.CleanUp(context.Background(), "example.com", "token", "token.thumbprint"))
+
+	// The held server is still listening once the challenge is cleaned up
+	s.Equal(http.StatusNotFound, s.getChallenge(address, "token"))
+
+	s.NoError(release())
+	s.NoError(release())
+	_, err = http.Get("http://" + address + "/.well-known/acme-challenge/token")
+	s.Error(err)
+}
+
+func (s *SolverSuite) freeAddress() string {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	s.Require().NoError(err)
+	address := listener.Addr().String()
+	_ = listener.Close()
+	return address
+}
+
+func (s *SolverSuite) getChallenge(address string, token string) int {
+	res, err := http.Get("http://" + address + "/.well-known/acme-challenge/" + token)
+	s.Require().NoError(err)
+	_ = res.Body.Close()
+	return res.StatusCode
+}
+
+func (s *SolverSuite) TestWebrootSolver() {
+	webroot := s.T().TempDir()
+	solver, err := NewSolver(ChallengeConfig{Webroot: webroot})
+	s.Require().NoError(err)
+	s.Equal(ChallengeHTTP01, solver.ChallengeType())
+
+	file := filepath.Join(webroot, ".well-known", "acme-challenge", "token")
+	s.Require().NoError(solver.Present(context.Background(), "example.com", "token", "token.thumbprint"))
+	content, err := os.ReadFile(file)
+	s.NoError(err)
+	s.Equal("token.thumbprint", string(content))
+
+	s.NoError(solver.CleanUp(context.Background(), "example.com", "token", "token.thumbprint"))
+	s.NoFileExists(file)
+}
+
+func (s *SolverSuite) TestDNSCommandSolver() {
+	if runtime.GOOS == "windows" {
+		s.T().Skip("the test hook is a shell script")
+	}
+
+	dir := s.T().TempDir()
+	output := filepath.Join(dir, "calls.txt")
+	hook := filepath.Join(dir, "hook.sh")
+	err := os.WriteFile(hook, []byte("#!/bin/sh\necho \"$1 $2 $3 $4\" >> \"$1\"\n"), 0700)
+	s.Require().NoError(err)
+
+	solver, err := NewSolver(ChallengeConfig{Type: ChallengeDNS01, DNSCommand: hook + " " + output})
+	s.Require().NoError(err)
+	s.Equal(ChallengeDNS01, solver.ChallengeType())
+
+	s.Require().NoError(solver.Present(context.Background(), "*.example.com", "token", "digest"))
+	s.Require().NoError(solver.CleanUp(context.Background(), "*.example.com", "token", "digest"))
+
+	content, err := os.ReadFile(output)
+	s.NoError(err)
+	s.Equal(output+" present _acme-challenge.example.com. digest\n"+output+" cleanup _acme-challenge.example.com. digest\n", string(content))
+}
@@ -0,0 +1,128 @@
+/*
+ * Copyright 2023 Venafi, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *  http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package cloud
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+
+	"github.com/Venafi/vcert/v5/pkg/policy"
+	"github.com/Venafi/vcert/v5/pkg/verror"
+)
+
+// ApplicationRequest describes an application provisioned by SetApplication
+type ApplicationRequest struct {
+	Name        string
+	Description string
+	// Owners are the names of the users and teams owning the application. A new application is owned by the user
+	// of the connector when empty, and the owners of an existing application are kept
+	Owners []string
+	// IssuingTemplates are the names of the issuing templates assigned to the application. The templates already
+	// assigned to an existing application are kept
+	IssuingTemplates []string
+}
+
+// SetApplication creates the application described by req, or updates it when it already exists, and assigns the
+// issuing templates of req to it. The issuing templates must exist already, they are created by SetPolicy
+func (c *Connector) SetApplication(ctx context.Context, req ApplicationRequest) (*policy.Application, error) {
+	if req.Name == "" {
+		return nil, fmt.Errorf("%w: application name is empty", verror.UserDataError)
+	}
+
+	cits := make([]*certificateTemplate, 0, len(req.IssuingTemplates))
+	for _, name := range req.IssuingTemplates {
+		cit, err := getCit(ctx, c, name)
+		if err != nil {
+			return nil, err
+		}
+		if cit == nil {
+			return nil, fmt.Errorf("%w: issuing template %s not found. Apply a policy specification to create it",
+				verror.UserDataError, name)
+		}
+		cits = append(cits, cit)
+	}
+
+	var owners []policy.OwnerIdType
+	var err error
+	if len(req.Owners) > 0 {
+		owners, err = c.resolveOwners(ctx, req.Owners)
+		if err != nil {
+			return nil, fmt.Errorf("an error happened trying to resolve the owners: %w", err)
+		}
+	}
+
+	appDetails, statusCode, err := c.getAppDetailsByName(ctx, req.Name)
+	notFound := errors.Is(err, verror.ApplicationNotFoundError) || statusCode == http.StatusNotFound
+	if err != nil && !notFound {
+		return nil, err
+	}
+
+	var app policy.Application
+	method := "POST"
+	expected := http.StatusCreated
+	url := c.getURL(urlAppRoot)
+	if notFound {
+		log.Printf("creating application: %s", req.Name)
+		if owners == nil {
+			owner, err := c.getOwnerFromUserDetails(ctx)
+			if err != nil {
+				return nil, fmt.Errorf("an error happened trying to resolve the owners: %w", err)
+			}
+			owners = []policy.OwnerIdType{*owner}
+		}
+		app = policy.Application{Name: req.Name}
+	} else {
+		log.Printf("updating application: %s", req.Name)
+		app = createAppUpdateRequest(appDetails)
+		method = "PUT"
+		expected = http.StatusOK
+		url = fmt.Sprint(url, "/", appDetails.ApplicationId)
+	}
+
+	if owners != nil {
+		app.OwnerIdsAndTypes = owners
+	}
+	if req.Description != "" {
+		app.Description = req.Description
+	}
+	if app.CertificateIssuingTemplateAliasIdMap == nil {
+		app.CertificateIssuingTemplateAliasIdMap = make(map[string]string)
+	}
+	for _, cit := range cits {
+		c.addCitToApp(&app, cit)
+		if c.zoneCache != nil {
+			c.zoneCache.Invalidate(c.baseURL, req.Name+"\\"+cit.Name)
+		}
+	}
+
+	statusCode, status, body, err := c.request(ctx, method, url, app)
+	if err != nil {
+		return nil, err
+	}
+	if statusCode != expected {
+		respErrors, err := parseResponseErrors(body)
+		if err == nil && len(respErrors) > 0 {
+			return nil, fmt.Errorf("%w: unexpected result %s provisioning application %s: %s", verror.ServerError,
+				status, req.Name, respErrors[0].Message)
+		}
+		return nil, fmt.Errorf("%w: unexpected result %s provisioning application %s", verror.ServerError, status, req.Name)
+	}
+	return &app, nil
+}
@@ -0,0 +1,119 @@
+/*
+ * Copyright 2023 Venafi, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *  http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package cloud
+
+import (
+	"context"
+	"crypto/x509"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/Venafi/vcert/v5/pkg/policy"
+	"github.com/Venafi/vcert/v5/pkg/verror"
+)
+
+// Provisioning is tested against a mock server, so applications can be created without leaving them behind
+func TestSetApplication(t *testing.T) {
+	ctx := context.Background()
+	apps := map[string]policy.Application{}
+	server := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		switch {
+		case r.URL.Path == "/v1/certificateissuingtemplates":
+			_, _ = w.Write([]byte(`{"certificateIssuingTemplates":[{"id":"cit-1","name":"Default"},{"id":"cit-2","name":"Web"}]}`))
+		case r.URL.Path == "/v1/useraccounts":
+			_, _ = w.Write([]byte(`{"user":{"id":"user-1","username":"me"},"company":{"id":"company-1"}}`))
+		case r.URL.Path == "/v1/users/username/jane":
+			_, _ = w.Write([]byte(`{"users":[{"id":"user-2","username":"jane"}]}`))
+		case r.URL.Path == "/v1/teams":
+			_, _ = w.Write([]byte(`{"teams":[{"id":"team-1","name":"Ops"}]}`))
+		case strings.HasPrefix(r.URL.Path, "/v1/users/username/"):
+			w.WriteHeader(http.StatusNotFound)
+		case strings.HasPrefix(r.URL.Path, "/outagedetection/v1/applications/name/"):
+			app, found := apps[strings.TrimPrefix(r.URL.Path, "/outagedetection/v1/applications/name/")]
+			if !found {
+				w.WriteHeader(http.StatusNotFound)
+				_, _ = w.Write([]byte(`{"errors":[{"code":10051,"message":"Unable to find application"}]}`))
+				return
+			}
+			_ = json.NewEncoder(w).Encode(ApplicationDetails{ApplicationId: "app-" + app.Name, Name: app.Name,
+				Description: app.Description, OwnerIdType: app.OwnerIdsAndTypes, CitAliasToIdMap: app.CertificateIssuingTemplateAliasIdMap})
+		case r.URL.Path == "/outagedetection/v1/applications" && r.Method == "POST",
+			strings.HasPrefix(r.URL.Path, "/outagedetection/v1/applications/app-") && r.Method == "PUT":
+			app := policy.Application{}
+			if err := json.NewDecoder(r.Body).Decode(&app); err != nil {
+				t.Errorf("invalid application: %s", err)
+			}
+			apps[app.Name] = app
+			if r.Method == "POST" {
+				w.WriteHeader(http.StatusCreated)
+			}
+			_, _ = w.Write([]byte(`{}`))
+		default:
+			t.Errorf("unexpected request %s %s", r.Method, r.URL.Path)
+			w.WriteHeader(http.StatusBadRequest)
+		}
+	}))
+	defer server.Close()
+
+	trust := x509.NewCertPool()
+	trust.AddCert(server.Certificate())
+	c, err := NewConnector(server.URL, "", false, trust)
+	if err != nil {
+		t.Fatal(err)
+	}
+	c.apiKey = "key"
+	c.user = &userDetails{User: &user{ID: "user-1"}, Company: &company{}}
+
+	_, err = c.SetApplication(ctx, ApplicationRequest{Name: "MyApp", IssuingTemplates: []string{"Default"}, Description: "My app"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	created := apps["MyApp"]
+	if created.Description != "My app" || !reflect.DeepEqual(created.CertificateIssuingTemplateAliasIdMap, map[string]string{"Default": "cit-1"}) {
+		t.Fatalf("unexpected application created %+v", created)
+	}
+	if !reflect.DeepEqual(created.OwnerIdsAndTypes, []policy.OwnerIdType{{OwnerId: "user-1", OwnerType: UserType.String()}}) {
+		t.Fatalf("expected the application to be owned by the current user, got %+v", created.OwnerIdsAndTypes)
+	}
+
+	_, err = c.SetApplication(ctx, ApplicationRequest{Name: "MyApp", IssuingTemplates: []string{"Web"}, Owners: []string{"jane", "Ops"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	updated := apps["MyApp"]
+	if updated.Description != "My app" {
+		t.Fatalf("expected the description to be kept, got %q", updated.Description)
+	}
+	if !reflect.DeepEqual(updated.CertificateIssuingTemplateAliasIdMap, map[string]string{"Default": "cit-1", "Web": "cit-2"}) {
+		t.Fatalf("expected the issuing template to be added, got %v", updated.CertificateIssuingTemplateAliasIdMap)
+	}
+	expectedOwners := []policy.OwnerIdType{{OwnerId: "user-2", OwnerType: UserType.String()}, {OwnerId: "team-1", OwnerType: TeamType.String()}}
+	if !reflect.DeepEqual(updated.OwnerIdsAndTypes, expectedOwners) {
+		t.Fatalf("unexpected owners %+v", updated.OwnerIdsAndTypes)
+	}
+
+	_, err = c.SetApplication(ctx, ApplicationRequest{Name: "MyApp", IssuingTemplates: []string{"Missing"}})
+	if !errors.Is(err, verror.UserDataError) {
+		t.Fatalf("expected an error assigning a missing issuing template, got %v", err)
+	}
+}
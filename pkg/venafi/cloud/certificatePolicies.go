@@ -20,6 +20,9 @@ import (
 	"strings"
 	"time"
 
+	"github.com/sosodev/duration"
+	"go.uber.org/zap"
+
 	"github.com/Venafi/vcert/v5/pkg/certificate"
 	"github.com/Venafi/vcert/v5/pkg/endpoint"
 )
@@ -119,6 +122,16 @@ func (ct certificateTemplate) toPolicy() (p endpoint.Policy) {
 	}
 	p.AllowWildcards = allowWildCards
 
+	if ct.ValidityPeriod != "" {
+		d, err := duration.Parse(ct.ValidityPeriod)
+		if err != nil {
+			zap.L().Warn("could not parse validity period of certificate issuing template",
+				zap.String("validityPeriod", ct.ValidityPeriod), zap.Error(err))
+		} else {
+			p.MaxValidity = d.ToTimeDuration()
+		}
+	}
+
 	for _, kt := range ct.KeyTypes {
 		keyConfiguration := endpoint.AllowedKeyConfiguration{}
 		if err := keyConfiguration.KeyType.Set(string(kt.KeyType), ""); err != nil {
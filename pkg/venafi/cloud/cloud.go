@@ -18,13 +18,12 @@ package cloud
 
 import (
 	"bytes"
+	"context"
 	"crypto/sha1"
-	"crypto/tls"
 	"encoding/json"
 	"fmt"
 	"io"
 	"log"
-	"net"
 	"net/http"
 	"sort"
 	"strconv"
@@ -237,11 +236,11 @@ type ApplicationDetails struct {
 }
 
 // GenerateRequest generates a CertificateRequest based on the zone configuration, and returns the request along with the private key.
-func (c *Connector) GenerateRequest(config *endpoint.ZoneConfiguration, req *certificate.Request) (err error) {
+func (c *Connector) GenerateRequest(ctx context.Context, config *endpoint.ZoneConfiguration, req *certificate.Request) (err error) {
 	switch req.CsrOrigin {
 	case certificate.LocalGeneratedCSR:
 		if config == nil {
-			config, err = c.ReadZoneConfiguration()
+			config, err = c.ReadZoneConfiguration(ctx)
 			if err != nil {
 				return fmt.Errorf("could not read zone configuration: %w", err)
 			}
@@ -259,8 +258,12 @@ func (c *Connector) GenerateRequest(config *endpoint.ZoneConfiguration, req *cer
 		return nil
 
 	case certificate.ServiceGeneratedCSR:
-		if req.KeyType == certificate.KeyTypeED25519 {
-			return fmt.Errorf("%w: ED25519 keys are not yet supported for Service Generated CSR", verror.UserDataError)
+		if req.KeyType.IsPostQuantum() {
+			return fmt.Errorf("%w: %s keys are only supported with a local generated or user provided CSR", verror.UserDataError, req.KeyType.String())
+		}
+		// The CSR attributes of VaaS have no field for them, so they can only be requested in a CSR
+		if len(req.Extensions) > 0 || len(req.ExtKeyUsages) > 0 {
+			return fmt.Errorf("%w: custom extensions and extended key usages are only supported with a local generated or user provided CSR", verror.UserDataError)
 		}
 		return nil
 
@@ -277,38 +280,28 @@ func (c *Connector) getHTTPClient() *http.Client {
 	if c.client != nil {
 		return c.client
 	}
-	var netTransport = &http.Transport{
-		Proxy: http.ProxyFromEnvironment,
-		DialContext: (&net.Dialer{
-			Timeout:   30 * time.Second,
-			KeepAlive: 30 * time.Second,
-			DualStack: true,
-		}).DialContext,
-		MaxIdleConns:          100,
-		IdleConnTimeout:       90 * time.Second,
-		TLSHandshakeTimeout:   10 * time.Second,
-		ExpectContinueTimeout: 1 * time.Second,
-	}
-	tlsConfig := http.DefaultTransport.(*http.Transport).TLSClientConfig
-	/* #nosec */
-	if c.trust != nil {
-		if tlsConfig == nil {
-			tlsConfig = &tls.Config{}
-		} else {
-			tlsConfig = tlsConfig.Clone()
-		}
-		tlsConfig.RootCAs = c.trust
-	}
-	netTransport.TLSClientConfig = tlsConfig
+	netTransport := util.NewTransportWithTLSConfig(c.transport, c.proxy, c.trust, c.tlsConfig)
 	c.client = &http.Client{
-		Timeout:   time.Second * 30,
-		Transport: netTransport,
+		Transport: &util.RetryTransport{
+			Base:    netTransport,
+			Policy:  c.retryPolicy,
+			Timeout: time.Second * 30,
+		},
 	}
 	return c.client
 }
 
-func (c *Connector) request(method string, url string, data interface{}, authNotRequired ...bool) (statusCode int, statusText string, body []byte, err error) {
-	if c.user == nil || c.user.Company == nil {
+// isAuthenticated reports whether the connector holds user details obtained with an API key,
+// or has been set up to authenticate with a service account
+func (c *Connector) isAuthenticated() bool {
+	if c.serviceAccount != nil {
+		return true
+	}
+	return c.user != nil && c.user.Company != nil
+}
+
+func (c *Connector) request(ctx context.Context, method string, url string, data interface{}, authNotRequired ...bool) (statusCode int, statusText string, body []byte, err error) {
+	if !c.isAuthenticated() {
 		if !(len(authNotRequired) == 1 && authNotRequired[0]) {
 			err = fmt.Errorf("%w: must be autheticated to retrieve certificate", verror.VcertError)
 			return
@@ -325,13 +318,20 @@ func (c *Connector) request(method string, url string, data interface{}, authNot
 		payload = bytes.NewReader(b)
 	}
 
-	r, err := http.NewRequest(method, url, payload)
+	r, err := http.NewRequestWithContext(ctx, method, url, payload)
 	if err != nil {
 		err = fmt.Errorf("%w: %v", verror.VcertError, err)
 		return
 	}
 	if c.apiKey != "" {
 		r.Header.Add("tppl-api-key", c.apiKey)
+	} else if c.serviceAccount != nil {
+		var token string
+		token, err = c.getAccessToken(ctx)
+		if err != nil {
+			return
+		}
+		r.Header.Add("Authorization", "Bearer "+token)
 	}
 	if method == "POST" {
 		r.Header.Add("Accept", "application/json")
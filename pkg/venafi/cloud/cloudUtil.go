@@ -1,6 +1,7 @@
 package cloud
 
 import (
+	"context"
 	"encoding/json"
 	"fmt"
 	"net/http"
@@ -64,9 +65,9 @@ func Load32KeyByte(keyBytes []byte) (*[32]byte, error) {
 	return key, nil
 }
 
-func getCsrAttributes(c *Connector, req *certificate.Request) (*CsrAttributes, error) {
+func getCsrAttributes(ctx context.Context, c *Connector, req *certificate.Request) (*CsrAttributes, error) {
 	zone := c.zone.zone
-	policy, err := c.GetPolicyWithRegex(zone)
+	policy, err := c.GetPolicyWithRegex(ctx, zone)
 
 	if err != nil {
 		return nil, err
@@ -213,6 +214,12 @@ func getCsrAttributes(c *Connector, req *certificate.Request) (*CsrAttributes, e
 		} else {
 			keyTypeParam.KeyLength = util.GetIntRef(2048)
 		}
+	} else if req.KeyType == certificate.KeyTypeED25519 {
+		// VaaS handles ED25519 as one more elliptic curve
+		keyTypeParam.KeyType = "EC"
+		curve := certificate.EllipticCurveED25519
+		keyCurve := curve.String()
+		keyTypeParam.KeyCurve = &keyCurve
 	} else if req.KeyType == certificate.KeyTypeECDSA {
 		keyTypeParam.KeyType = "EC"
 		if req.KeyCurve.String() != "" {
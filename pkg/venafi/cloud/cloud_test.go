@@ -17,6 +17,7 @@
 package cloud
 
 import (
+	"context"
 	"net/http"
 	"regexp"
 	"testing"
@@ -83,6 +84,7 @@ func TestUpdateRequest(t *testing.T) {
 }
 
 func TestGenerateRequest(t *testing.T) {
+	ctx := context.Background()
 
 	keyTypeRSA := certificate.KeyTypeRSA
 	keyTypeEC := certificate.KeyTypeECDSA
@@ -125,11 +127,11 @@ func TestGenerateRequest(t *testing.T) {
 			"",
 		},
 		{
-			"GenerateRequest-ED25519",
+			"GenerateRequest-ED25519-ServiceGenerated",
 			&keyTypeED25519,
 			&csrOriginServiceGenerated,
 			&certificate.Request{},
-			"ED25519 keys are not yet supported for Service Generated CSR",
+			"",
 		},
 	}
 
@@ -161,7 +163,7 @@ func TestGenerateRequest(t *testing.T) {
 			zoneConfig.UpdateCertificateRequest(req)
 
 			conn := Connector{}
-			err := conn.GenerateRequest(zoneConfig, req)
+			err := conn.GenerateRequest(ctx, zoneConfig, req)
 			if err != nil {
 				if c.expectedError != "" {
 					regexErr := regexp.MustCompile(c.expectedError)
@@ -19,7 +19,9 @@ package cloud
 import (
 	"archive/zip"
 	"bytes"
+	"context"
 	"crypto/rand"
+	"crypto/tls"
 	"crypto/x509"
 	"encoding/base64"
 	"encoding/json"
@@ -30,6 +32,7 @@ import (
 	"net/http"
 	netUrl "net/url"
 	"strings"
+	"sync"
 	"time"
 
 	"golang.org/x/crypto/nacl/box"
@@ -87,21 +90,32 @@ type Connector struct {
 	trust   *x509.CertPool
 	zone    cloudZone
 	client  *http.Client
+
+	retryPolicy util.RetryPolicy
+	proxy       util.ProxyConfig
+	transport   util.TransportConfig
+	tlsConfig   *tls.Config
+	zoneCache   *endpoint.ZoneConfigurationCache
+
+	serviceAccount    *serviceAccount
+	accessToken       string
+	accessTokenExpiry time.Time
+	tokenMutex        sync.Mutex
 }
 
-func (c *Connector) RetrieveCertificateMetaData(dn string) (*certificate.CertificateMetaData, error) {
+func (c *Connector) RetrieveCertificateMetaData(ctx context.Context, dn string) (*certificate.CertificateMetaData, error) {
 	panic("operation is not supported yet")
 }
 
-func (c *Connector) SearchCertificates(req *certificate.SearchRequest) (*certificate.CertSearchResponse, error) {
+func (c *Connector) SearchCertificates(ctx context.Context, req *certificate.SearchRequest) (*certificate.CertSearchResponse, error) {
 	panic("operation is not supported yet")
 }
 
-func (c *Connector) SearchCertificate(zone string, cn string, sans *certificate.Sans, certMinTimeLeft time.Duration) (certificateInfo *certificate.CertificateInfo, err error) {
+func (c *Connector) SearchCertificate(ctx context.Context, zone string, cn string, sans *certificate.Sans, certMinTimeLeft time.Duration) (certificateInfo *certificate.CertificateInfo, err error) {
 	// retrieve application name from zone
 	appName := getAppNameFromZone(zone)
 	// get application id from name
-	app, _, err := c.getAppDetailsByName(appName)
+	app, _, err := c.getAppDetailsByName(ctx, appName)
 	if err != nil {
 		return nil, err
 	}
@@ -110,7 +124,7 @@ func (c *Connector) SearchCertificate(zone string, cn string, sans *certificate.
 	req := formatSearchCertificateArguments(cn, sans, certMinTimeLeft)
 
 	// perform request
-	searchResult, err := c.searchCertificates(req)
+	searchResult, err := c.searchCertificates(ctx, req)
 	if err != nil {
 		return nil, err
 	}
@@ -141,27 +155,27 @@ func (c *Connector) SearchCertificate(zone string, cn string, sans *certificate.
 	return certificate.FindNewestCertificateWithSans(certificates, sans)
 }
 
-func (c *Connector) IsCSRServiceGenerated(req *certificate.Request) (bool, error) {
-	if c.user == nil || c.user.Company == nil {
+func (c *Connector) IsCSRServiceGenerated(ctx context.Context, req *certificate.Request) (bool, error) {
+	if !c.isAuthenticated() {
 		return false, fmt.Errorf("must be autheticated to retieve certificate")
 	}
 
-	if req.PickupID == "" && req.CertID == "" && req.Thumbprint != "" {
-		// search cert by Thumbprint and fill pickupID
+	if req.PickupID == "" && req.CertID == "" && (req.Thumbprint != "" || req.SerialNumber != "") {
+		// search cert by Thumbprint or serial number and fill pickupID
 		var certificateRequestId string
-		searchResult, err := c.searchCertificatesByFingerprint(req.Thumbprint)
+		searchResult, searchedBy, err := c.searchCertificatesOfRequest(ctx, req)
 		if err != nil {
 			return false, fmt.Errorf("failed to retrieve certificate: %s", err)
 		}
 		if len(searchResult.Certificates) == 0 {
-			return false, fmt.Errorf("no certificate found using fingerprint %s", req.Thumbprint)
+			return false, fmt.Errorf("no certificate found using %s", searchedBy)
 		}
 
 		var reqIds []string
 		for _, c := range searchResult.Certificates {
 			reqIds = append(reqIds, c.CertificateRequestId)
 			if certificateRequestId != "" && certificateRequestId != c.CertificateRequestId {
-				return false, fmt.Errorf("more than one CertificateRequestId was found with the same Fingerprint: %s", reqIds)
+				return false, fmt.Errorf("more than one CertificateRequestId was found using %s: %s", searchedBy, reqIds)
 			}
 			if c.CertificateRequestId != "" {
 				certificateRequestId = c.CertificateRequestId
@@ -177,12 +191,12 @@ func (c *Connector) IsCSRServiceGenerated(req *certificate.Request) (bool, error
 	var currentId string
 	var err error
 	if req.CertID != "" {
-		dekInfo, err = getDekInfo(c, req.CertID)
+		dekInfo, err = getDekInfo(ctx, c, req.CertID)
 	} else {
 		var certificateId string
-		certificateId, err = getCertificateId(c, req)
+		certificateId, err = getCertificateId(ctx, c, req)
 		if err == nil && certificateId != "" {
-			dekInfo, err = getDekInfo(c, certificateId)
+			dekInfo, err = getDekInfo(ctx, c, certificateId)
 		}
 	}
 
@@ -193,7 +207,7 @@ func (c *Connector) IsCSRServiceGenerated(req *certificate.Request) (bool, error
 	return false, nil
 }
 
-func getCertificateId(c *Connector, req *certificate.Request) (string, error) {
+func getCertificateId(ctx context.Context, c *Connector, req *certificate.Request) (string, error) {
 	startTime := time.Now()
 	//Wait for certificate to be issued by checking it's PickupID
 	//If certID is filled then certificate should be already issued.
@@ -201,7 +215,7 @@ func getCertificateId(c *Connector, req *certificate.Request) (string, error) {
 		if req.PickupID == "" {
 			break
 		}
-		certStatus, err := c.getCertificateStatus(req.PickupID)
+		certStatus, err := c.getCertificateStatus(ctx, req.PickupID)
 		if err != nil {
 			return "", fmt.Errorf("unable to retrieve: %s", err)
 		}
@@ -218,41 +232,51 @@ func getCertificateId(c *Connector, req *certificate.Request) (string, error) {
 		if time.Now().After(startTime.Add(req.Timeout)) {
 			return "", endpoint.ErrRetrieveCertificateTimeout{CertificateID: req.PickupID}
 		}
-		time.Sleep(2 * time.Second)
+		if err = util.Sleep(ctx, 2*time.Second); err != nil {
+			return "", err
+		}
 	}
 
 	return "", endpoint.ErrRetrieveCertificateTimeout{CertificateID: req.PickupID}
 }
 
-func (c *Connector) RetrieveSshConfig(ca *certificate.SshCaTemplateRequest) (*certificate.SshConfig, error) {
+func (c *Connector) RetrieveSshConfig(ctx context.Context, ca *certificate.SshCaTemplateRequest) (*certificate.SshConfig, error) {
 	panic("operation is not supported yet")
 }
 
-func (c *Connector) RetrieveSSHCertificate(req *certificate.SshCertRequest) (response *certificate.SshCertificateObject, err error) {
+func (c *Connector) RetrieveSSHCertificate(ctx context.Context, req *certificate.SshCertRequest) (response *certificate.SshCertificateObject, err error) {
 	panic("operation is not supported yet")
 }
 
-func (c *Connector) RequestSSHCertificate(req *certificate.SshCertRequest) (response *certificate.SshCertificateObject, err error) {
+func (c *Connector) RenewSSHCertificate(ctx context.Context, req *certificate.SshCertRequest) (response *certificate.SshCertificateObject, err error) {
 	panic("operation is not supported yet")
 }
 
-func (c *Connector) RetrieveAvailableSSHTemplates() (response []certificate.SshAvaliableTemplate, err error) {
+func (c *Connector) RevokeSSHCertificate(ctx context.Context, req *certificate.SshCertRequest) error {
 	panic("operation is not supported yet")
 }
 
-func (c *Connector) RetrieveSystemVersion() (response string, err error) {
+func (c *Connector) RequestSSHCertificate(ctx context.Context, req *certificate.SshCertRequest) (response *certificate.SshCertificateObject, err error) {
 	panic("operation is not supported yet")
 }
 
-func (c *Connector) GetPolicyWithRegex(name string) (*policy.PolicySpecification, error) {
+func (c *Connector) RetrieveAvailableSSHTemplates(ctx context.Context) (response []certificate.SshAvaliableTemplate, err error) {
+	panic("operation is not supported yet")
+}
 
-	cit, err := retrievePolicySpecification(c, name)
+func (c *Connector) RetrieveSystemVersion(ctx context.Context) (response string, err error) {
+	panic("operation is not supported yet")
+}
+
+func (c *Connector) GetPolicyWithRegex(ctx context.Context, name string) (*policy.PolicySpecification, error) {
+
+	cit, err := retrievePolicySpecification(ctx, c, name)
 
 	if err != nil {
 		return nil, err
 	}
 
-	info, err := getCertificateAuthorityInfoFromCloud(cit.CertificateAuthority, cit.CertificateAuthorityAccountId, cit.CertificateAuthorityProductOptionId, c)
+	info, err := getCertificateAuthorityInfoFromCloud(ctx, cit.CertificateAuthority, cit.CertificateAuthorityAccountId, cit.CertificateAuthorityProductOptionId, c)
 
 	if err != nil {
 		return nil, err
@@ -264,7 +288,7 @@ func (c *Connector) GetPolicyWithRegex(name string) (*policy.PolicySpecification
 	return ps, nil
 }
 
-func retrievePolicySpecification(c *Connector, name string) (*certificateTemplate, error) {
+func retrievePolicySpecification(ctx context.Context, c *Connector, name string) (*certificateTemplate, error) {
 	appName := policy.GetApplicationName(name)
 	if appName != "" {
 		c.zone.appName = appName
@@ -279,7 +303,7 @@ func retrievePolicySpecification(c *Connector, name string) (*certificateTemplat
 	}
 
 	log.Println("Getting CIT")
-	cit, err := c.getTemplateByID()
+	cit, err := c.getTemplateByID(ctx)
 
 	if err != nil {
 		return nil, err
@@ -289,14 +313,14 @@ func retrievePolicySpecification(c *Connector, name string) (*certificateTemplat
 
 }
 
-func (c *Connector) GetPolicy(name string) (*policy.PolicySpecification, error) {
+func (c *Connector) GetPolicy(ctx context.Context, name string) (*policy.PolicySpecification, error) {
 
-	cit, err := retrievePolicySpecification(c, name)
+	cit, err := retrievePolicySpecification(ctx, c, name)
 	if err != nil {
 		return nil, err
 	}
 
-	info, err := getCertificateAuthorityInfoFromCloud(cit.CertificateAuthority, cit.CertificateAuthorityAccountId, cit.CertificateAuthorityProductOptionId, c)
+	info, err := getCertificateAuthorityInfoFromCloud(ctx, cit.CertificateAuthority, cit.CertificateAuthorityAccountId, cit.CertificateAuthorityProductOptionId, c)
 
 	if err != nil {
 		return nil, err
@@ -306,7 +330,7 @@ func (c *Connector) GetPolicy(name string) (*policy.PolicySpecification, error)
 	ps := buildPolicySpecification(cit, info, true)
 
 	// getting the users to set to the PolicySpecification
-	users, error := c.getUsers()
+	users, error := c.getUsers(ctx)
 	if error != nil {
 		return nil, error
 	}
@@ -315,16 +339,16 @@ func (c *Connector) GetPolicy(name string) (*policy.PolicySpecification, error)
 	return ps, nil
 }
 
-func (c *Connector) getUsers() ([]string, error) {
+func (c *Connector) getUsers(ctx context.Context) ([]string, error) {
 	var usersList []string
-	appDetails, _, error := c.getAppDetailsByName(c.zone.getApplicationName())
+	appDetails, _, error := c.getAppDetailsByName(ctx, c.zone.getApplicationName())
 	if error != nil {
 		return nil, error
 	}
 	var teams *teams
 	for _, owner := range appDetails.OwnerIdType {
 		if owner.OwnerType == UserType.String() {
-			user, error := c.retrieveUser(owner.OwnerId)
+			user, error := c.retrieveUser(ctx, owner.OwnerId)
 			if error != nil {
 				return nil, error
 			}
@@ -332,7 +356,7 @@ func (c *Connector) getUsers() ([]string, error) {
 		} else {
 			if owner.OwnerType == TeamType.String() {
 				if teams == nil {
-					teams, error = c.retrieveTeams()
+					teams, error = c.retrieveTeams(ctx)
 					if error != nil {
 						return nil, error
 					}
@@ -351,7 +375,7 @@ func (c *Connector) getUsers() ([]string, error) {
 	return usersList, nil
 }
 
-func PolicyExist(policyName string, c *Connector) (bool, error) {
+func PolicyExist(ctx context.Context, policyName string, c *Connector) (bool, error) {
 
 	c.zone.appName = policy.GetApplicationName(policyName)
 	citName := policy.GetCitName(policyName)
@@ -361,11 +385,11 @@ func PolicyExist(policyName string, c *Connector) (bool, error) {
 		return false, fmt.Errorf("cit name is not valid, please provide a valid zone name in the format: appName\\CitName")
 	}
 
-	_, err := c.getTemplateByID()
+	_, err := c.getTemplateByID(ctx)
 	return err == nil, nil
 }
 
-func (c *Connector) SetPolicy(name string, ps *policy.PolicySpecification) (string, error) {
+func (c *Connector) SetPolicy(ctx context.Context, name string, ps *policy.PolicySpecification) (string, error) {
 
 	err := policy.ValidateCloudPolicySpecification(ps)
 	if err != nil {
@@ -376,6 +400,10 @@ func (c *Connector) SetPolicy(name string, ps *policy.PolicySpecification) (stri
 
 	var status string
 
+	if c.zoneCache != nil {
+		c.zoneCache.Invalidate(c.baseURL, name)
+	}
+
 	//validate if zone name is set and if zone already exist on Venafi cloud if not create it.
 	citName := policy.GetCitName(name)
 
@@ -387,7 +415,7 @@ func (c *Connector) SetPolicy(name string, ps *policy.PolicySpecification) (stri
 	var caDetails *policy.CADetails
 
 	if ps.Policy != nil && ps.Policy.CertificateAuthority != nil && *(ps.Policy.CertificateAuthority) != "" {
-		caDetails, err = getCertificateAuthorityDetails(*(ps.Policy.CertificateAuthority), c)
+		caDetails, err = getCertificateAuthorityDetails(ctx, *(ps.Policy.CertificateAuthority), c)
 
 		if err != nil {
 			return "", err
@@ -399,7 +427,7 @@ func (c *Connector) SetPolicy(name string, ps *policy.PolicySpecification) (stri
 			defaultCA := policy.DefaultCA
 			ps.Policy.CertificateAuthority = &defaultCA
 
-			caDetails, err = getCertificateAuthorityDetails(*(ps.Policy.CertificateAuthority), c)
+			caDetails, err = getCertificateAuthorityDetails(ctx, *(ps.Policy.CertificateAuthority), c)
 
 			if err != nil {
 				return "", err
@@ -407,7 +435,7 @@ func (c *Connector) SetPolicy(name string, ps *policy.PolicySpecification) (stri
 
 		} else {
 			//policy is not specified so we get the default CA
-			caDetails, err = getCertificateAuthorityDetails(policy.DefaultCA, c)
+			caDetails, err = getCertificateAuthorityDetails(ctx, policy.DefaultCA, c)
 
 			if err != nil {
 				return "", err
@@ -426,7 +454,7 @@ func (c *Connector) SetPolicy(name string, ps *policy.PolicySpecification) (stri
 
 	url := c.getURL(urlIssuingTemplate)
 
-	cit, err := getCit(c, citName)
+	cit, err := getCit(ctx, c, citName)
 
 	if err != nil {
 		return "", err
@@ -436,7 +464,7 @@ func (c *Connector) SetPolicy(name string, ps *policy.PolicySpecification) (stri
 		log.Printf("updating issuing template: %s", citName)
 		//update cit using the new values
 		url = fmt.Sprint(url, "/", cit.ID)
-		statusCode, status, body, err := c.request("PUT", url, req)
+		statusCode, status, body, err := c.request(ctx, "PUT", url, req)
 
 		if err != nil {
 			return "", err
@@ -451,7 +479,7 @@ func (c *Connector) SetPolicy(name string, ps *policy.PolicySpecification) (stri
 	} else {
 		log.Printf("creating issuing template: %s", citName)
 		//var body []byte
-		statusCode, status, body, err := c.request("POST", url, req)
+		statusCode, status, body, err := c.request(ctx, "POST", url, req)
 
 		if err != nil {
 			return "", err
@@ -473,19 +501,19 @@ func (c *Connector) SetPolicy(name string, ps *policy.PolicySpecification) (stri
 		return "", fmt.Errorf("application name is empty, please provide zone in the format: app_name\\cit_name")
 	}
 
-	appDetails, statusCode, err := c.getAppDetailsByName(appName)
+	appDetails, statusCode, err := c.getAppDetailsByName(ctx, appName)
 
 	if err != nil && statusCode == 404 { //means application was not found.
 		log.Printf("creating application: %s", appName)
 
-		_, error := c.createApplication(appName, ps, cit)
+		_, error := c.createApplication(ctx, appName, ps, cit)
 		if error != nil {
 			return "", error
 		}
 
 	} else { //determine if the application needs to be updated
 		log.Printf("updating application: %s", appName)
-		error := c.updateApplication(name, ps, cit, appDetails)
+		error := c.updateApplication(ctx, name, ps, cit, appDetails)
 		if error != nil {
 			return "", error
 		}
@@ -496,7 +524,7 @@ func (c *Connector) SetPolicy(name string, ps *policy.PolicySpecification) (stri
 	return status, nil
 }
 
-func (c *Connector) createApplication(appName string, ps *policy.PolicySpecification, cit *certificateTemplate) (*policy.Application, error) {
+func (c *Connector) createApplication(ctx context.Context, appName string, ps *policy.PolicySpecification, cit *certificateTemplate) (*policy.Application, error) {
 	appIssuingTemplate := make(map[string]string)
 	appIssuingTemplate[cit.Name] = cit.ID
 
@@ -507,10 +535,10 @@ func (c *Connector) createApplication(appName string, ps *policy.PolicySpecifica
 
 	//if users were passed to the PS, then it will needed to resolve the related Owners to set them
 	if len(ps.Users) > 0 {
-		owners, error = c.resolveOwners(ps.Users)
+		owners, error = c.resolveOwners(ctx, ps.Users)
 	} else { //if the users were not specified in PS, then the current User should be used as owner
 		var owner *policy.OwnerIdType
-		owner, error = c.getOwnerFromUserDetails()
+		owner, error = c.getOwnerFromUserDetails(ctx)
 		if owner != nil {
 			owners = []policy.OwnerIdType{*owner}
 		}
@@ -529,7 +557,7 @@ func (c *Connector) createApplication(appName string, ps *policy.PolicySpecifica
 
 	url := c.getURL(urlAppRoot)
 
-	statusCode, status, _, error = c.request("POST", url, appReq)
+	statusCode, status, _, error = c.request(ctx, "POST", url, appReq)
 	if error != nil {
 		return nil, error
 	}
@@ -540,14 +568,14 @@ func (c *Connector) createApplication(appName string, ps *policy.PolicySpecifica
 	return &appReq, nil
 }
 
-func (c *Connector) updateApplication(name string, ps *policy.PolicySpecification, cit *certificateTemplate, appDetails *ApplicationDetails) error {
+func (c *Connector) updateApplication(ctx context.Context, name string, ps *policy.PolicySpecification, cit *certificateTemplate, appDetails *ApplicationDetails) error {
 
 	//creating the app to use as request
 	appReq := createAppUpdateRequest(appDetails)
 
 	//determining if the relationship between application and cit exist
 	citAddedToApp := false
-	exist, err := PolicyExist(name, c)
+	exist, err := PolicyExist(ctx, name, c)
 	if err != nil {
 		return err
 	}
@@ -562,7 +590,7 @@ func (c *Connector) updateApplication(name string, ps *policy.PolicySpecificatio
 	//is that users in the policy specification were provided
 	if len(ps.Users) > 0 {
 		//resolving and setting owners
-		owners, error := c.resolveOwners(ps.Users)
+		owners, error := c.resolveOwners(ctx, ps.Users)
 		if error != nil {
 			return fmt.Errorf("an error happened trying to resolve the owners: %w", error)
 		}
@@ -575,7 +603,7 @@ func (c *Connector) updateApplication(name string, ps *policy.PolicySpecificatio
 	if citAddedToApp || ownersUpdated {
 		url := c.getURL(urlAppRoot)
 		url = fmt.Sprint(url, "/", appDetails.ApplicationId)
-		_, _, _, err = c.request("PUT", url, appReq)
+		_, _, _, err = c.request(ctx, "PUT", url, appReq)
 		if err != nil {
 			return err
 		}
@@ -592,7 +620,7 @@ func (c *Connector) addCitToApp(app *policy.Application, cit *certificateTemplat
 	}
 }
 
-func (c *Connector) resolveOwners(usersList []string) ([]policy.OwnerIdType, error) {
+func (c *Connector) resolveOwners(ctx context.Context, usersList []string) ([]policy.OwnerIdType, error) {
 
 	var owners []policy.OwnerIdType
 	var teams *teams
@@ -600,13 +628,13 @@ func (c *Connector) resolveOwners(usersList []string) ([]policy.OwnerIdType, err
 
 	for _, userName := range usersList {
 		//The error should be ignored in order to confirm if the userName is not a TeamName
-		users, _ := c.retrieveUsers(userName)
+		users, _ := c.retrieveUsers(ctx, userName)
 
 		if users != nil {
 			owners = appendOwner(owners, users.Users[0].ID, UserType)
 		} else {
 			if teams == nil {
-				teams, err = c.retrieveTeams()
+				teams, err = c.retrieveTeams(ctx)
 			}
 			if err != nil {
 				return nil, err
@@ -635,8 +663,8 @@ func appendOwner(owners []policy.OwnerIdType, ownerId string, ownerType OwnerTyp
 	return append(owners, *owner)
 }
 
-func (c *Connector) getOwnerFromUserDetails() (*policy.OwnerIdType, error) {
-	userDetails, err := getUserDetails(c)
+func (c *Connector) getOwnerFromUserDetails(ctx context.Context) (*policy.OwnerIdType, error) {
+	userDetails, err := getUserDetails(ctx, c)
 	if err != nil {
 		return nil, err
 	}
@@ -685,19 +713,29 @@ func (c *Connector) GetType() endpoint.ConnectorType {
 }
 
 // Ping attempts to connect to the Venafi Cloud API and returns an errror if it cannot
-func (c *Connector) Ping() (err error) {
+func (c *Connector) Ping(ctx context.Context) (err error) {
 
 	return nil
 }
 
-// Authenticate authenticates the user with Venafi Cloud using the provided API Key
-func (c *Connector) Authenticate(auth *endpoint.Authentication) (err error) {
+// Authenticate authenticates the user with Venafi Cloud using the provided API Key, or obtains an access token
+// for the provided service account. The access token is renewed automatically before it expires
+func (c *Connector) Authenticate(ctx context.Context, auth *endpoint.Authentication) (err error) {
 	if auth == nil {
 		return fmt.Errorf("failed to authenticate: missing credentials")
 	}
+	if isServiceAccountAuth(auth) {
+		sa, err := newServiceAccount(auth)
+		if err != nil {
+			return err
+		}
+		c.serviceAccount = sa
+		_, err = c.getAccessToken(ctx)
+		return err
+	}
 	c.apiKey = auth.APIKey
 	url := c.getURL(urlResourceUserAccounts)
-	statusCode, status, body, err := c.request("GET", url, nil, true)
+	statusCode, status, body, err := c.request(ctx, "GET", url, nil, true)
 	if err != nil {
 		return err
 	}
@@ -709,8 +747,8 @@ func (c *Connector) Authenticate(auth *endpoint.Authentication) (err error) {
 	return
 }
 
-func (c *Connector) ReadPolicyConfiguration() (policy *endpoint.Policy, err error) {
-	config, err := c.ReadZoneConfiguration()
+func (c *Connector) ReadPolicyConfiguration(ctx context.Context) (policy *endpoint.Policy, err error) {
+	config, err := c.ReadZoneConfiguration(ctx)
 	if err != nil {
 		return nil, err
 	}
@@ -719,7 +757,14 @@ func (c *Connector) ReadPolicyConfiguration() (policy *endpoint.Policy, err erro
 }
 
 // ReadZoneConfiguration reads the Zone information needed for generating and requesting a certificate from Venafi Cloud
-func (c *Connector) ReadZoneConfiguration() (config *endpoint.ZoneConfiguration, err error) {
+func (c *Connector) ReadZoneConfiguration(ctx context.Context) (config *endpoint.ZoneConfiguration, err error) {
+	if c.zoneCache != nil {
+		config, ok := c.zoneCache.Get(c.baseURL, c.zone.String())
+		if ok {
+			return config, nil
+		}
+	}
+
 	var template *certificateTemplate
 	var statusCode int
 
@@ -728,33 +773,36 @@ func (c *Connector) ReadZoneConfiguration() (config *endpoint.ZoneConfiguration,
 	citAlias := c.zone.getTemplateAlias()
 	if citAlias == "Default" {
 		appName := c.zone.getApplicationName()
-		_, statusCode, err = c.getAppDetailsByName(appName)
+		_, statusCode, err = c.getAppDetailsByName(ctx, appName)
 		if err != nil && statusCode == 404 {
 			log.Printf("creating application %s for issuing template %s", appName, citAlias)
 
 			ps := policy.PolicySpecification{}
-			template, err = getCit(c, citAlias)
+			template, err = getCit(ctx, c, citAlias)
 			if err != nil {
 				return
 			}
-			_, err = c.createApplication(appName, &ps, template)
+			_, err = c.createApplication(ctx, appName, &ps, template)
 			if err != nil {
 				return
 			}
 		}
 	}
 	if template == nil {
-		template, err = c.getTemplateByID()
+		template, err = c.getTemplateByID(ctx)
 		if err != nil {
 			return
 		}
 	}
 	config = getZoneConfiguration(template)
+	if c.zoneCache != nil {
+		c.zoneCache.Set(c.baseURL, c.zone.String(), config)
+	}
 	return config, nil
 }
 
-func getCloudRequest(c *Connector, req *certificate.Request) (*certificateRequest, error) {
-	if c.user == nil || c.user.Company == nil {
+func getCloudRequest(ctx context.Context, c *Connector, req *certificate.Request) (*certificateRequest, error) {
+	if !c.isAuthenticated() {
 		return nil, fmt.Errorf("must be autheticated to request a certificate")
 	}
 
@@ -766,7 +814,7 @@ func getCloudRequest(c *Connector, req *certificate.Request) (*certificateReques
 		}
 	}
 
-	appDetails, _, err := c.getAppDetailsByName(c.zone.getApplicationName())
+	appDetails, _, err := c.getAppDetailsByName(ctx, c.zone.getApplicationName())
 	if err != nil {
 		return nil, err
 	}
@@ -786,7 +834,7 @@ func getCloudRequest(c *Connector, req *certificate.Request) (*certificateReques
 	} else {
 
 		cloudReq.IsVaaSGenerated = true
-		csrAttr, err := getCsrAttributes(c, req)
+		csrAttr, err := getCsrAttributes(ctx, c, req)
 		if err != nil {
 			return nil, err
 		}
@@ -811,14 +859,9 @@ func getCloudRequest(c *Connector, req *certificate.Request) (*certificateReques
 		}
 	}
 
-	validityDuration := req.ValidityDuration
-
-	// DEPRECATED: ValidityHours is deprecated in favor of ValidityDuration, but we
-	// still support it for backwards compatibility.
-	if validityDuration == nil && req.ValidityHours > 0 { //nolint:staticcheck
-		duration := time.Duration(req.ValidityHours) * time.Hour //nolint:staticcheck
-		validityDuration = &duration
-	}
+	// DEPRECATED: ValidityHours is deprecated in favor of ValidityDuration, but GetValidityDuration
+	// still supports it for backwards compatibility.
+	validityDuration := req.GetValidityDuration()
 
 	if validityDuration != nil {
 		cloudReq.ValidityPeriod = "PT" + strings.ToUpper((*validityDuration).Truncate(time.Second).String())
@@ -828,20 +871,20 @@ func getCloudRequest(c *Connector, req *certificate.Request) (*certificateReques
 }
 
 // ResetCertificate resets the state of a certificate.
-func (c *Connector) ResetCertificate(req *certificate.Request, restart bool) (err error) {
+func (c *Connector) ResetCertificate(ctx context.Context, req *certificate.Request, restart bool) (err error) {
 	return fmt.Errorf("not supported by endpoint")
 }
 
 // RequestCertificate submits the CSR to the Venafi Cloud API for processing
-func (c *Connector) RequestCertificate(req *certificate.Request) (requestID string, err error) {
+func (c *Connector) RequestCertificate(ctx context.Context, req *certificate.Request) (requestID string, err error) {
 
 	url := c.getURL(urlResourceCertificateRequests)
-	cloudReq, err := getCloudRequest(c, req)
+	cloudReq, err := getCloudRequest(ctx, c, req)
 	if err != nil {
 		return "", err
 	}
 
-	statusCode, status, body, err := c.request("POST", url, cloudReq)
+	statusCode, status, body, err := c.request(ctx, "POST", url, cloudReq)
 
 	if err != nil {
 		return "", err
@@ -855,10 +898,10 @@ func (c *Connector) RequestCertificate(req *certificate.Request) (requestID stri
 	return requestID, nil
 }
 
-func (c *Connector) getCertificateStatus(requestID string) (certStatus *certificateStatus, err error) {
+func (c *Connector) getCertificateStatus(ctx context.Context, requestID string) (certStatus *certificateStatus, err error) {
 	url := c.getURL(urlResourceCertificateStatus)
 	url = fmt.Sprintf(url, requestID)
-	statusCode, _, body, err := c.request("GET", url, nil)
+	statusCode, _, body, err := c.request(ctx, "GET", url, nil)
 	if err != nil {
 		return nil, err
 	}
@@ -884,7 +927,7 @@ func (c *Connector) getCertificateStatus(requestID string) (certStatus *certific
 }
 
 // SynchronousRequestCertificate It's not supported yet in VaaS
-func (c *Connector) SynchronousRequestCertificate(_ *certificate.Request) (certificates *certificate.PEMCollection, err error) {
+func (c *Connector) SynchronousRequestCertificate(ctx context.Context, _ *certificate.Request) (certificates *certificate.PEMCollection, err error) {
 	panic("operation is not supported yet")
 }
 
@@ -894,17 +937,17 @@ func (c *Connector) SupportSynchronousRequestCertificate() bool {
 }
 
 // RetrieveCertificate retrieves the certificate for the specified ID
-func (c *Connector) RetrieveCertificate(req *certificate.Request) (certificates *certificate.PEMCollection, err error) {
+func (c *Connector) RetrieveCertificate(ctx context.Context, req *certificate.Request) (certificates *certificate.PEMCollection, err error) {
 
-	if req.PickupID == "" && req.CertID == "" && req.Thumbprint != "" {
-		// search cert by Thumbprint and fill pickupID
+	if req.PickupID == "" && req.CertID == "" && (req.Thumbprint != "" || req.SerialNumber != "") {
+		// search cert by Thumbprint or serial number and fill pickupID
 		var certificateRequestId string
-		searchResult, err := c.searchCertificatesByFingerprint(req.Thumbprint)
+		searchResult, searchedBy, err := c.searchCertificatesOfRequest(ctx, req)
 		if err != nil {
 			return nil, fmt.Errorf("failed to retrieve certificate: %s", err)
 		}
 		if len(searchResult.Certificates) == 0 {
-			return nil, fmt.Errorf("no certificate found using fingerprint %s", req.Thumbprint)
+			return nil, fmt.Errorf("no certificate found using %s", searchedBy)
 		}
 
 		var reqIds []string
@@ -922,7 +965,7 @@ func (c *Connector) RetrieveCertificate(req *certificate.Request) (certificates
 			}
 		}
 		if !isOnlyOneCertificateRequestId {
-			return nil, fmt.Errorf("more than one CertificateRequestId was found with the same Fingerprint: %s", reqIds)
+			return nil, fmt.Errorf("more than one CertificateRequestId was found using %s: %s", searchedBy, reqIds)
 		}
 
 		req.PickupID = certificateRequestId
@@ -937,7 +980,7 @@ func (c *Connector) RetrieveCertificate(req *certificate.Request) (certificates
 			if req.PickupID == "" {
 				break
 			}
-			certStatus, err := c.getCertificateStatus(req.PickupID)
+			certStatus, err := c.getCertificateStatus(ctx, req.PickupID)
 			if err != nil {
 				return nil, fmt.Errorf("unable to retrieve: %s", err)
 			}
@@ -957,13 +1000,15 @@ func (c *Connector) RetrieveCertificate(req *certificate.Request) (certificates
 				return nil, endpoint.ErrRetrieveCertificateTimeout{CertificateID: req.PickupID}
 			}
 			// fmt.Printf("pending... %s\n", status.Status)
-			time.Sleep(2 * time.Second)
+			if err = util.Sleep(ctx, 2*time.Second); err != nil {
+				return nil, err
+			}
 		}
 	} else {
 		certificateId = req.CertID
 	}
 
-	if c.user == nil || c.user.Company == nil {
+	if !c.isAuthenticated() {
 		return nil, fmt.Errorf("must be autheticated to retieve certificate")
 	}
 
@@ -973,20 +1018,20 @@ func (c *Connector) RetrieveCertificate(req *certificate.Request) (certificates
 	var dekInfo *EdgeEncryptionKey
 	var currentId string
 	if req.CertID != "" {
-		dekInfo, err = getDekInfo(c, req.CertID)
+		dekInfo, err = getDekInfo(ctx, c, req.CertID)
 		currentId = req.CertID
 	} else if certificateId != "" {
-		dekInfo, err = getDekInfo(c, certificateId)
+		dekInfo, err = getDekInfo(ctx, c, certificateId)
 		currentId = certificateId
 	}
 	if err == nil && dekInfo.Key != "" {
 		req.CertID = currentId
-		return retrieveServiceGeneratedCertData(c, req, dekInfo)
+		return retrieveServiceGeneratedCertData(ctx, c, req, dekInfo)
 	}
 
 	switch {
 	case req.CertID != "":
-		statusCode, status, body, err := c.waitForCertificate(url, req) //c.request("GET", url, nil)
+		statusCode, status, body, err := c.waitForCertificate(ctx, url, req) //c.request("GET", url, nil)
 		if err != nil {
 			return nil, err
 		}
@@ -1002,7 +1047,7 @@ func (c *Connector) RetrieveCertificate(req *certificate.Request) (certificates
 		default:
 			url = fmt.Sprintf(url, condorChainOptionRootLast)
 		}
-		statusCode, status, body, err := c.waitForCertificate(url, req) //c.request("GET", url, nil)
+		statusCode, status, body, err := c.waitForCertificate(ctx, url, req) //c.request("GET", url, nil)
 		if err != nil {
 			return nil, err
 		}
@@ -1022,7 +1067,7 @@ func (c *Connector) RetrieveCertificate(req *certificate.Request) (certificates
 	return nil, fmt.Errorf("couldn't retrieve certificate because both PickupID and CertId are empty")
 }
 
-func retrieveServiceGeneratedCertData(c *Connector, req *certificate.Request, dekInfo *EdgeEncryptionKey) (*certificate.PEMCollection, error) {
+func retrieveServiceGeneratedCertData(ctx context.Context, c *Connector, req *certificate.Request, dekInfo *EdgeEncryptionKey) (*certificate.PEMCollection, error) {
 
 	pkDecoded, err := base64.StdEncoding.DecodeString(dekInfo.Key)
 
@@ -1052,7 +1097,7 @@ func retrieveServiceGeneratedCertData(c *Connector, req *certificate.Request, de
 	url := c.getURL(urlResourceCertificateKS)
 	url = fmt.Sprintf(url, req.CertID)
 
-	statusCode, status, body, err := c.request("POST", url, ksRequest)
+	statusCode, status, body, err := c.request(ctx, "POST", url, ksRequest)
 
 	if err != nil {
 		return nil, err
@@ -1071,12 +1116,12 @@ func retrieveServiceGeneratedCertData(c *Connector, req *certificate.Request, de
 
 }
 
-func getDekInfo(c *Connector, cerId string) (*EdgeEncryptionKey, error) {
+func getDekInfo(ctx context.Context, c *Connector, cerId string) (*EdgeEncryptionKey, error) {
 	//get certificate details for getting DekHash
 	url := c.getURL(urlResourceCertificateByID)
 	url = fmt.Sprintf(url, cerId)
 
-	statusCode, status, body, err := c.request("GET", url, nil)
+	statusCode, status, body, err := c.request(ctx, "GET", url, nil)
 	if err != nil {
 		return nil, err
 	}
@@ -1091,7 +1136,7 @@ func getDekInfo(c *Connector, cerId string) (*EdgeEncryptionKey, error) {
 	url = c.getURL(urlDekPublicKey)
 	url = fmt.Sprintf(url, managedCert.DekHash)
 
-	statusCode, status, body, err = c.request("GET", url, nil)
+	statusCode, status, body, err = c.request(ctx, "GET", url, nil)
 	if err != nil {
 		return nil, err
 	}
@@ -1174,10 +1219,10 @@ func ConvertZipBytesToPem(dataByte []byte, rootFirst bool) (*certificate.PEMColl
 }
 
 // Waits for the Certificate to be available. Fails when the timeout is exceeded
-func (c *Connector) waitForCertificate(url string, request *certificate.Request) (statusCode int, status string, body []byte, err error) {
+func (c *Connector) waitForCertificate(ctx context.Context, url string, request *certificate.Request) (statusCode int, status string, body []byte, err error) {
 	startTime := time.Now()
 	for {
-		statusCode, status, body, err = c.request("GET", url, nil)
+		statusCode, status, body, err = c.request(ctx, "GET", url, nil)
 		if err != nil {
 			return
 		}
@@ -1192,29 +1237,31 @@ func (c *Connector) waitForCertificate(url string, request *certificate.Request)
 			err = endpoint.ErrRetrieveCertificateTimeout{CertificateID: request.PickupID}
 			return
 		}
-		time.Sleep(2 * time.Second)
+		if err = util.Sleep(ctx, 2*time.Second); err != nil {
+			return
+		}
 	}
 }
 
 // RevokeCertificate attempts to revoke the certificate
-func (c *Connector) RevokeCertificate(revReq *certificate.RevocationRequest) (err error) {
+func (c *Connector) RevokeCertificate(ctx context.Context, revReq *certificate.RevocationRequest) (err error) {
 	return fmt.Errorf("not supported by endpoint")
 }
 
 // Custom Logging not currently supported by VaaS
-func (c *Connector) WriteLog(logReq *endpoint.LogRequest) (err error) {
+func (c *Connector) WriteLog(ctx context.Context, logReq *endpoint.LogRequest) (err error) {
 	return fmt.Errorf("Outbound logging not supported by endpoint")
 }
 
 // RenewCertificate attempts to renew the certificate
-func (c *Connector) RenewCertificate(renewReq *certificate.RenewalRequest) (requestID string, err error) {
+func (c *Connector) RenewCertificate(ctx context.Context, renewReq *certificate.RenewalRequest) (requestID string, err error) {
 
 	/* 1st step is to get CertificateRequestId which is required to lookup managedCertificateId and zoneId */
 	var certificateRequestId string
 
 	if renewReq.Thumbprint != "" {
 		// by Thumbprint (aka Fingerprint)
-		searchResult, err := c.searchCertificatesByFingerprint(renewReq.Thumbprint)
+		searchResult, err := c.searchCertificatesByFingerprint(ctx, renewReq.Thumbprint)
 		if err != nil {
 			return "", fmt.Errorf("failed to create renewal request: %s", err)
 		}
@@ -1242,7 +1289,7 @@ func (c *Connector) RenewCertificate(renewReq *certificate.RenewalRequest) (requ
 	}
 
 	/* 2nd step is to get ManagedCertificateId & ZoneId by looking up certificate request record */
-	previousRequest, err := c.getCertificateStatus(certificateRequestId)
+	previousRequest, err := c.getCertificateStatus(ctx, certificateRequestId)
 	if err != nil {
 		return "", fmt.Errorf("certificate renew failed: %s", err)
 	}
@@ -1264,7 +1311,7 @@ func (c *Connector) RenewCertificate(renewReq *certificate.RenewalRequest) (requ
 
 	/* 3rd step is to get Certificate Object by id
 	and check if latestCertificateRequestId there equals to certificateRequestId from 1st step */
-	managedCertificate, err := c.getCertificate(certificateId)
+	managedCertificate, err := c.getCertificate(ctx, certificateId)
 	if err != nil {
 		return "", fmt.Errorf("failed to renew certificate: %s", err)
 	}
@@ -1281,7 +1328,7 @@ func (c *Connector) RenewCertificate(renewReq *certificate.RenewalRequest) (requ
 
 	/* 4th step is to send renewal request */
 	url := c.getURL(urlResourceCertificateRequests)
-	if c.user == nil || c.user.Company == nil {
+	if !c.isAuthenticated() {
 		return "", fmt.Errorf("must be autheticated to request a certificate")
 	}
 
@@ -1314,7 +1361,7 @@ func (c *Connector) RenewCertificate(renewReq *certificate.RenewalRequest) (requ
 		req.ReuseCSR = true
 		return "", fmt.Errorf("reuseCSR option is not currently available for Renew Certificate operation. A new CSR must be provided in the request")
 	}
-	statusCode, status, body, err := c.request("POST", url, req)
+	statusCode, status, body, err := c.request(ctx, "POST", url, req)
 	if err != nil {
 		return
 	}
@@ -1327,13 +1374,13 @@ func (c *Connector) RenewCertificate(renewReq *certificate.RenewalRequest) (requ
 }
 
 // RetireCertificate attempts to retire the certificate
-func (c *Connector) RetireCertificate(retireReq *certificate.RetireRequest) error {
+func (c *Connector) RetireCertificate(ctx context.Context, retireReq *certificate.RetireRequest) error {
 	url := c.getURL(urlResourceCertificatesRetirement)
 	/* 1st step is to get CertificateRequestId which is required to retire certificate */
 	var certificateRequestId string
 	if retireReq.Thumbprint != "" {
 		// by Thumbprint (aka Fingerprint)
-		searchResult, err := c.searchCertificatesByFingerprint(retireReq.Thumbprint)
+		searchResult, err := c.searchCertificatesByFingerprint(ctx, retireReq.Thumbprint)
 		if err != nil {
 			return fmt.Errorf("failed to create retire request: %s", err)
 		}
@@ -1360,22 +1407,31 @@ func (c *Connector) RetireCertificate(retireReq *certificate.RetireRequest) erro
 		return fmt.Errorf("failed to create retire request: CertificateDN or Thumbprint required")
 	}
 
-	/* 2nd step is to get ManagedCertificateId & ZoneId by looking up certificate request record */
-	previousRequest, err := c.getCertificateStatus(certificateRequestId)
+	/* 2nd step is to get the Certificate ID by looking up certificate request record */
+	var certificateIds []string
+	previousRequest, err := c.getCertificateStatus(ctx, certificateRequestId)
 	if err != nil {
-		if strings.Contains(err.Error(), "Unable to find certificateRequest") {
+		if !strings.Contains(err.Error(), "Unable to find certificateRequest") {
+			return fmt.Errorf("certificate retirement failed: error on getting Certificate ID: %s", err)
+		}
+		if retireReq.Thumbprint != "" {
 			return fmt.Errorf("Invalid thumbprint or certificate ID. No certificates were retired")
 		}
-		return fmt.Errorf("certificate retirement failed: error on getting Certificate ID: %s", err)
+		// the ID is not a certificate request, so it may be the ID of the certificate itself
+		certificateIds = []string{certificateRequestId}
+	} else if len(previousRequest.CertificateIdsList) > 0 {
+		certificateIds = previousRequest.CertificateIdsList[:1]
+	} else {
+		return fmt.Errorf("no certificate was issued for the certificate request %s. No certificates were retired", certificateRequestId)
 	}
-	certificateId := previousRequest.CertificateIdsList[0]
 
 	/* Now we do retirement*/
 	retRequest := certificateRetireRequest{
-		CertificateIds: []string{certificateId},
+		CertificateIds: certificateIds,
+		AddToBlocklist: retireReq.AddToBlocklist,
 	}
 
-	statusCode, status, response, err := c.request("POST", url, retRequest)
+	statusCode, status, response, err := c.request(ctx, "POST", url, retRequest)
 	if err != nil {
 		return err
 	}
@@ -1388,12 +1444,12 @@ func (c *Connector) RetireCertificate(retireReq *certificate.RetireRequest) erro
 	return nil
 }
 
-func (c *Connector) searchCertificates(req *SearchRequest) (*CertificateSearchResponse, error) {
+func (c *Connector) searchCertificates(ctx context.Context, req *SearchRequest) (*CertificateSearchResponse, error) {
 
 	var err error
 
 	url := c.getURL(urlResourceCertificateSearch)
-	statusCode, _, body, err := c.request("POST", url, req)
+	statusCode, _, body, err := c.request(ctx, "POST", url, req)
 	if err != nil {
 		return nil, err
 	}
@@ -1404,7 +1460,7 @@ func (c *Connector) searchCertificates(req *SearchRequest) (*CertificateSearchRe
 	return searchResult, nil
 }
 
-func (c *Connector) searchCertificatesByFingerprint(fp string) (*CertificateSearchResponse, error) {
+func (c *Connector) searchCertificatesByFingerprint(ctx context.Context, fp string) (*CertificateSearchResponse, error) {
 	fp = strings.Replace(fp, ":", "", -1)
 	fp = strings.Replace(fp, ".", "", -1)
 	fp = strings.ToUpper(fp)
@@ -1419,7 +1475,35 @@ func (c *Connector) searchCertificatesByFingerprint(fp string) (*CertificateSear
 			},
 		},
 	}
-	return c.searchCertificates(req)
+	return c.searchCertificates(ctx, req)
+}
+
+// searchCertificatesOfRequest searches the certificates with the thumbprint of req or, when it has none, its serial
+// number. It also returns how they were searched, for the error messages
+func (c *Connector) searchCertificatesOfRequest(ctx context.Context, req *certificate.Request) (*CertificateSearchResponse, string, error) {
+	if req.Thumbprint != "" {
+		searchResult, err := c.searchCertificatesByFingerprint(ctx, req.Thumbprint)
+		return searchResult, fmt.Sprintf("fingerprint %s", req.Thumbprint), err
+	}
+	searchResult, err := c.searchCertificatesBySerialNumber(ctx, req.SerialNumber)
+	return searchResult, fmt.Sprintf("serial number %s", req.SerialNumber), err
+}
+
+func (c *Connector) searchCertificatesBySerialNumber(ctx context.Context, serial string) (*CertificateSearchResponse, error) {
+	serial = strings.Replace(serial, ":", "", -1)
+	serial = strings.ToUpper(serial)
+	req := &SearchRequest{
+		Expression: &Expression{
+			Operands: []Operand{
+				{
+					Field:    "serialNumber",
+					Operator: MATCH,
+					Value:    serial,
+				},
+			},
+		},
+	}
+	return c.searchCertificates(ctx, req)
 }
 
 /*
@@ -1441,11 +1525,11 @@ type managedCertificate struct {
 	DekHash              string `json:"dekHash,omitempty"`
 }
 
-func (c *Connector) getCertificate(certificateId string) (*managedCertificate, error) {
+func (c *Connector) getCertificate(ctx context.Context, certificateId string) (*managedCertificate, error) {
 	var err error
 	url := c.getURL(urlResourceCertificateByID)
 	url = fmt.Sprintf(url, certificateId)
-	statusCode, _, body, err := c.request("GET", url, nil)
+	statusCode, _, body, err := c.request(ctx, "GET", url, nil)
 	if err != nil {
 		return nil, err
 	}
@@ -1473,14 +1557,14 @@ func (c *Connector) getCertificate(certificateId string) (*managedCertificate, e
 	}
 }
 
-func (c *Connector) ImportCertificate(req *certificate.ImportRequest) (*certificate.ImportResponse, error) {
+func (c *Connector) ImportCertificate(ctx context.Context, req *certificate.ImportRequest) (*certificate.ImportResponse, error) {
 	pBlock, _ := pem.Decode([]byte(req.CertificateData))
 	if pBlock == nil {
 		return nil, fmt.Errorf("%w can`t parse certificate", verror.UserDataError)
 	}
 	zone := req.PolicyDN
 	if zone == "" {
-		appDetails, _, err := c.getAppDetailsByName(c.zone.getApplicationName())
+		appDetails, _, err := c.getAppDetailsByName(ctx, c.zone.getApplicationName())
 		if err != nil {
 			return nil, err
 		}
@@ -1509,7 +1593,7 @@ func (c *Connector) ImportCertificate(req *certificate.ImportRequest) (*certific
 	}
 
 	url := c.getURL(urlResourceCertificates)
-	statusCode, status, body, err := c.request("POST", url, request)
+	statusCode, status, body, err := c.request(ctx, "POST", url, request)
 	if err != nil {
 		return nil, fmt.Errorf("%w: %v", verror.ServerTemporaryUnavailableError, err)
 	}
@@ -1529,8 +1613,10 @@ func (c *Connector) ImportCertificate(req *certificate.ImportRequest) (*certific
 	} else if !(len(r.CertificateInformations) == 1) {
 		return nil, fmt.Errorf("%w: certificate was not imported on unknown reason", verror.ServerBadDataResponce)
 	}
-	time.Sleep(time.Second)
-	foundCert, err := c.searchCertificatesByFingerprint(fingerprint)
+	if err = util.Sleep(ctx, time.Second); err != nil {
+		return nil, err
+	}
+	foundCert, err := c.searchCertificatesByFingerprint(ctx, fingerprint)
 	if err != nil {
 		return nil, err
 	}
@@ -1546,7 +1632,38 @@ func (c *Connector) SetHTTPClient(client *http.Client) {
 	c.client = client
 }
 
-func (c *Connector) ListCertificates(filter endpoint.Filter) ([]certificate.CertificateInfo, error) {
+// SetRetryPolicy sets how requests rate limited by Venafi as a Service, or failing with transient errors, are retried.
+// It has no effect on the client set with SetHTTPClient, nor once the connector has sent its first request
+func (c *Connector) SetRetryPolicy(policy util.RetryPolicy) {
+	c.retryPolicy = policy
+}
+
+// SetProxy sets the proxy the requests to Venafi as a Service are sent through, instead of the one of the environment variables.
+// It has no effect on the client set with SetHTTPClient, nor once the connector has sent its first request
+func (c *Connector) SetProxy(proxy util.ProxyConfig) {
+	c.proxy = proxy
+}
+
+// SetTransportConfig tunes the HTTP transport of the requests to Venafi as a Service, shared with the other connectors built with the
+// same settings. It has no effect on the client set with SetHTTPClient, nor once the connector has sent its first request
+func (c *Connector) SetTransportConfig(config util.TransportConfig) {
+	c.transport = config
+}
+
+// SetTLSConfig sets the TLS settings of the requests to Venafi as a Service, like the client certificate, instead of the ones of
+// http.DefaultTransport. The trust bundle of the connector replaces its root CAs. It has no effect on the client set
+// with SetHTTPClient, nor once the connector has sent its first request
+func (c *Connector) SetTLSConfig(config *tls.Config) {
+	c.tlsConfig = config
+}
+
+// SetZoneConfigurationCache sets the cache of the zone configurations read by the connector, usually shared with
+// other connectors. The zone configuration is read from Venafi as a Service on every request when no cache is set
+func (c *Connector) SetZoneConfigurationCache(cache *endpoint.ZoneConfigurationCache) {
+	c.zoneCache = cache
+}
+
+func (c *Connector) ListCertificates(ctx context.Context, filter endpoint.Filter) ([]certificate.CertificateInfo, error) {
 	if c.zone.String() == "" {
 		return nil, fmt.Errorf("empty zone")
 	}
@@ -1559,7 +1676,7 @@ func (c *Connector) ListCertificates(filter endpoint.Filter) ([]certificate.Cert
 	for page := 0; limit > 0; limit, page = limit-batchSize, page+1 {
 		var b []certificate.CertificateInfo
 		var err error
-		b, err = c.getCertsBatch(page, batchSize, filter.WithExpired)
+		b, err = c.getCertsBatch(ctx, page, batchSize, filter.WithExpired)
 		if limit < batchSize && len(b) > limit {
 			b = b[:limit]
 		}
@@ -1584,9 +1701,9 @@ func (c *Connector) ListCertificates(filter endpoint.Filter) ([]certificate.Cert
 	return infos, nil
 }
 
-func (c *Connector) getCertsBatch(page, pageSize int, withExpired bool) ([]certificate.CertificateInfo, error) {
+func (c *Connector) getCertsBatch(ctx context.Context, page, pageSize int, withExpired bool) ([]certificate.CertificateInfo, error) {
 
-	appDetails, _, err := c.getAppDetailsByName(c.zone.getApplicationName())
+	appDetails, _, err := c.getAppDetailsByName(ctx, c.zone.getApplicationName())
 	if err != nil {
 		return nil, err
 	}
@@ -1611,7 +1728,7 @@ func (c *Connector) getCertsBatch(page, pageSize int, withExpired bool) ([]certi
 			Value:    time.Now().Format(time.RFC3339),
 		})
 	}
-	r, err := c.searchCertificates(req)
+	r, err := c.searchCertificates(ctx, req)
 	if err != nil {
 		return nil, err
 	}
@@ -1622,14 +1739,14 @@ func (c *Connector) getCertsBatch(page, pageSize int, withExpired bool) ([]certi
 	return infos, nil
 }
 
-func (c *Connector) getAppDetailsByName(appName string) (*ApplicationDetails, int, error) {
+func (c *Connector) getAppDetailsByName(ctx context.Context, appName string) (*ApplicationDetails, int, error) {
 	url := c.getURL(urlAppDetailsByName)
-	if c.user == nil {
+	if !c.isAuthenticated() {
 		return nil, -1, fmt.Errorf("must be autheticated to read the zone configuration")
 	}
 	encodedAppName := netUrl.PathEscape(appName)
 	url = fmt.Sprintf(url, encodedAppName)
-	statusCode, status, body, err := c.request("GET", url, nil)
+	statusCode, status, body, err := c.request(ctx, "GET", url, nil)
 	if err != nil {
 		return nil, statusCode, err
 	}
@@ -1641,10 +1758,10 @@ func (c *Connector) getAppDetailsByName(appName string) (*ApplicationDetails, in
 }
 
 // GetZonesByParent returns a list of valid zones for a VaaS application specified by parent
-func (c *Connector) GetZonesByParent(parent string) ([]string, error) {
+func (c *Connector) GetZonesByParent(ctx context.Context, parent string) ([]string, error) {
 	var zones []string
 
-	appDetails, _, err := c.getAppDetailsByName(parent)
+	appDetails, _, err := c.getAppDetailsByName(ctx, parent)
 	if err != nil {
 		return nil, err
 	}
@@ -1656,12 +1773,12 @@ func (c *Connector) GetZonesByParent(parent string) ([]string, error) {
 	return zones, nil
 }
 
-func (c *Connector) getTemplateByID() (*certificateTemplate, error) {
+func (c *Connector) getTemplateByID(ctx context.Context) (*certificateTemplate, error) {
 	url := c.getURL(urlResourceTemplate)
 	appNameEncoded := netUrl.PathEscape(c.zone.getApplicationName())
 	citAliasEncoded := netUrl.PathEscape(c.zone.getTemplateAlias())
 	url = fmt.Sprintf(url, appNameEncoded, citAliasEncoded)
-	statusCode, status, body, err := c.request("GET", url, nil)
+	statusCode, status, body, err := c.request(ctx, "GET", url, nil)
 	if err != nil {
 		return nil, err
 	}
@@ -1669,9 +1786,9 @@ func (c *Connector) getTemplateByID() (*certificateTemplate, error) {
 	return t, err
 }
 
-func getCit(c *Connector, citName string) (*certificateTemplate, error) {
+func getCit(ctx context.Context, c *Connector, citName string) (*certificateTemplate, error) {
 	url := c.getURL(urlIssuingTemplate)
-	_, _, body, err := c.request("GET", url, nil)
+	_, _, body, err := c.request(ctx, "GET", url, nil)
 
 	if err != nil {
 		return nil, err
@@ -1699,7 +1816,7 @@ func getCit(c *Connector, citName string) (*certificateTemplate, error) {
 	return nil, nil
 }
 
-func (c *Connector) CreateAPIUserAccount(userName string, password string) (int, *userDetails, error) {
+func (c *Connector) CreateAPIUserAccount(ctx context.Context, userName string, password string) (int, *userDetails, error) {
 
 	indexOfAt := strings.Index(userName, "@")
 
@@ -1715,13 +1832,13 @@ func (c *Connector) CreateAPIUserAccount(userName string, password string) (int,
 		// required the workaround to set something on firstName or lastName field. For now we are setting the email's prefix
 	}
 
-	return c.CreateUserAccount(&userAccountReq)
+	return c.CreateUserAccount(ctx, &userAccountReq)
 }
 
-func (c *Connector) CreateUserAccount(userAccount *userAccount) (int, *userDetails, error) {
+func (c *Connector) CreateUserAccount(ctx context.Context, userAccount *userAccount) (int, *userDetails, error) {
 
 	url := c.getURL(urlResourceUserAccounts)
-	statusCode, status, body, err := c.request("POST", url, userAccount, true)
+	statusCode, status, body, err := c.request(ctx, "POST", url, userAccount, true)
 	if err != nil {
 		return statusCode, nil, err
 	}
@@ -1733,10 +1850,10 @@ func (c *Connector) CreateUserAccount(userAccount *userAccount) (int, *userDetai
 	return statusCode, ud, nil
 }
 
-func getUserDetails(c *Connector) (*userDetails, error) {
+func getUserDetails(ctx context.Context, c *Connector) (*userDetails, error) {
 
 	url := c.getURL(urlResourceUserAccounts)
-	statusCode, status, body, err := c.request("GET", url, nil)
+	statusCode, status, body, err := c.request(ctx, "GET", url, nil)
 	if err != nil {
 		return nil, err
 	}
@@ -1748,12 +1865,12 @@ func getUserDetails(c *Connector) (*userDetails, error) {
 	return ud, nil
 }
 
-func (c *Connector) retrieveUser(id string) (*user, error) {
+func (c *Connector) retrieveUser(ctx context.Context, id string) (*user, error) {
 
 	url := c.getURL(urlUserById)
 	url = fmt.Sprintf(url, id)
 
-	statusCode, status, body, err := c.request("GET", url, nil)
+	statusCode, status, body, err := c.request(ctx, "GET", url, nil)
 	if err != nil {
 		return nil, err
 	}
@@ -1764,12 +1881,12 @@ func (c *Connector) retrieveUser(id string) (*user, error) {
 	return user, nil
 }
 
-func (c *Connector) retrieveUsers(userName string) (*users, error) {
+func (c *Connector) retrieveUsers(ctx context.Context, userName string) (*users, error) {
 
 	url := c.getURL(urlUsersByName)
 	url = fmt.Sprintf(url, userName)
 
-	statusCode, status, body, err := c.request("GET", url, nil)
+	statusCode, status, body, err := c.request(ctx, "GET", url, nil)
 	if err != nil {
 		return nil, err
 	}
@@ -1780,11 +1897,11 @@ func (c *Connector) retrieveUsers(userName string) (*users, error) {
 	return users, nil
 }
 
-func (c *Connector) retrieveTeams() (*teams, error) {
+func (c *Connector) retrieveTeams(ctx context.Context) (*teams, error) {
 
 	url := c.getURL(urlTeams)
 
-	statusCode, status, body, err := c.request("GET", url, nil)
+	statusCode, status, body, err := c.request(ctx, "GET", url, nil)
 	if err != nil {
 		return nil, err
 	}
@@ -1795,7 +1912,7 @@ func (c *Connector) retrieveTeams() (*teams, error) {
 	return teams, nil
 }
 
-func getAccounts(caName string, c *Connector) (*policy.Accounts, *policy.CertificateAuthorityInfo, error) {
+func getAccounts(ctx context.Context, caName string, c *Connector) (*policy.Accounts, *policy.CertificateAuthorityInfo, error) {
 	info, err := policy.GetCertAuthorityInfo(caName)
 	if err != nil {
 		return nil, nil, err
@@ -1804,7 +1921,7 @@ func getAccounts(caName string, c *Connector) (*policy.Accounts, *policy.Certifi
 	caType := netUrl.PathEscape(info.CAType)
 	url := c.getURL(urlCAAccounts)
 	url = fmt.Sprintf(url, caType)
-	_, _, body, err := c.request("GET", url, nil)
+	_, _, body, err := c.request(ctx, "GET", url, nil)
 
 	if err != nil {
 		return nil, nil, err
@@ -1821,9 +1938,9 @@ func getAccounts(caName string, c *Connector) (*policy.Accounts, *policy.Certifi
 	return &accounts, &info, nil
 }
 
-func getCertificateAuthorityDetails(caName string, c *Connector) (*policy.CADetails, error) {
+func getCertificateAuthorityDetails(ctx context.Context, caName string, c *Connector) (*policy.CADetails, error) {
 
-	accounts, info, err := getAccounts(caName, c)
+	accounts, info, err := getAccounts(ctx, caName, c)
 	if err != nil {
 		return nil, err
 	}
@@ -1847,12 +1964,12 @@ func getCertificateAuthorityDetails(caName string, c *Connector) (*policy.CADeta
 	return nil, fmt.Errorf("specified CA doesn't exist")
 }
 
-func getCertificateAuthorityInfoFromCloud(caName, caAccountId, caProductOptionId string, c *Connector) (*policy.CertificateAuthorityInfo, error) {
+func getCertificateAuthorityInfoFromCloud(ctx context.Context, caName, caAccountId, caProductOptionId string, c *Connector) (*policy.CertificateAuthorityInfo, error) {
 
 	caName = netUrl.PathEscape(caName)
 	url := c.getURL(urlCAAccountDetails)
 	url = fmt.Sprintf(url, caName, caAccountId)
-	_, _, body, err := c.request("GET", url, nil)
+	_, _, body, err := c.request(ctx, "GET", url, nil)
 
 	if err != nil {
 		return nil, err
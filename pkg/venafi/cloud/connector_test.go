@@ -17,6 +17,7 @@
 package cloud
 
 import (
+	"context"
 	"crypto/rand"
 	"crypto/rsa"
 	"crypto/sha1"
@@ -44,49 +45,52 @@ import (
 	"github.com/Venafi/vcert/v5/test"
 )
 
-var ctx *test.Context
+var env *test.Context
 
 func init() {
-	ctx = test.GetEnvContext()
+	env = test.GetEnvContext()
 	// http.DefaultTransport.(*http.Transport).TLSClientConfig = &tls.Config{InsecureSkipVerify: true}
 
-	if ctx.CloudAPIkey == "" {
+	if env.CloudAPIkey == "" {
 		fmt.Println("API key cannot be empty. See Makefile")
 		os.Exit(1)
 	}
 }
 
 func getTestConnector(zone string) *Connector {
-	url, _ := normalizeURL(ctx.CloudUrl)
+	url, _ := normalizeURL(env.CloudUrl)
 	c, _ := NewConnector(url, zone, true, nil)
 	return c
 }
 
 func TestPing(t *testing.T) {
+	ctx := context.Background()
 	conn := getTestConnector("")
-	err := conn.Ping()
+	err := conn.Ping(ctx)
 	if err != nil {
 		t.Fatalf("%s", err)
 	}
 }
 
 func TestAuthenticate(t *testing.T) {
-	conn := getTestConnector(ctx.CloudZone)
-	err := conn.Authenticate(&endpoint.Authentication{APIKey: ctx.CloudAPIkey})
+	ctx := context.Background()
+	conn := getTestConnector(env.CloudZone)
+	err := conn.Authenticate(ctx, &endpoint.Authentication{APIKey: env.CloudAPIkey})
 	if err != nil {
 		t.Fatalf("%s", err)
 	}
 }
 
 func TestReadZoneConfiguration(t *testing.T) {
-	conn := getTestConnector(ctx.CloudZone)
-	err := conn.Authenticate(&endpoint.Authentication{APIKey: ctx.CloudAPIkey})
+	ctx := context.Background()
+	conn := getTestConnector(env.CloudZone)
+	err := conn.Authenticate(ctx, &endpoint.Authentication{APIKey: env.CloudAPIkey})
 	if err != nil {
 		t.Fatalf("%s", err)
 	}
 
 	conn.SetZone("d686146b-799b-4836-8ac3-f4a2d3a38934")
-	_, err = conn.ReadZoneConfiguration()
+	_, err = conn.ReadZoneConfiguration(ctx)
 	if !errors.Is(err, verror.ZoneNotFoundError) {
 		t.Fatalf("Unknown zone should have resulted in an error")
 	}
@@ -94,10 +98,10 @@ func TestReadZoneConfiguration(t *testing.T) {
 		zone       string
 		zoneConfig endpoint.ZoneConfiguration
 	}{
-		{ctx.CloudZone, endpoint.ZoneConfiguration{
+		{env.CloudZone, endpoint.ZoneConfiguration{
 			CustomAttributeValues: make(map[string]string),
 		}},
-		{ctx.CloudZoneRestricted, endpoint.ZoneConfiguration{
+		{env.CloudZoneRestricted, endpoint.ZoneConfiguration{
 			Organization:          "Venafi Inc.",
 			OrganizationalUnit:    []string{"Integrations"},
 			Country:               "US",
@@ -109,7 +113,7 @@ func TestReadZoneConfiguration(t *testing.T) {
 	}
 	for _, c := range testCases {
 		conn.SetZone(c.zone)
-		zoneConfig, err := conn.ReadZoneConfiguration()
+		zoneConfig, err := conn.ReadZoneConfiguration(ctx)
 		if err != nil {
 			t.Fatalf("%s", err)
 		}
@@ -122,13 +126,14 @@ func TestReadZoneConfiguration(t *testing.T) {
 }
 
 func TestRequestCertificate(t *testing.T) {
-	conn := getTestConnector(ctx.CloudZone)
+	ctx := context.Background()
+	conn := getTestConnector(env.CloudZone)
 	conn.verbose = true
-	err := conn.Authenticate(&endpoint.Authentication{APIKey: ctx.CloudAPIkey})
+	err := conn.Authenticate(ctx, &endpoint.Authentication{APIKey: env.CloudAPIkey})
 	if err != nil {
 		t.Fatalf("%s", err)
 	}
-	zoneConfig, err := conn.ReadZoneConfiguration()
+	zoneConfig, err := conn.ReadZoneConfiguration(ctx)
 	if err != nil {
 		t.Fatalf("%s", err)
 	}
@@ -136,20 +141,21 @@ func TestRequestCertificate(t *testing.T) {
 	req.Subject.CommonName = test.RandCN()
 	req.Subject.Organization = []string{"Venafi, Inc."}
 	req.Subject.OrganizationalUnit = []string{"Automated Tests"}
-	err = conn.GenerateRequest(zoneConfig, &req)
+	err = conn.GenerateRequest(ctx, zoneConfig, &req)
 	if err != nil {
 		t.Fatalf("%s", err)
 	}
-	_, err = conn.RequestCertificate(&req)
+	_, err = conn.RequestCertificate(ctx, &req)
 	if err != nil {
 		t.Fatalf("%s", err)
 	}
 }
 
 func TestRequestCertificateED25519WithValidation(t *testing.T) {
-	conn := getTestConnector(ctx.VAASzoneEC)
+	ctx := context.Background()
+	conn := getTestConnector(env.VAASzoneEC)
 	conn.verbose = true
-	err := conn.Authenticate(&endpoint.Authentication{APIKey: ctx.CloudAPIkey})
+	err := conn.Authenticate(ctx, &endpoint.Authentication{APIKey: env.CloudAPIkey})
 	if err != nil {
 		t.Fatalf("%s", err)
 	}
@@ -163,7 +169,7 @@ func TestRequestCertificateED25519WithValidation(t *testing.T) {
 	req.Subject.Country = []string{"US"}
 	req.KeyType = certificate.KeyTypeED25519
 
-	zoneConfig, err := conn.ReadZoneConfiguration()
+	zoneConfig, err := conn.ReadZoneConfiguration(ctx)
 	if err != nil {
 		t.Fatalf("%s", err)
 	}
@@ -175,20 +181,21 @@ func TestRequestCertificateED25519WithValidation(t *testing.T) {
 
 	zoneConfig.UpdateCertificateRequest(&req)
 
-	err = conn.GenerateRequest(zoneConfig, &req)
+	err = conn.GenerateRequest(ctx, zoneConfig, &req)
 	if err != nil {
 		t.Fatalf("%s", err)
 	}
-	_, err = conn.RequestCertificate(&req)
+	_, err = conn.RequestCertificate(ctx, &req)
 	if err != nil {
 		t.Fatalf("%s", err)
 	}
 }
 
 func TestRequestCertificateED25519WithPolicyValidation(t *testing.T) {
-	conn := getTestConnector(ctx.VAASzoneEC)
+	ctx := context.Background()
+	conn := getTestConnector(env.VAASzoneEC)
 	conn.verbose = true
-	err := conn.Authenticate(&endpoint.Authentication{APIKey: ctx.CloudAPIkey})
+	err := conn.Authenticate(ctx, &endpoint.Authentication{APIKey: env.CloudAPIkey})
 	if err != nil {
 		t.Fatalf("%s", err)
 	}
@@ -202,7 +209,7 @@ func TestRequestCertificateED25519WithPolicyValidation(t *testing.T) {
 	req.Subject.Country = []string{"US"}
 	req.KeyType = certificate.KeyTypeED25519
 
-	policy, err := conn.ReadPolicyConfiguration()
+	policy, err := conn.ReadPolicyConfiguration(ctx)
 	if err != nil {
 		t.Fatalf("could not read policy config certificate request: %s", err)
 	}
@@ -212,24 +219,25 @@ func TestRequestCertificateED25519WithPolicyValidation(t *testing.T) {
 		t.Fatalf("could not validate certificate request from policy: %s", err)
 	}
 
-	err = conn.GenerateRequest(nil, &req)
+	err = conn.GenerateRequest(ctx, nil, &req)
 	if err != nil {
 		t.Fatalf("%s", err)
 	}
-	_, err = conn.RequestCertificate(&req)
+	_, err = conn.RequestCertificate(ctx, &req)
 	if err != nil {
 		t.Fatalf("%s", err)
 	}
 }
 
 func TestRequestCertificateWithUsageMetadata(t *testing.T) {
-	conn := getTestConnector(ctx.CloudZone)
+	ctx := context.Background()
+	conn := getTestConnector(env.CloudZone)
 	conn.verbose = true
-	err := conn.Authenticate(&endpoint.Authentication{APIKey: ctx.CloudAPIkey})
+	err := conn.Authenticate(ctx, &endpoint.Authentication{APIKey: env.CloudAPIkey})
 	if err != nil {
 		t.Fatalf("%s", err)
 	}
-	zoneConfig, err := conn.ReadZoneConfiguration()
+	zoneConfig, err := conn.ReadZoneConfiguration(ctx)
 	if err != nil {
 		t.Fatalf("%s", err)
 	}
@@ -243,24 +251,25 @@ func TestRequestCertificateWithUsageMetadata(t *testing.T) {
 	}
 	req.Location = &location
 
-	err = conn.GenerateRequest(zoneConfig, &req)
+	err = conn.GenerateRequest(ctx, zoneConfig, &req)
 	if err != nil {
 		t.Fatalf("%s", err)
 	}
-	_, err = conn.RequestCertificate(&req)
+	_, err = conn.RequestCertificate(ctx, &req)
 	if err != nil {
 		t.Fatalf("%s", err)
 	}
 }
 
 func TestRequestCertificateWithValidityHours(t *testing.T) {
-	conn := getTestConnector(ctx.CloudZone)
+	ctx := context.Background()
+	conn := getTestConnector(env.CloudZone)
 	conn.verbose = true
-	err := conn.Authenticate(&endpoint.Authentication{APIKey: ctx.CloudAPIkey})
+	err := conn.Authenticate(ctx, &endpoint.Authentication{APIKey: env.CloudAPIkey})
 	if err != nil {
 		t.Fatalf("%s", err)
 	}
-	zoneConfig, err := conn.ReadZoneConfiguration()
+	zoneConfig, err := conn.ReadZoneConfiguration(ctx)
 	if err != nil {
 		t.Fatalf("%s", err)
 	}
@@ -274,11 +283,11 @@ func TestRequestCertificateWithValidityHours(t *testing.T) {
 	req.ValidityHours = nrHours
 	req.IssuerHint = util.IssuerHintMicrosoft
 
-	err = conn.GenerateRequest(zoneConfig, req)
+	err = conn.GenerateRequest(ctx, zoneConfig, req)
 	if err != nil {
 		t.Fatalf("%s", err)
 	}
-	pickupID, err := conn.RequestCertificate(req)
+	pickupID, err := conn.RequestCertificate(ctx, req)
 	if err != nil {
 		t.Fatalf("%s", err)
 	}
@@ -290,7 +299,7 @@ func TestRequestCertificateWithValidityHours(t *testing.T) {
 	startTime := time.Now()
 	for {
 
-		pcc, err = conn.RetrieveCertificate(req)
+		pcc, err = conn.RetrieveCertificate(ctx, req)
 		if err != nil {
 			_, ok := err.(endpoint.ErrCertificatePending)
 			if ok {
@@ -328,13 +337,14 @@ func TestRequestCertificateWithValidityHours(t *testing.T) {
 }
 
 func TestRequestCertificateWithValidityDuration(t *testing.T) {
-	conn := getTestConnector(ctx.CloudZone)
+	ctx := context.Background()
+	conn := getTestConnector(env.CloudZone)
 	conn.verbose = true
-	err := conn.Authenticate(&endpoint.Authentication{APIKey: ctx.CloudAPIkey})
+	err := conn.Authenticate(ctx, &endpoint.Authentication{APIKey: env.CloudAPIkey})
 	if err != nil {
 		t.Fatalf("%s", err)
 	}
-	zoneConfig, err := conn.ReadZoneConfiguration()
+	zoneConfig, err := conn.ReadZoneConfiguration(ctx)
 	if err != nil {
 		t.Fatalf("%s", err)
 	}
@@ -348,11 +358,11 @@ func TestRequestCertificateWithValidityDuration(t *testing.T) {
 	req.ValidityDuration = &validDuration
 	req.IssuerHint = util.IssuerHintMicrosoft
 
-	err = conn.GenerateRequest(zoneConfig, req)
+	err = conn.GenerateRequest(ctx, zoneConfig, req)
 	if err != nil {
 		t.Fatalf("%s", err)
 	}
-	pickupID, err := conn.RequestCertificate(req)
+	pickupID, err := conn.RequestCertificate(ctx, req)
 	if err != nil {
 		t.Fatalf("%s", err)
 	}
@@ -364,7 +374,7 @@ func TestRequestCertificateWithValidityDuration(t *testing.T) {
 	startTime := time.Now()
 	for {
 
-		pcc, err = conn.RetrieveCertificate(req)
+		pcc, err = conn.RetrieveCertificate(ctx, req)
 		if err != nil {
 			_, ok := err.(endpoint.ErrCertificatePending)
 			if ok {
@@ -402,12 +412,13 @@ func TestRequestCertificateWithValidityDuration(t *testing.T) {
 }
 
 func TestRetrieveCertificate(t *testing.T) {
-	conn := getTestConnector(ctx.CloudZone)
-	err := conn.Authenticate(&endpoint.Authentication{APIKey: ctx.CloudAPIkey})
+	ctx := context.Background()
+	conn := getTestConnector(env.CloudZone)
+	err := conn.Authenticate(ctx, &endpoint.Authentication{APIKey: env.CloudAPIkey})
 	if err != nil {
 		t.Fatalf("%s", err)
 	}
-	zoneConfig, err := conn.ReadZoneConfiguration()
+	zoneConfig, err := conn.ReadZoneConfiguration(ctx)
 	if err != nil {
 		t.Fatalf("%s", err)
 	}
@@ -415,11 +426,11 @@ func TestRetrieveCertificate(t *testing.T) {
 	req.Subject.CommonName = test.RandCN()
 	req.Subject.Organization = []string{"Venafi, Inc."}
 	req.Subject.OrganizationalUnit = []string{"Automated Tests"}
-	err = conn.GenerateRequest(zoneConfig, req)
+	err = conn.GenerateRequest(ctx, zoneConfig, req)
 	if err != nil {
 		t.Fatalf("%s", err)
 	}
-	pickupID, err := conn.RequestCertificate(req)
+	pickupID, err := conn.RequestCertificate(ctx, req)
 	if err != nil {
 		t.Fatalf("%s", err)
 	}
@@ -430,7 +441,7 @@ func TestRetrieveCertificate(t *testing.T) {
 	startTime := time.Now()
 	for {
 
-		pcc, err = conn.RetrieveCertificate(req)
+		pcc, err = conn.RetrieveCertificate(ctx, req)
 		if err != nil {
 			_, ok := err.(endpoint.ErrCertificatePending)
 			if ok {
@@ -468,12 +479,13 @@ func TestRetrieveCertificate(t *testing.T) {
 }
 
 func TestRetrieveCertificateRootFirst(t *testing.T) {
-	conn := getTestConnector(ctx.CloudZone)
-	err := conn.Authenticate(&endpoint.Authentication{APIKey: ctx.CloudAPIkey})
+	ctx := context.Background()
+	conn := getTestConnector(env.CloudZone)
+	err := conn.Authenticate(ctx, &endpoint.Authentication{APIKey: env.CloudAPIkey})
 	if err != nil {
 		t.Fatalf("%s", err)
 	}
-	zoneConfig, err := conn.ReadZoneConfiguration()
+	zoneConfig, err := conn.ReadZoneConfiguration(ctx)
 	if err != nil {
 		t.Fatalf("%s", err)
 	}
@@ -481,11 +493,11 @@ func TestRetrieveCertificateRootFirst(t *testing.T) {
 	req.Subject.CommonName = test.RandCN()
 	req.Subject.Organization = []string{"Venafi, Inc."}
 	req.Subject.OrganizationalUnit = []string{"Automated Tests"}
-	err = conn.GenerateRequest(zoneConfig, req)
+	err = conn.GenerateRequest(ctx, zoneConfig, req)
 	if err != nil {
 		t.Fatalf("%s", err)
 	}
-	pickupID, err := conn.RequestCertificate(req)
+	pickupID, err := conn.RequestCertificate(ctx, req)
 	if err != nil {
 		t.Fatalf("%s", err)
 	}
@@ -495,7 +507,7 @@ func TestRetrieveCertificateRootFirst(t *testing.T) {
 	startTime := time.Now()
 	pcc, _ := certificate.NewPEMCollection(nil, nil, nil)
 	for {
-		pcc, err = conn.RetrieveCertificate(req)
+		pcc, err = conn.RetrieveCertificate(ctx, req)
 		if err != nil {
 			_, ok := err.(endpoint.ErrCertificatePending)
 			if ok {
@@ -536,12 +548,13 @@ func TestRetrieveCertificateRootFirst(t *testing.T) {
 }
 
 func TestGetCertificateStatus(t *testing.T) {
-	conn := getTestConnector(ctx.CloudZone)
-	err := conn.Authenticate(&endpoint.Authentication{APIKey: ctx.CloudAPIkey})
+	ctx := context.Background()
+	conn := getTestConnector(env.CloudZone)
+	err := conn.Authenticate(ctx, &endpoint.Authentication{APIKey: env.CloudAPIkey})
 	if err != nil {
 		t.Fatalf("%s", err)
 	}
-	zoneConfig, err := conn.ReadZoneConfiguration()
+	zoneConfig, err := conn.ReadZoneConfiguration(ctx)
 	if err != nil {
 		t.Fatalf("%s", err)
 	}
@@ -549,35 +562,36 @@ func TestGetCertificateStatus(t *testing.T) {
 	req.Subject.CommonName = test.RandCN()
 	req.Subject.Organization = []string{"Venafi, Inc."}
 	req.Subject.OrganizationalUnit = []string{"Automated Tests"}
-	err = conn.GenerateRequest(zoneConfig, req)
+	err = conn.GenerateRequest(ctx, zoneConfig, req)
 	if err != nil {
 		t.Fatalf("%s", err)
 	}
-	reqId, err := conn.RequestCertificate(req)
+	reqId, err := conn.RequestCertificate(ctx, req)
 	if err != nil {
 		t.Fatalf("%s", err)
 	}
 
-	_, err = conn.getCertificateStatus(reqId)
+	_, err = conn.getCertificateStatus(ctx, reqId)
 	if err != nil {
 		t.Fatalf("failed to get certificate request status: %s", err)
 	}
 
 	invalidCertificateRequestId := "42424242-63a0-11e8-b5a3-f186be5c5fab"
-	_, err = conn.getCertificateStatus(invalidCertificateRequestId)
+	_, err = conn.getCertificateStatus(ctx, invalidCertificateRequestId)
 	if err == nil {
 		t.Fatalf("it should return error when there is not such request found")
 	}
 }
 
 func TestRenewCertificate(t *testing.T) {
+	ctx := context.Background()
 	t.Skip() //todo: remove if condor team fix bug. check after 2020.04
-	conn := getTestConnector(ctx.CloudZone)
-	err := conn.Authenticate(&endpoint.Authentication{APIKey: ctx.CloudAPIkey})
+	conn := getTestConnector(env.CloudZone)
+	err := conn.Authenticate(ctx, &endpoint.Authentication{APIKey: env.CloudAPIkey})
 	if err != nil {
 		t.Fatalf("%s", err)
 	}
-	zoneConfig, err := conn.ReadZoneConfiguration()
+	zoneConfig, err := conn.ReadZoneConfiguration(ctx)
 	if err != nil {
 		t.Fatalf("%s", err)
 	}
@@ -585,17 +599,17 @@ func TestRenewCertificate(t *testing.T) {
 	req.Subject.CommonName = test.RandCN()
 	req.Subject.Organization = []string{"Venafi, Inc."}
 	req.Subject.OrganizationalUnit = []string{"Automated Tests"}
-	err = conn.GenerateRequest(zoneConfig, req)
+	err = conn.GenerateRequest(ctx, zoneConfig, req)
 	if err != nil {
 		t.Fatalf("%s", err)
 	}
-	pickupID, err := conn.RequestCertificate(req)
+	pickupID, err := conn.RequestCertificate(ctx, req)
 	if err != nil {
 		t.Fatalf("%s", err)
 	}
 
 	renewTooEarly := &certificate.RenewalRequest{CertificateDN: pickupID}
-	_, err = conn.RenewCertificate(renewTooEarly)
+	_, err = conn.RenewCertificate(ctx, renewTooEarly)
 	if err == nil {
 		t.Fatal("it should return error on attempt to renew a certificate that is not issued yet")
 	}
@@ -605,7 +619,7 @@ func TestRenewCertificate(t *testing.T) {
 	startTime := time.Now()
 	pcc, _ := certificate.NewPEMCollection(nil, nil, nil)
 	for {
-		pcc, err = conn.RetrieveCertificate(req)
+		pcc, err = conn.RetrieveCertificate(ctx, req)
 		if err != nil {
 			_, ok := err.(endpoint.ErrCertificatePending)
 			if ok {
@@ -636,14 +650,14 @@ func TestRenewCertificate(t *testing.T) {
 
 	// time to renew
 	renewByFingerprint := &certificate.RenewalRequest{Thumbprint: strings.ToUpper(fingerprint)}
-	reqId3, err := conn.RenewCertificate(renewByFingerprint)
+	reqId3, err := conn.RenewCertificate(ctx, renewByFingerprint)
 	if err != nil {
 		t.Fatal(err)
 	}
 	t.Logf("requested renewal for %s, will pickup by %s", fingerprint, reqId3)
 
 	renewByCertificateDN := &certificate.RenewalRequest{CertificateDN: reqId3}
-	reqId1, err := conn.RenewCertificate(renewByCertificateDN)
+	reqId1, err := conn.RenewCertificate(ctx, renewByCertificateDN)
 	if err != nil {
 		t.Fatal(err)
 	}
@@ -652,13 +666,14 @@ func TestRenewCertificate(t *testing.T) {
 }
 
 func TestRenewCertificateWithUsageMetadata(t *testing.T) {
+	ctx := context.Background()
 	t.Skip() //todo: remove if condor team fix bug. check after 2020.04
-	conn := getTestConnector(ctx.CloudZone)
-	err := conn.Authenticate(&endpoint.Authentication{APIKey: ctx.CloudAPIkey})
+	conn := getTestConnector(env.CloudZone)
+	err := conn.Authenticate(ctx, &endpoint.Authentication{APIKey: env.CloudAPIkey})
 	if err != nil {
 		t.Fatalf("%s", err)
 	}
-	zoneConfig, err := conn.ReadZoneConfiguration()
+	zoneConfig, err := conn.ReadZoneConfiguration(ctx)
 	if err != nil {
 		t.Fatalf("%s", err)
 	}
@@ -672,11 +687,11 @@ func TestRenewCertificateWithUsageMetadata(t *testing.T) {
 	}
 	req.Location = &location
 
-	err = conn.GenerateRequest(zoneConfig, req)
+	err = conn.GenerateRequest(ctx, zoneConfig, req)
 	if err != nil {
 		t.Fatalf("%s", err)
 	}
-	pickupID, err := conn.RequestCertificate(req)
+	pickupID, err := conn.RequestCertificate(ctx, req)
 	if err != nil {
 		t.Fatalf("%s", err)
 	}
@@ -684,7 +699,7 @@ func TestRenewCertificateWithUsageMetadata(t *testing.T) {
 	renewTooEarly := &certificate.RenewalRequest{CertificateDN: pickupID}
 	renewTooEarly.CertificateRequest.Location = &location
 
-	_, err = conn.RenewCertificate(renewTooEarly)
+	_, err = conn.RenewCertificate(ctx, renewTooEarly)
 	if err == nil {
 		t.Fatal("it should return error on attempt to renew a certificate that is not issued yet")
 	}
@@ -694,7 +709,7 @@ func TestRenewCertificateWithUsageMetadata(t *testing.T) {
 	startTime := time.Now()
 	pcc, _ := certificate.NewPEMCollection(nil, nil, nil)
 	for {
-		pcc, err = conn.RetrieveCertificate(req)
+		pcc, err = conn.RetrieveCertificate(ctx, req)
 		if err != nil {
 			_, ok := err.(endpoint.ErrCertificatePending)
 			if ok {
@@ -726,7 +741,7 @@ func TestRenewCertificateWithUsageMetadata(t *testing.T) {
 	// time to renew
 	renewByFingerprint := &certificate.RenewalRequest{Thumbprint: strings.ToUpper(fingerprint)}
 	renewByFingerprint.CertificateRequest.Location = &location
-	reqId3, err := conn.RenewCertificate(renewByFingerprint)
+	reqId3, err := conn.RenewCertificate(ctx, renewByFingerprint)
 	if err != nil {
 		t.Fatal(err)
 	}
@@ -734,7 +749,7 @@ func TestRenewCertificateWithUsageMetadata(t *testing.T) {
 
 	renewByCertificateDN := &certificate.RenewalRequest{CertificateDN: reqId3}
 	renewByCertificateDN.CertificateRequest.Location = &location
-	reqId1, err := conn.RenewCertificate(renewByCertificateDN)
+	reqId1, err := conn.RenewCertificate(ctx, renewByCertificateDN)
 	if err != nil {
 		t.Fatal(err)
 	}
@@ -743,13 +758,14 @@ func TestRenewCertificateWithUsageMetadata(t *testing.T) {
 }
 
 func TestReadPolicyConfiguration(t *testing.T) {
+	ctx := context.Background()
 	//todo: add more zones
-	conn := getTestConnector(ctx.CloudZone)
-	err := conn.Authenticate(&endpoint.Authentication{APIKey: ctx.CloudAPIkey})
+	conn := getTestConnector(env.CloudZone)
+	err := conn.Authenticate(ctx, &endpoint.Authentication{APIKey: env.CloudAPIkey})
 	if err != nil {
 		t.Fatalf("%s", err)
 	}
-	policy, err := conn.ReadPolicyConfiguration()
+	policy, err := conn.ReadPolicyConfiguration(ctx)
 	if err != nil {
 		t.Fatalf("%s", err)
 	}
@@ -767,17 +783,18 @@ func TestReadPolicyConfiguration(t *testing.T) {
 	}
 
 	if !reflect.DeepEqual(*policy, expectedPolice) {
-		t.Fatalf("policy for zone %s is not as expected \nget:    %+v \nexpect: %+v", ctx.CloudZone, *policy, expectedPolice)
+		t.Fatalf("policy for zone %s is not as expected \nget:    %+v \nexpect: %+v", env.CloudZone, *policy, expectedPolice)
 	}
 }
 
 func TestRetireCertificate(t *testing.T) {
-	conn := getTestConnector(ctx.CloudZone)
-	err := conn.Authenticate(&endpoint.Authentication{APIKey: ctx.CloudAPIkey})
+	ctx := context.Background()
+	conn := getTestConnector(env.CloudZone)
+	err := conn.Authenticate(ctx, &endpoint.Authentication{APIKey: env.CloudAPIkey})
 	if err != nil {
 		t.Fatalf("%s", err)
 	}
-	zoneConfig, err := conn.ReadZoneConfiguration()
+	zoneConfig, err := conn.ReadZoneConfiguration(ctx)
 	if err != nil {
 		t.Fatalf("%s", err)
 	}
@@ -785,11 +802,11 @@ func TestRetireCertificate(t *testing.T) {
 	req.Subject.CommonName = test.RandCN()
 	req.Subject.Organization = []string{"Venafi, Inc."}
 	req.Subject.OrganizationalUnit = []string{"Automated Tests"}
-	err = conn.GenerateRequest(zoneConfig, req)
+	err = conn.GenerateRequest(ctx, zoneConfig, req)
 	if err != nil {
 		t.Fatalf("%s", err)
 	}
-	pickupID, err := conn.RequestCertificate(req)
+	pickupID, err := conn.RequestCertificate(ctx, req)
 	if err != nil {
 		t.Fatalf("%s", err)
 	}
@@ -799,7 +816,7 @@ func TestRetireCertificate(t *testing.T) {
 	pcc, _ := certificate.NewPEMCollection(nil, nil, nil)
 	startTime := time.Now()
 	for {
-		pcc, err = conn.RetrieveCertificate(req)
+		pcc, err = conn.RetrieveCertificate(ctx, req)
 		if err != nil {
 			_, ok := err.(endpoint.ErrCertificatePending)
 			if ok {
@@ -829,19 +846,20 @@ func TestRetireCertificate(t *testing.T) {
 	// VaaS may be able to retrieve cert from API immediately, but storing in inventory may take a few seconds
 	// or even stuck into it
 	time.Sleep(time.Duration(2) * time.Second)
-	err = conn.RetireCertificate(retireReq)
+	err = conn.RetireCertificate(ctx, retireReq)
 	if err != nil {
 		t.Fatalf("%s", err)
 	}
 }
 
 func TestRetireCertificateWithPickUpID(t *testing.T) {
-	conn := getTestConnector(ctx.CloudZone)
-	err := conn.Authenticate(&endpoint.Authentication{APIKey: ctx.CloudAPIkey})
+	ctx := context.Background()
+	conn := getTestConnector(env.CloudZone)
+	err := conn.Authenticate(ctx, &endpoint.Authentication{APIKey: env.CloudAPIkey})
 	if err != nil {
 		t.Fatalf("%s", err)
 	}
-	zoneConfig, err := conn.ReadZoneConfiguration()
+	zoneConfig, err := conn.ReadZoneConfiguration(ctx)
 	if err != nil {
 		t.Fatalf("%s", err)
 	}
@@ -849,11 +867,11 @@ func TestRetireCertificateWithPickUpID(t *testing.T) {
 	req.Subject.CommonName = test.RandCN()
 	req.Subject.Organization = []string{"Venafi, Inc."}
 	req.Subject.OrganizationalUnit = []string{"Automated Tests"}
-	err = conn.GenerateRequest(zoneConfig, req)
+	err = conn.GenerateRequest(ctx, zoneConfig, req)
 	if err != nil {
 		t.Fatalf("%s", err)
 	}
-	pickupID, err := conn.RequestCertificate(req)
+	pickupID, err := conn.RequestCertificate(ctx, req)
 	if err != nil {
 		t.Fatalf("%s", err)
 	}
@@ -865,19 +883,20 @@ func TestRetireCertificateWithPickUpID(t *testing.T) {
 	// VaaS may be able to retrieve cert from API immediately, but storing in inventory may take a few seconds
 	// or even stuck into it
 	time.Sleep(time.Duration(2) * time.Second)
-	err = conn.RetireCertificate(retireReq)
+	err = conn.RetireCertificate(ctx, retireReq)
 	if err != nil {
 		t.Fatalf("%s", err)
 	}
 }
 
 func TestRetireCertificateTwice(t *testing.T) {
-	conn := getTestConnector(ctx.CloudZone)
-	err := conn.Authenticate(&endpoint.Authentication{APIKey: ctx.CloudAPIkey})
+	ctx := context.Background()
+	conn := getTestConnector(env.CloudZone)
+	err := conn.Authenticate(ctx, &endpoint.Authentication{APIKey: env.CloudAPIkey})
 	if err != nil {
 		t.Fatalf("%s", err)
 	}
-	zoneConfig, err := conn.ReadZoneConfiguration()
+	zoneConfig, err := conn.ReadZoneConfiguration(ctx)
 	if err != nil {
 		t.Fatalf("%s", err)
 	}
@@ -885,11 +904,11 @@ func TestRetireCertificateTwice(t *testing.T) {
 	req.Subject.CommonName = test.RandCN()
 	req.Subject.Organization = []string{"Venafi, Inc."}
 	req.Subject.OrganizationalUnit = []string{"Automated Tests"}
-	err = conn.GenerateRequest(zoneConfig, req)
+	err = conn.GenerateRequest(ctx, zoneConfig, req)
 	if err != nil {
 		t.Fatalf("%s", err)
 	}
-	pickupID, err := conn.RequestCertificate(req)
+	pickupID, err := conn.RequestCertificate(ctx, req)
 	if err != nil {
 		t.Fatalf("%s", err)
 	}
@@ -901,7 +920,7 @@ func TestRetireCertificateTwice(t *testing.T) {
 	// VaaS may be able to retrieve cert from API immediately, but storing in inventory may take a few seconds
 	// or even stuck into it
 	time.Sleep(time.Duration(2) * time.Second)
-	err = conn.RetireCertificate(retireReq)
+	err = conn.RetireCertificate(ctx, retireReq)
 	if err != nil {
 		t.Fatalf("%s", err)
 	}
@@ -909,24 +928,25 @@ func TestRetireCertificateTwice(t *testing.T) {
 	retireReqSecond := &certificate.RetireRequest{}
 	retireReqSecond.CertificateDN = pickupID
 
-	err = conn.RetireCertificate(retireReqSecond)
+	err = conn.RetireCertificate(ctx, retireReqSecond)
 	if err != nil {
 		t.Fatalf("%s", err)
 	}
 }
 
 func TestReadPolicyConfigurationOnlyEC(t *testing.T) {
+	ctx := context.Background()
 	// IMPORTANT NOTE: Now in VCert, we are treating ED25519 Keys, as per it's a different algorithm from ECDSA, as another
 	// type of key. This is conflicting with how VaaS handles EC Keys, as it considers ED25519 as another curve, which is
 	// it shouldn't, this test may need to change in the future once this is solved
 	//todo: add more zones
-	conn := getTestConnector(ctx.VAASzoneEC)
-	err := conn.Authenticate(&endpoint.Authentication{APIKey: ctx.CloudAPIkey})
+	conn := getTestConnector(env.VAASzoneEC)
+	err := conn.Authenticate(ctx, &endpoint.Authentication{APIKey: env.CloudAPIkey})
 	if err != nil {
 		t.Fatalf("%s", err)
 	}
 	// This calls at the end of everything: policy.toPolicy() which is the function we wanted to test the KeyCurves
-	policy, err := conn.ReadPolicyConfiguration()
+	policy, err := conn.ReadPolicyConfiguration(ctx)
 	if err != nil {
 		t.Fatalf("%s", err)
 	}
@@ -944,7 +964,7 @@ func TestReadPolicyConfigurationOnlyEC(t *testing.T) {
 	}
 
 	if !reflect.DeepEqual(*policy, expectedPolice) {
-		t.Fatalf("policy for zone %s is not as expected \nget:    %+v \nexpect: %+v", ctx.CloudZone, *policy, expectedPolice)
+		t.Fatalf("policy for zone %s is not as expected \nget:    %+v \nexpect: %+v", env.CloudZone, *policy, expectedPolice)
 	}
 }
 
@@ -975,9 +995,10 @@ func newSelfSignedCert() (string, error) {
 }
 
 func TestImportCertificate(t *testing.T) {
+	ctx := context.Background()
 
-	conn := getTestConnector(ctx.CloudZone)
-	err := conn.Authenticate(&endpoint.Authentication{APIKey: ctx.CloudAPIkey})
+	conn := getTestConnector(env.CloudZone)
+	err := conn.Authenticate(ctx, &endpoint.Authentication{APIKey: env.CloudAPIkey})
 	if err != nil {
 		t.Fatalf("%s", err)
 	}
@@ -993,7 +1014,7 @@ func TestImportCertificate(t *testing.T) {
 		Reconcile:       false,
 	}
 
-	importResp, err := conn.ImportCertificate(importReq)
+	importResp, err := conn.ImportCertificate(ctx, importReq)
 	if err != nil {
 		t.Fatalf("failed to import certificate: %s", err)
 	}
@@ -1078,14 +1099,15 @@ func TestGetURL(t *testing.T) {
 }
 
 func TestRetrieveCertificatesList(t *testing.T) {
-	conn := getTestConnector(ctx.CloudZone)
-	err := conn.Authenticate(&endpoint.Authentication{APIKey: ctx.CloudAPIkey})
+	ctx := context.Background()
+	conn := getTestConnector(env.CloudZone)
+	err := conn.Authenticate(ctx, &endpoint.Authentication{APIKey: env.CloudAPIkey})
 	if err != nil {
 		t.Fatalf("%s", err)
 	}
 	for _, count := range []int{10, 100, 101, 153} {
 		timeStarted := time.Now()
-		l, err := conn.ListCertificates(endpoint.Filter{Limit: &count})
+		l, err := conn.ListCertificates(ctx, endpoint.Filter{Limit: &count})
 		if err != nil {
 			t.Fatal(err)
 		}
@@ -1103,12 +1125,13 @@ func TestRetrieveCertificatesList(t *testing.T) {
 }
 
 func TestSearchCertificate(t *testing.T) {
-	conn := getTestConnector(ctx.CloudZone)
-	err := conn.Authenticate(&endpoint.Authentication{APIKey: ctx.CloudAPIkey})
+	ctx := context.Background()
+	conn := getTestConnector(env.CloudZone)
+	err := conn.Authenticate(ctx, &endpoint.Authentication{APIKey: env.CloudAPIkey})
 	if err != nil {
 		t.Fatal(err)
 	}
-	zoneConfig, err := conn.ReadZoneConfiguration()
+	zoneConfig, err := conn.ReadZoneConfiguration(ctx)
 	if err != nil {
 		t.Fatal(err)
 	}
@@ -1116,34 +1139,35 @@ func TestSearchCertificate(t *testing.T) {
 	req := certificate.Request{}
 	req.Subject.CommonName = test.RandCN()
 	req.Timeout = time.Second * 10
-	err = conn.GenerateRequest(zoneConfig, &req)
+	err = conn.GenerateRequest(ctx, zoneConfig, &req)
 	if err != nil {
 		t.Fatal(err)
 	}
-	req.PickupID, err = conn.RequestCertificate(&req)
+	req.PickupID, err = conn.RequestCertificate(ctx, &req)
 	if err != nil {
 		t.Fatal(err)
 	}
-	cert, err := conn.RetrieveCertificate(&req)
+	cert, err := conn.RetrieveCertificate(ctx, &req)
 	if err != nil {
 		t.Fatal(err)
 	}
 	p, _ := pem.Decode([]byte(cert.Certificate))
 	thumbprint := certThumbprint(p.Bytes)
-	_, err = conn.searchCertificatesByFingerprint(thumbprint)
+	_, err = conn.searchCertificatesByFingerprint(ctx, thumbprint)
 	if err != nil {
 		t.Fatal(err)
 	}
 }
 
 func TestSetPolicy(t *testing.T) {
+	ctx := context.Background()
 	appName := test.RandAppName()
 
 	policyName := appName + "\\" + test.RandCitName()
-	conn := getTestConnector(ctx.CloudZone)
+	conn := getTestConnector(env.CloudZone)
 	conn.verbose = true
 
-	err := conn.Authenticate(&endpoint.Authentication{APIKey: ctx.CloudAPIkey})
+	err := conn.Authenticate(ctx, &endpoint.Authentication{APIKey: env.CloudAPIkey})
 
 	if err != nil {
 		t.Fatalf("%s", err)
@@ -1151,7 +1175,7 @@ func TestSetPolicy(t *testing.T) {
 
 	localPolicy := test.GetCloudPolicySpecification()
 
-	_, err = conn.SetPolicy(policyName, localPolicy)
+	_, err = conn.SetPolicy(ctx, policyName, localPolicy)
 
 	if err != nil {
 		t.Fatalf("%s", err)
@@ -1161,20 +1185,20 @@ func TestSetPolicy(t *testing.T) {
 	t.Log("updating policy, modifying ps.Policy.Subject.OrgUnits = []string{\"DevOps\", \"QA\"}.")
 	localPolicy.Policy.Subject.OrgUnits = []string{"DevOps", "QA"}
 	//policyName = appName + "\\" + test.RandCitName()
-	_, err = conn.SetPolicy(policyName, localPolicy)
+	_, err = conn.SetPolicy(ctx, policyName, localPolicy)
 
 	if err != nil {
 		t.Fatalf("%s", err)
 	}
 
-	ps, err := conn.GetPolicy(policyName)
+	ps, err := conn.GetPolicy(ctx, policyName)
 
 	if err != nil {
 		t.Fatalf("%s", err)
 	}
 
 	//validate each attribute
-	userDetails, err := getUserDetails(conn)
+	userDetails, err := getUserDetails(ctx, conn)
 	//validating the default users attribute was created
 	users := []string{
 		//"jenkins@opensource.qa.venafi.io",
@@ -1189,13 +1213,13 @@ func TestSetPolicy(t *testing.T) {
 	users = append(users, "resource-owner@opensource.qa.venafi.io")
 	ps.Users = users
 
-	_, err = conn.SetPolicy(policyName, ps)
+	_, err = conn.SetPolicy(ctx, policyName, ps)
 
 	if err != nil {
 		t.Fatalf("%s", err)
 	}
 
-	ps, err = conn.GetPolicy(policyName)
+	ps, err = conn.GetPolicy(ctx, policyName)
 
 	if err != nil {
 		t.Fatalf("%s", err)
@@ -1415,14 +1439,15 @@ func TestSetPolicy(t *testing.T) {
 }
 
 func TestGetPolicy(t *testing.T) {
+	ctx := context.Background()
 
 	t.Skip() //this is just for development purpose
 
 	policyName := os.Getenv("CLOUD_POLICY_MANAGEMENT_SAMPLE")
-	conn := getTestConnector(ctx.CloudZone)
+	conn := getTestConnector(env.CloudZone)
 	conn.verbose = true
 
-	err := conn.Authenticate(&endpoint.Authentication{APIKey: ctx.CloudAPIkey})
+	err := conn.Authenticate(ctx, &endpoint.Authentication{APIKey: env.CloudAPIkey})
 
 	if err != nil {
 		t.Fatalf("%s", err)
@@ -1430,7 +1455,7 @@ func TestGetPolicy(t *testing.T) {
 
 	specifiedPS := test.GetCloudPolicySpecification()
 
-	ps, err := conn.GetPolicy(policyName)
+	ps, err := conn.GetPolicy(ctx, policyName)
 
 	if err != nil {
 		t.Fatalf("%s", err)
@@ -1640,15 +1665,16 @@ func TestGetPolicy(t *testing.T) {
 }
 
 func TestGetPolicyOnlyEC(t *testing.T) {
+	ctx := context.Background()
 
 	// This test covers GetPolicy function from connector to test EC curves are return correctly for all the values,
 	// including RecommendSettings
 
 	policyName := os.Getenv("VAAS_ZONE_EC")
-	conn := getTestConnector(ctx.VAASzoneEC)
+	conn := getTestConnector(env.VAASzoneEC)
 	conn.verbose = true
 
-	err := conn.Authenticate(&endpoint.Authentication{APIKey: ctx.CloudAPIkey})
+	err := conn.Authenticate(ctx, &endpoint.Authentication{APIKey: env.CloudAPIkey})
 
 	if err != nil {
 		t.Fatalf("%s", err)
@@ -1656,7 +1682,7 @@ func TestGetPolicyOnlyEC(t *testing.T) {
 
 	specifiedPS := test.GetVAASpolicySpecificationEC()
 
-	ps, err := conn.GetPolicy(policyName)
+	ps, err := conn.GetPolicy(ctx, policyName)
 
 	if err != nil {
 		t.Fatalf("%s", err)
@@ -1869,12 +1895,13 @@ func TestGetPolicyOnlyEC(t *testing.T) {
 }
 
 func TestSetEmptyPolicy(t *testing.T) {
+	ctx := context.Background()
 
 	policyName := test.RandAppName() + "\\" + test.RandCitName()
-	conn := getTestConnector(ctx.CloudZone)
+	conn := getTestConnector(env.CloudZone)
 	conn.verbose = true
 
-	err := conn.Authenticate(&endpoint.Authentication{APIKey: ctx.CloudAPIkey})
+	err := conn.Authenticate(ctx, &endpoint.Authentication{APIKey: env.CloudAPIkey})
 
 	if err != nil {
 		t.Fatalf("%s", err)
@@ -1882,7 +1909,7 @@ func TestSetEmptyPolicy(t *testing.T) {
 
 	specification := policy.PolicySpecification{}
 
-	_, err = conn.SetPolicy(policyName, &specification)
+	_, err = conn.SetPolicy(ctx, policyName, &specification)
 
 	if err != nil {
 		t.Fatalf("%s", err)
@@ -1891,12 +1918,13 @@ func TestSetEmptyPolicy(t *testing.T) {
 }
 
 func TestSetDefaultPolicyValuesAndValidate(t *testing.T) {
+	ctx := context.Background()
 
 	policyName := test.RandAppName() + "\\" + test.RandCitName()
-	conn := getTestConnector(ctx.CloudZone)
+	conn := getTestConnector(env.CloudZone)
 	conn.verbose = true
 
-	err := conn.Authenticate(&endpoint.Authentication{APIKey: ctx.CloudAPIkey})
+	err := conn.Authenticate(ctx, &endpoint.Authentication{APIKey: env.CloudAPIkey})
 
 	if err != nil {
 		t.Fatalf("%s", err)
@@ -1909,16 +1937,16 @@ func TestSetDefaultPolicyValuesAndValidate(t *testing.T) {
 	serGenerated := true
 	specification.Default.KeyPair.EllipticCurve = &ec
 	specification.Default.KeyPair.ServiceGenerated = &serGenerated
-	ctx.CloudZone = policyName
+	env.CloudZone = policyName
 
-	_, err = conn.SetPolicy(policyName, specification)
+	_, err = conn.SetPolicy(ctx, policyName, specification)
 
 	if err != nil {
 		t.Fatalf("%s", err)
 	}
 
 	//get the created policy
-	ps, err := conn.GetPolicy(policyName)
+	ps, err := conn.GetPolicy(ctx, policyName)
 
 	if err != nil {
 		t.Fatalf("%s", err)
@@ -1969,12 +1997,13 @@ func TestSetDefaultPolicyValuesAndValidate(t *testing.T) {
 }
 
 func TestSetPolicyValuesAndValidate(t *testing.T) {
+	ctx := context.Background()
 
 	policyName := test.RandAppName() + "\\" + test.RandCitName()
-	conn := getTestConnector(ctx.CloudZone)
+	conn := getTestConnector(env.CloudZone)
 	conn.verbose = true
 
-	err := conn.Authenticate(&endpoint.Authentication{APIKey: ctx.CloudAPIkey})
+	err := conn.Authenticate(ctx, &endpoint.Authentication{APIKey: env.CloudAPIkey})
 
 	if err != nil {
 		t.Fatalf("%s", err)
@@ -1983,16 +2012,16 @@ func TestSetPolicyValuesAndValidate(t *testing.T) {
 	specification := test.GetCloudPolicySpecification()
 
 	specification.Default = nil
-	ctx.CloudZone = policyName
+	env.CloudZone = policyName
 
-	_, err = conn.SetPolicy(policyName, specification)
+	_, err = conn.SetPolicy(ctx, policyName, specification)
 
 	if err != nil {
 		t.Fatalf("%s", err)
 	}
 
 	//get the created policy
-	ps, err := conn.GetPolicy(policyName)
+	ps, err := conn.GetPolicy(ctx, policyName)
 
 	if err != nil {
 		t.Fatalf("%s", err)
@@ -2051,12 +2080,13 @@ func TestSetPolicyValuesAndValidate(t *testing.T) {
 
 // This test is just for verifying that a policy can be created using ENTRUST CA.
 func TestSetPolicyEntrust(t *testing.T) {
+	ctx := context.Background()
 
 	policyName := test.RandAppName() + "\\" + test.RandCitName()
-	conn := getTestConnector(ctx.CloudZone)
+	conn := getTestConnector(env.CloudZone)
 	conn.verbose = true
 
-	err := conn.Authenticate(&endpoint.Authentication{APIKey: ctx.CloudAPIkey})
+	err := conn.Authenticate(ctx, &endpoint.Authentication{APIKey: env.CloudAPIkey})
 
 	if err != nil {
 		t.Fatalf("%s", err)
@@ -2067,13 +2097,13 @@ func TestSetPolicyEntrust(t *testing.T) {
 	caName := os.Getenv("CLOUD_ENTRUST_CA_NAME")
 	specification.Policy.CertificateAuthority = &caName
 
-	_, err = conn.SetPolicy(policyName, specification)
+	_, err = conn.SetPolicy(ctx, policyName, specification)
 
 	if err != nil {
 		t.Fatalf("%s", err)
 	}
 
-	ps, err := conn.GetPolicy(policyName)
+	ps, err := conn.GetPolicy(ctx, policyName)
 
 	if ps.Policy.CertificateAuthority == nil || *(ps.Policy.CertificateAuthority) == "" {
 		t.Fatalf("venafi policy doesn't have a certificate authority")
@@ -2089,12 +2119,13 @@ func TestSetPolicyEntrust(t *testing.T) {
 This test is just for verifying that a policy can be created using DIGICERT	 CA.
 */
 func TestSetPolicyDigicert(t *testing.T) {
+	ctx := context.Background()
 
 	policyName := test.RandAppName() + "\\" + test.RandCitName()
-	conn := getTestConnector(ctx.CloudZone)
+	conn := getTestConnector(env.CloudZone)
 	conn.verbose = true
 
-	err := conn.Authenticate(&endpoint.Authentication{APIKey: ctx.CloudAPIkey})
+	err := conn.Authenticate(ctx, &endpoint.Authentication{APIKey: env.CloudAPIkey})
 
 	if err != nil {
 		t.Fatalf("%s", err)
@@ -2105,13 +2136,13 @@ func TestSetPolicyDigicert(t *testing.T) {
 	//change default CA to Digiert
 	caName := os.Getenv("CLOUD_DIGICERT_CA_NAME")
 	specification.Policy.CertificateAuthority = &caName
-	_, err = conn.SetPolicy(policyName, specification)
+	_, err = conn.SetPolicy(ctx, policyName, specification)
 
 	if err != nil {
 		t.Fatalf("%s", err)
 	}
 
-	ps, err := conn.GetPolicy(policyName)
+	ps, err := conn.GetPolicy(ctx, policyName)
 
 	if ps.Policy.CertificateAuthority == nil || *(ps.Policy.CertificateAuthority) == "" {
 		t.Fatalf("venafi policy doesn't have a certificate authority")
@@ -2122,10 +2153,11 @@ func TestSetPolicyDigicert(t *testing.T) {
 }
 
 func TestCreateCertServiceCSR(t *testing.T) {
+	ctx := context.Background()
 	policyName := os.Getenv("CLOUD_ZONE_RESTRICTED")
 	conn := getTestConnector(policyName)
 	conn.verbose = true
-	err := conn.Authenticate(&endpoint.Authentication{APIKey: ctx.CloudAPIkey})
+	err := conn.Authenticate(ctx, &endpoint.Authentication{APIKey: env.CloudAPIkey})
 	if err != nil {
 		t.Fatalf("%s", err)
 	}
@@ -2137,7 +2169,7 @@ func TestCreateCertServiceCSR(t *testing.T) {
 
 	req.CsrOrigin = certificate.ServiceGeneratedCSR
 
-	id, err := conn.RequestCertificate(&req)
+	id, err := conn.RequestCertificate(ctx, &req)
 	if err != nil {
 		t.Fatalf("%s", err)
 	}
@@ -2145,7 +2177,7 @@ func TestCreateCertServiceCSR(t *testing.T) {
 	req.ChainOption = certificate.ChainOptionRootFirst
 	req.KeyPassword = "abcede"
 	req.Timeout = time.Duration(180) * time.Second
-	pcc, err := conn.RetrieveCertificate(&req)
+	pcc, err := conn.RetrieveCertificate(ctx, &req)
 
 	if pcc.Certificate == "" {
 		t.Fatalf("certificate with pickup id: %s is empty", req.PickupID)
@@ -2160,10 +2192,11 @@ func TestCreateCertServiceCSR(t *testing.T) {
 }
 
 func TestCreateCertServiceCSRWithDefaults(t *testing.T) {
+	ctx := context.Background()
 	t.Skip("it will enabled on the future")
 	conn := getTestConnector("App Alfa\\Amoo")
 	conn.verbose = true
-	err := conn.Authenticate(&endpoint.Authentication{APIKey: ctx.CloudAPIkey})
+	err := conn.Authenticate(ctx, &endpoint.Authentication{APIKey: env.CloudAPIkey})
 	if err != nil {
 		t.Fatalf("%s", err)
 	}
@@ -2175,7 +2208,7 @@ func TestCreateCertServiceCSRWithDefaults(t *testing.T) {
 
 	req.CsrOrigin = certificate.ServiceGeneratedCSR
 
-	id, err := conn.RequestCertificate(&req)
+	id, err := conn.RequestCertificate(ctx, &req)
 	if err != nil {
 		t.Fatalf("%s", err)
 	}
@@ -2183,7 +2216,7 @@ func TestCreateCertServiceCSRWithDefaults(t *testing.T) {
 	req.ChainOption = certificate.ChainOptionRootFirst
 	req.KeyPassword = "abcede"
 	req.Timeout = time.Duration(180) * time.Second
-	pcc, err := conn.RetrieveCertificate(&req)
+	pcc, err := conn.RetrieveCertificate(ctx, &req)
 
 	if pcc.Certificate == "" {
 		t.Fatalf("certificate with pickup id: %s is empty", req.PickupID)
@@ -2198,6 +2231,7 @@ func TestCreateCertServiceCSRWithDefaults(t *testing.T) {
 }
 
 func TestGetDefaultCsrAttributes(t *testing.T) {
+	ctx := context.Background()
 
 	policyName := os.Getenv("CLOUD_ZONE_RESTRICTED")
 	conn := getTestConnector(policyName)
@@ -2205,12 +2239,12 @@ func TestGetDefaultCsrAttributes(t *testing.T) {
 	request := &certificate.Request{}
 	request.Subject.CommonName = "test.vfidev.com"
 
-	err := conn.Authenticate(&endpoint.Authentication{APIKey: ctx.CloudAPIkey})
+	err := conn.Authenticate(ctx, &endpoint.Authentication{APIKey: env.CloudAPIkey})
 
 	if err != nil {
 		t.Fatalf("%s", err)
 	}
-	attributes, err := getCsrAttributes(conn, request)
+	attributes, err := getCsrAttributes(ctx, conn, request)
 
 	if err != nil {
 		t.Fatalf("%s", err)
@@ -2222,17 +2256,18 @@ func TestGetDefaultCsrAttributes(t *testing.T) {
 }
 
 func TestGetCsrAttributes(t *testing.T) {
+	ctx := context.Background()
 
 	policyName := os.Getenv("CLOUD_ZONE_RESTRICTED")
 	conn := getTestConnector(policyName)
 	conn.verbose = true
 	req := getBasicRequest()
-	err := conn.Authenticate(&endpoint.Authentication{APIKey: ctx.CloudAPIkey})
+	err := conn.Authenticate(ctx, &endpoint.Authentication{APIKey: env.CloudAPIkey})
 
 	if err != nil {
 		t.Fatalf("%s", err)
 	}
-	attributes, err := getCsrAttributes(conn, &req)
+	attributes, err := getCsrAttributes(ctx, conn, &req)
 
 	if err != nil {
 		t.Fatalf("%s", err)
@@ -2244,6 +2279,7 @@ func TestGetCsrAttributes(t *testing.T) {
 }
 
 func TestCertificateSanTypes(t *testing.T) {
+	ctx := context.Background()
 
 	ip := net.ParseIP("127.0.0.1")
 	policyName := os.Getenv("CLOUD_ZONE_RESTRICTED")
@@ -2261,12 +2297,12 @@ func TestCertificateSanTypes(t *testing.T) {
 	uri, _ := url.Parse(fmt.Sprint("https://", req.Subject.CommonName))
 	req.URIs = []*url.URL{uri}
 
-	err := conn.Authenticate(&endpoint.Authentication{APIKey: ctx.CloudAPIkey})
+	err := conn.Authenticate(ctx, &endpoint.Authentication{APIKey: env.CloudAPIkey})
 
 	if err != nil {
 		t.Fatalf("%s", err)
 	}
-	attributes, err := getCsrAttributes(conn, &req)
+	attributes, err := getCsrAttributes(ctx, conn, &req)
 
 	if err != nil {
 		t.Fatalf("%s", err)
@@ -2278,11 +2314,12 @@ func TestCertificateSanTypes(t *testing.T) {
 }
 
 func TestVerifyCSRServiceGenerated(t *testing.T) {
+	ctx := context.Background()
 	policyName := os.Getenv("CLOUD_ZONE_RESTRICTED")
 
 	conn := getTestConnector(policyName)
 	conn.verbose = true
-	err := conn.Authenticate(&endpoint.Authentication{APIKey: ctx.CloudAPIkey})
+	err := conn.Authenticate(ctx, &endpoint.Authentication{APIKey: env.CloudAPIkey})
 	if err != nil {
 		t.Fatalf("%s", err)
 	}
@@ -2293,7 +2330,7 @@ func TestVerifyCSRServiceGenerated(t *testing.T) {
 
 	req.CsrOrigin = certificate.ServiceGeneratedCSR
 
-	id, err := conn.RequestCertificate(&req)
+	id, err := conn.RequestCertificate(ctx, &req)
 	if err != nil {
 		t.Fatalf("%s", err)
 	}
@@ -2302,7 +2339,7 @@ func TestVerifyCSRServiceGenerated(t *testing.T) {
 	req.KeyPassword = "abcede"
 	req.Timeout = time.Duration(180) * time.Second
 
-	isCSRService, err := conn.IsCSRServiceGenerated(&req)
+	isCSRService, err := conn.IsCSRServiceGenerated(ctx, &req)
 
 	if err != nil {
 		t.Fatalf("%s", err)
@@ -2315,11 +2352,12 @@ func TestVerifyCSRServiceGenerated(t *testing.T) {
 }
 
 func TestGenerateCertificateEC(t *testing.T) {
+	ctx := context.Background()
 	policyName := os.Getenv("VAAS_ZONE_ONLY_EC")
 
 	conn := getTestConnector(policyName)
 	conn.verbose = true
-	err := conn.Authenticate(&endpoint.Authentication{APIKey: ctx.CloudAPIkey})
+	err := conn.Authenticate(ctx, &endpoint.Authentication{APIKey: env.CloudAPIkey})
 	if err != nil {
 		t.Fatalf("%s", err)
 	}
@@ -2331,7 +2369,7 @@ func TestGenerateCertificateEC(t *testing.T) {
 	req.KeyCurve = certificate.EllipticCurveP384
 	req.CsrOrigin = certificate.ServiceGeneratedCSR
 
-	id, err := conn.RequestCertificate(&req)
+	id, err := conn.RequestCertificate(ctx, &req)
 	if err != nil {
 		t.Fatalf("%s", err)
 	}
@@ -2340,7 +2378,7 @@ func TestGenerateCertificateEC(t *testing.T) {
 	req.KeyPassword = "abcede"
 	req.Timeout = time.Duration(180) * time.Second
 
-	isCSRService, err := conn.IsCSRServiceGenerated(&req)
+	isCSRService, err := conn.IsCSRServiceGenerated(ctx, &req)
 
 	if err != nil {
 		t.Fatalf("%s", err)
@@ -2353,11 +2391,12 @@ func TestGenerateCertificateEC(t *testing.T) {
 }
 
 func TestGenerateCertificateECDefault(t *testing.T) {
+	ctx := context.Background()
 	policyName := os.Getenv("VAAS_ZONE_ONLY_EC")
 
 	conn := getTestConnector(policyName)
 	conn.verbose = true
-	err := conn.Authenticate(&endpoint.Authentication{APIKey: ctx.CloudAPIkey})
+	err := conn.Authenticate(ctx, &endpoint.Authentication{APIKey: env.CloudAPIkey})
 	if err != nil {
 		t.Fatalf("%s", err)
 	}
@@ -2368,7 +2407,7 @@ func TestGenerateCertificateECDefault(t *testing.T) {
 	req.KeyType = certificate.KeyTypeECDSA
 	req.CsrOrigin = certificate.ServiceGeneratedCSR
 
-	id, err := conn.RequestCertificate(&req)
+	id, err := conn.RequestCertificate(ctx, &req)
 	if err != nil {
 		t.Fatalf("%s", err)
 	}
@@ -2377,7 +2416,7 @@ func TestGenerateCertificateECDefault(t *testing.T) {
 	req.KeyPassword = "abcede"
 	req.Timeout = time.Duration(180) * time.Second
 
-	isCSRService, err := conn.IsCSRServiceGenerated(&req)
+	isCSRService, err := conn.IsCSRServiceGenerated(ctx, &req)
 
 	if err != nil {
 		t.Fatalf("%s", err)
@@ -2416,8 +2455,9 @@ func getBasicRequest() certificate.Request {
 
 // TODO: Expand unit tests to cover more cases
 func TestSearchValidCertificate(t *testing.T) {
-	conn := getTestConnector(ctx.CloudZone)
-	err := conn.Authenticate(&endpoint.Authentication{APIKey: ctx.CloudAPIkey})
+	ctx := context.Background()
+	conn := getTestConnector(env.CloudZone)
+	err := conn.Authenticate(ctx, &endpoint.Authentication{APIKey: env.CloudAPIkey})
 	if err != nil {
 		t.Fatal(err)
 	}
@@ -2438,7 +2478,7 @@ func TestSearchValidCertificate(t *testing.T) {
 	day := 24 * time.Hour
 	certMinTimeLeft := 3 * day
 
-	certificate, err := conn.SearchCertificate(zone, cn, sans, certMinTimeLeft)
+	certificate, err := conn.SearchCertificate(ctx, zone, cn, sans, certMinTimeLeft)
 	if err != nil {
 		t.Fatalf("%v", err)
 	}
@@ -0,0 +1,259 @@
+/*
+ * Copyright 2023 Venafi, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *  http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package cloud
+
+import (
+	"context"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/sha512"
+	"crypto/x509"
+	"encoding/json"
+	"encoding/pem"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	netUrl "net/url"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"golang.org/x/oauth2/jws"
+
+	"github.com/Venafi/vcert/v5/pkg/endpoint"
+	"github.com/Venafi/vcert/v5/pkg/verror"
+)
+
+const (
+	grantTypeClientCredentials = "client_credentials"
+	clientAssertionTypeJWT     = "urn:ietf:params:oauth:client-assertion-type:jwt-bearer"
+	// assertionValidity is the lifetime of the JWT signed with the service account private key
+	assertionValidity = 5 * time.Minute
+	// tokenRefreshMargin is how long before its expiration the access token gets renewed
+	tokenRefreshMargin = time.Minute
+)
+
+type accessTokenResponse struct {
+	AccessToken string `json:"access_token"`
+	TokenType   string `json:"token_type"`
+	ExpiresIn   int64  `json:"expires_in"`
+}
+
+// serviceAccount holds what is needed to exchange a JWT for a Venafi Control Plane access token.
+// Either externalJWT is set, or clientID and privateKey are used to sign a new JWT on every exchange
+type serviceAccount struct {
+	tokenURL    string
+	externalJWT string
+	clientID    string
+	privateKey  crypto.Signer
+}
+
+func isServiceAccountAuth(auth *endpoint.Authentication) bool {
+	return auth.APIKey == "" && (auth.ExternalJWT != "" || auth.PrivateKey != "")
+}
+
+func newServiceAccount(auth *endpoint.Authentication) (*serviceAccount, error) {
+	if auth.TokenURL == "" {
+		return nil, fmt.Errorf("%w: token URL is required for service account authentication", verror.UserDataError)
+	}
+	sa := &serviceAccount{tokenURL: auth.TokenURL, externalJWT: auth.ExternalJWT}
+	if sa.externalJWT != "" {
+		return sa, nil
+	}
+	if auth.ClientId == "" {
+		return nil, fmt.Errorf("%w: client ID is required to sign a JWT with the service account private key", verror.UserDataError)
+	}
+	key, err := parseServiceAccountKey([]byte(auth.PrivateKey))
+	if err != nil {
+		return nil, err
+	}
+	sa.clientID = auth.ClientId
+	sa.privateKey = key
+	return sa, nil
+}
+
+func parseServiceAccountKey(data []byte) (crypto.Signer, error) {
+	block, _ := pem.Decode(data)
+	if block == nil {
+		return nil, fmt.Errorf("%w: service account private key is not PEM encoded", verror.UserDataError)
+	}
+	if key, err := x509.ParsePKCS8PrivateKey(block.Bytes); err == nil {
+		signer, ok := key.(crypto.Signer)
+		if !ok {
+			return nil, fmt.Errorf("%w: unsupported service account private key type %T", verror.UserDataError, key)
+		}
+		return signer, nil
+	}
+	if key, err := x509.ParsePKCS1PrivateKey(block.Bytes); err == nil {
+		return key, nil
+	}
+	if key, err := x509.ParseECPrivateKey(block.Bytes); err == nil {
+		return key, nil
+	}
+	return nil, fmt.Errorf("%w: failed to parse service account private key", verror.UserDataError)
+}
+
+// assertion returns the JWT presented to the token endpoint
+func (sa *serviceAccount) assertion(now time.Time) (string, error) {
+	if sa.externalJWT != "" {
+		return sa.externalJWT, nil
+	}
+
+	var alg string
+	var hash crypto.Hash
+	switch k := sa.privateKey.(type) {
+	case *rsa.PrivateKey:
+		alg, hash = "RS256", crypto.SHA256
+	case *ecdsa.PrivateKey:
+		switch k.Curve.Params().BitSize {
+		case 256:
+			alg, hash = "ES256", crypto.SHA256
+		case 384:
+			alg, hash = "ES384", crypto.SHA384
+		case 521:
+			alg, hash = "ES512", crypto.SHA512
+		default:
+			return "", fmt.Errorf("unsupported elliptic curve %s", k.Curve.Params().Name)
+		}
+	case ed25519.PrivateKey:
+		alg = "EdDSA"
+	default:
+		return "", fmt.Errorf("unsupported service account private key type %T", k)
+	}
+
+	header := &jws.Header{Algorithm: alg, Typ: "JWT"}
+	claims := &jws.ClaimSet{
+		Iss:           sa.clientID,
+		Sub:           sa.clientID,
+		Aud:           sa.tokenURL,
+		Iat:           now.Unix(),
+		Exp:           now.Add(assertionValidity).Unix(),
+		PrivateClaims: map[string]interface{}{"jti": uuid.New().String()},
+	}
+
+	return jws.EncodeWithSigner(header, claims, func(data []byte) ([]byte, error) {
+		return signJWT(sa.privateKey, hash, data)
+	})
+}
+
+func signJWT(key crypto.Signer, hash crypto.Hash, data []byte) ([]byte, error) {
+	if hash == 0 {
+		// Ed25519 signs the message itself
+		return key.Sign(rand.Reader, data, crypto.Hash(0))
+	}
+	var digest []byte
+	switch hash {
+	case crypto.SHA256:
+		sum := sha256.Sum256(data)
+		digest = sum[:]
+	case crypto.SHA384:
+		sum := sha512.Sum384(data)
+		digest = sum[:]
+	case crypto.SHA512:
+		sum := sha512.Sum512(data)
+		digest = sum[:]
+	}
+
+	ecKey, ok := key.(*ecdsa.PrivateKey)
+	if !ok {
+		return key.Sign(rand.Reader, digest, hash)
+	}
+	// JWS expects the raw R || S concatenation instead of the ASN.1 encoding
+	r, s, err := ecdsa.Sign(rand.Reader, ecKey, digest)
+	if err != nil {
+		return nil, err
+	}
+	size := (ecKey.Curve.Params().BitSize + 7) / 8
+	sig := make([]byte, 2*size)
+	r.FillBytes(sig[:size])
+	s.FillBytes(sig[size:])
+	return sig, nil
+}
+
+// getAccessToken returns the current access token, requesting a new one when
+// there is none yet or the current one is about to expire
+func (c *Connector) getAccessToken(ctx context.Context) (string, error) {
+	c.tokenMutex.Lock()
+	defer c.tokenMutex.Unlock()
+
+	now := time.Now()
+	if c.accessToken != "" && now.Add(tokenRefreshMargin).Before(c.accessTokenExpiry) {
+		return c.accessToken, nil
+	}
+
+	resp, err := c.requestAccessToken(ctx, now)
+	if err != nil {
+		return "", err
+	}
+	c.accessToken = resp.AccessToken
+	c.accessTokenExpiry = now.Add(time.Duration(resp.ExpiresIn) * time.Second)
+	return c.accessToken, nil
+}
+
+func (c *Connector) requestAccessToken(ctx context.Context, now time.Time) (*accessTokenResponse, error) {
+	assertion, err := c.serviceAccount.assertion(now)
+	if err != nil {
+		return nil, fmt.Errorf("%w: failed to create service account JWT: %v", verror.VcertError, err)
+	}
+
+	form := netUrl.Values{}
+	form.Set("grant_type", grantTypeClientCredentials)
+	form.Set("client_assertion_type", clientAssertionTypeJWT)
+	form.Set("client_assertion", assertion)
+
+	r, err := http.NewRequestWithContext(ctx, http.MethodPost, c.serviceAccount.tokenURL, strings.NewReader(form.Encode()))
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", verror.VcertError, err)
+	}
+	r.Header.Add("Accept", "application/json")
+	r.Header.Add("content-type", "application/x-www-form-urlencoded")
+
+	res, err := c.getHTTPClient().Do(r)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", verror.ServerUnavailableError, err)
+	}
+	defer res.Body.Close()
+	body, err := io.ReadAll(res.Body)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", verror.ServerError, err)
+	}
+	if c.verbose {
+		log.Printf("Got %s status for %s %s\n", res.Status, http.MethodPost, c.serviceAccount.tokenURL)
+	}
+
+	return parseAccessTokenResult(res.StatusCode, res.Status, body)
+}
+
+func parseAccessTokenResult(httpStatusCode int, httpStatus string, body []byte) (*accessTokenResponse, error) {
+	if httpStatusCode != http.StatusOK {
+		return nil, fmt.Errorf("%w: unexpected status code on Venafi Control Plane access token request. Status: %s. Body: %s", verror.AuthError, httpStatus, body)
+	}
+	var resp accessTokenResponse
+	err := json.Unmarshal(body, &resp)
+	if err != nil {
+		return nil, fmt.Errorf("%w: failed to parse access token response: %v", verror.ServerError, err)
+	}
+	if resp.AccessToken == "" {
+		return nil, fmt.Errorf("%w: access token response does not contain an access token", verror.ServerError)
+	}
+	return &resp, nil
+}
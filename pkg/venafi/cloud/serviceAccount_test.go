@@ -0,0 +1,204 @@
+/*
+ * Copyright 2023 Venafi, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *  http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package cloud
+
+import (
+	"context"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"fmt"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/Venafi/vcert/v5/pkg/endpoint"
+)
+
+func encodeTestKey(t *testing.T, key crypto.Signer) string {
+	der, err := x509.MarshalPKCS8PrivateKey(key)
+	if err != nil {
+		t.Fatalf("failed to marshal key: %s", err)
+	}
+	return string(pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: der}))
+}
+
+func TestServiceAccountAssertion(t *testing.T) {
+	rsaKey, _ := rsa.GenerateKey(rand.Reader, 2048)
+	ecKey, _ := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	_, edKey, _ := ed25519.GenerateKey(rand.Reader)
+
+	cases := []struct {
+		name   string
+		key    crypto.Signer
+		alg    string
+		verify func(signed, sig []byte) bool
+	}{
+		{"RSA", rsaKey, "RS256", func(signed, sig []byte) bool {
+			sum := sha256.Sum256(signed)
+			return rsa.VerifyPKCS1v15(&rsaKey.PublicKey, crypto.SHA256, sum[:], sig) == nil
+		}},
+		{"ECDSA", ecKey, "ES256", func(signed, sig []byte) bool {
+			sum := sha256.Sum256(signed)
+			r := new(big.Int).SetBytes(sig[:32])
+			s := new(big.Int).SetBytes(sig[32:])
+			return len(sig) == 64 && ecdsa.Verify(&ecKey.PublicKey, sum[:], r, s)
+		}},
+		{"Ed25519", edKey, "EdDSA", func(signed, sig []byte) bool {
+			return ed25519.Verify(edKey.Public().(ed25519.PublicKey), signed, sig)
+		}},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			sa, err := newServiceAccount(&endpoint.Authentication{
+				TokenURL:   "https://api.venafi.cloud/token",
+				ClientId:   "my-service-account",
+				PrivateKey: encodeTestKey(t, c.key),
+			})
+			if err != nil {
+				t.Fatalf("failed to create service account: %s", err)
+			}
+
+			jwt, err := sa.assertion(time.Now())
+			if err != nil {
+				t.Fatalf("failed to create assertion: %s", err)
+			}
+			parts := strings.Split(jwt, ".")
+			if len(parts) != 3 {
+				t.Fatalf("malformed JWT: %s", jwt)
+			}
+
+			var header map[string]interface{}
+			data, _ := base64.RawURLEncoding.DecodeString(parts[0])
+			_ = json.Unmarshal(data, &header)
+			if header["alg"] != c.alg {
+				t.Fatalf("expected alg %s, got %v", c.alg, header["alg"])
+			}
+
+			var claims map[string]interface{}
+			data, _ = base64.RawURLEncoding.DecodeString(parts[1])
+			_ = json.Unmarshal(data, &claims)
+			if claims["iss"] != "my-service-account" || claims["sub"] != "my-service-account" || claims["aud"] != "https://api.venafi.cloud/token" {
+				t.Fatalf("unexpected claims: %v", claims)
+			}
+			if claims["jti"] == nil {
+				t.Fatalf("jti claim is missing")
+			}
+
+			sig, _ := base64.RawURLEncoding.DecodeString(parts[2])
+			if !c.verify([]byte(parts[0]+"."+parts[1]), sig) {
+				t.Fatalf("JWT signature does not verify")
+			}
+		})
+	}
+}
+
+func TestNewServiceAccountMissingData(t *testing.T) {
+	_, err := newServiceAccount(&endpoint.Authentication{ExternalJWT: "a.b.c"})
+	if err == nil {
+		t.Fatalf("expected an error for a missing token URL")
+	}
+	_, err = newServiceAccount(&endpoint.Authentication{TokenURL: "https://token", PrivateKey: "key"})
+	if err == nil {
+		t.Fatalf("expected an error for a missing client ID")
+	}
+	_, err = newServiceAccount(&endpoint.Authentication{TokenURL: "https://token", ClientId: "id", PrivateKey: "not a key"})
+	if err == nil {
+		t.Fatalf("expected an error for an invalid private key")
+	}
+}
+
+func TestServiceAccountTokenRefresh(t *testing.T) {
+	ctx := context.Background()
+	var tokenRequests int32
+	server := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/token":
+			_ = r.ParseForm()
+			if r.PostForm.Get("grant_type") != grantTypeClientCredentials ||
+				r.PostForm.Get("client_assertion_type") != clientAssertionTypeJWT ||
+				r.PostForm.Get("client_assertion") != "a.b.c" {
+				w.WriteHeader(http.StatusBadRequest)
+				return
+			}
+			n := atomic.AddInt32(&tokenRequests, 1)
+			// the token expires within the refresh margin, so every call renews it
+			_, _ = fmt.Fprintf(w, `{"access_token":"token-%d","token_type":"Bearer","expires_in":30}`, n)
+		case "/v1/useraccounts":
+			if !strings.HasPrefix(r.Header.Get("Authorization"), "Bearer token-") {
+				w.WriteHeader(http.StatusUnauthorized)
+				return
+			}
+			_, _ = w.Write([]byte(r.Header.Get("Authorization")))
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer server.Close()
+
+	trust := x509.NewCertPool()
+	trust.AddCert(server.Certificate())
+	c, err := NewConnector(server.URL, "", false, trust)
+	if err != nil {
+		t.Fatalf("%s", err)
+	}
+	err = c.Authenticate(ctx, &endpoint.Authentication{ExternalJWT: "a.b.c", TokenURL: server.URL + "/token"})
+	if err != nil {
+		t.Fatalf("failed to authenticate: %s", err)
+	}
+	if !c.isAuthenticated() {
+		t.Fatalf("connector is not authenticated")
+	}
+
+	_, _, body, err := c.request(ctx, "GET", c.getURL(urlResourceUserAccounts), nil)
+	if err != nil {
+		t.Fatalf("request failed: %s", err)
+	}
+	if string(body) != "Bearer token-2" {
+		t.Fatalf("expected a renewed access token, got %q", body)
+	}
+	if atomic.LoadInt32(&tokenRequests) != 2 {
+		t.Fatalf("expected 2 token requests, got %d", tokenRequests)
+	}
+}
+
+func TestServiceAccountTokenRejected(t *testing.T) {
+	ctx := context.Background()
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusUnauthorized)
+	}))
+	defer server.Close()
+
+	c, _ := NewConnector(server.URL, "", false, nil)
+	err := c.Authenticate(ctx, &endpoint.Authentication{ExternalJWT: "a.b.c", TokenURL: server.URL + "/token"})
+	if err == nil {
+		t.Fatalf("expected an error for a rejected JWT")
+	}
+}
@@ -0,0 +1,344 @@
+/*
+ * Copyright 2023 Venafi, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *  http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package est
+
+import (
+	"context"
+	"crypto/sha1"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/pem"
+	"fmt"
+	"net/http"
+	"sync"
+	"time"
+
+	"go.uber.org/zap"
+
+	"github.com/Venafi/vcert/v5/pkg/certificate"
+	"github.com/Venafi/vcert/v5/pkg/endpoint"
+	"github.com/Venafi/vcert/v5/pkg/policy"
+	"github.com/Venafi/vcert/v5/pkg/util"
+	"github.com/Venafi/vcert/v5/pkg/venafi"
+	"github.com/Venafi/vcert/v5/pkg/verror"
+)
+
+const (
+	// DefaultTimeout is the maximum time to wait for a certificate pending manual approval when the request has no timeout
+	DefaultTimeout = 5 * time.Minute
+)
+
+var (
+	fieldPlatform = zap.String("platform", venafi.EST.String())
+
+	errNotSupported = fmt.Errorf("operation is not supported by the EST connector")
+)
+
+// Connector contains the base data needed to communicate with an EST (RFC 7030) server
+type Connector struct {
+	baseURL   string
+	user      string
+	password  string
+	verbose   bool
+	trust     *x509.CertPool
+	client    *http.Client
+	proxy     util.ProxyConfig
+	transport util.TransportConfig
+	tlsConfig *tls.Config
+	zone      string // holds the optional CA label
+
+	// renewed holds the certificates issued by RenewCertificate until they are retrieved
+	renewed   map[string]*certificate.PEMCollection
+	renewedMu sync.Mutex
+}
+
+// NewConnector creates a new EST Connector object used to communicate with the EST server at the given url.
+// The /.well-known/est path is added to the url when missing
+func NewConnector(url string, zone string, verbose bool, trust *x509.CertPool) (*Connector, error) {
+	if url == "" {
+		return nil, fmt.Errorf("%w: an url is required to communicate with an EST server", verror.UserDataError)
+	}
+	return &Connector{
+		baseURL: normalizeURL(url),
+		zone:    zone,
+		verbose: verbose,
+		trust:   trust,
+		renewed: make(map[string]*certificate.PEMCollection),
+	}, nil
+}
+
+func (c *Connector) GetType() endpoint.ConnectorType {
+	return endpoint.ConnectorTypeEST
+}
+
+// SetZone sets the label of the CA, for EST servers hosting more than one CA
+func (c *Connector) SetZone(zone string) {
+	c.zone = zone
+}
+
+func (c *Connector) SetHTTPClient(client *http.Client) {
+	c.client = client
+}
+
+// SetProxy sets the proxy the requests to the EST server are sent through, instead of the one of the environment variables.
+// It has no effect on the client set with SetHTTPClient, nor once the connector has sent its first request
+func (c *Connector) SetProxy(proxy util.ProxyConfig) {
+	c.proxy = proxy
+}
+
+// SetTransportConfig tunes the HTTP transport of the requests to the EST server, shared with the other connectors built with the
+// same settings. It has no effect on the client set with SetHTTPClient, nor once the connector has sent its first request
+func (c *Connector) SetTransportConfig(config util.TransportConfig) {
+	c.transport = config
+}
+
+// SetTLSConfig sets the TLS settings of the requests to the EST server, like the client certificate, instead of the ones of
+// http.DefaultTransport. The trust bundle of the connector replaces its root CAs. It has no effect on the client set
+// with SetHTTPClient, nor once the connector has sent its first request
+func (c *Connector) SetTLSConfig(config *tls.Config) {
+	c.tlsConfig = config
+}
+
+// Authenticate sets the user and password sent with HTTP basic authentication. Authentication is optional:
+// EST servers may rely on the TLS client certificate instead, set with SetTLSConfig or read from the default http transport
+func (c *Connector) Authenticate(ctx context.Context, auth *endpoint.Authentication) error {
+	if auth == nil {
+		return nil
+	}
+	if auth.User != "" && auth.Password == "" {
+		return fmt.Errorf("%w: a password is required along with the user", verror.AuthError)
+	}
+	c.user = auth.User
+	c.password = auth.Password
+	return nil
+}
+
+// Ping checks the CA certificates can be retrieved from the EST server
+func (c *Connector) Ping(ctx context.Context) error {
+	_, err := c.getCACertificates(ctx)
+	return err
+}
+
+// GetCACertificates returns the current CA certificates distributed by the EST server
+func (c *Connector) GetCACertificates(ctx context.Context) ([]*x509.Certificate, error) {
+	return c.getCACertificates(ctx)
+}
+
+// ReadZoneConfiguration returns an empty zone configuration. EST servers do not publish policies
+func (c *Connector) ReadZoneConfiguration(ctx context.Context) (config *endpoint.ZoneConfiguration, err error) {
+	return endpoint.NewZoneConfiguration(), nil
+}
+
+// GenerateRequest creates the private key and CSR when the csrOrigin is local.
+// Service generated CSRs are not supported by EST simple enrollment
+func (c *Connector) GenerateRequest(ctx context.Context, _ *endpoint.ZoneConfiguration, req *certificate.Request) (err error) {
+	switch req.CsrOrigin {
+	case certificate.LocalGeneratedCSR:
+		err = req.GeneratePrivateKey()
+		if err != nil {
+			return err
+		}
+		return req.GenerateCSR()
+	case certificate.UserProvidedCSR:
+		if len(req.GetCSR()) == 0 {
+			return fmt.Errorf("%w: CSR was supposed to be provided by user, but it's empty", verror.UserDataError)
+		}
+		return nil
+	case certificate.ServiceGeneratedCSR:
+		return fmt.Errorf("%w: service generated CSR is not supported by EST", verror.UserDataError)
+	default:
+		return fmt.Errorf("%w: unrecognised req.CsrOrigin %v", verror.UserDataError, req.CsrOrigin)
+	}
+}
+
+// SupportSynchronousRequestCertificate returns if the connector support synchronous calls to request a certificate.
+func (c *Connector) SupportSynchronousRequestCertificate() bool {
+	return true
+}
+
+// SynchronousRequestCertificate enrolls the CSR with the EST simpleenroll operation and returns the issued certificate
+// along with its chain
+func (c *Connector) SynchronousRequestCertificate(ctx context.Context, req *certificate.Request) (certificates *certificate.PEMCollection, err error) {
+	zap.L().Info("requesting certificate", zap.String("cn", req.Subject.CommonName), fieldPlatform)
+	certificates, err = c.requestCertificate(ctx, urlResourceSimpleEnroll, req)
+	if err != nil {
+		zap.L().Error("failed to enroll certificate", fieldPlatform, zap.Error(err))
+		return nil, err
+	}
+	zap.L().Info("successfully requested certificate", fieldPlatform)
+	return certificates, nil
+}
+
+// RenewCertificate re-enrolls the certificate with the EST simplereenroll operation. EST servers identify the
+// certificate to renew by the TLS client certificate, and expect the CSR to keep its subject.
+//
+// The issued certificate is kept by the connector. The returned requestID retrieves it with RetrieveCertificate
+func (c *Connector) RenewCertificate(ctx context.Context, renewReq *certificate.RenewalRequest) (requestID string, err error) {
+	if renewReq == nil || renewReq.CertificateRequest == nil {
+		return "", fmt.Errorf("%w: a certificate request is required to renew with EST", verror.UserDataError)
+	}
+
+	zap.L().Info("renewing certificate", zap.String("cn", renewReq.CertificateRequest.Subject.CommonName), fieldPlatform)
+	certificates, err := c.requestCertificate(ctx, urlResourceSimpleReenroll, renewReq.CertificateRequest)
+	if err != nil {
+		zap.L().Error("failed to re-enroll certificate", fieldPlatform, zap.Error(err))
+		return "", err
+	}
+
+	block, _ := pem.Decode([]byte(certificates.Certificate))
+	requestID = fmt.Sprintf("%X", sha1.Sum(block.Bytes))
+
+	c.renewedMu.Lock()
+	c.renewed[requestID] = certificates
+	c.renewedMu.Unlock()
+
+	zap.L().Info("successfully renewed certificate", fieldPlatform)
+	return requestID, nil
+}
+
+// RetrieveCertificate returns a certificate issued by RenewCertificate. EST servers do not store certificates,
+// so no other certificate can be retrieved
+func (c *Connector) RetrieveCertificate(ctx context.Context, req *certificate.Request) (certificates *certificate.PEMCollection, err error) {
+	c.renewedMu.Lock()
+	defer c.renewedMu.Unlock()
+
+	certificates, found := c.renewed[req.PickupID]
+	if !found {
+		return nil, fmt.Errorf("%w: certificate %s was not issued by this connector. EST servers cannot retrieve certificates", verror.UserDataError, req.PickupID)
+	}
+	delete(c.renewed, req.PickupID)
+	return certificates, nil
+}
+
+func (c *Connector) requestCertificate(ctx context.Context, resource urlResource, req *certificate.Request) (*certificate.PEMCollection, error) {
+	block, _ := pem.Decode(req.GetCSR())
+	if block == nil {
+		return nil, fmt.Errorf("%w: could not decode the CSR", verror.UserDataError)
+	}
+
+	timeout := req.Timeout
+	if timeout == 0 {
+		timeout = DefaultTimeout
+	}
+	issued, err := c.enroll(ctx, resource, block.Bytes, timeout)
+	if err != nil {
+		return nil, err
+	}
+
+	var caCerts []*x509.Certificate
+	if len(issued) == 1 && req.ChainOption != certificate.ChainOptionIgnore {
+		caCerts, err = c.getCACertificates(ctx)
+		if err != nil {
+			zap.L().Warn("failed to retrieve the CA certificates, the chain will be empty", fieldPlatform, zap.Error(err))
+		}
+	}
+
+	// The chain is built from the leaf to the root. The output order is applied when the certificate is written
+	chainOption := certificate.ChainOptionRootLast
+	if req.ChainOption == certificate.ChainOptionIgnore {
+		chainOption = certificate.ChainOptionIgnore
+	}
+	return certificate.PEMCollectionFromBytes(certificate.BuildChain(issued, caCerts), chainOption)
+}
+
+func (c *Connector) GetZonesByParent(ctx context.Context, _ string) ([]string, error) {
+	return nil, errNotSupported
+}
+
+func (c *Connector) ReadPolicyConfiguration(ctx context.Context) (policy *endpoint.Policy, err error) {
+	return nil, errNotSupported
+}
+
+func (c *Connector) ResetCertificate(ctx context.Context, _ *certificate.Request, _ bool) (err error) {
+	return errNotSupported
+}
+
+func (c *Connector) RequestCertificate(ctx context.Context, _ *certificate.Request) (requestID string, err error) {
+	return "", errNotSupported
+}
+
+func (c *Connector) IsCSRServiceGenerated(ctx context.Context, _ *certificate.Request) (bool, error) {
+	return false, nil
+}
+
+func (c *Connector) RevokeCertificate(ctx context.Context, _ *certificate.RevocationRequest) error {
+	return errNotSupported
+}
+
+func (c *Connector) RetireCertificate(ctx context.Context, _ *certificate.RetireRequest) error {
+	return errNotSupported
+}
+
+func (c *Connector) ImportCertificate(ctx context.Context, _ *certificate.ImportRequest) (*certificate.ImportResponse, error) {
+	return nil, errNotSupported
+}
+
+func (c *Connector) ListCertificates(ctx context.Context, _ endpoint.Filter) ([]certificate.CertificateInfo, error) {
+	return nil, errNotSupported
+}
+
+func (c *Connector) SetPolicy(ctx context.Context, _ string, _ *policy.PolicySpecification) (string, error) {
+	return "", errNotSupported
+}
+
+func (c *Connector) GetPolicy(ctx context.Context, _ string) (*policy.PolicySpecification, error) {
+	return nil, errNotSupported
+}
+
+func (c *Connector) RequestSSHCertificate(ctx context.Context, _ *certificate.SshCertRequest) (response *certificate.SshCertificateObject, err error) {
+	return nil, errNotSupported
+}
+
+func (c *Connector) RetrieveSSHCertificate(ctx context.Context, _ *certificate.SshCertRequest) (response *certificate.SshCertificateObject, err error) {
+	return nil, errNotSupported
+}
+
+func (c *Connector) RenewSSHCertificate(ctx context.Context, _ *certificate.SshCertRequest) (response *certificate.SshCertificateObject, err error) {
+	return nil, errNotSupported
+}
+
+func (c *Connector) RevokeSSHCertificate(ctx context.Context, _ *certificate.SshCertRequest) error {
+	return errNotSupported
+}
+
+func (c *Connector) RetrieveSshConfig(ctx context.Context, _ *certificate.SshCaTemplateRequest) (*certificate.SshConfig, error) {
+	return nil, errNotSupported
+}
+
+func (c *Connector) SearchCertificates(ctx context.Context, _ *certificate.SearchRequest) (*certificate.CertSearchResponse, error) {
+	return nil, errNotSupported
+}
+
+func (c *Connector) SearchCertificate(ctx context.Context, _ string, _ string, _ *certificate.Sans, _ time.Duration) (*certificate.CertificateInfo, error) {
+	return nil, errNotSupported
+}
+
+func (c *Connector) RetrieveAvailableSSHTemplates(ctx context.Context) ([]certificate.SshAvaliableTemplate, error) {
+	return nil, errNotSupported
+}
+
+func (c *Connector) RetrieveCertificateMetaData(ctx context.Context, _ string) (*certificate.CertificateMetaData, error) {
+	return nil, errNotSupported
+}
+
+func (c *Connector) RetrieveSystemVersion(ctx context.Context) (string, error) {
+	return "", errNotSupported
+}
+
+func (c *Connector) WriteLog(ctx context.Context, _ *endpoint.LogRequest) error {
+	return errNotSupported
+}
@@ -0,0 +1,242 @@
+/*
+ * Copyright 2023 Venafi, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *  http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package est
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"errors"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/suite"
+
+	"github.com/Venafi/vcert/v5/pkg/certificate"
+	"github.com/Venafi/vcert/v5/pkg/endpoint"
+	"github.com/Venafi/vcert/v5/pkg/verror"
+)
+
+type ConnectorSuite struct {
+	suite.Suite
+	server *estMockServer
+}
+
+func (s *ConnectorSuite) SetupTest() {
+	s.server = newESTMockServer()
+}
+
+func (s *ConnectorSuite) TearDownTest() {
+	s.server.server.Close()
+}
+
+func TestConnectorSuite(t *testing.T) {
+	suite.Run(t, new(ConnectorSuite))
+}
+
+func (s *ConnectorSuite) newConnector(zone string) *Connector {
+	connector, err := NewConnector(s.server.server.URL, zone, false, s.server.trust())
+	s.Require().NoError(err)
+	return connector
+}
+
+func (s *ConnectorSuite) newRequest(cn string, chainOption certificate.ChainOption) *certificate.Request {
+	ctx := context.Background()
+	req := &certificate.Request{
+		Subject:     pkix.Name{CommonName: cn},
+		DNSNames:    []string{cn},
+		CsrOrigin:   certificate.LocalGeneratedCSR,
+		ChainOption: chainOption,
+		Timeout:     10 * time.Second,
+	}
+	s.Require().NoError(s.newConnector("").GenerateRequest(ctx, nil, req))
+	return req
+}
+
+func parsePEMCertificate(s *ConnectorSuite, data string) *x509.Certificate {
+	block, _ := pem.Decode([]byte(data))
+	s.Require().NotNil(block)
+	cert, err := x509.ParseCertificate(block.Bytes)
+	s.Require().NoError(err)
+	return cert
+}
+
+func (s *ConnectorSuite) TestNewConnector() {
+	_, err := NewConnector("", "", false, nil)
+	s.Error(err)
+
+	connector, err := NewConnector("est.example.com", "", false, nil)
+	s.Require().NoError(err)
+	s.Equal(endpoint.ConnectorTypeEST, connector.GetType())
+	s.Equal("https://est.example.com/.well-known/est/", connector.baseURL)
+	s.Equal("https://est.example.com/.well-known/est/simpleenroll", connector.getURL(urlResourceSimpleEnroll))
+
+	connector, err = NewConnector("https://est.example.com/.well-known/est/", "iot-devices", false, nil)
+	s.Require().NoError(err)
+	s.Equal("https://est.example.com/.well-known/est/iot-devices/cacerts", connector.getURL(urlResourceCACerts))
+}
+
+func (s *ConnectorSuite) TestGenerateRequest() {
+	ctx := context.Background()
+	connector := s.newConnector("")
+
+	req := &certificate.Request{Subject: pkix.Name{CommonName: "device.example.com"}, CsrOrigin: certificate.ServiceGeneratedCSR}
+	s.Error(connector.GenerateRequest(ctx, nil, req))
+
+	req.CsrOrigin = certificate.UserProvidedCSR
+	s.Error(connector.GenerateRequest(ctx, nil, req))
+
+	req.CsrOrigin = certificate.LocalGeneratedCSR
+	s.NoError(connector.GenerateRequest(ctx, nil, req))
+	s.NotEmpty(req.GetCSR())
+}
+
+func (s *ConnectorSuite) TestGetCACertificates() {
+	ctx := context.Background()
+	connector := s.newConnector("")
+	s.NoError(connector.Ping(ctx))
+
+	certs, err := connector.GetCACertificates(ctx)
+	s.Require().NoError(err)
+	s.Require().Len(certs, 2)
+	s.True(certs[0].Equal(s.server.interCert))
+	s.True(certs[1].Equal(s.server.rootCert))
+}
+
+func (s *ConnectorSuite) TestSynchronousRequestCertificate() {
+	ctx := context.Background()
+	s.server.user, s.server.password = "device", "secret"
+
+	connector := s.newConnector("iot-devices")
+	s.Require().NoError(connector.Authenticate(ctx, &endpoint.Authentication{User: "device", Password: "secret"}))
+
+	req := s.newRequest("device.example.com", certificate.ChainOptionRootFirst)
+	pcc, err := connector.SynchronousRequestCertificate(ctx, req)
+	s.Require().NoError(err)
+	s.Equal("iot-devices", s.server.label)
+
+	cert := parsePEMCertificate(s, pcc.Certificate)
+	s.Equal("device.example.com", cert.Subject.CommonName)
+
+	// The chain is completed from the CA certificates
+	s.Require().Len(pcc.Chain, 2)
+	s.True(parsePEMCertificate(s, pcc.Chain[0]).Equal(s.server.interCert))
+	s.True(parsePEMCertificate(s, pcc.Chain[1]).Equal(s.server.rootCert))
+}
+
+func (s *ConnectorSuite) TestSynchronousRequestCertificate_ChainIgnored() {
+	ctx := context.Background()
+	connector := s.newConnector("")
+
+	pcc, err := connector.SynchronousRequestCertificate(ctx, s.newRequest("device.example.com", certificate.ChainOptionIgnore))
+	s.Require().NoError(err)
+	s.NotEmpty(pcc.Certificate)
+	s.Empty(pcc.Chain)
+}
+
+func (s *ConnectorSuite) TestSynchronousRequestCertificate_WrongCredentials() {
+	ctx := context.Background()
+	s.server.user, s.server.password = "device", "secret"
+
+	connector := s.newConnector("")
+	s.Require().NoError(connector.Authenticate(ctx, &endpoint.Authentication{User: "device", Password: "wrong"}))
+
+	_, err := connector.SynchronousRequestCertificate(ctx, s.newRequest("device.example.com", certificate.ChainOptionRootLast))
+	s.True(errors.Is(err, verror.AuthError))
+}
+
+func (s *ConnectorSuite) TestSynchronousRequestCertificate_Pending() {
+	ctx := context.Background()
+	s.server.pending = 1
+
+	connector := s.newConnector("")
+	pcc, err := connector.SynchronousRequestCertificate(ctx, s.newRequest("device.example.com", certificate.ChainOptionRootLast))
+	s.Require().NoError(err)
+	s.NotEmpty(pcc.Certificate)
+	s.Equal(2, s.server.requests)
+
+	// The certificate is not polled past the timeout
+	s.server.pending = 1
+	req := s.newRequest("device.example.com", certificate.ChainOptionRootLast)
+	req.Timeout = 500 * time.Millisecond
+	_, err = connector.SynchronousRequestCertificate(ctx, req)
+	s.True(errors.Is(err, verror.ServerTemporaryUnavailableError))
+}
+
+func (s *ConnectorSuite) TestSynchronousRequestCertificate_Cancelled() {
+	s.server.pending = 1
+
+	// The wait for the pending certificate stops with the context
+	ctx, cancel := context.WithTimeout(context.Background(), 200*time.Millisecond)
+	defer cancel()
+	connector := s.newConnector("")
+	start := time.Now()
+	_, err := connector.SynchronousRequestCertificate(ctx, s.newRequest("device.example.com", certificate.ChainOptionRootLast))
+	s.True(errors.Is(err, context.DeadlineExceeded))
+	s.Less(time.Since(start), time.Second)
+}
+
+func (s *ConnectorSuite) TestRenewCertificate() {
+	ctx := context.Background()
+	connector := s.newConnector("")
+	req := s.newRequest("device.example.com", certificate.ChainOptionRootLast)
+	pcc, err := connector.SynchronousRequestCertificate(ctx, req)
+	s.Require().NoError(err)
+
+	renewReq := &certificate.RenewalRequest{CertificateRequest: s.newRequest("device.example.com", certificate.ChainOptionRootLast)}
+
+	// Re-enrollment is authenticated with the current certificate
+	_, err = connector.RenewCertificate(ctx, renewReq)
+	s.True(errors.Is(err, verror.AuthError))
+
+	keyPEM, err := certificate.GetPrivateKeyPEMBock(req.PrivateKey)
+	s.Require().NoError(err)
+	clientCert, err := tls.X509KeyPair([]byte(pcc.Certificate), pem.EncodeToMemory(keyPEM))
+	s.Require().NoError(err)
+	connector.SetHTTPClient(&http.Client{Transport: &http.Transport{
+		TLSClientConfig: &tls.Config{RootCAs: s.server.trust(), Certificates: []tls.Certificate{clientCert}},
+	}})
+
+	pickupID, err := connector.RenewCertificate(ctx, renewReq)
+	s.Require().NoError(err)
+
+	renewed, err := connector.RetrieveCertificate(ctx, &certificate.Request{PickupID: pickupID})
+	s.Require().NoError(err)
+	s.NotEqual(pcc.Certificate, renewed.Certificate)
+	s.Equal("device.example.com", parsePEMCertificate(s, renewed.Certificate).Subject.CommonName)
+
+	_, err = connector.RetrieveCertificate(ctx, &certificate.Request{PickupID: pickupID})
+	s.Error(err)
+}
+
+func (s *ConnectorSuite) TestParseCertsOnly() {
+	der, err := marshalCertsOnly([]*x509.Certificate{s.server.rootCert})
+	s.Require().NoError(err)
+	certs, err := parseCertsOnly(der)
+	s.Require().NoError(err)
+	s.Require().Len(certs, 1)
+	s.True(certs[0].Equal(s.server.rootCert))
+
+	_, err = parseCertsOnly(s.server.rootCert.Raw)
+	s.Error(err)
+
+	_, err = parseCertificatesResponse([]byte("not base64!"))
+	s.True(errors.Is(err, verror.ServerError))
+}
@@ -0,0 +1,185 @@
+/*
+ * Copyright 2023 Venafi, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *  http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package est
+
+import (
+	"bytes"
+	"context"
+	"crypto/x509"
+	"encoding/base64"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/Venafi/vcert/v5/pkg/util"
+	"github.com/Venafi/vcert/v5/pkg/verror"
+)
+
+type urlResource string
+
+const (
+	urlResourceCACerts        urlResource = "cacerts"
+	urlResourceSimpleEnroll   urlResource = "simpleenroll"
+	urlResourceSimpleReenroll urlResource = "simplereenroll"
+
+	// wellKnownPath is the path prefix of every EST operation, as defined by RFC 7030 section 3.2.2
+	wellKnownPath = ".well-known/est/"
+
+	mimeTypePKCS10 = "application/pkcs10"
+	mimeTypePKCS7  = "application/pkcs7-mime"
+
+	// defaultRetryAfter is the wait between polls when a server accepts a request without a Retry-After header
+	defaultRetryAfter = 10 * time.Second
+)
+
+// normalizeURL returns the base URL of the EST operations: https://<server>/.well-known/est/
+func normalizeURL(url string) string {
+	normalizedURL := util.NormalizeUrl(url)
+	if !strings.Contains(normalizedURL, wellKnownPath) {
+		normalizedURL = normalizedURL + wellKnownPath
+	}
+	return normalizedURL
+}
+
+// getURL returns the URL of the EST operation. The zone, when set, is the label of the CA as described in RFC 7030 section 3.2.2
+func (c *Connector) getURL(resource urlResource) string {
+	if c.zone != "" {
+		return fmt.Sprintf("%s%s/%s", c.baseURL, strings.Trim(c.zone, "/"), resource)
+	}
+	return fmt.Sprintf("%s%s", c.baseURL, resource)
+}
+
+func (c *Connector) request(ctx context.Context, method string, resource urlResource, body []byte) (statusCode int, header http.Header, respBody []byte, err error) {
+	var payload io.Reader
+	if body != nil {
+		payload = bytes.NewReader(body)
+	}
+
+	resourceURL := c.getURL(resource)
+	r, err := http.NewRequestWithContext(ctx, method, resourceURL, payload)
+	if err != nil {
+		return
+	}
+	if body != nil {
+		r.Header.Set("Content-Type", mimeTypePKCS10)
+		r.Header.Set("Content-Transfer-Encoding", "base64")
+	}
+	if c.user != "" {
+		r.SetBasicAuth(c.user, c.password)
+	}
+
+	res, err := c.getHTTPClient().Do(r)
+	if err != nil {
+		return
+	}
+	defer res.Body.Close()
+
+	statusCode = res.StatusCode
+	header = res.Header
+	respBody, err = io.ReadAll(res.Body)
+	if c.verbose {
+		log.Printf("Got %s status for %s %s\n", res.Status, method, resourceURL)
+	}
+	return
+}
+
+// getCACertificates returns the current CA certificates of the EST server
+func (c *Connector) getCACertificates(ctx context.Context) ([]*x509.Certificate, error) {
+	statusCode, _, body, err := c.request(ctx, http.MethodGet, urlResourceCACerts, nil)
+	if err != nil {
+		return nil, err
+	}
+	if statusCode != http.StatusOK {
+		return nil, fmt.Errorf("%w: unexpected status code on EST cacerts. Status: %d %s", verror.ServerError, statusCode, strings.TrimSpace(string(body)))
+	}
+	return parseCertificatesResponse(body)
+}
+
+// enroll sends the CSR to the simpleenroll or simplereenroll operation and returns the issued certificate.
+// Requests accepted for manual approval are polled, as the Retry-After header asks, until the timeout expires
+func (c *Connector) enroll(ctx context.Context, resource urlResource, csr []byte, timeout time.Duration) ([]*x509.Certificate, error) {
+	body := []byte(base64.StdEncoding.EncodeToString(csr))
+	deadline := time.Now().Add(timeout)
+
+	for {
+		statusCode, header, respBody, err := c.request(ctx, http.MethodPost, resource, body)
+		if err != nil {
+			return nil, err
+		}
+
+		switch statusCode {
+		case http.StatusOK:
+			return parseCertificatesResponse(respBody)
+		case http.StatusAccepted:
+			wait := getRetryAfter(header)
+			if time.Now().Add(wait).After(deadline) {
+				return nil, fmt.Errorf("%w: the EST server did not issue the certificate before the timeout", verror.ServerTemporaryUnavailableError)
+			}
+			if err = util.Sleep(ctx, wait); err != nil {
+				return nil, err
+			}
+		case http.StatusUnauthorized, http.StatusForbidden:
+			return nil, fmt.Errorf("%w: EST server rejected the credentials. Status: %d %s", verror.AuthError, statusCode, strings.TrimSpace(string(respBody)))
+		default:
+			return nil, fmt.Errorf("%w: unexpected status code on EST %s. Status: %d %s", verror.ServerError, resource, statusCode, strings.TrimSpace(string(respBody)))
+		}
+	}
+}
+
+// getRetryAfter reads the Retry-After header, either a number of seconds or a date
+func getRetryAfter(header http.Header) time.Duration {
+	value := header.Get("Retry-After")
+	if seconds, err := strconv.Atoi(value); err == nil && seconds > 0 {
+		return time.Duration(seconds) * time.Second
+	}
+	if date, err := http.ParseTime(value); err == nil {
+		if wait := time.Until(date); wait > 0 {
+			return wait
+		}
+	}
+	return defaultRetryAfter
+}
+
+// parseCertificatesResponse returns the certificates of the base64 encoded PKCS#7 body of an EST response
+func parseCertificatesResponse(body []byte) ([]*x509.Certificate, error) {
+	der, err := decodeBase64(body)
+	if err != nil {
+		return nil, fmt.Errorf("%w: could not decode EST response: %v", verror.ServerError, err)
+	}
+	certs, err := parseCertsOnly(der)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", verror.ServerError, err)
+	}
+	return certs, nil
+}
+
+func (c *Connector) getHTTPClient() *http.Client {
+	if c.client != nil {
+		return c.client
+	}
+	// The default transport holds the TLS client certificate used to authenticate to the EST server, kept by NewTransport
+	netTransport := util.NewTransportWithTLSConfig(c.transport, c.proxy, c.trust, c.tlsConfig)
+	c.client = &http.Client{
+		Timeout:   time.Second * 30,
+		Transport: netTransport,
+	}
+	return c.client
+}
@@ -0,0 +1,204 @@
+/*
+ * Copyright 2023 Venafi, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *  http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package est
+
+import (
+	"bytes"
+	"crypto/ecdsa"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/asn1"
+	"encoding/base64"
+	"io"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/Venafi/vcert/v5/test/testcert"
+)
+
+// estMockServer is a minimal EST server. Certificates are issued by an intermediate CA, and only the issued
+// certificate is returned by the enroll operations. Re-enrollment requires a TLS client certificate with the subject of the CSR
+type estMockServer struct {
+	server *httptest.Server
+
+	user     string
+	password string
+	// pending is the number of enroll requests answered with 202 Accepted before issuing the certificate
+	pending int
+
+	mu        sync.Mutex
+	label     string
+	requests  int
+	rootKey   *ecdsa.PrivateKey
+	rootCert  *x509.Certificate
+	interKey  *ecdsa.PrivateKey
+	interCert *x509.Certificate
+}
+
+func newESTMockServer() *estMockServer {
+	s := &estMockServer{}
+	root := testcert.Issue(testcert.Template("EST Test Root CA", true), nil, nil)
+	inter := testcert.Issue(testcert.Template("EST Test Intermediate CA", true), nil, &root)
+	s.rootKey, s.rootCert = root.Key.(*ecdsa.PrivateKey), root.Cert
+	s.interKey, s.interCert = inter.Key.(*ecdsa.PrivateKey), inter.Cert
+
+	s.server = httptest.NewUnstartedServer(http.HandlerFunc(s.handle))
+	s.server.TLS = &tls.Config{ClientAuth: tls.RequestClientCert}
+	s.server.StartTLS()
+	return s
+}
+
+// trust returns the pool to verify the TLS certificate of the server
+func (s *estMockServer) trust() *x509.CertPool {
+	pool := x509.NewCertPool()
+	pool.AddCert(s.server.Certificate())
+	return pool
+}
+
+func (s *estMockServer) handle(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	path, found := strings.CutPrefix(r.URL.Path, "/.well-known/est/")
+	if !found {
+		http.NotFound(w, r)
+		return
+	}
+	s.label = ""
+	if i := strings.LastIndex(path, "/"); i >= 0 {
+		s.label, path = path[:i], path[i+1:]
+	}
+
+	switch {
+	case path == "cacerts" && r.Method == http.MethodGet:
+		s.writeCertificates(w, s.interCert, s.rootCert)
+	case (path == "simpleenroll" || path == "simplereenroll") && r.Method == http.MethodPost:
+		s.enroll(w, r, path == "simplereenroll")
+	default:
+		http.NotFound(w, r)
+	}
+}
+
+func (s *estMockServer) enroll(w http.ResponseWriter, r *http.Request, reenroll bool) {
+	s.requests++
+	if r.Header.Get("Content-Type") != mimeTypePKCS10 {
+		http.Error(w, "unexpected content type", http.StatusUnsupportedMediaType)
+		return
+	}
+	if s.user != "" {
+		user, password, ok := r.BasicAuth()
+		if !ok || user != s.user || password != s.password {
+			http.Error(w, "unauthorized", http.StatusUnauthorized)
+			return
+		}
+	}
+
+	body, _ := io.ReadAll(r.Body)
+	der, err := decodeBase64(body)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	csr, err := x509.ParseCertificateRequest(der)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	if reenroll {
+		if len(r.TLS.PeerCertificates) == 0 || !bytes.Equal(r.TLS.PeerCertificates[0].RawSubject, csr.RawSubject) {
+			http.Error(w, "the client certificate does not match the CSR", http.StatusForbidden)
+			return
+		}
+	}
+
+	if s.pending > 0 {
+		s.pending--
+		w.Header().Set("Retry-After", "1")
+		w.WriteHeader(http.StatusAccepted)
+		return
+	}
+
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(time.Now().UnixNano()),
+		Subject:      csr.Subject,
+		DNSNames:     csr.DNSNames,
+		NotBefore:    time.Now().Add(-time.Minute),
+		NotAfter:     time.Now().Add(time.Hour),
+		KeyUsage:     x509.KeyUsageDigitalSignature,
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth, x509.ExtKeyUsageClientAuth},
+	}
+	certDER, err := x509.CreateCertificate(rand.Reader, template, s.interCert, csr.PublicKey, s.interKey)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	cert, _ := x509.ParseCertificate(certDER)
+	s.writeCertificates(w, cert)
+}
+
+func (s *estMockServer) writeCertificates(w http.ResponseWriter, certs ...*x509.Certificate) {
+	der, err := marshalCertsOnly(certs)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	// Servers usually break the base64 body in lines
+	encoded := base64.StdEncoding.EncodeToString(der)
+	var lines []string
+	for len(encoded) > 64 {
+		lines = append(lines, encoded[:64])
+		encoded = encoded[64:]
+	}
+	lines = append(lines, encoded)
+
+	w.Header().Set("Content-Type", mimeTypePKCS7+"; smime-type=certs-only")
+	w.Header().Set("Content-Transfer-Encoding", "base64")
+	_, _ = w.Write([]byte(strings.Join(lines, "\r\n")))
+}
+
+// marshalCertsOnly encodes the certificates as a PKCS#7 certs-only message
+func marshalCertsOnly(certs []*x509.Certificate) ([]byte, error) {
+	var raw []byte
+	for _, cert := range certs {
+		raw = append(raw, cert.Raw...)
+	}
+
+	data, err := asn1.Marshal(contentInfo{ContentType: asn1.ObjectIdentifier{1, 2, 840, 113549, 1, 7, 1}})
+	if err != nil {
+		return nil, err
+	}
+	sd, err := asn1.Marshal(signedData{
+		Version:          1,
+		DigestAlgorithms: asn1.RawValue{Tag: asn1.TagSet, IsCompound: true},
+		ContentInfo:      asn1.RawValue{FullBytes: data},
+		Certificates:     asn1.RawValue{Class: asn1.ClassContextSpecific, Tag: 0, IsCompound: true, Bytes: raw},
+		SignerInfos:      asn1.RawValue{Tag: asn1.TagSet, IsCompound: true},
+	})
+	if err != nil {
+		return nil, err
+	}
+	// The explicit tag is not applied to raw values, so the [0] wrapper is written here
+	content := asn1.RawValue{Class: asn1.ClassContextSpecific, Tag: 0, IsCompound: true, Bytes: sd}
+	return asn1.Marshal(contentInfo{ContentType: oidSignedData, Content: content})
+}
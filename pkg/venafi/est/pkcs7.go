@@ -0,0 +1,84 @@
+/*
+ * Copyright 2023 Venafi, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *  http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package est
+
+import (
+	"bytes"
+	"crypto/x509"
+	"encoding/asn1"
+	"encoding/base64"
+	"fmt"
+)
+
+var (
+	oidSignedData = asn1.ObjectIdentifier{1, 2, 840, 113549, 1, 7, 2}
+)
+
+// contentInfo is the outer structure of a PKCS#7 (RFC 2315) message
+type contentInfo struct {
+	ContentType asn1.ObjectIdentifier
+	Content     asn1.RawValue `asn1:"explicit,optional,tag:0"`
+}
+
+// signedData is the PKCS#7 structure EST servers use to return certificates. The responses are "certs-only":
+// they carry no signers, so only the certificates are read
+type signedData struct {
+	Version          int
+	DigestAlgorithms asn1.RawValue
+	ContentInfo      asn1.RawValue
+	Certificates     asn1.RawValue `asn1:"optional,tag:0"`
+	CRLs             asn1.RawValue `asn1:"optional,tag:1"`
+	SignerInfos      asn1.RawValue
+}
+
+// decodeBase64 decodes the base64 body of an EST response, ignoring the line breaks servers add to it
+func decodeBase64(body []byte) ([]byte, error) {
+	cleaned := bytes.Map(func(r rune) rune {
+		switch r {
+		case '\r', '\n', ' ', '\t':
+			return -1
+		}
+		return r
+	}, body)
+	return base64.StdEncoding.DecodeString(string(cleaned))
+}
+
+// parseCertsOnly returns the certificates of a DER encoded PKCS#7 certs-only message
+func parseCertsOnly(der []byte) ([]*x509.Certificate, error) {
+	var info contentInfo
+	rest, err := asn1.Unmarshal(der, &info)
+	if err != nil {
+		return nil, fmt.Errorf("could not parse PKCS#7 content info: %w", err)
+	}
+	if len(rest) > 0 {
+		return nil, fmt.Errorf("unexpected trailing data after PKCS#7 content info")
+	}
+	if !info.ContentType.Equal(oidSignedData) {
+		return nil, fmt.Errorf("unexpected PKCS#7 content type %s", info.ContentType)
+	}
+
+	var sd signedData
+	_, err = asn1.Unmarshal(info.Content.Bytes, &sd)
+	if err != nil {
+		return nil, fmt.Errorf("could not parse PKCS#7 signed data: %w", err)
+	}
+	if len(sd.Certificates.Bytes) == 0 {
+		return nil, fmt.Errorf("PKCS#7 message contains no certificates")
+	}
+
+	return x509.ParseCertificates(sd.Certificates.Bytes)
+}
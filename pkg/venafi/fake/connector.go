@@ -17,6 +17,7 @@
 package fake
 
 import (
+	"context"
 	"crypto"
 	"crypto/rand"
 	"crypto/x509"
@@ -38,47 +39,55 @@ type Connector struct {
 	verbose bool
 }
 
-func (c *Connector) RetrieveCertificateMetaData(dn string) (*certificate.CertificateMetaData, error) {
+func (c *Connector) RetrieveCertificateMetaData(ctx context.Context, dn string) (*certificate.CertificateMetaData, error) {
 	panic("operation is not supported yet")
 }
 
-func (c *Connector) SearchCertificates(req *certificate.SearchRequest) (*certificate.CertSearchResponse, error) {
+func (c *Connector) SearchCertificates(ctx context.Context, req *certificate.SearchRequest) (*certificate.CertSearchResponse, error) {
 	panic("operation is not supported yet")
 }
 
-func (c *Connector) SearchCertificate(zone string, cn string, sans *certificate.Sans, certMinTimeLeft time.Duration) (certificateInfo *certificate.CertificateInfo, err error) {
+func (c *Connector) SearchCertificate(ctx context.Context, zone string, cn string, sans *certificate.Sans, certMinTimeLeft time.Duration) (certificateInfo *certificate.CertificateInfo, err error) {
 	panic("operation is not supported yet")
 }
 
-func (c *Connector) IsCSRServiceGenerated(req *certificate.Request) (bool, error) {
+func (c *Connector) IsCSRServiceGenerated(ctx context.Context, req *certificate.Request) (bool, error) {
 	panic("operation is not supported yet")
 }
 
-func (c *Connector) RetrieveSshConfig(ca *certificate.SshCaTemplateRequest) (*certificate.SshConfig, error) {
+func (c *Connector) RetrieveSshConfig(ctx context.Context, ca *certificate.SshCaTemplateRequest) (*certificate.SshConfig, error) {
 	panic("operation is not supported yet")
 }
 
-func (c *Connector) RetrieveSSHCertificate(req *certificate.SshCertRequest) (response *certificate.SshCertificateObject, err error) {
+func (c *Connector) RetrieveSSHCertificate(ctx context.Context, req *certificate.SshCertRequest) (response *certificate.SshCertificateObject, err error) {
 	panic("operation is not supported yet")
 }
 
-func (c *Connector) RequestSSHCertificate(req *certificate.SshCertRequest) (response *certificate.SshCertificateObject, err error) {
+func (c *Connector) RenewSSHCertificate(ctx context.Context, req *certificate.SshCertRequest) (response *certificate.SshCertificateObject, err error) {
 	panic("operation is not supported yet")
 }
 
-func (c *Connector) RetrieveAvailableSSHTemplates() (response []certificate.SshAvaliableTemplate, err error) {
+func (c *Connector) RevokeSSHCertificate(ctx context.Context, req *certificate.SshCertRequest) error {
 	panic("operation is not supported yet")
 }
 
-func (c *Connector) RetrieveSystemVersion() (response string, err error) {
+func (c *Connector) RequestSSHCertificate(ctx context.Context, req *certificate.SshCertRequest) (response *certificate.SshCertificateObject, err error) {
 	panic("operation is not supported yet")
 }
 
-func (c *Connector) ResetCertificate(req *certificate.Request, restart bool) (err error) {
+func (c *Connector) RetrieveAvailableSSHTemplates(ctx context.Context) (response []certificate.SshAvaliableTemplate, err error) {
 	panic("operation is not supported yet")
 }
 
-func (c *Connector) GetPolicy(name string) (*policy.PolicySpecification, error) {
+func (c *Connector) RetrieveSystemVersion(ctx context.Context) (response string, err error) {
+	panic("operation is not supported yet")
+}
+
+func (c *Connector) ResetCertificate(ctx context.Context, req *certificate.Request, restart bool) (err error) {
+	panic("operation is not supported yet")
+}
+
+func (c *Connector) GetPolicy(ctx context.Context, name string) (*policy.PolicySpecification, error) {
 
 	caName := "\\VED\\Policy\\Certificate Authorities\\TEST CA\\QA Test CA - Server 90 Days"
 	validityHours := 120
@@ -144,7 +153,7 @@ func (c *Connector) GetPolicy(name string) (*policy.PolicySpecification, error)
 	return &specification, nil
 }
 
-func (c *Connector) SetPolicy(name string, ps *policy.PolicySpecification) (string, error) {
+func (c *Connector) SetPolicy(ctx context.Context, name string, ps *policy.PolicySpecification) (string, error) {
 	return "OK", nil
 }
 
@@ -160,11 +169,11 @@ func (c *Connector) GetType() endpoint.ConnectorType {
 func (c *Connector) SetZone(z string) {
 }
 
-func (c *Connector) Ping() (err error) {
+func (c *Connector) Ping(ctx context.Context) (err error) {
 	return
 }
 
-func (c *Connector) Authenticate(auth *endpoint.Authentication) (err error) {
+func (c *Connector) Authenticate(ctx context.Context, auth *endpoint.Authentication) (err error) {
 	return
 }
 
@@ -180,7 +189,7 @@ func validateRequest(req *certificate.Request) error {
 	return nil
 }
 
-func (c *Connector) RequestCertificate(req *certificate.Request) (requestID string, err error) {
+func (c *Connector) RequestCertificate(ctx context.Context, req *certificate.Request) (requestID string, err error) {
 	err = validateRequest(req)
 	if err != nil {
 		return "", fmt.Errorf("certificate request validation fail: %s", err)
@@ -211,7 +220,7 @@ func (c *Connector) RequestCertificate(req *certificate.Request) (requestID stri
 }
 
 // SynchronousRequestCertificate It's not supported yet
-func (c *Connector) SynchronousRequestCertificate(_ *certificate.Request) (certificates *certificate.PEMCollection, err error) {
+func (c *Connector) SynchronousRequestCertificate(ctx context.Context, _ *certificate.Request) (certificates *certificate.PEMCollection, err error) {
 	panic("operation is not supported yet")
 }
 
@@ -261,7 +270,7 @@ func issueCertificate(csr *x509.CertificateRequest) ([]byte, error) {
 	return res, nil
 }
 
-func (c *Connector) RetrieveCertificate(req *certificate.Request) (pcc *certificate.PEMCollection, err error) {
+func (c *Connector) RetrieveCertificate(ctx context.Context, req *certificate.Request) (pcc *certificate.PEMCollection, err error) {
 
 	bytes, err := base64.StdEncoding.DecodeString(req.PickupID)
 	if err != nil {
@@ -343,32 +352,32 @@ func (c *Connector) RetrieveCertificate(req *certificate.Request) (pcc *certific
 }
 
 // RevokeCertificate attempts to revoke the certificate
-func (c *Connector) RevokeCertificate(revReq *certificate.RevocationRequest) (err error) {
+func (c *Connector) RevokeCertificate(ctx context.Context, revReq *certificate.RevocationRequest) (err error) {
 	return fmt.Errorf("revocation is not supported in -test-mode")
 }
 
 // RetireCertificate attempts to retire the certificate
-func (c *Connector) RetireCertificate(revReq *certificate.RetireRequest) (err error) {
+func (c *Connector) RetireCertificate(ctx context.Context, revReq *certificate.RetireRequest) (err error) {
 	return fmt.Errorf("retire is not supported in -test-mode")
 }
 
-func (c *Connector) ReadZoneConfiguration() (config *endpoint.ZoneConfiguration, err error) {
+func (c *Connector) ReadZoneConfiguration(ctx context.Context) (config *endpoint.ZoneConfiguration, err error) {
 	config = endpoint.NewZoneConfiguration()
-	policy, err := c.ReadPolicyConfiguration()
+	policy, err := c.ReadPolicyConfiguration(ctx)
 	config.Policy = *policy
 	return
 }
 
 // RenewCertificate attempts to renew the certificate
-func (c *Connector) RenewCertificate(revReq *certificate.RenewalRequest) (requestID string, err error) {
+func (c *Connector) RenewCertificate(ctx context.Context, revReq *certificate.RenewalRequest) (requestID string, err error) {
 	return "", fmt.Errorf("renew is not supported in -test-mode")
 }
 
-func (c *Connector) ImportCertificate(req *certificate.ImportRequest) (*certificate.ImportResponse, error) {
+func (c *Connector) ImportCertificate(ctx context.Context, req *certificate.ImportRequest) (*certificate.ImportResponse, error) {
 	return nil, fmt.Errorf("import is not supported in -test-mode")
 }
 
-func (c *Connector) ReadPolicyConfiguration() (policy *endpoint.Policy, err error) {
+func (c *Connector) ReadPolicyConfiguration(ctx context.Context) (policy *endpoint.Policy, err error) {
 	policy = &endpoint.Policy{
 		SubjectCNRegexes: []string{".*"},
 		SubjectORegexes:  []string{".*"},
@@ -391,7 +400,7 @@ func (c *Connector) ReadPolicyConfiguration() (policy *endpoint.Policy, err erro
 	return
 }
 
-func (c *Connector) GetZonesByParent(parent string) ([]string, error) {
+func (c *Connector) GetZonesByParent(ctx context.Context, parent string) ([]string, error) {
 	var zones []string
 
 	children := []string{"Alpha", "Epsilon", "Eta", "Iota", "Omicron", "Upsilon", "Omega"}
@@ -405,10 +414,10 @@ func (c *Connector) GetZonesByParent(parent string) ([]string, error) {
 func (c *Connector) SetHTTPClient(client *http.Client) {
 }
 
-func (c *Connector) ListCertificates(filter endpoint.Filter) ([]certificate.CertificateInfo, error) {
+func (c *Connector) ListCertificates(ctx context.Context, filter endpoint.Filter) ([]certificate.CertificateInfo, error) {
 	return nil, nil
 }
 
-func (c *Connector) WriteLog(logReq *endpoint.LogRequest) (err error) {
+func (c *Connector) WriteLog(ctx context.Context, logReq *endpoint.LogRequest) (err error) {
 	return fmt.Errorf("Logging is not supported in -test-mode")
 }
@@ -17,6 +17,7 @@
 package fake
 
 import (
+	"context"
 	"fmt"
 	"testing"
 	"time"
@@ -25,27 +26,28 @@ import (
 )
 
 func TestRetrieveCertificate(t *testing.T) {
+	ctx := context.Background()
 
 	conn := getTestConnector()
-	zoneConfig, err := conn.ReadZoneConfiguration()
+	zoneConfig, err := conn.ReadZoneConfiguration(ctx)
 
 	req := &certificate.Request{}
 	req.Subject.CommonName = fmt.Sprintf("vcert.test%d.venafi.example.com", time.Now().Nanosecond())
 	req.Subject.Organization = []string{"Venafi, Inc."}
 	req.Subject.OrganizationalUnit = []string{"Automated Tests"}
 	req.KeyType = certificate.KeyTypeECDSA
-	err = conn.GenerateRequest(zoneConfig, req)
+	err = conn.GenerateRequest(ctx, zoneConfig, req)
 	if err != nil {
 		t.Fatalf("%s", err)
 	}
-	pickupID, err := conn.RequestCertificate(req)
+	pickupID, err := conn.RequestCertificate(ctx, req)
 	if err != nil {
 		t.Fatalf("%s", err)
 	}
 
 	req.PickupID = pickupID
 	req.ChainOption = certificate.ChainOptionRootLast
-	_, err = conn.RetrieveCertificate(req)
+	_, err = conn.RetrieveCertificate(ctx, req)
 	if err != nil {
 		t.Fatalf("%s", err)
 	}
@@ -57,35 +59,38 @@ func getTestConnector() *Connector {
 }
 
 func TestRevokeCertificate(t *testing.T) {
+	ctx := context.Background()
 	var revReq = &certificate.RevocationRequest{}
 	var connector = getTestConnector()
-	err := connector.RevokeCertificate(revReq)
+	err := connector.RevokeCertificate(ctx, revReq)
 	if err == nil {
 		t.Fatal("should fail with not-supported error")
 	}
 }
 
 func TestReadZoneConfiguration(t *testing.T) {
+	ctx := context.Background()
 	var connector = getTestConnector()
-	_, err := connector.ReadZoneConfiguration()
+	_, err := connector.ReadZoneConfiguration(ctx)
 	if err != nil {
 		t.Fatal("should return empty zone-config object")
 	}
 }
 
 func TestRequestCertificate(t *testing.T) {
+	ctx := context.Background()
 	var connector = NewConnector(true, nil)
 	req := &certificate.Request{}
 	req.Subject.CommonName = "test-mode"
 	req.CsrOrigin = certificate.LocalGeneratedCSR
 	req.KeyType = certificate.KeyTypeECDSA
 
-	err := connector.GenerateRequest(nil, req)
+	err := connector.GenerateRequest(ctx, nil, req)
 	if err != nil {
 		t.Fatalf("error: %s", err)
 	}
 
-	requestID, err := connector.RequestCertificate(req)
+	requestID, err := connector.RequestCertificate(ctx, req)
 	if err != nil {
 		t.Fatalf("error: %s", err)
 	}
@@ -17,6 +17,7 @@
 package fake
 
 import (
+	"context"
 	"fmt"
 
 	"github.com/Venafi/vcert/v5/pkg/certificate"
@@ -24,7 +25,7 @@ import (
 )
 
 // GenerateRequest creates a new certificate request, based on the zone/policy configuration and the user data
-func (c *Connector) GenerateRequest(config *endpoint.ZoneConfiguration, req *certificate.Request) (err error) {
+func (c *Connector) GenerateRequest(ctx context.Context, config *endpoint.ZoneConfiguration, req *certificate.Request) (err error) {
 
 	switch req.CsrOrigin {
 	case certificate.LocalGeneratedCSR:
@@ -17,12 +17,14 @@
 package fake
 
 import (
+	"context"
 	"testing"
 
 	"github.com/Venafi/vcert/v5/pkg/certificate"
 )
 
 func TestGenerateRequest(t *testing.T) {
+	ctx := context.Background()
 	var req certificate.Request
 
 	fake := Connector{}
@@ -32,7 +34,7 @@ func TestGenerateRequest(t *testing.T) {
 	req.CsrOrigin = certificate.LocalGeneratedCSR
 	req.KeyType = certificate.KeyTypeECDSA
 
-	err := fake.GenerateRequest(nil, &req)
+	err := fake.GenerateRequest(ctx, nil, &req)
 	if err != nil {
 		t.Fatalf("error: %s", err)
 	}
@@ -43,7 +45,7 @@ func TestGenerateRequest(t *testing.T) {
 	req = certificate.Request{}
 	req.Subject.CommonName = "vcert.test.vfidev.com"
 	req.CsrOrigin = certificate.UserProvidedCSR
-	err = fake.GenerateRequest(nil, &req)
+	err = fake.GenerateRequest(ctx, nil, &req)
 	if err == nil {
 		t.Fatalf("should fail without user-provided CSR")
 	}
@@ -51,7 +53,7 @@ func TestGenerateRequest(t *testing.T) {
 	req = certificate.Request{}
 	req.Subject.CommonName = "vcert.test.vfidev.com"
 	req.CsrOrigin = certificate.ServiceGeneratedCSR
-	err = fake.GenerateRequest(nil, &req)
+	err = fake.GenerateRequest(ctx, nil, &req)
 	if err != nil || len(req.GetCSR()) > 0 {
 		t.Fatalf("should do nothing")
 	}
@@ -18,6 +18,7 @@ package firefly
 
 import (
 	"context"
+	"crypto/tls"
 	"crypto/x509"
 	"fmt"
 	"net/http"
@@ -49,6 +50,9 @@ type Connector struct {
 	verbose     bool
 	trust       *x509.CertPool
 	client      *http.Client
+	proxy       util.ProxyConfig
+	transport   util.TransportConfig
+	tlsConfig   *tls.Config
 	zone        string // holds the policyName
 }
 
@@ -79,7 +83,7 @@ func (c *Connector) GetType() endpoint.ConnectorType {
 	return endpoint.ConnectorTypeFirefly
 }
 
-func (c *Connector) Authenticate(auth *endpoint.Authentication) error {
+func (c *Connector) Authenticate(ctx context.Context, auth *endpoint.Authentication) error {
 	if auth == nil {
 		msg := "failed to authenticate: no credentials provided"
 		zap.L().Error(msg, fieldPlatform)
@@ -89,7 +93,7 @@ func (c *Connector) Authenticate(auth *endpoint.Authentication) error {
 	if auth.AccessToken == "" {
 		zap.L().Info("no access token provided. Authorization needed", fieldPlatform)
 		var token *oauth2.Token
-		token, err := c.Authorize(auth)
+		token, err := c.Authorize(ctx, auth)
 		if err != nil {
 			return err
 		}
@@ -103,7 +107,7 @@ func (c *Connector) Authenticate(auth *endpoint.Authentication) error {
 }
 
 // Authorize Get an OAuth access token
-func (c *Connector) Authorize(auth *endpoint.Authentication) (token *oauth2.Token, err error) {
+func (c *Connector) Authorize(ctx context.Context, auth *endpoint.Authentication) (token *oauth2.Token, err error) {
 	defer func() {
 		if err != nil {
 			err = fmt.Errorf("%w: %s", verror.AuthError, err)
@@ -118,6 +122,12 @@ func (c *Connector) Authorize(auth *endpoint.Authentication) (token *oauth2.Toke
 		return nil, fmt.Errorf(msg)
 	}
 
+	if auth.IdentityProvider == nil {
+		msg := "failed to authenticate: missing identity provider"
+		zap.L().Error(msg, fieldPlatform)
+		return nil, fmt.Errorf(msg)
+	}
+
 	successMsg := "successfully authorized to OAuth2 server"
 	failureMsg := "authorization flow failed"
 
@@ -138,7 +148,7 @@ func (c *Connector) Authorize(auth *endpoint.Authentication) (token *oauth2.Toke
 			}
 		}
 
-		token, err = config.Token(context.Background())
+		token, err = config.Token(c.getOAuthContext(ctx))
 		if err != nil {
 			zap.L().Error(failureMsg, fieldPlatform, zap.Error(err))
 			return token, err
@@ -166,7 +176,7 @@ func (c *Connector) Authorize(auth *endpoint.Authentication) (token *oauth2.Toke
 			},
 		}
 
-		token, err = config.PasswordCredentialsToken(context.Background(), auth.User, auth.Password)
+		token, err = config.PasswordCredentialsToken(c.getOAuthContext(ctx), auth.User, auth.Password)
 		if err != nil {
 			zap.L().Error(failureMsg, fieldPlatform, zap.Error(err))
 			return token, err
@@ -180,7 +190,7 @@ func (c *Connector) Authorize(auth *endpoint.Authentication) (token *oauth2.Toke
 	if auth.IdentityProvider.DeviceURL != "" {
 		zap.L().Info("authorizing using device flow", fieldPlatform)
 
-		token, err = c.getDeviceAccessToken(auth)
+		token, err = c.getDeviceAccessToken(ctx, auth)
 		if err != nil {
 			zap.L().Error(failureMsg, fieldPlatform, zap.Error(err))
 			return token, err
@@ -195,10 +205,35 @@ func (c *Connector) Authorize(auth *endpoint.Authentication) (token *oauth2.Toke
 	return token, fmt.Errorf(errMsg)
 }
 
-// SynchronousRequestCertificate It's not supported yet in VaaS
-func (c *Connector) SynchronousRequestCertificate(req *certificate.Request) (certificates *certificate.PEMCollection, err error) {
+// SynchronousRequestCertificate requests a certificate and waits for it to be issued. If the issuance policy
+// doesn't issue it right away, the certificate is retrieved using req.Timeout as in RetrieveCertificate
+func (c *Connector) SynchronousRequestCertificate(ctx context.Context, req *certificate.Request) (certificates *certificate.PEMCollection, err error) {
+	cr, err := c.submitCertificateRequest(ctx, req)
+	if err != nil {
+		return nil, err
+	}
+
+	if cr.Status == requestStatusPending {
+		zap.L().Info("certificate request is pending", fieldPlatform, zap.String("requestId", cr.RequestID))
+		req.PickupID = cr.RequestID
+		return c.RetrieveCertificate(ctx, req)
+	}
+
+	certificates, err = toPEMCollection(cr, req)
+	if err != nil {
+		return nil, err
+	}
+	zap.L().Info("successfully requested certificate", fieldPlatform)
+	return certificates, nil
+}
 
+func (c *Connector) submitCertificateRequest(ctx context.Context, req *certificate.Request) (*certificateRequestResponse, error) {
 	zap.L().Info("requesting certificate", zap.String("cn", req.Subject.CommonName), fieldPlatform)
+	err := c.validatePolicyName(ctx)
+	if err != nil {
+		return nil, err
+	}
+
 	//creating the request object
 	certReq, err := c.getCertificateRequest(req)
 	if err != nil {
@@ -207,7 +242,7 @@ func (c *Connector) SynchronousRequestCertificate(req *certificate.Request) (cer
 	}
 
 	zap.L().Info("sending HTTP request", fieldPlatform)
-	statusCode, status, body, err := c.request("POST", c.getCertificateRequestUrl(req), certReq)
+	statusCode, status, body, err := c.request(ctx, "POST", c.getCertificateRequestUrl(req), certReq)
 	if err != nil {
 		zap.L().Error("HTTP request failed", fieldPlatform, zap.Error(err))
 		return nil, err
@@ -219,16 +254,17 @@ func (c *Connector) SynchronousRequestCertificate(req *certificate.Request) (cer
 		zap.L().Error("failed to request a certificate", fieldPlatform, zap.Error(err))
 		return nil, err
 	}
+	return cr, nil
+}
 
-	//converting to PEMCollection
-	certificates, err = certificate.PEMCollectionFromBytes([]byte(cr.CertificateChain), req.ChainOption)
+func toPEMCollection(cr *certificateRequestResponse, req *certificate.Request) (*certificate.PEMCollection, error) {
+	certificates, err := certificate.PEMCollectionFromBytes([]byte(cr.CertificateChain), req.ChainOption)
 	if err != nil {
 		zap.L().Error("failed to create pem collection", fieldPlatform, zap.Error(err))
 		return nil, err
 	}
 
 	certificates.PrivateKey = cr.PrivateKey
-	zap.L().Info("successfully requested certificate", fieldPlatform)
 	return certificates, nil
 }
 
@@ -301,8 +337,8 @@ func (c *Connector) getCertificateRequest(req *certificate.Request) (*certificat
 	if req.ValidityPeriod != "" {
 		fireflyCertRequest.ValidityPeriod = &req.ValidityPeriod
 	} else {
-		if req.ValidityDuration != nil { //if the validityDuration was set then it will convert to ISO 8601
-			validityPeriod := duration.Format(*req.ValidityDuration)
+		if validity := req.GetValidityDuration(); validity != nil { //if the validityDuration was set then it will convert to ISO 8601
+			validityPeriod := duration.Format(*validity)
 			fireflyCertRequest.ValidityPeriod = &validityPeriod
 		}
 	}
@@ -356,72 +392,126 @@ func (e *ErrCertNotFound) Unwrap() error {
 	return e.error
 }
 
-func (c *Connector) Ping() (err error) {
+func (c *Connector) Ping(ctx context.Context) (err error) {
 	panic("operation is not supported yet")
 }
 
-func (c *Connector) RetrieveSystemVersion() (string, error) {
+func (c *Connector) RetrieveSystemVersion(ctx context.Context) (string, error) {
 	panic("operation is not supported yet")
 }
 
 // RequestCertificate submits the CSR to the Venafi Firefly API for processing
-func (c *Connector) RequestCertificate(_ *certificate.Request) (requestID string, err error) {
-	panic("operation is not supported yet")
-}
+// RequestCertificate submits the certificate request to Firefly and returns the request ID used to retrieve the
+// certificate later on. The request ID is also stored as req.PickupID
+func (c *Connector) RequestCertificate(ctx context.Context, req *certificate.Request) (requestID string, err error) {
+	cr, err := c.submitCertificateRequest(ctx, req)
+	if err != nil {
+		return "", err
+	}
+	if cr.RequestID == "" {
+		return "", fmt.Errorf("%w: Firefly did not return a request ID", verror.ServerError)
+	}
 
-func (c *Connector) IsCSRServiceGenerated(_ *certificate.Request) (bool, error) {
-	panic("operation is not supported yet")
+	req.PickupID = cr.RequestID
+	zap.L().Info("successfully submitted certificate request", fieldPlatform, zap.String("requestId", cr.RequestID))
+	return cr.RequestID, nil
 }
 
-func (c *Connector) RetrieveSshConfig(_ *certificate.SshCaTemplateRequest) (*certificate.SshConfig, error) {
+func (c *Connector) IsCSRServiceGenerated(ctx context.Context, _ *certificate.Request) (bool, error) {
 	panic("operation is not supported yet")
 }
 
-func (c *Connector) RetrieveAvailableSSHTemplates() (response []certificate.SshAvaliableTemplate, err error) {
+func (c *Connector) RetrieveSshConfig(ctx context.Context, _ *certificate.SshCaTemplateRequest) (*certificate.SshConfig, error) {
 	panic("operation is not supported yet")
 }
 
-func (c *Connector) ResetCertificate(_ *certificate.Request, _ bool) (err error) {
+func (c *Connector) RetrieveAvailableSSHTemplates(ctx context.Context) (response []certificate.SshAvaliableTemplate, err error) {
 	panic("operation is not supported yet")
 }
 
-func (c *Connector) GetPolicy(_ string) (*policy.PolicySpecification, error) {
+func (c *Connector) ResetCertificate(ctx context.Context, _ *certificate.Request, _ bool) (err error) {
 	panic("operation is not supported yet")
 }
 
-func (c *Connector) SetPolicy(_ string, _ *policy.PolicySpecification) (string, error) {
+func (c *Connector) SetPolicy(ctx context.Context, _ string, _ *policy.PolicySpecification) (string, error) {
 	panic("operation is not supported yet")
 }
 
-func (c *Connector) RetrieveCertificate(_ *certificate.Request) (certificates *certificate.PEMCollection, err error) {
-	panic("operation is not supported yet")
+// RetrieveCertificate retrieves the certificate for the request ID in req.PickupID. While the request is pending,
+// it returns endpoint.ErrCertificatePending when req.Timeout is zero, or keeps polling until the timeout elapses
+func (c *Connector) RetrieveCertificate(ctx context.Context, req *certificate.Request) (certificates *certificate.PEMCollection, err error) {
+	if req.PickupID == "" {
+		return nil, fmt.Errorf("%w: a request ID is required to retrieve a certificate from Firefly", verror.UserDataError)
+	}
+
+	startTime := time.Now()
+	for {
+		zap.L().Info("retrieving certificate request", fieldPlatform, zap.String("requestId", req.PickupID))
+		statusCode, status, body, err := c.request(ctx, "GET", getCertificateRequestStatusUrl(req.PickupID), nil)
+		if err != nil {
+			zap.L().Error("HTTP request failed", fieldPlatform, zap.Error(err))
+			return nil, err
+		}
+		if statusCode == http.StatusNotFound {
+			return nil, &ErrCertNotFound{fmt.Errorf("certificate request %s was not found on Venafi Firefly", req.PickupID)}
+		}
+
+		cr, err := parseCertificateRequestResult(statusCode, status, body)
+		if err != nil {
+			zap.L().Error("failed to retrieve the certificate request", fieldPlatform, zap.Error(err))
+			return nil, err
+		}
+
+		switch cr.Status {
+		case requestStatusPending:
+		case requestStatusFailed, requestStatusRejected:
+			return nil, endpoint.ErrCertificateRejected{CertificateID: req.PickupID, Status: cr.StatusDetails}
+		default:
+			certificates, err = toPEMCollection(cr, req)
+			if err != nil {
+				return nil, err
+			}
+			zap.L().Info("successfully retrieved certificate", fieldPlatform)
+			return certificates, nil
+		}
+
+		if req.Timeout == 0 {
+			return nil, endpoint.ErrCertificatePending{CertificateID: req.PickupID, Status: cr.Status}
+		}
+		if time.Now().After(startTime.Add(req.Timeout)) {
+			return nil, endpoint.ErrRetrieveCertificateTimeout{CertificateID: req.PickupID}
+		}
+		if err = util.Sleep(ctx, pollInterval); err != nil {
+			return nil, err
+		}
+	}
 }
 
-func (c *Connector) RenewCertificate(_ *certificate.RenewalRequest) (requestID string, err error) {
+func (c *Connector) RenewCertificate(ctx context.Context, _ *certificate.RenewalRequest) (requestID string, err error) {
 	panic("operation is not supported yet")
 }
 
-func (c *Connector) RevokeCertificate(_ *certificate.RevocationRequest) (err error) {
+func (c *Connector) RevokeCertificate(ctx context.Context, _ *certificate.RevocationRequest) (err error) {
 	panic("operation is not supported yet")
 }
 
-func (c *Connector) ReadPolicyConfiguration() (policy *endpoint.Policy, err error) {
+func (c *Connector) ReadPolicyConfiguration(ctx context.Context) (policy *endpoint.Policy, err error) {
 	panic("operation is not supported yet")
 }
 
-func (c *Connector) ReadZoneConfiguration() (config *endpoint.ZoneConfiguration, err error) {
+func (c *Connector) ReadZoneConfiguration(ctx context.Context) (config *endpoint.ZoneConfiguration, err error) {
 	return nil, nil
 }
 
-func (c *Connector) ImportCertificate(_ *certificate.ImportRequest) (*certificate.ImportResponse, error) {
+func (c *Connector) ImportCertificate(ctx context.Context, _ *certificate.ImportRequest) (*certificate.ImportResponse, error) {
 	panic("operation is not supported yet")
 }
 
-func (c *Connector) SearchCertificates(_ *certificate.SearchRequest) (*certificate.CertSearchResponse, error) {
+func (c *Connector) SearchCertificates(ctx context.Context, _ *certificate.SearchRequest) (*certificate.CertSearchResponse, error) {
 	panic("operation is not supported yet")
 }
 
-func (c *Connector) SearchCertificate(_ string, _ string, _ *certificate.Sans, _ time.Duration) (certificateInfo *certificate.CertificateInfo, err error) {
+func (c *Connector) SearchCertificate(ctx context.Context, _ string, _ string, _ *certificate.Sans, _ time.Duration) (certificateInfo *certificate.CertificateInfo, err error) {
 	panic("operation is not supported yet")
 }
 
@@ -429,30 +519,66 @@ func (c *Connector) SetHTTPClient(client *http.Client) {
 	c.client = client
 }
 
-func (c *Connector) WriteLog(_ *endpoint.LogRequest) error {
+// SetProxy sets the proxy the requests to Firefly and its identity provider are sent through, instead of the one of the environment variables.
+// It has no effect on the client set with SetHTTPClient, nor once the connector has sent its first request
+func (c *Connector) SetProxy(proxy util.ProxyConfig) {
+	c.proxy = proxy
+}
+
+// SetTransportConfig tunes the HTTP transport of the requests to Firefly, shared with the other connectors built with the
+// same settings. It has no effect on the client set with SetHTTPClient, nor once the connector has sent its first request
+func (c *Connector) SetTransportConfig(config util.TransportConfig) {
+	c.transport = config
+}
+
+// SetTLSConfig sets the TLS settings of the requests to Firefly, like the client certificate, instead of the ones of
+// http.DefaultTransport. The trust bundle of the connector replaces its root CAs. It has no effect on the client set
+// with SetHTTPClient, nor once the connector has sent its first request
+func (c *Connector) SetTLSConfig(config *tls.Config) {
+	c.tlsConfig = config
+}
+
+// getOAuthContext returns the context of the token requests to the identity provider, which go through the proxy
+// set with SetProxy. The oauth2 package uses http.DefaultClient otherwise
+func (c *Connector) getOAuthContext(ctx context.Context) context.Context {
+	if c.proxy.URL == "" {
+		return ctx
+	}
+	return context.WithValue(ctx, oauth2.HTTPClient, &http.Client{Transport: util.NewTransportWithTLSConfig(c.transport, c.proxy, nil, c.tlsConfig)})
+}
+
+func (c *Connector) WriteLog(ctx context.Context, _ *endpoint.LogRequest) error {
+	panic("operation is not supported yet")
+}
+
+func (c *Connector) ListCertificates(ctx context.Context, _ endpoint.Filter) ([]certificate.CertificateInfo, error) {
+	panic("operation is not supported yet")
+}
+
+func (c *Connector) GetZonesByParent(ctx context.Context, _ string) ([]string, error) {
 	panic("operation is not supported yet")
 }
 
-func (c *Connector) ListCertificates(_ endpoint.Filter) ([]certificate.CertificateInfo, error) {
+func (c *Connector) RequestSSHCertificate(ctx context.Context, _ *certificate.SshCertRequest) (response *certificate.SshCertificateObject, err error) {
 	panic("operation is not supported yet")
 }
 
-func (c *Connector) GetZonesByParent(_ string) ([]string, error) {
+func (c *Connector) RetrieveSSHCertificate(ctx context.Context, _ *certificate.SshCertRequest) (response *certificate.SshCertificateObject, err error) {
 	panic("operation is not supported yet")
 }
 
-func (c *Connector) RequestSSHCertificate(_ *certificate.SshCertRequest) (response *certificate.SshCertificateObject, err error) {
+func (c *Connector) RenewSSHCertificate(ctx context.Context, _ *certificate.SshCertRequest) (response *certificate.SshCertificateObject, err error) {
 	panic("operation is not supported yet")
 }
 
-func (c *Connector) RetrieveSSHCertificate(_ *certificate.SshCertRequest) (response *certificate.SshCertificateObject, err error) {
+func (c *Connector) RevokeSSHCertificate(ctx context.Context, _ *certificate.SshCertRequest) error {
 	panic("operation is not supported yet")
 }
 
-func (c *Connector) RetrieveCertificateMetaData(_ string) (*certificate.CertificateMetaData, error) {
+func (c *Connector) RetrieveCertificateMetaData(ctx context.Context, _ string) (*certificate.CertificateMetaData, error) {
 	panic("operation is not supported yet")
 }
 
-func (c *Connector) RetireCertificate(_ *certificate.RetireRequest) error {
+func (c *Connector) RetireCertificate(ctx context.Context, _ *certificate.RetireRequest) error {
 	panic("operation is not supported yet")
 }
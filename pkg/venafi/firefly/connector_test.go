@@ -17,6 +17,7 @@
 package firefly
 
 import (
+	"context"
 	"crypto/tls"
 	"crypto/x509/pkix"
 	"fmt"
@@ -25,9 +26,11 @@ import (
 	"net/url"
 	"strconv"
 	"testing"
+	"time"
 
 	"github.com/Venafi/vcert/v5/pkg/certificate"
 	"github.com/Venafi/vcert/v5/pkg/endpoint"
+	"github.com/Venafi/vcert/v5/pkg/verror"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/suite"
 )
@@ -115,11 +118,12 @@ func (s *ConnectorSuite) TestGetType() {
 }
 
 func (s *ConnectorSuite) TestAuthenticate() {
+	ctx := context.Background()
 	s.Run("AuthenticationConfNotProvided", func() {
 		fireflyConnector, err := NewConnector(s.fireflyServer.serverURL, "", false, nil)
 		assert.Nil(s.T(), err, fmt.Errorf("error creating firefly connector: %w", err).Error())
 
-		err = fireflyConnector.Authenticate(nil)
+		err = fireflyConnector.Authenticate(ctx, nil)
 
 		if assert.Errorf(s.T(), err, "expected to get an error but was gotten the access_token") {
 			assert.Equal(s.T(), "failed to authenticate: no credentials provided", err.Error())
@@ -131,7 +135,7 @@ func (s *ConnectorSuite) TestAuthenticate() {
 		fireflyConnector, err := NewConnector(s.fireflyServer.serverURL, "", false, nil)
 		assert.Nil(s.T(), err, fmt.Errorf("error creating firefly connector: %w", err).Error())
 
-		err = fireflyConnector.Authenticate(s.createCredFlowAuth())
+		err = fireflyConnector.Authenticate(ctx, s.createCredFlowAuth())
 
 		assert.Nil(s.T(), err, fmt.Errorf("error getting acccess token: %w", err).Error())
 		assert.NotNil(s.T(), fireflyConnector.accessToken)
@@ -139,16 +143,18 @@ func (s *ConnectorSuite) TestAuthenticate() {
 }
 
 func (s *ConnectorSuite) TestClientCredentialFlow() {
+	ctx := context.Background()
 	fireflyConnector, err := NewConnector(s.fireflyServer.serverURL, "", false, nil)
 	assert.Nil(s.T(), err, fmt.Errorf("error creating firefly connector: %w", err).Error())
 
-	oauthToken, err := fireflyConnector.Authorize(s.createCredFlowAuth())
+	oauthToken, err := fireflyConnector.Authorize(ctx, s.createCredFlowAuth())
 
 	assert.Nil(s.T(), err, fmt.Errorf("error getting acccess token: %w", err).Error())
 	assert.NotNil(s.T(), oauthToken)
 }
 
 func (s *ConnectorSuite) TestClientCredentialFlow_Unauthorized() {
+	ctx := context.Background()
 	fireflyConnector, err := NewConnector(s.fireflyServer.serverURL, "", false, nil)
 	assert.Nil(s.T(), err, fmt.Errorf("error creating firefly connector: %w", err).Error())
 
@@ -156,79 +162,85 @@ func (s *ConnectorSuite) TestClientCredentialFlow_Unauthorized() {
 	//changing the clientId
 	auth.ClientId = "unauthorized"
 
-	oauthToken, err := fireflyConnector.Authorize(auth)
+	oauthToken, err := fireflyConnector.Authorize(ctx, auth)
 
 	assert.NotNil(s.T(), err, fmt.Errorf("error getting acccess token: %w", err).Error())
 	assert.Nil(s.T(), oauthToken)
 }
 
 func (s *ConnectorSuite) TestClientPasswordFlow() {
+	ctx := context.Background()
 	fireflyConnector, err := NewConnector(s.fireflyServer.serverURL, "", false, nil)
 	assert.Nil(s.T(), err, fmt.Errorf("error creating firefly connector: %w", err).Error())
 
-	oauthToken, err := fireflyConnector.Authorize(s.createPasswordFlowAuth())
+	oauthToken, err := fireflyConnector.Authorize(ctx, s.createPasswordFlowAuth())
 
 	assert.Nil(s.T(), err, fmt.Errorf("error getting acccess token: %w", err).Error())
 	assert.NotNil(s.T(), oauthToken)
 }
 
 func (s *ConnectorSuite) TestClientPasswordFlow_Unauthorized() {
+	ctx := context.Background()
 	fireflyConnector, err := NewConnector(s.fireflyServer.serverURL, "", false, nil)
 	assert.Nil(s.T(), err, fmt.Errorf("error creating firefly connector: %w", err).Error())
 
 	auth := s.createPasswordFlowAuth()
 	auth.ClientId = "unauthorized"
 
-	oauthToken, err := fireflyConnector.Authorize(auth)
+	oauthToken, err := fireflyConnector.Authorize(ctx, auth)
 
 	assert.NotNil(s.T(), err, fmt.Errorf("error getting acccess token: %w", err).Error())
 	assert.Nil(s.T(), oauthToken)
 }
 
 func (s *ConnectorSuite) TestDeviceFlow() {
+	ctx := context.Background()
 	fireflyConnector, err := NewConnector(s.fireflyServer.serverURL, "", false, nil)
 	assert.Nil(s.T(), err, fmt.Errorf("error creating firefly connector: %w", err).Error())
 
-	oauthToken, err := fireflyConnector.Authorize(s.createDevFlowAuth())
+	oauthToken, err := fireflyConnector.Authorize(ctx, s.createDevFlowAuth())
 
 	assert.Nil(s.T(), err, fmt.Errorf("error getting acccess token: %w", err).Error())
 	assert.NotNil(s.T(), oauthToken)
 }
 
 func (s *ConnectorSuite) TestDeviceFlow_AuthPending() {
+	ctx := context.Background()
 	fireflyConnector, err := NewConnector(s.fireflyServer.serverURL, "", false, nil)
 	assert.Nil(s.T(), err, fmt.Errorf("error creating firefly connector: %w", err).Error())
 
 	auth := s.createDevFlowAuth()
 	auth.ClientId = TestingClientIDAuthPending
 
-	oauthToken, err := fireflyConnector.Authorize(auth)
+	oauthToken, err := fireflyConnector.Authorize(ctx, auth)
 
 	assert.Nil(s.T(), err, fmt.Errorf("error getting acccess token: %w", err).Error())
 	assert.NotNil(s.T(), oauthToken)
 }
 
 func (s *ConnectorSuite) TestDeviceFlow_SlowDown() {
+	ctx := context.Background()
 	fireflyConnector, err := NewConnector(s.fireflyServer.serverURL, "", false, nil)
 	assert.Nil(s.T(), err, fmt.Errorf("error creating firefly connector: %w", err).Error())
 
 	auth := s.createDevFlowAuth()
 	auth.ClientId = TestingClientIDSlowDown
 
-	oauthToken, err := fireflyConnector.Authorize(auth)
+	oauthToken, err := fireflyConnector.Authorize(ctx, auth)
 
 	assert.Nil(s.T(), err, fmt.Errorf("error getting acccess token: %w", err).Error())
 	assert.NotNil(s.T(), oauthToken)
 }
 
 func (s *ConnectorSuite) TestDeviceFlow_AccessDenied() {
+	ctx := context.Background()
 	fireflyConnector, err := NewConnector(s.fireflyServer.serverURL, "", false, nil)
 	assert.Nil(s.T(), err, fmt.Errorf("error creating firefly connector: %w", err).Error())
 
 	auth := s.createDevFlowAuth()
 	auth.ClientId = TestingClientIDAccessDenied
 
-	oauthToken, err := fireflyConnector.Authorize(auth)
+	oauthToken, err := fireflyConnector.Authorize(ctx, auth)
 
 	if assert.Errorf(s.T(), err, "expected to get an error but was gotten the access_token") {
 		assert.Equal(s.T(), "vcert error: your data contains problems: auth error: the access from device was denied by the user", err.Error())
@@ -237,13 +249,14 @@ func (s *ConnectorSuite) TestDeviceFlow_AccessDenied() {
 }
 
 func (s *ConnectorSuite) TestDeviceFlow_ExpiredToken() {
+	ctx := context.Background()
 	fireflyConnector, err := NewConnector(s.fireflyServer.serverURL, "", false, nil)
 	assert.Nil(s.T(), err, fmt.Errorf("error creating firefly connector: %w", err).Error())
 
 	auth := s.createDevFlowAuth()
 	auth.ClientId = TestingClientIDExpiredToken
 
-	oauthToken, err := fireflyConnector.Authorize(auth)
+	oauthToken, err := fireflyConnector.Authorize(ctx, auth)
 
 	if assert.Errorf(s.T(), err, "expected to get an error but was gotten the access_token") {
 		assert.Equal(s.T(), "vcert error: your data contains problems: auth error: the device code expired", err.Error())
@@ -252,11 +265,12 @@ func (s *ConnectorSuite) TestDeviceFlow_ExpiredToken() {
 }
 
 func (s *ConnectorSuite) TestSynchronousRequestCertificate_CSR_Service_Generated() {
+	ctx := context.Background()
 	fireflyConnector, err := NewConnector(s.fireflyServer.serverURL, TestingPolicyName, false, nil)
 	assert.Nil(s.T(), err, fmt.Errorf("error creating firefly connector: %w", err).Error())
 
 	//connector authenticating
-	err = fireflyConnector.Authenticate(s.createDevFlowAuth())
+	err = fireflyConnector.Authenticate(ctx, s.createDevFlowAuth())
 
 	assert.Nil(s.T(), err, fmt.Errorf("error getting access token: %w", err).Error())
 	assert.NotNil(s.T(), fireflyConnector.accessToken)
@@ -275,7 +289,7 @@ func (s *ConnectorSuite) TestSynchronousRequestCertificate_CSR_Service_Generated
 		ValidityPeriod: "P90D",
 	}
 	s.Run("Success", func() {
-		pemCollection, err := fireflyConnector.SynchronousRequestCertificate(&request)
+		pemCollection, err := fireflyConnector.SynchronousRequestCertificate(ctx, &request)
 
 		assert.Nil(s.T(), err, fmt.Errorf("error requesting the certificate: %w", err).Error())
 		assert.NotNil(s.T(), pemCollection)
@@ -285,7 +299,7 @@ func (s *ConnectorSuite) TestSynchronousRequestCertificate_CSR_Service_Generated
 		requestRSASize1024 := request
 		requestRSASize1024.KeyLength = 1024
 
-		pemCollection, err := fireflyConnector.SynchronousRequestCertificate(&requestRSASize1024)
+		pemCollection, err := fireflyConnector.SynchronousRequestCertificate(ctx, &requestRSASize1024)
 
 		if assert.Errorf(s.T(), err, "expected to get an error but was gotten the certificate") {
 			assert.ErrorContains(s.T(), err, "key size 1024 is not supported. Valid RSA sizes for Firefly are ")
@@ -293,11 +307,16 @@ func (s *ConnectorSuite) TestSynchronousRequestCertificate_CSR_Service_Generated
 		assert.Nil(s.T(), pemCollection)
 	})
 	s.Run("Failure_request", func() {
-		//setting momentarily to work in secure mode to get an error managed by the request method
-		http.DefaultTransport.(*http.Transport).TLSClientConfig.InsecureSkipVerify = false
-		pemCollection, err := fireflyConnector.SynchronousRequestCertificate(&request)
+		//setting momentarily to work in secure mode to get an error managed by the request method. The TLS config is
+		//replaced, rather than changed, and the client of the connector dropped, since connectors copy the TLS config
+		//into the transport they share when sending their first request
+		insecureConfig := http.DefaultTransport.(*http.Transport).TLSClientConfig
+		http.DefaultTransport.(*http.Transport).TLSClientConfig = &tls.Config{}
+		fireflyConnector.client = nil
+		pemCollection, err := fireflyConnector.SynchronousRequestCertificate(ctx, &request)
 		//putting back to insecure mode
-		http.DefaultTransport.(*http.Transport).TLSClientConfig.InsecureSkipVerify = true
+		http.DefaultTransport.(*http.Transport).TLSClientConfig = insecureConfig
+		fireflyConnector.client = nil
 
 		if assert.Errorf(s.T(), err, "expected to get an error but was gotten the certificate") {
 			assert.ErrorContains(s.T(), err, "tls: failed to verify certificate: x509: certificate signed by unknown authority")
@@ -309,7 +328,7 @@ func (s *ConnectorSuite) TestSynchronousRequestCertificate_CSR_Service_Generated
 		requestWithoutSubject := request
 		requestWithoutSubject.Subject = pkix.Name{}
 
-		pemCollection, err := fireflyConnector.SynchronousRequestCertificate(&requestWithoutSubject)
+		pemCollection, err := fireflyConnector.SynchronousRequestCertificate(ctx, &requestWithoutSubject)
 
 		if assert.Errorf(s.T(), err, "expected to get an error but was gotten the certificate") {
 			assert.ErrorContains(s.T(), err, "unexpected status code on Venafi Firefly. Status: ")
@@ -320,7 +339,7 @@ func (s *ConnectorSuite) TestSynchronousRequestCertificate_CSR_Service_Generated
 		//copying the connector to keep the original without changes
 		fireflyConnectorFailingPolicy := fireflyConnector
 		fireflyConnectorFailingPolicy.zone = TestingFailingPolicyName
-		pemCollection, err := fireflyConnectorFailingPolicy.SynchronousRequestCertificate(&request)
+		pemCollection, err := fireflyConnectorFailingPolicy.SynchronousRequestCertificate(ctx, &request)
 
 		if assert.Errorf(s.T(), err, "expected to get an error but was gotten the certificate") {
 			assert.ErrorContains(s.T(), err, "x509: malformed certificate")
@@ -330,11 +349,12 @@ func (s *ConnectorSuite) TestSynchronousRequestCertificate_CSR_Service_Generated
 }
 
 func (s *ConnectorSuite) TestSynchronousRequestCertificate_CSR_Provided() {
+	ctx := context.Background()
 	fireflyConnector, err := NewConnector(s.fireflyServer.serverURL, TestingPolicyName, false, nil)
 	assert.Nil(s.T(), err, fmt.Errorf("error creating firefly connector: %w", err).Error())
 
 	//connector authenticating
-	err = fireflyConnector.Authenticate(s.createDevFlowAuth())
+	err = fireflyConnector.Authenticate(ctx, s.createDevFlowAuth())
 
 	assert.Nil(s.T(), err, fmt.Errorf("error getting access token: %w", err).Error())
 	assert.NotNil(s.T(), fireflyConnector.accessToken)
@@ -346,17 +366,22 @@ func (s *ConnectorSuite) TestSynchronousRequestCertificate_CSR_Provided() {
 	request.SetCSR([]byte(csr_test))
 
 	s.Run("Success", func() {
-		pemCollection, err := fireflyConnector.SynchronousRequestCertificate(&request)
+		pemCollection, err := fireflyConnector.SynchronousRequestCertificate(ctx, &request)
 
 		assert.Nil(s.T(), err, fmt.Errorf("error requesting the certificate: %w", err).Error())
 		assert.NotNil(s.T(), pemCollection)
 	})
 	s.Run("Failure_request", func() {
-		//setting momentarily to work in secure mode to get an error managed by the request method
-		http.DefaultTransport.(*http.Transport).TLSClientConfig.InsecureSkipVerify = false
-		pemCollection, err := fireflyConnector.SynchronousRequestCertificate(&request)
+		//setting momentarily to work in secure mode to get an error managed by the request method. The TLS config is
+		//replaced, rather than changed, and the client of the connector dropped, since connectors copy the TLS config
+		//into the transport they share when sending their first request
+		insecureConfig := http.DefaultTransport.(*http.Transport).TLSClientConfig
+		http.DefaultTransport.(*http.Transport).TLSClientConfig = &tls.Config{}
+		fireflyConnector.client = nil
+		pemCollection, err := fireflyConnector.SynchronousRequestCertificate(ctx, &request)
 		//putting back to insecure mode
-		http.DefaultTransport.(*http.Transport).TLSClientConfig.InsecureSkipVerify = true
+		http.DefaultTransport.(*http.Transport).TLSClientConfig = insecureConfig
+		fireflyConnector.client = nil
 
 		if assert.Errorf(s.T(), err, "expected to get an error but was gotten the certificate") {
 			assert.ErrorContains(s.T(), err, "tls: failed to verify certificate: x509: certificate signed by unknown authority")
@@ -367,7 +392,7 @@ func (s *ConnectorSuite) TestSynchronousRequestCertificate_CSR_Provided() {
 		//copying the request to keep the original without changes
 		requestWithoutSubject := certificate.Request{}
 
-		pemCollection, err := fireflyConnector.SynchronousRequestCertificate(&requestWithoutSubject)
+		pemCollection, err := fireflyConnector.SynchronousRequestCertificate(ctx, &requestWithoutSubject)
 
 		if assert.Errorf(s.T(), err, "expected to get an error but was gotten the certificate") {
 			assert.ErrorContains(s.T(), err, "unexpected status code on Venafi Firefly. Status: ")
@@ -378,7 +403,7 @@ func (s *ConnectorSuite) TestSynchronousRequestCertificate_CSR_Provided() {
 		//copying the connector to keep the original without changes
 		fireflyConnectorFailingPolicy := fireflyConnector
 		fireflyConnectorFailingPolicy.zone = TestingFailingPolicyName
-		pemCollection, err := fireflyConnectorFailingPolicy.SynchronousRequestCertificate(&request)
+		pemCollection, err := fireflyConnectorFailingPolicy.SynchronousRequestCertificate(ctx, &request)
 
 		if assert.Errorf(s.T(), err, "expected to get an error but was gotten the certificate") {
 			assert.ErrorContains(s.T(), err, "x509: malformed certificate")
@@ -410,6 +435,111 @@ func (s *ConnectorSuite) TestSupportSynchronousRequestCertificate() {
 	assert.True(s.T(), fireflyConnector.SupportSynchronousRequestCertificate())
 }
 
+func (s *ConnectorSuite) TestRequestAndRetrieveCertificate() {
+	ctx := context.Background()
+	//polling faster for testing purposes
+	defaultPollInterval := pollInterval
+	pollInterval = 10 * time.Millisecond
+	defer func() { pollInterval = defaultPollInterval }()
+
+	newConnector := func(policyName string) *Connector {
+		fireflyConnector, err := NewConnector(s.fireflyServer.serverURL, policyName, false, nil)
+		assert.Nil(s.T(), err, fmt.Errorf("error creating firefly connector: %w", err).Error())
+		err = fireflyConnector.Authenticate(ctx, s.createCredFlowAuth())
+		assert.Nil(s.T(), err, fmt.Errorf("error getting access token: %w", err).Error())
+		return fireflyConnector
+	}
+	newRequest := func() *certificate.Request {
+		return &certificate.Request{
+			Subject: pkix.Name{
+				CommonName: "vcert.test.vfidev.com",
+			},
+			KeyType:   certificate.KeyTypeRSA,
+			KeyLength: certificate.DefaultRSAlength,
+		}
+	}
+
+	s.Run("Issued", func() {
+		fireflyConnector := newConnector(TestingPolicyName)
+		request := newRequest()
+		requestID, err := fireflyConnector.RequestCertificate(ctx, request)
+		assert.Nil(s.T(), err, fmt.Errorf("error requesting the certificate: %w", err).Error())
+		assert.NotEmpty(s.T(), requestID)
+		assert.Equal(s.T(), requestID, request.PickupID)
+
+		pemCollection, err := fireflyConnector.RetrieveCertificate(ctx, request)
+		assert.Nil(s.T(), err, fmt.Errorf("error retrieving the certificate: %w", err).Error())
+		if assert.NotNil(s.T(), pemCollection) {
+			assert.NotEmpty(s.T(), pemCollection.Certificate)
+			assert.Equal(s.T(), pk_test, pemCollection.PrivateKey)
+		}
+	})
+	s.Run("Pending_without_timeout", func() {
+		fireflyConnector := newConnector(TestingPendingPolicyName)
+		request := newRequest()
+		requestID, err := fireflyConnector.RequestCertificate(ctx, request)
+		assert.Nil(s.T(), err, fmt.Errorf("error requesting the certificate: %w", err).Error())
+
+		pemCollection, err := fireflyConnector.RetrieveCertificate(ctx, request)
+		var pendingErr endpoint.ErrCertificatePending
+		if assert.ErrorAs(s.T(), err, &pendingErr) {
+			assert.Equal(s.T(), requestID, pendingErr.CertificateID)
+		}
+		assert.Nil(s.T(), pemCollection)
+
+		//the certificate is issued on the next retrieval
+		pemCollection, err = fireflyConnector.RetrieveCertificate(ctx, request)
+		assert.Nil(s.T(), err, fmt.Errorf("error retrieving the certificate: %w", err).Error())
+		assert.NotNil(s.T(), pemCollection)
+	})
+	s.Run("Pending_with_timeout", func() {
+		fireflyConnector := newConnector(TestingPendingPolicyName)
+		request := newRequest()
+		_, err := fireflyConnector.RequestCertificate(ctx, request)
+		assert.Nil(s.T(), err, fmt.Errorf("error requesting the certificate: %w", err).Error())
+
+		request.Timeout = time.Second
+		pemCollection, err := fireflyConnector.RetrieveCertificate(ctx, request)
+		assert.Nil(s.T(), err, fmt.Errorf("error retrieving the certificate: %w", err).Error())
+		assert.NotNil(s.T(), pemCollection)
+	})
+	s.Run("Synchronous_pending", func() {
+		fireflyConnector := newConnector(TestingPendingPolicyName)
+		request := newRequest()
+		request.Timeout = time.Second
+		pemCollection, err := fireflyConnector.SynchronousRequestCertificate(ctx, request)
+		assert.Nil(s.T(), err, fmt.Errorf("error requesting the certificate: %w", err).Error())
+		assert.NotNil(s.T(), pemCollection)
+		assert.NotEmpty(s.T(), request.PickupID)
+	})
+	s.Run("Rejected", func() {
+		fireflyConnector := newConnector(TestingRejectedPolicyName)
+		request := newRequest()
+		request.Timeout = time.Second
+		pemCollection, err := fireflyConnector.SynchronousRequestCertificate(ctx, request)
+		var rejectedErr endpoint.ErrCertificateRejected
+		if assert.ErrorAs(s.T(), err, &rejectedErr) {
+			assert.Equal(s.T(), request.PickupID, rejectedErr.CertificateID)
+		}
+		assert.Nil(s.T(), pemCollection)
+	})
+	s.Run("Unknown_request_id", func() {
+		fireflyConnector := newConnector(TestingPolicyName)
+		request := newRequest()
+		request.PickupID = "unknown"
+		pemCollection, err := fireflyConnector.RetrieveCertificate(ctx, request)
+		var notFoundErr *ErrCertNotFound
+		assert.ErrorAs(s.T(), err, &notFoundErr)
+		assert.Nil(s.T(), pemCollection)
+	})
+	s.Run("No_request_id", func() {
+		fireflyConnector := newConnector(TestingPolicyName)
+		pemCollection, err := fireflyConnector.RetrieveCertificate(ctx, newRequest())
+		assert.ErrorIs(s.T(), err, verror.UserDataError)
+		assert.Nil(s.T(), pemCollection)
+	})
+}
+
 func (s *ConnectorSuite) TestGetCertificateRequest() {
 	fireflyConnector, err := NewConnector(s.fireflyServer.serverURL, TestingPolicyName, false, nil)
 	assert.Nil(s.T(), err, fmt.Errorf("error creating firefly connector: %w", err).Error())
@@ -501,3 +631,52 @@ func (s *ConnectorSuite) TestGetCertificateRequest() {
 		assert.Equal(s.T(), csr_test, certReq.CSR)
 	})
 }
+
+func (s *ConnectorSuite) TestPolicies() {
+	ctx := context.Background()
+	fireflyConnector, err := NewConnector(s.fireflyServer.serverURL, TestingPolicyName, false, nil)
+	assert.Nil(s.T(), err, fmt.Errorf("error creating firefly connector: %w", err).Error())
+	err = fireflyConnector.Authenticate(ctx, s.createCredFlowAuth())
+	assert.Nil(s.T(), err, fmt.Errorf("error getting access token: %w", err).Error())
+
+	s.Run("List", func() {
+		names, err := fireflyConnector.ListPolicies(ctx)
+		assert.Nil(s.T(), err, fmt.Errorf("error listing policies: %w", err).Error())
+		assert.Contains(s.T(), names, TestingPolicyName)
+	})
+	s.Run("Get", func() {
+		ps, err := fireflyConnector.GetPolicy(ctx, TestingPolicyName)
+		assert.Nil(s.T(), err, fmt.Errorf("error getting policy: %w", err).Error())
+		if assert.NotNil(s.T(), ps) {
+			assert.Equal(s.T(), 90, *ps.Policy.MaxValidDays)
+			assert.Equal(s.T(), []string{"RSA", "EC"}, ps.Policy.KeyPair.KeyTypes)
+			assert.Equal(s.T(), []int{2048, 3072}, ps.Policy.KeyPair.RsaKeySizes)
+			assert.Equal(s.T(), []string{"P256"}, ps.Policy.KeyPair.EllipticCurves)
+			assert.Equal(s.T(), []string{"Venafi"}, ps.Policy.Subject.Orgs)
+			assert.Nil(s.T(), ps.Policy.Subject.Countries)
+			assert.True(s.T(), *ps.Policy.SubjectAltNames.DnsAllowed)
+			assert.False(s.T(), *ps.Policy.SubjectAltNames.EmailAllowed)
+			assert.Equal(s.T(), "US", *ps.Default.Subject.Country)
+			assert.Equal(s.T(), "EC", *ps.Default.KeyPair.KeyType)
+			assert.Equal(s.T(), "P256", *ps.Default.KeyPair.EllipticCurve)
+		}
+	})
+	s.Run("Get_unknown", func() {
+		ps, err := fireflyConnector.GetPolicy(ctx, "unknownPolicy")
+		assert.ErrorIs(s.T(), err, verror.ZoneNotFoundError)
+		assert.Nil(s.T(), ps)
+	})
+	s.Run("Request_unknown", func() {
+		unknownConnector := *fireflyConnector
+		unknownConnector.zone = "unknownPolicy"
+		request := certificate.Request{
+			Subject: pkix.Name{CommonName: "vcert.test.vfidev.com"},
+			KeyType: certificate.KeyTypeRSA,
+		}
+		pemCollection, err := unknownConnector.SynchronousRequestCertificate(ctx, &request)
+		if assert.ErrorIs(s.T(), err, verror.ZoneNotFoundError) {
+			assert.ErrorContains(s.T(), err, TestingPolicyName)
+		}
+		assert.Nil(s.T(), pemCollection)
+	})
+}
@@ -17,6 +17,7 @@
 package firefly
 
 import (
+	"context"
 	"encoding/json"
 	"fmt"
 	"net/http"
@@ -24,6 +25,7 @@ import (
 	"time"
 
 	"github.com/Venafi/vcert/v5/pkg/endpoint"
+	"github.com/Venafi/vcert/v5/pkg/util"
 	"github.com/Venafi/vcert/v5/pkg/verror"
 	"golang.org/x/oauth2"
 )
@@ -39,10 +41,10 @@ type DeviceCred struct {
 	ExpiresIn       int64  `json:"expires_in"`
 }
 
-func (c *Connector) getDeviceAccessToken(auth *endpoint.Authentication) (token *oauth2.Token, err error) {
+func (c *Connector) getDeviceAccessToken(ctx context.Context, auth *endpoint.Authentication) (token *oauth2.Token, err error) {
 
 	//requesting the device code
-	devCred, err := c.requestDeviceCode(auth)
+	devCred, err := c.requestDeviceCode(ctx, auth)
 
 	if err != nil {
 		return
@@ -60,14 +62,14 @@ func (c *Connector) getDeviceAccessToken(auth *endpoint.Authentication) (token *
 	fmt.Printf("Please open de following URL in your web browser:\n\n%v\n\n and then enter the code:\n\n%v\n\nIt will expire in %dm and %ds\n\n", verificationURL, devCred.UserCode, devCred.ExpiresIn/60, devCred.ExpiresIn%60)
 
 	//waiting for the user authorization
-	token, err = c.waitForDeviceAuthorization(devCred, auth)
+	token, err = c.waitForDeviceAuthorization(ctx, devCred, auth)
 	if err == nil {
 		fmt.Println("Successfully authorized device.")
 	}
 	return
 }
 
-func (c *Connector) requestDeviceCode(auth *endpoint.Authentication) (*DeviceCred, error) {
+func (c *Connector) requestDeviceCode(ctx context.Context, auth *endpoint.Authentication) (*DeviceCred, error) {
 	data := url.Values{
 		"client_id": {auth.ClientId},
 	}
@@ -83,7 +85,7 @@ func (c *Connector) requestDeviceCode(auth *endpoint.Authentication) (*DeviceCre
 		data.Add("audience", auth.IdentityProvider.Audience)
 	}
 
-	statusCode, status, body, err := c.request("POST", urlResource(auth.IdentityProvider.DeviceURL), data)
+	statusCode, status, body, err := c.request(ctx, "POST", urlResource(auth.IdentityProvider.DeviceURL), data)
 
 	if err != nil {
 		return nil, err
@@ -116,7 +118,7 @@ func parseDeviceCodeRequestData(b []byte) (*DeviceCred, error) {
 	return &data, nil
 }
 
-func (c *Connector) waitForDeviceAuthorization(devCred *DeviceCred, auth *endpoint.Authentication) (*oauth2.Token, error) {
+func (c *Connector) waitForDeviceAuthorization(ctx context.Context, devCred *DeviceCred, auth *endpoint.Authentication) (*oauth2.Token, error) {
 
 	data := url.Values{"client_id": {auth.ClientId},
 		"device_code": {devCred.DeviceCode},
@@ -131,7 +133,7 @@ func (c *Connector) waitForDeviceAuthorization(devCred *DeviceCred, auth *endpoi
 	//polling the authorization
 	for {
 		//requesting the authorization
-		statusCode, _, body, err := c.request("POST", urlResource(auth.IdentityProvider.TokenURL), data)
+		statusCode, _, body, err := c.request(ctx, "POST", urlResource(auth.IdentityProvider.TokenURL), data)
 		if err != nil {
 			return nil, err
 		}
@@ -147,10 +149,14 @@ func (c *Connector) waitForDeviceAuthorization(devCred *DeviceCred, auth *endpoi
 		//verifying the error gotten
 		switch GetDevAuthStatusFromError(err) {
 		case AuthorizationPending:
-			time.Sleep(time.Duration(devCred.Interval) * time.Second)
+			if err = util.Sleep(ctx, time.Duration(devCred.Interval)*time.Second); err != nil {
+				return nil, err
+			}
 		case SlowDown:
 			devCred.Interval += 5
-			time.Sleep(time.Duration(devCred.Interval) * time.Second)
+			if err = util.Sleep(ctx, time.Duration(devCred.Interval)*time.Second); err != nil {
+				return nil, err
+			}
 		case AccessDenied:
 			return nil, fmt.Errorf("the access from device was denied by the user")
 		case ExpiredToken:
@@ -18,12 +18,11 @@ package firefly
 
 import (
 	"bytes"
-	"crypto/tls"
+	"context"
 	"encoding/json"
 	"fmt"
 	"io"
 	"log"
-	"net"
 	"net/http"
 	"net/url"
 	"regexp"
@@ -32,6 +31,7 @@ import (
 
 	"github.com/Venafi/vcert/v5/pkg/certificate"
 	"github.com/Venafi/vcert/v5/pkg/endpoint"
+	"github.com/Venafi/vcert/v5/pkg/util"
 	"github.com/Venafi/vcert/v5/pkg/verror"
 )
 
@@ -42,10 +42,19 @@ const (
 	urlResourceCertificateRequestCSR urlResource = "v1/certificatesigningrequest"
 
 	scopesSeparator = " "
+
+	// Status of a certificate request. Requests are issued right away unless the policy requires a long-running issuance
+	requestStatusPending  = "PENDING"
+	requestStatusIssued   = "ISSUED"
+	requestStatusFailed   = "FAILED"
+	requestStatusRejected = "REJECTED"
 )
 
 var (
 	rsaSizes = map[int]bool{certificate.DefaultRSAlength: true, 3072: true, 4096: true}
+
+	// pollInterval is the wait between two retrievals of a pending certificate request
+	pollInterval = 2 * time.Second
 )
 
 type certificateRequest struct {
@@ -74,6 +83,9 @@ type AlternativeNames struct {
 }
 
 type certificateRequestResponse struct {
+	RequestID        string `json:"requestId,omitempty"`
+	Status           string `json:"status,omitempty"`
+	StatusDetails    string `json:"statusDetails,omitempty"`
 	CertificateChain string `json:"certificateChain,omitempty"`
 	PrivateKey       string `json:"privateKey"`
 }
@@ -81,7 +93,7 @@ type certificateRequestResponse struct {
 // GenerateRequest should generate a CertificateRequest based on the zone configuration when the csrOrigin was
 // set to LocalGeneratedCSR but given that is not supported by Firefly yet, then it's only validating if the CSR
 // was provided when the csrOrigin was set to UserProvidedCSR
-func (c *Connector) GenerateRequest(_ *endpoint.ZoneConfiguration, req *certificate.Request) (err error) {
+func (c *Connector) GenerateRequest(ctx context.Context, _ *endpoint.ZoneConfiguration, req *certificate.Request) (err error) {
 	switch req.CsrOrigin {
 	case certificate.LocalGeneratedCSR:
 		return fmt.Errorf("local generated CSR it's not supported by Firefly yet")
@@ -92,13 +104,16 @@ func (c *Connector) GenerateRequest(_ *endpoint.ZoneConfiguration, req *certific
 		return nil
 
 	case certificate.ServiceGeneratedCSR:
+		if req.KeyType.IsPostQuantum() {
+			return fmt.Errorf("%w: %s keys are only supported with a user provided CSR", verror.UserDataError, req.KeyType.String())
+		}
 		return nil
 	default:
 		return fmt.Errorf("%w: unrecognised req.CsrOrigin %v", verror.UserDataError, req.CsrOrigin)
 	}
 }
 
-func (c *Connector) request(method string, resource urlResource, data interface{}) (statusCode int, statusText string, body []byte, err error) {
+func (c *Connector) request(ctx context.Context, method string, resource urlResource, data interface{}) (statusCode int, statusText string, body []byte, err error) {
 
 	resourceUrl := string(resource)
 
@@ -129,8 +144,7 @@ func (c *Connector) request(method string, resource urlResource, data interface{
 		}
 	}
 
-	r, _ := http.NewRequest(method, resourceUrl, payload)
-	r.Close = true
+	r, _ := http.NewRequestWithContext(ctx, method, resourceUrl, payload)
 	if c.accessToken != "" {
 		r.Header.Add("Authorization", fmt.Sprintf("Bearer %s", c.accessToken))
 	}
@@ -174,30 +188,7 @@ func (c *Connector) getHTTPClient() *http.Client {
 	if c.client != nil {
 		return c.client
 	}
-	var netTransport = &http.Transport{
-		Proxy: http.ProxyFromEnvironment,
-		DialContext: (&net.Dialer{
-			Timeout:   30 * time.Second,
-			KeepAlive: 30 * time.Second,
-			DualStack: true,
-		}).DialContext,
-		MaxIdleConns:          100,
-		IdleConnTimeout:       90 * time.Second,
-		TLSHandshakeTimeout:   10 * time.Second,
-		ExpectContinueTimeout: 1 * time.Second,
-	}
-	tlsConfig := http.DefaultTransport.(*http.Transport).TLSClientConfig
-	/* #nosec */
-	if c.trust != nil {
-		if tlsConfig == nil {
-			tlsConfig = &tls.Config{}
-		} else {
-			tlsConfig = tlsConfig.Clone()
-		}
-		tlsConfig.RootCAs = c.trust
-	}
-
-	netTransport.TLSClientConfig = tlsConfig
+	netTransport := util.NewTransportWithTLSConfig(c.transport, c.proxy, c.trust, c.tlsConfig)
 	c.client = &http.Client{
 		Timeout:   time.Second * 30,
 		Transport: netTransport,
@@ -207,7 +198,7 @@ func (c *Connector) getHTTPClient() *http.Client {
 
 func parseCertificateRequestResult(httpStatusCode int, httpStatus string, body []byte) (*certificateRequestResponse, error) {
 	switch httpStatusCode {
-	case http.StatusOK:
+	case http.StatusOK, http.StatusAccepted:
 		return parseCertificateRequestData(body)
 	default:
 		respError, err := NewResponseError(body)
@@ -232,3 +223,8 @@ func parseCertificateRequestData(b []byte) (*certificateRequestResponse, error)
 func (c *Connector) getURL(resource urlResource) string {
 	return fmt.Sprintf("%s%s", c.baseURL, resource)
 }
+
+// getCertificateRequestStatusUrl returns the resource to retrieve the certificate request with the given ID
+func getCertificateRequestStatusUrl(requestID string) urlResource {
+	return urlResource(fmt.Sprintf("%s/%s", urlResourceCertificateRequest, url.PathEscape(requestID)))
+}
@@ -2,21 +2,34 @@ package firefly
 
 import (
 	"encoding/json"
+	"fmt"
 	"log"
 	"net/http"
 	"net/http/httptest"
 	"reflect"
 	"strings"
+	"sync"
 )
 
 const (
-	TestingPolicyName        = "myPolicy"
-	TestingFailingPolicyName = "failingPolicy" // used to return a corrupted certificate
+	TestingPolicyName         = "myPolicy"
+	TestingFailingPolicyName  = "failingPolicy"  // used to return a corrupted certificate
+	TestingPendingPolicyName  = "pendingPolicy"  // used to return a pending certificate request
+	TestingRejectedPolicyName = "rejectedPolicy" // used to return a pending certificate request which is rejected later
+
+	// testingPendingPolls is the number of times a pending certificate request is reported as pending before being issued
+	testingPendingPolls = 1
 )
 
 func newFireflyMockServer() *FireflyMockServer {
 	certReqPath := "/v1/certificaterequest"
 	certSignReqPath := "/v1/certificatesigningrequest"
+	policiesPath := "/" + string(urlResourcePolicies)
+	mockServer := &FireflyMockServer{
+		certReqPath:     certReqPath,
+		certSignReqPath: certSignReqPath,
+		requests:        make(map[string]*mockCertificateRequest),
+	}
 	server := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		//getting the AccessToken
 		accessToken := getAccessToken(r)
@@ -31,22 +44,24 @@ func newFireflyMockServer() *FireflyMockServer {
 			return
 		}
 
-		switch strings.TrimSpace(r.URL.Path) {
-		case certReqPath:
-			processCertificateRequest(w, r)
-		case certSignReqPath:
-			processCertificateSigningRequest(w, r)
+		path := strings.TrimSpace(r.URL.Path)
+		switch {
+		case path == certReqPath:
+			mockServer.processCertificateRequest(w, r)
+		case path == certSignReqPath:
+			mockServer.processCertificateSigningRequest(w, r)
+		case path == policiesPath:
+			mockServer.processPolicies(w, r)
+		case strings.HasPrefix(path, certReqPath+"/"):
+			mockServer.processCertificateRequestStatus(w, r, strings.TrimPrefix(path, certReqPath+"/"))
 		default:
 			http.NotFoundHandler().ServeHTTP(w, r)
 		}
 	}))
-	//creating and returning the Firefly server
-	return &FireflyMockServer{
-		server:          server,
-		serverURL:       server.URL,
-		certReqPath:     certReqPath,
-		certSignReqPath: certSignReqPath,
-	}
+	//completing and returning the Firefly server
+	mockServer.server = server
+	mockServer.serverURL = server.URL
+	return mockServer
 }
 
 type FireflyMockServer struct {
@@ -54,6 +69,122 @@ type FireflyMockServer struct {
 	serverURL       string
 	certReqPath     string
 	certSignReqPath string
+
+	mu       sync.Mutex
+	lastID   int
+	requests map[string]*mockCertificateRequest
+}
+
+// mockCertificateRequest holds the state of a certificate request submitted to the mock server
+type mockCertificateRequest struct {
+	policyName   string
+	pendingPolls int
+	privateKey   string
+}
+
+// addRequest stores a new certificate request and returns its ID
+func (m *FireflyMockServer) addRequest(policyName string, privateKey string) string {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.lastID++
+	id := fmt.Sprintf("request-%d", m.lastID)
+	pendingPolls := 0
+	if policyName == TestingPendingPolicyName || policyName == TestingRejectedPolicyName {
+		pendingPolls = testingPendingPolls
+	}
+	m.requests[id] = &mockCertificateRequest{policyName: policyName, pendingPolls: pendingPolls, privateKey: privateKey}
+	return id
+}
+
+// writeCertificateRequest writes the state of the certificate request with the given ID
+func (m *FireflyMockServer) writeCertificateRequest(w http.ResponseWriter, id string, submitted bool) {
+	m.mu.Lock()
+	req, found := m.requests[id]
+	var pending bool
+	if found {
+		pending = req.pendingPolls > 0
+		if pending && !submitted {
+			req.pendingPolls--
+		}
+	}
+	m.mu.Unlock()
+	if !found {
+		writeError(w, http.StatusNotFound, "not-found", "the certificate request was not found")
+		return
+	}
+
+	response := certificateRequestResponse{RequestID: id}
+	statusCode := http.StatusOK
+	switch {
+	case pending:
+		response.Status = requestStatusPending
+		if submitted {
+			statusCode = http.StatusAccepted
+		}
+	case req.policyName == TestingRejectedPolicyName:
+		response.Status = requestStatusRejected
+		response.StatusDetails = "the certificate request was rejected by the approver"
+	default:
+		response.Status = requestStatusIssued
+		response.CertificateChain = cert_test
+		if req.policyName == TestingFailingPolicyName {
+			response.CertificateChain = cert_test_corrupted
+		}
+		response.PrivateKey = req.privateKey
+	}
+
+	//Headers must be set before the status and the body are written to the response.
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(statusCode)
+
+	jsonResp, err := json.Marshal(response)
+	if err != nil {
+		log.Fatalf("Error happened in JSON marshal. Err: %s", err)
+	}
+	w.Write(jsonResp)
+}
+
+func isTestingPolicyName(policyName string) bool {
+	switch policyName {
+	case TestingPolicyName, TestingFailingPolicyName, TestingPendingPolicyName, TestingRejectedPolicyName:
+		return true
+	}
+	return false
+}
+
+func (m *FireflyMockServer) processPolicies(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.NotFoundHandler().ServeHTTP(w, r)
+		return
+	}
+
+	response := policiesResponse{}
+	for _, name := range []string{TestingPolicyName, TestingFailingPolicyName, TestingPendingPolicyName, TestingRejectedPolicyName} {
+		response.Policies = append(response.Policies, fireflyPolicy{
+			Name:           name,
+			ValidityPeriod: "P90D",
+			KeyAlgorithm: policyKeyAlgorithm{
+				AllowedValues: []string{"RSA_2048", "RSA_3072", "EC_P256"},
+				DefaultValue:  "EC_P256",
+			},
+			Subject: policySubject{
+				CommonName:   policyAttribute{Type: "REQUIRED", AllowedValues: []string{attributeAnyValue}},
+				Organization: policyAttribute{Type: "OPTIONAL", AllowedValues: []string{"Venafi"}, DefaultValues: []string{"Venafi"}},
+				Country:      policyAttribute{Type: "OPTIONAL", AllowedValues: []string{attributeAnyValue}, DefaultValues: []string{"US"}},
+			},
+			SANs: policySANs{
+				DNSNames:       policyAttribute{Type: "OPTIONAL", AllowedValues: []string{attributeAnyValue}},
+				EmailAddresses: policyAttribute{Type: attributeForbidden},
+			},
+		})
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	jsonResp, err := json.Marshal(response)
+	if err != nil {
+		log.Fatalf("Error happened in JSON marshal. Err: %s", err)
+	}
+	w.Write(jsonResp)
 }
 
 func getAccessToken(r *http.Request) string {
@@ -70,7 +201,7 @@ func getAccessToken(r *http.Request) string {
 	return accessToken
 }
 
-func processCertificateRequest(w http.ResponseWriter, r *http.Request) {
+func (m *FireflyMockServer) processCertificateRequest(w http.ResponseWriter, r *http.Request) {
 	var certReq certificateRequest
 
 	if r.Method != http.MethodPost {
@@ -98,33 +229,15 @@ func processCertificateRequest(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	if certReq.PolicyName != TestingPolicyName && certReq.PolicyName != TestingFailingPolicyName {
+	if !isTestingPolicyName(certReq.PolicyName) {
 		writeError(w, http.StatusBadRequest, "invalid-policyName", "the policy name is not valid")
 		return
 	}
 
-	var certChain = cert_test
-	if certReq.PolicyName == TestingFailingPolicyName {
-		certChain = cert_test_corrupted
-	}
-
-	//Headers must be set before the status and the body are written to the response.
-	w.Header().Set("Content-Type", "application/json")
-	w.WriteHeader(http.StatusOK)
-
-	certificateRequestResponse := certificateRequestResponse{
-		CertificateChain: certChain,
-		PrivateKey:       pk_test,
-	}
-
-	jsonResp, err := json.Marshal(certificateRequestResponse)
-	if err != nil {
-		log.Fatalf("Error happened in JSON marshal. Err: %s", err)
-	}
-	w.Write(jsonResp)
+	m.writeCertificateRequest(w, m.addRequest(certReq.PolicyName, pk_test), true)
 }
 
-func processCertificateSigningRequest(w http.ResponseWriter, r *http.Request) {
+func (m *FireflyMockServer) processCertificateSigningRequest(w http.ResponseWriter, r *http.Request) {
 	var certReq certificateRequest
 
 	if r.Method != http.MethodPost {
@@ -152,27 +265,19 @@ func processCertificateSigningRequest(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	if certReq.PolicyName != TestingPolicyName && certReq.PolicyName != TestingFailingPolicyName {
+	if !isTestingPolicyName(certReq.PolicyName) {
 		writeError(w, http.StatusBadRequest, "invalid-policyName", "the policy name is not valid")
 		return
 	}
 
-	var certChain = cert_test
-	if certReq.PolicyName == TestingFailingPolicyName {
-		certChain = cert_test_corrupted
-	}
-
-	//Headers must be set before the status and the body are written to the response.
-	w.Header().Set("Content-Type", "application/json")
-	w.WriteHeader(http.StatusOK)
+	m.writeCertificateRequest(w, m.addRequest(certReq.PolicyName, ""), true)
+}
 
-	certificateRequestResponse := certificateRequestResponse{
-		CertificateChain: certChain,
+func (m *FireflyMockServer) processCertificateRequestStatus(w http.ResponseWriter, r *http.Request, id string) {
+	if r.Method != http.MethodGet {
+		http.NotFoundHandler().ServeHTTP(w, r)
+		return
 	}
 
-	jsonResp, err := json.Marshal(certificateRequestResponse)
-	if err != nil {
-		log.Fatalf("Error happened in JSON marshal. Err: %s", err)
-	}
-	w.Write(jsonResp)
+	m.writeCertificateRequest(w, id, false)
 }
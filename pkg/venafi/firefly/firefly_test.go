@@ -17,6 +17,12 @@
 package firefly
 
 import (
+	"context"
+	"crypto/x509"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
 	"testing"
 )
 
@@ -26,3 +32,40 @@ func TestGetHttpClient(t *testing.T) {
 		t.Fatalf("Failed to get http client")
 	}
 }
+
+func TestRequestReusesConnection(t *testing.T) {
+	ctx := context.Background()
+	var connections int32
+	server := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write([]byte(`{}`))
+	}))
+	server.Config.ConnState = func(_ net.Conn, state http.ConnState) {
+		if state == http.StateNew {
+			atomic.AddInt32(&connections, 1)
+		}
+	}
+	server.StartTLS()
+	defer server.Close()
+
+	ca := x509.NewCertPool()
+	ca.AddCert(server.Certificate())
+	firefly, err := NewConnector(server.URL, "", false, ca)
+	if err != nil {
+		t.Fatal(err)
+	}
+	firefly.accessToken = "access-1"
+
+	for i := 0; i < 2; i++ {
+		statusCode, _, _, err := firefly.request(ctx, http.MethodGet, urlResourcePolicies, nil)
+		if err != nil {
+			t.Fatalf("request %d failed: %s", i+1, err)
+		}
+		if statusCode != http.StatusOK {
+			t.Fatalf("request %d: unexpected status code %d", i+1, statusCode)
+		}
+	}
+	if n := atomic.LoadInt32(&connections); n != 1 {
+		t.Fatalf("expected the second request to reuse the connection of the first one, but %d connections were opened", n)
+	}
+}
@@ -0,0 +1,271 @@
+/*
+ * Copyright 2023 Venafi, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *  http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package firefly
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/sosodev/duration"
+	"go.uber.org/zap"
+
+	"github.com/Venafi/vcert/v5/pkg/policy"
+	"github.com/Venafi/vcert/v5/pkg/util"
+	"github.com/Venafi/vcert/v5/pkg/verror"
+)
+
+const (
+	urlResourcePolicies urlResource = "v1/policies"
+
+	// attributeForbidden is the type of the subject and SAN attributes that cannot be requested
+	attributeForbidden = "FORBIDDEN"
+	// attributeAnyValue is the allowed value of the subject and SAN attributes that accept any value
+	attributeAnyValue = ".*"
+)
+
+// errPoliciesNotSupported is returned when the Firefly instance does not expose its policies
+var errPoliciesNotSupported = errors.New("listing policies is not supported by this Firefly instance")
+
+type policiesResponse struct {
+	Policies []fireflyPolicy `json:"policies"`
+}
+
+// fireflyPolicy is an issuance policy configured in a Firefly instance
+type fireflyPolicy struct {
+	Name           string             `json:"name"`
+	ValidityPeriod string             `json:"validityPeriod,omitempty"`
+	KeyAlgorithm   policyKeyAlgorithm `json:"keyAlgorithm"`
+	Subject        policySubject      `json:"subject"`
+	SANs           policySANs         `json:"sans"`
+}
+
+type policyKeyAlgorithm struct {
+	AllowedValues []string `json:"allowedValues,omitempty"`
+	DefaultValue  string   `json:"defaultValue,omitempty"`
+}
+
+// policyAttribute holds the values allowed for a subject or SAN attribute. Type is one of REQUIRED, OPTIONAL,
+// FORBIDDEN or LOCKED
+type policyAttribute struct {
+	Type          string   `json:"type,omitempty"`
+	AllowedValues []string `json:"allowedValues,omitempty"`
+	DefaultValues []string `json:"defaultValues,omitempty"`
+}
+
+type policySubject struct {
+	CommonName   policyAttribute `json:"commonName"`
+	Country      policyAttribute `json:"country"`
+	Locality     policyAttribute `json:"locality"`
+	Organization policyAttribute `json:"organization"`
+	OrgUnits     policyAttribute `json:"organizationalUnit"`
+	State        policyAttribute `json:"stateOrProvince"`
+}
+
+type policySANs struct {
+	DNSNames       policyAttribute `json:"dnsNames"`
+	IPAddresses    policyAttribute `json:"ipAddresses"`
+	EmailAddresses policyAttribute `json:"rfc822Names"`
+	URIs           policyAttribute `json:"uniformResourceIdentifiers"`
+}
+
+// ListPolicies returns the names of the policies configured in the Firefly instance
+func (c *Connector) ListPolicies(ctx context.Context) ([]string, error) {
+	policies, err := c.getPolicies(ctx)
+	if err != nil {
+		return nil, err
+	}
+	names := make([]string, 0, len(policies))
+	for _, p := range policies {
+		names = append(names, p.Name)
+	}
+	return names, nil
+}
+
+// GetPolicy returns the policy of the Firefly instance with the given name as a policy specification
+func (c *Connector) GetPolicy(ctx context.Context, name string) (*policy.PolicySpecification, error) {
+	policies, err := c.getPolicies(ctx)
+	if err != nil {
+		return nil, err
+	}
+	p, err := findPolicy(policies, name)
+	if err != nil {
+		return nil, err
+	}
+	return p.toPolicySpecification(), nil
+}
+
+func (c *Connector) getPolicies(ctx context.Context) ([]fireflyPolicy, error) {
+	statusCode, status, body, err := c.request(ctx, "GET", urlResourcePolicies, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	switch statusCode {
+	case http.StatusOK:
+		var data policiesResponse
+		err = json.Unmarshal(body, &data)
+		if err != nil {
+			return nil, fmt.Errorf("%w: failed to parse policies: %v", verror.ServerError, err)
+		}
+		return data.Policies, nil
+	case http.StatusNotFound:
+		return nil, errPoliciesNotSupported
+	default:
+		respError, err := NewResponseError(body)
+		if err != nil {
+			return nil, err
+		}
+		return nil, fmt.Errorf("unexpected status code on Venafi Firefly. Status: %s: %w", status, respError)
+	}
+}
+
+// validatePolicyName checks the policy set as zone is configured in the Firefly instance, so a wrong name is
+// reported with the available ones before any certificate is requested. Instances that do not expose their
+// policies are left to validate the request themselves
+func (c *Connector) validatePolicyName(ctx context.Context) error {
+	if c.zone == "" {
+		return fmt.Errorf("%w: a policy name is required to request a certificate from Firefly", verror.UserDataError)
+	}
+
+	policies, err := c.getPolicies(ctx)
+	if errors.Is(err, errPoliciesNotSupported) {
+		zap.L().Debug("skipping policy validation", fieldPlatform, zap.Error(err))
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("failed to retrieve policies: %w", err)
+	}
+	_, err = findPolicy(policies, c.zone)
+	return err
+}
+
+func findPolicy(policies []fireflyPolicy, name string) (*fireflyPolicy, error) {
+	names := make([]string, 0, len(policies))
+	for i, p := range policies {
+		if p.Name == name {
+			return &policies[i], nil
+		}
+		names = append(names, p.Name)
+	}
+	return nil, fmt.Errorf("%w: policy %q not found in Firefly. Available policies: %s", verror.ZoneNotFoundError,
+		name, strings.Join(names, ", "))
+}
+
+// toPolicySpecification returns the restrictions and defaults of the policy. Attributes accepting any value are
+// left unrestricted
+func (p *fireflyPolicy) toPolicySpecification() *policy.PolicySpecification {
+	ps := &policy.PolicySpecification{
+		Policy: &policy.Policy{
+			Subject: &policy.Subject{
+				Orgs:       p.Subject.Organization.restrictedValues(),
+				OrgUnits:   p.Subject.OrgUnits.restrictedValues(),
+				Localities: p.Subject.Locality.restrictedValues(),
+				States:     p.Subject.State.restrictedValues(),
+				Countries:  p.Subject.Country.restrictedValues(),
+			},
+			KeyPair: &policy.KeyPair{},
+			SubjectAltNames: &policy.SubjectAltNames{
+				DnsAllowed:   p.SANs.DNSNames.allowed(),
+				IpAllowed:    p.SANs.IPAddresses.allowed(),
+				EmailAllowed: p.SANs.EmailAddresses.allowed(),
+				UriAllowed:   p.SANs.URIs.allowed(),
+			},
+		},
+		Default: &policy.Default{
+			Subject: &policy.DefaultSubject{
+				Org:      p.Subject.Organization.defaultValue(),
+				OrgUnits: p.Subject.OrgUnits.DefaultValues,
+				Locality: p.Subject.Locality.defaultValue(),
+				State:    p.Subject.State.defaultValue(),
+				Country:  p.Subject.Country.defaultValue(),
+			},
+		},
+	}
+
+	if p.ValidityPeriod != "" {
+		d, err := duration.Parse(p.ValidityPeriod)
+		if err != nil {
+			zap.L().Warn("could not parse validity period of policy", fieldPlatform, zap.String("policy", p.Name),
+				zap.String("validityPeriod", p.ValidityPeriod), zap.Error(err))
+		} else {
+			days := int(d.ToTimeDuration() / (24 * time.Hour))
+			ps.Policy.MaxValidDays = &days
+		}
+	}
+
+	for _, algorithm := range p.KeyAlgorithm.AllowedValues {
+		keyType, param := parseKeyAlgorithm(algorithm)
+		switch keyType {
+		case "RSA":
+			if size, err := strconv.Atoi(param); err == nil {
+				ps.Policy.KeyPair.RsaKeySizes = append(ps.Policy.KeyPair.RsaKeySizes, size)
+			}
+		case "EC":
+			ps.Policy.KeyPair.EllipticCurves = append(ps.Policy.KeyPair.EllipticCurves, param)
+		default:
+			continue
+		}
+		if !util.ArrayContainsString(ps.Policy.KeyPair.KeyTypes, keyType) {
+			ps.Policy.KeyPair.KeyTypes = append(ps.Policy.KeyPair.KeyTypes, keyType)
+		}
+	}
+
+	if p.KeyAlgorithm.DefaultValue != "" {
+		keyType, param := parseKeyAlgorithm(p.KeyAlgorithm.DefaultValue)
+		ps.Default.KeyPair = &policy.DefaultKeyPair{KeyType: &keyType}
+		if size, err := strconv.Atoi(param); keyType == "RSA" && err == nil {
+			ps.Default.KeyPair.RsaKeySize = &size
+		} else if keyType == "EC" {
+			ps.Default.KeyPair.EllipticCurve = &param
+		}
+	}
+	return ps
+}
+
+// parseKeyAlgorithm splits a Firefly key algorithm, i.e. RSA_2048 or EC_P256, into its key type and size or curve
+func parseKeyAlgorithm(algorithm string) (string, string) {
+	keyType, param, _ := strings.Cut(strings.ToUpper(algorithm), "_")
+	return keyType, param
+}
+
+// restrictedValues returns the values allowed for the attribute, or nil when any value is allowed
+func (a policyAttribute) restrictedValues() []string {
+	for _, v := range a.AllowedValues {
+		if v == attributeAnyValue || v == "*" {
+			return nil
+		}
+	}
+	return a.AllowedValues
+}
+
+func (a policyAttribute) allowed() *bool {
+	allowed := a.Type != attributeForbidden
+	return &allowed
+}
+
+func (a policyAttribute) defaultValue() *string {
+	if len(a.DefaultValues) == 0 {
+		return nil
+	}
+	return &a.DefaultValues[0]
+}
@@ -35,10 +35,19 @@ const (
 	TPP
 	// Firefly represents the Firefly platform type
 	Firefly
+	// ACME represents any certificate authority implementing the ACME protocol (RFC 8555)
+	ACME
+	// EST represents any certificate authority implementing the EST protocol (RFC 7030)
+	EST
+	// SCEP represents any certificate authority implementing the SCEP protocol (RFC 8894)
+	SCEP
 
 	// String representations of the Platform types
+	strPlatformACME    = "ACME"
+	strPlatformEST     = "EST"
 	strPlatformFake    = "FAKE"
 	strPlatformFirefly = "FIREFLY"
+	strPlatformSCEP    = "SCEP"
 	strPlatformTPP     = "TPP"
 	strPlatformVaaS    = "VAAS"
 	strPlatformUnknown = "Unknown"
@@ -59,10 +68,16 @@ const (
 // String returns a string representation of this object
 func (p Platform) String() string {
 	switch p {
+	case ACME:
+		return strPlatformACME
+	case EST:
+		return strPlatformEST
 	case Fake:
 		return strPlatformFake
 	case Firefly:
 		return strPlatformFirefly
+	case SCEP:
+		return strPlatformSCEP
 	case TPP:
 		return strPlatformTPP
 	case TLSPCloud:
@@ -91,10 +106,16 @@ func (p *Platform) UnmarshalYAML(value *yaml.Node) error {
 
 func GetPlatformType(platformString string) Platform {
 	switch strings.ToUpper(platformString) {
+	case strPlatformACME:
+		return ACME
+	case strPlatformEST:
+		return EST
 	case strPlatformFake:
 		return Fake
 	case strPlatformFirefly, strPlatformOIDC:
 		return Firefly
+	case strPlatformSCEP:
+		return SCEP
 	case strPlatformTPP, strPlatformTLSPDC:
 		return TPP
 	case strPlatformVaaS, strPlatformTLSPC:
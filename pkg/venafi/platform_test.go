@@ -42,6 +42,9 @@ func (s *PlatformSuite) SetupTest() {
 		{ct: TPP, strValue: strPlatformTPP},
 		{ct: TLSPCloud, strValue: strPlatformVaaS},
 		{ct: Firefly, strValue: strPlatformFirefly},
+		{ct: ACME, strValue: strPlatformACME},
+		{ct: EST, strValue: strPlatformEST},
+		{ct: SCEP, strValue: strPlatformSCEP},
 	}
 
 	s.testYaml = `---
@@ -0,0 +1,314 @@
+/*
+ * Copyright 2023 Venafi, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *  http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package scep
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/pem"
+	"fmt"
+	"net/http"
+	"time"
+
+	"go.uber.org/zap"
+
+	"github.com/Venafi/vcert/v5/pkg/certificate"
+	"github.com/Venafi/vcert/v5/pkg/endpoint"
+	"github.com/Venafi/vcert/v5/pkg/policy"
+	"github.com/Venafi/vcert/v5/pkg/util"
+	"github.com/Venafi/vcert/v5/pkg/venafi"
+	"github.com/Venafi/vcert/v5/pkg/verror"
+)
+
+const (
+	// DefaultTimeout is the maximum time to wait for a certificate pending manual approval when the request has no timeout
+	DefaultTimeout = 5 * time.Minute
+)
+
+var (
+	fieldPlatform = zap.String("platform", venafi.SCEP.String())
+
+	errNotSupported = fmt.Errorf("operation is not supported by the SCEP connector")
+)
+
+// Connector contains the base data needed to communicate with a SCEP (RFC 8894) server
+type Connector struct {
+	baseURL   string
+	password  string
+	verbose   bool
+	trust     *x509.CertPool
+	client    *http.Client
+	proxy     util.ProxyConfig
+	transport util.TransportConfig
+	tlsConfig *tls.Config
+	zone      string // holds the optional CA identifier
+
+	pollInterval time.Duration
+}
+
+// NewConnector creates a new SCEP Connector object used to communicate with the SCEP server at the given url,
+// such as http://scep.example.com/cgi-bin/pkiclient.exe
+func NewConnector(url string, zone string, verbose bool, trust *x509.CertPool) (*Connector, error) {
+	if url == "" {
+		return nil, fmt.Errorf("%w: an url is required to communicate with a SCEP server", verror.UserDataError)
+	}
+	return &Connector{
+		baseURL:      normalizeURL(url),
+		zone:         zone,
+		verbose:      verbose,
+		trust:        trust,
+		pollInterval: defaultPollInterval,
+	}, nil
+}
+
+func (c *Connector) GetType() endpoint.ConnectorType {
+	return endpoint.ConnectorTypeSCEP
+}
+
+// SetZone sets the CA identifier, for SCEP servers hosting more than one CA
+func (c *Connector) SetZone(zone string) {
+	c.zone = zone
+}
+
+func (c *Connector) SetHTTPClient(client *http.Client) {
+	c.client = client
+}
+
+// SetProxy sets the proxy the requests to the SCEP server are sent through, instead of the one of the environment variables.
+// It has no effect on the client set with SetHTTPClient, nor once the connector has sent its first request
+func (c *Connector) SetProxy(proxy util.ProxyConfig) {
+	c.proxy = proxy
+}
+
+// SetTransportConfig tunes the HTTP transport of the requests to the SCEP server, shared with the other connectors built with the
+// same settings. It has no effect on the client set with SetHTTPClient, nor once the connector has sent its first request
+func (c *Connector) SetTransportConfig(config util.TransportConfig) {
+	c.transport = config
+}
+
+// SetTLSConfig sets the TLS settings of the requests to the SCEP server, like the client certificate, instead of the ones of
+// http.DefaultTransport. The trust bundle of the connector replaces its root CAs. It has no effect on the client set
+// with SetHTTPClient, nor once the connector has sent its first request
+func (c *Connector) SetTLSConfig(config *tls.Config) {
+	c.tlsConfig = config
+}
+
+// Authenticate sets the challenge password added to the CSRs. It is optional: SCEP servers may issue certificates
+// without a challenge, or after manual approval
+func (c *Connector) Authenticate(ctx context.Context, auth *endpoint.Authentication) error {
+	if auth == nil {
+		return nil
+	}
+	c.password = auth.Password
+	return nil
+}
+
+// Ping checks the CA certificates can be retrieved from the SCEP server
+func (c *Connector) Ping(ctx context.Context) error {
+	_, err := c.getCACertificates(ctx)
+	return err
+}
+
+// GetCACertificates returns the CA certificate distributed by the SCEP server, along with its RA certificates
+func (c *Connector) GetCACertificates(ctx context.Context) ([]*x509.Certificate, error) {
+	return c.getCACertificates(ctx)
+}
+
+// ReadZoneConfiguration returns an empty zone configuration. SCEP servers do not publish policies
+func (c *Connector) ReadZoneConfiguration(ctx context.Context) (config *endpoint.ZoneConfiguration, err error) {
+	return endpoint.NewZoneConfiguration(), nil
+}
+
+// GenerateRequest creates the private key and CSR, with the challenge password when one is set.
+// The CSR must be generated locally with an RSA key: SCEP messages are signed with the key of the request,
+// and the issued certificate is encrypted for it
+func (c *Connector) GenerateRequest(ctx context.Context, _ *endpoint.ZoneConfiguration, req *certificate.Request) (err error) {
+	switch req.CsrOrigin {
+	case certificate.LocalGeneratedCSR:
+	case certificate.UserProvidedCSR:
+		return fmt.Errorf("%w: user provided CSR is not supported by SCEP, the CSR must be generated locally", verror.UserDataError)
+	case certificate.ServiceGeneratedCSR:
+		return fmt.Errorf("%w: service generated CSR is not supported by SCEP", verror.UserDataError)
+	default:
+		return fmt.Errorf("%w: unrecognised req.CsrOrigin %v", verror.UserDataError, req.CsrOrigin)
+	}
+	if req.KeyType != certificate.KeyTypeRSA {
+		return fmt.Errorf("%w: key type %s is not supported by SCEP, only RSA keys are", verror.UserDataError, req.KeyType.String())
+	}
+
+	err = req.GeneratePrivateKey()
+	if err != nil {
+		return err
+	}
+	err = req.GenerateCSR()
+	if err != nil || c.password == "" {
+		return err
+	}
+
+	block, _ := pem.Decode(req.GetCSR())
+	csr, err := addChallengePassword(block.Bytes, c.password, req.PrivateKey)
+	if err != nil {
+		return fmt.Errorf("could not add the challenge password to the CSR: %w", err)
+	}
+	return req.SetCSR(csr)
+}
+
+// SupportSynchronousRequestCertificate returns if the connector support synchronous calls to request a certificate.
+func (c *Connector) SupportSynchronousRequestCertificate() bool {
+	return true
+}
+
+// SynchronousRequestCertificate enrolls the CSR with a SCEP PKCSReq message and returns the issued certificate
+// along with its chain. Requests pending manual approval are polled until the timeout of the request expires
+func (c *Connector) SynchronousRequestCertificate(ctx context.Context, req *certificate.Request) (certificates *certificate.PEMCollection, err error) {
+	zap.L().Info("requesting certificate", zap.String("cn", req.Subject.CommonName), fieldPlatform)
+	certificates, err = c.requestCertificate(ctx, req)
+	if err != nil {
+		zap.L().Error("failed to enroll certificate", fieldPlatform, zap.Error(err))
+		return nil, err
+	}
+	zap.L().Info("successfully requested certificate", fieldPlatform)
+	return certificates, nil
+}
+
+func (c *Connector) requestCertificate(ctx context.Context, req *certificate.Request) (*certificate.PEMCollection, error) {
+	if req.PrivateKey == nil {
+		return nil, fmt.Errorf("%w: SCEP requires the private key of the request", verror.UserDataError)
+	}
+	block, _ := pem.Decode(req.GetCSR())
+	if block == nil {
+		return nil, fmt.Errorf("%w: could not decode the CSR", verror.UserDataError)
+	}
+	csr, err := x509.ParseCertificateRequest(block.Bytes)
+	if err != nil {
+		return nil, fmt.Errorf("%w: could not parse the CSR: %v", verror.UserDataError, err)
+	}
+
+	timeout := req.Timeout
+	if timeout == 0 {
+		timeout = DefaultTimeout
+	}
+	issued, caCerts, err := c.enroll(ctx, csr, req.PrivateKey, timeout)
+	if err != nil {
+		return nil, err
+	}
+
+	// The chain is built from the leaf to the root. The output order is applied when the certificate is written
+	chainOption := certificate.ChainOptionRootLast
+	if req.ChainOption == certificate.ChainOptionIgnore {
+		chainOption = certificate.ChainOptionIgnore
+	}
+	return certificate.PEMCollectionFromBytes(certificate.BuildChain(issued, caCerts), chainOption)
+}
+
+// RetrieveCertificate is not supported. SCEP servers only return certificates to the requests they answer
+func (c *Connector) RetrieveCertificate(ctx context.Context, req *certificate.Request) (certificates *certificate.PEMCollection, err error) {
+	return nil, fmt.Errorf("%w: certificate %s cannot be retrieved, SCEP servers do not retrieve certificates", verror.UserDataError, req.PickupID)
+}
+
+func (c *Connector) RenewCertificate(ctx context.Context, _ *certificate.RenewalRequest) (requestID string, err error) {
+	return "", errNotSupported
+}
+
+func (c *Connector) GetZonesByParent(ctx context.Context, _ string) ([]string, error) {
+	return nil, errNotSupported
+}
+
+func (c *Connector) ReadPolicyConfiguration(ctx context.Context) (policy *endpoint.Policy, err error) {
+	return nil, errNotSupported
+}
+
+func (c *Connector) ResetCertificate(ctx context.Context, _ *certificate.Request, _ bool) (err error) {
+	return errNotSupported
+}
+
+func (c *Connector) RequestCertificate(ctx context.Context, _ *certificate.Request) (requestID string, err error) {
+	return "", errNotSupported
+}
+
+func (c *Connector) IsCSRServiceGenerated(ctx context.Context, _ *certificate.Request) (bool, error) {
+	return false, nil
+}
+
+func (c *Connector) RevokeCertificate(ctx context.Context, _ *certificate.RevocationRequest) error {
+	return errNotSupported
+}
+
+func (c *Connector) RetireCertificate(ctx context.Context, _ *certificate.RetireRequest) error {
+	return errNotSupported
+}
+
+func (c *Connector) ImportCertificate(ctx context.Context, _ *certificate.ImportRequest) (*certificate.ImportResponse, error) {
+	return nil, errNotSupported
+}
+
+func (c *Connector) ListCertificates(ctx context.Context, _ endpoint.Filter) ([]certificate.CertificateInfo, error) {
+	return nil, errNotSupported
+}
+
+func (c *Connector) SetPolicy(ctx context.Context, _ string, _ *policy.PolicySpecification) (string, error) {
+	return "", errNotSupported
+}
+
+func (c *Connector) GetPolicy(ctx context.Context, _ string) (*policy.PolicySpecification, error) {
+	return nil, errNotSupported
+}
+
+func (c *Connector) RequestSSHCertificate(ctx context.Context, _ *certificate.SshCertRequest) (response *certificate.SshCertificateObject, err error) {
+	return nil, errNotSupported
+}
+
+func (c *Connector) RetrieveSSHCertificate(ctx context.Context, _ *certificate.SshCertRequest) (response *certificate.SshCertificateObject, err error) {
+	return nil, errNotSupported
+}
+
+func (c *Connector) RenewSSHCertificate(ctx context.Context, _ *certificate.SshCertRequest) (response *certificate.SshCertificateObject, err error) {
+	return nil, errNotSupported
+}
+
+func (c *Connector) RevokeSSHCertificate(ctx context.Context, _ *certificate.SshCertRequest) error {
+	return errNotSupported
+}
+
+func (c *Connector) RetrieveSshConfig(ctx context.Context, _ *certificate.SshCaTemplateRequest) (*certificate.SshConfig, error) {
+	return nil, errNotSupported
+}
+
+func (c *Connector) SearchCertificates(ctx context.Context, _ *certificate.SearchRequest) (*certificate.CertSearchResponse, error) {
+	return nil, errNotSupported
+}
+
+func (c *Connector) SearchCertificate(ctx context.Context, _ string, _ string, _ *certificate.Sans, _ time.Duration) (*certificate.CertificateInfo, error) {
+	return nil, errNotSupported
+}
+
+func (c *Connector) RetrieveAvailableSSHTemplates(ctx context.Context) ([]certificate.SshAvaliableTemplate, error) {
+	return nil, errNotSupported
+}
+
+func (c *Connector) RetrieveCertificateMetaData(ctx context.Context, _ string) (*certificate.CertificateMetaData, error) {
+	return nil, errNotSupported
+}
+
+func (c *Connector) RetrieveSystemVersion(ctx context.Context) (string, error) {
+	return "", errNotSupported
+}
+
+func (c *Connector) WriteLog(ctx context.Context, _ *endpoint.LogRequest) error {
+	return errNotSupported
+}
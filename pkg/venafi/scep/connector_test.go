@@ -0,0 +1,259 @@
+/*
+ * Copyright 2023 Venafi, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *  http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package scep
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"errors"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/suite"
+
+	"github.com/Venafi/vcert/v5/pkg/certificate"
+	"github.com/Venafi/vcert/v5/pkg/endpoint"
+	"github.com/Venafi/vcert/v5/pkg/verror"
+)
+
+type ConnectorSuite struct {
+	suite.Suite
+	server *scepMockServer
+}
+
+func (s *ConnectorSuite) SetupTest() {
+	s.server = newSCEPMockServer()
+}
+
+func (s *ConnectorSuite) TearDownTest() {
+	s.server.server.Close()
+}
+
+func TestConnectorSuite(t *testing.T) {
+	suite.Run(t, new(ConnectorSuite))
+}
+
+func (s *ConnectorSuite) newConnector(zone string, challenge string) *Connector {
+	ctx := context.Background()
+	connector, err := NewConnector(s.server.server.URL+"/scep", zone, false, nil)
+	s.Require().NoError(err)
+	s.Require().NoError(connector.Authenticate(ctx, &endpoint.Authentication{Password: challenge}))
+	connector.pollInterval = 10 * time.Millisecond
+	return connector
+}
+
+func (s *ConnectorSuite) newRequest(connector *Connector, cn string, chainOption certificate.ChainOption) *certificate.Request {
+	ctx := context.Background()
+	req := &certificate.Request{
+		Subject:     pkix.Name{CommonName: cn},
+		DNSNames:    []string{cn},
+		CsrOrigin:   certificate.LocalGeneratedCSR,
+		KeyLength:   2048,
+		ChainOption: chainOption,
+		Timeout:     10 * time.Second,
+	}
+	s.Require().NoError(connector.GenerateRequest(ctx, nil, req))
+	return req
+}
+
+func parsePEMCertificate(s *ConnectorSuite, data string) *x509.Certificate {
+	block, _ := pem.Decode([]byte(data))
+	s.Require().NotNil(block)
+	cert, err := x509.ParseCertificate(block.Bytes)
+	s.Require().NoError(err)
+	return cert
+}
+
+func (s *ConnectorSuite) TestNewConnector() {
+	_, err := NewConnector("", "", false, nil)
+	s.Error(err)
+
+	connector, err := NewConnector("scep.example.com/cgi-bin/pkiclient.exe", "", false, nil)
+	s.Require().NoError(err)
+	s.Equal(endpoint.ConnectorTypeSCEP, connector.GetType())
+	s.Equal("https://scep.example.com/cgi-bin/pkiclient.exe", connector.baseURL)
+	s.Equal("https://scep.example.com/cgi-bin/pkiclient.exe?operation=GetCACaps", connector.getURL(operationGetCACaps, ""))
+
+	connector, err = NewConnector(" http://scep.example.com/scep?profile=mdm ", "MDM CA", false, nil)
+	s.Require().NoError(err)
+	s.Equal("http://scep.example.com/scep?profile=mdm&message=MDM+CA&operation=GetCACert", connector.getURL(operationGetCACert, "MDM CA"))
+}
+
+func (s *ConnectorSuite) TestGenerateRequest() {
+	ctx := context.Background()
+	connector := s.newConnector("", "secret")
+
+	req := &certificate.Request{Subject: pkix.Name{CommonName: "device.example.com"}, CsrOrigin: certificate.ServiceGeneratedCSR}
+	s.Error(connector.GenerateRequest(ctx, nil, req))
+
+	req.CsrOrigin = certificate.UserProvidedCSR
+	s.Error(connector.GenerateRequest(ctx, nil, req))
+
+	req.CsrOrigin = certificate.LocalGeneratedCSR
+	req.KeyType = certificate.KeyTypeECDSA
+	s.Error(connector.GenerateRequest(ctx, nil, req))
+
+	req.KeyType = certificate.KeyTypeRSA
+	s.Require().NoError(connector.GenerateRequest(ctx, nil, req))
+	block, _ := pem.Decode(req.GetCSR())
+	s.Require().NotNil(block)
+	csr, err := x509.ParseCertificateRequest(block.Bytes)
+	s.Require().NoError(err)
+	s.NoError(csr.CheckSignature())
+	s.Equal("device.example.com", csr.Subject.CommonName)
+	s.Equal("secret", getChallengePassword(block.Bytes))
+}
+
+func (s *ConnectorSuite) TestGetCACertificates() {
+	ctx := context.Background()
+	connector := s.newConnector("Test CA", "")
+	s.NoError(connector.Ping(ctx))
+	s.Equal("Test CA", s.server.caIdentifier)
+
+	certs, err := connector.GetCACertificates(ctx)
+	s.Require().NoError(err)
+	s.Require().Len(certs, 1)
+	s.True(certs[0].Equal(s.server.caCert))
+
+	s.server.ra = true
+	certs, err = connector.GetCACertificates(ctx)
+	s.Require().NoError(err)
+	s.Require().Len(certs, 2)
+	recipient, issuer := selectRecipient(certs)
+	s.True(recipient.Equal(s.server.raCert))
+	s.True(issuer.Equal(s.server.caCert))
+}
+
+func (s *ConnectorSuite) TestSynchronousRequestCertificate() {
+	ctx := context.Background()
+	s.server.challenge = "secret"
+	connector := s.newConnector("", "secret")
+	req := s.newRequest(connector, "device.example.com", certificate.ChainOptionRootLast)
+
+	pcc, err := connector.SynchronousRequestCertificate(ctx, req)
+	s.Require().NoError(err)
+	cert := parsePEMCertificate(s, pcc.Certificate)
+	s.Equal("device.example.com", cert.Subject.CommonName)
+	s.Equal(req.PrivateKey.Public(), cert.PublicKey)
+	s.Require().Len(pcc.Chain, 1)
+	s.True(parsePEMCertificate(s, pcc.Chain[0]).Equal(s.server.caCert))
+	s.Equal([]string{http.MethodPost}, s.server.methods)
+
+	req = s.newRequest(connector, "device.example.com", certificate.ChainOptionIgnore)
+	pcc, err = connector.SynchronousRequestCertificate(ctx, req)
+	s.Require().NoError(err)
+	s.Empty(pcc.Chain)
+}
+
+func (s *ConnectorSuite) TestSynchronousRequestCertificateRA() {
+	ctx := context.Background()
+	s.server.ra = true
+	s.server.legacy = true
+	connector := s.newConnector("", "")
+	req := s.newRequest(connector, "router.example.com", certificate.ChainOptionRootLast)
+
+	pcc, err := connector.SynchronousRequestCertificate(ctx, req)
+	s.Require().NoError(err)
+	s.Equal("router.example.com", parsePEMCertificate(s, pcc.Certificate).Subject.CommonName)
+	s.Require().Len(pcc.Chain, 1)
+	s.True(parsePEMCertificate(s, pcc.Chain[0]).Equal(s.server.caCert))
+	// Without GetCACaps, the messages are sent with GET
+	s.Equal([]string{http.MethodGet}, s.server.methods)
+}
+
+func (s *ConnectorSuite) TestSynchronousRequestCertificateWrongChallenge() {
+	ctx := context.Background()
+	s.server.challenge = "secret"
+	connector := s.newConnector("", "wrong")
+	req := s.newRequest(connector, "device.example.com", certificate.ChainOptionRootLast)
+
+	_, err := connector.SynchronousRequestCertificate(ctx, req)
+	s.Require().Error(err)
+	s.True(errors.Is(err, verror.UserDataError))
+	s.Contains(err.Error(), "badRequest")
+}
+
+func (s *ConnectorSuite) TestSynchronousRequestCertificatePending() {
+	ctx := context.Background()
+	s.server.pending = 2
+	connector := s.newConnector("", "")
+	req := s.newRequest(connector, "device.example.com", certificate.ChainOptionRootLast)
+
+	pcc, err := connector.SynchronousRequestCertificate(ctx, req)
+	s.Require().NoError(err)
+	s.Equal("device.example.com", parsePEMCertificate(s, pcc.Certificate).Subject.CommonName)
+	s.Equal(2, s.server.polls)
+}
+
+func (s *ConnectorSuite) TestSynchronousRequestCertificateTimeout() {
+	ctx := context.Background()
+	s.server.pending = 1000
+	connector := s.newConnector("", "")
+	req := s.newRequest(connector, "device.example.com", certificate.ChainOptionRootLast)
+	req.Timeout = 50 * time.Millisecond
+
+	_, err := connector.SynchronousRequestCertificate(ctx, req)
+	s.Require().Error(err)
+	s.True(errors.Is(err, verror.ServerTemporaryUnavailableError))
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	req.Timeout = time.Minute
+	_, err = connector.SynchronousRequestCertificate(ctx, req)
+	s.True(errors.Is(err, context.Canceled))
+}
+
+func (s *ConnectorSuite) TestSynchronousRequestCertificateUntrustedResponse() {
+	ctx := context.Background()
+	connector := s.newConnector("", "")
+	req := s.newRequest(connector, "device.example.com", certificate.ChainOptionRootLast)
+
+	s.server.rogue = true
+
+	_, err := connector.SynchronousRequestCertificate(ctx, req)
+	s.Require().Error(err)
+	s.Contains(err.Error(), "not signed by the CA")
+}
+
+func (s *ConnectorSuite) TestSynchronousRequestCertificateNoKey() {
+	ctx := context.Background()
+	connector := s.newConnector("", "")
+	key, _ := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	req := &certificate.Request{Subject: pkix.Name{CommonName: "device.example.com"}}
+	req.SetPrivateKey(key)
+	s.Require().NoError(req.GenerateCSR())
+	req.PrivateKey = nil
+
+	_, err := connector.SynchronousRequestCertificate(ctx, req)
+	s.True(errors.Is(err, verror.UserDataError))
+}
+
+func (s *ConnectorSuite) TestRetrieveCertificate() {
+	ctx := context.Background()
+	connector := s.newConnector("", "")
+	_, err := connector.RetrieveCertificate(ctx, &certificate.Request{PickupID: "ABC"})
+	s.True(errors.Is(err, verror.UserDataError))
+
+	_, err = connector.RenewCertificate(ctx, &certificate.RenewalRequest{})
+	s.Error(err)
+}
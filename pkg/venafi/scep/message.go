@@ -0,0 +1,206 @@
+/*
+ * Copyright 2023 Venafi, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *  http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package scep
+
+import (
+	"crypto"
+	"crypto/rand"
+	"crypto/x509"
+	"encoding/asn1"
+	"fmt"
+
+	"github.com/Venafi/vcert/v5/pkg/verror"
+)
+
+// The message types, statuses and failure reasons of RFC 8894 section 3.2.1
+const (
+	messageTypeCertRep  = "3"
+	messageTypePKCSReq  = "19"
+	messageTypeCertPoll = "20"
+
+	pkiStatusSuccess = "0"
+	pkiStatusFailure = "2"
+	pkiStatusPending = "3"
+
+	nonceSize = 16
+)
+
+var (
+	oidSCEPMessageType    = asn1.ObjectIdentifier{2, 16, 840, 1, 113733, 1, 9, 2}
+	oidSCEPPKIStatus      = asn1.ObjectIdentifier{2, 16, 840, 1, 113733, 1, 9, 3}
+	oidSCEPFailInfo       = asn1.ObjectIdentifier{2, 16, 840, 1, 113733, 1, 9, 4}
+	oidSCEPSenderNonce    = asn1.ObjectIdentifier{2, 16, 840, 1, 113733, 1, 9, 5}
+	oidSCEPRecipientNonce = asn1.ObjectIdentifier{2, 16, 840, 1, 113733, 1, 9, 6}
+	oidSCEPTransactionID  = asn1.ObjectIdentifier{2, 16, 840, 1, 113733, 1, 9, 7}
+
+	failInfoDescriptions = map[string]string{
+		"0": "badAlg: unrecognized or unsupported algorithm",
+		"1": "badMessageCheck: integrity check failed",
+		"2": "badRequest: transaction not permitted or supported",
+		"3": "badTime: the signingTime attribute was not sufficiently close to the system time",
+		"4": "badCertId: no certificate could be identified matching the provided criteria",
+	}
+)
+
+// issuerAndSubject is the content of a CertPoll message, identifying the pending request
+type issuerAndSubject struct {
+	Issuer  asn1.RawValue
+	Subject asn1.RawValue
+}
+
+// pkiMessage is a request to the SCEP server. Its content is encrypted for the recipient, the CA or its RA,
+// and the message is signed with the key of the request
+type pkiMessage struct {
+	messageType   string
+	transactionID string
+	senderNonce   []byte
+	content       []byte
+}
+
+// certRep is the response of the SCEP server to a PKCSReq or CertPoll message
+type certRep struct {
+	transactionID  string
+	pkiStatus      string
+	failInfo       string
+	recipientNonce []byte
+	// envelope holds the encrypted certificates, on success
+	envelope []byte
+}
+
+func newPKIMessage(messageType string, transactionID string, content []byte) (*pkiMessage, error) {
+	nonce := make([]byte, nonceSize)
+	_, err := rand.Read(nonce)
+	if err != nil {
+		return nil, err
+	}
+	return &pkiMessage{messageType: messageType, transactionID: transactionID, senderNonce: nonce, content: content}, nil
+}
+
+// marshal returns the DER encoding of the message, encrypted for recipient and signed with key and its certificate
+func (m *pkiMessage) marshal(recipient *x509.Certificate, useAES bool, signerCert *x509.Certificate, key crypto.Signer) ([]byte, error) {
+	enveloped, err := envelope(m.content, recipient, useAES)
+	if err != nil {
+		return nil, err
+	}
+
+	var attrs []attribute
+	for _, a := range []struct {
+		t     asn1.ObjectIdentifier
+		value interface{}
+	}{
+		{oidSCEPTransactionID, m.transactionID},
+		{oidSCEPMessageType, m.messageType},
+		{oidSCEPSenderNonce, m.senderNonce},
+	} {
+		attr, err := newAttribute(a.t, a.value)
+		if err != nil {
+			return nil, err
+		}
+		attrs = append(attrs, attr)
+	}
+	return signData(enveloped, attrs, signerCert, key)
+}
+
+// parseCertRep parses and verifies the response of the SCEP server. It must be signed by one of the CA certificates,
+// or by a certificate they issued
+func parseCertRep(der []byte, caCerts []*x509.Certificate) (*certRep, error) {
+	message, err := parseSignedData(der, caCerts)
+	if err != nil {
+		return nil, fmt.Errorf("%w: invalid SCEP response: %v", verror.ServerError, err)
+	}
+	if !isSignedByCA(message, caCerts) {
+		return nil, fmt.Errorf("%w: the SCEP response is not signed by the CA", verror.ServerError)
+	}
+
+	rep := &certRep{envelope: message.content}
+	var messageType string
+	for _, a := range []struct {
+		t        asn1.ObjectIdentifier
+		value    interface{}
+		required bool
+	}{
+		{oidSCEPMessageType, &messageType, true},
+		{oidSCEPTransactionID, &rep.transactionID, true},
+		{oidSCEPPKIStatus, &rep.pkiStatus, true},
+		{oidSCEPRecipientNonce, &rep.recipientNonce, true},
+		{oidSCEPFailInfo, &rep.failInfo, false},
+	} {
+		found, err := message.get(a.t, a.value)
+		if err != nil {
+			return nil, fmt.Errorf("%w: invalid attribute %s in the SCEP response: %v", verror.ServerError, a.t, err)
+		}
+		if !found && a.required {
+			return nil, fmt.Errorf("%w: the SCEP response has no attribute %s", verror.ServerError, a.t)
+		}
+	}
+	if messageType != messageTypeCertRep {
+		return nil, fmt.Errorf("%w: unexpected SCEP message type %s", verror.ServerError, messageType)
+	}
+	return rep, nil
+}
+
+// check returns an error when the response does not answer the request, or reports a failure
+func (r *certRep) check(request *pkiMessage) error {
+	if r.transactionID != request.transactionID {
+		return fmt.Errorf("%w: the SCEP response has the transaction ID %q instead of %q", verror.ServerError, r.transactionID, request.transactionID)
+	}
+	if string(r.recipientNonce) != string(request.senderNonce) {
+		return fmt.Errorf("%w: the recipient nonce of the SCEP response does not match the request", verror.ServerError)
+	}
+
+	switch r.pkiStatus {
+	case pkiStatusSuccess, pkiStatusPending:
+		return nil
+	case pkiStatusFailure:
+		description, found := failInfoDescriptions[r.failInfo]
+		if !found {
+			description = fmt.Sprintf("failInfo %q", r.failInfo)
+		}
+		return fmt.Errorf("%w: the SCEP server rejected the request: %s", verror.UserDataError, description)
+	default:
+		return fmt.Errorf("%w: unexpected SCEP pkiStatus %q", verror.ServerError, r.pkiStatus)
+	}
+}
+
+// certificates decrypts the certificates of a successful response with key, the private key of the request
+func (r *certRep) certificates(key crypto.Signer) ([]*x509.Certificate, error) {
+	content, err := openEnvelope(r.envelope, key)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", verror.ServerError, err)
+	}
+	message, err := parseSignedData(content, nil)
+	if err != nil {
+		return nil, fmt.Errorf("%w: invalid SCEP certificates: %v", verror.ServerError, err)
+	}
+	if len(message.certificates) == 0 {
+		return nil, fmt.Errorf("%w: the SCEP response has no certificate", verror.ServerError)
+	}
+	return message.certificates, nil
+}
+
+func isSignedByCA(message *signedMessage, caCerts []*x509.Certificate) bool {
+	signer := message.signer
+	if signer == nil {
+		return false
+	}
+	for _, caCert := range caCerts {
+		if signer.Equal(caCert) || signer.CheckSignatureFrom(caCert) == nil {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,503 @@
+/*
+ * Copyright 2023 Venafi, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *  http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package scep
+
+import (
+	"bytes"
+	"crypto"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/des"
+	"crypto/rand"
+	"crypto/rsa"
+	_ "crypto/sha1" // registers crypto.SHA1
+	"crypto/sha256"
+	_ "crypto/sha512" // registers crypto.SHA512
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/asn1"
+	"errors"
+	"fmt"
+	"math/big"
+)
+
+var (
+	oidData          = asn1.ObjectIdentifier{1, 2, 840, 113549, 1, 7, 1}
+	oidSignedData    = asn1.ObjectIdentifier{1, 2, 840, 113549, 1, 7, 2}
+	oidEnvelopedData = asn1.ObjectIdentifier{1, 2, 840, 113549, 1, 7, 3}
+
+	oidAttributeContentType       = asn1.ObjectIdentifier{1, 2, 840, 113549, 1, 9, 3}
+	oidAttributeMessageDigest     = asn1.ObjectIdentifier{1, 2, 840, 113549, 1, 9, 4}
+	oidAttributeChallengePassword = asn1.ObjectIdentifier{1, 2, 840, 113549, 1, 9, 7}
+
+	oidDigestSHA1   = asn1.ObjectIdentifier{1, 3, 14, 3, 2, 26}
+	oidDigestSHA256 = asn1.ObjectIdentifier{2, 16, 840, 1, 101, 3, 4, 2, 1}
+	oidDigestSHA512 = asn1.ObjectIdentifier{2, 16, 840, 1, 101, 3, 4, 2, 3}
+
+	oidEncryptionRSA          = asn1.ObjectIdentifier{1, 2, 840, 113549, 1, 1, 1}
+	oidSignatureSHA256WithRSA = asn1.ObjectIdentifier{1, 2, 840, 113549, 1, 1, 11}
+	oidEncryptionAES128CBC    = asn1.ObjectIdentifier{2, 16, 840, 1, 101, 3, 4, 1, 2}
+	oidEncryptionAES256CBC    = asn1.ObjectIdentifier{2, 16, 840, 1, 101, 3, 4, 1, 42}
+	oidEncryptionDESEDE3CBC   = asn1.ObjectIdentifier{1, 2, 840, 113549, 3, 7}
+)
+
+var errMessageSignatureInvalid = errors.New("the signature of the SCEP message is invalid")
+
+// contentInfo is the outer structure of a PKCS#7 (RFC 2315) message
+type contentInfo struct {
+	ContentType asn1.ObjectIdentifier
+	Content     asn1.RawValue `asn1:"explicit,optional,tag:0"`
+}
+
+// signedData is the PKCS#7 structure of SCEP messages, whose content is the pkcsPKIEnvelope, and of the degenerate
+// certs-only messages returning certificates
+type signedData struct {
+	Version          int
+	DigestAlgorithms []pkix.AlgorithmIdentifier `asn1:"set"`
+	ContentInfo      contentInfo
+	Certificates     asn1.RawValue `asn1:"optional,tag:0"`
+	CRLs             asn1.RawValue `asn1:"optional,tag:1"`
+	SignerInfos      []signerInfo  `asn1:"set"`
+}
+
+type signerInfo struct {
+	Version                   int
+	IssuerAndSerialNumber     issuerAndSerial
+	DigestAlgorithm           pkix.AlgorithmIdentifier
+	AuthenticatedAttributes   asn1.RawValue `asn1:"optional,tag:0"`
+	DigestEncryptionAlgorithm pkix.AlgorithmIdentifier
+	EncryptedDigest           []byte
+}
+
+type issuerAndSerial struct {
+	Issuer       asn1.RawValue
+	SerialNumber *big.Int
+}
+
+// attribute is an authenticated attribute of a signerInfo, holding a single value
+type attribute struct {
+	Type  asn1.ObjectIdentifier
+	Value asn1.RawValue
+}
+
+// envelopedData holds the content of a SCEP message, encrypted for the recipient
+type envelopedData struct {
+	Version              int
+	RecipientInfos       []recipientInfo `asn1:"set"`
+	EncryptedContentInfo encryptedContentInfo
+}
+
+type recipientInfo struct {
+	Version                int
+	IssuerAndSerialNumber  issuerAndSerial
+	KeyEncryptionAlgorithm pkix.AlgorithmIdentifier
+	EncryptedKey           []byte
+}
+
+type encryptedContentInfo struct {
+	ContentType                asn1.ObjectIdentifier
+	ContentEncryptionAlgorithm pkix.AlgorithmIdentifier
+	EncryptedContent           asn1.RawValue `asn1:"optional,tag:0"`
+}
+
+// signedMessage is a parsed signedData: its content, the certificates it carries, its signer and its authenticated attributes
+type signedMessage struct {
+	content      []byte
+	certificates []*x509.Certificate
+	signer       *x509.Certificate
+	attributes   []attribute
+}
+
+// newAttribute returns the attribute of type t whose single value is the DER encoding of value
+func newAttribute(t asn1.ObjectIdentifier, value interface{}) (attribute, error) {
+	der, err := asn1.Marshal(value)
+	if err != nil {
+		return attribute{}, err
+	}
+	return attribute{Type: t, Value: asn1.RawValue{Class: asn1.ClassUniversal, Tag: asn1.TagSet, IsCompound: true, Bytes: der}}, nil
+}
+
+// get unmarshals the value of the attribute of type t into value. It returns false when there is no such attribute
+func (m *signedMessage) get(t asn1.ObjectIdentifier, value interface{}) (bool, error) {
+	for _, attr := range m.attributes {
+		if attr.Type.Equal(t) {
+			_, err := asn1.Unmarshal(attr.Value.Bytes, value)
+			return true, err
+		}
+	}
+	return false, nil
+}
+
+// signData returns a PKCS#7 signedData of content, signed by key along with attrs, the content type and the digest of
+// the content. The certificate of the signer is included in the message
+func signData(content []byte, attrs []attribute, signerCert *x509.Certificate, key crypto.Signer) ([]byte, error) {
+	digest := sha256.Sum256(content)
+	contentType, err := newAttribute(oidAttributeContentType, oidData)
+	if err != nil {
+		return nil, err
+	}
+	messageDigest, err := newAttribute(oidAttributeMessageDigest, digest[:])
+	if err != nil {
+		return nil, err
+	}
+	attrs = append([]attribute{contentType, messageDigest}, attrs...)
+
+	// The signature covers the DER encoding of the attributes as a SET OF, which asn1 sorts
+	attrsDER, err := asn1.MarshalWithParams(attrs, "set")
+	if err != nil {
+		return nil, err
+	}
+	var attrsSet asn1.RawValue
+	_, err = asn1.Unmarshal(attrsDER, &attrsSet)
+	if err != nil {
+		return nil, err
+	}
+	attrsDigest := sha256.Sum256(attrsDER)
+	signature, err := key.Sign(rand.Reader, attrsDigest[:], crypto.SHA256)
+	if err != nil {
+		return nil, fmt.Errorf("could not sign SCEP message: %w", err)
+	}
+
+	encapsulated, err := asn1.Marshal(content)
+	if err != nil {
+		return nil, err
+	}
+	sd := signedData{
+		Version:          1,
+		DigestAlgorithms: []pkix.AlgorithmIdentifier{{Algorithm: oidDigestSHA256, Parameters: asn1.NullRawValue}},
+		ContentInfo: contentInfo{
+			ContentType: oidData,
+			Content:     asn1.RawValue{Class: asn1.ClassContextSpecific, Tag: 0, IsCompound: true, Bytes: encapsulated},
+		},
+		Certificates: asn1.RawValue{Class: asn1.ClassContextSpecific, Tag: 0, IsCompound: true, Bytes: signerCert.Raw},
+		SignerInfos: []signerInfo{{
+			Version:               1,
+			IssuerAndSerialNumber: newIssuerAndSerial(signerCert),
+			DigestAlgorithm:       pkix.AlgorithmIdentifier{Algorithm: oidDigestSHA256, Parameters: asn1.NullRawValue},
+			AuthenticatedAttributes: asn1.RawValue{Class: asn1.ClassContextSpecific, Tag: 0, IsCompound: true,
+				Bytes: attrsSet.Bytes},
+			DigestEncryptionAlgorithm: pkix.AlgorithmIdentifier{Algorithm: oidEncryptionRSA, Parameters: asn1.NullRawValue},
+			EncryptedDigest:           signature,
+		}},
+	}
+	return marshalContentInfo(oidSignedData, sd)
+}
+
+// certsOnly returns a degenerate PKCS#7 signedData carrying certs, with no content nor signers
+func certsOnly(certs []*x509.Certificate) ([]byte, error) {
+	var raw []byte
+	for _, cert := range certs {
+		raw = append(raw, cert.Raw...)
+	}
+	sd := signedData{
+		Version:          1,
+		DigestAlgorithms: []pkix.AlgorithmIdentifier{},
+		ContentInfo:      contentInfo{ContentType: oidData},
+		Certificates:     asn1.RawValue{Class: asn1.ClassContextSpecific, Tag: 0, IsCompound: true, Bytes: raw},
+		SignerInfos:      []signerInfo{},
+	}
+	return marshalContentInfo(oidSignedData, sd)
+}
+
+// parseSignedData parses a PKCS#7 signedData. When it has a signer, its signature is verified with the certificate
+// matching it, among the ones of the message and trusted
+func parseSignedData(der []byte, trusted []*x509.Certificate) (*signedMessage, error) {
+	var sd signedData
+	err := unmarshalContentInfo(der, oidSignedData, &sd)
+	if err != nil {
+		return nil, err
+	}
+
+	message := &signedMessage{}
+	if len(sd.Certificates.Bytes) > 0 {
+		message.certificates, err = x509.ParseCertificates(sd.Certificates.Bytes)
+		if err != nil {
+			return nil, fmt.Errorf("could not parse PKCS#7 certificates: %w", err)
+		}
+	}
+	if len(sd.ContentInfo.Content.Bytes) > 0 {
+		message.content, err = unmarshalOctetString(sd.ContentInfo.Content.Bytes)
+		if err != nil {
+			return nil, fmt.Errorf("could not parse PKCS#7 content: %w", err)
+		}
+	}
+	if len(sd.SignerInfos) == 0 {
+		return message, nil
+	}
+
+	signer := sd.SignerInfos[0]
+	signerCert := findCertificate(signer.IssuerAndSerialNumber, append(message.certificates, trusted...))
+	if signerCert == nil {
+		return nil, fmt.Errorf("the certificate of the signer of the SCEP message is unknown")
+	}
+	hash, err := getHash(signer.DigestAlgorithm.Algorithm)
+	if err != nil {
+		return nil, err
+	}
+
+	// The attributes are signed as a SET OF, while they are encoded with an implicit tag
+	attrsDER, err := asn1.Marshal(asn1.RawValue{Class: asn1.ClassUniversal, Tag: asn1.TagSet, IsCompound: true,
+		Bytes: signer.AuthenticatedAttributes.Bytes})
+	if err != nil {
+		return nil, err
+	}
+	publicKey, ok := signerCert.PublicKey.(*rsa.PublicKey)
+	if !ok {
+		return nil, fmt.Errorf("the SCEP message is not signed with an RSA key")
+	}
+	h := hash.New()
+	h.Write(attrsDER)
+	err = rsa.VerifyPKCS1v15(publicKey, hash, h.Sum(nil), signer.EncryptedDigest)
+	if err != nil {
+		return nil, errMessageSignatureInvalid
+	}
+
+	message.signer = signerCert
+	_, err = asn1.UnmarshalWithParams(attrsDER, &message.attributes, "set")
+	if err != nil {
+		return nil, fmt.Errorf("could not parse the attributes of the SCEP message: %w", err)
+	}
+	var digest []byte
+	found, err := message.get(oidAttributeMessageDigest, &digest)
+	if err != nil || !found {
+		return nil, fmt.Errorf("the SCEP message has no message digest")
+	}
+	h = hash.New()
+	h.Write(message.content)
+	if !bytes.Equal(digest, h.Sum(nil)) {
+		return nil, errMessageSignatureInvalid
+	}
+	return message, nil
+}
+
+// envelope encrypts content for recipient, using RSA key transport and AES, or triple DES when useAES is false
+func envelope(content []byte, recipient *x509.Certificate, useAES bool) ([]byte, error) {
+	publicKey, ok := recipient.PublicKey.(*rsa.PublicKey)
+	if !ok {
+		return nil, fmt.Errorf("the SCEP recipient certificate %q has no RSA key", recipient.Subject.CommonName)
+	}
+
+	algorithm, keySize := oidEncryptionDESEDE3CBC, 24
+	if useAES {
+		algorithm, keySize = oidEncryptionAES128CBC, 16
+	}
+	key := make([]byte, keySize)
+	_, err := rand.Read(key)
+	if err != nil {
+		return nil, err
+	}
+	block, err := newBlockCipher(algorithm, key)
+	if err != nil {
+		return nil, err
+	}
+	iv := make([]byte, block.BlockSize())
+	_, err = rand.Read(iv)
+	if err != nil {
+		return nil, err
+	}
+
+	padded := pad(content, block.BlockSize())
+	encrypted := make([]byte, len(padded))
+	cipher.NewCBCEncrypter(block, iv).CryptBlocks(encrypted, padded)
+
+	encryptedKey, err := rsa.EncryptPKCS1v15(rand.Reader, publicKey, key)
+	if err != nil {
+		return nil, err
+	}
+	ivDER, err := asn1.Marshal(iv)
+	if err != nil {
+		return nil, err
+	}
+
+	ed := envelopedData{
+		Version: 0,
+		RecipientInfos: []recipientInfo{{
+			Version:                0,
+			IssuerAndSerialNumber:  newIssuerAndSerial(recipient),
+			KeyEncryptionAlgorithm: pkix.AlgorithmIdentifier{Algorithm: oidEncryptionRSA, Parameters: asn1.NullRawValue},
+			EncryptedKey:           encryptedKey,
+		}},
+		EncryptedContentInfo: encryptedContentInfo{
+			ContentType:                oidData,
+			ContentEncryptionAlgorithm: pkix.AlgorithmIdentifier{Algorithm: algorithm, Parameters: asn1.RawValue{FullBytes: ivDER}},
+			EncryptedContent:           asn1.RawValue{Class: asn1.ClassContextSpecific, Tag: 0, Bytes: encrypted},
+		},
+	}
+	return marshalContentInfo(oidEnvelopedData, ed)
+}
+
+// openEnvelope decrypts the content of a PKCS#7 envelopedData with key, the private key of the recipient
+func openEnvelope(der []byte, key crypto.Signer) ([]byte, error) {
+	var ed envelopedData
+	err := unmarshalContentInfo(der, oidEnvelopedData, &ed)
+	if err != nil {
+		return nil, err
+	}
+	if len(ed.RecipientInfos) == 0 {
+		return nil, fmt.Errorf("the SCEP envelope has no recipient")
+	}
+	decrypter, ok := key.(crypto.Decrypter)
+	if !ok {
+		return nil, fmt.Errorf("the private key cannot decrypt the SCEP envelope")
+	}
+	contentKey, err := decrypter.Decrypt(rand.Reader, ed.RecipientInfos[0].EncryptedKey, nil)
+	if err != nil {
+		return nil, fmt.Errorf("could not decrypt the key of the SCEP envelope: %w", err)
+	}
+
+	info := ed.EncryptedContentInfo
+	block, err := newBlockCipher(info.ContentEncryptionAlgorithm.Algorithm, contentKey)
+	if err != nil {
+		return nil, err
+	}
+	var iv []byte
+	_, err = asn1.Unmarshal(info.ContentEncryptionAlgorithm.Parameters.FullBytes, &iv)
+	if err != nil || len(iv) != block.BlockSize() {
+		return nil, fmt.Errorf("invalid IV in the SCEP envelope")
+	}
+
+	// The encrypted content is either primitive, or constructed from octet strings
+	encrypted := info.EncryptedContent.Bytes
+	if info.EncryptedContent.IsCompound {
+		encrypted, err = concatOctetStrings(encrypted)
+		if err != nil {
+			return nil, err
+		}
+	}
+	if len(encrypted) == 0 || len(encrypted)%block.BlockSize() != 0 {
+		return nil, fmt.Errorf("invalid encrypted content in the SCEP envelope")
+	}
+	content := make([]byte, len(encrypted))
+	cipher.NewCBCDecrypter(block, iv).CryptBlocks(content, encrypted)
+	return unpad(content, block.BlockSize())
+}
+
+func newBlockCipher(algorithm asn1.ObjectIdentifier, key []byte) (cipher.Block, error) {
+	switch {
+	case algorithm.Equal(oidEncryptionAES128CBC), algorithm.Equal(oidEncryptionAES256CBC):
+		return aes.NewCipher(key)
+	case algorithm.Equal(oidEncryptionDESEDE3CBC):
+		return des.NewTripleDESCipher(key)
+	default:
+		return nil, fmt.Errorf("unsupported SCEP content encryption algorithm %s", algorithm)
+	}
+}
+
+func getHash(algorithm asn1.ObjectIdentifier) (crypto.Hash, error) {
+	switch {
+	case algorithm.Equal(oidDigestSHA1):
+		return crypto.SHA1, nil
+	case algorithm.Equal(oidDigestSHA256):
+		return crypto.SHA256, nil
+	case algorithm.Equal(oidDigestSHA512):
+		return crypto.SHA512, nil
+	default:
+		return 0, fmt.Errorf("unsupported SCEP digest algorithm %s", algorithm)
+	}
+}
+
+func newIssuerAndSerial(cert *x509.Certificate) issuerAndSerial {
+	return issuerAndSerial{Issuer: asn1.RawValue{FullBytes: cert.RawIssuer}, SerialNumber: cert.SerialNumber}
+}
+
+func findCertificate(id issuerAndSerial, certs []*x509.Certificate) *x509.Certificate {
+	for _, cert := range certs {
+		if cert.SerialNumber.Cmp(id.SerialNumber) == 0 && bytes.Equal(cert.RawIssuer, id.Issuer.FullBytes) {
+			return cert
+		}
+	}
+	return nil
+}
+
+func marshalContentInfo(contentType asn1.ObjectIdentifier, content interface{}) ([]byte, error) {
+	der, err := asn1.Marshal(content)
+	if err != nil {
+		return nil, err
+	}
+	return asn1.Marshal(contentInfo{
+		ContentType: contentType,
+		Content:     asn1.RawValue{Class: asn1.ClassContextSpecific, Tag: 0, IsCompound: true, Bytes: der},
+	})
+}
+
+func unmarshalContentInfo(der []byte, contentType asn1.ObjectIdentifier, content interface{}) error {
+	var info contentInfo
+	rest, err := asn1.Unmarshal(der, &info)
+	if err != nil {
+		return fmt.Errorf("could not parse PKCS#7 content info: %w", err)
+	}
+	if len(rest) > 0 {
+		return fmt.Errorf("unexpected trailing data after PKCS#7 content info")
+	}
+	if !info.ContentType.Equal(contentType) {
+		return fmt.Errorf("unexpected PKCS#7 content type %s", info.ContentType)
+	}
+	_, err = asn1.Unmarshal(info.Content.Bytes, content)
+	if err != nil {
+		return fmt.Errorf("could not parse PKCS#7 content: %w", err)
+	}
+	return nil
+}
+
+// unmarshalOctetString returns the bytes of a DER octet string, which may be constructed from octet strings
+func unmarshalOctetString(der []byte) ([]byte, error) {
+	var raw asn1.RawValue
+	_, err := asn1.Unmarshal(der, &raw)
+	if err != nil {
+		return nil, err
+	}
+	if raw.Tag != asn1.TagOctetString {
+		return nil, fmt.Errorf("expected an octet string")
+	}
+	if raw.IsCompound {
+		return concatOctetStrings(raw.Bytes)
+	}
+	return raw.Bytes, nil
+}
+
+func concatOctetStrings(der []byte) ([]byte, error) {
+	var content []byte
+	for len(der) > 0 {
+		var chunk []byte
+		var err error
+		der, err = asn1.Unmarshal(der, &chunk)
+		if err != nil {
+			return nil, err
+		}
+		content = append(content, chunk...)
+	}
+	return content, nil
+}
+
+// pad adds the PKCS#7 padding to data
+func pad(data []byte, blockSize int) []byte {
+	padding := blockSize - len(data)%blockSize
+	return append(append([]byte{}, data...), bytes.Repeat([]byte{byte(padding)}, padding)...)
+}
+
+func unpad(data []byte, blockSize int) ([]byte, error) {
+	padding := int(data[len(data)-1])
+	if padding == 0 || padding > blockSize || padding > len(data) {
+		return nil, fmt.Errorf("invalid padding in the SCEP envelope")
+	}
+	for _, b := range data[len(data)-padding:] {
+		if int(b) != padding {
+			return nil, fmt.Errorf("invalid padding in the SCEP envelope")
+		}
+	}
+	return data[:len(data)-padding], nil
+}
@@ -0,0 +1,394 @@
+/*
+ * Copyright 2023 Venafi, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *  http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package scep
+
+import (
+	"bytes"
+	"context"
+	"crypto"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/asn1"
+	"encoding/base64"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"log"
+	"math/big"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/Venafi/vcert/v5/pkg/util"
+	"github.com/Venafi/vcert/v5/pkg/verror"
+)
+
+type operation string
+
+const (
+	operationGetCACaps    operation = "GetCACaps"
+	operationGetCACert    operation = "GetCACert"
+	operationPKIOperation operation = "PKIOperation"
+
+	mimeTypeCACert     = "application/x-x509-ca-cert"
+	mimeTypeCARACert   = "application/x-x509-ca-ra-cert"
+	mimeTypePKIMessage = "application/x-pki-message"
+
+	// defaultPollInterval is the wait between two CertPoll messages, while a request is pending manual approval
+	defaultPollInterval = 10 * time.Second
+)
+
+// capabilities are the keywords returned by the GetCACaps operation, as defined by RFC 8894 section 3.5.2
+type capabilities map[string]bool
+
+func (caps capabilities) has(name string) bool {
+	return caps[strings.ToUpper(name)]
+}
+
+// useAES returns whether the content of the messages can be encrypted with AES instead of triple DES
+func (caps capabilities) useAES() bool {
+	return caps.has("AES") || caps.has("SCEPStandard")
+}
+
+// usePOST returns whether the messages can be sent in the body of POST requests instead of GET query parameters
+func (caps capabilities) usePOST() bool {
+	return caps.has("POSTPKIOperation") || caps.has("SCEPStandard")
+}
+
+// normalizeURL returns the URL of the SCEP operations. Unlike other connectors, its scheme and path are kept:
+// SCEP servers are commonly served over plain HTTP, at paths such as /cgi-bin/pkiclient.exe
+func normalizeURL(rawURL string) string {
+	normalizedURL := strings.TrimRight(strings.TrimSpace(rawURL), "?")
+	if !strings.Contains(normalizedURL, "://") {
+		normalizedURL = "https://" + normalizedURL
+	}
+	return normalizedURL
+}
+
+// getURL returns the URL of the SCEP operation, with its message
+func (c *Connector) getURL(op operation, message string) string {
+	query := url.Values{"operation": {string(op)}}
+	if message != "" {
+		query.Set("message", message)
+	}
+	separator := "?"
+	if strings.Contains(c.baseURL, "?") {
+		separator = "&"
+	}
+	return c.baseURL + separator + query.Encode()
+}
+
+func (c *Connector) request(ctx context.Context, method string, op operation, message string, body []byte) (statusCode int, contentType string, respBody []byte, err error) {
+	var payload io.Reader
+	if body != nil {
+		payload = bytes.NewReader(body)
+	}
+
+	operationURL := c.getURL(op, message)
+	r, err := http.NewRequestWithContext(ctx, method, operationURL, payload)
+	if err != nil {
+		return
+	}
+	if body != nil {
+		r.Header.Set("Content-Type", mimeTypePKIMessage)
+	}
+
+	res, err := c.getHTTPClient().Do(r)
+	if err != nil {
+		return
+	}
+	defer res.Body.Close()
+
+	statusCode = res.StatusCode
+	contentType = res.Header.Get("Content-Type")
+	respBody, err = io.ReadAll(res.Body)
+	if c.verbose {
+		log.Printf("Got %s status for SCEP %s %s\n", res.Status, method, op)
+	}
+	return
+}
+
+// getCACaps returns the capabilities of the SCEP server. Servers that predate GetCACaps are assumed to have none
+func (c *Connector) getCACaps(ctx context.Context) (capabilities, error) {
+	statusCode, _, body, err := c.request(ctx, http.MethodGet, operationGetCACaps, c.zone, nil)
+	if err != nil {
+		return nil, err
+	}
+	caps := capabilities{}
+	if statusCode != http.StatusOK {
+		return caps, nil
+	}
+	for _, line := range strings.Split(string(body), "\n") {
+		if name := strings.TrimSpace(line); name != "" {
+			caps[strings.ToUpper(name)] = true
+		}
+	}
+	return caps, nil
+}
+
+// getCACertificates returns the CA certificate of the SCEP server, along with its RA certificates and the
+// certificates of its chain when the server has any
+func (c *Connector) getCACertificates(ctx context.Context) ([]*x509.Certificate, error) {
+	statusCode, contentType, body, err := c.request(ctx, http.MethodGet, operationGetCACert, c.zone, nil)
+	if err != nil {
+		return nil, err
+	}
+	if statusCode != http.StatusOK {
+		return nil, fmt.Errorf("%w: unexpected status code on SCEP GetCACert. Status: %d %s", verror.ServerError, statusCode, strings.TrimSpace(string(body)))
+	}
+
+	var certs []*x509.Certificate
+	switch strings.ToLower(strings.TrimSpace(strings.Split(contentType, ";")[0])) {
+	case mimeTypeCACert:
+		var cert *x509.Certificate
+		cert, err = x509.ParseCertificate(body)
+		certs = []*x509.Certificate{cert}
+	case mimeTypeCARACert:
+		var message *signedMessage
+		message, err = parseSignedData(body, nil)
+		if err == nil {
+			certs = message.certificates
+		}
+	default:
+		return nil, fmt.Errorf("%w: unexpected content type %q on SCEP GetCACert", verror.ServerError, contentType)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("%w: could not parse the SCEP CA certificates: %v", verror.ServerError, err)
+	}
+	if len(certs) == 0 {
+		return nil, fmt.Errorf("%w: the SCEP server returned no CA certificate", verror.ServerError)
+	}
+	return certs, nil
+}
+
+// pkiOperation sends the message to the SCEP server and returns its response
+func (c *Connector) pkiOperation(ctx context.Context, caps capabilities, message []byte) ([]byte, error) {
+	var statusCode int
+	var contentType string
+	var body []byte
+	var err error
+	if caps.usePOST() {
+		statusCode, contentType, body, err = c.request(ctx, http.MethodPost, operationPKIOperation, "", message)
+	} else {
+		statusCode, contentType, body, err = c.request(ctx, http.MethodGet, operationPKIOperation, base64.StdEncoding.EncodeToString(message), nil)
+	}
+	if err != nil {
+		return nil, err
+	}
+	if statusCode != http.StatusOK {
+		return nil, fmt.Errorf("%w: unexpected status code on SCEP PKIOperation. Status: %d %s", verror.ServerError, statusCode, strings.TrimSpace(string(body)))
+	}
+	if !strings.HasPrefix(strings.ToLower(contentType), mimeTypePKIMessage) {
+		return nil, fmt.Errorf("%w: unexpected content type %q on SCEP PKIOperation", verror.ServerError, contentType)
+	}
+	return body, nil
+}
+
+// enroll sends the CSR with a PKCSReq message and returns the issued certificates, along with the CA certificates.
+// Requests pending manual approval are polled with CertPoll messages until the timeout expires
+func (c *Connector) enroll(ctx context.Context, csr *x509.CertificateRequest, key crypto.Signer, timeout time.Duration) (issued []*x509.Certificate, caCerts []*x509.Certificate, err error) {
+	caps, err := c.getCACaps(ctx)
+	if err != nil {
+		return nil, nil, err
+	}
+	caCerts, err = c.getCACertificates(ctx)
+	if err != nil {
+		return nil, nil, err
+	}
+	recipient, issuer := selectRecipient(caCerts)
+
+	// Until a certificate is issued, the messages are signed with a self-signed certificate of the requested key
+	signerCert, err := newSignerCertificate(csr, key)
+	if err != nil {
+		return nil, nil, err
+	}
+	transactionID, err := newTransactionID(key.Public())
+	if err != nil {
+		return nil, nil, err
+	}
+	pollContent, err := asn1.Marshal(issuerAndSubject{
+		Issuer:  asn1.RawValue{FullBytes: issuer.RawSubject},
+		Subject: asn1.RawValue{FullBytes: csr.RawSubject},
+	})
+	if err != nil {
+		return nil, nil, err
+	}
+
+	deadline := time.Now().Add(timeout)
+	messageType, content := messageTypePKCSReq, csr.Raw
+	for {
+		message, err := newPKIMessage(messageType, transactionID, content)
+		if err != nil {
+			return nil, nil, err
+		}
+		der, err := message.marshal(recipient, caps.useAES(), signerCert, key)
+		if err != nil {
+			return nil, nil, err
+		}
+		response, err := c.pkiOperation(ctx, caps, der)
+		if err != nil {
+			return nil, nil, err
+		}
+		rep, err := parseCertRep(response, caCerts)
+		if err != nil {
+			return nil, nil, err
+		}
+		err = rep.check(message)
+		if err != nil {
+			return nil, nil, err
+		}
+
+		if rep.pkiStatus == pkiStatusSuccess {
+			issued, err = rep.certificates(key)
+			return issued, caCerts, err
+		}
+		if time.Now().Add(c.pollInterval).After(deadline) {
+			return nil, nil, fmt.Errorf("%w: the SCEP server did not issue the certificate before the timeout", verror.ServerTemporaryUnavailableError)
+		}
+		if err = util.Sleep(ctx, c.pollInterval); err != nil {
+			return nil, nil, err
+		}
+		messageType, content = messageTypeCertPoll, pollContent
+	}
+}
+
+// selectRecipient returns the certificate the messages are encrypted for, and the certificate of the issuing CA.
+// The recipient is the RA certificate when the CA delegates to an RA, and the CA certificate otherwise
+func selectRecipient(caCerts []*x509.Certificate) (recipient *x509.Certificate, issuer *x509.Certificate) {
+	for _, cert := range caCerts {
+		if !cert.IsCA && (cert.KeyUsage == 0 || cert.KeyUsage&x509.KeyUsageKeyEncipherment != 0) && recipient == nil {
+			recipient = cert
+		}
+		// The issuing CA is the CA certificate which did not issue another CA certificate of the chain
+		if cert.IsCA && issuer == nil && !issuedCA(cert, caCerts) {
+			issuer = cert
+		}
+	}
+	if issuer == nil {
+		issuer = caCerts[0]
+	}
+	if recipient == nil {
+		recipient = issuer
+	}
+	return recipient, issuer
+}
+
+func issuedCA(cert *x509.Certificate, caCerts []*x509.Certificate) bool {
+	for _, other := range caCerts {
+		if other.IsCA && !other.Equal(cert) && bytes.Equal(other.RawIssuer, cert.RawSubject) {
+			return true
+		}
+	}
+	return false
+}
+
+// newSignerCertificate returns a self-signed certificate of key, with the subject of the CSR, as RFC 8894 section 2.3 describes
+func newSignerCertificate(csr *x509.CertificateRequest, key crypto.Signer) (*x509.Certificate, error) {
+	serial, err := rand.Int(rand.Reader, new(big.Int).Lsh(big.NewInt(1), 63))
+	if err != nil {
+		return nil, err
+	}
+	template := &x509.Certificate{
+		SerialNumber: serial,
+		RawSubject:   csr.RawSubject,
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(24 * time.Hour),
+		KeyUsage:     x509.KeyUsageDigitalSignature | x509.KeyUsageKeyEncipherment,
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, key.Public(), key)
+	if err != nil {
+		return nil, fmt.Errorf("could not create the SCEP signer certificate: %w", err)
+	}
+	return x509.ParseCertificate(der)
+}
+
+// newTransactionID returns the hex encoded SHA-256 hash of the public key, so that the transaction of a request
+// is the same when it is sent again
+func newTransactionID(publicKey crypto.PublicKey) (string, error) {
+	der, err := x509.MarshalPKIXPublicKey(publicKey)
+	if err != nil {
+		return "", err
+	}
+	hash := sha256.Sum256(der)
+	return strings.ToUpper(hex.EncodeToString(hash[:])), nil
+}
+
+type rawCertificateRequest struct {
+	TBS                asn1.RawValue
+	SignatureAlgorithm pkix.AlgorithmIdentifier
+	Signature          asn1.BitString
+}
+
+type rawCertificateRequestInfo struct {
+	Version       int
+	Subject       asn1.RawValue
+	PublicKey     asn1.RawValue
+	RawAttributes []asn1.RawValue `asn1:"tag:0"`
+}
+
+// addChallengePassword returns the CSR with the challengePassword attribute of PKCS#9, signed again with key.
+// crypto/x509 cannot encode the attribute, whose value is a string rather than a set of extensions
+func addChallengePassword(csrDER []byte, password string, key crypto.Signer) ([]byte, error) {
+	var csr rawCertificateRequest
+	if _, err := asn1.Unmarshal(csrDER, &csr); err != nil {
+		return nil, err
+	}
+	var info rawCertificateRequestInfo
+	if _, err := asn1.Unmarshal(csr.TBS.FullBytes, &info); err != nil {
+		return nil, err
+	}
+
+	challenge, err := newAttribute(oidAttributeChallengePassword, password)
+	if err != nil {
+		return nil, err
+	}
+	challengeDER, err := asn1.Marshal(challenge)
+	if err != nil {
+		return nil, err
+	}
+	info.RawAttributes = append(info.RawAttributes, asn1.RawValue{FullBytes: challengeDER})
+	tbs, err := asn1.Marshal(info)
+	if err != nil {
+		return nil, err
+	}
+
+	digest := sha256.Sum256(tbs)
+	signature, err := key.Sign(rand.Reader, digest[:], crypto.SHA256)
+	if err != nil {
+		return nil, err
+	}
+	return asn1.Marshal(rawCertificateRequest{
+		TBS:                asn1.RawValue{FullBytes: tbs},
+		SignatureAlgorithm: pkix.AlgorithmIdentifier{Algorithm: oidSignatureSHA256WithRSA, Parameters: asn1.NullRawValue},
+		Signature:          asn1.BitString{Bytes: signature, BitLength: 8 * len(signature)},
+	})
+}
+
+func (c *Connector) getHTTPClient() *http.Client {
+	if c.client != nil {
+		return c.client
+	}
+	netTransport := util.NewTransportWithTLSConfig(c.transport, c.proxy, c.trust, c.tlsConfig)
+	c.client = &http.Client{
+		Timeout:   time.Second * 30,
+		Transport: netTransport,
+	}
+	return c.client
+}
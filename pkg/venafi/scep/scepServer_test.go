@@ -0,0 +1,271 @@
+/*
+ * Copyright 2023 Venafi, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *  http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package scep
+
+import (
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/asn1"
+	"encoding/base64"
+	"io"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"time"
+
+	"github.com/Venafi/vcert/v5/test/testcert"
+)
+
+// scepMockServer is a minimal SCEP server. Certificates are issued by an RSA CA, which delegates the messages to an
+// RA when ra is set. Requests must carry the challenge password, when one is set
+type scepMockServer struct {
+	server *httptest.Server
+
+	challenge string
+	// pending is the number of PKCSReq and CertPoll messages answered as pending before issuing the certificate
+	pending int
+	// ra makes the RA certificate the recipient and the signer of the messages
+	ra bool
+	// legacy servers have no GetCACaps operation, so the messages are sent with GET and encrypted with triple DES
+	legacy bool
+	// rogue signs the responses with a certificate unrelated to the CA
+	rogue bool
+
+	mu           sync.Mutex
+	caIdentifier string
+	methods      []string
+	polls        int
+	requests     map[string]*x509.CertificateRequest
+	caKey        *rsa.PrivateKey
+	caCert       *x509.Certificate
+	raKey        *rsa.PrivateKey
+	raCert       *x509.Certificate
+}
+
+func newSCEPMockServer() *scepMockServer {
+	s := &scepMockServer{requests: make(map[string]*x509.CertificateRequest)}
+	ca := testcert.Issue(testcert.Template("SCEP Test CA", true), newRSAKey(), nil)
+	ra := testcert.Issue(testcert.Template("SCEP Test RA", false), newRSAKey(), &ca)
+	s.caKey, s.caCert = ca.Key.(*rsa.PrivateKey), ca.Cert
+	s.raKey, s.raCert = ra.Key.(*rsa.PrivateKey), ra.Cert
+
+	s.server = httptest.NewServer(http.HandlerFunc(s.handle))
+	return s
+}
+
+// newRSAKey returns the key of a certificate of the server, which must be RSA since the messages are encrypted with it
+func newRSAKey() *rsa.PrivateKey {
+	key, _ := rsa.GenerateKey(rand.Reader, 2048)
+	return key
+}
+
+// recipient returns the certificate and key the messages are encrypted for and signed with
+func (s *scepMockServer) recipient() (*x509.Certificate, *rsa.PrivateKey) {
+	if s.ra {
+		return s.raCert, s.raKey
+	}
+	return s.caCert, s.caKey
+}
+
+func (s *scepMockServer) handle(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	query := r.URL.Query()
+	switch query.Get("operation") {
+	case string(operationGetCACaps):
+		if s.legacy {
+			http.NotFound(w, r)
+			return
+		}
+		_, _ = w.Write([]byte("POSTPKIOperation\nSHA-256\nAES\n"))
+	case string(operationGetCACert):
+		s.caIdentifier = query.Get("message")
+		if !s.ra {
+			w.Header().Set("Content-Type", mimeTypeCACert)
+			_, _ = w.Write(s.caCert.Raw)
+			return
+		}
+		der, err := certsOnly([]*x509.Certificate{s.raCert, s.caCert})
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		w.Header().Set("Content-Type", mimeTypeCARACert)
+		_, _ = w.Write(der)
+	case string(operationPKIOperation):
+		s.methods = append(s.methods, r.Method)
+		var message []byte
+		var err error
+		if r.Method == http.MethodPost {
+			message, err = io.ReadAll(r.Body)
+		} else {
+			message, err = base64.StdEncoding.DecodeString(query.Get("message"))
+		}
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		s.pkiOperation(w, message)
+	default:
+		http.NotFound(w, r)
+	}
+}
+
+func (s *scepMockServer) pkiOperation(w http.ResponseWriter, der []byte) {
+	message, err := parseSignedData(der, nil)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	var messageType, transactionID string
+	var senderNonce []byte
+	_, _ = message.get(oidSCEPMessageType, &messageType)
+	_, _ = message.get(oidSCEPTransactionID, &transactionID)
+	_, _ = message.get(oidSCEPSenderNonce, &senderNonce)
+
+	_, recipientKey := s.recipient()
+	content, err := openEnvelope(message.content, recipientKey)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	var csr *x509.CertificateRequest
+	switch messageType {
+	case messageTypePKCSReq:
+		csr, err = x509.ParseCertificateRequest(content)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		if s.challenge != "" && getChallengePassword(content) != s.challenge {
+			s.reply(w, message, transactionID, senderNonce, pkiStatusFailure, "2", nil)
+			return
+		}
+		s.requests[transactionID] = csr
+	case messageTypeCertPoll:
+		s.polls++
+		var id issuerAndSubject
+		_, err = asn1.Unmarshal(content, &id)
+		csr = s.requests[transactionID]
+		if err != nil || csr == nil || string(id.Subject.FullBytes) != string(csr.RawSubject) ||
+			string(id.Issuer.FullBytes) != string(s.caCert.RawSubject) {
+			s.reply(w, message, transactionID, senderNonce, pkiStatusFailure, "4", nil)
+			return
+		}
+	default:
+		http.Error(w, "unexpected message type", http.StatusBadRequest)
+		return
+	}
+
+	if s.pending > 0 {
+		s.pending--
+		s.reply(w, message, transactionID, senderNonce, pkiStatusPending, "", nil)
+		return
+	}
+
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(time.Now().UnixNano()),
+		Subject:      csr.Subject,
+		DNSNames:     csr.DNSNames,
+		NotBefore:    time.Now().Add(-time.Minute),
+		NotAfter:     time.Now().Add(time.Hour),
+		KeyUsage:     x509.KeyUsageDigitalSignature | x509.KeyUsageKeyEncipherment,
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth, x509.ExtKeyUsageClientAuth},
+	}
+	certDER, err := x509.CreateCertificate(rand.Reader, template, s.caCert, csr.PublicKey, s.caKey)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	cert, _ := x509.ParseCertificate(certDER)
+	s.reply(w, message, transactionID, senderNonce, pkiStatusSuccess, "", cert)
+}
+
+// reply writes a CertRep message. The issued certificate is encrypted for the signer of the request
+func (s *scepMockServer) reply(w http.ResponseWriter, request *signedMessage, transactionID string, recipientNonce []byte, status string, failInfo string, issued *x509.Certificate) {
+	var content []byte
+	if issued != nil {
+		degenerate, err := certsOnly([]*x509.Certificate{issued})
+		if err == nil {
+			content, err = envelope(degenerate, request.signer, !s.legacy)
+		}
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+	}
+
+	nonce := make([]byte, nonceSize)
+	_, _ = rand.Read(nonce)
+	var attrs []attribute
+	for _, v := range []struct {
+		t     asn1.ObjectIdentifier
+		value interface{}
+	}{
+		{oidSCEPTransactionID, transactionID},
+		{oidSCEPMessageType, messageTypeCertRep},
+		{oidSCEPPKIStatus, status},
+		{oidSCEPSenderNonce, nonce},
+		{oidSCEPRecipientNonce, recipientNonce},
+		{oidSCEPFailInfo, failInfo},
+	} {
+		if v.t.Equal(oidSCEPFailInfo) && failInfo == "" {
+			continue
+		}
+		attr, _ := newAttribute(v.t, v.value)
+		attrs = append(attrs, attr)
+	}
+
+	signerCert, signerKey := s.recipient()
+	if s.rogue {
+		rogue := testcert.Issue(testcert.Template("SCEP Rogue RA", false), newRSAKey(), nil)
+		signerCert, signerKey = rogue.Cert, rogue.Key.(*rsa.PrivateKey)
+	}
+	der, err := signData(content, attrs, signerCert, signerKey)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", mimeTypePKIMessage)
+	_, _ = w.Write(der)
+}
+
+// getChallengePassword returns the challengePassword attribute of the CSR, which crypto/x509 does not parse
+func getChallengePassword(csrDER []byte) string {
+	var csr rawCertificateRequest
+	var info rawCertificateRequestInfo
+	if _, err := asn1.Unmarshal(csrDER, &csr); err != nil {
+		return ""
+	}
+	if _, err := asn1.Unmarshal(csr.TBS.FullBytes, &info); err != nil {
+		return ""
+	}
+	for _, raw := range info.RawAttributes {
+		var attr attribute
+		if _, err := asn1.Unmarshal(raw.FullBytes, &attr); err != nil || !attr.Type.Equal(oidAttributeChallengePassword) {
+			continue
+		}
+		var password string
+		_, _ = asn1.Unmarshal(attr.Value.Bytes, &password)
+		return password
+	}
+	return ""
+}
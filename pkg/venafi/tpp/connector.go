@@ -17,6 +17,8 @@
 package tpp
 
 import (
+	"context"
+	"crypto/tls"
 	"crypto/x509"
 	"encoding/json"
 	"encoding/pem"
@@ -28,6 +30,7 @@ import (
 	"regexp"
 	"strconv"
 	"strings"
+	"sync"
 	"time"
 
 	"github.com/Venafi/vcert/v5/pkg/certificate"
@@ -47,18 +50,31 @@ type Connector struct {
 	trust       *x509.CertPool
 	zone        string
 	client      *http.Client
-}
-
-func (c *Connector) IsCSRServiceGenerated(req *certificate.Request) (bool, error) {
+	retryPolicy util.RetryPolicy
+	proxy       util.ProxyConfig
+	transport   util.TransportConfig
+	tlsConfig   *tls.Config
+	zoneCache   *endpoint.ZoneConfigurationCache
+
+	// The access token rejected as expired by TPP is refreshed with refreshToken during the run
+	tokenMu        sync.Mutex
+	refreshMu      sync.Mutex
+	refreshToken   string
+	clientID       string
+	onTokenRefresh func(accessToken string, refreshToken string) error
+	lockTokens     func() (accessToken string, refreshToken string, unlock func() error, err error)
+}
+
+func (c *Connector) IsCSRServiceGenerated(ctx context.Context, req *certificate.Request) (bool, error) {
 	panic("operation is not supported yet")
 }
 
-func (c *Connector) RetrieveSshConfig(ca *certificate.SshCaTemplateRequest) (*certificate.SshConfig, error) {
-	return RetrieveSshConfig(c, ca)
+func (c *Connector) RetrieveSshConfig(ctx context.Context, ca *certificate.SshCaTemplateRequest) (*certificate.SshConfig, error) {
+	return RetrieveSshConfig(ctx, c, ca)
 }
 
-func (c *Connector) RetrieveAvailableSSHTemplates() (response []certificate.SshAvaliableTemplate, err error) {
-	return GetAvailableSshTemplates(c)
+func (c *Connector) RetrieveAvailableSSHTemplates(ctx context.Context) (response []certificate.SshAvaliableTemplate, err error) {
+	return GetAvailableSshTemplates(ctx, c)
 }
 
 // NewConnector creates a new TPP Connector object used to communicate with TPP
@@ -97,11 +113,11 @@ func (c *Connector) GetType() endpoint.ConnectorType {
 }
 
 // Ping attempts to connect to the TPP Server WebSDK API and returns an error if it cannot
-func (c *Connector) Ping() (err error) {
+func (c *Connector) Ping(ctx context.Context) (err error) {
 
 	//Extended timeout to allow the server to wake up
 	c.getHTTPClient().Timeout = time.Second * 90
-	statusCode, status, _, err := c.request("GET", "vedsdk/", nil)
+	statusCode, status, _, err := c.request(ctx, "GET", "vedsdk/", nil)
 	if err != nil {
 		return
 	}
@@ -112,7 +128,7 @@ func (c *Connector) Ping() (err error) {
 }
 
 // Authenticate authenticates the user to the TPP
-func (c *Connector) Authenticate(auth *endpoint.Authentication) (err error) {
+func (c *Connector) Authenticate(ctx context.Context, auth *endpoint.Authentication) (err error) {
 	defer func() {
 		if err != nil {
 			err = fmt.Errorf("%w: %s", verror.AuthError, err)
@@ -126,10 +142,13 @@ func (c *Connector) Authenticate(auth *endpoint.Authentication) (err error) {
 	if auth.ClientId == "" {
 		auth.ClientId = defaultClientID
 	}
+	c.clientID = auth.ClientId
+	c.onTokenRefresh = auth.OnTokenRefresh
+	c.lockTokens = auth.LockTokens
 
 	if auth.User != "" && auth.Password != "" {
 		data := authorizeResquest{Username: auth.User, Password: auth.Password}
-		result, err := processAuthData(c, urlResourceAuthorize, data)
+		result, err := processAuthData(ctx, c, urlResourceAuthorize, data)
 		if err != nil {
 			return err
 		}
@@ -138,25 +157,45 @@ func (c *Connector) Authenticate(auth *endpoint.Authentication) (err error) {
 		c.apiKey = resp.APIKey
 
 		if c.client != nil {
-			c.Identity, err = c.retrieveSelfIdentity()
+			c.Identity, err = c.retrieveSelfIdentity(ctx)
 			if err != nil {
 				return err
 			}
 		}
 		return nil
 
-	} else if auth.RefreshToken != "" {
-		data := oauthRefreshAccessTokenRequest{Client_id: auth.ClientId, Refresh_token: auth.RefreshToken}
-		result, err := processAuthData(c, urlResourceRefreshAccessToken, data)
+	} else if auth.RefreshToken != "" && auth.AccessToken == "" {
+		persistedAccessToken, persistedRefreshToken, unlock, err := c.lockPersistedTokens()
 		if err != nil {
 			return err
 		}
+		defer func() {
+			_ = unlock()
+		}()
 
-		resp := result.(OauthRefreshAccessTokenResponse)
-		c.accessToken = resp.Access_token
-		auth.RefreshToken = resp.Refresh_token
+		if persistedAccessToken != "" && persistedRefreshToken != "" && persistedRefreshToken != auth.RefreshToken {
+			// Another process refreshed the refresh token of the credentials since they were read
+			c.accessToken = persistedAccessToken
+			c.refreshToken = persistedRefreshToken
+			auth.RefreshToken = persistedRefreshToken
+		} else {
+			if persistedRefreshToken != "" {
+				auth.RefreshToken = persistedRefreshToken
+			}
+			data := oauthRefreshAccessTokenRequest{Client_id: auth.ClientId, Refresh_token: auth.RefreshToken}
+			result, err := processAuthData(ctx, c, urlResourceRefreshAccessToken, data)
+			if err != nil {
+				return err
+			}
+
+			resp := result.(OauthRefreshAccessTokenResponse)
+			c.accessToken = resp.Access_token
+			c.refreshToken = resp.Refresh_token
+			auth.RefreshToken = resp.Refresh_token
+			c.persistTokens(resp.Access_token, resp.Refresh_token)
+		}
 		if c.client != nil {
-			c.Identity, err = c.retrieveSelfIdentity()
+			c.Identity, err = c.retrieveSelfIdentity(ctx)
 			if err != nil {
 				return err
 			}
@@ -164,21 +203,38 @@ func (c *Connector) Authenticate(auth *endpoint.Authentication) (err error) {
 		return nil
 
 	} else if auth.AccessToken != "" {
+		// The refresh token, when given too, is only used once the access token expires
 		c.accessToken = auth.AccessToken
+		c.refreshToken = auth.RefreshToken
 
 		if c.client != nil {
-			c.Identity, err = c.retrieveSelfIdentity()
+			c.Identity, err = c.retrieveSelfIdentity(ctx)
 			if err != nil {
 				return err
 			}
 		}
 		return nil
+
+	} else if auth.ClientCertificate != nil {
+		resp, err := c.GetRefreshToken(ctx, auth)
+		if err != nil {
+			return err
+		}
+		c.accessToken = resp.Access_token
+		c.refreshToken = resp.Refresh_token
+		auth.RefreshToken = resp.Refresh_token
+
+		c.Identity, err = c.retrieveSelfIdentity(ctx)
+		if err != nil {
+			return err
+		}
+		return nil
 	}
 	return fmt.Errorf("failed to authenticate: can't determine valid credentials set")
 }
 
 // GetRefreshToken Get OAuth refresh and access token
-func (c *Connector) GetRefreshToken(auth *endpoint.Authentication) (resp OauthGetRefreshTokenResponse, err error) {
+func (c *Connector) GetRefreshToken(ctx context.Context, auth *endpoint.Authentication) (resp OauthGetRefreshTokenResponse, err error) {
 
 	if auth == nil {
 		return resp, fmt.Errorf("failed to authenticate: missing credentials")
@@ -193,16 +249,22 @@ func (c *Connector) GetRefreshToken(auth *endpoint.Authentication) (resp OauthGe
 
 	if auth.User != "" && auth.Password != "" {
 		data := oauthGetRefreshTokenRequest{Username: auth.User, Password: auth.Password, Scope: auth.Scope, Client_id: auth.ClientId}
-		result, err := processAuthData(c, urlResourceAuthorizeOAuth, data)
+		result, err := processAuthData(ctx, c, urlResourceAuthorizeOAuth, data)
 		if err != nil {
 			return resp, err
 		}
 		resp = result.(OauthGetRefreshTokenResponse)
 		return resp, nil
 
-	} else if auth.ClientPKCS12 {
+	} else if auth.ClientPKCS12 || auth.ClientCertificate != nil {
+		if auth.ClientCertificate != nil {
+			err = c.setClientCertificate(*auth.ClientCertificate)
+			if err != nil {
+				return resp, err
+			}
+		}
 		data := oauthCertificateTokenRequest{Client_id: auth.ClientId, Scope: auth.Scope}
-		result, err := processAuthData(c, urlResourceAuthorizeCertificate, data)
+		result, err := processAuthData(ctx, c, urlResourceAuthorizeCertificate, data)
 		if err != nil {
 			return resp, err
 		}
@@ -214,8 +276,43 @@ func (c *Connector) GetRefreshToken(auth *endpoint.Authentication) (resp OauthGe
 	return resp, fmt.Errorf("failed to authenticate: missing credentials")
 }
 
+// setClientCertificate makes the connector present cert in the TLS handshakes with TPP,
+// as required by the certificate token grant
+func (c *Connector) setClientCertificate(cert tls.Certificate) error {
+	client := c.getHTTPClient()
+	// The transport performing the requests is wrapped by the one retrying them
+	retry, wrapped := client.Transport.(*util.RetryTransport)
+	base := client.Transport
+	if wrapped {
+		base = retry.Base
+	}
+	transport, ok := base.(*http.Transport)
+	if !ok {
+		return fmt.Errorf("failed to set client certificate: the HTTP client does not use an *http.Transport")
+	}
+
+	tlsConfig := &tls.Config{}
+	if transport.TLSClientConfig != nil {
+		tlsConfig = transport.TLSClientConfig.Clone()
+	}
+	tlsConfig.Certificates = []tls.Certificate{cert}
+	// TPP servers may ask for the client certificate through a renegotiation
+	tlsConfig.Renegotiation = tls.RenegotiateFreelyAsClient
+
+	transport = transport.Clone()
+	transport.TLSClientConfig = tlsConfig
+	if wrapped {
+		retryCopy := *retry
+		retryCopy.Base = transport
+		client.Transport = &retryCopy
+		return nil
+	}
+	client.Transport = transport
+	return nil
+}
+
 // RefreshAccessToken Refresh OAuth access token
-func (c *Connector) RefreshAccessToken(auth *endpoint.Authentication) (resp OauthRefreshAccessTokenResponse, err error) {
+func (c *Connector) RefreshAccessToken(ctx context.Context, auth *endpoint.Authentication) (resp OauthRefreshAccessTokenResponse, err error) {
 
 	if auth == nil {
 		return resp, fmt.Errorf("failed to authenticate: missing credentials")
@@ -227,7 +324,7 @@ func (c *Connector) RefreshAccessToken(auth *endpoint.Authentication) (resp Oaut
 
 	if auth.RefreshToken != "" {
 		data := oauthRefreshAccessTokenRequest{Client_id: auth.ClientId, Refresh_token: auth.RefreshToken}
-		result, err := processAuthData(c, urlResourceRefreshAccessToken, data)
+		result, err := processAuthData(ctx, c, urlResourceRefreshAccessToken, data)
 		if err != nil {
 			return resp, err
 		}
@@ -238,8 +335,92 @@ func (c *Connector) RefreshAccessToken(auth *endpoint.Authentication) (resp Oaut
 	}
 }
 
+// getAccessToken returns the access token the requests are authenticated with
+func (c *Connector) getAccessToken() string {
+	c.tokenMu.Lock()
+	defer c.tokenMu.Unlock()
+	return c.accessToken
+}
+
+// canRefreshToken returns true when token, rejected by TPP, can be replaced using the refresh token
+func (c *Connector) canRefreshToken(token string, resource urlResource) bool {
+	c.tokenMu.Lock()
+	defer c.tokenMu.Unlock()
+	// The responses of the authorization server are returned as is, they are not about an expired token
+	return token != "" && c.refreshToken != "" && !strings.HasPrefix(string(resource), "vedauth/")
+}
+
+// refreshAccessToken gets a new token pair with the refresh token, to replace expired. When another request, or
+// another process sharing the persisted tokens, already replaced expired, the token it got is used instead of
+// refreshing it again
+func (c *Connector) refreshAccessToken(ctx context.Context, expired string) error {
+	c.refreshMu.Lock()
+	defer c.refreshMu.Unlock()
+	if c.getAccessToken() != expired {
+		return nil
+	}
+
+	persistedAccessToken, persistedRefreshToken, unlock, err := c.lockPersistedTokens()
+	if err != nil {
+		return err
+	}
+	defer func() {
+		_ = unlock()
+	}()
+
+	c.tokenMu.Lock()
+	if persistedAccessToken != "" && persistedAccessToken != expired {
+		c.accessToken = persistedAccessToken
+		c.refreshToken = persistedRefreshToken
+		c.tokenMu.Unlock()
+		return nil
+	}
+	if persistedRefreshToken != "" {
+		c.refreshToken = persistedRefreshToken
+	}
+	data := oauthRefreshAccessTokenRequest{Client_id: c.clientID, Refresh_token: c.refreshToken}
+	c.tokenMu.Unlock()
+	result, err := processAuthData(ctx, c, urlResourceRefreshAccessToken, data)
+	if err != nil {
+		return err
+	}
+
+	resp := result.(OauthRefreshAccessTokenResponse)
+	c.tokenMu.Lock()
+	c.accessToken = resp.Access_token
+	c.refreshToken = resp.Refresh_token
+	c.tokenMu.Unlock()
+	c.persistTokens(resp.Access_token, resp.Refresh_token)
+	return nil
+}
+
+// lockPersistedTokens takes the lock of the persisted tokens with the LockTokens of the credentials, and returns the
+// token pair persisted. Without LockTokens, no tokens are returned and the lock is a no-op
+func (c *Connector) lockPersistedTokens() (accessToken string, refreshToken string, unlock func() error, err error) {
+	if c.lockTokens == nil {
+		return "", "", func() error { return nil }, nil
+	}
+	accessToken, refreshToken, unlock, err = c.lockTokens()
+	if err != nil {
+		return "", "", nil, fmt.Errorf("failed to refresh access token: %w", err)
+	}
+	return accessToken, refreshToken, unlock, nil
+}
+
+// persistTokens reports the rotated token pair to the OnTokenRefresh of the credentials. The refresh token sent to
+// TPP is no longer valid, so a failure to persist the new one is logged but does not fail the request
+func (c *Connector) persistTokens(accessToken string, refreshToken string) {
+	if c.onTokenRefresh == nil {
+		return
+	}
+	err := c.onTokenRefresh(accessToken, refreshToken)
+	if err != nil {
+		log.Printf("failed to persist the refreshed access token: %s", err)
+	}
+}
+
 // VerifyAccessToken - call to check whether token is valid and, if so, return its properties
-func (c *Connector) VerifyAccessToken(auth *endpoint.Authentication) (resp OauthVerifyTokenResponse, err error) {
+func (c *Connector) VerifyAccessToken(ctx context.Context, auth *endpoint.Authentication) (resp OauthVerifyTokenResponse, err error) {
 
 	if auth == nil {
 		return resp, fmt.Errorf("failed to authenticate: missing credentials")
@@ -247,7 +428,7 @@ func (c *Connector) VerifyAccessToken(auth *endpoint.Authentication) (resp Oauth
 
 	if auth.AccessToken != "" {
 		c.accessToken = auth.AccessToken
-		statusCode, statusText, body, err := c.request("GET", urlResource(urlResourceAuthorizeVerify), nil)
+		statusCode, statusText, body, err := c.request(ctx, "GET", urlResource(urlResourceAuthorizeVerify), nil)
 		if err != nil {
 			return resp, err
 		}
@@ -267,7 +448,7 @@ func (c *Connector) VerifyAccessToken(auth *endpoint.Authentication) (resp Oauth
 }
 
 // RevokeAccessToken - call to revoke token so that it can never be used again
-func (c *Connector) RevokeAccessToken(auth *endpoint.Authentication) (err error) {
+func (c *Connector) RevokeAccessToken(ctx context.Context, auth *endpoint.Authentication) (err error) {
 
 	if auth == nil {
 		return fmt.Errorf("failed to authenticate: missing credentials")
@@ -275,7 +456,7 @@ func (c *Connector) RevokeAccessToken(auth *endpoint.Authentication) (err error)
 
 	if auth.AccessToken != "" {
 		c.accessToken = auth.AccessToken
-		statusCode, statusText, _, err := c.request("GET", urlResource(urlResourceRevokeAccessToken), nil)
+		statusCode, statusText, _, err := c.request(ctx, "GET", urlResource(urlResourceRevokeAccessToken), nil)
 		if err != nil {
 			return err
 		}
@@ -289,7 +470,7 @@ func (c *Connector) RevokeAccessToken(auth *endpoint.Authentication) (err error)
 	return fmt.Errorf("failed to authenticate: missing access token")
 }
 
-func processAuthData(c *Connector, url urlResource, data interface{}) (resp interface{}, err error) {
+func processAuthData(ctx context.Context, c *Connector, url urlResource, data interface{}) (resp interface{}, err error) {
 
 	//isReachable, err := c.isAuthServerReachable()
 	//if err != nil {
@@ -299,7 +480,7 @@ func processAuthData(c *Connector, url urlResource, data interface{}) (resp inte
 	//	return nil, fmt.Errorf("authentication server is not reachable: %s", c.baseURL)
 	//}
 
-	statusCode, status, body, err := c.request("POST", url, data)
+	statusCode, status, body, err := c.request(ctx, "POST", url, data)
 	if err != nil {
 		return resp, err
 	}
@@ -344,12 +525,12 @@ func processAuthData(c *Connector, url urlResource, data interface{}) (resp inte
 	return resp, nil
 }
 
-func (c *Connector) isAuthServerReachable() (bool, error) {
+func (c *Connector) isAuthServerReachable(ctx context.Context) (bool, error) {
 	url := urlResource(urlResourceAuthorizeIsAuthServer)
 
 	// Extended timeout to allow the server to wake up
 	c.getHTTPClient().Timeout = time.Second * 90
-	statusCode, statusText, _, err := c.request("GET", url, nil)
+	statusCode, statusText, _, err := c.request(ctx, "GET", url, nil)
 	if err != nil {
 		return false, fmt.Errorf("error while cheking the authentication server. URL: %s; Error: %v", url, err)
 	}
@@ -379,8 +560,8 @@ func wrapAltNames(req *certificate.Request) (items []sanItem) {
 	return items
 }
 
-func prepareLegacyMetadata(c *Connector, metaItems []customField, dn string) ([]guidData, error) {
-	metadataItems, err := c.requestAllMetadataItems(dn)
+func prepareLegacyMetadata(ctx context.Context, c *Connector, metaItems []customField, dn string) ([]guidData, error) {
+	metadataItems, err := c.requestAllMetadataItems(ctx, dn)
 	if nil != err {
 		return nil, err
 	}
@@ -400,8 +581,8 @@ func prepareLegacyMetadata(c *Connector, metaItems []customField, dn string) ([]
 }
 
 // requestAllMetadataItems returns all possible metadata items for a DN
-func (c *Connector) requestAllMetadataItems(dn string) ([]metadataItem, error) {
-	statusCode, status, body, err := c.request("POST", urlResourceAllMetadataGet, metadataGetItemsRequest{dn})
+func (c *Connector) requestAllMetadataItems(ctx context.Context, dn string) ([]metadataItem, error) {
+	statusCode, status, body, err := c.request(ctx, "POST", urlResourceAllMetadataGet, metadataGetItemsRequest{dn})
 	if err != nil {
 		return nil, err
 	}
@@ -415,8 +596,8 @@ func (c *Connector) requestAllMetadataItems(dn string) ([]metadataItem, error) {
 }
 
 // requestMetadataItems returns metadata items for a DN that have a value stored
-func (c *Connector) requestMetadataItems(dn string) ([]metadataKeyValueSet, error) {
-	statusCode, status, body, err := c.request("POST", urlResourceMetadataGet, metadataGetItemsRequest{dn})
+func (c *Connector) requestMetadataItems(ctx context.Context, dn string) ([]metadataKeyValueSet, error) {
+	statusCode, status, body, err := c.request(ctx, "POST", urlResourceMetadataGet, metadataGetItemsRequest{dn})
 	if err != nil {
 		return nil, err
 	}
@@ -429,11 +610,11 @@ func (c *Connector) requestMetadataItems(dn string) ([]metadataKeyValueSet, erro
 }
 
 // Retrieve user's self identity
-func (c *Connector) retrieveSelfIdentity() (response identity, err error) {
+func (c *Connector) retrieveSelfIdentity(ctx context.Context) (response identity, err error) {
 
 	var respIndentities = &identitiesResponse{}
 
-	statusCode, statusText, body, err := c.request("GET", urlRetrieveSelfIdentity, nil)
+	statusCode, statusText, body, err := c.request(ctx, "GET", urlRetrieveSelfIdentity, nil)
 	if err != nil {
 		log.Printf("Failed to get the used user. Error: %v", err)
 		return identity{}, err
@@ -456,8 +637,8 @@ func (c *Connector) retrieveSelfIdentity() (response identity, err error) {
 }
 
 // requestSystemVersion returns the TPP system version of the connector context
-func (c *Connector) RetrieveSystemVersion() (string, error) {
-	statusCode, status, body, err := c.request("GET", urlResourceSystemStatusVersion, "")
+func (c *Connector) RetrieveSystemVersion(ctx context.Context) (string, error) {
+	statusCode, status, body, err := c.request(ctx, "GET", urlResourceSystemStatusVersion, "")
 	if err != nil {
 		return "", err
 	}
@@ -476,7 +657,7 @@ func (c *Connector) RetrieveSystemVersion() (string, error) {
 }
 
 // setCertificateMetadata submits the metadata to TPP for storage returning the lock status of the metadata stored
-func (c *Connector) setCertificateMetadata(metadataRequest metadataSetRequest) (bool, error) {
+func (c *Connector) setCertificateMetadata(ctx context.Context, metadataRequest metadataSetRequest) (bool, error) {
 	if metadataRequest.DN == "" {
 		return false, fmt.Errorf("DN must be provided to setCertificateMetaData")
 	}
@@ -484,7 +665,7 @@ func (c *Connector) setCertificateMetadata(metadataRequest metadataSetRequest) (
 		return false, nil
 	} //Not an error, but there is nothing to do
 
-	statusCode, status, body, err := c.request("POST", urlResourceMetadataSet, metadataRequest)
+	statusCode, status, body, err := c.request(ctx, "POST", urlResourceMetadataSet, metadataRequest)
 	if err != nil {
 		return false, err
 	}
@@ -540,17 +721,16 @@ func prepareRequest(req *certificate.Request, zone string) (tppReq certificateRe
 	tppReq.CASpecificAttributes = append(tppReq.CASpecificAttributes, nameValuePair{Name: "Origin", Value: origin})
 	tppReq.Origin = origin
 
-	validityDuration := req.ValidityDuration
-
-	// DEPRECATED: ValidityHours is deprecated in favor of ValidityDuration, but we
-	// still support it for backwards compatibility.
-	if validityDuration == nil && req.ValidityHours > 0 { //nolint:staticcheck
-		duration := time.Duration(req.ValidityHours) * time.Hour //nolint:staticcheck
-		validityDuration = &duration
-	}
+	// DEPRECATED: ValidityHours is deprecated in favor of ValidityDuration, but GetValidityDuration
+	// still supports it for backwards compatibility.
+	validityDuration := req.GetValidityDuration()
 
 	if validityDuration != nil {
-		formattedExpirationDate := time.Now().Add(*validityDuration).Format(time.RFC3339)
+		expirationDate := time.Now().Add(*validityDuration)
+		if req.NotAfter != nil {
+			expirationDate = *req.NotAfter
+		}
+		formattedExpirationDate := expirationDate.Format(time.RFC3339)
 
 		var attributeNames []string
 
@@ -641,9 +821,9 @@ func prepareRequest(req *certificate.Request, zone string) (tppReq certificateRe
 	return tppReq, err
 }
 
-func (c *Connector) proccessLocation(req *certificate.Request) error {
+func (c *Connector) proccessLocation(ctx context.Context, req *certificate.Request) error {
 	certDN := getCertificateDN(c.zone, req.FriendlyName, req.Subject.CommonName)
-	guid, err := c.configDNToGuid(certDN)
+	guid, err := c.configDNToGuid(ctx, certDN)
 	if err != nil {
 		return fmt.Errorf("unable to retrieve certificate guid: %s", err)
 	}
@@ -653,7 +833,7 @@ func (c *Connector) proccessLocation(req *certificate.Request) error {
 		}
 		return nil
 	}
-	details, err := c.searchCertificateDetails(guid)
+	details, err := c.searchCertificateDetails(ctx, guid)
 	if err != nil {
 		return err
 	}
@@ -673,7 +853,7 @@ func (c *Connector) proccessLocation(req *certificate.Request) error {
 		}
 		if device == requestedDevice {
 			if req.Location.Replace {
-				err = c.dissociate(certDN, device)
+				err = c.dissociate(ctx, certDN, device)
 				if err != nil {
 					return err
 				}
@@ -686,9 +866,9 @@ func (c *Connector) proccessLocation(req *certificate.Request) error {
 }
 
 // RequestCertificate submits the CSR to TPP returning the DN of the requested Certificate
-func (c *Connector) RequestCertificate(req *certificate.Request) (requestID string, err error) {
+func (c *Connector) RequestCertificate(ctx context.Context, req *certificate.Request) (requestID string, err error) {
 	if req.Location != nil {
-		err = c.proccessLocation(req)
+		err = c.proccessLocation(ctx, req)
 		if err != nil {
 			return
 		}
@@ -697,7 +877,7 @@ func (c *Connector) RequestCertificate(req *certificate.Request) (requestID stri
 	if err != nil {
 		return "", err
 	}
-	statusCode, status, body, err := c.request("POST", urlResourceCertificateRequest, tppCertificateRequest)
+	statusCode, status, body, err := c.request(ctx, "POST", urlResourceCertificateRequest, tppCertificateRequest)
 	if err != nil {
 		return "", err
 	}
@@ -707,6 +887,14 @@ func (c *Connector) RequestCertificate(req *certificate.Request) (requestID stri
 	}
 	req.PickupID = requestID
 
+	if len(req.ApplicationDNs) > 0 {
+		// TPP provisions the certificate to its applications once it is issued, so there is nothing to push yet
+		err = c.associate(ctx, requestID, getApplicationDNs(req.ApplicationDNs), false)
+		if err != nil {
+			return "", fmt.Errorf("could not associate certificate %s with applications: %w", requestID, err)
+		}
+	}
+
 	if len(req.CustomFields) == 0 {
 		return
 	}
@@ -716,7 +904,7 @@ func (c *Connector) RequestCertificate(req *certificate.Request) (requestID stri
 	//saved metadata to the requested metadata. If all items match then no further
 	//changes need to be made. If they do not match, they try to update them using
 	//the 19.2 WebSDK calls
-	metadataItems, err := c.requestMetadataItems(requestID)
+	metadataItems, err := c.requestMetadataItems(ctx, requestID)
 	if err != nil {
 		log.Println(err)
 		return
@@ -751,14 +939,14 @@ func (c *Connector) RequestCertificate(req *certificate.Request) (requestID stri
 	}
 	log.Println("Saving metadata custom field using 19.2 method")
 	//Create a metadata/set command with the metadata from tppCertificateRequest
-	guidItems, err := prepareLegacyMetadata(c, tppCertificateRequest.CustomFields, requestID)
+	guidItems, err := prepareLegacyMetadata(ctx, c, tppCertificateRequest.CustomFields, requestID)
 	if err != nil {
 		log.Println(err)
 		return
 	}
 	requestData := metadataSetRequest{requestID, guidItems, true}
 	//c.request with the metadata request
-	_, err = c.setCertificateMetadata(requestData)
+	_, err = c.setCertificateMetadata(ctx, requestData)
 	if err != nil {
 		log.Println(err)
 	}
@@ -766,7 +954,7 @@ func (c *Connector) RequestCertificate(req *certificate.Request) (requestID stri
 }
 
 // SynchronousRequestCertificate It's not supported yet in TPP
-func (c *Connector) SynchronousRequestCertificate(_ *certificate.Request) (certificates *certificate.PEMCollection, err error) {
+func (c *Connector) SynchronousRequestCertificate(ctx context.Context, _ *certificate.Request) (certificates *certificate.PEMCollection, err error) {
 	panic("operation is not supported yet")
 }
 
@@ -795,10 +983,10 @@ func IsCertNotFound(err error) bool {
 // This function is idempotent, i.e., it won't fail if there is nothing to be
 // reset. It returns an error of type *ErrCertNotFound if the certificate is not
 // found.
-func (c *Connector) ResetCertificate(req *certificate.Request, restart bool) (err error) {
+func (c *Connector) ResetCertificate(ctx context.Context, req *certificate.Request, restart bool) (err error) {
 	certificateDN := getCertificateDN(c.zone, req.FriendlyName, req.Subject.CommonName)
 
-	statusCode, status, body, err := c.request("POST", urlResourceCertificateReset, certificateResetRequest{
+	statusCode, status, body, err := c.request(ctx, "POST", urlResourceCertificateReset, certificateResetRequest{
 		CertificateDN: certificateDN,
 		Restart:       restart,
 	})
@@ -830,7 +1018,7 @@ func (c *Connector) ResetCertificate(req *certificate.Request, restart bool) (er
 	}
 }
 
-func (c *Connector) GetPolicy(name string) (*policy.PolicySpecification, error) {
+func (c *Connector) GetPolicy(ctx context.Context, name string) (*policy.PolicySpecification, error) {
 	var ps *policy.PolicySpecification
 	var tp policy.TppPolicy
 
@@ -852,7 +1040,7 @@ func (c *Connector) GetPolicy(name string) (*policy.PolicySpecification, error)
 	req := policy.CheckPolicyRequest{
 		PolicyDN: name,
 	}
-	_, _, body, err := c.request("POST", urlResourceCheckPolicy, req)
+	_, _, body, err := c.request(ctx, "POST", urlResourceCheckPolicy, req)
 
 	if err != nil {
 		return nil, err
@@ -873,7 +1061,7 @@ func (c *Connector) GetPolicy(name string) (*policy.PolicySpecification, error)
 		return nil, err
 	}
 
-	userNames, error := c.retrieveUserNamesForPolicySpecification(name)
+	userNames, error := c.retrieveUserNamesForPolicySpecification(ctx, name)
 	if error != nil {
 		return nil, error
 	}
@@ -882,8 +1070,8 @@ func (c *Connector) GetPolicy(name string) (*policy.PolicySpecification, error)
 	return ps, nil
 }
 
-func (c *Connector) retrieveUserNamesForPolicySpecification(policyName string) ([]string, error) {
-	values, _, error := getPolicyAttribute(c, policy.TppContact, policyName)
+func (c *Connector) retrieveUserNamesForPolicySpecification(ctx context.Context, policyName string) ([]string, error) {
+	values, _, error := getPolicyAttribute(ctx, c, policy.TppContact, policyName)
 	if error != nil {
 		return nil, error
 	}
@@ -896,7 +1084,7 @@ func (c *Connector) retrieveUserNamesForPolicySpecification(policyName string) (
 				},
 			}
 
-			validateIdentityResponse, error := c.validateIdentity(validateIdentityRequest)
+			validateIdentityResponse, error := c.validateIdentity(ctx, validateIdentityRequest)
 			if error != nil {
 				return nil, error
 			}
@@ -910,9 +1098,9 @@ func (c *Connector) retrieveUserNamesForPolicySpecification(policyName string) (
 	return nil, nil
 }
 
-func (c *Connector) validateIdentity(validateIdentityRequest policy.ValidateIdentityRequest) (*policy.ValidateIdentityResponse, error) {
+func (c *Connector) validateIdentity(ctx context.Context, validateIdentityRequest policy.ValidateIdentityRequest) (*policy.ValidateIdentityResponse, error) {
 
-	statusCode, status, body, err := c.request("POST", urlResourceValidateIdentity, validateIdentityRequest)
+	statusCode, status, body, err := c.request(ctx, "POST", urlResourceValidateIdentity, validateIdentityRequest)
 	if err != nil {
 		return nil, err
 	}
@@ -923,12 +1111,12 @@ func (c *Connector) validateIdentity(validateIdentityRequest policy.ValidateIden
 	return &validateIdentityResponse, nil
 }
 
-func PolicyExist(policyName string, c *Connector) (bool, error) {
+func PolicyExist(ctx context.Context, policyName string, c *Connector) (bool, error) {
 
 	req := policy.PolicyExistPayloadRequest{
 		ObjectDN: policyName,
 	}
-	_, _, body, err := c.request("POST", urlResourceIsValidPolicy, req)
+	_, _, body, err := c.request(ctx, "POST", urlResourceIsValidPolicy, req)
 
 	if err != nil {
 		return false, err
@@ -951,7 +1139,7 @@ func PolicyExist(policyName string, c *Connector) (bool, error) {
 
 }
 
-func (c *Connector) SetPolicy(name string, ps *policy.PolicySpecification) (string, error) {
+func (c *Connector) SetPolicy(ctx context.Context, name string, ps *policy.PolicySpecification) (string, error) {
 
 	//validate policy specification and policy
 	err := policy.ValidateTppPolicySpecification(ps)
@@ -973,9 +1161,12 @@ func (c *Connector) SetPolicy(name string, ps *policy.PolicySpecification) (stri
 	}
 
 	tppPolicy.Name = &name
+	if c.zoneCache != nil {
+		c.zoneCache.Invalidate(c.baseURL, getPolicyDN(name))
+	}
 
 	//validate if the policy exists
-	policyExists, err := PolicyExist(name, c)
+	policyExists, err := PolicyExist(ctx, name, c)
 	if err != nil {
 		return "", err
 	}
@@ -987,7 +1178,7 @@ func (c *Connector) SetPolicy(name string, ps *policy.PolicySpecification) (stri
 		//validate if the parent exist
 		parent := policy.GetParent(name)
 
-		parentExist, err := PolicyExist(parent, c)
+		parentExist, err := PolicyExist(ctx, parent, c)
 		if err != nil {
 			return "", err
 		}
@@ -1009,7 +1200,7 @@ func (c *Connector) SetPolicy(name string, ps *policy.PolicySpecification) (stri
 			ObjectDN: *(tppPolicy.Name),
 		}
 
-		_, _, _, err = c.request("POST", urlResourceCreatePolicy, req)
+		_, _, _, err = c.request(ctx, "POST", urlResourceCreatePolicy, req)
 
 		if err != nil {
 			return "", err
@@ -1021,27 +1212,27 @@ func (c *Connector) SetPolicy(name string, ps *policy.PolicySpecification) (stri
 
 	//create Approver
 	if tppPolicy.Approver != nil {
-		_, _, _, err = createPolicyAttribute(c, policy.TppApprover, tppPolicy.Approver, *(tppPolicy.Name), true)
+		_, _, _, err = createPolicyAttribute(ctx, c, policy.TppApprover, tppPolicy.Approver, *(tppPolicy.Name), true)
 		if err != nil {
 			return "", err
 		}
 	}
 	if policyExists {
-		err = resetTPPAttributes(*(tppPolicy.Name), c)
+		err = resetTPPAttributes(ctx, *(tppPolicy.Name), c)
 		if err != nil {
 			return "", err
 		}
 	}
 
 	//set Contacts
-	status, err = c.setContact(&tppPolicy)
+	status, err = c.setContact(ctx, &tppPolicy)
 	if err != nil {
 		return "", err
 	}
 
 	//create Domain Suffix Whitelist
 	if tppPolicy.ManagementType != nil {
-		_, status, _, err = createPolicyAttribute(c, policy.TppManagementType, []string{tppPolicy.ManagementType.Value}, *(tppPolicy.Name), tppPolicy.ManagementType.Locked)
+		_, status, _, err = createPolicyAttribute(ctx, c, policy.TppManagementType, []string{tppPolicy.ManagementType.Value}, *(tppPolicy.Name), tppPolicy.ManagementType.Locked)
 		if err != nil {
 			return "", err
 		}
@@ -1049,7 +1240,7 @@ func (c *Connector) SetPolicy(name string, ps *policy.PolicySpecification) (stri
 
 	//create Domain Suffix Whitelist
 	if tppPolicy.DomainSuffixWhitelist != nil {
-		_, status, _, err = createPolicyAttribute(c, policy.TppDomainSuffixWhitelist, tppPolicy.DomainSuffixWhitelist, *(tppPolicy.Name), true)
+		_, status, _, err = createPolicyAttribute(ctx, c, policy.TppDomainSuffixWhitelist, tppPolicy.DomainSuffixWhitelist, *(tppPolicy.Name), true)
 		if err != nil {
 			return "", err
 		}
@@ -1057,7 +1248,7 @@ func (c *Connector) SetPolicy(name string, ps *policy.PolicySpecification) (stri
 
 	//create Prohibit Wildcard
 	if tppPolicy.ProhibitWildcard != nil {
-		_, status, _, err = createPolicyAttribute(c, policy.TppProhibitWildcard, []string{strconv.Itoa(*(tppPolicy.ProhibitWildcard))}, *(tppPolicy.Name), false)
+		_, status, _, err = createPolicyAttribute(ctx, c, policy.TppProhibitWildcard, []string{strconv.Itoa(*(tppPolicy.ProhibitWildcard))}, *(tppPolicy.Name), false)
 		if err != nil {
 			return "", err
 		}
@@ -1065,7 +1256,7 @@ func (c *Connector) SetPolicy(name string, ps *policy.PolicySpecification) (stri
 
 	//create Certificate Authority
 	if tppPolicy.CertificateAuthority != nil {
-		_, status, _, err = createPolicyAttribute(c, policy.TppCertificateAuthority, []string{*(tppPolicy.CertificateAuthority)}, *(tppPolicy.Name), false)
+		_, status, _, err = createPolicyAttribute(ctx, c, policy.TppCertificateAuthority, []string{*(tppPolicy.CertificateAuthority)}, *(tppPolicy.Name), false)
 		if err != nil {
 			return "", err
 		}
@@ -1073,7 +1264,7 @@ func (c *Connector) SetPolicy(name string, ps *policy.PolicySpecification) (stri
 
 	//create Organization attribute
 	if tppPolicy.Organization != nil {
-		_, status, _, err = createPolicyAttribute(c, policy.TppOrganization, []string{tppPolicy.Organization.Value}, *(tppPolicy.Name), tppPolicy.Organization.Locked)
+		_, status, _, err = createPolicyAttribute(ctx, c, policy.TppOrganization, []string{tppPolicy.Organization.Value}, *(tppPolicy.Name), tppPolicy.Organization.Locked)
 		if err != nil {
 			return "", err
 		}
@@ -1081,14 +1272,14 @@ func (c *Connector) SetPolicy(name string, ps *policy.PolicySpecification) (stri
 
 	//create Organizational Unit attribute
 	if tppPolicy.OrganizationalUnit != nil {
-		_, status, _, err = createPolicyAttribute(c, policy.TppOrganizationalUnit, tppPolicy.OrganizationalUnit.Value, *(tppPolicy.Name), tppPolicy.OrganizationalUnit.Locked)
+		_, status, _, err = createPolicyAttribute(ctx, c, policy.TppOrganizationalUnit, tppPolicy.OrganizationalUnit.Value, *(tppPolicy.Name), tppPolicy.OrganizationalUnit.Locked)
 		if err != nil {
 			return "", err
 		}
 	}
 	//create City attribute
 	if tppPolicy.City != nil {
-		_, status, _, err = createPolicyAttribute(c, policy.TppCity, []string{tppPolicy.City.Value}, *(tppPolicy.Name), tppPolicy.City.Locked)
+		_, status, _, err = createPolicyAttribute(ctx, c, policy.TppCity, []string{tppPolicy.City.Value}, *(tppPolicy.Name), tppPolicy.City.Locked)
 		if err != nil {
 			return "", err
 		}
@@ -1096,7 +1287,7 @@ func (c *Connector) SetPolicy(name string, ps *policy.PolicySpecification) (stri
 
 	//create State attribute
 	if tppPolicy.State != nil {
-		_, status, _, err = createPolicyAttribute(c, policy.TppState, []string{tppPolicy.State.Value}, *(tppPolicy.Name), tppPolicy.State.Locked)
+		_, status, _, err = createPolicyAttribute(ctx, c, policy.TppState, []string{tppPolicy.State.Value}, *(tppPolicy.Name), tppPolicy.State.Locked)
 		if err != nil {
 			return "", err
 		}
@@ -1104,7 +1295,7 @@ func (c *Connector) SetPolicy(name string, ps *policy.PolicySpecification) (stri
 
 	//create Country attribute
 	if tppPolicy.Country != nil {
-		_, status, _, err = createPolicyAttribute(c, policy.TppCountry, []string{tppPolicy.Country.Value}, *(tppPolicy.Name), tppPolicy.Country.Locked)
+		_, status, _, err = createPolicyAttribute(ctx, c, policy.TppCountry, []string{tppPolicy.Country.Value}, *(tppPolicy.Name), tppPolicy.Country.Locked)
 		if err != nil {
 			return "", err
 		}
@@ -1112,7 +1303,7 @@ func (c *Connector) SetPolicy(name string, ps *policy.PolicySpecification) (stri
 
 	//create Key Algorithm attribute
 	if tppPolicy.KeyAlgorithm != nil {
-		_, status, _, err = createPolicyAttribute(c, policy.TppKeyAlgorithm, []string{tppPolicy.KeyAlgorithm.Value}, *(tppPolicy.Name), tppPolicy.KeyAlgorithm.Locked)
+		_, status, _, err = createPolicyAttribute(ctx, c, policy.TppKeyAlgorithm, []string{tppPolicy.KeyAlgorithm.Value}, *(tppPolicy.Name), tppPolicy.KeyAlgorithm.Locked)
 		if err != nil {
 			return "", err
 		}
@@ -1120,7 +1311,7 @@ func (c *Connector) SetPolicy(name string, ps *policy.PolicySpecification) (stri
 
 	//create Key Bit Strength
 	if tppPolicy.KeyBitStrength != nil {
-		_, status, _, err = createPolicyAttribute(c, policy.TppKeyBitStrength, []string{tppPolicy.KeyBitStrength.Value}, *(tppPolicy.Name), tppPolicy.KeyBitStrength.Locked)
+		_, status, _, err = createPolicyAttribute(ctx, c, policy.TppKeyBitStrength, []string{tppPolicy.KeyBitStrength.Value}, *(tppPolicy.Name), tppPolicy.KeyBitStrength.Locked)
 		if err != nil {
 			return "", err
 		}
@@ -1128,7 +1319,7 @@ func (c *Connector) SetPolicy(name string, ps *policy.PolicySpecification) (stri
 
 	//create Elliptic Curve attribute
 	if tppPolicy.EllipticCurve != nil {
-		_, status, _, err = createPolicyAttribute(c, policy.TppEllipticCurve, []string{tppPolicy.EllipticCurve.Value}, *(tppPolicy.Name), tppPolicy.EllipticCurve.Locked)
+		_, status, _, err = createPolicyAttribute(ctx, c, policy.TppEllipticCurve, []string{tppPolicy.EllipticCurve.Value}, *(tppPolicy.Name), tppPolicy.EllipticCurve.Locked)
 		if err != nil {
 			return "", err
 		}
@@ -1136,14 +1327,14 @@ func (c *Connector) SetPolicy(name string, ps *policy.PolicySpecification) (stri
 
 	//create Manual Csr attribute
 	if tppPolicy.ManualCsr != nil {
-		_, status, _, err = createPolicyAttribute(c, policy.ServiceGenerated, []string{tppPolicy.ManualCsr.Value}, *(tppPolicy.Name), tppPolicy.ManualCsr.Locked)
+		_, status, _, err = createPolicyAttribute(ctx, c, policy.ServiceGenerated, []string{tppPolicy.ManualCsr.Value}, *(tppPolicy.Name), tppPolicy.ManualCsr.Locked)
 		if err != nil {
 			return "", err
 		}
 	}
 
 	if tppPolicy.ProhibitedSANType != nil {
-		_, status, _, err = createPolicyAttribute(c, policy.TppProhibitedSANTypes, tppPolicy.ProhibitedSANType, *(tppPolicy.Name), false)
+		_, status, _, err = createPolicyAttribute(ctx, c, policy.TppProhibitedSANTypes, tppPolicy.ProhibitedSANType, *(tppPolicy.Name), false)
 		if err != nil {
 			return "", err
 		}
@@ -1151,14 +1342,14 @@ func (c *Connector) SetPolicy(name string, ps *policy.PolicySpecification) (stri
 
 	//Allow Private Key Reuse" & "Want Renewal
 	if tppPolicy.AllowPrivateKeyReuse != nil {
-		_, status, _, err = createPolicyAttribute(c, policy.TppAllowPrivateKeyReuse, []string{strconv.Itoa(*(tppPolicy.AllowPrivateKeyReuse))}, *(tppPolicy.Name), true)
+		_, status, _, err = createPolicyAttribute(ctx, c, policy.TppAllowPrivateKeyReuse, []string{strconv.Itoa(*(tppPolicy.AllowPrivateKeyReuse))}, *(tppPolicy.Name), true)
 		if err != nil {
 			return "", err
 		}
 	}
 
 	if tppPolicy.WantRenewal != nil {
-		_, status, _, err = createPolicyAttribute(c, policy.TppWantRenewal, []string{strconv.Itoa(*(tppPolicy.WantRenewal))}, *(tppPolicy.Name), true)
+		_, status, _, err = createPolicyAttribute(ctx, c, policy.TppWantRenewal, []string{strconv.Itoa(*(tppPolicy.WantRenewal))}, *(tppPolicy.Name), true)
 		if err != nil {
 			return "", err
 		}
@@ -1169,17 +1360,17 @@ func (c *Connector) SetPolicy(name string, ps *policy.PolicySpecification) (stri
 	return status, nil
 }
 
-func (c *Connector) setContact(tppPolicy *policy.TppPolicy) (status string, err error) {
+func (c *Connector) setContact(ctx context.Context, tppPolicy *policy.TppPolicy) (status string, err error) {
 
 	if tppPolicy.Contact != nil {
-		contacts, err := c.resolveContacts(tppPolicy.Contact)
+		contacts, err := c.resolveContacts(ctx, tppPolicy.Contact)
 		if err != nil {
 			return "", fmt.Errorf("an error happened trying to resolve the contacts: %w", err)
 		}
 		if contacts != nil {
 			tppPolicy.Contact = contacts
 
-			_, status, _, err = createPolicyAttribute(c, policy.TppContact, tppPolicy.Contact, *(tppPolicy.Name), true)
+			_, status, _, err = createPolicyAttribute(ctx, c, policy.TppContact, tppPolicy.Contact, *(tppPolicy.Name), true)
 			if err != nil {
 				return "", err
 			}
@@ -1189,11 +1380,11 @@ func (c *Connector) setContact(tppPolicy *policy.TppPolicy) (status string, err
 	return status, nil
 }
 
-func (c *Connector) resolveContacts(contacts []string) ([]string, error) {
+func (c *Connector) resolveContacts(ctx context.Context, contacts []string) ([]string, error) {
 	var identities []string
 	uniqueContacts := getUniqueStringSlice(contacts)
 	for _, contact := range uniqueContacts {
-		identity, err := c.getIdentity(contact)
+		identity, err := c.getIdentity(ctx, contact)
 		if err != nil {
 			return nil, err
 		}
@@ -1215,7 +1406,7 @@ func getUniqueStringSlice(stringSlice []string) []string {
 	return list
 }
 
-func (c *Connector) getIdentity(userName string) (*policy.IdentityEntry, error) {
+func (c *Connector) getIdentity(ctx context.Context, userName string) (*policy.IdentityEntry, error) {
 	if userName == "" {
 		return nil, fmt.Errorf("identity string cannot be null")
 	}
@@ -1226,7 +1417,7 @@ func (c *Connector) getIdentity(userName string) (*policy.IdentityEntry, error)
 		IdentityType: policy.AllIdentities,
 	}
 
-	resp, err := c.browseIdentities(req)
+	resp, err := c.browseIdentities(ctx, req)
 	if err != nil {
 		return nil, err
 	}
@@ -1255,9 +1446,9 @@ func (c *Connector) getIdentityMatching(identities []policy.IdentityEntry, ident
 	}
 }
 
-func (c *Connector) browseIdentities(browseReq policy.BrowseIdentitiesRequest) (*policy.BrowseIdentitiesResponse, error) {
+func (c *Connector) browseIdentities(ctx context.Context, browseReq policy.BrowseIdentitiesRequest) (*policy.BrowseIdentitiesResponse, error) {
 
-	statusCode, status, body, err := c.request("POST", urlResourceBrowseIdentities, browseReq)
+	statusCode, status, body, err := c.request(ctx, "POST", urlResourceBrowseIdentities, browseReq)
 	if err != nil {
 		return nil, err
 	}
@@ -1269,22 +1460,30 @@ func (c *Connector) browseIdentities(browseReq policy.BrowseIdentitiesRequest) (
 }
 
 // RetrieveCertificate attempts to retrieve the requested certificate
-func (c *Connector) RetrieveCertificate(req *certificate.Request) (certificates *certificate.PEMCollection, err error) {
+func (c *Connector) RetrieveCertificate(ctx context.Context, req *certificate.Request) (certificates *certificate.PEMCollection, err error) {
 
 	includeChain := req.ChainOption != certificate.ChainOptionIgnore
 	rootFirstOrder := includeChain && req.ChainOption == certificate.ChainOptionRootFirst
 
-	if req.PickupID == "" && req.Thumbprint != "" {
-		// search cert by Thumbprint and fill pickupID
-		searchResult, err := c.searchCertificatesByFingerprint(req.Thumbprint)
+	if req.PickupID == "" && (req.Thumbprint != "" || req.SerialNumber != "") {
+		// search cert by Thumbprint or serial number and fill pickupID
+		var searchResult *certificate.CertSearchResponse
+		searchedBy := fmt.Sprintf("fingerprint %s", req.Thumbprint)
+		if req.Thumbprint != "" {
+			searchResult, err = c.searchCertificatesByFingerprint(ctx, req.Thumbprint)
+		} else {
+			searchedBy = fmt.Sprintf("serial number %s", req.SerialNumber)
+			searchResult, err = c.searchCertificatesBySerialNumber(ctx, req.SerialNumber)
+		}
 		if err != nil {
-			return nil, fmt.Errorf("Failed to create renewal request: %s", err)
+			return nil, fmt.Errorf("Failed to search certificate: %s", err)
 		}
 		if len(searchResult.Certificates) == 0 {
-			return nil, fmt.Errorf("No certificate found using fingerprint %s", req.Thumbprint)
+			return nil, fmt.Errorf("No certificate found using %s", searchedBy)
 		}
 		if len(searchResult.Certificates) > 1 {
-			return nil, fmt.Errorf("Error: more than one CertificateRequestId was found with the same thumbprint")
+			// serial numbers are only unique for an issuer
+			return nil, fmt.Errorf("Error: more than one CertificateRequestId was found using %s", searchedBy)
 		}
 		req.PickupID = searchResult.Certificates[0].CertificateRequestId
 	}
@@ -1301,17 +1500,24 @@ func (c *Connector) RetrieveCertificate(req *certificate.Request) (certificates
 			certReq.Format = "Base64 (PKCS #8)"
 		}
 		certReq.Password = req.KeyPassword
+		if req.RetrievePKCS12 {
+			certReq.Format = "PKCS #12"
+		}
 	}
 
 	startTime := time.Now()
 	for {
 		var retrieveResponse *certificateRetrieveResponse
-		retrieveResponse, err = c.retrieveCertificateOnce(certReq)
+		retrieveResponse, err = c.retrieveCertificateOnce(ctx, certReq)
 		if err != nil {
 			return nil, fmt.Errorf("unable to retrieve: %s", err)
 		}
 		if retrieveResponse.CertificateData != "" {
-			certificates, err = newPEMCollectionFromResponse(retrieveResponse.CertificateData, req.ChainOption)
+			if certReq.Format == "PKCS #12" {
+				certificates, err = newPEMCollectionFromPKCS12Response(retrieveResponse.CertificateData, req.KeyPassword, req.ChainOption)
+			} else {
+				certificates, err = newPEMCollectionFromResponse(retrieveResponse.CertificateData, req.ChainOption)
+			}
 			if err != nil {
 				return
 			}
@@ -1324,12 +1530,14 @@ func (c *Connector) RetrieveCertificate(req *certificate.Request) (certificates
 		if time.Now().After(startTime.Add(req.Timeout)) {
 			return nil, endpoint.ErrRetrieveCertificateTimeout{CertificateID: req.PickupID}
 		}
-		time.Sleep(2 * time.Second)
+		if err = util.Sleep(ctx, 2*time.Second); err != nil {
+			return nil, err
+		}
 	}
 }
 
-func (c *Connector) retrieveCertificateOnce(certReq certificateRetrieveRequest) (*certificateRetrieveResponse, error) {
-	statusCode, status, body, err := c.request("POST", urlResourceCertificateRetrieve, certReq)
+func (c *Connector) retrieveCertificateOnce(ctx context.Context, certReq certificateRetrieveRequest) (*certificateRetrieveResponse, error) {
+	statusCode, status, body, err := c.request(ctx, "POST", urlResourceCertificateRetrieve, certReq)
 	if err != nil {
 		return nil, err
 	}
@@ -1340,12 +1548,12 @@ func (c *Connector) retrieveCertificateOnce(certReq certificateRetrieveRequest)
 	return &retrieveResponse, nil
 }
 
-func (c *Connector) putCertificateInfo(dn string, attributes []nameSliceValuePair) error {
-	guid, err := c.configDNToGuid(dn)
+func (c *Connector) putCertificateInfo(ctx context.Context, dn string, attributes []nameSliceValuePair) error {
+	guid, err := c.configDNToGuid(ctx, dn)
 	if err != nil {
 		return err
 	}
-	statusCode, _, _, err := c.request("PUT", urlResourceCertificate+urlResource(guid), struct{ AttributeData []nameSliceValuePair }{attributes})
+	statusCode, _, _, err := c.request(ctx, "PUT", urlResourceCertificate+urlResource(guid), struct{ AttributeData []nameSliceValuePair }{attributes})
 	if err != nil {
 		return err
 	}
@@ -1355,7 +1563,7 @@ func (c *Connector) putCertificateInfo(dn string, attributes []nameSliceValuePai
 	return nil
 }
 
-func (c *Connector) prepareRenewalRequest(renewReq *certificate.RenewalRequest) error {
+func (c *Connector) prepareRenewalRequest(ctx context.Context, renewReq *certificate.RenewalRequest) error {
 	if renewReq.CertificateRequest != nil && len(renewReq.CertificateRequest.GetCSR()) != 0 {
 		return nil
 	}
@@ -1365,7 +1573,7 @@ func (c *Connector) prepareRenewalRequest(renewReq *certificate.RenewalRequest)
 	}
 
 	// here we fetch old cert anyway
-	oldPcc, err := c.RetrieveCertificate(searchReq)
+	oldPcc, err := c.RetrieveCertificate(ctx, searchReq)
 	if err != nil {
 		return fmt.Errorf("Failed to fetch old certificate by id %s: %s", renewReq.CertificateDN, err)
 	}
@@ -1380,15 +1588,15 @@ func (c *Connector) prepareRenewalRequest(renewReq *certificate.RenewalRequest)
 	if renewReq.CertificateRequest == nil {
 		renewReq.CertificateRequest = certificate.NewRequest(oldCert)
 	}
-	err = c.GenerateRequest(&endpoint.ZoneConfiguration{}, renewReq.CertificateRequest)
+	err = c.GenerateRequest(ctx, &endpoint.ZoneConfiguration{}, renewReq.CertificateRequest)
 	return err
 }
 
 // RenewCertificate attempts to renew the certificate
-func (c *Connector) RenewCertificate(renewReq *certificate.RenewalRequest) (requestID string, err error) {
+func (c *Connector) RenewCertificate(ctx context.Context, renewReq *certificate.RenewalRequest) (requestID string, err error) {
 	if renewReq.Thumbprint != "" && renewReq.CertificateDN == "" {
 		// search by Thumbprint and fill *renewReq.CertificateDN
-		searchResult, err := c.searchCertificatesByFingerprint(renewReq.Thumbprint)
+		searchResult, err := c.searchCertificatesByFingerprint(ctx, renewReq.Thumbprint)
 		if err != nil {
 			return "", fmt.Errorf("Failed to create renewal request: %s", err)
 		}
@@ -1407,7 +1615,7 @@ func (c *Connector) RenewCertificate(renewReq *certificate.RenewalRequest) (requ
 	if renewReq.CertificateRequest != nil && renewReq.CertificateRequest.OmitSANs {
 		// if OmitSANSs flag is presented we need to clean SANs values in TPP
 		// for preventing adding them to renew request on TPP side
-		err = c.putCertificateInfo(renewReq.CertificateDN, []nameSliceValuePair{
+		err = c.putCertificateInfo(ctx, renewReq.CertificateDN, []nameSliceValuePair{
 			{"X509 SubjectAltName DNS", nil},
 			{"X509 SubjectAltName IPAddress", nil},
 			{"X509 SubjectAltName RFC822", nil},
@@ -1427,7 +1635,7 @@ func (c *Connector) RenewCertificate(renewReq *certificate.RenewalRequest) (requ
 	if renewReq.CertificateRequest != nil && len(renewReq.CertificateRequest.GetCSR()) != 0 {
 		r.PKCS10 = string(renewReq.CertificateRequest.GetCSR())
 	}
-	statusCode, status, body, err := c.request("POST", urlResourceCertificateRenew, r)
+	statusCode, status, body, err := c.request(ctx, "POST", urlResourceCertificateRenew, r)
 	if err != nil {
 		return "", err
 	}
@@ -1443,7 +1651,7 @@ func (c *Connector) RenewCertificate(renewReq *certificate.RenewalRequest) (requ
 }
 
 // RevokeCertificate attempts to revoke the certificate
-func (c *Connector) RevokeCertificate(revReq *certificate.RevocationRequest) (err error) {
+func (c *Connector) RevokeCertificate(ctx context.Context, revReq *certificate.RevocationRequest) (err error) {
 	reason, ok := RevocationReasonsMap[revReq.Reason]
 	if !ok {
 		return fmt.Errorf("could not parse revocation reason `%s`", revReq.Reason)
@@ -1456,7 +1664,7 @@ func (c *Connector) RevokeCertificate(revReq *certificate.RevocationRequest) (er
 		revReq.Comments,
 		revReq.Disable,
 	}
-	statusCode, status, body, err := c.request("POST", urlResourceCertificateRevoke, r)
+	statusCode, status, body, err := c.request(ctx, "POST", urlResourceCertificateRevoke, r)
 	if err != nil {
 		return err
 	}
@@ -1470,11 +1678,11 @@ func (c *Connector) RevokeCertificate(revReq *certificate.RevocationRequest) (er
 	return
 }
 
-func (c *Connector) RetireCertificate(req *certificate.RetireRequest) (err error) {
+func (c *Connector) RetireCertificate(ctx context.Context, req *certificate.RetireRequest) (err error) {
 
 	if req.CertificateDN == "" && req.Thumbprint != "" {
 		// search cert by Thumbprint and fill pickupID
-		searchResult, err := c.searchCertificatesByFingerprint(req.Thumbprint)
+		searchResult, err := c.searchCertificatesByFingerprint(ctx, req.Thumbprint)
 		if err != nil {
 			return fmt.Errorf("Failed to create retire request: %s", err)
 		}
@@ -1491,18 +1699,26 @@ func (c *Connector) RetireCertificate(req *certificate.RetireRequest) (err error
 
 	retireSliceValuePair := []nameSliceValuePair{{Name: "Disabled", Value: []string{"1"}}}
 
-	err = c.putCertificateInfo(req.CertificateDN, retireSliceValuePair)
+	err = c.putCertificateInfo(ctx, req.CertificateDN, retireSliceValuePair)
 	return err
 }
 
 var zoneNonFoundregexp = regexp.MustCompile("PolicyDN: .+ does not exist")
 
-func (c *Connector) ReadPolicyConfiguration() (policy *endpoint.Policy, err error) {
+func (c *Connector) ReadPolicyConfiguration(ctx context.Context) (policy *endpoint.Policy, err error) {
 	if c.zone == "" {
 		return nil, fmt.Errorf("empty zone")
 	}
+	if c.zoneCache != nil {
+		// the zone configuration holds the same policy, and is the one cached
+		config, err := c.ReadZoneConfiguration(ctx)
+		if err != nil {
+			return nil, err
+		}
+		return &config.Policy, nil
+	}
 	rq := struct{ PolicyDN string }{getPolicyDN(c.zone)}
-	statusCode, status, body, err := c.request("POST", urlResourceCertificatePolicy, rq)
+	statusCode, status, body, err := c.request(ctx, "POST", urlResourceCertificatePolicy, rq)
 	if err != nil {
 		return
 	}
@@ -1532,14 +1748,20 @@ func (c *Connector) ReadPolicyConfiguration() (policy *endpoint.Policy, err erro
 }
 
 // ReadZoneConfiguration reads the policy data from TPP to get locked and pre-configured values for certificate requests
-func (c *Connector) ReadZoneConfiguration() (config *endpoint.ZoneConfiguration, err error) {
+func (c *Connector) ReadZoneConfiguration(ctx context.Context) (config *endpoint.ZoneConfiguration, err error) {
 	if c.zone == "" {
 		return nil, fmt.Errorf("empty zone")
 	}
+	if c.zoneCache != nil {
+		config, ok := c.zoneCache.Get(c.baseURL, getPolicyDN(c.zone))
+		if ok {
+			return config, nil
+		}
+	}
 	zoneConfig := endpoint.NewZoneConfiguration()
 	zoneConfig.HashAlgorithm = x509.SHA256WithRSA //todo: check this can have problem with ECDSA key
 	rq := struct{ PolicyDN string }{getPolicyDN(c.zone)}
-	statusCode, status, body, err := c.request("POST", urlResourceCertificatePolicy, rq)
+	statusCode, status, body, err := c.request(ctx, "POST", urlResourceCertificatePolicy, rq)
 	if err != nil {
 		return
 	}
@@ -1555,6 +1777,9 @@ func (c *Connector) ReadZoneConfiguration() (config *endpoint.ZoneConfiguration,
 		p := r.Policy.toPolicy()
 		r.Policy.toZoneConfig(zoneConfig)
 		zoneConfig.Policy = p
+		if c.zoneCache != nil {
+			c.zoneCache.Set(c.baseURL, getPolicyDN(c.zone), zoneConfig)
+		}
 		return zoneConfig, nil
 	} else if statusCode == http.StatusBadRequest {
 		err = json.Unmarshal(body, &r)
@@ -1569,7 +1794,7 @@ func (c *Connector) ReadZoneConfiguration() (config *endpoint.ZoneConfiguration,
 
 }
 
-func (c *Connector) ImportCertificate(req *certificate.ImportRequest) (*certificate.ImportResponse, error) {
+func (c *Connector) ImportCertificate(ctx context.Context, req *certificate.ImportRequest) (*certificate.ImportResponse, error) {
 	r := importRequest{
 		PolicyDN:        req.PolicyDN,
 		ObjectName:      req.ObjectName,
@@ -1589,7 +1814,7 @@ func (c *Connector) ImportCertificate(req *certificate.ImportRequest) (*certific
 			origin = f.Value + " (+)"
 		}
 	}
-	statusCode, _, body, err := c.request("POST", urlResourceCertificateImport, r)
+	statusCode, _, body, err := c.request(ctx, "POST", urlResourceCertificateImport, r)
 	if err != nil {
 		return nil, fmt.Errorf("%w: %v", verror.ServerTemporaryUnavailableError, err)
 	}
@@ -1600,7 +1825,7 @@ func (c *Connector) ImportCertificate(req *certificate.ImportRequest) (*certific
 		if err != nil {
 			return nil, fmt.Errorf("%w: failed to decode import response message: %s", verror.ServerError, err)
 		}
-		err = c.putCertificateInfo(response.CertificateDN, []nameSliceValuePair{{Name: "Origin", Value: []string{origin}}})
+		err = c.putCertificateInfo(ctx, response.CertificateDN, []nameSliceValuePair{{Name: "Origin", Value: []string{origin}}})
 		if err != nil {
 			log.Println(err)
 		}
@@ -1617,12 +1842,12 @@ func (c *Connector) ImportCertificate(req *certificate.ImportRequest) (*certific
 	}
 }
 
-func (c *Connector) SearchCertificates(req *certificate.SearchRequest) (*certificate.CertSearchResponse, error) {
+func (c *Connector) SearchCertificates(ctx context.Context, req *certificate.SearchRequest) (*certificate.CertSearchResponse, error) {
 
 	var err error
 
 	url := fmt.Sprintf("%s?%s", urlResourceCertificateSearch, strings.Join(*req, "&"))
-	statusCode, _, body, err := c.request("GET", urlResource(url), nil)
+	statusCode, _, body, err := c.request(ctx, "GET", urlResource(url), nil)
 	if err != nil {
 		return nil, err
 	}
@@ -1633,13 +1858,13 @@ func (c *Connector) SearchCertificates(req *certificate.SearchRequest) (*certifi
 	return searchResult, nil
 }
 
-func (c *Connector) SearchCertificate(zone string, cn string, sans *certificate.Sans, certMinTimeLeft time.Duration) (certificateInfo *certificate.CertificateInfo, err error) {
+func (c *Connector) SearchCertificate(ctx context.Context, zone string, cn string, sans *certificate.Sans, certMinTimeLeft time.Duration) (certificateInfo *certificate.CertificateInfo, err error) {
 	// format arguments for request
 	req := formatSearchCertificateArguments(cn, sans, certMinTimeLeft)
 
 	// perform request
 	url := fmt.Sprintf("%s?%s", urlResourceCertificateSearch, req)
-	statusCode, _, body, err := c.request("GET", urlResource(url), nil)
+	statusCode, _, body, err := c.request(ctx, "GET", urlResource(url), nil)
 	if err != nil {
 		return nil, err
 	}
@@ -1681,8 +1906,39 @@ func (c *Connector) SetHTTPClient(client *http.Client) {
 	c.client = client
 }
 
-func (c *Connector) WriteLog(logReq *endpoint.LogRequest) error {
-	statusCode, httpStatus, body, err := c.request("POST", urlResourceLog, logReq)
+// SetRetryPolicy sets how requests rate limited by TPP, or failing with transient errors, are retried.
+// It has no effect on the client set with SetHTTPClient, nor once the connector has sent its first request
+func (c *Connector) SetRetryPolicy(policy util.RetryPolicy) {
+	c.retryPolicy = policy
+}
+
+// SetProxy sets the proxy the requests to TPP are sent through, instead of the one of the environment variables.
+// It has no effect on the client set with SetHTTPClient, nor once the connector has sent its first request
+func (c *Connector) SetProxy(proxy util.ProxyConfig) {
+	c.proxy = proxy
+}
+
+// SetTransportConfig tunes the HTTP transport of the requests to TPP, shared with the other connectors built with the
+// same settings. It has no effect on the client set with SetHTTPClient, nor once the connector has sent its first request
+func (c *Connector) SetTransportConfig(config util.TransportConfig) {
+	c.transport = config
+}
+
+// SetTLSConfig sets the TLS settings of the requests to TPP, like the client certificate, instead of the ones of
+// http.DefaultTransport. The trust bundle of the connector replaces its root CAs. It has no effect on the client set
+// with SetHTTPClient, nor once the connector has sent its first request
+func (c *Connector) SetTLSConfig(config *tls.Config) {
+	c.tlsConfig = config
+}
+
+// SetZoneConfigurationCache sets the cache of the zone configurations read by the connector, usually shared with
+// other connectors. The zone configuration is read from TPP on every request when no cache is set
+func (c *Connector) SetZoneConfigurationCache(cache *endpoint.ZoneConfigurationCache) {
+	c.zoneCache = cache
+}
+
+func (c *Connector) WriteLog(ctx context.Context, logReq *endpoint.LogRequest) error {
+	statusCode, httpStatus, body, err := c.request(ctx, "POST", urlResourceLog, logReq)
 	if err != nil {
 		return err
 	}
@@ -1694,7 +1950,7 @@ func (c *Connector) WriteLog(logReq *endpoint.LogRequest) error {
 	return nil
 }
 
-func (c *Connector) ListCertificates(filter endpoint.Filter) ([]certificate.CertificateInfo, error) {
+func (c *Connector) ListCertificates(ctx context.Context, filter endpoint.Filter) ([]certificate.CertificateInfo, error) {
 	if c.zone == "" {
 		return nil, fmt.Errorf("empty zone")
 	}
@@ -1713,7 +1969,7 @@ func (c *Connector) ListCertificates(filter endpoint.Filter) ([]certificate.Cert
 	for offset := 0; limit > 0; limit, offset = limit-batchSize, offset+batchSize {
 		var b []certificate.CertificateInfo
 		var err error
-		b, err = c.getCertsBatch(offset, min(limit, batchSize), filter.WithExpired)
+		b, err = c.getCertsBatch(ctx, offset, min(limit, batchSize), filter.WithExpired)
 		if err != nil {
 			return nil, err
 		}
@@ -1735,7 +1991,7 @@ func (c *Connector) ListCertificates(filter endpoint.Filter) ([]certificate.Cert
 	return infos, nil
 }
 
-func (c *Connector) getCertsBatch(offset, limit int, withExpired bool) ([]certificate.CertificateInfo, error) {
+func (c *Connector) getCertsBatch(ctx context.Context, offset, limit int, withExpired bool) ([]certificate.CertificateInfo, error) {
 	url := urlResourceCertificatesList + urlResource(
 		"?ParentDNRecursive="+neturl.QueryEscape(getPolicyDN(c.zone))+
 			"&limit="+fmt.Sprintf("%d", limit)+
@@ -1743,7 +1999,7 @@ func (c *Connector) getCertsBatch(offset, limit int, withExpired bool) ([]certif
 	if !withExpired {
 		url += urlResource("&ValidToGreater=" + neturl.QueryEscape(time.Now().Format(time.RFC3339)))
 	}
-	statusCode, status, body, err := c.request("GET", url, nil)
+	statusCode, status, body, err := c.request(ctx, "GET", url, nil)
 	if err != nil {
 		return nil, err
 	}
@@ -1779,7 +2035,7 @@ func parseHostPort(s string) (host string, port string, err error) {
 	return
 }
 
-func (c *Connector) dissociate(certDN, applicationDN string) error {
+func (c *Connector) dissociate(ctx context.Context, certDN, applicationDN string) error {
 	req := struct {
 		CertificateDN string
 		ApplicationDN []string
@@ -1790,7 +2046,7 @@ func (c *Connector) dissociate(certDN, applicationDN string) error {
 		true,
 	}
 	log.Println("Dissociating device", applicationDN)
-	statusCode, status, body, err := c.request("POST", urlResourceCertificatesDissociate, req)
+	statusCode, status, body, err := c.request(ctx, "POST", urlResourceCertificatesDissociate, req)
 	if err != nil {
 		return err
 	}
@@ -1800,18 +2056,18 @@ func (c *Connector) dissociate(certDN, applicationDN string) error {
 	return nil
 }
 
-func (c *Connector) associate(certDN, applicationDN string, pushToNew bool) error {
+func (c *Connector) associate(ctx context.Context, certDN string, applicationDNs []string, pushToNew bool) error {
 	req := struct {
 		CertificateDN string
 		ApplicationDN []string
 		PushToNew     bool
 	}{
 		certDN,
-		[]string{applicationDN},
+		applicationDNs,
 		pushToNew,
 	}
-	log.Println("Associating device", applicationDN)
-	statusCode, status, body, err := c.request("POST", urlResourceCertificatesAssociate, req)
+	log.Println("Associating applications", applicationDNs)
+	statusCode, status, body, err := c.request(ctx, "POST", urlResourceCertificatesAssociate, req)
 	if err != nil {
 		return err
 	}
@@ -1822,7 +2078,7 @@ func (c *Connector) associate(certDN, applicationDN string, pushToNew bool) erro
 	return nil
 }
 
-func (c *Connector) configDNToGuid(objectDN string) (guid string, err error) {
+func (c *Connector) configDNToGuid(ctx context.Context, objectDN string) (guid string, err error) {
 
 	req := struct {
 		ObjectDN string
@@ -1839,7 +2095,7 @@ func (c *Connector) configDNToGuid(objectDN string) (guid string, err error) {
 	}
 
 	log.Println("Getting guid for object DN", objectDN)
-	statusCode, status, body, err := c.request("POST", urlResourceConfigDnToGuid, req)
+	statusCode, status, body, err := c.request(ctx, "POST", urlResourceConfigDnToGuid, req)
 
 	if err != nil {
 		return guid, err
@@ -1870,8 +2126,8 @@ func (c *Connector) configDNToGuid(objectDN string) (guid string, err error) {
 
 }
 
-func (c *Connector) findObjectsOfClass(req *findObjectsOfClassRequest) (*findObjectsOfClassResponse, error) {
-	statusCode, statusString, body, err := c.request("POST", urlResourceFindObjectsOfClass, req)
+func (c *Connector) findObjectsOfClass(ctx context.Context, req *findObjectsOfClassRequest) (*findObjectsOfClassResponse, error) {
+	statusCode, statusString, body, err := c.request(ctx, "POST", urlResourceFindObjectsOfClass, req)
 	if err != nil {
 		return nil, err
 	}
@@ -1883,7 +2139,7 @@ func (c *Connector) findObjectsOfClass(req *findObjectsOfClassRequest) (*findObj
 }
 
 // GetZonesByParent returns a list of valid zones for a TPP parent folder specified by parent
-func (c *Connector) GetZonesByParent(parent string) ([]string, error) {
+func (c *Connector) GetZonesByParent(ctx context.Context, parent string) ([]string, error) {
 	var zones []string
 
 	parentFolderDn := parent
@@ -1895,7 +2151,7 @@ func (c *Connector) GetZonesByParent(parent string) ([]string, error) {
 		Class:    "Policy",
 		ObjectDN: parentFolderDn,
 	}
-	response, err := c.findObjectsOfClass(&request)
+	response, err := c.findObjectsOfClass(ctx, &request)
 	if err != nil {
 		return nil, err
 	}
@@ -1907,7 +2163,7 @@ func (c *Connector) GetZonesByParent(parent string) ([]string, error) {
 	return zones, nil
 }
 
-func createPolicyAttribute(c *Connector, at string, av []string, n string, l bool) (statusCode int, statusText string, body []byte, err error) {
+func createPolicyAttribute(ctx context.Context, c *Connector, at string, av []string, n string, l bool) (statusCode int, statusText string, body []byte, err error) {
 
 	request := policy.PolicySetAttributePayloadRequest{
 		Locked:        l,
@@ -1919,7 +2175,7 @@ func createPolicyAttribute(c *Connector, at string, av []string, n string, l boo
 	// if is locked is a policy value
 	// if is not locked then is a default.
 
-	statusCode, statusText, body, err = c.request("POST", urlResourceWritePolicy, request)
+	statusCode, statusText, body, err = c.request(ctx, "POST", urlResourceWritePolicy, request)
 	if err != nil {
 		return statusCode, statusText, body, err
 	}
@@ -1939,7 +2195,7 @@ func createPolicyAttribute(c *Connector, at string, av []string, n string, l boo
 	return statusCode, statusText, body, err
 }
 
-func getPolicyAttribute(c *Connector, at string, n string) (s []string, b *bool, err error) {
+func getPolicyAttribute(ctx context.Context, c *Connector, at string, n string) (s []string, b *bool, err error) {
 
 	request := policy.PolicyGetAttributePayloadRequest{
 		ObjectDN:      n,
@@ -1949,7 +2205,7 @@ func getPolicyAttribute(c *Connector, at string, n string) (s []string, b *bool,
 	}
 	// if is locked is a policy value
 	// if is not locked then is a default.
-	_, _, body, err := c.request("POST", urlResourceReadPolicy, request)
+	_, _, body, err := c.request(ctx, "POST", urlResourceReadPolicy, request)
 	if err != nil {
 		return nil, nil, err
 	}
@@ -1968,104 +2224,104 @@ func getPolicyAttribute(c *Connector, at string, n string) (s []string, b *bool,
 	return nil, nil, nil
 }
 
-func resetTPPAttributes(zone string, c *Connector) error {
+func resetTPPAttributes(ctx context.Context, zone string, c *Connector) error {
 
 	//reset Contact
-	err := resetTPPAttribute(c, policy.TppContact, zone)
+	err := resetTPPAttribute(ctx, c, policy.TppContact, zone)
 	if err != nil {
 		return err
 	}
 
 	//reset Domain Suffix Whitelist
-	err = resetTPPAttribute(c, policy.TppDomainSuffixWhitelist, zone)
+	err = resetTPPAttribute(ctx, c, policy.TppDomainSuffixWhitelist, zone)
 	if err != nil {
 		return err
 	}
 
 	//reset Prohibit Wildcard
-	err = resetTPPAttribute(c, policy.TppProhibitWildcard, zone)
+	err = resetTPPAttribute(ctx, c, policy.TppProhibitWildcard, zone)
 	if err != nil {
 		return err
 	}
 
 	//reset Certificate Authority
-	err = resetTPPAttribute(c, policy.TppCertificateAuthority, zone)
+	err = resetTPPAttribute(ctx, c, policy.TppCertificateAuthority, zone)
 	if err != nil {
 		return err
 	}
 
 	//reset Organization attribute
-	err = resetTPPAttribute(c, policy.TppOrganization, zone)
+	err = resetTPPAttribute(ctx, c, policy.TppOrganization, zone)
 	if err != nil {
 		return err
 	}
 
 	//reset Organizational Unit attribute
-	err = resetTPPAttribute(c, policy.TppOrganizationalUnit, zone)
+	err = resetTPPAttribute(ctx, c, policy.TppOrganizationalUnit, zone)
 	if err != nil {
 		return err
 	}
 
 	//reset City attribute
-	err = resetTPPAttribute(c, policy.TppCity, zone)
+	err = resetTPPAttribute(ctx, c, policy.TppCity, zone)
 	if err != nil {
 		return err
 	}
 
 	//reset State attribute
-	err = resetTPPAttribute(c, policy.TppState, zone)
+	err = resetTPPAttribute(ctx, c, policy.TppState, zone)
 	if err != nil {
 		return err
 	}
 
 	//reset Country attribute
-	err = resetTPPAttribute(c, policy.TppCountry, zone)
+	err = resetTPPAttribute(ctx, c, policy.TppCountry, zone)
 	if err != nil {
 		return err
 	}
 
 	//reset Key Algorithm attribute
-	err = resetTPPAttribute(c, policy.TppKeyAlgorithm, zone)
+	err = resetTPPAttribute(ctx, c, policy.TppKeyAlgorithm, zone)
 	if err != nil {
 		return err
 	}
 
 	//reset Key Bit Strength
-	err = resetTPPAttribute(c, policy.TppKeyBitStrength, zone)
+	err = resetTPPAttribute(ctx, c, policy.TppKeyBitStrength, zone)
 	if err != nil {
 		return err
 	}
 
 	//reset Elliptic Curve attribute
-	err = resetTPPAttribute(c, policy.TppEllipticCurve, zone)
+	err = resetTPPAttribute(ctx, c, policy.TppEllipticCurve, zone)
 	if err != nil {
 		return err
 	}
 
 	//reset Manual Csr attribute
-	err = resetTPPAttribute(c, policy.ServiceGenerated, zone)
+	err = resetTPPAttribute(ctx, c, policy.ServiceGenerated, zone)
 	if err != nil {
 		return err
 	}
 
 	//reset Manual Csr attribute
-	err = resetTPPAttribute(c, policy.TppProhibitedSANTypes, zone)
+	err = resetTPPAttribute(ctx, c, policy.TppProhibitedSANTypes, zone)
 	if err != nil {
 		return err
 	}
 
 	//reset Allow Private Key Reuse" & "Want Renewal
-	err = resetTPPAttribute(c, policy.TppAllowPrivateKeyReuse, zone)
+	err = resetTPPAttribute(ctx, c, policy.TppAllowPrivateKeyReuse, zone)
 	if err != nil {
 		return err
 	}
 
-	err = resetTPPAttribute(c, policy.TppWantRenewal, zone)
+	err = resetTPPAttribute(ctx, c, policy.TppWantRenewal, zone)
 	if err != nil {
 		return err
 	}
 
-	err = resetTPPAttribute(c, policy.TppManagementType, zone)
+	err = resetTPPAttribute(ctx, c, policy.TppManagementType, zone)
 	if err != nil {
 		return err
 	}
@@ -2073,7 +2329,7 @@ func resetTPPAttributes(zone string, c *Connector) error {
 	return nil
 }
 
-func resetTPPAttribute(c *Connector, at, zone string) error {
+func resetTPPAttribute(ctx context.Context, c *Connector, at, zone string) error {
 
 	request := policy.ClearTTPAttributesRequest{
 		ObjectDN:      zone,
@@ -2083,7 +2339,7 @@ func resetTPPAttribute(c *Connector, at, zone string) error {
 	// if is locked is a policy value
 	// if is not locked then is a default.
 
-	_, _, body, err := c.request("POST", urlResourceCleanPolicy, request)
+	_, _, body, err := c.request(ctx, "POST", urlResourceCleanPolicy, request)
 	if err != nil {
 		return err
 	}
@@ -2103,21 +2359,29 @@ func resetTPPAttribute(c *Connector, at, zone string) error {
 	return nil
 }
 
-func (c *Connector) RequestSSHCertificate(req *certificate.SshCertRequest) (response *certificate.SshCertificateObject, err error) {
+func (c *Connector) RequestSSHCertificate(ctx context.Context, req *certificate.SshCertRequest) (response *certificate.SshCertificateObject, err error) {
+
+	return RequestSshCertificate(ctx, c, req)
 
-	return RequestSshCertificate(c, req)
+}
+
+func (c *Connector) RetrieveSSHCertificate(ctx context.Context, req *certificate.SshCertRequest) (response *certificate.SshCertificateObject, err error) {
+	return RetrieveSshCertificate(ctx, c, req)
+}
 
+func (c *Connector) RenewSSHCertificate(ctx context.Context, req *certificate.SshCertRequest) (response *certificate.SshCertificateObject, err error) {
+	return RenewSshCertificate(ctx, c, req)
 }
 
-func (c *Connector) RetrieveSSHCertificate(req *certificate.SshCertRequest) (response *certificate.SshCertificateObject, err error) {
-	return RetrieveSshCertificate(c, req)
+func (c *Connector) RevokeSSHCertificate(ctx context.Context, req *certificate.SshCertRequest) error {
+	return RevokeSshCertificate(ctx, c, req)
 }
 
-func (c *Connector) RetrieveCertificateMetaData(dn string) (*certificate.CertificateMetaData, error) {
+func (c *Connector) RetrieveCertificateMetaData(ctx context.Context, dn string) (*certificate.CertificateMetaData, error) {
 
 	//first step convert dn to guid
 	request := DNToGUIDRequest{ObjectDN: dn}
-	statusCode, status, body, err := c.request("POST", urlResourceDNToGUID, request)
+	statusCode, status, body, err := c.request(ctx, "POST", urlResourceDNToGUID, request)
 
 	if err != nil {
 		return nil, err
@@ -2132,7 +2396,7 @@ func (c *Connector) RetrieveCertificateMetaData(dn string) (*certificate.Certifi
 	//second step get certificate metadata
 	url := fmt.Sprintf("%s%s", urlResourceCertificate, guidInfo.GUID)
 
-	statusCode, status, body, err = c.request("GET", urlResource(url), nil)
+	statusCode, status, body, err = c.request(ctx, "GET", urlResource(url), nil)
 
 	if err != nil {
 		return nil, err
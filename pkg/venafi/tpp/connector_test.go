@@ -17,7 +17,10 @@
 package tpp
 
 import (
+	"context"
 	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
 	"crypto/rsa"
 	"crypto/sha1"
 	"crypto/tls"
@@ -29,6 +32,8 @@ import (
 	"errors"
 	"fmt"
 	"io/ioutil"
+	"math/big"
+	"net"
 	"net/http"
 	"net/http/httptest"
 	"net/url"
@@ -50,33 +55,34 @@ import (
 	"github.com/Venafi/vcert/v5/test"
 )
 
-var ctx *test.Context
+var env *test.Context
 
 func init() {
-	ctx = test.GetEnvContext()
+	ctx := context.Background()
+	env = test.GetEnvContext()
 	http.DefaultTransport.(*http.Transport).TLSClientConfig = &tls.Config{
 		Renegotiation:      tls.RenegotiateFreelyAsClient,
 		InsecureSkipVerify: true}
 
-	if ctx.TPPurl == "" {
+	if env.TPPurl == "" {
 		fmt.Println("TPP URL cannot be empty. See Makefile")
 		os.Exit(1)
 	}
 
-	tpp, err := getTestConnector(ctx.TPPurl, ctx.TPPZone)
+	tpp, err := getTestConnector(env.TPPurl, env.TPPZone)
 	if err != nil {
 		panic(err)
 	}
 
-	resp, err := tpp.GetRefreshToken(&endpoint.Authentication{
-		User: ctx.TPPuser, Password: ctx.TPPPassword,
+	resp, err := tpp.GetRefreshToken(ctx, &endpoint.Authentication{
+		User: env.TPPuser, Password: env.TPPPassword,
 		Scope: "certificate:discover,manage,revoke;configuration:manage;ssh:manage"})
 	if err != nil {
 		panic(err)
 	}
 
-	ctx.TPPRefreshToken = resp.Refresh_token
-	ctx.TPPaccessToken = resp.Access_token
+	env.TPPRefreshToken = resp.Refresh_token
+	env.TPPaccessToken = resp.Access_token
 }
 
 func getTestConnector(url string, zone string) (c *Connector, err error) {
@@ -146,14 +152,15 @@ func TestNewConnectorURLErrors(t *testing.T) {
 }
 
 func TestAuthenticateAuthError(t *testing.T) {
+	ctx := context.Background()
 	// An attempt to Authenticate with invalid credentials results in an
 	// AuthError.
 	// TODO: Test that all Authenticate errors wrap verrors.AuthError
-	tpp, err := getTestConnector(ctx.TPPurl, ctx.TPPZone)
+	tpp, err := getTestConnector(env.TPPurl, env.TPPZone)
 	if err != nil {
-		t.Fatalf("err is not nil, err: %s url: %s", err, ctx.TPPurl)
+		t.Fatalf("err is not nil, err: %s url: %s", err, env.TPPurl)
 	}
-	err = tpp.Authenticate(&endpoint.Authentication{
+	err = tpp.Authenticate(ctx, &endpoint.Authentication{
 		User:     "invalid-user",
 		Password: "invalid-password",
 	})
@@ -166,24 +173,25 @@ func TestAuthenticateAuthError(t *testing.T) {
 }
 
 func TestRetrieveSystemVersion(t *testing.T) {
-	tpp, err := getTestConnector(ctx.TPPurl, "")
+	ctx := context.Background()
+	tpp, err := getTestConnector(env.TPPurl, "")
 	if err != nil {
-		t.Fatalf("err is not nil, err: %s url: %s", err, ctx.TPPurl)
+		t.Fatalf("err is not nil, err: %s url: %s", err, env.TPPurl)
 	}
 
-	refreshToken, err := tpp.GetRefreshToken(&endpoint.Authentication{
-		User: ctx.TPPuser, Password: ctx.TPPPassword,
+	refreshToken, err := tpp.GetRefreshToken(ctx, &endpoint.Authentication{
+		User: env.TPPuser, Password: env.TPPPassword,
 		Scope: "certificate:discover,manage,revoke", ClientId: "vcert-sdk"})
 	if err != nil {
 		t.Fatalf("%s", err)
 	}
 
-	err = tpp.Authenticate(&endpoint.Authentication{AccessToken: refreshToken.Access_token})
+	err = tpp.Authenticate(ctx, &endpoint.Authentication{AccessToken: refreshToken.Access_token})
 	if err != nil {
 		t.Fatalf("err is not nil, err: %s", err)
 	}
 
-	serviceVersion, err := tpp.RetrieveSystemVersion()
+	serviceVersion, err := tpp.RetrieveSystemVersion(ctx)
 	if err != nil {
 		t.Fatalf("Failed to get Venafi system version. Error: %v", err)
 	}
@@ -200,24 +208,25 @@ func TestRetrieveSystemVersion(t *testing.T) {
 }
 
 func TestRetrieveSelfIdentity(t *testing.T) {
-	tpp, err := getTestConnector(ctx.TPPurl, "")
+	ctx := context.Background()
+	tpp, err := getTestConnector(env.TPPurl, "")
 	if err != nil {
-		t.Fatalf("err is not nil, err: %s url: %s", err, ctx.TPPurl)
+		t.Fatalf("err is not nil, err: %s url: %s", err, env.TPPurl)
 	}
 
-	refreshToken, err := tpp.GetRefreshToken(&endpoint.Authentication{
-		User: ctx.TPPuser, Password: ctx.TPPPassword,
+	refreshToken, err := tpp.GetRefreshToken(ctx, &endpoint.Authentication{
+		User: env.TPPuser, Password: env.TPPPassword,
 		Scope: "certificate:discover,manage,revoke", ClientId: "vcert-sdk"})
 	if err != nil {
 		t.Fatalf("%s", err)
 	}
 
-	err = tpp.Authenticate(&endpoint.Authentication{AccessToken: refreshToken.Access_token})
+	err = tpp.Authenticate(ctx, &endpoint.Authentication{AccessToken: refreshToken.Access_token})
 	if err != nil {
 		t.Fatalf("err is not nil, err: %s", err)
 	}
 
-	identity, err := tpp.retrieveSelfIdentity()
+	identity, err := tpp.retrieveSelfIdentity(ctx)
 	if err != nil {
 		t.Fatalf("Failed to get the used user. Error: %v", err)
 	}
@@ -228,60 +237,64 @@ func TestRetrieveSelfIdentity(t *testing.T) {
 }
 
 func TestPingTPP(t *testing.T) {
-	tpp, err := getTestConnector(ctx.TPPurl, ctx.TPPZone)
+	ctx := context.Background()
+	tpp, err := getTestConnector(env.TPPurl, env.TPPZone)
 	if err != nil {
-		t.Fatalf("err is not nil, err: %s url: %s", err, ctx.TPPurl)
+		t.Fatalf("err is not nil, err: %s url: %s", err, env.TPPurl)
 	}
-	err = tpp.Ping()
+	err = tpp.Ping(ctx)
 	if err != nil {
 		t.Fatalf("err is not nil, err: %s", err)
 	}
 }
 
 func TestBadPingTPP(t *testing.T) {
-	tpp, err := getTestConnector("http://bonjo-w10dev:333/vedsdk/", ctx.TPPZone)
+	ctx := context.Background()
+	tpp, err := getTestConnector("http://bonjo-w10dev:333/vedsdk/", env.TPPZone)
 	if err != nil {
 		t.Fatalf("err is not nil, err: %s url: http://bonjo-w10dev:333/vedsdk/", err)
 	}
-	err = tpp.Ping()
+	err = tpp.Ping(ctx)
 	if err == nil {
 		t.Fatalf("err should not be nil, URL does not exist")
 	}
 }
 
 func TestGetRefreshToken(t *testing.T) {
-	tpp, err := getTestConnector(ctx.TPPurl, "")
+	ctx := context.Background()
+	tpp, err := getTestConnector(env.TPPurl, "")
 	if err != nil {
 		t.Fatalf("err is not nil, err: %s url: %s", err, expectedURL)
 	}
 
-	refreshToken, err := tpp.GetRefreshToken(&endpoint.Authentication{
-		User: ctx.TPPuser, Password: ctx.TPPPassword,
+	refreshToken, err := tpp.GetRefreshToken(ctx, &endpoint.Authentication{
+		User: env.TPPuser, Password: env.TPPPassword,
 		Scope: "certificate:discover,manage,revoke", ClientId: "vcert-sdk"})
 	if err != nil {
 		t.Fatalf("%s", err)
 	}
 
-	err = tpp.Authenticate(&endpoint.Authentication{AccessToken: refreshToken.Access_token})
+	err = tpp.Authenticate(ctx, &endpoint.Authentication{AccessToken: refreshToken.Access_token})
 	if err != nil {
 		t.Fatalf("err is not nil, err: %s", err)
 	}
 
-	tpp.SetZone(ctx.TPPZone)
-	_, err = tpp.ReadZoneConfiguration()
+	tpp.SetZone(env.TPPZone)
+	_, err = tpp.ReadZoneConfiguration(ctx)
 	if err != nil {
 		t.Fatalf("%s", err)
 	}
 }
 
 func TestGetRefreshTokenWithDefaultScope(t *testing.T) {
-	tpp, err := getTestConnector(ctx.TPPurl, ctx.TPPZone)
+	ctx := context.Background()
+	tpp, err := getTestConnector(env.TPPurl, env.TPPZone)
 	if err != nil {
 		t.Fatalf("err is not nil, err: %s url: %s", err, expectedURL)
 	}
 
-	refreshToken, err := tpp.GetRefreshToken(&endpoint.Authentication{
-		User: ctx.TPPuser, Password: ctx.TPPPassword})
+	refreshToken, err := tpp.GetRefreshToken(ctx, &endpoint.Authentication{
+		User: env.TPPuser, Password: env.TPPPassword})
 	if err != nil {
 		t.Fatalf("%s", err)
 	}
@@ -289,25 +302,26 @@ func TestGetRefreshTokenWithDefaultScope(t *testing.T) {
 	if refreshToken.Scope != defaultScope {
 		t.Fatalf("Scope from refresh roken %s is not as default scope %s;", refreshToken.Scope, defaultScope)
 	}
-	err = tpp.Authenticate(&endpoint.Authentication{AccessToken: refreshToken.Access_token})
+	err = tpp.Authenticate(ctx, &endpoint.Authentication{AccessToken: refreshToken.Access_token})
 	if err != nil {
 		t.Fatalf("err is not nil, err: %s", err)
 	}
 
-	tpp.SetZone(ctx.TPPZone)
-	_, err = tpp.ReadZoneConfiguration()
+	tpp.SetZone(env.TPPZone)
+	_, err = tpp.ReadZoneConfiguration(ctx)
 	if err != nil {
 		t.Fatalf("%s", err)
 	}
 }
 
 func TestFailRefreshAccessToken(t *testing.T) {
-	tpp, err := getTestConnector(ctx.TPPurl, ctx.TPPZone)
+	ctx := context.Background()
+	tpp, err := getTestConnector(env.TPPurl, env.TPPZone)
 	if err != nil {
 		t.Fatalf("err is not nil, err: %s url: %s", err, expectedURL)
 	}
-	auth := &endpoint.Authentication{RefreshToken: "WRONGREFRESHTOKEN", ClientId: ctx.ClientID}
-	err = tpp.Authenticate(auth)
+	auth := &endpoint.Authentication{RefreshToken: "WRONGREFRESHTOKEN", ClientId: env.ClientID}
+	err = tpp.Authenticate(ctx, auth)
 	if err == nil {
 		t.Fatalf("err should not be nil, er")
 	}
@@ -318,69 +332,72 @@ func TestFailRefreshAccessToken(t *testing.T) {
 }
 
 func TestRefreshAccessToken(t *testing.T) {
-	tpp, err := getTestConnector(ctx.TPPurl, "")
+	ctx := context.Background()
+	tpp, err := getTestConnector(env.TPPurl, "")
 	if err != nil {
 		t.Fatalf("err is not nil, err: %s url: %s", err, expectedURL)
 	}
 
-	auth := &endpoint.Authentication{RefreshToken: ctx.TPPRefreshToken, ClientId: ctx.ClientID}
-	err = tpp.Authenticate(auth)
+	auth := &endpoint.Authentication{RefreshToken: env.TPPRefreshToken, ClientId: env.ClientID}
+	err = tpp.Authenticate(ctx, auth)
 	if err != nil {
 		t.Fatalf("err is not nil, err: %s", err)
 	}
 
-	tpp.SetZone(ctx.TPPZone)
-	_, err = tpp.ReadZoneConfiguration()
+	tpp.SetZone(env.TPPZone)
+	_, err = tpp.ReadZoneConfiguration(ctx)
 	if err != nil {
 		t.Fatalf("%s", err)
 	}
 
 	//Uppdate refresh token for further tests
-	ctx.TPPRefreshToken = auth.RefreshToken
+	env.TPPRefreshToken = auth.RefreshToken
 
 }
 
 func TestRefreshAccessTokenNoClientID(t *testing.T) {
-	tpp, err := getTestConnector(ctx.TPPurl, ctx.TPPZone)
+	ctx := context.Background()
+	tpp, err := getTestConnector(env.TPPurl, env.TPPZone)
 	if err != nil {
 		t.Fatalf("err is not nil, err: %s url: %s", err, expectedURL)
 	}
-	auth := &endpoint.Authentication{RefreshToken: ctx.TPPRefreshToken}
-	err = tpp.Authenticate(auth)
+	auth := &endpoint.Authentication{RefreshToken: env.TPPRefreshToken}
+	err = tpp.Authenticate(ctx, auth)
 	if err != nil {
 		t.Fatalf("err is not nil, err: %s", err)
 	}
 
-	tpp.SetZone(ctx.TPPZone)
-	_, err = tpp.ReadZoneConfiguration()
+	tpp.SetZone(env.TPPZone)
+	_, err = tpp.ReadZoneConfiguration(ctx)
 	if err != nil {
 		t.Fatalf("%s", err)
 	}
 
 	//Update tokens for further tests
-	ctx.TPPRefreshToken = auth.RefreshToken
-	ctx.TPPaccessToken = tpp.accessToken
+	env.TPPRefreshToken = auth.RefreshToken
+	env.TPPaccessToken = tpp.accessToken
 
 }
 
 func TestAuthenticationAccessToken(t *testing.T) {
-	tpp, err := getTestConnector(ctx.TPPurl, ctx.TPPZone)
+	ctx := context.Background()
+	tpp, err := getTestConnector(env.TPPurl, env.TPPZone)
 	if err != nil {
 		t.Fatalf("err is not nil, err: %s url: %s", err, expectedURL)
 	}
 
-	err = tpp.Authenticate(&endpoint.Authentication{AccessToken: ctx.TPPaccessToken})
+	err = tpp.Authenticate(ctx, &endpoint.Authentication{AccessToken: env.TPPaccessToken})
 	if err != nil {
 		t.Fatalf("err is not nil, err: %s", err)
 	}
 
-	tpp.SetZone(ctx.TPPZone)
-	_, err = tpp.ReadZoneConfiguration()
+	tpp.SetZone(env.TPPZone)
+	_, err = tpp.ReadZoneConfiguration(ctx)
 	if err != nil {
 		t.Fatalf("%s", err)
 	}
 
-	err = tpp.Authenticate(&endpoint.Authentication{AccessToken: "WRONGm3XPAT5nlWxd3iA=="})
+	err = tpp.Authenticate(ctx, &endpoint.Authentication{AccessToken: "WRONGm3XPAT5nlWxd3iA=="})
 	if err == nil {
 		t.Fatalf("Auth with wrong token should fail")
 	}
@@ -388,36 +405,39 @@ func TestAuthenticationAccessToken(t *testing.T) {
 }
 
 func TestAuthorizeToTPP(t *testing.T) {
-	tpp, err := getTestConnector(ctx.TPPurl, ctx.TPPZone)
+	ctx := context.Background()
+	tpp, err := getTestConnector(env.TPPurl, env.TPPZone)
 	if err != nil {
-		t.Fatalf("err is not nil, err: %s url: %s", err, ctx.TPPurl)
+		t.Fatalf("err is not nil, err: %s url: %s", err, env.TPPurl)
 	}
-	auth := &endpoint.Authentication{User: ctx.TPPuser, Password: ctx.TPPPassword}
-	err = tpp.Authenticate(auth)
+	auth := &endpoint.Authentication{User: env.TPPuser, Password: env.TPPPassword}
+	err = tpp.Authenticate(ctx, auth)
 	if err != nil {
 		t.Fatalf("err is not nil, err: %s, %+v", err, auth)
 	}
 }
 
 func TestBadAuthorizeToTPP(t *testing.T) {
-	tpp, err := getTestConnector(ctx.TPPurl, ctx.TPPZone)
+	ctx := context.Background()
+	tpp, err := getTestConnector(env.TPPurl, env.TPPZone)
 	if err != nil {
-		t.Fatalf("err is not nil, err: %s url: %s", err, ctx.TPPurl)
+		t.Fatalf("err is not nil, err: %s url: %s", err, env.TPPurl)
 	}
-	err = tpp.Authenticate(&endpoint.Authentication{User: ctx.TPPuser, Password: "wrongPassword"})
+	err = tpp.Authenticate(ctx, &endpoint.Authentication{User: env.TPPuser, Password: "wrongPassword"})
 	if err == nil {
 		t.Fatalf("err should not be nil, bad password was used")
 	}
 }
 
 func TestReadConfigData(t *testing.T) {
-	tpp, err := getTestConnector(ctx.TPPurl, ctx.TPPZone)
+	ctx := context.Background()
+	tpp, err := getTestConnector(env.TPPurl, env.TPPZone)
 	if err != nil {
 		t.Fatalf("err is not nil, err: %s url: %s", err, expectedURL)
 	}
 
 	if tpp.apiKey == "" {
-		err = tpp.Authenticate(&endpoint.Authentication{AccessToken: ctx.TPPaccessToken})
+		err = tpp.Authenticate(ctx, &endpoint.Authentication{AccessToken: env.TPPaccessToken})
 		if err != nil {
 			t.Fatalf("err is not nil, err: %s", err)
 		}
@@ -426,7 +446,7 @@ func TestReadConfigData(t *testing.T) {
 		zone       string
 		zoneConfig endpoint.ZoneConfiguration
 	}{
-		{getPolicyDN(ctx.TPPZone), endpoint.ZoneConfiguration{
+		{getPolicyDN(env.TPPZone), endpoint.ZoneConfiguration{
 			Organization:          "Venafi Inc.",
 			OrganizationalUnit:    []string{"Integrations"},
 			Country:               "US",
@@ -436,7 +456,7 @@ func TestReadConfigData(t *testing.T) {
 			KeyConfiguration:      &endpoint.AllowedKeyConfiguration{KeySizes: []int{2048}},
 			CustomAttributeValues: make(map[string]string),
 		}},
-		{getPolicyDN(ctx.TPPZoneRestricted), endpoint.ZoneConfiguration{
+		{getPolicyDN(env.TPPZoneRestricted), endpoint.ZoneConfiguration{
 			Organization:          "Venafi Inc.",
 			OrganizationalUnit:    []string{"Integration"},
 			Country:               "US",
@@ -449,7 +469,7 @@ func TestReadConfigData(t *testing.T) {
 	}
 	for _, c := range testCases {
 		tpp.SetZone(c.zone)
-		zoneConfig, err := tpp.ReadZoneConfiguration()
+		zoneConfig, err := tpp.ReadZoneConfiguration(ctx)
 		if err != nil {
 			t.Fatalf("%s", err)
 		}
@@ -462,38 +482,40 @@ func TestReadConfigData(t *testing.T) {
 		}
 	}
 	tpp.SetZone("Wrong Zone")
-	_, err = tpp.ReadZoneConfiguration()
+	_, err = tpp.ReadZoneConfiguration(ctx)
 	if err == nil {
 		t.Fatalf("err should be not nil for not existed zone")
 	}
 }
 
 func TestBadReadConfigData(t *testing.T) {
-	tpp, err := getTestConnector(ctx.TPPurl, "notexistedzone")
+	ctx := context.Background()
+	tpp, err := getTestConnector(env.TPPurl, "notexistedzone")
 	if err != nil {
 		t.Fatalf("err is not nil, err: %s url: %s", err, expectedURL)
 	}
 
 	if tpp.apiKey == "" {
-		err = tpp.Authenticate(&endpoint.Authentication{AccessToken: ctx.TPPaccessToken})
+		err = tpp.Authenticate(ctx, &endpoint.Authentication{AccessToken: env.TPPaccessToken})
 		if err != nil {
 			t.Fatalf("err is not nil, err: %s", err)
 		}
 	}
-	_, err = tpp.ReadZoneConfiguration()
+	_, err = tpp.ReadZoneConfiguration(ctx)
 	if err == nil {
 		t.Fatalf("err should not be nil, invalid policy was used")
 	}
 }
 
 func TestRequestCertificateUserPassword(t *testing.T) {
-	tpp, err := getTestConnector(ctx.TPPurl, ctx.TPPZone)
+	ctx := context.Background()
+	tpp, err := getTestConnector(env.TPPurl, env.TPPZone)
 	if err != nil {
 		t.Fatalf("err is not nil, err: %s url: %s", err, expectedURL)
 	}
 
 	if tpp.apiKey == "" {
-		err = tpp.Authenticate(&endpoint.Authentication{User: ctx.TPPuser, Password: ctx.TPPPassword})
+		err = tpp.Authenticate(ctx, &endpoint.Authentication{User: env.TPPuser, Password: env.TPPPassword})
 		if err != nil {
 			t.Fatalf("err is not nil, err: %s", err)
 		}
@@ -502,13 +524,14 @@ func TestRequestCertificateUserPassword(t *testing.T) {
 }
 
 func TestRequestCertificateToken(t *testing.T) {
-	tpp, err := getTestConnector(ctx.TPPurl, ctx.TPPZone)
+	ctx := context.Background()
+	tpp, err := getTestConnector(env.TPPurl, env.TPPZone)
 	if err != nil {
 		t.Fatalf("err is not nil, err: %s url: %s", err, expectedURL)
 	}
 
 	if tpp.apiKey == "" {
-		err = tpp.Authenticate(&endpoint.Authentication{AccessToken: ctx.TPPaccessToken})
+		err = tpp.Authenticate(ctx, &endpoint.Authentication{AccessToken: env.TPPaccessToken})
 		if err != nil {
 			t.Fatalf("err is not nil, err: %s", err)
 		}
@@ -517,13 +540,14 @@ func TestRequestCertificateToken(t *testing.T) {
 }
 
 func TestRequestCertificateWithValidityHours(t *testing.T) {
-	tpp, err := getTestConnector(ctx.TPPurl, ctx.TPPZone)
+	ctx := context.Background()
+	tpp, err := getTestConnector(env.TPPurl, env.TPPZone)
 	if err != nil {
 		t.Fatalf("err is not nil, err: %s url: %s", err, expectedURL)
 	}
 
 	if tpp.apiKey == "" {
-		err = tpp.Authenticate(&endpoint.Authentication{AccessToken: ctx.TPPaccessToken})
+		err = tpp.Authenticate(ctx, &endpoint.Authentication{AccessToken: env.TPPaccessToken})
 		if err != nil {
 			t.Fatalf("err is not nil, err: %s", err)
 		}
@@ -532,13 +556,14 @@ func TestRequestCertificateWithValidityHours(t *testing.T) {
 }
 
 func TestRequestCertificateWithValidityDuration(t *testing.T) {
-	tpp, err := getTestConnector(ctx.TPPurl, ctx.TPPZone)
+	ctx := context.Background()
+	tpp, err := getTestConnector(env.TPPurl, env.TPPZone)
 	if err != nil {
 		t.Fatalf("err is not nil, err: %s url: %s", err, expectedURL)
 	}
 
 	if tpp.apiKey == "" {
-		err = tpp.Authenticate(&endpoint.Authentication{AccessToken: ctx.TPPaccessToken})
+		err = tpp.Authenticate(ctx, &endpoint.Authentication{AccessToken: env.TPPaccessToken})
 		if err != nil {
 			t.Fatalf("err is not nil, err: %s", err)
 		}
@@ -548,6 +573,7 @@ func TestRequestCertificateWithValidityDuration(t *testing.T) {
 
 // The mocked HTTP interactions have been recorded using TPP 22.4.
 func TestResetCertificate(t *testing.T) {
+	ctx := context.Background()
 	type mockCall struct {
 		expectReqPath string
 		expectReqBody string
@@ -681,7 +707,7 @@ func TestResetCertificate(t *testing.T) {
 				t.Fatal(err)
 			}
 
-			err = tpp.ResetCertificate(tt.request, false)
+			err = tpp.ResetCertificate(ctx, tt.request, false)
 			if tt.expCertNotFoundErr {
 				if err == nil || !IsCertNotFound(err) {
 					t.Errorf("expected error of type %T but got %T", &ErrCertNotFound{}, err)
@@ -700,6 +726,262 @@ func TestResetCertificate(t *testing.T) {
 	}
 }
 
+// The certificate grant is tested against a mock server, since it requires a TPP user mapped to a client certificate
+func TestGetRefreshTokenWithClientCertificate(t *testing.T) {
+	ctx := context.Background()
+	server := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/"+string(urlResourceAuthorizeCertificate) {
+			t.Errorf("expected request path %q but got %q", urlResourceAuthorizeCertificate, r.URL.Path)
+		}
+		if len(r.TLS.PeerCertificates) == 0 || r.TLS.PeerCertificates[0].Subject.CommonName != "tpp-user" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write([]byte(`{"access_token":"access-123","refresh_token":"refresh-456","expires":1700000000}`))
+	}))
+	server.TLS = &tls.Config{ClientAuth: tls.RequireAnyClientCert}
+	server.StartTLS()
+	defer server.Close()
+
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(1),
+		Subject:      pkix.Name{CommonName: "tpp-user"},
+		NotBefore:    time.Now(),
+		NotAfter:     time.Now().Add(time.Hour),
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ca := x509.NewCertPool()
+	ca.AddCert(server.Certificate())
+	tpp, err := NewConnector(server.URL, "", false, ca)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	resp, err := tpp.GetRefreshToken(ctx, &endpoint.Authentication{
+		ClientCertificate: &tls.Certificate{Certificate: [][]byte{der}, PrivateKey: key},
+	})
+	if err != nil {
+		t.Fatalf("failed to get token with client certificate: %s", err)
+	}
+	if resp.Access_token != "access-123" || resp.Refresh_token != "refresh-456" {
+		t.Fatalf("unexpected token response %+v", resp)
+	}
+	if _, ok := tpp.client.Transport.(*util.RetryTransport); !ok {
+		t.Fatalf("expected the client certificate to keep the retry transport but got %T", tpp.client.Transport)
+	}
+}
+
+func TestRequestReusesConnection(t *testing.T) {
+	ctx := context.Background()
+	var connections int32
+	server := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write([]byte(`{"Version":"23.1.0.0"}`))
+	}))
+	server.Config.ConnState = func(_ net.Conn, state http.ConnState) {
+		if state == http.StateNew {
+			atomic.AddInt32(&connections, 1)
+		}
+	}
+	server.StartTLS()
+	defer server.Close()
+
+	ca := x509.NewCertPool()
+	ca.AddCert(server.Certificate())
+	tpp, err := NewConnector(server.URL, "", false, ca)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tpp.accessToken = "access-1"
+
+	for i := 0; i < 2; i++ {
+		statusCode, _, _, err := tpp.request(ctx, http.MethodGet, urlResourceSystemStatusVersion, nil)
+		if err != nil {
+			t.Fatalf("request %d failed: %s", i+1, err)
+		}
+		if statusCode != http.StatusOK {
+			t.Fatalf("request %d: unexpected status code %d", i+1, statusCode)
+		}
+	}
+	if n := atomic.LoadInt32(&connections); n != 1 {
+		t.Fatalf("expected the second request to reuse the connection of the first one, but %d connections were opened", n)
+	}
+}
+
+// The refresh of an access token expiring during the run is tested against a mock server, since TPP tokens live for hours
+func TestRequestRefreshesExpiredAccessToken(t *testing.T) {
+	ctx := context.Background()
+	var refreshes int32
+	server := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		switch r.URL.Path {
+		case "/" + string(urlResourceRefreshAccessToken):
+			atomic.AddInt32(&refreshes, 1)
+			var req oauthRefreshAccessTokenRequest
+			_ = json.NewDecoder(r.Body).Decode(&req)
+			if req.Refresh_token != "refresh-1" || req.Client_id != "my-app" {
+				w.WriteHeader(http.StatusBadRequest)
+				return
+			}
+			_, _ = w.Write([]byte(`{"access_token":"access-2","refresh_token":"refresh-2","expires":1700000000}`))
+		case "/" + string(urlResourceSystemStatusVersion):
+			if r.Header.Get("Authorization") != "Bearer access-2" {
+				w.WriteHeader(http.StatusUnauthorized)
+				return
+			}
+			_, _ = w.Write([]byte(`{"Version":"23.1.0.0"}`))
+		default:
+			t.Errorf("unexpected request path %q", r.URL.Path)
+		}
+	}))
+	defer server.Close()
+
+	ca := x509.NewCertPool()
+	ca.AddCert(server.Certificate())
+	tpp, err := NewConnector(server.URL, "", false, ca)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var persisted []string
+	err = tpp.Authenticate(ctx, &endpoint.Authentication{
+		AccessToken:  "access-1",
+		RefreshToken: "refresh-1",
+		ClientId:     "my-app",
+		OnTokenRefresh: func(accessToken string, refreshToken string) error {
+			persisted = append(persisted, accessToken, refreshToken)
+			return nil
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	version, err := tpp.RetrieveSystemVersion(ctx)
+	if err != nil {
+		t.Fatalf("request with expired access token failed: %s", err)
+	}
+	if version != "23.1.0.0" {
+		t.Fatalf("unexpected version %q", version)
+	}
+	if len(persisted) != 2 || persisted[0] != "access-2" || persisted[1] != "refresh-2" {
+		t.Fatalf("rotated tokens not persisted, got %v", persisted)
+	}
+
+	// The new token is used from then on
+	_, err = tpp.RetrieveSystemVersion(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n := atomic.LoadInt32(&refreshes); n != 1 {
+		t.Fatalf("expected 1 token refresh, got %d", n)
+	}
+}
+
+// The persisted tokens are read again under the lock before refreshing, so the access token refreshed by another
+// process is used rather than refreshing the same refresh token twice
+func TestRequestUsesTokensRefreshedByAnotherProcess(t *testing.T) {
+	ctx := context.Background()
+	tests := []struct {
+		name              string
+		persistedAccess   string
+		persistedRefresh  string
+		expectedRefreshes int32
+		expectedPersisted []string
+	}{
+		{
+			name:              "persisted token is still the expired one",
+			persistedAccess:   "access-1",
+			persistedRefresh:  "refresh-1",
+			expectedRefreshes: 1,
+			expectedPersisted: []string{"access-2", "refresh-2"},
+		},
+		{
+			name:              "persisted token was refreshed by another process",
+			persistedAccess:   "access-2",
+			persistedRefresh:  "refresh-2",
+			expectedRefreshes: 0,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var refreshes int32
+			server := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				w.Header().Set("Content-Type", "application/json")
+				switch r.URL.Path {
+				case "/" + string(urlResourceRefreshAccessToken):
+					atomic.AddInt32(&refreshes, 1)
+					_, _ = w.Write([]byte(`{"access_token":"access-2","refresh_token":"refresh-2","expires":1700000000}`))
+				case "/" + string(urlResourceSystemStatusVersion):
+					if r.Header.Get("Authorization") != "Bearer access-2" {
+						w.WriteHeader(http.StatusUnauthorized)
+						return
+					}
+					_, _ = w.Write([]byte(`{"Version":"23.1.0.0"}`))
+				default:
+					t.Errorf("unexpected request path %q", r.URL.Path)
+				}
+			}))
+			defer server.Close()
+
+			ca := x509.NewCertPool()
+			ca.AddCert(server.Certificate())
+			tpp, err := NewConnector(server.URL, "", false, ca)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			locked := false
+			var persisted []string
+			err = tpp.Authenticate(ctx, &endpoint.Authentication{
+				AccessToken:  "access-1",
+				RefreshToken: "refresh-1",
+				LockTokens: func() (string, string, func() error, error) {
+					locked = true
+					return tt.persistedAccess, tt.persistedRefresh, func() error {
+						locked = false
+						return nil
+					}, nil
+				},
+				OnTokenRefresh: func(accessToken string, refreshToken string) error {
+					if !locked {
+						t.Error("tokens persisted without holding the lock")
+					}
+					persisted = append(persisted, accessToken, refreshToken)
+					return nil
+				},
+			})
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			_, err = tpp.RetrieveSystemVersion(ctx)
+			if err != nil {
+				t.Fatalf("request with expired access token failed: %s", err)
+			}
+			if locked {
+				t.Error("lock of the persisted tokens not released")
+			}
+			if n := atomic.LoadInt32(&refreshes); n != tt.expectedRefreshes {
+				t.Fatalf("expected %d token refresh, got %d", tt.expectedRefreshes, n)
+			}
+			if strings.Join(persisted, ",") != strings.Join(tt.expectedPersisted, ",") {
+				t.Fatalf("expected persisted tokens %v, got %v", tt.expectedPersisted, persisted)
+			}
+		})
+	}
+}
+
 // The reason we are using a mock HTTP server rather than the live TPP server is
 // because consistently triggering the 500 error in a stage different than 0
 // requires putting a powershell script on the TPP VM or turning the Microsoft
@@ -709,6 +991,7 @@ func TestResetCertificate(t *testing.T) {
 // The HTTP response samples below are based on tests performed manually with a
 // TPP 20.1 instance by inspecting the HTTP responses with curl.
 func TestRetrieveCertificate(t *testing.T) {
+	ctx := context.Background()
 	certData := "LS0tLS1CRUdJTiBDRVJUSUZJQ0FURS0tLS0tDQpNSUlGTWpDQ0JCcWdBd0lCQWdJVEx3QUFBVmVJV0tkUVFTbmRmd0FBQUFBQlZ6QU5CZ2txaGtpRzl3MEJBUXNGDQpBREJOTVJNd0VRWUtDWkltaVpQeUxHUUJHUllEWTI5dE1Sb3dHQVlLQ1pJbWlaUHlMR1FCR1JZS2RtVnVZV1pwDQpaR1Z0YnpFYU1CZ0dBMVVFQXhNUmRtVnVZV1pwWkdWdGJ5MVVVRkF0UTBFd0hoY05Nakl4TVRFME1UVXdOVFU0DQpXaGNOTWpReE1ERTNNVFUxTnpRNVdqQVhNUlV3RXdZRFZRUURFd3hpWlhoaGJYQnNaUzVqYjIwd2dnRWlNQTBHDQpDU3FHU0liM0RRRUJBUVVBQTRJQkR3QXdnZ0VLQW9JQkFRQzAwZE05RjdmNk12RlpZMzgyejQvbFZiRWNROFp2DQp1VkNTL2ovaVpmNkVwbUpjMzdubnlFeDJqSVR4eTNPdXRjMmJVUmZ2a284MmV5cHFieDhlNWNCV3pwUWs0NU5pDQpUdzlhMmFqbGhPbE11c1R2RzRLc29DUGM5K29URHRmb3NvRjRSK05rbjZZa0RFenZUb1pzME5yKy9LcTl4THVPDQpOWkM3d0M1dE5TemJRT01aVVV0NGt0WXZBQkp0dHZENlNrY0Y5ZVMySGRJWFJ3aHhPV05TZjYrcGhKZko4ZnhGDQpKSWJmaU9wMktGRVlEWGJ1L0kzeUpSdHFmY211M2FqV0MyR2NkMkNGaTJxdUJ4SDdCVXZoL1ltTm10K2F5MExJDQphK21PTXduNytZdDhKbVh6TWRIVjZZSEhZanQwR0pyRjBxRlR1bjFIeENGQnRBYU1ITzJVSmZVSEFnTUJBQUdqDQpnZ0kvTUlJQ096QVhCZ05WSFJFRUVEQU9nZ3hpWlhoaGJYQnNaUzVqYjIwd0hRWURWUjBPQkJZRUZQV3d2YmE3DQp4cTVhOWZhNWw3cFRCMlVZcjV2cE1COEdBMVVkSXdRWU1CYUFGSU4xZWxSWUdMZ2lIU2gzdnUzbEtUL1lvZlgrDQpNSUhPQmdOVkhSOEVnY1l3Z2NNd2djQ2dnYjJnZ2JxR2diZHNaR0Z3T2k4dkwwTk9QWFpsYm1GbWFXUmxiVzh0DQpWRkJRTFVOQkxFTk9QWFJ3Y0N4RFRqMURSRkFzUTA0OVVIVmliR2xqSlRJd1MyVjVKVEl3VTJWeWRtbGpaWE1zDQpRMDQ5VTJWeWRtbGpaWE1zUTA0OVEyOXVabWxuZFhKaGRHbHZiaXhFUXoxMlpXNWhabWxrWlcxdkxFUkRQV052DQpiVDlqWlhKMGFXWnBZMkYwWlZKbGRtOWpZWFJwYjI1TWFYTjBQMkpoYzJVL2IySnFaV04wUTJ4aGMzTTlZMUpNDQpSR2x6ZEhKcFluVjBhVzl1VUc5cGJuUXdnY1lHQ0NzR0FRVUZCd0VCQklHNU1JRzJNSUd6QmdnckJnRUZCUWN3DQpBb2FCcG14a1lYQTZMeTh2UTA0OWRtVnVZV1pwWkdWdGJ5MVVVRkF0UTBFc1EwNDlRVWxCTEVOT1BWQjFZbXhwDQpZeVV5TUV0bGVTVXlNRk5sY25acFkyVnpMRU5PUFZObGNuWnBZMlZ6TEVOT1BVTnZibVpwWjNWeVlYUnBiMjRzDQpSRU05ZG1WdVlXWnBaR1Z0Ynl4RVF6MWpiMjAvWTBGRFpYSjBhV1pwWTJGMFpUOWlZWE5sUDI5aWFtVmpkRU5zDQpZWE56UFdObGNuUnBabWxqWVhScGIyNUJkWFJvYjNKcGRIa3dJUVlKS3dZQkJBR0NOeFFDQkJRZUVnQlhBR1VBDQpZZ0JUQUdVQWNnQjJBR1VBY2pBT0JnTlZIUThCQWY4RUJBTUNCYUF3RXdZRFZSMGxCQXd3Q2dZSUt3WUJCUVVIDQpBd0V3RFFZSktvWklodmNOQVFFTEJRQURnZ0VCQUhCWFEwTVdUU1UzcllXTWNMVDBySE5lSVBMSXJoWDNFWGpWDQpmdzIxT1RKL09MWFBFY0lhVTQrWlNUOThpZE5oRG15VkNudmVxaXpzT0tibDdQUFR4OHZrbWUwOFpmS2R1QmNODQpsL1VwOTV2YVZYU0Y4K0k4dUNNd3pQZ3dtclVRYUhkNWl3b1hHOHpmdE5ndGcxdUNqZ2ZNVk1acUVsTmtxWk5QDQpETkpvYUp5U1VzY0ZFTE5FTENDa05IR2EyenZZaDRCMjk0UDY2RlRzdkpZanl6YnkzVTV5RW9HM0RaWmxjMzArDQpJNWZXMlI3K3djcWRvRUV4R1dHZXh0N0QzU0Rqc3RYL2ZiNUV1RG1BS0NIOFZoWmFKdUQ1Qkc3L3AvbC9PaVBxDQp0aGdpcHhXb2VzOEVITERaVWVDS0xQR2lUR3pyZWtvNXdqVWxDaFdkM0Q1MkhBWjFxTTQ9DQotLS0tLUVORCBDRVJUSUZJQ0FURS0tLS0tDQo="
 
 	type mockResp struct {
@@ -868,7 +1151,7 @@ func TestRetrieveCertificate(t *testing.T) {
 				t.Fatalf("unexpected err, err: %q, url: %s", err, expectedURL)
 			}
 
-			_, err = tpp.RetrieveCertificate(&certificate.Request{PickupID: `\VED\Policy\Test\bexample.com`, Timeout: tt.givenTimeout})
+			_, err = tpp.RetrieveCertificate(ctx, &certificate.Request{PickupID: `\VED\Policy\Test\bexample.com`, Timeout: tt.givenTimeout})
 			if atomic.LoadInt32(retrieveCount) != int32(len(tt.mockRetrieve)) {
 				t.Fatalf("tpp.RetrieveCertificate: expected %d calls to /certificates/retrieve, but got %d", len(tt.mockRetrieve), atomic.LoadInt32(retrieveCount))
 			}
@@ -924,7 +1207,8 @@ func writeRespWithCustomStatus(w http.ResponseWriter, status, body string) {
 }
 
 func DoRequestCertificateWithValidityHours(t *testing.T, tpp *Connector) {
-	config, err := tpp.ReadZoneConfiguration()
+	ctx := context.Background()
+	config, err := tpp.ReadZoneConfiguration(ctx)
 	if err != nil {
 		t.Fatalf("err is not nil, err: %s", err)
 	}
@@ -948,17 +1232,17 @@ func DoRequestCertificateWithValidityHours(t *testing.T, tpp *Connector) {
 	req.ValidityHours = nrHours
 	req.IssuerHint = util.IssuerHintMicrosoft
 
-	err = tpp.GenerateRequest(config, req)
+	err = tpp.GenerateRequest(ctx, config, req)
 	if err != nil {
 		t.Fatalf("err is not nil, err: %s", err)
 	}
 
-	t.Logf("getPolicyDN(ctx.TPPZone) = %s", getPolicyDN(ctx.TPPZone))
-	req.PickupID, err = tpp.RequestCertificate(req)
+	t.Logf("getPolicyDN(ctx.TPPZone) = %s", getPolicyDN(env.TPPZone))
+	req.PickupID, err = tpp.RequestCertificate(ctx, req)
 	if err != nil {
 		t.Fatalf("err is not nil, err: %s", err)
 	}
-	certCollections, err := tpp.RetrieveCertificate(req)
+	certCollections, err := tpp.RetrieveCertificate(ctx, req)
 	if err != nil {
 		t.Fatal(err)
 	}
@@ -982,7 +1266,8 @@ func DoRequestCertificateWithValidityHours(t *testing.T, tpp *Connector) {
 }
 
 func DoRequestCertificateWithValidityDuration(t *testing.T, tpp *Connector) {
-	config, err := tpp.ReadZoneConfiguration()
+	ctx := context.Background()
+	config, err := tpp.ReadZoneConfiguration(ctx)
 	if err != nil {
 		t.Fatalf("err is not nil, err: %s", err)
 	}
@@ -1006,17 +1291,17 @@ func DoRequestCertificateWithValidityDuration(t *testing.T, tpp *Connector) {
 	req.ValidityDuration = &validDuration
 	req.IssuerHint = util.IssuerHintMicrosoft
 
-	err = tpp.GenerateRequest(config, req)
+	err = tpp.GenerateRequest(ctx, config, req)
 	if err != nil {
 		t.Fatalf("err is not nil, err: %s", err)
 	}
 
-	t.Logf("getPolicyDN(ctx.TPPZone) = %s", getPolicyDN(ctx.TPPZone))
-	req.PickupID, err = tpp.RequestCertificate(req)
+	t.Logf("getPolicyDN(ctx.TPPZone) = %s", getPolicyDN(env.TPPZone))
+	req.PickupID, err = tpp.RequestCertificate(ctx, req)
 	if err != nil {
 		t.Fatalf("err is not nil, err: %s", err)
 	}
-	certCollections, err := tpp.RetrieveCertificate(req)
+	certCollections, err := tpp.RetrieveCertificate(ctx, req)
 	if err != nil {
 		t.Fatal(err)
 	}
@@ -1040,7 +1325,8 @@ func DoRequestCertificateWithValidityDuration(t *testing.T, tpp *Connector) {
 }
 
 func DoRequestCertificate(t *testing.T, tpp *Connector) {
-	config, err := tpp.ReadZoneConfiguration()
+	ctx := context.Background()
+	config, err := tpp.ReadZoneConfiguration(ctx)
 	if err != nil {
 		t.Fatalf("err is not nil, err: %s", err)
 	}
@@ -1059,17 +1345,17 @@ func DoRequestCertificate(t *testing.T, tpp *Connector) {
 	req.CustomFields = []certificate.CustomField{
 		{Name: "custom", Value: "2019-10-10"},
 	}
-	err = tpp.GenerateRequest(config, req)
+	err = tpp.GenerateRequest(ctx, config, req)
 	if err != nil {
 		t.Fatalf("err is not nil, err: %s", err)
 	}
 
-	t.Logf("getPolicyDN(ctx.TPPZone) = %s", getPolicyDN(ctx.TPPZone))
-	req.PickupID, err = tpp.RequestCertificate(req)
+	t.Logf("getPolicyDN(ctx.TPPZone) = %s", getPolicyDN(env.TPPZone))
+	req.PickupID, err = tpp.RequestCertificate(ctx, req)
 	if err != nil {
 		t.Fatalf("err is not nil, err: %s", err)
 	}
-	certCollections, err := tpp.RetrieveCertificate(req)
+	certCollections, err := tpp.RetrieveCertificate(ctx, req)
 	if err != nil {
 		t.Fatal(err)
 	}
@@ -1087,7 +1373,8 @@ func DoRequestCertificate(t *testing.T, tpp *Connector) {
 }
 
 func DoRevokeAndDisableCertificate(t *testing.T, tpp *Connector) (req *certificate.Request) {
-	config, err := tpp.ReadZoneConfiguration()
+	ctx := context.Background()
+	config, err := tpp.ReadZoneConfiguration(ctx)
 	if err != nil {
 		t.Fatalf("err is not nil, err: %s", err)
 	}
@@ -1100,12 +1387,12 @@ func DoRevokeAndDisableCertificate(t *testing.T, tpp *Connector) (req *certifica
 	req.Subject.Province = []string{"Nevada"}
 	req.Subject.Country = []string{"US"}
 	// req.FriendlyName = fmt.Sprintf("vcert integration test - %d", time.Now().Unix())
-	err = tpp.GenerateRequest(config, req)
+	err = tpp.GenerateRequest(ctx, config, req)
 	if err != nil {
 		t.Fatalf("err is not nil, err: %s", err)
 	}
 
-	certDN, err := tpp.RequestCertificate(req)
+	certDN, err := tpp.RequestCertificate(ctx, req)
 	if err != nil {
 		t.Fatalf("err is not nil, err: %s", err)
 	}
@@ -1120,7 +1407,7 @@ func DoRevokeAndDisableCertificate(t *testing.T, tpp *Connector) (req *certifica
 		req.PickupID = certDN
 		req.ChainOption = certificate.ChainOptionIgnore
 
-		_, err = tpp.RetrieveCertificate(req)
+		_, err = tpp.RetrieveCertificate(ctx, req)
 		_, isPending = err.(endpoint.ErrCertificatePending)
 	}
 	if err != nil {
@@ -1129,20 +1416,20 @@ func DoRevokeAndDisableCertificate(t *testing.T, tpp *Connector) (req *certifica
 
 	t.Logf("Start revocation for %s", certDN)
 	revReq := &certificate.RevocationRequest{CertificateDN: certDN, Disable: true}
-	err = tpp.RevokeCertificate(revReq)
+	err = tpp.RevokeCertificate(ctx, revReq)
 	if err != nil {
 		t.Fatalf("%s", err)
 	}
 
 	t.Logf("Verifying the Certificate is Disabled")
-	guid, err := tpp.configDNToGuid(certDN)
+	guid, err := tpp.configDNToGuid(ctx, certDN)
 	if err != nil {
 		t.Fatalf("%s", err)
 	}
 	if guid == "" {
 		t.Fatalf("Certificate with DN %s doesn't exists", certDN)
 	}
-	details, err := tpp.searchCertificateDetails(guid)
+	details, err := tpp.searchCertificateDetails(ctx, guid)
 	if err != nil {
 		t.Fatalf("%s", err)
 	}
@@ -1157,17 +1444,18 @@ func DoRevokeAndDisableCertificate(t *testing.T, tpp *Connector) (req *certifica
 }
 
 func TestRequestCertificateServiceGenerated(t *testing.T) {
-	tpp, err := getTestConnector(ctx.TPPurl, ctx.TPPZone)
+	ctx := context.Background()
+	tpp, err := getTestConnector(env.TPPurl, env.TPPZone)
 	if err != nil {
 		t.Fatalf("err is not nil, err: %s url: %s", err, expectedURL)
 	}
 
-	err = tpp.Authenticate(&endpoint.Authentication{AccessToken: ctx.TPPaccessToken})
+	err = tpp.Authenticate(ctx, &endpoint.Authentication{AccessToken: env.TPPaccessToken})
 	if err != nil {
 		t.Fatalf("err is not nil, err: %s", err)
 	}
 
-	config, err := tpp.ReadZoneConfiguration()
+	config, err := tpp.ReadZoneConfiguration(ctx)
 	if err != nil {
 		t.Fatal("failed to read zone configuration")
 	}
@@ -1189,7 +1477,7 @@ func TestRequestCertificateServiceGenerated(t *testing.T) {
 	req.KeyPassword = "newPassw0rd!"
 	config.UpdateCertificateRequest(req)
 
-	pickupId, err := tpp.RequestCertificate(req)
+	pickupId, err := tpp.RequestCertificate(ctx, req)
 	if err != nil {
 		t.Fatalf("err is not nil, err: %s", err)
 	}
@@ -1203,7 +1491,7 @@ func TestRequestCertificateServiceGenerated(t *testing.T) {
 	for isPending {
 		t.Logf("%s is pending...", pickupId)
 		time.Sleep(time.Second * 1)
-		pcc, err = tpp.RetrieveCertificate(req)
+		pcc, err = tpp.RetrieveCertificate(ctx, req)
 		_, isPending = err.(endpoint.ErrCertificatePending)
 	}
 	if err != nil {
@@ -1216,18 +1504,19 @@ func TestRequestCertificateServiceGenerated(t *testing.T) {
 }
 
 func TestRetrieveNonIssuedCertificate(t *testing.T) {
-	tpp, err := getTestConnector(ctx.TPPurl, ctx.TPPZone)
+	ctx := context.Background()
+	tpp, err := getTestConnector(env.TPPurl, env.TPPZone)
 	if err != nil {
 		t.Fatalf("err is not nil, err: %s url: %s", err, expectedURL)
 	}
 
 	if tpp.apiKey == "" {
-		err = tpp.Authenticate(&endpoint.Authentication{AccessToken: ctx.TPPaccessToken})
+		err = tpp.Authenticate(ctx, &endpoint.Authentication{AccessToken: env.TPPaccessToken})
 		if err != nil {
 			t.Fatalf("err is not nil, err: %s", err)
 		}
 	}
-	config, err := tpp.ReadZoneConfiguration()
+	config, err := tpp.ReadZoneConfiguration(ctx)
 	if err != nil {
 		t.Fatalf("err is not nil, err: %s", err)
 	}
@@ -1240,40 +1529,41 @@ func TestRetrieveNonIssuedCertificate(t *testing.T) {
 	req.Subject.Province = []string{"Nevada"}
 	req.Subject.Country = []string{"US"}
 	req.FriendlyName = fmt.Sprintf("vcert integration test - %d", time.Now().Unix())
-	err = tpp.GenerateRequest(config, req)
+	err = tpp.GenerateRequest(ctx, config, req)
 	if err != nil {
 		t.Fatalf("err is not nil, err: %s", err)
 	}
 
-	requestID, err := tpp.RequestCertificate(req)
+	requestID, err := tpp.RequestCertificate(ctx, req)
 	if err != nil {
 		t.Fatalf("err is not nil, err: %s", err)
 	}
 
 	req.PickupID = requestID
 	req.ChainOption = certificate.ChainOptionIgnore
-	_, err = tpp.RetrieveCertificate(req)
+	_, err = tpp.RetrieveCertificate(ctx, req)
 	if err == nil {
 		t.Fatalf("Error should not be nil, certificate has not been issued.")
 	}
 }
 
 func TestRevokeCertificate(t *testing.T) {
+	ctx := context.Background()
 
 	cn := "www-1.venqa.venafi.com"
 
-	tpp, err := getTestConnector(ctx.TPPurl, ctx.TPPZone)
+	tpp, err := getTestConnector(env.TPPurl, env.TPPZone)
 	if err != nil {
 		t.Fatalf("err is not nil, err: %s url: %s", err, expectedURL)
 	}
 
 	if tpp.apiKey == "" {
-		err = tpp.Authenticate(&endpoint.Authentication{AccessToken: ctx.TPPaccessToken})
+		err = tpp.Authenticate(ctx, &endpoint.Authentication{AccessToken: env.TPPaccessToken})
 		if err != nil {
 			t.Fatalf("err is not nil, err: %s", err)
 		}
 	}
-	config, err := tpp.ReadZoneConfiguration()
+	config, err := tpp.ReadZoneConfiguration(ctx)
 	if err != nil {
 		t.Fatalf("err is not nil, err: %s", err)
 	}
@@ -1286,12 +1576,12 @@ func TestRevokeCertificate(t *testing.T) {
 	req.Subject.Province = []string{"Nevada"}
 	req.Subject.Country = []string{"US"}
 	// req.FriendlyName = fmt.Sprintf("vcert integration test - %d", time.Now().Unix())
-	err = tpp.GenerateRequest(config, req)
+	err = tpp.GenerateRequest(ctx, config, req)
 	if err != nil {
 		t.Fatalf("err is not nil, err: %s", err)
 	}
 
-	certDN, err := tpp.RequestCertificate(req)
+	certDN, err := tpp.RequestCertificate(ctx, req)
 	if err != nil {
 		t.Fatalf("err is not nil, err: %s", err)
 	}
@@ -1304,7 +1594,7 @@ func TestRevokeCertificate(t *testing.T) {
 	for isPending {
 		t.Logf("%s is pending...", certDN)
 		time.Sleep(time.Second * 1)
-		_, err = tpp.RetrieveCertificate(req)
+		_, err = tpp.RetrieveCertificate(ctx, req)
 		_, isPending = err.(endpoint.ErrCertificatePending)
 	}
 	if err != nil {
@@ -1313,46 +1603,48 @@ func TestRevokeCertificate(t *testing.T) {
 
 	t.Logf("Start revocation for %s", certDN)
 	revReq := &certificate.RevocationRequest{CertificateDN: certDN, Disable: false}
-	err = tpp.RevokeCertificate(revReq)
+	err = tpp.RevokeCertificate(ctx, revReq)
 	if err != nil {
 		t.Fatalf("%s", err)
 	}
 }
 
 func TestRevokeNonIssuedCertificate(t *testing.T) {
+	ctx := context.Background()
 
 	cn := "does-not-exist.venqa.venafi.com"
 
-	certDN := fmt.Sprintf(`\VED\Policy\%s\%s`, ctx.TPPZone, cn)
+	certDN := fmt.Sprintf(`\VED\Policy\%s\%s`, env.TPPZone, cn)
 
-	tpp, err := getTestConnector(ctx.TPPurl, ctx.TPPZone)
+	tpp, err := getTestConnector(env.TPPurl, env.TPPZone)
 	if err != nil {
 		t.Fatalf("err is not nil, err: %s url: %s", err, expectedURL)
 	}
 
 	if tpp.apiKey == "" {
-		err = tpp.Authenticate(&endpoint.Authentication{AccessToken: ctx.TPPaccessToken})
+		err = tpp.Authenticate(ctx, &endpoint.Authentication{AccessToken: env.TPPaccessToken})
 		if err != nil {
 			t.Fatalf("err is not nil, err: %s", err)
 		}
 	}
 
 	revReq := &certificate.RevocationRequest{CertificateDN: certDN, Disable: false}
-	err = tpp.RevokeCertificate(revReq)
+	err = tpp.RevokeCertificate(ctx, revReq)
 	if err == nil {
 		t.Fatalf("It should NOT revoke certificate at %s which doesn't exist", certDN)
 	}
 }
 
 func TestReEnrollRevokedAndDisabledCertificate(t *testing.T) {
+	ctx := context.Background()
 
-	tpp, err := getTestConnector(ctx.TPPurl, ctx.TPPZone)
+	tpp, err := getTestConnector(env.TPPurl, env.TPPZone)
 	if err != nil {
 		t.Fatalf("err is not nil, err: %s url: %s", err, expectedURL)
 	}
 
 	if tpp.apiKey == "" {
-		err = tpp.Authenticate(&endpoint.Authentication{AccessToken: ctx.TPPaccessToken})
+		err = tpp.Authenticate(ctx, &endpoint.Authentication{AccessToken: env.TPPaccessToken})
 		if err != nil {
 			t.Fatalf("err is not nil, err: %s", err)
 		}
@@ -1361,7 +1653,7 @@ func TestReEnrollRevokedAndDisabledCertificate(t *testing.T) {
 	req := DoRevokeAndDisableCertificate(t, tpp)
 
 	t.Logf("Trying to re-enable the certificate")
-	_, err = tpp.RequestCertificate(req)
+	_, err = tpp.RequestCertificate(ctx, req)
 	if err != nil {
 		t.Fatal(err)
 	}
@@ -1369,14 +1661,15 @@ func TestReEnrollRevokedAndDisabledCertificate(t *testing.T) {
 }
 
 func TestRevokeAndDisableCertificate(t *testing.T) {
+	ctx := context.Background()
 
-	tpp, err := getTestConnector(ctx.TPPurl, ctx.TPPZone)
+	tpp, err := getTestConnector(env.TPPurl, env.TPPZone)
 	if err != nil {
 		t.Fatalf("err is not nil, err: %s url: %s", err, expectedURL)
 	}
 
 	if tpp.apiKey == "" {
-		err = tpp.Authenticate(&endpoint.Authentication{AccessToken: ctx.TPPaccessToken})
+		err = tpp.Authenticate(ctx, &endpoint.Authentication{AccessToken: env.TPPaccessToken})
 		if err != nil {
 			t.Fatalf("err is not nil, err: %s", err)
 		}
@@ -1386,21 +1679,22 @@ func TestRevokeAndDisableCertificate(t *testing.T) {
 }
 
 func TestRetireCertificate(t *testing.T) {
+	ctx := context.Background()
 
 	cn := "www-retire1.venqa.venafi.com"
 
-	tpp, err := getTestConnector(ctx.TPPurl, ctx.TPPZone)
+	tpp, err := getTestConnector(env.TPPurl, env.TPPZone)
 	if err != nil {
 		t.Fatalf("err is not nil, err: %s url: %s", err, expectedURL)
 	}
 
 	if tpp.apiKey == "" {
-		err = tpp.Authenticate(&endpoint.Authentication{AccessToken: ctx.TPPaccessToken})
+		err = tpp.Authenticate(ctx, &endpoint.Authentication{AccessToken: env.TPPaccessToken})
 		if err != nil {
 			t.Fatalf("err is not nil, err: %s", err)
 		}
 	}
-	config, err := tpp.ReadZoneConfiguration()
+	config, err := tpp.ReadZoneConfiguration(ctx)
 	if err != nil {
 		t.Fatalf("err is not nil, err: %s", err)
 	}
@@ -1412,12 +1706,12 @@ func TestRetireCertificate(t *testing.T) {
 	req.Subject.Locality = []string{"Las Vegas"}
 	req.Subject.Province = []string{"Nevada"}
 	req.Subject.Country = []string{"US"}
-	err = tpp.GenerateRequest(config, req)
+	err = tpp.GenerateRequest(ctx, config, req)
 	if err != nil {
 		t.Fatalf("err is not nil, err: %s", err)
 	}
 
-	certDN, err := tpp.RequestCertificate(req)
+	certDN, err := tpp.RequestCertificate(ctx, req)
 	if err != nil {
 		t.Fatalf("err is not nil, err: %s", err)
 	}
@@ -1430,7 +1724,7 @@ func TestRetireCertificate(t *testing.T) {
 	for isPending {
 		t.Logf("%s is pending...", certDN)
 		time.Sleep(time.Second * 1)
-		_, err = tpp.RetrieveCertificate(req)
+		_, err = tpp.RetrieveCertificate(ctx, req)
 		_, isPending = err.(endpoint.ErrCertificatePending)
 	}
 	if err != nil {
@@ -1439,28 +1733,29 @@ func TestRetireCertificate(t *testing.T) {
 
 	t.Logf("Start retire for %s", certDN)
 	retireReq := &certificate.RetireRequest{CertificateDN: certDN}
-	err = tpp.RetireCertificate(retireReq)
+	err = tpp.RetireCertificate(ctx, retireReq)
 	if err != nil {
 		t.Fatalf("%s", err)
 	}
 }
 
 func TestRetireWithThumbprintCertificate(t *testing.T) {
+	ctx := context.Background()
 
 	cn := "www-retireThumprint.venqa.venafi.com"
 
-	tpp, err := getTestConnector(ctx.TPPurl, ctx.TPPZone)
+	tpp, err := getTestConnector(env.TPPurl, env.TPPZone)
 	if err != nil {
 		t.Fatalf("err is not nil, err: %s url: %s", err, expectedURL)
 	}
 
 	if tpp.apiKey == "" {
-		err = tpp.Authenticate(&endpoint.Authentication{AccessToken: ctx.TPPaccessToken})
+		err = tpp.Authenticate(ctx, &endpoint.Authentication{AccessToken: env.TPPaccessToken})
 		if err != nil {
 			t.Fatalf("err is not nil, err: %s", err)
 		}
 	}
-	config, err := tpp.ReadZoneConfiguration()
+	config, err := tpp.ReadZoneConfiguration(ctx)
 	if err != nil {
 		t.Fatalf("err is not nil, err: %s", err)
 	}
@@ -1472,12 +1767,12 @@ func TestRetireWithThumbprintCertificate(t *testing.T) {
 	req.Subject.Locality = []string{"Las Vegas"}
 	req.Subject.Province = []string{"Nevada"}
 	req.Subject.Country = []string{"US"}
-	err = tpp.GenerateRequest(config, req)
+	err = tpp.GenerateRequest(ctx, config, req)
 	if err != nil {
 		t.Fatalf("err is not nil, err: %s", err)
 	}
 
-	certDN, err := tpp.RequestCertificate(req)
+	certDN, err := tpp.RequestCertificate(ctx, req)
 	if err != nil {
 		t.Fatalf("err is not nil, err: %s", err)
 	}
@@ -1491,7 +1786,7 @@ func TestRetireWithThumbprintCertificate(t *testing.T) {
 	for isPending {
 		t.Logf("%s is pending...", certDN)
 		time.Sleep(time.Second * 1)
-		pcc, err = tpp.RetrieveCertificate(req)
+		pcc, err = tpp.RetrieveCertificate(ctx, req)
 		_, isPending = err.(endpoint.ErrCertificatePending)
 	}
 	if err != nil {
@@ -1504,25 +1799,26 @@ func TestRetireWithThumbprintCertificate(t *testing.T) {
 
 	t.Logf("Start retire for %s", certDN)
 	retireReq := &certificate.RetireRequest{Thumbprint: hexThumbprint}
-	err = tpp.RetireCertificate(retireReq)
+	err = tpp.RetireCertificate(ctx, retireReq)
 	if err != nil {
 		t.Fatalf("%s", err)
 	}
 }
 
 func TestRetireNonIssuedCertificate(t *testing.T) {
+	ctx := context.Background()
 
 	cn := "does-not-exist.venqa.venafi.com"
 
-	certDN := fmt.Sprintf(`\VED\Policy\%s\%s`, ctx.TPPZone, cn)
+	certDN := fmt.Sprintf(`\VED\Policy\%s\%s`, env.TPPZone, cn)
 
-	tpp, err := getTestConnector(ctx.TPPurl, ctx.TPPZone)
+	tpp, err := getTestConnector(env.TPPurl, env.TPPZone)
 	if err != nil {
 		t.Fatalf("err is not nil, err: %s url: %s", err, expectedURL)
 	}
 
 	if tpp.apiKey == "" {
-		err = tpp.Authenticate(&endpoint.Authentication{AccessToken: ctx.TPPaccessToken})
+		err = tpp.Authenticate(ctx, &endpoint.Authentication{AccessToken: env.TPPaccessToken})
 		if err != nil {
 			t.Fatalf("err is not nil, err: %s", err)
 		}
@@ -1530,28 +1826,29 @@ func TestRetireNonIssuedCertificate(t *testing.T) {
 
 	t.Logf("Start retire for %s", certDN)
 	retireReq := &certificate.RetireRequest{CertificateDN: certDN}
-	err = tpp.RetireCertificate(retireReq)
+	err = tpp.RetireCertificate(ctx, retireReq)
 	if err == nil {
 		t.Fatalf("It should NOT retire certificate at %s which doesn't exist", certDN)
 	}
 }
 
 func TestRetireCertificateTwice(t *testing.T) {
+	ctx := context.Background()
 
 	cn := "www-retire2.venqa.venafi.com"
 
-	tpp, err := getTestConnector(ctx.TPPurl, ctx.TPPZone)
+	tpp, err := getTestConnector(env.TPPurl, env.TPPZone)
 	if err != nil {
 		t.Fatalf("err is not nil, err: %s url: %s", err, expectedURL)
 	}
 
 	if tpp.apiKey == "" {
-		err = tpp.Authenticate(&endpoint.Authentication{AccessToken: ctx.TPPaccessToken})
+		err = tpp.Authenticate(ctx, &endpoint.Authentication{AccessToken: env.TPPaccessToken})
 		if err != nil {
 			t.Fatalf("err is not nil, err: %s", err)
 		}
 	}
-	config, err := tpp.ReadZoneConfiguration()
+	config, err := tpp.ReadZoneConfiguration(ctx)
 	if err != nil {
 		t.Fatalf("err is not nil, err: %s", err)
 	}
@@ -1563,12 +1860,12 @@ func TestRetireCertificateTwice(t *testing.T) {
 	req.Subject.Locality = []string{"Las Vegas"}
 	req.Subject.Province = []string{"Nevada"}
 	req.Subject.Country = []string{"US"}
-	err = tpp.GenerateRequest(config, req)
+	err = tpp.GenerateRequest(ctx, config, req)
 	if err != nil {
 		t.Fatalf("err is not nil, err: %s", err)
 	}
 
-	certDN, err := tpp.RequestCertificate(req)
+	certDN, err := tpp.RequestCertificate(ctx, req)
 	if err != nil {
 		t.Fatalf("err is not nil, err: %s", err)
 	}
@@ -1581,7 +1878,7 @@ func TestRetireCertificateTwice(t *testing.T) {
 	for isPending {
 		t.Logf("%s is pending...", certDN)
 		time.Sleep(time.Second * 1)
-		_, err = tpp.RetrieveCertificate(req)
+		_, err = tpp.RetrieveCertificate(ctx, req)
 		_, isPending = err.(endpoint.ErrCertificatePending)
 	}
 	if err != nil {
@@ -1590,35 +1887,36 @@ func TestRetireCertificateTwice(t *testing.T) {
 
 	t.Logf("Start retire for %s", certDN)
 	retireReq := &certificate.RetireRequest{CertificateDN: certDN}
-	err = tpp.RetireCertificate(retireReq)
+	err = tpp.RetireCertificate(ctx, retireReq)
 	if err != nil {
 		t.Fatalf("%s", err)
 	}
 
 	t.Logf("Start retire for second time same cert with DN: %s", certDN)
 	retireReqSecond := &certificate.RetireRequest{CertificateDN: certDN}
-	err = tpp.RetireCertificate(retireReqSecond)
+	err = tpp.RetireCertificate(ctx, retireReqSecond)
 	if err != nil {
 		t.Fatalf("%s", err)
 	}
 }
 
 func TestRenewCertificate(t *testing.T) {
+	ctx := context.Background()
 
 	cn := test.RandCN()
 
-	tpp, err := getTestConnector(ctx.TPPurl, ctx.TPPZone)
+	tpp, err := getTestConnector(env.TPPurl, env.TPPZone)
 	if err != nil {
 		t.Fatalf("err is not nil, err: %s url: %s", err, expectedURL)
 	}
 
 	if tpp.apiKey == "" {
-		err = tpp.Authenticate(&endpoint.Authentication{AccessToken: ctx.TPPaccessToken})
+		err = tpp.Authenticate(ctx, &endpoint.Authentication{AccessToken: env.TPPaccessToken})
 		if err != nil {
 			t.Fatalf("err is not nil, err: %s", err)
 		}
 	}
-	config, err := tpp.ReadZoneConfiguration()
+	config, err := tpp.ReadZoneConfiguration(ctx)
 	if err != nil {
 		t.Fatalf("err is not nil, err: %s", err)
 	}
@@ -1631,12 +1929,12 @@ func TestRenewCertificate(t *testing.T) {
 	req.Subject.Province = []string{"Nevada"}
 	req.Subject.Country = []string{"US"}
 	req.CsrOrigin = certificate.ServiceGeneratedCSR
-	err = tpp.GenerateRequest(config, req)
+	err = tpp.GenerateRequest(ctx, config, req)
 	if err != nil {
 		t.Fatalf("err is not nil, err: %s", err)
 	}
 
-	certDN, err := tpp.RequestCertificate(req)
+	certDN, err := tpp.RequestCertificate(ctx, req)
 	if err != nil {
 		t.Fatalf("err is not nil, err: %s", err)
 	}
@@ -1649,7 +1947,7 @@ func TestRenewCertificate(t *testing.T) {
 		for isPending {
 			t.Logf("%s is pending...", certDN)
 			time.Sleep(time.Second * 1)
-			pcc, err = tpp.RetrieveCertificate(req)
+			pcc, err = tpp.RetrieveCertificate(ctx, req)
 			_, isPending = err.(endpoint.ErrCertificatePending)
 		}
 		if err != nil {
@@ -1667,7 +1965,7 @@ func TestRenewCertificate(t *testing.T) {
 	t.Logf("retrieved certificate, Serial is %s", oldCert.SerialNumber)
 
 	renewByCertificateDN := &certificate.RenewalRequest{CertificateDN: certDN}
-	reqId1, err := tpp.RenewCertificate(renewByCertificateDN)
+	reqId1, err := tpp.RenewCertificate(ctx, renewByCertificateDN)
 	if err != nil {
 		t.Fatal(err)
 	}
@@ -1681,7 +1979,7 @@ func TestRenewCertificate(t *testing.T) {
 		for isPending {
 			t.Logf("%s is pending...", certDN)
 			time.Sleep(time.Second * 1)
-			pcc, err = tpp.RetrieveCertificate(req)
+			pcc, err = tpp.RetrieveCertificate(ctx, req)
 			_, isPending = err.(endpoint.ErrCertificatePending)
 		}
 		if err != nil {
@@ -1704,14 +2002,15 @@ func TestRenewCertificate(t *testing.T) {
 }
 
 func TestRenewCertRestoringValues(t *testing.T) {
+	ctx := context.Background()
 	cn := test.RandCN()
-	tpp, err := getTestConnector(ctx.TPPurl, ctx.TPPZoneECDSA)
+	tpp, err := getTestConnector(env.TPPurl, env.TPPZoneECDSA)
 	if err != nil {
 		t.Fatalf("err is not nil, err: %s url: %s", err, expectedURL)
 	}
 
 	if tpp.apiKey == "" {
-		err = tpp.Authenticate(&endpoint.Authentication{AccessToken: ctx.TPPaccessToken})
+		err = tpp.Authenticate(ctx, &endpoint.Authentication{AccessToken: env.TPPaccessToken})
 		if err != nil {
 			t.Fatalf("err is not nil, err: %s", err)
 		}
@@ -1728,12 +2027,12 @@ func TestRenewCertRestoringValues(t *testing.T) {
 	req.KeyCurve = certificate.EllipticCurveP521
 	req.CsrOrigin = certificate.ServiceGeneratedCSR
 	req.Timeout = time.Second * 10
-	err = tpp.GenerateRequest(&endpoint.ZoneConfiguration{}, req)
+	err = tpp.GenerateRequest(ctx, &endpoint.ZoneConfiguration{}, req)
 	if err != nil {
 		t.Fatalf("err is not nil, err: %s", err)
 	}
 
-	_, err = tpp.RequestCertificate(req)
+	_, err = tpp.RequestCertificate(ctx, req)
 	if err != nil {
 		t.Fatal(err)
 	}
@@ -1741,7 +2040,7 @@ func TestRenewCertRestoringValues(t *testing.T) {
 	req.FetchPrivateKey = true
 	req.KeyPassword = os.Getenv("TPP_PASSWORD")
 
-	pcc, err := tpp.RetrieveCertificate(req)
+	pcc, err := tpp.RetrieveCertificate(ctx, req)
 	if err != nil {
 		t.Fatal(err)
 	}
@@ -1760,12 +2059,12 @@ func TestRenewCertRestoringValues(t *testing.T) {
 	renewReq := certificate.RenewalRequest{
 		CertificateDN: req.PickupID,
 	}
-	pickupdID, err := tpp.RenewCertificate(&renewReq)
+	pickupdID, err := tpp.RenewCertificate(ctx, &renewReq)
 	if err != nil {
 		t.Fatal(err)
 	}
 	req = &certificate.Request{PickupID: pickupdID, Timeout: 30 * time.Second}
-	pcc, err = tpp.RetrieveCertificate(req)
+	pcc, err = tpp.RetrieveCertificate(ctx, req)
 	if err != nil {
 		t.Fatal(err)
 	}
@@ -1838,19 +2137,20 @@ ZtIVl/CH/az0xqLKWIlmWOip9SfUVlZdgege+PlQtRqoFVOsH8+MEg==
 -----END RSA PRIVATE KEY-----`
 
 func TestImportCertificate(t *testing.T) {
+	ctx := context.Background()
 
-	tpp, err := getTestConnector(ctx.TPPurl, ctx.TPPZone)
+	tpp, err := getTestConnector(env.TPPurl, env.TPPZone)
 	if err != nil {
 		t.Fatalf("err is not nil, err: %s", err)
 	}
-	err = tpp.Authenticate(&endpoint.Authentication{AccessToken: ctx.TPPaccessToken})
+	err = tpp.Authenticate(ctx, &endpoint.Authentication{AccessToken: env.TPPaccessToken})
 	if err != nil {
 		t.Fatalf("err is not nil, err: %s", err)
 	}
 
 	importReq := &certificate.ImportRequest{
 		// PolicyDN should be like "\\VED\\Policy\\devops\\vcert", or empty (c.zone is used then)
-		PolicyDN:        getPolicyDN(ctx.TPPZone),
+		PolicyDN:        getPolicyDN(env.TPPZone),
 		ObjectName:      "import12348.venafi.example.com",
 		CertificateData: crt,
 		PrivateKeyData:  pk,
@@ -1859,7 +2159,7 @@ func TestImportCertificate(t *testing.T) {
 
 	pp(importReq)
 
-	importResp, err := tpp.ImportCertificate(importReq)
+	importResp, err := tpp.ImportCertificate(ctx, importReq)
 	if err != nil {
 		t.Fatalf("failed to import certificate: %s", err)
 	}
@@ -1868,14 +2168,15 @@ func TestImportCertificate(t *testing.T) {
 }
 
 func TestReadPolicyConfiguration(t *testing.T) {
+	ctx := context.Background()
 	//todo: add more zones tests
-	tpp, err := getTestConnector(ctx.TPPurl, ctx.TPPZone)
+	tpp, err := getTestConnector(env.TPPurl, env.TPPZone)
 	if err != nil {
 		t.Fatalf("err is not nil, err: %s url: %s", err, expectedURL)
 	}
 
 	if tpp.apiKey == "" {
-		err = tpp.Authenticate(&endpoint.Authentication{AccessToken: ctx.TPPaccessToken})
+		err = tpp.Authenticate(ctx, &endpoint.Authentication{AccessToken: env.TPPaccessToken})
 		if err != nil {
 			t.Fatalf("err is not nil, err: %s", err)
 		}
@@ -1885,7 +2186,7 @@ func TestReadPolicyConfiguration(t *testing.T) {
 		policy endpoint.Policy
 	}{
 		{
-			ctx.TPPZone, // todo: replace with env variable
+			env.TPPZone, // todo: replace with env variable
 			endpoint.Policy{
 				[]string{".*"},
 				[]string{".*"},
@@ -1904,10 +2205,11 @@ func TestReadPolicyConfiguration(t *testing.T) {
 				[]string{".*"},
 				true,
 				true,
+				0,
 			},
 		},
 		{
-			ctx.TPPZoneRestricted,
+			env.TPPZoneRestricted,
 			endpoint.Policy{
 				[]string{`^([\p{L}\p{N}-*]+\.)*vfidev\.com$`, `^([\p{L}\p{N}-*]+\.)*vfidev\.net$`, `^([\p{L}\p{N}-*]+\.)*vfide\.org$`},
 				[]string{`^Venafi Inc\.$`},
@@ -1923,10 +2225,11 @@ func TestReadPolicyConfiguration(t *testing.T) {
 				[]string{".*"},
 				true,
 				true,
+				0,
 			},
 		},
 		{
-			ctx.TPPZoneECDSA,
+			env.TPPZoneECDSA,
 			endpoint.Policy{
 				[]string{".*"},
 				[]string{".*"},
@@ -1944,12 +2247,13 @@ func TestReadPolicyConfiguration(t *testing.T) {
 				[]string{".*"},
 				true,
 				true,
+				0,
 			},
 		},
 	}
 	for _, c := range cases {
 		tpp.SetZone(c.zone)
-		policy, err := tpp.ReadPolicyConfiguration()
+		policy, err := tpp.ReadPolicyConfiguration(ctx)
 		if err != nil {
 			t.Fatal(err)
 		}
@@ -1967,18 +2271,19 @@ func pp(a interface{}) {
 }
 
 func Test_EnrollDoesntChange(t *testing.T) {
-	tpp, err := getTestConnector(ctx.TPPurl, ctx.TPPZone)
+	ctx := context.Background()
+	tpp, err := getTestConnector(env.TPPurl, env.TPPZone)
 	if err != nil {
 		t.Fatalf("err is not nil, err: %s url: %s", err, expectedURL)
 	}
 
 	if tpp.apiKey == "" {
-		err = tpp.Authenticate(&endpoint.Authentication{AccessToken: ctx.TPPaccessToken})
+		err = tpp.Authenticate(ctx, &endpoint.Authentication{AccessToken: env.TPPaccessToken})
 		if err != nil {
 			t.Fatalf("err is not nil, err: %s", err)
 		}
 	}
-	config, err := tpp.ReadZoneConfiguration()
+	config, err := tpp.ReadZoneConfiguration(ctx)
 	if err != nil {
 		t.Fatalf("err is not nil, err: %s", err)
 	}
@@ -1995,13 +2300,13 @@ func Test_EnrollDoesntChange(t *testing.T) {
 	req.PrivateKey = pemRSADecode([]byte(pk))
 
 	req.FriendlyName = cn
-	err = tpp.GenerateRequest(config, req)
+	err = tpp.GenerateRequest(ctx, config, req)
 	if err != nil {
 		t.Fatalf("err is not nil, err: %s", err)
 	}
 
-	t.Logf("getPolicyDN(ctx.TPPZone) = %s", getPolicyDN(ctx.TPPZone))
-	_, err = tpp.RequestCertificate(req)
+	t.Logf("getPolicyDN(ctx.TPPZone) = %s", getPolicyDN(env.TPPZone))
+	_, err = tpp.RequestCertificate(ctx, req)
 	if err != nil {
 		t.Fatalf("err is not nil, err: %s", err)
 	}
@@ -2074,19 +2379,20 @@ func TestNormalizeURL(t *testing.T) {
 }
 
 func Test_GetCertificateList(t *testing.T) {
-	tpp, err := getTestConnector(ctx.TPPurl, ctx.TPPZone)
+	ctx := context.Background()
+	tpp, err := getTestConnector(env.TPPurl, env.TPPZone)
 	if err != nil {
 		t.Fatalf("err is not nil, err: %s url: %s", err, expectedURL)
 	}
 	if tpp.apiKey == "" {
-		err = tpp.Authenticate(&endpoint.Authentication{AccessToken: ctx.TPPaccessToken})
+		err = tpp.Authenticate(ctx, &endpoint.Authentication{AccessToken: env.TPPaccessToken})
 		if err != nil {
 			t.Fatalf("err is not nil, err: %s", err)
 		}
 	}
 	for _, count := range []int{10, 100, 101, 153, 200, 300} {
 		timeStarted := time.Now()
-		l, err := tpp.ListCertificates(endpoint.Filter{Limit: &count})
+		l, err := tpp.ListCertificates(ctx, endpoint.Filter{Limit: &count})
 		if err != nil {
 			t.Fatal(err)
 		}
@@ -2098,12 +2404,13 @@ func Test_GetCertificateList(t *testing.T) {
 			}
 		}
 		if len(set) != count {
-			t.Errorf("mismatched certificates number: wait %d, got %d for zone %s", count, len(set), ctx.TPPZone)
+			t.Errorf("mismatched certificates number: wait %d, got %d for zone %s", count, len(set), env.TPPZone)
 		}
 	}
 }
 
 func Test_GetCertificateListFull(t *testing.T) {
+	ctx := context.Background()
 	const certPem = `-----BEGIN CERTIFICATE-----
 MIICZjCCAcegAwIBAgIIe1Dq0CjsAx8wCgYIKoZIzj0EAwQwEjEQMA4GA1UEAxMH
 VGVzdCBDQTAeFw0xOTExMjAxNDU3MDBaFw0xOTExMjYxNDUwMDBaMHoxCzAJBgNV
@@ -2120,26 +2427,26 @@ vOjeQhOnqrPdQINzUCKMSuqxqFGbQAJCAZs3Be1Pz6eeKHNLzr7mYQ2/pWSjfun4
 45nAry0Rb308mXI49fEprVJDQ0zyb3gM8Z8OA0wDyaQ+pcwloQkvOAM2
 -----END CERTIFICATE-----
 `
-	tpp, err := getTestConnector(ctx.TPPurl, ctx.TPPZoneRestricted)
+	tpp, err := getTestConnector(env.TPPurl, env.TPPZoneRestricted)
 	if err != nil {
 		t.Fatalf("err is not nil, err: %s url: %s", err, expectedURL)
 	}
 	if tpp.apiKey == "" {
-		err = tpp.Authenticate(&endpoint.Authentication{AccessToken: ctx.TPPaccessToken})
+		err = tpp.Authenticate(ctx, &endpoint.Authentication{AccessToken: env.TPPaccessToken})
 		if err != nil {
 			t.Fatalf("err is not nil, err: %s", err)
 		}
 	}
 	importReq := certificate.ImportRequest{CertificateData: certPem}
-	_, err = tpp.ImportCertificate(&importReq)
+	_, err = tpp.ImportCertificate(ctx, &importReq)
 	if err != nil {
 		t.Fatal(err)
 	}
-	validList, err := tpp.ListCertificates(endpoint.Filter{})
+	validList, err := tpp.ListCertificates(ctx, endpoint.Filter{})
 	if err != nil {
 		t.Fatal(err)
 	}
-	fullList, err := tpp.ListCertificates(endpoint.Filter{WithExpired: true})
+	fullList, err := tpp.ListCertificates(ctx, endpoint.Filter{WithExpired: true})
 	if err != nil {
 		t.Fatal(err)
 	}
@@ -2148,21 +2455,21 @@ vOjeQhOnqrPdQINzUCKMSuqxqFGbQAJCAZs3Be1Pz6eeKHNLzr7mYQ2/pWSjfun4
 	}
 	req := certificate.Request{Subject: pkix.Name{CommonName: fmt.Sprintf("test%d%d.vfidev.com", time.Now().Unix(), time.Now().Nanosecond())}, KeyType: certificate.KeyTypeRSA, KeyLength: 2048}
 
-	err = tpp.GenerateRequest(nil, &req)
+	err = tpp.GenerateRequest(ctx, nil, &req)
 	if err != nil {
 		t.Fatal(err)
 	}
 
-	req.PickupID, err = tpp.RequestCertificate(&req)
+	req.PickupID, err = tpp.RequestCertificate(ctx, &req)
 	if err != nil {
 		t.Fatal(err)
 	}
 	time.Sleep(time.Second * 10) //todo: remove after fix bug VEN-54714
-	validList2, err := tpp.ListCertificates(endpoint.Filter{})
+	validList2, err := tpp.ListCertificates(ctx, endpoint.Filter{})
 	if err != nil {
 		t.Fatal(err)
 	}
-	fullList2, err := tpp.ListCertificates(endpoint.Filter{WithExpired: true})
+	fullList2, err := tpp.ListCertificates(ctx, endpoint.Filter{WithExpired: true})
 	if err != nil {
 		t.Fatal(err)
 	}
@@ -2177,7 +2484,8 @@ vOjeQhOnqrPdQINzUCKMSuqxqFGbQAJCAZs3Be1Pz6eeKHNLzr7mYQ2/pWSjfun4
 }
 
 func TestEnrollWithLocation(t *testing.T) {
-	tpp, err := getTestConnector(ctx.TPPurl, ctx.TPPZone)
+	ctx := context.Background()
+	tpp, err := getTestConnector(env.TPPurl, env.TPPZone)
 	if err != nil {
 		t.Fatalf("err is not nil, err: %s url: %s", err, expectedURL)
 	}
@@ -2185,14 +2493,14 @@ func TestEnrollWithLocation(t *testing.T) {
 	tpp.verbose = true
 
 	if tpp.apiKey == "" {
-		err = tpp.Authenticate(&endpoint.Authentication{AccessToken: ctx.TPPaccessToken})
+		err = tpp.Authenticate(ctx, &endpoint.Authentication{AccessToken: env.TPPaccessToken})
 		if err != nil {
 			t.Fatalf("err is not nil, err: %s", err)
 		}
 	}
 
 	cn := test.RandCN()
-	zoneConfig, err := tpp.ReadZoneConfiguration()
+	zoneConfig, err := tpp.ReadZoneConfiguration(ctx)
 	if err != nil {
 		t.Fatal(err)
 	}
@@ -2208,11 +2516,11 @@ func TestEnrollWithLocation(t *testing.T) {
 		TLSAddress: "example.com:443",
 	}
 
-	err = tpp.GenerateRequest(zoneConfig, &req)
+	err = tpp.GenerateRequest(ctx, zoneConfig, &req)
 	if err != nil {
 		t.Fatal(err)
 	}
-	_, err = tpp.RequestCertificate(&req)
+	_, err = tpp.RequestCertificate(ctx, &req)
 	if err != nil {
 		t.Fatal(err)
 	}
@@ -2225,11 +2533,11 @@ func TestEnrollWithLocation(t *testing.T) {
 		TLSAddress: "example.com:443",
 	}
 
-	err = tpp.GenerateRequest(zoneConfig, &req)
+	err = tpp.GenerateRequest(ctx, zoneConfig, &req)
 	if err != nil {
 		t.Fatal(err)
 	}
-	_, err = tpp.RequestCertificate(&req)
+	_, err = tpp.RequestCertificate(ctx, &req)
 	if err == nil {
 		t.Fatal("Should fail with devices conflict")
 	}
@@ -2243,12 +2551,12 @@ func TestEnrollWithLocation(t *testing.T) {
 		Replace:    true,
 	}
 
-	err = tpp.GenerateRequest(zoneConfig, &req)
+	err = tpp.GenerateRequest(ctx, zoneConfig, &req)
 	if err != nil {
 		t.Fatal(err)
 	}
 
-	_, err = tpp.RequestCertificate(&req)
+	_, err = tpp.RequestCertificate(ctx, &req)
 	if err != nil {
 		t.Fatal(err)
 	}
@@ -2261,12 +2569,12 @@ func TestEnrollWithLocation(t *testing.T) {
 		Replace:    false,
 	}
 
-	err = tpp.GenerateRequest(zoneConfig, &req)
+	err = tpp.GenerateRequest(ctx, zoneConfig, &req)
 	if err != nil {
 		t.Fatal(err)
 	}
 
-	_, err = tpp.RequestCertificate(&req)
+	_, err = tpp.RequestCertificate(ctx, &req)
 	if err != nil {
 		t.Fatal(err)
 	}
@@ -2279,12 +2587,12 @@ func TestEnrollWithLocation(t *testing.T) {
 		Replace:    false,
 	}
 
-	err = tpp.GenerateRequest(zoneConfig, &req)
+	err = tpp.GenerateRequest(ctx, zoneConfig, &req)
 	if err != nil {
 		t.Fatal(err)
 	}
 
-	_, err = tpp.RequestCertificate(&req)
+	_, err = tpp.RequestCertificate(ctx, &req)
 	if err == nil {
 		t.Fatal("There should be a error if we're trying to set same device twice in location")
 	}
@@ -2303,17 +2611,18 @@ func TestEnrollWithLocation(t *testing.T) {
 }
 
 func TestOmitSans(t *testing.T) {
-	tpp, err := getTestConnector(ctx.TPPurl, ctx.TPPZone)
+	ctx := context.Background()
+	tpp, err := getTestConnector(env.TPPurl, env.TPPZone)
 	if err != nil {
 		t.Fatalf("err is not nil, err: %s url: %s", err, expectedURL)
 	}
 	if tpp.apiKey == "" {
-		err = tpp.Authenticate(&endpoint.Authentication{AccessToken: ctx.TPPaccessToken})
+		err = tpp.Authenticate(ctx, &endpoint.Authentication{AccessToken: env.TPPaccessToken})
 		if err != nil {
 			t.Fatalf("err is not nil, err: %s", err)
 		}
 	}
-	zone, err := tpp.ReadZoneConfiguration()
+	zone, err := tpp.ReadZoneConfiguration(ctx)
 	if err != nil {
 		t.Fatal(err)
 	}
@@ -2348,7 +2657,7 @@ func TestOmitSans(t *testing.T) {
 		CsrOrigin: certificate.LocalGeneratedCSR,
 		Timeout:   30 * time.Second,
 	}
-	err = tpp.GenerateRequest(zone, &req)
+	err = tpp.GenerateRequest(ctx, zone, &req)
 	if err != nil {
 		t.Fatal(err)
 	}
@@ -2360,21 +2669,22 @@ func TestOmitSans(t *testing.T) {
 	if len(csr.DNSNames) > 0 {
 		t.Fatal("certificate should have 0 SANs")
 	}
-	_, err = tpp.RequestCertificate(&req)
+	_, err = tpp.RequestCertificate(ctx, &req)
 	if err != nil {
 		t.Fatal(err)
 	}
-	_, err = tpp.RetrieveCertificate(&req)
+	_, err = tpp.RetrieveCertificate(ctx, &req)
 	if err != nil {
 		t.Fatal(err)
 	}
 }
 
 func TestSetPolicy(t *testing.T) {
+	ctx := context.Background()
 	policyName := os.Getenv("TPP_PM_ROOT") + "\\" + test.RandTppPolicyName()
-	ctx.CloudZone = policyName
+	env.CloudZone = policyName
 
-	tpp, err := getTestConnector(ctx.TPPurl, ctx.TPPZone)
+	tpp, err := getTestConnector(env.TPPurl, env.TPPZone)
 	if err != nil {
 		t.Fatalf("err is not nil, err: %s url: %s", err, expectedURL)
 	}
@@ -2382,7 +2692,7 @@ func TestSetPolicy(t *testing.T) {
 	tpp.verbose = true
 
 	if tpp.apiKey == "" {
-		err = tpp.Authenticate(&endpoint.Authentication{AccessToken: ctx.TPPaccessToken})
+		err = tpp.Authenticate(ctx, &endpoint.Authentication{AccessToken: env.TPPaccessToken})
 		if err != nil {
 			t.Fatalf("err is not nil, err: %s", err)
 		}
@@ -2392,13 +2702,13 @@ func TestSetPolicy(t *testing.T) {
 	users := []string{"osstestuser"}
 	ps.Users = users
 
-	_, err = tpp.SetPolicy(policyName, ps)
+	_, err = tpp.SetPolicy(ctx, policyName, ps)
 
 	if err != nil {
 		t.Fatalf("%s", err)
 	}
 
-	ps, err = tpp.GetPolicy(policyName)
+	ps, err = tpp.GetPolicy(ctx, policyName)
 
 	if err != nil {
 		t.Fatalf("%s", err)
@@ -2410,11 +2720,12 @@ func TestSetPolicy(t *testing.T) {
 }
 
 func TestGetPolicy(t *testing.T) {
+	ctx := context.Background()
 	t.Skip() //this is just for development purpose
 
 	policyName := os.Getenv("TPP_POLICY_MANAGEMENT_SAMPLE")
 
-	tpp, err := getTestConnector(ctx.TPPurl, ctx.TPPZone)
+	tpp, err := getTestConnector(env.TPPurl, env.TPPZone)
 	if err != nil {
 		t.Fatalf("err is not nil, err: %s url: %s", err, expectedURL)
 	}
@@ -2422,7 +2733,7 @@ func TestGetPolicy(t *testing.T) {
 	tpp.verbose = true
 
 	if tpp.apiKey == "" {
-		err = tpp.Authenticate(&endpoint.Authentication{AccessToken: ctx.TPPaccessToken})
+		err = tpp.Authenticate(ctx, &endpoint.Authentication{AccessToken: env.TPPaccessToken})
 		if err != nil {
 			t.Fatalf("err is not nil, err: %s", err)
 		}
@@ -2430,7 +2741,7 @@ func TestGetPolicy(t *testing.T) {
 
 	specifiedPS := test.GetTppPolicySpecification()
 
-	ps, err := tpp.GetPolicy(policyName)
+	ps, err := tpp.GetPolicy(ctx, policyName)
 
 	if err != nil {
 		t.Fatalf("%s", err)
@@ -2568,22 +2879,23 @@ func TestGetPolicy(t *testing.T) {
 }
 
 func TestSetEmptyPolicy(t *testing.T) {
+	ctx := context.Background()
 	policyName := os.Getenv("TPP_PM_ROOT") + "\\" + test.RandTppPolicyName()
-	ctx.CloudZone = policyName
+	env.CloudZone = policyName
 
-	tpp, err := getTestConnector(ctx.TPPurl, ctx.TPPZone)
+	tpp, err := getTestConnector(env.TPPurl, env.TPPZone)
 	specification := policy.PolicySpecification{}
 
 	tpp.verbose = true
 
 	if tpp.apiKey == "" {
-		err = tpp.Authenticate(&endpoint.Authentication{AccessToken: ctx.TPPaccessToken})
+		err = tpp.Authenticate(ctx, &endpoint.Authentication{AccessToken: env.TPPaccessToken})
 		if err != nil {
 			t.Fatalf("err is not nil, err: %s", err)
 		}
 	}
 
-	_, err = tpp.SetPolicy(policyName, &specification)
+	_, err = tpp.SetPolicy(ctx, policyName, &specification)
 
 	if err != nil {
 		t.Fatalf("%s", err)
@@ -2592,6 +2904,7 @@ func TestSetEmptyPolicy(t *testing.T) {
 }
 
 func TestSetDefaultPolicyValuesAndValidate(t *testing.T) {
+	ctx := context.Background()
 
 	specification := test.GetTppPolicySpecification()
 
@@ -2601,27 +2914,27 @@ func TestSetDefaultPolicyValuesAndValidate(t *testing.T) {
 	specification.Default.KeyPair.EllipticCurve = &ec
 	specification.Default.KeyPair.ServiceGenerated = &serGenerated
 	policyName := os.Getenv("TPP_PM_ROOT") + "\\" + test.RandTppPolicyName()
-	ctx.CloudZone = policyName
+	env.CloudZone = policyName
 
-	tpp, err := getTestConnector(ctx.TPPurl, ctx.TPPZone)
+	tpp, err := getTestConnector(env.TPPurl, env.TPPZone)
 
 	tpp.verbose = true
 
 	if tpp.apiKey == "" {
-		err = tpp.Authenticate(&endpoint.Authentication{AccessToken: ctx.TPPaccessToken})
+		err = tpp.Authenticate(ctx, &endpoint.Authentication{AccessToken: env.TPPaccessToken})
 		if err != nil {
 			t.Fatalf("err is not nil, err: %s", err)
 		}
 	}
 
-	_, err = tpp.SetPolicy(policyName, specification)
+	_, err = tpp.SetPolicy(ctx, policyName, specification)
 
 	if err != nil {
 		t.Fatalf("%s", err)
 	}
 
 	//get the created policy
-	ps, err := tpp.GetPolicy(policyName)
+	ps, err := tpp.GetPolicy(ctx, policyName)
 
 	if err != nil {
 		t.Fatalf("%s", err)
@@ -2683,32 +2996,33 @@ func TestSetDefaultPolicyValuesAndValidate(t *testing.T) {
 }
 
 func TestSetPolicyValuesAndValidate(t *testing.T) {
+	ctx := context.Background()
 	specification := test.GetTppPolicySpecification()
 
 	specification.Default = nil
 
 	policyName := os.Getenv("TPP_PM_ROOT") + "\\" + test.RandTppPolicyName()
-	ctx.CloudZone = policyName
+	env.CloudZone = policyName
 
-	tpp, err := getTestConnector(ctx.TPPurl, ctx.TPPZone)
+	tpp, err := getTestConnector(env.TPPurl, env.TPPZone)
 
 	tpp.verbose = true
 
 	if tpp.apiKey == "" {
-		err = tpp.Authenticate(&endpoint.Authentication{AccessToken: ctx.TPPaccessToken})
+		err = tpp.Authenticate(ctx, &endpoint.Authentication{AccessToken: env.TPPaccessToken})
 		if err != nil {
 			t.Fatalf("err is not nil, err: %s", err)
 		}
 	}
 
-	_, err = tpp.SetPolicy(policyName, specification)
+	_, err = tpp.SetPolicy(ctx, policyName, specification)
 
 	if err != nil {
 		t.Fatalf("%s", err)
 	}
 
 	//get the created policy
-	ps, err := tpp.GetPolicy(policyName)
+	ps, err := tpp.GetPolicy(ctx, policyName)
 
 	if err != nil {
 		t.Fatalf("%s", err)
@@ -2773,15 +3087,16 @@ func TestSetPolicyValuesAndValidate(t *testing.T) {
 }
 
 func TestCreateSshCertServiceGeneratedKP(t *testing.T) {
+	ctx := context.Background()
 
-	tpp, err := getTestConnector(ctx.TPPurl, ctx.TPPZone)
+	tpp, err := getTestConnector(env.TPPurl, env.TPPZone)
 
 	duration := 4
 
 	tpp.verbose = true
 
 	if tpp.apiKey == "" {
-		err = tpp.Authenticate(&endpoint.Authentication{AccessToken: ctx.TPPaccessToken})
+		err = tpp.Authenticate(ctx, &endpoint.Authentication{AccessToken: env.TPPaccessToken})
 		if err != nil {
 			t.Fatalf("err is not nil, err: %s", err)
 		}
@@ -2795,7 +3110,7 @@ func TestCreateSshCertServiceGeneratedKP(t *testing.T) {
 	req.SourceAddresses = []string{"test.com"}
 	req.Timeout = time.Second * 10
 
-	respData, err := tpp.RequestSSHCertificate(req)
+	respData, err := tpp.RequestSSHCertificate(ctx, req)
 
 	if err != nil {
 		t.Fatalf("err is not nil, err: %s", err)
@@ -2807,7 +3122,7 @@ func TestCreateSshCertServiceGeneratedKP(t *testing.T) {
 		Timeout:                   time.Duration(10) * time.Second,
 	}
 
-	resp, err := tpp.RetrieveSSHCertificate(retReq)
+	resp, err := tpp.RetrieveSSHCertificate(ctx, retReq)
 
 	if err != nil {
 		t.Fatalf("err is not nil, err: %s", err)
@@ -2838,15 +3153,16 @@ func TestCreateSshCertServiceGeneratedKP(t *testing.T) {
 }
 
 func TestCreateSshCertLocalGeneratedKP(t *testing.T) {
+	ctx := context.Background()
 
-	tpp, err := getTestConnector(ctx.TPPurl, ctx.TPPZone)
+	tpp, err := getTestConnector(env.TPPurl, env.TPPZone)
 
 	duration := 4
 
 	tpp.verbose = true
 
 	if tpp.apiKey == "" {
-		err = tpp.Authenticate(&endpoint.Authentication{AccessToken: ctx.TPPaccessToken})
+		err = tpp.Authenticate(ctx, &endpoint.Authentication{AccessToken: env.TPPaccessToken})
 		if err != nil {
 			t.Fatalf("err is not nil, err: %s", err)
 		}
@@ -2878,7 +3194,7 @@ func TestCreateSshCertLocalGeneratedKP(t *testing.T) {
 
 	req.PublicKeyData = sPubKey
 
-	respData, err := tpp.RequestSSHCertificate(req)
+	respData, err := tpp.RequestSSHCertificate(ctx, req)
 
 	if err != nil {
 		t.Fatalf("err is not nil, err: %s", err)
@@ -2890,7 +3206,7 @@ func TestCreateSshCertLocalGeneratedKP(t *testing.T) {
 		Timeout:                   time.Duration(10) * time.Second,
 	}
 
-	resp, err := tpp.RetrieveSSHCertificate(retReq)
+	resp, err := tpp.RetrieveSSHCertificate(ctx, retReq)
 
 	if err != nil {
 		t.Fatalf("err is not nil, err: %s", err)
@@ -2925,6 +3241,7 @@ func TestCreateSshCertLocalGeneratedKP(t *testing.T) {
 }
 
 func TestCreateSshCertProvidedPubKey(t *testing.T) {
+	ctx := context.Background()
 	t.Skip("skipping this test since a fresh generated ssh public key is required")
 
 	var fileContent []byte
@@ -2946,14 +3263,14 @@ func TestCreateSshCertProvidedPubKey(t *testing.T) {
 		t.Fatal("public key is empty")
 	}
 
-	tpp, err := getTestConnector(ctx.TPPurl, ctx.TPPZone)
+	tpp, err := getTestConnector(env.TPPurl, env.TPPZone)
 
 	duration := 4
 
 	tpp.verbose = true
 
 	if tpp.apiKey == "" {
-		err = tpp.Authenticate(&endpoint.Authentication{AccessToken: ctx.TPPaccessToken})
+		err = tpp.Authenticate(ctx, &endpoint.Authentication{AccessToken: env.TPPaccessToken})
 		if err != nil {
 			t.Fatalf("err is not nil, err: %s", err)
 		}
@@ -2967,7 +3284,7 @@ func TestCreateSshCertProvidedPubKey(t *testing.T) {
 	req.PublicKeyData = content
 	req.SourceAddresses = []string{"test.com"}
 
-	respData, err := tpp.RequestSSHCertificate(req)
+	respData, err := tpp.RequestSSHCertificate(ctx, req)
 
 	if err != nil {
 		t.Fatalf("err is not nil, err: %s", err)
@@ -2979,7 +3296,7 @@ func TestCreateSshCertProvidedPubKey(t *testing.T) {
 		Timeout:                   time.Duration(10) * time.Second,
 	}
 
-	resp, err := tpp.RetrieveSSHCertificate(retReq)
+	resp, err := tpp.RetrieveSSHCertificate(ctx, retReq)
 
 	if err != nil {
 		t.Fatalf("err is not nil, err: %s", err)
@@ -3001,9 +3318,82 @@ func TestCreateSshCertProvidedPubKey(t *testing.T) {
 	}
 }
 
+func TestRenewAndRevokeSshCert(t *testing.T) {
+	ctx := context.Background()
+
+	tpp, err := getTestConnector(env.TPPurl, env.TPPZone)
+
+	tpp.verbose = true
+
+	if tpp.apiKey == "" {
+		err = tpp.Authenticate(ctx, &endpoint.Authentication{AccessToken: env.TPPaccessToken})
+		if err != nil {
+			t.Fatalf("err is not nil, err: %s", err)
+		}
+	}
+
+	var req = &certificate.SshCertRequest{}
+
+	req.KeyId = test.RandSshKeyId()
+	req.ValidityPeriod = "4h"
+	req.Template = os.Getenv("TPP_SSH_CA")
+	req.Timeout = time.Second * 10
+
+	respData, err := tpp.RequestSSHCertificate(ctx, req)
+
+	if err != nil {
+		t.Fatalf("err is not nil, err: %s", err)
+	}
+
+	_, pub, err := util.GenerateSshKeyPair(3072, "", req.KeyId)
+
+	if err != nil {
+		t.Fatalf("err is not nil, err: %s", err)
+	}
+
+	renewReq := &certificate.SshCertRequest{
+		PickupID:      respData.DN,
+		PublicKeyData: string(pub),
+		Timeout:       time.Second * 10,
+	}
+
+	renewData, err := tpp.RenewSSHCertificate(ctx, renewReq)
+
+	if err != nil {
+		t.Fatalf("err is not nil, err: %s", err)
+	}
+
+	retReq := &certificate.SshCertRequest{
+		PickupID:                  renewData.DN,
+		IncludeCertificateDetails: true,
+		Timeout:                   time.Duration(10) * time.Second,
+	}
+
+	resp, err := tpp.RetrieveSSHCertificate(ctx, retReq)
+
+	if err != nil {
+		t.Fatalf("err is not nil, err: %s", err)
+	}
+
+	if resp.CertificateData == "" {
+		t.Error("Certificate key data is empty")
+	}
+
+	if resp.CertificateDetails.KeyID != req.KeyId {
+		t.Errorf("renewed certificate key id is different, expected: %s but got %s", req.KeyId, resp.CertificateDetails.KeyID)
+	}
+
+	err = tpp.RevokeSSHCertificate(ctx, &certificate.SshCertRequest{PickupID: renewData.DN})
+
+	if err != nil {
+		t.Fatalf("err is not nil, err: %s", err)
+	}
+}
+
 func TestSshGetConfig(t *testing.T) {
+	ctx := context.Background()
 
-	tpp, err := getTestConnector(ctx.TPPurl, ctx.TPPZone)
+	tpp, err := getTestConnector(env.TPPurl, env.TPPZone)
 
 	if err != nil {
 		t.Fatalf("err is not nil, err: %s", err)
@@ -3012,7 +3402,7 @@ func TestSshGetConfig(t *testing.T) {
 	tpp.verbose = true
 
 	if tpp.apiKey == "" {
-		err = tpp.Authenticate(&endpoint.Authentication{AccessToken: ctx.TPPaccessToken})
+		err = tpp.Authenticate(ctx, &endpoint.Authentication{AccessToken: env.TPPaccessToken})
 		if err != nil {
 			t.Fatalf("err is not nil, err: %s", err)
 		}
@@ -3021,7 +3411,7 @@ func TestSshGetConfig(t *testing.T) {
 	var req = &certificate.SshCaTemplateRequest{}
 	req.Template = os.Getenv("TPP_SSH_CA")
 
-	data, err := tpp.RetrieveSshConfig(req)
+	data, err := tpp.RetrieveSshConfig(ctx, req)
 
 	if err != nil {
 		t.Fatalf("err is not nil, err: %s", err)
@@ -3038,18 +3428,19 @@ func TestSshGetConfig(t *testing.T) {
 }
 
 func TestGetCertificateMetaData(t *testing.T) {
-	tpp, err := getTestConnector(ctx.TPPurl, ctx.TPPZone)
+	ctx := context.Background()
+	tpp, err := getTestConnector(env.TPPurl, env.TPPZone)
 	if err != nil {
 		t.Fatalf("err is not nil, err: %s url: %s", err, expectedURL)
 	}
 
 	if tpp.apiKey == "" {
-		err = tpp.Authenticate(&endpoint.Authentication{AccessToken: ctx.TPPaccessToken})
+		err = tpp.Authenticate(ctx, &endpoint.Authentication{AccessToken: env.TPPaccessToken})
 		if err != nil {
 			t.Fatalf("err is not nil, err: %s", err)
 		}
 	}
-	config, err := tpp.ReadZoneConfiguration()
+	config, err := tpp.ReadZoneConfiguration(ctx)
 	if err != nil {
 		t.Fatalf("err is not nil, err: %s", err)
 	}
@@ -3063,18 +3454,18 @@ func TestGetCertificateMetaData(t *testing.T) {
 	req.Subject.Province = []string{"Nevada"}
 	req.Subject.Country = []string{"US"}
 
-	err = tpp.GenerateRequest(config, req)
+	err = tpp.GenerateRequest(ctx, config, req)
 	if err != nil {
 		t.Fatalf("err is not nil, err: %s", err)
 	}
 
-	t.Logf("getPolicyDN(ctx.TPPZone) = %s", getPolicyDN(ctx.TPPZone))
-	dn, err := tpp.RequestCertificate(req)
+	t.Logf("getPolicyDN(ctx.TPPZone) = %s", getPolicyDN(env.TPPZone))
+	dn, err := tpp.RequestCertificate(ctx, req)
 	if err != nil {
 		t.Fatalf("err is not nil, err: %s", err)
 	}
 
-	metaData, err := tpp.RetrieveCertificateMetaData(dn)
+	metaData, err := tpp.RetrieveCertificateMetaData(ctx, dn)
 
 	if err != nil {
 		t.Fatalf("err is not nil, err: %s", err)
@@ -3087,13 +3478,14 @@ func TestGetCertificateMetaData(t *testing.T) {
 
 // TODO: Expand unit tests to cover more cases
 func TestSearchValidCertificate(t *testing.T) {
-	tpp, err := getTestConnector(ctx.TPPurl, ctx.TPPZone)
+	ctx := context.Background()
+	tpp, err := getTestConnector(env.TPPurl, env.TPPZone)
 	if err != nil {
 		t.Fatalf("err is not nil, err: %s url: %s", err, expectedURL)
 	}
 
 	if tpp.apiKey == "" {
-		err = tpp.Authenticate(&endpoint.Authentication{AccessToken: ctx.TPPaccessToken})
+		err = tpp.Authenticate(ctx, &endpoint.Authentication{AccessToken: env.TPPaccessToken})
 		if err != nil {
 			t.Fatalf("err is not nil, err: %s", err)
 		}
@@ -3110,7 +3502,7 @@ func TestSearchValidCertificate(t *testing.T) {
 	day := 24 * time.Hour
 	certMinTimeLeft := 3 * day
 
-	certificate, err := tpp.SearchCertificate(zone, cn, sans, certMinTimeLeft)
+	certificate, err := tpp.SearchCertificate(ctx, zone, cn, sans, certMinTimeLeft)
 	if err != nil {
 		t.Fatalf("%v", err)
 	}
@@ -3123,13 +3515,14 @@ func TestSearchValidCertificate(t *testing.T) {
 }
 
 func TestWriteLog(t *testing.T) {
-	tpp, err := getTestConnector(ctx.TPPurl, ctx.TPPZone)
+	ctx := context.Background()
+	tpp, err := getTestConnector(env.TPPurl, env.TPPZone)
 	if err != nil {
 		t.Fatalf("err is not nil, err: %s url: %s", err, expectedURL)
 	}
 
 	if tpp.apiKey == "" {
-		err = tpp.Authenticate(&endpoint.Authentication{AccessToken: ctx.TPPaccessToken})
+		err = tpp.Authenticate(ctx, &endpoint.Authentication{AccessToken: env.TPPaccessToken})
 		if err != nil {
 			t.Fatalf("err is not nil, err: %s", err)
 		}
@@ -3143,10 +3536,10 @@ func TestWriteLog(t *testing.T) {
 	logReq := endpoint.LogRequest{
 		LogID:     "26808595",
 		Text1:     hostname,
-		Component: getPolicyDN(ctx.TPPZone),
+		Component: getPolicyDN(env.TPPZone),
 	}
 
-	err = tpp.WriteLog(&logReq)
+	err = tpp.WriteLog(ctx, &logReq)
 	if err != nil {
 		t.Fatalf("err is not nil, err: %s", err)
 	}
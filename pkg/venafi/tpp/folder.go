@@ -0,0 +1,201 @@
+/*
+ * Copyright 2023 Venafi, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *  http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package tpp
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"strings"
+
+	"github.com/Venafi/vcert/v5/pkg/policy"
+	"github.com/Venafi/vcert/v5/pkg/util"
+	"github.com/Venafi/vcert/v5/pkg/verror"
+)
+
+// configResultSuccess is the Result of the Config requests that succeed
+const configResultSuccess = 1
+
+type configCreateResponse struct {
+	Object policy.PolicyObject `json:"Object"`
+	Result int                 `json:"Result"`
+	Error  string              `json:"Error,omitempty"`
+}
+
+type configRenameObjectRequest struct {
+	ObjectDN    string `json:"ObjectDN"`
+	NewObjectDN string `json:"NewObjectDN"`
+}
+
+type configRenameObjectResponse struct {
+	Result int    `json:"Result"`
+	Error  string `json:"Error,omitempty"`
+}
+
+// CreatePolicyFolder creates the policy folder with the given DN, along with the parent folders that do not exist
+// yet, and returns the full DN of the folder. Folders that already exist are left untouched
+func (c *Connector) CreatePolicyFolder(ctx context.Context, name string) (string, error) {
+	dn := getPolicyDN(stripBackSlashes(strings.TrimSuffix(name, util.PathSeparator)))
+	if dn == policy.RootPath {
+		return "", fmt.Errorf("%w: a policy folder under %s is required", verror.UserDataError, policy.RootPath)
+	}
+
+	// Folders are created from the first one missing, down to the one requested
+	missing := make([]string, 0)
+	for folder := dn; folder != policy.RootPath; folder = policy.GetParent(folder) {
+		obj, err := c.readObject(ctx, folder)
+		if err != nil {
+			return "", err
+		}
+		if obj != nil {
+			if obj.TypeName != policy.PolicyClass {
+				return "", fmt.Errorf("%w: %s is a %s, not a policy folder", verror.UserDataError, folder, obj.TypeName)
+			}
+			break
+		}
+		missing = append(missing, folder)
+	}
+	if len(missing) == 0 {
+		log.Printf("found existing policy folder: %s", dn)
+		return dn, nil
+	}
+
+	for i := len(missing) - 1; i >= 0; i-- {
+		log.Printf("creating policy folder: %s", missing[i])
+		req := policy.PolicyPayloadRequest{
+			Class:    policy.PolicyClass,
+			ObjectDN: missing[i],
+		}
+		_, status, body, err := c.request(ctx, "POST", urlResourceCreatePolicy, req)
+		if err != nil {
+			return "", err
+		}
+		var resp configCreateResponse
+		err = json.Unmarshal(body, &resp)
+		if err != nil {
+			return "", fmt.Errorf("%w: failed to parse response of %s. Status: %s", verror.ServerError, urlResourceCreatePolicy, status)
+		}
+		if resp.Result != configResultSuccess {
+			return "", fmt.Errorf("%w: failed to create policy folder %s: %s", verror.ServerError, missing[i], resp.Error)
+		}
+	}
+	if c.zoneCache != nil {
+		c.zoneCache.Invalidate(c.baseURL, dn)
+	}
+	return dn, nil
+}
+
+// SetPolicyFolderAttribute sets the values of a certificate attribute, i.e. Organization or Management Type, in the
+// policy folder. Locked values are enforced on the certificates of the folder, while the others are defaults
+// the certificate requests can override
+func (c *Connector) SetPolicyFolderAttribute(ctx context.Context, folder string, attribute string, values []string, locked bool) error {
+	dn := getPolicyDN(stripBackSlashes(folder))
+	exists, err := PolicyExist(ctx, dn, c)
+	if err != nil {
+		return err
+	}
+	if !exists {
+		return fmt.Errorf("%w: policy folder %s does not exist", verror.ZoneNotFoundError, dn)
+	}
+
+	_, _, _, err = createPolicyAttribute(ctx, c, attribute, values, dn, locked)
+	if err != nil {
+		return fmt.Errorf("failed to set attribute %q of policy folder %s: %w", attribute, dn, err)
+	}
+	if c.zoneCache != nil {
+		c.zoneCache.Invalidate(c.baseURL, dn)
+	}
+	return nil
+}
+
+// MoveCertificate moves or renames the certificate object with the given DN, and returns its new DN. When newDN is an
+// existing policy folder the certificate is moved into it, keeping its name. The history and associations of the
+// certificate are kept, as it remains the same object
+func (c *Connector) MoveCertificate(ctx context.Context, certificateDN string, newDN string) (string, error) {
+	dn := getPolicyDN(stripBackSlashes(certificateDN))
+	obj, err := c.readObject(ctx, dn)
+	if err != nil {
+		return "", err
+	}
+	if obj == nil {
+		return "", fmt.Errorf("%w: certificate %s does not exist", verror.NoCertificateFoundError, dn)
+	}
+	if obj.TypeName == policy.PolicyClass {
+		return "", fmt.Errorf("%w: %s is a policy folder, not a certificate", verror.UserDataError, dn)
+	}
+
+	target := getPolicyDN(stripBackSlashes(strings.TrimSuffix(newDN, util.PathSeparator)))
+	targetObj, err := c.readObject(ctx, target)
+	if err != nil {
+		return "", err
+	}
+	if targetObj != nil {
+		if targetObj.TypeName != policy.PolicyClass {
+			return "", fmt.Errorf("%w: an object already exists at %s", verror.UserDataError, target)
+		}
+		target = target + util.PathSeparator + obj.Name
+	} else if parent := policy.GetParent(target); parent != policy.RootPath {
+		exists, err := PolicyExist(ctx, parent, c)
+		if err != nil {
+			return "", err
+		}
+		if !exists {
+			return "", fmt.Errorf("%w: policy folder %s does not exist", verror.ZoneNotFoundError, parent)
+		}
+	}
+
+	req := configRenameObjectRequest{ObjectDN: dn, NewObjectDN: target}
+	_, status, body, err := c.request(ctx, "POST", urlResourceConfigRenameObject, req)
+	if err != nil {
+		return "", err
+	}
+	var resp configRenameObjectResponse
+	err = json.Unmarshal(body, &resp)
+	if err != nil {
+		return "", fmt.Errorf("%w: failed to parse response of %s. Status: %s", verror.ServerError, urlResourceConfigRenameObject, status)
+	}
+	if resp.Result != configResultSuccess {
+		return "", fmt.Errorf("%w: failed to move certificate %s to %s: %s", verror.ServerError, dn, target, resp.Error)
+	}
+	return target, nil
+}
+
+// readObject returns the object with the given DN, or nil when it does not exist
+func (c *Connector) readObject(ctx context.Context, dn string) (*policy.PolicyObject, error) {
+	req := policy.PolicyExistPayloadRequest{
+		ObjectDN: dn,
+	}
+	_, _, body, err := c.request(ctx, "POST", urlResourceIsValidPolicy, req)
+	if err != nil {
+		return nil, err
+	}
+	var response policy.PolicyIsValidResponse
+	err = json.Unmarshal(body, &response)
+	if err != nil {
+		return nil, err
+	}
+
+	switch {
+	case response.Result == configResultSuccess && response.PolicyObject.DN != "":
+		return &response.PolicyObject, nil
+	case response.Error != "" && response.Result == 400:
+		return nil, nil
+	default:
+		return nil, fmt.Errorf("%w: failed to read object %s: %s", verror.ServerError, dn, response.Error)
+	}
+}
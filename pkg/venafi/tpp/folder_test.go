@@ -0,0 +1,168 @@
+/*
+ * Copyright 2023 Venafi, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *  http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package tpp
+
+import (
+	"context"
+	"crypto/x509"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/Venafi/vcert/v5/pkg/policy"
+	"github.com/Venafi/vcert/v5/pkg/verror"
+)
+
+// mockConfigServer serves the Config requests of the WebSDK from objects, a map of object DNs to their class
+func mockConfigServer(t *testing.T, objects map[string]string) (*Connector, *[]string) {
+	var calls []string
+	server := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		req := map[string]interface{}{}
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			t.Errorf("invalid request body: %s", err)
+		}
+		dn, _ := req["ObjectDN"].(string)
+		resource := strings.ToLower(strings.TrimPrefix(r.URL.Path, "/vedsdk/"))
+		calls = append(calls, resource+" "+dn)
+
+		var resp interface{}
+		switch resource {
+		case "config/isvalid":
+			class, found := objects[dn]
+			if !found {
+				resp = map[string]interface{}{"Error": "Object does not exist", "Result": 400}
+				break
+			}
+			resp = map[string]interface{}{"Result": 1, "Object": map[string]string{
+				"DN": dn, "Name": dn[strings.LastIndex(dn, "\\")+1:], "TypeName": class}}
+		case "config/create":
+			objects[dn] = req["Class"].(string)
+			resp = map[string]interface{}{"Result": 1, "Object": map[string]string{"DN": dn}}
+		case "config/renameobject":
+			newDN := req["NewObjectDN"].(string)
+			objects[newDN] = objects[dn]
+			delete(objects, dn)
+			resp = map[string]interface{}{"Result": 1}
+		case "config/writepolicy":
+			resp = map[string]interface{}{"Result": 1}
+		default:
+			t.Errorf("unexpected request %s", r.URL.Path)
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		_ = json.NewEncoder(w).Encode(resp)
+	}))
+	t.Cleanup(server.Close)
+
+	ca := x509.NewCertPool()
+	ca.AddCert(server.Certificate())
+	tpp, err := NewConnector(server.URL, "", false, ca)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tpp.accessToken = "token"
+	return tpp, &calls
+}
+
+func TestCreatePolicyFolder(t *testing.T) {
+	ctx := context.Background()
+	objects := map[string]string{`\VED\Policy\Certificates`: policy.PolicyClass}
+	tpp, calls := mockConfigServer(t, objects)
+
+	dn, err := tpp.CreatePolicyFolder(ctx, `Certificates\Team\Web\`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if dn != `\VED\Policy\Certificates\Team\Web` {
+		t.Fatalf("unexpected folder DN %s", dn)
+	}
+	if objects[`\VED\Policy\Certificates\Team`] != policy.PolicyClass || objects[dn] != policy.PolicyClass {
+		t.Fatalf("expected the folder and its missing parent to be created, got %v", objects)
+	}
+
+	*calls = nil
+	_, err = tpp.CreatePolicyFolder(ctx, dn)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(*calls) != 1 {
+		t.Fatalf("expected an existing folder to be left untouched, got requests %v", *calls)
+	}
+
+	objects[`\VED\Policy\Certificates\www.example.com`] = "X509 Server Certificate"
+	_, err = tpp.CreatePolicyFolder(ctx, `\VED\Policy\Certificates\www.example.com\Sub`)
+	if !errors.Is(err, verror.UserDataError) {
+		t.Fatalf("expected an error creating a folder under a certificate, got %v", err)
+	}
+}
+
+func TestSetPolicyFolderAttribute(t *testing.T) {
+	ctx := context.Background()
+	tpp, calls := mockConfigServer(t, map[string]string{`\VED\Policy\Certificates`: policy.PolicyClass})
+
+	err := tpp.SetPolicyFolderAttribute(ctx, "Certificates", policy.TppOrganization, []string{"Venafi"}, true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if (*calls)[len(*calls)-1] != `config/writepolicy \VED\Policy\Certificates` {
+		t.Fatalf("unexpected requests %v", *calls)
+	}
+
+	err = tpp.SetPolicyFolderAttribute(ctx, "Missing", policy.TppOrganization, []string{"Venafi"}, true)
+	if !errors.Is(err, verror.ZoneNotFoundError) {
+		t.Fatalf("expected an error setting an attribute of a missing folder, got %v", err)
+	}
+}
+
+func TestMoveCertificate(t *testing.T) {
+	ctx := context.Background()
+	objects := map[string]string{
+		`\VED\Policy\Old`:                 policy.PolicyClass,
+		`\VED\Policy\New`:                 policy.PolicyClass,
+		`\VED\Policy\Old\www.example.com`: "X509 Server Certificate",
+	}
+	tpp, _ := mockConfigServer(t, objects)
+
+	dn, err := tpp.MoveCertificate(ctx, `Old\www.example.com`, `\VED\Policy\New`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if dn != `\VED\Policy\New\www.example.com` || objects[dn] == "" {
+		t.Fatalf("expected the certificate to be moved into the folder keeping its name, got %s", dn)
+	}
+
+	dn, err = tpp.MoveCertificate(ctx, dn, `New\web.example.com`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if dn != `\VED\Policy\New\web.example.com` || objects[dn] == "" {
+		t.Fatalf("expected the certificate to be renamed, got %s", dn)
+	}
+
+	_, err = tpp.MoveCertificate(ctx, dn, `Missing\web.example.com`)
+	if !errors.Is(err, verror.ZoneNotFoundError) {
+		t.Fatalf("expected an error moving to a missing folder, got %v", err)
+	}
+	_, err = tpp.MoveCertificate(ctx, `New\missing.example.com`, "Old")
+	if !errors.Is(err, verror.NoCertificateFoundError) {
+		t.Fatalf("expected an error moving a missing certificate, got %v", err)
+	}
+}
@@ -17,6 +17,7 @@
 package tpp
 
 import (
+	"context"
 	"crypto/sha1"
 	"encoding/json"
 	"encoding/pem"
@@ -50,7 +51,7 @@ type CertificateDetailsResponse struct {
 	Disabled  bool `json:",omitempty"`
 }
 
-func (c *Connector) searchCertificatesByFingerprint(fp string) (*certificate.CertSearchResponse, error) {
+func (c *Connector) searchCertificatesByFingerprint(ctx context.Context, fp string) (*certificate.CertSearchResponse, error) {
 	fp = strings.Replace(fp, ":", "", -1)
 	fp = strings.Replace(fp, ".", "", -1)
 	fp = strings.ToUpper(fp)
@@ -58,12 +59,22 @@ func (c *Connector) searchCertificatesByFingerprint(fp string) (*certificate.Cer
 	var req certificate.SearchRequest
 	req = append(req, fmt.Sprintf("Thumbprint=%s", fp))
 
-	return c.SearchCertificates(&req)
+	return c.SearchCertificates(ctx, &req)
 }
 
-func (c *Connector) configReadDN(req ConfigReadDNRequest) (resp ConfigReadDNResponse, err error) {
+func (c *Connector) searchCertificatesBySerialNumber(ctx context.Context, serial string) (*certificate.CertSearchResponse, error) {
+	serial = strings.Replace(serial, ":", "", -1)
+	serial = strings.ToUpper(serial)
 
-	statusCode, status, body, err := c.request("POST", urlResourceConfigReadDn, req)
+	var req certificate.SearchRequest
+	req = append(req, fmt.Sprintf("Serial=%s", serial))
+
+	return c.SearchCertificates(ctx, &req)
+}
+
+func (c *Connector) configReadDN(ctx context.Context, req ConfigReadDNRequest) (resp ConfigReadDNResponse, err error) {
+
+	statusCode, status, body, err := c.request(ctx, "POST", urlResourceConfigReadDn, req)
 	if err != nil {
 		return resp, err
 	}
@@ -80,11 +91,11 @@ func (c *Connector) configReadDN(req ConfigReadDNRequest) (resp ConfigReadDNResp
 	return resp, nil
 }
 
-func (c *Connector) searchCertificateDetails(guid string) (*CertificateDetailsResponse, error) {
+func (c *Connector) searchCertificateDetails(ctx context.Context, guid string) (*CertificateDetailsResponse, error) {
 	var err error
 
 	url := fmt.Sprintf("%s%s", urlResourceCertificateSearch, guid)
-	statusCode, _, body, err := c.request("GET", urlResource(url), nil)
+	statusCode, _, body, err := c.request(ctx, "GET", urlResource(url), nil)
 	if err != nil {
 		return nil, err
 	}
@@ -179,6 +190,96 @@ func formatSearchCertificateArguments(cn string, sans *certificate.Sans, certMin
 	return strings.Join(req, "&")
 }
 
+// CertificateFilter selects the certificates returned by FindCertificates. Empty fields are ignored
+type CertificateFilter struct {
+	// CN matches the common name of the certificate
+	CN string
+	// SAN matches a DNS subject alternative name of the certificate
+	SAN string
+	// Thumbprint matches the SHA-1 thumbprint of the certificate. Separators are ignored
+	Thumbprint string
+	// ParentDN restricts the search to the given policy folder. The \VED\Policy prefix is optional
+	ParentDN string
+	// Recursive includes the certificates in the sub-folders of ParentDN
+	Recursive bool
+	// ExpiresAfter matches the certificates that expire after the given time
+	ExpiresAfter time.Time
+	// ExpiresBefore matches the certificates that expire before the given time
+	ExpiresBefore time.Time
+	// Limit is the maximum number of certificates returned. Defaults to DefaultSearchLimit
+	Limit int
+	// Offset is the number of matching certificates to skip, used for paging
+	Offset int
+}
+
+// DefaultSearchLimit is the page size used by FindCertificates when the filter does not specify a limit
+const DefaultSearchLimit = 100
+
+// query returns the WebSDK certificate search arguments for the filter
+func (f CertificateFilter) query() string {
+	values := neturl.Values{}
+	if f.CN != "" {
+		values.Set("CN", f.CN)
+	}
+	if f.SAN != "" {
+		values.Set("SAN-DNS", f.SAN)
+	}
+	if f.Thumbprint != "" {
+		fp := strings.NewReplacer(":", "", ".", "", " ", "").Replace(f.Thumbprint)
+		values.Set("Thumbprint", strings.ToUpper(fp))
+	}
+	if f.ParentDN != "" {
+		if f.Recursive {
+			values.Set("ParentDnRecursive", getPolicyDN(f.ParentDN))
+		} else {
+			values.Set("ParentDn", getPolicyDN(f.ParentDN))
+		}
+	}
+	if !f.ExpiresAfter.IsZero() {
+		values.Set("ValidToGreater", f.ExpiresAfter.Format(time.RFC3339))
+	}
+	if !f.ExpiresBefore.IsZero() {
+		values.Set("ValidToLess", f.ExpiresBefore.Format(time.RFC3339))
+	}
+	limit := f.Limit
+	if limit <= 0 {
+		limit = DefaultSearchLimit
+	}
+	values.Set("Limit", fmt.Sprintf("%d", limit))
+	if f.Offset > 0 {
+		values.Set("Offset", fmt.Sprintf("%d", f.Offset))
+	}
+	return values.Encode()
+}
+
+// FindCertificates returns one page of the certificates matching the filter. The Count of the response is the
+// total number of matching certificates, so that callers can page through the results using Offset
+func (c *Connector) FindCertificates(ctx context.Context, filter CertificateFilter) (*CertificateSearchResponse, error) {
+	url := fmt.Sprintf("%s?%s", urlResourceCertificateSearch, filter.query())
+	statusCode, _, body, err := c.request(ctx, "GET", urlResource(url), nil)
+	if err != nil {
+		return nil, err
+	}
+	return parseSearchCertificateResponse(statusCode, body)
+}
+
+// FindAllCertificates pages through FindCertificates starting at the Offset of the filter and returns every matching
+// certificate. The Limit of the filter is used as the page size
+func (c *Connector) FindAllCertificates(ctx context.Context, filter CertificateFilter) ([]CertificateSearchInfo, error) {
+	var found []CertificateSearchInfo
+	for {
+		page, err := c.FindCertificates(ctx, filter)
+		if err != nil {
+			return nil, err
+		}
+		found = append(found, page.Certificates...)
+		if len(page.Certificates) == 0 || filter.Offset+len(page.Certificates) >= page.Count {
+			return found, nil
+		}
+		filter.Offset += len(page.Certificates)
+	}
+}
+
 func calcThumbprint(cert string) string {
 	p, _ := pem.Decode([]byte(cert))
 	h := sha1.New()
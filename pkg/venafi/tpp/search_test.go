@@ -17,9 +17,12 @@
 package tpp
 
 import (
+	"context"
 	"crypto/x509"
 	"encoding/pem"
 	"fmt"
+	"net/http"
+	"net/http/httptest"
 	"net/url"
 	"regexp"
 	"strings"
@@ -161,19 +164,20 @@ func TestParseCertificateDetailsResponse(t *testing.T) {
 }
 
 func TestRequestAndSearchCertificate(t *testing.T) {
-	tpp, err := getTestConnector(ctx.TPPurl, ctx.TPPZone)
+	ctx := context.Background()
+	tpp, err := getTestConnector(env.TPPurl, env.TPPZone)
 	if err != nil {
 		t.Fatalf("err is not nil, err: %s url: %s", err, expectedURL)
 	}
 
 	if tpp.apiKey == "" {
-		err = tpp.Authenticate(&endpoint.Authentication{AccessToken: ctx.TPPaccessToken})
+		err = tpp.Authenticate(ctx, &endpoint.Authentication{AccessToken: env.TPPaccessToken})
 		if err != nil {
 			t.Fatalf("err is not nil, err: %s", err)
 		}
 	}
 
-	config, err := tpp.ReadZoneConfiguration()
+	config, err := tpp.ReadZoneConfiguration(ctx)
 	if err != nil {
 		t.Fatalf("err is not nil, err: %s", err)
 	}
@@ -205,16 +209,16 @@ func TestRequestAndSearchCertificate(t *testing.T) {
 
 	req.KeyLength = 1024
 
-	err = tpp.GenerateRequest(config, req)
+	err = tpp.GenerateRequest(ctx, config, req)
 	if err != nil {
 		t.Fatalf("err is not nil, err: %s", err)
 	}
 
-	req.PickupID, err = tpp.RequestCertificate(req)
+	req.PickupID, err = tpp.RequestCertificate(ctx, req)
 	if err != nil {
 		t.Fatalf("err is not nil, err: %s", err)
 	}
-	certCollections, err := tpp.RetrieveCertificate(req)
+	certCollections, err := tpp.RetrieveCertificate(ctx, req)
 	if err != nil {
 		t.Fatal(err)
 	}
@@ -231,13 +235,13 @@ func TestRequestAndSearchCertificate(t *testing.T) {
 	}
 
 	thumbprint := calcThumbprint(certCollections.Certificate)
-	searchResult, err := tpp.searchCertificatesByFingerprint(thumbprint)
+	searchResult, err := tpp.searchCertificatesByFingerprint(ctx, thumbprint)
 	if err != nil {
 		t.Fatal(err)
 	}
 
 	guid := searchResult.Certificates[0].CertificateRequestGuid
-	details, err := tpp.searchCertificateDetails(guid)
+	details, err := tpp.searchCertificateDetails(ctx, guid)
 	if err != nil {
 		t.Fatal(err)
 	}
@@ -253,11 +257,11 @@ func TestRequestAndSearchCertificate(t *testing.T) {
 	}
 
 	configReq := ConfigReadDNRequest{
-		ObjectDN:      getCertificateDN(ctx.TPPZone, "", cn),
+		ObjectDN:      getCertificateDN(env.TPPZone, "", cn),
 		AttributeName: "Origin",
 	}
 
-	configResp, err := tpp.configReadDN(configReq)
+	configResp, err := tpp.configReadDN(ctx, configReq)
 	if err != nil {
 		t.Fatal(err)
 	}
@@ -272,23 +276,23 @@ func TestRequestAndSearchCertificate(t *testing.T) {
 		TLSAddress: "wwww.example.com:443",
 	}
 
-	err = tpp.GenerateRequest(config, req)
+	err = tpp.GenerateRequest(ctx, config, req)
 	if err != nil {
 		t.Fatalf("err is not nil, err: %s", err)
 	}
 
-	req.PickupID, err = tpp.RequestCertificate(req)
+	req.PickupID, err = tpp.RequestCertificate(ctx, req)
 	if err != nil {
 		t.Fatalf("err is not nil, err: %s", err)
 	}
 
 	//to wait until cert will be aprooved so we can check list of devices
-	_, err = tpp.RetrieveCertificate(req)
+	_, err = tpp.RetrieveCertificate(ctx, req)
 	if err != nil {
 		t.Fatal(err)
 	}
 
-	details, err = tpp.searchCertificateDetails(guid)
+	details, err = tpp.searchCertificateDetails(ctx, guid)
 	if err != nil {
 		t.Fatal(err)
 	}
@@ -309,23 +313,23 @@ func TestRequestAndSearchCertificate(t *testing.T) {
 		Replace:    true,
 	}
 
-	err = tpp.GenerateRequest(config, req)
+	err = tpp.GenerateRequest(ctx, config, req)
 	if err != nil {
 		t.Fatalf("err is not nil, err: %s", err)
 	}
 
-	req.PickupID, err = tpp.RequestCertificate(req)
+	req.PickupID, err = tpp.RequestCertificate(ctx, req)
 	if err != nil {
 		t.Fatalf("err is not nil, err: %s", err)
 	}
 
 	//to wait until cert will be aprooved so we can check list of devices
-	_, err = tpp.RetrieveCertificate(req)
+	_, err = tpp.RetrieveCertificate(ctx, req)
 	if err != nil {
 		t.Fatal(err)
 	}
 
-	details, err = tpp.searchCertificateDetails(guid)
+	details, err = tpp.searchCertificateDetails(ctx, guid)
 	if err != nil {
 		t.Fatal(err)
 	}
@@ -341,19 +345,20 @@ func TestRequestAndSearchCertificate(t *testing.T) {
 }
 
 func TestRequestAndSearchCertificateWithFriendlyName(t *testing.T) {
-	tpp, err := getTestConnector(ctx.TPPurl, ctx.TPPZone)
+	ctx := context.Background()
+	tpp, err := getTestConnector(env.TPPurl, env.TPPZone)
 	if err != nil {
 		t.Fatalf("err is not nil, err: %s url: %s", err, expectedURL)
 	}
 
 	if tpp.apiKey == "" {
-		err = tpp.Authenticate(&endpoint.Authentication{AccessToken: ctx.TPPaccessToken})
+		err = tpp.Authenticate(ctx, &endpoint.Authentication{AccessToken: env.TPPaccessToken})
 		if err != nil {
 			t.Fatalf("err is not nil, err: %s", err)
 		}
 	}
 
-	config, err := tpp.ReadZoneConfiguration()
+	config, err := tpp.ReadZoneConfiguration(ctx)
 	if err != nil {
 		t.Fatalf("err is not nil, err: %s", err)
 	}
@@ -385,16 +390,16 @@ func TestRequestAndSearchCertificateWithFriendlyName(t *testing.T) {
 
 	req.KeyLength = 1024
 
-	err = tpp.GenerateRequest(config, req)
+	err = tpp.GenerateRequest(ctx, config, req)
 	if err != nil {
 		t.Fatalf("err is not nil, err: %s", err)
 	}
 
-	req.PickupID, err = tpp.RequestCertificate(req)
+	req.PickupID, err = tpp.RequestCertificate(ctx, req)
 	if err != nil {
 		t.Fatalf("err is not nil, err: %s", err)
 	}
-	certCollections, err := tpp.RetrieveCertificate(req)
+	certCollections, err := tpp.RetrieveCertificate(ctx, req)
 	if err != nil {
 		t.Fatal(err)
 	}
@@ -411,13 +416,13 @@ func TestRequestAndSearchCertificateWithFriendlyName(t *testing.T) {
 	}
 
 	thumbprint := calcThumbprint(certCollections.Certificate)
-	searchResult, err := tpp.searchCertificatesByFingerprint(thumbprint)
+	searchResult, err := tpp.searchCertificatesByFingerprint(ctx, thumbprint)
 	if err != nil {
 		t.Fatal(err)
 	}
 
 	guid := searchResult.Certificates[0].CertificateRequestGuid
-	details, err := tpp.searchCertificateDetails(guid)
+	details, err := tpp.searchCertificateDetails(ctx, guid)
 	if err != nil {
 		t.Fatal(err)
 	}
@@ -433,11 +438,11 @@ func TestRequestAndSearchCertificateWithFriendlyName(t *testing.T) {
 	}
 
 	configReq := ConfigReadDNRequest{
-		ObjectDN:      getCertificateDN(ctx.TPPZone, req.FriendlyName, cn),
+		ObjectDN:      getCertificateDN(env.TPPZone, req.FriendlyName, cn),
 		AttributeName: "Origin",
 	}
 
-	configResp, err := tpp.configReadDN(configReq)
+	configResp, err := tpp.configReadDN(ctx, configReq)
 	if err != nil {
 		t.Fatal(err)
 	}
@@ -452,23 +457,23 @@ func TestRequestAndSearchCertificateWithFriendlyName(t *testing.T) {
 		TLSAddress: "wwww.example.com:443",
 	}
 
-	err = tpp.GenerateRequest(config, req)
+	err = tpp.GenerateRequest(ctx, config, req)
 	if err != nil {
 		t.Fatalf("err is not nil, err: %s", err)
 	}
 
-	req.PickupID, err = tpp.RequestCertificate(req)
+	req.PickupID, err = tpp.RequestCertificate(ctx, req)
 	if err != nil {
 		t.Fatalf("err is not nil, err: %s", err)
 	}
 
 	//to wait until cert will be aprooved so we can check list of devices
-	_, err = tpp.RetrieveCertificate(req)
+	_, err = tpp.RetrieveCertificate(ctx, req)
 	if err != nil {
 		t.Fatal(err)
 	}
 
-	details, err = tpp.searchCertificateDetails(guid)
+	details, err = tpp.searchCertificateDetails(ctx, guid)
 	if err != nil {
 		t.Fatal(err)
 	}
@@ -489,23 +494,23 @@ func TestRequestAndSearchCertificateWithFriendlyName(t *testing.T) {
 		Replace:    true,
 	}
 
-	err = tpp.GenerateRequest(config, req)
+	err = tpp.GenerateRequest(ctx, config, req)
 	if err != nil {
 		t.Fatalf("err is not nil, err: %s", err)
 	}
 
-	req.PickupID, err = tpp.RequestCertificate(req)
+	req.PickupID, err = tpp.RequestCertificate(ctx, req)
 	if err != nil {
 		t.Fatalf("err is not nil, err: %s", err)
 	}
 
 	//to wait until cert will be aprooved so we can check list of devices
-	_, err = tpp.RetrieveCertificate(req)
+	_, err = tpp.RetrieveCertificate(ctx, req)
 	if err != nil {
 		t.Fatal(err)
 	}
 
-	details, err = tpp.searchCertificateDetails(guid)
+	details, err = tpp.searchCertificateDetails(ctx, guid)
 	if err != nil {
 		t.Fatal(err)
 	}
@@ -521,21 +526,22 @@ func TestRequestAndSearchCertificateWithFriendlyName(t *testing.T) {
 }
 
 func TestSearchDevice(t *testing.T) {
+	ctx := context.Background()
 	t.Skip() //we don't use this method now, keep this test for future usage
 
-	tpp, err := getTestConnector(ctx.TPPurl, ctx.TPPZone)
+	tpp, err := getTestConnector(env.TPPurl, env.TPPZone)
 	if err != nil {
 		t.Fatalf("err is not nil, err: %s url: %s", err, expectedURL)
 	}
 
-	authResp, err := tpp.GetRefreshToken(&endpoint.Authentication{
-		User: ctx.TPPuser, Password: ctx.TPPPassword,
+	authResp, err := tpp.GetRefreshToken(ctx, &endpoint.Authentication{
+		User: env.TPPuser, Password: env.TPPPassword,
 		Scope: "configuration:read"})
 	if err != nil {
 		panic(err)
 	}
 
-	err = tpp.Authenticate(&endpoint.Authentication{
+	err = tpp.Authenticate(ctx, &endpoint.Authentication{
 		AccessToken: authResp.Access_token,
 	})
 
@@ -548,7 +554,7 @@ func TestSearchDevice(t *testing.T) {
 		AttributeName: "Certificate",
 	}
 
-	resp, err := tpp.configReadDN(req)
+	resp, err := tpp.configReadDN(ctx, req)
 	if err != nil {
 		t.Fatal(err)
 	}
@@ -640,3 +646,96 @@ func TestFormatSearchCertificateArguments(t *testing.T) {
 		})
 	}
 }
+
+func TestCertificateFilterQuery(t *testing.T) {
+	expiry := time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC)
+	testCases := []struct {
+		name     string
+		filter   CertificateFilter
+		expected string
+	}{
+		{
+			name:     "Empty",
+			filter:   CertificateFilter{},
+			expected: "Limit=100",
+		},
+		{
+			name:     "CN and SAN",
+			filter:   CertificateFilter{CN: "test.example.com", SAN: "one.example.com"},
+			expected: "CN=test.example.com&Limit=100&SAN-DNS=one.example.com",
+		},
+		{
+			name:     "Thumbprint",
+			filter:   CertificateFilter{Thumbprint: "ab:cd:ef.01"},
+			expected: "Limit=100&Thumbprint=ABCDEF01",
+		},
+		{
+			name:     "Folder",
+			filter:   CertificateFilter{ParentDN: "Certificates\\Web"},
+			expected: "Limit=100&ParentDn=%5CVED%5CPolicy%5CCertificates%5CWeb",
+		},
+		{
+			name:     "Folder recursive",
+			filter:   CertificateFilter{ParentDN: "\\VED\\Policy\\Certificates", Recursive: true},
+			expected: "Limit=100&ParentDnRecursive=%5CVED%5CPolicy%5CCertificates",
+		},
+		{
+			name:     "Expiry window",
+			filter:   CertificateFilter{ExpiresAfter: expiry, ExpiresBefore: expiry.AddDate(0, 0, 30)},
+			expected: "Limit=100&ValidToGreater=2024-01-02T03%3A04%3A05Z&ValidToLess=2024-02-01T03%3A04%3A05Z",
+		},
+		{
+			name:     "Paging",
+			filter:   CertificateFilter{Limit: 20, Offset: 40},
+			expected: "Limit=20&Offset=40",
+		},
+	}
+
+	for _, testCase := range testCases {
+		t.Run(testCase.name, func(t *testing.T) {
+			if query := testCase.filter.query(); query != testCase.expected {
+				t.Errorf("unexpected query\nExpected:\n%v\nGot:\n%v", testCase.expected, query)
+			}
+		})
+	}
+}
+
+// Paging is tested against a mock server, since the live TPP server does not hold a known number of certificates
+func TestFindAllCertificates(t *testing.T) {
+	ctx := context.Background()
+	var offsets []string
+	server := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		offset := r.URL.Query().Get("Offset")
+		offsets = append(offsets, offset)
+		w.Header().Set("Content-Type", "application/json")
+		switch offset {
+		case "":
+			_, _ = w.Write([]byte(`{"Certificates":[{"DN":"\\VED\\Policy\\one"},{"DN":"\\VED\\Policy\\two"}],"TotalCount":3}`))
+		case "2":
+			_, _ = w.Write([]byte(`{"Certificates":[{"DN":"\\VED\\Policy\\three"}],"TotalCount":3}`))
+		default:
+			t.Errorf("unexpected offset %q", offset)
+			w.WriteHeader(http.StatusBadRequest)
+		}
+	}))
+	defer server.Close()
+
+	ca := x509.NewCertPool()
+	ca.AddCert(server.Certificate())
+	tpp, err := NewConnector(server.URL, "", false, ca)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tpp.accessToken = "token"
+
+	found, err := tpp.FindAllCertificates(ctx, CertificateFilter{Limit: 2})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(found) != 3 || found[2].DN != "\\VED\\Policy\\three" {
+		t.Fatalf("unexpected certificates %+v", found)
+	}
+	if len(offsets) != 2 {
+		t.Fatalf("expected 2 requests but got %d", len(offsets))
+	}
+}
@@ -17,6 +17,7 @@
 package tpp
 
 import (
+	"context"
 	"encoding/json"
 	"fmt"
 	"log"
@@ -34,7 +35,7 @@ const (
 	SSHCaRootPath = util.PathSeparator + "VED" + util.PathSeparator + "Certificate Authority" + util.PathSeparator + "SSH" + util.PathSeparator + "Templates"
 )
 
-func RequestSshCertificate(c *Connector, req *certificate.SshCertRequest) (*certificate.SshCertificateObject, error) {
+func RequestSshCertificate(ctx context.Context, c *Connector, req *certificate.SshCertRequest) (*certificate.SshCertificateObject, error) {
 
 	sshCertReq := convertToSshCertReq(req)
 
@@ -46,7 +47,7 @@ func RequestSshCertificate(c *Connector, req *certificate.SshCertRequest) (*cert
 
 	//TODO: Maybe, there is a better way to set the timeout.
 	c.client.Timeout = time.Duration(req.Timeout) * time.Second
-	statusCode, status, body, err := c.request("POST", urlResourceSshCertReq, sshCertReq)
+	statusCode, status, body, err := c.request(ctx, "POST", urlResourceSshCertReq, sshCertReq)
 	if err != nil {
 		return nil, err
 	}
@@ -141,7 +142,7 @@ func convertToSshCertReq(req *certificate.SshCertRequest) certificate.TPPSshCert
 	return tppSshCertReq
 }
 
-func RetrieveSshCertificate(c *Connector, req *certificate.SshCertRequest) (*certificate.SshCertificateObject, error) {
+func RetrieveSshCertificate(ctx context.Context, c *Connector, req *certificate.SshCertRequest) (*certificate.SshCertificateObject, error) {
 	var reqRetrieve certificate.TppSshCertRetrieveRequest
 
 	if req.PickupID != "" {
@@ -163,7 +164,7 @@ func RetrieveSshCertificate(c *Connector, req *certificate.SshCertRequest) (*cer
 	startTime := time.Now()
 	for {
 		var retrieveResponse *certificate.TppSshCertOperationResponse
-		retrieveResponse, err := retrieveSshCerOnce(reqRetrieve, c)
+		retrieveResponse, err := retrieveSshCerOnce(ctx, reqRetrieve, c)
 		if err != nil {
 			return nil, err
 		}
@@ -181,12 +182,92 @@ func RetrieveSshCertificate(c *Connector, req *certificate.SshCertRequest) (*cer
 		if time.Now().After(startTime.Add(req.Timeout)) {
 			return nil, endpoint.ErrRetrieveCertificateTimeout{CertificateID: req.PickupID}
 		}
-		time.Sleep(2 * time.Second)
+		if err = util.Sleep(ctx, 2*time.Second); err != nil {
+			return nil, err
+		}
+	}
+}
+
+func RenewSshCertificate(ctx context.Context, c *Connector, req *certificate.SshCertRequest) (*certificate.SshCertificateObject, error) {
+
+	if req.PickupID == "" && req.Guid == "" {
+		return nil, fmt.Errorf("the DN or GUID of the SSH certificate to renew is required")
+	}
+
+	renewReq := certificate.TppSshCertRenewRequest{
+		DN:                        req.PickupID,
+		Guid:                      req.Guid,
+		PublicKeyData:             req.PublicKeyData,
+		PrivateKeyPassphrase:      req.PrivateKeyPassphrase,
+		IncludePrivateKeyData:     true,
+		IncludeCertificateDetails: true,
+	}
+
+	log.Println("Renewing SSH certificate: ", getSshCertIdentifier(req))
+
+	statusCode, status, body, err := c.request(ctx, "POST", urlResourceSshCertRenew, renewReq)
+	if err != nil {
+		return nil, err
+	}
+
+	response, err := parseSshCertOperationResponse(statusCode, status, body)
+
+	if err != nil {
+		if response.Response.ErrorMessage != "" && c.verbose {
+			log.Println(util.GetJsonAsString(response.Response))
+		}
+		return nil, err
+	}
+
+	log.Println("SSH certificate DN: ", response.DN)
+	log.Println("GUID: ", response.Guid)
+
+	if response.Response.Success && response.ProcessingDetails.Status == "Rejected" {
+		return nil, endpoint.ErrCertificateRejected{CertificateID: response.DN, Status: response.ProcessingDetails.StatusDescription}
+	}
+
+	return convertToGenericRetrieveResponse(&response), nil
+}
+
+func RevokeSshCertificate(ctx context.Context, c *Connector, req *certificate.SshCertRequest) error {
+
+	if req.PickupID == "" && req.Guid == "" {
+		return fmt.Errorf("the DN or GUID of the SSH certificate to revoke is required")
+	}
+
+	revokeReq := certificate.TppSshCertRevokeRequest{
+		DN:   req.PickupID,
+		Guid: req.Guid,
+	}
+
+	log.Println("Revoking SSH certificate: ", getSshCertIdentifier(req))
+
+	statusCode, status, body, err := c.request(ctx, "POST", urlResourceSshCertRevoke, revokeReq)
+	if err != nil {
+		return err
+	}
+
+	response, err := parseSshCertOperationResponse(statusCode, status, body)
+	if err != nil {
+		if response.Response.ErrorMessage != "" && c.verbose {
+			log.Println(util.GetJsonAsString(response.Response))
+		}
+		return err
+	}
+
+	return nil
+}
+
+// getSshCertIdentifier returns the DN of the SSH certificate, or its GUID when the DN is not set
+func getSshCertIdentifier(req *certificate.SshCertRequest) string {
+	if req.PickupID != "" {
+		return req.PickupID
 	}
+	return req.Guid
 }
 
-func retrieveSshCerOnce(sshRetrieveReq certificate.TppSshCertRetrieveRequest, c *Connector) (*certificate.TppSshCertOperationResponse, error) {
-	statusCode, status, body, err := c.request("POST", urlResourceSshCertRet, sshRetrieveReq)
+func retrieveSshCerOnce(ctx context.Context, sshRetrieveReq certificate.TppSshCertRetrieveRequest, c *Connector) (*certificate.TppSshCertOperationResponse, error) {
+	statusCode, status, body, err := c.request(ctx, "POST", urlResourceSshCertRet, sshRetrieveReq)
 	if err != nil {
 		return nil, err
 	}
@@ -261,7 +342,7 @@ func getSshConfigUrl(key, value string) string {
 	return url
 }
 
-func RetrieveSshConfig(c *Connector, ca *certificate.SshCaTemplateRequest) (*certificate.SshConfig, error) {
+func RetrieveSshConfig(ctx context.Context, c *Connector, ca *certificate.SshCaTemplateRequest) (*certificate.SshConfig, error) {
 
 	var url string
 	if ca.Template != "" {
@@ -275,7 +356,7 @@ func RetrieveSshConfig(c *Connector, ca *certificate.SshCaTemplateRequest) (*cer
 		return nil, fmt.Errorf("CA template or GUID are not specified")
 	}
 
-	statusCode, status, body, err := c.request("GET", urlResource(url), nil)
+	statusCode, status, body, err := c.request(ctx, "GET", urlResource(url), nil)
 
 	if err != nil {
 		return nil, err
@@ -291,7 +372,7 @@ func RetrieveSshConfig(c *Connector, ca *certificate.SshCaTemplateRequest) (*cer
 	}
 
 	if c.accessToken != "" {
-		principals, err := RetrieveSshCaPrincipals(c, ca)
+		principals, err := RetrieveSshCaPrincipals(ctx, c, ca)
 		if err != nil {
 			return nil, err
 		}
@@ -304,9 +385,9 @@ func RetrieveSshConfig(c *Connector, ca *certificate.SshCaTemplateRequest) (*cer
 	return &conf, nil
 }
 
-func GetAvailableSshTemplates(c *Connector) ([]certificate.SshAvaliableTemplate, error) {
+func GetAvailableSshTemplates(ctx context.Context, c *Connector) ([]certificate.SshAvaliableTemplate, error) {
 	var response []certificate.SshAvaliableTemplate
-	statusCode, status, body, err := c.request("GET", urlResourceSshTemplateAvaliable, nil)
+	statusCode, status, body, err := c.request(ctx, "GET", urlResourceSshTemplateAvaliable, nil)
 	if err != nil {
 		return nil, err
 	}
@@ -326,7 +407,7 @@ func GetAvailableSshTemplates(c *Connector) ([]certificate.SshAvaliableTemplate,
 	return response, nil
 }
 
-func RetrieveSshCaPrincipals(c *Connector, ca *certificate.SshCaTemplateRequest) ([]string, error) {
+func RetrieveSshCaPrincipals(ctx context.Context, c *Connector, ca *certificate.SshCaTemplateRequest) ([]string, error) {
 
 	tppReq := certificate.SshTppCaTemplateRequest{}
 
@@ -340,7 +421,7 @@ func RetrieveSshCaPrincipals(c *Connector, ca *certificate.SshCaTemplateRequest)
 		return nil, fmt.Errorf("CA template or GUID are not specified")
 	}
 
-	statusCode, status, body, err := c.request("POST", urlResourceSshCADetails, tppReq)
+	statusCode, status, body, err := c.request(ctx, "POST", urlResourceSshCADetails, tppReq)
 
 	if err != nil {
 		return nil, err
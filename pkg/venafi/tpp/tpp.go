@@ -18,14 +18,13 @@ package tpp
 
 import (
 	"bytes"
-	"crypto/tls"
+	"context"
 	"crypto/x509"
 	"encoding/base64"
 	"encoding/json"
 	"fmt"
 	"io"
 	"log"
-	"net"
 	"net/http"
 	"regexp"
 	"strings"
@@ -34,6 +33,7 @@ import (
 	"github.com/Venafi/vcert/v5/pkg/certificate"
 	"github.com/Venafi/vcert/v5/pkg/endpoint"
 	"github.com/Venafi/vcert/v5/pkg/policy"
+	"github.com/Venafi/vcert/v5/pkg/util"
 )
 
 const defaultKeySize = 2048
@@ -372,6 +372,7 @@ const (
 	urlResourceCertificatesList                   = urlResourceCertificate
 	urlResourceConfigDnToGuid         urlResource = "vedsdk/config/dntoguid"
 	urlResourceConfigReadDn           urlResource = "vedsdk/config/readdn"
+	urlResourceConfigRenameObject     urlResource = "vedsdk/Config/RenameObject"
 	urlResourceFindPolicy             urlResource = "vedsdk/config/findpolicy"
 	urlResourceMetadataSet            urlResource = "vedsdk/metadata/set"
 	urlResourceAllMetadataGet         urlResource = "vedsdk/metadata/getitems"
@@ -388,6 +389,8 @@ const (
 	urlResourceValidateIdentity       urlResource = "vedsdk/Identity/Validate"
 	urlResourceSshCertReq             urlResource = "vedsdk/SSHCertificates/request"
 	urlResourceSshCertRet             urlResource = "vedsdk/SSHCertificates/retrieve"
+	urlResourceSshCertRenew           urlResource = "vedsdk/SSHCertificates/renew"
+	urlResourceSshCertRevoke          urlResource = "vedsdk/SSHCertificates/revoke"
 	urlResourceSshCAPubKey            urlResource = "vedsdk/SSHCertificates/Template/Retrieve/PublicKeyData"
 	urlResourceSshCADetails           urlResource = "vedsdk/SSHCertificates/Template/Retrieve"
 	urlResourceSshTemplateAvaliable   urlResource = "vedsdk/SSHCertificates/Template/Available"
@@ -443,7 +446,23 @@ func retrieveChainOptionFromString(order string) retrieveChainOption {
 	}
 }
 
-func (c *Connector) request(method string, resource urlResource, data interface{}) (statusCode int, statusText string, body []byte, err error) {
+func (c *Connector) request(ctx context.Context, method string, resource urlResource, data interface{}) (statusCode int, statusText string, body []byte, err error) {
+	token := c.getAccessToken()
+	statusCode, statusText, body, err = c.doRequest(ctx, method, resource, data, token)
+	if err != nil || statusCode != http.StatusUnauthorized || !c.canRefreshToken(token, resource) {
+		return
+	}
+
+	// The access token expired during the run. It is refreshed, and the request sent again once with the new one
+	err = c.refreshAccessToken(ctx, token)
+	if err != nil {
+		return statusCode, statusText, body, fmt.Errorf("access token expired and could not be refreshed: %w", err)
+	}
+	return c.doRequest(ctx, method, resource, data, c.getAccessToken())
+}
+
+// doRequest sends a request to TPP, authenticated with token or, when it is empty, with the API key
+func (c *Connector) doRequest(ctx context.Context, method string, resource urlResource, data interface{}, token string) (statusCode int, statusText string, body []byte, err error) {
 	url := c.baseURL + string(resource)
 	var payload io.Reader
 	var b []byte
@@ -452,10 +471,9 @@ func (c *Connector) request(method string, resource urlResource, data interface{
 		payload = bytes.NewReader(b)
 	}
 
-	r, _ := http.NewRequest(method, url, payload)
-	r.Close = true
-	if c.accessToken != "" {
-		r.Header.Add("Authorization", fmt.Sprintf("Bearer %s", c.accessToken))
+	r, _ := http.NewRequestWithContext(ctx, method, url, payload)
+	if token != "" {
+		r.Header.Add("Authorization", fmt.Sprintf("Bearer %s", token))
 	} else if c.apiKey != "" {
 		r.Header.Add("x-venafi-api-key", c.apiKey)
 	}
@@ -495,44 +513,21 @@ func (c *Connector) getHTTPClient() *http.Client {
 	if c.client != nil {
 		return c.client
 	}
-	var netTransport = &http.Transport{
-		Proxy: http.ProxyFromEnvironment,
-		DialContext: (&net.Dialer{
-			Timeout:   30 * time.Second,
-			KeepAlive: 30 * time.Second,
-			DualStack: true,
-		}).DialContext,
-		MaxIdleConns:          100,
-		IdleConnTimeout:       90 * time.Second,
-		TLSHandshakeTimeout:   10 * time.Second,
-		ExpectContinueTimeout: 1 * time.Second,
-	}
-	tlsConfig := http.DefaultTransport.(*http.Transport).TLSClientConfig
-	/* #nosec */
-	if c.trust != nil {
-		if tlsConfig == nil {
-			tlsConfig = &tls.Config{}
-		} else {
-			tlsConfig = tlsConfig.Clone()
-		}
-		tlsConfig.RootCAs = c.trust
-	}
-	netTransport.TLSClientConfig = tlsConfig
+	netTransport := util.NewTransportWithTLSConfig(c.transport, c.proxy, c.trust, c.tlsConfig)
 	c.client = &http.Client{
-		Timeout:   time.Second * 30,
-		Transport: netTransport,
+		Transport: &util.RetryTransport{
+			Base:    netTransport,
+			Policy:  c.retryPolicy,
+			Timeout: time.Second * 30,
+		},
 	}
 	return c.client
 }
 
 // GenerateRequest creates a new certificate request, based on the zone/policy configuration and the user data
-func (c *Connector) GenerateRequest(config *endpoint.ZoneConfiguration, req *certificate.Request) (err error) {
-	if req.KeyType == certificate.KeyTypeED25519 {
-		return fmt.Errorf("Unable to request certificate from TPP, ed25519 key type is not for TPP")
-	}
-
+func (c *Connector) GenerateRequest(ctx context.Context, config *endpoint.ZoneConfiguration, req *certificate.Request) (err error) {
 	if config == nil {
-		config, err = c.ReadZoneConfiguration()
+		config, err = c.ReadZoneConfiguration(ctx)
 		if err != nil {
 			return fmt.Errorf("could not read zone configuration: %s", err)
 		}
@@ -566,6 +561,17 @@ func (c *Connector) GenerateRequest(config *endpoint.ZoneConfiguration, req *cer
 		}
 
 	case certificate.ServiceGeneratedCSR:
+		// TPP only generates RSA and ECDSA keys. Ed25519 keys must be generated locally
+		if req.KeyType == certificate.KeyTypeED25519 {
+			return fmt.Errorf("Unable to request certificate from TPP, ed25519 keys are only supported with a local generated CSR")
+		}
+		if req.KeyType.IsPostQuantum() {
+			return fmt.Errorf("Unable to request certificate from TPP, %s keys are only supported with a local generated CSR", req.KeyType.String())
+		}
+		// TPP has no field for them, so they can only be requested in a CSR
+		if len(req.Extensions) > 0 || len(req.ExtKeyUsages) > 0 {
+			return fmt.Errorf("Unable to request certificate from TPP, custom extensions and extended key usages are only supported with a local generated or user provided CSR")
+		}
 	}
 	return nil
 }
@@ -596,6 +602,16 @@ func getDeviceDN(zone string, location certificate.Location) string {
 	return getPolicyDN(zone + "\\" + location.Instance + "\\" + workload)
 }
 
+// getApplicationDNs returns the full DNs of the given application objects, relative to the policy root when they
+// are not absolute
+func getApplicationDNs(applicationDNs []string) []string {
+	dns := make([]string, 0, len(applicationDNs))
+	for _, dn := range applicationDNs {
+		dns = append(dns, getPolicyDN(stripBackSlashes(dn)))
+	}
+	return dns
+}
+
 func getCertificateDN(zone, friendlyName string, cn string) string {
 	if friendlyName != "" {
 		return getPolicyDN(zone + "\\" + friendlyName)
@@ -717,6 +733,16 @@ func newPEMCollectionFromResponse(base64Response string, chainOrder certificate.
 	return nil, nil
 }
 
+// newPEMCollectionFromPKCS12Response decodes a keystore retrieved in the "PKCS #12" format, keeping the private key
+// encrypted with the keystore password
+func newPEMCollectionFromPKCS12Response(base64Response string, password string, chainOrder certificate.ChainOption) (*certificate.PEMCollection, error) {
+	keystore, err := base64.StdEncoding.DecodeString(base64Response)
+	if err != nil {
+		return nil, err
+	}
+	return certificate.PEMCollectionFromPKCS12(keystore, password, chainOrder)
+}
+
 func parseBrowseIdentitiesResult(httpStatusCode int, httpStatus string, body []byte) (policy.BrowseIdentitiesResponse, error) {
 	var browseIdentitiesResponse policy.BrowseIdentitiesResponse
 	switch httpStatusCode {
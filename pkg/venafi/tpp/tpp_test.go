@@ -17,6 +17,7 @@
 package tpp
 
 import (
+	"context"
 	"crypto/x509"
 	"net/http"
 	"strings"
@@ -124,6 +125,19 @@ func getBaseZoneConfiguration() *endpoint.ZoneConfiguration {
 	return z
 }
 
+func TestGetApplicationDNs(t *testing.T) {
+	actual := getApplicationDNs([]string{"Servers\\web01\\Apache", "\\VED\\Policy\\Servers\\web02\\Apache"})
+	expected := []string{"\\VED\\Policy\\Servers\\web01\\Apache", "\\VED\\Policy\\Servers\\web02\\Apache"}
+	if len(actual) != len(expected) {
+		t.Fatalf("getApplicationDNs did not return the expected value of %s -- Actual value %s", expected, actual)
+	}
+	for i := range expected {
+		if expected[i] != actual[i] {
+			t.Fatalf("getApplicationDNs did not return the expected value of %s -- Actual value %s", expected, actual)
+		}
+	}
+}
+
 func TestGetPolicyDN(t *testing.T) {
 	const expectedPolicy = "\\VED\\Policy\\One\\Level 2\\This is level Three"
 
@@ -192,6 +206,7 @@ func TestNewPEMCertificateCollectionFromResponse(t *testing.T) {
 }
 
 func TestGenerateRequest(t *testing.T) {
+	ctx := context.Background()
 	tpp := Connector{}
 	zoneConfig := getBaseZoneConfiguration()
 	req := certificate.Request{}
@@ -202,13 +217,14 @@ func TestGenerateRequest(t *testing.T) {
 	req.Subject.Province = []string{"Nevada"}
 	req.Subject.Country = []string{"US"}
 	zoneConfig.UpdateCertificateRequest(&req)
-	err := tpp.GenerateRequest(zoneConfig, &req)
+	err := tpp.GenerateRequest(ctx, zoneConfig, &req)
 	if err != nil {
 		t.Fatalf("Error: %s", err)
 	}
 }
 
 func TestGenerateRequestWithLockedMgmtType(t *testing.T) {
+	ctx := context.Background()
 	tpp := Connector{}
 	zoneConfig := getBaseZoneConfiguration()
 	zoneConfig.CustomAttributeValues[tppAttributeManagementType] = "Monitoring"
@@ -220,13 +236,14 @@ func TestGenerateRequestWithLockedMgmtType(t *testing.T) {
 	req.Subject.Province = []string{"Nevada"}
 	req.Subject.Country = []string{"US"}
 	zoneConfig.UpdateCertificateRequest(&req)
-	err := tpp.GenerateRequest(zoneConfig, &req)
+	err := tpp.GenerateRequest(ctx, zoneConfig, &req)
 	if err == nil {
 		t.Fatalf("Error expected, request should not be generated with mgmt type set to Monitoring")
 	}
 }
 
 func TestGenerateRequestWithNoUserProvidedCSRAllowed(t *testing.T) {
+	ctx := context.Background()
 	tpp := Connector{}
 	zoneConfig := getBaseZoneConfiguration()
 	zoneConfig.CustomAttributeValues[tppAttributeManualCSR] = "0"
@@ -238,13 +255,14 @@ func TestGenerateRequestWithNoUserProvidedCSRAllowed(t *testing.T) {
 	req.Subject.Province = []string{"Nevada"}
 	req.Subject.Country = []string{"US"}
 	zoneConfig.UpdateCertificateRequest(&req)
-	err := tpp.GenerateRequest(zoneConfig, &req)
+	err := tpp.GenerateRequest(ctx, zoneConfig, &req)
 	if err == nil {
 		t.Fatalf("Error expected, request should not be generated with Manual CSR set to 0")
 	}
 }
 
 func TestGenerateRequestWithLockedKeyConfiguration(t *testing.T) {
+	ctx := context.Background()
 	tpp := Connector{}
 	zoneConfig := getBaseZoneConfiguration()
 	zoneConfig.AllowedKeyConfigurations = []endpoint.AllowedKeyConfiguration{{KeyType: certificate.KeyTypeECDSA, KeyCurves: []certificate.EllipticCurve{certificate.EllipticCurveP384}}}
@@ -257,7 +275,7 @@ func TestGenerateRequestWithLockedKeyConfiguration(t *testing.T) {
 	req.Subject.Country = []string{"US"}
 	req.KeyType = certificate.KeyTypeRSA
 	zoneConfig.UpdateCertificateRequest(&req)
-	err := tpp.GenerateRequest(zoneConfig, &req)
+	err := tpp.GenerateRequest(ctx, zoneConfig, &req)
 	if err != nil {
 		t.Fatalf("Error expected, request should be update with key type goten from zone")
 	}
@@ -0,0 +1,194 @@
+/*
+ * Copyright 2023 Venafi, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *  http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package vcert
+
+import (
+	"context"
+	"crypto"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/pem"
+	"fmt"
+	"log"
+	"sync"
+	"time"
+
+	"github.com/Venafi/vcert/v5/pkg/certificate"
+	"github.com/Venafi/vcert/v5/pkg/util"
+	"github.com/Venafi/vcert/v5/pkg/verror"
+)
+
+const (
+	// defaultTLSRetrieveTimeout is the time to wait for a certificate to be issued when the request sets no Timeout
+	defaultTLSRetrieveTimeout = 180 * time.Second
+	// tlsRenewRetryMin and tlsRenewRetryMax bound the delay between the attempts of a failed background renewal
+	tlsRenewRetryMin = time.Minute
+	tlsRenewRetryMax = time.Hour
+)
+
+// EnrollTLSCertificate requests the certificate described by req to the Venafi platform defined by cfg, and returns it
+// as a tls.Certificate ready to be served, with its chain and Leaf set. Nothing is written to disk.
+//
+// The private key is generated locally, or in the KeyProvider of req when set, so only locally generated CSRs are
+// supported. req is used as a template and is not modified. Cancelling ctx aborts the requests in progress and the
+// wait for the certificate to be issued.
+func EnrollTLSCertificate(ctx context.Context, cfg *Config, req *certificate.Request) (*tls.Certificate, error) {
+	if req == nil {
+		return nil, fmt.Errorf("%w: certificate request is required", verror.UserDataError)
+	}
+	if req.CsrOrigin != certificate.LocalGeneratedCSR {
+		return nil, fmt.Errorf("%w: only locally generated CSRs are supported, as the private key must be available", verror.UserDataError)
+	}
+
+	conn, err := cfg.NewClientContext(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	// The fields set while the request is made must not leak into the template, which is reused on renewal
+	r := *req
+	r.PrivateKey = nil
+	r.PickupID = ""
+
+	zc, err := conn.ReadZoneConfiguration(ctx)
+	if err != nil {
+		return nil, err
+	}
+	err = conn.GenerateRequest(ctx, zc, &r)
+	if err != nil {
+		return nil, err
+	}
+
+	var pcc *certificate.PEMCollection
+	if conn.SupportSynchronousRequestCertificate() {
+		pcc, err = conn.SynchronousRequestCertificate(ctx, &r)
+	} else {
+		r.PickupID, err = conn.RequestCertificate(ctx, &r)
+		if err != nil {
+			return nil, err
+		}
+		if r.Timeout == 0 {
+			r.Timeout = defaultTLSRetrieveTimeout
+		}
+		pcc, err = conn.RetrieveCertificate(ctx, &r)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return toTLSCertificate(pcc, &r)
+}
+
+// toTLSCertificate returns the certificate of pcc, with its chain, and the private key of the request that enrolled it
+func toTLSCertificate(pcc *certificate.PEMCollection, req *certificate.Request) (*tls.Certificate, error) {
+	if req.PrivateKey == nil {
+		return nil, fmt.Errorf("%w: no private key found for the certificate", verror.VcertError)
+	}
+
+	cert := &tls.Certificate{PrivateKey: req.PrivateKey}
+	for _, p := range append([]string{pcc.Certificate}, pcc.Chain...) {
+		block, _ := pem.Decode([]byte(p))
+		if block == nil {
+			return nil, fmt.Errorf("%w: failed to decode certificate PEM", verror.VcertError)
+		}
+		cert.Certificate = append(cert.Certificate, block.Bytes)
+	}
+
+	leaf, err := x509.ParseCertificate(cert.Certificate[0])
+	if err != nil {
+		return nil, fmt.Errorf("%w: failed to parse certificate: %v", verror.VcertError, err)
+	}
+	publicKey, ok := req.PrivateKey.Public().(interface{ Equal(crypto.PublicKey) bool })
+	if !ok || !publicKey.Equal(leaf.PublicKey) {
+		return nil, fmt.Errorf("%w: the certificate does not match the private key", verror.VcertError)
+	}
+	cert.Leaf = leaf
+	return cert, nil
+}
+
+// GetCertificateFunc enrolls the certificate described by req, like EnrollTLSCertificate, and returns a function
+// suitable for tls.Config.GetCertificate that serves it.
+//
+// The certificate is renewed in background once two thirds of its lifetime have passed. A failed renewal is retried,
+// while the current certificate keeps being served, until it succeeds or ctx is done. Cancelling ctx stops the renewals.
+func GetCertificateFunc(ctx context.Context, cfg *Config, req *certificate.Request) (func(*tls.ClientHelloInfo) (*tls.Certificate, error), error) {
+	cert, err := EnrollTLSCertificate(ctx, cfg, req)
+	if err != nil {
+		return nil, err
+	}
+
+	m := &tlsCertificateManager{cfg: cfg, req: req, cert: cert}
+	go m.renew(ctx)
+	return m.getCertificate, nil
+}
+
+// tlsCertificateManager serves the certificate enrolled by GetCertificateFunc and renews it
+type tlsCertificateManager struct {
+	cfg *Config
+	req *certificate.Request
+
+	mu   sync.RWMutex
+	cert *tls.Certificate
+}
+
+func (m *tlsCertificateManager) getCertificate(_ *tls.ClientHelloInfo) (*tls.Certificate, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	return m.cert, nil
+}
+
+// renew replaces the certificate every time it reaches its renewal date, until ctx is done
+func (m *tlsCertificateManager) renew(ctx context.Context) {
+	retry := tlsRenewRetryMin
+	for {
+		m.mu.RLock()
+		wait := time.Until(tlsRenewalDate(m.cert.Leaf))
+		m.mu.RUnlock()
+
+		if err := util.Sleep(ctx, wait); err != nil {
+			return
+		}
+
+		cert, err := EnrollTLSCertificate(ctx, m.cfg, m.req)
+		for err != nil {
+			if ctx.Err() != nil {
+				return
+			}
+			log.Printf("Failed to renew certificate %s, retrying in %s: %s", m.req.Subject.CommonName, retry, err)
+			if util.Sleep(ctx, retry) != nil {
+				return
+			}
+			retry *= 2
+			if retry > tlsRenewRetryMax {
+				retry = tlsRenewRetryMax
+			}
+			cert, err = EnrollTLSCertificate(ctx, m.cfg, m.req)
+		}
+		retry = tlsRenewRetryMin
+
+		m.mu.Lock()
+		m.cert = cert
+		m.mu.Unlock()
+		log.Printf("Renewed certificate %s, valid until %s", m.req.Subject.CommonName, cert.Leaf.NotAfter)
+	}
+}
+
+// tlsRenewalDate returns the date once two thirds of the lifetime of cert have passed
+func tlsRenewalDate(cert *x509.Certificate) time.Time {
+	lifetime := cert.NotAfter.Sub(cert.NotBefore)
+	return cert.NotBefore.Add(lifetime * 2 / 3)
+}
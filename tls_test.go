@@ -0,0 +1,112 @@
+/*
+ * Copyright 2023 Venafi, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *  http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package vcert
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/Venafi/vcert/v5/pkg/certificate"
+	"github.com/Venafi/vcert/v5/pkg/endpoint"
+	"github.com/Venafi/vcert/v5/pkg/verror"
+)
+
+func TestEnrollTLSCertificate(t *testing.T) {
+	cfg := &Config{ConnectorType: endpoint.ConnectorTypeFake}
+	req := &certificate.Request{
+		Subject:   pkix.Name{CommonName: "tls.vcert.test"},
+		CsrOrigin: certificate.LocalGeneratedCSR,
+		KeyType:   certificate.KeyTypeRSA,
+		KeyLength: 2048,
+	}
+
+	cert, err := EnrollTLSCertificate(context.Background(), cfg, req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if cert.Leaf == nil || cert.Leaf.Subject.CommonName != "tls.vcert.test" {
+		t.Fatalf("unexpected leaf certificate: %v", cert.Leaf)
+	}
+	if len(cert.Certificate) < 2 {
+		t.Fatalf("expected the certificate chain, got %d certificates", len(cert.Certificate))
+	}
+	if req.PrivateKey != nil || req.PickupID != "" {
+		t.Fatal("certificate request template was modified")
+	}
+
+	// The same template must enroll a new key pair every time
+	renewed, err := EnrollTLSCertificate(context.Background(), cfg, req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if renewed.Leaf.Equal(cert.Leaf) || renewed.PrivateKey == cert.PrivateKey {
+		t.Fatal("expected a new certificate and private key")
+	}
+}
+
+func TestEnrollTLSCertificate_UserProvidedCSR(t *testing.T) {
+	cfg := &Config{ConnectorType: endpoint.ConnectorTypeFake}
+	req := &certificate.Request{
+		Subject:   pkix.Name{CommonName: "tls.vcert.test"},
+		CsrOrigin: certificate.UserProvidedCSR,
+	}
+
+	_, err := EnrollTLSCertificate(context.Background(), cfg, req)
+	if !errors.Is(err, verror.UserDataError) {
+		t.Fatalf("expected user data error, got %v", err)
+	}
+}
+
+func TestGetCertificateFunc(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	cfg := &Config{ConnectorType: endpoint.ConnectorTypeFake}
+	req := &certificate.Request{
+		Subject:   pkix.Name{CommonName: "tls.vcert.test"},
+		CsrOrigin: certificate.LocalGeneratedCSR,
+		KeyType:   certificate.KeyTypeECDSA,
+		KeyCurve:  certificate.EllipticCurveP256,
+	}
+
+	getCertificate, err := GetCertificateFunc(ctx, cfg, req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cert, err := getCertificate(&tls.ClientHelloInfo{ServerName: "tls.vcert.test"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if cert.Leaf.Subject.CommonName != "tls.vcert.test" {
+		t.Fatalf("unexpected common name %s", cert.Leaf.Subject.CommonName)
+	}
+}
+
+func TestTLSRenewalDate(t *testing.T) {
+	notBefore := time.Date(2023, 1, 1, 0, 0, 0, 0, time.UTC)
+	cert := &x509.Certificate{NotBefore: notBefore, NotAfter: notBefore.Add(90 * time.Hour)}
+
+	expected := notBefore.Add(60 * time.Hour)
+	if date := tlsRenewalDate(cert); !date.Equal(expected) {
+		t.Fatalf("expected renewal date %s, got %s", expected, date)
+	}
+}
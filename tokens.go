@@ -0,0 +1,184 @@
+/*
+ * Copyright 2023 Venafi, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *  http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package vcert
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+
+	"gopkg.in/ini.v1"
+
+	"github.com/Venafi/vcert/v5/pkg/endpoint"
+	"github.com/Venafi/vcert/v5/pkg/util"
+	"github.com/Venafi/vcert/v5/pkg/venafi/firefly"
+	"github.com/Venafi/vcert/v5/pkg/venafi/tpp"
+)
+
+// tppTimeFormat is the format of the ISO 8601 times of the TPP token verification
+const tppTimeFormat = "2006-01-02T15:04:05Z"
+
+// RenewTokensInFile renews the access token of the section of the configuration file at path when it expires within
+// renewBefore, or when its expiration cannot be told. TPP tokens are refreshed with refresh_token, and Firefly tokens
+// are requested again with the OAuth client credentials or the OAuth user of the section.
+//
+// The file is locked while the tokens are checked and renewed, so concurrent vcert processes using it do not
+// refresh the same tokens twice, or overwrite the tokens written by each other.
+// Returns when the access token in the file expires, zero when unknown, and whether it was renewed
+func RenewTokensInFile(ctx context.Context, path string, section string, renewBefore time.Duration) (expires time.Time, renewed bool, err error) {
+	fname, err := expand(path)
+	if err != nil {
+		return expires, false, fmt.Errorf("failed to renew tokens: %s", err)
+	}
+	unlock, err := util.LockFile(fname)
+	if err != nil {
+		return expires, false, fmt.Errorf("failed to renew tokens: %s", err)
+	}
+	defer func() {
+		_ = unlock()
+	}()
+
+	if section == "" {
+		// nolint:staticcheck
+		section = ini.DEFAULT_SECTION
+	}
+	// The file is read once locked, so the tokens written by the last process holding the lock are used
+	cfg, err := LoadConfigFromFile(fname, section)
+	if err != nil {
+		return expires, false, err
+	}
+	// The tokens are saved here, with the lock held
+	cfg.Credentials.OnTokenRefresh = nil
+	cfg.Credentials.LockTokens = nil
+
+	switch cfg.ConnectorType {
+	case endpoint.ConnectorTypeTPP:
+		return renewTPPTokens(ctx, &cfg, fname, section, renewBefore)
+	case endpoint.ConnectorTypeFirefly:
+		return renewFireflyToken(ctx, &cfg, fname, section, renewBefore)
+	default:
+		return expires, false, fmt.Errorf("failed to renew tokens: section %s is not a TPP or Firefly section", section)
+	}
+}
+
+// renewTPPTokens refreshes the TPP token pair of cfg when the access token expires within renewBefore, or is no
+// longer valid, and saves the new pair to the section of the file
+func renewTPPTokens(ctx context.Context, cfg *Config, path string, section string, renewBefore time.Duration) (time.Time, bool, error) {
+	if cfg.Credentials.RefreshToken == "" {
+		return time.Time{}, false, fmt.Errorf("failed to renew tokens: no %s in section %s", tppRefreshTokenKey, section)
+	}
+
+	client, err := NewClient(cfg, false)
+	if err != nil {
+		return time.Time{}, false, err
+	}
+	connector, ok := client.(*tpp.Connector)
+	if !ok {
+		return time.Time{}, false, fmt.Errorf("failed to renew tokens: unexpected connector %T", client)
+	}
+
+	if cfg.Credentials.AccessToken != "" {
+		resp, err := connector.VerifyAccessToken(ctx, &endpoint.Authentication{AccessToken: cfg.Credentials.AccessToken})
+		// TPP answering the verification with an error status means the token is no longer valid. Any other error,
+		// like a connectivity one, says nothing about the token
+		if err != nil && !strings.HasPrefix(err.Error(), "failed to verify token. Message:") {
+			return time.Time{}, false, err
+		}
+		if err == nil {
+			issued, err := time.Parse(tppTimeFormat, resp.AccessIssuedOn)
+			expires := issued.Add(time.Duration(resp.ValidFor) * time.Second)
+			if err == nil && time.Until(expires) > renewBefore {
+				return expires, false, nil
+			}
+		}
+	}
+
+	resp, err := connector.RefreshAccessToken(ctx, &endpoint.Authentication{
+		RefreshToken: cfg.Credentials.RefreshToken,
+		ClientId:     cfg.Credentials.ClientId,
+	})
+	if err != nil {
+		return time.Time{}, false, err
+	}
+	err = saveTokensToFile(path, section, map[string]string{
+		tppAccessTokenKey:  resp.Access_token,
+		tppRefreshTokenKey: resp.Refresh_token,
+	})
+	if err != nil {
+		return time.Time{}, false, err
+	}
+	return time.Unix(int64(resp.Expires), 0), true, nil
+}
+
+// renewFireflyToken requests a new Firefly access token for cfg when its access token expires within renewBefore, or
+// its expiration cannot be told, and saves it to the section of the file
+func renewFireflyToken(ctx context.Context, cfg *Config, path string, section string, renewBefore time.Duration) (time.Time, bool, error) {
+	expires := jwtExpiration(cfg.Credentials.AccessToken)
+	if !expires.IsZero() && time.Until(expires) > renewBefore {
+		return expires, false, nil
+	}
+
+	// The device flow needs the user to authorize the request in a browser, so it cannot run unattended
+	if cfg.Credentials.ClientId == "" || (cfg.Credentials.IdentityProvider != nil && cfg.Credentials.IdentityProvider.DeviceURL != "") {
+		return time.Time{}, false, fmt.Errorf("failed to renew tokens: section %s needs %s along with %s, or %s and %s",
+			section, fireflyClientIdKey, fireflyClientSecretKey, fireflyUserKey, fireflyPasswordKey)
+	}
+
+	client, err := NewClient(cfg, false)
+	if err != nil {
+		return time.Time{}, false, err
+	}
+	connector, ok := client.(*firefly.Connector)
+	if !ok {
+		return time.Time{}, false, fmt.Errorf("failed to renew tokens: unexpected connector %T", client)
+	}
+
+	auth := *cfg.Credentials
+	auth.AccessToken = ""
+	token, err := connector.Authorize(ctx, &auth)
+	if err != nil {
+		return time.Time{}, false, err
+	}
+	err = saveTokensToFile(path, section, map[string]string{fireflyAccessTokenKey: token.AccessToken})
+	if err != nil {
+		return time.Time{}, false, err
+	}
+	return token.Expiry, true, nil
+}
+
+// jwtExpiration returns the time of the exp claim of token, or zero when token is not a JWT or has no exp claim.
+// The signature is not verified, the claim is only used to tell when to renew the token
+func jwtExpiration(token string) time.Time {
+	parts := strings.Split(token, ".")
+	if len(parts) != 3 {
+		return time.Time{}
+	}
+	payload, err := base64.RawURLEncoding.DecodeString(strings.TrimRight(parts[1], "="))
+	if err != nil {
+		return time.Time{}
+	}
+	claims := struct {
+		Exp int64 `json:"exp"`
+	}{}
+	if json.Unmarshal(payload, &claims) != nil || claims.Exp == 0 {
+		return time.Time{}
+	}
+	return time.Unix(claims.Exp, 0)
+}
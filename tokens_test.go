@@ -0,0 +1,108 @@
+/*
+ * Copyright 2023 Venafi, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *  http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package vcert
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/pem"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestRenewTokensInFile(t *testing.T) {
+	refreshes := 0
+	server := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		switch r.URL.Path {
+		case "/vedauth/authorize/verify":
+			if r.Header.Get("Authorization") != "Bearer valid" {
+				w.WriteHeader(http.StatusUnauthorized)
+				return
+			}
+			issued := time.Now().UTC().Format(tppTimeFormat)
+			_, _ = fmt.Fprintf(w, `{"access_issued_on_ISO8601":"%s","valid_for":3600}`, issued)
+		case "/vedauth/authorize/token":
+			refreshes++
+			_, _ = fmt.Fprintf(w, `{"access_token":"valid","refresh_token":"refresh-%d","expires":%d}`, refreshes,
+				time.Now().Add(time.Hour).Unix())
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer server.Close()
+
+	dir := t.TempDir()
+	bundle := filepath.Join(dir, "bundle.pem")
+	err := os.WriteFile(bundle, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: server.Certificate().Raw}), 0600)
+	if err != nil {
+		t.Fatal(err)
+	}
+	path := filepath.Join(dir, "vcert.ini")
+	config := fmt.Sprintf("[tpp]\nurl = %s\naccess_token = expired\nrefresh_token = refresh-0\ntrust_bundle = %s\n", server.URL, bundle)
+	err = os.WriteFile(path, []byte(config), 0600)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// The expired access token is refreshed and the new pair written to the file
+	expires, renewed, err := RenewTokensInFile(context.Background(), path, "tpp", 15*time.Minute)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !renewed || time.Until(expires) < 50*time.Minute {
+		t.Fatalf("tokens not renewed, renewed %t, expires %s", renewed, expires)
+	}
+	cfg, err := LoadConfigFromFile(path, "tpp")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if cfg.Credentials.AccessToken != "valid" || cfg.Credentials.RefreshToken != "refresh-1" {
+		t.Fatalf("tokens not persisted, got %+v", cfg.Credentials)
+	}
+
+	// A valid access token is kept until it expires within renewBefore
+	_, renewed, err = RenewTokensInFile(context.Background(), path, "tpp", 15*time.Minute)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if renewed || refreshes != 1 {
+		t.Fatalf("valid access token renewed, %d refreshes", refreshes)
+	}
+	_, renewed, err = RenewTokensInFile(context.Background(), path, "tpp", 2*time.Hour)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !renewed || refreshes != 2 {
+		t.Fatalf("access token expiring within renewBefore not renewed, %d refreshes", refreshes)
+	}
+}
+
+func TestJWTExpiration(t *testing.T) {
+	claims := base64.RawURLEncoding.EncodeToString([]byte(`{"sub":"vcert","exp":1700000000}`))
+	if exp := jwtExpiration("eyJhbGciOiJub25lIn0." + claims + ".sig"); !exp.Equal(time.Unix(1700000000, 0)) {
+		t.Fatalf("unexpected expiration %s", exp)
+	}
+	if exp := jwtExpiration("opaque-token"); !exp.IsZero() {
+		t.Fatalf("unexpected expiration %s for an opaque token", exp)
+	}
+}